@@ -35,7 +35,39 @@ var agentCmd = &cobra.Command{
 	Aliases: []string{"ag"}, // Optional alias
 	Short:   "Manage agent configurations",
 	Long: `Manage agent configurations that allow you to quickly switch between
-different AI assistant setups with different models, tools, and settings.`,
+different AI assistant setups with different models, tools, and settings.
+
+An agent file can declare 'extends: <agent>' in its frontmatter to inherit the
+model, tools, capabilities and system prompt of another agent, overriding only the
+fields it sets. An empty prompt inherits the parent's, and {{parent}} in the prompt
+is replaced by it.
+
+'shell: <name>' selects the shell of the shell tool: auto (default), sh, bash,
+powershell, pwsh or cmd. On Windows auto uses bash inside Git Bash, otherwise
+PowerShell, falling back to cmd.
+
+'injection_guard: <mode>' sets how prompt injection found in web_fetch, web_search
+and MCP results is handled: off, flag (default, warn and mark the content as
+untrusted), strip (remove the suspicious parts) or confirm (ask before the content
+enters the conversation).
+
+'show_tools: <mode>' sets how tool calls are displayed: full (arguments, duration
+and a truncated result), compact (one line per call) or off. --show-tools and
+/tools-view override it.
+
+'budget_usd: <amount>' caps the estimated spend of a session with the agent, from
+the model prices. When it is reached gllm asks before calling the model again.
+--max-cost sets a budget for a single invocation.
+
+'best_of: <n>' samples n answers to each prompt at once, without tools, and keeps the
+best: by majority vote when the answers are short or JSON, otherwise the model picks
+one. --show-candidates prints all of them.
+
+'paths: {read: [...], write: [...], deny: [...]}' limits the files the tools may
+touch, with globs relative to the working directory. Once read or write globs are
+set the agent writes only under the write globs, read globs alone make it read-only.
+With read globs it reads only under the read and write globs. deny blocks both. The
+shell runs only when the working directory is writable.`,
 	// Add completion support
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 0 {
@@ -143,7 +175,7 @@ var agentAddCmd = &cobra.Command{
 		toolsList := service.GetEmbeddingTools()
 		var toolsOptions []huh.Option[string]
 		for _, s := range toolsList {
-			toolsOptions = append(toolsOptions, huh.NewOption(s, s).Selected(true))
+			toolsOptions = append(toolsOptions, huh.NewOption(s, s).Selected(!service.IsOptionalTool(s)))
 		}
 		ui.SortOptions(toolsOptions, "")
 
@@ -280,6 +312,7 @@ var agentAddCmd = &cobra.Command{
 				huh.NewOption("Enable Agent Memory", service.CapabilityAgentMemory).Selected(false),
 				huh.NewOption("Enable Sub Agents", service.CapabilitySubAgents).Selected(false),
 				huh.NewOption("Enable Web Search", service.CapabilityWebSearch).Selected(false),
+				huh.NewOption("Recall Related Sessions", service.CapabilitySessionRecall).Selected(false),
 			).
 			Value(&capabilities)
 		featureNote := ui.GetDynamicHuhNote("Feature Details", msfeatures, service.GetCapabilityDescHighlight)
@@ -529,6 +562,7 @@ var agentSetCmd = &cobra.Command{
 			huh.NewOption("Enable Agent Memory", service.CapabilityAgentMemory).Selected(capsSet[service.CapabilityAgentMemory]),
 			huh.NewOption("Enable Sub Agents", service.CapabilitySubAgents).Selected(capsSet[service.CapabilitySubAgents]),
 			huh.NewOption("Enable Web Search", service.CapabilityWebSearch).Selected(capsSet[service.CapabilityWebSearch]),
+			huh.NewOption("Recall Related Sessions", service.CapabilitySessionRecall).Selected(capsSet[service.CapabilitySessionRecall]),
 		}
 		ui.SortMultiOptions(capsOpts, capabilities)
 		msfeatures := huh.NewMultiSelect[string]().
@@ -557,14 +591,22 @@ var agentSetCmd = &cobra.Command{
 		sysPrompt = strings.TrimSpace(sysPrompt)
 
 		agentConfig := &data.AgentConfig{
-			Name:          name,
-			Description:   description,
-			Model:         data.Model{Name: model},
-			Tools:         tools,
-			Capabilities:  capabilities,
-			Think:         think,
-			SystemPrompt:  sysPrompt,
-			MaxRecursions: recursionVal,
+			Name:           name,
+			Description:    description,
+			Model:          data.Model{Name: model},
+			Tools:          tools,
+			Capabilities:   capabilities,
+			Think:          think,
+			SystemPrompt:   sysPrompt,
+			MaxRecursions:  recursionVal,
+			AutoFormat:     agent.AutoFormat,
+			Shell:          agent.Shell,
+			InjectionGuard: agent.InjectionGuard,
+			Paths:          agent.Paths,
+			ShowTools:      agent.ShowTools,
+			BudgetUSD:      agent.BudgetUSD,
+			BestOf:         agent.BestOf,
+			Extends:        agent.Extends,
 		}
 
 		err = store.SetAgent(name, agentConfig)
@@ -720,6 +762,9 @@ tools, search settings, and other preferences to match the selected agent.`,
 			util.Printf(cmd, "Error switching to agent: %v\n", err)
 			return
 		}
+		// The agent comes with its own model, and owes no agent a handoff
+		data.SetModelInSession("")
+		service.ClearHandoffReturns()
 
 		util.Printf(cmd, "Switched to agent '%s'.\n", name)
 	},
@@ -872,6 +917,58 @@ var agentRenameCmd = &cobra.Command{
 	},
 }
 
+var agentConcurrencyCmd = &cobra.Command{
+	Use:   "concurrency [N]",
+	Short: "Show or set how many sub-agent tasks run at the same time",
+	Long: `Limits the sub-agent tasks of one spawn that run at once, the others wait for a
+slot and start by priority. Without flags N is the limit of all tasks, 0 restores
+the default. --per-agent sets the limit of the tasks of any one agent and --agent
+the limit of the named agent, 0 removes them.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := data.GetSettingsStore()
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 0 {
+				return fmt.Errorf("invalid limit '%s'", args[0])
+			}
+			perAgent, _ := cmd.Flags().GetBool("per-agent")
+			agentName, _ := cmd.Flags().GetString("agent")
+			switch {
+			case agentName != "":
+				if data.NewConfigStore().GetAgent(agentName) == nil {
+					return fmt.Errorf("agent '%s' not found", agentName)
+				}
+				err = store.SetSubagentAgentLimit(agentName, n)
+			case perAgent:
+				err = store.SetSubagentMaxPerAgent(n)
+			default:
+				err = store.SetSubagentMaxConcurrent(n)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		limits := store.GetSubagentLimits()
+		util.Printf(cmd, "Sub-agent tasks at once: %d\n", limits.MaxConcurrent)
+		if limits.MaxPerAgent > 0 {
+			util.Printf(cmd, "Tasks of one agent at once: %d\n", limits.MaxPerAgent)
+		} else {
+			util.Println(cmd, "Tasks of one agent at once: no limit")
+		}
+		names := make([]string, 0, len(limits.AgentLimits))
+		for name := range limits.AgentLimits {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			util.Printf(cmd, "  %s%s%s: %d\n", data.KeyColor, name, data.ResetSeq, limits.AgentLimits[name])
+		}
+		return nil
+	},
+}
+
 func init() {
 	rootCmd.AddCommand(agentCmd)
 
@@ -885,6 +982,11 @@ func init() {
 	agentCmd.AddCommand(agentInfoCmd)
 	agentCmd.AddCommand(agentExportCmd)
 	agentCmd.AddCommand(agentImportCmd)
+	agentCmd.AddCommand(agentConcurrencyCmd)
+
+	agentExportCmd.Flags().StringP("output", "o", "", "Output file, a .yaml/.yml file writes a shareable bundle")
+	agentConcurrencyCmd.Flags().Bool("per-agent", false, "Set the limit of the tasks of any one agent")
+	agentConcurrencyCmd.Flags().String("agent", "", "Set the limit of the tasks of this agent")
 }
 
 // NOTE: getToolsFromConfig is no longer needed - data.AgentConfig.Tools is already []string
@@ -902,6 +1004,9 @@ func renderAgentConfigDetails(agent *data.AgentConfig, spaceholder string) strin
 	if agent.Description != "" {
 		fmt.Fprintf(&sb, "%sDescription: %s\n", spaceholder, agent.Description)
 	}
+	if agent.Extends != "" {
+		fmt.Fprintf(&sb, "%sExtends: %s\n", spaceholder, agent.Extends)
+	}
 	if agent.Model.Name != "" {
 		fmt.Fprintf(&sb, "%sModel: %s\n", spaceholder, agent.Model.Name)
 	}
@@ -924,6 +1029,32 @@ func renderAgentConfigDetails(agent *data.AgentConfig, spaceholder string) strin
 	}
 	fmt.Fprintf(&sb, "%sCapabilities:%s\n", spaceholder, capsSlice.String())
 	fmt.Fprintf(&sb, "%sMax Recursions: %d\n", spaceholder, agent.MaxRecursions)
+	if agent.AutoFormat {
+		fmt.Fprintf(&sb, "%sAuto Format: on\n", spaceholder)
+	}
+	if agent.Shell != "" {
+		fmt.Fprintf(&sb, "%sShell: %s\n", spaceholder, agent.Shell)
+	}
+	if agent.InjectionGuard != "" {
+		fmt.Fprintf(&sb, "%sInjection Guard: %s\n", spaceholder, agent.InjectionGuard)
+	}
+	if agent.ShowTools != "" {
+		fmt.Fprintf(&sb, "%sShow Tools: %s\n", spaceholder, agent.ShowTools)
+	}
+	if agent.BudgetUSD > 0 {
+		fmt.Fprintf(&sb, "%sBudget: $%.2f per session\n", spaceholder, agent.BudgetUSD)
+	}
+	if agent.BestOf > 1 {
+		fmt.Fprintf(&sb, "%sBest Of: %d\n", spaceholder, agent.BestOf)
+	}
+	for _, p := range []struct {
+		label string
+		globs []string
+	}{{"Read Paths", agent.Paths.Read}, {"Write Paths", agent.Paths.Write}, {"Denied Paths", agent.Paths.Deny}} {
+		if len(p.globs) > 0 {
+			fmt.Fprintf(&sb, "%s%s: %s\n", spaceholder, p.label, strings.Join(p.globs, ", "))
+		}
+	}
 
 	return sb.String()
 }
@@ -1011,11 +1142,17 @@ func runMaxRecursionsSelection(currentVal int) (int, error) {
 // agentExportCmd exports an agent's .md file to the given path.
 var agentExportCmd = &cobra.Command{
 	Use:   "export [NAME] [FILE]",
-	Short: "Export an agent to a Markdown file",
+	Short: "Export an agent to a Markdown file or a shareable bundle",
 	Long: `Export a named agent's configuration to a portable .md file.
 
 If [FILE] is omitted the agent is exported to ./<name>.md in the current directory.
-If [FILE] is a directory the file is placed inside that directory as <name>.md.`,
+If [FILE] is a directory the file is placed inside that directory as <name>.md.
+
+If [FILE] ends in .yaml or .yml the agent is exported as a bundle: its resolved settings,
+system prompt and prompt template, tool list and the definition of its model.
+API keys and credentials in the model endpoint are left out, so bundles can be shared.`,
+	Example: `  gllm agent export coder
+  gllm agent export coder -o coder.agent.yaml`,
 	Args: cobra.MaximumNArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		var name string
@@ -1049,16 +1186,22 @@ If [FILE] is a directory the file is placed inside that directory as <name>.md.`
 			}
 		}
 
-		if len(args) == 2 {
+		if output, _ := cmd.Flags().GetString("output"); output != "" {
+			destPath = output
+		} else if len(args) == 2 {
 			destPath = args[1]
-			if info, err := os.Stat(destPath); err == nil && info.IsDir() {
-				destPath = filepath.Join(destPath, name+".md")
-			}
-		} else {
+		}
+		if destPath == "" {
 			destPath = name + ".md"
+		} else if info, err := os.Stat(destPath); err == nil && info.IsDir() {
+			destPath = filepath.Join(destPath, name+".md")
 		}
 
-		if err := data.ExportAgent(name, destPath); err != nil {
+		if data.IsAgentBundleFile(destPath) {
+			if err := data.NewConfigStore().ExportAgentBundle(name, destPath); err != nil {
+				return fmt.Errorf("failed to export agent: %w", err)
+			}
+		} else if err := data.ExportAgent(name, destPath); err != nil {
 			return fmt.Errorf("failed to export agent: %w", err)
 		}
 		util.Printf(cmd, "Agent '%s' exported to: %s\n", name, destPath)
@@ -1066,15 +1209,19 @@ If [FILE] is a directory the file is placed inside that directory as <name>.md.`
 	},
 }
 
-// agentImportCmd imports an agent from a .md file into the agents directory.
+// agentImportCmd imports an agent from a .md file or a bundle into the agents directory.
 var agentImportCmd = &cobra.Command{
 	Use:   "import [FILE]",
-	Short: "Import an agent from a Markdown file",
+	Short: "Import an agent from a Markdown file or a bundle",
 	Long: `Import an agent from a portable .md file into your local agents directory.
 
 The file must contain valid YAML frontmatter (name, description, model ...) between
 --- delimiters, followed by the system prompt. If an agent with the same name
-already exists you will be prompted to confirm overwrite.`,
+already exists you will be prompted to confirm overwrite.
+
+Bundles (.yaml/.yml files written by 'gllm agent export') are imported too. When the
+bundled model is not defined yet it is added without an API key; when the agent
+extends an agent that exists here, it keeps inheriting from it.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		srcPath := args[0]
@@ -1086,28 +1233,42 @@ already exists you will be prompted to confirm overwrite.`,
 
 		store := data.NewConfigStore()
 
-		// Parse and validate frontmatter before touching the config dir.
-		agent, err := data.ParseAgentFile(srcPath)
-		if err != nil {
-			util.Errorf(cmd, "Invalid agent file: %v\n", err)
-			return
-		}
-		if agent.Name == "" {
-			util.Errorf(cmd, "Agent file is missing a 'name' field in its frontmatter.\n")
-			return
-		}
-		if err := util.ValidateResourceName("agent", agent.Name); err != nil {
-			util.Errorf(cmd, "Agent name is invalid: %v\n", err)
-			return
+		// Parse and validate the file before touching the config dir.
+		var name string
+		var tools []string
+		var bundle *data.AgentBundle
+		if data.IsAgentBundleFile(srcPath) {
+			var err error
+			bundle, err = data.ReadAgentBundle(srcPath)
+			if err != nil {
+				util.Errorf(cmd, "Invalid agent bundle: %v\n", err)
+				return
+			}
+			name, tools = bundle.Agent.Name, bundle.Agent.Tools
+		} else {
+			agent, err := data.ParseAgentFile(srcPath)
+			if err != nil {
+				util.Errorf(cmd, "Invalid agent file: %v\n", err)
+				return
+			}
+			if agent.Name == "" {
+				util.Errorf(cmd, "Agent file is missing a 'name' field in its frontmatter.\n")
+				return
+			}
+			if err := util.ValidateResourceName("agent", agent.Name); err != nil {
+				util.Errorf(cmd, "Agent name is invalid: %v\n", err)
+				return
+			}
+			name, tools = agent.Name, agent.Tools
 		}
 
 		// Warn on collision.
-		if existing := store.GetAgent(agent.Name); existing != nil {
+		if existing := store.GetAgent(name); existing != nil {
 			var overwrite bool
 			_ = huh.NewForm(
 				huh.NewGroup(
 					huh.NewConfirm().
-						Title(fmt.Sprintf("Agent '%s' already exists. Overwrite?", agent.Name)).
+						Title(fmt.Sprintf("Agent '%s' already exists. Overwrite?", name)).
 						Value(&overwrite),
 				),
 			).Run()
@@ -1117,10 +1278,25 @@ already exists you will be prompted to confirm overwrite.`,
 			}
 		}
 
-		if err := data.ImportAgent(srcPath); err != nil {
+		if bundle != nil {
+			notes, err := store.ImportAgentBundle(bundle)
+			if err != nil {
+				util.Errorf(cmd, "Failed to import agent: %v\n", err)
+				return
+			}
+			for _, note := range notes {
+				util.Warnf(cmd, "Note: %s\n", note)
+			}
+		} else if err := data.ImportAgent(srcPath); err != nil {
 			util.Errorf(cmd, "Failed to import agent: %v\n", err)
 			return
 		}
-		util.Printf(cmd, "Agent '%s' imported successfully.\n", agent.Name)
+
+		for _, tool := range tools {
+			if !service.IsAvailableOpenTool(tool) {
+				util.Warnf(cmd, "Note: tool '%s' is not available in this version of gllm and will be ignored.\n", tool)
+			}
+		}
+		util.Printf(cmd, "Agent '%s' imported successfully.\n", name)
 	},
 }
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(apiCmd)
+	apiCmd.AddCommand(apiImportCmd)
+	apiCmd.AddCommand(apiListCmd)
+	apiCmd.AddCommand(apiShowCmd)
+	apiCmd.AddCommand(apiRemoveCmd)
+
+	apiImportCmd.Flags().String("name", "", "Name of the API, the prefix of its tools (default: the name of the spec file)")
+	apiImportCmd.Flags().String("profile", "", "Auth profile sent with the operations, see 'gllm config http'")
+	apiImportCmd.Flags().String("base-url", "", "Server the operations are sent to (default: the first server of the spec)")
+}
+
+var apiCmd = &cobra.Command{
+	Use:   "api",
+	Short: "Turn OpenAPI specs into tools",
+	Long: `Import OpenAPI 3 and Swagger 2 specs, JSON or YAML. Every operation becomes a tool named
+after the API and its operationId, e.g. petstore_getPetById, sent through the http_request
+tool: agents with http_request enabled can call them. Requests only go to the server of
+the API, operations other than GET, HEAD and OPTIONS are confirmed, and credentials come
+from an auth profile the model never sees.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		apiListCmd.Run(cmd, args)
+	},
+}
+
+var apiImportCmd = &cobra.Command{
+	Use:   "import SPEC",
+	Short: "Import an OpenAPI spec from a file or URL",
+	Long: `Import an OpenAPI spec from a file or URL, replacing the API of the same name. Import it
+again to pick up changes to the spec.`,
+	Example: `  gllm api import petstore.yaml --name petstore
+  gllm api import https://api.example.com/openapi.json --name example --profile example
+  gllm api import spec.json --name internal --base-url http://localhost:8080/api`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		source := args[0]
+		name, _ := cmd.Flags().GetString("name")
+		if name == "" {
+			base := filepath.Base(source)
+			name = strings.TrimSuffix(base, filepath.Ext(base))
+		}
+		profile, _ := cmd.Flags().GetString("profile")
+		baseURL, _ := cmd.Flags().GetString("base-url")
+		if !strings.HasPrefix(source, "https://") && !strings.HasPrefix(source, "http://") {
+			if abs, err := filepath.Abs(source); err == nil {
+				source = abs
+			}
+		}
+
+		content, err := service.ReadOpenAPISpec(context.Background(), source)
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		api, err := service.ImportOpenAPI(content, name, source, strings.TrimSpace(baseURL))
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		api.Profile = profile
+		if err := service.CheckAPIProfile(api); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if err := data.SaveAPI(api); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "API %s%s%s imported with %d operations, sent to %s.\n", data.KeyColor, api.Name, data.ResetSeq, len(api.Operations), api.BaseURL)
+		util.Printf(cmd, "Agents with the %s tool can call them, see 'gllm api show %s'.\n", service.ToolHTTPRequest, api.Name)
+	},
+}
+
+var apiListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the imported APIs",
+	Run: func(cmd *cobra.Command, args []string) {
+		apis, err := data.LoadAPIs()
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if len(apis) == 0 {
+			util.Println(cmd, "No APIs imported. Use 'gllm api import <spec> --name <name>' to import one.")
+			return
+		}
+		util.Println(cmd, "Imported APIs:")
+		for _, api := range apis {
+			auth := "no auth"
+			if api.Profile != "" {
+				auth = "profile " + api.Profile
+			}
+			util.Printf(cmd, "  %s%s%s  %d operations, %s (%s)\n", data.KeyColor, api.Name, data.ResetSeq, len(api.Operations), api.BaseURL, auth)
+		}
+	},
+}
+
+var apiShowCmd = &cobra.Command{
+	Use:   "show NAME",
+	Short: "Show the tools of an imported API",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		apis, err := data.LoadAPIs()
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		for _, api := range apis {
+			if api.Name != args[0] {
+				continue
+			}
+			title := api.Name
+			if api.Title != "" {
+				title += " - " + api.Title
+			}
+			util.Printf(cmd, "%s\nSource: %s\nServer: %s\n", title, api.Source, api.BaseURL)
+			if api.Profile != "" {
+				util.Printf(cmd, "Auth profile: %s\n", api.Profile)
+			}
+			util.Println(cmd)
+			for _, op := range api.Operations {
+				util.Printf(cmd, "  %s%s%s  %s %s\n", data.KeyColor, op.Tool, data.ResetSeq, op.Method, op.Path)
+			}
+			return
+		}
+		util.Errorf(cmd, "API %s not found.\n", args[0])
+	},
+}
+
+var apiRemoveCmd = &cobra.Command{
+	Use:     "remove NAME",
+	Aliases: []string{"rm", "delete"},
+	Short:   "Remove an imported API and its tools",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := service.ValidateAPIName(args[0]); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		removed, err := data.RemoveAPI(args[0])
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if !removed {
+			util.Errorf(cmd, "API %s not found.\n", args[0])
+			return
+		}
+		util.Printf(cmd, "API %s removed.\n", args[0])
+	},
+}
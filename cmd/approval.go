@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configCmd.AddCommand(approvalCmd)
+	approvalCmd.AddCommand(approvalSetCmd)
+	approvalCmd.AddCommand(approvalAllowCmd)
+	approvalCmd.AddCommand(approvalDisallowCmd)
+	approvalCmd.AddCommand(approvalListCmd)
+	approvalCmd.AddCommand(approvalApproveCmd)
+	approvalCmd.AddCommand(approvalDenyCmd)
+
+	approvalSetCmd.Flags().String("backend", "", "Approval backend: "+strings.Join(service.SupportedApprovalBackends, ", "))
+	approvalSetCmd.Flags().String("dir", "", "Directory of the file backend (empty for the default)")
+	approvalSetCmd.Flags().String("webhook", "", "URL the webhook backend posts requests to")
+	approvalSetCmd.Flags().String("plugin", "", "Plugin the plugin backend asks, see 'gllm plugin add'")
+	approvalSetCmd.Flags().Int("timeout", 0, "Seconds to wait for a decision (0 for the default)")
+	approvalAllowCmd.Flags().Bool("command", false, "Treat the arguments as shell command prefixes, e.g. 'go test'")
+	approvalDisallowCmd.Flags().Bool("command", false, "Treat the arguments as shell command prefixes")
+}
+
+var approvalCmd = &cobra.Command{
+	Use:   "approval",
+	Short: "Manage how tool calls are approved without a terminal",
+	Long: `Display or manage headless tool approval.
+When gllm runs without a terminal (cron, CI) or the backend is set in serve mode,
+tool confirmations are not prompted. The policy approves the tools and command prefixes
+it allows, the backend decides on the rest:
+
+  prompt   Ask in the terminal; without one, deny what the policy does not allow (default)
+  policy   Deny what the policy does not allow
+  file     Write a request to the approval directory and wait for
+           'gllm config approval approve|deny ID', or an ID.approved / ID.denied file
+  webhook  POST the request as JSON to a URL, which answers {"approve": bool, "feedback": "..."}
+  plugin   Send the request to a plugin as approval/request, which answers the same
+
+Denied calls are reported to the model with the reason. Every decision is recorded in the
+audit log with its approver, see 'gllm audit list'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Print Long description
+		util.Println(cmd, cmd.Long)
+		util.Println(cmd)
+		util.Print(cmd, renderApprovalSettings(data.GetSettingsStore().GetApprovalSettings()))
+	},
+}
+
+var approvalSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Set the approval backend and its options",
+	Example: `  gllm config approval set --backend file --timeout 600
+  gllm config approval set --backend webhook --webhook https://approvals.example.com/gllm
+  gllm config approval set --backend plugin --plugin slack-approvals`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		settings := data.GetSettingsStore()
+		approval := settings.GetApprovalSettings()
+		if cmd.Flags().Changed("backend") {
+			approval.Backend, _ = cmd.Flags().GetString("backend")
+			approval.Backend = strings.ToLower(approval.Backend)
+		}
+		if cmd.Flags().Changed("dir") {
+			approval.Dir, _ = cmd.Flags().GetString("dir")
+		}
+		if cmd.Flags().Changed("webhook") {
+			approval.Webhook, _ = cmd.Flags().GetString("webhook")
+		}
+		if cmd.Flags().Changed("plugin") {
+			approval.Plugin, _ = cmd.Flags().GetString("plugin")
+			approval.Plugin = strings.ToLower(approval.Plugin)
+		}
+		if cmd.Flags().Changed("timeout") {
+			approval.Timeout, _ = cmd.Flags().GetInt("timeout")
+		}
+		if err := service.ValidateApprovalSettings(approval); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if err := settings.SetApprovalSettings(approval); err != nil {
+			util.Errorf(cmd, "Failed to update settings: %v\n", err)
+			return
+		}
+		util.Print(cmd, renderApprovalSettings(approval))
+	},
+}
+
+var approvalAllowCmd = &cobra.Command{
+	Use:   "allow TOOL...",
+	Short: "Let the policy approve tools or shell command prefixes",
+	Example: `  gllm config approval allow read_file list_directory
+  gllm config approval allow --command "go test" "git status"`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		updateApprovalPolicy(cmd, args, true)
+	},
+}
+
+var approvalDisallowCmd = &cobra.Command{
+	Use:   "disallow TOOL...",
+	Short: "Remove tools or shell command prefixes from the policy",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		updateApprovalPolicy(cmd, args, false)
+	},
+}
+
+var approvalListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List tool calls waiting for a decision from the file backend",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		requests, err := data.ListApprovalRequests(data.GetSettingsStore().GetApprovalSettings().Dir)
+		if err != nil {
+			util.Errorf(cmd, "Failed to read approval requests: %v\n", err)
+			return
+		}
+		if len(requests) == 0 {
+			util.Println(cmd, "No tool calls waiting for approval.")
+			return
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tAGENT\tTOOL\tREQUEST")
+		for _, r := range requests {
+			request := r.Description
+			if r.Command != "" {
+				request = r.Command
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.ID, r.Time.Local().Format("15:04:05"), r.Agent, r.Tool, benchPreview(request, 60))
+		}
+		w.Flush()
+	},
+}
+
+var approvalApproveCmd = &cobra.Command{
+	Use:   "approve ID",
+	Short: "Approve a tool call waiting in the file backend",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		decideApproval(cmd, args[0], data.ApprovalDecision{Approve: true})
+	},
+}
+
+var approvalDenyCmd = &cobra.Command{
+	Use:     "deny ID [REASON]",
+	Short:   "Deny a tool call waiting in the file backend, the reason is sent to the model",
+	Example: `  gllm config approval deny 3f2a9c1e "run the tests with -short"`,
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		decideApproval(cmd, args[0], data.ApprovalDecision{Feedback: strings.Join(args[1:], " ")})
+	},
+}
+
+func decideApproval(cmd *cobra.Command, id string, decision data.ApprovalDecision) {
+	if err := data.WriteApprovalDecision(data.GetSettingsStore().GetApprovalSettings().Dir, id, decision); err != nil {
+		util.Errorf(cmd, "%v\n", err)
+		return
+	}
+	if decision.Approve {
+		util.Printf(cmd, "Approved %s.\n", id)
+	} else {
+		util.Printf(cmd, "Denied %s.\n", id)
+	}
+}
+
+// updateApprovalPolicy adds or removes tools, or command prefixes with --command, from the policy.
+func updateApprovalPolicy(cmd *cobra.Command, args []string, allow bool) {
+	isCommand, _ := cmd.Flags().GetBool("command")
+	settings := data.GetSettingsStore()
+	approval := settings.GetApprovalSettings()
+	list := &approval.AllowTools
+	if isCommand {
+		list = &approval.AllowCommands
+	}
+	for _, arg := range args {
+		arg = strings.Join(strings.Fields(arg), " ")
+		if !isCommand && allow && !slices.Contains(service.GetAllOpenTools(), arg) {
+			util.Warnf(cmd, "'%s' is not a built-in tool, it only matches an MCP tool of that name\n", arg)
+		}
+		*list = slices.DeleteFunc(*list, func(s string) bool { return s == arg })
+		if allow {
+			*list = append(*list, arg)
+		}
+	}
+	if err := settings.SetApprovalSettings(approval); err != nil {
+		util.Errorf(cmd, "Failed to update settings: %v\n", err)
+		return
+	}
+	util.Print(cmd, renderApprovalSettings(approval))
+}
+
+func renderApprovalSettings(approval data.ApprovalSettings) string {
+	var sb strings.Builder
+	backend := approval.Backend
+	if backend == "" {
+		backend = service.ApprovalBackendPrompt
+	}
+	fmt.Fprintf(&sb, "Backend: %s%s%s\n", data.KeyColor, backend, data.ResetSeq)
+	switch backend {
+	case service.ApprovalBackendFile:
+		dir := approval.Dir
+		if dir == "" {
+			dir = data.GetApprovalsDirPath()
+		}
+		fmt.Fprintf(&sb, "Directory: %s\n", dir)
+	case service.ApprovalBackendWebhook:
+		fmt.Fprintf(&sb, "Webhook: %s\n", approval.Webhook)
+	case service.ApprovalBackendPlugin:
+		fmt.Fprintf(&sb, "Plugin: %s\n", approval.Plugin)
+	}
+	if backend != service.ApprovalBackendPrompt && backend != service.ApprovalBackendPolicy {
+		timeout := service.DefaultApprovalTimeout.String()
+		if approval.Timeout > 0 {
+			timeout = fmt.Sprintf("%ds", approval.Timeout)
+		}
+		fmt.Fprintf(&sb, "Timeout: %s\n", timeout)
+	}
+	fmt.Fprintf(&sb, "%sPolicy allows:%s\n", data.SectionColor, data.ResetSeq)
+	if len(approval.AllowTools) == 0 && len(approval.AllowCommands) == 0 {
+		sb.WriteString("  (nothing)\n")
+	}
+	for _, name := range approval.AllowTools {
+		fmt.Fprintf(&sb, "  tool     %s\n", name)
+	}
+	for _, prefix := range approval.AllowCommands {
+		fmt.Fprintf(&sb, "  command  %s ...\n", prefix)
+	}
+	return sb.String()
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(artifactsCmd)
+	artifactsCmd.AddCommand(artifactsListCmd)
+	artifactsCmd.AddCommand(artifactsShowCmd)
+}
+
+var artifactsCmd = &cobra.Command{
+	Use:     "artifacts",
+	Aliases: []string{"artifact"},
+	Short:   "Browse the outputs of sub-agent runs",
+	Long: `Every spawn of sub-agents saves the full output of each task to
+.gllm/artifacts/<run-id>/ in the working directory, with an index.json manifest
+of the agent, instruction, timing and token usage of the tasks. The SharedState
+only keeps a summary of each output.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		artifactsListCmd.Run(cmd, args)
+	},
+}
+
+var artifactsListCmd = &cobra.Command{
+	Use:     "list [RUN]",
+	Aliases: []string{"ls"},
+	Short:   "List the runs, or the tasks of a run",
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 1 {
+			run, err := loadArtifactRun(args[0])
+			if err != nil {
+				util.Errorf(cmd, "%v\n", err)
+				return
+			}
+			util.Printf(cmd, "%sRun %s%s  %s  %s\n\n", data.SectionColor, run.ID, data.ResetSeq, run.Created.Format(time.DateTime), run.Session)
+			for _, a := range run.Artifacts {
+				util.Printf(cmd, "%s%s%s  %s  %s  %s  %d tokens  %s\n", data.KeyColor, a.TaskKey, data.ResetSeq, a.Agent, a.Status,
+					(time.Duration(a.DurationMs) * time.Millisecond).Round(time.Millisecond), a.TotalTokens, formatArtifactSize(a.Size))
+			}
+			return
+		}
+
+		runs, err := data.ListArtifactRuns()
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if len(runs) == 0 {
+			util.Printf(cmd, "No artifacts in %s.\n", data.GetArtifactsDirPath())
+			return
+		}
+		for _, r := range runs {
+			session := ""
+			if r.Session != "" {
+				session = "  session " + r.Session
+			}
+			util.Printf(cmd, "%s%s%s  %s  %d task(s)%s\n", data.KeyColor, r.ID, data.ResetSeq, r.Created.Format(time.DateTime), len(r.Artifacts), session)
+		}
+	},
+}
+
+var artifactsShowCmd = &cobra.Command{
+	Use:   "show RUN [TASK]",
+	Short: "Print the outputs of a run, or of one of its tasks",
+	Long: `Print the outputs of a run with their manifest entries. RUN may be 'latest'.
+TASK is the task key of the spawn, or the agentName_taskKey key of the SharedState.`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		run, err := loadArtifactRun(args[0])
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		keys := []string{}
+		if len(args) == 2 {
+			keys = append(keys, args[1])
+		} else {
+			for _, a := range run.Artifacts {
+				keys = append(keys, a.StateKey)
+			}
+		}
+		for i, key := range keys {
+			artifact, content, err := data.ReadArtifact(run.ID, key)
+			if err != nil {
+				util.Errorf(cmd, "%v\n", err)
+				return
+			}
+			if i > 0 {
+				util.Println(cmd)
+			}
+			util.Printf(cmd, "%s==> %s [%s -> %s] %s%s\n", data.SectionColor, artifact.TaskKey, artifact.Caller, artifact.Agent, artifact.Status, data.ResetSeq)
+			util.Printf(cmd, "Instruction: %s\n", artifact.Instruction)
+			util.Printf(cmd, "Started: %s, took %s, %d input and %d output tokens\n", artifact.StartTime.Format(time.DateTime),
+				(time.Duration(artifact.DurationMs) * time.Millisecond).Round(time.Millisecond), artifact.InputTokens, artifact.OutputTokens)
+			if artifact.Error != "" {
+				util.Printf(cmd, "%sError: %s%s\n", data.StatusErrorColor, artifact.Error, data.ResetSeq)
+			}
+			util.Println(cmd)
+			util.Println(cmd, content)
+		}
+	},
+}
+
+// loadArtifactRun reads the manifest of a run, 'latest' for the newest one.
+func loadArtifactRun(id string) (*data.ArtifactRun, error) {
+	if id != "latest" {
+		return data.LoadArtifactRun(id)
+	}
+	runs, err := data.ListArtifactRuns()
+	if err != nil {
+		return nil, err
+	}
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("no artifacts in %s", data.GetArtifactsDirPath())
+	}
+	return &runs[0], nil
+}
+
+func formatArtifactSize(size int) string {
+	if size < 1024 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.1f KB", float64(size)/1024)
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/io"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// askColumnWidth is the narrowest column of the side by side layout, narrower
+// terminals get the answers one after the other.
+const askColumnWidth = 36
+
+func init() {
+	rootCmd.AddCommand(askCmd)
+	askCmd.Flags().StringSliceP("models", "m", nil, "Models to ask, comma separated (required)")
+	askCmd.Flags().StringP("judge", "j", "", "Model comparing the answers, 'off' for none (default: council.judge setting)")
+	askCmd.Flags().StringP("layout", "l", "auto", "Layout of the answers: auto, side or stacked")
+	askCmd.Flags().IntP("parallel", "p", 0, "Models asked at once (default: all)")
+	askCmd.Flags().StringSliceP("attachment", "a", nil, "Specify file(s), image(s), url(s), glob(s) or directories to append to the prompt")
+	askCmd.Flags().Bool("json", false, "Print the answers as JSON")
+	askCmd.MarkFlagRequired("models")
+}
+
+var askCmd = &cobra.Command{
+	Use:   "ask [PROMPT]",
+	Short: "Ask several models the same question",
+	Long: `Asks several models the same question in parallel and shows their answers side by
+side, or one after the other when the terminal is too narrow. A judge model, set with
+--judge or 'gllm config set council.judge MODEL', then compares the answers and gives
+the best one. The current agent provides the system prompt, the models run without tools.
+
+Example:
+  gllm ask --models gpt4,claude,gemini "Is a B-tree or an LSM tree better for this workload?"
+  gllm ask -m gpt4,claude --judge gemini --layout stacked "Review this function" -a main.go`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		modelNames, _ := cmd.Flags().GetStringSlice("models")
+		judgeName, _ := cmd.Flags().GetString("judge")
+		layout, _ := cmd.Flags().GetString("layout")
+		parallel, _ := cmd.Flags().GetInt("parallel")
+		attachments, _ := cmd.Flags().GetStringSlice("attachment")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		switch layout {
+		case "auto", "side", "stacked":
+		default:
+			return fmt.Errorf("unknown layout '%s', expected auto, side or stacked", layout)
+		}
+
+		store := data.NewConfigStore()
+		var models []*data.Model
+		for _, name := range modelNames {
+			model := store.GetModel(strings.TrimSpace(name))
+			if model == nil {
+				return fmt.Errorf("model '%s' not found", name)
+			}
+			models = append(models, model)
+		}
+		if len(models) == 0 {
+			return fmt.Errorf("no models to ask")
+		}
+
+		if !cmd.Flags().Changed("judge") {
+			judgeName = data.GetSettingsStore().GetCouncilJudge()
+		}
+		var judge *data.Model
+		if judgeName != "" && !strings.EqualFold(judgeName, "off") {
+			if judge = store.GetModel(judgeName); judge == nil {
+				return fmt.Errorf("judge model '%s' not found", judgeName)
+			}
+		}
+
+		var files []*service.FileData
+		if len(attachments) > 0 {
+			files = BatchAttachments(attachments)
+		}
+
+		ctx, stopInterrupt := newInterruptContext()
+		defer stopInterrupt()
+
+		done := 0
+		result := service.RunCouncil(service.CouncilOptions{
+			Ctx:      ctx,
+			Prompt:   strings.Join(args, " "),
+			Files:    files,
+			Models:   models,
+			Judge:    judge,
+			Agent:    store.GetActiveAgent(),
+			Parallel: parallel,
+			OnAnswer: func(a service.CouncilAnswer) {
+				done++
+				if asJSON {
+					return
+				}
+				status := data.StatusSuccessColor + "answered" + data.ResetSeq
+				if a.Error != "" {
+					status = data.StatusErrorColor + "failed" + data.ResetSeq
+				}
+				util.Printf(cmd, "[%d/%d] %s: %s (%.2fs)\n", done, len(models), a.Model, status, float64(a.LatencyMs)/1000)
+				if done == len(models) && judge != nil && ctx.Err() == nil {
+					util.Printf(cmd, "Asking %s to compare the answers...\n", judge.Name)
+				}
+			},
+		})
+
+		if asJSON {
+			report, err := json.MarshalIndent(result, "", "  ")
+			if err != nil {
+				return err
+			}
+			util.Println(cmd, string(report))
+			return nil
+		}
+
+		util.Println(cmd)
+		width := io.GetTerminalWidth()
+		if layout == "side" || (layout == "auto" && width >= len(result.Answers)*(askColumnWidth+2)) {
+			util.Println(cmd, renderAnswersSideBySide(result.Answers, width))
+		} else {
+			for _, answer := range result.Answers {
+				util.Println(cmd, renderAnswer(answer, width))
+			}
+		}
+		if result.Verdict != nil {
+			util.Println(cmd, renderAnswer(*result.Verdict, width))
+		}
+		return nil
+	},
+}
+
+// askAnswerTitle labels an answer with its model, latency and cost.
+func askAnswerTitle(a service.CouncilAnswer) string {
+	title := fmt.Sprintf("%s · %.2fs", a.Model, float64(a.LatencyMs)/1000)
+	if a.Cost > 0 {
+		title += fmt.Sprintf(" · $%.4f", a.Cost)
+	}
+	return title
+}
+
+// askAnswerBody is the text of an answer, or its error.
+func askAnswerBody(a service.CouncilAnswer) string {
+	body := a.Output
+	if a.Error != "" {
+		body = strings.TrimSpace(body + "\n\n" + data.StatusErrorColor + "error: " + a.Error + data.ResetSeq)
+	}
+	return body
+}
+
+// renderAnswer renders an answer in a box of the given width.
+func renderAnswer(a service.CouncilAnswer, width int) string {
+	return askAnswerBox(a, max(askColumnWidth, width-2))
+}
+
+// renderAnswersSideBySide renders the answers as columns sharing the width.
+func renderAnswersSideBySide(answers []service.CouncilAnswer, width int) string {
+	columnWidth := max(askColumnWidth, width/len(answers)-1)
+	columns := make([]string, len(answers))
+	for i, answer := range answers {
+		columns[i] = askAnswerBox(answer, columnWidth)
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top, columns...)
+}
+
+func askAnswerBox(a service.CouncilAnswer, width int) string {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(data.SectionHex))
+	boxStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(data.BorderHex)).
+		Padding(0, 1)
+	boxStyle = boxStyle.Width(width - boxStyle.GetHorizontalBorderSize())
+	return boxStyle.Render(titleStyle.Render(askAnswerTitle(a)) + "\n\n" + askAnswerBody(a))
+}
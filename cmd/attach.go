@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/internal/ui"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(attachCmd)
+}
+
+var attachCmd = &cobra.Command{
+	Use:   "attach SESSION",
+	Short: "Attach the terminal to a session kept by the daemon",
+	Long: `Attach the terminal to a session of the gllm daemon, starting the daemon when it is
+not running. Each line typed is a prompt, or a command such as /clear, queued in the
+session; the session keeps running when the terminal detaches. Other terminals
+attached to the same session see the same prompts and answers.
+
+While attached:
+  /cancel    Cancel the running prompt and drop the queued ones
+  /detach    Leave the session (also Ctrl-D)
+Ctrl-C cancels the running prompt.`,
+	Example: `  gllm attach my-session
+  gllm attach 3`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		session := args[0]
+		// Resolve index-based names to their canonical file name.
+		name, err := service.FindSessionByIndex(session)
+		if err != nil {
+			return fmt.Errorf("error finding session: %v", err)
+		}
+		if name != "" {
+			session = name
+		}
+
+		if _, err := startDaemon(); err != nil {
+			return err
+		}
+		return attachSession(session)
+	},
+}
+
+// attachSession runs the terminal client of a daemon session until the user detaches.
+func attachSession(session string) error {
+	client := newDaemonClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := client.Events(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	if agent, err := EnsureActiveAgent(); err == nil {
+		if history, _, _ := service.RenderSessionHistory(agent, session); history != "" {
+			fmt.Println(history)
+		}
+	}
+	fmt.Printf("%sAttached to %s.%s Type /detach or Ctrl-D to leave.\n", data.StatusInfoColor, session, data.ResetSeq)
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+
+	a := &attachClient{client: client, session: session}
+	a.showPrompt()
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				fmt.Printf("\n%sThe daemon closed the session.%s\n", data.StatusWarnColor, data.ResetSeq)
+				return nil
+			}
+			a.render(ev)
+		case line, ok := <-lines:
+			if !ok {
+				fmt.Println()
+				return nil
+			}
+			if !a.input(line) {
+				return nil
+			}
+		case <-interrupts:
+			if err := client.Cancel(session); err != nil {
+				fmt.Printf("\n%sNothing to cancel, type /detach to leave.%s\n", data.DetailColor, data.ResetSeq)
+				a.showPrompt()
+			}
+		}
+	}
+}
+
+// attachClient renders the events of a session and sends the lines typed in the terminal.
+type attachClient struct {
+	client    *service.DaemonClient
+	session   string
+	request   *service.DaemonEvent // Interaction waiting for the next line
+	reasoning bool
+}
+
+func (a *attachClient) showPrompt() {
+	if a.request == nil {
+		fmt.Printf("%s> %s", data.PromptColor, data.ResetSeq)
+	}
+}
+
+func eventString(ev service.DaemonEvent, key string) string {
+	s, _ := ev.Data[key].(string)
+	return s
+}
+
+func (a *attachClient) render(ev service.DaemonEvent) {
+	if ev.Type != "reasoning" && a.reasoning {
+		a.reasoning = false
+		fmt.Print(data.ResetSeq + "\n")
+	}
+	switch ev.Type {
+	case "prompt":
+		fmt.Printf("\n%s%s%s\n", data.RoleUserColor, eventString(ev, "content"), data.ResetSeq)
+	case "content":
+		fmt.Print(ev.Content)
+	case "reasoning":
+		if !a.reasoning {
+			a.reasoning = true
+			fmt.Print(data.ReasoningTextColor)
+		}
+		fmt.Print(ev.Content)
+	case "tool_call":
+		call, _ := ev.Data["content"].(map[string]interface{})
+		function, _ := call["function"].(string)
+		description, _ := call["description"].(string)
+		fmt.Printf("\n%s%s%s %s\n", data.ToolCallColor, function, data.ResetSeq, description)
+	case "command":
+		if msg := eventString(ev, "error"); msg != "" {
+			fmt.Printf("%s%s%s\n", data.StatusErrorColor, msg, data.ResetSeq)
+		} else {
+			fmt.Println(eventString(ev, "content"))
+		}
+	case "error":
+		fmt.Printf("\n%sError: %s%s\n", data.StatusErrorColor, eventString(ev, "content"), data.ResetSeq)
+	case "diff":
+		diff, _ := ui.DiffForConfirm(eventString(ev, "before"), eventString(ev, "after"), 3)
+		fmt.Println(diff)
+	case "request":
+		a.request = &ev
+		if eventString(ev, "type") == string(service.InteractionKindAskUser) {
+			fmt.Printf("\n%s%s%s\nAnswer: ", data.StatusWarnColor, eventString(ev, "purpose"), data.ResetSeq)
+		} else {
+			fmt.Printf("\n%s%s%s\nAllow? [y/N]: ", data.StatusWarnColor, eventString(ev, "purpose"), data.ResetSeq)
+		}
+	case "done":
+		fmt.Println()
+		a.showPrompt()
+	}
+}
+
+// input handles a line typed in the terminal, returning false to detach.
+func (a *attachClient) input(line string) bool {
+	if a.request != nil {
+		a.answer(strings.TrimSpace(line))
+		return true
+	}
+	text := strings.TrimSpace(line)
+	switch text {
+	case "":
+		a.showPrompt()
+		return true
+	case "/detach", "/exit", "/quit":
+		return false
+	case "/cancel":
+		if err := a.client.Cancel(a.session); err != nil {
+			fmt.Printf("%s%v%s\n", data.StatusErrorColor, err, data.ResetSeq)
+		}
+		a.showPrompt()
+		return true
+	}
+	if err := a.client.Prompt(a.session, text); err != nil {
+		fmt.Printf("%s%v%s\n", data.StatusErrorColor, err, data.ResetSeq)
+		a.showPrompt()
+	}
+	return true
+}
+
+// answer resolves the pending interaction. Another terminal may have answered it first.
+func (a *attachClient) answer(text string) {
+	ev := a.request
+	a.request = nil
+	req := InteractRequest{ID: eventString(*ev, "id"), Kind: eventString(*ev, "type")}
+	if req.Kind == string(service.InteractionKindAskUser) {
+		req.Answer = text
+		req.Cancelled = text == ""
+	} else if strings.EqualFold(text, "y") || strings.EqualFold(text, "yes") {
+		req.Approve = "once"
+	} else {
+		req.Approve = "cancel"
+	}
+	if err := a.client.Interact(req); err != nil {
+		util.LogWarnf("The request was already answered: %v\n", err)
+	}
+}
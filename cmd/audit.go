@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+	auditCmd.AddCommand(auditListCmd)
+	auditCmd.AddCommand(auditPathCmd)
+
+	auditListCmd.Flags().String("tool", "", "Only show calls of this tool")
+	auditListCmd.Flags().String("agent", "", "Only show calls made by this agent")
+	auditListCmd.Flags().StringP("session", "s", "", "Only show calls made in this session")
+	auditListCmd.Flags().String("since", "", "Only show calls newer than this, e.g. 30m, 24h, 7d")
+	auditListCmd.Flags().IntP("limit", "n", 50, "Show at most this many recent calls (0 for all)")
+	auditListCmd.Flags().Bool("json", false, "Print entries as JSON lines")
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Inspect the log of tool calls made by agents",
+	Long: `Every tool call an agent makes is appended to an audit log with its arguments,
+a summary of its result, the approval decision, the agent, the session and the time.
+Decisions made without a user name the approval backend that made them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var auditListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List recorded tool calls",
+	Example: `  gllm audit list --tool shell --since 24h
+  gllm audit list --agent coder --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tool, _ := cmd.Flags().GetString("tool")
+		agent, _ := cmd.Flags().GetString("agent")
+		session, _ := cmd.Flags().GetString("session")
+		sinceStr, _ := cmd.Flags().GetString("since")
+		limit, _ := cmd.Flags().GetInt("limit")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		filter := data.AuditFilter{Tool: tool, Agent: agent, Session: session, Limit: limit}
+		if sinceStr != "" {
+			since, err := parseSince(sinceStr)
+			if err != nil {
+				return err
+			}
+			filter.Since = time.Now().Add(-since)
+		}
+
+		entries, err := data.QueryAuditEntries(filter)
+		if err != nil {
+			return err
+		}
+
+		if asJSON {
+			for _, e := range entries {
+				line, _ := json.Marshal(e)
+				util.Println(cmd, string(line))
+			}
+			return nil
+		}
+		if len(entries) == 0 {
+			util.Println(cmd, "No tool calls recorded.")
+			return nil
+		}
+		printAuditTable(cmd, entries)
+		return nil
+	},
+}
+
+var auditPathCmd = &cobra.Command{
+	Use:   "path",
+	Short: "Show the audit log directory",
+	Run: func(cmd *cobra.Command, args []string) {
+		util.Println(cmd, data.GetAuditDirPath())
+	},
+}
+
+// parseSince parses a look-back window. Days are accepted on top of Go durations.
+func parseSince(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid --since value '%s'", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid --since value '%s', use e.g. 30m, 24h or 7d", s)
+	}
+	return d, nil
+}
+
+// printAuditTable renders audit entries as an aligned table.
+func printAuditTable(cmd *cobra.Command, entries []data.AuditEntry) {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tAGENT\tTOOL\tAPPROVAL\tARGS\tRESULT")
+	for _, e := range entries {
+		result := e.Result
+		if e.Error != "" {
+			result = "error: " + e.Error
+		}
+		args, _ := json.Marshal(e.Args)
+		agent := e.Agent
+		if agent == "" {
+			agent = "-"
+		}
+		approval := e.Approval
+		if e.Approver != "" {
+			approval += " (" + e.Approver + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"), agent, e.Tool, approval,
+			benchPreview(string(args), 50), benchPreview(result, 40))
+	}
+	w.Flush()
+}
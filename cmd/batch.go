@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(batchCmd)
+	batchCmd.AddCommand(batchSubmitCmd)
+	batchCmd.AddCommand(batchStatusCmd)
+	batchCmd.AddCommand(batchFetchCmd)
+	batchSubmitCmd.Flags().StringP("model", "m", "", "Model to run the jobs (default: current model)")
+	batchSubmitCmd.Flags().StringP("workflow", "w", "", "Workflow each prompt is run through")
+	batchFetchCmd.Flags().StringP("output", "o", "", "Write the results to a file instead of the standard output")
+}
+
+var batchCmd = &cobra.Command{
+	Use:   "batch",
+	Short: "Run offline jobs through the batch API of a provider",
+	Long: `Submits a file of prompts to the batch API of OpenAI or Anthropic, which runs them
+within 24 hours at half the price. The jobs file has one JSON job per line:
+  {"id": "review-1", "prompt": "Summarize ...", "system": "You are ..."}
+The id, which matches a result to its job, defaults to job-N, and the system prompt is optional.
+
+A workflow with 'batch: true' in its frontmatter submits the jobs file given to it,
+e.g. /summarize jobs.jsonl, each prompt following the workflow's instructions.
+
+Example:
+  gllm batch submit jobs.jsonl --model gpt4
+  gllm batch status
+  gllm batch fetch batch_abc123 -o results.jsonl`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Default action: list batches
+		batchStatusCmd.Run(batchStatusCmd, nil)
+	},
+}
+
+var batchSubmitCmd = &cobra.Command{
+	Use:   "submit FILE",
+	Short: "Submit a jobs file as a batch",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		modelName, _ := cmd.Flags().GetString("model")
+		workflow, _ := cmd.Flags().GetString("workflow")
+
+		jobs, err := data.LoadBatchJobs(args[0])
+		if err != nil {
+			return err
+		}
+		if workflow != "" {
+			wm := service.GetWorkflowManager()
+			if err := wm.LoadMetadata(replCommandMap); err != nil {
+				return fmt.Errorf("failed to load workflows: %w", err)
+			}
+			content, _, err := wm.GetWorkflowByName(workflow)
+			if err != nil {
+				return err
+			}
+			jobs = service.WorkflowBatchJobs(content, jobs)
+		}
+
+		store := data.NewConfigStore()
+		if modelName == "" {
+			agent := store.GetActiveAgent()
+			if agent == nil || agent.Model.Name == "" {
+				return fmt.Errorf("no model specified and no current model")
+			}
+			modelName = agent.Model.Name
+		}
+		model := store.GetModel(modelName)
+		if model == nil {
+			return fmt.Errorf("model '%s' not found", modelName)
+		}
+
+		ctx, stopInterrupt := newInterruptContext()
+		defer stopInterrupt()
+
+		id, provider, err := service.SubmitBatch(ctx, model, jobs)
+		if err != nil {
+			return err
+		}
+		batch := data.Batch{
+			ID:        id,
+			Model:     modelName,
+			Provider:  provider,
+			Jobs:      len(jobs),
+			Source:    args[0],
+			Workflow:  workflow,
+			Status:    "submitted",
+			CreatedAt: time.Now(),
+		}
+		if err := data.SaveBatch(batch); err != nil {
+			return fmt.Errorf("submitted batch %s but failed to record it: %w", id, err)
+		}
+		util.Printf(cmd, "Submitted %d jobs to %s as batch %s.\n", len(jobs), modelName, id)
+		util.Printf(cmd, "Check it with 'gllm batch status %s', results are ready within 24 hours.\n", id)
+		return nil
+	},
+}
+
+var batchStatusCmd = &cobra.Command{
+	Use:     "status [ID]",
+	Aliases: []string{"list", "ls"},
+	Short:   "Show the progress of the batches",
+	Args:    cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		batches, err := data.LoadBatches()
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if len(args) > 0 {
+			batches = filterBatches(batches, args[0])
+			if len(batches) == 0 {
+				util.Errorf(cmd, "batch '%s' not found\n", args[0])
+				return
+			}
+		}
+		if len(batches) == 0 {
+			util.Println(cmd, "No batches submitted.")
+			return
+		}
+
+		ctx, stopInterrupt := newInterruptContext()
+		defer stopInterrupt()
+
+		store := data.NewConfigStore()
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tMODEL\tSUBMITTED\tSTATUS\tJOBS\tSUCCEEDED\tFAILED")
+		for _, b := range batches {
+			succeeded, failed := "-", "-"
+			if model := store.GetModel(b.Model); model == nil {
+				b.Status = "unknown model"
+			} else if status, err := service.GetBatchStatus(ctx, model, b.ID); err != nil {
+				util.LogWarnf("Failed to check batch %s: %v\n", b.ID, err)
+			} else {
+				succeeded, failed = fmt.Sprint(status.Succeeded), fmt.Sprint(status.Failed)
+				if b.Status != status.Status {
+					b.Status = status.Status
+					data.SaveBatch(b)
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n", b.ID, b.Model, b.CreatedAt.Format("2006-01-02 15:04"), b.Status, b.Jobs, succeeded, failed)
+		}
+		w.Flush()
+	},
+}
+
+var batchFetchCmd = &cobra.Command{
+	Use:   "fetch ID",
+	Short: "Download the results of a batch",
+	Long: `Downloads the results of a finished batch as JSON lines:
+  {"id": "review-1", "output": "...", "input_tokens": 812, "output_tokens": 164}
+  {"id": "review-2", "error": "..."}`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputFile, _ := cmd.Flags().GetString("output")
+
+		batch, err := data.GetBatch(args[0])
+		if err != nil {
+			return err
+		}
+		if batch == nil {
+			return fmt.Errorf("batch '%s' not found", args[0])
+		}
+		model := data.NewConfigStore().GetModel(batch.Model)
+		if model == nil {
+			return fmt.Errorf("model '%s' of batch %s not found", batch.Model, batch.ID)
+		}
+
+		ctx, stopInterrupt := newInterruptContext()
+		defer stopInterrupt()
+
+		results, err := service.FetchBatchResults(ctx, model, batch.ID)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if outputFile != "" {
+			f, err := os.Create(outputFile)
+			if err != nil {
+				return fmt.Errorf("failed to write results: %w", err)
+			}
+			defer f.Close()
+			out = f
+		}
+		enc := json.NewEncoder(out)
+		enc.SetEscapeHTML(false)
+		failed := 0
+		for _, r := range results {
+			if r.Error != "" {
+				failed++
+			}
+			if err := enc.Encode(r); err != nil {
+				return fmt.Errorf("failed to write results: %w", err)
+			}
+		}
+		if outputFile != "" {
+			util.Printf(cmd, "Wrote %d results (%d failed) to %s\n", len(results), failed, outputFile)
+		}
+		return nil
+	},
+}
+
+// filterBatches keeps the batch of the ID.
+func filterBatches(batches []data.Batch, id string) []data.Batch {
+	for _, b := range batches {
+		if b.ID == id {
+			return []data.Batch{b}
+		}
+	}
+	return nil
+}
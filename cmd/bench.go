@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(benchCmd)
+	benchCmd.Flags().StringP("file", "f", "", "Prompt file (YAML) to run (required)")
+	benchCmd.Flags().StringSliceP("models", "m", nil, "Models to compare, comma separated (default: current model)")
+	benchCmd.Flags().Bool("no-tools", false, "Run without tools and agent capabilities")
+	benchCmd.Flags().Bool("json", false, "Print the report as JSON")
+	benchCmd.Flags().StringP("output", "o", "", "Write the JSON report to a file")
+	benchCmd.MarkFlagRequired("file")
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Compare models on a set of prompts",
+	Long: `Runs each prompt of a prompt file against each model and reports latency,
+token usage, cost and output. The current agent provides the system prompt and tools.
+
+The prompt file is YAML:
+  prompts:
+    - name: sort
+      prompt: Write a quicksort in Go
+  pricing:            # optional, USD per million tokens, keyed by model name
+    gpt4: {input: 2.5, output: 10}
+
+Example:
+  gllm bench -f prompts.yaml --models gpt4,claude,gemini --no-tools`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file, _ := cmd.Flags().GetString("file")
+		modelNames, _ := cmd.Flags().GetStringSlice("models")
+		noTools, _ := cmd.Flags().GetBool("no-tools")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		outputFile, _ := cmd.Flags().GetString("output")
+
+		suite, err := data.LoadBenchSuite(file)
+		if err != nil {
+			return err
+		}
+
+		store := data.NewConfigStore()
+		agent := store.GetActiveAgent()
+		if len(modelNames) == 0 {
+			if agent == nil || agent.Model.Name == "" {
+				return fmt.Errorf("no models specified and no current model")
+			}
+			modelNames = []string{agent.Model.Name}
+		}
+
+		var models []*data.Model
+		for _, name := range modelNames {
+			model := store.GetModel(strings.TrimSpace(name))
+			if model == nil {
+				return fmt.Errorf("model '%s' not found", name)
+			}
+			models = append(models, model)
+		}
+
+		ctx, stopInterrupt := newInterruptContext()
+		defer stopInterrupt()
+
+		total := len(models) * len(suite.Prompts)
+		done := 0
+		results := service.RunBenchmark(service.BenchOptions{
+			Ctx:     ctx,
+			Suite:   suite,
+			Models:  models,
+			Agent:   agent,
+			NoTools: noTools,
+			OnResult: func(r service.BenchResult) {
+				done++
+				if asJSON {
+					return
+				}
+				status := data.StatusSuccessColor + "ok" + data.ResetSeq
+				if r.Error != "" {
+					status = data.StatusErrorColor + "failed" + data.ResetSeq
+				}
+				util.Printf(cmd, "[%d/%d] %s / %s: %s (%s)\n", done, total, r.Model, r.Prompt, status, time.Duration(r.LatencyMs)*time.Millisecond)
+			},
+		})
+
+		report, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		if outputFile != "" {
+			if err := os.WriteFile(outputFile, report, 0644); err != nil {
+				return fmt.Errorf("failed to write report: %w", err)
+			}
+		}
+		if asJSON {
+			util.Println(cmd, string(report))
+			return nil
+		}
+
+		util.Println(cmd)
+		printBenchTable(cmd, results)
+		if outputFile != "" {
+			util.Printf(cmd, "\nReport written to %s\n", outputFile)
+		}
+		return nil
+	},
+}
+
+// printBenchTable renders the benchmark results as an aligned table.
+func printBenchTable(cmd *cobra.Command, results []service.BenchResult) {
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "MODEL\tPROMPT\tLATENCY\tINPUT\tOUTPUT\tCOST\tRESULT")
+	for _, r := range results {
+		cost := "-"
+		if r.Cost > 0 {
+			cost = fmt.Sprintf("$%.4f", r.Cost)
+		}
+		result := r.Output
+		if r.Error != "" {
+			result = "error: " + r.Error
+		}
+		fmt.Fprintf(w, "%s\t%s\t%.2fs\t%d\t%d\t%s\t%s\n",
+			r.Model, r.Prompt, float64(r.LatencyMs)/1000, r.InputTokens, r.OutputTokens, cost, benchPreview(result, 40))
+	}
+	w.Flush()
+}
+
+// benchPreview flattens a result to a single line of at most n runes.
+func benchPreview(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) > n {
+		return string(runes[:n-3]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cache of identical model requests",
+	Long: `Identical model requests, the same model, messages, tools and parameters, are answered
+from a cache on disk when it is on, so retried workflows, benchmarks and test runs do not
+pay twice. Turn it on with 'gllm config set cache.enabled on', skip it for a run with
+--no-cache.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cacheStatsCmd.Run(cmd, args)
+	},
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the size and the hit rate of the cache",
+	Run: func(cmd *cobra.Command, args []string) {
+		settings := data.GetSettingsStore().GetCacheSettings()
+		ttl, maxBytes := service.ResponseCacheConfig(settings)
+		state := "off"
+		if settings.Enabled {
+			state = "on"
+		}
+		stats := service.GetResponseCacheStats()
+		util.Printf(cmd, "Response cache: %s%s%s (ttl %s, max %s)\n", data.KeyColor, state, data.ResetSeq, ttl, formatCacheBytes(maxBytes))
+		util.Printf(cmd, "Entries: %d, %s\n", stats.Entries, formatCacheBytes(stats.Bytes))
+		if stats.Entries > 0 {
+			util.Printf(cmd, "Oldest: %s, newest: %s\n", stats.Oldest.Format(time.DateTime), stats.Newest.Format(time.DateTime))
+		}
+		if total := stats.Hits + stats.Misses; total > 0 {
+			util.Printf(cmd, "Hits: %d, misses: %d (%.0f%% hit rate) since %s\n", stats.Hits, stats.Misses,
+				float64(stats.Hits)*100/float64(total), stats.Since.Format(time.DateTime))
+		}
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every cached response",
+	Run: func(cmd *cobra.Command, args []string) {
+		n, err := service.ClearResponseCache()
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "Removed %d cached responses.\n", n)
+	},
+}
+
+func formatCacheBytes(n int64) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%d B", n)
+}
+
+// updateCacheSettings applies a change to the cache settings.
+func updateCacheSettings(change func(*data.CacheSettings)) error {
+	settings := data.GetSettingsStore()
+	cache := settings.GetCacheSettings()
+	change(&cache)
+	if err := settings.SetCacheSettings(cache); err != nil {
+		return fmt.Errorf("failed to update settings: %w", err)
+	}
+	return nil
+}
+
+func setCacheEnabled(value string) (string, error) {
+	var enabled bool
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "on", "true", "yes", "1":
+		enabled = true
+	case "off", "false", "no", "0":
+	default:
+		return "", fmt.Errorf("expected on or off, got '%s'", value)
+	}
+	if err := updateCacheSettings(func(c *data.CacheSettings) { c.Enabled = enabled }); err != nil {
+		return "", err
+	}
+	if enabled {
+		return "on", nil
+	}
+	return "off", nil
+}
+
+func setCacheTTL(value string) (string, error) {
+	ttl, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || ttl <= 0 {
+		return "", fmt.Errorf("invalid ttl '%s', expected a duration such as 1h or 168h", value)
+	}
+	if err := updateCacheSettings(func(c *data.CacheSettings) { c.TTL = ttl.String() }); err != nil {
+		return "", err
+	}
+	return ttl.String(), nil
+}
+
+func setCacheMaxSize(value string) (string, error) {
+	size, err := strconv.Atoi(strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(value)), "MB"))
+	if err != nil || size <= 0 {
+		return "", fmt.Errorf("invalid size '%s', expected megabytes, e.g. 200", value)
+	}
+	if err := updateCacheSettings(func(c *data.CacheSettings) { c.MaxSize = size }); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d MB", size), nil
+}
@@ -119,6 +119,14 @@ var capsSwitchCmd = &cobra.Command{
 			options = append(options, huh.NewOption("Auto Rename", service.CapabilityAutoRename))
 		}
 
+		// Session Recall
+		if service.IsSessionRecallEnabled(agent.Capabilities) {
+			options = append(options, huh.NewOption("Session Recall", service.CapabilitySessionRecall).Selected(true))
+			selected = append(selected, service.CapabilitySessionRecall)
+		} else {
+			options = append(options, huh.NewOption("Session Recall", service.CapabilitySessionRecall))
+		}
+
 		// Auto Compression
 		if service.IsAutoCompressionEnabled(agent.Capabilities) {
 			options = append(options, huh.NewOption("Auto Compression", service.CapabilityAutoCompression).Selected(true))
@@ -170,6 +178,7 @@ var capsSwitchCmd = &cobra.Command{
 			service.CapabilityAgentMemory,
 			service.CapabilityWebSearch,
 			service.CapabilityAutoRename,
+			service.CapabilitySessionRecall,
 			service.CapabilityAutoCompression,
 			service.CapabilityPlanMode,
 		}
@@ -204,6 +213,7 @@ func renderCapSummary(caps []string) string {
 	sb.WriteString(renderCapStatus(service.CapabilityMemoryTitle, service.IsAgentMemoryEnabled(caps)))
 	sb.WriteString(renderCapStatus(service.CapabilitySubAgentsTitle, service.IsSubAgentsEnabled(caps)))
 	sb.WriteString(renderCapStatus(service.CapabilityAutoRenameTitle, service.IsAutoRenameEnabled(caps)))
+	sb.WriteString(renderCapStatus(service.CapabilityRecallTitle, service.IsSessionRecallEnabled(caps)))
 	sb.WriteString(renderCapStatus(service.CapabilityAutoCompressTitle, service.IsAutoCompressionEnabled(caps)))
 	sb.WriteString(renderCapStatus(service.CapabilityPlanModeTitle, service.IsPlanModeEnabled(caps)))
 
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(checkpointCmd)
+	checkpointCmd.AddCommand(checkpointListCmd)
+	checkpointCmd.AddCommand(checkpointRestoreCmd)
+}
+
+var checkpointCmd = &cobra.Command{
+	Use:   "checkpoint",
+	Short: "Undo multi-file changes made by agents",
+	Long: `Every apply_changes transaction records a checkpoint of the files it touched,
+restoring a checkpoint puts those files back the way they were.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var checkpointListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List recorded checkpoints",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		checkpoints, err := data.ListCheckpoints()
+		if err != nil {
+			return err
+		}
+		if len(checkpoints) == 0 {
+			util.Println(cmd, "No checkpoints recorded.")
+			return nil
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTIME\tFILES\tPURPOSE")
+		for _, cp := range checkpoints {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", cp.ID, cp.Time.Local().Format("2006-01-02 15:04:05"),
+				len(cp.Files), benchPreview(cp.Purpose, 50))
+		}
+		w.Flush()
+		return nil
+	},
+}
+
+var checkpointRestoreCmd = &cobra.Command{
+	Use:   "restore <id>",
+	Short: "Restore the files of a checkpoint",
+	Long: `Puts every file of the checkpoint back to its recorded content, files the change
+created are removed. Edits made to those files since then are lost.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cp, err := data.LoadCheckpoint(args[0])
+		if err != nil {
+			return err
+		}
+		if err := service.RestoreCheckpoint(cp); err != nil {
+			return err
+		}
+		for _, f := range cp.Files {
+			util.Printf(cmd, "Restored %s\n", f.Path)
+		}
+		return nil
+	},
+}
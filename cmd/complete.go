@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/spf13/cobra"
+)
+
+// Dynamic shell completions. They read the config at completion time, so new agents,
+// models, sessions and servers complete without regenerating the completion script.
+// Candidates carry a description after a tab, shown by zsh, fish and PowerShell.
+
+// completeNames returns a ValidArgsFunction completing the first maxArgs arguments
+// (0 for any number) from names, skipping names already given.
+func completeNames(maxArgs int, names func() []string) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if maxArgs > 0 && len(args) >= maxArgs {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var out []string
+		for _, name := range names() {
+			value, _, _ := strings.Cut(name, "\t")
+			if strings.HasPrefix(value, toComplete) && !slices.Contains(args, value) {
+				out = append(out, name)
+			}
+		}
+		return out, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// agentCompletions lists the agents with their models.
+func agentCompletions() []string {
+	agents := data.NewConfigStore().GetAllAgents()
+	names := make([]string, 0, len(agents))
+	for name, agent := range agents {
+		names = append(names, fmt.Sprintf("%s\t%s", name, agent.Model.Name))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// modelCompletions lists the models with their provider and model ID.
+func modelCompletions() []string {
+	models := data.NewConfigStore().GetModels()
+	names := make([]string, 0, len(models))
+	for name, m := range models {
+		names = append(names, fmt.Sprintf("%s\t%s %s", name, m.Provider, m.Model))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// workflowCompletions lists the workflow templates with their descriptions.
+func workflowCompletions() []string {
+	workflows, err := data.ScanWorkflows()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(workflows))
+	for _, w := range workflows {
+		names = append(names, fmt.Sprintf("%s\t%s", w.Name, w.Description))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mcpServerCompletions lists the configured MCP servers with their transport.
+func mcpServerCompletions() []string {
+	servers, err := data.NewMCPStore().Load()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(servers))
+	for name, server := range servers {
+		names = append(names, fmt.Sprintf("%s\t%s", name, server.Type))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sessionCompletions lists the sessions, most recent first, as the session list shows them.
+func sessionCompletions() []string {
+	sessions, err := service.ListSortedSessions(false, false)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(sessions))
+	for _, s := range sessions {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+// scheduleCompletions lists the schedules with their cron expressions.
+func scheduleCompletions() []string {
+	schedules, err := data.LoadSchedules()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(schedules))
+	for _, s := range schedules {
+		names = append(names, fmt.Sprintf("%s\t%s", s.Name, s.Cron))
+	}
+	return names
+}
+
+// isCompletionCommand reports whether cmd generates a completion script or is cobra's
+// hidden completion request, both must work without config and without prompts.
+func isCompletionCommand(cmd *cobra.Command) bool {
+	return cmd.Name() == "completion" || cmd.Name() == cobra.ShellCompRequestCmd || cmd.Name() == cobra.ShellCompNoDescRequestCmd
+}
+
+// registerCompletions attaches the completions, once all commands and flags are defined.
+func registerCompletions() {
+	agentSetCmd.ValidArgsFunction = completeNames(1, agentCompletions)
+	agentRemoveCmd.ValidArgsFunction = completeNames(1, agentCompletions)
+	agentSwitchCmd.ValidArgsFunction = completeNames(1, agentCompletions)
+	agentInfoCmd.ValidArgsFunction = completeNames(1, agentCompletions)
+	agentRenameCmd.ValidArgsFunction = completeNames(1, agentCompletions)
+
+	modelSetCmd.ValidArgsFunction = completeNames(1, modelCompletions)
+	modelInfoCmd.ValidArgsFunction = completeNames(1, modelCompletions)
+	modelRemoveCmd.ValidArgsFunction = completeNames(1, modelCompletions)
+	modelSwitchCmd.ValidArgsFunction = completeNames(1, modelCompletions)
+	modelRenameCmd.ValidArgsFunction = completeNames(1, modelCompletions)
+
+	workflowRemoveCmd.ValidArgsFunction = completeNames(1, workflowCompletions)
+	workflowRenameCmd.ValidArgsFunction = completeNames(1, workflowCompletions)
+	workflowInfoCmd.ValidArgsFunction = completeNames(1, workflowCompletions)
+	workflowSetCmd.ValidArgsFunction = completeNames(1, workflowCompletions)
+
+	mcpSwitchCmd.ValidArgsFunction = completeNames(0, mcpServerCompletions)
+
+	sessionRemoveCmd.ValidArgsFunction = completeNames(0, sessionCompletions)
+	sessionInfoCmd.ValidArgsFunction = completeNames(1, sessionCompletions)
+	sessionRenameCmd.ValidArgsFunction = completeNames(1, sessionCompletions)
+	sessionShareCmd.ValidArgsFunction = completeNames(1, sessionCompletions)
+	sessionForkCmd.ValidArgsFunction = completeNames(1, sessionCompletions)
+
+	for _, c := range []*cobra.Command{scheduleAddCmd, scheduleRemoveCmd, schedulePauseCmd, scheduleResumeCmd, scheduleRunCmd} {
+		c.ValidArgsFunction = completeNames(1, scheduleCompletions)
+	}
+
+	for _, c := range []*cobra.Command{rootCmd, replCmd, watchCmd} {
+		c.RegisterFlagCompletionFunc("agent", completeNames(0, agentCompletions))
+		c.RegisterFlagCompletionFunc("session", completeNames(0, sessionCompletions))
+	}
+	benchCmd.RegisterFlagCompletionFunc("models", completeNames(0, modelCompletions))
+	auditListCmd.RegisterFlagCompletionFunc("agent", completeNames(0, agentCompletions))
+	auditListCmd.RegisterFlagCompletionFunc("session", completeNames(0, sessionCompletions))
+	scheduleAddCmd.RegisterFlagCompletionFunc("agent", completeNames(0, agentCompletions))
+	hookInstallCmd.RegisterFlagCompletionFunc("agent", completeNames(0, agentCompletions))
+	hookRunCmd.RegisterFlagCompletionFunc("agent", completeNames(0, agentCompletions))
+	scheduleAddCmd.RegisterFlagCompletionFunc("session", completeNames(0, sessionCompletions))
+}
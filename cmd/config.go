@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/activebook/gllm/util"
 
 	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
 	"github.com/spf13/cobra"
 )
 
@@ -165,6 +169,96 @@ var configPrintCmd = &cobra.Command{
 	},
 }
 
+// configKey is a setting that 'config set' changes.
+type configKey struct {
+	description string
+	set         func(value string) (string, error) // Stores the value and returns how it reads back
+}
+
+// configKeys are the settings 'config set' knows, by key.
+var configKeys = map[string]configKey{
+	"notify.threshold":        {"Notify the desktop when a generation runs longer, e.g. 30s (0 or off disables)", setNotifyThreshold},
+	"tickets.provider":        {"Issue tracker of the ticket_* tools, jira or linear", setTicketProvider},
+	"tickets.jira.url":        {"Base URL of the Jira site, e.g. https://acme.atlassian.net", setTicketSetting(func(t *data.TicketSettings) *string { return &t.JiraURL })},
+	"tickets.jira.email":      {"Account of the Jira Cloud API token, empty for a Jira Server access token", setTicketSetting(func(t *data.TicketSettings) *string { return &t.JiraEmail })},
+	"tickets.jira.project":    {"Default Jira project key", setTicketSetting(func(t *data.TicketSettings) *string { return &t.JiraProject })},
+	"tickets.linear.url":      {"Linear GraphQL endpoint, empty uses api.linear.app", setTicketSetting(func(t *data.TicketSettings) *string { return &t.LinearURL })},
+	"tickets.linear.team":     {"Default Linear team key", setTicketSetting(func(t *data.TicketSettings) *string { return &t.LinearTeam })},
+	"mail.host":               {"SMTP server of 'gllm mail' and email sinks, none sends through sendmail", setMailSetting(func(m *data.MailSettings) *string { return &m.Host })},
+	"mail.port":               {"SMTP port, 587 with STARTTLS by default, 465 for implicit TLS", setMailPort},
+	"mail.username":           {"SMTP account, the password is the smtp_password secret", setMailSetting(func(m *data.MailSettings) *string { return &m.Username })},
+	"mail.from":               {"Sender address, defaults to the SMTP account", setMailSetting(func(m *data.MailSettings) *string { return &m.From })},
+	"mail.sendmail":           {"Path of sendmail, used when no SMTP server is set", setMailSetting(func(m *data.MailSettings) *string { return &m.Sendmail })},
+	"mail.subject":            {"Subject template, e.g. '{{.Title}} - {{.Date}}'", setMailSubject},
+	"cache.enabled":           {"Answer identical model requests from the response cache, on or off", setCacheEnabled},
+	"cache.ttl":               {"How long cached responses are reused, e.g. 24h", setCacheTTL},
+	"cache.max_size":          {"Megabytes of responses the cache keeps, 200 by default", setCacheMaxSize},
+	"ui.theme":                {"Markdown and code theme: auto, dark, light, dracula, solarized, ... or a chroma style", setMarkdownTheme},
+	"safety.retry":            {"Retry a request blocked by the safety filters once with a sanitized prompt, on or off", setSafetyRetry},
+	"council.judge":           {"Model comparing the answers of 'gllm ask --models', or off", setCouncilJudge},
+	"embedding.model":         {"Embedding model of skill matching and session recall, or off to match words", setEmbeddingModel},
+	"http.proxy":              {"Proxy of the models without their own, none uses the environment", setHTTPProxy},
+	"http.connect_timeout":    {"Timeout to connect to a model provider, 30s by default", setHTTPDuration(func(h *data.HTTPSettings) *string { return &h.ConnectTimeout })},
+	"http.idle_timeout":       {"How long idle connections are kept for the next request, 90s by default", setHTTPDuration(func(h *data.HTTPSettings) *string { return &h.IdleTimeout })},
+	"http.response_timeout":   {"Timeout to wait for a provider to start answering, or off", setHTTPDuration(func(h *data.HTTPSettings) *string { return &h.ResponseTimeout })},
+	"http.max_conns_per_host": {"Idle connections kept per provider host, 16 by default", setHTTPMaxConns},
+	"http.http2":              {"Use HTTP/2 with the providers that support it, on or off", setHTTP2},
+	"tools.hard_delete":       {"Delete files for good instead of moving them to the trash, on or off", setHardDelete},
+}
+
+// configSetCmd sets a single setting by key
+var configSetCmd = &cobra.Command{
+	Use:   "set [KEY VALUE]",
+	Short: "Set a setting by key",
+	Long:  `Set a setting by key, run without arguments to list the keys.`,
+	Example: `  gllm config set notify.threshold 30s
+  gllm config set notify.threshold off`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return fmt.Errorf("expected a key and a value, got %d arguments", len(args))
+		}
+		return nil
+	},
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var keys []string
+		for key, k := range configKeys {
+			keys = append(keys, key+"\t"+k.description)
+		}
+		sort.Strings(keys)
+		return keys, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			keys := make([]string, 0, len(configKeys))
+			for key := range configKeys {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			for _, key := range keys {
+				fmt.Fprintf(w, "%s\t%s\n", key, configKeys[key].description)
+			}
+			w.Flush()
+			return
+		}
+
+		key, ok := configKeys[strings.ToLower(args[0])]
+		if !ok {
+			util.Errorf(cmd, "Unknown setting '%s', run 'gllm config set' to list the keys.\n", args[0])
+			return
+		}
+		value, err := key.set(args[1])
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "%s: %s%s%s\n", strings.ToLower(args[0]), data.KeyColor, value, data.ResetSeq)
+	},
+}
+
 func init() {
 	// Add configCmd to the root command
 	rootCmd.AddCommand(configCmd)
@@ -174,4 +268,158 @@ func init() {
 	configCmd.AddCommand(configPrintCmd)
 	configCmd.AddCommand(configExportCmd) // Register theconfig export command
 	configCmd.AddCommand(configImportCmd) // Register the config import command
+	configCmd.AddCommand(configSetCmd)
+}
+
+func setHardDelete(value string) (string, error) {
+	var hard bool
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "on", "true", "yes", "1":
+		hard = true
+	case "off", "false", "no", "0":
+	default:
+		return "", fmt.Errorf("expected on or off, got '%s'", value)
+	}
+	if err := data.GetSettingsStore().SetHardDelete(hard); err != nil {
+		return "", fmt.Errorf("failed to update settings: %w", err)
+	}
+	if hard {
+		return "on", nil
+	}
+	return "off", nil
+}
+
+func setSafetyRetry(value string) (string, error) {
+	var retry bool
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "on", "true", "yes", "1":
+		retry = true
+	case "off", "false", "no", "0":
+	default:
+		return "", fmt.Errorf("expected on or off, got '%s'", value)
+	}
+	store := data.GetSettingsStore()
+	safety := store.GetSafetySettings()
+	safety.Retry = retry
+	if err := store.SetSafetySettings(safety); err != nil {
+		return "", fmt.Errorf("failed to update settings: %w", err)
+	}
+	if retry {
+		return "on", nil
+	}
+	return "off", nil
+}
+
+func setCouncilJudge(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "off") || value == "" {
+		value = ""
+	} else if data.NewConfigStore().GetModel(value) == nil {
+		return "", fmt.Errorf("model '%s' not found", value)
+	}
+	if err := data.GetSettingsStore().SetCouncilJudge(value); err != nil {
+		return "", fmt.Errorf("failed to update settings: %w", err)
+	}
+	if value == "" {
+		return "off", nil
+	}
+	return value, nil
+}
+
+func setEmbeddingModel(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "off") || value == "" {
+		value = ""
+	} else if data.NewConfigStore().GetModel(value) == nil {
+		return "", fmt.Errorf("model '%s' not found", value)
+	}
+	if err := data.GetSettingsStore().SetEmbeddingModel(value); err != nil {
+		return "", fmt.Errorf("failed to update settings: %w", err)
+	}
+	if value == "" {
+		return "off", nil
+	}
+	return value, nil
+}
+
+func updateHTTPSettings(change func(*data.HTTPSettings)) error {
+	settings := data.GetSettingsStore()
+	http := settings.GetHTTPSettings()
+	change(&http)
+	if err := settings.SetHTTPSettings(http); err != nil {
+		return fmt.Errorf("failed to update settings: %w", err)
+	}
+	return nil
+}
+
+func setHTTPProxy(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "none") {
+		value = ""
+	}
+	if err := service.ValidateProxy(value); err != nil {
+		return "", err
+	}
+	if err := updateHTTPSettings(func(h *data.HTTPSettings) { h.Proxy = value }); err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "none", nil
+	}
+	return value, nil
+}
+
+// setHTTPDuration sets a timeout of the HTTP settings, off or default clears it.
+func setHTTPDuration(field func(*data.HTTPSettings) *string) func(value string) (string, error) {
+	return func(value string) (string, error) {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "off" || value == "default" || value == "0" {
+			value = ""
+		} else {
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return "", fmt.Errorf("invalid duration '%s', expected e.g. 30s or 2m", value)
+			}
+			value = d.String()
+		}
+		if err := updateHTTPSettings(func(h *data.HTTPSettings) { *field(h) = value }); err != nil {
+			return "", err
+		}
+		if value == "" {
+			return "default", nil
+		}
+		return value, nil
+	}
+}
+
+func setHTTPMaxConns(value string) (string, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("invalid number '%s', expected e.g. 16, 0 for the default", value)
+	}
+	if err := updateHTTPSettings(func(h *data.HTTPSettings) { h.MaxConnsPerHost = n }); err != nil {
+		return "", err
+	}
+	if n == 0 {
+		return fmt.Sprintf("default (%d)", service.DefaultHTTPMaxConnsPerHost), nil
+	}
+	return strconv.Itoa(n), nil
+}
+
+func setHTTP2(value string) (string, error) {
+	var enabled bool
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "on", "true", "yes", "1":
+		enabled = true
+	case "off", "false", "no", "0":
+	default:
+		return "", fmt.Errorf("expected on or off, got '%s'", value)
+	}
+	if err := updateHTTPSettings(func(h *data.HTTPSettings) { h.DisableHTTP2 = !enabled }); err != nil {
+		return "", err
+	}
+	if enabled {
+		return "on", nil
+	}
+	return "off", nil
 }
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configCmd.AddCommand(contextCmd)
+	contextCmd.AddCommand(contextShowCmd)
+	contextCmd.AddCommand(contextSwitchCmd)
+}
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage automatic instruction file loading (GLLM.md / AGENTS.md)",
+	Long: `Display or manage the instruction files injected into the system prompt.
+On every turn gllm loads the global GLLM.md from the config directory and the
+nearest GLLM.md or AGENTS.md from the current directory up to the repository root.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Print Long description
+		util.Println(cmd, cmd.Long)
+		util.Println(cmd)
+		util.Print(cmd, renderContextStatus(data.GetSettingsStore().GetContextFilesEnabled()))
+	},
+}
+
+var contextShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the instruction files that are loaded",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		enabled := data.GetSettingsStore().GetContextFilesEnabled()
+		util.Print(cmd, renderContextStatus(enabled))
+
+		files := data.DiscoverInstructionFiles()
+		if len(files) == 0 {
+			util.Println(cmd, "No instruction files found.")
+			return
+		}
+		if !enabled {
+			util.Println(cmd, "The following files were found but are not loaded:")
+		}
+		for _, file := range files {
+			lines := strings.Count(file.Content, "\n") + 1
+			util.Printf(cmd, "\n%s[%s]%s %s%s%s (%d lines)\n", data.SectionColor, file.Scope, data.ResetSeq, data.DirectoryColor, file.Path, data.ResetSeq, lines)
+			util.Println(cmd, file.Content)
+		}
+	},
+}
+
+var contextSwitchCmd = &cobra.Command{
+	Use:     "switch [true|false]",
+	Aliases: []string{"sw"},
+	Short:   "Toggle or set automatic instruction file loading",
+	Args:    cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return []string{"true", "false", "on", "off", "enable", "disable"}, cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		settings := data.GetSettingsStore()
+		current := settings.GetContextFilesEnabled()
+
+		var enable bool
+		if len(args) == 0 {
+			// Toggle behavior
+			enable = !current
+		} else {
+			arg := args[0]
+			switch arg {
+			case "true", "on", "enable":
+				enable = true
+			case "false", "off", "disable":
+				enable = false
+			default:
+				util.Printf(cmd, "%sInvalid argument: %s. Use 'true' or 'false'.%s\n", data.StatusErrorColor, arg, data.ResetSeq)
+				return
+			}
+		}
+
+		if err := settings.SetContextFilesEnabled(enable); err != nil {
+			util.Printf(cmd, "%sFailed to update settings: %v%s\n", data.StatusErrorColor, err, data.ResetSeq)
+			return
+		}
+
+		util.Print(cmd, renderContextStatus(enable))
+	},
+}
+
+func renderContextStatus(enabled bool) string {
+	status := data.SwitchOffColor + "false" + data.ResetSeq
+	if enabled {
+		status = data.SwitchOnColor + "true" + data.ResetSeq
+	}
+	return fmt.Sprintf("Instruction files auto-load: %s\n", status)
+}
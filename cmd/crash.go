@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+const issuesURL = "https://github.com/activebook/gllm/issues"
+
+func init() {
+	rootCmd.AddCommand(crashCmd)
+	crashCmd.AddCommand(crashReportCmd)
+	crashCmd.AddCommand(crashListCmd)
+	crashCmd.AddCommand(crashClearCmd)
+}
+
+var crashCmd = &cobra.Command{
+	Use:   "crash",
+	Short: "Show the reports of gllm crashes",
+	Long: `When gllm crashes it restores the terminal and saves a report: the stack, the latest
+log lines and a summary of the config without keys or prompts. Nothing is sent
+anywhere, 'gllm crash report' prints the latest report to attach to an issue at
+` + issuesURL + `.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		crashListCmd.Run(cmd, args)
+	},
+}
+
+var crashReportCmd = &cobra.Command{
+	Use:   "report [ID]",
+	Short: "Print a crash report, the latest one by default",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := ""
+		if len(args) > 0 {
+			id = args[0]
+		}
+		_, content, err := data.ReadCrashReport(id)
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Print(cmd, content)
+	},
+}
+
+var crashListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the saved crash reports",
+	Run: func(cmd *cobra.Command, args []string) {
+		reports, err := data.ListCrashReports()
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if len(reports) == 0 {
+			util.Println(cmd, "No crash reports.")
+			return
+		}
+		for _, r := range reports {
+			util.Printf(cmd, "%s%s%s  %s\n", data.KeyColor, r.ID, data.ResetSeq, r.Time.Format(time.DateTime))
+		}
+	},
+}
+
+var crashClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove every crash report",
+	Run: func(cmd *cobra.Command, args []string) {
+		n, err := data.ClearCrashReports()
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "Removed %d crash reports.\n", n)
+	},
+}
+
+// terminalState is the state of the terminal before gllm touched it, restored after a crash.
+var terminalState *term.State
+
+// saveTerminalState keeps the state of the terminal for recoverCrash.
+func saveTerminalState() {
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		terminalState, _ = term.GetState(fd)
+	}
+}
+
+// restoreTerminal puts back the echo and the cooked mode, and undoes what the full-screen
+// UIs may have left on: the alternate screen, mouse reporting, bracketed paste and the
+// hidden cursor.
+func restoreTerminal() {
+	if terminalState != nil {
+		term.Restore(int(os.Stdin.Fd()), terminalState)
+	}
+	if term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Fprint(os.Stdout, "\033[?1000l\033[?1002l\033[?1003l\033[?1006l\033[?2004l\033[?1049l\033[?25h\r\n")
+	}
+}
+
+// recoverCrash is deferred by Execute: a panic of the command restores the terminal, saves
+// a crash report and exits.
+func recoverCrash() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stack := debug.Stack()
+	restoreTerminal()
+
+	command := "gllm"
+	if c, _, err := rootCmd.Find(os.Args[1:]); err == nil && c != nil {
+		command = c.CommandPath()
+	}
+	report := service.BuildCrashReport(service.CrashInfo{Value: r, Stack: stack, Version: version, Command: command})
+	path, err := data.SaveCrashReport(report)
+
+	fmt.Fprintf(os.Stderr, "gllm crashed: %v\n", r)
+	if err != nil {
+		// Without a report, the stack is all there is to go on
+		fmt.Fprintf(os.Stderr, "The crash report could not be saved (%v):\n\n%s\n", err, report)
+	} else {
+		fmt.Fprintf(os.Stderr, "A crash report was saved to %s\n", path)
+		fmt.Fprintf(os.Stderr, "Please run 'gllm crash report' and attach it to an issue at %s\n", issuesURL)
+	}
+	os.Exit(2)
+}
+
+// isCrashCommand reports whether cmd reads the crash reports, which must work without a config.
+func isCrashCommand(cmd *cobra.Command) bool {
+	return strings.HasPrefix(cmd.CommandPath(), crashCmd.CommandPath())
+}
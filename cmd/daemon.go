@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/io"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+const (
+	daemonStartTimeout = 5 * time.Second
+	daemonStopTimeout  = 10 * time.Second
+)
+
+func init() {
+	daemonCmd.AddCommand(daemonRunCmd)
+	daemonCmd.AddCommand(daemonStartCmd)
+	daemonCmd.AddCommand(daemonStopCmd)
+	daemonCmd.AddCommand(daemonStatusCmd)
+	rootCmd.AddCommand(daemonCmd)
+}
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep sessions alive in a background daemon",
+	Long: `The daemon keeps named sessions running in the background, with their MCP
+connections and language servers warm, so that terminals attach to them with
+'gllm attach SESSION' instead of starting gllm again. Several terminals can
+attach to the same session: every one sees the prompts and answers of the others.
+
+The daemon listens on a socket in the config directory. It serves the same
+/v1/chat/completions and /v1/interact endpoints as 'gllm serve', plus the
+sessions endpoints used by attach.`,
+	Example: `  gllm daemon start
+  gllm attach my-session
+  gllm daemon status
+  gllm daemon stop`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var daemonRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Run the daemon in the foreground",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runDaemon()
+	},
+}
+
+var daemonStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the daemon in the background",
+	Run: func(cmd *cobra.Command, args []string) {
+		status, err := startDaemon()
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Successf(cmd, "Daemon is running, pid %d.\n", status.PID)
+	},
+}
+
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the daemon and the sessions it keeps",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := newDaemonClient().Shutdown(); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Successln(cmd, "Daemon stopped.")
+	},
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the daemon and its sessions",
+	Run: func(cmd *cobra.Command, args []string) {
+		status, err := newDaemonClient().Status()
+		if err != nil {
+			util.Println(cmd, "Daemon is not running.")
+			return
+		}
+		util.Print(cmd, renderDaemonStatus(status))
+	},
+}
+
+func newDaemonClient() *service.DaemonClient {
+	return service.NewDaemonClient(data.GetDaemonSocketPath())
+}
+
+// runDaemon serves the sessions on the daemon socket until it is stopped.
+func runDaemon() error {
+	socketPath := data.GetDaemonSocketPath()
+	if _, err := newDaemonClient().Status(); err == nil {
+		return fmt.Errorf("daemon is already running")
+	}
+	// Only the user may reach the socket, from the moment it exists: the agents it drives run
+	// the shell tools as the user
+	dir := filepath.Dir(socketPath)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return err
+	}
+	// A socket left by a daemon that crashed
+	os.Remove(socketPath)
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	defer os.Remove(socketPath)
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		ln.Close()
+		return err
+	}
+
+	hub := service.NewDaemonHub(runDaemonPrompt)
+	mux := http.NewServeMux()
+	hub.Register(mux)
+	mux.HandleFunc("/v1/chat/completions", chatCompletionHandler)
+	mux.HandleFunc("/v1/interact", interactHandler)
+	server := &http.Server{Handler: mux}
+
+	stop := make(chan struct{})
+	var once sync.Once
+	hub.OnShutdown = func() { once.Do(func() { close(stop) }) }
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			hub.OnShutdown()
+		case <-stop:
+		}
+	}()
+
+	warmMCPServers()
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.Serve(ln) }()
+	util.LogInfof("gllm daemon listening on %s (pid %d)\n", socketPath, os.Getpid())
+
+	select {
+	case <-stop:
+	case err := <-serveErr:
+		return err
+	}
+
+	// A run waiting on a confirmation does not see the cancellation, don't wait for it forever
+	closed := make(chan struct{})
+	go func() {
+		hub.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(daemonStopTimeout):
+		util.LogWarnf("Sessions did not stop in %s\n", daemonStopTimeout)
+	}
+	server.Close()
+	util.LogInfof("gllm daemon stopped\n")
+	return nil
+}
+
+// warmMCPServers connects the MCP servers of the active agent in the background, so that
+// the sessions of the daemon share live connections instead of connecting on first use.
+func warmMCPServers() {
+	mc := service.GetMCPClient()
+	mc.Reconnectable = true
+	go func() {
+		agent, err := EnsureActiveAgent()
+		if err != nil || !service.IsMCPServersEnabled(agent.Capabilities) {
+			return
+		}
+		mcpConfig, err := data.NewMCPStore().Load()
+		if err != nil {
+			return
+		}
+		if err := mc.Init(mcpConfig, service.MCPLoadOption{LoadTools: true}); err != nil {
+			util.LogWarnf("MCP servers unavailable: %v\n", err)
+		}
+	}()
+}
+
+// runDaemonPrompt runs a prompt of a daemon session, as gllm serve runs a completion.
+func runDaemonPrompt(ctx context.Context, sessionName, prompt string, out *io.SSEOutput) {
+	agent, err := EnsureActiveAgent()
+	if err != nil {
+		out.WriteErrorEvent(err.Error(), "agent_error")
+		return
+	}
+
+	var guideline string
+	if strings.HasPrefix(prompt, "/") {
+		var handled bool
+		handled, prompt, guideline = handleWebCommand(prompt, sessionName, out)
+		if handled {
+			return
+		}
+	}
+
+	if err := runAgentWithSSE(prompt, guideline, sessionName, out, agent, ctx); err != nil {
+		util.LogErrorf("Daemon agent error: %v\n", err)
+		out.WriteErrorEvent(err.Error(), "agent_error")
+	}
+}
+
+// startDaemon starts the daemon in the background, unless it runs already, and waits for it
+// to answer.
+func startDaemon() (*service.DaemonStatus, error) {
+	client := newDaemonClient()
+	if status, err := client.Status(); err == nil {
+		return status, nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+	logPath := data.GetDaemonLogPath()
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return nil, err
+	}
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	defer logFile.Close()
+
+	daemon := exec.Command(exe, "daemon", "run")
+	daemon.Stdout = logFile
+	daemon.Stderr = logFile
+	service.DetachProcess(daemon)
+	if err := daemon.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start the daemon: %w", err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- daemon.Wait() }()
+
+	deadline := time.After(daemonStartTimeout)
+	for {
+		if status, err := client.Status(); err == nil {
+			return status, nil
+		}
+		select {
+		case <-exited:
+			return nil, fmt.Errorf("the daemon exited, see %s", logPath)
+		case <-deadline:
+			return nil, fmt.Errorf("the daemon did not start in %s, see %s", daemonStartTimeout, logPath)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+func renderDaemonStatus(status *service.DaemonStatus) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%sDaemon%s pid %d, up %s\n", data.KeyColor, data.ResetSeq, status.PID,
+		time.Since(status.Started).Round(time.Second)))
+	if len(status.Sessions) == 0 {
+		sb.WriteString("  No sessions.\n")
+		return sb.String()
+	}
+	for _, s := range status.Sessions {
+		state := "idle"
+		if s.Busy {
+			state = "busy"
+		}
+		if s.Queued > 0 {
+			state += fmt.Sprintf(", %d queued", s.Queued)
+		}
+		sb.WriteString(fmt.Sprintf("  %s%s%s  %s, %d attached, last used %s\n", data.KeyColor, s.Name, data.ResetSeq,
+			state, s.Clients, s.LastUsed.Format("2006-01-02 15:04")))
+	}
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/internal/ui"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+var doctorOffline bool
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorOffline, "offline", false, "Check the configuration without probing the providers")
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the configuration and reach every provider",
+	Long: `Validates the whole configuration: models have an endpoint and a key, agents use
+models that exist and valid settings, the search engine of agents searching the web
+is configured, MCP servers can be started, and workflows can be run and post where
+they notify. Each provider is then sent a cheap request listing its models, skip it
+with --offline.
+
+Every problem comes with what to do about it. The command exits with an error when
+a problem breaks the runs that meet it.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts := service.DoctorOptions{Probe: !doctorOffline, ReservedCommands: replCommandMap}
+		if opts.Probe {
+			ui.GetIndicator().Start(ui.IndicatorProbingProviders)
+		}
+		findings := service.RunDoctor(cmd.Context(), opts)
+		if opts.Probe {
+			ui.GetIndicator().Stop()
+		}
+
+		fails, warns := 0, 0
+		area := ""
+		for _, f := range findings {
+			if f.Area != area {
+				area = f.Area
+				util.Printf(cmd, "\n%s%s%s\n", data.SectionColor, area, data.ResetSeq)
+			}
+			mark, color := "✓", data.StatusSuccessColor
+			switch f.Status {
+			case service.DoctorWarn:
+				mark, color = "!", data.StatusWarnColor
+				warns++
+			case service.DoctorFail:
+				mark, color = "✗", data.StatusErrorColor
+				fails++
+			}
+			subject := ""
+			if f.Subject != "" {
+				subject = data.KeyColor + f.Subject + data.ResetSeq + ": "
+			}
+			util.Printf(cmd, "  %s%s%s %s%s\n", color, mark, data.ResetSeq, subject, f.Message)
+			if f.Fix != "" {
+				util.Printf(cmd, "    %s→ %s%s\n", data.DetailColor, f.Fix, data.ResetSeq)
+			}
+		}
+
+		util.Println(cmd)
+		switch {
+		case fails > 0:
+			cmd.SilenceUsage = true // The findings are the report, not the usage
+			return fmt.Errorf("%s, %s", plural(fails, "problem"), plural(warns, "warning"))
+		case warns > 0:
+			util.Printf(cmd, "No problems, %s.\n", plural(warns, "warning"))
+		default:
+			util.Println(cmd, "No problems found.")
+		}
+		return nil
+	},
+}
+
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
@@ -0,0 +1,313 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Exit codes of 'gllm hook run': git blocks on anything but 0, the installed hooks let
+// hookExitError through so a missing model or network never blocks a commit.
+const (
+	hookExitPass  = 0
+	hookExitFail  = 1
+	hookExitError = 2
+)
+
+func init() {
+	rootCmd.AddCommand(hookCmd)
+	hookCmd.AddCommand(hookInstallCmd)
+	hookCmd.AddCommand(hookUninstallCmd)
+	hookCmd.AddCommand(hookRunCmd)
+	hookCmd.AddCommand(hookScriptsCmd)
+
+	hookInstallCmd.Flags().String("agent", "", "Agent whose model the hook uses (empty for the active agent)")
+	hookInstallCmd.Flags().Bool("force", false, "Replace an existing hook that gllm did not install")
+	hookRunCmd.Flags().String("agent", "", "Agent whose model the hook uses (empty for the active agent)")
+}
+
+var hookCmd = &cobra.Command{
+	Use:   "hook",
+	Short: "Manage git hooks and the Starlark hook scripts",
+	Long: `Manage git hooks calling gllm.
+
+  commit-msg  Writes the commit message from the staged diff when it is left empty,
+              otherwise fails messages that do not describe the change
+  pre-push    Fails pushes whose diff has leaked secrets, debugging leftovers,
+              conflict markers or obvious bugs
+
+'gllm hook run' exits with 0 when the check passes, 1 when it fails and 2 when it could
+not run. The installed hooks let commits and pushes through on 2.
+Skip them once with 'git commit --no-verify' or 'git push --no-verify'.
+
+Hook scripts customize gllm itself. They are Starlark files (*.star) in the hooks
+directory, run in the order of their names, that define any of:
+
+  on_prompt(prompt, agent)         Return the prompt to send, None keeps it; fail() refuses it
+  on_tool_call(tool, args, agent)  Return a reason to block the call, None lets it run
+  on_response(text, agent)         Return text shown after the response, such as a footer
+  on_session_end(session)          Run when a one-shot run or the REPL ends
+
+Besides the Starlark built-ins, scripts can use json.encode/json.decode and url_host(url).
+See 'gllm hook scripts'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Print Long description
+		util.Println(cmd, cmd.Long)
+	},
+}
+
+var hookInstallCmd = &cobra.Command{
+	Use:   "install HOOK",
+	Short: "Install a git hook in the current repository",
+	Example: `  gllm hook install commit-msg --agent committer
+  gllm hook install pre-push`,
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: service.SupportedGitHooks,
+	Run: func(cmd *cobra.Command, args []string) {
+		hook := args[0]
+		if !service.IsSupportedGitHook(hook) {
+			util.Errorf(cmd, "unsupported hook '%s', supported: %s\n", hook, strings.Join(service.SupportedGitHooks, ", "))
+			return
+		}
+		agent, _ := cmd.Flags().GetString("agent")
+		if agent != "" && data.NewConfigStore().GetAgent(agent) == nil {
+			util.Errorf(cmd, "agent %s does not exist\n", agent)
+			return
+		}
+		path, err := gitHookPath(hook)
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		force, _ := cmd.Flags().GetBool("force")
+		if existing, err := os.ReadFile(path); err == nil && !service.IsGllmGitHook(string(existing)) && !force {
+			util.Errorf(cmd, "%s already exists and was not installed by gllm, use --force to replace it\n", path)
+			return
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			util.Errorf(cmd, "failed to locate gllm: %v\n", err)
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if err := os.WriteFile(path, []byte(service.GitHookScript(exe, hook, agent)), 0755); err != nil {
+			util.Errorf(cmd, "failed to write hook: %v\n", err)
+			return
+		}
+		util.Printf(cmd, "Installed %s hook at %s\n", hook, path)
+	},
+}
+
+var hookUninstallCmd = &cobra.Command{
+	Use:       "uninstall HOOK",
+	Short:     "Remove a git hook gllm installed",
+	Args:      cobra.ExactArgs(1),
+	ValidArgs: service.SupportedGitHooks,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := gitHookPath(args[0])
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		existing, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			util.Errorf(cmd, "no %s hook is installed\n", args[0])
+			return
+		}
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if !service.IsGllmGitHook(string(existing)) {
+			util.Errorf(cmd, "%s was not installed by gllm, leaving it\n", path)
+			return
+		}
+		if err := os.Remove(path); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "Removed %s hook\n", args[0])
+	},
+}
+
+var hookRunCmd = &cobra.Command{
+	Use:   "run HOOK [ARGS...]",
+	Short: "Run a hook check, as git does",
+	Long: `Run a hook check with the arguments git passes to the hook.
+
+  commit-msg [FILE]  Fill an empty message in FILE or check it; without FILE, print
+                     a message for the staged diff
+  pre-push           Review the commits of the ref lines on stdin; without any,
+                     review the commits of the current branch no remote has
+
+Exits with 0 when the check passes, 1 when it fails and 2 when it could not run.`,
+	Args:      cobra.MinimumNArgs(1),
+	ValidArgs: service.SupportedGitHooks,
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("agent")
+		var code int
+		switch args[0] {
+		case service.GitHookCommitMsg:
+			code = runCommitMsgHook(cmd, name, args[1:])
+		case service.GitHookPrePush:
+			code = runPrePushHook(cmd, name)
+		default:
+			util.Errorf(cmd, "unsupported hook '%s', supported: %s\n", args[0], strings.Join(service.SupportedGitHooks, ", "))
+			code = hookExitError
+		}
+		os.Exit(code)
+	},
+}
+
+var hookScriptsCmd = &cobra.Command{
+	Use:   "scripts",
+	Short: "List the hook scripts and the hooks they define",
+	Example: `  # ~/.config/gllm/hooks/guard.star
+  def on_tool_call(tool, args, agent):
+      if tool == "web_fetch" and url_host(args.get("url", "")).endswith("internal.example.com"):
+          return "internal hosts are off limits"`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir := data.GetHooksDirPath()
+		hooks := service.LoadScriptHooks(dir)
+		util.Printf(cmd, "Directory: %s\n\n", dir)
+		if len(hooks.Scripts) == 0 && len(hooks.Errors) == 0 {
+			util.Println(cmd, "No hook scripts.")
+			return
+		}
+		for _, script := range hooks.Scripts {
+			defined := strings.Join(script.Hooks, ", ")
+			if defined == "" {
+				defined = "no hooks"
+			}
+			util.Printf(cmd, "%s%s%s: %s\n", data.KeyColor, script.Name, data.ResetSeq, defined)
+		}
+		for _, err := range hooks.Errors {
+			util.Errorf(cmd, "%v\n", err)
+		}
+	},
+}
+
+// gitHookPath returns where the hook lives in the repository of the working directory.
+func gitHookPath(hook string) (string, error) {
+	if !service.IsSupportedGitHook(hook) {
+		return "", fmt.Errorf("unsupported hook '%s', supported: %s", hook, strings.Join(service.SupportedGitHooks, ", "))
+	}
+	dir, err := service.GitHooksDir(context.Background(), ".")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, hook), nil
+}
+
+func hookAgent(name string) (*data.AgentConfig, error) {
+	if name == "" {
+		return EnsureActiveAgent()
+	}
+	return EnsureAgent(name)
+}
+
+func runCommitMsgHook(cmd *cobra.Command, agentName string, args []string) int {
+	ctx := context.Background()
+	diff, err := service.StagedDiff(ctx, ".")
+	if err != nil {
+		util.Errorf(cmd, "%v\n", err)
+		return hookExitError
+	}
+	if diff == "" {
+		// Nothing staged, such as an amended message, leave it to git
+		return hookExitPass
+	}
+	agent, err := hookAgent(agentName)
+	if err != nil {
+		util.Errorf(cmd, "%v\n", err)
+		return hookExitError
+	}
+
+	var content []byte
+	if len(args) > 0 {
+		if content, err = os.ReadFile(args[0]); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return hookExitError
+		}
+	}
+	message := service.CleanCommitMessage(string(content))
+
+	if message == "" {
+		generated, err := service.GenerateCommitMessage(agent, diff)
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return hookExitError
+		}
+		if len(args) == 0 {
+			util.Println(cmd, generated)
+			return hookExitPass
+		}
+		// Keep git's comments below the message, git strips them
+		if err := os.WriteFile(args[0], []byte(generated+"\n\n"+string(content)), 0644); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return hookExitError
+		}
+		util.Printf(cmd, "gllm wrote the commit message:\n\n%s\n\n", generated)
+		return hookExitPass
+	}
+
+	verdict, err := service.ReviewCommitMessage(agent, message, diff)
+	if err != nil {
+		util.Errorf(cmd, "%v\n", err)
+		return hookExitError
+	}
+	return reportHookVerdict(cmd, "commit message", verdict)
+}
+
+func runPrePushHook(cmd *cobra.Command, agentName string) int {
+	ctx := context.Background()
+	refs := cmd.InOrStdin()
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		// Run by hand: push the current branch to nowhere yet
+		refs = strings.NewReader("HEAD HEAD refs/heads/none " + strings.Repeat("0", 40) + "\n")
+	}
+	diff, err := service.PushDiff(ctx, ".", refs)
+	if err != nil {
+		util.Errorf(cmd, "%v\n", err)
+		return hookExitError
+	}
+	if diff == "" {
+		return hookExitPass
+	}
+	agent, err := hookAgent(agentName)
+	if err != nil {
+		util.Errorf(cmd, "%v\n", err)
+		return hookExitError
+	}
+	verdict, err := service.ReviewPush(agent, diff)
+	if err != nil {
+		util.Errorf(cmd, "%v\n", err)
+		return hookExitError
+	}
+	return reportHookVerdict(cmd, "push", verdict)
+}
+
+func reportHookVerdict(cmd *cobra.Command, what string, verdict service.HookVerdict) int {
+	if verdict.Pass {
+		util.Printf(cmd, "gllm: %s passed the review.\n", what)
+		return hookExitPass
+	}
+	util.Errorf(cmd, "gllm: %s failed the review.\n", what)
+	if verdict.Reason != "" {
+		util.Errorf(cmd, "%s\n", verdict.Reason)
+	}
+	util.Errorf(cmd, "Skip the check with --no-verify.\n")
+	return hookExitFail
+}
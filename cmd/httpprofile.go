@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"bufio"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/charmbracelet/huh"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// configHTTPCmd manages the auth profiles of the http_request tool
+var configHTTPCmd = &cobra.Command{
+	Use:     "http",
+	Aliases: []string{"http-profile", "http-profiles"},
+	Short:   "Manage the auth profiles of the http_request tool",
+	Long: `Manage the auth profiles the http_request tool sends requests with. A profile holds a
+credential for one base URL and is never sent anywhere else, not even when a response
+redirects. Credentials are kept with the secrets, the model only sees the profile name.
+
+Types of profiles:
+  bearer  Authorization: Bearer <secret>
+  basic   Authorization: Basic <username:secret>, needs --username
+  header  <Header>: <secret>, needs --header, e.g. X-API-Key`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configHTTPListCmd.Run(cmd, args)
+	},
+}
+
+var configHTTPListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the auth profiles",
+	Run: func(cmd *cobra.Command, args []string) {
+		profiles := data.GetSettingsStore().GetHTTPProfiles()
+		if len(profiles) == 0 {
+			util.Println(cmd, "No auth profiles configured.")
+			return
+		}
+		names := make([]string, 0, len(profiles))
+		for name := range profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		util.Println(cmd, "Auth profiles:")
+		for _, name := range names {
+			p := profiles[name]
+			detail := p.Type
+			switch p.Type {
+			case service.HTTPAuthBasic:
+				detail += ", user " + p.Username
+			case service.HTTPAuthHeader:
+				detail += " " + p.Header
+			}
+			util.Printf(cmd, "  %s%s%s  %s (%s)\n", data.KeyColor, name, data.ResetSeq, p.URL, detail)
+		}
+	},
+}
+
+var configHTTPAddCmd = &cobra.Command{
+	Use:   "add NAME [SECRET]",
+	Short: "Add an auth profile",
+	Long: `Add an auth profile, or replace the one of the same name. Without a secret it is asked
+for, or read from stdin when it is not a terminal, so that tokens do not end up in the
+shell history.`,
+	Example: `  gllm config http add github --url https://api.github.com
+  gllm config http add jira --type basic --username me@example.com --url https://example.atlassian.net
+  echo "$API_KEY" | gllm config http add search --type header --header X-API-Key --url https://api.example.com/v1`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := strings.TrimSpace(args[0])
+		p := data.HTTPProfile{}
+		p.Type, _ = cmd.Flags().GetString("type")
+		p.URL, _ = cmd.Flags().GetString("url")
+		p.Username, _ = cmd.Flags().GetString("username")
+		p.Header, _ = cmd.Flags().GetString("header")
+		p.Type = strings.ToLower(strings.TrimSpace(p.Type))
+		p.URL = strings.TrimSpace(p.URL)
+		if err := service.ValidateHTTPProfile(p); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+
+		var secret string
+		switch {
+		case len(args) == 2:
+			secret = args[1]
+		case term.IsTerminal(int(os.Stdin.Fd())):
+			err := huh.NewInput().
+				Title("Secret of " + name).
+				Value(&secret).
+				EchoMode(huh.EchoModePassword).
+				Run()
+			if err != nil {
+				util.Errorf(cmd, "%v\n", err)
+				return
+			}
+		default:
+			line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			secret = line
+		}
+		secret = strings.TrimSpace(secret)
+		if secret == "" {
+			util.Errorf(cmd, "The secret of %s is empty.\n", name)
+			return
+		}
+
+		if err := data.SetSecret(data.HTTPProfileSecretName(name), secret); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if err := data.GetSettingsStore().SetHTTPProfile(name, p); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "Auth profile %s%s%s (%s) added for %s.\n", data.KeyColor, name, data.ResetSeq, p.Type, p.URL)
+	},
+}
+
+var configHTTPRemoveCmd = &cobra.Command{
+	Use:     "remove NAME",
+	Aliases: []string{"rm", "delete"},
+	Short:   "Remove an auth profile",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		removed, err := data.GetSettingsStore().RemoveHTTPProfile(args[0])
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if !removed {
+			util.Errorf(cmd, "Auth profile %s not found.\n", args[0])
+			return
+		}
+		data.RemoveSecret(data.HTTPProfileSecretName(args[0]))
+		util.Printf(cmd, "Auth profile %s removed.\n", args[0])
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configHTTPCmd)
+	configHTTPCmd.AddCommand(configHTTPListCmd)
+	configHTTPCmd.AddCommand(configHTTPAddCmd)
+	configHTTPCmd.AddCommand(configHTTPRemoveCmd)
+
+	configHTTPAddCmd.Flags().String("type", service.HTTPAuthBearer, "Type of the profile: bearer, basic or header")
+	configHTTPAddCmd.Flags().String("url", "", "Base URL the credential is sent to")
+	configHTTPAddCmd.Flags().String("username", "", "Username of basic auth")
+	configHTTPAddCmd.Flags().String("header", "", "Header of header auth, e.g. X-API-Key")
+}
@@ -42,8 +42,8 @@ func buildToolsOptions() []huh.Option[string] {
 	toolsList := service.GetEmbeddingTools()
 	var options []huh.Option[string]
 	for _, tool := range toolsList {
-		// All tools selected by default for new agents
-		options = append(options, huh.NewOption(tool, tool).Selected(true))
+		// All tools but the optional ones selected by default for new agents
+		options = append(options, huh.NewOption(tool, tool).Selected(!service.IsOptionalTool(tool)))
 	}
 	return options
 }
@@ -181,22 +181,10 @@ func RunInitWizard(cmd *cobra.Command) error {
 		defaultEndpoint = "https://generativelanguage.googleapis.com"
 		defaultModelName = "gemini-flash-latest"
 		defaultProvider = service.ModelProviderGemini
-	case "xai":
-		defaultEndpoint = "https://api.x.ai/v1"
-		defaultModelName = "grok-4-1-fast"
-		defaultProvider = service.ModelProviderOpenAICompatible
 	case "groq":
 		defaultEndpoint = "https://api.groq.com/openai/v1"
 		defaultModelName = "openai/gpt-oss-120b"
 		defaultProvider = service.ModelProviderOpenAICompatible
-	case "mistral":
-		defaultEndpoint = "https://api.mistral.ai/v1"
-		defaultModelName = "mistral-large-latest"
-		defaultProvider = service.ModelProviderOpenAICompatible
-	case "deepseek":
-		defaultEndpoint = "https://api.deepseek.com"
-		defaultModelName = "deepseek-chat"
-		defaultProvider = service.ModelProviderOpenAICompatible
 	case "alibaba":
 		defaultEndpoint = "https://dashscope.aliyuncs.com/compatible-mode/v1"
 		defaultModelName = "qwen3-max"
@@ -233,6 +221,13 @@ func RunInitWizard(cmd *cobra.Command) error {
 		defaultEndpoint = "https://api.tokenfactory.nebius.com/v1"
 		defaultModelName = "openai/gpt-oss-120b"
 		defaultProvider = service.ModelProviderOpenAICompatible
+	default:
+		// APIs with known quirks: xai, mistral, deepseek
+		if preset := service.LookupProviderPreset(provider); preset != nil {
+			defaultEndpoint = preset.Endpoint
+			defaultModelName = preset.Model
+			defaultProvider = preset.Provider
+		}
 	}
 
 	// Pre-populate with defaults or existing values
@@ -273,6 +268,7 @@ func RunInitWizard(cmd *cobra.Command) error {
 			huh.NewOption("Enable Agent Memory", service.CapabilityAgentMemory).Selected(false),
 			huh.NewOption("Enable Sub Agents", service.CapabilitySubAgents).Selected(false),
 			huh.NewOption("Enable Web Search", service.CapabilityWebSearch).Selected(false),
+			huh.NewOption("Recall Related Sessions", service.CapabilitySessionRecall).Selected(false),
 		).Value(&selectedFeatures)
 	featureNote := ui.GetDynamicHuhNote("Feature Details", msfeatures, service.GetCapabilityDescHighlight)
 
@@ -506,8 +502,7 @@ func runInstructionWizard(cmd *cobra.Command, agentConfig *data.AgentConfig) err
 	// Render the generated Markdown so the user can review it before committing to save.
 	util.Println(cmd)
 	util.Println(cmd, strings.Repeat("─", 60))
-	glamourStyle := data.MostSimilarGlamourStyle()
-	if tr, err := glamour.NewTermRenderer(glamour.WithStandardStyle(glamourStyle)); err == nil {
+	if tr, err := glamour.NewTermRenderer(data.MarkdownStyleOption()); err == nil {
 		if rendered, err := tr.Render(content); err == nil {
 			util.Print(cmd, rendered)
 		} else {
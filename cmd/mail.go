@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(mailCmd)
+
+	mailCmd.Flags().String("to", "", "Recipients, comma separated")
+	mailCmd.Flags().String("subject", "", "Subject template, e.g. \"Digest {{.Date}}\" (empty for the configured one)")
+	mailCmd.Flags().String("title", "", "Title of the report for the subject (defaults to the file name)")
+	mailCmd.Flags().Bool("dry-run", false, "Print the email instead of sending it")
+}
+
+var mailCmd = &cobra.Command{
+	Use:   "mail [FILE]",
+	Short: "Email a markdown report",
+	Long: `Email a markdown file, or stdin, as a plain text and HTML message.
+Mail goes over SMTP when mail.host is set, otherwise through the local sendmail:
+
+  gllm config set mail.host smtp.example.com
+  gllm config set mail.username me@example.com
+  gllm config secret set smtp_password
+
+The subject is a template with {{.Title}}, {{.Heading}} (first heading of the report),
+{{.Date}} and {{.Time}}. Workflows and schedules mail their output with the sink
+email:address, e.g. 'notify: email:team@example.com' in a workflow.`,
+	Example: `  gllm "Summarize today's AI news" | gllm mail --to me@example.com --title "AI digest"
+  gllm mail report.md --to team@example.com --subject "Weekly report {{.Date}}"`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		toFlag, _ := cmd.Flags().GetString("to")
+		if toFlag == "" {
+			util.Errorf(cmd, "--to is required\n")
+			return
+		}
+		to, err := service.ParseMailAddresses(toFlag)
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+
+		title, _ := cmd.Flags().GetString("title")
+		var body string
+		if len(args) == 1 {
+			content, err := os.ReadFile(args[0])
+			if err != nil {
+				util.Errorf(cmd, "%v\n", err)
+				return
+			}
+			body = string(content)
+			if title == "" {
+				title = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+			}
+		} else {
+			body = readStdin()
+		}
+		if strings.TrimSpace(body) == "" {
+			util.Errorf(cmd, "Nothing to send, give a file or pipe the report to stdin.\n")
+			return
+		}
+		if title == "" {
+			title = "gllm"
+		}
+
+		settings := data.GetSettingsStore().GetMailSettings()
+		subjectTmpl := settings.Subject
+		if cmd.Flags().Changed("subject") {
+			subjectTmpl, _ = cmd.Flags().GetString("subject")
+		}
+		subject, err := service.RenderMailSubject(subjectTmpl, title, body, time.Now())
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		msg := service.MailMessage{To: to, Subject: subject, Body: body}
+
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			if msg.From = settings.From; msg.From == "" {
+				msg.From = settings.Username
+			}
+			raw, err := service.BuildMailMessage(msg, time.Now())
+			if err != nil {
+				util.Errorf(cmd, "%v\n", err)
+				return
+			}
+			util.Print(cmd, string(raw))
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+		defer cancel()
+		if err := service.SendMail(ctx, settings, msg); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "Mailed '%s' to %s.\n", subject, strings.Join(to, ", "))
+	},
+}
+
+// setMailSetting returns the 'config set' handler of a text setting of email, "none" clears it.
+func setMailSetting(field func(*data.MailSettings) *string) func(value string) (string, error) {
+	return func(value string) (string, error) {
+		value = strings.TrimSpace(value)
+		if strings.EqualFold(value, "none") {
+			value = ""
+		}
+		settings := data.GetSettingsStore()
+		mail := settings.GetMailSettings()
+		*field(&mail) = value
+		if err := settings.SetMailSettings(mail); err != nil {
+			return "", fmt.Errorf("failed to update settings: %w", err)
+		}
+		if value == "" {
+			return "none", nil
+		}
+		return value, nil
+	}
+}
+
+func setMailPort(value string) (string, error) {
+	port, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || port < 0 || port > 65535 {
+		return "", fmt.Errorf("invalid port '%s'", value)
+	}
+	settings := data.GetSettingsStore()
+	mail := settings.GetMailSettings()
+	mail.Port = port
+	if err := settings.SetMailSettings(mail); err != nil {
+		return "", fmt.Errorf("failed to update settings: %w", err)
+	}
+	if port == 0 {
+		return "default", nil
+	}
+	return strconv.Itoa(port), nil
+}
+
+func setMailSubject(value string) (string, error) {
+	if _, err := service.RenderMailSubject(value, "title", "", time.Now()); err != nil {
+		return "", err
+	}
+	return setMailSetting(func(m *data.MailSettings) *string { return &m.Subject })(value)
+}
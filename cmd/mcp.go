@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -282,10 +283,11 @@ var mcpPathCmd = &cobra.Command{
 
 // mcpSwitchCmd (formerly mcpSwitchCmd)
 var mcpSwitchCmd = &cobra.Command{
-	Use:     "switch",
+	Use:     "switch [NAME...]",
 	Aliases: []string{"sw", "sel", "select"},
 	Short:   "Toggle which MCP servers are allowed",
-	Long:    `Interactively select which MCP servers should be allowed. Use space to toggle, enter to confirm.`,
+	Long: `Interactively select which MCP servers should be allowed. Use space to toggle, enter to confirm.
+With server names, toggle those servers without prompting.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		store := data.NewMCPStore()
 		servers, err := store.Load()
@@ -319,19 +321,34 @@ var mcpSwitchCmd = &cobra.Command{
 			}
 		}
 
-		// Sort options by name alphabetically and keep selected ones at top
-		ui.SortMultiOptions(options, selected)
-		height := io.GetTermFitHeight(len(options))
-
-		err = huh.NewMultiSelect[string]().
-			Title("Select MCP servers to allow").
-			Description("Use space to toggle, enter to confirm.").
-			Height(height).
-			Options(options...).
-			Value(&selected).
-			Run()
-		if err != nil {
-			return // User cancelled
+		if len(args) > 0 {
+			// Toggle the named servers
+			for _, name := range args {
+				if _, ok := servers[name]; !ok {
+					util.Errorf(cmd, "MCP server '%s' not found\n", name)
+					return
+				}
+				if i := slices.Index(selected, name); i >= 0 {
+					selected = slices.Delete(selected, i, i+1)
+				} else {
+					selected = append(selected, name)
+				}
+			}
+		} else {
+			// Sort options by name alphabetically and keep selected ones at top
+			ui.SortMultiOptions(options, selected)
+			height := io.GetTermFitHeight(len(options))
+
+			err = huh.NewMultiSelect[string]().
+				Title("Select MCP servers to allow").
+				Description("Use space to toggle, enter to confirm.").
+				Height(height).
+				Options(options...).
+				Value(&selected).
+				Run()
+			if err != nil {
+				return // User cancelled
+			}
 		}
 
 		// Check if the selection actually changed
@@ -368,7 +385,7 @@ var mcpSwitchCmd = &cobra.Command{
 		// Run mcp list to show updated list
 		util.Printf(cmd, "%d MCP Server(s) enabled.\n", len(selected))
 		util.Println(cmd)
-		mcpListCmd.Run(mcpListCmd, args)
+		mcpListCmd.Run(mcpListCmd, nil)
 	},
 }
 
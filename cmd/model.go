@@ -3,6 +3,7 @@ package cmd
 
 import (
 	"fmt"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -36,6 +37,7 @@ func init() {
 	modelCmd.AddCommand(modelRemoveCmd)
 	modelCmd.AddCommand(modelClearCmd)
 	modelCmd.AddCommand(modelSwitchCmd)
+	modelCmd.AddCommand(modelDiscoverCmd)
 
 	// Add flags to the list command
 	modelListCmd.Flags().BoolP("verbose", "v", false, "Show model names and their content")
@@ -43,7 +45,7 @@ func init() {
 	// Add required flags to the add command
 	// e.g. ./gllm model add --name minimax2 --provider openai --endpoint "https://api.openai.com/v1" --key "bbcc" --model gpt-4o --temp 0.5 --top_p 0.8 --seed 0
 	modelAddCmd.Flags().StringP("name", "n", "", "Model name (required)")
-	modelAddCmd.Flags().StringP("provider", "p", "", "Model provider (required)")
+	modelAddCmd.Flags().StringP("provider", "p", "", "Model provider, or the preset xai, mistral or deepseek (required)")
 	modelAddCmd.Flags().StringP("endpoint", "e", "", "API endpoint URL (required)")
 	modelAddCmd.Flags().StringP("key", "k", "", "API key (required)")
 	modelAddCmd.Flags().StringP("model", "m", "", "Model ID (required)")
@@ -53,13 +55,31 @@ func init() {
 
 	// Add optional flags to the set command
 	// e.g. ./gllm model set minimax2 --provider openai --endpoint "https://api.openai.com/v1" --key "bbcc" --model gpt-5 --temp 0.75 --top_p 0.9 --seed 1010
-	modelSetCmd.Flags().StringP("provider", "p", "", "Model provider (required)")
+	modelSetCmd.Flags().StringP("provider", "p", "", "Model provider, or the preset xai, mistral or deepseek (required)")
 	modelSetCmd.Flags().StringP("endpoint", "e", "", "API endpoint URL")
 	modelSetCmd.Flags().StringP("key", "k", "", "API key")
 	modelSetCmd.Flags().StringP("model", "m", "", "Model ID")
 	modelSetCmd.Flags().Float32P("temp", "t", 1.0, "Temperature for generation")
 	modelSetCmd.Flags().Float32P("top_p", "o", 1.0, "Top-p sampling parameter")
 	modelSetCmd.Flags().IntP("seed", "s", 0, "Seed for deterministic generation (default 0, use 0 for random)")
+	modelSetCmd.Flags().Int("max_tokens", 0, "Max tokens to generate per response (0 for provider default)")
+	modelSetCmd.Flags().StringSlice("stop", nil, "Stop sequences, comma separated (empty to clear)")
+	modelSetCmd.Flags().Float32("frequency_penalty", 0, "Frequency penalty (-2.0 - 2.0)")
+	modelSetCmd.Flags().Float32("presence_penalty", 0, "Presence penalty (-2.0 - 2.0)")
+	modelSetCmd.Flags().String("proxy", "", "Proxy URL for this model, http(s):// or socks5:// (empty to use the environment)")
+	modelSetCmd.Flags().String("ca_cert", "", "PEM file with extra CA certificates to trust (empty to clear)")
+	modelSetCmd.Flags().Bool("insecure_skip_verify", false, "Skip TLS certificate verification (unsafe)")
+	modelSetCmd.Flags().StringArray("header", nil, "Extra HTTP header as KEY=VALUE, e.g. X-Title=gllm (repeatable, KEY= removes it, empty to clear)")
+	modelSetCmd.Flags().StringSlice("provider_order", nil, "OpenRouter: providers to try first, comma separated, e.g. anthropic,together (empty to clear)")
+	modelSetCmd.Flags().String("provider_sort", "", "OpenRouter: prefer the providers by price, throughput or latency (empty for the default)")
+	modelSetCmd.Flags().String("allow_fallbacks", "", "OpenRouter: let providers outside the order serve requests, on or off (empty for the default)")
+	modelSetCmd.Flags().StringSlice("fallback_models", nil, "OpenRouter: models tried in order when the model fails, comma separated (empty to clear)")
+	modelSetCmd.Flags().StringArray("query_param", nil, "Extra query parameter as KEY=VALUE (repeatable, KEY= removes it, empty to clear)")
+	modelSetCmd.Flags().Float32("input_price", 0, "Price of input tokens in USD per million, for cost estimates (0 if unknown)")
+	modelSetCmd.Flags().Float32("output_price", 0, "Price of output tokens in USD per million, for cost estimates (0 if unknown)")
+	modelSetCmd.Flags().Bool("code_execution", false, "Gemini, Anthropic: let the model run the code it writes on the provider's servers")
+	modelSetCmd.Flags().Bool("native_tools", false, "Anthropic: use Claude's bash and text editor tools in place of the shell and file tools")
+	modelSetCmd.Flags().StringSlice("safety", nil, "Gemini safety thresholds as CATEGORY=THRESHOLD, e.g. dangerous_content=only_high (empty to clear)")
 
 	// Add the force flag to the remove command
 	modelRemoveCmd.Flags().BoolP("force", "f", false, "Skip error when model doesn't exist")
@@ -205,12 +225,7 @@ Example:
 					huh.NewSelect[string]().
 						Title("Provider").
 						Description(providerSelectDesc).
-						Options(
-							huh.NewOption("OpenAI", service.ModelProviderOpenAI),
-							huh.NewOption("Anthropic", service.ModelProviderAnthropic),
-							huh.NewOption("Google Gemini", service.ModelProviderGemini),
-							huh.NewOption("Other (OpenAI Compatible)", service.ModelProviderOpenAICompatible),
-						).
+						Options(providerOptions()...).
 						Value(&provider),
 					huh.NewNote().
 						Description(providerSelectNote),
@@ -220,22 +235,7 @@ Example:
 				return nil
 			}
 
-			defaultEndpoint := ""
-			defaultModel := ""
-			switch provider {
-			case service.ModelProviderOpenAI:
-				defaultEndpoint = "https://api.openai.com/v1"
-				defaultModel = "gpt-5.2"
-			case service.ModelProviderAnthropic:
-				defaultEndpoint = "https://api.anthropic.com/v1"
-				defaultModel = "claude-4-5-sonnet"
-			case service.ModelProviderGemini:
-				defaultEndpoint = "https://generativelanguage.googleapis.com"
-				defaultModel = "gemini-flash-latest"
-			case service.ModelProviderOpenAICompatible:
-				defaultEndpoint = "https://openrouter.ai/api/v1"
-				defaultModel = "deepseek-r1"
-			}
+			defaultEndpoint, defaultModel := providerDefaults(provider)
 
 			// 3. Endpoint, Key, Model ID
 			err = huh.NewForm(
@@ -325,6 +325,8 @@ Example:
 			return fmt.Errorf("model named '%s' already exists. Use 'remove' first or use 'set' to change its config or choose a different name", name)
 		}
 
+		provider, endpoint = resolveProviderPreset(provider, endpoint)
+
 		// Create new model config
 		newModel := data.Model{
 			Provider: provider,
@@ -417,6 +419,9 @@ gllm model set gpt4 --endpoint "..." --key $OPENAI_KEY --model gpt-4o --temp 1.0
 			var tempStr = "1.0"
 			var topPStr = "1.0"
 			var seedStr = ""
+			var maxTokensStr, stopStr, freqPenaltyStr, presPenaltyStr string
+			var proxy, caCert string
+			var insecure bool
 
 			// Populate from existing
 			provider = modelConfig.Provider
@@ -428,18 +433,26 @@ gllm model set gpt4 --endpoint "..." --key $OPENAI_KEY --model gpt-4o --temp 1.0
 			if modelConfig.Seed != nil {
 				seedStr = fmt.Sprintf("%v", *modelConfig.Seed)
 			}
+			if modelConfig.MaxTokens > 0 {
+				maxTokensStr = fmt.Sprintf("%d", modelConfig.MaxTokens)
+			}
+			stopStr = strings.Join(modelConfig.Stop, ", ")
+			if modelConfig.FrequencyPenalty != nil {
+				freqPenaltyStr = fmt.Sprintf("%v", *modelConfig.FrequencyPenalty)
+			}
+			if modelConfig.PresencePenalty != nil {
+				presPenaltyStr = fmt.Sprintf("%v", *modelConfig.PresencePenalty)
+			}
+			proxy = modelConfig.Proxy
+			caCert = modelConfig.CACert
+			insecure = modelConfig.InsecureSkipVerify
 
 			err := huh.NewForm(
 				huh.NewGroup(
 					huh.NewSelect[string]().
 						Title("Provider").
 						Description(providerSelectDesc).
-						Options(
-							huh.NewOption("OpenAI", service.ModelProviderOpenAI),
-							huh.NewOption("Anthropic", service.ModelProviderAnthropic),
-							huh.NewOption("Google Gemini", service.ModelProviderGemini),
-							huh.NewOption("Other (OpenAI Compatible)", service.ModelProviderOpenAICompatible),
-						).
+						Options(providerOptions()...).
 						Value(&provider),
 					huh.NewNote().
 						Description(providerSelectNote),
@@ -474,11 +487,49 @@ gllm model set gpt4 --endpoint "..." --key $OPENAI_KEY --model gpt-4o --temp 1.0
 						Value(&seedStr).
 						Validate(ValidateSeed),
 				).Title("Advanced Settings"),
+				huh.NewGroup(
+					huh.NewInput().
+						Title("Max Tokens").
+						Description("Max tokens per response. Leave empty for provider default.").
+						Value(&maxTokensStr).
+						Validate(ValidateMaxTokens),
+					huh.NewInput().
+						Title("Stop Sequences").
+						Description("Comma separated. Leave empty for none.").
+						Value(&stopStr),
+					huh.NewInput().
+						Title("Frequency Penalty").
+						Description("Penalize repeated tokens (-2.0 - 2.0). Leave empty for provider default.").
+						Value(&freqPenaltyStr).
+						Validate(ValidatePenalty),
+					huh.NewInput().
+						Title("Presence Penalty").
+						Description("Encourage new topics (-2.0 - 2.0). Leave empty for provider default.").
+						Value(&presPenaltyStr).
+						Validate(ValidatePenalty),
+				).Title("Generation Controls"),
+				huh.NewGroup(
+					huh.NewInput().
+						Title("Proxy").
+						Description("http(s):// or socks5:// URL. Leave empty to use HTTPS_PROXY from the environment.").
+						Value(&proxy).
+						Validate(service.ValidateProxy),
+					huh.NewInput().
+						Title("CA Certificate").
+						Description("PEM file with extra CA certificates to trust, e.g. of a corporate proxy.").
+						Value(&caCert).
+						Validate(ValidateCACert),
+					huh.NewConfirm().
+						Title("Skip TLS Verification").
+						Description("Unsafe, only for testing against self-signed endpoints.").
+						Value(&insecure),
+				).Title("Network"),
 			).Run()
 			if err != nil {
 				return nil
 			}
 
+			provider, endpoint = resolveProviderPreset(provider, endpoint)
 			modelConfig.Endpoint = endpoint
 			modelConfig.Key = key
 			modelConfig.Model = model
@@ -496,11 +547,22 @@ gllm model set gpt4 --endpoint "..." --key $OPENAI_KEY --model gpt-4o --temp 1.0
 			} else {
 				modelConfig.Seed = nil
 			}
+			if n, err := strconv.Atoi(maxTokensStr); err == nil {
+				modelConfig.MaxTokens = int32(n)
+			} else {
+				modelConfig.MaxTokens = 0
+			}
+			modelConfig.Stop = parseStopSequences(stopStr)
+			modelConfig.FrequencyPenalty = parseOptionalFloat(freqPenaltyStr)
+			modelConfig.PresencePenalty = parseOptionalFloat(presPenaltyStr)
+			modelConfig.Proxy = strings.TrimSpace(proxy)
+			modelConfig.CACert = strings.TrimSpace(caCert)
+			modelConfig.InsecureSkipVerify = insecure
 		} else {
 			// Update from flags
 			if cmd.Flags().Changed("provider") {
 				if v, err := cmd.Flags().GetString("provider"); err == nil {
-					modelConfig.Provider = v
+					modelConfig.Provider, modelConfig.Endpoint = resolveProviderPreset(v, modelConfig.Endpoint)
 				}
 			}
 			if cmd.Flags().Changed("endpoint") {
@@ -538,6 +600,153 @@ gllm model set gpt4 --endpoint "..." --key $OPENAI_KEY --model gpt-4o --temp 1.0
 					}
 				}
 			}
+			if cmd.Flags().Changed("max_tokens") {
+				if v, err := cmd.Flags().GetInt("max_tokens"); err == nil {
+					if v < 0 {
+						return fmt.Errorf("max_tokens must not be negative, got: %d", v)
+					}
+					modelConfig.MaxTokens = int32(v)
+				}
+			}
+			if cmd.Flags().Changed("stop") {
+				if v, err := cmd.Flags().GetStringSlice("stop"); err == nil {
+					modelConfig.Stop = parseStopSequences(strings.Join(v, ","))
+				}
+			}
+			if cmd.Flags().Changed("frequency_penalty") {
+				if v, err := cmd.Flags().GetFloat32("frequency_penalty"); err == nil {
+					if v < -2.0 || v > 2.0 {
+						return fmt.Errorf("frequency_penalty must be between -2.0 and 2.0, got: %f", v)
+					}
+					modelConfig.FrequencyPenalty = &v
+				}
+			}
+			if cmd.Flags().Changed("presence_penalty") {
+				if v, err := cmd.Flags().GetFloat32("presence_penalty"); err == nil {
+					if v < -2.0 || v > 2.0 {
+						return fmt.Errorf("presence_penalty must be between -2.0 and 2.0, got: %f", v)
+					}
+					modelConfig.PresencePenalty = &v
+				}
+			}
+			if cmd.Flags().Changed("proxy") {
+				if v, err := cmd.Flags().GetString("proxy"); err == nil {
+					if err := service.ValidateProxy(v); err != nil {
+						return err
+					}
+					modelConfig.Proxy = v
+				}
+			}
+			if cmd.Flags().Changed("ca_cert") {
+				if v, err := cmd.Flags().GetString("ca_cert"); err == nil {
+					if err := ValidateCACert(v); err != nil {
+						return err
+					}
+					modelConfig.CACert = v
+				}
+			}
+			if cmd.Flags().Changed("insecure_skip_verify") {
+				if v, err := cmd.Flags().GetBool("insecure_skip_verify"); err == nil {
+					modelConfig.InsecureSkipVerify = v
+				}
+			}
+			for _, extra := range []struct {
+				flag   string
+				kind   string
+				values *map[string]string
+			}{{"header", "header", &modelConfig.Headers}, {"query_param", "query parameter", &modelConfig.QueryParams}} {
+				if cmd.Flags().Changed(extra.flag) {
+					if v, err := cmd.Flags().GetStringArray(extra.flag); err == nil {
+						values, err := mergeRequestExtras(*extra.values, extra.kind, v)
+						if err != nil {
+							return err
+						}
+						*extra.values = values
+					}
+				}
+			}
+			if cmd.Flags().Changed("provider_order") {
+				if v, err := cmd.Flags().GetStringSlice("provider_order"); err == nil {
+					modelConfig.ProviderOrder = nonEmptyValues(v)
+				}
+			}
+			if cmd.Flags().Changed("provider_sort") {
+				if v, err := cmd.Flags().GetString("provider_sort"); err == nil {
+					v = strings.ToLower(strings.TrimSpace(v))
+					if err := service.ValidateOpenRouterSort(v); err != nil {
+						return err
+					}
+					modelConfig.ProviderSort = v
+				}
+			}
+			if cmd.Flags().Changed("allow_fallbacks") {
+				if v, err := cmd.Flags().GetString("allow_fallbacks"); err == nil {
+					switch strings.ToLower(strings.TrimSpace(v)) {
+					case "":
+						modelConfig.AllowFallbacks = nil
+					case "on", "true", "yes", "1":
+						modelConfig.AllowFallbacks = service.Ptr(true)
+					case "off", "false", "no", "0":
+						modelConfig.AllowFallbacks = service.Ptr(false)
+					default:
+						return fmt.Errorf("invalid allow_fallbacks '%s', use on or off", v)
+					}
+				}
+			}
+			if cmd.Flags().Changed("fallback_models") {
+				if v, err := cmd.Flags().GetStringSlice("fallback_models"); err == nil {
+					modelConfig.FallbackModels = nonEmptyValues(v)
+				}
+			}
+			if !service.IsOpenRouterEndpoint(modelConfig.Endpoint) && (len(modelConfig.ProviderOrder) > 0 || modelConfig.ProviderSort != "" ||
+				modelConfig.AllowFallbacks != nil || len(modelConfig.FallbackModels) > 0) {
+				util.Warnf(cmd, "The routing options are only sent to OpenRouter, the endpoint of '%s' is %s.\n", name, modelConfig.Endpoint)
+			}
+			for _, price := range []struct {
+				flag  string
+				value *float32
+			}{{"input_price", &modelConfig.InputPrice}, {"output_price", &modelConfig.OutputPrice}} {
+				if cmd.Flags().Changed(price.flag) {
+					if v, err := cmd.Flags().GetFloat32(price.flag); err == nil {
+						if v < 0 {
+							return fmt.Errorf("%s must not be negative, got: %f", price.flag, v)
+						}
+						*price.value = v
+					}
+				}
+			}
+			if cmd.Flags().Changed("safety") {
+				if v, err := cmd.Flags().GetStringSlice("safety"); err == nil {
+					settings := make(map[string]string, len(v))
+					for _, setting := range v {
+						if strings.TrimSpace(setting) == "" {
+							continue
+						}
+						category, threshold, err := service.ParseGeminiSafetySetting(setting)
+						if err != nil {
+							return err
+						}
+						settings[category] = threshold
+					}
+					modelConfig.SafetySettings = settings
+				}
+			}
+			if cmd.Flags().Changed("code_execution") {
+				if v, err := cmd.Flags().GetBool("code_execution"); err == nil {
+					modelConfig.CodeExecution = v
+				}
+			}
+			if modelConfig.CodeExecution && modelConfig.Provider != service.ModelProviderGemini && modelConfig.Provider != service.ModelProviderAnthropic {
+				util.Warnf(cmd, "Code execution is a Gemini and Anthropic tool, '%s' is a %s model.\n", name, modelConfig.Provider)
+			}
+			if cmd.Flags().Changed("native_tools") {
+				if v, err := cmd.Flags().GetBool("native_tools"); err == nil {
+					modelConfig.NativeTools = v
+				}
+			}
+			if modelConfig.NativeTools && modelConfig.Provider != service.ModelProviderAnthropic {
+				util.Warnf(cmd, "Native tools are Claude tools, '%s' is a %s model.\n", name, modelConfig.Provider)
+			}
 		}
 
 		// Update the entry via data layer
@@ -550,6 +759,135 @@ gllm model set gpt4 --endpoint "..." --key $OPENAI_KEY --model gpt-4o --temp 1.0
 	},
 }
 
+var modelDiscoverCmd = &cobra.Command{
+	Use:     "discover [NAME|PROVIDER]",
+	Aliases: []string{"ds"},
+	Short:   "List models from the provider API and add the selected ones",
+	Long: `Queries the provider's model listing endpoint and lets you pick model IDs to save.
+Pass the name of an existing model to reuse its endpoint and key,
+or a provider (openai, anthropic, gemini, openchat) to enter new credentials.
+
+Example:
+gllm model discover gpt4
+gllm model discover gemini`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := data.NewConfigStore()
+		modelsMap := store.GetModels()
+
+		var target string
+		if len(args) > 0 {
+			target = args[0]
+		} else {
+			// Select a configured model, its credentials are reused
+			if len(modelsMap) == 0 {
+				return fmt.Errorf("no models found, pass a provider name instead")
+			}
+			var options []huh.Option[string]
+			for m := range modelsMap {
+				options = append(options, huh.NewOption(m, m))
+			}
+			if model := GetEffectModel(); model != nil {
+				target = model.Name
+			}
+			ui.SortOptions(options, target)
+			err := huh.NewSelect[string]().
+				Title("Select Model Endpoint").
+				Description("The endpoint and key of this model are used to list models").
+				Height(io.GetTermFitHeight(len(options))).
+				Options(options...).
+				Value(&target).
+				Run()
+			if err != nil {
+				return nil
+			}
+		}
+
+		// Resolve the credentials
+		var base data.Model
+		if existing, ok := modelsMap[target]; ok && existing != nil {
+			base = *existing
+		} else {
+			switch target {
+			case service.ModelProviderOpenAI, service.ModelProviderAnthropic, service.ModelProviderGemini, service.ModelProviderOpenAICompatible:
+			default:
+				return fmt.Errorf("'%s' is neither a configured model nor a known provider", target)
+			}
+			base.Provider = target
+			base.Endpoint, _ = providerDefaults(target)
+			err := huh.NewForm(
+				huh.NewGroup(
+					huh.NewInput().
+						Title("Endpoint").
+						Value(&base.Endpoint).
+						Validate(func(s string) error {
+							if !strings.HasPrefix(s, "https://") && !strings.HasPrefix(s, "http://") {
+								return fmt.Errorf("endpoint must start with 'https://' or 'http://'")
+							}
+							return nil
+						}),
+					huh.NewInput().
+						Title("API Key").
+						Value(&base.Key).
+						EchoMode(huh.EchoModePassword),
+				),
+			).Run()
+			if err != nil {
+				return nil
+			}
+		}
+
+		ui.GetIndicator().Start(ui.IndicatorListingModels)
+		ids, err := service.ListProviderModels(cmd.Context(), &base)
+		ui.GetIndicator().Stop()
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			util.Println(cmd, "The provider returned no models.")
+			return nil
+		}
+
+		var options []huh.Option[string]
+		for _, id := range ids {
+			options = append(options, huh.NewOption(id, id))
+		}
+		var selected []string
+		err = huh.NewMultiSelect[string]().
+			Title("Select Models to Add").
+			Description(fmt.Sprintf("%d models available, press / to filter", len(ids))).
+			Height(io.GetTermFitHeight(len(options))).
+			Options(options...).
+			Value(&selected).
+			Run()
+		if err != nil || len(selected) == 0 {
+			return nil
+		}
+
+		for _, id := range selected {
+			// Model names are config keys, dots are not allowed
+			name := strings.ToLower(strings.ReplaceAll(service.NormalizeModelName(id), ".", "-"))
+			if _, exists := modelsMap[name]; exists {
+				util.Printf(cmd, "Model '%s' already exists, skipped.\n", name)
+				continue
+			}
+			newModel := data.Model{
+				Provider: base.Provider,
+				Endpoint: base.Endpoint,
+				Key:      base.Key,
+				Model:    id,
+				Temp:     1.0,
+				TopP:     1.0,
+			}
+			if err := store.SetModel(name, &newModel); err != nil {
+				return fmt.Errorf("failed to save model: %w", err)
+			}
+			util.Printf(cmd, "Model '%s' (%s) added successfully.\n", name, id)
+		}
+		return nil
+	},
+}
+
 var modelInfoCmd = &cobra.Command{
 	Use:     "info NAME",
 	Aliases: []string{"in"},
@@ -611,6 +949,81 @@ var modelInfoCmd = &cobra.Command{
 			}
 			util.Printf(cmd, "Context Length: %d\n", modelConfig.ContextLength)
 			util.Printf(cmd, "Max Output Tokens: %d\n", modelConfig.MaxOutputTokens)
+			if modelConfig.MaxTokens > 0 {
+				util.Printf(cmd, "Max Tokens: %d\n", modelConfig.MaxTokens)
+			}
+			if len(modelConfig.Stop) > 0 {
+				util.Printf(cmd, "Stop: %q\n", modelConfig.Stop)
+			}
+			if modelConfig.FrequencyPenalty != nil {
+				util.Printf(cmd, "Frequency Penalty: %v\n", *modelConfig.FrequencyPenalty)
+			}
+			if modelConfig.PresencePenalty != nil {
+				util.Printf(cmd, "Presence Penalty: %v\n", *modelConfig.PresencePenalty)
+			}
+			if modelConfig.Proxy != "" {
+				util.Printf(cmd, "Proxy: %s\n", modelConfig.Proxy)
+			}
+			if modelConfig.CACert != "" {
+				util.Printf(cmd, "CA Certificate: %s\n", modelConfig.CACert)
+			}
+			if modelConfig.InsecureSkipVerify {
+				util.Printf(cmd, "Skip TLS Verification: %v\n", modelConfig.InsecureSkipVerify)
+			}
+			for _, extra := range []struct {
+				title  string
+				values map[string]string
+				redact bool
+			}{{"Headers", modelConfig.Headers, true}, {"Query Params", modelConfig.QueryParams, false}} {
+				if len(extra.values) == 0 {
+					continue
+				}
+				keys := make([]string, 0, len(extra.values))
+				for k := range extra.values {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				util.Printf(cmd, "%s:\n", extra.title)
+				for _, k := range keys {
+					v := extra.values[k]
+					if extra.redact {
+						v = service.RedactHeaderValue(k, v)
+					}
+					util.Printf(cmd, "  %s: %s\n", k, v)
+				}
+			}
+			if len(modelConfig.ProviderOrder) > 0 {
+				util.Printf(cmd, "Provider Order: %s\n", strings.Join(modelConfig.ProviderOrder, ", "))
+			}
+			if modelConfig.ProviderSort != "" {
+				util.Printf(cmd, "Provider Sort: %s\n", modelConfig.ProviderSort)
+			}
+			if modelConfig.AllowFallbacks != nil {
+				util.Printf(cmd, "Allow Fallbacks: %v\n", *modelConfig.AllowFallbacks)
+			}
+			if len(modelConfig.FallbackModels) > 0 {
+				util.Printf(cmd, "Fallback Models: %s\n", strings.Join(modelConfig.FallbackModels, ", "))
+			}
+			if modelConfig.InputPrice > 0 || modelConfig.OutputPrice > 0 {
+				util.Printf(cmd, "Price: $%v input, $%v output per 1M tokens\n", modelConfig.InputPrice, modelConfig.OutputPrice)
+			}
+			if len(modelConfig.SafetySettings) > 0 {
+				categories := make([]string, 0, len(modelConfig.SafetySettings))
+				for category := range modelConfig.SafetySettings {
+					categories = append(categories, category)
+				}
+				sort.Strings(categories)
+				util.Println(cmd, "Safety:")
+				for _, category := range categories {
+					util.Printf(cmd, "  %s: %s\n", category, modelConfig.SafetySettings[category])
+				}
+			}
+			if modelConfig.CodeExecution {
+				util.Println(cmd, "Code Execution: on")
+			}
+			if modelConfig.NativeTools {
+				util.Println(cmd, "Native Tools: on")
+			}
 			util.Println(cmd, "---")
 			return nil
 		}
@@ -801,6 +1214,7 @@ to the specified one for all subsequent operations.`,
 		if err := store.SetAgent(agent.Name, agent); err != nil {
 			return fmt.Errorf("failed to set active agent: %w", err)
 		}
+		data.SetModelInSession("")
 
 		util.Printf(cmd, "Switched to model '%s' successfully.\n", name)
 		util.Println(cmd, "This model will be used for all subsequent operations.")
@@ -907,9 +1321,57 @@ func GetEffectModel() (model *data.Model) {
 	if agent == nil {
 		return nil
 	}
+	if name := data.GetModelInSession(); name != "" {
+		if model := store.GetModel(name); model != nil {
+			return model
+		}
+	}
 	return &agent.Model
 }
 
+// providerOptions are the providers to pick from, with the presets of the OpenAI-compatible
+// APIs whose quirks are known.
+func providerOptions() []huh.Option[string] {
+	opts := []huh.Option[string]{
+		huh.NewOption("OpenAI", service.ModelProviderOpenAI),
+		huh.NewOption("Anthropic", service.ModelProviderAnthropic),
+		huh.NewOption("Google Gemini", service.ModelProviderGemini),
+	}
+	for _, preset := range service.ProviderPresets {
+		opts = append(opts, huh.NewOption(preset.Title, preset.Name))
+	}
+	return append(opts, huh.NewOption("Other (OpenAI Compatible)", service.ModelProviderOpenAICompatible))
+}
+
+// resolveProviderPreset turns a preset into the provider it uses, with its endpoint when none is set.
+func resolveProviderPreset(provider, endpoint string) (string, string) {
+	if preset := service.LookupProviderPreset(provider); preset != nil {
+		if endpoint == "" {
+			endpoint = preset.Endpoint
+		}
+		return preset.Provider, endpoint
+	}
+	return provider, endpoint
+}
+
+// providerDefaults returns the default endpoint and a suggested model ID for a provider.
+func providerDefaults(provider string) (endpoint string, model string) {
+	if preset := service.LookupProviderPreset(provider); preset != nil {
+		return preset.Endpoint, preset.Model
+	}
+	switch provider {
+	case service.ModelProviderOpenAI:
+		return "https://api.openai.com/v1", "gpt-5.2"
+	case service.ModelProviderAnthropic:
+		return "https://api.anthropic.com/v1", "claude-4-5-sonnet"
+	case service.ModelProviderGemini:
+		return "https://generativelanguage.googleapis.com", "gemini-flash-latest"
+	case service.ModelProviderOpenAICompatible:
+		return "https://openrouter.ai/api/v1", "deepseek-r1"
+	}
+	return "", ""
+}
+
 func CheckModelName(name string) error {
 	if strings.Contains(name, ".") {
 		return fmt.Errorf("model name '%s' contains a dot, which is not allowed", name)
@@ -967,3 +1429,104 @@ func ValidateSeed(s string) error {
 	_, err := strconv.Atoi(s)
 	return err
 }
+
+func ValidateMaxTokens(s string) error {
+	if s == "" {
+		return nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	if v < 0 {
+		return fmt.Errorf("max tokens must not be negative")
+	}
+	return nil
+}
+
+// ValidatePenalty checks frequency and presence penalties, both in the range [-2, 2].
+func ValidatePenalty(s string) error {
+	if s == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		return err
+	}
+	if v < -2.0 || v > 2.0 {
+		return fmt.Errorf("penalty must be between -2.0 and 2.0")
+	}
+	return nil
+}
+
+// mergeRequestExtras applies KEY=VALUE flags to the extra headers or query parameters of a
+// model: KEY= removes a key and a single empty flag clears them all.
+func mergeRequestExtras(values map[string]string, kind string, flags []string) (map[string]string, error) {
+	if len(flags) == 1 && strings.TrimSpace(flags[0]) == "" {
+		return nil, nil
+	}
+	merged := make(map[string]string, len(values)+len(flags))
+	for k, v := range values {
+		merged[k] = v
+	}
+	for _, flag := range flags {
+		key, value, err := service.ParseRequestExtra(kind, flag)
+		if err != nil {
+			return nil, err
+		}
+		// Keys are stored lowercase in the config file
+		key = strings.ToLower(key)
+		if value == "" {
+			delete(merged, key)
+		} else {
+			merged[key] = value
+		}
+	}
+	if len(merged) == 0 {
+		return nil, nil
+	}
+	return merged, nil
+}
+
+// ValidateCACert checks that a CA certificate file exists, empty is allowed.
+func ValidateCACert(s string) error {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	if _, err := os.Stat(strings.TrimSpace(s)); err != nil {
+		return fmt.Errorf("CA certificate file not found: %s", s)
+	}
+	return nil
+}
+
+// parseStopSequences splits a comma separated list, dropping empty entries.
+func parseStopSequences(s string) []string {
+	var stops []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			stops = append(stops, part)
+		}
+	}
+	return stops
+}
+
+// nonEmptyValues trims the values of a list flag and drops the empty ones.
+func nonEmptyValues(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// parseOptionalFloat returns nil for an empty or invalid string.
+func parseOptionalFloat(s string) *float32 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 32)
+	if err != nil {
+		return nil
+	}
+	f := float32(v)
+	return &f
+}
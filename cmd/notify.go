@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configCmd.AddCommand(notifyCmd)
+	notifyCmd.AddCommand(notifyTestCmd)
+
+	notifyTestCmd.Flags().String("sink", "", "Post the test message to a chat sink instead, e.g. slack:#channel or discord")
+}
+
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Manage desktop notifications for long-running generations",
+	Long: `Display desktop notification settings.
+When a generation or workflow runs longer than the threshold, gllm notifies the desktop
+once it finishes, or when it stops to wait for a tool confirmation or an answer.
+Notifications use osascript on macOS, notify-send on Linux and a toast on Windows.
+
+Set the threshold with 'gllm config set notify.threshold 30s', 'off' disables them.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Print Long description
+		util.Println(cmd, cmd.Long)
+		util.Println(cmd)
+		util.Print(cmd, renderNotifyStatus(data.GetSettingsStore().GetNotifySettings()))
+	},
+}
+
+var notifyTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a test notification",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if sink, _ := cmd.Flags().GetString("sink"); sink != "" {
+			if err := service.PostToSink(context.Background(), sink, "gllm", "gllm can post to this channel."); err != nil {
+				util.Errorf(cmd, "%v\n", err)
+				return
+			}
+			util.Printf(cmd, "Message posted to %s.\n", sink)
+			return
+		}
+		if err := service.SendDesktopNotification("gllm", "Desktop notifications work."); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Println(cmd, "Notification sent.")
+	},
+}
+
+// setNotifyThreshold stores the notify threshold of 'config set notify.threshold'.
+func setNotifyThreshold(value string) (string, error) {
+	threshold, err := service.ParseNotifyThreshold(value)
+	if err != nil {
+		return "", err
+	}
+	settings := data.GetSettingsStore()
+	notify := settings.GetNotifySettings()
+	notify.Threshold = ""
+	if threshold > 0 {
+		notify.Threshold = threshold.String()
+	}
+	if err := settings.SetNotifySettings(notify); err != nil {
+		return "", fmt.Errorf("failed to update settings: %w", err)
+	}
+	if threshold == 0 {
+		return "off", nil
+	}
+	return notify.Threshold, nil
+}
+
+// newNotifyTimer starts timing a generation for desktop notifications, nil when they are off
+// or no one sits at the desktop.
+func newNotifyTimer() *service.NotifyTimer {
+	if isHeadless() {
+		return nil
+	}
+	timer, err := service.NewNotifyTimerFromSettings(data.GetSettingsStore().GetNotifySettings())
+	if err != nil {
+		util.LogWarnf("Desktop notifications are off: %v\n", err)
+	}
+	return timer
+}
+
+func renderNotifyStatus(notify data.NotifySettings) string {
+	threshold := data.SwitchOffColor + "off" + data.ResetSeq
+	if d, err := service.ParseNotifyThreshold(notify.Threshold); err == nil && d > 0 {
+		threshold = data.SwitchOnColor + d.String() + data.ResetSeq
+	}
+	return fmt.Sprintf("Notify threshold: %s\n", threshold)
+}
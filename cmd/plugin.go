@@ -30,6 +30,12 @@ var KnownPlugins = []struct {
 func init() {
 	rootCmd.AddCommand(pluginCmd)
 	pluginCmd.AddCommand(pluginSwitchCmd)
+	pluginCmd.AddCommand(pluginAddCmd)
+	pluginCmd.AddCommand(pluginRemoveCmd)
+	pluginCmd.AddCommand(pluginToolsCmd)
+
+	pluginAddCmd.Flags().StringArrayP("env", "e", nil, "Environment variables of the plugin as KEY=VALUE")
+	pluginAddCmd.Flags().Bool("disabled", false, "Declare the plugin without starting it")
 }
 
 var pluginCmd = &cobra.Command{
@@ -37,12 +43,92 @@ var pluginCmd = &cobra.Command{
 	Aliases: []string{"plugins", "pg"},
 	Short:   "Manage gllm plugins",
 	Long: `View and manage plugins for gllm.
-Use 'gllm plugin switch' to toggle plugins on or off.`,
+Use 'gllm plugin switch' to toggle plugins on or off.
+
+External plugins are programs declared in the plugins section of gllm.yaml. gllm starts
+them and talks JSON-RPC 2.0 over stdin and stdout, one message per line:
+
+  initialize        {"protocol": 1}
+                    -> {"name", "version", "tools": [{"name", "description", "parameters"}], "approval": bool}
+  tools/call        {"name", "arguments"} -> {"content": "...", "is_error": bool}
+  approval/request  {"id", "tool", "command", "description", ...} -> {"approve": bool, "feedback": "..."}
+
+Their tools are offered to agents with tools enabled as <plugin>_<tool>, and each call is
+confirmed like a shell command. A plugin that answers approvals can be the approval
+backend, see 'gllm config approval'.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		store := data.GetSettingsStore()
 		enabled := store.GetEnabledPlugins()
 		util.Print(cmd, renderPluginSummary(enabled))
 		util.Println(cmd, "Use 'gllm plugin switch' to change.")
+		util.Println(cmd)
+		util.Print(cmd, renderExternalPlugins(data.NewConfigStore().GetExternalPlugins()))
+	},
+}
+
+var pluginAddCmd = &cobra.Command{
+	Use:   "add NAME COMMAND [ARGS...]",
+	Short: "Declare an external plugin",
+	Example: `  gllm plugin add jira gllm-jira-plugin --env JIRA_TOKEN=secret
+  gllm plugin add approvals python3 -- ~/bin/approve_on_slack.py`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		plugin := data.ExternalPlugin{Name: args[0], Command: args[1], Args: args[2:]}
+		plugin.Env, _ = cmd.Flags().GetStringArray("env")
+		for _, kv := range plugin.Env {
+			if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+				util.Errorf(cmd, "Invalid environment variable '%s', expected KEY=VALUE\n", kv)
+				return
+			}
+		}
+		plugin.Disabled, _ = cmd.Flags().GetBool("disabled")
+		if err := data.NewConfigStore().SetExternalPlugin(&plugin); err != nil {
+			util.Errorf(cmd, "Failed to save plugin: %v\n", err)
+			return
+		}
+		util.Printf(cmd, "Plugin '%s' saved. Check it with 'gllm plugin tools %s'.\n", strings.ToLower(plugin.Name), strings.ToLower(plugin.Name))
+	},
+}
+
+var pluginRemoveCmd = &cobra.Command{
+	Use:     "remove NAME",
+	Aliases: []string{"rm"},
+	Short:   "Remove an external plugin",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := data.NewConfigStore().DeleteExternalPlugin(args[0]); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "Plugin '%s' removed.\n", strings.ToLower(args[0]))
+	},
+}
+
+var pluginToolsCmd = &cobra.Command{
+	Use:   "tools NAME",
+	Short: "Start an external plugin and list what it registers",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		client, err := service.GetPluginClient(cmd.Context(), args[0])
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		info := client.Info()
+		util.Printf(cmd, "%s %s\n", info.Name, info.Version)
+		if info.Approval {
+			util.Println(cmd, "Answers approval requests")
+		}
+		util.Println(cmd)
+		for _, t := range info.Tools {
+			util.Printf(cmd, "%s%s%s\n", data.KeyColor, service.PluginToolName(strings.ToLower(args[0]), t.Name), data.ResetSeq)
+			if t.Description != "" {
+				util.Printf(cmd, "%s%s%s\n", data.DetailColor, t.Description, data.ResetSeq)
+			}
+		}
+		if len(info.Tools) == 0 {
+			util.Println(cmd, "No tools")
+		}
 	},
 }
 
@@ -132,3 +218,18 @@ func renderPluginSummary(enabled []string) string {
 	fmt.Fprintf(&sb, "%s = Enabled\n", ui.FormatEnabledIndicator(true))
 	return sb.String()
 }
+
+func renderExternalPlugins(plugins []data.ExternalPlugin) string {
+	var sb strings.Builder
+	sb.WriteString("External Plugins:\n\n")
+	if len(plugins) == 0 {
+		sb.WriteString("None, declare one with 'gllm plugin add'.\n")
+		return sb.String()
+	}
+	for _, p := range plugins {
+		indicator := ui.FormatEnabledIndicator(!p.Disabled)
+		fmt.Fprintf(&sb, "%s %s\n", indicator, p.Name)
+		fmt.Fprintf(&sb, "%s%s%s\n", data.DetailColor, strings.Join(append([]string{p.Command}, p.Args...), " "), data.ResetSeq)
+	}
+	return sb.String()
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configCmd.AddCommand(redactCmd)
+	redactCmd.AddCommand(redactSwitchCmd)
+	redactCmd.AddCommand(redactAddCmd)
+	redactCmd.AddCommand(redactRemoveCmd)
+	redactCmd.AddCommand(redactEnableCmd)
+	redactCmd.AddCommand(redactDisableCmd)
+	redactCmd.AddCommand(redactTestCmd)
+}
+
+var redactCmd = &cobra.Command{
+	Use:   "redact",
+	Short: "Manage redaction of secrets and personal data in outgoing prompts",
+	Long: `Display or manage the redaction filter.
+When enabled, prompts, attached text files and tool results are scanned for API keys,
+AWS credentials, private keys, emails and custom patterns before they are sent to a provider.
+Matches are replaced with placeholders such as [REDACTED_EMAIL_1]. The mapping stays local,
+tools that write files get the real values back, commands keep the placeholders.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Print Long description
+		util.Println(cmd, cmd.Long)
+		util.Println(cmd)
+		util.Print(cmd, renderRedactionStatus(data.GetSettingsStore().GetRedactionSettings()))
+	},
+}
+
+var redactSwitchCmd = &cobra.Command{
+	Use:     "switch [true|false]",
+	Aliases: []string{"sw"},
+	Short:   "Toggle or set redaction",
+	Args:    cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return []string{"true", "false", "on", "off", "enable", "disable"}, cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		settings := data.GetSettingsStore()
+		redaction := settings.GetRedactionSettings()
+
+		if len(args) == 0 {
+			// Toggle behavior
+			redaction.Enabled = !redaction.Enabled
+		} else {
+			switch args[0] {
+			case "true", "on", "enable":
+				redaction.Enabled = true
+			case "false", "off", "disable":
+				redaction.Enabled = false
+			default:
+				util.Printf(cmd, "%sInvalid argument: %s. Use 'true' or 'false'.%s\n", data.StatusErrorColor, args[0], data.ResetSeq)
+				return
+			}
+		}
+
+		if err := settings.SetRedactionSettings(redaction); err != nil {
+			util.Errorf(cmd, "Failed to update settings: %v\n", err)
+			return
+		}
+		util.Print(cmd, renderRedactionStatus(redaction))
+	},
+}
+
+var redactAddCmd = &cobra.Command{
+	Use:   "add NAME REGEX",
+	Short: "Add a custom redaction pattern",
+	Long: `Add a custom redaction pattern. Values matching REGEX (Go syntax) are replaced
+with a placeholder labelled with NAME. An existing pattern with the same name is replaced.`,
+	Example: `  gllm config redact add employee_id 'EMP-[0-9]{6}'
+  gllm config redact add internal_host '[a-z0-9-]+\.corp\.example\.com'`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		pattern := data.RedactionPattern{Name: strings.ToLower(args[0]), Regex: args[1]}
+		if err := util.ValidateResourceName("pattern", pattern.Name); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if service.IsBuiltinRedactionRule(pattern.Name) {
+			util.Errorf(cmd, "'%s' is a built-in rule, choose another name\n", pattern.Name)
+			return
+		}
+		if _, err := service.CompileRedactionPattern(pattern); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+
+		settings := data.GetSettingsStore()
+		redaction := settings.GetRedactionSettings()
+		redaction.Patterns = slices.DeleteFunc(redaction.Patterns, func(p data.RedactionPattern) bool { return p.Name == pattern.Name })
+		redaction.Patterns = append(redaction.Patterns, pattern)
+		if err := settings.SetRedactionSettings(redaction); err != nil {
+			util.Errorf(cmd, "Failed to update settings: %v\n", err)
+			return
+		}
+		util.Printf(cmd, "Redaction pattern '%s' added.\n", pattern.Name)
+		if !redaction.Enabled {
+			util.Println(cmd, "Redaction is off, turn it on with: gllm config redact switch on")
+		}
+	},
+}
+
+var redactRemoveCmd = &cobra.Command{
+	Use:     "remove NAME",
+	Aliases: []string{"rm"},
+	Short:   "Remove a custom redaction pattern",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := strings.ToLower(args[0])
+		settings := data.GetSettingsStore()
+		redaction := settings.GetRedactionSettings()
+		n := len(redaction.Patterns)
+		redaction.Patterns = slices.DeleteFunc(redaction.Patterns, func(p data.RedactionPattern) bool { return p.Name == name })
+		if len(redaction.Patterns) == n {
+			if service.IsBuiltinRedactionRule(name) {
+				util.Errorf(cmd, "'%s' is a built-in rule, turn it off with: gllm config redact disable %s\n", name, name)
+			} else {
+				util.Errorf(cmd, "Redaction pattern '%s' not found\n", name)
+			}
+			return
+		}
+		if err := settings.SetRedactionSettings(redaction); err != nil {
+			util.Errorf(cmd, "Failed to update settings: %v\n", err)
+			return
+		}
+		util.Printf(cmd, "Redaction pattern '%s' removed.\n", name)
+	},
+}
+
+var redactEnableCmd = &cobra.Command{
+	Use:   "enable RULE",
+	Short: "Turn a built-in redaction rule back on",
+	Args:  cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return builtinRedactionRuleNames(), cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		setBuiltinRedactionRule(cmd, strings.ToLower(args[0]), true)
+	},
+}
+
+var redactDisableCmd = &cobra.Command{
+	Use:   "disable RULE",
+	Short: "Turn off a built-in redaction rule, e.g. email",
+	Args:  cobra.ExactArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return builtinRedactionRuleNames(), cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		setBuiltinRedactionRule(cmd, strings.ToLower(args[0]), false)
+	},
+}
+
+var redactTestCmd = &cobra.Command{
+	Use:   "test TEXT",
+	Short: "Show how a text would be redacted",
+	Long: `Show how a text would be redacted with the current rules, even when redaction is off.
+Nothing is remembered, placeholders are numbered from 1.`,
+	Example: `  gllm config redact test "mail me at jane@example.com"`,
+	Args:    cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		redactor := service.NewRedactor(data.GetSettingsStore().GetRedactionSettings())
+		util.Println(cmd, redactor.Redact(strings.Join(args, " ")))
+	},
+}
+
+func builtinRedactionRuleNames() []string {
+	var names []string
+	for _, r := range service.BuiltinRedactionRules() {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+// setBuiltinRedactionRule turns a built-in rule on or off.
+func setBuiltinRedactionRule(cmd *cobra.Command, name string, enable bool) {
+	if !service.IsBuiltinRedactionRule(name) {
+		util.Errorf(cmd, "Unknown built-in rule '%s', expected one of: %s\n", name, strings.Join(builtinRedactionRuleNames(), ", "))
+		return
+	}
+	settings := data.GetSettingsStore()
+	redaction := settings.GetRedactionSettings()
+	redaction.Disabled = slices.DeleteFunc(redaction.Disabled, func(n string) bool { return n == name })
+	if !enable {
+		redaction.Disabled = append(redaction.Disabled, name)
+	}
+	if err := settings.SetRedactionSettings(redaction); err != nil {
+		util.Errorf(cmd, "Failed to update settings: %v\n", err)
+		return
+	}
+	util.Print(cmd, renderRedactionStatus(redaction))
+}
+
+func renderRedactionStatus(redaction data.RedactionSettings) string {
+	var sb strings.Builder
+	status := data.SwitchOffColor + "false" + data.ResetSeq
+	if redaction.Enabled {
+		status = data.SwitchOnColor + "true" + data.ResetSeq
+	}
+	fmt.Fprintf(&sb, "Redaction: %s\n", status)
+
+	fmt.Fprintf(&sb, "%sBuilt-in rules:%s\n", data.SectionColor, data.ResetSeq)
+	for _, r := range service.BuiltinRedactionRules() {
+		state := data.SwitchOnColor + "on" + data.ResetSeq
+		if slices.Contains(redaction.Disabled, r.Name) {
+			state = data.SwitchOffColor + "off" + data.ResetSeq
+		}
+		fmt.Fprintf(&sb, "  %-16s %s\n", r.Name, state)
+	}
+	if len(redaction.Patterns) > 0 {
+		fmt.Fprintf(&sb, "%sCustom patterns:%s\n", data.SectionColor, data.ResetSeq)
+		for _, p := range redaction.Patterns {
+			fmt.Fprintf(&sb, "  %-16s %s\n", p.Name, p.Regex)
+		}
+	}
+	return sb.String()
+}
@@ -1,9 +1,9 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os/exec"
-	"runtime"
 	"sort"
 	"strings"
 	"sync"
@@ -104,11 +104,25 @@ type ReplInfo struct {
 	QuitFlag       bool              // for cmd /quit or /exit
 	EditorInput    string            // for /e editor edit
 	Guideline      string            // for underlying guideline (e.g. skill activation)
+	Notify         string            // sink the next response is posted to (e.g. a workflow's notify)
+	NotifyTitle    string            // title of the post, the workflow's name
 	History        []string          // for input history
 	sharedState    *data.SharedState // Persistent SharedState for the session
+	control        *replControl      // Inputs sent through the control socket
 	autoRenameOnce sync.Once         // ensures auto-rename fires at most once per REPL session
 }
 
+// nextInput returns the oldest input queued through the control socket, otherwise it waits
+// for the user.
+func (ri *ReplInfo) nextInput() (string, error) {
+	ri.control.setBusy(false)
+	defer ri.control.setBusy(true)
+	if text, ok := ri.control.next(); ok {
+		return text, nil
+	}
+	return ri.awaitInput()
+}
+
 // This is the new awaitInput function, which uses bubbletea, support auto-complete
 func (ri *ReplInfo) awaitInput() (string, error) {
 	agent, err := EnsureActiveAgent()
@@ -174,6 +188,11 @@ func (ri *ReplInfo) awaitInput() (string, error) {
 		// Return user cancel error
 		return "", service.UserCancelError{Reason: service.UserCancelReasonCancel}
 	}
+	if result.Yielded {
+		// Nothing typed, the queued input runs next
+		ri.History = result.History
+		return "", nil
+	}
 
 	// Update history
 	ri.History = result.History
@@ -187,6 +206,10 @@ func (ri *ReplInfo) getChatInputHooks(agent *data.AgentConfig) ui.ChatInputHooks
 		// Start load MCP server when chat input is ready
 		EventChatInputReady: func() {
 			StartLoadMCPServer(agent)
+			// Input queued while the input was starting
+			if pending := ri.control.pending(); pending > 0 {
+				ui.SendEvent(ui.QueuedInputMsg{Pending: pending})
+			}
 		},
 		IsPlanModeActive: func() bool {
 			return data.IsPlanModeInSessionEnabled() && data.GetPlanModeInSession()
@@ -221,6 +244,10 @@ func (ri *ReplInfo) startREPL(cmd *cobra.Command) {
 	ri.sharedState = data.NewSharedState()
 	defer ri.sharedState.Clear()
 
+	// Let editors and scripts send prompts with 'gllm send'
+	ri.control = startReplControl()
+	defer ri.control.close()
+
 	// Set auto approve for the session
 	data.SetYoloModeInSession(yoloFlag)
 
@@ -233,6 +260,7 @@ func (ri *ReplInfo) startREPL(cmd *cobra.Command) {
 	// Launch background update check (non-blocking).
 	// Only check once repl started
 	StartBackgroundUpdateCheck()
+	StartBackgroundTeamSync()
 
 	// Define prompt style
 	tcol := io.GetTerminalWidth()
@@ -248,8 +276,8 @@ func (ri *ReplInfo) startREPL(cmd *cobra.Command) {
 		var input string
 		var err error
 
-		// Get user input
-		input, err = ri.awaitInput()
+		// Get user input, or what was sent through the control socket
+		input, err = ri.nextInput()
 		if err != nil {
 			if service.IsUserCancelError(err) {
 				// Handle user cancellation (Ctrl+C)
@@ -296,6 +324,7 @@ func (ri *ReplInfo) startREPL(cmd *cobra.Command) {
 		ri.callAgent(input)
 		fmt.Println()
 	}
+	service.RunSessionEndHooks(sessionName)
 }
 
 func (ri *ReplInfo) startWithInnerCommand(line string) bool {
@@ -430,13 +459,30 @@ func (ri *ReplInfo) callAgent(input string) {
 		ri.Guideline = "" // Clear it after use
 	}
 
+	// A workflow posts only what this run responds
+	if ri.Notify != "" {
+		data.ClearClipboardText()
+	}
+
 	// Call agent using the shared runner, passing persisted SharedState
 	err := RunAgent(prompt, guideline, ri.Files, sessionName, "", ri.sharedState)
 	if err != nil {
+		ri.Notify, ri.NotifyTitle = "", ""
 		util.LogErrorf("%v\n", err)
 		return
 	}
 
+	// Post the output of a workflow to its sink
+	if ri.Notify != "" {
+		sink, title := ri.Notify, ri.NotifyTitle
+		ri.Notify, ri.NotifyTitle = "", ""
+		if err := service.PostToSink(context.Background(), sink, title, data.GetClipboardText()); err != nil {
+			util.LogWarnf("Failed to post to %s: %v\n", sink, err)
+		} else {
+			util.LogInfof("Posted the response to %s\n", sink)
+		}
+	}
+
 	// Auto-rename session once
 	ri.autoRenameSessionOnce()
 
@@ -451,13 +497,17 @@ func (ri *ReplInfo) executeShellCommand(command string) {
 		return
 	}
 
-	// Execute the command
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/C", command)
-	} else {
-		cmd = exec.Command("sh", "-c", command)
+	// Execute the command with the shell of the active agent
+	shellName := ""
+	if agent, err := EnsureActiveAgent(); err == nil {
+		shellName = agent.Shell
+	}
+	shell, err := service.ResolveShell(shellName)
+	if err != nil {
+		util.LogErrorf("%v\n", err)
+		return
 	}
+	cmd := shell.Command(context.Background(), command)
 
 	// Capture both stdout and stderr
 	output, err := cmd.CombinedOutput()
@@ -474,6 +524,6 @@ func (ri *ReplInfo) executeShellCommand(command string) {
 	// Display the output
 	if len(output) > 0 {
 		// shell output color
-		fmt.Printf(data.ShellOutputColor+"%s\n"+data.ResetSeq, output)
+		fmt.Printf(data.ShellOutputColor+"%s\n"+data.ResetSeq, service.DecodeShellOutput(output))
 	}
 }
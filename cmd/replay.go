@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+	replayCmd.Flags().BoolP("yolo", "y", false, "Approve tool calls without asking")
+}
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <cassette>",
+	Short: "Re-run a recorded session offline",
+	Long: `Re-runs a run recorded with --record, answering every provider request from the
+cassette instead of the network. The prompt, system prompt, model and tools come from
+the recording; tool calls are executed again on this machine.
+
+Example:
+  gllm "fix the failing test" --record bug.json
+  gllm replay bug.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		yolo, _ := cmd.Flags().GetBool("yolo")
+
+		cassette, err := service.LoadCassette(args[0])
+		if err != nil {
+			return err
+		}
+		run := cassette.Run
+
+		service.StartReplay(cassette)
+		defer service.StopReplay()
+
+		ctx, stopInterrupt := newInterruptContext()
+		defer stopInterrupt()
+
+		model := run.Model
+		model.Key = "replay" // Requests never leave the machine
+
+		op := service.AgentOptions{
+			Ctx:           ctx,
+			Prompt:        run.Prompt,
+			SysPrompt:     run.SysPrompt,
+			ModelInfo:     &model,
+			MaxRecursions: run.MaxRecursions,
+			ThinkingLevel: run.ThinkingLevel,
+			EnabledTools:  run.EnabledTools,
+			Capabilities:  run.Capabilities,
+			YoloMode:      yolo,
+			SessionName:   "", // In-memory, replays must not touch saved sessions
+			Interaction:   service.DefaultInteractionHandler{},
+			AgentName:     run.AgentName,
+			ModelName:     model.Name,
+		}
+
+		err = service.CallAgent(&op)
+		if err != nil && !service.IsUserCancelError(err) {
+			return err
+		}
+		if remaining := cassette.Remaining(); remaining > 0 {
+			util.LogWarnf("Replay finished with %d recorded responses unused, the run diverged from the recording.\n", remaining)
+		}
+		return nil
+	},
+}
+
+// startCassetteRecording starts recording provider traffic to path,
+// the returned func saves the cassette.
+func startCassetteRecording(path string) func() {
+	cassetteRecorder = service.StartRecording(path)
+	return func() {
+		if err := cassetteRecorder.Close(); err != nil {
+			util.LogErrorf("failed to save recording: %v\n", err)
+			return
+		}
+		cassetteRecorder = nil
+		fmt.Printf("Recording saved to %s\n", path)
+	}
+}
@@ -6,9 +6,11 @@ import (
 	stdio "io"
 	"os"
 	"os/exec"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/activebook/gllm/data"
 	"github.com/activebook/gllm/internal/ui"
@@ -22,35 +24,43 @@ import (
 
 var (
 	replCommandMap = map[string]string{
-		"/init":     "Initialize or update agent configuration and GLLM.md",
-		"/exit":     "Exit current session",
-		"/quit":     "Exit current session",
-		"/help":     "Show this help message",
-		"/history":  "Show recent session history",
-		"/clear":    "Clear session history",
-		"/plan":     "Toggle Plan Mode (shift+tab to cycle)",
-		"/yolo":     "Toggle YOLO mode (shift+tab to cycle)",
-		"/model":    "Manage models (list, switch, add, etc.)",
-		"/agent":    "Manage agents (list, switch, add, etc.)",
-		"/search":   "Manage search engines (list, switch, etc.)",
-		"/tools":    "Switch embedding tools",
-		"/mcp":      "Manage MCP servers (list, switch, etc.)",
-		"/skills":   "Manage agent skills (list, switch, install, etc.)",
-		"/memory":   "Manage memory (list, add, clear)",
-		"/session":  "Manage sessions (list, info, remove, etc.)",
-		"/compress": "Compresses the context by replacing it with a summary",
-		"/rename":   "Rename current session using model-inferred title",
-		"/think":    "Set thinking level",
-		"/features": "Switch agent features",
-		"/editor":   "Manage editor or open for multi-line input",
-		"/attach":   "Attach file(s) or URL(s)",
-		"/detach":   "Detach file(s) or URL(s), or 'all'",
-		"/copy":     "Copy the last result or code snippet to clipboard",
-		"/about":    "Show current session settings",
-		"/theme":    "Manage and switch themes",
-		"/verbose":  "Toggle verbose mode",
-		"/workflow": "Manage workflow commands",
-		"/update":   "Check and update to the latest version",
+		"/init":       "Initialize or update agent configuration and GLLM.md",
+		"/exit":       "Exit current session",
+		"/quit":       "Exit current session",
+		"/help":       "Show this help message",
+		"/history":    "Show recent session history",
+		"/clear":      "Clear session history",
+		"/plan":       "Toggle Plan Mode (shift+tab to cycle)",
+		"/act":        "Approve the plan, leave Plan Mode and execute it",
+		"/yolo":       "Toggle YOLO mode (shift+tab to cycle)",
+		"/grants":     "Show or clear tool approvals given for this session",
+		"/related":    "Show the past sessions recalled for the last prompt, or search them: /related [query]",
+		"/model":      "Manage models (list, switch, add, etc.), or go on with another model: /model NAME",
+		"/agent":      "Manage agents (list, switch, add, etc.)",
+		"/search":     "Manage search engines (list, switch, etc.)",
+		"/tools":      "Switch embedding tools",
+		"/mcp":        "Manage MCP servers (list, switch, etc.)",
+		"/skills":     "Manage agent skills (list, switch, install, etc.)",
+		"/memory":     "Manage memory (list, add, clear)",
+		"/session":    "Manage sessions (list, info, remove, etc.)",
+		"/compress":   "Compresses the context by replacing it with a summary",
+		"/rename":     "Rename current session using model-inferred title",
+		"/fork":       "Branch the session from a turn: /fork [turn] [name]",
+		"/think":      "Set thinking level",
+		"/features":   "Switch agent features",
+		"/editor":     "Manage editor or open for multi-line input",
+		"/attach":     "Attach file(s) or URL(s)",
+		"/detach":     "Detach file(s) or URL(s), or 'all'",
+		"/copy":       "Copy the last result or code snippet to clipboard",
+		"/about":      "Show current session settings",
+		"/theme":      "Manage and switch themes",
+		"/verbose":    "Toggle verbose mode",
+		"/tools-view": "Switch the display of tool calls: full, compact or off",
+		"/redact":     "Manage redaction of secrets in outgoing prompts",
+		"/context":    "Show or toggle loaded GLLM.md / AGENTS.md files",
+		"/system":     "Show the assembled system prompt (show, order)",
+		"/workflow":   "Manage workflow commands",
+		"/update":     "Check and update to the latest version",
 	}
 
 	replSpecMap = map[string]string{
@@ -140,6 +150,11 @@ func runCommandCtx(ctx context.Context, cmd *cobra.Command, args []string, w ...
 			switch f.Value.Type() {
 			case "bool", "string", "int", "int64", "float32", "float64":
 				_ = f.Value.Set(f.DefValue)
+			case "stringSlice", "stringArray":
+				// Slice flags append on Set, so clear them instead
+				if sv, ok := f.Value.(pflag.SliceValue); ok {
+					_ = sv.Replace(nil)
+				}
 			}
 			// Reset the changed state so pflag treats it as a fresh parse
 			f.Changed = false
@@ -218,7 +233,11 @@ func (ri *ReplInfo) handleCommand(cmd *cobra.Command, input string) {
 		ri.Files = []*service.FileData{}
 
 	case "/model":
-		runCommand(modelCmd, parts[1:])
+		if len(parts) == 2 && isSessionModelSwitch(parts[1]) {
+			switchSessionModel(cmd, parts[1])
+		} else {
+			runCommand(modelCmd, parts[1:])
+		}
 
 	case "/agent":
 		runCommand(agentCmd, parts[1:])
@@ -241,9 +260,18 @@ func (ri *ReplInfo) handleCommand(cmd *cobra.Command, input string) {
 	case "/yolo":
 		switchYoloMode(cmd, showYoloModeStatus)
 
+	case "/grants":
+		handleToolGrants(cmd, parts[1:])
+
+	case "/related":
+		handleRelatedSessions(cmd, parts[1:])
+
 	case "/plan":
 		switchPlanMode(cmd, showPlanModeStatus)
 
+	case "/act":
+		ri.approvePlan(cmd, strings.Join(parts[1:], " "))
+
 	case "/session":
 		runCommand(sessionCmd, parts[1:])
 
@@ -253,6 +281,9 @@ func (ri *ReplInfo) handleCommand(cmd *cobra.Command, input string) {
 	case "/rename":
 		runCommand(sessionRenameCurrentCmd, parts[1:])
 
+	case "/fork":
+		runCommand(sessionForkCurrentCmd, parts[1:])
+
 	case "/think":
 		runCommand(thinkCmd, parts[1:])
 
@@ -292,6 +323,18 @@ func (ri *ReplInfo) handleCommand(cmd *cobra.Command, input string) {
 	case "/verbose":
 		runCommand(verboseCmd, parts[1:])
 
+	case "/tools-view":
+		switchToolsView(cmd, parts[1:])
+
+	case "/redact":
+		runCommand(redactCmd, parts[1:])
+
+	case "/context":
+		runCommand(contextCmd, parts[1:])
+
+	case "/system":
+		runCommand(systemCmd, parts[1:])
+
 	case "/workflow":
 		runCommand(workflowCmd, parts[1:])
 
@@ -467,10 +510,10 @@ func (ri *ReplInfo) attachFiles(cmd *cobra.Command, input string) {
 				go func(filePath string) {
 					defer wg.Done()
 
-					// Verify file exists and is not a directory
-					if !checkIsLink(filePath) {
-						fileInfo, err := os.Stat(filePath)
-						if err != nil {
+					// Verify file exists, a glob or a directory is expanded
+					isPattern := service.IsAttachmentPattern(filePath)
+					if !checkIsLink(filePath) && !isPattern {
+						if _, err := os.Stat(filePath); err != nil {
 							if os.IsNotExist(err) {
 								util.LogErrorf("File not found: %s\n", filePath)
 							} else {
@@ -478,10 +521,6 @@ func (ri *ReplInfo) attachFiles(cmd *cobra.Command, input string) {
 							}
 							return
 						}
-						if fileInfo.IsDir() {
-							util.LogErrorf("Cannot attach directory: %s\n", filePath)
-							return
-						}
 					}
 					// Check if file is already attached
 					mu.Lock()
@@ -500,15 +539,15 @@ func (ri *ReplInfo) attachFiles(cmd *cobra.Command, input string) {
 					}
 
 					// Process the attachment
-					file := ProcessAttachment(filePath)
-					if file == nil {
+					files := ProcessAttachments(filePath)
+					if len(files) == 0 {
 						util.LogErrorf("Error loading attachment: %s\n", filePath)
 						return
 					}
 
-					// Append the file to the list of attachments
+					// Append the files to the list of attachments
 					mu.Lock()
-					ri.Files = append(ri.Files, file)
+					ri.Files = append(ri.Files, files...)
 					mu.Unlock()
 					util.Printf(cmd, "Attachment loaded: %s\n", filePath)
 				}(filePath)
@@ -590,6 +629,12 @@ func (ri *ReplInfo) executeWorkflow(command string, parts []string) bool {
 		return false // Not a workflow command
 	}
 
+	// A batch workflow submits the jobs file it is given
+	if wm.GetWorkflowBatch(name) {
+		runCommand(batchCmd, append([]string{"submit", "--workflow", name}, parts[1:]...))
+		return true
+	}
+
 	// Extract any arguments passed to the workflow command
 	var userArgs string
 	if len(parts) > 1 {
@@ -602,6 +647,10 @@ func (ri *ReplInfo) executeWorkflow(command string, parts []string) bool {
 
 	// Set the content as input to be processed by the agent
 	ri.EditorInput = input
+	ri.Notify, ri.NotifyTitle = wm.GetWorkflowNotify(name), "/"+name
+	if action := wm.GetWorkflowApprove(name); action != "" {
+		ri.Guideline = service.WorkflowApprovalGuideline(action)
+	}
 	return true
 }
 
@@ -669,6 +718,131 @@ func showYoloModeStatus(cmd *cobra.Command, yolo bool) {
 	}
 }
 
+// switchToolsView sets the display of tool calls for the session, without a mode it goes on
+// to the next one.
+func switchToolsView(cmd *cobra.Command, args []string) {
+	mode := ""
+	if len(args) > 0 {
+		mode = strings.ToLower(args[0])
+		if err := service.ValidateShowTools(mode); err != nil || mode == "" {
+			util.Errorf(cmd, "Unknown display '%s', expected %s.\n", args[0], strings.Join(service.ShowToolsModes, ", "))
+			return
+		}
+	} else {
+		current := showToolsFlag
+		if agent, err := EnsureActiveAgent(); err == nil && current == "" {
+			current = agent.ShowTools
+		}
+		// full → compact → off → full, the default boxes go on to full
+		next := service.ShowToolsModes[0]
+		if i := slices.Index(service.ShowToolsModes, current); i >= 0 {
+			next = service.ShowToolsModes[(i+1)%len(service.ShowToolsModes)]
+		}
+		mode = next
+	}
+	showToolsFlag = mode
+	util.Printf(cmd, "Tool calls display: %s%s%s\n", data.KeyColor, mode, data.ResetSeq)
+}
+
+// handleToolGrants lists the tools and command prefixes approved at confirmation prompts,
+// or revokes them with "clear".
+func handleToolGrants(cmd *cobra.Command, args []string) {
+	grants := data.GetSessionToolGrants()
+	if len(args) > 0 {
+		if args[0] != "clear" {
+			util.Printf(cmd, "%sUnknown argument: %s. Use '/grants' or '/grants clear'.%s\n", data.StatusErrorColor, args[0], data.ResetSeq)
+			return
+		}
+		grants.Reset()
+		util.Println(cmd, "Tool approvals for this session cleared.")
+		return
+	}
+	tools, prefixes := grants.List()
+	if len(tools) == 0 && len(prefixes) == 0 {
+		util.Println(cmd, "No tools approved for this session.")
+		return
+	}
+	for _, name := range tools {
+		util.Printf(cmd, "  tool     %s%s%s\n", data.KeyColor, name, data.ResetSeq)
+	}
+	for _, prefix := range prefixes {
+		util.Printf(cmd, "  command  %s%s ...%s\n", data.KeyColor, prefix, data.ResetSeq)
+	}
+}
+
+// handleRelatedSessions shows the past sessions recalled for the last prompt, or the
+// past sessions related to a query.
+func handleRelatedSessions(cmd *cobra.Command, args []string) {
+	var related []service.RecalledSession
+	if len(args) > 0 {
+		var err error
+		related, _, err = service.FindRelatedSessions(context.Background(), strings.Join(args, " "), sessionName, 5)
+		if err != nil {
+			util.Printf(cmd, "%sFailed to search past sessions: %v%s\n", data.StatusErrorColor, err, data.ResetSeq)
+			return
+		}
+	} else {
+		recall := service.LastSessionRecall()
+		if recall == nil {
+			util.Println(cmd, "Nothing recalled yet. Enable the Session Recall feature with /features, or search with '/related QUERY'.")
+			return
+		}
+		related = recall.Sessions
+	}
+	if len(related) == 0 {
+		util.Println(cmd, "No related past sessions.")
+		return
+	}
+	for _, r := range related {
+		util.Printf(cmd, "%s%s%s  %s  score %.2f\n", data.KeyColor, r.Session, data.ResetSeq, time.Unix(r.ModTime, 0).Format("2006-01-02"), r.Score)
+		for _, line := range strings.Split(r.Snippet, "\n") {
+			util.Printf(cmd, "    %s\n", line)
+		}
+	}
+}
+
+// isSessionModelSwitch reports whether /model NAME names a model rather than a subcommand.
+func isSessionModelSwitch(name string) bool {
+	for _, sub := range modelCmd.Commands() {
+		if sub.Name() == name || sub.HasAlias(name) {
+			return false
+		}
+	}
+	return data.NewConfigStore().GetModel(name) != nil
+}
+
+// switchSessionModel goes on with the conversation on another model, its history converted
+// to the format of the new provider. The agent keeps its model for other sessions.
+func switchSessionModel(cmd *cobra.Command, name string) {
+	previous := data.GetModelInSession()
+	data.SetModelInSession(name)
+	agent, err := EnsureActiveAgent()
+	if err != nil {
+		data.SetModelInSession(previous)
+		util.Printf(cmd, "%sFailed to switch the model: %v%s\n", data.StatusErrorColor, err, data.ResetSeq)
+		return
+	}
+
+	ui.GetIndicator().Start(ui.IndicatorConvertingSession)
+	sw, err := service.SwitchSessionModel(agent, sessionName)
+	ui.GetIndicator().Stop()
+	if err != nil {
+		data.SetModelInSession(previous)
+		util.Printf(cmd, "%sFailed to carry the conversation over to %s: %v%s\n", data.StatusErrorColor, name, err, data.ResetSeq)
+		return
+	}
+
+	util.Printf(cmd, "Switched this session to model '%s' (%s).\n", agent.Model.Name, agent.Model.Model)
+	switch {
+	case sw.Messages == 0:
+	case sw.Converted:
+		util.Printf(cmd, "Converted %d messages and %d tool calls from the %s format to %s.\n", sw.Messages, sw.ToolCalls, sw.From, sw.To)
+	default:
+		util.Printf(cmd, "Kept %d messages and %d tool calls, the history is already in the %s format.\n", sw.Messages, sw.ToolCalls, sw.To)
+	}
+	util.Printf(cmd, "Agent '%s' keeps its model for other sessions, use '/model switch %s' to change it.\n", agent.Name, name)
+}
+
 // switchYoloMode toggles YOLO mode
 func switchYoloMode(cmd *cobra.Command, showStatus func(*cobra.Command, bool)) {
 	yolo := data.GetYoloModeInSession()
@@ -715,6 +889,35 @@ func switchPlanMode(cmd *cobra.Command, showStatus func(*cobra.Command, bool)) {
 	}
 }
 
+// approvePlan leaves plan mode and queues the approved plan as the next prompt,
+// so the agent starts executing it with all tools unlocked.
+func (ri *ReplInfo) approvePlan(cmd *cobra.Command, extra string) {
+	if !data.GetPlanModeInSession() {
+		util.LogWarnln("Not in Plan Mode, use /plan to start planning first.")
+		return
+	}
+
+	// Read the plan before leaving plan mode, the lookup is bound to the plan mode start
+	path, plan, err := data.GetLatestPlan()
+	if err != nil {
+		util.LogWarnf("Failed to read the plan: %v\n", err)
+	}
+	switchPlanMode(cmd, showPlanModeStatus)
+
+	var sb strings.Builder
+	sb.WriteString(data.PlanApprovedPrompt)
+	if path != "" {
+		util.Printf(cmd, "Approved plan: %s\n", path)
+		sb.WriteString(fmt.Sprintf("\n\n<approved_plan path=%q>\n%s\n</approved_plan>", path, strings.TrimSpace(plan)))
+	} else {
+		sb.WriteString(" Follow the plan you presented above.")
+	}
+	if extra != "" {
+		sb.WriteString("\n\n" + extra)
+	}
+	ri.EditorInput = sb.String()
+}
+
 /**
  * Switches the session mode to the next mode in the cycle [normal->plan->yolo->normal].
  * If plan mode is enabled, it will switch to plan mode.
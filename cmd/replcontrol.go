@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/internal/ui"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+)
+
+// replControl queues the inputs sent to the REPL through its control socket, see 'gllm send'.
+// A nil *replControl has no socket.
+type replControl struct {
+	mu     sync.Mutex
+	queue  []string
+	busy   bool
+	server *service.ControlServer
+}
+
+// startReplControl listens on the control socket of the REPL, the REPL works without it.
+func startReplControl() *replControl {
+	c := &replControl{}
+	server, err := service.StartControlServer(c.handle)
+	if err != nil {
+		util.LogWarnf("Control socket unavailable: %v\n", err)
+		return nil
+	}
+	c.server = server
+	util.LogDebugf("Control socket: %s\n", server.Path())
+	return c
+}
+
+func (c *replControl) close() {
+	if c != nil {
+		c.server.Close()
+	}
+}
+
+func (c *replControl) handle(req service.ControlRequest) service.ControlResponse {
+	switch req.Action {
+	case service.ControlActionStatus:
+		return service.ControlResponse{OK: true, Status: c.status()}
+
+	case service.ControlActionPrompt, service.ControlActionCommand:
+		text := strings.TrimSpace(req.Text)
+		if text == "" {
+			return service.ControlResponse{Error: "nothing to send"}
+		}
+		if req.Action == service.ControlActionCommand && !strings.HasPrefix(text, "/") {
+			text = "/" + text
+		}
+		c.mu.Lock()
+		c.queue = append(c.queue, text)
+		pending, busy := len(c.queue), c.busy
+		c.mu.Unlock()
+		if !busy {
+			// The input being typed gives way when it is empty
+			ui.SendEvent(ui.QueuedInputMsg{Pending: pending})
+		}
+		return service.ControlResponse{OK: true, Status: c.status()}
+
+	case service.ControlActionInsert:
+		if c.isBusy() {
+			return service.ControlResponse{Error: "the REPL is running a prompt, send the text as a prompt to queue it"}
+		}
+		ui.SendEvent(ui.PasteResultMsg{PastedText: req.Text})
+		return service.ControlResponse{OK: true, Status: c.status()}
+
+	default:
+		return service.ControlResponse{Error: fmt.Sprintf("unknown action '%s'", req.Action)}
+	}
+}
+
+func (c *replControl) status() *service.ControlStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	status := &service.ControlStatus{
+		PID:     os.Getpid(),
+		Session: sessionName,
+		Agent:   data.NewConfigStore().GetActiveAgentName(),
+		Busy:    c.busy,
+		Queued:  len(c.queue),
+	}
+	status.WorkDir, _ = os.Getwd()
+	return status
+}
+
+func (c *replControl) isBusy() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.busy
+}
+
+// setBusy records whether the REPL runs an input or waits for one.
+func (c *replControl) setBusy(busy bool) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.busy = busy
+	c.mu.Unlock()
+}
+
+// next takes the oldest queued input.
+func (c *replControl) next() (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.queue) == 0 {
+		return "", false
+	}
+	text := c.queue[0]
+	c.queue = c.queue[1:]
+	return text, true
+}
+
+// pending returns the number of queued inputs.
+func (c *replControl) pending() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.queue)
+}
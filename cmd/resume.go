@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/io"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(resumeTasksCmd)
+	resumeTasksCmd.Flags().BoolP("list", "l", false, "List the interrupted batches instead of resuming one")
+	resumeTasksCmd.Flags().Bool("discard", false, "Remove the batch instead of resuming it")
+}
+
+var resumeTasksCmd = &cobra.Command{
+	Use:   "resume-tasks [BATCH]",
+	Short: "Run again the sub-agent tasks of a batch that did not complete",
+	Long: `Every spawn of sub-agents is queued on disk until all its tasks complete. When
+gllm stops in the middle of a batch, resume-tasks restores the results of the
+tasks that completed and runs the others again, in the working directory and
+under the session of the batch. BATCH is the latest batch by default.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		list, _ := cmd.Flags().GetBool("list")
+		discard, _ := cmd.Flags().GetBool("discard")
+
+		if list {
+			batches, err := data.ListTaskBatches()
+			if err != nil {
+				util.Errorf(cmd, "%v\n", err)
+				return
+			}
+			if len(batches) == 0 {
+				util.Println(cmd, "No interrupted sub-agent batches.")
+				return
+			}
+			for _, b := range batches {
+				session := ""
+				if b.Session != "" {
+					session = "  session " + b.Session
+				}
+				util.Printf(cmd, "%s%s%s  %s  %d of %d task(s) to run%s\n  %s\n", data.KeyColor, b.ID, data.ResetSeq,
+					b.Created.Format(time.DateTime), b.Incomplete(), len(b.Tasks), session, b.WorkDir)
+			}
+			return
+		}
+
+		batch, err := loadTaskBatch(args)
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if discard {
+			if err := data.RemoveTaskBatch(batch.ID); err != nil {
+				util.Errorf(cmd, "%v\n", err)
+				return
+			}
+			util.Printf(cmd, "Batch %s discarded.\n", batch.ID)
+			return
+		}
+
+		// Relative paths of the instructions and the artifacts are of the working directory of the batch
+		if batch.WorkDir != "" {
+			if err := os.Chdir(batch.WorkDir); err != nil {
+				util.Errorf(cmd, "Failed to enter the working directory of the batch: %v\n", err)
+				return
+			}
+		}
+
+		util.Printf(cmd, "Resuming batch %s: %d of %d task(s) to run.\n", batch.ID, batch.Incomplete(), len(batch.Tasks))
+		ctx, stopInterrupt := newInterruptContext()
+		defer stopInterrupt()
+
+		executor := service.NewSubAgentExecutor(data.NewSharedState(), batch.Session, io.NewStdOutput(), nil, nil)
+		defer executor.Shutdown()
+		responses, err := executor.ResumeBatch(ctx, batch)
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+		}
+		util.Println(cmd, executor.FormatSummary(responses))
+	},
+}
+
+// loadTaskBatch reads the batch given as argument, the latest one when none is given.
+func loadTaskBatch(args []string) (*data.TaskBatch, error) {
+	if len(args) == 1 {
+		return data.LoadTaskBatch(args[0])
+	}
+	batches, err := data.ListTaskBatches()
+	if err != nil {
+		return nil, err
+	}
+	if len(batches) == 0 {
+		return nil, fmt.Errorf("no interrupted sub-agent batches")
+	}
+	return &batches[0], nil
+}
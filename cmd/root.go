@@ -14,16 +14,24 @@ import (
 	log "github.com/sirupsen/logrus" // Import logrus
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"golang.org/x/term"
 )
 
 var (
 	versionFlag bool // To hold the version flag value
 	debugMode   bool // Flag to enable debug logging
-
-	agentName   string   // gllm "What is Go?" -agent(-g) plan
-	attachments []string // gllm "Summarize this" --attachment(-a) report.txt
-	sessionName string   // gllm --session(-s) "My Session"
-	yoloFlag    bool     // gllm -y, --yolo enable yolo mode (non-interactive)
+	noCache     bool // Skip the model response cache for this run
+	plainFlag   bool // No colors, markdown rendering or spinner, for piping
+	profileFlag bool // Print the time taken by each step before the first model request
+
+	agentName          string   // gllm "What is Go?" -agent(-g) plan
+	attachments        []string // gllm "Summarize this" --attachment(-a) report.txt
+	sessionName        string   // gllm --session(-s) "My Session"
+	yoloFlag           bool     // gllm -y, --yolo enable yolo mode (non-interactive)
+	recordFile         string   // gllm "Fix it" --record bug.json
+	showToolsFlag      string   // gllm --show-tools compact, display of tool calls
+	maxCostFlag        float64  // gllm --max-cost 0.50, cost budget of this invocation in USD
+	showCandidatesFlag bool     // gllm --show-candidates, print every answer of an agent with best_of
 
 	// Global cmd instance, to be used by subcommands
 	rootCmd = &cobra.Command{
@@ -54,9 +62,15 @@ Configure your API keys and preferred models, then start chatting or executing c
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			// This ensures setupLogging runs *after* flags are parsed and *after* initConfig
 			setupLogging()
+			if profileFlag {
+				service.EnableStartupProfile()
+			}
+			if noCache {
+				service.DisableResponseCache()
+			}
 
 			// Check if we are running a help/version command or init itself
-			if cmd.Name() == "help" || cmd.Name() == "init" || cmd.Name() == "version" || versionFlag {
+			if cmd.Name() == "help" || cmd.Name() == "init" || cmd.Name() == "version" || cmd.Name() == "doctor" || isCrashCommand(cmd) || versionFlag || isCompletionCommand(cmd) {
 				return nil
 			}
 
@@ -83,6 +97,7 @@ Configure your API keys and preferred models, then start chatting or executing c
 
 				return fmt.Errorf("configuration required to proceed. Run 'gllm init' to setup")
 			}
+			service.MarkStartup(service.StartupConfigLoaded)
 
 			return nil
 		},
@@ -165,10 +180,17 @@ Configure your API keys and preferred models, then start chatting or executing c
 			}
 
 			ui.GetIndicator().Stop()
+			service.MarkStartup(service.StartupAgentResolved)
+
+			// Record provider traffic for offline replay
+			if recordFile != "" {
+				defer startCassetteRecording(recordFile)()
+			}
 
 			// Call your LLM service here
 			// Call agent using the shared runner, passing nil for SharedState (single turn)
 			err := RunAgent(prompt, "", files, sessionName, "", nil)
+			service.RunSessionEndHooks(sessionName)
 			if err != nil {
 				util.Errorf(cmd, "%v\n", err)
 				return
@@ -180,6 +202,11 @@ Configure your API keys and preferred models, then start chatting or executing c
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	// A crash restores the terminal and leaves a report instead of a broken shell
+	service.SetAppVersion(version)
+	saveTerminalState()
+	defer recoverCrash()
+
 	// Start the UI event bus listener before executing any commands
 	ui.StartUIEventListener()
 
@@ -193,7 +220,15 @@ func Execute() {
 
 	// Actually, we don't need to Close it, because the process would exit
 	// defer service.GetMCPClient().Close()
-	if err := rootCmd.Execute(); err != nil {
+	registerCompletions()
+	err := rootCmd.Execute()
+	// Language servers are separate processes, stop them before leaving
+	service.ShutdownLSPClients()
+	service.ShutdownPlugins()
+	if report := service.StartupProfileReport(); report != "" {
+		fmt.Fprint(os.Stderr, report)
+	}
+	if err != nil {
 		util.LogErrorf("'%s'\n", err)
 		os.Exit(1)
 	}
@@ -224,7 +259,10 @@ func addCompletionCommand() {
 		ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
 		Args:      cobra.MaximumNArgs(1), // Changed from ExactArgs(1) to MaximumNArgs(1)
 		Long: `Generate shell completion scripts for gllm.
-Supported shells: bash, zsh, fish, powershell`,
+Supported shells: bash, zsh, fish, powershell
+
+Agent, model, workflow, MCP server and session names are read from the config
+each time you press tab, so the script does not need to be regenerated when they change.`,
 		Example: `  # Generate bash completion
   gllm completion bash > ~/.gllm-completion.bash
 
@@ -233,6 +271,9 @@ Supported shells: bash, zsh, fish, powershell`,
 
   # Generate fish completion
   gllm completion fish > ~/.config/fish/completions/gllm.fish
+
+  # Load PowerShell completion in the current session
+  gllm completion powershell | Out-String | Invoke-Expression
   
   # Add this to your ~/.bash_profile or ~/.bashrc
   echo 'source ~/.gllm-completion.bash' >> ~/.bash_profile `,
@@ -316,6 +357,9 @@ func init() {
 	// Define flags
 	//rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", fmt.Sprintf("config file (default is %s)", appConfigFilePath))
 	rootCmd.PersistentFlags().BoolVarP(&debugMode, "debug", "d", false, "Enable debug logging (overrides config file level)")
+	rootCmd.PersistentFlags().BoolVar(&noCache, "no-cache", false, "Send every model request, even when the response cache has it")
+	rootCmd.PersistentFlags().BoolVar(&plainFlag, "plain", false, "Print plain text without colors, markdown rendering or spinner, for piping")
+	rootCmd.PersistentFlags().BoolVar(&profileFlag, "profile-startup", false, "Print the time taken by each step before the first model request")
 
 	// Disable the default completion command
 	rootCmd.CompletionOptions.DisableDefaultCmd = true
@@ -325,9 +369,13 @@ func init() {
 
 	// Define the flags
 	rootCmd.Flags().StringVarP(&agentName, "agent", "g", "", "Switch to the agent to use")
-	rootCmd.Flags().StringSliceVarP(&attachments, "attachment", "a", []string{}, "Specify file(s), image(s), url(s) to append to the prompt")
+	rootCmd.Flags().StringSliceVarP(&attachments, "attachment", "a", []string{}, "Specify file(s), image(s), url(s), glob(s) or directories to append to the prompt")
 	rootCmd.Flags().StringVarP(&sessionName, "session", "s", "", "Specify a session name or index to track")
 	rootCmd.Flags().BoolVarP(&yoloFlag, "yolo", "y", false, "Enable yolo mode (non-interactive)")
+	rootCmd.Flags().StringVar(&recordFile, "record", "", "Record provider responses to a cassette file for 'gllm replay'")
+	rootCmd.Flags().StringVar(&showToolsFlag, "show-tools", "", "Display of tool calls: full, compact or off (overrides the agent's show_tools)")
+	rootCmd.Flags().Float64Var(&maxCostFlag, "max-cost", 0, "Cost budget of this invocation in USD, asks before going over it (needs the model prices)")
+	rootCmd.Flags().BoolVar(&showCandidatesFlag, "show-candidates", false, "Print every candidate answer of an agent with best_of, not only the best")
 	rootCmd.Flags().BoolVarP(&versionFlag, "version", "v", false, "Print the version number of gllm")
 
 	// *** Placeholder for Log Configuration ***
@@ -351,8 +399,29 @@ func initConfig() {
 		return
 	}
 
+	// Upgrade the layouts older versions wrote, logging isn't set up yet
+	applied, backupDir, err := store.Migrate(service.GetAllOpenTools())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		if backupDir != "" {
+			fmt.Fprintf(os.Stderr, "The previous config is kept in %s\n", backupDir)
+		}
+	} else if len(applied) > 0 {
+		fmt.Fprintf(os.Stderr, "Upgraded the config to version %d, the previous one is kept in %s:\n", data.CurrentConfigVersion, backupDir)
+		for _, note := range applied {
+			fmt.Fprintf(os.Stderr, "  - %s\n", note)
+		}
+	}
+
 	// Load the configured theme (or default to Dracula if not set)
 	data.LoadTheme(data.GetThemeFromConfig())
+
+	// Colors only go to a terminal, and never when NO_COLOR is set (https://no-color.org)
+	if plainFlag {
+		data.SetPlainOutput()
+	} else if os.Getenv("NO_COLOR") != "" || !term.IsTerminal(int(os.Stdout.Fd())) {
+		data.DisableColors()
+	}
 }
 
 // setupLogging configures the global logger based on Viper settings and flags.
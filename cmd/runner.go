@@ -1,8 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
 	"sync"
+	"time"
 
 	"github.com/activebook/gllm/data"
 	"github.com/activebook/gllm/internal/ui"
@@ -17,7 +21,27 @@ func EnsureActiveAgent() (*data.AgentConfig, error) {
 	if agent == nil {
 		return nil, fmt.Errorf("no active agent found")
 	}
+	// A model picked with /model only lasts for the session
+	if name := data.GetModelInSession(); name != "" {
+		if model := store.GetModel(name); model != nil {
+			agent.Model = *model
+		}
+	}
+	return ensureAgentModel(store, agent)
+}
 
+// EnsureAgent returns the agent of the name ready to run, without making it the active agent.
+func EnsureAgent(name string) (*data.AgentConfig, error) {
+	store := data.NewConfigStore()
+	agent := store.GetAgent(name)
+	if agent == nil {
+		return nil, fmt.Errorf("agent %s does not exist", name)
+	}
+	return ensureAgentModel(store, agent)
+}
+
+// ensureAgentModel checks the model of the agent, detecting what it misses.
+func ensureAgentModel(store *data.ConfigStore, agent *data.AgentConfig) (*data.AgentConfig, error) {
 	// Auto-detect provider if not set
 	if agent.Model.Provider == "" {
 		agent.Model.Provider = service.DetectModelProvider(agent.Model.Endpoint, agent.Model.Model)
@@ -56,6 +80,13 @@ func RunAgent(prompt string, guideline string, files []*service.FileData, sessio
 		defer sharedState.Clear() // Clean up on session end
 	}
 
+	// Ctrl+C cancels the in-flight generation instead of killing the process
+	ctx, stopInterrupt := newInterruptContext()
+	defer stopInterrupt()
+
+	// Tell the desktop when a long generation finishes or waits for the user
+	notify := newNotifyTimer()
+
 	for {
 		// Get YOLO mode
 		yolo := data.GetYoloModeInSession()
@@ -78,12 +109,14 @@ func RunAgent(prompt string, guideline string, files []*service.FileData, sessio
 		if err := service.EnsureSessionCompatibility(agent, sessionName, hook); err != nil {
 			return err
 		}
+		service.MarkStartup(service.StartupSessionChecked)
 
 		// Start indeterminate progress bar
 		ui.GetIndicator().Start("")
 
 		// Build Final Prompt (Input + @ Processing)
 		finalPrompt := buildFinalPrompt(prompt, guideline)
+		service.MarkStartup(service.StartupPromptBuilt)
 
 		// Load MCP config
 		mcpStore := data.NewMCPStore()
@@ -96,39 +129,61 @@ func RunAgent(prompt string, guideline string, files []*service.FileData, sessio
 		ui.GetIndicator().Stop()
 
 		// Default interaction handler (using inner event bus)
-		interaction := service.DefaultInteractionHandler{}
+		var interaction service.InteractionHandler = service.DefaultInteractionHandler{}
+		// Without a terminal to prompt in, tool calls are decided by the approval backend
+		if isHeadless() {
+			interaction = service.NewApprovalHandler(data.GetSettingsStore().GetApprovalSettings(), nil, sessionName, agent.Name)
+		}
+		interaction = notify.Wrap(interaction)
 
 		// Prepare Agent Options
 		op := service.AgentOptions{
-			Prompt:        finalPrompt,
-			SysPrompt:     agent.SystemPrompt,
-			Files:         files,
-			ModelInfo:     &agent.Model,
-			MaxRecursions: agent.MaxRecursions,
-			ThinkingLevel: agent.Think,
-			EnabledTools:  agent.Tools,
-			Capabilities:  agent.Capabilities,
-			YoloMode:      yolo,
-			OutputFile:    outputFile,
-			QuietMode:     false,
-			SessionName:   sessionName,
-			MCPConfig:     mcpConfig,
-			Interaction:   interaction,
+			Ctx:            ctx,
+			Prompt:         finalPrompt,
+			SysPrompt:      agent.SystemPrompt,
+			Files:          files,
+			ModelInfo:      &agent.Model,
+			MaxRecursions:  agent.MaxRecursions,
+			ThinkingLevel:  agent.Think,
+			EnabledTools:   agent.Tools,
+			AutoFormat:     agent.AutoFormat,
+			Shell:          agent.Shell,
+			InjectionGuard: agent.InjectionGuard,
+			Paths:          agent.Paths,
+			ShowTools:      showToolsMode(agent),
+			Capabilities:   agent.Capabilities,
+			Budget:         invocationBudget(),
+			SessionBudget:  agent.BudgetUSD,
+			BestOf:         agent.BestOf,
+			ShowCandidates: showCandidatesFlag,
+			YoloMode:       yolo,
+			OutputFile:     outputFile,
+			QuietMode:      false,
+			SessionName:    sessionName,
+			MCPConfig:      mcpConfig,
+			Interaction:    interaction,
 			// Sub-agent orchestration
 			SharedState: sharedState,
 			AgentName:   agent.Name,
 			ModelName:   agent.Model.Name,
 		}
 
+		if cassetteRecorder != nil {
+			cassetteRecorder.RecordRun(&op)
+		}
+
 		// Execute
 		err = service.CallAgent(&op)
+		if !service.IsSwitchAgentError(err) && ctx.Err() == nil {
+			notify.Done(agent.Name, err)
+		}
 		if err != nil {
 			// Switch agent signal
 			if service.IsSwitchAgentError(err) {
 				switchErr, _ := service.AsSwitchAgentError(err)
 				util.LogInfof("Already switched to agent [%s].\n", switchErr.TargetAgent)
 				// Set instruction, shouldn't use the old prompt
-				prompt = switchErr.Instruction
+				prompt = switchErr.Prompt()
 				util.LogDebugf("Switch agent instruction: %s\n", prompt)
 				// Clearup files
 				files = nil
@@ -156,6 +211,40 @@ func RunAgent(prompt string, guideline string, files []*service.FileData, sessio
 	return nil
 }
 
+// cassetteRecorder is set while provider traffic is being recorded with --record
+var cassetteRecorder *service.Recorder
+
+// newInterruptContext returns a context that is cancelled by the first Ctrl+C.
+// A second Ctrl+C before the returned stop func is called exits the process.
+func newInterruptContext() (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, os.Interrupt)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-sigCh:
+			cancel()
+			util.LogWarnf("Interrupted, press Ctrl+C again to exit.\n")
+		case <-done:
+			return
+		}
+		select {
+		case <-sigCh:
+			os.Exit(130)
+		case <-done:
+		}
+	}()
+
+	stop := func() {
+		signal.Stop(sigCh)
+		close(done)
+		cancel()
+	}
+	return ctx, stop
+}
+
 // buildFinalPrompt combines user input, injects registered context providers, and processes @ references
 func buildFinalPrompt(input string, guideline string) string {
 	tb := TextBuilder{}
@@ -167,8 +256,9 @@ func buildFinalPrompt(input string, guideline string) string {
 	}
 
 	// 2. Collect @ reference context from the user input
+	// Context already in the input, such as a handoff from another agent, is not searched
 	atRefProcessor := service.NewAtRefProcessor()
-	if atCtx, err := atRefProcessor.ParseAndCollect(input); err == nil && atCtx != "" {
+	if atCtx, err := atRefProcessor.ParseAndCollect(service.StripInlineContext(input)); err == nil && atCtx != "" {
 		contextBlobs = append(contextBlobs, atCtx)
 	} else if err != nil {
 		util.LogWarnf("Skip processing @ references in prompt: %v\n", err)
@@ -195,13 +285,12 @@ func buildFinalPrompt(input string, guideline string) string {
 // It uses a WaitGroup to manage goroutines and a channel to collect results safely.
 func BatchAttachments(attachments []string) (files []*service.FileData) {
 	var wg sync.WaitGroup
-	filesCh := make(chan *service.FileData, len(attachments))
+	filesCh := make(chan []*service.FileData, len(attachments))
 	for _, attachment := range attachments {
 		wg.Add(1)
 		go func(att string) {
 			defer wg.Done()
-			fileData := ProcessAttachment(att)
-			if fileData != nil {
+			if fileData := ProcessAttachments(att); len(fileData) > 0 {
 				filesCh <- fileData
 			}
 		}(attachment)
@@ -209,12 +298,56 @@ func BatchAttachments(attachments []string) (files []*service.FileData) {
 	wg.Wait()
 	close(filesCh)
 	for fileData := range filesCh {
-		files = append(files, fileData)
+		files = append(files, fileData...)
 	}
 	return files
 }
 
+// ProcessAttachments processes an attachment that may be a glob or a directory, which
+// expands into a context block of its text files and its images and documents.
+func ProcessAttachments(path string) []*service.FileData {
+	if service.IsURLAttachment(path) {
+		// Fetch like the model would, through the proxy of its model
+		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+		defer cancel()
+		fileData, err := service.FetchURLAttachment(ctx, path, attachmentNetwork())
+		if err != nil {
+			util.LogErrorf("Error fetching attachment[%s]: %v\n", path, err)
+			return nil
+		}
+		return []*service.FileData{fileData}
+	}
+	if !service.IsAttachmentPattern(path) {
+		if fileData := ProcessAttachment(path); fileData != nil {
+			return []*service.FileData{fileData}
+		}
+		return nil
+	}
+	set, err := service.ExpandAttachment(path)
+	if err != nil {
+		util.LogErrorf("Error expanding attachment[%s]: %v\n", path, err)
+		return nil
+	}
+	util.LogDebugf("%s", set.Manifest())
+	for i, skipped := range set.Skipped {
+		if i == 5 {
+			util.LogWarnf("Attachment %s: %d more files skipped\n", path, len(set.Skipped)-i)
+			break
+		}
+		util.LogWarnf("Attachment %s: skipped %s, %s\n", path, skipped.Path, skipped.Reason)
+	}
+	return set.FileData()
+}
+
 // Processes a single attachment (file or stdin marker)
+// attachmentNetwork returns the proxy and TLS settings of the active agent's model.
+func attachmentNetwork() service.NetworkSettings {
+	if agent := data.NewConfigStore().GetActiveAgent(); agent != nil {
+		return service.ModelNetworkSettings(&agent.Model)
+	}
+	return service.NetworkSettings{}
+}
+
 func ProcessAttachment(path string) *service.FileData {
 	// Handle stdin or regular file
 	data, err := readContentFromPath(path)
@@ -257,6 +390,31 @@ func StartLoadMCPServer(agent *data.AgentConfig) {
 		mc.PreloadAsync(mcpConfig, service.MCPLoadOption{
 			LoadAll:   false,
 			LoadTools: true,
+			Lazy:      true,
 		})
 	}()
 }
+
+// showToolsMode is the display of tool calls, --show-tools or /tools-view override the agent's.
+func showToolsMode(agent *data.AgentConfig) string {
+	mode := agent.ShowTools
+	if showToolsFlag != "" {
+		mode = showToolsFlag
+	}
+	if err := service.ValidateShowTools(mode); err != nil {
+		util.LogWarnf("%v\n", err)
+		return ""
+	}
+	return mode
+}
+
+// costBudget is the cost budget of this invocation, shared by all its runs.
+var costBudget *service.CostBudget
+
+// invocationBudget returns the cost budget of this invocation, set with --max-cost.
+func invocationBudget() *service.CostBudget {
+	if costBudget == nil {
+		costBudget = service.NewCostBudget(maxCostFlag)
+	}
+	return costBudget
+}
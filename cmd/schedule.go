@@ -0,0 +1,517 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(scheduleCmd)
+	scheduleCmd.AddCommand(scheduleAddCmd)
+	scheduleCmd.AddCommand(scheduleListCmd)
+	scheduleCmd.AddCommand(scheduleRemoveCmd)
+	scheduleCmd.AddCommand(schedulePauseCmd)
+	scheduleCmd.AddCommand(scheduleResumeCmd)
+	scheduleCmd.AddCommand(scheduleRunCmd)
+	scheduleCmd.AddCommand(scheduleDaemonCmd)
+	scheduleCmd.AddCommand(scheduleCrontabCmd)
+
+	scheduleAddCmd.Flags().String("cron", "", "Cron expression of the runs, e.g. '0 9 * * 1-5' or @daily")
+	scheduleAddCmd.Flags().String("agent", "", "Agent to run (empty for the active agent)")
+	scheduleAddCmd.Flags().String("prompt", "", "Prompt of each run")
+	scheduleAddCmd.Flags().String("session", "", "Session every run continues (empty starts a new one each run)")
+	scheduleAddCmd.Flags().String("output-dir", "", "Directory of the run outputs (empty for the default)")
+	scheduleAddCmd.Flags().Bool("notify", false, "Notify the desktop when a run finishes")
+	scheduleAddCmd.Flags().String("sink", "", "Post the output of every run to a chat channel or email, e.g. slack:#reports, discord or email:me@example.com (none to stop)")
+}
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Run agents on a recurring schedule",
+	Long: `Manage recurring agent runs.
+A schedule runs an agent with a prompt on a cron expression, saving the output of every run
+as a markdown file. Runs happen while 'gllm schedule daemon' is running, or through the
+system cron with the entries of 'gllm schedule crontab'.
+
+Without a terminal, tool calls are decided by the approval backend, see 'gllm config approval'.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Print Long description
+		util.Println(cmd, cmd.Long)
+		util.Println(cmd)
+		scheduleListCmd.Run(cmd, args)
+	},
+}
+
+var scheduleAddCmd = &cobra.Command{
+	Use:   "add NAME",
+	Short: "Add or update a schedule",
+	Example: `  gllm schedule add daily-digest --cron "0 9 * * *" --agent researcher --prompt "Summarize today's AI news"
+  gllm schedule add weekly-report --cron @weekly --prompt "Report on the open issues" --notify
+  gllm schedule add standup --cron "0 9 * * 1-5" --prompt "Summarize yesterday's commits" --sink slack:#team`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if err := util.ValidateResourceName("schedule", name); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		existing, err := data.GetSchedule(name)
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if existing == nil {
+			// A new schedule needs both when and what to run
+			for _, flag := range []string{"cron", "prompt"} {
+				if !cmd.Flags().Changed(flag) {
+					util.Errorf(cmd, "--%s is required for a new schedule\n", flag)
+					return
+				}
+			}
+		}
+
+		cron, _ := cmd.Flags().GetString("cron")
+		if cmd.Flags().Changed("cron") {
+			if _, err := service.ParseCron(cron); err != nil {
+				util.Errorf(cmd, "%v\n", err)
+				return
+			}
+		}
+		sink, _ := cmd.Flags().GetString("sink")
+		if strings.EqualFold(sink, "none") {
+			sink = ""
+		}
+		if sink != "" {
+			if _, err := service.ParseSinkSpec(sink); err != nil {
+				util.Errorf(cmd, "%v\n", err)
+				return
+			}
+		}
+		agent, _ := cmd.Flags().GetString("agent")
+		if agent != "" && data.NewConfigStore().GetAgent(agent) == nil {
+			util.Errorf(cmd, "agent %s does not exist\n", agent)
+			return
+		}
+
+		err = data.UpdateSchedule(name, true, func(s *data.Schedule) {
+			if cmd.Flags().Changed("cron") {
+				s.Cron = cron
+			}
+			if cmd.Flags().Changed("agent") {
+				s.Agent = agent
+			}
+			if cmd.Flags().Changed("prompt") {
+				s.Prompt, _ = cmd.Flags().GetString("prompt")
+			}
+			if cmd.Flags().Changed("session") {
+				s.Session, _ = cmd.Flags().GetString("session")
+			}
+			if cmd.Flags().Changed("output-dir") {
+				s.OutputDir, _ = cmd.Flags().GetString("output-dir")
+			}
+			if cmd.Flags().Changed("notify") {
+				s.Notify, _ = cmd.Flags().GetBool("notify")
+			}
+			if cmd.Flags().Changed("sink") {
+				s.Sink = sink
+			}
+		})
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if existing == nil {
+			util.Printf(cmd, "Schedule '%s' added.\n", name)
+		} else {
+			util.Printf(cmd, "Schedule '%s' updated.\n", name)
+		}
+	},
+}
+
+var scheduleListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the schedules with their next runs",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		schedules, err := data.LoadSchedules()
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if len(schedules) == 0 {
+			util.Println(cmd, "No schedules. Add one with 'gllm schedule add'.")
+			return
+		}
+		util.Print(cmd, renderSchedules(schedules, time.Now()))
+	},
+}
+
+var scheduleRemoveCmd = &cobra.Command{
+	Use:     "remove NAME",
+	Aliases: []string{"rm"},
+	Short:   "Remove a schedule, keeping the outputs of its runs",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := data.RemoveSchedule(args[0]); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "Schedule '%s' removed.\n", args[0])
+	},
+}
+
+var schedulePauseCmd = &cobra.Command{
+	Use:   "pause NAME",
+	Short: "Pause a schedule, the daemon skips it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := data.UpdateSchedule(args[0], false, func(s *data.Schedule) { s.Paused = true }); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "Schedule '%s' paused.\n", args[0])
+	},
+}
+
+var scheduleResumeCmd = &cobra.Command{
+	Use:   "resume NAME",
+	Short: "Resume a paused schedule",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := data.UpdateSchedule(args[0], false, func(s *data.Schedule) { s.Paused = false }); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "Schedule '%s' resumed.\n", args[0])
+	},
+}
+
+var scheduleRunCmd = &cobra.Command{
+	Use:   "run NAME",
+	Short: "Run a schedule now",
+	Long: `Run a schedule now and save its output, whether or not it is paused.
+This is the command the entries of 'gllm schedule crontab' call.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		s, err := data.GetSchedule(args[0])
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if s == nil {
+			util.Errorf(cmd, "schedule '%s' not found\n", args[0])
+			return
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		output, err := runSchedule(ctx, *s)
+		if err != nil {
+			util.Errorf(cmd, "Schedule '%s' failed: %v\n", s.Name, err)
+			os.Exit(1)
+		}
+		util.Printf(cmd, "Schedule '%s' finished, output saved to %s\n", s.Name, output)
+	},
+}
+
+var scheduleDaemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the schedules in the foreground as they come due",
+	Long: `Run the schedules as they come due until interrupted.
+The schedules are reloaded every minute, so added, changed and paused schedules take effect
+without a restart. A schedule still running when it comes due again is skipped.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		util.Printf(cmd, "Schedule daemon started, press Ctrl+C to stop.\n")
+		runScheduleDaemon(ctx)
+		util.Printf(cmd, "Schedule daemon stopped.\n")
+	},
+}
+
+var scheduleCrontabCmd = &cobra.Command{
+	Use:   "crontab",
+	Short: "Print system crontab entries for the schedules",
+	Long: `Print a crontab entry for every schedule that is not paused, to run them
+with the system cron instead of the daemon:
+
+  gllm schedule crontab | crontab -
+
+Note that this replaces the existing crontab, merge them with 'crontab -e' instead if you have one.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		schedules, err := data.LoadSchedules()
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		exe, err := os.Executable()
+		if err != nil {
+			util.Errorf(cmd, "failed to locate gllm: %v\n", err)
+			return
+		}
+		util.Print(cmd, renderCrontab(schedules, exe))
+	},
+}
+
+// runScheduleDaemon runs the schedules due at every minute until the context is done.
+func runScheduleDaemon(ctx context.Context) {
+	var mu sync.Mutex
+	running := make(map[string]bool)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		next := time.Now().Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+
+		// Reload every minute to pick up changes
+		schedules, err := data.LoadSchedules()
+		if err != nil {
+			util.LogErrorf("Failed to load schedules: %v\n", err)
+			continue
+		}
+		for _, s := range schedules {
+			if s.Paused {
+				continue
+			}
+			cron, err := service.ParseCron(s.Cron)
+			if err != nil {
+				util.LogWarnf("Skipping schedule '%s': %v\n", s.Name, err)
+				continue
+			}
+			if !cron.Matches(next) {
+				continue
+			}
+			mu.Lock()
+			if running[s.Name] {
+				mu.Unlock()
+				util.LogWarnf("Skipping schedule '%s', its last run has not finished\n", s.Name)
+				continue
+			}
+			running[s.Name] = true
+			mu.Unlock()
+
+			wg.Add(1)
+			go func(s data.Schedule) {
+				defer wg.Done()
+				defer func() {
+					mu.Lock()
+					delete(running, s.Name)
+					mu.Unlock()
+				}()
+				util.LogInfof("Running schedule '%s'\n", s.Name)
+				if output, err := runSchedule(ctx, s); err != nil {
+					util.LogErrorf("Schedule '%s' failed: %v\n", s.Name, err)
+				} else {
+					util.LogInfof("Schedule '%s' finished, output saved to %s\n", s.Name, output)
+				}
+			}(s)
+		}
+	}
+}
+
+// runSchedule runs a schedule once, saving its output and recording the run.
+// It returns the output file.
+func runSchedule(ctx context.Context, s data.Schedule) (string, error) {
+	start := time.Now()
+	dir := s.OutputDir
+	if dir == "" {
+		dir = data.GetScheduleRunsDirPath(s.Name)
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	output := filepath.Join(dir, start.Format("2006-01-02_15-04-05")+".md")
+
+	runErr := runScheduledAgent(ctx, s, output)
+	err := data.UpdateSchedule(s.Name, false, func(saved *data.Schedule) {
+		saved.LastRun = start
+		saved.LastError = ""
+		if runErr != nil {
+			saved.LastError = runErr.Error()
+		}
+	})
+	if err != nil {
+		util.LogWarnf("Failed to record the run of schedule '%s': %v\n", s.Name, err)
+	}
+
+	if s.Sink != "" {
+		postScheduleOutput(ctx, s, output, runErr)
+	}
+	if s.Notify {
+		title, message := "gllm finished", fmt.Sprintf("Schedule %s finished in %s", s.Name, time.Since(start).Round(time.Second))
+		if runErr != nil {
+			title, message = "gllm failed", fmt.Sprintf("Schedule %s failed: %v", s.Name, runErr)
+		}
+		if err := service.SendDesktopNotification(title, message); err != nil {
+			util.LogWarnf("Failed to notify: %v\n", err)
+		}
+	}
+	return output, runErr
+}
+
+// runScheduledAgent runs the agent of the schedule without a terminal, writing to the output file.
+func runScheduledAgent(ctx context.Context, s data.Schedule, outputFile string) error {
+	ensure := func(name string) (*data.AgentConfig, error) {
+		if name == "" {
+			return EnsureActiveAgent()
+		}
+		return EnsureAgent(name)
+	}
+
+	agentName, prompt := s.Agent, s.Prompt
+	sharedState := data.NewSharedState()
+	for {
+		agent, err := ensure(agentName)
+		if err != nil {
+			return err
+		}
+		hook := service.SessionConvertHook{OnStartConvert: func() {}, OnFinishedConvert: func() {}}
+		if err := service.EnsureSessionCompatibility(agent, s.Session, hook); err != nil {
+			return err
+		}
+		mcpConfig, err := data.NewMCPStore().Load()
+		if err != nil {
+			return err
+		}
+
+		op := service.AgentOptions{
+			Ctx:            ctx,
+			Prompt:         buildFinalPrompt(prompt, ""),
+			SysPrompt:      agent.SystemPrompt,
+			ModelInfo:      &agent.Model,
+			MaxRecursions:  agent.MaxRecursions,
+			ThinkingLevel:  agent.Think,
+			EnabledTools:   agent.Tools,
+			AutoFormat:     agent.AutoFormat,
+			Shell:          agent.Shell,
+			InjectionGuard: agent.InjectionGuard,
+			Paths:          agent.Paths,
+			Capabilities:   agent.Capabilities,
+			YoloMode:       false,
+			OutputFile:     outputFile,
+			QuietMode:      true, // Nobody watches, the output file is the record
+			SessionName:    s.Session,
+			MCPConfig:      mcpConfig,
+			// No one is there to confirm, the approval backend decides
+			Interaction: newScheduleApprovalHandler(s, agent.Name),
+			SharedState: sharedState,
+			AgentName:   agent.Name,
+			ModelName:   agent.Model.Name,
+		}
+		err = service.CallAgent(&op)
+
+		// Switching agents continues the run with the new agent
+		if switchErr, ok := service.AsSwitchAgentError(err); ok {
+			if prompt = switchErr.Prompt(); prompt == "" {
+				return nil
+			}
+			agentName = switchErr.TargetAgent
+			continue
+		}
+		return err
+	}
+}
+
+// newScheduleApprovalHandler returns the approval handler of a run, which asks for approvals
+// in the sink of the schedule.
+func newScheduleApprovalHandler(s data.Schedule, agent string) *service.ApprovalHandler {
+	h := service.NewApprovalHandler(data.GetSettingsStore().GetApprovalSettings(), nil, s.Session, agent)
+	if s.Sink != "" {
+		if sink, err := service.NewSink(s.Sink); err == nil {
+			h.SetSink(sink)
+		} else {
+			util.LogWarnf("Approval requests of schedule '%s' are not posted: %v\n", s.Name, err)
+		}
+	}
+	return h
+}
+
+// postScheduleOutput posts the output of a run to the sink of the schedule.
+func postScheduleOutput(ctx context.Context, s data.Schedule, outputFile string, runErr error) {
+	title := s.Name
+	var text string
+	if runErr != nil {
+		title = fmt.Sprintf("%s failed", s.Name)
+		text = runErr.Error()
+	} else {
+		content, err := os.ReadFile(outputFile)
+		if err != nil {
+			util.LogWarnf("Failed to read the output of schedule '%s': %v\n", s.Name, err)
+			return
+		}
+		text = string(content)
+	}
+	// Post even when the run was cancelled, the daemon may be stopping
+	if err := service.PostToSink(context.WithoutCancel(ctx), s.Sink, title, text); err != nil {
+		util.LogWarnf("Failed to post the output of schedule '%s' to %s: %v\n", s.Name, s.Sink, err)
+	}
+}
+
+func renderSchedules(schedules []data.Schedule, now time.Time) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tCRON\tAGENT\tNEXT RUN\tLAST RUN\tSTATUS")
+	for _, s := range schedules {
+		agent := s.Agent
+		if agent == "" {
+			agent = "(active)"
+		}
+		next := "-"
+		if cron, err := service.ParseCron(s.Cron); err != nil {
+			next = "invalid cron"
+		} else if t := cron.Next(now); !t.IsZero() && !s.Paused {
+			next = t.Format("2006-01-02 15:04")
+		}
+		last := "never"
+		if !s.LastRun.IsZero() {
+			last = s.LastRun.Format("2006-01-02 15:04")
+		}
+		status := data.SwitchOnColor + "ok" + data.ResetSeq
+		switch {
+		case s.Paused:
+			status = data.SwitchOffColor + "paused" + data.ResetSeq
+		case s.LastError != "":
+			status = data.StatusErrorColor + "failed: " + util.TruncateString(s.LastError, 60) + data.ResetSeq
+		case s.LastRun.IsZero():
+			status = "pending"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", s.Name, s.Cron, agent, next, last, status)
+	}
+	w.Flush()
+	return sb.String()
+}
+
+func renderCrontab(schedules []data.Schedule, exe string) string {
+	if strings.ContainsAny(exe, " '\"") {
+		exe = "'" + strings.ReplaceAll(exe, "'", `'\''`) + "'"
+	}
+	var sb strings.Builder
+	sb.WriteString("# gllm schedules, generated by 'gllm schedule crontab'\n")
+	for _, s := range schedules {
+		if s.Paused {
+			continue
+		}
+		fmt.Fprintf(&sb, "%s %s schedule run %s\n", s.Cron, exe, s.Name)
+	}
+	return sb.String()
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+	"github.com/charmbracelet/huh"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// knownSecrets are the secrets of the built-in tools, by name.
+var knownSecrets = map[string]string{
+	data.SecretGitHubToken:    "GitHub token of the gh_* tools",
+	data.SecretGitLabToken:    "GitLab token of the gh_* tools on GitLab repositories",
+	data.SecretJiraToken:      "Jira API token of the ticket_* tools",
+	data.SecretLinearToken:    "Linear API key of the ticket_* tools",
+	data.SecretSlackWebhook:   "Slack incoming webhook of the slack sink, slack_webhook:#channel for one channel",
+	data.SecretSlackToken:     "Slack bot token of the slack sink, posts to any channel",
+	data.SecretDiscordWebhook: "Discord webhook of the discord sink, discord_webhook:name for a named one",
+	data.SecretSMTPPassword:   "SMTP password of the email sink and 'gllm mail'",
+}
+
+// configSecretCmd manages the credentials of the built-in integrations
+var configSecretCmd = &cobra.Command{
+	Use:     "secret",
+	Aliases: []string{"secrets"},
+	Short:   "Manage tokens of the built-in integrations",
+	Long: `Manage tokens of the built-in integrations, such as the GitHub token of the gh_* tools.
+Secrets are kept in a file only you can read and are never printed in full.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		configSecretListCmd.Run(cmd, args)
+	},
+}
+
+var configSecretListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the stored secrets",
+	Run: func(cmd *cobra.Command, args []string) {
+		names, err := data.ListSecretNames()
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if len(names) == 0 {
+			util.Println(cmd, "No secrets stored. Known secrets:")
+			for name, desc := range knownSecrets {
+				util.Printf(cmd, "  %s%s%s  %s\n", data.KeyColor, name, data.ResetSeq, desc)
+			}
+			return
+		}
+		for _, name := range names {
+			value, _ := data.GetSecret(name)
+			util.Printf(cmd, "%s%s%s  %s\n", data.KeyColor, name, data.ResetSeq, maskSecret(value))
+		}
+	},
+}
+
+var configSecretSetCmd = &cobra.Command{
+	Use:   "set NAME [VALUE]",
+	Short: "Store a secret",
+	Long: `Store a secret. Without a value it is asked for, or read from stdin when it is not a terminal,
+so that it does not end up in the shell history.`,
+	Example: `  gllm config secret set github_token
+  echo $TOKEN | gllm config secret set gitlab_token`,
+	Args: cobra.RangeArgs(1, 2),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		var names []string
+		for name, desc := range knownSecrets {
+			names = append(names, name+"\t"+desc)
+		}
+		return names, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		var value string
+		switch {
+		case len(args) == 2:
+			value = args[1]
+		case term.IsTerminal(int(os.Stdin.Fd())):
+			err := huh.NewInput().
+				Title(name).
+				Value(&value).
+				EchoMode(huh.EchoModePassword).
+				Run()
+			if err != nil {
+				util.Errorf(cmd, "%v\n", err)
+				return
+			}
+		default:
+			line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			value = line
+		}
+		value = strings.TrimSpace(value)
+		if value == "" {
+			util.Errorf(cmd, "Empty secret, nothing stored.\n")
+			return
+		}
+		if err := data.SetSecret(name, value); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "Secret %s%s%s stored.\n", data.KeyColor, name, data.ResetSeq)
+	},
+}
+
+var configSecretRemoveCmd = &cobra.Command{
+	Use:     "remove NAME",
+	Aliases: []string{"rm", "delete"},
+	Short:   "Remove a secret",
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := data.RemoveSecret(args[0]); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Printf(cmd, "Secret %s removed.\n", args[0])
+	},
+}
+
+// maskSecret shows only the ends of a secret.
+func maskSecret(value string) string {
+	if len(value) <= 8 {
+		return strings.Repeat("*", len(value))
+	}
+	return fmt.Sprintf("%s...%s", value[:4], value[len(value)-4:])
+}
+
+func init() {
+	configCmd.AddCommand(configSecretCmd)
+	configSecretCmd.AddCommand(configSecretListCmd)
+	configSecretCmd.AddCommand(configSecretSetCmd)
+	configSecretCmd.AddCommand(configSecretRemoveCmd)
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(sendCmd)
+	sendCmd.Flags().String("to", "", "PID or session of the REPL, needed when several are running")
+	sendCmd.Flags().Bool("insert", false, "Insert the text into the input being typed instead of sending it")
+	sendCmd.Flags().Bool("command", false, "Send a REPL command, such as compress or clear")
+	sendCmd.Flags().Bool("status", false, "Show the status of the REPL")
+	sendCmd.Flags().BoolP("list", "l", false, "List the running REPLs")
+}
+
+var sendCmd = &cobra.Command{
+	Use:   "send [TEXT]",
+	Short: "Send a prompt or command to a running REPL",
+	Long: `Every gllm REPL listens on a control socket. send queues a prompt or a REPL command
+there: it runs when the REPL is idle and nothing is being typed, otherwise after the
+current prompt. With --insert, the text lands in the input being typed, for the user
+to finish. TEXT is read from stdin when it is not given.`,
+	Example: `  gllm send "Explain the failing test"
+  gllm send --command compress
+  git diff | gllm send --to my-session
+  # In vim, send the selected lines to the input of the running REPL
+  :'<,'>w !gllm send --insert`,
+	Args: cobra.ArbitraryArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if list, _ := cmd.Flags().GetBool("list"); list {
+			running := service.ListControlSockets()
+			if len(running) == 0 {
+				util.Println(cmd, "No REPL is running.")
+				return
+			}
+			for _, status := range running {
+				util.Print(cmd, renderControlStatus(&status))
+			}
+			return
+		}
+
+		target, _ := cmd.Flags().GetString("to")
+		path, err := service.FindControlSocket(target)
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+
+		req := service.ControlRequest{Action: service.ControlActionPrompt}
+		if status, _ := cmd.Flags().GetBool("status"); status {
+			req.Action = service.ControlActionStatus
+		} else {
+			if insert, _ := cmd.Flags().GetBool("insert"); insert {
+				req.Action = service.ControlActionInsert
+			} else if command, _ := cmd.Flags().GetBool("command"); command {
+				req.Action = service.ControlActionCommand
+			}
+			req.Text = strings.Join(args, " ")
+			if req.Text == "" {
+				req.Text = readStdin()
+			}
+			if strings.TrimSpace(req.Text) == "" {
+				util.Errorf(cmd, "nothing to send, give TEXT or pipe it\n")
+				return
+			}
+		}
+
+		resp, err := service.SendControlRequest(path, req)
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		if req.Action == service.ControlActionStatus && resp.Status != nil {
+			util.Print(cmd, renderControlStatus(resp.Status))
+		}
+	},
+}
+
+func renderControlStatus(status *service.ControlStatus) string {
+	state := "idle"
+	if status.Busy {
+		state = "busy"
+	}
+	if status.Queued > 0 {
+		state += fmt.Sprintf(", %d queued", status.Queued)
+	}
+	return fmt.Sprintf("%spid %d%s  %s\n  session: %s  agent: %s\n  %s\n",
+		data.KeyColor, status.PID, data.ResetSeq, state, status.Session, status.Agent, status.WorkDir)
+}
@@ -222,6 +222,10 @@ func handleWebCommand(prompt string, sessionName string, sseOut *io.SSEOutput) (
 		// Attempt to execute as Workflow
 		wm := service.GetWorkflowManager()
 		content, _, err := wm.GetWorkflowByName(strings.TrimPrefix(command, "/"))
+		if err == nil && wm.GetWorkflowBatch(strings.TrimPrefix(command, "/")) {
+			runCommand(batchCmd, append([]string{"submit", "--workflow", strings.TrimPrefix(command, "/")}, parts[1:]...), io.NewSSEWriter(sseOut))
+			return true, prompt, ""
+		}
 		if err == nil {
 			userArgs := ""
 			if len(parts) > 1 {
@@ -297,34 +301,43 @@ func runAgentWithSSE(prompt string, guideline string, sessionName string, sseIO
 			},
 			0, // no timeout: block until frontend responds
 		)
+		// A configured approval backend decides on tool calls instead of the frontend
+		var interaction service.InteractionHandler = sseInteraction
+		if approval := data.GetSettingsStore().GetApprovalSettings(); approval.Backend != "" && approval.Backend != service.ApprovalBackendPrompt {
+			interaction = service.NewApprovalHandler(approval, sseInteraction, sessionName, agent.Name)
+		}
 
 		op := service.AgentOptions{
-			Ctx:           ctx, // Carry HTTP completion cancellation context
-			Prompt:        finalPrompt,
-			SysPrompt:     agent.SystemPrompt,
-			Files:         nil, // Can map attachments later if needed
-			ModelInfo:     &agent.Model,
-			MaxRecursions: agent.MaxRecursions,
-			ThinkingLevel: agent.Think,
-			EnabledTools:  agent.Tools,
-			Capabilities:  agent.Capabilities,
-			YoloMode:      false, // Now user-driven; approval comes via /v1/interact
-			OutputFile:    "",
-			QuietMode:     !serveVerbose, // True by default unless --verbose is provided
-			SSEOutput:     sseIO,         // SSE Output for streaming
-			SessionName:   sessionName,
-			MCPConfig:     mcpConfig,
-			Interaction:   sseInteraction,
-			SharedState:   sharedState,
-			AgentName:     agent.Name,
-			ModelName:     agent.Model.Name,
+			Ctx:            ctx, // Carry HTTP completion cancellation context
+			Prompt:         finalPrompt,
+			SysPrompt:      agent.SystemPrompt,
+			Files:          nil, // Can map attachments later if needed
+			ModelInfo:      &agent.Model,
+			MaxRecursions:  agent.MaxRecursions,
+			ThinkingLevel:  agent.Think,
+			EnabledTools:   agent.Tools,
+			AutoFormat:     agent.AutoFormat,
+			Shell:          agent.Shell,
+			InjectionGuard: agent.InjectionGuard,
+			Paths:          agent.Paths,
+			Capabilities:   agent.Capabilities,
+			YoloMode:       false, // Now user-driven; approval comes via /v1/interact
+			OutputFile:     "",
+			QuietMode:      !serveVerbose, // True by default unless --verbose is provided
+			SSEOutput:      sseIO,         // SSE Output for streaming
+			SessionName:    sessionName,
+			MCPConfig:      mcpConfig,
+			Interaction:    interaction,
+			SharedState:    sharedState,
+			AgentName:      agent.Name,
+			ModelName:      agent.Model.Name,
 		}
 
 		err = service.CallAgent(&op)
 		if err != nil {
 			if service.IsSwitchAgentError(err) {
 				switchErr, _ := service.AsSwitchAgentError(err)
-				prompt = switchErr.Instruction
+				prompt = switchErr.Prompt()
 				if prompt == "" {
 					break
 				}
@@ -344,7 +357,8 @@ func runAgentWithSSE(prompt string, guideline string, sessionName string, sseIO
 type InteractRequest struct {
 	ID        string `json:"id"`                  // UUID matching the SSE interaction request event
 	Kind      string `json:"kind"`                // "tool_confirm" | "ask_user"
-	Approve   string `json:"approve,omitempty"`   // For tool_confirm ("once", "always", "cancel")
+	Approve   string `json:"approve,omitempty"`   // For tool_confirm ("once", "tool", "prefix", "always", "cancel")
+	Feedback  string `json:"feedback,omitempty"`  // For tool_confirm: why the call was cancelled, sent to the model
 	Answer    string `json:"answer,omitempty"`    // For ask_user
 	Cancelled bool   `json:"cancelled,omitempty"` // For ask_user: user dismissed the dialog
 }
@@ -374,7 +388,7 @@ func interactHandler(w http.ResponseWriter, r *http.Request) {
 	var resolveErr error
 	switch req.Kind {
 	case string(service.InteractionKindConfirm):
-		resolveErr = service.InteractionRegistry.ResolveConfirm(req.ID, req.Approve)
+		resolveErr = service.InteractionRegistry.ResolveConfirm(req.ID, req.Approve, req.Feedback)
 	case string(service.InteractionKindAskUser):
 		resolveErr = service.InteractionRegistry.ResolveAskUser(req.ID, req.Answer, req.Cancelled)
 	default:
@@ -29,6 +29,8 @@ func init() {
 	sessionCmd.AddCommand(sessionClearCmd)
 	sessionCmd.AddCommand(sessionRenameCmd)
 	sessionCmd.AddCommand(sessionShareCmd)
+	sessionCmd.AddCommand(sessionForkCmd)
+	sessionCmd.AddCommand(sessionForkCurrentCmd)
 	sessionCmd.AddCommand(sessionClearCurrentCmd)
 	sessionCmd.AddCommand(sessionCompressCurrentCmd)
 	sessionCmd.AddCommand(sessionRenameCurrentCmd)
@@ -37,6 +39,7 @@ func init() {
 	sessionRemoveCmd.Flags().BoolP("force", "f", false, "Skip confirm")
 	sessionClearCmd.Flags().BoolP("force", "f", false, "Force clear all without confirmation")
 	sessionRenameCmd.Flags().BoolP("force", "f", false, "Skip confirm")
+	sessionForkCmd.Flags().IntP("at", "t", 0, "Keep history up to this user turn (default: the whole session)")
 }
 
 // sessionCmd represents the session command
@@ -48,7 +51,7 @@ var sessionCmd = &cobra.Command{
 	Args:    cobra.NoArgs,
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 0 {
-			return []string{"list", "remove", "info", "clear", "rename", "share"}, cobra.ShellCompDirectiveNoFileComp
+			return []string{"list", "remove", "info", "clear", "rename", "share", "fork"}, cobra.ShellCompDirectiveNoFileComp
 		}
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	},
@@ -544,6 +547,112 @@ var sessionShareCmd = &cobra.Command{
 	},
 }
 
+// sessionForkCmd represents the session fork command
+var sessionForkCmd = &cobra.Command{
+	Use:   "fork [session|index] [newname]",
+	Short: "Branch a session from an earlier turn",
+	Long: `Create a new session that shares the history of an existing one up to a user turn,
+so an alternative approach can be explored without losing the original thread.
+
+Without --at the whole history is copied. Run without --at and without a new name
+to list the turns of the session.`,
+	Example: `  gllm session fork my-session --at 3
+  gllm session fork 1 retry-with-sqlite --at 2`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		srcName, err := service.FindSessionByIndex(args[0])
+		if err != nil {
+			return err
+		}
+		if !service.SessionExists(srcName, false) {
+			return fmt.Errorf("session '%s' not found", args[0])
+		}
+
+		turn, _ := cmd.Flags().GetInt("at")
+		if len(args) == 1 && !cmd.Flags().Changed("at") {
+			turns, err := service.ListSessionTurns(srcName)
+			if err != nil {
+				return err
+			}
+			util.Printf(cmd, "Turns of session '%s':\n", srcName)
+			for _, t := range turns {
+				util.Printf(cmd, "  %3d  %s\n", t.Index, t.Preview)
+			}
+			util.Printf(cmd, "\nUse 'gllm session fork %s --at <turn> [newname]' to branch from a turn.\n", srcName)
+			return nil
+		}
+
+		newName := service.NextForkName(srcName)
+		if len(args) == 2 {
+			newName = args[1]
+			if err := util.ValidateResourceName("session", newName); err != nil {
+				return err
+			}
+		}
+
+		if err := service.ForkSession(srcName, newName, turn); err != nil {
+			return err
+		}
+		util.Successln(cmd, describeFork(srcName, newName, turn))
+		return nil
+	},
+}
+
+// sessionForkCurrentCmd forks the REPL session and switches the REPL to the fork
+var sessionForkCurrentCmd = &cobra.Command{
+	Use:    "fork-current",
+	Hidden: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		tgtSession := GetContextSession(cmd)
+		if tgtSession == "" || !service.SessionExists(tgtSession, false) {
+			util.Println(cmd, "No session history yet — nothing to fork.")
+			return nil
+		}
+
+		// Usage: /fork [turn] [newname], a lone non-numeric argument is the new name
+		turn := 0
+		newName := ""
+		for _, arg := range args {
+			if n, err := strconv.Atoi(arg); err == nil && turn == 0 {
+				turn = n
+			} else if newName == "" {
+				newName = arg
+			}
+		}
+		if newName == "" {
+			newName = service.NextForkName(tgtSession)
+		} else if err := util.ValidateResourceName("session", newName); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return nil
+		}
+
+		if err := service.ForkSession(tgtSession, newName, turn); err != nil {
+			util.Errorf(cmd, "Failed to fork session: %v\n", err)
+			if turns, _ := service.ListSessionTurns(tgtSession); len(turns) > 0 {
+				for _, t := range turns {
+					util.Printf(cmd, "  %3d  %s\n", t.Index, t.Preview)
+				}
+			}
+			return nil
+		}
+
+		// Continue the conversation in the fork, the original session stays as it was
+		if tgtSession == sessionName {
+			sessionName = newName
+		}
+		util.Successln(cmd, describeFork(tgtSession, newName, turn)+"\nNow continuing in the forked session.")
+		return nil
+	},
+}
+
+// describeFork summarizes a fork for the user.
+func describeFork(src, dst string, turn int) string {
+	if turn == 0 {
+		return fmt.Sprintf("Session forked: %s → %s (full history)", src, dst)
+	}
+	return fmt.Sprintf("Session forked: %s → %s (history up to turn %d)", src, dst, turn)
+}
+
 var sessionClearCurrentCmd = &cobra.Command{
 	Use:    "clear-current",
 	Hidden: true,
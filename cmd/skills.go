@@ -33,6 +33,7 @@ func init() {
 	skillsInstallCmd.Flags().StringSliceVar(&skillsInstallPaths, "path", []string{}, "Paths to the skill directories within the git repository (comma separated or multiple flags)")
 	skillsCmd.AddCommand(skillsUninstallCmd)
 	skillsCmd.AddCommand(skillsSwCmd)
+	skillsCmd.AddCommand(skillsSuggestCmd)
 	skillsCmd.AddCommand(skillsUpdateCmd)
 	skillsUpdateCmd.Flags().BoolVarP(&skillsUpdateAll, "all", "a", false, "Update all installed skills that have source tracking")
 }
@@ -507,6 +508,52 @@ var skillsSwCmd = &cobra.Command{
 	},
 }
 
+// skillsSuggestCmd toggles matching prompts against the skills
+var skillsSuggestCmd = &cobra.Command{
+	Use:   "suggest [true|false]",
+	Short: "Toggle or set skill suggestions from the prompt",
+	Long: `Match each prompt against the names and descriptions of the enabled skills.
+The best matching skill is activated right away when its SKILL.md sets 'auto_activate: true',
+the other relevant skills are hinted at in the system prompt for the model to activate.`,
+	Args: cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) == 0 {
+			return []string{"true", "false", "on", "off", "enable", "disable"}, cobra.ShellCompDirectiveNoFileComp
+		}
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		settings := data.GetSettingsStore()
+
+		var enable bool
+		if len(args) == 0 {
+			// Toggle behavior
+			enable = !settings.GetSkillSuggestEnabled()
+		} else {
+			switch args[0] {
+			case "true", "on", "enable":
+				enable = true
+			case "false", "off", "disable":
+				enable = false
+			default:
+				util.Printf(cmd, "%sInvalid argument: %s. Use 'true' or 'false'.%s\n", data.StatusErrorColor, args[0], data.ResetSeq)
+				return
+			}
+		}
+
+		if err := settings.SetSkillSuggestEnabled(enable); err != nil {
+			util.Printf(cmd, "%sFailed to update settings: %v%s\n", data.StatusErrorColor, err, data.ResetSeq)
+			return
+		}
+
+		status := data.SwitchOffColor + "false" + data.ResetSeq
+		if enable {
+			status = data.SwitchOnColor + "true" + data.ResetSeq
+		}
+		util.Printf(cmd, "Skill suggestions: %s\n", status)
+	},
+}
+
 // skillsUpdateCmd updates an installed skill
 var skillsUpdateCmd = &cobra.Command{
 	Use:     "update [name]",
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/internal/ui"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configCmd.AddCommand(syncCmd)
+	syncCmd.AddCommand(syncStatusCmd)
+	syncCmd.AddCommand(syncRemoveCmd)
+
+	syncCmd.Flags().String("ref", "", "Branch or tag of the repository to follow")
+	syncCmd.Flags().String("refresh", "", "Refresh interval in the REPL, e.g. 12h; 'off' disables it")
+}
+
+var syncCmd = &cobra.Command{
+	Use:   "sync [REPO]",
+	Short: "Sync agents, skills, workflows and instructions from a team git repository",
+	Long: `Sync a team config repository and use it as a read-only layer under your local config.
+
+The repository is laid out like the gllm config directory:
+  agents/*.md        agents, which local agents can also extend as templates
+  skills/<name>/     skills with a SKILL.md
+  workflows/*.md     workflows
+  GLLM.md            team instructions, added before your global instructions
+
+Local agents, skills and workflows with the same name take precedence, 'sync status'
+lists them. Pass REPO the first time, later runs pull the latest commit.
+With --refresh the REPL refreshes the team config in the background.`,
+	Example: `  gllm config sync https://github.com/acme/gllm-config.git --ref main --refresh 24h
+  gllm config sync
+  gllm config sync status`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		settings := data.GetSettingsStore()
+		team := settings.GetTeamSettings()
+		if len(args) == 1 {
+			if args[0] != team.Repo {
+				team.SyncedAt = time.Time{}
+			}
+			team.Repo = args[0]
+		}
+		if cmd.Flags().Changed("ref") {
+			team.Ref, _ = cmd.Flags().GetString("ref")
+		}
+		if cmd.Flags().Changed("refresh") {
+			refresh, _ := cmd.Flags().GetString("refresh")
+			if refresh == "off" || refresh == "0" {
+				refresh = ""
+			}
+			if refresh != "" {
+				if d, err := time.ParseDuration(refresh); err != nil || d <= 0 {
+					util.Errorf(cmd, "Invalid refresh interval '%s', use a duration such as 30m or 24h\n", refresh)
+					return
+				}
+			}
+			team.Refresh = refresh
+		}
+		if team.Repo == "" {
+			util.Errorf(cmd, "No team config repository is configured, pass it as: gllm config sync REPO\n")
+			return
+		}
+		if err := settings.SetTeamSettings(team); err != nil {
+			util.Errorf(cmd, "Failed to update settings: %v\n", err)
+			return
+		}
+
+		ui.GetIndicator().Start(ui.IndicatorSyncingTeamConfig)
+		result, err := service.SyncTeamConfig()
+		ui.GetIndicator().Stop()
+		if err != nil {
+			util.Errorf(cmd, "Failed to sync the team config: %v\n", err)
+			return
+		}
+
+		action := "Updated"
+		if result.Cloned {
+			action = "Cloned"
+		}
+		util.Printf(cmd, "%s team config from %s at %s%s%s\n", action, team.Repo, data.SwitchOnColor, result.Commit, data.ResetSeq)
+		util.Print(cmd, renderTeamConfigContents(result.Conflicts))
+	},
+}
+
+var syncStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the team config repository and what it provides",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		team := data.GetSettingsStore().GetTeamSettings()
+		if team.Repo == "" {
+			util.Println(cmd, "No team config repository is configured.")
+			return
+		}
+		ref := team.Ref
+		if ref == "" {
+			ref = "default branch"
+		}
+		refresh := team.Refresh
+		if refresh == "" {
+			refresh = "off"
+		}
+		synced := "never"
+		if !team.SyncedAt.IsZero() {
+			synced = team.SyncedAt.Format("2006-01-02 15:04")
+		}
+		util.Printf(cmd, "Repository: %s (%s)\n", team.Repo, ref)
+		util.Printf(cmd, "Last sync:  %s\n", synced)
+		util.Printf(cmd, "Refresh:    %s\n", refresh)
+		util.Printf(cmd, "Checkout:   %s%s%s\n", data.DirectoryColor, data.GetTeamConfigDirPath(), data.ResetSeq)
+		util.Print(cmd, renderTeamConfigContents(data.FindTeamConflicts()))
+	},
+}
+
+var syncRemoveCmd = &cobra.Command{
+	Use:     "remove",
+	Aliases: []string{"rm"},
+	Short:   "Stop syncing and remove the team config layer",
+	Args:    cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := service.RemoveTeamConfig(); err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		util.Println(cmd, "Team config removed.")
+	},
+}
+
+// renderTeamConfigContents summarizes the team config layer and the local items that override it.
+func renderTeamConfigContents(conflicts []data.TeamConflict) string {
+	var sb strings.Builder
+	agents, skills, workflows := data.TeamConfigCounts()
+	fmt.Fprintf(&sb, "Provides %d agent(s), %d skill(s), %d workflow(s)\n", agents, skills, workflows)
+	if len(conflicts) > 0 {
+		fmt.Fprintf(&sb, "%sOverridden by local config:%s\n", data.SectionColor, data.ResetSeq)
+		for _, c := range conflicts {
+			fmt.Fprintf(&sb, "  %-8s %s\n", c.Kind, c.Name)
+		}
+	}
+	return sb.String()
+}
+
+// StartBackgroundTeamSync refreshes the team config in the background when its refresh interval has elapsed.
+func StartBackgroundTeamSync() {
+	go func() {
+		if !service.TeamSyncDue(data.GetSettingsStore().GetTeamSettings(), time.Now()) {
+			return
+		}
+		// Refresh quietly, the next explicit sync reports errors
+		if _, err := service.SyncTeamConfig(); err != nil {
+			util.LogDebugf("Team config refresh failed: %v\n", err)
+		}
+	}()
+}
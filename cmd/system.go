@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configCmd.AddCommand(systemCmd)
+	systemCmd.AddCommand(systemShowCmd)
+	systemCmd.AddCommand(systemOrderCmd)
+
+	systemShowCmd.Flags().BoolP("summary", "s", false, "Only show the layers and their token cost")
+}
+
+var systemCmd = &cobra.Command{
+	Use:   "system",
+	Short: "Inspect how the system prompt is composed",
+	Long: `The system prompt is composed from layers: the agent's own prompt (base),
+GLLM.md / AGENTS.md instruction files, the skills preamble, the memory digest,
+plan mode instructions and a date/OS/environment block.
+Use 'order' to change the order of the layers or leave some of them out.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		util.Println(cmd, cmd.Long)
+		util.Println(cmd)
+		util.Print(cmd, renderPromptLayerOrder())
+	},
+}
+
+var systemShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Show the assembled system prompt of the active agent and its token cost",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		agent, err := EnsureActiveAgent()
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+
+		layers := service.ComposeSystemPrompt(agent.SystemPrompt, agent.Capabilities)
+		total := 0
+		util.Printf(cmd, "%sSystem prompt of agent '%s'%s\n\n", data.SectionColor, agent.Name, data.ResetSeq)
+		for _, l := range layers {
+			total += l.Tokens
+			util.Printf(cmd, "  %-14s ~%d tokens\n", l.Name, l.Tokens)
+		}
+		util.Printf(cmd, "  %-14s ~%d tokens\n", "total", total)
+
+		if summary, _ := cmd.Flags().GetBool("summary"); summary {
+			return
+		}
+		for _, l := range layers {
+			util.Printf(cmd, "\n%s[%s]%s\n%s\n", data.SectionColor, l.Name, data.ResetSeq, l.Content)
+		}
+	},
+}
+
+var systemOrderCmd = &cobra.Command{
+	Use:   "order [layer...]",
+	Short: "Show or set the order of the system prompt layers",
+	Long: fmt.Sprintf(`Show or set the order of the system prompt layers.
+Layers left out are not injected. Use 'default' to restore the default order.
+
+Layers: %s`, strings.Join(service.DefaultPromptLayers, ", ")),
+	Example: `  gllm config system order base instructions memory environment
+  gllm config system order default`,
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return append([]string{"default"}, service.DefaultPromptLayers...), cobra.ShellCompDirectiveNoFileComp
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		settings := data.GetSettingsStore()
+		if len(args) == 0 {
+			util.Print(cmd, renderPromptLayerOrder())
+			return
+		}
+
+		var layers []string
+		if !(len(args) == 1 && args[0] == "default") {
+			for _, arg := range args {
+				// Accept both "base instructions" and "base,instructions"
+				for _, name := range strings.Split(arg, ",") {
+					if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+						layers = append(layers, name)
+					}
+				}
+			}
+			if err := service.ValidatePromptLayers(layers); err != nil {
+				util.Errorf(cmd, "%v\n", err)
+				return
+			}
+		}
+
+		if err := settings.SetPromptLayers(layers); err != nil {
+			util.Errorf(cmd, "Failed to update settings: %v\n", err)
+			return
+		}
+		util.Print(cmd, renderPromptLayerOrder())
+	},
+}
+
+func renderPromptLayerOrder() string {
+	order := service.GetPromptLayerOrder()
+	used := make(map[string]bool)
+	for _, l := range order {
+		used[l] = true
+	}
+	var off []string
+	for _, l := range service.DefaultPromptLayers {
+		if !used[l] {
+			off = append(off, l)
+		}
+	}
+	s := fmt.Sprintf("System prompt layers: %s%s%s\n", data.SwitchOnColor, strings.Join(order, " → "), data.ResetSeq)
+	if len(off) > 0 {
+		s += fmt.Sprintf("Not injected: %s%s%s\n", data.SwitchOffColor, strings.Join(off, ", "), data.ResetSeq)
+	}
+	return s
+}
@@ -104,9 +104,9 @@ var themeCmd = &cobra.Command{
 			Render(strings.Join(leftParts, "\n"))
 
 		// --- Right panel: markdown preview ---
-		glamourStyle := data.MostSimilarGlamourStyle()
+		glamourStyle := data.MarkdownStyleName()
 		tr, _ := glamour.NewTermRenderer(
-			glamour.WithStandardStyle(glamourStyle),
+			data.MarkdownStyleOption(),
 			glamour.WithWordWrap(rightWidth),
 		)
 		md := fmt.Sprintf(markdownSample, glamourStyle)
@@ -191,3 +191,18 @@ var themeSwitchCmd = &cobra.Command{
 		themeCmd.Run(themeCmd, []string{})
 	},
 }
+
+// setMarkdownTheme sets the theme of rendered markdown and code, auto matches the color theme.
+func setMarkdownTheme(value string) (string, error) {
+	name, err := data.ValidateMarkdownTheme(value)
+	if err != nil {
+		return "", err
+	}
+	if name == "auto" {
+		name = ""
+	}
+	if err := data.GetSettingsStore().SetMarkdownTheme(name); err != nil {
+		return "", err
+	}
+	return data.MarkdownStyleName(), nil
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+)
+
+// setTicketSetting returns the 'config set' handler of a text setting of the issue trackers,
+// "" or "none" clears it.
+func setTicketSetting(field func(*data.TicketSettings) *string) func(value string) (string, error) {
+	return func(value string) (string, error) {
+		value = strings.TrimSpace(value)
+		if strings.EqualFold(value, "none") {
+			value = ""
+		}
+		settings := data.GetSettingsStore()
+		tickets := settings.GetTicketSettings()
+		*field(&tickets) = strings.TrimSuffix(value, "/")
+		if err := settings.SetTicketSettings(tickets); err != nil {
+			return "", fmt.Errorf("failed to update settings: %w", err)
+		}
+		if value == "" {
+			return "none", nil
+		}
+		return *field(&tickets), nil
+	}
+}
+
+func setTicketProvider(value string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value != "" && value != "none" && value != service.TicketProviderJira && value != service.TicketProviderLinear {
+		return "", fmt.Errorf("unsupported tracker '%s', supported: %s, %s", value, service.TicketProviderJira, service.TicketProviderLinear)
+	}
+	return setTicketSetting(func(t *data.TicketSettings) *string { return &t.Provider })(value)
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/activebook/gllm/util"
@@ -30,6 +31,7 @@ Security Warning ( [important]()):
 
 func init() {
 	toolsCmd.AddCommand(toolsSwCmd)
+	toolsCmd.AddCommand(toolsReadLimitCmd)
 	rootCmd.AddCommand(toolsCmd)
 }
 
@@ -41,7 +43,7 @@ Use 'gllm tools sw' to select which tools to enable for the current agent.`,
 	// Add completion support
 	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		if len(args) == 0 {
-			return []string{"list", "switch", "--help"}, cobra.ShellCompDirectiveNoFileComp
+			return []string{"list", "switch", "read-limit", "--help"}, cobra.ShellCompDirectiveNoFileComp
 		}
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	},
@@ -119,6 +121,30 @@ var toolsSwCmd = &cobra.Command{
 	},
 }
 
+var toolsReadLimitCmd = &cobra.Command{
+	Use:   "read-limit [TOKENS]",
+	Short: "Show or set the token limit of a single read_file result",
+	Long: `Caps how much of a file read_file returns in one call, larger reads are truncated
+with a notice telling the model where to continue. Use 0 to restore the default.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := data.GetSettingsStore()
+		if len(args) == 0 {
+			util.Printf(cmd, "read_file token limit: %d\n", store.GetReadMaxTokens())
+			return nil
+		}
+		tokens, err := strconv.Atoi(args[0])
+		if err != nil || tokens < 0 {
+			return fmt.Errorf("invalid token limit '%s'", args[0])
+		}
+		if err := store.SetReadMaxTokens(tokens); err != nil {
+			return err
+		}
+		util.Printf(cmd, "read_file token limit set to %d\n", store.GetReadMaxTokens())
+		return nil
+	},
+}
+
 func GetAllTools(agent *data.AgentConfig) string {
 	allTools := service.GetAllOpenTools()
 
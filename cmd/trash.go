@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+var trashSession string
+
+func init() {
+	rootCmd.AddCommand(trashCmd)
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashCmd.AddCommand(trashEmptyCmd)
+	trashListCmd.Flags().StringVarP(&trashSession, "session", "s", "", "Only the files deleted in this session")
+	trashEmptyCmd.Flags().StringVarP(&trashSession, "session", "s", "", "Only the files deleted in this session")
+}
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "Restore files deleted by agents",
+	Long: `delete_file and delete_directory move what they delete to the trash of the session,
+restore_deleted or 'gllm trash restore' put it back where it was.
+'gllm config set tools.hard_delete on' deletes files for good instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+var trashListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List the deleted files in the trash",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entries, err := data.ListTrash()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		listed := 0
+		for _, e := range entries {
+			if trashSession != "" && e.Session != trashSession {
+				continue
+			}
+			if listed == 0 {
+				fmt.Fprintln(w, "ID\tTIME\tSESSION\tSIZE\tPATH")
+			}
+			path := e.Path
+			if e.IsDir {
+				path += string(filepath.Separator)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", e.ID, e.Time.Local().Format("2006-01-02 15:04:05"),
+				e.Session, formatCacheBytes(e.Size), path)
+			listed++
+		}
+		w.Flush()
+		if listed == 0 {
+			util.Println(cmd, "The trash is empty.")
+		}
+		return nil
+	},
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore <id|path>",
+	Short: "Put a deleted file or directory back where it was",
+	Long: `Restores the entry with the given ID, or the latest file or directory deleted from
+the path. Nothing is overwritten: the restore fails when the path exists again.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		entry, err := data.FindTrashEntry(args[0])
+		if err != nil {
+			entry, err = findTrashByPath(args[0])
+			if err != nil {
+				return err
+			}
+		}
+		if err := service.RestoreTrashEntry(entry); err != nil {
+			return err
+		}
+		util.Printf(cmd, "Restored %s\n", entry.Path)
+		return nil
+	},
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Delete the files in the trash for good",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		n, err := service.EmptyTrash(trashSession, trashSession == "")
+		if err != nil {
+			return err
+		}
+		util.Printf(cmd, "Deleted %d entries from the trash.\n", n)
+		return nil
+	},
+}
+
+// findTrashByPath returns the latest entry of any session deleted from the path.
+func findTrashByPath(path string) (*data.TrashEntry, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := data.ListTrash()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.Path == abs {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("'%s' is neither a trash entry nor a deleted path", path)
+}
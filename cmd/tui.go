@@ -0,0 +1,849 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/internal/event"
+	"github.com/activebook/gllm/internal/ui"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(tuiCmd)
+	tuiCmd.Flags().StringP("agent", "g", "", "Agent to use")
+	tuiCmd.Flags().StringP("session", "s", "", "Session to open, by name or index (a new one by default)")
+}
+
+var tuiCmd = &cobra.Command{
+	Use:   "tui",
+	Short: "Start a full-screen dashboard",
+	Long: `Start a full-screen dashboard to chat with the active agent.
+The conversation streams on the left. The right side shows the tool calls of the
+current turn, the sub-agent tasks, the token usage with its estimated cost, and the
+sessions to switch between.
+
+Keys:
+  enter        Send the prompt, or open the selected session
+  tab          Move between the prompt and the session list
+  pgup/pgdown  Scroll the conversation
+  esc          Cancel the running turn
+  ctrl+n       Start a new session
+  ctrl+c       Quit
+
+Tool calls that need confirmation are answered in place: y allow once, t allow the tool
+for this session, p allow the command prefix for this session, a allow all tools,
+n deny, f deny and tell the model why.
+The cost is estimated from the model prices, set them with
+'gllm model set NAME --input_price 2.5 --output_price 10'.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		store := data.NewConfigStore()
+		if name, _ := cmd.Flags().GetString("agent"); name != "" {
+			if store.GetAgent(name) == nil {
+				return fmt.Errorf("agent %s does not exist", name)
+			}
+			store.SetActiveAgent(name)
+		}
+		if _, err := EnsureActiveAgent(); err != nil {
+			return err
+		}
+
+		session, _ := cmd.Flags().GetString("session")
+		if session == "" {
+			session = GenerateSessionName()
+		} else if name, err := service.FindSessionByIndex(session); err != nil {
+			return fmt.Errorf("error finding session: %v", err)
+		} else if name != "" {
+			session = name
+		}
+
+		state := data.NewSharedState()
+		defer state.Clear()
+
+		bridge := &tuiBridge{quit: make(chan struct{})}
+		m := newTUIModel(bridge, state)
+		m.openSession(session)
+		p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
+		bridge.p = p
+
+		// The dashboard owns the terminal, logs show up in its status line
+		util.SetLoggerOutput(bridge)
+		defer util.SetLoggerOutput(os.Stderr)
+
+		_, err := p.Run()
+		close(bridge.quit)
+		return err
+	},
+}
+
+// tuiBridge connects an agent run to the dashboard: it forwards the stream events and
+// asks the dashboard for confirmations and answers. Calls come from the agent goroutines.
+type tuiBridge struct {
+	p    *tea.Program
+	quit chan struct{} // Closed when the dashboard exits, releasing waiting requests
+}
+
+// Messages sent to the dashboard
+type (
+	tuiDataMsg     service.StreamData
+	tuiNotifyMsg   service.StreamNotify
+	tuiSubAgentMsg service.SubAgentResult
+	tuiLogMsg      string
+	tuiConfirmMsg  struct {
+		description string
+		toolsUse    *data.ToolsUse
+		done        chan struct{}
+	}
+	tuiAskMsg struct {
+		req  event.AskUserRequest
+		resp chan event.AskUserResponse
+	}
+	tuiTurnDoneMsg struct {
+		usage service.TokenUsage
+		cost  float64
+		err   error
+	}
+)
+
+func (b *tuiBridge) OnData(d service.StreamData)         { b.p.Send(tuiDataMsg(d)) }
+func (b *tuiBridge) OnNotify(n service.StreamNotify)     { b.p.Send(tuiNotifyMsg(n)) }
+func (b *tuiBridge) OnSubAgent(r service.SubAgentResult) { b.p.Send(tuiSubAgentMsg(r)) }
+
+func (b *tuiBridge) RequestConfirm(description string, toolsUse *data.ToolsUse) {
+	if toolsUse.AutoApprove {
+		toolsUse.Confirm = data.ToolConfirmYes
+		return
+	}
+	done := make(chan struct{})
+	b.p.Send(tuiConfirmMsg{description: description, toolsUse: toolsUse, done: done})
+	select {
+	case <-done:
+	case <-b.quit:
+		toolsUse.ConfirmCancel()
+	}
+}
+
+func (b *tuiBridge) RequestAskUser(req event.AskUserRequest) (event.AskUserResponse, error) {
+	respCh := make(chan event.AskUserResponse, 1)
+	b.p.Send(tuiAskMsg{req: req, resp: respCh})
+	var resp event.AskUserResponse
+	select {
+	case resp = <-respCh:
+	case <-b.quit:
+		resp = event.AskUserResponse{Cancelled: true}
+	}
+	if resp.Cancelled {
+		return resp, fmt.Errorf("user cancelled")
+	}
+	return resp, nil
+}
+
+func (b *tuiBridge) RequestDiff(before, after string, contextLines int) string {
+	diff, _ := ui.DiffForConfirm(before, after, contextLines)
+	return diff
+}
+
+// Write receives the log. It may be called from the dashboard's own update, so it never waits.
+func (b *tuiBridge) Write(p []byte) (int, error) {
+	line := strings.TrimSpace(string(p))
+	// Keep only the message of logrus' key=value lines
+	if i := strings.Index(line, "msg="); i >= 0 {
+		line = line[i+len("msg="):]
+		if s, err := strconv.Unquote(line); err == nil {
+			line = s
+		}
+	}
+	go b.p.Send(tuiLogMsg(strings.TrimSpace(line)))
+	return len(p), nil
+}
+
+// runTUITurn runs a prompt like RunAgent, with the dashboard rendering the stream and answering
+// the confirmations. It returns the token usage of the turn and its estimated cost.
+func runTUITurn(ctx context.Context, prompt, sessionName string, state *data.SharedState, bridge *tuiBridge) (service.TokenUsage, float64, error) {
+	var usage service.TokenUsage
+	var cost float64
+	notify := newNotifyTimer()
+	for {
+		agent, err := EnsureActiveAgent()
+		if err != nil {
+			return usage, cost, err
+		}
+		hook := service.SessionConvertHook{OnStartConvert: func() {}, OnFinishedConvert: func() {}}
+		if err := service.EnsureSessionCompatibility(agent, sessionName, hook); err != nil {
+			return usage, cost, err
+		}
+		mcpConfig, err := data.NewMCPStore().Load()
+		if err != nil {
+			return usage, cost, err
+		}
+
+		tu := service.NewTokenUsage()
+		op := service.AgentOptions{
+			Ctx:            ctx,
+			Prompt:         buildFinalPrompt(prompt, ""),
+			SysPrompt:      agent.SystemPrompt,
+			ModelInfo:      &agent.Model,
+			MaxRecursions:  agent.MaxRecursions,
+			ThinkingLevel:  agent.Think,
+			EnabledTools:   agent.Tools,
+			AutoFormat:     agent.AutoFormat,
+			Shell:          agent.Shell,
+			InjectionGuard: agent.InjectionGuard,
+			Paths:          agent.Paths,
+			Capabilities:   agent.Capabilities,
+			YoloMode:       data.GetYoloModeInSession(),
+			QuietMode:      true, // The dashboard renders through the observer
+			SessionName:    sessionName,
+			MCPConfig:      mcpConfig,
+			Interaction:    notify.Wrap(bridge),
+			TokenUsage:     tu,
+			Observer:       bridge,
+			SharedState:    state,
+			AgentName:      agent.Name,
+			ModelName:      agent.Model.Name,
+		}
+		err = service.CallAgent(&op)
+		if !service.IsSwitchAgentError(err) && ctx.Err() == nil {
+			notify.Done(agent.Name, err)
+		}
+		usage.RecordTokenUsage(tu.InputTokens, tu.OutputTokens, tu.CachedTokens, tu.ThoughtTokens, tu.TotalTokens)
+		cost += tu.Cost(&agent.Model)
+
+		// Switching agents continues the turn with the new agent
+		if switchErr, ok := service.AsSwitchAgentError(err); ok {
+			if prompt = switchErr.Prompt(); prompt == "" {
+				return usage, cost, nil
+			}
+			continue
+		}
+		return usage, cost, err
+	}
+}
+
+type tuiPane int
+
+const (
+	tuiPanePrompt tuiPane = iota
+	tuiPaneSessions
+)
+
+// tuiToolCall is a tool call of the current turn.
+type tuiToolCall struct {
+	name string
+	args string
+	done bool
+}
+
+type tuiModel struct {
+	bridge *tuiBridge
+	state  *data.SharedState
+
+	width, height int
+	focus         tuiPane
+	input         textinput.Model
+	conversation  viewport.Model
+	spinner       spinner.Model
+
+	session    string
+	transcript strings.Builder
+	tools      []tuiToolCall
+	tasks      []service.SubAgentResult
+	usage      service.TokenUsage // Of all turns since the dashboard started
+	cost       float64
+	sessions   []service.SessionMeta
+	selected   int // Selected row of the session list
+
+	running  bool
+	cancel   context.CancelFunc
+	confirm  *tuiConfirmMsg // Pending tool confirmation
+	ask      *tuiAskMsg     // Pending question to the user
+	feedback bool           // Typing the reason of a denied tool call
+	status   string         // Last log line
+}
+
+func newTUIModel(bridge *tuiBridge, state *data.SharedState) *tuiModel {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.Placeholder = "Ask anything, @file to attach"
+	input.Focus()
+
+	sp := spinner.New()
+	sp.Spinner = spinner.Dot
+	sp.Style = lipgloss.NewStyle().Foreground(lipgloss.Color(data.SpinnerHex))
+
+	return &tuiModel{
+		bridge:       bridge,
+		state:        state,
+		input:        input,
+		conversation: viewport.New(0, 0),
+		spinner:      sp,
+	}
+}
+
+func (m *tuiModel) Init() tea.Cmd {
+	return tea.Batch(textinput.Blink, m.spinner.Tick)
+}
+
+func (m *tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmds []tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+
+	case tea.KeyMsg:
+		if cmd, handled := m.handleKey(msg); handled {
+			m.layout()
+			return m, cmd
+		}
+
+	case tea.MouseMsg:
+		var cmd tea.Cmd
+		m.conversation, cmd = m.conversation.Update(msg)
+		cmds = append(cmds, cmd)
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		cmds = append(cmds, cmd)
+
+	case tuiDataMsg:
+		switch msg.Type {
+		case service.DataTypeNormal:
+			m.write(msg.Text)
+		case service.DataTypeReasoning:
+			m.write(tuiColor(data.LabelHex).Italic(true).Render(msg.Text))
+		case service.DataTypeFinished:
+			m.write("\n")
+		}
+
+	case tuiNotifyMsg:
+		m.handleNotify(service.StreamNotify(msg))
+
+	case tuiSubAgentMsg:
+		m.updateTask(service.SubAgentResult(msg))
+
+	case tuiLogMsg:
+		m.status = string(msg)
+
+	case tuiConfirmMsg:
+		m.confirm = &msg
+		m.focus = tuiPanePrompt
+
+	case tuiAskMsg:
+		m.ask = &msg
+		m.focus = tuiPanePrompt
+		m.write("\n" + tuiColor(data.LabelHex).Render("? "+msg.req.Question) + "\n")
+		for i, option := range msg.req.Options {
+			m.write(fmt.Sprintf("  %d. %s\n", i+1, option))
+		}
+
+	case tuiTurnDoneMsg:
+		m.running = false
+		m.cancel = nil
+		m.usage.RecordTokenUsage(msg.usage.InputTokens, msg.usage.OutputTokens, msg.usage.CachedTokens, msg.usage.ThoughtTokens, msg.usage.TotalTokens)
+		m.cost += msg.cost
+		if msg.err != nil {
+			// A cancelled context surfaces as the provider's error text
+			if service.IsUserCancelError(msg.err) || strings.Contains(msg.err.Error(), context.Canceled.Error()) {
+				m.write("\n" + tuiColor(data.WarnStatusHex).Render("Cancelled.") + "\n")
+			} else {
+				m.write("\n" + tuiColor(data.CurrentTheme.Red).Render(msg.err.Error()) + "\n")
+			}
+		}
+		m.write("\n")
+		m.loadSessions()
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	cmds = append(cmds, cmd)
+	m.layout()
+	return m, tea.Batch(cmds...)
+}
+
+// handleKey handles the keys of the dashboard, other keys go to the prompt.
+func (m *tuiModel) handleKey(msg tea.KeyMsg) (tea.Cmd, bool) {
+	key := msg.String()
+	if key == "ctrl+c" {
+		m.shutdown()
+		return tea.Quit, true
+	}
+	if m.confirm != nil && !m.feedback {
+		m.answerConfirm(key)
+		return nil, true
+	}
+
+	switch key {
+	case "esc":
+		if m.feedback {
+			m.feedback = false
+			m.input.Reset()
+			return nil, true
+		}
+		if m.ask != nil {
+			m.ask.resp <- event.AskUserResponse{Cancelled: true}
+			m.ask = nil
+			return nil, true
+		}
+		if m.running && m.cancel != nil {
+			m.cancel()
+		}
+		return nil, true
+	case "tab", "shift+tab":
+		if m.focus == tuiPanePrompt {
+			m.focus = tuiPaneSessions
+			m.input.Blur()
+		} else {
+			m.focus = tuiPanePrompt
+			m.input.Focus()
+		}
+		return nil, true
+	case "pgup", "pgdown":
+		m.conversation, _ = m.conversation.Update(msg)
+		return nil, true
+	case "ctrl+n":
+		if !m.running {
+			m.openSession(GenerateSessionName())
+		}
+		return nil, true
+	}
+
+	if m.focus == tuiPaneSessions {
+		switch key {
+		case "up", "k":
+			m.selected = max(m.selected-1, 0)
+		case "down", "j":
+			m.selected = min(m.selected+1, len(m.sessions)-1)
+		case "enter":
+			if !m.running && m.selected >= 0 && m.selected < len(m.sessions) {
+				m.openSession(m.sessions[m.selected].Name)
+				m.focus = tuiPanePrompt
+				m.input.Focus()
+			}
+		}
+		return nil, true
+	}
+
+	if key == "enter" {
+		return m.submit(), true
+	}
+	return nil, false
+}
+
+// submit sends the prompt, or answers the pending question or denial.
+func (m *tuiModel) submit() tea.Cmd {
+	text := strings.TrimSpace(m.input.Value())
+	if text == "" {
+		return nil
+	}
+
+	switch {
+	case m.feedback:
+		m.input.Reset()
+		m.confirm.toolsUse.ConfirmDeny(text)
+		m.write(tuiColor(data.WarnStatusHex).Render("Denied: "+text) + "\n")
+		m.feedback = false
+		m.releaseConfirm()
+		return nil
+	case m.ask != nil:
+		m.input.Reset()
+		m.write(tuiColor(data.LabelHex).Render("> "+text) + "\n")
+		m.ask.resp <- tuiAnswer(m.ask.req, text)
+		m.ask = nil
+		return nil
+	case m.running:
+		return nil
+	}
+
+	m.input.Reset()
+	m.write(tuiColor(data.SectionHex).Bold(true).Render("> "+text) + "\n\n")
+	m.conversation.GotoBottom()
+	m.tools = nil
+	m.tasks = nil
+	m.running = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	session, state, bridge := m.session, m.state, m.bridge
+	return func() tea.Msg {
+		defer cancel()
+		usage, cost, err := runTUITurn(ctx, text, session, state, bridge)
+		return tuiTurnDoneMsg{usage: usage, cost: cost, err: err}
+	}
+}
+
+// answerConfirm decides on the pending tool call with the key pressed.
+func (m *tuiModel) answerConfirm(key string) {
+	tu := m.confirm.toolsUse
+	switch key {
+	case "y", "enter":
+		tu.ConfirmOnce()
+	case "t":
+		if tu.Tool == "" {
+			return
+		}
+		tu.ConfirmTool()
+	case "p":
+		prefix := data.CommandPrefix(tu.Command)
+		if prefix == "" {
+			return
+		}
+		tu.ConfirmCommandPrefix(prefix)
+	case "a":
+		tu.ConfirmAlways()
+		// Like the prompt in the terminal, allowing all tools turns on yolo for the session
+		if planMode, yoloMode := data.GetSessionMode(); !planMode && !yoloMode {
+			data.SetYoloModeInSession(true)
+		}
+	case "n", "esc":
+		tu.ConfirmCancel()
+	case "f":
+		m.feedback = true
+		m.focus = tuiPanePrompt
+		m.input.Focus()
+		return
+	default:
+		return
+	}
+	m.releaseConfirm()
+}
+
+func (m *tuiModel) releaseConfirm() {
+	close(m.confirm.done)
+	m.confirm = nil
+}
+
+// shutdown cancels the running turn and releases the requests waiting for an answer.
+func (m *tuiModel) shutdown() {
+	if m.cancel != nil {
+		m.cancel()
+	}
+	if m.confirm != nil {
+		m.confirm.toolsUse.ConfirmCancel()
+		m.releaseConfirm()
+	}
+	if m.ask != nil {
+		m.ask.resp <- event.AskUserResponse{Cancelled: true}
+		m.ask = nil
+	}
+}
+
+func (m *tuiModel) handleNotify(notify service.StreamNotify) {
+	switch notify.Status {
+	case service.StatusReasoning:
+		m.write(tuiColor(data.LabelHex).Italic(true).Render("Thinking...") + "\n")
+	case service.StatusReasoningOver:
+		m.write("\n\n")
+	case service.StatusFunctionCalling:
+		var call struct {
+			Function string      `json:"function"`
+			Args     interface{} `json:"args"`
+		}
+		if err := json.Unmarshal([]byte(notify.Data), &call); err != nil || call.Function == "" {
+			call.Function = notify.Data
+		}
+		var args string
+		if call.Args != nil {
+			b, _ := json.Marshal(call.Args)
+			args = string(b)
+		}
+		m.tools = append(m.tools, tuiToolCall{name: call.Function, args: args})
+		m.write("\n" + tuiColor(data.KeyHex).Bold(true).Render("⚙ "+call.Function) + " " + tuiColor(data.LabelHex).Render(benchPreview(args, 80)) + "\n")
+	case service.StatusFunctionCallingOver:
+		for i := len(m.tools) - 1; i >= 0; i-- {
+			if !m.tools[i].done {
+				m.tools[i].done = true
+				break
+			}
+		}
+	case service.StatusShowDiff, service.StatusShowTodos:
+		m.write(notify.Data + "\n")
+	case service.StatusWarn:
+		m.write(tuiColor(data.WarnStatusHex).Render(notify.Data) + "\n")
+	}
+}
+
+// updateTask records the latest status of a sub-agent task.
+func (m *tuiModel) updateTask(result service.SubAgentResult) {
+	for i, task := range m.tasks {
+		if task.AgentName == result.AgentName && task.TaskKey == result.TaskKey {
+			m.tasks[i] = result
+			return
+		}
+	}
+	m.tasks = append(m.tasks, result)
+}
+
+// openSession switches to a session and shows its history.
+func (m *tuiModel) openSession(name string) {
+	m.session = name
+	m.transcript.Reset()
+	m.tools = nil
+	m.tasks = nil
+	if agent, err := EnsureActiveAgent(); err == nil {
+		history, notice, err := service.RenderSessionHistory(agent, name)
+		switch {
+		case err != nil:
+			m.write(tuiColor(data.CurrentTheme.Red).Render(err.Error()) + "\n")
+		case !util.IsEmpty(history):
+			m.write(tuiColor(data.LabelHex).Render("── Resuming session: "+name+" ──") + "\n\n" + history + "\n")
+			if !util.IsEmpty(notice) {
+				m.write(tuiColor(data.WarnStatusHex).Render(notice) + "\n")
+			}
+		}
+	}
+	m.loadSessions()
+	m.conversation.GotoBottom()
+}
+
+func (m *tuiModel) loadSessions() {
+	sessions, err := service.ListSortedSessions(false, false)
+	if err != nil {
+		m.status = err.Error()
+		return
+	}
+	m.sessions = sessions
+	m.selected = 0
+	for i, s := range sessions {
+		if s.Name == m.session {
+			m.selected = i
+		}
+	}
+}
+
+// write appends to the conversation, following it when it is scrolled to the end.
+func (m *tuiModel) write(text string) {
+	atBottom := m.conversation.AtBottom()
+	m.transcript.WriteString(text)
+	m.conversation.SetContent(lipgloss.NewStyle().Width(max(m.conversation.Width, 1)).Render(m.transcript.String()))
+	if atBottom {
+		m.conversation.GotoBottom()
+	}
+}
+
+// Styles are built when rendering, as the theme is loaded after start up
+func tuiColor(hex string) lipgloss.Style {
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(hex))
+}
+
+func tuiBox(focused bool) lipgloss.Style {
+	border := data.BorderHex
+	if focused {
+		border = data.SectionHex
+	}
+	return lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color(border))
+}
+
+const tuiSideWidth = 38 // Width of the side panes, borders included
+
+// layout sizes the conversation to the space left by the prompt, or the pending confirmation.
+func (m *tuiModel) layout() {
+	if m.width == 0 {
+		return
+	}
+	mainWidth := max(m.width-tuiSideWidth, 20)
+	m.input.Width = mainWidth - 6
+	height := m.height - lipgloss.Height(m.bottomView()) - 1 - 2 // status line and borders
+	width := mainWidth - 2
+	if m.conversation.Width != width {
+		m.conversation.Width = width
+		m.conversation.SetContent(lipgloss.NewStyle().Width(width).Render(m.transcript.String()))
+	}
+	m.conversation.Height = max(height, 1)
+}
+
+func (m *tuiModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+	mainWidth := max(m.width-tuiSideWidth, 20)
+	main := lipgloss.JoinVertical(lipgloss.Left,
+		tuiBox(false).Width(mainWidth-2).Render(m.conversation.View()),
+		m.bottomView(),
+	)
+	side := m.sideView(m.height - 1)
+	return lipgloss.JoinVertical(lipgloss.Left,
+		lipgloss.JoinHorizontal(lipgloss.Top, main, side),
+		m.statusView(),
+	)
+}
+
+// bottomView is the prompt, or the pending tool confirmation.
+func (m *tuiModel) bottomView() string {
+	width := max(m.width-tuiSideWidth, 20) - 2
+	if m.confirm != nil && !m.feedback {
+		description := strings.TrimSpace(m.confirm.description)
+		if lines := strings.Split(description, "\n"); len(lines) > 8 {
+			description = strings.Join(lines[:8], "\n") + "\n..."
+		}
+		keys := "y allow once"
+		if m.confirm.toolsUse.Tool != "" {
+			keys += " · t allow " + m.confirm.toolsUse.Tool
+		}
+		if prefix := data.CommandPrefix(m.confirm.toolsUse.Command); prefix != "" {
+			keys += " · p allow '" + prefix + "'"
+		}
+		keys += " · a allow all · n deny · f deny with feedback"
+		content := tuiColor(data.SectionHex).Bold(true).Render("Confirm tool call") + "\n" + description + "\n" + tuiColor(data.LabelHex).Render(keys)
+		return tuiBox(true).Width(width).Render(lipgloss.NewStyle().Width(width).Render(content))
+	}
+
+	title := "Prompt"
+	switch {
+	case m.feedback:
+		title = "Tell the model why the call is denied (esc to go back)"
+	case m.ask != nil:
+		title = "Answer (esc to cancel)"
+	case m.running:
+		title = m.spinner.View() + " Working (esc to cancel)"
+	}
+	return tuiBox(m.focus == tuiPanePrompt).Width(width).Render(tuiColor(data.SectionHex).Bold(true).Render(title) + "\n" + m.input.View())
+}
+
+// sideView stacks the tool, sub-agent, usage and session panes.
+func (m *tuiModel) sideView(height int) string {
+	width := tuiSideWidth - 2
+	box := func(style lipgloss.Style, title string, lines []string, rows int) string {
+		if len(lines) > rows {
+			lines = lines[len(lines)-rows:]
+		}
+		for i, line := range lines {
+			lines[i] = lipgloss.NewStyle().MaxWidth(width).Render(line)
+		}
+		for len(lines) < rows {
+			lines = append(lines, "")
+		}
+		return style.Width(width).Render(tuiColor(data.SectionHex).Bold(true).Render(title) + "\n" + strings.Join(lines, "\n"))
+	}
+
+	var tools []string
+	for _, call := range m.tools {
+		mark := m.spinner.View()
+		if call.done {
+			mark = tuiColor(data.CurrentTheme.Green).Render("✓")
+		}
+		tools = append(tools, fmt.Sprintf("%s %s %s", mark, call.name, tuiColor(data.LabelHex).Render(call.args)))
+	}
+	if len(tools) == 0 {
+		tools = append(tools, tuiColor(data.LabelHex).Render("No tool calls"))
+	}
+
+	var tasks []string
+	for _, task := range m.tasks {
+		mark := m.spinner.View()
+		detail := time.Since(task.StartTime).Round(time.Second).String()
+		switch task.Status {
+		case service.StatusCompleted:
+			mark, detail = tuiColor(data.CurrentTheme.Green).Render("✓"), task.Duration.Round(time.Second).String()
+		case service.StatusFailed, service.StatusCancelled:
+			mark, detail = tuiColor(data.CurrentTheme.Red).Render("✗"), task.Status.String()
+		}
+		tasks = append(tasks, fmt.Sprintf("%s %s %s %s", mark, task.AgentName, task.TaskKey, tuiColor(data.LabelHex).Render(detail)))
+	}
+	if len(tasks) == 0 {
+		tasks = append(tasks, tuiColor(data.LabelHex).Render("No sub-agent tasks"))
+	}
+
+	cost := tuiColor(data.LabelHex).Render("n/a, no model prices")
+	if m.cost > 0 {
+		cost = fmt.Sprintf("$%.4f", m.cost)
+	}
+	usage := []string{
+		fmt.Sprintf("Input    %10d", m.usage.InputTokens),
+		fmt.Sprintf("Output   %10d", m.usage.OutputTokens),
+		fmt.Sprintf("Cached   %10d", m.usage.CachedTokens),
+		fmt.Sprintf("Thought  %10d", m.usage.ThoughtTokens),
+		fmt.Sprintf("Total    %10d", m.usage.TotalTokens),
+		"Cost     " + cost,
+	}
+
+	// Each pane takes its rows plus the title and the borders
+	toolRows := max((height-len(usage)-3*4)/3, 1)
+	taskRows := max(toolRows/2, 1)
+	sessionRows := max(height-toolRows-taskRows-len(usage)-3*4, 1)
+
+	var sessions []string
+	start := max(m.selected-sessionRows+1, 0)
+	for i := start; i < len(m.sessions) && len(sessions) < sessionRows; i++ {
+		name := m.sessions[i].Name
+		if name == m.session {
+			name = "● " + name
+		} else {
+			name = "  " + name
+		}
+		if i == m.selected && m.focus == tuiPaneSessions {
+			name = tuiColor(data.SectionHex).Bold(true).Render(name)
+		}
+		sessions = append(sessions, name)
+	}
+	if len(sessions) == 0 {
+		sessions = append(sessions, tuiColor(data.LabelHex).Render("No saved sessions"))
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left,
+		box(tuiBox(false), "Tool calls", tools, toolRows),
+		box(tuiBox(false), "Sub-agents", tasks, taskRows),
+		box(tuiBox(false), "Usage", usage, len(usage)),
+		box(tuiBox(m.focus == tuiPaneSessions), "Sessions", sessions, sessionRows),
+	)
+}
+
+func (m *tuiModel) statusView() string {
+	agent := data.NewConfigStore().GetActiveAgentName()
+	parts := []string{"agent " + agent, "session " + m.session}
+	if m.status != "" {
+		parts = append(parts, m.status)
+	} else {
+		parts = append(parts, "tab sessions · ctrl+n new · ctrl+c quit")
+	}
+	return lipgloss.NewStyle().MaxWidth(m.width).Render(tuiColor(data.LabelHex).Render(" " + strings.Join(parts, " · ")))
+}
+
+// tuiAnswer turns the typed text into the answer of a question, options are picked by number or text.
+func tuiAnswer(req event.AskUserRequest, text string) event.AskUserResponse {
+	option := func(s string) string {
+		s = strings.TrimSpace(s)
+		if n, err := strconv.Atoi(s); err == nil && n >= 1 && n <= len(req.Options) {
+			return req.Options[n-1]
+		}
+		return s
+	}
+	switch req.QuestionType {
+	case "multiselect":
+		var answers []string
+		for _, s := range strings.Split(text, ",") {
+			if s = option(s); s != "" {
+				answers = append(answers, s)
+			}
+		}
+		return event.AskUserResponse{Answers: answers}
+	case "confirm":
+		yes, no := "Yes", "No"
+		if len(req.Options) > 1 {
+			yes, no = req.Options[0], req.Options[1]
+		}
+		switch strings.ToLower(option(text)) {
+		case "y", "yes", strings.ToLower(yes):
+			return event.AskUserResponse{Answer: yes}
+		default:
+			return event.AskUserResponse{Answer: no}
+		}
+	default:
+		return event.AskUserResponse{Answer: option(text)}
+	}
+}
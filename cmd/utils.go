@@ -5,17 +5,16 @@ package cmd
 import (
 	"bufio"
 	"bytes"
-	"context"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
-	"time"
 
-	"github.com/activebook/gllm/service"
 	"github.com/activebook/gllm/util"
+	"golang.org/x/term"
 )
 
 // readStdin checks if there's piped input and reads it
@@ -55,6 +54,24 @@ func hasStdinData() bool {
 	return (stat.Mode() & os.ModeCharDevice) == 0
 }
 
+// isHeadless reports whether no one can answer a prompt: stdin is not a terminal
+// and there is no controlling terminal to open instead, as in cron jobs and CI.
+func isHeadless() bool {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return false
+	}
+	name := "/dev/tty"
+	if runtime.GOOS == "windows" {
+		name = "CONIN$"
+	}
+	tty, err := os.Open(name)
+	if err != nil {
+		return true
+	}
+	tty.Close()
+	return false
+}
+
 func checkIsLink(source string) bool {
 	return strings.HasPrefix(source, "http") || strings.HasPrefix(source, "https")
 }
@@ -91,28 +108,6 @@ func readContentFromPath(source string) ([]byte, error) {
 	if source == "-" {
 		return io.ReadAll(os.Stdin)
 	}
-	if checkIsLink(source) {
-		// Fetch content from the URL
-		urls := []string{source}
-		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-		defer cancel()
-
-		results := service.FetchProcess(ctx, urls)
-		if len(results) == 0 {
-			return nil, fmt.Errorf("failed to fetch URL: no results returned")
-		}
-
-		res := results[0]
-		if res.Error != nil {
-			return nil, res.Error
-		}
-
-		if res.Content == "" {
-			return nil, fmt.Errorf("failed to fetch URL: content is empty")
-		}
-
-		return []byte(res.Content), nil
-	}
 	return os.ReadFile(source)
 }
 
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/activebook/gllm/util"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+
+	watchCmd.Flags().StringSlice("glob", []string{"**/*"}, "Files to watch, relative to the directory; ** matches any directories (repeatable)")
+	watchCmd.Flags().String("dir", ".", "Directory to watch")
+	watchCmd.Flags().Duration("debounce", service.DefaultWatchDebounce, "Wait for changes to settle this long before a rerun")
+	watchCmd.Flags().Bool("no-diff", false, "Only list the changed files, without their git diff")
+	watchCmd.Flags().Bool("run-now", false, "Run once at start, before any change")
+	watchCmd.Flags().StringP("agent", "g", "", "Switch to the agent to use")
+	watchCmd.Flags().StringP("session", "s", "", "Session every run continues (empty for independent runs)")
+}
+
+var watchCmd = &cobra.Command{
+	Use:   "watch PROMPT",
+	Short: "Rerun a prompt whenever watched files change",
+	Long: `Watch files and rerun the prompt whenever they change.
+Changes are collected until they settle, then the prompt runs with the list of changed
+files and their git diff against HEAD appended, for continuous reviews or docs kept in sync.
+
+Hidden directories, .git, node_modules, vendor and __pycache__ are not watched. Changes made
+while the agent runs, including its own edits, do not trigger another run.
+Press Ctrl+C during a run to cancel it, or while waiting to stop watching.`,
+	Example: `  gllm watch --glob "src/**/*.go" "Review the changed files"
+  gllm watch --glob "*.go" --glob "docs/*.md" -s docs "Update the docs to match the changed code"`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		prompt := strings.Join(args, " ")
+		globs, _ := cmd.Flags().GetStringSlice("glob")
+		dir, _ := cmd.Flags().GetString("dir")
+		debounce, _ := cmd.Flags().GetDuration("debounce")
+		noDiff, _ := cmd.Flags().GetBool("no-diff")
+		runNow, _ := cmd.Flags().GetBool("run-now")
+		session, _ := cmd.Flags().GetString("session")
+
+		if name, _ := cmd.Flags().GetString("agent"); name != "" {
+			store := data.NewConfigStore()
+			if store.GetAgent(name) == nil {
+				util.Errorf(cmd, "agent %s does not exist\n", name)
+				return
+			}
+			store.SetActiveAgent(name)
+		}
+
+		watcher, err := service.NewFileWatcher(dir, globs, debounce)
+		if err != nil {
+			util.Errorf(cmd, "%v\n", err)
+			return
+		}
+		defer watcher.Close()
+
+		if runNow {
+			if err := RunAgent(prompt, "", nil, session, "", nil); err != nil {
+				util.Errorf(cmd, "%v\n", err)
+			}
+			watcher.Discard()
+		}
+
+		for {
+			util.Printf(cmd, "Watching %s for %s, press Ctrl+C to stop.\n", watcher.Root(), strings.Join(globs, ", "))
+			files, err := waitForChanges(watcher)
+			if err != nil {
+				// Interrupted while waiting
+				return
+			}
+			util.Printf(cmd, "\n%d file(s) changed: %s\n", len(files), strings.Join(files, ", "))
+
+			diff := ""
+			if !noDiff {
+				diff = service.WatchDiff(context.Background(), watcher.Root(), files)
+			}
+			finalPrompt := service.BuildWatchPrompt(prompt, watcher.Root(), files, diff)
+			if err := RunAgent(finalPrompt, "", nil, session, "", nil); err != nil {
+				// A failed run does not stop watching
+				util.Errorf(cmd, "%v\n", err)
+			}
+			watcher.Discard()
+		}
+	},
+}
+
+// waitForChanges waits for the next batch of changes. Ctrl+C stops the wait, while during
+// a run it only cancels the run.
+func waitForChanges(watcher *service.FileWatcher) ([]string, error) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return watcher.Next(ctx)
+}
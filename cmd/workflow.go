@@ -23,7 +23,13 @@ var workflowCmd = &cobra.Command{
 	Use:     "workflow",
 	Aliases: []string{"wf", "work", "wk"},
 	Short:   "Manage workflow commands",
-	Long:    `Manage user-defined workflow commands stored as markdown files.`,
+	Long: `Manage user-defined workflow commands stored as markdown files.
+'notify: slack:#channel' (or discord[:name]) in the frontmatter posts the final output of
+the workflow to that channel, see 'gllm config secret' for the webhooks. 'notify: email:address'
+mails it instead, see 'gllm mail'. 'approve: deploy to production' pauses the workflow before
+that action until someone approves it, in the terminal or through the approval backend of
+'gllm config approval' when there is none. 'batch: true' submits the jobs file given to the
+workflow as a batch, see 'gllm batch'.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Default action: list workflows
 		workflowListCmd.Run(workflowListCmd, args)
@@ -368,6 +374,15 @@ var workflowInfoCmd = &cobra.Command{
 		util.Printf(cmd, "%s---%s\n", data.BorderColor, data.ResetSeq)
 		util.Printf(cmd, "%sName: %s%s%s\n", data.LabelColor, data.ResetSeq, name, data.ResetSeq)
 		util.Printf(cmd, "%sDescription: %s%s%s\n", data.LabelColor, data.ResetSeq, desc, data.ResetSeq)
+		if notify := wm.GetWorkflowNotify(name); notify != "" {
+			util.Printf(cmd, "%sNotify: %s%s%s\n", data.LabelColor, data.ResetSeq, notify, data.ResetSeq)
+		}
+		if approve := wm.GetWorkflowApprove(name); approve != "" {
+			util.Printf(cmd, "%sApprove: %s%s%s\n", data.LabelColor, data.ResetSeq, approve, data.ResetSeq)
+		}
+		if wm.GetWorkflowBatch(name) {
+			util.Printf(cmd, "%sBatch: %son%s\n", data.LabelColor, data.ResetSeq, data.ResetSeq)
+		}
 		util.Printf(cmd, "%s---%s\n%s\n", data.BorderColor, data.ResetSeq, content)
 	},
 }
@@ -12,13 +12,21 @@ import (
 )
 
 type AgentFrontmatter struct {
-	Name          string   `yaml:"name"`
-	Description   string   `yaml:"description,omitempty"`
-	Model         string   `yaml:"model"`
-	Tools         []string `yaml:"tools,omitempty"`
-	Capabilities  []string `yaml:"capabilities,omitempty"`
-	Think         string   `yaml:"think,omitempty"`
-	MaxRecursions int      `yaml:"max_recursions,omitempty"`
+	Name           string     `yaml:"name"`
+	Description    string     `yaml:"description,omitempty"`
+	Extends        string     `yaml:"extends,omitempty"`
+	Model          string     `yaml:"model"`
+	Tools          []string   `yaml:"tools,omitempty"`
+	Capabilities   []string   `yaml:"capabilities,omitempty"`
+	Think          string     `yaml:"think,omitempty"`
+	MaxRecursions  int        `yaml:"max_recursions,omitempty"`
+	AutoFormat     bool       `yaml:"auto_format,omitempty"`
+	Shell          string     `yaml:"shell,omitempty"`
+	InjectionGuard string     `yaml:"injection_guard,omitempty"`
+	ShowTools      string     `yaml:"show_tools,omitempty"`
+	BudgetUSD      float64    `yaml:"budget_usd,omitempty"`
+	BestOf         int        `yaml:"best_of,omitempty"`
+	Paths          AgentPaths `yaml:"paths,omitempty"`
 }
 
 // EnsureAgentsDir creates the agents directory if it doesn't exist.
@@ -26,55 +34,161 @@ func EnsureAgentsDir() error {
 	return os.MkdirAll(GetAgentsDirPath(), 0750)
 }
 
-// ParseAgentFile reads and parses an agent .md file, returning the raw AgentConfig.
-func ParseAgentFile(path string) (*AgentConfig, error) {
+// splitAgentFile reads an agent .md file and returns its raw frontmatter and system prompt.
+func splitAgentFile(path string) (string, string, error) {
 	content, err := os.ReadFile(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read agent file: %w", err)
+		return "", "", fmt.Errorf("failed to read agent file: %w", err)
 	}
 
 	s := string(content)
 	if !strings.HasPrefix(s, "---") {
-		return nil, fmt.Errorf("agent file missing frontmatter in %s", path)
+		return "", "", fmt.Errorf("agent file missing frontmatter in %s", path)
 	}
 
 	parts := strings.SplitN(s, "---", 3)
 	if len(parts) < 3 {
-		return nil, fmt.Errorf("invalid frontmatter format in %s", path)
+		return "", "", fmt.Errorf("invalid frontmatter format in %s", path)
 	}
 
-	frontmatterStr := parts[1]
-	systemPromptStr := strings.TrimSpace(parts[2])
-
-	var meta AgentFrontmatter
-	if err := yaml.Unmarshal([]byte(frontmatterStr), &meta); err != nil {
-		return nil, fmt.Errorf("failed to parse frontmatter in %s: %w", path, err)
-	}
+	return parts[1], strings.TrimSpace(parts[2]), nil
+}
 
-	if meta.MaxRecursions == 0 {
+// agentFromFrontmatter builds an AgentConfig from parsed frontmatter.
+func agentFromFrontmatter(meta AgentFrontmatter, systemPrompt, path string) *AgentConfig {
+	if meta.MaxRecursions == 0 && meta.Extends == "" {
 		meta.MaxRecursions = 50 // default
 	}
 
 	agentName := strings.TrimSuffix(filepath.Base(path), ".md")
 
 	agent := &AgentConfig{
-		Name:          agentName,
-		Description:   meta.Description,
-		Model:         Model{Name: meta.Model},
-		Think:         meta.Think,
-		SystemPrompt:  systemPromptStr,
-		MaxRecursions: meta.MaxRecursions,
-		Tools:         meta.Tools,
-		Capabilities:  meta.Capabilities,
+		Name:           agentName,
+		Description:    meta.Description,
+		Extends:        meta.Extends,
+		Model:          Model{Name: meta.Model},
+		Think:          meta.Think,
+		SystemPrompt:   systemPrompt,
+		MaxRecursions:  meta.MaxRecursions,
+		Tools:          meta.Tools,
+		Capabilities:   meta.Capabilities,
+		AutoFormat:     meta.AutoFormat,
+		Shell:          meta.Shell,
+		InjectionGuard: meta.InjectionGuard,
+		ShowTools:      meta.ShowTools,
+		BudgetUSD:      meta.BudgetUSD,
+		BestOf:         meta.BestOf,
+		Paths:          meta.Paths,
 	}
 
 	if meta.Name != "" {
 		agent.Name = meta.Name
 	}
 
+	return agent
+}
+
+// ParseAgentFile reads and parses an agent .md file, returning the raw AgentConfig.
+// Fields inherited through extends are not resolved, use ResolveAgentFile for that.
+func ParseAgentFile(path string) (*AgentConfig, error) {
+	frontmatterStr, systemPromptStr, err := splitAgentFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var meta AgentFrontmatter
+	if err := yaml.Unmarshal([]byte(frontmatterStr), &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse frontmatter in %s: %w", path, err)
+	}
+
+	return agentFromFrontmatter(meta, systemPromptStr, path), nil
+}
+
+// AgentPromptParentPlaceholder is replaced by the parent's system prompt in an extending agent.
+const AgentPromptParentPlaceholder = "{{parent}}"
+
+// ResolveAgentFile parses an agent .md file and applies the agents it extends.
+// Frontmatter keys set in the agent override the parent's, an empty system prompt
+// inherits the parent's prompt, and {{parent}} in the prompt is replaced by it.
+func ResolveAgentFile(path string) (*AgentConfig, error) {
+	meta, systemPrompt, err := resolveAgentSource(path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round trip the merged keys through YAML to decode them like a single file
+	merged, err := yaml.Marshal(meta)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge frontmatter in %s: %w", path, err)
+	}
+	var fm AgentFrontmatter
+	if err := yaml.Unmarshal(merged, &fm); err != nil {
+		return nil, fmt.Errorf("failed to parse frontmatter in %s: %w", path, err)
+	}
+
+	agent := agentFromFrontmatter(fm, systemPrompt, path)
+	if agent.MaxRecursions == 0 {
+		agent.MaxRecursions = 50 // default
+	}
 	return agent, nil
 }
 
+// resolveAgentSource returns the frontmatter keys and system prompt of an agent with its parents applied.
+// chain holds the agents being resolved, to detect inheritance cycles.
+func resolveAgentSource(path string, chain []string) (map[string]interface{}, string, error) {
+	frontmatterStr, systemPrompt, err := splitAgentFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	meta := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(frontmatterStr), &meta); err != nil {
+		return nil, "", fmt.Errorf("failed to parse frontmatter in %s: %w", path, err)
+	}
+
+	parentName, _ := meta["extends"].(string)
+	parentName = strings.ToLower(strings.TrimSpace(parentName))
+	if parentName == "" {
+		return meta, systemPrompt, nil
+	}
+
+	chain = append(chain, strings.TrimSuffix(filepath.Base(path), ".md"))
+	for _, name := range chain {
+		if name == parentName {
+			return nil, "", fmt.Errorf("agent inheritance cycle: %s → %s", strings.Join(chain, " → "), parentName)
+		}
+	}
+	if err := util.ValidateResourceName("agent", parentName); err != nil {
+		return nil, "", fmt.Errorf("invalid extends in %s: %w", path, err)
+	}
+
+	parentPath := agentFilePath(parentName)
+	if _, err := os.Stat(parentPath); err != nil {
+		return nil, "", fmt.Errorf("agent '%s' extends '%s', which does not exist", chain[len(chain)-1], parentName)
+	}
+	parentMeta, parentPrompt, err := resolveAgentSource(parentPath, chain)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The parent's identity is not inherited
+	delete(parentMeta, "name")
+	delete(parentMeta, "extends")
+	for k, v := range meta {
+		if v == nil || v == "" {
+			continue
+		}
+		parentMeta[k] = v
+	}
+
+	switch {
+	case systemPrompt == "":
+		systemPrompt = parentPrompt
+	case strings.Contains(systemPrompt, AgentPromptParentPlaceholder):
+		systemPrompt = strings.ReplaceAll(systemPrompt, AgentPromptParentPlaceholder, parentPrompt)
+	}
+	return parentMeta, systemPrompt, nil
+}
+
 // WriteAgentFile writes an AgentConfig to a .md file in the agents directory.
 func WriteAgentFile(agent *AgentConfig) error {
 	if err := util.ValidateResourceName("agent", agent.Name); err != nil {
@@ -88,13 +202,21 @@ func WriteAgentFile(agent *AgentConfig) error {
 	filename := filepath.Join(GetAgentsDirPath(), agent.Name+".md")
 
 	meta := AgentFrontmatter{
-		Name:          agent.Name,
-		Description:   agent.Description,
-		Model:         agent.Model.Name,
-		Tools:         agent.Tools,
-		Capabilities:  agent.Capabilities,
-		Think:         agent.Think,
-		MaxRecursions: agent.MaxRecursions,
+		Name:           agent.Name,
+		Description:    agent.Description,
+		Extends:        agent.Extends,
+		Model:          agent.Model.Name,
+		Tools:          agent.Tools,
+		Capabilities:   agent.Capabilities,
+		Think:          agent.Think,
+		MaxRecursions:  agent.MaxRecursions,
+		AutoFormat:     agent.AutoFormat,
+		Shell:          agent.Shell,
+		InjectionGuard: agent.InjectionGuard,
+		ShowTools:      agent.ShowTools,
+		BudgetUSD:      agent.BudgetUSD,
+		BestOf:         agent.BestOf,
+		Paths:          agent.Paths,
 	}
 
 	yamlData, err := yaml.Marshal(&meta)
@@ -107,8 +229,6 @@ func WriteAgentFile(agent *AgentConfig) error {
 	return os.WriteFile(filename, []byte(content), 0644)
 }
 
-
-
 // ExportAgent exports an agent's .md file to the specified destination path.
 // It validates the agent exists and is well-formed before exporting.
 func ExportAgent(name, destPath string) error {
@@ -118,7 +238,7 @@ func ExportAgent(name, destPath string) error {
 	}
 
 	// Check if agent exists
-	srcPath := filepath.Join(GetAgentsDirPath(), name+".md")
+	srcPath := agentFilePath(name)
 	if _, err := os.Stat(srcPath); os.IsNotExist(err) {
 		return fmt.Errorf("agent '%s' not found", name)
 	}
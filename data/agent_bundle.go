@@ -0,0 +1,266 @@
+package data
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/activebook/gllm/util"
+	"gopkg.in/yaml.v3"
+)
+
+// AgentBundleKind identifies a gllm agent bundle file.
+const AgentBundleKind = "gllm/agent"
+
+// AgentBundleVersion is the bundle format written by ExportAgentBundle.
+const AgentBundleVersion = 1
+
+// AgentBundle is a portable, self-contained description of an agent.
+// The agent fields are resolved through extends, so the bundle works without its parents.
+// Model keys and endpoint credentials are never written.
+type AgentBundle struct {
+	Kind         string            `yaml:"kind"`
+	Version      int               `yaml:"version"`
+	Agent        AgentFrontmatter  `yaml:"agent"`
+	SystemPrompt string            `yaml:"system_prompt,omitempty"`
+	Template     string            `yaml:"template,omitempty"` // The agent's own prompt with {{parent}}, when it extends another agent
+	Model        *AgentBundleModel `yaml:"model,omitempty"`
+}
+
+// AgentBundleModel is the model definition referenced by a bundled agent, without its key.
+type AgentBundleModel struct {
+	Provider         string   `yaml:"provider"`
+	Endpoint         string   `yaml:"endpoint,omitempty"`
+	Model            string   `yaml:"model"`
+	Temperature      float32  `yaml:"temperature"`
+	TopP             float32  `yaml:"top_p"`
+	Seed             *int32   `yaml:"seed,omitempty"`
+	MaxTokens        int32    `yaml:"max_tokens,omitempty"`
+	Stop             []string `yaml:"stop,omitempty"`
+	FrequencyPenalty *float32 `yaml:"frequency_penalty,omitempty"`
+	PresencePenalty  *float32 `yaml:"presence_penalty,omitempty"`
+}
+
+// IsAgentBundleFile reports whether path names an agent bundle rather than an agent .md file.
+func IsAgentBundleFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// endpointSecretParams are query parameters that carry credentials in model endpoints.
+var endpointSecretParams = map[string]bool{
+	"key": true, "api_key": true, "apikey": true, "api-key": true, "access_token": true,
+	"token": true, "secret": true, "password": true, "sig": true, "signature": true,
+}
+
+// stripEndpointSecrets removes user info and credential query parameters from an endpoint URL.
+func stripEndpointSecrets(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return endpoint
+	}
+	u.User = nil
+	if u.RawQuery != "" {
+		q := u.Query()
+		for k := range q {
+			if endpointSecretParams[strings.ToLower(k)] {
+				q.Del(k)
+			}
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// BuildAgentBundle assembles the bundle of an agent, with its model definition and without secrets.
+func (c *ConfigStore) BuildAgentBundle(name string) (*AgentBundle, error) {
+	name = strings.ToLower(name)
+	if err := util.ValidateResourceName("agent", name); err != nil {
+		return nil, fmt.Errorf("cannot export: %w", err)
+	}
+	path := agentFilePath(name)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil, fmt.Errorf("agent '%s' not found", name)
+	}
+	agent, err := ResolveAgentFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("agent file is malformed: %w", err)
+	}
+
+	bundle := &AgentBundle{
+		Kind:    AgentBundleKind,
+		Version: AgentBundleVersion,
+		Agent: AgentFrontmatter{
+			Name:           agent.Name,
+			Description:    agent.Description,
+			Extends:        agent.Extends,
+			Model:          agent.Model.Name,
+			Tools:          agent.Tools,
+			Capabilities:   agent.Capabilities,
+			Think:          agent.Think,
+			MaxRecursions:  agent.MaxRecursions,
+			AutoFormat:     agent.AutoFormat,
+			Shell:          agent.Shell,
+			InjectionGuard: agent.InjectionGuard,
+			ShowTools:      agent.ShowTools,
+			BudgetUSD:      agent.BudgetUSD,
+			BestOf:         agent.BestOf,
+			Paths:          agent.Paths,
+		},
+		SystemPrompt: agent.SystemPrompt,
+	}
+
+	if agent.Extends != "" {
+		raw, err := ParseAgentFile(path)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Template = raw.SystemPrompt
+	}
+
+	if agent.Model.Name != "" {
+		model := c.GetModel(agent.Model.Name)
+		if model == nil {
+			return nil, fmt.Errorf("agent '%s' uses model '%s', which is not defined", agent.Name, agent.Model.Name)
+		}
+		bundle.Model = &AgentBundleModel{
+			Provider:         model.Provider,
+			Endpoint:         stripEndpointSecrets(model.Endpoint),
+			Model:            model.Model,
+			Temperature:      model.Temp,
+			TopP:             model.TopP,
+			Seed:             model.Seed,
+			MaxTokens:        model.MaxTokens,
+			Stop:             model.Stop,
+			FrequencyPenalty: model.FrequencyPenalty,
+			PresencePenalty:  model.PresencePenalty,
+		}
+	}
+	return bundle, nil
+}
+
+// ExportAgentBundle writes the bundle of an agent to destPath.
+func (c *ConfigStore) ExportAgentBundle(name, destPath string) error {
+	bundle, err := c.BuildAgentBundle(name)
+	if err != nil {
+		return err
+	}
+	content, err := yaml.Marshal(bundle)
+	if err != nil {
+		return fmt.Errorf("failed to marshal agent bundle: %w", err)
+	}
+	if err := os.WriteFile(destPath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	return nil
+}
+
+// ReadAgentBundle reads and validates an agent bundle file.
+func ReadAgentBundle(path string) (*AgentBundle, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	var bundle AgentBundle
+	if err := yaml.Unmarshal(content, &bundle); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle %s: %w", path, err)
+	}
+	if bundle.Kind != AgentBundleKind {
+		return nil, fmt.Errorf("%s is not an agent bundle (kind '%s', expected '%s')", path, bundle.Kind, AgentBundleKind)
+	}
+	if bundle.Version < 1 || bundle.Version > AgentBundleVersion {
+		return nil, fmt.Errorf("unsupported agent bundle version %d", bundle.Version)
+	}
+	bundle.Agent.Name = strings.ToLower(bundle.Agent.Name)
+	if bundle.Agent.Name == "" {
+		return nil, fmt.Errorf("agent bundle is missing the agent name")
+	}
+	if err := util.ValidateResourceName("agent", bundle.Agent.Name); err != nil {
+		return nil, fmt.Errorf("agent name is invalid: %w", err)
+	}
+	if m := bundle.Model; m != nil && (m.Provider == "" || m.Model == "") {
+		return nil, fmt.Errorf("the model in the bundle needs a provider and a model name")
+	}
+	return &bundle, nil
+}
+
+// ImportAgentBundle installs a bundled agent, adding its model when it is not defined yet.
+// An agent that extends another keeps extends and its prompt template when the parent exists here,
+// otherwise it is installed with its resolved settings. It returns notes about what needs attention.
+func (c *ConfigStore) ImportAgentBundle(bundle *AgentBundle) ([]string, error) {
+	var notes []string
+	meta := bundle.Agent
+
+	// Validate the model reference before writing anything
+	var newModel *Model
+	if meta.Model != "" {
+		modelName := strings.ToLower(meta.Model)
+		existing := c.GetModel(modelName)
+		switch {
+		case existing != nil:
+			if m := bundle.Model; m != nil && (existing.Provider != m.Provider || existing.Model != m.Model) {
+				notes = append(notes, fmt.Sprintf("model '%s' is defined here as %s/%s, the bundle expected %s/%s; the local definition is used",
+					modelName, existing.Provider, existing.Model, m.Provider, m.Model))
+			}
+		case bundle.Model != nil:
+			m := bundle.Model
+			newModel = &Model{
+				Name:             modelName,
+				Provider:         m.Provider,
+				Endpoint:         m.Endpoint,
+				Model:            m.Model,
+				Temp:             m.Temperature,
+				TopP:             m.TopP,
+				Seed:             m.Seed,
+				MaxTokens:        m.MaxTokens,
+				Stop:             m.Stop,
+				FrequencyPenalty: m.FrequencyPenalty,
+				PresencePenalty:  m.PresencePenalty,
+			}
+			notes = append(notes, fmt.Sprintf("model '%s' was added without an API key, set one with 'gllm model set %s'", modelName, modelName))
+		default:
+			return nil, fmt.Errorf("agent '%s' uses model '%s', which is neither defined here nor included in the bundle", meta.Name, modelName)
+		}
+	}
+
+	agent := &AgentConfig{
+		Name:           meta.Name,
+		Description:    meta.Description,
+		Model:          Model{Name: strings.ToLower(meta.Model)},
+		Tools:          meta.Tools,
+		Capabilities:   meta.Capabilities,
+		Think:          meta.Think,
+		SystemPrompt:   bundle.SystemPrompt,
+		MaxRecursions:  meta.MaxRecursions,
+		AutoFormat:     meta.AutoFormat,
+		Shell:          meta.Shell,
+		InjectionGuard: meta.InjectionGuard,
+		ShowTools:      meta.ShowTools,
+		BudgetUSD:      meta.BudgetUSD,
+		BestOf:         meta.BestOf,
+		Paths:          meta.Paths,
+	}
+	if parent := strings.ToLower(meta.Extends); parent != "" {
+		if _, err := os.Stat(agentFilePath(parent)); err == nil && parent != meta.Name {
+			agent.Extends = parent
+			agent.SystemPrompt = bundle.Template
+		} else {
+			notes = append(notes, fmt.Sprintf("agent '%s' extends '%s', which does not exist here; its resolved settings were imported instead", meta.Name, parent))
+		}
+	}
+
+	if err := EnsureAgentsDir(); err != nil {
+		return nil, fmt.Errorf("failed to create agents directory: %w", err)
+	}
+	if newModel != nil {
+		if err := c.SetModel(newModel.Name, newModel); err != nil {
+			return nil, fmt.Errorf("failed to add model '%s': %w", newModel.Name, err)
+		}
+	}
+	if err := c.SetAgent(agent.Name, agent); err != nil {
+		return nil, fmt.Errorf("failed to write agent file: %w", err)
+	}
+	return notes, nil
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// APIDefinition is an OpenAPI spec imported with gllm api import, each operation is a tool.
+type APIDefinition struct {
+	Name       string         `json:"name"`
+	Title      string         `json:"title,omitempty"`
+	Source     string         `json:"source"`            // File or URL the spec was imported from
+	BaseURL    string         `json:"baseUrl"`           // Every operation is sent under it
+	Profile    string         `json:"profile,omitempty"` // Auth profile of http_request sent with the operations
+	Operations []APIOperation `json:"operations"`
+}
+
+// APIOperation is an operation of an imported spec.
+type APIOperation struct {
+	Tool        string                 `json:"tool"`
+	Method      string                 `json:"method"`
+	Path        string                 `json:"path"` // With {param} placeholders
+	Description string                 `json:"description,omitempty"`
+	Params      []APIParam             `json:"params,omitempty"`
+	Body        map[string]interface{} `json:"body,omitempty"` // JSON schema of the request body
+	BodyType    string                 `json:"bodyType,omitempty"`
+	BodyNeeded  bool                   `json:"bodyNeeded,omitempty"`
+}
+
+// APIParam is a path, query or header parameter of an operation.
+type APIParam struct {
+	Name     string                 `json:"name"`
+	In       string                 `json:"in"` // path, query or header
+	Required bool                   `json:"required,omitempty"`
+	Schema   map[string]interface{} `json:"schema,omitempty"`
+}
+
+func apiFilePath(name string) string {
+	return filepath.Join(GetAPIsDirPath(), name+".json")
+}
+
+// SaveAPI writes an imported spec, replacing the one of the same name.
+func SaveAPI(api *APIDefinition) error {
+	if err := os.MkdirAll(GetAPIsDirPath(), 0750); err != nil {
+		return fmt.Errorf("failed to create apis directory: %w", err)
+	}
+	content, err := json.MarshalIndent(api, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal api: %w", err)
+	}
+	if err := os.WriteFile(apiFilePath(api.Name), content, 0644); err != nil {
+		return fmt.Errorf("failed to write api file: %w", err)
+	}
+	return nil
+}
+
+// LoadAPIs reads the imported specs, sorted by name.
+func LoadAPIs() ([]*APIDefinition, error) {
+	entries, err := os.ReadDir(GetAPIsDirPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read apis directory: %w", err)
+	}
+	var apis []*APIDefinition
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(GetAPIsDirPath(), entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read api file: %w", err)
+		}
+		var api APIDefinition
+		if err := json.Unmarshal(content, &api); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", entry.Name(), err)
+		}
+		if api.Name == "" {
+			api.Name = strings.TrimSuffix(entry.Name(), ".json")
+		}
+		apis = append(apis, &api)
+	}
+	sort.Slice(apis, func(i, j int) bool { return apis[i].Name < apis[j].Name })
+	return apis, nil
+}
+
+// RemoveAPI deletes an imported spec, reporting whether it existed.
+func RemoveAPI(name string) (bool, error) {
+	err := os.Remove(apiFilePath(name))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to remove api: %w", err)
+	}
+	return true, nil
+}
@@ -0,0 +1,119 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ApprovalRequest is a tool call waiting for a decision in the approval directory.
+// A decision is a file next to it: <id>.approved, or <id>.denied holding an optional reason.
+type ApprovalRequest struct {
+	ID          string    `json:"id"`
+	Time        time.Time `json:"time"`
+	Session     string    `json:"session,omitempty"`
+	Agent       string    `json:"agent,omitempty"`
+	Tool        string    `json:"tool,omitempty"`
+	Command     string    `json:"command,omitempty"`
+	Description string    `json:"description"`
+}
+
+// ApprovalDecision is the answer to an approval request.
+type ApprovalDecision struct {
+	Approve  bool   `json:"approve"`
+	Feedback string `json:"feedback,omitempty"` // Why the call was denied, sent to the model
+}
+
+const (
+	approvalRequestExt  = ".json"
+	approvalApprovedExt = ".approved"
+	approvalDeniedExt   = ".denied"
+)
+
+func approvalDir(dir string) string {
+	if dir == "" {
+		return GetApprovalsDirPath()
+	}
+	return dir
+}
+
+// WriteApprovalRequest stores a request in the approval directory, dir "" uses the default.
+func WriteApprovalRequest(dir string, req ApprovalRequest) error {
+	dir = approvalDir(dir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(req, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, req.ID+approvalRequestExt), content, 0600)
+}
+
+// ReadApprovalDecision returns the decision on a request, false while there is none.
+func ReadApprovalDecision(dir, id string) (ApprovalDecision, bool) {
+	dir = approvalDir(dir)
+	if _, err := os.Stat(filepath.Join(dir, id+approvalApprovedExt)); err == nil {
+		return ApprovalDecision{Approve: true}, true
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, id+approvalDeniedExt)); err == nil {
+		return ApprovalDecision{Feedback: strings.TrimSpace(string(content))}, true
+	}
+	return ApprovalDecision{}, false
+}
+
+// WriteApprovalDecision answers a pending request.
+func WriteApprovalDecision(dir, id string, decision ApprovalDecision) error {
+	dir = approvalDir(dir)
+	if _, err := os.Stat(filepath.Join(dir, id+approvalRequestExt)); err != nil {
+		return fmt.Errorf("approval request '%s' not found", id)
+	}
+	if decision.Approve {
+		return os.WriteFile(filepath.Join(dir, id+approvalApprovedExt), nil, 0600)
+	}
+	return os.WriteFile(filepath.Join(dir, id+approvalDeniedExt), []byte(decision.Feedback), 0600)
+}
+
+// RemoveApproval deletes a request and its decision.
+func RemoveApproval(dir, id string) {
+	dir = approvalDir(dir)
+	for _, ext := range []string{approvalRequestExt, approvalApprovedExt, approvalDeniedExt} {
+		os.Remove(filepath.Join(dir, id+ext))
+	}
+}
+
+// ListApprovalRequests returns the requests still waiting for a decision, oldest first.
+func ListApprovalRequests(dir string) ([]ApprovalRequest, error) {
+	dir = approvalDir(dir)
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var requests []ApprovalRequest
+	for _, f := range files {
+		id, ok := strings.CutSuffix(f.Name(), approvalRequestExt)
+		if f.IsDir() || !ok {
+			continue
+		}
+		if _, decided := ReadApprovalDecision(dir, id); decided {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			continue
+		}
+		var req ApprovalRequest
+		if json.Unmarshal(content, &req) == nil {
+			requests = append(requests, req)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Time.Before(requests[j].Time) })
+	return requests, nil
+}
@@ -0,0 +1,167 @@
+package data
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/activebook/gllm/util"
+)
+
+// ArtifactIndexFile is the manifest of a run of sub-agents, next to their outputs.
+const ArtifactIndexFile = "index.json"
+
+// Artifact is the output of a sub-agent task, as listed in the manifest of its run.
+type Artifact struct {
+	TaskKey      string    `json:"task_key"`
+	StateKey     string    `json:"state_key"` // The SharedState key, agentName_taskKey
+	File         string    `json:"file"`      // Name of the output file in the run directory
+	Agent        string    `json:"agent"`
+	Caller       string    `json:"caller,omitempty"`
+	Instruction  string    `json:"instruction"`
+	Status       string    `json:"status"`
+	Error        string    `json:"error,omitempty"`
+	StartTime    time.Time `json:"start_time"`
+	EndTime      time.Time `json:"end_time"`
+	DurationMs   int64     `json:"duration_ms"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	TotalTokens  int       `json:"total_tokens"`
+	Size         int       `json:"size"` // Bytes of the output
+}
+
+// ArtifactRun is the manifest of one spawn of sub-agents.
+type ArtifactRun struct {
+	ID        string     `json:"id"`
+	Session   string     `json:"session,omitempty"`
+	Created   time.Time  `json:"created"`
+	Artifacts []Artifact `json:"artifacts"`
+}
+
+// artifactsMu serializes the updates of the manifests, the tasks of a run finish concurrently.
+var artifactsMu sync.Mutex
+
+// GetArtifactsDirPath returns the directory of the sub-agent outputs, .gllm/artifacts in the
+// working directory so they stay with the project they were made for.
+func GetArtifactsDirPath() string {
+	wd, err := os.Getwd()
+	if err != nil {
+		wd = "."
+	}
+	return filepath.Join(wd, ".gllm", "artifacts")
+}
+
+// NewArtifactRunID returns the ID of a new run, sortable by time.
+func NewArtifactRunID() string {
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// SaveArtifact writes the output of a task into the directory of its run and adds the task
+// to the manifest, replacing a previous entry of the same state key. It returns the path
+// of the output.
+func SaveArtifact(runID, session string, artifact Artifact, content string) (string, error) {
+	dir := filepath.Join(GetArtifactsDirPath(), runID)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return "", err
+	}
+	artifact.File = util.GetSanitizeTitle(artifact.StateKey) + ".md"
+	artifact.Size = len(content)
+	path := filepath.Join(dir, artifact.File)
+	if err := os.WriteFile(path, []byte(content), 0640); err != nil {
+		return "", err
+	}
+
+	artifactsMu.Lock()
+	defer artifactsMu.Unlock()
+	run, err := LoadArtifactRun(runID)
+	if err != nil {
+		run = &ArtifactRun{ID: runID, Session: session, Created: time.Now()}
+	}
+	replaced := false
+	for i := range run.Artifacts {
+		if run.Artifacts[i].StateKey == artifact.StateKey {
+			run.Artifacts[i] = artifact
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		run.Artifacts = append(run.Artifacts, artifact)
+	}
+	index, err := json.MarshalIndent(run, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ArtifactIndexFile), index, 0640); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// LoadArtifactRun reads the manifest of a run.
+func LoadArtifactRun(runID string) (*ArtifactRun, error) {
+	content, err := os.ReadFile(filepath.Join(GetArtifactsDirPath(), runID, ArtifactIndexFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("artifact run '%s' not found", runID)
+		}
+		return nil, err
+	}
+	var run ArtifactRun
+	if err := json.Unmarshal(content, &run); err != nil {
+		return nil, fmt.Errorf("failed to parse the manifest of run '%s': %w", runID, err)
+	}
+	return &run, nil
+}
+
+// ListArtifactRuns returns the runs with a manifest, newest first.
+func ListArtifactRuns() ([]ArtifactRun, error) {
+	entries, err := os.ReadDir(GetArtifactsDirPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var runs []ArtifactRun
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		run, err := LoadArtifactRun(e.Name())
+		if err != nil {
+			continue
+		}
+		runs = append(runs, *run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].ID > runs[j].ID })
+	return runs, nil
+}
+
+// ReadArtifact returns a task of a run and its output. The task is found by its task key or
+// its state key.
+func ReadArtifact(runID, key string) (*Artifact, string, error) {
+	run, err := LoadArtifactRun(runID)
+	if err != nil {
+		return nil, "", err
+	}
+	for i := range run.Artifacts {
+		a := &run.Artifacts[i]
+		if a.TaskKey == key || a.StateKey == key {
+			content, err := os.ReadFile(filepath.Join(GetArtifactsDirPath(), runID, a.File))
+			if err != nil {
+				return nil, "", err
+			}
+			return a, string(content), nil
+		}
+	}
+	return nil, "", fmt.Errorf("task '%s' not found in run '%s'", key, runID)
+}
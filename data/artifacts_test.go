@@ -0,0 +1,56 @@
+package data
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestArtifacts(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if runs, err := ListArtifactRuns(); err != nil || len(runs) != 0 {
+		t.Fatalf("expected no runs, got %v, %v", runs, err)
+	}
+
+	started := time.Now()
+	artifact := Artifact{TaskKey: "review", StateKey: "critic_review", Agent: "critic", Status: "Completed", StartTime: started}
+	path, err := SaveArtifact("20260101-120000-abcd", "main", artifact, "first draft")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "critic_review.md" {
+		t.Errorf("unexpected output path %s", path)
+	}
+	if _, err := SaveArtifact("20260101-120000-abcd", "main", Artifact{TaskKey: "tests", StateKey: "tester_tests", Agent: "tester"}, "all green"); err != nil {
+		t.Fatal(err)
+	}
+	// A resumed task replaces its entry
+	if _, err := SaveArtifact("20260101-120000-abcd", "main", artifact, "final review"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := SaveArtifact("20260102-090000-ef01", "", Artifact{TaskKey: "x", StateKey: "a_x"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	runs, err := ListArtifactRuns()
+	if err != nil || len(runs) != 2 || runs[0].ID != "20260102-090000-ef01" {
+		t.Fatalf("expected two runs, newest first, got %+v, %v", runs, err)
+	}
+	if run := runs[1]; run.Session != "main" || len(run.Artifacts) != 2 || run.Artifacts[0].Size != len("final review") {
+		t.Errorf("unexpected manifest %+v", run)
+	}
+
+	got, content, err := ReadArtifact("20260101-120000-abcd", "review")
+	if err != nil || content != "final review" || got.Agent != "critic" || !got.StartTime.Equal(started) {
+		t.Errorf("unexpected artifact %+v, %q, %v", got, content, err)
+	}
+	if _, content, err := ReadArtifact("20260101-120000-abcd", "tester_tests"); err != nil || content != "all green" {
+		t.Errorf("the state key must find the task too, got %q, %v", content, err)
+	}
+	if _, _, err := ReadArtifact("20260101-120000-abcd", "missing"); err == nil {
+		t.Error("expected an error for an unknown task")
+	}
+	if _, err := LoadArtifactRun("nope"); err == nil {
+		t.Error("expected an error for an unknown run")
+	}
+}
@@ -0,0 +1,160 @@
+package data
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Approval decisions recorded in the audit log
+const (
+	AuditApprovalAuto        = "auto"         // Approved by yolo mode or an earlier "always"
+	AuditApprovalApproved    = "approved"     // Approved by the user when asked
+	AuditApprovalDenied      = "denied"       // Rejected by the user when asked
+	AuditApprovalBlocked     = "blocked"      // Rejected by plan mode permissions
+	AuditApprovalNotRequired = "not_required" // The tool didn't ask for approval
+)
+
+// AuditEntry is one tool invocation in the audit log.
+type AuditEntry struct {
+	Time       time.Time              `json:"time"`
+	Session    string                 `json:"session,omitempty"`
+	Agent      string                 `json:"agent,omitempty"`
+	Tool       string                 `json:"tool"`
+	Args       map[string]interface{} `json:"args,omitempty"`
+	Approval   string                 `json:"approval"`
+	Approver   string                 `json:"approver,omitempty"` // Who decided when no user was asked: policy, file or webhook
+	Result     string                 `json:"result,omitempty"`   // Truncated summary of the tool output
+	Error      string                 `json:"error,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+}
+
+// AuditFilter selects entries when querying the audit log, zero values match everything.
+type AuditFilter struct {
+	Tool    string
+	Agent   string
+	Session string
+	Since   time.Time
+	Limit   int // Keep only the most recent entries
+}
+
+var auditMu sync.Mutex
+
+// auditFilePath returns the daily log file for t.
+func auditFilePath(t time.Time) string {
+	return filepath.Join(GetAuditDirPath(), t.Format("2006-01-02")+".jsonl")
+}
+
+// AppendAuditEntry appends an entry to the audit log of the entry's day.
+// The log is append-only, entries are never rewritten.
+func AppendAuditEntry(entry AuditEntry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	if err := os.MkdirAll(GetAuditDirPath(), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(auditFilePath(entry.Time), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// QueryAuditEntries returns the matching entries, oldest first.
+func QueryAuditEntries(filter AuditFilter) ([]AuditEntry, error) {
+	files, err := os.ReadDir(GetAuditDirPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var names []string
+	for _, f := range files {
+		if f.IsDir() || !strings.HasSuffix(f.Name(), ".jsonl") {
+			continue
+		}
+		// Skip whole days before the window
+		if !filter.Since.IsZero() {
+			day, err := time.ParseInLocation("2006-01-02", strings.TrimSuffix(f.Name(), ".jsonl"), time.Local)
+			if err == nil && day.AddDate(0, 0, 1).Before(filter.Since) {
+				continue
+			}
+		}
+		names = append(names, f.Name())
+	}
+	sort.Strings(names)
+
+	var entries []AuditEntry
+	for _, name := range names {
+		found, err := readAuditFile(filepath.Join(GetAuditDirPath(), name), filter)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, found...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+func readAuditFile(path string, filter AuditFilter) ([]AuditEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue // A torn line must not hide the rest of the log
+		}
+		if filter.matches(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log %s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func (f AuditFilter) matches(e AuditEntry) bool {
+	if f.Tool != "" && !strings.EqualFold(e.Tool, f.Tool) {
+		return false
+	}
+	if f.Agent != "" && !strings.EqualFold(e.Agent, f.Agent) {
+		return false
+	}
+	if f.Session != "" && e.Session != f.Session {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	return true
+}
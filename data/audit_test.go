@@ -0,0 +1,50 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuditLogQuery(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	now := time.Now()
+	entries := []AuditEntry{
+		{Time: now.Add(-48 * time.Hour), Tool: "shell", Agent: "coder", Approval: AuditApprovalApproved},
+		{Time: now.Add(-time.Hour), Tool: "shell", Agent: "coder", Approval: AuditApprovalDenied},
+		{Time: now.Add(-time.Minute), Tool: "read_file", Agent: "planner", Approval: AuditApprovalNotRequired},
+		{Time: now, Tool: "shell", Agent: "planner", Approval: AuditApprovalAuto},
+	}
+	for _, e := range entries {
+		if err := AppendAuditEntry(e); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter AuditFilter
+		want   []string // approvals, oldest first
+	}{
+		{"all", AuditFilter{}, []string{AuditApprovalApproved, AuditApprovalDenied, AuditApprovalNotRequired, AuditApprovalAuto}},
+		{"tool and since", AuditFilter{Tool: "shell", Since: now.Add(-24 * time.Hour)}, []string{AuditApprovalDenied, AuditApprovalAuto}},
+		{"agent", AuditFilter{Agent: "Planner"}, []string{AuditApprovalNotRequired, AuditApprovalAuto}},
+		{"limit keeps newest", AuditFilter{Limit: 1}, []string{AuditApprovalAuto}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := QueryAuditEntries(tt.filter)
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d entries, want %d", len(got), len(tt.want))
+			}
+			for i, e := range got {
+				if e.Approval != tt.want[i] {
+					t.Errorf("entry %d: got %s, want %s", i, e.Approval, tt.want[i])
+				}
+			}
+		})
+	}
+}
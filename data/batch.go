@@ -0,0 +1,147 @@
+package data
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchJob is a prompt of a batch, one line of a jobs file:
+//
+//	{"id": "review-1", "prompt": "Summarize ...", "system": "You are ..."}
+type BatchJob struct {
+	ID     string `json:"id"`               // Matches the result to the job, defaults to job-N
+	Prompt string `json:"prompt"`           // User prompt
+	System string `json:"system,omitempty"` // System prompt, empty for none
+}
+
+// Batch is a batch of jobs submitted to the batch API of a provider.
+type Batch struct {
+	ID        string    `json:"id"`                 // ID given by the provider
+	Model     string    `json:"model"`              // Name of the model in the config
+	Provider  string    `json:"provider"`           // openai or anthropic
+	Jobs      int       `json:"jobs"`               // Number of jobs submitted
+	Source    string    `json:"source"`             // Jobs file
+	Workflow  string    `json:"workflow,omitempty"` // Workflow the prompts were run through
+	Status    string    `json:"status"`             // Last status seen
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// batchMu serializes changes to the batch file.
+var batchMu sync.Mutex
+
+// GetBatchesFilePath returns the path of the record of the submitted batches.
+func GetBatchesFilePath() string {
+	return filepath.Join(GetConfigDir(), "batches.json")
+}
+
+// LoadBatchJobs reads a jobs file, one JSON job per line. Blank lines are skipped.
+func LoadBatchJobs(path string) ([]BatchJob, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs: %w", err)
+	}
+	defer file.Close()
+
+	var jobs []BatchJob
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var job BatchJob
+		if err := json.Unmarshal([]byte(text), &job); err != nil {
+			return nil, fmt.Errorf("line %d of %s: %w", line, path, err)
+		}
+		if strings.TrimSpace(job.Prompt) == "" {
+			return nil, fmt.Errorf("line %d of %s: the prompt is missing", line, path)
+		}
+		if job.ID == "" {
+			job.ID = fmt.Sprintf("job-%d", len(jobs)+1)
+		}
+		if seen[job.ID] {
+			return nil, fmt.Errorf("line %d of %s: duplicate id '%s'", line, path, job.ID)
+		}
+		seen[job.ID] = true
+		jobs = append(jobs, job)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read jobs: %w", err)
+	}
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("no jobs in %s", path)
+	}
+	return jobs, nil
+}
+
+// LoadBatches reads the submitted batches, the latest first. A missing file has none.
+func LoadBatches() ([]Batch, error) {
+	content, err := os.ReadFile(GetBatchesFilePath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read batches: %w", err)
+	}
+	var batches []Batch
+	if err := json.Unmarshal(content, &batches); err != nil {
+		return nil, fmt.Errorf("failed to parse batches: %w", err)
+	}
+	sort.Slice(batches, func(i, j int) bool { return batches[i].CreatedAt.After(batches[j].CreatedAt) })
+	return batches, nil
+}
+
+// GetBatch returns the batch of the ID, or nil.
+func GetBatch(id string) (*Batch, error) {
+	batches, err := LoadBatches()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range batches {
+		if b.ID == id {
+			return &b, nil
+		}
+	}
+	return nil, nil
+}
+
+// SaveBatch adds the batch, or replaces the one of the same ID.
+func SaveBatch(batch Batch) error {
+	batchMu.Lock()
+	defer batchMu.Unlock()
+
+	batches, err := LoadBatches()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range batches {
+		if batches[i].ID == batch.ID {
+			batches[i] = batch
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		batches = append(batches, batch)
+	}
+	if err := EnsureConfigDir(); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(batches, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(GetBatchesFilePath(), content, 0600)
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadBatchJobs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.jsonl")
+	content := `{"id":"a","prompt":"one","system":"be brief"}
+
+{"prompt":"two"}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := LoadBatchJobs(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 || jobs[0].System != "be brief" || jobs[1].ID != "job-2" {
+		t.Errorf("jobs = %+v", jobs)
+	}
+
+	for _, bad := range []string{`{"id":"a"}`, `{"id":"a","prompt":"x"}` + "\n" + `{"id":"a","prompt":"y"}`, `not json`, ``} {
+		os.WriteFile(path, []byte(bad), 0644)
+		if _, err := LoadBatchJobs(path); err == nil {
+			t.Errorf("%q loaded", bad)
+		}
+	}
+}
+
+func TestSaveBatch(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	now := time.Now()
+	if err := SaveBatch(Batch{ID: "b1", Model: "gpt4", Status: "submitted", CreatedAt: now.Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveBatch(Batch{ID: "b2", Model: "claude", Status: "submitted", CreatedAt: now}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveBatch(Batch{ID: "b1", Model: "gpt4", Status: "completed", CreatedAt: now.Add(-time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	batches, err := LoadBatches()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 2 || batches[0].ID != "b2" {
+		t.Errorf("batches = %+v", batches)
+	}
+	b, _ := GetBatch("b1")
+	if b == nil || b.Status != "completed" {
+		t.Errorf("b1 = %+v", b)
+	}
+	if b, _ := GetBatch("missing"); b != nil {
+		t.Error("missing batch found")
+	}
+	if !strings.HasSuffix(GetBatchesFilePath(), "batches.json") {
+		t.Error(GetBatchesFilePath())
+	}
+}
@@ -0,0 +1,59 @@
+package data
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// BenchPrompt is a single prompt of a benchmark set.
+type BenchPrompt struct {
+	Name   string `yaml:"name"`   // Short label shown in the report
+	Prompt string `yaml:"prompt"` // User prompt sent to every model
+	System string `yaml:"system"` // Optional system prompt, overrides the agent's one
+}
+
+// BenchPricing is the price of a model in USD per million tokens.
+type BenchPricing struct {
+	Input  float64 `yaml:"input"`
+	Output float64 `yaml:"output"`
+}
+
+// BenchSuite is the content of a benchmark prompt file.
+type BenchSuite struct {
+	Prompts []BenchPrompt           `yaml:"prompts"`
+	Pricing map[string]BenchPricing `yaml:"pricing"` // Keyed by model name
+}
+
+// LoadBenchSuite reads a benchmark prompt file.
+// The file is either a suite with prompts and pricing, or a plain list of prompts.
+func LoadBenchSuite(path string) (*BenchSuite, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt file: %w", err)
+	}
+
+	var suite BenchSuite
+	if err := yaml.Unmarshal(content, &suite); err != nil {
+		// Fall back to a plain list of prompts
+		var prompts []BenchPrompt
+		if listErr := yaml.Unmarshal(content, &prompts); listErr != nil {
+			return nil, fmt.Errorf("failed to parse prompt file: %w", err)
+		}
+		suite.Prompts = prompts
+	}
+
+	for i := range suite.Prompts {
+		if suite.Prompts[i].Prompt == "" {
+			return nil, fmt.Errorf("prompt #%d has no prompt text", i+1)
+		}
+		if suite.Prompts[i].Name == "" {
+			suite.Prompts[i].Name = fmt.Sprintf("prompt-%d", i+1)
+		}
+	}
+	if len(suite.Prompts) == 0 {
+		return nil, fmt.Errorf("no prompts found in %s", path)
+	}
+	return &suite, nil
+}
@@ -0,0 +1,95 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CheckpointFile is the state of one file before a change was applied.
+type CheckpointFile struct {
+	Path    string `json:"path"`
+	Existed bool   `json:"existed"`           // False when the change created the file
+	Content []byte `json:"content,omitempty"` // Original content
+	Mode    uint32 `json:"mode,omitempty"`    // Original permission bits
+}
+
+// Checkpoint snapshots the files touched by one multi-file change so it can be undone.
+type Checkpoint struct {
+	ID      string           `json:"id"`
+	Time    time.Time        `json:"time"`
+	Session string           `json:"session,omitempty"`
+	Agent   string           `json:"agent,omitempty"`
+	Purpose string           `json:"purpose,omitempty"`
+	Files   []CheckpointFile `json:"files"`
+}
+
+func checkpointFilePath(id string) string {
+	return filepath.Join(GetCheckpointsDirPath(), id+".json")
+}
+
+// SaveCheckpoint stores a checkpoint, assigning its ID and time when unset.
+func SaveCheckpoint(cp *Checkpoint) error {
+	if cp.Time.IsZero() {
+		cp.Time = time.Now()
+	}
+	if cp.ID == "" {
+		cp.ID = cp.Time.Format("20060102-150405.000")
+		cp.ID = strings.ReplaceAll(cp.ID, ".", "-")
+	}
+	content, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(GetCheckpointsDirPath(), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(checkpointFilePath(cp.ID), content, 0600)
+}
+
+// LoadCheckpoint reads the checkpoint with the given ID.
+func LoadCheckpoint(id string) (*Checkpoint, error) {
+	content, err := os.ReadFile(checkpointFilePath(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("checkpoint '%s' not found", id)
+		}
+		return nil, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(content, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint '%s': %w", id, err)
+	}
+	return &cp, nil
+}
+
+// ListCheckpoints returns all checkpoints, newest first.
+func ListCheckpoints() ([]*Checkpoint, error) {
+	entries, err := os.ReadDir(GetCheckpointsDirPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var checkpoints []*Checkpoint
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if entry.IsDir() || !ok {
+			continue
+		}
+		cp, err := LoadCheckpoint(id)
+		if err != nil {
+			continue // Skip unreadable checkpoints
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Time.After(checkpoints[j].Time)
+	})
+	return checkpoints, nil
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 
@@ -15,14 +16,41 @@ import (
 // All fields are strongly typed - no interface{} leaks to other layers.
 // Named AgentConfig to avoid conflict with the runtime Agent struct in service/agent.go.
 type AgentConfig struct {
-	Name          string   // Name
-	Description   string   // Description
-	Model         Model    // Model name reference
-	Tools         []string // List of enabled tools
-	Capabilities  []string // List of enabled capabilities (mcp, skills, usage, markdown, subagents)
-	Think         string   // Thinking level: off, low, medium, high
-	SystemPrompt  string   // System prompt reference
-	MaxRecursions int      // Maximum tool call recursions
+	Name           string     // Name
+	Description    string     // Description
+	Extends        string     // Name of the agent this one inherits from
+	Model          Model      // Model name reference
+	Tools          []string   // List of enabled tools
+	Capabilities   []string   // List of enabled capabilities (mcp, skills, usage, markdown, subagents)
+	Think          string     // Thinking level: off, low, medium, high
+	SystemPrompt   string     // System prompt reference
+	MaxRecursions  int        // Maximum tool call recursions
+	AutoFormat     bool       // Run the project formatter after successful file edits
+	Shell          string     // Shell of the shell tool: auto, sh, bash, powershell, pwsh or cmd
+	InjectionGuard string     // Handling of prompt injection in web and MCP results: off, flag, strip or confirm
+	ShowTools      string     // Display of tool calls: full, compact or off, empty for the default boxes
+	BudgetUSD      float64    // Cost budget of a session in USD, 0 for none
+	BestOf         int        // Candidate answers sampled for each prompt, 0 or 1 for one
+	Paths          AgentPaths // Files the tools may read and write
+}
+
+// AgentPaths limits the files the tools of an agent touch, by globs relative to the working
+// directory. An agent that lists read or write globs writes only to the write globs; reads
+// are limited when read globs are listed. Deny globs are never read nor written.
+type AgentPaths struct {
+	Read  []string `yaml:"read,omitempty"`
+	Write []string `yaml:"write,omitempty"`
+	Deny  []string `yaml:"deny,omitempty"`
+}
+
+// IsZero reports whether the agent may touch any file.
+func (p AgentPaths) IsZero() bool {
+	return len(p.Read) == 0 && len(p.Write) == 0 && len(p.Deny) == 0
+}
+
+// Equal reports whether both list the same globs.
+func (p AgentPaths) Equal(other AgentPaths) bool {
+	return slices.Equal(p.Read, other.Read) && slices.Equal(p.Write, other.Write) && slices.Equal(p.Deny, other.Deny)
 }
 
 // Model represents a model definition.
@@ -37,6 +65,38 @@ type Model struct {
 	Seed            *int32  // Model seed
 	ContextLength   int32   // Model context length
 	MaxOutputTokens int32   // Model max output tokens
+
+	// Generation controls, zero values leave the provider default in place
+	MaxTokens        int32    // Max tokens to generate per response
+	Stop             []string // Stop sequences
+	FrequencyPenalty *float32 // Frequency penalty
+	PresencePenalty  *float32 // Presence penalty
+
+	// Network, for proxies and TLS-inspecting corporate networks
+	Proxy              string // HTTP(S) or SOCKS5 proxy URL
+	CACert             string // Extra CA certificates (PEM file) to trust
+	InsecureSkipVerify bool   // Skip TLS certificate verification
+
+	// Extra headers and query parameters of every request, for gateways such as OpenRouter
+	Headers     map[string]string
+	QueryParams map[string]string
+
+	// OpenRouter routing, only sent to OpenRouter endpoints
+	ProviderOrder  []string // Providers tried first, e.g. anthropic, together
+	ProviderSort   string   // price, throughput or latency
+	AllowFallbacks *bool    // Whether providers outside the order may serve the request, nil for the default
+	FallbackModels []string // Models tried in order when the model fails
+
+	// Pricing in USD per million tokens, zero when unknown
+	InputPrice  float32 // Price of input tokens
+	OutputPrice float32 // Price of output tokens
+
+	// Gemini safety thresholds by harm category, e.g. dangerous_content: only_high
+	SafetySettings map[string]string
+	// Gemini or Anthropic runs the code the model writes
+	CodeExecution bool
+	// Claude uses its native bash and text editor tools in place of the shell and file tools
+	NativeTools bool
 }
 
 // SearchEngine represents search engine configuration.
@@ -129,6 +189,8 @@ func (c *ConfigStore) SetConfigFile(path string) error {
 	// Set default log settings in Viper *before* reading the config
 	// This ensures these keys exist even if not in the file
 	c.v.SetDefault("log.level", "info")
+	// New config files start at the current layout
+	c.v.SetDefault(configVersionKey, CurrentConfigVersion)
 
 	// If a config file is found, read it in.
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -160,39 +222,34 @@ func (c *ConfigStore) ConfigExists() bool {
 // GetAgent returns a specific agent configuration by name.
 // Returns nil if agent doesn't exist.
 func (c *ConfigStore) GetAgent(name string) *AgentConfig {
+	agent, err := c.LoadAgent(name)
+	if err != nil {
+		return nil
+	}
+	return agent
+}
+
+// LoadAgent is GetAgent with the reason an agent can't be loaded.
+func (c *ConfigStore) LoadAgent(name string) (*AgentConfig, error) {
 	name = strings.ToLower(name)
 	if err := util.ValidateResourceName("agent", name); err != nil {
-		return nil
+		return nil, err
 	}
-	agentPath := filepath.Join(GetAgentsDirPath(), name+".md")
-	agent, err := ParseAgentFile(agentPath)
+	agent, err := ResolveAgentFile(agentFilePath(name))
 	if err != nil {
-		return nil
+		return nil, err
 	}
 	// Hydrate the model
 	agent.Model = c.getModelFromAgentMap(map[string]interface{}{"model": agent.Model.Name}, "model")
-	return agent
+	return agent, nil
 }
 
-// GetAllAgents returns all configured agents as a map.
+// GetAllAgents returns all configured agents as a map, including the agents of the team config.
 func (c *ConfigStore) GetAllAgents() map[string]*AgentConfig {
 	result := make(map[string]*AgentConfig)
-	entries, err := os.ReadDir(GetAgentsDirPath())
-	if err != nil {
-		if os.IsNotExist(err) {
-			return result
-		}
-		return result
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
-			continue
-		}
-
-		name := strings.TrimSuffix(entry.Name(), ".md")
-		agentPath := filepath.Join(GetAgentsDirPath(), entry.Name())
-		agent, err := ParseAgentFile(agentPath)
+	for _, name := range c.GetAgentNames() {
+		agentPath := agentFilePath(name)
+		agent, err := ResolveAgentFile(agentPath)
 		if err != nil {
 			fmt.Printf("Warning: failed to parse agent file %s: %v\n", agentPath, err)
 			continue
@@ -204,29 +261,115 @@ func (c *ConfigStore) GetAllAgents() map[string]*AgentConfig {
 	return result
 }
 
-// GetAgentNames returns a sorted list of agent names.
+// GetAgentNames returns a sorted list of agent names, including the agents of the team config.
 func (c *ConfigStore) GetAgentNames() []string {
 	var names []string
-	entries, err := os.ReadDir(GetAgentsDirPath())
-	if err != nil {
-		return names
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
-			continue
+	seen := make(map[string]bool)
+	for _, dir := range []string{GetAgentsDirPath(), GetTeamAgentsDirPath()} {
+		for _, name := range listNames(dir, false, ".md") {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
 		}
-		name := strings.TrimSuffix(entry.Name(), ".md")
-		names = append(names, name)
 	}
 	sort.Strings(names)
 	return names
 }
 
 // SetAgent saves or updates an agent configuration.
+// For an agent that extends another, fields equal to the parent's are left out of the file so they stay inherited.
 func (c *ConfigStore) SetAgent(name string, agent *AgentConfig) error {
 	agent.Name = strings.ToLower(name)
-	return WriteAgentFile(agent)
+	if agent.Extends == "" {
+		return WriteAgentFile(agent)
+	}
+
+	agent.Extends = strings.ToLower(agent.Extends)
+	if agent.Extends == agent.Name {
+		return fmt.Errorf("agent '%s' cannot extend itself", agent.Name)
+	}
+	parent, err := ResolveAgentFile(agentFilePath(agent.Extends))
+	if err != nil {
+		return fmt.Errorf("cannot extend '%s': %w", agent.Extends, err)
+	}
+	if c.extendsChainContains(agent.Extends, agent.Name) {
+		return fmt.Errorf("agent inheritance cycle: '%s' already inherits from '%s'", agent.Extends, agent.Name)
+	}
+
+	own := *agent
+	if own.Description == parent.Description {
+		own.Description = ""
+	}
+	if own.Model.Name == parent.Model.Name {
+		own.Model = Model{}
+	}
+	if slices.Equal(own.Tools, parent.Tools) {
+		own.Tools = nil
+	}
+	if slices.Equal(own.Capabilities, parent.Capabilities) {
+		own.Capabilities = nil
+	}
+	if own.Think == parent.Think {
+		own.Think = ""
+	}
+	if own.MaxRecursions == parent.MaxRecursions {
+		own.MaxRecursions = 0
+	}
+	if own.AutoFormat == parent.AutoFormat {
+		own.AutoFormat = false
+	}
+	if own.Shell == parent.Shell {
+		own.Shell = ""
+	}
+	if own.InjectionGuard == parent.InjectionGuard {
+		own.InjectionGuard = ""
+	}
+	if own.ShowTools == parent.ShowTools {
+		own.ShowTools = ""
+	}
+	if own.BudgetUSD == parent.BudgetUSD {
+		own.BudgetUSD = 0
+	}
+	if own.BestOf == parent.BestOf {
+		own.BestOf = 0
+	}
+	if own.Paths.Equal(parent.Paths) {
+		own.Paths = AgentPaths{}
+	}
+	if own.SystemPrompt == parent.SystemPrompt {
+		own.SystemPrompt = ""
+	}
+	return WriteAgentFile(&own)
+}
+
+// extendsChainContains reports whether name appears in the extends chain starting at agent.
+func (c *ConfigStore) extendsChainContains(agent, name string) bool {
+	seen := make(map[string]bool)
+	for agent != "" && !seen[agent] {
+		if agent == name {
+			return true
+		}
+		seen[agent] = true
+		raw, err := ParseAgentFile(agentFilePath(agent))
+		if err != nil {
+			return false
+		}
+		agent = strings.ToLower(raw.Extends)
+	}
+	return false
+}
+
+// GetAgentChildren returns the names of the agents that directly extend name.
+func (c *ConfigStore) GetAgentChildren(name string) []string {
+	var children []string
+	for _, agentName := range c.GetAgentNames() {
+		raw, err := ParseAgentFile(agentFilePath(agentName))
+		if err == nil && strings.EqualFold(raw.Extends, name) {
+			children = append(children, agentName)
+		}
+	}
+	return children
 }
 
 // DeleteAgent removes an agent configuration.
@@ -237,9 +380,15 @@ func (c *ConfigStore) DeleteAgent(name string) error {
 	}
 	agentPath := filepath.Join(GetAgentsDirPath(), name+".md")
 
+	if IsTeamAgent(name) {
+		return fmt.Errorf("agent '%s' comes from the team config and is read-only", name)
+	}
 	if _, err := os.Stat(agentPath); os.IsNotExist(err) {
 		return fmt.Errorf("agent '%s' not found", name)
 	}
+	if children := c.GetAgentChildren(name); len(children) > 0 {
+		return fmt.Errorf("agent '%s' is extended by: %s", name, strings.Join(children, ", "))
+	}
 
 	return os.Remove(agentPath)
 }
@@ -260,13 +409,15 @@ func (c *ConfigStore) RenameAgent(oldName, newName string) error {
 	}
 
 	oldPath := filepath.Join(GetAgentsDirPath(), oldName+".md")
-	newPath := filepath.Join(GetAgentsDirPath(), newName+".md")
 
+	if IsTeamAgent(oldName) {
+		return fmt.Errorf("agent '%s' comes from the team config and is read-only", oldName)
+	}
 	if _, err := os.Stat(oldPath); os.IsNotExist(err) {
 		return fmt.Errorf("agent '%s' not found", oldName)
 	}
 
-	if _, err := os.Stat(newPath); err == nil {
+	if _, err := os.Stat(agentFilePath(newName)); err == nil {
 		return fmt.Errorf("agent '%s' already exists", newName)
 	}
 
@@ -292,6 +443,19 @@ func (c *ConfigStore) RenameAgent(oldName, newName string) error {
 		return fmt.Errorf("failed to remove old agent file: %w", err)
 	}
 
+	// Point the agents that extend it to the new name
+	for _, child := range c.GetAgentChildren(oldName) {
+		childPath := filepath.Join(GetAgentsDirPath(), child+".md")
+		raw, err := ParseAgentFile(childPath)
+		if err != nil {
+			continue
+		}
+		raw.Extends = newName
+		if err := WriteAgentFile(raw); err != nil {
+			return fmt.Errorf("failed to update agent '%s' extending '%s': %w", child, oldName, err)
+		}
+	}
+
 	// Update active agent if necessary
 	if c.GetActiveAgentName() == oldName {
 		c.v.Set("agent", newName)
@@ -572,6 +736,60 @@ func (c *ConfigStore) modelToMap(model *Model) map[string]interface{} {
 	if model.Seed != nil {
 		m["seed"] = *model.Seed
 	}
+	if model.MaxTokens > 0 {
+		m["max_tokens"] = model.MaxTokens
+	}
+	if len(model.Stop) > 0 {
+		m["stop"] = model.Stop
+	}
+	if model.FrequencyPenalty != nil {
+		m["frequency_penalty"] = *model.FrequencyPenalty
+	}
+	if model.PresencePenalty != nil {
+		m["presence_penalty"] = *model.PresencePenalty
+	}
+	if model.Proxy != "" {
+		m["proxy"] = model.Proxy
+	}
+	if model.CACert != "" {
+		m["ca_cert"] = model.CACert
+	}
+	if model.InsecureSkipVerify {
+		m["insecure_skip_verify"] = true
+	}
+	if len(model.Headers) > 0 {
+		m["headers"] = model.Headers
+	}
+	if len(model.QueryParams) > 0 {
+		m["query_params"] = model.QueryParams
+	}
+	if len(model.ProviderOrder) > 0 {
+		m["provider_order"] = model.ProviderOrder
+	}
+	if model.ProviderSort != "" {
+		m["provider_sort"] = model.ProviderSort
+	}
+	if model.AllowFallbacks != nil {
+		m["allow_fallbacks"] = *model.AllowFallbacks
+	}
+	if len(model.FallbackModels) > 0 {
+		m["fallback_models"] = model.FallbackModels
+	}
+	if model.InputPrice > 0 {
+		m["input_price"] = model.InputPrice
+	}
+	if model.OutputPrice > 0 {
+		m["output_price"] = model.OutputPrice
+	}
+	if len(model.SafetySettings) > 0 {
+		m["safety_settings"] = model.SafetySettings
+	}
+	if model.CodeExecution {
+		m["code_execution"] = true
+	}
+	if model.NativeTools {
+		m["native_tools"] = true
+	}
 	return m
 }
 
@@ -588,6 +806,30 @@ func (c *ConfigStore) mapToModel(name string, m map[string]interface{}) Model {
 		Seed:            getPtrInt(m, "seed"),
 		ContextLength:   int32(getInt(m, "context_length", 0)),
 		MaxOutputTokens: int32(getInt(m, "max_output_tokens", 0)),
+
+		MaxTokens:        int32(getInt(m, "max_tokens", 0)),
+		Stop:             getStringSlice(m, "stop"),
+		FrequencyPenalty: getPtrFloat(m, "frequency_penalty"),
+		PresencePenalty:  getPtrFloat(m, "presence_penalty"),
+
+		Proxy:              getString(m, "proxy"),
+		CACert:             getString(m, "ca_cert"),
+		InsecureSkipVerify: getBool(m, "insecure_skip_verify"),
+
+		Headers:     getStringMap(m, "headers"),
+		QueryParams: getStringMap(m, "query_params"),
+
+		ProviderOrder:  getStringSlice(m, "provider_order"),
+		ProviderSort:   getString(m, "provider_sort"),
+		AllowFallbacks: getPtrBool(m, "allow_fallbacks"),
+		FallbackModels: getStringSlice(m, "fallback_models"),
+
+		InputPrice:  getFloat(m, "input_price", 0),
+		OutputPrice: getFloat(m, "output_price", 0),
+
+		SafetySettings: getStringMap(m, "safety_settings"),
+		CodeExecution:  getBool(m, "code_execution"),
+		NativeTools:    getBool(m, "native_tools"),
 	}
 }
 
@@ -649,6 +891,13 @@ func getBool(m map[string]interface{}, key string) bool {
 	return false
 }
 
+func getPtrBool(m map[string]interface{}, key string) *bool {
+	if v, ok := m[key].(bool); ok {
+		return &v
+	}
+	return nil
+}
+
 func getInt(m map[string]interface{}, key string, defaultVal int) int {
 	switch v := m[key].(type) {
 	case int:
@@ -695,6 +944,15 @@ func getPtrInt(m map[string]interface{}, key string) *int32 {
 	return nil
 }
 
+func getPtrFloat(m map[string]interface{}, key string) *float32 {
+	switch m[key].(type) {
+	case float32, float64, int, int64:
+		v := getFloat(m, key, 0)
+		return &v
+	}
+	return nil
+}
+
 func getStringSlice(m map[string]interface{}, key string) []string {
 	switch v := m[key].(type) {
 	case []string:
@@ -710,3 +968,19 @@ func getStringSlice(m map[string]interface{}, key string) []string {
 	}
 	return nil
 }
+
+func getStringMap(m map[string]interface{}, key string) map[string]string {
+	switch v := m[key].(type) {
+	case map[string]string:
+		return v
+	case map[string]interface{}:
+		result := make(map[string]string, len(v))
+		for k, item := range v {
+			if s, ok := item.(string); ok {
+				result[k] = s
+			}
+		}
+		return result
+	}
+	return nil
+}
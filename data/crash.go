@@ -0,0 +1,113 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxCrashReports is the number of crash reports kept, older ones are removed.
+const maxCrashReports = 20
+
+const crashReportExt = ".txt"
+
+// CrashReport is a saved crash report.
+type CrashReport struct {
+	ID   string // File name without the extension, e.g. crash-20260102-150405
+	Path string
+	Time time.Time
+}
+
+// SaveCrashReport writes a crash report and returns its path.
+func SaveCrashReport(content string) (string, error) {
+	dir := GetCrashesDirPath()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	path := filepath.Join(dir, "crash-"+now.Format("20060102-150405")+crashReportExt)
+	// Reports may hold paths and log lines, they are only readable by the user
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		return "", err
+	}
+
+	reports, _ := ListCrashReports()
+	for i := maxCrashReports; i < len(reports); i++ {
+		os.Remove(reports[i].Path)
+	}
+	return path, nil
+}
+
+// ListCrashReports returns the saved crash reports, newest first.
+func ListCrashReports() ([]CrashReport, error) {
+	entries, err := os.ReadDir(GetCrashesDirPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var reports []CrashReport
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != crashReportExt {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		reports = append(reports, CrashReport{
+			ID:   strings.TrimSuffix(e.Name(), crashReportExt),
+			Path: filepath.Join(GetCrashesDirPath(), e.Name()),
+			Time: info.ModTime(),
+		})
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ID > reports[j].ID })
+	return reports, nil
+}
+
+// ReadCrashReport returns the content of a crash report, the latest one when id is empty.
+func ReadCrashReport(id string) (*CrashReport, string, error) {
+	reports, err := ListCrashReports()
+	if err != nil {
+		return nil, "", err
+	}
+	if len(reports) == 0 {
+		return nil, "", fmt.Errorf("no crash reports")
+	}
+	report := &reports[0]
+	if id != "" {
+		report = nil
+		for i := range reports {
+			if reports[i].ID == id || reports[i].ID == "crash-"+id {
+				report = &reports[i]
+				break
+			}
+		}
+		if report == nil {
+			return nil, "", fmt.Errorf("crash report '%s' not found", id)
+		}
+	}
+	content, err := os.ReadFile(report.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	return report, string(content), nil
+}
+
+// ClearCrashReports removes every crash report and returns how many there were.
+func ClearCrashReports() (int, error) {
+	reports, err := ListCrashReports()
+	if err != nil {
+		return 0, err
+	}
+	for _, r := range reports {
+		if err := os.Remove(r.Path); err != nil {
+			return 0, err
+		}
+	}
+	return len(reports), nil
+}
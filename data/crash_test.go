@@ -0,0 +1,50 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCrashReports(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if _, _, err := ReadCrashReport(""); err == nil {
+		t.Error("expected an error without reports")
+	}
+
+	dir := GetCrashesDirPath()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxCrashReports+2; i++ {
+		name := fmt.Sprintf("crash-20200101-0000%02d.txt", i)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("old"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path, err := SaveCrashReport("panic: boom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, err := ListCrashReports()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != maxCrashReports || reports[0].Path != path {
+		t.Fatalf("expected the %d newest reports kept, newest first, got %d starting with %s", maxCrashReports, len(reports), reports[0].ID)
+	}
+
+	report, content, err := ReadCrashReport("")
+	if err != nil || report.Path != path || content != "panic: boom" {
+		t.Errorf("expected the latest report, got %v, %q, %v", report, content, err)
+	}
+	if _, content, err := ReadCrashReport("20200101-000021"); err != nil || content != "old" {
+		t.Errorf("expected a report by its ID without the prefix, got %q, %v", content, err)
+	}
+
+	n, err := ClearCrashReports()
+	if err != nil || n != maxCrashReports {
+		t.Errorf("expected %d reports removed, got %d, %v", maxCrashReports, n, err)
+	}
+}
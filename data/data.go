@@ -65,11 +65,92 @@ func GetWorkflowsDirPath() string {
 	return filepath.Join(GetConfigDir(), "workflows")
 }
 
+// GetHooksDirPath returns the path to the directory of the Starlark hook scripts.
+func GetHooksDirPath() string {
+	return filepath.Join(GetConfigDir(), "hooks")
+}
+
+// GetControlDirPath returns the path to the directory of the control sockets of running REPLs.
+func GetControlDirPath() string {
+	return filepath.Join(GetConfigDir(), "control")
+}
+
+// GetDaemonSocketPath returns the path to the socket of the gllm daemon. It sits in a
+// directory of its own, which only the user may enter.
+func GetDaemonSocketPath() string {
+	return filepath.Join(GetConfigDir(), "daemon", "daemon.sock")
+}
+
+// GetDaemonLogPath returns the path to the log of the gllm daemon.
+func GetDaemonLogPath() string {
+	return filepath.Join(GetConfigDir(), "daemon.log")
+}
+
 // GetAgentsDirPath returns the path to the agents directory.
 func GetAgentsDirPath() string {
 	return filepath.Join(GetConfigDir(), "agents")
 }
 
+// GetTeamConfigDirPath returns the path to the checkout of the team config repository.
+func GetTeamConfigDirPath() string {
+	return filepath.Join(GetConfigDir(), "team")
+}
+
+// GetAuditDirPath returns the path to the tool audit log directory.
+func GetAuditDirPath() string {
+	return filepath.Join(GetConfigDir(), "audit")
+}
+
+// GetApprovalsDirPath returns the default directory of the file approval backend.
+func GetApprovalsDirPath() string {
+	return filepath.Join(GetConfigDir(), "approvals")
+}
+
+// GetCheckpointsDirPath returns the path to the file checkpoint directory.
+func GetCheckpointsDirPath() string {
+	return filepath.Join(GetConfigDir(), "checkpoints")
+}
+
+// GetTrashDirPath returns the path to the directory of the files deleted by the tools.
+func GetTrashDirPath() string {
+	return filepath.Join(GetConfigDir(), "trash")
+}
+
+// GetAPIsDirPath returns the path to the directory of the imported OpenAPI specs.
+func GetAPIsDirPath() string {
+	return filepath.Join(GetConfigDir(), "apis")
+}
+
+// GetCacheDirPath returns the path to the directory of cached model responses.
+func GetCacheDirPath() string {
+	return filepath.Join(GetConfigDir(), "cache")
+}
+
+// GetURLCacheDirPath returns the path to the directory of fetched URL attachments.
+func GetURLCacheDirPath() string {
+	return filepath.Join(GetConfigDir(), "url_cache")
+}
+
+// GetUploadsFilePath returns the path to the record of the attachments uploaded to the files APIs of the providers.
+func GetUploadsFilePath() string {
+	return filepath.Join(GetConfigDir(), "uploads.json")
+}
+
+// GetMCPCacheDirPath returns the path to the directory of cached MCP tool schemas.
+func GetMCPCacheDirPath() string {
+	return filepath.Join(GetConfigDir(), "mcp_cache")
+}
+
+// GetRecallIndexFilePath returns the path to the index of past sessions used by session recall.
+func GetRecallIndexFilePath() string {
+	return filepath.Join(GetConfigDir(), "recall.json")
+}
+
+// GetCrashesDirPath returns the path to the crash reports directory.
+func GetCrashesDirPath() string {
+	return filepath.Join(GetConfigDir(), "crashes")
+}
+
 // GetSettingsFilePath returns the path to the settings file.
 func GetSettingsFilePath() string {
 	return filepath.Join(GetConfigDir(), "settings.json")
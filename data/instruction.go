@@ -8,10 +8,24 @@ import (
 )
 
 const (
-	InstructionFileName = "GLLM.md"
+	InstructionFileName       = "GLLM.md"
+	AgentsInstructionFileName = "AGENTS.md"
+
+	InstructionScopeTeam    = "team"
+	InstructionScopeGlobal  = "global"
+	InstructionScopeProject = "project"
 )
 
-var ()
+// InstructionFileNames lists the recognised instruction files in order of preference.
+// Only the first one found in a directory is loaded.
+var InstructionFileNames = []string{InstructionFileName, AgentsInstructionFileName}
+
+// InstructionFile is an instruction file discovered on disk.
+type InstructionFile struct {
+	Scope   string // InstructionScopeTeam, InstructionScopeGlobal or InstructionScopeProject
+	Path    string // Absolute path of the file
+	Content string // Trimmed file content
+}
 
 // GetGlobalInstructionFilePath returns the path to the global instruction file.
 func GetGlobalInstructionFilePath() string {
@@ -23,28 +37,94 @@ func GetLocalInstructionFilePath() string {
 	return filepath.Join(".", InstructionFileName)
 }
 
-// GetInstructionContent discovers and loads static instruction files (global and local).
-// It formats them into an XML structure suitable for injection into the system prompt.
-func GetInstructionContent() string {
-	var content strings.Builder
+// findInstructionFile returns the first non-empty instruction file in dir.
+func findInstructionFile(dir string, scope string) *InstructionFile {
+	for _, name := range InstructionFileNames {
+		path := filepath.Join(dir, name)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		text := strings.TrimSpace(string(content))
+		if text == "" {
+			continue
+		}
+		return &InstructionFile{Scope: scope, Path: path, Content: text}
+	}
+	return nil
+}
+
+// DiscoverInstructionFiles finds the team and global instruction files and the project instruction files
+// from the current working directory up through its parents.
+// The walk stops at the repository root (a directory containing .git), the home directory,
+// or the filesystem root, whichever comes first.
+// Files are returned from the most general to the most specific, so later entries take precedence.
+func DiscoverInstructionFiles() []InstructionFile {
+	var files []InstructionFile
+
+	// Team instructions from the synced team config
+	if team := findInstructionFile(GetTeamConfigDirPath(), InstructionScopeTeam); team != nil {
+		files = append(files, *team)
+	}
 
-	// Load Global Instructions
-	globalPath := GetGlobalInstructionFilePath()
-	if globalData, err := os.ReadFile(globalPath); err == nil && len(globalData) > 0 {
-		content.WriteString(fmt.Sprintf("<global_instructions path=\"%s\">\n", globalPath))
-		content.WriteString(strings.TrimSpace(string(globalData)))
-		content.WriteString("\n</global_instructions>\n\n")
+	// Global instructions
+	if global := findInstructionFile(GetConfigDir(), InstructionScopeGlobal); global != nil {
+		files = append(files, *global)
 	}
 
-	// Load Local (Project) Instructions
-	localPath := GetLocalInstructionFilePath()
-	if absLocalPath, err := filepath.Abs(localPath); err == nil {
-		localPath = absLocalPath
+	cwd, err := os.Getwd()
+	if err != nil {
+		return files
 	}
-	if localData, err := os.ReadFile(localPath); err == nil && len(localData) > 0 {
-		content.WriteString(fmt.Sprintf("<project_instructions path=\"%s\">\n", localPath))
-		content.WriteString(strings.TrimSpace(string(localData)))
-		content.WriteString("\n</project_instructions>\n\n")
+	home, _ := os.UserHomeDir()
+
+	// Project instructions, collected from the nearest directory outwards
+	var project []InstructionFile
+	dir := cwd
+	for {
+		if found := findInstructionFile(dir, InstructionScopeProject); found != nil {
+			project = append(project, *found)
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+		if home != "" && dir == home {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	// Outermost first, so the closest file is read last
+	for i := len(project) - 1; i >= 0; i-- {
+		files = append(files, project[i])
+	}
+	return files
+}
+
+// GetInstructionContent discovers and loads static instruction files (team, global and project).
+// It formats them into an XML structure suitable for injection into the system prompt.
+// It returns an empty string if instruction file loading is disabled in settings.
+func GetInstructionContent() string {
+	if !GetSettingsStore().GetContextFilesEnabled() {
+		return ""
+	}
+
+	var content strings.Builder
+	for _, file := range DiscoverInstructionFiles() {
+		tag := "project_instructions"
+		switch file.Scope {
+		case InstructionScopeGlobal:
+			tag = "global_instructions"
+		case InstructionScopeTeam:
+			tag = "team_instructions"
+		}
+		content.WriteString(fmt.Sprintf("<%s path=\"%s\">\n", tag, file.Path))
+		content.WriteString(file.Content)
+		content.WriteString(fmt.Sprintf("\n</%s>\n\n", tag))
 	}
 
 	return strings.TrimSpace(content.String())
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscoverInstructionFiles(t *testing.T) {
+	root := t.TempDir()
+	t.Setenv("HOME", root)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(root, ".config"))
+
+	repo := filepath.Join(root, "repo")
+	sub := filepath.Join(repo, "pkg", "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(repo, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(filepath.Join(GetConfigDir(), InstructionFileName), "global rules")
+	// Above the repository root, must not be picked up
+	write(filepath.Join(root, AgentsInstructionFileName), "outside")
+	write(filepath.Join(repo, AgentsInstructionFileName), "repo rules")
+	// GLLM.md wins over AGENTS.md in the same directory
+	write(filepath.Join(sub, AgentsInstructionFileName), "ignored")
+	write(filepath.Join(sub, InstructionFileName), "sub rules")
+
+	t.Chdir(sub)
+	files := DiscoverInstructionFiles()
+
+	want := []string{"global rules", "repo rules", "sub rules"}
+	if len(files) != len(want) {
+		t.Fatalf("expected %d files, got %d: %+v", len(want), len(files), files)
+	}
+	for i, w := range want {
+		if files[i].Content != w {
+			t.Errorf("file %d: expected %q, got %q", i, w, files[i].Content)
+		}
+	}
+	if files[0].Scope != InstructionScopeGlobal || files[2].Scope != InstructionScopeProject {
+		t.Errorf("unexpected scopes: %+v", files)
+	}
+}
@@ -0,0 +1,302 @@
+package data
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/util"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+Config migrations upgrade the layouts older gllm versions wrote, so an upgrade doesn't
+leave agents behind or fail to parse them. gllm.yaml carries a config_version, every
+migration above it runs once on load, in order, and the version is stamped after them.
+The previous gllm.yaml and every agent file a migration rewrites are copied to a
+backup directory first.
+*/
+
+// CurrentConfigVersion is the config layout this version of gllm writes.
+const CurrentConfigVersion = 3
+
+// configVersionKey is the key of the layout version in gllm.yaml.
+const configVersionKey = "config_version"
+
+// configMigration upgrades the config from the previous version to version, apply
+// reports whether there was anything to upgrade.
+type configMigration struct {
+	version     int
+	description string
+	apply       func(m *migrationContext) (bool, error)
+}
+
+var configMigrations = []configMigration{
+	{1, "moved the agents of gllm.yaml to agent files", migrateConfigAgents},
+	{2, "moved the workflow agents of gllm.yaml to agent files", migrateWorkflowAgents},
+	{3, "turned the boolean tools flag of agent files into a tools list", migrateAgentToolsFlag},
+}
+
+// migrationContext is the state a migration works on.
+type migrationContext struct {
+	config    map[string]interface{} // Raw gllm.yaml, written back after the migrations
+	allTools  []string               // Tools a legacy 'tools: true' enables
+	backupDir string
+}
+
+// backup copies a file to the backup directory before it is changed, keeping its path
+// relative to the config directory.
+func (m *migrationContext) backup(path string) error {
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(GetConfigDir(), path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(path)
+	}
+	dest := filepath.Join(m.backupDir, rel)
+	if _, err := os.Stat(dest); err == nil {
+		return nil // Keep the oldest copy
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(dest, content, 0600)
+}
+
+// writeAgent writes a migrated agent, an agent file already there wins over the legacy entry.
+func (m *migrationContext) writeAgent(agent *AgentConfig) error {
+	agent.Name = strings.ToLower(agent.Name)
+	if err := util.ValidateResourceName("agent", agent.Name); err != nil {
+		return err
+	}
+	if _, err := os.Stat(agentFilePath(agent.Name)); err == nil {
+		return nil
+	}
+	if agent.MaxRecursions == 0 {
+		agent.MaxRecursions = 50
+	}
+	return WriteAgentFile(agent)
+}
+
+// Migrate upgrades gllm.yaml and the agent files to the current layout. allTools are the
+// tools a legacy boolean tools flag enables. It returns the migrations applied and the
+// backup directory, both empty when the config was up to date.
+func (c *ConfigStore) Migrate(allTools []string) ([]string, string, error) {
+	path := c.v.ConfigFileUsed()
+	if path == "" {
+		return nil, "", nil
+	}
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	config := map[string]interface{}{}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, "", fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	version := 0
+	if v, ok := config[configVersionKey].(int); ok {
+		version = v
+	}
+	if version > CurrentConfigVersion {
+		return nil, "", fmt.Errorf("%s has config version %d, newer than this gllm supports (%d), please upgrade gllm", filepath.Base(path), version, CurrentConfigVersion)
+	}
+	if version == CurrentConfigVersion {
+		return nil, "", nil
+	}
+
+	m := &migrationContext{
+		config:    config,
+		allTools:  allTools,
+		backupDir: filepath.Join(GetConfigDir(), "backups", fmt.Sprintf("v%d-%s", version, time.Now().Format("20060102-150405"))),
+	}
+	// Migrations may write agent files, the config is kept before any of them runs
+	if err := m.backup(path); err != nil {
+		return nil, "", fmt.Errorf("failed to back up %s: %w", path, err)
+	}
+
+	var applied []string
+	for _, migration := range configMigrations {
+		if migration.version <= version {
+			continue
+		}
+		changed, err := migration.apply(m)
+		if err != nil {
+			return applied, m.backupDir, fmt.Errorf("config migration to version %d failed: %w", migration.version, err)
+		}
+		if changed {
+			applied = append(applied, migration.description)
+		}
+	}
+	if len(applied) == 0 {
+		// Only the version is stamped, there is nothing worth keeping
+		os.RemoveAll(m.backupDir)
+		m.backupDir = ""
+	}
+
+	config[configVersionKey] = CurrentConfigVersion
+	out, err := yaml.Marshal(config)
+	if err != nil {
+		return applied, m.backupDir, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, out, 0644); err != nil {
+		return applied, m.backupDir, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := c.v.ReadInConfig(); err != nil {
+		return applied, m.backupDir, err
+	}
+	return applied, m.backupDir, nil
+}
+
+// legacyTools reads a tools value that is a list, or a boolean flag enabling every tool.
+func legacyTools(v interface{}, allTools []string) []string {
+	switch t := v.(type) {
+	case bool:
+		if t {
+			return append([]string(nil), allTools...)
+		}
+		return nil
+	case []interface{}:
+		var tools []string
+		for _, tool := range t {
+			if s, ok := tool.(string); ok && s != "" {
+				tools = append(tools, s)
+			}
+		}
+		return tools
+	}
+	return nil
+}
+
+// legacyThink reads a think value that is a level, or a boolean flag.
+func legacyThink(v interface{}) string {
+	switch t := v.(type) {
+	case bool:
+		if t {
+			return "high"
+		}
+		return "off"
+	case string:
+		return t
+	}
+	return ""
+}
+
+// legacyAgent builds an agent from an entry of the old gllm.yaml layout.
+func legacyAgent(name string, entry map[string]interface{}, allTools []string) *AgentConfig {
+	prompt := getString(entry, "system_prompt")
+	if prompt == "" {
+		prompt = getString(entry, "instruction")
+	}
+	if prompt == "" {
+		prompt = getString(entry, "role")
+	}
+	return &AgentConfig{
+		Name:          name,
+		Description:   getString(entry, "description"),
+		Model:         Model{Name: strings.ToLower(getString(entry, "model"))},
+		Tools:         legacyTools(entry["tools"], allTools),
+		Capabilities:  getStringSlice(entry, "capabilities"),
+		Think:         legacyThink(entry["think"]),
+		SystemPrompt:  prompt,
+		MaxRecursions: getInt(entry, "max_recursions", 0),
+	}
+}
+
+// migrateConfigAgents moves the agents map of gllm.yaml to agent files.
+func migrateConfigAgents(m *migrationContext) (bool, error) {
+	if _, ok := m.config["agents"]; !ok {
+		return false, nil
+	}
+	for name, v := range toStringMap(m.config["agents"]) {
+		if err := m.writeAgent(legacyAgent(name, toStringMap(v), m.allTools)); err != nil {
+			return false, fmt.Errorf("agent '%s': %w", name, err)
+		}
+	}
+	delete(m.config, "agents")
+	return true, nil
+}
+
+// migrateWorkflowAgents moves the agents of the old workflow section to agent files, the
+// workflows themselves are markdown files now.
+func migrateWorkflowAgents(m *migrationContext) (bool, error) {
+	if _, ok := m.config["workflow"]; !ok {
+		return false, nil
+	}
+	entries, _ := toStringMap(m.config["workflow"])["agents"].([]interface{})
+	for _, v := range entries {
+		entry := toStringMap(v)
+		name := getString(entry, "name")
+		if name == "" {
+			continue
+		}
+		if err := m.writeAgent(legacyAgent(name, entry, m.allTools)); err != nil {
+			return false, fmt.Errorf("workflow agent '%s': %w", name, err)
+		}
+	}
+	delete(m.config, "workflow")
+	return true, nil
+}
+
+// migrateAgentToolsFlag rewrites 'tools: true' or 'tools: false' in agent files as the list
+// of tools it meant, leaving the rest of the file as it was.
+func migrateAgentToolsFlag(m *migrationContext) (bool, error) {
+	rewritten := false
+	paths, _ := filepath.Glob(filepath.Join(GetAgentsDirPath(), "*.md"))
+	for _, path := range paths {
+		frontmatter, prompt, err := splitAgentFile(path)
+		if err != nil {
+			continue // Not an agent file gllm could read before either
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(frontmatter), &doc); err != nil || len(doc.Content) == 0 {
+			continue
+		}
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			continue
+		}
+		changed := false
+		for i := 0; i+1 < len(root.Content); i += 2 {
+			key, value := root.Content[i], root.Content[i+1]
+			if key.Value != "tools" || value.Kind != yaml.ScalarNode || value.Tag != "!!bool" {
+				continue
+			}
+			list := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			if value.Value == "true" {
+				for _, tool := range m.allTools {
+					list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: tool})
+				}
+			}
+			root.Content[i+1] = list
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		if err := m.backup(path); err != nil {
+			return rewritten, fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		out, err := yaml.Marshal(&doc)
+		if err != nil {
+			return rewritten, fmt.Errorf("failed to rewrite %s: %w", path, err)
+		}
+		content := fmt.Sprintf("---\n%s---\n\n%s\n", string(out), prompt)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return rewritten, fmt.Errorf("failed to rewrite %s: %w", path, err)
+		}
+		rewritten = true
+	}
+	return rewritten, nil
+}
@@ -0,0 +1,119 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestMigrateLegacyConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := EnsureConfigDir(); err != nil {
+		t.Fatal(err)
+	}
+	legacy := `agent: coder
+models:
+  gpt:
+    model: gpt-4o
+agents:
+  coder:
+    model: gpt
+    tools: true
+    think: true
+    system_prompt: You write Go.
+workflow:
+  agents:
+    - name: Reviewer
+      model: gpt
+      role: You review code.
+      tools: false
+`
+	path := GetConfigFilePath()
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureAgentsDir(); err != nil {
+		t.Fatal(err)
+	}
+	flagged := "---\nname: helper\nmodel: gpt\ntools: true\nthink: low\n---\n\nYou help.\n"
+	if err := os.WriteFile(agentFilePath("helper"), []byte(flagged), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	store := &ConfigStore{v: viper.New()}
+	if err := store.SetConfigFile(path); err != nil {
+		t.Fatal(err)
+	}
+	applied, backupDir, err := store.Migrate([]string{"read_file", "shell"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != 3 {
+		t.Errorf("expected 3 migrations applied, got %q", applied)
+	}
+
+	coder, err := ParseAgentFile(agentFilePath("coder"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(coder.Tools, ",") != "read_file,shell" || coder.Think != "high" || coder.SystemPrompt != "You write Go." {
+		t.Errorf("unexpected migrated agent: %+v", coder)
+	}
+	reviewer, err := ParseAgentFile(agentFilePath("reviewer"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reviewer.Tools) != 0 || reviewer.SystemPrompt != "You review code." {
+		t.Errorf("unexpected workflow agent: %+v", reviewer)
+	}
+	helper, err := ParseAgentFile(agentFilePath("helper"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(helper.Tools, ",") != "read_file,shell" || helper.Think != "low" || helper.SystemPrompt != "You help." {
+		t.Errorf("unexpected rewritten agent: %+v", helper)
+	}
+
+	if store.v.IsSet("agents") || store.v.IsSet("workflow") || store.v.GetInt(configVersionKey) != CurrentConfigVersion {
+		t.Errorf("expected the legacy keys gone and the version stamped, got %v", store.v.AllSettings())
+	}
+	if store.GetActiveAgentName() != "coder" {
+		t.Errorf("expected the other keys kept, got %v", store.v.AllSettings())
+	}
+	for _, kept := range []string{"gllm.yaml", filepath.Join("agents", "helper.md")} {
+		content, err := os.ReadFile(filepath.Join(backupDir, kept))
+		if err != nil {
+			t.Fatalf("expected %s in the backup: %v", kept, err)
+		}
+		if !strings.Contains(string(content), "tools: true") {
+			t.Errorf("expected the previous %s in the backup, got:\n%s", kept, content)
+		}
+	}
+
+	// Upgraded configs are left alone
+	applied, _, err = store.Migrate([]string{"read_file"})
+	if err != nil || len(applied) != 0 {
+		t.Errorf("expected nothing to migrate twice, got %q, %v", applied, err)
+	}
+}
+
+func TestMigrateNewerConfig(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := EnsureConfigDir(); err != nil {
+		t.Fatal(err)
+	}
+	path := GetConfigFilePath()
+	if err := os.WriteFile(path, []byte("config_version: 99\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := &ConfigStore{v: viper.New()}
+	if err := store.SetConfigFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Migrate(nil); err == nil {
+		t.Error("expected a config from a newer gllm to be refused")
+	}
+}
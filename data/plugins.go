@@ -0,0 +1,87 @@
+package data
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ExternalPlugin is a binary declared in the plugins section of gllm.yaml. gllm starts it and
+// talks JSON-RPC over its stdin and stdout, it registers tools and may decide on approvals.
+type ExternalPlugin struct {
+	Name     string   // Name is the key
+	Command  string   // Executable, looked up in PATH
+	Args     []string // Arguments of the executable
+	Env      []string // KEY=VALUE variables added to the environment of gllm; a list, as config keys lose their case
+	Disabled bool     // Keep the declaration without starting the plugin
+}
+
+// GetExternalPlugins returns the declared plugins, sorted by name.
+func (c *ConfigStore) GetExternalPlugins() []ExternalPlugin {
+	pluginsMap := c.v.GetStringMap("plugins")
+	var result []ExternalPlugin
+	for name, config := range pluginsMap {
+		if configMap := toStringMap(config); configMap != nil {
+			result = append(result, mapToExternalPlugin(name, configMap))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// GetExternalPlugin returns a declared plugin by name.
+func (c *ConfigStore) GetExternalPlugin(name string) *ExternalPlugin {
+	name = strings.ToLower(name)
+	pluginsMap := c.v.GetStringMap("plugins")
+	if config, ok := pluginsMap[name]; ok {
+		if configMap := toStringMap(config); configMap != nil {
+			p := mapToExternalPlugin(name, configMap)
+			return &p
+		}
+	}
+	return nil
+}
+
+// SetExternalPlugin adds or updates a plugin.
+func (c *ConfigStore) SetExternalPlugin(p *ExternalPlugin) error {
+	name := strings.ToLower(p.Name)
+	pluginsMap := c.v.GetStringMap("plugins")
+	if pluginsMap == nil {
+		pluginsMap = make(map[string]interface{})
+	}
+	config := map[string]interface{}{"command": p.Command}
+	if len(p.Args) > 0 {
+		config["args"] = p.Args
+	}
+	if len(p.Env) > 0 {
+		config["env"] = p.Env
+	}
+	if p.Disabled {
+		config["disabled"] = true
+	}
+	pluginsMap[name] = config
+	c.v.Set("plugins", pluginsMap)
+	return c.Save()
+}
+
+// DeleteExternalPlugin removes a plugin.
+func (c *ConfigStore) DeleteExternalPlugin(name string) error {
+	name = strings.ToLower(name)
+	pluginsMap := c.v.GetStringMap("plugins")
+	if _, ok := pluginsMap[name]; !ok {
+		return fmt.Errorf("plugin '%s' not found", name)
+	}
+	delete(pluginsMap, name)
+	c.v.Set("plugins", pluginsMap)
+	return c.Save()
+}
+
+func mapToExternalPlugin(name string, m map[string]interface{}) ExternalPlugin {
+	return ExternalPlugin{
+		Name:     name,
+		Command:  getString(m, "command"),
+		Args:     getStringSlice(m, "args"),
+		Env:      getStringSlice(m, "env"),
+		Disabled: getBool(m, "disabled"),
+	}
+}
@@ -0,0 +1,49 @@
+package data
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestExternalPlugins(t *testing.T) {
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "gllm.yaml")
+	store := &ConfigStore{v: viper.New()}
+	store.v.SetConfigFile(configFile)
+
+	if err := store.SetExternalPlugin(&ExternalPlugin{Name: "Jira", Command: "gllm-jira", Args: []string{"--site", "acme"}, Env: []string{"JIRA_TOKEN=secret"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetExternalPlugin(&ExternalPlugin{Name: "approvals", Command: "approve.sh", Disabled: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Read back from the file, where keys lose their case
+	store = &ConfigStore{v: viper.New()}
+	store.v.SetConfigFile(configFile)
+	if err := store.v.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	plugins := store.GetExternalPlugins()
+	if len(plugins) != 2 || plugins[0].Name != "approvals" || !plugins[0].Disabled {
+		t.Fatalf("unexpected plugins: %+v", plugins)
+	}
+	jira := store.GetExternalPlugin("JIRA")
+	if jira == nil || jira.Command != "gllm-jira" || !slices.Equal(jira.Args, []string{"--site", "acme"}) ||
+		!slices.Equal(jira.Env, []string{"JIRA_TOKEN=secret"}) || jira.Disabled {
+		t.Errorf("unexpected plugin: %+v", jira)
+	}
+
+	if err := store.DeleteExternalPlugin("jira"); err != nil {
+		t.Fatal(err)
+	}
+	if store.GetExternalPlugin("jira") != nil {
+		t.Error("plugin not deleted")
+	}
+	if err := store.DeleteExternalPlugin("jira"); err == nil {
+		t.Error("expected an error for a missing plugin")
+	}
+}
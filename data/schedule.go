@@ -0,0 +1,121 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Schedule is an agent run that recurs on a cron expression.
+type Schedule struct {
+	Name      string    `json:"name"`
+	Cron      string    `json:"cron"`                // e.g. "0 9 * * *"
+	Agent     string    `json:"agent,omitempty"`     // Agent to run, empty uses the active agent
+	Prompt    string    `json:"prompt"`              // Prompt of each run
+	Session   string    `json:"session,omitempty"`   // Session every run continues, empty starts a new one each run
+	OutputDir string    `json:"outputDir,omitempty"` // Directory of the run outputs, empty uses the default
+	Notify    bool      `json:"notify,omitempty"`    // Notify the desktop when a run finishes
+	Sink      string    `json:"sink,omitempty"`      // Chat channel the output is posted to, e.g. slack:#channel
+	Paused    bool      `json:"paused,omitempty"`    // Skipped by the daemon
+	LastRun   time.Time `json:"lastRun"`             // Start of the last run
+	LastError string    `json:"lastError,omitempty"` // Error of the last run, empty when it succeeded
+}
+
+// scheduleMu serializes changes to the schedule file, runs finish concurrently.
+var scheduleMu sync.Mutex
+
+// GetSchedulesFilePath returns the path of the schedule file.
+func GetSchedulesFilePath() string {
+	return filepath.Join(GetConfigDir(), "schedules.json")
+}
+
+// GetScheduleRunsDirPath returns the default directory of the outputs of a schedule.
+func GetScheduleRunsDirPath(name string) string {
+	return filepath.Join(GetConfigDir(), "schedules", name)
+}
+
+// LoadSchedules reads the schedules, sorted by name. A missing file has none.
+func LoadSchedules() ([]Schedule, error) {
+	content, err := os.ReadFile(GetSchedulesFilePath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules: %w", err)
+	}
+	var schedules []Schedule
+	if err := json.Unmarshal(content, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to parse schedules: %w", err)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Name < schedules[j].Name })
+	return schedules, nil
+}
+
+func saveSchedules(schedules []Schedule) error {
+	if err := EnsureConfigDir(); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(schedules, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(GetSchedulesFilePath(), content, 0600)
+}
+
+// GetSchedule returns the schedule of the name, or nil.
+func GetSchedule(name string) (*Schedule, error) {
+	schedules, err := LoadSchedules()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range schedules {
+		if s.Name == name {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+// UpdateSchedule changes the schedule of the name with fn, creating it when create is set.
+func UpdateSchedule(name string, create bool, fn func(*Schedule)) error {
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+
+	schedules, err := LoadSchedules()
+	if err != nil {
+		return err
+	}
+	for i := range schedules {
+		if schedules[i].Name == name {
+			fn(&schedules[i])
+			return saveSchedules(schedules)
+		}
+	}
+	if !create {
+		return fmt.Errorf("schedule '%s' not found", name)
+	}
+	s := Schedule{Name: name}
+	fn(&s)
+	return saveSchedules(append(schedules, s))
+}
+
+// RemoveSchedule deletes the schedule of the name, its outputs are kept.
+func RemoveSchedule(name string) error {
+	scheduleMu.Lock()
+	defer scheduleMu.Unlock()
+
+	schedules, err := LoadSchedules()
+	if err != nil {
+		return err
+	}
+	for i := range schedules {
+		if schedules[i].Name == name {
+			return saveSchedules(append(schedules[:i], schedules[i+1:]...))
+		}
+	}
+	return fmt.Errorf("schedule '%s' not found", name)
+}
@@ -0,0 +1,127 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Secrets are credentials of the built-in integrations, such as the GitHub token.
+// They are kept apart from the settings in a file only the user can read, and are
+// never shown in full or exported.
+
+// Names of the secrets the built-in tools use.
+const (
+	SecretGitHubToken = "github_token"
+	SecretGitLabToken = "gitlab_token"
+	SecretJiraToken   = "jira_token"
+	SecretLinearToken = "linear_token"
+
+	// Webhooks of the output sinks, a ":target" suffix names the webhook of one channel,
+	// e.g. slack_webhook:#releases
+	SecretSlackWebhook   = "slack_webhook"
+	SecretSlackToken     = "slack_token"
+	SecretDiscordWebhook = "discord_webhook"
+	SecretSMTPPassword   = "smtp_password"
+)
+
+// DatabaseSecretName is the name of the secret holding the DSN of a database of the project
+// directory, a DSN often carries a password.
+func DatabaseSecretName(project, name string) string {
+	return "database:" + name + "@" + project
+}
+
+// HTTPProfileSecretName is the name of the secret holding the token or password of an auth
+// profile of the http_request tool.
+func HTTPProfileSecretName(profile string) string {
+	return "http_profile:" + profile
+}
+
+// secretsMu serializes changes to the secrets file.
+var secretsMu sync.Mutex
+
+// GetSecretsFilePath returns the path of the secrets file.
+func GetSecretsFilePath() string {
+	return filepath.Join(GetConfigDir(), "secrets.json")
+}
+
+func loadSecrets() (map[string]string, error) {
+	content, err := os.ReadFile(GetSecretsFilePath())
+	if os.IsNotExist(err) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets: %w", err)
+	}
+	secrets := map[string]string{}
+	if err := json.Unmarshal(content, &secrets); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets: %w", err)
+	}
+	return secrets, nil
+}
+
+func saveSecrets(secrets map[string]string) error {
+	if err := EnsureConfigDir(); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(GetSecretsFilePath(), content, 0600)
+}
+
+// GetSecret returns the secret of the name, empty when it is not set.
+func GetSecret(name string) (string, error) {
+	secrets, err := loadSecrets()
+	if err != nil {
+		return "", err
+	}
+	return secrets[name], nil
+}
+
+// SetSecret stores the secret of the name.
+func SetSecret(name, value string) error {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	secrets, err := loadSecrets()
+	if err != nil {
+		return err
+	}
+	secrets[name] = value
+	return saveSecrets(secrets)
+}
+
+// RemoveSecret deletes the secret of the name.
+func RemoveSecret(name string) error {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+
+	secrets, err := loadSecrets()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[name]; !ok {
+		return fmt.Errorf("secret '%s' not found", name)
+	}
+	delete(secrets, name)
+	return saveSecrets(secrets)
+}
+
+// ListSecretNames returns the names of the stored secrets, sorted.
+func ListSecretNames() ([]string, error) {
+	secrets, err := loadSecrets()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -1,5 +1,12 @@
 package data
 
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
 // // SessionStore provides file operations for session history files.
 // type SessionStore struct {
 // 	dir string
@@ -162,24 +169,70 @@ var (
 	planModeInSession        = false
 	planModeInSessionEnabled = false
 	yoloModeInSession        = false
+	planModeSince            time.Time // When plan mode was last turned on
+	modelInSession           string    // Model picked with /model for this session, over the agent's
 )
 
 const (
+	// PlanModeSystemPrompt takes the plans directory as its only argument
 	PlanModeSystemPrompt = `<system-reminder>
 Plan mode is active. The user indicated that they do not want you to execute yet — you MUST NOT make any edits, run any non-readonly tools.
 Instead, you should: 
 1. Answer the user's query comprehensively 
-2. When you're done researching, present your plan by calling the exit_plan_mode tool.
+2. When you're done researching, write a structured plan as a markdown file under %s with these sections:
+   ## Goal, ## Steps (numbered, one change per step), ## Files (to create or modify), ## Risks, ## Verification
+3. Present the plan by calling the exit_plan_mode tool. The user may also approve it with /act.
 </system-reminder>`
+
+	// PlanApprovedPrompt is sent when the user approves the plan with /act
+	PlanApprovedPrompt = "The plan is approved. Plan mode is off and all tools are unlocked. Execute the plan step by step now."
 )
 
 /**
  * Set plan mode in session
  */
 func SetPlanModeInSession(value bool) {
+	if value && !planModeInSession {
+		planModeSince = time.Now()
+	}
 	planModeInSession = value
 }
 
+// GetLatestPlan returns the most recently written plan file since plan mode was turned on.
+// An empty path means no plan was written.
+func GetLatestPlan() (path string, content string, err error) {
+	entries, err := os.ReadDir(GetPlansDirPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+
+	// File systems may store coarser timestamps than the clock
+	since := planModeSince.Truncate(time.Second)
+	var latest time.Time
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().Before(since) || !info.ModTime().After(latest) {
+			continue
+		}
+		latest = info.ModTime()
+		path = filepath.Join(GetPlansDirPath(), entry.Name())
+	}
+	if path == "" {
+		return "", "", nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", err
+	}
+	return path, string(raw), nil
+}
+
 /**
  * Get plan mode in session
  */
@@ -215,6 +268,20 @@ func GetYoloModeInSession() bool {
 	return yoloModeInSession
 }
 
+/**
+ * Set the model used in session instead of the active agent's, empty restores it
+ */
+func SetModelInSession(name string) {
+	modelInSession = name
+}
+
+/**
+ * Get the model used in session, empty for the active agent's
+ */
+func GetModelInSession() string {
+	return modelInSession
+}
+
 /**
  * Get session mode
  *
@@ -0,0 +1,42 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetLatestPlan(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := os.MkdirAll(GetPlansDirPath(), 0750); err != nil {
+		t.Fatal(err)
+	}
+	defer SetPlanModeInSession(false)
+
+	// Written before plan mode started, must be ignored
+	old := filepath.Join(GetPlansDirPath(), "old.md")
+	if err := os.WriteFile(old, []byte("old plan"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Hour)
+	os.Chtimes(old, past, past)
+
+	SetPlanModeInSession(false)
+	SetPlanModeInSession(true)
+	if path, _, err := GetLatestPlan(); err != nil || path != "" {
+		t.Fatalf("expected no plan, got %q (%v)", path, err)
+	}
+
+	current := filepath.Join(GetPlansDirPath(), "current.md")
+	if err := os.WriteFile(current, []byte("## Goal\nship it"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	path, content, err := GetLatestPlan()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != current || content != "## Goal\nship it" {
+		t.Errorf("got %q with %q", path, content)
+	}
+}
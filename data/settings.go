@@ -14,6 +14,7 @@ import (
 // SkillsSettings holds skill-related settings.
 type SkillsSettings struct {
 	Disabled []string `json:"disabled"`
+	Suggest  bool     `json:"suggest"` // Match prompts against the skills to suggest or auto-activate them
 }
 
 // MCPSettings holds MCP-related settings.
@@ -31,6 +32,11 @@ type VerboseSettings struct {
 	Enabled bool `json:"enabled"` // Whether verbose output is enabled
 }
 
+// ContextSettings holds settings for automatic instruction file discovery.
+type ContextSettings struct {
+	Disabled bool `json:"disabled"` // Whether GLLM.md / AGENTS.md auto-loading is disabled
+}
+
 // UpdateSettings holds update-check-related settings.
 type UpdateSettings struct {
 	CheckAt time.Time `json:"checkAt"`
@@ -41,17 +47,168 @@ type PluginSettings struct {
 	Enabled []string `json:"enabled"` // List of enabled plugin IDs
 }
 
+// ToolsSettings holds limits applied by the embedded tools.
+type ToolsSettings struct {
+	ReadMaxTokens int  `json:"readMaxTokens"` // Token cap of a single read_file result, 0 uses the default
+	HardDelete    bool `json:"hardDelete"`    // Delete files for good instead of moving them to the trash
+}
+
+// PromptSettings controls how the system prompt is assembled.
+type PromptSettings struct {
+	Layers []string `json:"layers,omitempty"` // Order of the system prompt layers, empty uses the default order
+}
+
+// TeamSettings configures the git repository that team config is synced from.
+type TeamSettings struct {
+	Repo     string    `json:"repo,omitempty"`    // Git URL or path of the team config repository
+	Ref      string    `json:"ref,omitempty"`     // Branch or tag to follow, empty uses the remote default
+	Refresh  string    `json:"refresh,omitempty"` // How often the REPL refreshes it in the background, e.g. "24h"; empty disables
+	SyncedAt time.Time `json:"syncedAt"`          // Time of the last successful sync
+}
+
+// RedactionSettings configures the redaction of secrets and personal data in outgoing prompts.
+type RedactionSettings struct {
+	Enabled  bool               `json:"enabled"`
+	Disabled []string           `json:"disabled,omitempty"` // Built-in rules that are turned off
+	Patterns []RedactionPattern `json:"patterns,omitempty"` // Custom rules
+}
+
+// RedactionPattern is a custom redaction rule.
+type RedactionPattern struct {
+	Name  string `json:"name"`
+	Regex string `json:"regex"`
+}
+
+// ApprovalSettings configures how tool calls are approved when no one can answer a prompt,
+// such as in cron jobs, CI or serve mode.
+type ApprovalSettings struct {
+	Backend       string   `json:"backend,omitempty"`       // prompt, policy, file, webhook or plugin; empty uses prompt
+	AllowTools    []string `json:"allowTools,omitempty"`    // Tools approved without asking
+	AllowCommands []string `json:"allowCommands,omitempty"` // Shell command prefixes approved without asking
+	Dir           string   `json:"dir,omitempty"`           // Directory of the file backend, empty uses the default
+	Webhook       string   `json:"webhook,omitempty"`       // URL the webhook backend posts requests to
+	Plugin        string   `json:"plugin,omitempty"`        // Plugin the plugin backend asks
+	Timeout       int      `json:"timeout,omitempty"`       // Seconds to wait for a decision, 0 uses the default
+}
+
+// NotifySettings configures desktop notifications for long-running generations.
+type NotifySettings struct {
+	Threshold string `json:"threshold,omitempty"` // Notify when a generation runs longer, e.g. "30s"; empty disables
+}
+
+// TicketSettings configures the issue trackers of the ticket_* tools, their tokens are secrets.
+type TicketSettings struct {
+	Provider    string `json:"provider,omitempty"`    // jira or linear, empty uses the one that is configured
+	JiraURL     string `json:"jiraUrl,omitempty"`     // Base URL of the Jira site, e.g. https://acme.atlassian.net
+	JiraEmail   string `json:"jiraEmail,omitempty"`   // Account of a Jira Cloud API token, empty sends the token as a bearer PAT
+	JiraProject string `json:"jiraProject,omitempty"` // Default project key of searches and new issues
+	LinearURL   string `json:"linearUrl,omitempty"`   // GraphQL endpoint, empty uses https://api.linear.app/graphql
+	LinearTeam  string `json:"linearTeam,omitempty"`  // Default team key of searches and new issues
+}
+
+// MailSettings configures the email sink and 'gllm mail', the SMTP password is a secret.
+type MailSettings struct {
+	Host     string `json:"host,omitempty"`     // SMTP server, empty sends through sendmail
+	Port     int    `json:"port,omitempty"`     // SMTP port, 587 by default; 465 uses implicit TLS
+	Username string `json:"username,omitempty"` // SMTP account, empty sends without authentication
+	From     string `json:"from,omitempty"`     // Sender address, empty uses the username
+	Sendmail string `json:"sendmail,omitempty"` // Path of sendmail, empty looks it up when no host is set
+	Subject  string `json:"subject,omitempty"`  // Subject template, e.g. "{{.Title}} - {{.Date}}"
+}
+
+// DatabaseSettings is a database of the query_database tool, its DSN is a secret.
+type DatabaseSettings struct {
+	Name  string `json:"name"`
+	Write bool   `json:"write,omitempty"` // Allow statements that change data, each one still needs confirmation
+}
+
+// HTTPProfile is a named authentication of the http_request tool, its token or password is a secret.
+type HTTPProfile struct {
+	Type     string `json:"type"`               // bearer, basic or header
+	URL      string `json:"url"`                // Requests must start with this URL, so the credential goes nowhere else
+	Username string `json:"username,omitempty"` // User of basic auth
+	Header   string `json:"header,omitempty"`   // Header holding the secret, e.g. X-API-Key
+}
+
+// CacheSettings configures the cache of identical model requests.
+type CacheSettings struct {
+	Enabled bool   `json:"enabled"`
+	TTL     string `json:"ttl,omitempty"`     // How long a response is reused, e.g. "24h"; empty uses the default
+	MaxSize int    `json:"maxSize,omitempty"` // Megabytes kept on disk, 0 uses the default
+}
+
+// HTTPSettings configures the HTTP client shared by the model providers.
+type HTTPSettings struct {
+	Proxy           string `json:"proxy,omitempty"`           // Proxy of the models without their own, empty uses the environment
+	ConnectTimeout  string `json:"connectTimeout,omitempty"`  // Dialing and TLS handshake, e.g. "10s"; empty uses the default
+	IdleTimeout     string `json:"idleTimeout,omitempty"`     // How long an idle connection is kept for reuse; empty uses the default
+	ResponseTimeout string `json:"responseTimeout,omitempty"` // Wait for the response headers, empty waits as long as the request runs
+	MaxConnsPerHost int    `json:"maxConnsPerHost,omitempty"` // Idle connections kept per host, 0 uses the default
+	DisableHTTP2    bool   `json:"disableHTTP2,omitempty"`    // Stay on HTTP/1.1, for proxies that break HTTP/2
+}
+
+// UISettings configures how responses are displayed.
+type UISettings struct {
+	Theme string `json:"theme,omitempty"` // Markdown and code theme, empty matches the color theme
+}
+
+// SafetySettings configures the handling of responses blocked by the provider's safety filters.
+type SafetySettings struct {
+	Retry bool `json:"retry"` // Retry a blocked request once with a sanitized prompt
+}
+
+// CouncilSettings configures 'gllm ask' with several models.
+type CouncilSettings struct {
+	Judge string `json:"judge,omitempty"` // Model comparing the answers, empty for none
+}
+
+// EmbeddingSettings configures the model that embeds prompts, skills and past sessions.
+type EmbeddingSettings struct {
+	Model string `json:"model,omitempty"` // Model of the config, empty matches words instead
+}
+
+// SubagentSettings limits how many sub-agent tasks run at the same time.
+type SubagentSettings struct {
+	MaxConcurrent int            `json:"maxConcurrent,omitempty"` // Tasks running at once, 0 uses the default
+	MaxPerAgent   int            `json:"maxPerAgent,omitempty"`   // Tasks of one agent running at once, 0 for no limit but the global one
+	AgentLimits   map[string]int `json:"agentLimits,omitempty"`   // Per-agent limits overriding MaxPerAgent
+}
+
 // Settings represents the structure of settings.json.
 type Settings struct {
 	MCP     MCPSettings    `json:"mcp"`
 	Skills  SkillsSettings `json:"skills"`
 	Search  SearchSettings `json:"search"`
 	Verbose VerboseSettings `json:"verbose"`
+	Context ContextSettings `json:"context"`
 	Plugin  PluginSettings `json:"plugin"`
 	Theme   string         `json:"theme"`
 	Editor  string         `json:"editor"`
 	Update  UpdateSettings `json:"update"`
-}
+	Tools   ToolsSettings  `json:"tools"`
+	Prompt  PromptSettings `json:"prompt"`
+	Team    TeamSettings   `json:"team"`
+	Redaction RedactionSettings `json:"redaction"`
+	Approval  ApprovalSettings  `json:"approval"`
+	Notify    NotifySettings    `json:"notify"`
+	Tickets   TicketSettings    `json:"tickets"`
+	Mail      MailSettings      `json:"mail"`
+	Databases map[string][]DatabaseSettings `json:"databases,omitempty"` // By project directory
+	HTTPProfiles map[string]HTTPProfile `json:"httpProfiles,omitempty"`
+	Cache        CacheSettings          `json:"cache"`
+	UI           UISettings             `json:"ui"`
+	Safety       SafetySettings         `json:"safety"`
+	Council      CouncilSettings        `json:"council"`
+	Embedding    EmbeddingSettings      `json:"embedding"`
+	Subagents    SubagentSettings       `json:"subagents"`
+	HTTP         HTTPSettings           `json:"http"`
+}
+
+// DefaultReadMaxTokens caps a single read_file result when no limit is configured.
+const DefaultReadMaxTokens = 25000
+
+// DefaultSubagentMaxConcurrent is how many sub-agent tasks run at once when no limit is configured.
+const DefaultSubagentMaxConcurrent = 4
 
 // SettingsStore provides access to settings.json.
 type SettingsStore struct {
@@ -191,6 +348,21 @@ func (s *SettingsStore) EnableSkill(name string) error {
 	return s.Save()
 }
 
+// GetSkillSuggestEnabled returns whether prompts are matched against the skills.
+func (s *SettingsStore) GetSkillSuggestEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.Skills.Suggest
+}
+
+// SetSkillSuggestEnabled enables or disables matching prompts against the skills.
+func (s *SettingsStore) SetSkillSuggestEnabled(enabled bool) error {
+	s.mu.Lock()
+	s.settings.Skills.Suggest = enabled
+	s.mu.Unlock()
+	return s.Save()
+}
+
 // GetAllowedMCPServers returns the list of allowed MCP server names.
 func (s *SettingsStore) GetAllowedMCPServers() []string {
 	s.mu.RLock()
@@ -277,6 +449,21 @@ func (s *SettingsStore) SetVerboseEnabled(enabled bool) error {
 	return s.Save()
 }
 
+// GetContextFilesEnabled returns whether instruction files are loaded into the system prompt.
+func (s *SettingsStore) GetContextFilesEnabled() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return !s.settings.Context.Disabled
+}
+
+// SetContextFilesEnabled enables or disables loading instruction files into the system prompt.
+func (s *SettingsStore) SetContextFilesEnabled(enabled bool) error {
+	s.mu.Lock()
+	s.settings.Context.Disabled = !enabled
+	s.mu.Unlock()
+	return s.Save()
+}
+
 // GetTheme returns the configured theme name.
 func (s *SettingsStore) GetTheme() string {
 	s.mu.RLock()
@@ -376,3 +563,377 @@ func (s *SettingsStore) SetEnabledPlugins(ids []string) error {
 	s.mu.Unlock()
 	return s.Save()
 }
+
+// GetReadMaxTokens returns the token cap of a single read_file result.
+func (s *SettingsStore) GetReadMaxTokens() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.settings.Tools.ReadMaxTokens <= 0 {
+		return DefaultReadMaxTokens
+	}
+	return s.settings.Tools.ReadMaxTokens
+}
+
+// SetReadMaxTokens sets the token cap of a single read_file result, 0 restores the default.
+func (s *SettingsStore) SetReadMaxTokens(tokens int) error {
+	s.mu.Lock()
+	s.settings.Tools.ReadMaxTokens = tokens
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetHardDelete reports whether delete_file and delete_directory remove files for good.
+func (s *SettingsStore) GetHardDelete() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.Tools.HardDelete
+}
+
+// SetHardDelete sets whether delete_file and delete_directory remove files for good.
+func (s *SettingsStore) SetHardDelete(hard bool) error {
+	s.mu.Lock()
+	s.settings.Tools.HardDelete = hard
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetSubagentLimits returns the concurrency limits of sub-agent tasks, with the defaults applied.
+func (s *SettingsStore) GetSubagentLimits() SubagentSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	limits := SubagentSettings{
+		MaxConcurrent: s.settings.Subagents.MaxConcurrent,
+		MaxPerAgent:   s.settings.Subagents.MaxPerAgent,
+		AgentLimits:   make(map[string]int, len(s.settings.Subagents.AgentLimits)),
+	}
+	if limits.MaxConcurrent <= 0 {
+		limits.MaxConcurrent = DefaultSubagentMaxConcurrent
+	}
+	for name, n := range s.settings.Subagents.AgentLimits {
+		limits.AgentLimits[name] = n
+	}
+	return limits
+}
+
+// SetSubagentMaxConcurrent sets how many sub-agent tasks run at once, 0 restores the default.
+func (s *SettingsStore) SetSubagentMaxConcurrent(n int) error {
+	s.mu.Lock()
+	s.settings.Subagents.MaxConcurrent = n
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// SetSubagentMaxPerAgent sets how many tasks of one agent run at once, 0 removes the limit.
+func (s *SettingsStore) SetSubagentMaxPerAgent(n int) error {
+	s.mu.Lock()
+	s.settings.Subagents.MaxPerAgent = n
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// SetSubagentAgentLimit sets how many tasks of the agent run at once, 0 falls back to the
+// limit of every agent.
+func (s *SettingsStore) SetSubagentAgentLimit(agent string, n int) error {
+	s.mu.Lock()
+	if n <= 0 {
+		delete(s.settings.Subagents.AgentLimits, agent)
+	} else {
+		if s.settings.Subagents.AgentLimits == nil {
+			s.settings.Subagents.AgentLimits = make(map[string]int)
+		}
+		s.settings.Subagents.AgentLimits[agent] = n
+	}
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetPromptLayers returns the configured system prompt layer order, nil means the default order.
+func (s *SettingsStore) GetPromptLayers() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.settings.Prompt.Layers) == 0 {
+		return nil
+	}
+	return append([]string(nil), s.settings.Prompt.Layers...)
+}
+
+// SetPromptLayers sets the system prompt layer order, nil restores the default order.
+func (s *SettingsStore) SetPromptLayers(layers []string) error {
+	s.mu.Lock()
+	s.settings.Prompt.Layers = layers
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetTeamSettings returns the team config sync settings.
+func (s *SettingsStore) GetTeamSettings() TeamSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.Team
+}
+
+// SetTeamSettings replaces the team config sync settings.
+func (s *SettingsStore) SetTeamSettings(team TeamSettings) error {
+	s.mu.Lock()
+	s.settings.Team = team
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetRedactionSettings returns the redaction settings.
+func (s *SettingsStore) GetRedactionSettings() RedactionSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r := s.settings.Redaction
+	r.Disabled = append([]string(nil), r.Disabled...)
+	r.Patterns = append([]RedactionPattern(nil), r.Patterns...)
+	return r
+}
+
+// SetRedactionSettings replaces the redaction settings.
+func (s *SettingsStore) SetRedactionSettings(redaction RedactionSettings) error {
+	s.mu.Lock()
+	s.settings.Redaction = redaction
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetApprovalSettings returns the settings of headless tool approval.
+func (s *SettingsStore) GetApprovalSettings() ApprovalSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	a := s.settings.Approval
+	a.AllowTools = append([]string(nil), a.AllowTools...)
+	a.AllowCommands = append([]string(nil), a.AllowCommands...)
+	return a
+}
+
+// SetApprovalSettings replaces the settings of headless tool approval.
+func (s *SettingsStore) SetApprovalSettings(approval ApprovalSettings) error {
+	s.mu.Lock()
+	s.settings.Approval = approval
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetNotifySettings returns the settings of desktop notifications.
+func (s *SettingsStore) GetNotifySettings() NotifySettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.Notify
+}
+
+// SetNotifySettings replaces the settings of desktop notifications.
+func (s *SettingsStore) SetNotifySettings(notify NotifySettings) error {
+	s.mu.Lock()
+	s.settings.Notify = notify
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetTicketSettings returns the settings of the issue trackers.
+func (s *SettingsStore) GetTicketSettings() TicketSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.Tickets
+}
+
+// SetTicketSettings replaces the settings of the issue trackers.
+func (s *SettingsStore) SetTicketSettings(tickets TicketSettings) error {
+	s.mu.Lock()
+	s.settings.Tickets = tickets
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetMailSettings returns the settings of sending email.
+func (s *SettingsStore) GetMailSettings() MailSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.Mail
+}
+
+// SetMailSettings replaces the settings of sending email.
+func (s *SettingsStore) SetMailSettings(mail MailSettings) error {
+	s.mu.Lock()
+	s.settings.Mail = mail
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetHTTPProfiles returns the auth profiles of the http_request tool by name.
+func (s *SettingsStore) GetHTTPProfiles() map[string]HTTPProfile {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	profiles := make(map[string]HTTPProfile, len(s.settings.HTTPProfiles))
+	for name, p := range s.settings.HTTPProfiles {
+		profiles[name] = p
+	}
+	return profiles
+}
+
+// SetHTTPProfile adds or replaces an auth profile of the http_request tool.
+func (s *SettingsStore) SetHTTPProfile(name string, profile HTTPProfile) error {
+	s.mu.Lock()
+	if s.settings.HTTPProfiles == nil {
+		s.settings.HTTPProfiles = make(map[string]HTTPProfile)
+	}
+	s.settings.HTTPProfiles[name] = profile
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// RemoveHTTPProfile removes an auth profile, it reports whether it was there.
+func (s *SettingsStore) RemoveHTTPProfile(name string) (bool, error) {
+	s.mu.Lock()
+	_, ok := s.settings.HTTPProfiles[name]
+	delete(s.settings.HTTPProfiles, name)
+	s.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, s.Save()
+}
+
+// GetDatabases returns the databases configured for the project directory.
+func (s *SettingsStore) GetDatabases(project string) []DatabaseSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]DatabaseSettings(nil), s.settings.Databases[project]...)
+}
+
+// SetDatabase adds the database to the project directory, or replaces the one of the same name.
+func (s *SettingsStore) SetDatabase(project string, db DatabaseSettings) error {
+	s.mu.Lock()
+	if s.settings.Databases == nil {
+		s.settings.Databases = make(map[string][]DatabaseSettings)
+	}
+	dbs := s.settings.Databases[project]
+	replaced := false
+	for i := range dbs {
+		if dbs[i].Name == db.Name {
+			dbs[i] = db
+			replaced = true
+		}
+	}
+	if !replaced {
+		dbs = append(dbs, db)
+	}
+	s.settings.Databases[project] = dbs
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// RemoveDatabase removes the database from the project directory, it reports whether it was there.
+func (s *SettingsStore) RemoveDatabase(project, name string) (bool, error) {
+	s.mu.Lock()
+	var kept []DatabaseSettings
+	for _, db := range s.settings.Databases[project] {
+		if db.Name != name {
+			kept = append(kept, db)
+		}
+	}
+	removed := len(kept) < len(s.settings.Databases[project])
+	if len(kept) == 0 {
+		delete(s.settings.Databases, project)
+	} else {
+		s.settings.Databases[project] = kept
+	}
+	s.mu.Unlock()
+	if !removed {
+		return false, nil
+	}
+	return true, s.Save()
+}
+
+// GetCacheSettings returns the settings of the model response cache.
+func (s *SettingsStore) GetCacheSettings() CacheSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.Cache
+}
+
+// SetCacheSettings replaces the settings of the model response cache.
+func (s *SettingsStore) SetCacheSettings(cache CacheSettings) error {
+	s.mu.Lock()
+	s.settings.Cache = cache
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetMarkdownTheme returns the configured markdown and code theme.
+func (s *SettingsStore) GetMarkdownTheme() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.UI.Theme
+}
+
+// SetMarkdownTheme sets the markdown and code theme, empty matches the color theme.
+func (s *SettingsStore) SetMarkdownTheme(name string) error {
+	s.mu.Lock()
+	s.settings.UI.Theme = name
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetSafetySettings returns the handling of safety blocks.
+func (s *SettingsStore) GetSafetySettings() SafetySettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.Safety
+}
+
+// SetSafetySettings replaces the handling of safety blocks.
+func (s *SettingsStore) SetSafetySettings(safety SafetySettings) error {
+	s.mu.Lock()
+	s.settings.Safety = safety
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetHTTPSettings returns the settings of the HTTP client of the model providers.
+func (s *SettingsStore) GetHTTPSettings() HTTPSettings {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.HTTP
+}
+
+// SetHTTPSettings replaces the settings of the HTTP client of the model providers.
+func (s *SettingsStore) SetHTTPSettings(http HTTPSettings) error {
+	s.mu.Lock()
+	s.settings.HTTP = http
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetCouncilJudge returns the model judging the answers of 'gllm ask', empty for none.
+func (s *SettingsStore) GetCouncilJudge() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.Council.Judge
+}
+
+// SetCouncilJudge sets the model judging the answers of 'gllm ask', empty for none.
+func (s *SettingsStore) SetCouncilJudge(model string) error {
+	s.mu.Lock()
+	s.settings.Council.Judge = model
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// GetEmbeddingModel returns the model embedding text for skill matching and session recall,
+// empty for none.
+func (s *SettingsStore) GetEmbeddingModel() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings.Embedding.Model
+}
+
+// SetEmbeddingModel sets the model embedding text for skill matching and session recall,
+// empty for none.
+func (s *SettingsStore) SetEmbeddingModel(model string) error {
+	s.mu.Lock()
+	s.settings.Embedding.Model = model
+	s.mu.Unlock()
+	return s.Save()
+}
@@ -24,10 +24,27 @@ type SkillSourceMeta struct {
 
 // SkillMetadata represents the metadata for a single skill.
 type SkillMetadata struct {
+	Name         string           `yaml:"name"`
+	Description  string           `yaml:"description"`
+	AutoActivate bool             `yaml:"auto_activate"` // Activated without a tool call when it matches the prompt
+	Scripts      []SkillScript    `yaml:"scripts"`       // Executable scripts the agent may run with run_skill_script
+	Location     string           `yaml:"-"`             // Full path to SKILL.md, not in YAML
+	SourceMeta   *SkillSourceMeta `yaml:"-"`             // Loaded from skill.meta.json, not in YAML
+}
+
+// SkillScript is an executable script bundled with a skill, declared in its frontmatter.
+type SkillScript struct {
 	Name        string           `yaml:"name"`
+	Path        string           `yaml:"path"` // Relative to the skill directory
 	Description string           `yaml:"description"`
-	Location    string           `yaml:"-"` // Full path to SKILL.md, not in YAML
-	SourceMeta  *SkillSourceMeta `yaml:"-"` // Loaded from skill.meta.json, not in YAML
+	Args        []SkillScriptArg `yaml:"args"` // Passed to the script in this order
+}
+
+// SkillScriptArg is a declared argument of a skill script.
+type SkillScriptArg struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Required    bool   `yaml:"required"`
 }
 
 // EnsureSkillsDir creates the skills directory if it doesn't exist.
@@ -65,12 +82,34 @@ func ParseSkillFrontmatter(path string) (*SkillMetadata, error) {
 
 // ScanSkills scans the skills directory for valid skills.
 // A valid skill is a directory containing a SKILL.md file with valid frontmatter.
+// Skills of the team config are included unless a local skill has the same name.
 func ScanSkills() ([]SkillMetadata, error) {
 	if err := EnsureSkillsDir(); err != nil {
 		return nil, fmt.Errorf("failed to ensure skills directory: %w", err)
 	}
 
-	skillsDir := GetSkillsDirPath()
+	skills, err := scanSkillsInDir(GetSkillsDirPath())
+	if err != nil {
+		return nil, err
+	}
+	team, _ := scanSkillsInDir(GetTeamSkillsDirPath())
+	for _, skill := range team {
+		shadowed := false
+		for _, own := range skills {
+			if strings.EqualFold(own.Name, skill.Name) {
+				shadowed = true
+				break
+			}
+		}
+		if !shadowed {
+			skills = append(skills, skill)
+		}
+	}
+	return skills, nil
+}
+
+// scanSkillsInDir scans a single directory for valid skills.
+func scanSkillsInDir(skillsDir string) ([]SkillMetadata, error) {
 	entries, err := os.ReadDir(skillsDir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -0,0 +1,138 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Statuses of a queued sub-agent task.
+const (
+	QueuedTaskPending   = "pending"
+	QueuedTaskRunning   = "running"
+	QueuedTaskCompleted = "completed"
+	QueuedTaskFailed    = "failed"
+)
+
+// QueuedTask is a sub-agent task of a batch as submitted, with where it got to.
+type QueuedTask struct {
+	CallerAgentName string    `json:"caller_agent_name"`
+	AgentName       string    `json:"agent_name"`
+	Instruction     string    `json:"instruction"`
+	TaskKey         string    `json:"task_key"`
+	InputKeys       []string  `json:"input_keys,omitempty"`
+	Priority        int       `json:"priority,omitempty"`
+	OutputSchema    string    `json:"output_schema,omitempty"`
+	Status          string    `json:"status"`
+	Error           string    `json:"error,omitempty"`
+	StateKey        string    `json:"state_key,omitempty"`
+	Result          string    `json:"result,omitempty"` // The SharedState value, to recover it
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TaskBatch is one spawn of sub-agent tasks, kept on disk until all its tasks complete so an
+// interrupted batch can be resumed.
+type TaskBatch struct {
+	ID      string       `json:"id"`
+	Session string       `json:"session,omitempty"` // Session of the orchestrator
+	WorkDir string       `json:"work_dir"`
+	Created time.Time    `json:"created"`
+	Tasks   []QueuedTask `json:"tasks"`
+}
+
+// Done reports whether every task of the batch completed.
+func (b *TaskBatch) Done() bool {
+	for _, t := range b.Tasks {
+		if t.Status != QueuedTaskCompleted {
+			return false
+		}
+	}
+	return true
+}
+
+// Incomplete returns how many tasks of the batch are to be run again.
+func (b *TaskBatch) Incomplete() int {
+	n := 0
+	for _, t := range b.Tasks {
+		if t.Status != QueuedTaskCompleted {
+			n++
+		}
+	}
+	return n
+}
+
+// GetTaskQueueDirPath returns the directory of the sub-agent batches that did not complete.
+func GetTaskQueueDirPath() string {
+	return filepath.Join(GetTasksDirPath(), "queue")
+}
+
+// SaveTaskBatch writes a batch, replacing the file atomically so a crash never leaves half of it.
+func SaveTaskBatch(batch *TaskBatch) error {
+	dir := GetTaskQueueDirPath()
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, batch.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, content, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadTaskBatch reads a batch.
+func LoadTaskBatch(id string) (*TaskBatch, error) {
+	content, err := os.ReadFile(filepath.Join(GetTaskQueueDirPath(), id+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("task batch '%s' not found", id)
+		}
+		return nil, err
+	}
+	var batch TaskBatch
+	if err := json.Unmarshal(content, &batch); err != nil {
+		return nil, fmt.Errorf("failed to parse task batch '%s': %w", id, err)
+	}
+	return &batch, nil
+}
+
+// ListTaskBatches returns the saved batches, newest first.
+func ListTaskBatches() ([]TaskBatch, error) {
+	entries, err := os.ReadDir(GetTaskQueueDirPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var batches []TaskBatch
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		batch, err := LoadTaskBatch(strings.TrimSuffix(e.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		batches = append(batches, *batch)
+	}
+	sort.Slice(batches, func(i, j int) bool { return batches[i].ID > batches[j].ID })
+	return batches, nil
+}
+
+// RemoveTaskBatch deletes a batch, a missing one is not an error.
+func RemoveTaskBatch(id string) error {
+	err := os.Remove(filepath.Join(GetTaskQueueDirPath(), id+".json"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
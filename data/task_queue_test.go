@@ -0,0 +1,54 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTaskQueue(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if batches, err := ListTaskBatches(); err != nil || len(batches) != 0 {
+		t.Fatalf("expected no batches, got %v, %v", batches, err)
+	}
+
+	batch := &TaskBatch{
+		ID:      "20260101-120000-abcd",
+		Session: "main",
+		Created: time.Now(),
+		Tasks: []QueuedTask{
+			{AgentName: "critic", TaskKey: "review", Status: QueuedTaskCompleted, StateKey: "critic_review", Result: "ok"},
+			{AgentName: "tester", TaskKey: "tests", Status: QueuedTaskRunning},
+		},
+	}
+	if batch.Done() || batch.Incomplete() != 1 {
+		t.Errorf("expected one incomplete task, got %d", batch.Incomplete())
+	}
+	if err := SaveTaskBatch(batch); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveTaskBatch(&TaskBatch{ID: "20260102-090000-ef01"}); err != nil {
+		t.Fatal(err)
+	}
+
+	batches, err := ListTaskBatches()
+	if err != nil || len(batches) != 2 || batches[0].ID != "20260102-090000-ef01" {
+		t.Fatalf("expected two batches, newest first, got %+v, %v", batches, err)
+	}
+	loaded, err := LoadTaskBatch(batch.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Session != "main" || loaded.Tasks[0].Result != "ok" || loaded.Tasks[1].Status != QueuedTaskRunning {
+		t.Errorf("unexpected batch %+v", loaded)
+	}
+
+	if err := RemoveTaskBatch(batch.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveTaskBatch(batch.ID); err != nil {
+		t.Errorf("removing a missing batch should not fail: %v", err)
+	}
+	if _, err := LoadTaskBatch(batch.ID); err == nil {
+		t.Error("expected the removed batch to be gone")
+	}
+}
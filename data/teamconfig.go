@@ -0,0 +1,118 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+/*
+The team config is a git repository synced into GetTeamConfigDirPath().
+It is a read-only layer under the local config, laid out like the config dir:
+
+	agents/*.md        agents, also usable as templates through extends
+	skills/<name>/     skills with a SKILL.md
+	workflows/*.md     workflows
+	GLLM.md            team instructions, injected before the global instructions
+
+Local agents, skills and workflows with the same name take precedence.
+*/
+
+// GetTeamAgentsDirPath returns the path to the team agents directory.
+func GetTeamAgentsDirPath() string {
+	return filepath.Join(GetTeamConfigDirPath(), "agents")
+}
+
+// GetTeamSkillsDirPath returns the path to the team skills directory.
+func GetTeamSkillsDirPath() string {
+	return filepath.Join(GetTeamConfigDirPath(), "skills")
+}
+
+// GetTeamWorkflowsDirPath returns the path to the team workflows directory.
+func GetTeamWorkflowsDirPath() string {
+	return filepath.Join(GetTeamConfigDirPath(), "workflows")
+}
+
+// agentFilePath returns the file of an agent, the local one when both layers define it.
+// For an agent that does not exist yet it returns the local path.
+func agentFilePath(name string) string {
+	local := filepath.Join(GetAgentsDirPath(), name+".md")
+	if _, err := os.Stat(local); err == nil {
+		return local
+	}
+	team := filepath.Join(GetTeamAgentsDirPath(), name+".md")
+	if _, err := os.Stat(team); err == nil {
+		return team
+	}
+	return local
+}
+
+// IsTeamAgent reports whether an agent comes from the team config only and is therefore read-only.
+func IsTeamAgent(name string) bool {
+	name = strings.ToLower(name)
+	if _, err := os.Stat(filepath.Join(GetAgentsDirPath(), name+".md")); err == nil {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(GetTeamAgentsDirPath(), name+".md"))
+	return err == nil
+}
+
+// listNames returns the names of the entries in dir, directories when dirs is set,
+// otherwise files with the given extension (stripped from the name).
+func listNames(dir string, dirs bool, ext string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		switch {
+		case dirs && entry.IsDir():
+			names = append(names, entry.Name())
+		case !dirs && !entry.IsDir() && filepath.Ext(entry.Name()) == ext:
+			names = append(names, strings.TrimSuffix(entry.Name(), ext))
+		}
+	}
+	return names
+}
+
+// TeamConflict is an item defined both locally and in the team config.
+// The local definition wins.
+type TeamConflict struct {
+	Kind string // agent, skill or workflow
+	Name string
+}
+
+// FindTeamConflicts lists the team items that are shadowed by local ones.
+func FindTeamConflicts() []TeamConflict {
+	var conflicts []TeamConflict
+	check := func(kind string, local, team []string) {
+		own := make(map[string]bool)
+		for _, name := range local {
+			own[strings.ToLower(name)] = true
+		}
+		for _, name := range team {
+			if own[strings.ToLower(name)] {
+				conflicts = append(conflicts, TeamConflict{Kind: kind, Name: name})
+			}
+		}
+	}
+	check("agent", listNames(GetAgentsDirPath(), false, ".md"), listNames(GetTeamAgentsDirPath(), false, ".md"))
+	check("skill", listNames(GetSkillsDirPath(), true, ""), listNames(GetTeamSkillsDirPath(), true, ""))
+	check("workflow", listNames(GetWorkflowsDirPath(), false, WorkflowFileExt), listNames(GetTeamWorkflowsDirPath(), false, WorkflowFileExt))
+	sort.Slice(conflicts, func(i, j int) bool {
+		if conflicts[i].Kind != conflicts[j].Kind {
+			return conflicts[i].Kind < conflicts[j].Kind
+		}
+		return conflicts[i].Name < conflicts[j].Name
+	})
+	return conflicts
+}
+
+// TeamConfigCounts returns how many agents, skills and workflows the team config provides.
+func TeamConfigCounts() (agents, skills, workflows int) {
+	return len(listNames(GetTeamAgentsDirPath(), false, ".md")),
+		len(listNames(GetTeamSkillsDirPath(), true, "")),
+		len(listNames(GetTeamWorkflowsDirPath(), false, WorkflowFileExt))
+}
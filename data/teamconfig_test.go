@@ -0,0 +1,49 @@
+package data
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTeamConfigLayer(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	teamAgents := GetTeamAgentsDirPath()
+	if err := os.MkdirAll(teamAgents, 0750); err != nil {
+		t.Fatal(err)
+	}
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(teamAgents, "base-coder.md"), "---\nname: base-coder\nmodel: gpt\ntools: [shell]\n---\n\nTeam prompt.\n")
+	write(filepath.Join(teamAgents, "reviewer.md"), "---\nname: reviewer\nmodel: gpt\n---\n\nTeam reviewer.\n")
+	writeTestAgent(t, "reviewer", "---\nname: reviewer\nmodel: local\n---\n\nMy reviewer.\n")
+	writeTestAgent(t, "coder", "---\nname: coder\nextends: base-coder\n---\n\n{{parent}} Local.\n")
+
+	store := NewConfigStore()
+	names := store.GetAgentNames()
+	if strings.Join(names, ",") != "base-coder,coder,reviewer" {
+		t.Errorf("unexpected agent names: %v", names)
+	}
+	if a := store.GetAgent("reviewer"); a == nil || a.SystemPrompt != "My reviewer." {
+		t.Errorf("the local agent should override the team agent: %+v", a)
+	}
+	if a := store.GetAgent("coder"); a == nil || a.SystemPrompt != "Team prompt. Local." || a.Model.Name != "gpt" {
+		t.Errorf("a local agent should extend a team agent: %+v", a)
+	}
+	if err := store.DeleteAgent("base-coder"); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("team agents should be read-only, got %v", err)
+	}
+
+	conflicts := FindTeamConflicts()
+	if len(conflicts) != 1 || conflicts[0] != (TeamConflict{Kind: "agent", Name: "reviewer"}) {
+		t.Errorf("unexpected conflicts: %v", conflicts)
+	}
+	if agents, _, _ := TeamConfigCounts(); agents != 2 {
+		t.Errorf("expected 2 team agents, got %d", agents)
+	}
+}
@@ -3,24 +3,33 @@ package data
 import (
 	"fmt"
 	"math"
+	"slices"
 	"sort"
 	"strings"
 
+	chromastyles "github.com/alecthomas/chroma/v2/styles"
+	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
 	"github.com/muesli/termenv"
 	goghthemes "github.com/willyv3/gogh-themes"
 )
 
 const (
 	DefaultThemeName string = "Dracula"
+)
 
-	// Formatting Utilities
+var (
+	// Formatting Utilities, empty when colors are off
 	BoldSeq      string = "\033[1m"
 	UnderlineSeq string = "\033[4m"
 	ResetSeq     string = "\033[0m"
-)
 
-var (
+	// colorsDisabled is set for NO_COLOR, output that is not a terminal and --plain
+	colorsDisabled bool
+	// plainOutput is set by --plain, the output is meant for piping
+	plainOutput bool
+
 	// Current Theme Name
 	CurrentThemeName string = DefaultThemeName
 	CurrentTheme     goghthemes.Theme
@@ -147,14 +156,14 @@ func applyTheme(t goghthemes.Theme) {
 
 	// Helper to convert hex to ANSI sequence
 	toAnsi := func(hex string) string {
-		if hex == "" {
+		if hex == "" || colorsDisabled {
 			return ""
 		}
 		c := p.Color(hex)
 		return fmt.Sprintf("%s%sm", termenv.CSI, c.Sequence(false))
 	}
 	toAnsiBg := func(hex string) string {
-		if hex == "" {
+		if hex == "" || colorsDisabled {
 			return ""
 		}
 		c := p.Color(hex)
@@ -265,6 +274,31 @@ func applyTheme(t goghthemes.Theme) {
 	BackgroundStatusHex = t.Foreground
 }
 
+// DisableColors turns off the colors of everything gllm prints.
+func DisableColors() {
+	colorsDisabled = true
+	BoldSeq, UnderlineSeq, ResetSeq = "", "", ""
+	lipgloss.SetColorProfile(termenv.Ascii)
+	applyTheme(CurrentTheme)
+}
+
+// ColorsDisabled reports whether the colors are off.
+func ColorsDisabled() bool {
+	return colorsDisabled
+}
+
+// SetPlainOutput turns off the colors, the markdown rendering and the spinner, so that the
+// output can be piped.
+func SetPlainOutput() {
+	plainOutput = true
+	DisableColors()
+}
+
+// PlainOutput reports whether --plain is set.
+func PlainOutput() bool {
+	return plainOutput
+}
+
 // ListThemes returns a sorted list of all available theme names.
 func ListThemes() []string {
 	names := goghthemes.Names()
@@ -359,6 +393,82 @@ func MostSimilarGlamourStyle() string {
 	return best
 }
 
+// markdownBaseStyles are the glamour styles the markdown theme can name.
+var markdownBaseStyles = []string{
+	styles.DarkStyle, styles.LightStyle, styles.DraculaStyle, styles.TokyoNightStyle,
+	styles.PinkStyle, styles.AsciiStyle, styles.NoTTYStyle,
+}
+
+// MarkdownThemes lists the markdown themes by name, any chroma style is accepted as well.
+func MarkdownThemes() []string {
+	return append([]string{"auto", "solarized"}, markdownBaseStyles...)
+}
+
+// ChromaStyles lists the chroma styles code blocks can be highlighted with.
+func ChromaStyles() []string {
+	return chromastyles.Names()
+}
+
+// ValidateMarkdownTheme checks a markdown theme, it returns the name in lower case.
+func ValidateMarkdownTheme(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || slices.Contains(MarkdownThemes(), name) {
+		return name, nil
+	}
+	if _, ok := chromastyles.Registry[name]; ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("unknown theme '%s', expected %s or a chroma style such as monokai", name, strings.Join(MarkdownThemes(), ", "))
+}
+
+// markdownTheme resolves the markdown theme to a glamour style, and the chroma style of the
+// code blocks when it replaces the one of the glamour style.
+func markdownTheme() (string, string) {
+	if colorsDisabled {
+		return styles.NoTTYStyle, ""
+	}
+	name := strings.ToLower(GetSettingsStore().GetMarkdownTheme())
+	// A chroma style goes with the dark or light style of the terminal theme
+	base, variant := styles.DarkStyle, "dark"
+	if hexLuminance(CurrentTheme.Background) > 0.45 {
+		base, variant = styles.LightStyle, "light"
+	}
+	switch {
+	case name == "" || name == "auto":
+		return MostSimilarGlamourStyle(), ""
+	case slices.Contains(markdownBaseStyles, name):
+		return name, ""
+	case name == "solarized":
+		return base, "solarized-" + variant
+	}
+	if _, ok := chromastyles.Registry[name]; ok {
+		return base, name
+	}
+	return MostSimilarGlamourStyle(), ""
+}
+
+// MarkdownStyleName describes the markdown theme in use, e.g. dracula, or dark with monokai.
+func MarkdownStyleName() string {
+	style, code := markdownTheme()
+	if code == "" {
+		return style
+	}
+	return style + " with " + code
+}
+
+// MarkdownStyleOption returns the option that renders markdown with the markdown theme.
+func MarkdownStyleOption() glamour.TermRendererOption {
+	style, code := markdownTheme()
+	config, ok := styles.DefaultStyles[style]
+	if code == "" || !ok {
+		return glamour.WithStandardStyle(style)
+	}
+	c := *config
+	c.CodeBlock.Chroma = nil
+	c.CodeBlock.Theme = code
+	return glamour.WithStyles(c)
+}
+
 // hexLuminance returns the perceived luminance [0,1] of a hex colour string.
 func hexLuminance(hex string) float64 {
 	var r, g, b int
@@ -0,0 +1,17 @@
+package data
+
+import "testing"
+
+func TestValidateMarkdownTheme(t *testing.T) {
+	for _, name := range []string{"", "auto", "Solarized", "tokyo-night", "notty", "monokai", "github-dark"} {
+		if _, err := ValidateMarkdownTheme(name); err != nil {
+			t.Errorf("expected %q to be a theme: %v", name, err)
+		}
+	}
+	if name, _ := ValidateMarkdownTheme(" Dracula "); name != "dracula" {
+		t.Errorf("expected the name in lower case, got %q", name)
+	}
+	if _, err := ValidateMarkdownTheme("no-such-style"); err == nil {
+		t.Error("expected an unknown theme to be refused")
+	}
+}
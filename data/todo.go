@@ -0,0 +1,98 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Todo item statuses
+const (
+	TodoPending    = "pending"
+	TodoInProgress = "in_progress"
+	TodoCompleted  = "completed"
+)
+
+// TodoItem is one entry of an agent's task list.
+type TodoItem struct {
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	Status  string `json:"status"`
+}
+
+var (
+	todoMu      sync.Mutex
+	memoryTodos []TodoItem // Todos of sessions that are not saved to disk
+)
+
+// LoadTodos reads the todo list stored at path. An empty path means the in-memory list.
+func LoadTodos(path string) ([]TodoItem, error) {
+	todoMu.Lock()
+	defer todoMu.Unlock()
+	if path == "" {
+		return append([]TodoItem(nil), memoryTodos...), nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var items []TodoItem
+	if err := json.Unmarshal(content, &items); err != nil {
+		return nil, fmt.Errorf("failed to parse todo list %s: %w", path, err)
+	}
+	return items, nil
+}
+
+// SaveTodos replaces the todo list stored at path. An empty path means the in-memory list.
+func SaveTodos(path string, items []TodoItem) error {
+	todoMu.Lock()
+	defer todoMu.Unlock()
+	if path == "" {
+		memoryTodos = append([]TodoItem(nil), items...)
+		return nil
+	}
+
+	content, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// ValidateTodos checks statuses and ids, and that at most one item is in progress.
+func ValidateTodos(items []TodoItem) error {
+	seen := make(map[string]bool, len(items))
+	inProgress := 0
+	for i, item := range items {
+		if item.ID == "" {
+			return fmt.Errorf("todo #%d has no id", i+1)
+		}
+		if seen[item.ID] {
+			return fmt.Errorf("duplicate todo id '%s'", item.ID)
+		}
+		seen[item.ID] = true
+		if item.Content == "" {
+			return fmt.Errorf("todo '%s' has no content", item.ID)
+		}
+		switch item.Status {
+		case TodoPending, TodoCompleted:
+		case TodoInProgress:
+			inProgress++
+		default:
+			return fmt.Errorf("todo '%s' has invalid status '%s', use %s, %s or %s", item.ID, item.Status, TodoPending, TodoInProgress, TodoCompleted)
+		}
+	}
+	if inProgress > 1 {
+		return fmt.Errorf("only one todo can be %s at a time, got %d", TodoInProgress, inProgress)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package data
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateTodos(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   []TodoItem
+		wantErr bool
+	}{
+		{"valid", []TodoItem{{"1", "a", TodoCompleted}, {"2", "b", TodoInProgress}, {"3", "c", TodoPending}}, false},
+		{"empty", nil, false},
+		{"missing id", []TodoItem{{"", "a", TodoPending}}, true},
+		{"duplicate id", []TodoItem{{"1", "a", TodoPending}, {"1", "b", TodoPending}}, true},
+		{"missing content", []TodoItem{{"1", "", TodoPending}}, true},
+		{"bad status", []TodoItem{{"1", "a", "done"}}, true},
+		{"two in progress", []TodoItem{{"1", "a", TodoInProgress}, {"2", "b", TodoInProgress}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTodos(tt.items)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTodos() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSaveLoadTodos(t *testing.T) {
+	items := []TodoItem{{"1", "write code", TodoCompleted}, {"2", "run tests", TodoInProgress}}
+
+	for _, path := range []string{filepath.Join(t.TempDir(), "s", "main.todos.json"), ""} {
+		missing, err := LoadTodos(filepath.Join(t.TempDir(), "none.json"))
+		if err != nil || len(missing) != 0 {
+			t.Fatalf("expected empty list for missing file, got %v, %v", missing, err)
+		}
+		if err := SaveTodos(path, items); err != nil {
+			t.Fatalf("SaveTodos(%q) failed: %v", path, err)
+		}
+		got, err := LoadTodos(path)
+		if err != nil {
+			t.Fatalf("LoadTodos(%q) failed: %v", path, err)
+		}
+		if len(got) != 2 || got[1] != items[1] {
+			t.Errorf("LoadTodos(%q) = %v, want %v", path, got, items)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package data
+
+import (
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ToolGrants are the approvals given at confirmation prompts for the rest of the session:
+// whole tools, and shell commands by prefix.
+type ToolGrants struct {
+	mu       sync.RWMutex
+	tools    map[string]bool
+	prefixes []string
+}
+
+var sessionToolGrants = NewToolGrants()
+
+// NewToolGrants creates an empty set of grants.
+func NewToolGrants() *ToolGrants {
+	return &ToolGrants{tools: make(map[string]bool)}
+}
+
+// GetSessionToolGrants returns the grants of the current session.
+func GetSessionToolGrants() *ToolGrants {
+	return sessionToolGrants
+}
+
+// AllowTool approves all calls of a tool.
+func (g *ToolGrants) AllowTool(name string) {
+	if name == "" {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tools[name] = true
+}
+
+// AllowCommandPrefix approves shell commands that start with prefix.
+func (g *ToolGrants) AllowCommandPrefix(prefix string) {
+	prefix = strings.Join(strings.Fields(prefix), " ")
+	if prefix == "" {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !slices.Contains(g.prefixes, prefix) {
+		g.prefixes = append(g.prefixes, prefix)
+	}
+}
+
+// shellChaining are the characters that let a command run more than its first program.
+const shellChaining = ";&|`$<>()\n\r"
+
+// Allows reports whether a call is covered by a grant. command is the shell command
+// of a shell call, it matches a prefix grant only when it does not chain other commands.
+func (g *ToolGrants) Allows(tool, command string) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if g.tools[tool] {
+		return true
+	}
+	if command == "" || strings.ContainsAny(command, shellChaining) {
+		return false
+	}
+	command = strings.Join(strings.Fields(command), " ")
+	for _, prefix := range g.prefixes {
+		if command == prefix || strings.HasPrefix(command, prefix+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+// List returns the granted tools and command prefixes.
+func (g *ToolGrants) List() (tools []string, prefixes []string) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	for name := range g.tools {
+		tools = append(tools, name)
+	}
+	slices.Sort(tools)
+	return tools, slices.Clone(g.prefixes)
+}
+
+// Reset revokes all grants.
+func (g *ToolGrants) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tools = make(map[string]bool)
+	g.prefixes = nil
+}
+
+// CommandPrefix suggests the prefix to grant for a shell command: the program and,
+// when it has one, its subcommand, e.g. "go test" for "go test ./...".
+// It returns "" for commands that chain others, which a prefix grant never covers.
+func CommandPrefix(command string) string {
+	if strings.ContainsAny(command, shellChaining) {
+		return ""
+	}
+	fields := strings.Fields(command)
+	switch {
+	case len(fields) == 0:
+		return ""
+	case len(fields) == 1 || !isSubcommand(fields[1]):
+		return fields[0]
+	default:
+		return fields[0] + " " + fields[1]
+	}
+}
+
+// isSubcommand reports whether an argument looks like a subcommand rather than a flag, path or value.
+func isSubcommand(arg string) bool {
+	if arg == "" || arg[0] == '-' {
+		return false
+	}
+	for _, r := range arg {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
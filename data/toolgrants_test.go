@@ -0,0 +1,58 @@
+package data
+
+import "testing"
+
+func TestToolGrants(t *testing.T) {
+	g := NewToolGrants()
+	g.AllowTool("read_file")
+	g.AllowCommandPrefix("go  test")
+
+	cases := []struct {
+		tool, command string
+		want          bool
+	}{
+		{"read_file", "", true},
+		{"write_file", "", false},
+		{"shell", "go test ./...", true},
+		{"shell", "go test", true},
+		{"shell", "go testing", false},
+		{"shell", "go vet ./...", false},
+		{"shell", "go test ./... && rm -rf ~", false},
+		{"shell", "go test $(rm -rf ~)", false},
+		{"shell", "go test ./... | tee out.txt", false},
+	}
+	for _, c := range cases {
+		if got := g.Allows(c.tool, c.command); got != c.want {
+			t.Errorf("Allows(%q, %q) = %v, want %v", c.tool, c.command, got, c.want)
+		}
+	}
+
+	tools, prefixes := g.List()
+	if len(tools) != 1 || len(prefixes) != 1 || prefixes[0] != "go test" {
+		t.Errorf("List() = %v, %v", tools, prefixes)
+	}
+	g.Reset()
+	if g.Allows("read_file", "") {
+		t.Error("grants must be gone after Reset")
+	}
+}
+
+func TestCommandPrefix(t *testing.T) {
+	cases := map[string]string{
+		"go test ./...":          "go test",
+		"git status":             "git status",
+		"ls -la":                 "ls",
+		"cat README.md":          "cat",
+		"npm run build":          "npm run",
+		"make":                   "make",
+		"go test ./... && ls":    "",
+		"echo hi > out.txt":      "",
+		"  ":                     "",
+		"python3 script.py --go": "python3",
+	}
+	for command, want := range cases {
+		if got := CommandPrefix(command); got != want {
+			t.Errorf("CommandPrefix(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package data
 
+import "context"
+
 type ToolConfirmResult int
 
 const (
@@ -11,6 +13,11 @@ type ToolsUse struct {
 	AutoApprove bool              // Whether tools can be used without user confirmation
 	Confirm     ToolConfirmResult // User confirmation result
 	FilePath    string            // File path relevant to the tool use, if any
+	Tool        string            // Name of the tool being confirmed, if known
+	Command     string            // Shell command being confirmed, if any
+	Feedback    string            // Why the user declined, sent to the model instead of cancelling
+	Approver    string            // Approval backend that decided instead of the user, if any
+	Ctx         context.Context   // Cancellation of the tool call being confirmed, nil for none
 }
 
 func (tu *ToolsUse) ConfirmOnce() {
@@ -27,3 +34,21 @@ func (tu *ToolsUse) ConfirmCancel() {
 	tu.Confirm = ToolConfirmCancel
 	tu.AutoApprove = false
 }
+
+// ConfirmTool approves this call and all later calls of the same tool in this session.
+func (tu *ToolsUse) ConfirmTool() {
+	tu.ConfirmOnce()
+	GetSessionToolGrants().AllowTool(tu.Tool)
+}
+
+// ConfirmCommandPrefix approves this call and later shell commands starting with prefix in this session.
+func (tu *ToolsUse) ConfirmCommandPrefix(prefix string) {
+	tu.ConfirmOnce()
+	GetSessionToolGrants().AllowCommandPrefix(prefix)
+}
+
+// ConfirmDeny declines the call and tells the model why, the turn goes on with the feedback.
+func (tu *ToolsUse) ConfirmDeny(feedback string) {
+	tu.ConfirmCancel()
+	tu.Feedback = feedback
+}
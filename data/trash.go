@@ -0,0 +1,136 @@
+package data
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TrashEntry is a file or directory deleted by a tool, kept in the trash of its session until
+// it is restored or the trash is emptied.
+type TrashEntry struct {
+	ID      string    `json:"id"`
+	Time    time.Time `json:"time"`
+	Session string    `json:"session,omitempty"`
+	Agent   string    `json:"agent,omitempty"`
+	Path    string    `json:"path"` // Absolute path it was deleted from
+	IsDir   bool      `json:"isDir,omitempty"`
+	Size    int64     `json:"size,omitempty"` // Bytes of the file, or of the files of the directory
+}
+
+const (
+	trashItemName  = "item"       // Deleted file or directory in the folder of its entry
+	trashEntryName = "entry.json" // Metadata in the folder of an entry
+)
+
+// trashSessionDir returns the trash of a session, the sessions without a name share one.
+func trashSessionDir(session string) string {
+	if session == "" {
+		session = "_"
+	}
+	return filepath.Join(GetTrashDirPath(), strings.NewReplacer("/", "_", "\\", "_").Replace(session))
+}
+
+// Folder returns the folder of the entry, with its metadata and the deleted item.
+func (e *TrashEntry) Folder() string {
+	return filepath.Join(trashSessionDir(e.Session), e.ID)
+}
+
+// ItemPath returns where the deleted file or directory is kept.
+func (e *TrashEntry) ItemPath() string {
+	return filepath.Join(e.Folder(), trashItemName)
+}
+
+// CreateTrashEntry assigns an ID and time to the entry, creates its folder and saves its
+// metadata. The deleted item is then moved to ItemPath.
+func CreateTrashEntry(e *TrashEntry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	base := strings.ReplaceAll(e.Time.Format("20060102-150405.000"), ".", "-")
+	if err := os.MkdirAll(trashSessionDir(e.Session), 0750); err != nil {
+		return err
+	}
+	for n := 1; ; n++ {
+		e.ID = base
+		if n > 1 {
+			e.ID = fmt.Sprintf("%s-%d", base, n)
+		}
+		err := os.Mkdir(e.Folder(), 0750)
+		if err == nil {
+			break
+		}
+		if !os.IsExist(err) {
+			return err
+		}
+	}
+	content, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(e.Folder(), trashEntryName), content, 0600)
+}
+
+// RemoveTrashEntry deletes the entry and what is left of its item.
+func RemoveTrashEntry(e *TrashEntry) error {
+	if err := os.RemoveAll(e.Folder()); err != nil {
+		return err
+	}
+	os.Remove(trashSessionDir(e.Session)) // Only when it is empty
+	return nil
+}
+
+// ListTrash returns the entries of every session, newest first.
+func ListTrash() ([]*TrashEntry, error) {
+	sessions, err := os.ReadDir(GetTrashDirPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var entries []*TrashEntry
+	for _, session := range sessions {
+		if !session.IsDir() {
+			continue
+		}
+		dir := filepath.Join(GetTrashDirPath(), session.Name())
+		folders, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, folder := range folders {
+			content, err := os.ReadFile(filepath.Join(dir, folder.Name(), trashEntryName))
+			if err != nil {
+				continue // Skip entries still being created or damaged
+			}
+			var e TrashEntry
+			if err := json.Unmarshal(content, &e); err != nil {
+				continue
+			}
+			entries = append(entries, &e)
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.After(entries[j].Time)
+	})
+	return entries, nil
+}
+
+// FindTrashEntry returns the entry with the given ID.
+func FindTrashEntry(id string) (*TrashEntry, error) {
+	entries, err := ListTrash()
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("trash entry '%s' not found", id)
+}
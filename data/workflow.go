@@ -17,6 +17,9 @@ const (
 type WorkflowMetadata struct {
 	Name        string `yaml:"name"`        // Display name
 	Description string `yaml:"description"` // Brief description for /help
+	Notify      string `yaml:"notify"`      // Sink the final output is posted to, e.g. slack:#channel
+	Approve     string `yaml:"approve"`     // Action that waits for someone's approval, e.g. "deploy to production"
+	Batch       bool   `yaml:"batch"`       // Runs a jobs file through the batch API of the provider
 	Location    string // Full path to workflow file
 }
 
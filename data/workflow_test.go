@@ -27,6 +27,9 @@ func TestWorkflowParsing(t *testing.T) {
 	validContent := `---
 name: debug-flow
 description: A debug workflow
+notify: "slack:#dev"
+approve: deploy to staging
+batch: true
 ---
 Do debugging steps.
 `
@@ -45,6 +48,15 @@ Do debugging steps.
 	if meta.Description != "A debug workflow" {
 		t.Errorf("Expected description 'A debug workflow', got '%s'", meta.Description)
 	}
+	if meta.Notify != "slack:#dev" {
+		t.Errorf("Expected notify 'slack:#dev', got '%s'", meta.Notify)
+	}
+	if meta.Approve != "deploy to staging" {
+		t.Errorf("Expected approve 'deploy to staging', got '%s'", meta.Approve)
+	}
+	if !meta.Batch {
+		t.Error("Expected batch to be set")
+	}
 
 	// Test Case 2: No Frontmatter
 	simpleContent := `Just do this.`
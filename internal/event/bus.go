@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/activebook/gllm/data"
 )
@@ -20,6 +21,10 @@ type Bus struct {
 
 	// Thread-safe flag to track if indicator is currently active
 	indicatorActive atomic.Bool
+
+	progressMu sync.Mutex
+	progress   Progress
+	lastToken  time.Time
 }
 
 var (
@@ -69,11 +74,92 @@ func IsIndicatorActive() bool {
 	return GetBus().indicatorActive.Load()
 }
 
+// progressStreamGap is the longest pause between two chunks that still counts as streaming.
+const progressStreamGap = 2 * time.Second
+
+// StartProgress starts the clock of the status line for a new generation.
+func StartProgress() {
+	b := GetBus()
+	b.progressMu.Lock()
+	defer b.progressMu.Unlock()
+	b.progress = Progress{Started: time.Now()}
+	b.lastToken = time.Time{}
+}
+
+// StopProgress clears the status line once the generation is over.
+func StopProgress() {
+	b := GetBus()
+	b.progressMu.Lock()
+	defer b.progressMu.Unlock()
+	b.progress = Progress{}
+}
+
+// AddProgressTokens counts streamed tokens.
+func AddProgressTokens(n int) {
+	b := GetBus()
+	b.progressMu.Lock()
+	defer b.progressMu.Unlock()
+	if b.progress.Started.IsZero() {
+		return
+	}
+	now := time.Now()
+	if gap := now.Sub(b.lastToken); gap < progressStreamGap {
+		b.progress.Streaming += gap
+	}
+	b.lastToken = now
+	b.progress.Tokens += n
+}
+
+// SetProgressTool sets the tool being executed, "" once it is done. The tool call is complete,
+// so its preview is cleared.
+func SetProgressTool(name string) {
+	b := GetBus()
+	b.progressMu.Lock()
+	defer b.progressMu.Unlock()
+	b.progress.Tool = name
+	b.progress.Preview = ""
+}
+
+// SetProgressPreview sets the preview of the tool call the model is writing, "" clears it.
+func SetProgressPreview(text string) {
+	b := GetBus()
+	b.progressMu.Lock()
+	defer b.progressMu.Unlock()
+	b.progress.Preview = text
+}
+
+// SetProgressActivity sets what the run is busy with before the model answers, "" clears it.
+func SetProgressActivity(text string) {
+	b := GetBus()
+	b.progressMu.Lock()
+	defer b.progressMu.Unlock()
+	b.progress.Activity = text
+}
+
+// GetProgress returns the progress of the running generation.
+func GetProgress() Progress {
+	b := GetBus()
+	b.progressMu.Lock()
+	defer b.progressMu.Unlock()
+	return b.progress
+}
+
+// PauseIndicator stops the indicator while something else is written to the terminal, the
+// returned function starts it again.
+func PauseIndicator() func() {
+	if !IsIndicatorActive() {
+		return func() {}
+	}
+	StopIndicator()
+	return func() { StartIndicator("") }
+}
+
 // --- Request-response helpers ---
 
 // RequestConfirm sends a ConfirmRequest to the UI and blocks until the user responds.
 // The ToolsUse.Confirm field will be set by the subscriber before unblocking.
 func RequestConfirm(description string, toolsUse *data.ToolsUse) {
+	defer PauseIndicator()()
 	done := make(chan struct{})
 	GetBus().Confirm <- ConfirmRequest{Description: description, ToolsUse: toolsUse, Done: done}
 	<-done
@@ -88,6 +174,7 @@ func RequestDiff(before, after string, contextLines int) string {
 
 // RequestAskUser sends an AskUserRequest to the UI and returns the response.
 func RequestAskUser(req AskUserRequest) (AskUserResponse, error) {
+	defer PauseIndicator()()
 	respCh := make(chan AskUserResponse, 1)
 	req.Response = respCh
 	GetBus().AskUser <- req
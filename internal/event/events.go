@@ -1,6 +1,8 @@
 package event
 
 import (
+	"time"
+
 	"github.com/activebook/gllm/data"
 )
 
@@ -27,6 +29,16 @@ const (
 	IndicatorStop
 )
 
+// Progress is what the status line of the indicator tells about the running generation.
+type Progress struct {
+	Started   time.Time     // Zero when nothing runs
+	Tokens    int           // Streamed tokens, estimated
+	Streaming time.Duration // Time spent streaming them
+	Tool      string        // Tool being executed
+	Preview   string        // Tool call the model is still writing, e.g. "write_file main.go · 120 lines"
+	Activity  string        // Work before the model answers, e.g. "uploading talk.mp4 40%"
+}
+
 type SessionModeEvent struct {
 	Mode int // 0=Normal, 1=Plan, 2=Yolo
 }
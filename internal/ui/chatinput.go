@@ -81,6 +81,12 @@ type PasteResultMsg struct {
 	PastedText        string // non-empty: insert into the textarea (text fallback)
 }
 
+// QueuedInputMsg tells the UI that input arrived from elsewhere, such as the control socket.
+// An empty input gives way to it, otherwise the UI shows that it waits.
+type QueuedInputMsg struct {
+	Pending int // Number of queued inputs
+}
+
 // SendSyncEvent dispatches a message to the active chat input program synchronously.
 // This is used for messages that need to be processed immediately.
 func SendSyncEvent(msg tea.Msg) {
@@ -114,6 +120,7 @@ func SendEvent(msg tea.Msg) {
 type ChatInputResult struct {
 	Value    string
 	Canceled bool
+	Yielded  bool // Nothing was typed, queued input should run instead
 	History  []string
 }
 
@@ -135,6 +142,7 @@ type ChatInputModel struct {
 	height           int          // terminal height
 	canceled         bool         // whether the input was canceled
 	submitted        bool         // whether the input was submitted
+	yielded          bool         // whether the input gave way to queued input
 	suggestionType   int          // type of suggestion
 	suggestionStart  int          // start index of the suggestion(cursor position)
 	history          []string     // input history
@@ -399,6 +407,14 @@ func (m ChatInputModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case QueuedInputMsg:
+		if strings.TrimSpace(m.textarea.Value()) == "" {
+			m.yielded = true
+			return m, tea.Quit
+		}
+		m.pendingBanner = fmt.Sprintf("📨 %d queued input(s) run after yours", msg.Pending)
+		return m, nil
+
 	case PasteResultMsg:
 		// Orchestrator resolved the paste: apply the result to the UI only.
 		m.processingBanner = ""
@@ -800,6 +816,9 @@ func RunChatInput(commands []Suggestion, initialValue string, history []string,
 	if m.canceled {
 		return ChatInputResult{Canceled: true}, nil
 	}
+	if m.yielded {
+		return ChatInputResult{Yielded: true, History: m.history}, nil
+	}
 
 	// Update history
 	text := strings.TrimSpace(m.textarea.Value())
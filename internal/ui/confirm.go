@@ -88,7 +88,8 @@ func RunFormClearable(form *huh.Form, ctx context.Context) error {
 
 // NeedUserConfirmToolUse prompts the user for tool execution confirmation.
 // If toolsUse.AutoApprove is true, it returns ToolConfirmYes immediately.
-// Otherwise, it displays a selection menu for the user to choose "once", "session", or "cancel".
+// Otherwise, it displays a selection menu for the user to allow the call once, the tool or the
+// command prefix for this session, all tools for this session, or to decline with or without feedback.
 func NeedUserConfirmToolUse(info string, prompt string, description string, toolsUse *data.ToolsUse) {
 	// Output the info message if provided
 	if len(strings.TrimSpace(info)) > 0 {
@@ -99,6 +100,8 @@ func NeedUserConfirmToolUse(info string, prompt string, description string, tool
 		toolsUse.Confirm = data.ToolConfirmYes
 		return
 	}
+	// The pages of the diffs shown for this confirmation are no use afterwards
+	defer clearPendingDiffs()
 
 	var fields []huh.Field
 
@@ -110,15 +113,16 @@ func NeedUserConfirmToolUse(info string, prompt string, description string, tool
 		fields = append(fields, GetStaticHuhNoteFull("", description))
 	}
 
+	options := confirmToolUseOptions(toolsUse)
+	more := nextPendingDiff()
+	if more != nil {
+		options = append(options, huh.NewOption(fmt.Sprintf("Show more of the diff (%d lines left)", more.RemainingLines()), "More"))
+	}
+
 	var choice string
 	confirmField := huh.NewSelect[string]().
 		Title(prompt).
-		Options(
-			huh.NewOption("Yes, allow once", "Yes"),
-			huh.NewOption("Yes, allow for this session", "All"),
-			// huh.NewOption("Yes, allow always", "Always"),
-			huh.NewOption("No, suggest changes", "No"),
-		).
+		Options(options...).
 		Value(&choice)
 
 	// If description is not too long and not empty, use the built-in Description
@@ -169,6 +173,23 @@ func NeedUserConfirmToolUse(info string, prompt string, description string, tool
 	}
 
 	switch choice {
+	case "More":
+		fmt.Print(more.Next(DiffPageLines))
+		if more.RemainingLines() > 0 {
+			fmt.Print(more.moreNote())
+		}
+		NeedUserConfirmToolUse("", prompt, description, toolsUse)
+	case "Tool":
+		toolsUse.ConfirmTool()
+	case "Prefix":
+		toolsUse.ConfirmCommandPrefix(data.CommandPrefix(toolsUse.Command))
+	case "Feedback":
+		feedback, err := askDenyFeedback()
+		if err != nil || feedback == "" {
+			toolsUse.ConfirmCancel()
+		} else {
+			toolsUse.ConfirmDeny(feedback)
+		}
 	case "All":
 		toolsUse.ConfirmAlways()
 		// If user choose "All", it means user want to use tools without confirmation in this session
@@ -184,3 +205,36 @@ func NeedUserConfirmToolUse(info string, prompt string, description string, tool
 		toolsUse.ConfirmCancel()
 	}
 }
+
+// confirmToolUseOptions lists the choices of a tool confirmation. The tool and command prefix
+// grants are offered only when the call names them.
+func confirmToolUseOptions(toolsUse *data.ToolsUse) []huh.Option[string] {
+	options := []huh.Option[string]{huh.NewOption("Yes, allow once", "Yes")}
+	if toolsUse.Tool != "" {
+		options = append(options, huh.NewOption(fmt.Sprintf("Yes, allow %s for this session", toolsUse.Tool), "Tool"))
+	}
+	if prefix := data.CommandPrefix(toolsUse.Command); prefix != "" {
+		options = append(options, huh.NewOption(fmt.Sprintf("Yes, allow commands starting with '%s' for this session", prefix), "Prefix"))
+	}
+	return append(options,
+		huh.NewOption("Yes, allow all tools for this session", "All"),
+		// huh.NewOption("Yes, allow always", "Always"),
+		huh.NewOption("No, suggest changes", "No"),
+		huh.NewOption("No, and tell the model why", "Feedback"),
+	)
+}
+
+// askDenyFeedback asks why a tool call was declined, the answer is sent to the model.
+func askDenyFeedback() (string, error) {
+	var feedback string
+	form := huh.NewForm(huh.NewGroup(
+		huh.NewText().
+			Title("Tell the model why").
+			Description("Sent as the tool result, the model goes on with it").
+			Value(&feedback),
+	))
+	if err := RunFormClearable(form, context.Background()); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(feedback), nil
+}
@@ -65,7 +65,10 @@ func StartUIEventListener() {
 
 	go func() {
 		for req := range bus.Diff {
-			result := Diff(req.Before, req.After, req.File1, req.File2, req.ContextLines)
+			result, rest := DiffForConfirm(req.Before, req.After, req.ContextLines)
+			if rest != nil {
+				addPendingDiff(rest)
+			}
 			req.Response <- result
 		}
 	}()
@@ -1,107 +1,322 @@
 package ui
 
 import (
+	"bufio"
 	"fmt"
-	"strconv"
+	"os"
 	"strings"
+	"sync"
 
 	"github.com/activebook/gllm/data"
 	"github.com/activebook/gllm/io"
 	"github.com/pmezard/go-difflib/difflib"
 )
 
+// DiffPageLines is how many lines of a diff are shown for a confirmation at once, the rest is
+// paged from the confirmation and saved to a file.
+const DiffPageLines = 400
+
+// Diff renders the whole colored diff of two contents, "" when they are the same.
 func Diff(content1, content2, file1, file2 string, contextLines int) string {
-	// Colors
-	var red, green, cyan, dim func(string) string
+	p := NewDiffPager(content1, content2, file1, file2, contextLines)
+	return p.Next(p.RemainingLines())
+}
+
+// DiffForConfirm renders the first page of a diff shown for a confirmation. When there is more,
+// the full diff is saved to a temporary file, and the pager of the rest is returned, nil otherwise.
+func DiffForConfirm(content1, content2 string, contextLines int) (string, *DiffPager) {
+	p := NewDiffPager(content1, content2, "", "", contextLines)
+	text := p.Next(DiffPageLines)
+	if p.RemainingLines() == 0 {
+		return text, nil
+	}
+	if path, err := p.SaveFull(); err == nil {
+		p.fullPath = path
+	}
+	return text + p.moreNote(), p
+}
+
+// pendingDiffs are the diffs shown for the coming confirmation that have pages left.
+var pendingDiffs struct {
+	sync.Mutex
+	pagers []*DiffPager
+}
 
-	red = func(s string) string { return data.DiffRemovedBgColor + data.DiffRemovedColor + s + data.ResetSeq }
-	green = func(s string) string { return data.DiffAddedBgColor + data.DiffAddedColor + s + data.ResetSeq }
-	cyan = func(s string) string { return data.DiffHeaderColor + s + data.ResetSeq }
-	dim = func(s string) string { return data.DiffSeparatorColor + s + data.ResetSeq }
+// addPendingDiff lets the coming confirmation page through the rest of a diff.
+func addPendingDiff(p *DiffPager) {
+	pendingDiffs.Lock()
+	defer pendingDiffs.Unlock()
+	pendingDiffs.pagers = append(pendingDiffs.pagers, p)
+}
 
-	diff := difflib.UnifiedDiff{
-		A:        difflib.SplitLines(content1),
-		B:        difflib.SplitLines(content2),
-		FromFile: file1,
-		ToFile:   file2,
-		Context:  contextLines,
+// nextPendingDiff returns the first pending diff with pages left, nil when there is none.
+func nextPendingDiff() *DiffPager {
+	pendingDiffs.Lock()
+	defer pendingDiffs.Unlock()
+	for _, p := range pendingDiffs.pagers {
+		if p.RemainingLines() > 0 {
+			return p
+		}
 	}
+	return nil
+}
+
+// clearPendingDiffs forgets the diffs once their confirmation is answered.
+func clearPendingDiffs() {
+	pendingDiffs.Lock()
+	defer pendingDiffs.Unlock()
+	pendingDiffs.pagers = nil
+}
+
+// DiffPager renders a diff a page at a time. The hunks are located up front, as line ranges
+// only, and their lines are rendered when a page needs them, so a diff of a large file does
+// not hold its whole rendering in memory.
+type DiffPager struct {
+	a, b         []string
+	file1, file2 string
+	groups       [][]difflib.OpCode
+	group, row   int // Next row to render, row 0 is the separator of the hunk
+	started      bool
+	fullPath     string // File with the full diff, when it was saved
+}
 
-	// Get the diff as a string
-	diffText, _ := difflib.GetUnifiedDiffString(diff)
+// NewDiffPager locates the hunks of the diff of two contents.
+func NewDiffPager(content1, content2, file1, file2 string, contextLines int) *DiffPager {
+	p := &DiffPager{a: splitDiffLines(content1), b: splitDiffLines(content2), file1: file1, file2: file2}
+	p.groups = groupOpCodes(diffOpCodes(p.a, p.b), contextLines)
+	return p
+}
 
-	// Parse and display the diff with line numbers
-	lines := strings.Split(diffText, "\n")
+// RemainingLines returns how many lines are left to render.
+func (p *DiffPager) RemainingLines() int {
+	n := 0
+	for g := p.group; g < len(p.groups); g++ {
+		n += groupRows(p.groups[g])
+	}
+	return n - p.row
+}
 
-	// Variables to track line numbers
-	lineNum1 := 0 // Line number for file 1
-	lineNum2 := 0 // Line number for file 2
+// remainingHunks returns how many hunks are not fully rendered yet.
+func (p *DiffPager) remainingHunks() int {
+	return len(p.groups) - p.group
+}
 
+// Next renders up to n more lines of the diff.
+func (p *DiffPager) Next(n int) string {
 	var output strings.Builder
+	if !p.started && len(p.groups) > 0 {
+		p.started = true
+		if p.file1 != "" || p.file2 != "" {
+			output.WriteString(data.DiffHeaderColor + "--- " + p.file1 + data.ResetSeq + "\n")
+			output.WriteString(data.DiffHeaderColor + "+++ " + p.file2 + data.ResetSeq + "\n")
+		}
+	}
+	for ; n > 0 && p.group < len(p.groups); n-- {
+		group := p.groups[p.group]
+		output.WriteString(p.renderRow(group, p.row))
+		p.row++
+		if p.row >= groupRows(group) {
+			p.group++
+			p.row = 0
+		}
+	}
+	return output.String()
+}
 
-	for _, line := range lines {
-		switch {
-		case strings.HasPrefix(line, "---"), strings.HasPrefix(line, "+++"):
-			// File headers
-			output.WriteString(cyan(line) + "\n")
-		case strings.HasPrefix(line, "@@"):
-			// Hunk header - use a simple separator instead of complex line numbers
-			separator := strings.Repeat("═", (io.GetTerminalWidth()*3)/4)
-			output.WriteString(dim(separator + "\n"))
-
-			// Parse the hunk header to extract starting line numbers for both files
-			lineNum1, lineNum2 = parseHunkHeader(line, lineNum1, lineNum2)
-		case strings.HasPrefix(line, "-"):
-			// Removed line from file 1
-			lineNum1++
-			lineNumStr := fmt.Sprintf("%-6d", lineNum1)
-			output.WriteString(red(lineNumStr) + red(line) + "\n")
-		case strings.HasPrefix(line, "+"):
-			// Added line to file 2
-			lineNum2++
-			lineNumStr := fmt.Sprintf("%-6d", lineNum2)
-			output.WriteString(green(lineNumStr) + green(line) + "\n")
-		case strings.HasPrefix(line, " "):
-			// Unchanged line
-			lineNum1++
-			lineNum2++
-			lineNumStr1 := fmt.Sprintf("%-6d", lineNum1)
-			lineNumStr2 := fmt.Sprintf("%-6d", lineNum2)
-			// Show both line numbers for context lines
-			output.WriteString(dim(lineNumStr1) + dim(lineNumStr2) + line + "\n")
-		case line == "":
-			// Empty line at the end
+// moreNote tells what is left of the diff and where the full diff is.
+func (p *DiffPager) moreNote() string {
+	hunks := "1 more hunk"
+	if h := p.remainingHunks(); h != 1 {
+		hunks = fmt.Sprintf("%d more hunks", h)
+	}
+	note := fmt.Sprintf("… %s, %d lines", hunks, p.RemainingLines())
+	if p.fullPath != "" {
+		note += ", full diff in " + p.fullPath
+	}
+	return data.DiffSeparatorColor + note + data.ResetSeq + "\n"
+}
+
+// renderRow renders a row of a hunk: the separator, then its lines with their line numbers.
+func (p *DiffPager) renderRow(group []difflib.OpCode, row int) string {
+	red := func(s string) string { return data.DiffRemovedBgColor + data.DiffRemovedColor + s + data.ResetSeq }
+	green := func(s string) string { return data.DiffAddedBgColor + data.DiffAddedColor + s + data.ResetSeq }
+	dim := func(s string) string { return data.DiffSeparatorColor + s + data.ResetSeq }
+
+	if row == 0 {
+		return dim(strings.Repeat("═", (io.GetTerminalWidth()*3)/4) + "\n")
+	}
+	row--
+	for _, c := range group {
+		if c.Tag == 'e' {
+			if row < c.I2-c.I1 {
+				i, j := c.I1+row, c.J1+row
+				return dim(fmt.Sprintf("%-6d", i+1)) + dim(fmt.Sprintf("%-6d", j+1)) + " " + strings.TrimSuffix(p.a[i], "\n") + "\n"
+			}
+			row -= c.I2 - c.I1
 			continue
 		}
+		if c.Tag == 'r' || c.Tag == 'd' {
+			if row < c.I2-c.I1 {
+				i := c.I1 + row
+				return red(fmt.Sprintf("%-6d", i+1)) + red("-"+strings.TrimSuffix(p.a[i], "\n")) + "\n"
+			}
+			row -= c.I2 - c.I1
+		}
+		if c.Tag == 'r' || c.Tag == 'i' {
+			if row < c.J2-c.J1 {
+				j := c.J1 + row
+				return green(fmt.Sprintf("%-6d", j+1)) + green("+"+strings.TrimSuffix(p.b[j], "\n")) + "\n"
+			}
+			row -= c.J2 - c.J1
+		}
+	}
+	return ""
+}
+
+// SaveFull writes the full unified diff, without colors, to a temporary file and returns its path.
+func (p *DiffPager) SaveFull() (string, error) {
+	f, err := os.CreateTemp("", "gllm-diff-*.diff")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+	if p.file1 != "" || p.file2 != "" {
+		fmt.Fprintf(w, "--- %s\n+++ %s\n", p.file1, p.file2)
+	}
+	for _, group := range p.groups {
+		first, last := group[0], group[len(group)-1]
+		fmt.Fprintf(w, "@@ -%s +%s @@\n", formatDiffRange(first.I1, last.I2), formatDiffRange(first.J1, last.J2))
+		for _, c := range group {
+			if c.Tag == 'e' {
+				writeDiffLines(w, " ", p.a[c.I1:c.I2])
+				continue
+			}
+			if c.Tag == 'r' || c.Tag == 'd' {
+				writeDiffLines(w, "-", p.a[c.I1:c.I2])
+			}
+			if c.Tag == 'r' || c.Tag == 'i' {
+				writeDiffLines(w, "+", p.b[c.J1:c.J2])
+			}
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func writeDiffLines(w *bufio.Writer, prefix string, lines []string) {
+	for _, line := range lines {
+		w.WriteString(prefix)
+		w.WriteString(line)
+		if !strings.HasSuffix(line, "\n") {
+			w.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// formatDiffRange formats the range of a hunk header as unified diffs do.
+func formatDiffRange(start, stop int) string {
+	beginning, length := start+1, stop-start
+	if length == 1 {
+		return fmt.Sprintf("%d", beginning)
 	}
-	return (output.String())
+	if length == 0 {
+		beginning--
+	}
+	return fmt.Sprintf("%d,%d", beginning, length)
+}
+
+// splitDiffLines splits a content into lines that keep their newline, sharing its memory.
+func splitDiffLines(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
 }
 
-// parseHunkHeader parses the hunk header line to extract starting line numbers
-// Format: @@ -line1,count1 +line2,count2 @@
-// Returns the updated line numbers for file1 and file2
-func parseHunkHeader(line string, currentLineNum1, currentLineNum2 int) (int, int) {
-	// Trim the "@@" and split by spaces
-	parts := strings.Split(strings.Trim(line, "@ "), " ")
-	if len(parts) < 2 {
-		return currentLineNum1, currentLineNum2
+// diffOpCodes compares two contents by lines. The lines they start and end with are left out
+// of the matcher, which is slow on large inputs, so editing a large file compares only the
+// part that changed.
+func diffOpCodes(a, b []string) []difflib.OpCode {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
 	}
 
-	// Parse file1 line number (format: -line,count)
-	file1Part := strings.Split(strings.TrimPrefix(parts[0], "-"), ",")
-	if len(file1Part) > 0 {
-		if num, err := strconv.Atoi(file1Part[0]); err == nil {
-			currentLineNum1 = num - 1 // Adjust for 0-based indexing
+	var codes []difflib.OpCode
+	if prefix > 0 {
+		codes = append(codes, difflib.OpCode{Tag: 'e', I1: 0, I2: prefix, J1: 0, J2: prefix})
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	switch {
+	case len(midA) == 0 && len(midB) == 0:
+	case len(midA) == 0:
+		codes = append(codes, difflib.OpCode{Tag: 'i', I1: prefix, I2: prefix, J1: prefix, J2: prefix + len(midB)})
+	case len(midB) == 0:
+		codes = append(codes, difflib.OpCode{Tag: 'd', I1: prefix, I2: prefix + len(midA), J1: prefix, J2: prefix})
+	default:
+		for _, c := range difflib.NewMatcher(midA, midB).GetOpCodes() {
+			codes = append(codes, difflib.OpCode{Tag: c.Tag, I1: c.I1 + prefix, I2: c.I2 + prefix, J1: c.J1 + prefix, J2: c.J2 + prefix})
 		}
 	}
+	if suffix > 0 {
+		codes = append(codes, difflib.OpCode{Tag: 'e', I1: len(a) - suffix, I2: len(a), J1: len(b) - suffix, J2: len(b)})
+	}
+	return codes
+}
 
-	// Parse file2 line number (format: +line,count)
-	file2Part := strings.Split(strings.TrimPrefix(parts[1], "+"), ",")
-	if len(file2Part) > 0 {
-		if num, err := strconv.Atoi(file2Part[0]); err == nil {
-			currentLineNum2 = num - 1 // Adjust for 0-based indexing
+// groupOpCodes groups the changes into hunks with n lines of context, as difflib does.
+func groupOpCodes(codes []difflib.OpCode, n int) [][]difflib.OpCode {
+	if n < 0 {
+		n = 3
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	if c := codes[0]; c.Tag == 'e' {
+		codes[0] = difflib.OpCode{Tag: 'e', I1: max(c.I1, c.I2-n), I2: c.I2, J1: max(c.J1, c.J2-n), J2: c.J2}
+	}
+	if c := codes[len(codes)-1]; c.Tag == 'e' {
+		codes[len(codes)-1] = difflib.OpCode{Tag: 'e', I1: c.I1, I2: min(c.I2, c.I1+n), J1: c.J1, J2: min(c.J2, c.J1+n)}
+	}
+	var groups [][]difflib.OpCode
+	var group []difflib.OpCode
+	for _, c := range codes {
+		i1, i2, j1, j2 := c.I1, c.I2, c.J1, c.J2
+		// A long range without changes ends the hunk
+		if c.Tag == 'e' && i2-i1 > 2*n {
+			group = append(group, difflib.OpCode{Tag: 'e', I1: i1, I2: min(i2, i1+n), J1: j1, J2: min(j2, j1+n)})
+			groups = append(groups, group)
+			group = nil
+			i1, j1 = max(i1, i2-n), max(j1, j2-n)
 		}
+		group = append(group, difflib.OpCode{Tag: c.Tag, I1: i1, I2: i2, J1: j1, J2: j2})
 	}
+	if len(group) > 0 && !(len(group) == 1 && group[0].Tag == 'e') {
+		groups = append(groups, group)
+	}
+	return groups
+}
 
-	return currentLineNum1, currentLineNum2
+// groupRows returns how many rows a hunk renders: its separator and its lines.
+func groupRows(group []difflib.OpCode) int {
+	rows := 1
+	for _, c := range group {
+		switch c.Tag {
+		case 'e', 'd':
+			rows += c.I2 - c.I1
+		case 'i':
+			rows += c.J2 - c.J1
+		case 'r':
+			rows += c.I2 - c.I1 + c.J2 - c.J1
+		}
+	}
+	return rows
 }
@@ -4,12 +4,16 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/briandowns/spinner"
+	"github.com/charmbracelet/x/ansi"
 
 	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/internal/event"
+	"github.com/activebook/gllm/io"
 	"github.com/activebook/gllm/util"
 )
 
@@ -38,6 +42,9 @@ const (
 	IndicatorCheckingUpdate     = "Checking for updates..."
 	IndicatorInstallingUpdate   = "Downloading and installing..."
 	IndicatorGenInstruction     = "Generating GLLM.md ..."
+	IndicatorListingModels      = "Fetching models from provider..."
+	IndicatorSyncingTeamConfig  = "Syncing team config..."
+	IndicatorProbingProviders   = "Probing providers..."
 )
 
 // WhimsicalProcessingWords is a collection of fun, playful processing indicators
@@ -115,6 +122,7 @@ type Indicator struct {
 	rotating     bool
 	lastRotation time.Time
 	lastWord     string
+	label        string // Text shown before the status line
 }
 
 var (
@@ -145,7 +153,7 @@ func (i *Indicator) setupSpinner() {
 	i.s.Enable()
 	i.s.Stop()
 
-	// Setup the pre-update hook for word rotation
+	// Setup the pre-update hook for word rotation and the status line
 	i.s.PreUpdate = func(s *spinner.Spinner) {
 		i.stateMu.Lock()
 		defer i.stateMu.Unlock()
@@ -155,11 +163,12 @@ func (i *Indicator) setupSpinner() {
 				for newWord == i.lastWord && len(WhimsicalProcessingWords) > 1 {
 					newWord = GetRandomProcessingWord()
 				}
-				s.Suffix = fmt.Sprintf(" %s", newWord)
+				i.label = newWord
 				i.lastWord = newWord
 				i.lastRotation = time.Now()
 			}
 		}
+		s.Suffix = fitIndicatorLine(fmt.Sprintf(" %s%s", i.label, FormatProgress(event.GetProgress(), time.Now())))
 	}
 }
 
@@ -179,6 +188,11 @@ func (i *Indicator) Stop() {
 }
 
 func (i *Indicator) Start(text string) {
+	// No spinner on output meant for piping
+	if data.PlainOutput() {
+		return
+	}
+
 	i.mu.Lock()
 	defer i.mu.Unlock()
 
@@ -200,10 +214,11 @@ func (i *Indicator) Start(text string) {
 	} else {
 		i.rotating = false
 	}
+	i.label = text
 	i.stateMu.Unlock()
 
 	// Set the suffix BEFORE starting
-	i.s.Suffix = fmt.Sprintf(" %s", text)
+	i.s.Suffix = fitIndicatorLine(fmt.Sprintf(" %s%s", text, FormatProgress(event.GetProgress(), time.Now())))
 
 	// Start Indicator
 	// fmt.Println("Start Indicator")
@@ -214,6 +229,39 @@ func (i *Indicator) Start(text string) {
 	time.Sleep(1 * time.Millisecond)
 }
 
+// FormatProgress formats the status line of a running generation, e.g.
+// " (12s · 1.2k tokens · 35 tok/s · running read_file · ctrl+c to cancel)", or "" when none runs.
+func FormatProgress(p event.Progress, now time.Time) string {
+	if p.Started.IsZero() {
+		return ""
+	}
+	parts := []string{now.Sub(p.Started).Round(time.Second).String()}
+	if p.Tokens > 0 {
+		tokens := fmt.Sprintf("%d tokens", p.Tokens)
+		if p.Tokens >= 1000 {
+			tokens = fmt.Sprintf("%.1fk tokens", float64(p.Tokens)/1000)
+		}
+		parts = append(parts, tokens)
+		if p.Streaming >= time.Second {
+			parts = append(parts, fmt.Sprintf("%.0f tok/s", float64(p.Tokens)/p.Streaming.Seconds()))
+		}
+	}
+	if p.Tool != "" {
+		parts = append(parts, "running "+p.Tool)
+	} else if p.Preview != "" {
+		parts = append(parts, "writing "+p.Preview)
+	} else if p.Activity != "" {
+		parts = append(parts, p.Activity)
+	}
+	parts = append(parts, "ctrl+c to cancel")
+	return " (" + strings.Join(parts, " · ") + ")"
+}
+
+// fitIndicatorLine keeps the spinner on one line, a wrapped one could not be erased.
+func fitIndicatorLine(suffix string) string {
+	return ansi.Truncate(suffix, io.GetTerminalWidth()-3, "…")
+}
+
 // LoggerHook implementation to satisfy util.LoggerHook interface
 func (i *Indicator) BeforeLog() bool {
 	active := i.IsActive()
@@ -103,6 +103,16 @@ func (s *SSEOutput) WriteStatusEvent(status string) {
 	})
 }
 
+// WritePromptEvent emits the prompt a run answers, so that every client of a shared
+// session sees what was asked.
+//
+//	data: {"type":"prompt","data":{"content":"..."}}
+func (s *SSEOutput) WritePromptEvent(prompt string) {
+	s.writeSSEEvent("prompt", map[string]interface{}{
+		"content": prompt,
+	})
+}
+
 // WriteToolCallEvent emits a tool invocation event. The content is a structured
 // object containing the function name and its description.
 // it shouldn't expose the arguments of the function to the web client
@@ -0,0 +1,263 @@
+// Package gllm embeds the agent engine of gllm in other Go programs. A Client runs the agents,
+// models, tools and MCP servers of the gllm config, like the command line does, and streams
+// what happens during a run as events:
+//
+//	client := gllm.NewClient()
+//	events, err := client.Stream(ctx, gllm.Request{Prompt: "What changed in the last commit?", Agent: "coder"})
+//	if err != nil {
+//		return err
+//	}
+//	for e := range events {
+//		switch e.Type {
+//		case gllm.EventText:
+//			fmt.Print(e.Text)
+//		case gllm.EventToolCall:
+//			fmt.Printf("\n[%s %v]\n", e.Tool, e.Args)
+//		case gllm.EventError:
+//			return e.Err
+//		}
+//	}
+package gllm
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Request is a prompt to run with an agent.
+type Request struct {
+	Prompt  string
+	Agent   string   // Agent of the config, empty for the active agent
+	Model   string   // Model of the config in place of the agent's, empty for the agent's
+	System  string   // System prompt in place of the agent's, empty for the agent's
+	Session string   // Session the conversation is continued in and saved to, empty for none
+	Files   []string // Paths of the files attached to the prompt
+	Tools   []string // Tools in place of the agent's, nil for the agent's, empty for none
+
+	// Yolo runs the tool calls that need a confirmation without asking.
+	Yolo bool
+	// Approve decides the tool calls that need a confirmation, from their description.
+	// Without it they are denied, which cancels the run, unless Yolo is set.
+	Approve func(ctx context.Context, description string) bool
+}
+
+// Client runs requests with the gllm config of the user.
+type Client struct {
+	store *data.ConfigStore
+}
+
+// NewClient returns a client of the gllm config.
+func NewClient() *Client {
+	return &Client{store: data.NewConfigStore()}
+}
+
+// Stream starts a run and returns its events. The channel is closed after the last event,
+// EventDone or EventError, and must be read until then. Cancelling the context stops the run.
+func (c *Client) Stream(ctx context.Context, req Request) (<-chan Event, error) {
+	if strings.TrimSpace(req.Prompt) == "" {
+		return nil, fmt.Errorf("the prompt is empty")
+	}
+	agent, err := c.agent(req.Agent, req.Model)
+	if err != nil {
+		return nil, err
+	}
+	var files []*service.FileData
+	for _, path := range req.Files {
+		file, err := loadAttachment(path)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	mcpConfig, err := data.NewMCPStore().Load()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan Event, 64)
+	go func() {
+		defer close(events)
+		usage, err := c.run(ctx, req, agent, files, mcpConfig, events)
+		if err != nil {
+			events <- Event{Type: EventError, Err: err, Usage: usage}
+			return
+		}
+		events <- Event{Type: EventDone, Usage: usage}
+	}()
+	return events, nil
+}
+
+// Run runs a request to the end and returns the text of the answer.
+func (c *Client) Run(ctx context.Context, req Request) (string, error) {
+	events, err := c.Stream(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	var runErr error
+	for e := range events {
+		switch e.Type {
+		case EventText:
+			sb.WriteString(e.Text)
+		case EventError:
+			runErr = e.Err
+		}
+	}
+	return sb.String(), runErr
+}
+
+// agent returns the agent of the name, or the active one, with the model of the name if set.
+func (c *Client) agent(name, model string) (*data.AgentConfig, error) {
+	var agent *data.AgentConfig
+	if name != "" {
+		if agent = c.store.GetAgent(name); agent == nil {
+			return nil, fmt.Errorf("agent %s does not exist", name)
+		}
+	} else if agent = c.store.GetActiveAgent(); agent == nil {
+		return nil, fmt.Errorf("no active agent found")
+	}
+	if model != "" {
+		m := c.store.GetModel(model)
+		if m == nil {
+			return nil, fmt.Errorf("model %s not found", model)
+		}
+		agent.Model = *m
+	}
+	if agent.Model.Name == "" && agent.Model.Model == "" {
+		return nil, fmt.Errorf("agent %s has no model", agent.Name)
+	}
+	if agent.Model.Provider == "" {
+		agent.Model.Provider = service.DetectModelProvider(agent.Model.Endpoint, agent.Model.Model)
+	}
+	return agent, nil
+}
+
+// run runs the request, following the handoffs to other agents, and returns the usage of
+// all the agents.
+func (c *Client) run(ctx context.Context, req Request, agent *data.AgentConfig, files []*service.FileData, mcpConfig map[string]*data.MCPServer, events chan<- Event) (*Usage, error) {
+	state := data.NewSharedState()
+	defer state.Clear()
+
+	observer := &streamObserver{ctx: ctx, events: events}
+	usage := &Usage{}
+	prompt := req.Prompt
+	for {
+		hook := service.SessionConvertHook{OnStartConvert: func() {}, OnFinishedConvert: func() {}}
+		if err := service.EnsureSessionCompatibility(agent, req.Session, hook); err != nil {
+			return usage, err
+		}
+
+		tools := agent.Tools
+		if req.Tools != nil {
+			tools = req.Tools
+		}
+		system := agent.SystemPrompt
+		if req.System != "" {
+			system = req.System
+		}
+		tu := service.NewTokenUsage()
+		op := service.AgentOptions{
+			Ctx:            ctx,
+			Prompt:         prompt,
+			SysPrompt:      system,
+			Files:          files,
+			ModelInfo:      &agent.Model,
+			MaxRecursions:  agent.MaxRecursions,
+			ThinkingLevel:  agent.Think,
+			EnabledTools:   tools,
+			AutoFormat:     agent.AutoFormat,
+			Shell:          agent.Shell,
+			InjectionGuard: agent.InjectionGuard,
+			Paths:          agent.Paths,
+			Capabilities:   agent.Capabilities,
+			SessionBudget:  agent.BudgetUSD,
+			BestOf:         agent.BestOf,
+			YoloMode:       req.Yolo,
+			QuietMode:      true, // Everything goes through the events
+			SessionName:    req.Session,
+			MCPConfig:      mcpConfig,
+			Interaction:    &interaction{ctx: ctx, approve: req.Approve},
+			TokenUsage:     tu,
+			Observer:       observer,
+			SharedState:    state,
+			AgentName:      agent.Name,
+			ModelName:      agent.Model.Name,
+		}
+		err := service.CallAgent(&op)
+		usage.InputTokens += tu.InputTokens
+		usage.OutputTokens += tu.OutputTokens
+		usage.CachedTokens += tu.CachedTokens
+		usage.TotalTokens += tu.TotalTokens
+		usage.Cost += tu.Cost(&agent.Model)
+
+		// A handoff continues the run with the other agent, the attachments went with the prompt
+		switchErr, ok := service.AsSwitchAgentError(err)
+		if !ok {
+			return usage, err
+		}
+		if agent, err = c.agent(switchErr.TargetAgent, ""); err != nil {
+			return usage, err
+		}
+		observer.send(Event{Type: EventAgentSwitch, Agent: agent.Name})
+		if prompt = switchErr.Prompt(); prompt == "" {
+			return usage, nil
+		}
+		files = nil
+	}
+}
+
+// interaction answers the confirmations with the Approve function of the request and renders
+// diffs as plain unified diffs. No one is there to answer the questions to the user, which fail.
+type interaction struct {
+	service.HeadlessInteractionHandler
+	ctx     context.Context
+	approve func(ctx context.Context, description string) bool
+}
+
+func (i *interaction) RequestConfirm(description string, toolsUse *data.ToolsUse) {
+	if toolsUse.AutoApprove {
+		toolsUse.Confirm = data.ToolConfirmYes
+		return
+	}
+	if i.approve != nil && i.approve(i.ctx, description) {
+		toolsUse.ConfirmOnce()
+		return
+	}
+	toolsUse.ConfirmCancel()
+}
+
+func (i *interaction) RequestDiff(before, after string, contextLines int) string {
+	diff, _ := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(before),
+		B:        difflib.SplitLines(after),
+		FromFile: "before",
+		ToFile:   "after",
+		Context:  contextLines,
+	})
+	return diff
+}
+
+// loadAttachment reads a file attached to the prompt, with its MIME type.
+func loadAttachment(path string) (*service.FileData, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	isImage, format, err := service.CheckIfImageFromBytes(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the type of %s: %w", path, err)
+	}
+	if !isImage {
+		format = service.GetMIMEType(path)
+		if service.IsUnknownMIMEType(format) {
+			format = service.GetMIMETypeByContent(content)
+		}
+	}
+	return service.NewFileData(format, content, path), nil
+}
@@ -0,0 +1,155 @@
+package gllm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/service"
+	"github.com/spf13/viper"
+)
+
+// setupAgent configures an agent named tester on a mock model that plays the turns.
+func setupAgent(t *testing.T, model string, tools []string, turns ...service.MockTurn) {
+	t.Helper()
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("HOME", configDir)
+	t.Cleanup(viper.Reset)
+
+	viper.Reset()
+	viper.Set("models."+model, map[string]interface{}{
+		"provider": service.ModelProviderMock,
+		"model":    model,
+	})
+	if err := data.EnsureAgentsDir(); err != nil {
+		t.Fatal(err)
+	}
+	agentFile := "---\nname: tester\nmodel: " + model + "\nmax_recursions: 5\n"
+	if len(tools) > 0 {
+		agentFile += "tools: [" + strings.Join(tools, ", ") + "]\n"
+	}
+	agentFile += "---\nYou are a tester.\n"
+	if err := os.WriteFile(filepath.Join(data.GetAgentsDirPath(), "tester.md"), []byte(agentFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	service.RegisterMockScript(model, &service.MockScript{Turns: turns})
+}
+
+func collect(t *testing.T, events <-chan Event) []Event {
+	t.Helper()
+	var all []Event
+	for e := range events {
+		all = append(all, e)
+	}
+	if len(all) == 0 {
+		t.Fatal("no events")
+	}
+	return all
+}
+
+func TestStreamToolCall(t *testing.T) {
+	setupAgent(t, "mock-stream", []string{service.ToolReadFile},
+		service.MockTurn{ToolCalls: []service.MockToolCall{{Name: service.ToolReadFile, Args: map[string]interface{}{"path": "notes.txt"}}}},
+		service.MockTurn{Text: "The notes say hello.", InputTokens: 20, OutputTokens: 5},
+	)
+	work := t.TempDir()
+	t.Chdir(work)
+	os.WriteFile(filepath.Join(work, "notes.txt"), []byte("hello"), 0644)
+
+	events, err := NewClient().Stream(context.Background(), Request{Prompt: "Read the notes.", Agent: "tester"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := collect(t, events)
+
+	var text strings.Builder
+	var call, result *Event
+	for i, e := range all {
+		switch e.Type {
+		case EventText:
+			text.WriteString(e.Text)
+		case EventToolCall:
+			call = &all[i]
+		case EventToolResult:
+			result = &all[i]
+		}
+	}
+	if call == nil || call.Tool != service.ToolReadFile || call.Args["path"] != "notes.txt" {
+		t.Errorf("expected a read_file call of notes.txt, got %+v", call)
+	}
+	if result == nil || result.Tool != service.ToolReadFile || !strings.Contains(result.Text, "hello") {
+		t.Errorf("expected the content of notes.txt as the result, got %+v", result)
+	}
+	if text.String() != "The notes say hello." {
+		t.Errorf("unexpected text %q", text.String())
+	}
+	last := all[len(all)-1]
+	if last.Type != EventDone || last.Usage == nil {
+		t.Fatalf("expected the run to end with done, got %+v", last)
+	}
+	if last.Usage.OutputTokens < 5 {
+		t.Errorf("expected the usage of the run, got %+v", last.Usage)
+	}
+}
+
+func TestStreamConfirmation(t *testing.T) {
+	setupAgent(t, "mock-confirm", []string{service.ToolWriteFile},
+		service.MockTurn{ToolCalls: []service.MockToolCall{{Name: service.ToolWriteFile, Args: map[string]interface{}{"path": "out.txt", "content": "data", "purpose": "save the data"}}}},
+		service.MockTurn{ToolCalls: []service.MockToolCall{{Name: service.ToolWriteFile, Args: map[string]interface{}{"path": "out.txt", "content": "data", "purpose": "save the data"}}}},
+		service.MockTurn{Text: "Done."},
+	)
+	work := t.TempDir()
+	t.Chdir(work)
+	client := NewClient()
+
+	// Without an approver the write is denied, which ends the run
+	if _, err := client.Run(context.Background(), Request{Prompt: "Write the file.", Agent: "tester"}); err == nil {
+		t.Error("expected the denied write to cancel the run")
+	}
+	if _, err := os.Stat(filepath.Join(work, "out.txt")); err == nil {
+		t.Fatal("the write ran without approval")
+	}
+
+	asked := ""
+	approve := func(ctx context.Context, description string) bool {
+		asked = description
+		return true
+	}
+	if _, err := client.Run(context.Background(), Request{Prompt: "Write the file.", Agent: "tester", Approve: approve}); err != nil {
+		t.Fatal(err)
+	}
+	if asked == "" {
+		t.Error("the approver was not asked")
+	}
+	if content, err := os.ReadFile(filepath.Join(work, "out.txt")); err != nil || string(content) != "data" {
+		t.Errorf("expected the approved write, got %q, %v", content, err)
+	}
+}
+
+func TestStreamErrors(t *testing.T) {
+	setupAgent(t, "mock-errors", nil, service.MockTurn{Error: "overloaded"})
+	client := NewClient()
+
+	if _, err := client.Stream(context.Background(), Request{Prompt: "Hi", Agent: "nobody"}); err == nil {
+		t.Error("expected an error for an unknown agent")
+	}
+	if _, err := client.Stream(context.Background(), Request{Prompt: "Hi", Agent: "tester", Model: "nothing"}); err == nil {
+		t.Error("expected an error for an unknown model")
+	}
+	if _, err := client.Stream(context.Background(), Request{Prompt: " ", Agent: "tester"}); err == nil {
+		t.Error("expected an error for an empty prompt")
+	}
+
+	events, err := client.Stream(context.Background(), Request{Prompt: "Hi", Agent: "tester"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all := collect(t, events)
+	if last := all[len(all)-1]; last.Type != EventError || last.Err == nil {
+		t.Errorf("expected the run to end with an error, got %+v", last)
+	}
+}
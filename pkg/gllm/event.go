@@ -0,0 +1,107 @@
+package gllm
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/activebook/gllm/service"
+)
+
+// EventType is the kind of an Event.
+type EventType string
+
+const (
+	EventText        EventType = "text"         // Text of the answer, streamed in chunks
+	EventReasoning   EventType = "reasoning"    // Reasoning of a thinking model, streamed in chunks
+	EventToolCall    EventType = "tool_call"    // The agent calls Tool with Args
+	EventToolResult  EventType = "tool_result"  // Result of the last call of Tool, in Text
+	EventDiff        EventType = "diff"         // Diff of a file edit, in Text
+	EventWarning     EventType = "warning"      // Something went wrong but the run goes on
+	EventSubAgent    EventType = "subagent"     // A sub-agent task of Agent changed to Status
+	EventAgentSwitch EventType = "agent_switch" // The run was handed off to Agent
+	EventDone        EventType = "done"         // The run finished, with its Usage
+	EventError       EventType = "error"        // The run failed with Err
+)
+
+// Event is something that happened during a run. The fields set depend on the Type.
+type Event struct {
+	Type   EventType
+	Text   string
+	Tool   string
+	Args   map[string]interface{}
+	Agent  string
+	Status string // Status of a sub-agent task: pending, running, completed, failed or cancelled
+	Usage  *Usage
+	Err    error
+}
+
+// Usage is the token usage of a run, with its estimated cost in USD when the model has prices.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	CachedTokens int
+	TotalTokens  int
+	Cost         float64
+}
+
+// streamObserver turns the stream of an agent run into events.
+type streamObserver struct {
+	ctx    context.Context
+	events chan<- Event
+
+	mu    sync.Mutex
+	calls []string // Tools called and not answered yet
+}
+
+// send delivers an event, it gives up once the run is cancelled so that a consumer that
+// stopped reading doesn't block the agent.
+func (o *streamObserver) send(e Event) {
+	select {
+	case o.events <- e:
+	case <-o.ctx.Done():
+	}
+}
+
+func (o *streamObserver) OnData(d service.StreamData) {
+	switch d.Type {
+	case service.DataTypeNormal:
+		o.send(Event{Type: EventText, Text: d.Text})
+	case service.DataTypeReasoning:
+		o.send(Event{Type: EventReasoning, Text: d.Text})
+	}
+}
+
+func (o *streamObserver) OnNotify(n service.StreamNotify) {
+	switch n.Status {
+	case service.StatusFunctionCalling:
+		var call struct {
+			Function string                 `json:"function"`
+			Args     map[string]interface{} `json:"args"`
+		}
+		if err := json.Unmarshal([]byte(n.Data), &call); err != nil || call.Function == "" {
+			call.Function = n.Data
+		}
+		o.mu.Lock()
+		o.calls = append(o.calls, call.Function)
+		o.mu.Unlock()
+		o.send(Event{Type: EventToolCall, Tool: call.Function, Args: call.Args})
+	case service.StatusFunctionCallingOver:
+		var tool string
+		o.mu.Lock()
+		if len(o.calls) > 0 {
+			tool = o.calls[len(o.calls)-1]
+			o.calls = o.calls[:len(o.calls)-1]
+		}
+		o.mu.Unlock()
+		o.send(Event{Type: EventToolResult, Tool: tool, Text: n.Data})
+	case service.StatusShowDiff:
+		o.send(Event{Type: EventDiff, Text: n.Data})
+	case service.StatusWarn:
+		o.send(Event{Type: EventWarning, Text: n.Data})
+	}
+}
+
+func (o *streamObserver) OnSubAgent(r service.SubAgentResult) {
+	o.send(Event{Type: EventSubAgent, Agent: r.AgentName, Status: r.Status.String(), Text: r.Progress, Err: r.Error})
+}
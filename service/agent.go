@@ -4,11 +4,15 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"os"
+	"runtime/debug"
+	"strings"
 
 	"github.com/activebook/gllm/data"
 	"github.com/activebook/gllm/internal/event"
 	"github.com/activebook/gllm/io"
 	"github.com/activebook/gllm/util"
+	"golang.org/x/term"
 )
 
 type StreamDataType int
@@ -35,6 +39,26 @@ type ModelInfo struct {
 	Seed            *int32  // Seed for deterministic generation
 	ContextLength   int32   // Model context length limit
 	MaxOutputTokens int32   // Model max output tokens
+
+	// Generation controls, zero values leave the provider default in place
+	MaxTokens        int32    // Max tokens to generate per response
+	Stop             []string // Stop sequences
+	FrequencyPenalty *float32 // Frequency penalty
+	PresencePenalty  *float32 // Presence penalty
+
+	Network     NetworkSettings   // Proxy and TLS settings of the model's HTTP client
+	Headers     map[string]string // Extra headers of every request
+	QueryParams map[string]string // Extra query parameters of every request
+
+	OpenRouter       *OpenRouterRouting // Routing of a model served by OpenRouter, nil otherwise
+	openRouterServed *openRouterServed  // Provider, model and cost reported by OpenRouter
+
+	SafetySettings map[string]string // Gemini safety thresholds by harm category
+	CodeExecution  bool              // Gemini or Anthropic runs the code the model writes
+	NativeTools    bool              // Claude's bash and text editor stand for the shell and file tools
+
+	InputPrice  float32 // USD per million input tokens, zero when unknown
+	OutputPrice float32 // USD per million output tokens, zero when unknown
 }
 
 type Agent struct {
@@ -56,12 +80,17 @@ type Agent struct {
 	LastWrittenData string              // Last written data
 
 	// Tools
-	SearchEngine *SearchEngine      // Search engine name
-	ToolsUse     data.ToolsUse      // Use tools
-	Interaction  InteractionHandler // Handler for confirmations and prompts
-	EnabledTools []string           // List of enabled embedding tools
-	UseCodeTool  bool               // Use code tool
-	MCPClient    *MCPClient         // MCP client for MCP tools
+	SearchEngine   *SearchEngine      // Search engine name
+	ToolsUse       data.ToolsUse      // Use tools
+	Interaction    InteractionHandler // Handler for confirmations and prompts
+	EnabledTools   []string           // List of enabled embedding tools
+	AutoFormat     bool               // Format files after successful edits
+	Shell          string             // Shell of the shell tool, empty detects one
+	InjectionGuard string             // Handling of prompt injection in untrusted tool results
+	PathRules      *PathRules         // Paths the file tools may read and write, nil for any
+	UseCodeTool    bool               // Use code tool
+	MCPClient      *MCPClient         // MCP client for MCP tools
+	Redactor       *Redactor          // Redaction of outgoing prompts, nil when off
 
 	// Output triage
 	StdOutput  io.Output     // Standard I/O
@@ -74,8 +103,23 @@ type Agent struct {
 	ModelName   string            // Current model name of current agent (agent model key)
 
 	// Output mode
-	Verbose   bool // Whether verbose output mode is enabled
-	QuietMode bool // Whether quiet mode is enabled
+	Verbose   bool           // Whether verbose output mode is enabled
+	ShowTools string         // Display of tool calls: full, compact or off, empty for the default boxes
+	QuietMode bool           // Whether quiet mode is enabled
+	Observer  StreamObserver // Receiver of the stream events, nil when none
+	toolView  *toolCallView  // Tool call being displayed in the ShowTools mode
+
+	budget      *budgetGuard // Cost budgets checked before each call to the provider
+	usageHidden bool         // Token usage is only tracked for the budgets, not shown
+}
+
+// ModelNetworkSettings returns the proxy and TLS settings of a model.
+func ModelNetworkSettings(model *data.Model) NetworkSettings {
+	return NetworkSettings{
+		Proxy:              model.Proxy,
+		CACert:             model.CACert,
+		InsecureSkipVerify: model.InsecureSkipVerify,
+	}
 }
 
 func constructModelInfo(model *data.Model) *ModelInfo {
@@ -97,9 +141,43 @@ func constructModelInfo(model *data.Model) *ModelInfo {
 	mi.Seed = model.Seed
 	mi.ContextLength = model.ContextLength
 	mi.MaxOutputTokens = model.MaxOutputTokens
+	mi.MaxTokens = model.MaxTokens
+	mi.Stop = model.Stop
+	mi.FrequencyPenalty = model.FrequencyPenalty
+	mi.PresencePenalty = model.PresencePenalty
+	mi.Network = ModelNetworkSettings(model)
+	mi.Headers = model.Headers
+	mi.QueryParams = model.QueryParams
+	mi.OpenRouter = ModelOpenRouterRouting(model)
+	mi.SafetySettings = model.SafetySettings
+	mi.CodeExecution = model.CodeExecution
+	mi.NativeTools = model.NativeTools
+	mi.InputPrice = model.InputPrice
+	mi.OutputPrice = model.OutputPrice
+	if provider == ModelProviderMock {
+		useMockProvider(&mi)
+	}
 	return &mi
 }
 
+// generateStream runs the generation with the client of the model's provider.
+func (ag *Agent) generateStream() error {
+	switch ag.Model.Provider {
+	case ModelProviderOpenAICompatible:
+		// Used for Chinese Models, they use "thinking[enable/disable]" as extra_body
+		return ag.GenerateOpenChatStream()
+	case ModelProviderOpenAI:
+		// Used for OpenAI compatible models
+		return ag.GenerateOpenAIStream()
+	case ModelProviderGemini:
+		return ag.GenerateGeminiStream()
+	case ModelProviderAnthropic:
+		return ag.GenerateAnthropicStream()
+	default:
+		return fmt.Errorf("unsupported model provider: %s", ag.Model.Provider)
+	}
+}
+
 func constructSearchEngine(capabilities []string) *SearchEngine {
 	se := SearchEngine{}
 	se.Name = GetNoneSearchEngineName()
@@ -153,40 +231,33 @@ func constructIO(quiet bool, outputFile string) (io.Output, io.Output) {
 	return stdIO, fileIO
 }
 
-// ConstructSystemPrompt constructs the system prompt by injecting memory and skills into the prompt
+// ConstructSystemPrompt constructs the system prompt from its layers: the agent prompt,
+// instruction files, skills, memory, plan mode and the environment block
 func ConstructSystemPrompt(prompt string, capabilities []string) string {
-	sysPrompt := prompt
-
-	// Inject memory into system prompt
-	if IsAgentMemoryEnabled(capabilities) {
-		memStore := data.NewMemoryStore()
-		if memoryContent := memStore.GetAll(); memoryContent != "" {
-			sysPrompt += "\n\n" + memoryContent
-		}
-	}
+	return JoinPromptLayers(ComposeSystemPrompt(prompt, capabilities))
+}
 
-	// Inject skills into system prompt if any are available and enabled
-	if IsAgentSkillsEnabled(capabilities) {
-		// Load available skills metadata
-		sm := GetSkillManager() // Use singleton
-		if skillsXML := sm.GetAvailableSkills(); skillsXML != "" {
-			sysPrompt += "\n\n" + skillsXML
-		}
-	}
+// isFastPath reports whether a run needs no agentic setup: a prompt without attachments to
+// an agent with no tools of its own, nor capabilities that bring tools it is meant to use.
+func isFastPath(op *AgentOptions) bool {
+	return len(op.EnabledTools) == 0 && len(op.Files) == 0 &&
+		!IsWebSearchEnabled(op.Capabilities) &&
+		!IsSubAgentsEnabled(op.Capabilities) &&
+		!IsPlanModeEnabled(op.Capabilities)
+}
 
-	// Inject plan mode into system prompt if plan mode is enabled
-	if IsPlanModeEnabled(capabilities) {
-		if data.GetPlanModeInSession() {
-			sysPrompt += "\n\n" + data.PlanModeSystemPrompt
+// fastPathCapabilities leaves out the capabilities that load MCP servers, skills and memory,
+// which only add tools and context a fast path run does without.
+func fastPathCapabilities(capabilities []string) []string {
+	var result []string
+	for _, c := range capabilities {
+		switch c {
+		case CapabilityMCPServers, CapabilityAgentSkills, CapabilityAgentMemory:
+			continue
 		}
+		result = append(result, c)
 	}
-
-	// Inject global and project instruction files (GLLM.md)
-	if instructionContent := data.GetInstructionContent(); instructionContent != "" {
-		sysPrompt += "\n\n" + instructionContent
-	}
-
-	return sysPrompt
+	return result
 }
 
 // construct all enabled tools including features tools
@@ -277,22 +348,33 @@ func ConstructSession(sessionName string, provider string) (Session, error) {
 }
 
 type AgentOptions struct {
-	Ctx           context.Context // Context to carry cancellation logic
-	Prompt        string
-	SysPrompt     string
-	Files         []*FileData
-	ModelInfo     *data.Model
-	MaxRecursions int
-	ThinkingLevel string
-	EnabledTools  []string      // List of enabled embedding tools
-	Capabilities  []string      // List of enabled capabilities
-	YoloMode      bool          // Whether to automatically approve tools
-	QuietMode     bool          // If Quiet mode then don't print to console
-	OutputFile    string        // If OutputFile is set then write to file
-	SSEOutput     *io.SSEOutput // SSE networking adapter
-	SessionName   string
-	MCPConfig     map[string]*data.MCPServer
-	Interaction   InteractionHandler // Handler for confirmations and prompts
+	Ctx            context.Context // Context to carry cancellation logic
+	Prompt         string
+	SysPrompt      string
+	Files          []*FileData
+	ModelInfo      *data.Model
+	MaxRecursions  int
+	ThinkingLevel  string
+	EnabledTools   []string        // List of enabled embedding tools
+	AutoFormat     bool            // Format files after successful edits
+	Shell          string          // Shell of the shell tool, empty detects one
+	InjectionGuard string          // Handling of prompt injection in web and MCP results, empty flags it
+	Paths          data.AgentPaths // Paths the file tools may read and write, empty for any
+	ShowTools      string          // Display of tool calls: full, compact or off, empty for the default boxes
+	Capabilities   []string        // List of enabled capabilities
+	YoloMode       bool            // Whether to automatically approve tools
+	QuietMode      bool            // If Quiet mode then don't print to console
+	OutputFile     string          // If OutputFile is set then write to file
+	SSEOutput      *io.SSEOutput   // SSE networking adapter
+	SessionName    string
+	MCPConfig      map[string]*data.MCPServer
+	Interaction    InteractionHandler // Handler for confirmations and prompts
+	TokenUsage     *TokenUsage        // Optional collector, token usage is always tracked when set
+	Observer       StreamObserver     // Optional receiver of the stream events
+	Budget         *CostBudget        // Cost budget of the invocation, shared by its runs, nil for none
+	SessionBudget  float64            // Cost budget of the session in USD, 0 for none
+	BestOf         int                // Candidate answers sampled at once, the best one is kept, 0 or 1 for one
+	ShowCandidates bool               // Print every candidate answer of BestOf, not only the best
 
 	// Sub-agent orchestration fields
 	SharedState *data.SharedState // Shared state for inter-agent communication
@@ -301,6 +383,9 @@ type AgentOptions struct {
 }
 
 func CallAgent(op *AgentOptions) error {
+	if op.BestOf > 1 {
+		return callBestOf(op)
+	}
 
 	// Set up model settings
 	mi := constructModelInfo(op.ModelInfo)
@@ -312,7 +397,7 @@ func CallAgent(op *AgentOptions) error {
 	toolsUse := data.ToolsUse{AutoApprove: op.YoloMode}
 
 	// Set up code tool settings
-	exeCode := IsCodeExecutionEnabled()
+	exeCode := IsCodeExecutionEnabled() || mi.CodeExecution
 
 	// Get verbose setting
 	settingsStore := data.GetSettingsStore()
@@ -346,9 +431,16 @@ func CallAgent(op *AgentOptions) error {
 		defer fileIO.Close()
 	}
 
+	// A plain question to an agent without tools skips MCP, skills and memory
+	capabilities := op.Capabilities
+	if isFastPath(op) {
+		capabilities = fastPathCapabilities(capabilities)
+		MarkStartup(StartupFastPath)
+	}
+
 	// Set up MCP client
 	var mc *MCPClient
-	if IsMCPServersEnabled(op.Capabilities) {
+	if IsMCPServersEnabled(capabilities) {
 		mc = GetMCPClient() // use the shared instance
 		if !op.QuietMode {
 			event.StartIndicator("")
@@ -356,6 +448,7 @@ func CallAgent(op *AgentOptions) error {
 		err := mc.Init(op.MCPConfig, MCPLoadOption{
 			LoadAll:   false,
 			LoadTools: true, // only load tools
+			Lazy:      true, // cached tools are enough until one is called
 		}) // Load only allowed servers
 		if !op.QuietMode {
 			event.StopIndicator()
@@ -369,55 +462,116 @@ func CallAgent(op *AgentOptions) error {
 		// Because next turn in repl mode, it would need to re-init the mcp client, which is wasteful and slow
 		// The singleton pattern with cleanup at application exit is the **correct** design pattern for shared resources like MCP client connections.
 		// defer mc.Close()
+		MarkStartup(StartupMCPReady)
 	}
 
 	// Need to append markdown
 	var markdown *Markdown
-	if IsMarkdownEnabled(op.Capabilities) {
-		markdown = NewMarkdown()
+	// Piped with --plain, the raw response is the output
+	if IsMarkdownEnabled(op.Capabilities) && !data.PlainOutput() {
+		// Render while streaming on a terminal, otherwise once the response is complete
+		if _, ok := stdIO.(*io.StdOutput); ok && term.IsTerminal(int(os.Stdout.Fd())) {
+			markdown = NewStreamingMarkdown(stdIO)
+		} else {
+			markdown = NewMarkdown()
+		}
 	}
 
 	// Need to append token usage
-	var tu *TokenUsage
-	if IsTokenUsageEnabled(op.Capabilities) {
+	tu := op.TokenUsage
+	if tu == nil && IsTokenUsageEnabled(op.Capabilities) {
+		tu = NewTokenUsage()
+	}
+
+	// The spend of a run is checked against the budgets and added to the session's
+	budget := newBudgetGuard(op.Budget, op.SessionName, op.SessionBudget, mi)
+	usageHidden := false
+	if tu == nil && (budget.limited() || mi.InputPrice > 0 || mi.OutputPrice > 0) {
 		tu = NewTokenUsage()
+		usageHidden = true
 	}
 
 	// Inject memory, skills, plan mode into system prompt
-	op.SysPrompt = ConstructSystemPrompt(op.SysPrompt, op.Capabilities)
+	op.SysPrompt = ConstructSystemPrompt(op.SysPrompt, capabilities)
+	MarkStartup(StartupSystemPrompt)
+
+	// Tell the model what past sessions said about a prompt that looks related to them
+	if IsSessionRecallEnabled(op.Capabilities) {
+		if recall := SessionRecallContext(op.Ctx, op.Prompt, op.SessionName); recall != "" {
+			op.SysPrompt += "\n\n" + recall
+		}
+	}
+
+	// Activate or hint at the skills that match the prompt
+	if IsAgentSkillsEnabled(capabilities) && data.GetSettingsStore().GetSkillSuggestEnabled() {
+		var hint string
+		op.Prompt, hint = SuggestSkills(op.Ctx, op.Prompt, op.SessionName)
+		if hint != "" {
+			op.SysPrompt += "\n\n" + hint
+		}
+	}
+
+	// Let the hook scripts rewrite or refuse the prompt
+	hooks := GetScriptHooks()
+	prompt, err := hooks.Prompt(op.Prompt, op.AgentName)
+	if err != nil {
+		return err
+	}
+	op.Prompt = prompt
 
 	// Construct all enabled tools
-	enabledTools := constructEnabledTools(op.EnabledTools, op.Capabilities)
+	enabledTools := constructEnabledTools(op.EnabledTools, capabilities)
+	enabledTools = withHandoffBackTool(enabledTools, op.AgentName)
+	MarkStartup(StartupToolsBuilt)
+
+	// Anchor the paths of the agent in the working directory
+	pathRules, err := NewPathRules(op.Paths)
+	if err != nil {
+		return err
+	}
+
+	// Redact secrets and personal data before anything is sent
+	redactor := GetRedactor()
 
 	ag := Agent{
-		Ctx:           op.Ctx,
-		Model:         mi,
-		SystemPrompt:  op.SysPrompt,
-		UserPrompt:    op.Prompt,
-		Files:         op.Files,
-		NotifyChan:    notifyCh,
-		DataChan:      dataCh,
-		ProceedChan:   proceedCh,
-		SearchEngine:  se,
-		ToolsUse:      toolsUse,
-		Interaction:   op.Interaction,
-		EnabledTools:  enabledTools,
-		UseCodeTool:   exeCode,
-		MCPClient:     mc,
-		ThinkingLevel: thinkingLevel,
-		MaxRecursions: op.MaxRecursions,
-		Markdown:      markdown,
-		TokenUsage:    tu,
-		StdOutput:     stdIO,
-		FileOutput:    fileIO,
-		SSEOutput:     op.SSEOutput,
-		Status:        StatusStack{},
-		SharedState:   op.SharedState,
-		AgentName:     op.AgentName,
-		ModelName:     op.ModelName,
-		Verbose:       verboseMode,
-		QuietMode:     op.QuietMode,
+		Ctx:            op.Ctx,
+		Model:          mi,
+		SystemPrompt:   redactor.Redact(op.SysPrompt),
+		UserPrompt:     redactor.Redact(op.Prompt),
+		Files:          redactor.redactFiles(op.Files),
+		NotifyChan:     notifyCh,
+		DataChan:       dataCh,
+		ProceedChan:    proceedCh,
+		SearchEngine:   se,
+		ToolsUse:       toolsUse,
+		Interaction:    op.Interaction,
+		EnabledTools:   enabledTools,
+		AutoFormat:     op.AutoFormat,
+		Shell:          op.Shell,
+		InjectionGuard: op.InjectionGuard,
+		PathRules:      pathRules,
+		UseCodeTool:    exeCode,
+		MCPClient:      mc,
+		Redactor:       redactor,
+		ThinkingLevel:  thinkingLevel,
+		MaxRecursions:  op.MaxRecursions,
+		Markdown:       markdown,
+		TokenUsage:     tu,
+		StdOutput:      stdIO,
+		FileOutput:     fileIO,
+		SSEOutput:      op.SSEOutput,
+		Status:         StatusStack{},
+		SharedState:    op.SharedState,
+		AgentName:      op.AgentName,
+		ModelName:      op.ModelName,
+		Verbose:        verboseMode,
+		ShowTools:      op.ShowTools,
+		QuietMode:      op.QuietMode,
+		Observer:       op.Observer,
+		budget:         budget,
+		usageHidden:    usageHidden,
 	}
+	defer func() { ag.budget.end(ag.runCost()) }()
 
 	// If no context is provided, use background context
 	if ag.Ctx == nil {
@@ -443,60 +597,45 @@ func CallAgent(op *AgentOptions) error {
 		defer func() {
 			// Recover from panics and convert them to errors
 			if r := recover(); r != nil {
+				ReportRecoveredPanic(r, debug.Stack())
 				errMsg := fmt.Sprintf("Panic occurred: %v", r)
 				notifyCh <- StreamNotify{Status: StatusError, Data: errMsg}
 			}
 		}()
 
-		switch ag.Model.Provider {
-		case ModelProviderOpenAICompatible:
-			// Used for Chinese Models, they use "thinking[enable/disable]" as extra_body
-			if err := ag.GenerateOpenChatStream(); err != nil {
-				// Send error through channel instead of returning
-				if IsSwitchAgentError(err) {
-					notifyCh <- StreamNotify{Status: StatusSwitchAgent, Extra: err}
-				} else if IsUserCancelError(err) {
-					notifyCh <- StreamNotify{Status: StatusUserCancel, Extra: err}
-				} else {
-					notifyCh <- StreamNotify{Status: StatusError, Data: fmt.Sprintf("%v", err)}
+		// Remember where the session stood, a blocked run may be retried from there
+		length := -1
+		if data.GetSettingsStore().GetSafetySettings().Retry {
+			length = ag.sessionLength()
+		}
+		err := ag.generateStream()
+		if block, ok := AsSafetyBlockError(err); ok && length >= 0 {
+			if redacted, ok := ag.prepareSafetyRetry(length); ok {
+				if ag.Status.Peek() == StatusReasoning {
+					ag.Status.ChangeTo(notifyCh, StreamNotify{Status: StatusReasoningOver}, proceedCh)
 				}
-			}
-		case ModelProviderOpenAI:
-			// Used for OpenAI compatible models
-			if err := ag.GenerateOpenAIStream(); err != nil {
-				// Send error through channel instead of returning
-				if IsSwitchAgentError(err) {
-					notifyCh <- StreamNotify{Status: StatusSwitchAgent, Extra: err}
-				} else if IsUserCancelError(err) {
-					notifyCh <- StreamNotify{Status: StatusUserCancel, Extra: err}
-				} else {
-					notifyCh <- StreamNotify{Status: StatusError, Data: fmt.Sprintf("%v", err)}
+				msg := fmt.Sprintf("%v, retrying once with a sanitized prompt", block)
+				if redacted > 0 {
+					msg += fmt.Sprintf(" (%d values redacted)", redacted)
 				}
+				ag.Status.ChangeTo(notifyCh, StreamNotify{Status: StatusWarn, Data: msg}, nil)
+				err = ag.generateStream()
 			}
-		case ModelProviderGemini:
-			if err := ag.GenerateGeminiStream(); err != nil {
-				// Send error through channel instead of returning
-				if IsSwitchAgentError(err) {
-					notifyCh <- StreamNotify{Status: StatusSwitchAgent, Extra: err}
-				} else if IsUserCancelError(err) {
-					notifyCh <- StreamNotify{Status: StatusUserCancel, Extra: err}
-				} else {
-					notifyCh <- StreamNotify{Status: StatusError, Data: fmt.Sprintf("%v", err)}
-				}
-			}
-		case ModelProviderAnthropic:
-			if err := ag.GenerateAnthropicStream(); err != nil {
-				// Send error through channel instead of returning
-				if IsSwitchAgentError(err) {
-					notifyCh <- StreamNotify{Status: StatusSwitchAgent, Extra: err}
-				} else if IsUserCancelError(err) {
-					notifyCh <- StreamNotify{Status: StatusUserCancel, Extra: err}
-				} else {
-					notifyCh <- StreamNotify{Status: StatusError, Data: fmt.Sprintf("%v", err)}
-				}
+		}
+		if err != nil {
+			// Send error through channel instead of returning
+			if IsSwitchAgentError(err) {
+				notifyCh <- StreamNotify{Status: StatusSwitchAgent, Extra: err}
+			} else if IsUserCancelError(err) {
+				notifyCh <- StreamNotify{Status: StatusUserCancel, Extra: err}
+			} else if block, ok := AsSafetyBlockError(err); ok {
+				notifyCh <- StreamNotify{Status: StatusError, Data: fmt.Sprintf("%v\n%s", block, SafetyBlockHint(block))}
+			} else if IsBudgetExceededError(err) {
+				// Kept as is, the caller tells a budget stop from a failure
+				notifyCh <- StreamNotify{Status: StatusError, Data: fmt.Sprintf("%v", err), Extra: err}
+			} else {
+				notifyCh <- StreamNotify{Status: StatusError, Data: fmt.Sprintf("%v", err)}
 			}
-		default:
-			notifyCh <- StreamNotify{Status: StatusError, Data: fmt.Sprintf("Unsupported model provider: %s", ag.Model.Provider)}
 		}
 	}()
 
@@ -506,6 +645,14 @@ func CallAgent(op *AgentOptions) error {
 
 	// Error variable to store any error from the goroutine
 	var processingErr error
+	// The text of the response, for the on_response hooks
+	var response strings.Builder
+
+	// The status line of the indicator follows the run
+	if ag.StdOutput != nil {
+		event.StartProgress()
+		defer event.StopProgress()
+	}
 
 	// Process notifications in the main thread
 	// listen on multiple channels in Go, it listens to them simultaneously.
@@ -520,14 +667,22 @@ func CallAgent(op *AgentOptions) error {
 			// disable notify channel while processing data
 			activeNotifyCh = nil
 
+			if op.Observer != nil {
+				op.Observer.OnData(data)
+			}
+			MarkStartup(StartupFirstToken)
+
 			switch data.Type {
 			case DataTypeNormal:
 				// Render the streamed text and save to markdown buffer
 				ag.WriteText(data.Text)
+				ag.countProgress(data.Text)
+				response.WriteString(data.Text)
 
 			case DataTypeReasoning:
 				// Reasoning data don't need to be saved to markdown buffer
 				ag.WriteReasoning(data.Text)
+				ag.countProgress(data.Text)
 
 			case DataTypeFinished:
 				// Wait all data to be processed(flush)
@@ -555,6 +710,10 @@ func CallAgent(op *AgentOptions) error {
 			// disable data channel while processing notify
 			activeDataCh = nil
 
+			if op.Observer != nil {
+				op.Observer.OnNotify(notify)
+			}
+
 			switch notify.Status {
 			case StatusProcessing:
 				// Start indicator (let indicator decide the text)
@@ -571,6 +730,9 @@ func CallAgent(op *AgentOptions) error {
 				ag.StopIndicator()
 				ag.Error(notify.Data)
 				processingErr = fmt.Errorf("%s", notify.Data)
+				if err, ok := notify.Extra.(error); ok {
+					processingErr = err
+				}
 				return processingErr
 			case StatusSwitchAgent:
 				// Switch agent signal, pop up
@@ -595,6 +757,13 @@ func CallAgent(op *AgentOptions) error {
 				return fmt.Errorf("unknown user cancel error type: %v", notify.Extra)
 			case StatusFinished:
 				ag.StopIndicator()
+				// Show what the hook scripts add, such as a footer
+				if extra := hooks.Response(response.String(), op.AgentName); extra != "" {
+					if !strings.HasSuffix(response.String(), "\n") {
+						extra = "\n" + extra
+					}
+					ag.WriteHookText(extra)
+				}
 				// Render the markdown
 				ag.WriteMarkdown()
 				// Render the token usage
@@ -611,19 +780,32 @@ func CallAgent(op *AgentOptions) error {
 				// Complete Thinking color at the end
 				ag.CompleteReasoning()
 				proceedCh <- true
+			case StatusToolPreview:
+				ag.ShowToolPreview(notify.Data)
+				proceedCh <- true
 			case StatusFunctionCalling:
+				ag.StopToolPreview()
 				ag.WriteEnd() // ensure previous data ends with newline, because function call box starts a new line
 				ag.WriteFunctionCall(notify.Data)
-				// ag.StartIndicator("Function Calling...")
+				// The status line names the tool while it runs
+				ag.StartToolIndicator(notify.Data)
 				proceedCh <- true
 			case StatusFunctionCallingOver:
-				ag.WriteFunctionCallOver()
+				ag.StopToolIndicator()
+				ag.WriteFunctionCallOver(notify.Data)
 				proceedCh <- true
 			case StatusShowDiff:
+				ag.StopIndicator()
 				ag.WriteDiff(notify.Data)
 				proceedCh <- true
 			case StatusShowDiffOver:
 				ag.WriteDiff("") // just write a newline
+				ag.ResumeToolIndicator()
+				proceedCh <- true
+			case StatusShowTodos:
+				ag.StopIndicator()
+				ag.WriteTodos(notify.Data)
+				ag.ResumeToolIndicator()
 				proceedCh <- true
 			}
 
@@ -22,12 +22,18 @@ const (
 WriteText writes the given text to the Agent's Std, Markdown, and OutputFile writers if they are set.
 */
 func (ag *Agent) WriteText(text string) {
-	if ag.StdOutput != nil {
-		ag.StdOutput.Writef("%s", text)
-		ag.LastWrittenData = text
-	}
-	if ag.Markdown != nil {
+	if ag.Markdown != nil && ag.Markdown.Streaming() {
+		// Rendered blocks end with a newline
 		ag.Markdown.Writef("%s", text)
+		ag.LastWrittenData = "\n"
+	} else {
+		if ag.StdOutput != nil {
+			ag.StdOutput.Writef("%s", text)
+			ag.LastWrittenData = text
+		}
+		if ag.Markdown != nil {
+			ag.Markdown.Writef("%s", text)
+		}
 	}
 	if ag.FileOutput != nil {
 		ag.FileOutput.Writef("%s", text)
@@ -37,11 +43,19 @@ func (ag *Agent) WriteText(text string) {
 	}
 }
 
+// settleMarkdown finishes the markdown block being rendered, so that other output lands below it.
+func (ag *Agent) settleMarkdown() {
+	if ag.Markdown != nil {
+		ag.Markdown.Settle()
+	}
+}
+
 /*
 StartReasoning notifies the user and logs to file that the agent has started thinking.
 It writes a status message to both Std and OutputFile if they are available.
 */
 func (ag *Agent) StartReasoning() {
+	ag.settleMarkdown()
 	if ag.SSEOutput != nil {
 		ag.SSEOutput.WriteStatusEvent("start_reasoning")
 	}
@@ -64,6 +78,7 @@ func (ag *Agent) StartReasoning() {
 }
 
 func (ag *Agent) CompleteReasoning() {
+	ag.settleMarkdown()
 	if ag.SSEOutput != nil {
 		ag.SSEOutput.WriteStatusEvent("end_reasoning")
 	}
@@ -86,6 +101,7 @@ func (ag *Agent) CompleteReasoning() {
 WriteReasoning writes the provided reasoning text to both the standard output and an output file, applying specific formatting to each if they are available.
 */
 func (ag *Agent) WriteReasoning(text string) {
+	ag.settleMarkdown()
 	if ag.StdOutput != nil {
 		// Only output reasoning content under verbose
 		if ag.Verbose {
@@ -122,7 +138,7 @@ func (ag *Agent) WriteMarkdown() {
 
 func (ag *Agent) WriteUsage() {
 	// Render the token usage
-	if ag.TokenUsage != nil {
+	if ag.TokenUsage != nil && !ag.usageHidden {
 		if ag.StdOutput != nil {
 			ag.TokenUsage.Render(ag.StdOutput)
 		}
@@ -130,6 +146,7 @@ func (ag *Agent) WriteUsage() {
 }
 
 func (ag *Agent) WriteDiff(text string) {
+	ag.settleMarkdown()
 	// Only write to stdout
 	if ag.StdOutput != nil {
 		ag.StdOutput.Writeln(text)
@@ -137,6 +154,11 @@ func (ag *Agent) WriteDiff(text string) {
 }
 
 func (ag *Agent) WriteFunctionCall(text string) {
+	ag.settleMarkdown()
+	if ag.StdOutput != nil && ag.ShowTools != "" {
+		ag.beginToolView(text)
+		return
+	}
 	// Attempt to parse text as JSON
 	// The text is expected to be in format "function_name(arguments)" or just raw text
 	// But in our new implementation, we will pass a JSON string: {"function": name, "args": args}
@@ -260,7 +282,11 @@ func (ag *Agent) WriteFunctionCall(text string) {
 	}
 }
 
-func (ag *Agent) WriteFunctionCallOver() {
+func (ag *Agent) WriteFunctionCallOver(result string) {
+	if ag.StdOutput != nil && ag.ShowTools != "" {
+		ag.endToolView(result)
+		return
+	}
 	if ag.StdOutput != nil {
 		if !ag.Verbose {
 			// Add a newline to separate the function call from the output
@@ -269,7 +295,56 @@ func (ag *Agent) WriteFunctionCallOver() {
 	}
 }
 
+// WriteTodos renders the agent's todo list as a checklist panel.
+func (ag *Agent) WriteTodos(text string) {
+	ag.settleMarkdown()
+	if ag.StdOutput == nil {
+		return
+	}
+	var items []data.TodoItem
+	if err := json.Unmarshal([]byte(text), &items); err != nil || len(items) == 0 {
+		return
+	}
+
+	style := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color(data.BorderHex)).
+		Padding(0, 1)
+	titleStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(data.SectionHex)).Bold(true)
+	doneStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(data.DetailHex)).Strikethrough(true)
+	activeStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(data.KeyHex)).Bold(true)
+	pendingStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(data.LabelHex))
+
+	// Make sure we have enough space for the border
+	tcol := io.GetTerminalWidth() - 8
+
+	done := 0
+	lines := make([]string, 0, len(items)+1)
+	for _, item := range items {
+		switch item.Status {
+		case data.TodoCompleted:
+			done++
+			lines = append(lines, doneStyle.Width(tcol).Render("✓ "+item.Content))
+		case data.TodoInProgress:
+			lines = append(lines, activeStyle.Width(tcol).Render("▶ "+item.Content))
+		default:
+			lines = append(lines, pendingStyle.Width(tcol).Render("○ "+item.Content))
+		}
+	}
+	title := titleStyle.Render(fmt.Sprintf("Todos %d/%d", done, len(items)))
+	lines = append([]string{title}, lines...)
+	ag.StdOutput.Writeln(style.Render(strings.Join(lines, "\n")))
+}
+
+// WriteHookText writes what the hook scripts add after the response.
+func (ag *Agent) WriteHookText(text string) {
+	ag.settleMarkdown()
+	ag.WriteText(text + "\n")
+	ag.settleMarkdown()
+}
+
 func (ag *Agent) WriteEnd() {
+	ag.settleMarkdown()
 	if ag.SSEOutput != nil {
 		ag.SSEOutput.WriteStatusEvent("agent_finished")
 	}
@@ -285,6 +360,7 @@ func (ag *Agent) WriteEnd() {
 }
 
 func (ag *Agent) StartIndicator(text string) {
+	ag.settleMarkdown()
 	if ag.StdOutput != nil {
 		// fmt.Println("Start Indicator From Agent")
 		event.StartIndicator(text)
@@ -298,6 +374,72 @@ func (ag *Agent) StopIndicator() {
 	}
 }
 
+// countProgress adds streamed text to the token count of the status line.
+func (ag *Agent) countProgress(text string) {
+	if ag.StdOutput != nil {
+		event.AddProgressTokens(EstimateTokens(text))
+	}
+}
+
+// StartToolIndicator runs the indicator while a tool executes, the status line names it.
+func (ag *Agent) StartToolIndicator(text string) {
+	if ag.StdOutput == nil || ag.Verbose {
+		return
+	}
+	var toolData struct {
+		Function string `json:"function"`
+	}
+	if err := json.Unmarshal([]byte(text), &toolData); err != nil || toolData.Function == "" {
+		return
+	}
+	event.SetProgressTool(toolData.Function)
+	ag.StartIndicator("")
+}
+
+// ShowToolPreview runs the indicator while the model writes the arguments of a tool call,
+// the status line sums them up.
+func (ag *Agent) ShowToolPreview(text string) {
+	if ag.StdOutput == nil || ag.Verbose {
+		return
+	}
+	event.SetProgressPreview(text)
+	ag.StartIndicator("")
+}
+
+// ShowActivity shows what the run is busy with on the status line of the indicator, such as
+// an upload, "" clears it.
+func (ag *Agent) ShowActivity(text string) {
+	if ag.StdOutput == nil {
+		return
+	}
+	event.SetProgressActivity(text)
+}
+
+// StopToolPreview stops the indicator of the preview before the tool call is shown.
+func (ag *Agent) StopToolPreview() {
+	if ag.StdOutput == nil || event.GetProgress().Preview == "" {
+		return
+	}
+	event.SetProgressPreview("")
+	ag.StopIndicator()
+}
+
+// StopToolIndicator stops the indicator once the tool is done.
+func (ag *Agent) StopToolIndicator() {
+	if ag.StdOutput == nil || event.GetProgress().Tool == "" {
+		return
+	}
+	event.SetProgressTool("")
+	ag.StopIndicator()
+}
+
+// ResumeToolIndicator starts the indicator again after output shown while a tool runs.
+func (ag *Agent) ResumeToolIndicator() {
+	if ag.StdOutput != nil && event.GetProgress().Tool != "" {
+		ag.StartIndicator("")
+	}
+}
+
 func (ag *Agent) Error(text string) {
 	// ignore stdout, because CallAgent will return the error
 	// if ag.Std != nil {
@@ -309,6 +451,7 @@ func (ag *Agent) Error(text string) {
 }
 
 func (ag *Agent) Warn(text string) {
+	ag.settleMarkdown()
 	if ag.StdOutput != nil {
 		util.LogWarnf("%s\n", text)
 	}
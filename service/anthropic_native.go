@@ -0,0 +1,326 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// Names of Claude's native tools. Claude is trained on them, so they do better than the gllm
+// tools they stand for, which still run them: the calls go through the same checks and
+// confirmations.
+const (
+	anthropicBashTool         = "bash"
+	anthropicTextEditorTool   = "str_replace_based_edit_tool"
+	anthropicTextEditorLegacy = "str_replace_editor" // Claude 3.7
+)
+
+// Beta features of the Anthropic API used by code execution.
+const (
+	anthropicBetaFilesAPI      = "files-api-2025-04-14"
+	anthropicBetaCodeExecution = "code-execution-2025-08-25"
+)
+
+// anthropicNativeTools are the native tools offered to a Claude model in place of the gllm
+// tools they cover.
+type anthropicNativeTools struct {
+	replaced map[string]bool // gllm tools standing behind a native tool
+}
+
+// anthropicNativeToolSet replaces the shell and the file tools among the enabled tools with
+// Claude's bash and text editor tools. It returns the native tools and the tools left enabled.
+func anthropicNativeToolSet(model string, enabled []string) ([]anthropic.ToolUnionParam, []string, *anthropicNativeTools) {
+	native := &anthropicNativeTools{replaced: make(map[string]bool)}
+	var tools []anthropic.ToolUnionParam
+	var remaining []string
+	editor := false
+	for _, name := range enabled {
+		switch name {
+		case ToolShell:
+			tools = append(tools, anthropic.ToolUnionParam{OfBashTool20250124: &anthropic.ToolBash20250124Param{}})
+			native.replaced[name] = true
+		case ToolReadFile, ToolWriteFile, ToolEditFile:
+			native.replaced[name] = true
+			editor = true
+		default:
+			remaining = append(remaining, name)
+		}
+	}
+	if editor {
+		if isLegacyClaude(model) {
+			tools = append(tools, anthropic.ToolUnionParam{OfTextEditor20250124: &anthropic.ToolTextEditor20250124Param{}})
+		} else {
+			tools = append(tools, anthropic.ToolUnionParam{OfTextEditor20250728: &anthropic.ToolTextEditor20250728Param{}})
+		}
+	}
+	return tools, remaining, native
+}
+
+// isLegacyClaude reports whether the model predates Claude 4, whose text editor has another name.
+func isLegacyClaude(model string) bool {
+	return strings.Contains(strings.ToLower(model), "claude-3")
+}
+
+// anthropicNativeCall is a call of a native tool mapped onto the gllm tool it stands for.
+type anthropicNativeCall struct {
+	tool   string
+	args   map[string]interface{}
+	result string // Answers the call without running a tool
+
+	// prepare settles what depends on the files once the path rules let the call through:
+	// listing a directory instead of reading it, or the content with the text inserted.
+	// A result answers the call.
+	prepare func() string
+}
+
+// translate maps a call of a native tool onto the gllm tool it stands for, nil for the other
+// tools. The files are not touched until the call is prepared.
+func (n *anthropicNativeTools) translate(name string, input interface{}) *anthropicNativeCall {
+	if n == nil || (name != anthropicBashTool && name != anthropicTextEditorTool && name != anthropicTextEditorLegacy) {
+		return nil
+	}
+	in, _ := input.(map[string]interface{})
+	if in == nil {
+		if s, isString := input.(string); isString {
+			json.Unmarshal([]byte(s), &in)
+		}
+	}
+	var call *anthropicNativeCall
+	if name == anthropicBashTool {
+		call = n.translateBash(in)
+	} else {
+		call = n.translateTextEditor(in)
+	}
+	if call.result == "" && !n.covers(call.tool) {
+		call.result = fmt.Sprintf("Error: %s is not available to this agent.", call.tool)
+	}
+	return call
+}
+
+// covers reports whether a native tool may run the gllm tool. Viewing a directory lists it
+// with the file reading tool.
+func (n *anthropicNativeTools) covers(tool string) bool {
+	if tool == ToolListDirectory {
+		tool = ToolReadFile
+	}
+	return n.replaced[tool]
+}
+
+func (n *anthropicNativeTools) translateBash(in map[string]interface{}) *anthropicNativeCall {
+	if restart, _ := in["restart"].(bool); restart {
+		return &anthropicNativeCall{tool: ToolShell, result: "The shell was restarted. Every command runs in a new shell: chain the commands that depend on each other, e.g. cd dir && make."}
+	}
+	command, _ := in["command"].(string)
+	if strings.TrimSpace(command) == "" {
+		return &anthropicNativeCall{tool: ToolShell, result: "Error: the command is missing."}
+	}
+	return &anthropicNativeCall{tool: ToolShell, args: map[string]interface{}{"command": command, "purpose": "Run a command with the bash tool"}}
+}
+
+func (n *anthropicNativeTools) translateTextEditor(in map[string]interface{}) *anthropicNativeCall {
+	command, _ := in["command"].(string)
+	path, _ := in["path"].(string)
+	if path == "" {
+		return &anthropicNativeCall{tool: ToolReadFile, result: "Error: the path is missing."}
+	}
+	call := &anthropicNativeCall{}
+	switch command {
+	case "view":
+		call.tool = ToolReadFile
+		call.args = map[string]interface{}{"path": path, "line_numbers": true}
+		if viewRange, _ := in["view_range"].([]interface{}); len(viewRange) == 2 {
+			if start, ok := viewRange[0].(float64); ok && start >= 1 {
+				call.args["start_line"] = int(start)
+			}
+			if end, ok := viewRange[1].(float64); ok && end >= 1 {
+				call.args["end_line"] = int(end)
+			}
+		}
+		call.prepare = func() string {
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				call.tool = ToolListDirectory
+				call.args = map[string]interface{}{"path": path}
+			}
+			return ""
+		}
+	case "create":
+		text, _ := in["file_text"].(string)
+		call.tool = ToolWriteFile
+		call.args = map[string]interface{}{"path": path, "content": text, "purpose": "Create the file with the text editor tool"}
+	case "str_replace":
+		oldStr, _ := in["old_str"].(string)
+		newStr, _ := in["new_str"].(string)
+		call.tool = ToolEditFile
+		if oldStr == "" {
+			call.result = "Error: old_str is missing."
+			break
+		}
+		edits := []interface{}{map[string]interface{}{"search": oldStr, "replace": newStr}}
+		call.args = map[string]interface{}{"path": path, "edits": edits, "purpose": "Edit the file with the text editor tool"}
+	case "insert":
+		text, ok := in["insert_text"].(string)
+		if !ok {
+			text, _ = in["new_str"].(string) // Claude 3.7
+		}
+		line, _ := in["insert_line"].(float64)
+		// The checks see the inserted text as the content until the file is read
+		call.tool = ToolWriteFile
+		call.args = map[string]interface{}{"path": path, "content": text, "purpose": fmt.Sprintf("Insert text after line %d with the text editor tool", int(line))}
+		call.prepare = func() string {
+			content, result := insertLines(path, int(line), text)
+			call.args["content"] = content
+			return result
+		}
+	case "undo_edit":
+		call.tool = ToolWriteFile
+		call.args = map[string]interface{}{"path": path, "content": "", "purpose": "Undo the last edit with the text editor tool"}
+		call.prepare = func() string {
+			content, ok := lastFileBackup(path)
+			if !ok {
+				return fmt.Sprintf("Error: there is no edit of %s to undo.", path)
+			}
+			call.args["content"] = string(content)
+			return ""
+		}
+	default:
+		call.tool = ToolReadFile
+		call.result = fmt.Sprintf("Error: unknown command '%s', use view, create, str_replace, insert or undo_edit.", command)
+	}
+	return call
+}
+
+// insertLines returns the content of the file with the text inserted after the line, 0 for the
+// top. A result explains why it can't be done.
+func insertLines(path string, line int, text string) (string, string) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Sprintf("Error: %v", err)
+	}
+	if fileChangedOnDisk(path) {
+		return "", fmt.Sprintf(ToolRespFileChangedOnDisk, path)
+	}
+	lines := strings.SplitAfter(string(content), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if line < 0 || line > len(lines) {
+		return "", fmt.Sprintf("Error: insert_line %d is out of range, the file has %d lines.", line, len(lines))
+	}
+	if line > 0 && !strings.HasSuffix(lines[line-1], "\n") {
+		lines[line-1] += "\n"
+	}
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	var b strings.Builder
+	for _, l := range lines[:line] {
+		b.WriteString(l)
+	}
+	b.WriteString(text)
+	for _, l := range lines[line:] {
+		b.WriteString(l)
+	}
+	return b.String(), ""
+}
+
+// anthropicCodeExecutionTool is Anthropic's code execution tool, which runs code in a container
+// that gets the uploaded attachments.
+func anthropicCodeExecutionTool() anthropic.ToolUnionParam {
+	return anthropic.ToolUnionParam{OfCodeExecutionTool20250825: &anthropic.CodeExecutionTool20250825Param{}}
+}
+
+// hasContainerUploads reports whether the messages attach uploaded files to the container of
+// the code execution tool.
+func hasContainerUploads(messages []anthropic.MessageParam) bool {
+	for _, msg := range messages {
+		for _, block := range msg.Content {
+			if block.OfContainerUpload != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// uploadFile uploads an attachment with the Files API, or reuses the upload of the same content,
+// and returns the block that puts it in the container of the code execution tool.
+func (a *Anthropic) uploadFile(ag *Agent, uploads *fileUploads, file *FileData) (anthropic.ContentBlockParamUnion, error) {
+	key := uploadKey(ag.Model, file.Data())
+	if up, ok := uploads.active(key, time.Now()); ok {
+		return anthropic.NewContainerUploadBlock(up.Name), nil
+	}
+
+	name := filepath.Base(file.Path())
+	defer ag.ShowActivity("")
+	reader := &uploadProgress{r: bytes.NewReader(file.Data()), total: len(file.Data()), last: -1, report: func(percent int) {
+		ag.ShowActivity(fmt.Sprintf("uploading %s %d%%", name, percent))
+	}}
+	meta, err := a.client.Beta.Files.Upload(ag.Ctx, anthropic.BetaFileUploadParams{File: anthropic.File(reader, name, file.Format())})
+	if err != nil {
+		return anthropic.ContentBlockParamUnion{}, fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+	// Files are kept until deleted
+	uploads.Uploads[key] = fileUpload{Name: meta.ID, MIMEType: meta.MimeType, File: name}
+	return anthropic.NewContainerUploadBlock(meta.ID), nil
+}
+
+// anthropicServerToolText renders the code run by the code execution tool as markdown.
+func anthropicServerToolText(name, input string) string {
+	var in struct {
+		Command string `json:"command"`
+		Code    string `json:"code"`
+		Path    string `json:"path"`
+	}
+	if json.Unmarshal([]byte(input), &in) != nil {
+		return ""
+	}
+	switch name {
+	case "bash_code_execution":
+		return codeBlockText("bash", in.Command)
+	case "code_execution":
+		return codeBlockText("python", in.Code)
+	case "text_editor_code_execution":
+		if in.Command == "" || in.Path == "" {
+			return ""
+		}
+		return fmt.Sprintf("\n`%s %s`\n", in.Command, in.Path)
+	}
+	return ""
+}
+
+// anthropicCodeResultText renders the output of the code execution tool as markdown, from the
+// raw JSON of its result block.
+func anthropicCodeResultText(raw string) string {
+	var block struct {
+		Content struct {
+			Stdout     string `json:"stdout"`
+			Stderr     string `json:"stderr"`
+			ReturnCode int    `json:"return_code"`
+			ErrorCode  string `json:"error_code"`
+		} `json:"content"`
+	}
+	if json.Unmarshal([]byte(raw), &block) != nil {
+		return ""
+	}
+	c := block.Content
+	output := strings.TrimRight(c.Stdout, "\n")
+	if stderr := strings.TrimRight(c.Stderr, "\n"); stderr != "" {
+		if output != "" {
+			output += "\n"
+		}
+		output += stderr
+	}
+	switch {
+	case c.ErrorCode != "":
+		return codeOutputText(output, c.ErrorCode)
+	case c.ReturnCode != 0:
+		return codeOutputText(output, fmt.Sprintf("exit code %d", c.ReturnCode))
+	}
+	return codeOutputText(output, "")
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestAnthropicNativeToolSet(t *testing.T) {
+	tools, remaining, native := anthropicNativeToolSet("claude-sonnet-4-5", []string{ToolShell, ToolReadFile, ToolEditFile, ToolWebFetch})
+	if len(tools) != 2 || tools[0].OfBashTool20250124 == nil || tools[1].OfTextEditor20250728 == nil {
+		t.Errorf("tools = %+v", tools)
+	}
+	if len(remaining) != 1 || remaining[0] != ToolWebFetch {
+		t.Errorf("remaining = %v", remaining)
+	}
+	if !native.covers(ToolEditFile) || !native.covers(ToolListDirectory) || native.covers(ToolWriteFile) {
+		t.Errorf("replaced = %v", native.replaced)
+	}
+
+	tools, _, _ = anthropicNativeToolSet("claude-3-7-sonnet-latest", []string{ToolWriteFile})
+	if len(tools) != 1 || tools[0].OfTextEditor20250124 == nil {
+		t.Errorf("legacy tools = %+v", tools)
+	}
+}
+
+func TestAnthropicNativeTranslate(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, native := anthropicNativeToolSet("claude-opus-4-1", []string{ToolShell, ToolReadFile, ToolEditFile})
+
+	if call := native.translate(ToolShell, map[string]interface{}{}); call != nil {
+		t.Error("a gllm tool is translated")
+	}
+	call := native.translate(anthropicBashTool, map[string]interface{}{"command": "ls"})
+	if call.tool != ToolShell || call.args["command"] != "ls" || call.args["purpose"] == nil || call.result != "" {
+		t.Errorf("bash = %+v", call)
+	}
+	if call = native.translate(anthropicBashTool, map[string]interface{}{"restart": true}); call.result == "" {
+		t.Error("restart is not answered")
+	}
+
+	call = native.translate(anthropicTextEditorTool, `{"command":"view","path":"`+path+`","view_range":[2,-1]}`)
+	if call.tool != ToolReadFile || call.args["start_line"] != 2 || call.args["end_line"] != nil {
+		t.Errorf("view = %+v", call)
+	}
+	// The directory is only listed once the call is prepared
+	call = native.translate(anthropicTextEditorTool, map[string]interface{}{"command": "view", "path": dir})
+	if call.tool != ToolReadFile {
+		t.Errorf("view of a directory before prepare = %s", call.tool)
+	}
+	if result := call.prepare(); result != "" || call.tool != ToolListDirectory {
+		t.Errorf("view of a directory = %s %q", call.tool, result)
+	}
+	call = native.translate(anthropicTextEditorTool, map[string]interface{}{"command": "str_replace", "path": path, "old_str": "one", "new_str": "1"})
+	edits, _ := call.args["edits"].([]interface{})
+	if call.tool != ToolEditFile || len(edits) != 1 {
+		t.Errorf("str_replace = %+v", call)
+	}
+	// write_file is not enabled
+	if call = native.translate(anthropicTextEditorTool, map[string]interface{}{"command": "create", "path": path, "file_text": "x"}); !strings.HasPrefix(call.result, "Error:") {
+		t.Errorf("create = %q", call.result)
+	}
+}
+
+func TestAnthropicNativeInsertAndUndo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, _, native := anthropicNativeToolSet("claude-opus-4-1", []string{ToolWriteFile})
+
+	// Nothing is read before the call is prepared
+	call := native.translate(anthropicTextEditorTool, map[string]interface{}{"command": "insert", "path": path, "insert_line": 1.0, "insert_text": "new"})
+	if call.tool != ToolWriteFile || call.args["content"] != "new" {
+		t.Errorf("insert = %+v", call)
+	}
+	if result := call.prepare(); result != "" || call.args["content"] != "one\nnew\ntwo\n" {
+		t.Errorf("prepared insert = %v %q", call.args, result)
+	}
+
+	call = native.translate(anthropicTextEditorTool, map[string]interface{}{"command": "undo_edit", "path": path})
+	if result := call.prepare(); !strings.HasPrefix(result, "Error:") {
+		t.Errorf("undo_edit without an edit = %q", result)
+	}
+	if err := writeFileAtomic(path, []byte("one\nnew\ntwo\n")); err != nil {
+		t.Fatal(err)
+	}
+	call = native.translate(anthropicTextEditorTool, map[string]interface{}{"command": "undo_edit", "path": path})
+	if result := call.prepare(); result != "" || call.tool != ToolWriteFile || call.args["content"] != "one\ntwo\n" {
+		t.Errorf("undo_edit = %+v %q", call, result)
+	}
+	// Writing the backup back undoes the edit, there is nothing left to undo
+	if err := writeFileAtomic(path, []byte(call.args["content"].(string))); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := lastFileBackup(path); ok {
+		t.Error("the undone edit is still backed up")
+	}
+}
+
+func TestInsertLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(path, []byte("one\ntwo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for line, want := range map[int]string{
+		0: "new\none\ntwo",
+		1: "one\nnew\ntwo",
+		2: "one\ntwo\nnew\n",
+	} {
+		got, result := insertLines(path, line, "new")
+		if result != "" || got != want {
+			t.Errorf("insert after %d = %q %q", line, got, result)
+		}
+	}
+	if _, result := insertLines(path, 3, "new"); !strings.Contains(result, "out of range") {
+		t.Errorf("result = %q", result)
+	}
+}
+
+func TestAnthropicCodeExecutionText(t *testing.T) {
+	if code := anthropicServerToolText("bash_code_execution", `{"command":"ls -l\n"}`); code != "\n```bash\nls -l\n```\n" {
+		t.Errorf("code = %q", code)
+	}
+	output := anthropicCodeResultText(`{"type":"bash_code_execution_tool_result","content":{"type":"bash_code_execution_result","stdout":"2\n","stderr":"","return_code":0}}`)
+	if output != "\nOutput:\n```\n2\n```\n" {
+		t.Errorf("output = %q", output)
+	}
+	failed := anthropicCodeResultText(`{"content":{"stdout":"","stderr":"boom","return_code":1}}`)
+	if !strings.Contains(failed, "exit code 1") || !strings.Contains(failed, "boom") {
+		t.Errorf("failed = %q", failed)
+	}
+
+	messages := []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("hi"), anthropic.NewContainerUploadBlock("file_1"))}
+	if !hasContainerUploads(messages) || hasContainerUploads(messages[:0]) {
+		t.Error("container uploads not found")
+	}
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/internal/event"
+	"github.com/activebook/gllm/util"
+	"github.com/google/uuid"
+)
+
+// Approval backends decide on tool calls when no one can answer a prompt.
+const (
+	ApprovalBackendPrompt  = "prompt"  // Ask in the terminal; without one, only the policy approves (default)
+	ApprovalBackendPolicy  = "policy"  // Approve what the policy allows, deny the rest
+	ApprovalBackendFile    = "file"    // Write a request file and wait for a decision file
+	ApprovalBackendWebhook = "webhook" // Post the request to a URL and use its answer
+	ApprovalBackendPlugin  = "plugin"  // Send the request to a plugin and use its answer
+)
+
+// SupportedApprovalBackends lists the values accepted for the approval backend.
+var SupportedApprovalBackends = []string{ApprovalBackendPrompt, ApprovalBackendPolicy, ApprovalBackendFile, ApprovalBackendWebhook, ApprovalBackendPlugin}
+
+// DefaultApprovalTimeout is how long the file, webhook and plugin backends wait for a decision.
+const DefaultApprovalTimeout = 10 * time.Minute
+
+// approvalPollInterval is how often the file backend looks for a decision.
+var approvalPollInterval = time.Second
+
+// ValidateApprovalSettings checks the backend and the settings it needs.
+func ValidateApprovalSettings(settings data.ApprovalSettings) error {
+	switch settings.Backend {
+	case "", ApprovalBackendPrompt, ApprovalBackendPolicy, ApprovalBackendFile:
+	case ApprovalBackendWebhook:
+		if !strings.HasPrefix(settings.Webhook, "http://") && !strings.HasPrefix(settings.Webhook, "https://") {
+			return fmt.Errorf("the webhook backend needs an http(s) URL, set it with --webhook")
+		}
+	case ApprovalBackendPlugin:
+		if settings.Plugin == "" {
+			return fmt.Errorf("the plugin backend needs a plugin, set it with --plugin")
+		}
+	default:
+		return fmt.Errorf("unknown approval backend '%s', expected one of: %s", settings.Backend, strings.Join(SupportedApprovalBackends, ", "))
+	}
+	if settings.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	return nil
+}
+
+// ApprovalHandler answers tool confirmations without a terminal: the policy approves the tools
+// and commands it allows, the backend decides on the rest. Other interactions go to the
+// wrapped handler. Each decision is noted in the audit log as the approver.
+type ApprovalHandler struct {
+	InteractionHandler
+	settings data.ApprovalSettings
+	policy   *data.ToolGrants
+	session  string
+	agent    string
+	client   *http.Client
+	sink     Sink // Where the file backend announces pending requests, may be nil
+}
+
+// NewApprovalHandler creates the handler for the settings, wrapping next for the other interactions.
+func NewApprovalHandler(settings data.ApprovalSettings, next InteractionHandler, session, agent string) *ApprovalHandler {
+	policy := data.NewToolGrants()
+	for _, name := range settings.AllowTools {
+		policy.AllowTool(name)
+	}
+	for _, prefix := range settings.AllowCommands {
+		policy.AllowCommandPrefix(prefix)
+	}
+	if next == nil {
+		next = HeadlessInteractionHandler{}
+	}
+	h := &ApprovalHandler{InteractionHandler: next, settings: settings, policy: policy, session: session, agent: agent}
+	h.client = &http.Client{Timeout: h.timeout()}
+	return h
+}
+
+// SetSink makes the file backend post pending requests to the sink, so that someone sees them.
+func (h *ApprovalHandler) SetSink(sink Sink) {
+	h.sink = sink
+}
+
+func (h *ApprovalHandler) timeout() time.Duration {
+	if h.settings.Timeout > 0 {
+		return time.Duration(h.settings.Timeout) * time.Second
+	}
+	return DefaultApprovalTimeout
+}
+
+// RequestConfirm decides on a tool call without asking in the terminal.
+func (h *ApprovalHandler) RequestConfirm(description string, toolsUse *data.ToolsUse) {
+	if toolsUse.AutoApprove {
+		toolsUse.Confirm = data.ToolConfirmYes
+		return
+	}
+	if h.policy.Allows(toolsUse.Tool, toolsUse.Command) {
+		toolsUse.ConfirmOnce()
+		toolsUse.Approver = ApprovalBackendPolicy
+		return
+	}
+
+	req := data.ApprovalRequest{
+		ID:          uuid.New().String()[:8],
+		Time:        time.Now(),
+		Session:     h.session,
+		Agent:       h.agent,
+		Tool:        toolsUse.Tool,
+		Command:     toolsUse.Command,
+		Description: description,
+	}
+
+	ctx := toolsUse.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var decision data.ApprovalDecision
+	var err error
+	approver := h.settings.Backend
+	switch approver {
+	case ApprovalBackendFile:
+		decision, err = h.askFile(ctx, req)
+	case ApprovalBackendWebhook:
+		decision, err = h.askWebhook(ctx, req)
+	case ApprovalBackendPlugin:
+		decision, err = h.askPlugin(ctx, req)
+	default:
+		decision = data.ApprovalDecision{Feedback: fmt.Sprintf("%s is not allowed by the approval policy and no user is available to approve it.", toolLabel(req))}
+		approver = ApprovalBackendPolicy
+	}
+	// The turn was cancelled while waiting, it stops rather than telling the model
+	if ctx.Err() != nil {
+		toolsUse.ConfirmCancel()
+		toolsUse.Approver = approver
+		return
+	}
+	if err != nil {
+		util.LogWarnf("Approval of %s failed: %v\n", toolLabel(req), err)
+		decision = data.ApprovalDecision{Feedback: fmt.Sprintf("%s was not approved: %v", toolLabel(req), err)}
+	}
+
+	if decision.Approve {
+		toolsUse.ConfirmOnce()
+	} else {
+		toolsUse.ConfirmDeny(decision.Feedback)
+	}
+	toolsUse.Approver = approver
+}
+
+// askFile writes the request to the approval directory and waits for a decision file.
+func (h *ApprovalHandler) askFile(ctx context.Context, req data.ApprovalRequest) (data.ApprovalDecision, error) {
+	if err := data.WriteApprovalRequest(h.settings.Dir, req); err != nil {
+		return data.ApprovalDecision{}, err
+	}
+	defer data.RemoveApproval(h.settings.Dir, req.ID)
+	util.LogInfof("Waiting for approval of %s, answer with: gllm config approval approve %s (or deny %s)\n", toolLabel(req), req.ID, req.ID)
+	if h.sink != nil {
+		title := fmt.Sprintf("Approval needed for %s", toolLabel(req))
+		text := fmt.Sprintf("Agent %s asks to:\n%s\n\nAnswer with `gllm config approval approve %s` (or `deny %s`) within %s.",
+			req.Agent, req.Description, req.ID, req.ID, h.timeout())
+		postCtx, cancel := context.WithTimeout(ctx, sinkRequestTimeout)
+		if err := h.sink.Post(postCtx, title, text); err != nil {
+			util.LogWarnf("Failed to post the approval request to %s: %v\n", h.sink, err)
+		}
+		cancel()
+	}
+
+	deadline := time.Now().Add(h.timeout())
+	for {
+		if decision, ok := data.ReadApprovalDecision(h.settings.Dir, req.ID); ok {
+			return decision, nil
+		}
+		if time.Now().After(deadline) {
+			return data.ApprovalDecision{}, fmt.Errorf("no decision within %s", h.timeout())
+		}
+		select {
+		case <-ctx.Done():
+			return data.ApprovalDecision{}, ctx.Err()
+		case <-time.After(approvalPollInterval):
+		}
+	}
+}
+
+// askWebhook posts the request to the webhook, which answers with an approval decision.
+func (h *ApprovalHandler) askWebhook(ctx context.Context, req data.ApprovalRequest) (data.ApprovalDecision, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return data.ApprovalDecision{}, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, h.settings.Webhook, bytes.NewReader(body))
+	if err != nil {
+		return data.ApprovalDecision{}, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		return data.ApprovalDecision{}, err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return data.ApprovalDecision{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return data.ApprovalDecision{}, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	var decision data.ApprovalDecision
+	if err := json.Unmarshal(content, &decision); err != nil {
+		return data.ApprovalDecision{}, fmt.Errorf("invalid webhook answer: %w", err)
+	}
+	return decision, nil
+}
+
+// askPlugin sends the request to the approval plugin, which answers with a decision.
+func (h *ApprovalHandler) askPlugin(ctx context.Context, req data.ApprovalRequest) (data.ApprovalDecision, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout())
+	defer cancel()
+	client, err := GetPluginClient(ctx, h.settings.Plugin)
+	if err != nil {
+		return data.ApprovalDecision{}, err
+	}
+	return client.RequestApproval(ctx, req)
+}
+
+// toolLabel names the call of a request for messages.
+func toolLabel(req data.ApprovalRequest) string {
+	switch {
+	case req.Command != "":
+		return fmt.Sprintf("the command '%s'", req.Command)
+	case req.Tool != "":
+		return fmt.Sprintf("the tool %s", req.Tool)
+	default:
+		return "the request"
+	}
+}
+
+// HeadlessInteractionHandler is used when no one can answer: questions to the user fail
+// instead of waiting for a terminal, diffs are rendered as usual.
+type HeadlessInteractionHandler struct {
+	DefaultInteractionHandler
+}
+
+func (HeadlessInteractionHandler) RequestConfirm(description string, toolsUse *data.ToolsUse) {
+	if toolsUse.AutoApprove {
+		toolsUse.Confirm = data.ToolConfirmYes
+		return
+	}
+	toolsUse.ConfirmCancel()
+}
+
+// errNoUser is returned by the interactions that can't reach anyone to answer.
+var errNoUser = errors.New("no user is available to answer")
+
+func (HeadlessInteractionHandler) RequestAskUser(req event.AskUserRequest) (event.AskUserResponse, error) {
+	return event.AskUserResponse{Cancelled: true}, errNoUser
+}
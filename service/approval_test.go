@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/internal/event"
+)
+
+func TestApprovalHandlerPolicy(t *testing.T) {
+	h := NewApprovalHandler(data.ApprovalSettings{
+		AllowTools:    []string{ToolReadFile},
+		AllowCommands: []string{"go test"},
+	}, nil, "s1", "coder")
+
+	tu := &data.ToolsUse{Tool: ToolShell, Command: "go test ./..."}
+	h.RequestConfirm("run tests", tu)
+	if tu.Confirm != data.ToolConfirmYes || tu.Approver != ApprovalBackendPolicy {
+		t.Errorf("allowed command: %+v", tu)
+	}
+
+	tu = &data.ToolsUse{Tool: ToolShell, Command: "rm -rf build"}
+	h.RequestConfirm("clean", tu)
+	if tu.Confirm != data.ToolConfirmCancel || !strings.Contains(tu.Feedback, "rm -rf build") || tu.Approver != ApprovalBackendPolicy {
+		t.Errorf("command outside the policy: %+v", tu)
+	}
+
+	if _, err := h.RequestAskUser(event.AskUserRequest{Question: "which branch?"}); err == nil {
+		t.Error("asking the user must fail without one")
+	}
+}
+
+func TestApprovalHandlerFile(t *testing.T) {
+	defer func(d time.Duration) { approvalPollInterval = d }(approvalPollInterval)
+	approvalPollInterval = 10 * time.Millisecond
+	dir := t.TempDir()
+	h := NewApprovalHandler(data.ApprovalSettings{Backend: ApprovalBackendFile, Dir: dir, Timeout: 5}, nil, "", "")
+
+	// Answer the request as soon as it shows up
+	go func() {
+		for {
+			requests, _ := data.ListApprovalRequests(dir)
+			if len(requests) > 0 {
+				data.WriteApprovalDecision(dir, requests[0].ID, data.ApprovalDecision{Feedback: "use -short"})
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	tu := &data.ToolsUse{Tool: ToolShell, Command: "go test ./..."}
+	h.RequestConfirm("run tests", tu)
+	if tu.Confirm != data.ToolConfirmCancel || tu.Feedback != "use -short" || tu.Approver != ApprovalBackendFile {
+		t.Errorf("file decision: %+v", tu)
+	}
+	if requests, _ := data.ListApprovalRequests(dir); len(requests) != 0 {
+		t.Errorf("answered requests must be removed, found %d", len(requests))
+	}
+}
+
+func TestApprovalHandlerCancel(t *testing.T) {
+	defer func(d time.Duration) { approvalPollInterval = d }(approvalPollInterval)
+	approvalPollInterval = 10 * time.Millisecond
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-block:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(block)
+
+	for _, settings := range []data.ApprovalSettings{
+		{Backend: ApprovalBackendFile, Dir: t.TempDir()},
+		{Backend: ApprovalBackendWebhook, Webhook: srv.URL},
+	} {
+		h := NewApprovalHandler(settings, nil, "", "")
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		tu := &data.ToolsUse{Tool: ToolShell, Command: "go test ./...", Ctx: ctx}
+		start := time.Now()
+		h.RequestConfirm("run tests", tu)
+		cancel()
+		if time.Since(start) > 2*time.Second {
+			t.Errorf("%s: a cancelled turn kept waiting for the approval", settings.Backend)
+		}
+		if tu.Confirm != data.ToolConfirmCancel || tu.Feedback != "" {
+			t.Errorf("%s: a cancelled approval must cancel the call: %+v", settings.Backend, tu)
+		}
+	}
+}
+
+func TestApprovalHandlerWebhook(t *testing.T) {
+	var got data.ApprovalRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		json.NewEncoder(w).Encode(data.ApprovalDecision{Approve: got.Tool == ToolWriteFile})
+	}))
+	defer srv.Close()
+
+	settings := data.ApprovalSettings{Backend: ApprovalBackendWebhook, Webhook: srv.URL}
+	if err := ValidateApprovalSettings(settings); err != nil {
+		t.Fatal(err)
+	}
+	h := NewApprovalHandler(settings, nil, "s1", "coder")
+	tu := &data.ToolsUse{Tool: ToolWriteFile}
+	h.RequestConfirm("write main.go", tu)
+	if tu.Confirm != data.ToolConfirmYes || tu.Approver != ApprovalBackendWebhook {
+		t.Errorf("webhook approval: %+v", tu)
+	}
+	if got.Agent != "coder" || got.Description != "write main.go" {
+		t.Errorf("webhook request = %+v", got)
+	}
+
+	// An unreachable webhook denies
+	srv.Close()
+	tu = &data.ToolsUse{Tool: ToolWriteFile}
+	h.RequestConfirm("write main.go", tu)
+	if tu.Confirm != data.ToolConfirmCancel || tu.Feedback == "" {
+		t.Errorf("failed webhook: %+v", tu)
+	}
+
+	if err := ValidateApprovalSettings(data.ApprovalSettings{Backend: ApprovalBackendWebhook}); err == nil {
+		t.Error("the webhook backend needs a URL")
+	}
+}
+
+// scriptedInteraction answers the questions of a test in order.
+type scriptedInteraction struct {
+	DefaultInteractionHandler
+	answers   []string
+	questions []string
+}
+
+func (s *scriptedInteraction) RequestAskUser(req event.AskUserRequest) (event.AskUserResponse, error) {
+	s.questions = append(s.questions, req.Question)
+	answer := s.answers[0]
+	s.answers = s.answers[1:]
+	return event.AskUserResponse{Answer: answer}, nil
+}
+
+func TestRequestApproval(t *testing.T) {
+	args := map[string]interface{}{"action": "Publish the release notes", "content": "v1.4 fixes the login bug"}
+	// YOLO mode does not skip the approval
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}}
+
+	if _, err := requestApprovalToolCallImpl(context.Background(), &args, op); err == nil {
+		t.Error("expected an error without anyone to approve")
+	}
+
+	ui := &scriptedInteraction{answers: []string{approvalChoiceEdit, "Mention the new flag"}}
+	op.interaction = ui
+	out, err := requestApprovalToolCallImpl(context.Background(), &args, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `"approved":true`) || !strings.Contains(out, "Mention the new flag") {
+		t.Errorf("unexpected approval with edits: %s", out)
+	}
+	if !strings.Contains(ui.questions[0], "v1.4 fixes the login bug") {
+		t.Errorf("the content was not presented: %q", ui.questions[0])
+	}
+
+	op.interaction = &scriptedInteraction{answers: []string{approvalChoiceReject, "Wait for QA"}}
+	if out, _ := requestApprovalToolCallImpl(context.Background(), &args, op); !strings.Contains(out, `"approved":false`) || !strings.Contains(out, "Wait for QA") {
+		t.Errorf("unexpected rejection: %s", out)
+	}
+
+	// Headless, the backend decides
+	op.interaction = NewApprovalHandler(data.ApprovalSettings{}, nil, "s1", "writer")
+	if out, _ := requestApprovalToolCallImpl(context.Background(), &args, op); !strings.Contains(out, `"approved":false`) || !strings.Contains(out, `"approver":"policy"`) {
+		t.Errorf("expected the policy to deny: %s", out)
+	}
+	op.interaction = NewApprovalHandler(data.ApprovalSettings{AllowTools: []string{ToolRequestApproval}}, nil, "s1", "writer")
+	if out, _ := requestApprovalToolCallImpl(context.Background(), &args, op); !strings.Contains(out, `"approved":true`) {
+		t.Errorf("expected the policy to approve: %s", out)
+	}
+}
+
+func TestAskUserHeadless(t *testing.T) {
+	args := map[string]interface{}{"question": "Which branch?", "question_type": "text"}
+	for _, op := range []*OpenProcessor{
+		{toolsUse: &data.ToolsUse{}},
+		{toolsUse: &data.ToolsUse{}, interaction: NewApprovalHandler(data.ApprovalSettings{}, nil, "", "")},
+	} {
+		out, err := askUserToolCallImpl(context.Background(), &args, op)
+		if err != nil || out != askUserHeadlessNote {
+			t.Errorf("headless ask_user = %q, %v", out, err)
+		}
+	}
+
+	ui := &scriptedInteraction{answers: []string{"main"}}
+	out, err := askUserToolCallImpl(context.Background(), &args, &OpenProcessor{toolsUse: &data.ToolsUse{}, interaction: ui})
+	if err != nil || out != `{"answer":"main"}` || ui.questions[0] != "Which branch?" {
+		t.Errorf("ask_user = %q, %v", out, err)
+	}
+
+	args = map[string]interface{}{"question": "Which one?", "question_type": "select"}
+	if out, _ := askUserToolCallImpl(context.Background(), &args, &OpenProcessor{toolsUse: &data.ToolsUse{}, interaction: ui}); !strings.HasPrefix(out, "Error: options are required") {
+		t.Errorf("select without options = %q", out)
+	}
+}
+
+func TestPresentOptions(t *testing.T) {
+	args := map[string]interface{}{
+		"question": "Which refactor strategy do you prefer?",
+		"options": []interface{}{
+			map[string]interface{}{"title": "Extract an interface", "summary": "Put the store behind an interface.", "pros": []interface{}{"Easy to mock"}},
+			map[string]interface{}{"title": "Split the package", "cons": []interface{}{"Many imports change"}},
+		},
+		"recommended": "Split the package",
+	}
+
+	out, err := presentOptionsToolCallImpl(context.Background(), &args, &OpenProcessor{toolsUse: &data.ToolsUse{}})
+	if err != nil || !strings.Contains(out, "recommended option 'Split the package'") {
+		t.Errorf("headless present_options = %q, %v", out, err)
+	}
+
+	ui := &scriptedInteraction{answers: []string{"Split the package (recommended)"}}
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{}, interaction: ui}
+	out, err = presentOptionsToolCallImpl(context.Background(), &args, op)
+	if err != nil || out != `{"choice":"Split the package","index":2}` {
+		t.Errorf("present_options = %q, %v", out, err)
+	}
+	for _, want := range []string{"1. Extract an interface\n   Put the store behind an interface.\n   + Easy to mock", "2. Split the package (recommended)\n   - Many imports change"} {
+		if !strings.Contains(ui.questions[0], want) {
+			t.Errorf("question should contain %q, got:\n%s", want, ui.questions[0])
+		}
+	}
+
+	ui.answers = []string{optionOther, "Keep it as is"}
+	if out, _ := presentOptionsToolCallImpl(context.Background(), &args, op); out != `{"comment":"Keep it as is"}` {
+		t.Errorf("present_options with another approach = %q", out)
+	}
+
+	args["options"] = []interface{}{map[string]interface{}{"title": "Only one"}}
+	if out, _ := presentOptionsToolCallImpl(context.Background(), &args, op); !strings.HasPrefix(out, "Error: at least two options") {
+		t.Errorf("a single option = %q", out)
+	}
+}
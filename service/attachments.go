@@ -0,0 +1,306 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// AttachMaxFileSize is the largest file of a glob or directory attachment.
+	AttachMaxFileSize = 256 * 1024
+	// AttachMaxTotalSize caps the text of a glob or directory attachment.
+	AttachMaxTotalSize = 2 * 1024 * 1024
+	// AttachMaxFiles caps the number of files of a glob or directory attachment.
+	AttachMaxFiles = 500
+)
+
+// AttachedFile is a file of a glob or directory attachment.
+type AttachedFile struct {
+	Path  string // As matched, relative to the working directory when the pattern is
+	Size  int64
+	Lines int
+}
+
+// SkippedFile is a matched file that was left out, and why.
+type SkippedFile struct {
+	Path   string
+	Reason string
+}
+
+// AttachmentSet is a glob or directory attachment, expanded: its text files go into one
+// context block with a manifest, its images and documents are attached on their own.
+type AttachmentSet struct {
+	Pattern string
+	Text    []AttachedFile
+	Media   []*FileData
+	Skipped []SkippedFile
+	content map[string][]byte
+}
+
+// IsAttachmentPattern reports whether an attachment is a glob or a directory to expand.
+func IsAttachmentPattern(attachment string) bool {
+	if attachment == "-" || IsURLAttachment(attachment) {
+		return false
+	}
+	if strings.ContainsAny(attachment, "*?[") {
+		return true
+	}
+	info, err := os.Stat(attachment)
+	return err == nil && info.IsDir()
+}
+
+// ExpandAttachment expands a glob, where ** matches any number of directories, or a
+// directory into its files. Files ignored by git, too large or binary are skipped.
+func ExpandAttachment(pattern string) (*AttachmentSet, error) {
+	set := &AttachmentSet{Pattern: pattern, content: map[string][]byte{}}
+
+	root, glob := splitAttachmentPattern(pattern)
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("nothing matches %s: %w", pattern, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("nothing matches %s: %s is not a directory", pattern, root)
+	}
+
+	paths, err := listAttachmentFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for _, p := range paths {
+		if glob == "" || matchGlobParts(strings.Split(glob, "/"), strings.Split(filepath.ToSlash(p), "/")) {
+			matched = append(matched, p)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+	sort.Strings(matched)
+
+	var total int64
+	for _, p := range matched {
+		if len(set.Text)+len(set.Media) >= AttachMaxFiles {
+			set.Skipped = append(set.Skipped, SkippedFile{Path: p, Reason: fmt.Sprintf("over the limit of %d files", AttachMaxFiles)})
+			continue
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			set.Skipped = append(set.Skipped, SkippedFile{Path: p, Reason: err.Error()})
+			continue
+		}
+		if info.Size() > AttachMaxFileSize {
+			set.Skipped = append(set.Skipped, SkippedFile{Path: p, Reason: fmt.Sprintf("too large (%s, limit %s)", formatAttachSize(info.Size()), formatAttachSize(AttachMaxFileSize))})
+			continue
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			set.Skipped = append(set.Skipped, SkippedFile{Path: p, Reason: err.Error()})
+			continue
+		}
+
+		format := attachmentFormat(p, content)
+		if IsImageMIMEType(format) || IsPDFMIMEType(format) {
+			set.Media = append(set.Media, NewFileData(format, content, p))
+			continue
+		}
+		if !IsTextMIMEType(format) && bytes.IndexByte(content, 0) >= 0 {
+			set.Skipped = append(set.Skipped, SkippedFile{Path: p, Reason: "binary"})
+			continue
+		}
+		if total+info.Size() > AttachMaxTotalSize {
+			set.Skipped = append(set.Skipped, SkippedFile{Path: p, Reason: fmt.Sprintf("over the total limit of %s", formatAttachSize(AttachMaxTotalSize))})
+			continue
+		}
+		total += info.Size()
+		set.content[p] = content
+		set.Text = append(set.Text, AttachedFile{Path: p, Size: info.Size(), Lines: bytes.Count(content, []byte("\n")) + 1})
+	}
+	return set, nil
+}
+
+// splitAttachmentPattern returns the directory to list and the glob the listed paths must
+// match, empty for every file.
+func splitAttachmentPattern(pattern string) (root, glob string) {
+	pattern = filepath.ToSlash(filepath.Clean(pattern))
+	if !strings.ContainsAny(pattern, "*?[") {
+		return filepath.FromSlash(pattern), ""
+	}
+	parts := strings.Split(pattern, "/")
+	i := 0
+	for i < len(parts) && !strings.ContainsAny(parts[i], "*?[") {
+		i++
+	}
+	root = strings.Join(parts[:i], "/")
+	if root == "" && strings.HasPrefix(pattern, "/") {
+		root = "/"
+	} else if root == "" {
+		root = "."
+	}
+	return filepath.FromSlash(root), pattern
+}
+
+// listAttachmentFiles returns the files under a directory that git doesn't ignore. Outside
+// a git repository the .gitignore of the directory is used, and the usual dependency and
+// cache directories are skipped.
+func listAttachmentFiles(root string) ([]string, error) {
+	if files, ok := gitListFiles(root); ok {
+		return files, nil
+	}
+
+	ignore := readGitignore(filepath.Join(root, ".gitignore"))
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if p != root && (watchSkipDirs[d.Name()] || gitignored(ignore, rel, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && !gitignored(ignore, rel, false) {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// gitListFiles lists the tracked and untracked files under a directory that git doesn't
+// ignore, false outside a git repository.
+func gitListFiles(root string) ([]string, bool) {
+	out, err := exec.Command("git", "-C", root, "ls-files", "--cached", "--others", "--exclude-standard", "-z").Output()
+	if err != nil {
+		return nil, false
+	}
+	var files []string
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name == "" {
+			continue
+		}
+		p := filepath.Join(root, filepath.FromSlash(name))
+		// Deleted but still tracked files are listed too
+		if info, err := os.Stat(p); err == nil && info.Mode().IsRegular() {
+			files = append(files, p)
+		}
+	}
+	return files, true
+}
+
+// readGitignore returns the patterns of a .gitignore file, negations are not supported.
+func readGitignore(file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
+
+// gitignored reports whether a path relative to the .gitignore matches one of its patterns.
+func gitignored(patterns []string, rel string, isDir bool) bool {
+	for _, p := range patterns {
+		if strings.HasSuffix(p, "/") {
+			if !isDir {
+				continue
+			}
+			p = strings.TrimSuffix(p, "/")
+		}
+		if strings.HasPrefix(p, "/") {
+			if ok, _ := path.Match(strings.TrimPrefix(p, "/"), rel); ok {
+				return true
+			}
+			continue
+		}
+		if MatchGlob(p, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// attachmentFormat returns the MIME type of a file, like a single attachment gets.
+func attachmentFormat(p string, content []byte) string {
+	if isImage, format, err := CheckIfImageFromBytes(content); err == nil && isImage {
+		return format
+	}
+	format := GetMIMEType(p)
+	if IsUnknownMIMEType(format) {
+		format = GetMIMETypeByContent(content)
+	}
+	return format
+}
+
+func formatAttachSize(size int64) string {
+	if size < 1024 {
+		return fmt.Sprintf("%d B", size)
+	}
+	if size < 1024*1024 {
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	}
+	return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+}
+
+// Manifest lists what the attachment holds, so the model knows what it received.
+func (s *AttachmentSet) Manifest() string {
+	var sb strings.Builder
+	var total int64
+	for _, f := range s.Text {
+		total += f.Size
+	}
+	fmt.Fprintf(&sb, "Attached files from %s: %d text files (%s)", s.Pattern, len(s.Text), formatAttachSize(total))
+	if len(s.Media) > 0 {
+		fmt.Fprintf(&sb, ", %d images and documents attached separately", len(s.Media))
+	}
+	sb.WriteString("\n")
+	for _, f := range s.Text {
+		fmt.Fprintf(&sb, "- %s (%d lines, %s)\n", filepath.ToSlash(f.Path), f.Lines, formatAttachSize(f.Size))
+	}
+	for _, m := range s.Media {
+		fmt.Fprintf(&sb, "- %s (%s)\n", filepath.ToSlash(m.Path()), m.Format())
+	}
+	if len(s.Skipped) > 0 {
+		sb.WriteString("Skipped:\n")
+		for _, f := range s.Skipped {
+			fmt.Fprintf(&sb, "- %s: %s\n", filepath.ToSlash(f.Path), f.Reason)
+		}
+	}
+	return sb.String()
+}
+
+// FileData returns the attachment as files for the model: one text block with the manifest
+// and the text files, then the images and documents.
+func (s *AttachmentSet) FileData() []*FileData {
+	var sb strings.Builder
+	sb.WriteString(s.Manifest())
+	for _, f := range s.Text {
+		fmt.Fprintf(&sb, "\n<file path=%q>\n%s", filepath.ToSlash(f.Path), s.content[f.Path])
+		if !bytes.HasSuffix(s.content[f.Path], []byte("\n")) {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("</file>\n")
+	}
+	files := []*FileData{NewFileData("text/plain", []byte(sb.String()), s.Pattern)}
+	return append(files, s.Media...)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandAttachment(t *testing.T) {
+	root := t.TempDir()
+	write := func(name, content string) {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(".gitignore", "gen/\n*.log\n")
+	write("main.go", "package main\n")
+	write("pkg/a/a.go", "package a\n\nfunc A() {}\n")
+	write("pkg/a/a_test.txt", "notes\n")
+	write("gen/z.go", "package gen\n")
+	write("debug.log", "noise\n")
+	write("node_modules/x/index.go", "package x\n")
+	write("pkg/big.go", strings.Repeat("x", AttachMaxFileSize+1))
+	write("pkg/blob.bin", "a\x00b")
+
+	set, err := ExpandAttachment(filepath.Join(root, "**", "*.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range set.Text {
+		rel, _ := filepath.Rel(root, f.Path)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	if strings.Join(got, ",") != "main.go,pkg/a/a.go" {
+		t.Errorf("unexpected files: %v", got)
+	}
+	if len(set.Skipped) != 1 || !strings.Contains(set.Skipped[0].Reason, "too large") {
+		t.Errorf("unexpected skipped files: %+v", set.Skipped)
+	}
+
+	// A directory gets every file, binary ones are skipped
+	set, err = ExpandAttachment(filepath.Join(root, "pkg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Text) != 2 || len(set.Skipped) != 2 {
+		t.Errorf("unexpected expansion of the directory: %+v", set)
+	}
+
+	files := set.FileData()
+	block := string(files[0].Data())
+	if !strings.HasPrefix(block, "Attached files from "+filepath.Join(root, "pkg")+": 2 text files") {
+		t.Errorf("the block misses the manifest: %q", block)
+	}
+	for _, want := range []string{"a/a.go (4 lines", "blob.bin: binary", `<file path="` + filepath.ToSlash(filepath.Join(root, "pkg", "a", "a.go")) + `">` + "\npackage a\n"} {
+		if !strings.Contains(block, want) {
+			t.Errorf("the block misses %q:\n%s", want, block)
+		}
+	}
+
+	if _, err := ExpandAttachment(filepath.Join(root, "*.rs")); err == nil {
+		t.Errorf("expected an error when nothing matches")
+	}
+}
+
+func TestIsAttachmentPattern(t *testing.T) {
+	dir := t.TempDir()
+	for attachment, want := range map[string]bool{
+		"src/**/*.go":             true,
+		dir:                       true,
+		"main.go":                 false,
+		"-":                       false,
+		"https://example.com/a?b": false,
+	} {
+		if got := IsAttachmentPattern(attachment); got != want {
+			t.Errorf("IsAttachmentPattern(%q) = %v", attachment, got)
+		}
+	}
+}
+
+func TestExpandAttachmentInGitRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := t.TempDir()
+	if out, err := exec.Command("git", "-C", root, "init", "-q").CombinedOutput(); err != nil {
+		t.Skipf("git init failed: %s", out)
+	}
+	os.MkdirAll(filepath.Join(root, "docs", "drafts"), 0750)
+	os.WriteFile(filepath.Join(root, ".gitignore"), []byte("drafts/\n"), 0644)
+	os.WriteFile(filepath.Join(root, "docs", "guide.md"), []byte("# Guide\n"), 0644)
+	os.WriteFile(filepath.Join(root, "docs", "drafts", "wip.md"), []byte("# WIP\n"), 0644)
+
+	set, err := ExpandAttachment(filepath.Join(root, "docs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(set.Text) != 1 || filepath.Base(set.Text[0].Path) != "guide.md" {
+		t.Errorf("the files ignored by git were attached: %+v", set.Text)
+	}
+}
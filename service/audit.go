@@ -0,0 +1,167 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/openai/openai-go/v3"
+	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+	"google.golang.org/genai"
+)
+
+const (
+	auditMaxArgLength    = 500
+	auditMaxResultLength = 300
+)
+
+// toolAudit tracks one tool invocation until it is written to the audit log.
+type toolAudit struct {
+	op          *OpenProcessor
+	entry       data.AuditEntry
+	autoApprove bool
+	prompted    bool
+	interaction InteractionHandler // Handler to restore once the tool is done
+}
+
+// auditedInteraction notes whether the tool asked for approval.
+type auditedInteraction struct {
+	InteractionHandler
+	audit *toolAudit
+}
+
+func (h auditedInteraction) RequestConfirm(description string, toolsUse *data.ToolsUse) {
+	h.audit.prompted = true
+	toolsUse.Approver = ""
+	h.InteractionHandler.RequestConfirm(description, toolsUse)
+	h.audit.entry.Approver = toolsUse.Approver
+}
+
+// beginToolAudit starts tracking a tool call, finish it with end.
+func (op *OpenProcessor) beginToolAudit(name string, args map[string]interface{}) *toolAudit {
+	a := &toolAudit{
+		op: op,
+		entry: data.AuditEntry{
+			Time:    time.Now(),
+			Session: op.sessionName,
+			Agent:   op.agentName,
+			Tool:    name,
+			Args:    auditArgs(args),
+		},
+		autoApprove: op.toolsUse != nil && op.toolsUse.AutoApprove,
+		interaction: op.interaction,
+	}
+	if op.interaction != nil {
+		op.interaction = auditedInteraction{InteractionHandler: op.interaction, audit: a}
+	}
+	return a
+}
+
+// end writes the tool call to the audit log. Failing to log never fails the tool call.
+func (a *toolAudit) end(result string, err error) {
+	a.op.interaction = a.interaction
+
+	a.entry.DurationMs = time.Since(a.entry.Time).Milliseconds()
+	a.entry.Approval = a.approval(err)
+	a.entry.Result = truncateAuditText(result, auditMaxResultLength)
+	if err != nil {
+		a.entry.Error = err.Error()
+	}
+	if logErr := data.AppendAuditEntry(a.entry); logErr != nil {
+		util.LogWarnf("Failed to write audit log: %v\n", logErr)
+	}
+}
+
+func (a *toolAudit) approval(err error) string {
+	var cancelErr UserCancelError
+	switch {
+	case a.prompted && a.op.toolsUse != nil && a.op.toolsUse.Confirm == data.ToolConfirmCancel:
+		return data.AuditApprovalDenied
+	case errors.As(err, &cancelErr) && cancelErr.Reason == UserCancelReasonDeny:
+		return data.AuditApprovalDenied
+	case a.prompted:
+		return data.AuditApprovalApproved
+	case err != nil && strings.HasPrefix(err.Error(), toolPermissionDenied):
+		return data.AuditApprovalBlocked
+	case a.autoApprove:
+		return data.AuditApprovalAuto
+	default:
+		return data.AuditApprovalNotRequired
+	}
+}
+
+// auditArgs copies the arguments, shortening long values such as file contents.
+func auditArgs(args map[string]interface{}) map[string]interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	out := make(map[string]interface{}, len(args))
+	for k, v := range args {
+		switch val := v.(type) {
+		case string:
+			out[k] = truncateAuditText(val, auditMaxArgLength)
+		case []interface{}, map[string]interface{}:
+			// Nested values such as edit batches can hold whole files
+			encoded, _ := json.Marshal(val)
+			if len(encoded) > auditMaxArgLength {
+				out[k] = truncateAuditText(string(encoded), auditMaxArgLength)
+			} else {
+				out[k] = v
+			}
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func truncateAuditText(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "…"
+}
+
+// Tool result text of each provider's tool message, for the audit log
+
+func openAIToolResultText(msg openai.ChatCompletionMessageParamUnion) string {
+	if msg.OfTool == nil {
+		return ""
+	}
+	return msg.OfTool.Content.OfString.Value
+}
+
+func openChatToolResultText(msg *model.ChatCompletionMessage) string {
+	if msg == nil || msg.Content == nil || msg.Content.StringValue == nil {
+		return ""
+	}
+	return *msg.Content.StringValue
+}
+
+func anthropicToolResultText(msg anthropic.MessageParam) string {
+	var sb strings.Builder
+	for _, block := range msg.Content {
+		if block.OfToolResult == nil {
+			continue
+		}
+		for _, c := range block.OfToolResult.Content {
+			if c.OfText != nil {
+				sb.WriteString(c.OfText.Text)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func geminiToolResultText(resp *genai.FunctionResponse) string {
+	if resp == nil {
+		return ""
+	}
+	output, _ := resp.Response["output"].(string)
+	return output
+}
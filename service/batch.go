@@ -0,0 +1,426 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// BatchStatus is the progress of a submitted batch.
+type BatchStatus struct {
+	Status    string // Status reported by the provider
+	Total     int
+	Succeeded int
+	Failed    int  // Errored, expired or cancelled jobs
+	Done      bool // Whether the results can be fetched
+}
+
+// BatchResult is the outcome of one job of a batch, a line of the results file.
+type BatchResult struct {
+	ID           string `json:"id"`
+	Output       string `json:"output,omitempty"`
+	Error        string `json:"error,omitempty"`
+	InputTokens  int    `json:"input_tokens,omitempty"`
+	OutputTokens int    `json:"output_tokens,omitempty"`
+}
+
+// anthropicCustomID is the form of the job IDs the Anthropic batch API accepts.
+var anthropicCustomID = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// SubmitBatch submits the jobs to the batch API of the model's provider and returns the ID
+// of the batch and the provider. Only OpenAI and Anthropic have one.
+func SubmitBatch(ctx context.Context, model *data.Model, jobs []data.BatchJob) (string, string, error) {
+	mi := constructModelInfo(model)
+	var id string
+	var err error
+	switch mi.Provider {
+	case ModelProviderOpenAI:
+		id, err = submitOpenAIBatch(ctx, mi, jobs)
+	case ModelProviderAnthropic:
+		id, err = submitAnthropicBatch(ctx, mi, jobs)
+	default:
+		return "", "", fmt.Errorf("batches need an openai or anthropic model, '%s' is a %s model", model.Name, mi.Provider)
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to submit the batch to %s: %w", mi.Provider, err)
+	}
+	return id, mi.Provider, nil
+}
+
+// GetBatchStatus asks the provider how far the batch has got.
+func GetBatchStatus(ctx context.Context, model *data.Model, id string) (*BatchStatus, error) {
+	mi := constructModelInfo(model)
+	switch mi.Provider {
+	case ModelProviderOpenAI:
+		return openAIBatchStatus(ctx, mi, id)
+	case ModelProviderAnthropic:
+		return anthropicBatchStatus(ctx, mi, id)
+	}
+	return nil, fmt.Errorf("batches need an openai or anthropic model, '%s' is a %s model", model.Name, mi.Provider)
+}
+
+// FetchBatchResults downloads the results of a batch that is done.
+func FetchBatchResults(ctx context.Context, model *data.Model, id string) ([]BatchResult, error) {
+	mi := constructModelInfo(model)
+	switch mi.Provider {
+	case ModelProviderOpenAI:
+		return fetchOpenAIBatch(ctx, mi, id)
+	case ModelProviderAnthropic:
+		return fetchAnthropicBatch(ctx, mi, id)
+	}
+	return nil, fmt.Errorf("batches need an openai or anthropic model, '%s' is a %s model", model.Name, mi.Provider)
+}
+
+// WorkflowBatchJobs runs each prompt through a workflow, as the workflow command does with
+// its arguments.
+func WorkflowBatchJobs(content string, jobs []data.BatchJob) []data.BatchJob {
+	out := make([]data.BatchJob, len(jobs))
+	for i, job := range jobs {
+		job.Prompt = content + "\n" + job.Prompt
+		out[i] = job
+	}
+	return out
+}
+
+func newOpenAIBatchClient(mi *ModelInfo) (openai.Client, error) {
+	opts := []option.RequestOption{option.WithAPIKey(mi.ApiKey)}
+	if mi.EndPoint != "" {
+		opts = append(opts, option.WithBaseURL(mi.EndPoint))
+	}
+	// Batches are created and polled, a cached answer would bring back an old batch
+	hc, err := uncachedProviderHTTPClient(mi)
+	if err != nil {
+		return openai.Client{}, err
+	}
+	if hc != nil {
+		opts = append(opts, option.WithHTTPClient(hc))
+	}
+	return openai.NewClient(opts...), nil
+}
+
+// openAIBatchInput renders the jobs as the input file of the batch API, one chat completion
+// request per line.
+func openAIBatchInput(mi *ModelInfo, jobs []data.BatchJob) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, job := range jobs {
+		var messages []openai.ChatCompletionMessageParamUnion
+		if job.System != "" {
+			messages = append(messages, openai.SystemMessage(job.System))
+		}
+		messages = append(messages, openai.UserMessage(job.Prompt))
+		req := openai.ChatCompletionNewParams{
+			Model:    openai.ChatModel(mi.Model),
+			Messages: messages,
+		}
+		if mi.Temperature != 0 {
+			req.Temperature = openai.Float(float64(mi.Temperature))
+		}
+		if mi.TopP != 0 {
+			req.TopP = openai.Float(float64(mi.TopP))
+		}
+		applyOpenAIGenerationParams(&req, mi)
+		if mi.Seed != nil {
+			req.Seed = openai.Int(int64(*mi.Seed))
+		}
+		line, err := json.Marshal(map[string]interface{}{
+			"custom_id": job.ID,
+			"method":    "POST",
+			"url":       string(openai.BatchNewParamsEndpointV1ChatCompletions),
+			"body":      req,
+		})
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+func submitOpenAIBatch(ctx context.Context, mi *ModelInfo, jobs []data.BatchJob) (string, error) {
+	input, err := openAIBatchInput(mi, jobs)
+	if err != nil {
+		return "", err
+	}
+	client, err := newOpenAIBatchClient(mi)
+	if err != nil {
+		return "", err
+	}
+	file, err := client.Files.New(ctx, openai.FileNewParams{
+		File:    openai.File(bytes.NewReader(input), "batch.jsonl", "application/jsonl"),
+		Purpose: openai.FilePurposeBatch,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload the jobs: %w", err)
+	}
+	batch, err := client.Batches.New(ctx, openai.BatchNewParams{
+		CompletionWindow: openai.BatchNewParamsCompletionWindow24h,
+		Endpoint:         openai.BatchNewParamsEndpointV1ChatCompletions,
+		InputFileID:      file.ID,
+	})
+	if err != nil {
+		return "", err
+	}
+	return batch.ID, nil
+}
+
+func openAIBatchStatus(ctx context.Context, mi *ModelInfo, id string) (*BatchStatus, error) {
+	client, err := newOpenAIBatchClient(mi)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := client.Batches.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	status := &BatchStatus{
+		Status:    string(batch.Status),
+		Total:     int(batch.RequestCounts.Total),
+		Succeeded: int(batch.RequestCounts.Completed),
+		Failed:    int(batch.RequestCounts.Failed),
+	}
+	switch batch.Status {
+	case openai.BatchStatusCompleted, openai.BatchStatusFailed, openai.BatchStatusExpired, openai.BatchStatusCancelled:
+		status.Done = true
+	}
+	return status, nil
+}
+
+func fetchOpenAIBatch(ctx context.Context, mi *ModelInfo, id string) ([]BatchResult, error) {
+	client, err := newOpenAIBatchClient(mi)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := client.Batches.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	switch batch.Status {
+	case openai.BatchStatusCompleted, openai.BatchStatusFailed, openai.BatchStatusExpired, openai.BatchStatusCancelled:
+	default:
+		return nil, fmt.Errorf("batch %s is still %s", id, batch.Status)
+	}
+
+	// Successful requests are in the output file, failed ones in the error file
+	var results []BatchResult
+	for _, fileID := range []string{batch.OutputFileID, batch.ErrorFileID} {
+		if fileID == "" {
+			continue
+		}
+		resp, err := client.Files.Content(ctx, fileID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download the results: %w", err)
+		}
+		lines, err := parseOpenAIBatchOutput(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, lines...)
+	}
+	if len(results) == 0 && len(batch.Errors.Data) > 0 {
+		return nil, fmt.Errorf("batch %s %s: %s", id, batch.Status, batch.Errors.Data[0].Message)
+	}
+	return results, nil
+}
+
+// parseOpenAIBatchOutput reads an output or error file of the batch API.
+func parseOpenAIBatchOutput(r io.Reader) ([]BatchResult, error) {
+	var results []BatchResult
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64<<20)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var line struct {
+			CustomID string `json:"custom_id"`
+			Response *struct {
+				StatusCode int             `json:"status_code"`
+				Body       json.RawMessage `json:"body"`
+			} `json:"response"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(text), &line); err != nil {
+			return nil, fmt.Errorf("failed to parse the results: %w", err)
+		}
+		result := BatchResult{ID: line.CustomID}
+		switch {
+		case line.Error != nil:
+			result.Error = line.Error.Message
+		case line.Response == nil:
+			result.Error = "no response"
+		case line.Response.StatusCode != 200:
+			var body struct {
+				Error struct {
+					Message string `json:"message"`
+				} `json:"error"`
+			}
+			json.Unmarshal(line.Response.Body, &body)
+			result.Error = fmt.Sprintf("status %d: %s", line.Response.StatusCode, body.Error.Message)
+		default:
+			var completion openai.ChatCompletion
+			if err := json.Unmarshal(line.Response.Body, &completion); err != nil {
+				return nil, fmt.Errorf("failed to parse the result of %s: %w", line.CustomID, err)
+			}
+			if len(completion.Choices) > 0 {
+				result.Output = completion.Choices[0].Message.Content
+			}
+			result.InputTokens = int(completion.Usage.PromptTokens)
+			result.OutputTokens = int(completion.Usage.CompletionTokens)
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
+
+func newAnthropicBatchClient(mi *ModelInfo) (anthropic.Client, error) {
+	opts := []anthropicoption.RequestOption{
+		anthropicoption.WithAPIKey(mi.ApiKey),
+		anthropicoption.WithAuthToken(mi.ApiKey),
+	}
+	if mi.EndPoint != "" {
+		opts = append(opts, anthropicoption.WithBaseURL(mi.EndPoint))
+	}
+	// Batches are created and polled, a cached answer would bring back an old batch
+	hc, err := uncachedProviderHTTPClient(mi)
+	if err != nil {
+		return anthropic.Client{}, err
+	}
+	if hc != nil {
+		opts = append(opts, anthropicoption.WithHTTPClient(hc))
+	}
+	return anthropic.NewClient(opts...), nil
+}
+
+// anthropicBatchRequests renders the jobs as the requests of a message batch.
+func anthropicBatchRequests(mi *ModelInfo, jobs []data.BatchJob) ([]anthropic.MessageBatchNewParamsRequest, error) {
+	maxTokens := int64(DefaultModelLimits.MaxOutputTokens)
+	if mi.MaxTokens > 0 {
+		maxTokens = int64(mi.MaxTokens)
+	} else if mi.MaxOutputTokens > 0 {
+		maxTokens = int64(mi.MaxOutputTokens)
+	}
+	requests := make([]anthropic.MessageBatchNewParamsRequest, 0, len(jobs))
+	for _, job := range jobs {
+		if !anthropicCustomID.MatchString(job.ID) {
+			return nil, fmt.Errorf("job id '%s' has more than 64 characters or others than letters, digits, _ and -", job.ID)
+		}
+		params := anthropic.MessageBatchNewParamsRequestParams{
+			Model:         anthropic.Model(mi.Model),
+			MaxTokens:     maxTokens,
+			Messages:      []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock(job.Prompt))},
+			StopSequences: mi.Stop,
+		}
+		if job.System != "" {
+			params.System = []anthropic.TextBlockParam{{Text: job.System}}
+		}
+		// Anthropic takes either temperature or top_p
+		if mi.Temperature > 0 {
+			params.Temperature = param.NewOpt(float64(mi.Temperature))
+		} else if mi.TopP > 0 {
+			params.TopP = param.NewOpt(float64(mi.TopP))
+		}
+		requests = append(requests, anthropic.MessageBatchNewParamsRequest{CustomID: job.ID, Params: params})
+	}
+	return requests, nil
+}
+
+func submitAnthropicBatch(ctx context.Context, mi *ModelInfo, jobs []data.BatchJob) (string, error) {
+	requests, err := anthropicBatchRequests(mi, jobs)
+	if err != nil {
+		return "", err
+	}
+	client, err := newAnthropicBatchClient(mi)
+	if err != nil {
+		return "", err
+	}
+	batch, err := client.Messages.Batches.New(ctx, anthropic.MessageBatchNewParams{Requests: requests})
+	if err != nil {
+		return "", err
+	}
+	return batch.ID, nil
+}
+
+func anthropicBatchStatus(ctx context.Context, mi *ModelInfo, id string) (*BatchStatus, error) {
+	client, err := newAnthropicBatchClient(mi)
+	if err != nil {
+		return nil, err
+	}
+	batch, err := client.Messages.Batches.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	counts := batch.RequestCounts
+	failed := counts.Errored + counts.Expired + counts.Canceled
+	return &BatchStatus{
+		Status:    string(batch.ProcessingStatus),
+		Total:     int(counts.Processing + counts.Succeeded + failed),
+		Succeeded: int(counts.Succeeded),
+		Failed:    int(failed),
+		Done:      batch.ProcessingStatus == anthropic.MessageBatchProcessingStatusEnded,
+	}, nil
+}
+
+func fetchAnthropicBatch(ctx context.Context, mi *ModelInfo, id string) ([]BatchResult, error) {
+	status, err := anthropicBatchStatus(ctx, mi, id)
+	if err != nil {
+		return nil, err
+	}
+	if !status.Done {
+		return nil, fmt.Errorf("batch %s is still %s", id, status.Status)
+	}
+	client, err := newAnthropicBatchClient(mi)
+	if err != nil {
+		return nil, err
+	}
+	stream := client.Messages.Batches.ResultsStreaming(ctx, id)
+	defer stream.Close()
+
+	var results []BatchResult
+	for stream.Next() {
+		results = append(results, anthropicBatchResult(stream.Current()))
+	}
+	if err := stream.Err(); err != nil {
+		return nil, fmt.Errorf("failed to download the results: %w", err)
+	}
+	return results, nil
+}
+
+// anthropicBatchResult converts the result of a request of a message batch.
+func anthropicBatchResult(resp anthropic.MessageBatchIndividualResponse) BatchResult {
+	result := BatchResult{ID: resp.CustomID}
+	switch resp.Result.Type {
+	case "succeeded":
+		var sb strings.Builder
+		for _, block := range resp.Result.Message.Content {
+			if block.Type == "text" {
+				sb.WriteString(block.Text)
+			}
+		}
+		result.Output = sb.String()
+		result.InputTokens = int(resp.Result.Message.Usage.InputTokens)
+		result.OutputTokens = int(resp.Result.Message.Usage.OutputTokens)
+	case "errored":
+		result.Error = resp.Result.Error.Error.Message
+	default:
+		// Canceled or expired before it ran
+		result.Error = resp.Result.Type
+	}
+	return result
+}
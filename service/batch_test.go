@@ -0,0 +1,98 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestOpenAIBatchInput(t *testing.T) {
+	mi := &ModelInfo{Model: "gpt-4o-mini", Temperature: 0.2, MaxTokens: 100}
+	input, err := openAIBatchInput(mi, []data.BatchJob{{ID: "a", Prompt: "hi", System: "be brief"}, {ID: "b", Prompt: "bye"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(input)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("input = %s", input)
+	}
+	var line struct {
+		CustomID string `json:"custom_id"`
+		Method   string `json:"method"`
+		URL      string `json:"url"`
+		Body     struct {
+			Model               string                   `json:"model"`
+			Messages            []map[string]interface{} `json:"messages"`
+			MaxCompletionTokens int                      `json:"max_completion_tokens"`
+		} `json:"body"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &line); err != nil {
+		t.Fatal(err)
+	}
+	if line.CustomID != "a" || line.Method != "POST" || line.URL != "/v1/chat/completions" ||
+		line.Body.Model != "gpt-4o-mini" || len(line.Body.Messages) != 2 || line.Body.MaxCompletionTokens != 100 {
+		t.Errorf("line = %s", lines[0])
+	}
+}
+
+func TestParseOpenAIBatchOutput(t *testing.T) {
+	output := `{"id":"r1","custom_id":"a","response":{"status_code":200,"body":{"id":"c","object":"chat.completion","model":"gpt-4o-mini","choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],"usage":{"prompt_tokens":5,"completion_tokens":2,"total_tokens":7}}},"error":null}
+{"id":"r2","custom_id":"b","response":{"status_code":400,"body":{"error":{"message":"bad request"}}},"error":null}
+{"id":"r3","custom_id":"c","response":null,"error":{"code":"batch_expired","message":"expired"}}
+`
+	results, err := parseOpenAIBatchOutput(strings.NewReader(output))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("results = %+v", results)
+	}
+	if results[0].Output != "hello" || results[0].InputTokens != 5 || results[0].OutputTokens != 2 {
+		t.Errorf("result a = %+v", results[0])
+	}
+	if !strings.Contains(results[1].Error, "bad request") || results[2].Error != "expired" {
+		t.Errorf("errors = %+v", results[1:])
+	}
+}
+
+func TestAnthropicBatchRequests(t *testing.T) {
+	mi := &ModelInfo{Model: "claude-haiku-4-5", Temperature: 0.5, TopP: 0.9}
+	requests, err := anthropicBatchRequests(mi, []data.BatchJob{{ID: "a", Prompt: "hi", System: "be brief"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := requests[0].Params
+	if requests[0].CustomID != "a" || p.MaxTokens != int64(DefaultModelLimits.MaxOutputTokens) || len(p.System) != 1 || p.TopP.Valid() {
+		t.Errorf("request = %+v", requests[0])
+	}
+	if _, err := anthropicBatchRequests(mi, []data.BatchJob{{ID: "has space", Prompt: "hi"}}); err == nil {
+		t.Error("an id Anthropic rejects is submitted")
+	}
+}
+
+func TestAnthropicBatchResult(t *testing.T) {
+	var resp anthropic.MessageBatchIndividualResponse
+	if err := json.Unmarshal([]byte(`{"custom_id":"a","result":{"type":"succeeded","message":{"id":"m","type":"message","role":"assistant","model":"claude","content":[{"type":"text","text":"hello"}],"usage":{"input_tokens":3,"output_tokens":1}}}}`), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if r := anthropicBatchResult(resp); r.ID != "a" || r.Output != "hello" || r.InputTokens != 3 {
+		t.Errorf("result = %+v", r)
+	}
+	if err := json.Unmarshal([]byte(`{"custom_id":"b","result":{"type":"expired"}}`), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if r := anthropicBatchResult(resp); r.Error != "expired" {
+		t.Errorf("result = %+v", r)
+	}
+}
+
+func TestWorkflowBatchJobs(t *testing.T) {
+	jobs := []data.BatchJob{{ID: "a", Prompt: "text"}}
+	out := WorkflowBatchJobs("Summarize:", jobs)
+	if out[0].Prompt != "Summarize:\ntext" || jobs[0].Prompt != "text" {
+		t.Errorf("jobs = %+v, %+v", out, jobs)
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+// BenchResult is the outcome of one prompt against one model.
+type BenchResult struct {
+	Model        string  `json:"model"`
+	Prompt       string  `json:"prompt"`
+	LatencyMs    int64   `json:"latency_ms"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	TotalTokens  int     `json:"total_tokens"`
+	Cost         float64 `json:"cost,omitempty"` // USD, only when pricing is known
+	Output       string  `json:"output"`
+	Error        string  `json:"error,omitempty"`
+}
+
+// BenchOptions configures a benchmark run.
+type BenchOptions struct {
+	Ctx     context.Context
+	Suite   *data.BenchSuite
+	Models  []*data.Model     // Models to compare, Name is used as the report key
+	Agent   *data.AgentConfig // Agent providing system prompt, tools and capabilities
+	NoTools bool              // Run without tools and capabilities
+
+	// OnResult is called after each run, e.g. to report progress
+	OnResult func(BenchResult)
+	// Runner executes a single run (default: CallAgent)
+	Runner AgentRunner
+}
+
+// RunBenchmark runs every prompt of the suite against every model, one at a time,
+// so latencies are not skewed by concurrent requests.
+func RunBenchmark(opts BenchOptions) []BenchResult {
+	runner := opts.Runner
+	if runner == nil {
+		runner = CallAgent
+	}
+	ctx := opts.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var results []BenchResult
+	for _, model := range opts.Models {
+		for _, prompt := range opts.Suite.Prompts {
+			if ctx.Err() != nil {
+				return results
+			}
+			result := runBenchOnce(ctx, runner, opts, model, prompt)
+			results = append(results, result)
+			if opts.OnResult != nil {
+				opts.OnResult(result)
+			}
+		}
+	}
+	return results
+}
+
+func runBenchOnce(ctx context.Context, runner AgentRunner, opts BenchOptions, model *data.Model, prompt data.BenchPrompt) BenchResult {
+	result := BenchResult{Model: model.Name, Prompt: prompt.Name}
+
+	op := AgentOptions{
+		Ctx:           ctx,
+		Prompt:        prompt.Prompt,
+		SysPrompt:     prompt.System,
+		ModelInfo:     model,
+		YoloMode:      true, // Nobody is there to confirm
+		QuietMode:     true,
+		SessionName:   "", // In-memory session, every run starts fresh
+		MaxRecursions: 10,
+		TokenUsage:    NewTokenUsage(),
+		ModelName:     model.Name,
+	}
+	if opts.Agent != nil {
+		op.AgentName = opts.Agent.Name
+		op.MaxRecursions = opts.Agent.MaxRecursions
+		op.ThinkingLevel = opts.Agent.Think
+		if op.SysPrompt == "" {
+			op.SysPrompt = opts.Agent.SystemPrompt
+		}
+		if !opts.NoTools {
+			op.EnabledTools = opts.Agent.Tools
+			op.Capabilities = opts.Agent.Capabilities
+			op.Paths = opts.Agent.Paths
+		}
+	}
+
+	start := time.Now()
+	output, err := runCaptured(runner, &op)
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.Output = output
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	tu := op.TokenUsage
+	result.InputTokens = tu.InputTokens
+	result.OutputTokens = tu.OutputTokens
+	result.TotalTokens = tu.TotalTokens
+	if pricing, ok := opts.Suite.Pricing[model.Name]; ok {
+		result.Cost = (float64(tu.InputTokens)*pricing.Input + float64(tu.OutputTokens)*pricing.Output) / 1e6
+	}
+	return result
+}
+
+// runCaptured runs an agent quietly and returns what it wrote, the output is captured
+// through the file output of the agent.
+func runCaptured(runner AgentRunner, op *AgentOptions) (string, error) {
+	out, err := os.CreateTemp("", "gllm-run-*.md")
+	if err != nil {
+		return "", err
+	}
+	outPath := out.Name()
+	out.Close()
+	defer os.Remove(outPath)
+
+	op.QuietMode = true
+	op.OutputFile = outPath
+	err = runner(op)
+
+	var output string
+	if content, readErr := os.ReadFile(outPath); readErr == nil {
+		output = strings.TrimSpace(string(content))
+	}
+	return output, err
+}
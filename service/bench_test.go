@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestRunBenchmark(t *testing.T) {
+	suite := &data.BenchSuite{
+		Prompts: []data.BenchPrompt{{Name: "p1", Prompt: "hello"}, {Name: "p2", Prompt: "fail"}},
+		Pricing: map[string]data.BenchPricing{"m1": {Input: 1, Output: 2}},
+	}
+	models := []*data.Model{{Name: "m1"}, {Name: "m2"}}
+
+	runner := func(op *AgentOptions) error {
+		if op.Prompt == "fail" {
+			return fmt.Errorf("boom")
+		}
+		if err := os.WriteFile(op.OutputFile, []byte("answer from "+op.ModelName), 0644); err != nil {
+			return err
+		}
+		op.TokenUsage.RecordTokenUsage(1000, 500, 0, 0, 1500)
+		return nil
+	}
+
+	var seen int
+	results := RunBenchmark(BenchOptions{
+		Suite:    suite,
+		Models:   models,
+		Runner:   runner,
+		OnResult: func(BenchResult) { seen++ },
+	})
+
+	if len(results) != 4 || seen != 4 {
+		t.Fatalf("expected 4 results and callbacks, got %d and %d", len(results), seen)
+	}
+	first := results[0]
+	if first.Model != "m1" || first.Prompt != "p1" || first.Output != "answer from m1" {
+		t.Errorf("unexpected first result: %+v", first)
+	}
+	if first.TotalTokens != 1500 || first.Cost != 0.002 {
+		t.Errorf("unexpected usage or cost: %+v", first)
+	}
+	if results[1].Error != "boom" {
+		t.Errorf("expected error to be recorded, got %+v", results[1])
+	}
+	if results[2].Cost != 0 {
+		t.Errorf("expected no cost without pricing, got %v", results[2].Cost)
+	}
+}
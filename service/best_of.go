@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/activebook/gllm/data"
+)
+
+// bestOfTemperature is the temperature of the candidates when the model has none,
+// identical samples would make best_of pointless.
+const bestOfTemperature = 0.8
+
+// bestOfJudgePrompt asks the model which candidate answers the request best.
+const bestOfJudgePrompt = `Here are candidate answers to the same request. Pick the best one: the most
+correct, complete and relevant. Reply with the number of the best candidate alone on the
+first line, then one sentence saying why.
+
+# Request
+
+%s
+`
+
+// bestOfJudgeChoice reads the number of the candidate the model picked.
+var bestOfJudgeChoice = regexp.MustCompile(`^\D*(\d+)`)
+
+// bestOfCandidate is one sampled answer.
+type bestOfCandidate struct {
+	output  string
+	session []byte // Session of the candidate, kept when it is the best
+	usage   *TokenUsage
+	err     error
+}
+
+// bestOfChoice is the candidate kept and why.
+type bestOfChoice struct {
+	index  int
+	reason string
+}
+
+// callBestOf samples op.BestOf answers at once and keeps the best one. The candidates run
+// without tools, several agents changing the same files at once would step on each other,
+// each on its own copy of the session. The best is found by majority vote when the answers
+// are structured, otherwise the model picks it, and its session becomes the session.
+func callBestOf(op *AgentOptions) error {
+	ctx := op.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var history []byte
+	if op.SessionName != "" {
+		history, _ = ReadSessionContent(op.SessionName)
+	}
+
+	candidates := make([]bestOfCandidate, op.BestOf)
+	var wg sync.WaitGroup
+	for i := range candidates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			candidates[i] = sampleBestOfCandidate(ctx, op, i, history)
+		}(i)
+	}
+	wg.Wait()
+
+	usage := NewTokenUsage()
+	var answered []int
+	for i, c := range candidates {
+		addTokenUsage(usage, c.usage)
+		if c.err == nil && c.output != "" {
+			answered = append(answered, i)
+		}
+	}
+	if len(answered) == 0 {
+		for _, c := range candidates {
+			if c.err != nil {
+				return c.err
+			}
+		}
+		return fmt.Errorf("none of the %d candidates answered", op.BestOf)
+	}
+
+	choice, ok := voteBestOf(candidates, answered)
+	if !ok {
+		var judgeUsage *TokenUsage
+		choice, judgeUsage = judgeBestOf(ctx, op, candidates, answered)
+		addTokenUsage(usage, judgeUsage)
+	}
+
+	best := candidates[choice.index]
+	if op.SessionName != "" && best.session != nil {
+		if err := WriteSessionContent(op.SessionName, best.session); err != nil {
+			return fmt.Errorf("failed to save the best candidate in the session: %w", err)
+		}
+	}
+	addTokenUsage(op.TokenUsage, usage)
+	writeBestOf(op, candidates, choice, usage)
+	return nil
+}
+
+// bestOfSessionName names the copy of the session a candidate runs on, next to the session.
+func bestOfSessionName(session string, i int) string {
+	if strings.Contains(session, "::") {
+		return fmt.Sprintf("%s-best-of-%d", session, i+1)
+	}
+	return fmt.Sprintf("%s::best-of-%d", session, i+1)
+}
+
+// sampleBestOfCandidate runs one candidate quietly on a copy of the session.
+func sampleBestOfCandidate(ctx context.Context, op *AgentOptions, i int, history []byte) bestOfCandidate {
+	model := *op.ModelInfo
+	if model.Temp <= 0 {
+		model.Temp = bestOfTemperature
+	}
+	cop := AgentOptions{
+		Ctx:           ctx,
+		Prompt:        op.Prompt,
+		SysPrompt:     op.SysPrompt,
+		Files:         op.Files,
+		ModelInfo:     &model,
+		MaxRecursions: 1,
+		ThinkingLevel: op.ThinkingLevel,
+		YoloMode:      true, // No tools, nothing to confirm
+		TokenUsage:    NewTokenUsage(),
+		Budget:        op.Budget,
+		SessionBudget: op.SessionBudget,
+		AgentName:     op.AgentName,
+		ModelName:     op.ModelName,
+	}
+	c := bestOfCandidate{usage: cop.TokenUsage}
+
+	if op.SessionName != "" {
+		cop.SessionName = bestOfSessionName(op.SessionName, i)
+		defer ClearSession(cop.SessionName)
+		if len(history) > 0 {
+			if c.err = WriteSessionContent(cop.SessionName, history); c.err != nil {
+				return c
+			}
+		}
+	}
+
+	c.output, c.err = runCaptured(CallAgent, &cop)
+	if cop.SessionName != "" && c.err == nil {
+		c.session, _ = ReadSessionContent(cop.SessionName)
+	}
+	return c
+}
+
+// voteBestOf keeps the most common answer when every answer is structured: JSON, or a
+// single short line like a number or a name. Without a clear majority the model decides.
+func voteBestOf(candidates []bestOfCandidate, answered []int) (bestOfChoice, bool) {
+	votes := make(map[string]int)
+	keys := make([]string, len(candidates))
+	for _, i := range answered {
+		key, ok := structuredAnswerKey(candidates[i].output)
+		if !ok {
+			return bestOfChoice{}, false
+		}
+		keys[i] = key
+		votes[key]++
+	}
+
+	top, tied := 0, false
+	for _, n := range votes {
+		if n > top {
+			top, tied = n, false
+		} else if n == top {
+			tied = true
+		}
+	}
+	if top < 2 || tied {
+		return bestOfChoice{}, false
+	}
+	for _, i := range answered {
+		if votes[keys[i]] == top {
+			return bestOfChoice{index: i, reason: fmt.Sprintf("majority vote, %d of %d", top, len(answered))}, true
+		}
+	}
+	return bestOfChoice{}, false
+}
+
+// structuredAnswerKey returns the text compared by the vote, false when the answer is prose.
+func structuredAnswerKey(text string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		if _, body, ok := strings.Cut(text, "\n"); ok {
+			text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(body), "```"))
+		}
+	}
+
+	var value interface{}
+	if strings.HasPrefix(text, "{") || strings.HasPrefix(text, "[") {
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return "", false
+		}
+		// Maps are marshaled with sorted keys, equal values give equal text
+		canonical, _ := json.Marshal(value)
+		return "json:" + string(canonical), true
+	}
+	if strings.Contains(text, "\n") || len([]rune(text)) > 100 {
+		return "", false
+	}
+	return "text:" + strings.TrimRight(strings.ToLower(text), ".! "), true
+}
+
+// judgeBestOf asks the model to pick the best candidate, the first one is kept when its
+// reply can't be read.
+func judgeBestOf(ctx context.Context, op *AgentOptions, candidates []bestOfCandidate, answered []int) (bestOfChoice, *TokenUsage) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, bestOfJudgePrompt, op.Prompt)
+	for _, i := range answered {
+		fmt.Fprintf(&sb, "\n# Candidate %d\n\n%s\n", i+1, candidates[i].output)
+	}
+
+	jop := AgentOptions{
+		Ctx:           ctx,
+		Prompt:        sb.String(),
+		ModelInfo:     op.ModelInfo,
+		MaxRecursions: 1,
+		YoloMode:      true,
+		TokenUsage:    NewTokenUsage(),
+		Budget:        op.Budget,
+		AgentName:     op.AgentName,
+		ModelName:     op.ModelName,
+	}
+	reply, err := runCaptured(CallAgent, &jop)
+	choice := bestOfChoice{index: answered[0], reason: "the model's pick could not be read, kept the first answer"}
+	if err != nil {
+		return choice, jop.TokenUsage
+	}
+
+	first, rest, _ := strings.Cut(strings.TrimSpace(reply), "\n")
+	m := bestOfJudgeChoice.FindStringSubmatch(first)
+	if m == nil {
+		return choice, jop.TokenUsage
+	}
+	n, _ := strconv.Atoi(m[1])
+	for _, i := range answered {
+		if i+1 == n {
+			reason, _, _ := strings.Cut(strings.TrimSpace(rest), "\n")
+			if reason == "" {
+				reason = "picked by the model"
+			}
+			return bestOfChoice{index: i, reason: reason}, jop.TokenUsage
+		}
+	}
+	return choice, jop.TokenUsage
+}
+
+// addTokenUsage adds the usage of a run to a total.
+func addTokenUsage(total, tu *TokenUsage) {
+	if total == nil || tu == nil {
+		return
+	}
+	total.RecordTokenUsage(tu.InputTokens, tu.OutputTokens, tu.CachedTokens, tu.ThoughtTokens, tu.TotalTokens)
+	total.CachedTokensInPrompt = tu.CachedTokensInPrompt
+}
+
+// writeBestOf writes the best answer like a run would, after the other candidates when
+// they are shown.
+func writeBestOf(op *AgentOptions, candidates []bestOfCandidate, choice bestOfChoice, usage *TokenUsage) {
+	stdIO, fileIO := constructIO(op.QuietMode, op.OutputFile)
+	if fileIO != nil {
+		defer fileIO.Close()
+		fileIO.Writef("%s\n", candidates[choice.index].output)
+	}
+	if stdIO == nil {
+		return
+	}
+	defer stdIO.Close()
+
+	if op.ShowCandidates {
+		for i, c := range candidates {
+			mark := ""
+			if i == choice.index {
+				mark = " (best)"
+			}
+			stdIO.Writeln(fmt.Sprintf("%s── Candidate %d of %d%s ──%s", data.SectionColor, i+1, len(candidates), mark, data.ResetSeq))
+			if c.err != nil {
+				stdIO.Writeln(fmt.Sprintf("%sError: %v%s", data.StatusErrorColor, c.err, data.ResetSeq))
+			} else {
+				stdIO.Writeln(c.output)
+			}
+			stdIO.Writeln("")
+		}
+	}
+	stdIO.Writeln(fmt.Sprintf("%sBest of %d: candidate %d, %s%s", data.StatusInfoColor, len(candidates), choice.index+1, choice.reason, data.ResetSeq))
+
+	if IsMarkdownEnabled(op.Capabilities) && !data.PlainOutput() {
+		md := NewMarkdown()
+		md.Writef("%s", candidates[choice.index].output)
+		md.Render(stdIO)
+	} else {
+		stdIO.Writeln(candidates[choice.index].output)
+	}
+	if IsTokenUsageEnabled(op.Capabilities) {
+		usage.Render(stdIO)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func runBestOfAgent(t *testing.T, modelID string, script *MockScript, n int, sessionName string) (string, *TokenUsage, error) {
+	t.Helper()
+	RegisterMockScript(modelID, script)
+	outPath := filepath.Join(t.TempDir(), "out.md")
+	tu := NewTokenUsage()
+	err := CallAgent(&AgentOptions{
+		Ctx:         context.Background(),
+		Prompt:      "What is 6 times 7?",
+		ModelInfo:   &data.Model{Provider: ModelProviderMock, Model: modelID},
+		BestOf:      n,
+		QuietMode:   true,
+		OutputFile:  outPath,
+		SessionName: sessionName,
+		TokenUsage:  tu,
+	})
+	content, _ := os.ReadFile(outPath)
+	return strings.TrimSpace(string(content)), tu, err
+}
+
+func TestStructuredAnswerKey(t *testing.T) {
+	a, _ := structuredAnswerKey(`{"b": 1, "a": [1, 2]}`)
+	b, _ := structuredAnswerKey("```json\n{\"a\": [1,2], \"b\": 1}\n```")
+	if a == "" || a != b {
+		t.Errorf("equal JSON gave different keys: %q and %q", a, b)
+	}
+	c, _ := structuredAnswerKey("42.")
+	d, _ := structuredAnswerKey(" 42 ")
+	if c != d {
+		t.Errorf("equal short answers gave different keys: %q and %q", c, d)
+	}
+	if _, ok := structuredAnswerKey("The answer is 42.\nBecause 6 times 7 is 42."); ok {
+		t.Errorf("prose must not be voted on")
+	}
+}
+
+func TestBestOfMajorityVote(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	script := &MockScript{Turns: []MockTurn{
+		{Text: "42", InputTokens: 10, OutputTokens: 1},
+		{Text: "41", InputTokens: 10, OutputTokens: 1},
+		{Text: "42.", InputTokens: 10, OutputTokens: 1},
+	}}
+	out, tu, err := runBestOfAgent(t, "mock-best-of-vote", script, 3, "voted")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "42" && out != "42." {
+		t.Errorf("the majority answer was not kept: %q", out)
+	}
+	if len(script.Requests()) != 3 {
+		t.Errorf("a vote needs no judging pass, got %d requests", len(script.Requests()))
+	}
+	if tu.InputTokens != 30 {
+		t.Errorf("the usage of the candidates was not added: %+v", tu)
+	}
+
+	// The session holds the best answer, the copies of the candidates are gone
+	content, err := ReadSessionContent("voted")
+	if err != nil || !strings.Contains(string(content), "42") {
+		t.Errorf("the best answer was not saved in the session: %v", err)
+	}
+	entries, _ := os.ReadDir(GetSessionPath("voted"))
+	for _, e := range entries {
+		if strings.Contains(e.Name(), "best-of") {
+			t.Errorf("the session copy %s was not removed", e.Name())
+		}
+	}
+}
+
+func TestBestOfJudge(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	prose := "Six times seven is 42.\nMultiply 6 by 7."
+	script := &MockScript{Turns: []MockTurn{
+		{Text: prose},
+		{Text: prose},
+		{Text: "2\nIt is the clearest."},
+	}}
+	out, _, err := runBestOfAgent(t, "mock-best-of-judge", script, 2, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != prose {
+		t.Errorf("unexpected answer: %q", out)
+	}
+	requests := script.Requests()
+	if len(requests) != 3 || !strings.Contains(string(requests[2]), "# Candidate 2") {
+		t.Fatalf("the model was not asked to pick the best candidate")
+	}
+}
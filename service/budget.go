@@ -0,0 +1,186 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+)
+
+// sessionSpendFile keeps the estimated spend of a session next to its history.
+const sessionSpendFile = "spend.json"
+
+// CostBudget caps the estimated spend of an invocation of gllm, across its runs: the
+// turns of a REPL or the agents it switched to.
+type CostBudget struct {
+	mu      sync.Mutex
+	limit   float64 // USD, 0 for none
+	allowed float64 // USD, the limit raised each time the user chose to go on
+	spent   float64 // USD spent by the finished runs
+	always  bool    // The user chose to go on without asking again
+}
+
+// NewCostBudget returns the budget of an invocation, a limit of 0 only tracks the spend.
+func NewCostBudget(limit float64) *CostBudget {
+	return &CostBudget{limit: limit, allowed: limit}
+}
+
+// Spent returns the estimated spend of the finished runs in USD.
+func (b *CostBudget) Spent() float64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.spent
+}
+
+func (b *CostBudget) add(usd float64) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spent += usd
+}
+
+// GetSessionSpend returns the estimated spend of a session in USD, 0 when unknown.
+func GetSessionSpend(name string) float64 {
+	if name == "" {
+		return 0
+	}
+	content, err := os.ReadFile(sessionSpendPath(name))
+	if err != nil {
+		return 0
+	}
+	var spend struct {
+		USD float64 `json:"usd"`
+	}
+	if err := json.Unmarshal(content, &spend); err != nil {
+		return 0
+	}
+	return spend.USD
+}
+
+// addSessionSpend adds to the estimated spend of a session.
+func addSessionSpend(name string, usd float64) error {
+	if name == "" || usd <= 0 {
+		return nil
+	}
+	content, err := json.Marshal(map[string]float64{"usd": GetSessionSpend(name) + usd})
+	if err != nil {
+		return err
+	}
+	path := sessionSpendPath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// sessionSpendPath returns the spend file of a session, sub-agent tasks count for their session.
+func sessionSpendPath(name string) string {
+	name, _, _ = strings.Cut(name, "::")
+	return filepath.Join(GetSessionPath(name), sessionSpendFile)
+}
+
+// budgetGuard enforces the budgets of a run before each call to the provider.
+type budgetGuard struct {
+	invocation   *CostBudget // Budget of the invocation, nil when none
+	sessionName  string
+	sessionLimit float64 // USD for the whole session, 0 for none
+	sessionSpent float64 // USD spent by the session before the run
+	sessionAsked float64 // Session limit raised by the user going on
+	sessionOff   bool    // The user chose to go on without asking again
+}
+
+// newBudgetGuard returns the guard of a run. Without model prices the spend is unknown and
+// a budget can't be enforced, which is said once.
+func newBudgetGuard(invocation *CostBudget, sessionName string, sessionLimit float64, mi *ModelInfo) *budgetGuard {
+	g := &budgetGuard{
+		invocation:   invocation,
+		sessionName:  sessionName,
+		sessionLimit: sessionLimit,
+		sessionSpent: GetSessionSpend(sessionName),
+	}
+	if g.limited() && mi.InputPrice == 0 && mi.OutputPrice == 0 {
+		util.LogWarnf("The model %s has no prices, the cost budget can't be enforced. Set them with 'gllm model set NAME --input_price USD --output_price USD'.\n", mi.Model)
+	}
+	return g
+}
+
+func (g *budgetGuard) limited() bool {
+	return g.sessionLimit > 0 || (g.invocation != nil && g.invocation.limit > 0)
+}
+
+// check is called before each call to the provider with the cost of the run so far. When a
+// budget is reached the user is asked whether to go on, a yes allows the same amount again.
+func (g *budgetGuard) check(runCost float64, interaction InteractionHandler) error {
+	if g == nil {
+		return nil
+	}
+	if b := g.invocation; b != nil && b.limit > 0 {
+		b.mu.Lock()
+		spent, limit, always := b.spent+runCost, b.allowed, b.always
+		b.mu.Unlock()
+		if !always && spent >= limit {
+			decision := g.ask("run", limit, spent, interaction)
+			if decision.Confirm == data.ToolConfirmCancel {
+				return BudgetExceededError{Scope: "run", Limit: limit, Spent: spent}
+			}
+			b.mu.Lock()
+			b.always = decision.AutoApprove
+			b.allowed += b.limit
+			b.mu.Unlock()
+		}
+	}
+	if g.sessionLimit > 0 && !g.sessionOff {
+		spent, limit := g.sessionSpent+runCost, g.sessionLimit+g.sessionAsked
+		if spent >= limit {
+			decision := g.ask("session", limit, spent, interaction)
+			if decision.Confirm == data.ToolConfirmCancel {
+				return BudgetExceededError{Scope: "session", Limit: limit, Spent: spent}
+			}
+			g.sessionOff = decision.AutoApprove
+			g.sessionAsked += g.sessionLimit
+		}
+	}
+	return nil
+}
+
+// ask pauses the run for the user to decide, without a user the run stops.
+func (g *budgetGuard) ask(scope string, limit, spent float64, interaction InteractionHandler) data.ToolsUse {
+	decision := data.ToolsUse{Confirm: data.ToolConfirmCancel}
+	if interaction != nil {
+		// A separate decision, going on must not auto-approve later tools
+		decision = data.ToolsUse{}
+		interaction.RequestConfirm(fmt.Sprintf("The %s budget of $%.2f is reached, already spent $%.2f. Continue?", scope, limit, spent), &decision)
+	}
+	return decision
+}
+
+// end records the cost of a finished run in the invocation and the session.
+func (g *budgetGuard) end(runCost float64) {
+	if g == nil || runCost <= 0 {
+		return
+	}
+	g.invocation.add(runCost)
+	if err := addSessionSpend(g.sessionName, runCost); err != nil {
+		util.LogDebugf("Failed to save the session spend: %v\n", err)
+	}
+}
+
+// checkBudget is called before each call to the provider.
+func (ag *Agent) checkBudget() error {
+	return ag.budget.check(ag.runCost(), ag.Interaction)
+}
+
+// runCost returns the estimated cost of the run so far in USD.
+func (ag *Agent) runCost() float64 {
+	return ag.TokenUsage.CostAt(ag.Model.InputPrice, ag.Model.OutputPrice)
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+// confirmingInteraction answers every confirmation with yes and keeps the questions.
+type confirmingInteraction struct {
+	HeadlessInteractionHandler
+	asked []string
+}
+
+func (c *confirmingInteraction) RequestConfirm(description string, toolsUse *data.ToolsUse) {
+	c.asked = append(c.asked, description)
+	toolsUse.ConfirmOnce()
+}
+
+func runBudgetAgent(t *testing.T, modelID, sessionName string, budget *CostBudget, sessionBudget float64, interaction InteractionHandler) error {
+	t.Helper()
+	turn := MockTurn{ToolCalls: []MockToolCall{{Name: ToolTodoRead, Args: map[string]interface{}{}}}, InputTokens: 100000, OutputTokens: 100000}
+	RegisterMockScript(modelID, &MockScript{Turns: []MockTurn{turn, turn, {Text: "done"}}})
+	return CallAgent(&AgentOptions{
+		Ctx:           context.Background(),
+		Prompt:        "hello",
+		ModelInfo:     &data.Model{Provider: ModelProviderMock, Model: modelID, InputPrice: 1, OutputPrice: 1},
+		EnabledTools:  []string{ToolTodoRead},
+		MaxRecursions: 5,
+		YoloMode:      true,
+		QuietMode:     true,
+		OutputFile:    filepath.Join(t.TempDir(), "out.md"),
+		SessionName:   sessionName,
+		Budget:        budget,
+		SessionBudget: sessionBudget,
+		Interaction:   interaction,
+	})
+}
+
+func TestCostBudgetStopsTheRun(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	// Each turn costs $0.20, the second call is over the budget and no one can say go on
+	budget := NewCostBudget(0.10)
+	err := runBudgetAgent(t, "mock-budget", "", budget, 0, HeadlessInteractionHandler{})
+	exceeded, ok := AsBudgetExceededError(err)
+	if !ok || exceeded.Scope != "run" || exceeded.Limit != 0.10 {
+		t.Fatalf("expected the run budget to stop the run, got %v", err)
+	}
+	if spent := budget.Spent(); spent < 0.19 || spent > 0.21 {
+		t.Errorf("the spend of the stopped run was not recorded: $%.2f", spent)
+	}
+
+	// Going on allows the same amount again
+	interaction := &confirmingInteraction{}
+	if err := runBudgetAgent(t, "mock-budget-confirm", "", NewCostBudget(0.30), 0, interaction); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(interaction.asked) != 1 || !strings.Contains(interaction.asked[0], "run budget of $0.30 is reached, already spent $0.40") {
+		t.Errorf("unexpected questions: %q", interaction.asked)
+	}
+}
+
+func TestSessionBudget(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	if err := runBudgetAgent(t, "mock-session-budget", "priced", nil, 1, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if spent := GetSessionSpend("priced"); spent < 0.39 || spent > 0.41 {
+		t.Fatalf("the session spend was not saved: $%.2f", spent)
+	}
+
+	// The next run starts with what the session already spent
+	err := runBudgetAgent(t, "mock-session-budget-2", "priced", nil, 0.50, nil)
+	exceeded, ok := AsBudgetExceededError(err)
+	if !ok || exceeded.Scope != "session" {
+		t.Fatalf("expected the session budget to stop the run, got %v", err)
+	}
+}
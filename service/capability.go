@@ -16,6 +16,7 @@ const (
 	CapabilityAutoCompression = "auto_compression"
 	CapabilityPlanMode        = "plan_mode"
 	CapabilityAutoRename      = "auto_rename"
+	CapabilitySessionRecall   = "session_recall"
 )
 
 const (
@@ -29,6 +30,7 @@ const (
 	CapabilityAutoCompressTitle = "Auto Compression"
 	CapabilityPlanModeTitle     = "Plan Mode"
 	CapabilityAutoRenameTitle   = "Auto Rename"
+	CapabilityRecallTitle       = "Session Recall"
 
 	CapabilityMCPTitleHighlight          = "[MCP (Model Context Protocol)]()"
 	CapabilitySkillsTitleHighlight       = "[Agent Skills]()"
@@ -40,6 +42,7 @@ const (
 	CapabilityAutoCompressTitleHighlight = "[Auto Compression]()"
 	CapabilityPlanModeTitleHighlight     = "[Plan Mode]()"
 	CapabilityAutoRenameTitleHighlight   = "[Auto Rename]()"
+	CapabilityRecallTitleHighlight       = "[Session Recall]()"
 
 	CapabilityMCPBody          = "enables communication with locally running MCP servers that provide additional tools and resources to extend capabilities.\nYou need to set up MCP servers specifically to use this feature."
 	CapabilitySkillsBody       = "are a lightweight, open format for extending AI agent capabilities with specialized knowledge and workflows.\nAfter integrating skills, **agent** will use skills automatically."
@@ -53,6 +56,7 @@ const (
 	CapabilityAutoCompressBody = "automatically compresses session context using a summary when context window limits are reached.\nThis provides an infinite context window continuity with minimal detail loss."
 	CapabilityPlanModeBody     = "allows agents to plan their work before executing tasks.\nUse for deepresearch, complex tasks, or collaborative work"
 	CapabilityAutoRenameBody   = "automatically renames the session after the first turn using the model to infer a meaningful, human-readable title from the conversation content."
+	CapabilityRecallBody       = "indexes past sessions and prepends the most relevant ones to a prompt that looks related, e.g. \"as we discussed last week\".\nUse /related to see what was recalled."
 
 	CapabilityMCPDescription          = CapabilityMCPTitle + " " + CapabilityMCPBody
 	CapabilitySkillsDescription       = CapabilitySkillsTitle + " " + CapabilitySkillsBody
@@ -64,6 +68,7 @@ const (
 	CapabilityAutoCompressDescription = CapabilityAutoCompressTitle + " " + CapabilityAutoCompressBody
 	CapabilityPlanModeDescription     = CapabilityPlanModeTitle + " " + CapabilityPlanModeBody
 	CapabilityAutoRenameDescription   = CapabilityAutoRenameTitle + " " + CapabilityAutoRenameBody
+	CapabilityRecallDescription       = CapabilityRecallTitle + " " + CapabilityRecallBody
 
 	// Agent Features Description Highlight
 	CapabilityMCPDescriptionHighlight          = CapabilityMCPTitleHighlight + CapabilityMCPBody
@@ -76,6 +81,7 @@ const (
 	CapabilityAutoCompressDescriptionHighlight = CapabilityAutoCompressTitleHighlight + CapabilityAutoCompressBody
 	CapabilityPlanModeDescriptionHighlight     = CapabilityPlanModeTitleHighlight + CapabilityPlanModeBody
 	CapabilityAutoRenameDescriptionHighlight   = CapabilityAutoRenameTitleHighlight + CapabilityAutoRenameBody
+	CapabilityRecallDescriptionHighlight       = CapabilityRecallTitleHighlight + CapabilityRecallBody
 )
 
 var (
@@ -90,6 +96,7 @@ var (
 		CapabilityAutoCompression,
 		CapabilityPlanMode,
 		CapabilityAutoRename,
+		CapabilitySessionRecall,
 	}
 )
 
@@ -134,6 +141,8 @@ func GetCapabilityTitle(cap string) string {
 		return CapabilityPlanModeTitle
 	case CapabilityAutoRename:
 		return CapabilityAutoRenameTitle
+	case CapabilitySessionRecall:
+		return CapabilityRecallTitle
 	default:
 		return "Unknown"
 	}
@@ -163,6 +172,8 @@ func GetCapabilityDescHighlight(cap string) string {
 		return CapabilityPlanModeDescriptionHighlight
 	case CapabilityAutoRename, CapabilityAutoRenameTitle:
 		return CapabilityAutoRenameDescriptionHighlight
+	case CapabilitySessionRecall, CapabilityRecallTitle:
+		return CapabilityRecallDescriptionHighlight
 	default:
 		return ""
 	}
@@ -191,6 +202,8 @@ func GetCapabilityDescription(cap string) string {
 		return CapabilityPlanModeDescription
 	case CapabilityAutoRename, CapabilityAutoRenameTitle:
 		return CapabilityAutoRenameDescription
+	case CapabilitySessionRecall, CapabilityRecallTitle:
+		return CapabilityRecallDescription
 	default:
 		return ""
 	}
@@ -377,3 +390,18 @@ func EnableAutoRename(capabilities []string) []string {
 func DisableAutoRename(capabilities []string) []string {
 	return disableCapability(capabilities, CapabilityAutoRename)
 }
+
+/*
+ * Session Recall
+ */
+func IsSessionRecallEnabled(capabilities []string) bool {
+	return isCapabilityEnabled(capabilities, CapabilitySessionRecall)
+}
+
+func EnableSessionRecall(capabilities []string) []string {
+	return enableCapability(capabilities, CapabilitySessionRecall)
+}
+
+func DisableSessionRecall(capabilities []string) []string {
+	return disableCapability(capabilities, CapabilitySessionRecall)
+}
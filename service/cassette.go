@@ -0,0 +1,253 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+/*
+A cassette is a recording of the provider traffic of one run.
+Record mode captures every provider HTTP exchange as it streams through,
+replay mode serves the recorded responses back in order without touching the network.
+Tools still run for real on replay, so tool-loop problems can be reproduced offline.
+Request headers are never recorded, they carry the API keys.
+*/
+
+const CassetteVersion = 1
+
+// CassetteRun describes the run that produced the recording, so it can be started again.
+type CassetteRun struct {
+	AgentName     string     `json:"agent_name,omitempty"`
+	Prompt        string     `json:"prompt"`
+	SysPrompt     string     `json:"system_prompt,omitempty"`
+	Model         data.Model `json:"model"`
+	MaxRecursions int        `json:"max_recursions"`
+	ThinkingLevel string     `json:"thinking_level,omitempty"`
+	EnabledTools  []string   `json:"tools,omitempty"`
+	Capabilities  []string   `json:"capabilities,omitempty"`
+}
+
+// CassetteInteraction is one recorded provider request and its response.
+type CassetteInteraction struct {
+	Method       string `json:"method"`
+	Path         string `json:"path"`
+	RequestBody  string `json:"request_body,omitempty"`
+	Status       int    `json:"status"`
+	ContentType  string `json:"content_type,omitempty"`
+	ResponseBody string `json:"response_body"`
+}
+
+// Cassette is the on-disk format of a recording.
+type Cassette struct {
+	Version      int                   `json:"version"`
+	RecordedAt   time.Time             `json:"recorded_at"`
+	Run          *CassetteRun          `json:"run,omitempty"`
+	Interactions []CassetteInteraction `json:"interactions"`
+
+	mu   sync.Mutex
+	next int // Replay cursor
+}
+
+// LoadCassette reads a cassette file.
+func LoadCassette(path string) (*Cassette, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cassette: %w", err)
+	}
+	var c Cassette
+	if err := json.Unmarshal(content, &c); err != nil {
+		return nil, fmt.Errorf("failed to parse cassette %s: %w", path, err)
+	}
+	if c.Version > CassetteVersion {
+		return nil, fmt.Errorf("cassette version %d is newer than supported version %d", c.Version, CassetteVersion)
+	}
+	if c.Run == nil {
+		return nil, fmt.Errorf("cassette %s has no recorded run", path)
+	}
+	return &c, nil
+}
+
+// Save writes the cassette to path.
+func (c *Cassette) Save(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+// Remaining returns the number of interactions not replayed yet.
+func (c *Cassette) Remaining() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.Interactions) - c.next
+}
+
+func (c *Cassette) append(i CassetteInteraction) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Interactions = append(c.Interactions, i)
+}
+
+// Recorder captures provider traffic into a cassette until it is closed.
+type Recorder struct {
+	path     string
+	cassette *Cassette
+	base     http.RoundTripper
+}
+
+// StartRecording routes provider traffic through a recorder that saves to path on Close.
+func StartRecording(path string) *Recorder {
+	rec := &Recorder{
+		path:     path,
+		cassette: &Cassette{Version: CassetteVersion, RecordedAt: time.Now()},
+		base:     http.DefaultTransport,
+	}
+	setProviderTransport(rec)
+	return rec
+}
+
+// RecordRun stores the options of the run being recorded. Only the first run is kept,
+// later ones (e.g. after an agent switch) are replayed as part of the same conversation.
+func (r *Recorder) RecordRun(op *AgentOptions) {
+	r.cassette.mu.Lock()
+	defer r.cassette.mu.Unlock()
+	if r.cassette.Run != nil || op.ModelInfo == nil {
+		return
+	}
+	model := *op.ModelInfo
+	model.Key = "" // Never persist credentials
+	r.cassette.Run = &CassetteRun{
+		AgentName:     op.AgentName,
+		Prompt:        op.Prompt,
+		SysPrompt:     op.SysPrompt,
+		Model:         model,
+		MaxRecursions: op.MaxRecursions,
+		ThinkingLevel: op.ThinkingLevel,
+		EnabledTools:  op.EnabledTools,
+		Capabilities:  op.Capabilities,
+	}
+}
+
+// Close stops recording and writes the cassette.
+func (r *Recorder) Close() error {
+	setProviderTransport(nil)
+	return r.cassette.Save(r.path)
+}
+
+// RoundTrip forwards the request and tees the response body into the cassette,
+// so streamed responses still reach the caller incrementally.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		var err error
+		reqBody, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(reqBody))
+	}
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &teeBody{
+		ReadCloser: resp.Body,
+		onDone: func(body []byte) {
+			r.cassette.append(CassetteInteraction{
+				Method:       req.Method,
+				Path:         req.URL.Path,
+				RequestBody:  string(reqBody),
+				Status:       resp.StatusCode,
+				ContentType:  resp.Header.Get("Content-Type"),
+				ResponseBody: string(body),
+			})
+		},
+	}
+	return resp, nil
+}
+
+// teeBody buffers everything read and reports it once, on EOF or Close.
+type teeBody struct {
+	io.ReadCloser
+	buf    bytes.Buffer
+	once   sync.Once
+	onDone func([]byte)
+}
+
+func (t *teeBody) Read(p []byte) (int, error) {
+	n, err := t.ReadCloser.Read(p)
+	t.buf.Write(p[:n])
+	if err == io.EOF {
+		t.once.Do(func() { t.onDone(t.buf.Bytes()) })
+	}
+	return n, err
+}
+
+func (t *teeBody) Close() error {
+	t.once.Do(func() { t.onDone(t.buf.Bytes()) })
+	return t.ReadCloser.Close()
+}
+
+// StartReplay serves provider requests from the cassette until StopReplay is called.
+func StartReplay(c *Cassette) {
+	setProviderTransport(&cassettePlayer{cassette: c})
+}
+
+// StopReplay restores live provider traffic.
+func StopReplay() {
+	setProviderTransport(nil)
+}
+
+type cassettePlayer struct {
+	cassette *Cassette
+}
+
+// RoundTrip answers with the next recorded interaction. Requests are matched by order;
+// a different route means the run diverged from the recording and is reported as an error.
+func (p *cassettePlayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		io.Copy(io.Discard, req.Body)
+		req.Body.Close()
+	}
+
+	c := p.cassette
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.next >= len(c.Interactions) {
+		return nil, fmt.Errorf("replay exhausted: no recorded response left for %s %s", req.Method, req.URL.Path)
+	}
+	rec := c.Interactions[c.next]
+	if rec.Method != req.Method || rec.Path != req.URL.Path {
+		return nil, fmt.Errorf("replay diverged: expected %s %s, got %s %s", rec.Method, rec.Path, req.Method, req.URL.Path)
+	}
+	c.next++
+
+	header := http.Header{}
+	if rec.ContentType != "" {
+		header.Set("Content-Type", rec.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(rec.ResponseBody))),
+		ContentLength: int64(len(rec.ResponseBody)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec := StartRecording(path)
+	rec.RecordRun(&AgentOptions{
+		Prompt:       "hello",
+		ModelInfo:    &data.Model{Provider: ModelProviderMock, Model: "mock-cassette", Key: "secret-key"},
+		EnabledTools: []string{ToolListDirectory},
+	})
+	out, err := runMockAgent(t, context.Background(), "mock-cassette",
+		&MockScript{Turns: []MockTurn{
+			{ToolCalls: []MockToolCall{{Name: ToolListDirectory, Args: map[string]interface{}{"path": t.TempDir()}}}},
+			{Text: "recorded answer", ChunkSize: 3},
+		}}, []string{ToolListDirectory})
+	if closeErr := rec.Close(); closeErr != nil {
+		t.Fatalf("failed to save cassette: %v", closeErr)
+	}
+	if err != nil || !strings.Contains(out, "recorded answer") {
+		t.Fatalf("recording run failed: %v, output %q", err, out)
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("failed to load cassette: %v", err)
+	}
+	if len(cassette.Interactions) != 2 {
+		t.Fatalf("expected 2 interactions, got %d", len(cassette.Interactions))
+	}
+	if cassette.Run.Model.Key != "" {
+		t.Errorf("API key was persisted in the cassette")
+	}
+
+	// The mock script is emptied, so any answer must come from the cassette
+	StartReplay(cassette)
+	defer StopReplay()
+
+	out, err = runMockAgent(t, context.Background(), "mock-cassette", &MockScript{}, cassette.Run.EnabledTools)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if !strings.Contains(out, "recorded answer") {
+		t.Errorf("replay output %q does not contain the recorded answer", out)
+	}
+	if remaining := cassette.Remaining(); remaining != 0 {
+		t.Errorf("expected all interactions to be replayed, %d left", remaining)
+	}
+}
+
+func TestCassetteReplayExhausted(t *testing.T) {
+	StartReplay(&Cassette{Version: CassetteVersion})
+	defer StopReplay()
+
+	_, err := runMockAgent(t, context.Background(), "mock-exhausted", &MockScript{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "replay exhausted") {
+		t.Fatalf("expected replay exhausted error, got %v", err)
+	}
+}
@@ -41,7 +41,7 @@ func CompressSession(modelConfig *data.AgentConfig, sessionData []byte) (string,
 
 	switch modelConfig.Model.Provider {
 
-	case ModelProviderOpenAI:
+	case ModelProviderOpenAI, ModelProviderMock:
 		var messages []openai.ChatCompletionMessageParamUnion
 		if err := parseJSONL(sessionData, &messages); err != nil {
 			return "", fmt.Errorf("failed to parse OpenAI session: %w", err)
@@ -95,7 +95,7 @@ func CompressSession(modelConfig *data.AgentConfig, sessionData []byte) (string,
 // formatted for the specified provider. User provides the summary, assistant acknowledges.
 func BuildCompressedSession(summary string, provider string) ([]byte, error) {
 	switch provider {
-	case ModelProviderOpenAI:
+	case ModelProviderOpenAI, ModelProviderMock:
 		messages := []openai.ChatCompletionMessageParamUnion{
 			openai.UserMessage(CompressedContextPrefix + summary),
 			openai.AssistantMessage(CompressedContextAck),
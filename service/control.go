@@ -0,0 +1,194 @@
+package service
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+)
+
+// A running REPL listens on a control socket, so that editors and scripts can send it
+// prompts. Each request is a JSON line, answered with a JSON line:
+//
+//	{"action": "prompt", "text": "..."}   queue a prompt, run as soon as the REPL is idle
+//	{"action": "command", "text": "/compress"}   queue a REPL command
+//	{"action": "insert", "text": "..."}   insert text into the input being typed
+//	{"action": "status"}                  -> {"ok": true, "status": {...}}
+const (
+	ControlActionPrompt  = "prompt"
+	ControlActionCommand = "command"
+	ControlActionInsert  = "insert"
+	ControlActionStatus  = "status"
+
+	controlDialTimeout = 500 * time.Millisecond
+	controlTimeout     = 5 * time.Second
+)
+
+// ControlRequest is a request to a running REPL.
+type ControlRequest struct {
+	Action string `json:"action"`
+	Text   string `json:"text,omitempty"`
+}
+
+// ControlStatus describes a running REPL.
+type ControlStatus struct {
+	PID     int    `json:"pid"`
+	Session string `json:"session"`
+	Agent   string `json:"agent"`
+	WorkDir string `json:"work_dir"`
+	Busy    bool   `json:"busy"`   // A prompt is running
+	Queued  int    `json:"queued"` // Inputs waiting to run
+}
+
+// ControlResponse is the answer of a running REPL.
+type ControlResponse struct {
+	OK     bool           `json:"ok"`
+	Error  string         `json:"error,omitempty"`
+	Status *ControlStatus `json:"status,omitempty"`
+}
+
+// ControlHandler answers the requests of the control socket.
+type ControlHandler func(req ControlRequest) ControlResponse
+
+// ControlServer is the control socket of a running REPL.
+type ControlServer struct {
+	path     string
+	listener net.Listener
+	handler  ControlHandler
+	wg       sync.WaitGroup
+}
+
+// ControlSocketPath returns the socket of the REPL running as the process pid.
+func ControlSocketPath(pid int) string {
+	return filepath.Join(data.GetControlDirPath(), fmt.Sprintf("%d.sock", pid))
+}
+
+// StartControlServer listens on the control socket of this process.
+func StartControlServer(handler ControlHandler) (*ControlServer, error) {
+	if err := os.MkdirAll(data.GetControlDirPath(), 0700); err != nil {
+		return nil, err
+	}
+	path := ControlSocketPath(os.Getpid())
+	os.Remove(path) // Left by a crashed process with a recycled pid
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	os.Chmod(path, 0600)
+	s := &ControlServer{path: path, listener: listener, handler: handler}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Path returns the path of the socket.
+func (s *ControlServer) Path() string {
+	return s.path
+}
+
+func (s *ControlServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return // Closed
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *ControlServer) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+	reader := bufio.NewReader(conn)
+	line, err := reader.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return
+	}
+	var req ControlRequest
+	var resp ControlResponse
+	if err := json.Unmarshal(line, &req); err != nil {
+		resp = ControlResponse{Error: fmt.Sprintf("invalid request: %v", err)}
+	} else {
+		resp = s.handler(req)
+	}
+	json.NewEncoder(conn).Encode(resp)
+}
+
+// Close stops listening and removes the socket.
+func (s *ControlServer) Close() {
+	s.listener.Close()
+	s.wg.Wait()
+	os.Remove(s.path)
+}
+
+// SendControlRequest sends a request to the control socket at path.
+func SendControlRequest(path string, req ControlRequest) (ControlResponse, error) {
+	conn, err := net.DialTimeout("unix", path, controlDialTimeout)
+	if err != nil {
+		return ControlResponse{}, fmt.Errorf("no REPL is listening on %s", path)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(controlTimeout))
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return ControlResponse{}, err
+	}
+	var resp ControlResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return ControlResponse{}, fmt.Errorf("invalid answer: %w", err)
+	}
+	if !resp.OK && resp.Error != "" {
+		return resp, fmt.Errorf("%s", resp.Error)
+	}
+	return resp, nil
+}
+
+// ListControlSockets returns the status of the running REPLs, removing the sockets of
+// processes that are gone.
+func ListControlSockets() []ControlStatus {
+	files, _ := filepath.Glob(filepath.Join(data.GetControlDirPath(), "*.sock"))
+	var result []ControlStatus
+	for _, file := range files {
+		resp, err := SendControlRequest(file, ControlRequest{Action: ControlActionStatus})
+		if err != nil || resp.Status == nil {
+			util.LogDebugf("Removing stale control socket %s: %v\n", file, err)
+			os.Remove(file)
+			continue
+		}
+		result = append(result, *resp.Status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].PID < result[j].PID })
+	return result
+}
+
+// FindControlSocket returns the socket of a running REPL, chosen by pid or session name.
+// Without a target there must be a single REPL running.
+func FindControlSocket(target string) (string, error) {
+	running := ListControlSockets()
+	if target != "" {
+		for _, status := range running {
+			if strconv.Itoa(status.PID) == target || strings.EqualFold(status.Session, target) {
+				return ControlSocketPath(status.PID), nil
+			}
+		}
+		return "", fmt.Errorf("no running REPL with pid or session '%s'", target)
+	}
+	switch len(running) {
+	case 0:
+		return "", fmt.Errorf("no REPL is running")
+	case 1:
+		return ControlSocketPath(running[0].PID), nil
+	default:
+		return "", fmt.Errorf("%d REPLs are running, choose one with --to", len(running))
+	}
+}
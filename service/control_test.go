@@ -0,0 +1,60 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestControlServer(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	var got []ControlRequest
+	server, err := StartControlServer(func(req ControlRequest) ControlResponse {
+		got = append(got, req)
+		if req.Action == "fail" {
+			return ControlResponse{Error: "no way"}
+		}
+		return ControlResponse{OK: true, Status: &ControlStatus{PID: os.Getpid(), Session: "My Session", Queued: len(got)}}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	resp, err := SendControlRequest(server.Path(), ControlRequest{Action: ControlActionPrompt, Text: "hello\nworld"})
+	if err != nil || !resp.OK || resp.Status.Queued != 1 {
+		t.Fatalf("prompt: %+v, %v", resp, err)
+	}
+	if len(got) != 1 || got[0].Text != "hello\nworld" {
+		t.Errorf("unexpected requests: %+v", got)
+	}
+	if _, err := SendControlRequest(server.Path(), ControlRequest{Action: "fail"}); err == nil || err.Error() != "no way" {
+		t.Errorf("expected the error of the handler, got %v", err)
+	}
+
+	// A socket left by a process that is gone
+	stale := filepath.Join(filepath.Dir(server.Path()), "1.sock")
+	os.WriteFile(stale, nil, 0600)
+
+	running := ListControlSockets()
+	if len(running) != 1 || running[0].Session != "My Session" {
+		t.Fatalf("unexpected running REPLs: %+v", running)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Error("the stale socket should be removed")
+	}
+	for _, target := range []string{"", "my session"} {
+		if path, err := FindControlSocket(target); err != nil || path != server.Path() {
+			t.Errorf("FindControlSocket(%q) = %s, %v", target, path, err)
+		}
+	}
+	if _, err := FindControlSocket("other"); err == nil {
+		t.Error("expected an error for an unknown target")
+	}
+
+	server.Close()
+	if _, err := FindControlSocket(""); err == nil || !strings.Contains(err.Error(), "no REPL") {
+		t.Errorf("expected no REPL after close, got %v", err)
+	}
+}
@@ -19,7 +19,7 @@ import (
  * Key Design Decisions:
  * 1. Text content and images are preserved via Parts.
  * 2. Reasoning is preserved.
- * 3. Tool calls and tool responses are discarded.
+ * 3. Tool calls and tool responses are preserved, paired by call ID.
  * 4. Role normalization: "model" (Gemini) → "assistant"
  */
 type UniversalMessage struct {
@@ -185,6 +185,37 @@ func ConvertMessages(data []byte, sourceProvider, targetProvider string) ([]byte
 	}
 
 	// Step 1: Parse source data to universal format
+	uniMsgs, err := ParseUniversalMessages(data, sourceProvider)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 2: Build target format and marshal as JSONL
+	switch targetProvider {
+	case ModelProviderOpenAI:
+		newmsgs := BuildOpenAIMessages(uniMsgs)
+		return marshalJSONL(newmsgs)
+
+	case ModelProviderOpenAICompatible:
+		newmsgs := BuildOpenChatMessages(uniMsgs)
+		return marshalJSONL(newmsgs)
+
+	case ModelProviderAnthropic:
+		newmsgs := BuildAnthropicMessages(uniMsgs)
+		return marshalJSONL(newmsgs)
+
+	case ModelProviderGemini:
+		newmsgs := BuildGeminiMessages(uniMsgs)
+		return marshalJSONL(newmsgs)
+
+	default:
+		return nil, fmt.Errorf("unsupported target provider: %s", targetProvider)
+	}
+}
+
+// ParseUniversalMessages parses a session in the format of a provider into universal
+// messages, with the names of the tools backfilled in their results.
+func ParseUniversalMessages(data []byte, sourceProvider string) ([]UniversalMessage, error) {
 	var uniMsgs []UniversalMessage
 
 	switch sourceProvider {
@@ -220,28 +251,7 @@ func ConvertMessages(data []byte, sourceProvider, targetProvider string) ([]byte
 		return nil, fmt.Errorf("unsupported source provider: %s", sourceProvider)
 	}
 
-	// Step 1.5: Backfill tool names for ToolResults (needed for OpenAI -> Gemini)
+	// Backfill tool names for ToolResults (needed for OpenAI -> Gemini)
 	correlateToolNames(uniMsgs)
-
-	// Step 2: Build target format and marshal as JSONL
-	switch targetProvider {
-	case ModelProviderOpenAI:
-		newmsgs := BuildOpenAIMessages(uniMsgs)
-		return marshalJSONL(newmsgs)
-
-	case ModelProviderOpenAICompatible:
-		newmsgs := BuildOpenChatMessages(uniMsgs)
-		return marshalJSONL(newmsgs)
-
-	case ModelProviderAnthropic:
-		newmsgs := BuildAnthropicMessages(uniMsgs)
-		return marshalJSONL(newmsgs)
-
-	case ModelProviderGemini:
-		newmsgs := BuildGeminiMessages(uniMsgs)
-		return marshalJSONL(newmsgs)
-
-	default:
-		return nil, fmt.Errorf("unsupported target provider: %s", targetProvider)
-	}
+	return uniMsgs, nil
 }
@@ -2,7 +2,10 @@ package service
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
+
+	"github.com/activebook/gllm/data"
 )
 
 /*
@@ -342,3 +345,39 @@ func TestConvertMessages_Multimodal_AnthropicToOpenChat(t *testing.T) {
 		t.Errorf("Expected Data URL 'data:image/webp;base64,d2VicGRhdGE=', got '%v'", imageURL["url"])
 	}
 }
+
+func TestSwitchSessionModel(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	history := `{"role": "user", "content": "Search for cats"}
+{"role": "assistant", "tool_calls": [{"id": "1", "type": "function", "function": {"name": "search", "arguments": "{\"q\":\"cat\"}"}}]}
+{"role": "tool", "tool_call_id": "1", "content": "Search results"}
+{"role": "assistant", "content": "Here are the results."}
+`
+	if err := WriteSessionContent("escalate", []byte(history)); err != nil {
+		t.Fatal(err)
+	}
+
+	agent := &data.AgentConfig{Name: "coder", Model: data.Model{Name: "strong", Provider: ModelProviderGemini}}
+	sw, err := SwitchSessionModel(agent, "escalate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sw.Converted || sw.From != ModelProviderOpenAI || sw.To != ModelProviderGemini || sw.Messages != 4 || sw.ToolCalls != 1 {
+		t.Fatalf("unexpected switch: %+v", sw)
+	}
+	converted, _ := ReadSessionContent("escalate")
+	if DetectMessageProviderByContent(converted) != ModelProviderGemini || !strings.Contains(string(converted), "functionResponse") {
+		t.Errorf("the history was not converted with its tool calls:\n%s", converted)
+	}
+
+	// Switching to a model of the same format keeps the history
+	sw, err = SwitchSessionModel(agent, "escalate")
+	if err != nil || sw.Converted || sw.Messages != 4 {
+		t.Errorf("unexpected second switch: %+v, %v", sw, err)
+	}
+
+	if sw, err := SwitchSessionModel(agent, "no-such-session"); err != nil || sw.Messages != 0 {
+		t.Errorf("a new session has no history to switch: %+v, %v", sw, err)
+	}
+}
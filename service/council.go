@@ -0,0 +1,158 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+// councilJudgePrompt asks the judge to compare the answers of the council.
+const councilJudgePrompt = `Several models answered the same question. Compare their answers: say where they agree,
+where they differ and which claims are wrong or unsupported. Then give the best answer to the
+question, synthesized from theirs. Refer to the answers by model name.
+
+# Question
+
+%s
+`
+
+// CouncilAnswer is the answer of one model of the council, or of the judge.
+type CouncilAnswer struct {
+	Model        string  `json:"model"`
+	Output       string  `json:"output"`
+	Error        string  `json:"error,omitempty"`
+	LatencyMs    int64   `json:"latency_ms"`
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Cost         float64 `json:"cost,omitempty"` // USD, only when the model has prices
+}
+
+// CouncilResult holds the answers in the order of the models, and the verdict of the judge.
+type CouncilResult struct {
+	Answers []CouncilAnswer `json:"answers"`
+	Verdict *CouncilAnswer  `json:"verdict,omitempty"`
+}
+
+// CouncilOptions configures a council run.
+type CouncilOptions struct {
+	Ctx      context.Context
+	Prompt   string
+	Files    []*FileData
+	Models   []*data.Model     // Models asked, Name labels the answers
+	Judge    *data.Model       // Model comparing the answers, nil for none
+	Agent    *data.AgentConfig // Agent providing system prompt and thinking level
+	Parallel int               // Models asked at once, 0 for all
+
+	// OnAnswer is called after each answer, one call at a time
+	OnAnswer func(CouncilAnswer)
+	// Runner executes a single run (default: CallAgent)
+	Runner AgentRunner
+}
+
+// RunCouncil asks every model the same question in parallel, then lets the judge, if any,
+// compare the answers. Models run without tools: several agents changing the same files
+// at once would step on each other.
+func RunCouncil(opts CouncilOptions) CouncilResult {
+	runner := opts.Runner
+	if runner == nil {
+		runner = CallAgent
+	}
+	ctx := opts.Ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	parallel := opts.Parallel
+	if parallel <= 0 || parallel > len(opts.Models) {
+		parallel = len(opts.Models)
+	}
+
+	result := CouncilResult{Answers: make([]CouncilAnswer, len(opts.Models))}
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for i, model := range opts.Models {
+		wg.Add(1)
+		go func(i int, model *data.Model) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			answer := CouncilAnswer{Model: model.Name, Error: "canceled"}
+			if ctx.Err() == nil {
+				answer = runCouncilMember(ctx, runner, opts, model, opts.Prompt, opts.Files)
+			}
+			result.Answers[i] = answer
+			if opts.OnAnswer != nil {
+				mu.Lock()
+				opts.OnAnswer(answer)
+				mu.Unlock()
+			}
+		}(i, model)
+	}
+	wg.Wait()
+
+	if opts.Judge == nil || ctx.Err() != nil {
+		return result
+	}
+	prompt, ok := councilJudgeInput(opts.Prompt, result.Answers)
+	if !ok {
+		return result
+	}
+	verdict := runCouncilMember(ctx, runner, opts, opts.Judge, prompt, opts.Files)
+	result.Verdict = &verdict
+	return result
+}
+
+// councilJudgeInput builds the question to the judge, false when no model answered.
+func councilJudgeInput(question string, answers []CouncilAnswer) (string, bool) {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, councilJudgePrompt, question)
+	answered := 0
+	for _, answer := range answers {
+		if answer.Error != "" || answer.Output == "" {
+			continue
+		}
+		answered++
+		fmt.Fprintf(&sb, "\n# Answer of %s\n\n%s\n", answer.Model, answer.Output)
+	}
+	return sb.String(), answered > 0
+}
+
+func runCouncilMember(ctx context.Context, runner AgentRunner, opts CouncilOptions, model *data.Model, prompt string, files []*FileData) CouncilAnswer {
+	answer := CouncilAnswer{Model: model.Name}
+
+	op := AgentOptions{
+		Ctx:           ctx,
+		Prompt:        prompt,
+		Files:         files,
+		ModelInfo:     model,
+		YoloMode:      true, // No tools, nothing to confirm
+		SessionName:   "",   // In-memory session, every member starts fresh
+		MaxRecursions: 1,
+		TokenUsage:    NewTokenUsage(),
+		ModelName:     model.Name,
+	}
+	if opts.Agent != nil {
+		op.AgentName = opts.Agent.Name
+		op.SysPrompt = opts.Agent.SystemPrompt
+		op.ThinkingLevel = opts.Agent.Think
+	}
+
+	start := time.Now()
+	output, err := runCaptured(runner, &op)
+	answer.LatencyMs = time.Since(start).Milliseconds()
+	answer.Output = output
+	if err != nil {
+		answer.Error = err.Error()
+	}
+
+	tu := op.TokenUsage
+	answer.InputTokens = tu.InputTokens
+	answer.OutputTokens = tu.OutputTokens
+	answer.Cost = tu.CostAt(model.InputPrice, model.OutputPrice)
+	return answer
+}
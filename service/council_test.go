@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestRunCouncil(t *testing.T) {
+	models := []*data.Model{{Name: "m1", InputPrice: 1, OutputPrice: 2}, {Name: "m2"}, {Name: "m3"}}
+
+	var judgePrompt string
+	runner := func(op *AgentOptions) error {
+		if op.ModelName == "m2" {
+			return fmt.Errorf("boom")
+		}
+		answer := "answer from " + op.ModelName
+		if op.ModelName == "judge" {
+			judgePrompt = op.Prompt
+			answer = "m1 and m3 agree"
+		}
+		if err := os.WriteFile(op.OutputFile, []byte(answer), 0644); err != nil {
+			return err
+		}
+		op.TokenUsage.RecordTokenUsage(1000, 500, 0, 0, 1500)
+		return nil
+	}
+
+	var seen int
+	result := RunCouncil(CouncilOptions{
+		Prompt:   "question",
+		Models:   models,
+		Judge:    &data.Model{Name: "judge"},
+		Parallel: 2,
+		Runner:   runner,
+		OnAnswer: func(CouncilAnswer) { seen++ },
+	})
+
+	if len(result.Answers) != 3 || seen != 3 {
+		t.Fatalf("expected 3 answers and callbacks, got %d and %d", len(result.Answers), seen)
+	}
+	first := result.Answers[0]
+	if first.Model != "m1" || first.Output != "answer from m1" || first.Cost != 0.002 {
+		t.Errorf("unexpected first answer: %+v", first)
+	}
+	if result.Answers[1].Error != "boom" || result.Answers[2].Model != "m3" {
+		t.Errorf("the answers are not in the order of the models: %+v", result.Answers)
+	}
+	if result.Verdict == nil || result.Verdict.Output != "m1 and m3 agree" {
+		t.Fatalf("unexpected verdict: %+v", result.Verdict)
+	}
+	if !strings.Contains(judgePrompt, "# Answer of m3\n\nanswer from m3") || strings.Contains(judgePrompt, "Answer of m2") {
+		t.Errorf("the judge did not get the answers: %q", judgePrompt)
+	}
+
+	// Without an answer there is nothing to judge
+	result = RunCouncil(CouncilOptions{
+		Models: []*data.Model{{Name: "m2"}},
+		Judge:  &data.Model{Name: "judge"},
+		Runner: runner,
+	})
+	if result.Verdict != nil {
+		t.Errorf("expected no verdict, got %+v", result.Verdict)
+	}
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+)
+
+// CrashInfo is what is known about a crash when it is caught.
+type CrashInfo struct {
+	Value   interface{} // Value passed to panic
+	Stack   []byte
+	Version string // Version of gllm, the one set with SetAppVersion when empty
+	Command string // Command that was running, e.g. "gllm session list"
+}
+
+// appVersion is the version of gllm written in crash reports.
+var appVersion = "dev"
+
+// SetAppVersion sets the version of gllm written in crash reports.
+func SetAppVersion(version string) {
+	appVersion = version
+}
+
+// ReportRecoveredPanic saves a crash report for a panic that was recovered, so the stack
+// isn't lost when the run goes on with an error.
+func ReportRecoveredPanic(value interface{}, stack []byte) {
+	path, err := data.SaveCrashReport(BuildCrashReport(CrashInfo{Value: value, Stack: stack}))
+	if err != nil {
+		util.LogDebugf("Failed to save the crash report: %v\n", err)
+		return
+	}
+	util.LogWarnf("A crash report was saved to %s, 'gllm crash report' prints it for an issue\n", path)
+}
+
+// BuildCrashReport renders a crash report for an issue. The config is summed up without
+// keys, endpoints paths or prompts, and the whole report goes through the built-in
+// redaction rules, so it can be pasted as is.
+func BuildCrashReport(info CrashInfo) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "gllm crash report\n\n")
+	fmt.Fprintf(&sb, "Time:    %s\n", time.Now().Format(time.RFC3339))
+	if info.Version == "" {
+		info.Version = appVersion
+	}
+	fmt.Fprintf(&sb, "Version: %s\n", info.Version)
+	fmt.Fprintf(&sb, "Go:      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if info.Command != "" {
+		fmt.Fprintf(&sb, "Command: %s\n", info.Command)
+	}
+	fmt.Fprintf(&sb, "\nPanic: %v\n\nStack:\n%s\n", info.Value, strings.TrimRight(string(info.Stack), "\n"))
+	fmt.Fprintf(&sb, "\nConfig:\n%s", crashConfigSummary())
+
+	if logs := util.RecentLogs(); len(logs) > 0 {
+		fmt.Fprintf(&sb, "\nRecent log:\n")
+		for _, line := range logs {
+			fmt.Fprintf(&sb, "  %s\n", line)
+		}
+	}
+	return sanitizeCrashReport(sb.String())
+}
+
+// crashConfigSummary sums up the config, a config too broken to read doesn't lose the report.
+func crashConfigSummary() (summary string) {
+	defer func() {
+		if r := recover(); r != nil {
+			summary = fmt.Sprintf("  unavailable: %v\n", r)
+		}
+	}()
+	var sb strings.Builder
+	store := data.NewConfigStore()
+	fmt.Fprintf(&sb, "  models: %d, agents: %d\n", len(store.GetModels()), len(store.GetAgentNames()))
+	if agent := store.GetActiveAgent(); agent != nil {
+		fmt.Fprintf(&sb, "  active agent: %s\n", agent.Name)
+		model := agent.Model
+		host := ""
+		if u, err := url.Parse(model.Endpoint); err == nil {
+			host = u.Host
+		}
+		fmt.Fprintf(&sb, "  model: %s %s", doctorProvider(&model), model.Model)
+		if host != "" {
+			fmt.Fprintf(&sb, " (%s)", host)
+		}
+		sb.WriteString("\n")
+		fmt.Fprintf(&sb, "  tools: %d, think: %s\n", len(agent.Tools), agent.Think)
+		if len(agent.Capabilities) > 0 {
+			caps := append([]string(nil), agent.Capabilities...)
+			sort.Strings(caps)
+			fmt.Fprintf(&sb, "  capabilities: %s\n", strings.Join(caps, ", "))
+		}
+	} else {
+		fmt.Fprintf(&sb, "  active agent: none\n")
+	}
+	fmt.Fprintf(&sb, "  MCP servers enabled: %d\n", len(data.GetSettingsStore().GetAllowedMCPServers()))
+	fmt.Fprintf(&sb, "  plain: %v, colors: %v\n", data.PlainOutput(), !data.ColorsDisabled())
+	return sb.String()
+}
+
+// sanitizeCrashReport removes the keys of the configured models and anything the built-in
+// redaction rules find.
+func sanitizeCrashReport(report string) (sanitized string) {
+	sanitized = report
+	defer func() {
+		// The report matters more than a perfect redaction
+		recover()
+	}()
+	for _, model := range data.NewConfigStore().GetModels() {
+		if len(model.Key) >= 8 {
+			sanitized = strings.ReplaceAll(sanitized, model.Key, "[REDACTED_KEY]")
+		}
+	}
+	// Paths under the home directory carry the user name
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		sanitized = strings.ReplaceAll(sanitized, home+string(os.PathSeparator), "~"+string(os.PathSeparator))
+	}
+	return NewRedactor(data.RedactionSettings{}).Redact(sanitized)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+	"github.com/spf13/viper"
+)
+
+func TestBuildCrashReport(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := data.EnsureConfigDir(); err != nil {
+		t.Fatal(err)
+	}
+	config := "models:\n  gpt:\n    endpoint: https://api.openai.com/v1\n    model: gpt-4o\n    key: my-secret-key-123\n"
+	if err := os.WriteFile(data.GetConfigFilePath(), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	if err := data.NewConfigStore().SetConfigFile(data.GetConfigFilePath()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(viper.Reset)
+
+	report := BuildCrashReport(CrashInfo{
+		Value:   "index out of range",
+		Stack:   []byte("goroutine 1 [running]:\nmain.main()\n\tkey my-secret-key-123 and sk-abcdefghijklmnopqrstuvwxyz\n"),
+		Version: "v1.2.3",
+		Command: "gllm session list",
+	})
+	for _, want := range []string{"Version: v1.2.3", "Command: gllm session list", "Panic: index out of range", "goroutine 1", "models: 1, agents: 0"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected %q in the report:\n%s", want, report)
+		}
+	}
+	for _, secret := range []string{"my-secret-key-123", "sk-abcdefghijklmnopqrstuvwxyz"} {
+		if strings.Contains(report, secret) {
+			t.Errorf("expected %q redacted:\n%s", secret, report)
+		}
+	}
+}
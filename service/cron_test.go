@@ -0,0 +1,124 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"0 9 * * 1-5",
+		"*/15 * * * *",
+		"0 0 1,15 * *",
+		"30 8 * jan-mar mon,wed,fri",
+		"5/10 * * * 7",
+		"@daily",
+		"@Hourly",
+	}
+	for _, expr := range valid {
+		if _, err := ParseCron(expr); err != nil {
+			t.Errorf("ParseCron(%q) failed: %v", expr, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"@sometimes",
+		"a b c d e",
+	}
+	for _, expr := range invalid {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		expr string
+		time string
+		want bool
+	}{
+		{"0 9 * * 1-5", "2026-10-16 09:00", true},  // Friday
+		{"0 9 * * 1-5", "2026-10-17 09:00", false}, // Saturday
+		{"0 9 * * 1-5", "2026-10-16 09:01", false},
+		{"*/15 * * * *", "2026-10-16 10:45", true},
+		{"*/15 * * * *", "2026-10-16 10:46", false},
+		{"0 0 * * 7", "2026-10-18 00:00", true}, // Sunday as 7
+		{"0 0 * * sun", "2026-10-18 00:00", true},
+		{"0 12 * jun *", "2026-06-03 12:00", true},
+		{"0 12 * jun *", "2026-07-03 12:00", false},
+		// Both day fields restricted: either matches
+		{"0 0 13 * fri", "2026-11-13 00:00", true}, // Friday the 13th
+		{"0 0 13 * fri", "2026-10-16 00:00", true}, // Friday
+		{"0 0 13 * fri", "2026-10-13 00:00", true}, // 13th
+		{"0 0 13 * fri", "2026-10-14 00:00", false},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) failed: %v", tt.expr, err)
+		}
+		if got := c.Matches(at(tt.time)); got != tt.want {
+			t.Errorf("ParseCron(%q).Matches(%s) = %v, want %v", tt.expr, tt.time, got, tt.want)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		expr string
+		from string
+		want string
+	}{
+		{"0 9 * * 1-5", "2026-10-16 09:00:00", "2026-10-19 09:00:00"}, // Friday to Monday
+		{"0 9 * * 1-5", "2026-10-16 08:59:30", "2026-10-16 09:00:00"},
+		{"*/15 * * * *", "2026-10-16 10:46:10", "2026-10-16 11:00:00"},
+		{"@daily", "2026-12-31 23:59:00", "2027-01-01 00:00:00"},
+		{"@monthly", "2026-01-15 12:00:00", "2026-02-01 00:00:00"},
+		{"0 0 29 2 *", "2026-03-01 00:00:00", "2028-02-29 00:00:00"},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) failed: %v", tt.expr, err)
+		}
+		if got := c.Next(at(tt.from)); !got.Equal(at(tt.want)) {
+			t.Errorf("ParseCron(%q).Next(%s) = %s, want %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+
+	// February 30th never comes
+	c, err := ParseCron("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Next(at("2026-01-01 00:00:00")); !got.IsZero() {
+		t.Errorf("Next of February 30th = %s, want the zero time", got)
+	}
+}
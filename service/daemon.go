@@ -0,0 +1,490 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/activebook/gllm/io"
+)
+
+// The daemon serves named sessions over a local socket: a session keeps running in the daemon,
+// with its MCP connections warm, while terminals attach to it and detach from it. Every
+// attached client receives the SSE stream of the session, the same events as gllm serve.
+const (
+	daemonClientBuffer = 256 // Events buffered per client before a slow client is dropped
+	daemonHost         = "http://gllm-daemon"
+)
+
+// DaemonRunner runs a prompt of a session, writing the run to out. The hub closes out.
+type DaemonRunner func(ctx context.Context, session, prompt string, out *io.SSEOutput)
+
+// DaemonSessionStatus describes a session kept by the daemon.
+type DaemonSessionStatus struct {
+	Name     string    `json:"name"`
+	Clients  int       `json:"clients"`
+	Busy     bool      `json:"busy"`
+	Queued   int       `json:"queued"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// DaemonStatus describes the daemon.
+type DaemonStatus struct {
+	PID      int                   `json:"pid"`
+	Started  time.Time             `json:"started"`
+	Sessions []DaemonSessionStatus `json:"sessions"`
+}
+
+// DaemonPromptRequest is the body of POST /v1/sessions/prompt and /v1/sessions/cancel.
+type DaemonPromptRequest struct {
+	Session string `json:"session"`
+	Prompt  string `json:"prompt,omitempty"`
+}
+
+// daemonClient is an attached client, fed the chunks of the session stream.
+type daemonClient struct {
+	ch chan []byte
+}
+
+// daemonSession is a session kept by the daemon, with its clients and queued prompts.
+type daemonSession struct {
+	name     string
+	clients  map[*daemonClient]struct{}
+	queue    []string
+	busy     bool
+	cancel   context.CancelFunc
+	lastUsed time.Time
+}
+
+// DaemonHub keeps the sessions of the daemon. Prompts of a session run one after the other,
+// and their output is broadcast to every client attached to the session.
+type DaemonHub struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	stop     context.CancelFunc
+	run      DaemonRunner
+	started  time.Time
+	sessions map[string]*daemonSession
+	wg       sync.WaitGroup
+
+	// OnShutdown is called when a client asks the daemon to stop.
+	OnShutdown func()
+}
+
+// NewDaemonHub creates a hub running the prompts of its sessions with run.
+func NewDaemonHub(run DaemonRunner) *DaemonHub {
+	ctx, stop := context.WithCancel(context.Background())
+	return &DaemonHub{
+		ctx:      ctx,
+		stop:     stop,
+		run:      run,
+		started:  time.Now(),
+		sessions: make(map[string]*daemonSession),
+	}
+}
+
+// session returns a session by name, creating it. The caller holds the lock.
+func (h *DaemonHub) session(name string) *daemonSession {
+	s, ok := h.sessions[name]
+	if !ok {
+		s = &daemonSession{name: name, clients: make(map[*daemonClient]struct{}), lastUsed: time.Now()}
+		h.sessions[name] = s
+	}
+	return s
+}
+
+// Attach adds a client to a session, creating the session. The returned channel receives
+// the raw SSE chunks of the session and is closed on detach or when the client is too slow.
+func (h *DaemonHub) Attach(name string) (<-chan []byte, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.session(name)
+	c := &daemonClient{ch: make(chan []byte, daemonClientBuffer)}
+	s.clients[c] = struct{}{}
+	return c.ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if _, ok := s.clients[c]; ok {
+			delete(s.clients, c)
+			close(c.ch)
+		}
+	}
+}
+
+// Prompt queues a prompt in a session, starting its worker if it is idle.
+func (h *DaemonHub) Prompt(name, prompt string) error {
+	if strings.TrimSpace(prompt) == "" {
+		return fmt.Errorf("empty prompt")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.ctx.Err() != nil {
+		return fmt.Errorf("daemon is shutting down")
+	}
+	s := h.session(name)
+	s.queue = append(s.queue, prompt)
+	s.lastUsed = time.Now()
+	if !s.busy {
+		s.busy = true
+		h.wg.Add(1)
+		go h.work(s)
+	}
+	return nil
+}
+
+// Cancel cancels the running prompt of a session and drops its queued prompts.
+func (h *DaemonHub) Cancel(name string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.sessions[name]
+	if !ok || !s.busy {
+		return false
+	}
+	s.queue = nil
+	if s.cancel != nil {
+		s.cancel()
+	}
+	return true
+}
+
+// work runs the queued prompts of a session until its queue is empty.
+func (h *DaemonHub) work(s *daemonSession) {
+	defer h.wg.Done()
+	for {
+		h.mu.Lock()
+		if len(s.queue) == 0 || h.ctx.Err() != nil {
+			s.busy = false
+			s.cancel = nil
+			s.queue = nil
+			h.mu.Unlock()
+			return
+		}
+		prompt := s.queue[0]
+		s.queue = s.queue[1:]
+		ctx, cancel := context.WithCancel(h.ctx)
+		s.cancel = cancel
+		h.mu.Unlock()
+
+		// The broadcast writer always flushes, so the output cannot fail
+		out, _ := io.NewSSEOutput(&daemonBroadcast{hub: h, session: s})
+		out.WritePromptEvent(prompt)
+		h.run(ctx, s.name, prompt, out)
+		out.Close()
+		cancel()
+	}
+}
+
+// broadcast sends a chunk to every client of a session, dropping the clients that fall behind.
+func (h *DaemonHub) broadcast(s *daemonSession, chunk []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range s.clients {
+		select {
+		case c.ch <- chunk:
+		default:
+			delete(s.clients, c)
+			close(c.ch)
+		}
+	}
+}
+
+// Status returns the status of the daemon and its sessions.
+func (h *DaemonHub) Status() DaemonStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := DaemonStatus{PID: os.Getpid(), Started: h.started}
+	for _, s := range h.sessions {
+		status.Sessions = append(status.Sessions, DaemonSessionStatus{
+			Name:     s.name,
+			Clients:  len(s.clients),
+			Busy:     s.busy,
+			Queued:   len(s.queue),
+			LastUsed: s.lastUsed,
+		})
+	}
+	sort.Slice(status.Sessions, func(i, j int) bool { return status.Sessions[i].Name < status.Sessions[j].Name })
+	return status
+}
+
+// Close cancels the running prompts, waits for the workers and detaches every client.
+func (h *DaemonHub) Close() {
+	h.stop()
+	h.wg.Wait()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, s := range h.sessions {
+		for c := range s.clients {
+			delete(s.clients, c)
+			close(c.ch)
+		}
+	}
+}
+
+// daemonBroadcast is the http.ResponseWriter an SSEOutput of a session writes to.
+type daemonBroadcast struct {
+	hub     *DaemonHub
+	session *daemonSession
+	header  http.Header
+}
+
+func (b *daemonBroadcast) Header() http.Header {
+	if b.header == nil {
+		b.header = make(http.Header)
+	}
+	return b.header
+}
+
+func (b *daemonBroadcast) Write(p []byte) (int, error) {
+	b.hub.broadcast(b.session, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func (b *daemonBroadcast) WriteHeader(int) {}
+
+func (b *daemonBroadcast) Flush() {}
+
+// Register adds the session routes of the daemon to a mux.
+func (h *DaemonHub) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/v1/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(h.Status())
+	})
+	mux.HandleFunc("/v1/shutdown", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		if h.OnShutdown != nil {
+			go h.OnShutdown()
+		}
+	})
+	mux.HandleFunc("/v1/sessions/events", h.eventsHandler)
+	mux.HandleFunc("/v1/sessions/prompt", func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeDaemonRequest(w, r)
+		if !ok {
+			return
+		}
+		if err := h.Prompt(req.Session, req.Prompt); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	})
+	mux.HandleFunc("/v1/sessions/cancel", func(w http.ResponseWriter, r *http.Request) {
+		req, ok := decodeDaemonRequest(w, r)
+		if !ok {
+			return
+		}
+		if !h.Cancel(req.Session) {
+			http.Error(w, fmt.Sprintf("session '%s' is not running", req.Session), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// eventsHandler handles GET /v1/sessions/events?session=NAME, streaming the session to a client.
+func (h *DaemonHub) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("session")
+	if name == "" {
+		http.Error(w, "missing session", http.StatusBadRequest)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported by client", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ch, detach := h.Attach(name)
+	defer detach()
+	for {
+		select {
+		case chunk, ok := <-ch:
+			if !ok {
+				return
+			}
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func decodeDaemonRequest(w http.ResponseWriter, r *http.Request) (*DaemonPromptRequest, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	var req DaemonPromptRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	if req.Session == "" {
+		http.Error(w, "missing session", http.StatusBadRequest)
+		return nil, false
+	}
+	return &req, true
+}
+
+// DaemonEvent is an event of a session stream, as read by an attached client.
+type DaemonEvent struct {
+	Type    string                 // content, reasoning, done, or the type of a GLLM event
+	Content string                 // Delta text of content and reasoning events
+	Data    map[string]interface{} // Payload of GLLM events
+}
+
+// DaemonClient talks to the daemon over its socket.
+type DaemonClient struct {
+	http *http.Client
+}
+
+// NewDaemonClient creates a client of the daemon listening on socketPath.
+func NewDaemonClient(socketPath string) *DaemonClient {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}
+	return &DaemonClient{http: &http.Client{Transport: transport}}
+}
+
+// Status returns the status of the daemon, failing if it is not running.
+func (c *DaemonClient) Status() (*DaemonStatus, error) {
+	resp, err := c.http.Get(daemonHost + "/v1/status")
+	if err != nil {
+		return nil, fmt.Errorf("daemon is not running: %w", err)
+	}
+	defer resp.Body.Close()
+	var status DaemonStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Prompt queues a prompt, or a slash command, in a session.
+func (c *DaemonClient) Prompt(session, prompt string) error {
+	return c.post("/v1/sessions/prompt", DaemonPromptRequest{Session: session, Prompt: prompt})
+}
+
+// Cancel cancels the running prompt of a session.
+func (c *DaemonClient) Cancel(session string) error {
+	return c.post("/v1/sessions/cancel", DaemonPromptRequest{Session: session})
+}
+
+// Interact resolves a pending interaction, with the body of POST /v1/interact.
+func (c *DaemonClient) Interact(body interface{}) error {
+	return c.post("/v1/interact", body)
+}
+
+// Shutdown asks the daemon to stop.
+func (c *DaemonClient) Shutdown() error {
+	return c.post("/v1/shutdown", struct{}{})
+}
+
+func (c *DaemonClient) post(path string, body interface{}) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Post(daemonHost+path, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("daemon is not running: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var msg bytes.Buffer
+		msg.ReadFrom(resp.Body)
+		return fmt.Errorf("daemon: %s", strings.TrimSpace(msg.String()))
+	}
+	return nil
+}
+
+// Events attaches to a session and returns its events. The channel is closed when ctx is
+// done or the daemon ends the stream.
+func (c *DaemonClient) Events(ctx context.Context, session string) (<-chan DaemonEvent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, daemonHost+"/v1/sessions/events?session="+session, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("daemon is not running: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("daemon: %s", resp.Status)
+	}
+
+	events := make(chan DaemonEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			line, ok := strings.CutPrefix(scanner.Text(), "data: ")
+			if !ok {
+				continue
+			}
+			ev, ok := ParseDaemonEvent(line)
+			if !ok {
+				continue
+			}
+			select {
+			case events <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return events, nil
+}
+
+// ParseDaemonEvent parses the data of an SSE packet of the session stream.
+func ParseDaemonEvent(data string) (DaemonEvent, bool) {
+	if data == "[DONE]" {
+		return DaemonEvent{Type: "done"}, true
+	}
+	var packet struct {
+		Type    string                 `json:"type"`
+		Data    map[string]interface{} `json:"data"`
+		Choices []struct {
+			Delta map[string]string `json:"delta"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal([]byte(data), &packet); err != nil {
+		return DaemonEvent{}, false
+	}
+	if packet.Type != "" {
+		return DaemonEvent{Type: packet.Type, Data: packet.Data}, true
+	}
+	if len(packet.Choices) == 0 {
+		return DaemonEvent{}, false
+	}
+	delta := packet.Choices[0].Delta
+	if text, ok := delta["reasoning_content"]; ok {
+		return DaemonEvent{Type: "reasoning", Content: text}, true
+	}
+	return DaemonEvent{Type: "content", Content: delta["content"]}, true
+}
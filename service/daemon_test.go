@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/activebook/gllm/io"
+)
+
+func TestDaemonHub(t *testing.T) {
+	release := make(chan struct{})
+	hub := NewDaemonHub(func(ctx context.Context, session, prompt string, out *io.SSEOutput) {
+		switch prompt {
+		case "wait":
+			<-release
+		case "block":
+			<-ctx.Done()
+			out.WriteErrorEvent("cancelled", "cancelled")
+			return
+		}
+		out.Writef("%s: %s", session, prompt)
+	})
+
+	socketPath := filepath.Join(t.TempDir(), "daemon.sock")
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	hub.Register(mux)
+	server := &http.Server{Handler: mux}
+	go server.Serve(ln)
+	t.Cleanup(func() {
+		hub.Close()
+		server.Close()
+	})
+
+	client := NewDaemonClient(socketPath)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	first, err := client.Events(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := client.Events(ctx, "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Both clients are attached once the status counts them
+	waitFor(t, func() bool {
+		status, err := client.Status()
+		return err == nil && len(status.Sessions) == 1 && status.Sessions[0].Clients == 2
+	})
+
+	next := func(events <-chan DaemonEvent) DaemonEvent {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event")
+			return DaemonEvent{}
+		}
+	}
+
+	// Prompts run in order, every client sees them
+	if err := client.Prompt("s1", "wait"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Prompt("s1", "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(first); ev.Type != "prompt" || ev.Data["content"] != "wait" {
+		t.Fatalf("expected the prompt event, got %+v", ev)
+	}
+	status, _ := client.Status()
+	if !status.Sessions[0].Busy || status.Sessions[0].Queued != 1 {
+		t.Errorf("expected a busy session with one queued prompt, got %+v", status.Sessions[0])
+	}
+	close(release)
+	wantTypes := []string{"prompt", "content", "done", "prompt", "content", "done"}
+	for i, events := range []<-chan DaemonEvent{first, second} {
+		want := wantTypes
+		if i == 0 {
+			want = want[1:] // the first prompt event was read already
+		}
+		var got []DaemonEvent
+		for _, typ := range want {
+			ev := next(events)
+			if ev.Type != typ {
+				t.Fatalf("client %d: expected %s, got %+v", i, typ, ev)
+			}
+			got = append(got, ev)
+		}
+		if answer := got[len(got)-2]; answer.Content != "s1: hello" {
+			t.Errorf("expected the answer of the second prompt, got %q", answer.Content)
+		}
+	}
+
+	// Cancel stops the running prompt
+	if err := client.Prompt("s1", "block"); err != nil {
+		t.Fatal(err)
+	}
+	next(first)
+	next(second)
+	waitFor(t, func() bool { status, _ := client.Status(); return status.Sessions[0].Busy })
+	if err := client.Cancel("s1"); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(first); ev.Type != "error" {
+		t.Errorf("expected the cancellation, got %+v", ev)
+	}
+	next(second)
+	waitFor(t, func() bool { status, _ := client.Status(); return !status.Sessions[0].Busy })
+	if err := client.Cancel("s1"); err == nil {
+		t.Error("expected an error cancelling an idle session")
+	}
+	if err := client.Prompt("s1", " "); err == nil {
+		t.Error("expected an error for an empty prompt")
+	}
+}
+
+func TestParseDaemonEvent(t *testing.T) {
+	cases := []struct {
+		data string
+		want DaemonEvent
+	}{
+		{`[DONE]`, DaemonEvent{Type: "done"}},
+		{`{"choices":[{"delta":{"content":"hi"}}]}`, DaemonEvent{Type: "content", Content: "hi"}},
+		{`{"choices":[{"delta":{"reasoning_content":"hmm"}}]}`, DaemonEvent{Type: "reasoning", Content: "hmm"}},
+	}
+	for _, c := range cases {
+		got, ok := ParseDaemonEvent(c.data)
+		if !ok || got.Type != c.want.Type || got.Content != c.want.Content {
+			t.Errorf("ParseDaemonEvent(%s) = %+v, want %+v", c.data, got, c.want)
+		}
+	}
+	got, ok := ParseDaemonEvent(`{"type":"status","data":{"content":"session_ready"}}`)
+	if !ok || got.Type != "status" || got.Data["content"] != "session_ready" {
+		t.Errorf("unexpected status event %+v", got)
+	}
+	if _, ok := ParseDaemonEvent(`not json`); ok {
+		t.Error("expected invalid data to be skipped")
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
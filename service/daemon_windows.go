@@ -0,0 +1,16 @@
+//go:build windows
+
+package service
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// DetachProcess makes a command outlive the console that starts it.
+func DetachProcess(cmd *exec.Cmd) {
+	const detachedProcess = 0x00000008
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP,
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDatabaseEngine(t *testing.T) {
+	tests := []struct {
+		dsn, engine, source string
+	}{
+		{"postgres://u:p@localhost/app", DatabasePostgres, "postgres://u:p@localhost/app"},
+		{"mysql://u:p@tcp(db:3306)/app", DatabaseMySQL, "u:p@tcp(db:3306)/app"},
+		{"sqlite:data/app.db", DatabaseSQLite, "data/app.db"},
+		{"/tmp/app.sqlite3", DatabaseSQLite, "/tmp/app.sqlite3"},
+	}
+	for _, tt := range tests {
+		engine, source, err := DatabaseEngine(tt.dsn)
+		if err != nil || engine != tt.engine || source != tt.source {
+			t.Errorf("DatabaseEngine(%q) = %q, %q, %v", tt.dsn, engine, source, err)
+		}
+	}
+	if _, _, err := DatabaseEngine("mongodb://localhost"); err == nil {
+		t.Error("expected an unrecognized DSN error")
+	}
+	for source, want := range map[string]string{"data/app.db": "data/app.db", "file:data/app.db?cache=shared": "data/app.db", ":memory:": ""} {
+		if got := sqliteFilePath(source); got != want {
+			t.Errorf("sqliteFilePath(%q) = %q, want %q", source, got, want)
+		}
+	}
+}
+
+func TestIsReadOnlySQL(t *testing.T) {
+	tests := map[string]bool{
+		"SELECT * FROM users":                      true,
+		"  with t as (select 1) select * from t; ": true,
+		"-- count\nSELECT count(*) FROM users":     true,
+		"EXPLAIN SELECT 1":                         true,
+		"PRAGMA table_info(users)":                 true,
+		"PRAGMA journal_mode = WAL":                false,
+		"DELETE FROM users":                        false,
+		"/* SELECT */ DROP TABLE users":            false,
+		"SELECT 1; DROP TABLE users":               false,
+		"SELECT '--' AS a; UPDATE users SET a = 1": false,
+		"": false,
+	}
+	for query, want := range tests {
+		if got := IsReadOnlySQL(query); got != want {
+			t.Errorf("IsReadOnlySQL(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestRunDatabaseQuerySQLite(t *testing.T) {
+	dsn := "sqlite:" + filepath.Join(t.TempDir(), "app.db")
+	ctx := context.Background()
+
+	db, engine, err := OpenDatabase(dsn, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE users (id INTEGER, name TEXT, note TEXT)",
+		"INSERT INTO users VALUES (1, 'Ann', 'a|b'), (2, 'Bob', NULL), (3, 'Cid', '" + strings.Repeat("x", 100) + "')",
+	} {
+		if _, err := RunDatabaseQuery(ctx, db, engine, stmt, true, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	db, engine, err = OpenDatabase(dsn, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	got, err := RunDatabaseQuery(ctx, db, engine, "SELECT id, name, note FROM users ORDER BY id", false, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "| id | name | note |\n| --- | --- | --- |\n| 1 | Ann | a\\|b |\n| 2 | Bob | NULL |\n\nShowing 2 of 3 rows."
+	if got != want {
+		t.Errorf("result =\n%s\nwant\n%s", got, want)
+	}
+
+	got, _ = RunDatabaseQuery(ctx, db, engine, "SELECT note FROM users WHERE id = 3", false, 0)
+	if !strings.Contains(got, strings.Repeat("x", 79)+"…") || !strings.HasSuffix(got, "1 row.") {
+		t.Errorf("long cell not cut: %s", got)
+	}
+
+	got, _ = RunDatabaseQuery(ctx, db, engine, "SELECT id FROM users WHERE id > 5", false, 0)
+	if got != "No rows. Columns: id." {
+		t.Errorf("empty result = %q", got)
+	}
+
+	// The read only connection refuses writes the keyword check would miss
+	if _, err := RunDatabaseQuery(ctx, db, engine, "WITH x AS (SELECT 1) DELETE FROM users", false, 0); err == nil {
+		t.Error("expected a read only error")
+	}
+}
@@ -0,0 +1,635 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+)
+
+// Formats of the data files of inspect_data and query_data.
+const (
+	DataFormatCSV     = "csv"
+	DataFormatTSV     = "tsv"
+	DataFormatJSON    = "json"
+	DataFormatJSONL   = "jsonl"
+	DataFormatParquet = "parquet"
+)
+
+const (
+	defaultInspectRows = 5
+	maxInspectRows     = 1000000 // Rows read for the statistics of a file
+	maxDistinctValues  = 1000    // Distinct values counted per column
+	maxJSONDataSize    = 200 * 1024 * 1024
+)
+
+// DataFormatOf returns the format of a data file by its extension.
+func DataFormatOf(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		return DataFormatCSV, nil
+	case ".tsv", ".tab":
+		return DataFormatTSV, nil
+	case ".json":
+		return DataFormatJSON, nil
+	case ".jsonl", ".ndjson":
+		return DataFormatJSONL, nil
+	case ".parquet", ".pq":
+		return DataFormatParquet, nil
+	}
+	return "", fmt.Errorf("unsupported data file %s, expected .csv, .tsv, .json, .jsonl or .parquet", path)
+}
+
+// dataRecords streams the rows of a data file. Values are nil, int64, float64, bool, string
+// or time.Time; nested JSON and Parquet values are written as JSON text.
+type dataRecords struct {
+	Format  string
+	Columns []string
+	Total   int64 // Number of rows when the format stores it, -1 otherwise
+	next    func() ([]any, error)
+	close   func() error
+}
+
+// Next returns the next row, or io.EOF after the last one.
+func (d *dataRecords) Next() ([]any, error) {
+	return d.next()
+}
+
+func (d *dataRecords) Close() error {
+	if d.close == nil {
+		return nil
+	}
+	return d.close()
+}
+
+// openDataFile opens a CSV, TSV, JSON array, JSON lines or Parquet file.
+func openDataFile(path string) (*dataRecords, error) {
+	format, err := DataFormatOf(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var records *dataRecords
+	switch format {
+	case DataFormatCSV, DataFormatTSV:
+		records, err = openCSVRecords(f, format == DataFormatTSV)
+	case DataFormatParquet:
+		records, err = openParquetRecords(f)
+	default:
+		records, err = openJSONRecords(f, format == DataFormatJSONL)
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	records.Format = format
+	records.close = f.Close
+	return records, nil
+}
+
+func openCSVRecords(f *os.File, tabs bool) (*dataRecords, error) {
+	r := csv.NewReader(bufio.NewReader(f))
+	if tabs {
+		r.Comma = '\t'
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, err
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	columns := uniqueColumns(header)
+	return &dataRecords{
+		Columns: columns,
+		Total:   -1,
+		next: func() ([]any, error) {
+			record, err := r.Read()
+			if err != nil {
+				return nil, err
+			}
+			row := make([]any, len(columns))
+			for i := range row {
+				if i < len(record) {
+					row[i] = parseDataValue(record[i])
+				}
+			}
+			return row, nil
+		},
+	}, nil
+}
+
+// parseDataValue types a text value: empty is NULL, numbers are numbers.
+func parseDataValue(s string) any {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+	return s
+}
+
+// uniqueColumns names the empty columns and numbers the repeated ones.
+func uniqueColumns(names []string) []string {
+	seen := map[string]int{}
+	columns := make([]string, len(names))
+	for i, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			name = fmt.Sprintf("column_%d", i+1)
+		}
+		if n := seen[strings.ToLower(name)]; n > 0 {
+			seen[strings.ToLower(name)]++
+			name = fmt.Sprintf("%s_%d", name, n+1)
+		}
+		seen[strings.ToLower(name)]++
+		columns[i] = name
+	}
+	return columns
+}
+
+// openJSONRecords reads a JSON array of objects, an object holding one, or JSON lines. The file
+// is read whole, the columns are the keys of all the objects.
+func openJSONRecords(f *os.File, lines bool) (*dataRecords, error) {
+	content, err := io.ReadAll(io.LimitReader(f, maxJSONDataSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(content) > maxJSONDataSize {
+		return nil, fmt.Errorf("JSON file larger than %d MB", maxJSONDataSize/(1024*1024))
+	}
+
+	var objects []map[string]any
+	if lines {
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		for {
+			var obj map[string]any
+			if err := dec.Decode(&obj); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			objects = append(objects, obj)
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(content))
+		dec.UseNumber()
+		var doc any
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		objects, err = jsonObjects(doc)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Columns in the order they first appear
+	var columns []string
+	index := map[string]int{}
+	for _, obj := range objects {
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			if _, ok := index[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			index[k] = len(columns)
+			columns = append(columns, k)
+		}
+	}
+
+	i := 0
+	return &dataRecords{
+		Columns: columns,
+		Total:   int64(len(objects)),
+		next: func() ([]any, error) {
+			if i >= len(objects) {
+				return nil, io.EOF
+			}
+			row := make([]any, len(columns))
+			for k, v := range objects[i] {
+				row[index[k]] = jsonDataValue(v)
+			}
+			i++
+			return row, nil
+		},
+	}, nil
+}
+
+// jsonObjects finds the records of a JSON document: the array, the array of the single key of
+// an object such as {"data": [...]}, or the object itself.
+func jsonObjects(doc any) ([]map[string]any, error) {
+	if obj, ok := doc.(map[string]any); ok {
+		var arrays []any
+		for _, v := range obj {
+			if _, ok := v.([]any); ok {
+				arrays = append(arrays, v)
+			}
+		}
+		if len(arrays) != 1 {
+			return []map[string]any{obj}, nil
+		}
+		doc = arrays[0]
+	}
+	items, ok := doc.([]any)
+	if !ok {
+		return nil, fmt.Errorf("expected an array of objects")
+	}
+	objects := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			obj = map[string]any{"value": item}
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+func jsonDataValue(v any) any {
+	switch v := v.(type) {
+	case nil, bool, string:
+		return v
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		nested, _ := json.Marshal(v)
+		return string(nested)
+	}
+}
+
+func openParquetRecords(f *os.File) (*dataRecords, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	file, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		return nil, err
+	}
+	fields := file.Schema().Fields()
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.Name()
+	}
+
+	reader := parquet.NewReader(file)
+	return &dataRecords{
+		Columns: columns,
+		Total:   file.NumRows(),
+		next: func() ([]any, error) {
+			record := map[string]any{}
+			if err := reader.Read(&record); err != nil {
+				return nil, err
+			}
+			row := make([]any, len(columns))
+			for i, field := range fields {
+				row[i] = parquetDataValue(field, record[field.Name()])
+			}
+			return row, nil
+		},
+	}, nil
+}
+
+func parquetDataValue(field parquet.Field, v any) any {
+	switch v := v.(type) {
+	case nil, bool, string, float64, time.Time:
+		return v
+	case int64:
+		if lt := field.Type().LogicalType(); lt != nil {
+			if ts, ok := lt.Value.(*format.TimestampType); ok && ts.Unit.Value != nil {
+				return time.Unix(0, v*int64(ts.Unit.Value.Duration())).UTC()
+			}
+		}
+		return v
+	case int32:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		return int64(v)
+	case float32:
+		return float64(v)
+	case []byte:
+		return string(v)
+	}
+	nested, _ := json.Marshal(v)
+	return string(nested)
+}
+
+// columnStats are the statistics of a column of a data file.
+type columnStats struct {
+	kinds    map[string]bool
+	nonNull  int64
+	distinct map[string]bool
+	capped   bool // More distinct values than counted
+	numbers  int64
+	sum      float64
+	minNum   float64
+	maxNum   float64
+	texts    int64
+	minText  string
+	maxText  string
+}
+
+func (c *columnStats) add(v any) {
+	if v == nil {
+		return
+	}
+	c.nonNull++
+	if len(c.distinct) < maxDistinctValues {
+		c.distinct[queryValue(v)] = true
+	} else if !c.distinct[queryValue(v)] {
+		c.capped = true
+	}
+
+	var n float64
+	isNumber := true
+	switch v := v.(type) {
+	case int64:
+		c.kinds["integer"] = true
+		n = float64(v)
+	case float64:
+		c.kinds["float"] = true
+		n = v
+	case bool:
+		c.kinds["boolean"] = true
+		isNumber = false
+	case time.Time:
+		c.kinds["timestamp"] = true
+		isNumber = false
+	default:
+		c.kinds["text"] = true
+		isNumber = false
+	}
+	if isNumber {
+		if c.numbers == 0 || n < c.minNum {
+			c.minNum = n
+		}
+		if c.numbers == 0 || n > c.maxNum {
+			c.maxNum = n
+		}
+		c.sum += n
+		c.numbers++
+		return
+	}
+	text := queryValue(v)
+	if c.texts == 0 || text < c.minText {
+		c.minText = text
+	}
+	if c.texts == 0 || text > c.maxText {
+		c.maxText = text
+	}
+	c.texts++
+}
+
+// kind names the type of the column, e.g. integer, or mixed (integer, text).
+func (c *columnStats) kind() string {
+	if c.kinds["integer"] && c.kinds["float"] {
+		delete(c.kinds, "integer")
+	}
+	var kinds []string
+	for k := range c.kinds {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	switch len(kinds) {
+	case 0:
+		return "null"
+	case 1:
+		return kinds[0]
+	}
+	return "mixed (" + strings.Join(kinds, ", ") + ")"
+}
+
+// InspectDataFile describes a data file: its format, row count and the type, count of values,
+// distinct values and range of each column, followed by its first rows.
+func InspectDataFile(path string, headRows int) (string, error) {
+	records, err := openDataFile(path)
+	if err != nil {
+		return "", err
+	}
+	defer records.Close()
+
+	stats := make([]*columnStats, len(records.Columns))
+	for i := range stats {
+		stats[i] = &columnStats{kinds: map[string]bool{}, distinct: map[string]bool{}}
+	}
+	var head [][]any
+	var scanned int64
+	for scanned < maxInspectRows {
+		row, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read row %d: %w", scanned+1, err)
+		}
+		scanned++
+		if len(head) < headRows {
+			head = append(head, row)
+		}
+		for i, v := range row {
+			stats[i].add(v)
+		}
+	}
+	truncated := scanned == maxInspectRows
+
+	var sb strings.Builder
+	rows := fmt.Sprintf("%d rows", scanned)
+	switch {
+	case records.Total >= 0:
+		rows = fmt.Sprintf("%d rows", records.Total)
+	case truncated:
+		rows = fmt.Sprintf("more than %d rows", scanned)
+	}
+	sb.WriteString(fmt.Sprintf("%s: %s, %s, %d columns\n", path, strings.ToUpper(records.Format), rows, len(records.Columns)))
+	if truncated {
+		sb.WriteString(fmt.Sprintf("Statistics of the first %d rows.\n", scanned))
+	}
+
+	sb.WriteString("\n| column | type | non-null | distinct | min | max | mean |\n| --- | --- | --- | --- | --- | --- | --- |\n")
+	for i, c := range stats {
+		distinct := strconv.Itoa(len(c.distinct))
+		if c.capped {
+			distinct += "+"
+		}
+		var lo, hi, mean string
+		switch {
+		case c.numbers > 0 && c.texts == 0:
+			lo, hi = strconv.FormatFloat(c.minNum, 'g', -1, 64), strconv.FormatFloat(c.maxNum, 'g', -1, 64)
+			mean = strconv.FormatFloat(c.sum/float64(c.numbers), 'g', 6, 64)
+		case c.texts > 0 && c.numbers == 0:
+			lo, hi = c.minText, c.maxText
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %d | %s | %s | %s | %s |\n",
+			queryCell(records.Columns[i]), c.kind(), c.nonNull, distinct, queryCell(lo), queryCell(hi), mean))
+	}
+
+	if len(head) > 0 {
+		sb.WriteString(fmt.Sprintf("\nFirst %d rows:\n|", len(head)))
+		for _, c := range records.Columns {
+			sb.WriteString(" " + queryCell(c) + " |")
+		}
+		sb.WriteString("\n|" + strings.Repeat(" --- |", len(records.Columns)) + "\n")
+		for _, row := range head {
+			sb.WriteString("|")
+			for _, v := range row {
+				sb.WriteString(" " + queryCell(queryValue(v)) + " |")
+			}
+			sb.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// DataTableName is the table a data file is loaded in by QueryDataFiles: its name without the
+// extension, with other characters than letters, digits and underscores replaced.
+func DataTableName(path string) string {
+	base := filepath.Base(path)
+	base = strings.TrimSuffix(base, filepath.Ext(base))
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, base)
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "t_" + name
+	}
+	return name
+}
+
+// QueryDataFiles loads the data files in an in-memory SQLite database, a table per file, and
+// runs the query on them. The result starts with the tables and their row counts.
+func QueryDataFiles(ctx context.Context, paths []string, query string, maxRows int) (string, error) {
+	// A statement like ATTACH would reach files beyond the loaded ones
+	if !IsReadOnlySQL(query) {
+		return "", fmt.Errorf("only a single SELECT or WITH statement can query data files")
+	}
+	db, err := sql.Open(DatabaseSQLite, ":memory:")
+	if err != nil {
+		return "", err
+	}
+	// Every connection has its own in-memory database
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	var sb strings.Builder
+	used := map[string]bool{}
+	for _, path := range paths {
+		name := DataTableName(path)
+		for i := 2; used[strings.ToLower(name)]; i++ {
+			name = fmt.Sprintf("%s_%d", DataTableName(path), i)
+		}
+		used[strings.ToLower(name)] = true
+
+		count, truncated, err := loadDataTable(ctx, db, name, path)
+		if err != nil {
+			return "", err
+		}
+		note := ""
+		if truncated {
+			note = ", truncated"
+		}
+		sb.WriteString(fmt.Sprintf("Table %s: %s (%d rows%s)\n", name, path, count, note))
+	}
+	sb.WriteString("\n")
+
+	result, err := RunDatabaseQuery(ctx, db, DatabaseSQLite, query, false, maxRows)
+	if err != nil {
+		return sb.String(), err
+	}
+	return sb.String() + result, nil
+}
+
+// loadDataTable creates the table of a data file and inserts at most maxInspectRows rows.
+func loadDataTable(ctx context.Context, db *sql.DB, table, path string) (int64, bool, error) {
+	records, err := openDataFile(path)
+	if err != nil {
+		return 0, false, err
+	}
+	defer records.Close()
+	if len(records.Columns) == 0 {
+		return 0, false, fmt.Errorf("%s has no columns", path)
+	}
+
+	columns := make([]string, len(records.Columns))
+	for i, c := range records.Columns {
+		columns[i] = quoteSQLName(c)
+	}
+	// Columns without a type keep the values as they are, numbers stay numbers
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (%s)", quoteSQLName(table), strings.Join(columns, ", "))); err != nil {
+		return 0, false, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+	insert, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s VALUES (%s)",
+		quoteSQLName(table), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")))
+	if err != nil {
+		return 0, false, err
+	}
+	defer insert.Close()
+
+	var count int64
+	for count < maxInspectRows {
+		row, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to read row %d of %s: %w", count+1, path, err)
+		}
+		if _, err := insert.ExecContext(ctx, row...); err != nil {
+			return 0, false, err
+		}
+		count++
+	}
+	truncated := false
+	if count == maxInspectRows {
+		_, err := records.Next()
+		truncated = err != io.EOF
+	}
+	return count, truncated, tx.Commit()
+}
+
+// quoteSQLName quotes an identifier for SQLite.
+func quoteSQLName(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
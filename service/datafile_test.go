@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func writeDataFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestInspectDataFileCSV(t *testing.T) {
+	path := writeDataFile(t, "sales.csv", "\ufeffregion,amount,note,amount\nnorth,10,a|b,1\nsouth,2.5,,2\nnorth,7,x,3\n")
+	got, err := InspectDataFile(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"CSV, 3 rows, 4 columns",
+		"| region | text | 3 | 2 | north | south |  |",
+		"| amount | float | 3 | 3 | 2.5 | 10 | 6.5 |",
+		"| note | text | 2 | 2 | a\\|b | x |  |",
+		"| amount_2 | integer | 3 | 3 | 1 | 3 | 2 |",
+		"First 2 rows:",
+		"| south | 2.5 | NULL | 2 |",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+}
+
+func TestInspectDataFileJSON(t *testing.T) {
+	path := writeDataFile(t, "users.json", `{"data": [{"id": 1, "name": "Ann"}, {"id": 2, "tags": ["a"], "name": "Bob"}], "total": 2}`)
+	got, err := InspectDataFile(path, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "JSON, 2 rows, 3 columns") || !strings.Contains(got, `| 2 | Bob | ["a"] |`) {
+		t.Errorf("unexpected inspection:\n%s", got)
+	}
+
+	lines := writeDataFile(t, "events.jsonl", "{\"kind\":\"click\",\"ms\":12}\n{\"kind\":\"view\",\"ms\":30}\n")
+	got, err = InspectDataFile(lines, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "JSONL, 2 rows, 2 columns") || strings.Contains(got, "First") {
+		t.Errorf("unexpected inspection:\n%s", got)
+	}
+}
+
+func TestInspectDataFileParquet(t *testing.T) {
+	type event struct {
+		ID   int64     `parquet:"id"`
+		Kind string    `parquet:"kind,optional"`
+		At   time.Time `parquet:"at,timestamp"`
+	}
+	path := filepath.Join(t.TempDir(), "events.parquet")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	w := parquet.NewGenericWriter[event](f)
+	if _, err := w.Write([]event{{1, "click", at}, {2, "", at}}); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	f.Close()
+
+	got, err := InspectDataFile(path, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "PARQUET, 2 rows, 3 columns") || !strings.Contains(got, "| 1 | click | 2026-01-02T03:04:05Z |") {
+		t.Errorf("unexpected inspection:\n%s", got)
+	}
+}
+
+func TestQueryDataFiles(t *testing.T) {
+	dir := t.TempDir()
+	sales := filepath.Join(dir, "sales-2024.csv")
+	os.WriteFile(sales, []byte("region,amount\nnorth,10\nsouth,2\nnorth,7\n"), 0644)
+	regions := filepath.Join(dir, "regions.json")
+	os.WriteFile(regions, []byte(`[{"region":"north","manager":"Ann"},{"region":"south","manager":"Bob"}]`), 0644)
+
+	got, err := QueryDataFiles(context.Background(), []string{sales, regions},
+		"SELECT r.manager, sum(s.amount) AS total FROM sales_2024 s JOIN regions r USING (region) GROUP BY 1 ORDER BY total DESC", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Table sales_2024: ", "(3 rows)", "| manager | total |", "| Ann | 17 |\n| Bob | 2 |", "2 rows."} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+
+	if _, err := QueryDataFiles(context.Background(), []string{sales}, "ATTACH DATABASE 'x.db' AS x", 0); err == nil {
+		t.Error("expected ATTACH to be refused")
+	}
+}
+
+func TestDataTableName(t *testing.T) {
+	tests := map[string]string{
+		"data/sales-2024.csv":  "sales_2024",
+		"2024 report.parquet":  "t_2024_report",
+		"events.log.jsonl":     "events_log",
+	}
+	for path, want := range tests {
+		if got := DataTableName(path); got != want {
+			t.Errorf("DataTableName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
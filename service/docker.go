@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/moby/moby/api/pkg/stdcopy"
+	"github.com/moby/moby/api/types/container"
+	"github.com/moby/moby/client"
+)
+
+const (
+	defaultDockerLogLines = 200
+	maxDockerLogLines     = 2000
+	maxDockerOutput       = 64 * 1024 // Bytes of output kept, the end of it
+	defaultDockerTimeout  = 2 * time.Minute
+	maxDockerTimeout      = 30 * time.Minute
+)
+
+// NewDockerClient connects to the Docker daemon of the environment, DOCKER_HOST or the local socket.
+func NewDockerClient() (*client.Client, error) {
+	cli, err := client.New(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Docker: %w", err)
+	}
+	return cli, nil
+}
+
+// tailBuffer keeps the last bytes written to it, the end of an output holds the errors.
+type tailBuffer struct {
+	data      []byte
+	max       int
+	truncated bool
+}
+
+func (t *tailBuffer) Write(p []byte) (int, error) {
+	t.data = append(t.data, p...)
+	if len(t.data) > t.max {
+		t.data = t.data[len(t.data)-t.max:]
+		t.truncated = true
+	}
+	return len(p), nil
+}
+
+func (t *tailBuffer) String() string {
+	s := strings.ToValidUTF8(string(t.data), "")
+	if t.truncated {
+		// Start at a whole line
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			s = s[i+1:]
+		}
+		return "[earlier output truncated]\n" + s
+	}
+	return s
+}
+
+// DockerPS lists the running containers, or all of them, as a markdown table. name filters them
+// by a part of their name.
+func DockerPS(ctx context.Context, cli *client.Client, all bool, name string) (string, error) {
+	opts := client.ContainerListOptions{All: all}
+	if name != "" {
+		opts.Filters = make(client.Filters).Add("name", name)
+	}
+	list, err := cli.ContainerList(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	if len(list.Items) == 0 {
+		if all {
+			return "No containers.", nil
+		}
+		return "No running containers, pass all to list stopped ones.", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("| id | name | image | state | status | ports |\n| --- | --- | --- | --- | --- | --- |\n")
+	for _, c := range list.Items {
+		names := make([]string, len(c.Names))
+		for i, n := range c.Names {
+			names[i] = strings.TrimPrefix(n, "/")
+		}
+		var ports []string
+		for _, p := range c.Ports {
+			if p.PublicPort != 0 {
+				ports = append(ports, fmt.Sprintf("%d->%d/%s", p.PublicPort, p.PrivatePort, p.Type))
+			} else {
+				ports = append(ports, fmt.Sprintf("%d/%s", p.PrivatePort, p.Type))
+			}
+		}
+		sb.WriteString(fmt.Sprintf("| %s | %s | %s | %s | %s | %s |\n", shortDockerID(c.ID), queryCell(strings.Join(names, ", ")),
+			queryCell(c.Image), c.State, queryCell(c.Status), queryCell(strings.Join(ports, ", "))))
+	}
+	sb.WriteString(fmt.Sprintf("\n%d containers.", len(list.Items)))
+	return sb.String(), nil
+}
+
+func shortDockerID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// DockerLogs returns the last lines of the logs of a container, stdout and stderr together.
+// since limits them to a duration, e.g. 10m, or a timestamp.
+func DockerLogs(ctx context.Context, cli *client.Client, containerID string, lines int, since string) (string, error) {
+	if lines <= 0 {
+		lines = defaultDockerLogLines
+	}
+	lines = min(lines, maxDockerLogLines)
+	inspect, err := cli.ContainerInspect(ctx, containerID, client.ContainerInspectOptions{})
+	if err != nil {
+		return "", err
+	}
+	logs, err := cli.ContainerLogs(ctx, containerID, client.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       fmt.Sprint(lines),
+		Since:      since,
+		Timestamps: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	defer logs.Close()
+
+	out := &tailBuffer{max: maxDockerOutput}
+	if inspect.Container.Config != nil && inspect.Container.Config.Tty {
+		_, err = io.Copy(out, logs)
+	} else {
+		_, err = stdcopy.StdCopy(out, out, logs)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(out.data) == 0 {
+		return "No logs.", nil
+	}
+	return out.String(), nil
+}
+
+// DockerExec runs a command in a running container and returns its exit code and output.
+func DockerExec(ctx context.Context, cli *client.Client, containerID string, cmd []string, workdir, user string) (string, error) {
+	exec, err := cli.ExecCreate(ctx, containerID, client.ExecCreateOptions{
+		Cmd:          cmd,
+		WorkingDir:   workdir,
+		User:         user,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	attach, err := cli.ExecAttach(ctx, exec.ID, client.ExecAttachOptions{})
+	if err != nil {
+		return "", err
+	}
+	defer attach.Close()
+
+	// The hijacked connection ignores the context, close it when the context ends
+	stop := context.AfterFunc(ctx, attach.Close)
+	defer stop()
+	out := &tailBuffer{max: maxDockerOutput}
+	_, copyErr := stdcopy.StdCopy(out, out, attach.Reader)
+	if ctx.Err() != nil {
+		return dockerOutput(-1, out), fmt.Errorf("command did not finish in time: %w", ctx.Err())
+	}
+	if copyErr != nil {
+		return "", copyErr
+	}
+
+	result, err := cli.ExecInspect(ctx, exec.ID, client.ExecInspectOptions{})
+	if err != nil {
+		return "", err
+	}
+	return dockerOutput(result.ExitCode, out), nil
+}
+
+func dockerOutput(exitCode int, out *tailBuffer) string {
+	output := strings.TrimRight(out.String(), "\n")
+	if output == "" {
+		output = "<no output>"
+	}
+	if exitCode < 0 {
+		return "Output:\n" + output
+	}
+	return fmt.Sprintf("Exit code: %d\nOutput:\n%s", exitCode, output)
+}
+
+// DockerRunOptions are the container DockerRun starts.
+type DockerRunOptions struct {
+	Image   string
+	Cmd     []string // Empty runs the command of the image
+	Env     []string // KEY=value
+	Name    string
+	Workdir string
+	Detach  bool // Leave the container running, otherwise wait for it and remove it
+}
+
+// DockerRun creates and starts a container, pulling its image when it is missing. A detached
+// container is left running; otherwise the output of the container is returned once it exits,
+// and the container is removed.
+func DockerRun(ctx context.Context, cli *client.Client, opts DockerRunOptions) (string, error) {
+	create := client.ContainerCreateOptions{
+		Name:       opts.Name,
+		Config:     &container.Config{Image: opts.Image, Cmd: opts.Cmd, Env: opts.Env, WorkingDir: opts.Workdir},
+		HostConfig: &container.HostConfig{},
+	}
+	created, err := cli.ContainerCreate(ctx, create)
+	if cerrdefs.IsNotFound(err) {
+		if err := pullDockerImage(ctx, cli, opts.Image); err != nil {
+			return "", err
+		}
+		created, err = cli.ContainerCreate(ctx, create)
+	}
+	if err != nil {
+		return "", err
+	}
+	id := created.ID
+
+	// Left behind when the run fails, unless it was meant to keep running
+	cleanup := func() {
+		removeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+		defer cancel()
+		cli.ContainerRemove(removeCtx, id, client.ContainerRemoveOptions{Force: true})
+	}
+
+	if _, err := cli.ContainerStart(ctx, id, client.ContainerStartOptions{}); err != nil {
+		cleanup()
+		return "", err
+	}
+	if opts.Detach {
+		return fmt.Sprintf("Started container %s from %s.", shortDockerID(id), opts.Image), nil
+	}
+	defer cleanup()
+
+	wait := cli.ContainerWait(ctx, id, client.ContainerWaitOptions{Condition: container.WaitConditionNotRunning})
+	exitCode := -1
+	var waitErr error
+	select {
+	case result := <-wait.Result:
+		exitCode = int(result.StatusCode)
+	case waitErr = <-wait.Error:
+	}
+
+	// Read what the container wrote, even when it timed out
+	logsCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 30*time.Second)
+	defer cancel()
+	out := &tailBuffer{max: maxDockerOutput}
+	if logs, err := cli.ContainerLogs(logsCtx, id, client.ContainerLogsOptions{ShowStdout: true, ShowStderr: true}); err == nil {
+		stdcopy.StdCopy(out, out, logs)
+		logs.Close()
+	}
+	if waitErr != nil {
+		if ctx.Err() != nil {
+			return dockerOutput(-1, out), fmt.Errorf("container did not exit in time: %w", ctx.Err())
+		}
+		return dockerOutput(-1, out), waitErr
+	}
+	return dockerOutput(exitCode, out), nil
+}
+
+// pullDockerImage pulls the image and waits for the pull to finish.
+func pullDockerImage(ctx context.Context, cli *client.Client, image string) error {
+	pull, err := cli.ImagePull(ctx, image, client.ImagePullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %w", image, err)
+	}
+	defer pull.Close()
+	if err := pull.Wait(ctx); err != nil {
+		return fmt.Errorf("failed to pull %s: %w", image, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/moby/moby/client"
+)
+
+// dockerFrame multiplexes a log line the way the daemon does without a TTY.
+func dockerFrame(stream byte, text string) []byte {
+	header := make([]byte, 8)
+	header[0] = stream
+	binary.BigEndian.PutUint32(header[4:], uint32(len(text)))
+	return append(header, text...)
+}
+
+func newTestDockerClient(t *testing.T, handler http.HandlerFunc) *client.Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	cli, err := client.New(client.WithHost("tcp://" + strings.TrimPrefix(server.URL, "http://")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cli.Close() })
+	return cli
+}
+
+func TestDockerPS(t *testing.T) {
+	cli := newTestDockerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/containers/json") {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("all") != "1" || !strings.Contains(r.URL.Query().Get("filters"), "web") {
+			t.Errorf("query = %s", r.URL.RawQuery)
+		}
+		w.Write([]byte(`[{"Id":"0123456789abcdef","Names":["/web"],"Image":"nginx:1.27","State":"running","Status":"Up 2 hours",
+			"Ports":[{"PrivatePort":80,"PublicPort":8080,"Type":"tcp"},{"PrivatePort":443,"Type":"tcp"}]}]`))
+	})
+
+	got, err := DockerPS(context.Background(), cli, true, "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "| 0123456789ab | web | nginx:1.27 | running | Up 2 hours | 8080->80/tcp, 443/tcp |"
+	if !strings.Contains(got, want) || !strings.HasSuffix(got, "1 containers.") {
+		t.Errorf("DockerPS =\n%s", got)
+	}
+}
+
+func TestDockerLogs(t *testing.T) {
+	cli := newTestDockerClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/web/json"):
+			w.Write([]byte(`{"Id":"0123","Config":{"Tty":false}}`))
+		case strings.HasSuffix(r.URL.Path, "/containers/web/logs"):
+			if r.URL.Query().Get("tail") != "50" || r.URL.Query().Get("stderr") != "1" {
+				t.Errorf("query = %s", r.URL.RawQuery)
+			}
+			w.Write(dockerFrame(1, "started\n"))
+			w.Write(dockerFrame(2, "warning: slow\n"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	got, err := DockerLogs(context.Background(), cli, "web", 50, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "started\nwarning: slow\n" {
+		t.Errorf("DockerLogs = %q", got)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	out := &tailBuffer{max: 16}
+	out.Write([]byte("first line\nsecond\nthird\n"))
+	if got := out.String(); got != "[earlier output truncated]\nsecond\nthird\n" {
+		t.Errorf("tailBuffer = %q", got)
+	}
+}
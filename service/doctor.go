@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+)
+
+// doctorProbeTimeout bounds the request probing a provider.
+const doctorProbeTimeout = 15 * time.Second
+
+// DoctorStatus is the outcome of a check of 'gllm doctor'.
+type DoctorStatus string
+
+const (
+	DoctorOK   DoctorStatus = "ok"
+	DoctorWarn DoctorStatus = "warn" // Works, but likely not as intended
+	DoctorFail DoctorStatus = "fail" // Breaks the runs that use it
+)
+
+// DoctorFinding is the result of one check.
+type DoctorFinding struct {
+	Area    string // config, models, agents, search, mcp or workflows
+	Subject string // Name of the model, agent, server or workflow checked
+	Status  DoctorStatus
+	Message string
+	Fix     string // What to do about it, empty when it is fine
+}
+
+// DoctorOptions tunes 'gllm doctor'.
+type DoctorOptions struct {
+	Probe            bool              // Send a cheap request to every provider
+	ReservedCommands map[string]string // REPL commands workflows can't be named after
+}
+
+// doctor collects the findings of the checks.
+type doctor struct {
+	findings []DoctorFinding
+}
+
+func (d *doctor) add(area, subject string, status DoctorStatus, message, fix string) {
+	d.findings = append(d.findings, DoctorFinding{Area: area, Subject: subject, Status: status, Message: message, Fix: fix})
+}
+
+// RunDoctor validates the whole configuration and, with Probe, reaches every provider.
+func RunDoctor(ctx context.Context, opts DoctorOptions) []DoctorFinding {
+	d := &doctor{}
+	store := data.NewConfigStore()
+	if !store.ConfigExists() {
+		d.add("config", "gllm.yaml", DoctorFail, "no configuration file", "run 'gllm init'")
+		return d.findings
+	}
+	d.add("config", "gllm.yaml", DoctorOK, store.ConfigFileUsed(), "")
+
+	models := store.GetModels()
+	d.checkModels(ctx, models, opts.Probe)
+	webSearch := d.checkAgents(store, models)
+	if webSearch {
+		d.checkSearchEngine(store)
+	}
+	d.checkMCPServers()
+	d.checkWorkflows(opts.ReservedCommands)
+	return d.findings
+}
+
+// isLocalEndpoint reports whether an endpoint is served from this machine, like Ollama or
+// LM Studio, which usually take no key.
+func isLocalEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsPrivate())
+}
+
+// doctorProvider is the provider of a model, detected when it isn't set.
+func doctorProvider(model *data.Model) string {
+	if model.Provider != "" {
+		return model.Provider
+	}
+	return DetectModelProvider(model.Endpoint, model.Model)
+}
+
+// checkModels checks the settings of every model, then probes the endpoints they use.
+func (d *doctor) checkModels(ctx context.Context, models map[string]*data.Model, probe bool) {
+	if len(models) == 0 {
+		d.add("models", "", DoctorFail, "no models configured", "add one with 'gllm model add' or 'gllm model discover'")
+		return
+	}
+	names := make([]string, 0, len(models))
+	for name := range models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Models sharing an endpoint and a key are probed once
+	probes := map[string][]string{}
+	var order []string
+	for _, name := range names {
+		model := models[name]
+		provider := doctorProvider(model)
+		if provider == ModelProviderMock {
+			d.add("models", name, DoctorOK, "mock provider", "")
+			continue
+		}
+		ok := true
+		if model.Model == "" {
+			d.add("models", name, DoctorFail, "no model ID", fmt.Sprintf("set it with 'gllm model set %s'", name))
+			ok = false
+		}
+		if model.Endpoint != "" {
+			if u, err := url.Parse(model.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				d.add("models", name, DoctorFail, fmt.Sprintf("invalid endpoint '%s'", model.Endpoint), "the endpoint must be an http:// or https:// URL")
+				ok = false
+			}
+		}
+		if strings.TrimSpace(model.Key) == "" && !isLocalEndpoint(model.Endpoint) {
+			d.add("models", name, DoctorFail, "empty API key", fmt.Sprintf("set the key with 'gllm model set %s'", name))
+			ok = false
+		}
+		if model.CACert != "" {
+			if _, err := os.Stat(model.CACert); err != nil {
+				d.add("models", name, DoctorFail, fmt.Sprintf("CA certificate %s not found", model.CACert), "fix or clear ca_cert of the model")
+				ok = false
+			}
+		}
+		if model.InsecureSkipVerify {
+			d.add("models", name, DoctorWarn, "TLS certificate verification is off", "prefer ca_cert with the certificate of your proxy")
+		}
+		for category, threshold := range model.SafetySettings {
+			if provider != ModelProviderGemini {
+				d.add("models", name, DoctorWarn, "safety settings are only used by Gemini", fmt.Sprintf("clear them with 'gllm model set %s --safety \"\"'", name))
+				break
+			}
+			if _, _, err := ParseGeminiSafetySetting(category + "=" + threshold); err != nil {
+				d.add("models", name, DoctorWarn, err.Error(), fmt.Sprintf("fix it with 'gllm model set %s --safety CATEGORY=THRESHOLD'", name))
+			}
+		}
+		if !ok {
+			continue
+		}
+		if !probe {
+			d.add("models", name, DoctorOK, fmt.Sprintf("%s %s", provider, model.Model), "")
+			continue
+		}
+		key := provider + "\n" + model.Endpoint + "\n" + model.Key
+		if _, seen := probes[key]; !seen {
+			order = append(order, key)
+		}
+		probes[key] = append(probes[key], name)
+	}
+
+	type probeResult struct {
+		ids []string
+		err error
+	}
+	results := make([]probeResult, len(order))
+	var wg sync.WaitGroup
+	for i, key := range order {
+		wg.Add(1)
+		go func(i int, model *data.Model) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, doctorProbeTimeout)
+			defer cancel()
+			results[i].ids, results[i].err = ListProviderModels(probeCtx, model)
+		}(i, models[probes[key][0]])
+	}
+	wg.Wait()
+
+	for i, key := range order {
+		for _, name := range probes[key] {
+			model := models[name]
+			switch res := results[i]; {
+			case res.err != nil:
+				d.add("models", name, DoctorFail, fmt.Sprintf("unreachable: %v", res.err), probeFix(res.err))
+			case len(res.ids) > 0 && !slices.Contains(res.ids, model.Model) && !slices.Contains(res.ids, "models/"+model.Model):
+				d.add("models", name, DoctorWarn, fmt.Sprintf("the provider doesn't list '%s'", model.Model),
+					fmt.Sprintf("check the model ID, 'gllm model discover %s' lists the available ones", name))
+			default:
+				d.add("models", name, DoctorOK, fmt.Sprintf("%s %s reachable", doctorProvider(model), model.Model), "")
+			}
+		}
+	}
+}
+
+// probeFix suggests a fix from the error of a probe.
+func probeFix(err error) string {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "401") || strings.Contains(msg, "403") || strings.Contains(msg, "unauthorized") || strings.Contains(msg, "api key"):
+		return "the key was refused, check it with 'gllm model set'"
+	case strings.Contains(msg, "certificate") || strings.Contains(msg, "x509"):
+		return "TLS failed, set ca_cert of the model behind a TLS-inspecting proxy"
+	case strings.Contains(msg, "deadline") || strings.Contains(msg, "timeout") || strings.Contains(msg, "no such host") || strings.Contains(msg, "connection refused"):
+		return "the endpoint can't be reached, check it and the proxy of the model"
+	case strings.Contains(msg, "404"):
+		return "the endpoint doesn't serve a model list, check its path (e.g. a missing /v1)"
+	}
+	return "check the endpoint and the key of the model"
+}
+
+// checkAgents checks every agent and reports whether one of them searches the web.
+func (d *doctor) checkAgents(store *data.ConfigStore, models map[string]*data.Model) bool {
+	active := store.GetActiveAgentName()
+	names := store.GetAgentNames()
+	switch {
+	case len(names) == 0:
+		d.add("agents", "", DoctorFail, "no agents configured", "add one with 'gllm agent add'")
+	case active == "":
+		d.add("agents", "", DoctorWarn, "no active agent", "pick one with 'gllm agent switch'")
+	case !slices.Contains(names, active):
+		d.add("agents", active, DoctorFail, "the active agent doesn't exist", "pick another with 'gllm agent switch'")
+	}
+
+	webSearch := false
+	for _, name := range names {
+		agent, err := store.LoadAgent(name)
+		if err != nil {
+			d.add("agents", name, DoctorFail, err.Error(), fmt.Sprintf("fix the file of the agent, see 'gllm agent info %s'", name))
+			continue
+		}
+		var problems []string
+		fail := func(message, fix string) {
+			d.add("agents", name, DoctorFail, message, fix)
+			problems = append(problems, message)
+		}
+		switch {
+		case agent.Model.Name == "":
+			fail("no model", fmt.Sprintf("set one with 'gllm agent set %s'", name))
+		case models[strings.ToLower(agent.Model.Name)] == nil:
+			fail(fmt.Sprintf("model '%s' doesn't exist", agent.Model.Name), fmt.Sprintf("add the model or pick another with 'gllm agent set %s'", name))
+		}
+		for _, tool := range agent.Tools {
+			if !IsAvailableOpenTool(tool) {
+				d.add("agents", name, DoctorWarn, fmt.Sprintf("unknown tool '%s'", tool), fmt.Sprintf("remove it with 'gllm agent set %s'", name))
+			}
+		}
+		if agent.Shell != "" {
+			if _, err := ResolveShell(agent.Shell); err != nil {
+				fail(err.Error(), "install the shell or change shell: in the agent file")
+			}
+		}
+		if guard := strings.ToLower(agent.InjectionGuard); guard != "" && !slices.Contains(SupportedInjectionGuards, guard) {
+			d.add("agents", name, DoctorWarn, fmt.Sprintf("unknown injection_guard '%s', flag is used", agent.InjectionGuard),
+				"use one of "+strings.Join(SupportedInjectionGuards, ", "))
+		}
+		if err := ValidateShowTools(agent.ShowTools); err != nil {
+			d.add("agents", name, DoctorWarn, err.Error(), "fix show_tools in the agent file")
+		}
+		if IsWebSearchEnabled(agent.Capabilities) {
+			webSearch = true
+		}
+		if len(problems) == 0 {
+			d.add("agents", name, DoctorOK, agent.Model.Name, "")
+		}
+	}
+	return webSearch
+}
+
+// checkSearchEngine checks the engine of the agents that search the web.
+func (d *doctor) checkSearchEngine(store *data.ConfigStore) {
+	name := data.GetSettingsStore().GetAllowedSearchEngine()
+	if name == "" {
+		name = GetDefaultSearchEngineName()
+	}
+	engine := store.GetSearchEngine(name)
+	switch {
+	case engine == nil:
+		d.add("search", name, DoctorFail, "web search is on but the engine isn't configured, searches are skipped",
+			fmt.Sprintf("configure it with 'gllm search set %s' or pick another with 'gllm search switch'", name))
+	case engine.Config["key"] == "":
+		d.add("search", name, DoctorFail, "empty API key", fmt.Sprintf("set the key with 'gllm search set %s'", name))
+	case name == GoogleSearchEngine && engine.Config["cx"] == "":
+		d.add("search", name, DoctorFail, "empty search engine ID (cx)", fmt.Sprintf("set it with 'gllm search set %s'", name))
+	default:
+		d.add("search", name, DoctorOK, "configured", "")
+	}
+}
+
+// checkMCPServers checks that the enabled MCP servers can be started or reached.
+func (d *doctor) checkMCPServers() {
+	servers, err := data.NewMCPStore().Load()
+	if err != nil {
+		d.add("mcp", "mcp.json", DoctorFail, err.Error(), "fix "+data.GetMcpFilePath())
+		return
+	}
+	names := make([]string, 0, len(servers))
+	for name, server := range servers {
+		if server.Allowed {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		server := servers[name]
+		remote := server.URL
+		if remote == "" {
+			remote = server.HTTPUrl
+		}
+		if remote == "" {
+			remote = server.BaseURL
+		}
+		switch {
+		case server.Command != "":
+			if _, err := exec.LookPath(server.Command); err != nil {
+				d.add("mcp", name, DoctorFail, fmt.Sprintf("command '%s' not found", server.Command), "install it or fix its path in "+data.GetMcpFilePath())
+				continue
+			}
+			dir := server.WorkDir
+			if dir == "" {
+				dir = server.Cwd
+			}
+			if dir != "" {
+				if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+					d.add("mcp", name, DoctorFail, fmt.Sprintf("working directory %s not found", dir), "fix it in "+data.GetMcpFilePath())
+					continue
+				}
+			}
+			d.add("mcp", name, DoctorOK, server.Command, "")
+		case remote != "":
+			if u, err := url.Parse(remote); err != nil || u.Host == "" {
+				d.add("mcp", name, DoctorFail, fmt.Sprintf("invalid URL '%s'", remote), "fix it in "+data.GetMcpFilePath())
+				continue
+			}
+			d.add("mcp", name, DoctorOK, remote, "")
+		default:
+			d.add("mcp", name, DoctorFail, "neither a command nor a URL", "fix it in "+data.GetMcpFilePath())
+		}
+	}
+}
+
+// checkWorkflows checks the workflow files: their names and where they post.
+func (d *doctor) checkWorkflows(reserved map[string]string) {
+	paths, _ := filepath.Glob(filepath.Join(data.GetWorkflowsDirPath(), "*"+data.WorkflowFileExt))
+	seen := map[string]string{}
+	for _, path := range paths {
+		meta, err := data.ParseWorkflowFrontmatter(path)
+		if err != nil {
+			d.add("workflows", filepath.Base(path), DoctorFail, err.Error(), "fix the frontmatter of "+path)
+			continue
+		}
+		name := strings.ToLower(meta.Name)
+		fix := "rename it with 'gllm workflow rename'"
+		if err := util.ValidateResourceName("workflow", name); err != nil {
+			d.add("workflows", meta.Name, DoctorFail, err.Error(), fix)
+			continue
+		}
+		if _, ok := reserved["/"+name]; ok {
+			d.add("workflows", meta.Name, DoctorFail, fmt.Sprintf("/%s is a built-in command, the workflow can't be run", name), fix)
+			continue
+		}
+		if other, ok := seen[name]; ok {
+			d.add("workflows", meta.Name, DoctorWarn, fmt.Sprintf("same name as %s, only one of them runs", other), fix)
+		}
+		seen[name] = filepath.Base(path)
+		if meta.Notify != "" {
+			if _, err := NewSink(meta.Notify); err != nil {
+				d.add("workflows", meta.Name, DoctorFail, fmt.Sprintf("notify: %v", err), "fix notify: in "+path)
+				continue
+			}
+		}
+		d.add("workflows", meta.Name, DoctorOK, meta.Description, "")
+	}
+}
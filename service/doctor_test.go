@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+	"github.com/spf13/viper"
+)
+
+func TestRunDoctor(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := data.EnsureConfigDir(); err != nil {
+		t.Fatal(err)
+	}
+	config := `agent: coder
+models:
+  good:
+    provider: mock
+    model: scripted
+  keyless:
+    endpoint: https://api.openai.com/v1
+    model: gpt-4o
+  local:
+    endpoint: http://localhost:11434/v1
+    model: llama3
+`
+	if err := os.WriteFile(data.GetConfigFilePath(), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Reset()
+	if err := data.NewConfigStore().SetConfigFile(data.GetConfigFilePath()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(viper.Reset)
+
+	if err := data.EnsureAgentsDir(); err != nil {
+		t.Fatal(err)
+	}
+	agents := map[string]string{
+		"coder":  "---\nname: coder\nmodel: good\ntools: [read_file, no_such_tool]\n---\n\nYou code.\n",
+		"orphan": "---\nname: orphan\nmodel: gone\n---\n\nYou are lost.\n",
+	}
+	for name, content := range agents {
+		if err := os.WriteFile(filepath.Join(data.GetAgentsDirPath(), name+".md"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := data.EnsureWorkflowsDir(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(data.GetWorkflowsDirPath(), "help.md"), []byte("---\nname: help\n---\n\nHelp.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	findings := RunDoctor(context.Background(), DoctorOptions{ReservedCommands: map[string]string{"/help": "Show help"}})
+	status := map[string]DoctorStatus{}
+	for _, f := range findings {
+		key := f.Area + "/" + f.Subject
+		// The worst finding of a subject wins
+		if status[key] != DoctorFail && (f.Status != DoctorOK || status[key] == "") {
+			status[key] = f.Status
+		}
+		if f.Status != DoctorOK && f.Fix == "" {
+			t.Errorf("expected a fix for %+v", f)
+		}
+	}
+	want := map[string]DoctorStatus{
+		"models/good":      DoctorOK,
+		"models/keyless":   DoctorFail,
+		"models/local":     DoctorOK,
+		"agents/coder":     DoctorWarn,
+		"agents/orphan":    DoctorFail,
+		"workflows/help":   DoctorFail,
+		"config/gllm.yaml": DoctorOK,
+	}
+	for key, s := range want {
+		if status[key] != s {
+			t.Errorf("expected %s to be %s, got %q", key, s, status[key])
+		}
+	}
+}
+
+func TestProbeFix(t *testing.T) {
+	fix := probeFix(os.ErrDeadlineExceeded)
+	if !strings.Contains(fix, "can't be reached") {
+		t.Errorf("unexpected fix for a timeout: %q", fix)
+	}
+}
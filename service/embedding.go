@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"google.golang.org/genai"
+)
+
+const (
+	// embeddingTimeout bounds the embedding requests made for a prompt, matching falls back
+	// to words after it.
+	embeddingTimeout = 15 * time.Second
+	// embeddingCacheLimit is how many embeddings are kept in memory.
+	embeddingCacheLimit = 2048
+)
+
+var (
+	embeddingCacheMu sync.Mutex
+	// embeddingCache holds the embeddings already computed, by model and text, so the skills
+	// are embedded once per process rather than once per prompt
+	embeddingCache = map[string][]float64{}
+)
+
+// EmbeddingModel returns the model configured to embed text, nil when none is. Skill matching
+// and session recall compare words without one.
+func EmbeddingModel() *data.Model {
+	name := data.GetSettingsStore().GetEmbeddingModel()
+	if name == "" {
+		return nil
+	}
+	return data.NewConfigStore().GetModel(name)
+}
+
+// EmbedTexts returns the embeddings of the texts with the model, in the order of the texts.
+func EmbedTexts(ctx context.Context, model *data.Model, texts []string) ([][]float64, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	mi := constructModelInfo(model)
+
+	var vectors [][]float64
+	var err error
+	switch mi.Provider {
+	case ModelProviderGemini:
+		vectors, err = embedGemini(ctx, mi, texts)
+	case ModelProviderOpenAI, ModelProviderOpenAICompatible:
+		vectors, err = embedOpenAI(ctx, mi, texts)
+	default:
+		return nil, fmt.Errorf("%s has no embedding API", mi.Provider)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed with %s: %w", model.Name, err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d texts", model.Name, len(vectors), len(texts))
+	}
+	return vectors, nil
+}
+
+// embedCached returns the embeddings of the texts, computing only the ones not seen yet.
+func embedCached(ctx context.Context, model *data.Model, texts []string) ([][]float64, error) {
+	keys := make([]string, len(texts))
+	vectors := make([][]float64, len(texts))
+	var missing []string
+	var missingAt []int
+	embeddingCacheMu.Lock()
+	for i, text := range texts {
+		sum := sha256.Sum256([]byte(model.Name + "\x00" + model.Model + "\x00" + text))
+		keys[i] = hex.EncodeToString(sum[:])
+		if v, ok := embeddingCache[keys[i]]; ok {
+			vectors[i] = v
+			continue
+		}
+		missing = append(missing, text)
+		missingAt = append(missingAt, i)
+	}
+	embeddingCacheMu.Unlock()
+	if len(missing) == 0 {
+		return vectors, nil
+	}
+
+	computed, err := EmbedTexts(ctx, model, missing)
+	if err != nil {
+		return nil, err
+	}
+	embeddingCacheMu.Lock()
+	defer embeddingCacheMu.Unlock()
+	if len(embeddingCache)+len(computed) > embeddingCacheLimit {
+		embeddingCache = map[string][]float64{}
+	}
+	for j, i := range missingAt {
+		vectors[i] = computed[j]
+		embeddingCache[keys[i]] = computed[j]
+	}
+	return vectors, nil
+}
+
+func embedOpenAI(ctx context.Context, mi *ModelInfo, texts []string) ([][]float64, error) {
+	opts := []option.RequestOption{option.WithAPIKey(mi.ApiKey)}
+	if mi.EndPoint != "" {
+		opts = append(opts, option.WithBaseURL(mi.EndPoint))
+	}
+	hc, err := providerHTTPClient(mi)
+	if err != nil {
+		return nil, err
+	}
+	if hc != nil {
+		opts = append(opts, option.WithHTTPClient(hc))
+	}
+	client := openai.NewClient(opts...)
+
+	resp, err := client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+		Model: openai.EmbeddingModel(mi.Model),
+	})
+	if err != nil {
+		return nil, err
+	}
+	vectors := make([][]float64, len(texts))
+	for _, e := range resp.Data {
+		if e.Index >= 0 && int(e.Index) < len(vectors) {
+			vectors[e.Index] = e.Embedding
+		}
+	}
+	for _, v := range vectors {
+		if v == nil {
+			return nil, fmt.Errorf("missing embeddings in the response")
+		}
+	}
+	return vectors, nil
+}
+
+func embedGemini(ctx context.Context, mi *ModelInfo, texts []string) ([][]float64, error) {
+	hc, err := providerHTTPClient(mi)
+	if err != nil {
+		return nil, err
+	}
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:      mi.ApiKey,
+		Backend:     genai.BackendGeminiAPI,
+		HTTPClient:  hc,
+		HTTPOptions: genai.HTTPOptions{BaseURL: mi.EndPoint},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	contents := make([]*genai.Content, len(texts))
+	for i, text := range texts {
+		contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+	}
+	resp, err := client.Models.EmbedContent(ctx, mi.Model, contents, nil)
+	if err != nil {
+		return nil, err
+	}
+	vectors := make([][]float64, 0, len(resp.Embeddings))
+	for _, e := range resp.Embeddings {
+		v := make([]float64, len(e.Values))
+		for i, x := range e.Values {
+			v[i] = float64(x)
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, nil
+}
+
+// denseCosineSimilarity returns the cosine of the angle between two embeddings, 0 when their
+// sizes differ.
+func denseCosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
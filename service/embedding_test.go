@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+// embeddingTopics are the dimensions of the embeddings of fakeEmbeddingServer: a text has a
+// 1 in the dimension of each topic it mentions.
+var embeddingTopics = [][]string{
+	{"paper", "arxiv", "research", "publication"},
+	{"video", "youtube", "clip", "footage"},
+	{"pdf", "document"},
+	{"database", "postgres", "index", "query"},
+	{"weather", "forecast", "rain"},
+}
+
+// fakeEmbeddingServer serves an OpenAI compatible embeddings endpoint and returns a model of
+// it. Its embeddings place the texts by topic, whatever words they use for it.
+func fakeEmbeddingServer(t *testing.T) (*data.Model, *int) {
+	t.Helper()
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req struct {
+			Input []string `json:"input"`
+		}
+		if !strings.HasSuffix(r.URL.Path, "/embeddings") || json.NewDecoder(r.Body).Decode(&req) != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var out []map[string]interface{}
+		for i, text := range req.Input {
+			text = strings.ToLower(text)
+			vector := make([]float64, len(embeddingTopics)+1)
+			vector[len(embeddingTopics)] = 0.1 // Every text shares a little
+			for d, words := range embeddingTopics {
+				for _, w := range words {
+					if strings.Contains(text, w) {
+						vector[d] = 1
+					}
+				}
+			}
+			out = append(out, map[string]interface{}{"object": "embedding", "index": i, "embedding": vector})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"object": "list", "data": out, "model": "fake-embed"})
+	}))
+	t.Cleanup(srv.Close)
+	return &data.Model{Name: "embed", Provider: ModelProviderOpenAI, Endpoint: srv.URL + "/v1", Key: "test", Model: "fake-embed"}, &calls
+}
+
+func TestEmbedTexts(t *testing.T) {
+	model, calls := fakeEmbeddingServer(t)
+	vectors, err := EmbedTexts(context.Background(), model, []string{"a research paper", "a youtube clip", "a new publication"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vectors) != 3 {
+		t.Fatalf("expected 3 embeddings, got %d", len(vectors))
+	}
+	if same, other := denseCosineSimilarity(vectors[0], vectors[2]), denseCosineSimilarity(vectors[0], vectors[1]); same <= other {
+		t.Errorf("texts of the same topic must be closer: %.2f <= %.2f", same, other)
+	}
+
+	// The cached embeddings are not asked for again
+	before := *calls
+	if _, err := embedCached(context.Background(), model, []string{"a research paper"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := embedCached(context.Background(), model, []string{"a research paper"}); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != before+1 {
+		t.Errorf("expected one request for a text embedded twice, got %d", *calls-before)
+	}
+
+	if _, err := EmbedTexts(context.Background(), &data.Model{Name: "claude", Provider: ModelProviderAnthropic}, []string{"text"}); err == nil {
+		t.Error("expected an error for a provider without embeddings")
+	}
+}
@@ -1,8 +1,10 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"strings"
 )
 
 // SwitchAgentError is a sentinel error used to signal that the agent should be switched.
@@ -11,6 +13,7 @@ import (
 type SwitchAgentError struct {
 	TargetAgent string
 	Instruction string
+	Handoff     string // Digest of the previous agent's tool results, empty when not shared
 }
 
 func (e SwitchAgentError) Error() string {
@@ -20,6 +23,15 @@ func (e SwitchAgentError) Error() string {
 	return fmt.Sprintf("switching to agent: %s", e.TargetAgent)
 }
 
+// Prompt returns the prompt of the new agent: the instruction, after the handoff digest
+// in a hidden context block. It is empty without an instruction, there is nothing to do.
+func (e SwitchAgentError) Prompt() string {
+	if e.Instruction == "" || e.Handoff == "" {
+		return e.Instruction
+	}
+	return BuildInlineContextBlock([]string{e.Handoff}) + "\n" + e.Instruction
+}
+
 func IsSwitchAgentError(err error) bool {
 	var target SwitchAgentError
 	var target2 *SwitchAgentError
@@ -41,11 +53,12 @@ func AsSwitchAgentError(err error) (SwitchAgentError, bool) {
 }
 
 const (
-	UserCancelCommon        = "[Operation Cancelled]"
-	UserCancelReasonUnknown = "Unknown"
-	UserCancelReasonTimeout = "Timeout"
-	UserCancelReasonDeny    = "User denied execution."
-	UserCancelReasonCancel  = "User canceled execution."
+	UserCancelCommon          = "[Operation Cancelled]"
+	UserCancelReasonUnknown   = "Unknown"
+	UserCancelReasonTimeout   = "Timeout"
+	UserCancelReasonDeny      = "User denied execution."
+	UserCancelReasonCancel    = "User canceled execution."
+	UserCancelReasonInterrupt = "User interrupted generation."
 )
 
 // UserCancelError is a sentinel error used to signal that the user has cancelled an operation.
@@ -82,3 +95,91 @@ func AsUserCancelError(err error) (UserCancelError, bool) {
 	}
 	return UserCancelError{}, false
 }
+
+// checkInterrupted returns a UserCancelError if the generation context has been cancelled,
+// e.g. the user pressed Ctrl+C. It returns nil while the context is still active.
+func checkInterrupted(ctx context.Context) error {
+	if ctx != nil && ctx.Err() != nil {
+		return UserCancelError{Reason: UserCancelReasonInterrupt}
+	}
+	return nil
+}
+
+// SafetyBlockError is returned when the provider refused the prompt or cut the response
+// short because of its safety or content filters.
+type SafetyBlockError struct {
+	Provider   string
+	Reason     string   // Finish or block reason given by the provider, e.g. SAFETY or content_filter
+	Categories []string // Harm categories that tripped the filter, when the provider names them
+	Prompt     bool     // The prompt was blocked before anything was generated
+}
+
+// Error implements [error].
+func (e SafetyBlockError) Error() string {
+	what := "the response was blocked"
+	if e.Prompt {
+		what = "the prompt was blocked"
+	}
+	msg := fmt.Sprintf("%s by the %s safety filters", what, e.Provider)
+	if e.Reason != "" {
+		msg += fmt.Sprintf(" (%s", e.Reason)
+		if len(e.Categories) > 0 {
+			msg += ": " + strings.Join(e.Categories, ", ")
+		}
+		msg += ")"
+	}
+	return msg
+}
+
+func IsSafetyBlockError(err error) bool {
+	var target SafetyBlockError
+	var target2 *SafetyBlockError
+	return errors.As(err, &target) || errors.As(err, &target2)
+}
+
+// AsSafetyBlockError safely extracts a SafetyBlockError from an error,
+// handling both value and pointer variants.
+func AsSafetyBlockError(err error) (SafetyBlockError, bool) {
+	var target SafetyBlockError
+	if errors.As(err, &target) {
+		return target, true
+	}
+	var target2 *SafetyBlockError
+	if errors.As(err, &target2) {
+		return *target2, true
+	}
+	return SafetyBlockError{}, false
+}
+
+// BudgetExceededError is returned when the estimated spend reached a cost budget and
+// the run was not allowed to go on.
+type BudgetExceededError struct {
+	Scope string  // Budget that was reached: run or session
+	Limit float64 // USD
+	Spent float64 // USD, estimated from the model prices
+}
+
+// Error implements [error].
+func (e BudgetExceededError) Error() string {
+	return fmt.Sprintf("stopped at the %s budget of $%.2f, $%.2f spent", e.Scope, e.Limit, e.Spent)
+}
+
+func IsBudgetExceededError(err error) bool {
+	var target BudgetExceededError
+	var target2 *BudgetExceededError
+	return errors.As(err, &target) || errors.As(err, &target2)
+}
+
+// AsBudgetExceededError safely extracts a BudgetExceededError from an error,
+// handling both value and pointer variants.
+func AsBudgetExceededError(err error) (BudgetExceededError, bool) {
+	var target BudgetExceededError
+	if errors.As(err, &target) {
+		return target, true
+	}
+	var target2 *BudgetExceededError
+	if errors.As(err, &target2) {
+		return *target2, true
+	}
+	return BudgetExceededError{}, false
+}
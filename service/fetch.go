@@ -25,6 +25,7 @@ type ExtractorConfig struct {
 	MinTextLength      int
 	BoilerplateIDs     []string
 	BoilerplateClasses []string
+	Transport          http.RoundTripper // Optional transport, e.g. with a proxy; nil uses the default
 }
 
 // Default configuration with modern browser headers (Chrome 131, December 2024)
@@ -46,12 +47,23 @@ func ExtractTextFromURL(ctx context.Context, url string, config *ExtractorConfig
 	if config == nil {
 		config = &defaultConfig
 	}
+	resp, err := fetchURLResponse(ctx, url, config)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return extractTextByContentType(resp.Header.Get("Content-Type"), resp.Body, config)
+}
 
+// fetchURLResponse requests a URL like a browser would, the caller closes the body of
+// the response, which is always 200 OK.
+func fetchURLResponse(ctx context.Context, url string, config *ExtractorConfig) (*http.Response, error) {
 	// Setup HTTP client with timeout
 	jar, _ := cookiejar.New(nil)
 	client := &http.Client{
-		Timeout: config.Timeout,
-		Jar:     jar,
+		Timeout:   config.Timeout,
+		Jar:       jar,
+		Transport: config.Transport,
 	}
 
 	// Create request with headers
@@ -89,14 +101,16 @@ func ExtractTextFromURL(ctx context.Context, url string, config *ExtractorConfig
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch URL: %w", err)
 	}
-	defer resp.Body.Close()
-
 	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
+	return resp, nil
+}
 
+// extractTextByContentType extracts the text of a response body by its content type.
+func extractTextByContentType(contentType string, body io.Reader, config *ExtractorConfig) ([]string, error) {
 	// Detect content type and route to appropriate handler
-	contentType := resp.Header.Get("Content-Type")
 	contentType = strings.ToLower(strings.Split(contentType, ";")[0]) // Remove charset, etc.
 
 	switch {
@@ -108,15 +122,15 @@ func ExtractTextFromURL(ctx context.Context, url string, config *ExtractorConfig
 		strings.HasPrefix(contentType, "application/json"),
 		strings.HasPrefix(contentType, "text/csv"):
 		// Plain text content - return as-is
-		return extractPlainText(resp.Body, config.MinTextLength)
+		return extractPlainText(body, config.MinTextLength)
 
 	case strings.HasPrefix(contentType, "application/pdf"):
 		// PDF content - extract text using PDF reader
-		return extractPDFText(resp.Body)
+		return extractPDFText(body)
 
 	default:
 		// Assume HTML content - use goquery parsing
-		return extractHTMLText(resp.Body, config)
+		return extractHTMLText(body, config)
 	}
 }
 
@@ -340,8 +354,8 @@ type FetchResult struct {
 	Error   error
 }
 
-func fetchWorker(ctx context.Context, url string) (string, error) {
-	content, err := ExtractTextFromURL(ctx, url, nil)
+func fetchWorker(ctx context.Context, url string, config *ExtractorConfig) (string, error) {
+	content, err := ExtractTextFromURL(ctx, url, config)
 	if err != nil {
 		util.LogDebugf("Error fetching URL [%s]: %v\n", url, err)
 		return "", err
@@ -350,6 +364,18 @@ func fetchWorker(ctx context.Context, url string) (string, error) {
 }
 
 func FetchProcess(ctx context.Context, urls []string) []FetchResult {
+	return FetchProcessWithTransport(ctx, urls, nil)
+}
+
+// FetchProcessWithTransport fetches the URLs concurrently through the given transport, nil uses the default.
+func FetchProcessWithTransport(ctx context.Context, urls []string, transport http.RoundTripper) []FetchResult {
+	config := &defaultConfig
+	if transport != nil {
+		withTransport := defaultConfig
+		withTransport.Transport = transport
+		config = &withTransport
+	}
+
 	var wg sync.WaitGroup
 	results := make([]FetchResult, len(urls))
 	resultCh := make(chan struct {
@@ -376,7 +402,7 @@ func FetchProcess(ctx context.Context, urls []string) []FetchResult {
 				}
 			}()
 
-			text, err := fetchWorker(ctx, u)
+			text, err := fetchWorker(ctx, u, config)
 			resultCh <- struct {
 				Index  int
 				Result FetchResult
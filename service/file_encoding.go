@@ -0,0 +1,154 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Text encodings recognized by read_file
+const (
+	EncodingUTF8    = "UTF-8"
+	EncodingUTF8BOM = "UTF-8 with BOM"
+	EncodingUTF16LE = "UTF-16LE"
+	EncodingUTF16BE = "UTF-16BE"
+	EncodingLatin1  = "ISO-8859-1"
+)
+
+const (
+	encodingSniffSize = 8 * 1024 // Bytes inspected to guess the encoding
+	binaryPreviewSize = 256      // Bytes shown in the hexdump of binary files
+)
+
+// DecodeText converts file content to UTF-8 text.
+// It returns the detected encoding, or ok=false when the content looks binary.
+func DecodeText(content []byte) (text string, encoding string, ok bool) {
+	switch {
+	case bytes.HasPrefix(content, []byte{0xEF, 0xBB, 0xBF}):
+		return string(content[3:]), EncodingUTF8BOM, true
+	case bytes.HasPrefix(content, []byte{0xFF, 0xFE}):
+		return decodeUTF16(content[2:], binary.LittleEndian), EncodingUTF16LE, true
+	case bytes.HasPrefix(content, []byte{0xFE, 0xFF}):
+		return decodeUTF16(content[2:], binary.BigEndian), EncodingUTF16BE, true
+	}
+
+	sample := content[:min(len(content), encodingSniffSize)]
+	// UTF-16 without BOM, ASCII text leaves every other byte zero
+	switch sniffUTF16(sample) {
+	case EncodingUTF16LE:
+		return decodeUTF16(content, binary.LittleEndian), EncodingUTF16LE, true
+	case EncodingUTF16BE:
+		return decodeUTF16(content, binary.BigEndian), EncodingUTF16BE, true
+	}
+	if utf8.Valid(content) {
+		if bytes.IndexByte(sample, 0) >= 0 {
+			return "", "", false
+		}
+		return string(content), EncodingUTF8, true
+	}
+	if looksBinary(sample) {
+		return "", "", false
+	}
+	// Latin-1 maps every byte to the code point of the same value
+	runes := make([]rune, len(content))
+	for i, b := range content {
+		runes[i] = rune(b)
+	}
+	return string(runes), EncodingLatin1, true
+}
+
+func decodeUTF16(content []byte, order binary.ByteOrder) string {
+	units := make([]uint16, len(content)/2)
+	for i := range units {
+		units[i] = order.Uint16(content[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// sniffUTF16 guesses the byte order of BOM-less UTF-16, or returns "".
+func sniffUTF16(sample []byte) string {
+	if len(sample) < 4 || len(sample)%2 != 0 {
+		return ""
+	}
+	evenZeros, oddZeros := 0, 0
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			evenZeros++
+		} else {
+			oddZeros++
+		}
+	}
+	half := len(sample) / 2
+	switch {
+	case oddZeros > half*7/10 && evenZeros == 0:
+		return EncodingUTF16LE
+	case evenZeros > half*7/10 && oddZeros == 0:
+		return EncodingUTF16BE
+	}
+	return ""
+}
+
+// looksBinary reports whether the sample has NUL bytes or many control characters.
+func looksBinary(sample []byte) bool {
+	control := 0
+	for _, b := range sample {
+		switch {
+		case b == 0:
+			return true
+		case b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != '\b', b == 0x7F:
+			control++
+		}
+	}
+	return len(sample) > 0 && control*10 > len(sample)
+}
+
+// DescribeBinaryFile summarizes a binary file for the model: its type, size,
+// a hint on how to inspect it, and a hexdump of its first bytes.
+func DescribeBinaryFile(path string, content []byte) string {
+	mimeType := GetMIMETypeByContent(content)
+	if mimeType == "application/octet-stream" {
+		if byExt := GetMIMEType(path); byExt != "" {
+			mimeType = byExt
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("File %s is binary (%s, %d bytes), it cannot be read as text.\n", path, mimeType, len(content)))
+	if hint := binaryFileHint(mimeType); hint != "" {
+		sb.WriteString(hint + "\n")
+	}
+	preview := content[:min(len(content), binaryPreviewSize)]
+	sb.WriteString(fmt.Sprintf("Hexdump of the first %d bytes:\n", len(preview)))
+	sb.WriteString(hex.Dump(preview))
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func binaryFileHint(mimeType string) string {
+	switch {
+	case IsImageMIMEType(mimeType):
+		return "This is an image. Ask the user to attach it to the prompt if its content matters."
+	case IsPDFMIMEType(mimeType):
+		return "This is a PDF document. Use a shell command such as 'pdftotext' to extract its text."
+	case IsAudioMIMEType(mimeType), IsVideoMIMEType(mimeType):
+		return "This is a media file. Use a shell command such as 'ffprobe' to inspect it."
+	case isArchiveMIMEType(mimeType):
+		return "This is an archive. Use a shell command such as 'unzip -l' or 'tar -tf' to list its contents."
+	}
+	return ""
+}
+
+func isArchiveMIMEType(mimeType string) bool {
+	switch mimeType {
+	case "application/zip", "application/x-gzip", "application/gzip", "application/x-tar",
+		"application/x-rar-compressed", "application/x-7z-compressed", "application/x-bzip2", "application/x-xz":
+		return true
+	}
+	return false
+}
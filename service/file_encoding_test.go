@@ -0,0 +1,49 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  []byte
+		wantText string
+		wantEnc  string
+		wantOK   bool
+	}{
+		{"utf8", []byte("héllo\n"), "héllo\n", EncodingUTF8, true},
+		{"utf8 bom", []byte("\xEF\xBB\xBFhi"), "hi", EncodingUTF8BOM, true},
+		{"utf16le bom", []byte{0xFF, 0xFE, 'h', 0, 'i', 0}, "hi", EncodingUTF16LE, true},
+		{"utf16be bom", []byte{0xFE, 0xFF, 0, 'h', 0, 'i'}, "hi", EncodingUTF16BE, true},
+		{"utf16le no bom", []byte{'a', 0, 'b', 0, 'c', 0, 'd', 0}, "abcd", EncodingUTF16LE, true},
+		{"latin1", []byte("caf\xE9 cr\xE8me"), "café crème", EncodingLatin1, true},
+		{"nul bytes", []byte("ELF\x00\x01\x02\x00\x00rest"), "", "", false},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, enc, ok := DecodeText(tt.content)
+			if ok != tt.wantOK || enc != tt.wantEnc || text != tt.wantText {
+				t.Errorf("DecodeText() = (%q, %q, %v), want (%q, %q, %v)", text, enc, ok, tt.wantText, tt.wantEnc, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDescribeBinaryFile(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01")
+	got := DescribeBinaryFile("logo.png", png)
+	for _, want := range []string{"logo.png is binary (image/png, 20 bytes)", "This is an image", "00000000  89 50 4e 47"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("DescribeBinaryFile() got:\n%s\nwant to contain: %s", got, want)
+		}
+	}
+
+	zip := append([]byte("PK\x03\x04"), make([]byte, 400)...)
+	got = DescribeBinaryFile("a.zip", zip)
+	if !strings.Contains(got, "unzip -l") || !strings.Contains(got, "first 256 bytes") {
+		t.Errorf("DescribeBinaryFile() got:\n%s", got)
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileStamp identifies the content of a file as last seen by the tools.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	hash    [sha256.Size]byte
+}
+
+var (
+	fileStampsMu sync.Mutex
+	fileStamps   = make(map[string]fileStamp) // Keyed by absolute path
+
+	fileBackupsMu sync.Mutex
+	fileBackups   = make(map[string][][]byte) // Content replaced by the writes, newest last, keyed by absolute path
+)
+
+// maxFileBackups is the number of writes of a file that can be undone.
+const maxFileBackups = 10
+
+// absPath returns the absolute form of path, or path itself if it can't be resolved.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// rememberFileContent records the content of path the model has seen.
+func rememberFileContent(path string, content []byte) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+	fileStampsMu.Lock()
+	defer fileStampsMu.Unlock()
+	fileStamps[absPath(path)] = fileStamp{modTime: info.ModTime(), size: info.Size(), hash: sha256.Sum256(content)}
+}
+
+// fileChangedOnDisk reports whether path differs from the content last remembered for it.
+// Files that were never seen or no longer exist are not considered changed.
+func fileChangedOnDisk(path string) bool {
+	key := absPath(path)
+	fileStampsMu.Lock()
+	stamp, ok := fileStamps[key]
+	fileStampsMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if info.ModTime().Equal(stamp.modTime) && info.Size() == stamp.size {
+		return false
+	}
+	// Touched but possibly not modified, compare the content
+	content, err := os.ReadFile(path)
+	if err != nil || sha256.Sum256(content) != stamp.hash {
+		return true
+	}
+	rememberFileContent(path, content)
+	return false
+}
+
+// lastFileBackup returns the content path had before its last write, false if there is
+// none to go back to.
+func lastFileBackup(path string) ([]byte, bool) {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	fileBackupsMu.Lock()
+	defer fileBackupsMu.Unlock()
+	backups := fileBackups[absPath(path)]
+	if len(backups) == 0 {
+		return nil, false
+	}
+	return backups[len(backups)-1], true
+}
+
+// backupFileContent records the content a write of path replaced. Writing back the content
+// before the last write undoes it, and drops its backup instead.
+func backupFileContent(path string, old, content []byte) {
+	key := absPath(path)
+	fileBackupsMu.Lock()
+	defer fileBackupsMu.Unlock()
+	backups := fileBackups[key]
+	if n := len(backups); n > 0 && bytes.Equal(backups[n-1], content) {
+		fileBackups[key] = backups[:n-1]
+		return
+	}
+	if len(backups) == maxFileBackups {
+		backups = backups[1:]
+	}
+	fileBackups[key] = append(backups, old)
+}
+
+// writeFileAtomic writes content to a temp file next to path and renames it into place,
+// so readers never see a partially written file. Existing permissions are kept and
+// symlinks are written through. The content of an existing file is backed up.
+func writeFileAtomic(path string, content []byte) error {
+	if target, err := filepath.EvalSymlinks(path); err == nil {
+		path = target
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	old, readErr := os.ReadFile(path)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // No-op once renamed
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	if readErr == nil {
+		backupFileContent(path, old, content)
+	}
+	return nil
+}
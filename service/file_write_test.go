@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(path, []byte("old"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.sh")
+	if err := os.Symlink(path, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	if err := writeFileAtomic(link, []byte("new")); err != nil {
+		t.Fatalf("writeFileAtomic failed: %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	info, _ := os.Stat(path)
+	if string(got) != "new" || info.Mode().Perm() != 0755 {
+		t.Errorf("got content %q mode %v, want \"new\" 0755", got, info.Mode().Perm())
+	}
+	if fi, err := os.Lstat(link); err != nil || fi.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("symlink was replaced by a regular file")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("expected no temp files left, found %d entries", len(entries))
+	}
+}
+
+func TestWriteFileRefusesStaleContent(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}}
+	read := func() {
+		args := map[string]interface{}{"path": path}
+		if _, err := readFileToolCallImpl(context.Background(), &args); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(content string) string {
+		args := map[string]interface{}{"path": path, "content": content}
+		out, err := writeFileToolCallImpl(context.Background(), &args, op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	read()
+	// The user edits the file in their IDE meanwhile
+	if err := os.WriteFile(path, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out := write("package model\n"); !strings.HasPrefix(out, "WRITE ABORTED") {
+		t.Fatalf("expected the stale write to be refused, got %q", out)
+	}
+	if got, _ := os.ReadFile(path); !strings.Contains(string(got), "func main") {
+		t.Fatalf("the user's change was overwritten")
+	}
+
+	read()
+	if out := write("package model\n"); !strings.HasPrefix(out, "Successfully wrote") {
+		t.Fatalf("expected the write to succeed after a re-read, got %q", out)
+	}
+	// Our own write doesn't count as an outside change
+	if out := write("package model2\n"); !strings.HasPrefix(out, "Successfully wrote") {
+		t.Fatalf("expected a second write to succeed, got %q", out)
+	}
+}
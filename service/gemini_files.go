@@ -0,0 +1,191 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Attachments larger than geminiUploadThreshold are uploaded with the Files API and referenced
+// by URI. The smaller ones are sent inline, as long as they add up to less than geminiInlineLimit,
+// which leaves room for their base64 encoding under the 20MB limit of a request.
+const (
+	geminiUploadThreshold = 8 << 20
+	geminiInlineLimit     = 14 << 20
+)
+
+const (
+	// geminiUploadTTL is how long the Files API keeps an upload when it doesn't say.
+	geminiUploadTTL = 48 * time.Hour
+	// geminiFilePollInterval is the wait between two checks of a file being processed.
+	geminiFilePollInterval = 2 * time.Second
+)
+
+// forgetExpiredGeminiFiles replaces the references to expired uploads in the history with a
+// note, the API rejects the requests that still use them.
+func forgetExpiredGeminiFiles(messages []*genai.Content, uploads *fileUploads, now time.Time) []*genai.Content {
+	for _, content := range messages {
+		if content == nil {
+			continue
+		}
+		for i, part := range content.Parts {
+			if part == nil || part.FileData == nil || !isGeminiFilesURI(part.FileData.FileURI) {
+				continue
+			}
+			up, known := uploads.byURI(part.FileData.FileURI)
+			if known && up.usable(now) {
+				continue
+			}
+			name := "A file"
+			if known && up.File != "" {
+				name = up.File
+			}
+			content.Parts[i] = &genai.Part{Text: fmt.Sprintf("[%s attached here has expired, attach it again if it is still needed]", name)}
+		}
+	}
+	return messages
+}
+
+// isGeminiFilesURI reports whether the URI is a file of the Files API, rather than a YouTube
+// video or a Cloud Storage object.
+func isGeminiFilesURI(uri string) bool {
+	return strings.HasPrefix(uri, "https://") && strings.Contains(uri, "/files/")
+}
+
+// geminiShouldUpload reports whether an attachment of the size goes through the Files API,
+// given the size of the attachments already inline.
+func geminiShouldUpload(size, inline int) bool {
+	return size > geminiUploadThreshold || inline+size > geminiInlineLimit
+}
+
+// uploadFile uploads an attachment with the Files API, or reuses the upload of the same content,
+// and returns the part that references it.
+func (ga *Gemini) uploadFile(ag *Agent, uploads *fileUploads, file *FileData) (*genai.Part, error) {
+	key := uploadKey(ag.Model, file.Data())
+	if up, ok := uploads.active(key, time.Now()); ok {
+		return genai.NewPartFromURI(up.URI, up.MIMEType), nil
+	}
+
+	name := filepath.Base(file.Path())
+	defer ag.ShowActivity("")
+	reader := &uploadProgress{r: bytes.NewReader(file.Data()), total: len(file.Data()), last: -1, report: func(percent int) {
+		ag.ShowActivity(fmt.Sprintf("uploading %s %d%%", name, percent))
+	}}
+	files, err := ga.filesClient(ag)
+	if err != nil {
+		return nil, err
+	}
+	f, err := files.Files.Upload(ag.Ctx, reader, &genai.UploadFileConfig{MIMEType: file.Format(), DisplayName: name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload %s: %w", name, err)
+	}
+	// Videos are processed before they can be used
+	for f.State == genai.FileStateProcessing {
+		ag.ShowActivity("processing " + name)
+		select {
+		case <-ag.Ctx.Done():
+			return nil, ag.Ctx.Err()
+		case <-time.After(geminiFilePollInterval):
+		}
+		if f, err = files.Files.Get(ag.Ctx, f.Name, nil); err != nil {
+			return nil, fmt.Errorf("failed to check the upload of %s: %w", name, err)
+		}
+	}
+	if f.State == genai.FileStateFailed {
+		return nil, fmt.Errorf("gemini failed to process %s", name)
+	}
+
+	expires := f.ExpirationTime
+	if expires.IsZero() {
+		expires = time.Now().Add(geminiUploadTTL)
+	}
+	mimeType := f.MIMEType
+	if mimeType == "" {
+		mimeType = file.Format()
+	}
+	uploads.Uploads[key] = fileUpload{Name: f.Name, URI: f.URI, MIMEType: mimeType, File: name, ExpiresAt: expires}
+	return genai.NewPartFromURI(f.URI, mimeType), nil
+}
+
+// filesClient returns the client of the Files API. Uploads go around the response cache: the
+// upload session is started by a POST whose answer must come from the API.
+func (ga *Gemini) filesClient(ag *Agent) (*genai.Client, error) {
+	if ga.files != nil {
+		return ga.files, nil
+	}
+	hc, err := uncachedProviderHTTPClient(ag.Model)
+	if err != nil {
+		return nil, err
+	}
+	if ga.files, err = ag.newGeminiClient(hc); err != nil {
+		return nil, err
+	}
+	return ga.files, nil
+}
+
+// uploadProgress reports the share of an upload read so far.
+type uploadProgress struct {
+	r      io.Reader
+	total  int
+	read   int
+	last   int // Last percentage reported
+	report func(percent int)
+}
+
+func (p *uploadProgress) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += n
+	if p.total > 0 {
+		if percent := p.read * 100 / p.total; percent != p.last {
+			p.last = percent
+			p.report(percent)
+		}
+	}
+	return n, err
+}
+
+// geminiCodeExecutionText renders the code Gemini ran and its output as markdown, "" for the
+// other parts.
+func geminiCodeExecutionText(part *genai.Part) string {
+	switch {
+	case part.ExecutableCode != nil:
+		language := "python"
+		if l := part.ExecutableCode.Language; l != "" && l != genai.LanguageUnspecified {
+			language = strings.ToLower(string(l))
+		}
+		return codeBlockText(language, part.ExecutableCode.Code)
+	case part.CodeExecutionResult != nil:
+		result := part.CodeExecutionResult
+		failure := ""
+		if result.Outcome != "" && result.Outcome != genai.OutcomeOK {
+			failure = strings.ToLower(strings.TrimPrefix(string(result.Outcome), "OUTCOME_"))
+		}
+		return codeOutputText(strings.TrimRight(result.Output, "\n"), failure)
+	}
+	return ""
+}
+
+// codeBlockText renders code run by a provider as a fenced block, "" without code.
+func codeBlockText(language, code string) string {
+	code = strings.TrimRight(code, "\n")
+	if code == "" {
+		return ""
+	}
+	return fmt.Sprintf("\n```%s\n%s\n```\n", language, code)
+}
+
+// codeOutputText renders the output of code run by a provider, with the reason it failed if it did.
+func codeOutputText(output, failure string) string {
+	if failure != "" {
+		return fmt.Sprintf("\nExecution failed (%s):\n```\n%s\n```\n", failure, output)
+	}
+	if output == "" {
+		return ""
+	}
+	return fmt.Sprintf("\nOutput:\n```\n%s\n```\n", output)
+}
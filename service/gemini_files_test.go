@@ -0,0 +1,117 @@
+package service
+
+import (
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestGeminiShouldUpload(t *testing.T) {
+	if geminiShouldUpload(1<<20, 0) {
+		t.Error("a small attachment is sent inline")
+	}
+	if !geminiShouldUpload(geminiUploadThreshold+1, 0) {
+		t.Error("a large attachment is uploaded")
+	}
+	if !geminiShouldUpload(6<<20, 10<<20) {
+		t.Error("attachments over the inline limit are uploaded")
+	}
+}
+
+func TestGeminiUploadsReuse(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "uploads.json")
+	now := time.Now()
+	mi := &ModelInfo{EndPoint: "https://generativelanguage.googleapis.com", ApiKey: "key"}
+	file := NewFileData("video/mp4", []byte("video"), "/tmp/talk.mp4")
+
+	uploads := loadFileUploads(path)
+	uploads.Uploads[uploadKey(mi, file.Data())] = fileUpload{
+		Name: "files/abc", URI: "https://generativelanguage.googleapis.com/v1beta/files/abc",
+		MIMEType: "video/mp4", File: "talk.mp4", ExpiresAt: now.Add(24 * time.Hour),
+	}
+	uploads.Uploads["old"] = fileUpload{URI: "https://generativelanguage.googleapis.com/v1beta/files/old", ExpiresAt: now.Add(-uploadRetention - time.Hour)}
+	if !uploads.prune(now) {
+		t.Error("an upload expired long ago is kept")
+	}
+	if err := uploads.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same content for the same key is not uploaded again, so no client is needed
+	ga := &Gemini{}
+	ag := &Agent{Model: mi}
+	part, err := ga.uploadFile(ag, loadFileUploads(path), file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part.FileData == nil || part.FileData.FileURI != "https://generativelanguage.googleapis.com/v1beta/files/abc" {
+		t.Errorf("part = %+v", part)
+	}
+
+	other := &ModelInfo{EndPoint: mi.EndPoint, ApiKey: "other key"}
+	if uploadKey(other, file.Data()) == uploadKey(mi, file.Data()) {
+		t.Error("the uploads of another API key are reused")
+	}
+	if _, ok := uploads.active(uploadKey(mi, file.Data()), now.Add(24*time.Hour-uploadMargin/2)); ok {
+		t.Error("an upload about to expire is reused")
+	}
+}
+
+func TestGeminiForgetExpired(t *testing.T) {
+	now := time.Now()
+	uploads := &fileUploads{Uploads: map[string]fileUpload{
+		"a": {URI: "https://generativelanguage.googleapis.com/v1beta/files/a", File: "a.pdf", ExpiresAt: now.Add(-time.Hour)},
+		"b": {URI: "https://generativelanguage.googleapis.com/v1beta/files/b", File: "b.pdf", ExpiresAt: now.Add(10 * time.Hour)},
+	}}
+	messages := []*genai.Content{{Role: genai.RoleUser, Parts: []*genai.Part{
+		{Text: "compare"},
+		genai.NewPartFromURI("https://generativelanguage.googleapis.com/v1beta/files/a", "application/pdf"),
+		genai.NewPartFromURI("https://generativelanguage.googleapis.com/v1beta/files/b", "application/pdf"),
+		genai.NewPartFromURI("https://www.youtube.com/watch?v=x", "video/mp4"),
+	}}}
+	parts := forgetExpiredGeminiFiles(messages, uploads, now)[0].Parts
+	if parts[1].FileData != nil || !strings.Contains(parts[1].Text, "a.pdf") {
+		t.Errorf("expired upload kept: %+v", parts[1])
+	}
+	if parts[2].FileData == nil || parts[3].FileData == nil {
+		t.Error("a valid reference was dropped")
+	}
+}
+
+func TestUploadProgress(t *testing.T) {
+	var reported []int
+	p := &uploadProgress{r: strings.NewReader(strings.Repeat("x", 100)), total: 100, last: -1, report: func(percent int) {
+		reported = append(reported, percent)
+	}}
+	buf := make([]byte, 40)
+	for {
+		if _, err := p.Read(buf); err == io.EOF {
+			break
+		}
+	}
+	if len(reported) != 3 || reported[2] != 100 {
+		t.Errorf("reported %v", reported)
+	}
+}
+
+func TestGeminiCodeExecutionText(t *testing.T) {
+	code := geminiCodeExecutionText(&genai.Part{ExecutableCode: &genai.ExecutableCode{Code: "print(1+1)\n", Language: genai.LanguagePython}})
+	if code != "\n```python\nprint(1+1)\n```\n" {
+		t.Errorf("code = %q", code)
+	}
+	output := geminiCodeExecutionText(&genai.Part{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeOK, Output: "2\n"}})
+	if output != "\nOutput:\n```\n2\n```\n" {
+		t.Errorf("output = %q", output)
+	}
+	failed := geminiCodeExecutionText(&genai.Part{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeDeadlineExceeded}})
+	if !strings.Contains(failed, "deadline_exceeded") {
+		t.Errorf("failed = %q", failed)
+	}
+	if geminiCodeExecutionText(&genai.Part{Text: "hi"}) != "" {
+		t.Error("text is not code")
+	}
+}
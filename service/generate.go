@@ -0,0 +1,61 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/activebook/gllm/data"
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	openai "github.com/openai/openai-go/v3"
+	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
+	"github.com/volcengine/volcengine-go-sdk/volcengine"
+	"google.golang.org/genai"
+)
+
+// GenerateText sends a single user prompt to the model of the agent and returns the reply.
+//
+// It mirrors GenerateSessionName's architecture: a minimal *Agent is constructed
+// from the config (ModelInfo + ContextManager only) and the provider-specific sync
+// method is invoked. No agentic tool loop is involved.
+func GenerateText(modelConfig *data.AgentConfig, systemPrompt, userPrompt string) (string, error) {
+	ag := &Agent{Model: constructModelInfo(&modelConfig.Model)}
+	ag.Context = NewContextManager(ag, StrategyNone)
+
+	switch modelConfig.Model.Provider {
+
+	case ModelProviderOpenAI, ModelProviderMock:
+		msgs := []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage(userPrompt),
+		}
+		return ag.GenerateOpenAISync(msgs, systemPrompt)
+
+	case ModelProviderAnthropic:
+		msgs := []anthropic.MessageParam{
+			anthropic.NewUserMessage(anthropic.NewTextBlock(userPrompt)),
+		}
+		return ag.GenerateAnthropicSync(msgs, systemPrompt)
+
+	case ModelProviderGemini:
+		msgs := []*genai.Content{
+			{
+				Role:  genai.RoleUser,
+				Parts: []*genai.Part{{Text: userPrompt}},
+			},
+		}
+		return ag.GenerateGeminiSync(msgs, systemPrompt)
+
+	case ModelProviderOpenAICompatible:
+		msgs := []*model.ChatCompletionMessage{
+			{
+				Role: model.ChatMessageRoleUser,
+				Content: &model.ChatCompletionMessageContent{
+					StringValue: volcengine.String(userPrompt),
+				},
+				Name: Ptr(""),
+			},
+		}
+		return ag.GenerateOpenChatSync(msgs, systemPrompt)
+
+	default:
+		return "", fmt.Errorf("unsupported provider: %s", modelConfig.Model.Provider)
+	}
+}
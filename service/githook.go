@@ -0,0 +1,239 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+)
+
+// Git hooks gllm can install.
+const (
+	GitHookCommitMsg = "commit-msg"
+	GitHookPrePush   = "pre-push"
+)
+
+// SupportedGitHooks lists the git hooks gllm can install.
+var SupportedGitHooks = []string{GitHookCommitMsg, GitHookPrePush}
+
+// gitHookMarker identifies the hooks gllm installed, so they are never mistaken for the user's.
+const gitHookMarker = "# Installed by 'gllm hook install'"
+
+// maxHookDiffSize bounds the diff sent to the model by a hook.
+const maxHookDiffSize = 96 * 1024
+
+// gitZeroSHA is the object name pre-push reports for a ref that does not exist on the other side.
+const gitZeroSHA = "0000000000000000000000000000000000000000"
+
+const commitMessageSystemPrompt = `You write git commit messages from a staged diff.
+Write a subject line in the imperative mood under 72 characters, without a trailing period.
+If the change needs explaining, add a blank line and a short body wrapped at 72 characters
+saying what changed and why. Output the message only, without code fences or commentary.`
+
+const commitReviewSystemPrompt = `You check that a git commit message fits its staged diff.
+Fail the message only when it is empty of meaning (such as "wip" or "fix"), misdescribes the
+change, or its subject line is longer than 72 characters.
+Answer with PASS or FAIL on the first line, then at most a few lines of reasons.`
+
+const pushReviewSystemPrompt = `You review the diff of commits about to be pushed.
+Fail the push only for clear problems: leaked secrets or credentials, debugging leftovers,
+merge conflict markers, or obvious bugs. Style and taste are not reasons to fail.
+Answer with PASS or FAIL on the first line, then at most a few lines of reasons.`
+
+// HookVerdict is the outcome of a hook review.
+type HookVerdict struct {
+	Pass   bool
+	Reason string
+}
+
+// IsSupportedGitHook reports whether gllm can install the hook.
+func IsSupportedGitHook(hook string) bool {
+	for _, h := range SupportedGitHooks {
+		if h == hook {
+			return true
+		}
+	}
+	return false
+}
+
+// GitHooksDir returns the hooks directory of the repository at dir, honoring core.hooksPath.
+func GitHooksDir(ctx context.Context, dir string) (string, error) {
+	hooks, err := runGit(ctx, dir, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", fmt.Errorf("not a git repository: %w", err)
+	}
+	if !filepath.IsAbs(hooks) {
+		hooks = filepath.Join(dir, hooks)
+	}
+	return hooks, nil
+}
+
+// GitHookScript returns the script of a hook calling 'gllm hook run'. When gllm cannot run,
+// with exit code 2, the hook lets git go on rather than blocking the user.
+func GitHookScript(exe, hook, agent string) string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	sb.WriteString(gitHookMarker + ", remove with 'gllm hook uninstall " + hook + "'\n")
+	sb.WriteString(shellQuote(exe) + " hook run " + hook)
+	if agent != "" {
+		sb.WriteString(" --agent " + shellQuote(agent))
+	}
+	sb.WriteString(` "$@"
+status=$?
+if [ $status -eq 2 ]; then
+	echo "gllm hook could not run, skipping it" >&2
+	exit 0
+fi
+exit $status
+`)
+	return sb.String()
+}
+
+// IsGllmGitHook reports whether the hook script was installed by gllm.
+func IsGllmGitHook(script string) bool {
+	return strings.Contains(script, gitHookMarker)
+}
+
+func shellQuote(s string) string {
+	if s != "" && !strings.ContainsAny(s, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// CleanCommitMessage drops the comment lines git adds to the message file and the
+// scissors line with everything below it, as git does.
+func CleanCommitMessage(content string) string {
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, "# ------------------------ >8 ------------------------") {
+			break
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, strings.TrimRight(line, " \t\r"))
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// StagedDiff returns the staged changes of the repository at dir.
+func StagedDiff(ctx context.Context, dir string) (string, error) {
+	return runGit(ctx, dir, "diff", "--cached", "--no-color")
+}
+
+// PushDiff returns the diff of the commits a push sends, read from the ref lines git
+// passes to pre-push: "<local ref> <local sha> <remote ref> <remote sha>".
+func PushDiff(ctx context.Context, dir string, refs io.Reader) (string, error) {
+	var diffs []string
+	scanner := bufio.NewScanner(refs)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 || fields[1] == gitZeroSHA {
+			// Malformed, or deleting the remote ref
+			continue
+		}
+		local, remote := fields[1], fields[3]
+		base := remote
+		if remote == gitZeroSHA {
+			// A new branch: review the commits no remote has yet
+			commits, err := runGit(ctx, dir, "rev-list", "--reverse", local, "--not", "--remotes")
+			if err != nil {
+				return "", err
+			}
+			if commits == "" {
+				continue
+			}
+			oldest := strings.SplitN(commits, "\n", 2)[0]
+			if base, err = runGit(ctx, dir, "rev-parse", "--verify", "--quiet", oldest+"^"); err != nil {
+				// From the root commit on, compare with the empty tree
+				if base, err = runGit(ctx, dir, "hash-object", "-t", "tree", "--stdin"); err != nil {
+					return "", err
+				}
+			}
+		}
+		diff, err := runGit(ctx, dir, "diff", "--no-color", base, local)
+		if err != nil {
+			return "", err
+		}
+		if diff != "" {
+			diffs = append(diffs, diff)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return strings.Join(diffs, "\n"), nil
+}
+
+func truncateHookDiff(diff string) string {
+	if len(diff) > maxHookDiffSize {
+		return diff[:maxHookDiffSize] + "\n... (diff truncated)"
+	}
+	return diff
+}
+
+// GenerateCommitMessage writes a commit message for the staged diff with the model of the agent.
+func GenerateCommitMessage(agent *data.AgentConfig, diff string) (string, error) {
+	prompt := fmt.Sprintf("Write the commit message for this staged diff:\n\n<diff>\n%s\n</diff>", truncateHookDiff(diff))
+	raw, err := GenerateText(agent, commitMessageSystemPrompt, prompt)
+	if err != nil {
+		return "", err
+	}
+	message := strings.TrimSpace(stripCodeFence(raw))
+	if message == "" {
+		return "", fmt.Errorf("model returned an empty commit message")
+	}
+	return message, nil
+}
+
+// ReviewCommitMessage checks the commit message against its staged diff.
+func ReviewCommitMessage(agent *data.AgentConfig, message, diff string) (HookVerdict, error) {
+	prompt := fmt.Sprintf("<message>\n%s\n</message>\n\n<diff>\n%s\n</diff>", message, truncateHookDiff(diff))
+	raw, err := GenerateText(agent, commitReviewSystemPrompt, prompt)
+	if err != nil {
+		return HookVerdict{}, err
+	}
+	return ParseHookVerdict(raw)
+}
+
+// ReviewPush reviews the diff of the commits about to be pushed.
+func ReviewPush(agent *data.AgentConfig, diff string) (HookVerdict, error) {
+	prompt := fmt.Sprintf("Review the commits about to be pushed:\n\n<diff>\n%s\n</diff>", truncateHookDiff(diff))
+	raw, err := GenerateText(agent, pushReviewSystemPrompt, prompt)
+	if err != nil {
+		return HookVerdict{}, err
+	}
+	return ParseHookVerdict(raw)
+}
+
+// ParseHookVerdict reads a PASS or FAIL answer and its reasons.
+func ParseHookVerdict(raw string) (HookVerdict, error) {
+	raw = strings.TrimSpace(stripCodeFence(raw))
+	first, rest, _ := strings.Cut(raw, "\n")
+	word := strings.ToUpper(strings.Trim(strings.TrimSpace(first), "*#:. "))
+	switch {
+	case strings.HasPrefix(word, "PASS"):
+		return HookVerdict{Pass: true, Reason: strings.TrimSpace(rest)}, nil
+	case strings.HasPrefix(word, "FAIL"):
+		return HookVerdict{Pass: false, Reason: strings.TrimSpace(rest)}, nil
+	}
+	return HookVerdict{}, fmt.Errorf("model answered neither PASS nor FAIL: %q", first)
+}
+
+// stripCodeFence removes a code fence wrapping the whole text, which models add unasked.
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") || !strings.HasSuffix(s, "```") || len(s) < 6 {
+		return s
+	}
+	s = strings.TrimSuffix(s, "```")
+	if _, body, ok := strings.Cut(s, "\n"); ok {
+		return body
+	}
+	return ""
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCleanCommitMessage(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"# Please enter the commit message\n#\n# On branch main\n", ""},
+		{"Fix the parser\n\n# comment\n", "Fix the parser"},
+		{"Fix the parser\n\nBody line  \n# comment\n", "Fix the parser\n\nBody line"},
+		{"Subject\n# ------------------------ >8 ------------------------\ndiff --git a/x b/x\n", "Subject"},
+	}
+	for _, tt := range tests {
+		if got := CleanCommitMessage(tt.in); got != tt.want {
+			t.Errorf("CleanCommitMessage(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseHookVerdict(t *testing.T) {
+	tests := []struct {
+		raw    string
+		pass   bool
+		reason string
+	}{
+		{"PASS", true, ""},
+		{"pass\nLooks fine.", true, "Looks fine."},
+		{"**FAIL**\n- leaked API key in config.go", false, "- leaked API key in config.go"},
+		{"```\nFAIL: vague\nThe subject says wip.\n```", false, "The subject says wip."},
+	}
+	for _, tt := range tests {
+		got, err := ParseHookVerdict(tt.raw)
+		if err != nil {
+			t.Errorf("ParseHookVerdict(%q) failed: %v", tt.raw, err)
+			continue
+		}
+		if got.Pass != tt.pass || got.Reason != tt.reason {
+			t.Errorf("ParseHookVerdict(%q) = %+v, want pass=%v reason=%q", tt.raw, got, tt.pass, tt.reason)
+		}
+	}
+	if _, err := ParseHookVerdict("I think it is fine"); err == nil {
+		t.Error("ParseHookVerdict should fail without PASS or FAIL")
+	}
+}
+
+func TestGitHookScript(t *testing.T) {
+	script := GitHookScript("/opt/my tools/gllm", GitHookCommitMsg, "committer")
+	if !IsGllmGitHook(script) {
+		t.Error("the script should be recognized as installed by gllm")
+	}
+	if !strings.Contains(script, `'/opt/my tools/gllm' hook run commit-msg --agent committer "$@"`) {
+		t.Errorf("unexpected command in script:\n%s", script)
+	}
+	if IsGllmGitHook("#!/bin/sh\nnpm test\n") {
+		t.Error("a user's hook should not be recognized as installed by gllm")
+	}
+	if _, err := exec.LookPath("sh"); err == nil {
+		if out, err := exec.Command("sh", "-n", "-c", script).CombinedOutput(); err != nil {
+			t.Errorf("script is not valid sh: %v %s", err, out)
+		}
+	}
+}
+
+func TestPushDiff(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	git := func(args ...string) string {
+		t.Helper()
+		out, err := runGit(ctx, repo, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	commitFile := func(name, content string) string {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repo, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "-A")
+		git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "update "+name)
+		return git("rev-parse", "HEAD")
+	}
+	git("init", "-q", "-b", "main")
+	first := commitFile("a.txt", "one\n")
+	second := commitFile("b.txt", "two\n")
+
+	// An update of an existing remote ref reviews the commits after it
+	diff, err := PushDiff(ctx, repo, strings.NewReader("refs/heads/main "+second+" refs/heads/main "+first+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "b.txt") || strings.Contains(diff, "a.txt") {
+		t.Errorf("diff should cover b.txt only:\n%s", diff)
+	}
+
+	// A new branch without remotes reviews the whole history, from the root commit
+	diff, err = PushDiff(ctx, repo, strings.NewReader("refs/heads/main "+second+" refs/heads/main "+gitZeroSHA+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "a.txt") || !strings.Contains(diff, "b.txt") {
+		t.Errorf("diff should cover all the commits:\n%s", diff)
+	}
+
+	// Deleting a remote ref has nothing to review
+	diff, err = PushDiff(ctx, repo, strings.NewReader("(delete) "+gitZeroSHA+" refs/heads/old "+first+"\n"))
+	if err != nil || diff != "" {
+		t.Errorf("PushDiff of a deletion = %q, %v, want nothing", diff, err)
+	}
+}
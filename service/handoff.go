@@ -0,0 +1,196 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// handoffResults is how many of the latest tool results a handoff carries at most.
+	handoffResults = 12
+	// handoffResultMaxRunes caps each tool result of a handoff.
+	handoffResultMaxRunes = 2000
+	// handoffDigestMaxRunes caps the whole digest of a handoff.
+	handoffDigestMaxRunes = 16000
+)
+
+// handoffSkipTools are read-only tools whose results are of no use to another agent.
+var handoffSkipTools = map[string]bool{
+	ToolAskUser:         true,
+	ToolPresentOptions:  true,
+	ToolRequestApproval: true,
+	ToolExitPlanMode:    true,
+	ToolEnterPlanMode:   true,
+	ToolActivateSkill:   true,
+	ToolListAgent:       true,
+	ToolSpawnSubAgents:  true,
+	ToolMapOverFiles:    true,
+	ToolGetState:        true, // The state itself is listed
+	ToolListState:       true,
+	ToolTodoWrite:       true,
+	ToolTodoRead:        true,
+	ToolListMemory:      true,
+}
+
+// handoffResult is a tool call of the run and its result.
+type handoffResult struct {
+	call   string
+	result string
+}
+
+// handoffLog keeps the latest results of the reading and searching tools of a run, for
+// switch_agent to pass on so the next agent doesn't call them again.
+type handoffLog struct {
+	results []handoffResult
+}
+
+func (l *handoffLog) record(call, result string) {
+	// A repeated call keeps its latest result only
+	for i, r := range l.results {
+		if r.call == call {
+			l.results = append(l.results[:i], l.results[i+1:]...)
+			break
+		}
+	}
+	l.results = append(l.results, handoffResult{call: call, result: result})
+	if len(l.results) > handoffResults {
+		l.results = l.results[len(l.results)-handoffResults:]
+	}
+}
+
+// handoffFilter returns the result filter that keeps the result of a reading or searching
+// tool for a handoff.
+func (op *OpenProcessor) handoffFilter(name string, args map[string]interface{}) func(string) string {
+	if !readOnlyTools[name] || handoffSkipTools[name] {
+		return func(text string) string { return text }
+	}
+	call := toolCallText(name, args)
+	return func(text string) string {
+		op.handoff.record(call, text)
+		return text
+	}
+}
+
+// handoffDigest describes what the agent found for the agent it hands off to: the latest
+// tool results, newest first until the size cap, and the keys of the shared state.
+func (op *OpenProcessor) handoffDigest(target string) string {
+	var results []string
+	size := 0
+	for i := len(op.handoff.results) - 1; i >= 0; i-- {
+		r := op.handoff.results[i]
+		entry := fmt.Sprintf("## %s\n%s", r.call, truncateRunes(r.result, handoffResultMaxRunes))
+		if size += len([]rune(entry)); size > handoffDigestMaxRunes {
+			break
+		}
+		results = append(results, entry)
+	}
+
+	var state []string
+	if op.sharedState != nil {
+		entries := op.sharedState.List()
+		keys := make([]string, 0, len(entries))
+		for key := range entries {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			meta := entries[key]
+			state = append(state, fmt.Sprintf("- %s (%s, %d bytes, set by %s)", key, meta.ContentType, meta.Size, meta.CreatedBy))
+		}
+	}
+	if len(results) == 0 && len(state) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	from := op.agentName
+	if from == "" {
+		from = "the previous agent"
+	} else {
+		from = fmt.Sprintf("agent '%s'", from)
+	}
+	fmt.Fprintf(&sb, "Handoff to agent '%s' from %s.", target, from)
+	if len(results) > 0 {
+		sb.WriteString(" These are the results of its latest tool calls, oldest first: reuse them instead of calling the same tools again, unless the files may have changed since.\n\n")
+		for i := len(results) - 1; i >= 0; i-- {
+			sb.WriteString(results[i])
+			sb.WriteString("\n\n")
+		}
+	} else {
+		sb.WriteString("\n\n")
+	}
+	if len(state) > 0 {
+		sb.WriteString("## Shared state, read the keys with get_state\n")
+		sb.WriteString(strings.Join(state, "\n"))
+		sb.WriteString("\n")
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// handoffReturn is a switch_agent call made with return_to_caller, waiting for the target
+// agent to hand back.
+type handoffReturn struct {
+	caller string
+	target string
+}
+
+var (
+	handoffReturnsMu sync.Mutex
+	handoffReturns   []handoffReturn
+)
+
+// pushHandoffReturn records that the target agent must hand back to the caller when done.
+func pushHandoffReturn(caller, target string) {
+	handoffReturnsMu.Lock()
+	defer handoffReturnsMu.Unlock()
+	handoffReturns = append(handoffReturns, handoffReturn{caller: caller, target: target})
+}
+
+// popHandoffReturn removes the latest pending return of the agent and gives its caller.
+func popHandoffReturn(agent string) (string, bool) {
+	handoffReturnsMu.Lock()
+	defer handoffReturnsMu.Unlock()
+	n := len(handoffReturns)
+	if n == 0 || handoffReturns[n-1].target != agent {
+		return "", false
+	}
+	caller := handoffReturns[n-1].caller
+	handoffReturns = handoffReturns[:n-1]
+	return caller, true
+}
+
+// PendingHandoffReturn reports whether the agent was switched to with return_to_caller and
+// still has to hand back, and to which agent.
+func PendingHandoffReturn(agent string) (string, bool) {
+	handoffReturnsMu.Lock()
+	defer handoffReturnsMu.Unlock()
+	n := len(handoffReturns)
+	if n == 0 || handoffReturns[n-1].target != agent {
+		return "", false
+	}
+	return handoffReturns[n-1].caller, true
+}
+
+// ClearHandoffReturns drops the pending returns, when the user switches agents themselves.
+func ClearHandoffReturns() {
+	handoffReturnsMu.Lock()
+	defer handoffReturnsMu.Unlock()
+	handoffReturns = nil
+}
+
+// withHandoffBackTool offers handoff_back only to an agent that has to hand back.
+func withHandoffBackTool(tools []string, agent string) []string {
+	tools = slices.DeleteFunc(slices.Clone(tools), func(t string) bool { return t == ToolHandoffBack })
+	if _, ok := PendingHandoffReturn(agent); ok {
+		tools = append(tools, ToolHandoffBack)
+	}
+	return tools
+}
+
+// handoffReturnNote tells the agent switched to with return_to_caller how to hand back.
+func handoffReturnNote(caller string) string {
+	return fmt.Sprintf("When you are done, call %s with a summary of your results: control then returns to agent '%s', which continues the task. Don't hand back before the instruction is done, unless you can't do it.", ToolHandoffBack, caller)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestHandoffDigest(t *testing.T) {
+	state := data.NewSharedState()
+	state.Set("findings", "the bug is in the parser", "researcher")
+	op := &OpenProcessor{agentName: "researcher", sharedState: state}
+
+	read := map[string]interface{}{"path": "parser.go"}
+	op.handoffFilter(ToolReadFile, read)("package parser // v1")
+	op.handoffFilter(ToolSearchFiles, map[string]interface{}{"pattern": "*.go"})("parser.go\nlexer.go")
+	op.handoffFilter(ToolReadFile, read)("package parser // v2")
+	op.handoffFilter(ToolWriteFile, map[string]interface{}{"path": "x.go"})("written")
+	op.handoffFilter(ToolTodoRead, map[string]interface{}{})("no tasks")
+
+	digest := op.handoffDigest("coder")
+	for _, want := range []string{
+		"Handoff to agent 'coder' from agent 'researcher'.",
+		`## search_files {"pattern":"*.go"}` + "\nparser.go\nlexer.go",
+		`## read_file {"path":"parser.go"}` + "\npackage parser // v2",
+		"- findings (text, ",
+	} {
+		if !strings.Contains(digest, want) {
+			t.Errorf("the digest misses %q:\n%s", want, digest)
+		}
+	}
+	for _, unwanted := range []string{"// v1", "write_file", "todo_read"} {
+		if strings.Contains(digest, unwanted) {
+			t.Errorf("the digest has %q:\n%s", unwanted, digest)
+		}
+	}
+	if strings.Index(digest, "search_files") > strings.Index(digest, "read_file") {
+		t.Errorf("the results must be oldest first:\n%s", digest)
+	}
+
+	// The digest is capped, the oldest results are left out
+	op = &OpenProcessor{}
+	for i := 0; i < handoffResults; i++ {
+		op.handoffFilter(ToolReadFile, map[string]interface{}{"path": string(rune('a' + i))})(strings.Repeat("x", handoffResultMaxRunes*2))
+	}
+	if digest := op.handoffDigest("coder"); len([]rune(digest)) > handoffDigestMaxRunes+500 || strings.Contains(digest, `"path":"a"`) {
+		t.Errorf("the digest is not capped: %d runes", len([]rune(digest)))
+	}
+
+	if digest := (&OpenProcessor{}).handoffDigest("coder"); digest != "" {
+		t.Errorf("nothing to hand off, got %q", digest)
+	}
+}
+
+func TestSwitchAgentErrorPrompt(t *testing.T) {
+	if p := (SwitchAgentError{Handoff: "digest"}).Prompt(); p != "" {
+		t.Errorf("a switch without an instruction has nothing to do, got %q", p)
+	}
+	p := SwitchAgentError{Instruction: "Fix the parser", Handoff: "digest"}.Prompt()
+	if !strings.Contains(p, "digest") || StripInlineContext(p) != "Fix the parser" {
+		t.Errorf("unexpected prompt %q", p)
+	}
+}
+
+func TestHandoffReturns(t *testing.T) {
+	defer ClearHandoffReturns()
+
+	pushHandoffReturn("planner", "coder")
+	pushHandoffReturn("coder", "tester")
+	if _, ok := PendingHandoffReturn("coder"); ok {
+		t.Errorf("only the latest target has to hand back")
+	}
+	if caller, ok := PendingHandoffReturn("tester"); !ok || caller != "coder" {
+		t.Errorf("PendingHandoffReturn(tester) = %q, %v", caller, ok)
+	}
+	tools := withHandoffBackTool([]string{ToolReadFile, ToolHandoffBack}, "tester")
+	if len(tools) != 2 || tools[1] != ToolHandoffBack {
+		t.Errorf("tester must be offered handoff_back once: %v", tools)
+	}
+	if tools := withHandoffBackTool([]string{ToolReadFile, ToolHandoffBack}, "planner"); len(tools) != 1 {
+		t.Errorf("planner must not be offered handoff_back: %v", tools)
+	}
+
+	if caller, ok := popHandoffReturn("tester"); !ok || caller != "coder" {
+		t.Errorf("popHandoffReturn(tester) = %q, %v", caller, ok)
+	}
+	if caller, ok := popHandoffReturn("coder"); !ok || caller != "planner" {
+		t.Errorf("popHandoffReturn(coder) = %q, %v", caller, ok)
+	}
+	if _, ok := popHandoffReturn("coder"); ok {
+		t.Errorf("nothing left to hand back")
+	}
+
+	pushHandoffReturn("planner", "coder")
+	ClearHandoffReturns()
+	if _, ok := PendingHandoffReturn("coder"); ok {
+		t.Errorf("the returns are cleared")
+	}
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/activebook/gllm/data"
+)
+
+// Authentications of the auth profiles of the http_request tool.
+const (
+	HTTPAuthBearer = "bearer" // Authorization: Bearer <secret>
+	HTTPAuthBasic  = "basic"  // Authorization: Basic <username:secret>
+	HTTPAuthHeader = "header" // <Header>: <secret>
+)
+
+const (
+	defaultHTTPRequestTimeout = 30 * time.Second
+	maxHTTPRequestTimeout     = 5 * time.Minute
+	defaultHTTPResponseBytes  = 100 * 1024
+	maxHTTPResponseBytes      = 1024 * 1024
+)
+
+// httpResponseHeaders are the response headers shown besides the body.
+var httpResponseHeaders = []string{"Content-Type", "Content-Length", "Location", "Retry-After", "Link", "ETag", "Last-Modified", "WWW-Authenticate"}
+
+// HTTPRequest is a request of the http_request tool.
+type HTTPRequest struct {
+	Method   string
+	URL      string
+	Headers  map[string]string
+	Body     string
+	Profile  string        // Name of the auth profile, empty sends no credentials
+	Timeout  time.Duration // 0 uses the default
+	MaxBytes int           // Bytes of the body shown, 0 uses the default
+}
+
+// ValidateHTTPProfile checks that the profile has what its type needs.
+func ValidateHTTPProfile(p data.HTTPProfile) error {
+	switch p.Type {
+	case HTTPAuthBearer:
+	case HTTPAuthBasic:
+		if p.Username == "" {
+			return fmt.Errorf("basic auth needs a username")
+		}
+	case HTTPAuthHeader:
+		if p.Header == "" {
+			return fmt.Errorf("header auth needs the name of the header")
+		}
+	default:
+		return fmt.Errorf("unsupported auth type '%s', expected %s, %s or %s", p.Type, HTTPAuthBearer, HTTPAuthBasic, HTTPAuthHeader)
+	}
+	u, err := url.Parse(p.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("the profile needs the base URL its credential is sent to, e.g. https://api.example.com")
+	}
+	return nil
+}
+
+// httpProfileAllows reports whether target is under the base URL of a profile: the same scheme
+// and host, and a path below the base path.
+func httpProfileAllows(base, target string) bool {
+	b, err := url.Parse(base)
+	if err != nil {
+		return false
+	}
+	t, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	if !strings.EqualFold(b.Scheme, t.Scheme) || !strings.EqualFold(b.Host, t.Host) {
+		return false
+	}
+	prefix := strings.TrimSuffix(b.EscapedPath(), "/")
+	path := t.EscapedPath()
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// DoHTTPRequest sends the request with the credential of its profile and returns the status,
+// the main headers and the body, JSON pretty-printed and cut at the size limit.
+func DoHTTPRequest(ctx context.Context, transport http.RoundTripper, r HTTPRequest) (string, error) {
+	method := strings.ToUpper(strings.TrimSpace(r.Method))
+	if method == "" {
+		method = http.MethodGet
+	}
+	u, err := url.Parse(r.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return "", fmt.Errorf("invalid URL '%s', expected http:// or https://", r.URL)
+	}
+
+	var profile data.HTTPProfile
+	var secret string
+	if r.Profile != "" {
+		var ok bool
+		profile, ok = data.GetSettingsStore().GetHTTPProfiles()[r.Profile]
+		if !ok {
+			return "", fmt.Errorf("auth profile '%s' not found, the user can add it with 'gllm config http add %s'", r.Profile, r.Profile)
+		}
+		if !httpProfileAllows(profile.URL, r.URL) {
+			return "", fmt.Errorf("auth profile '%s' is only sent to %s", r.Profile, profile.URL)
+		}
+		secret, err = data.GetSecret(data.HTTPProfileSecretName(r.Profile))
+		if err != nil {
+			return "", err
+		}
+		if secret == "" {
+			return "", fmt.Errorf("the secret of auth profile '%s' is missing, add it again with 'gllm config http add %s'", r.Profile, r.Profile)
+		}
+	}
+
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPRequestTimeout
+	}
+	timeout = min(timeout, maxHTTPRequestTimeout)
+	maxBytes := r.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultHTTPResponseBytes
+	}
+	maxBytes = min(maxBytes, maxHTTPResponseBytes)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	var body io.Reader
+	if r.Body != "" {
+		body = strings.NewReader(r.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, r.URL, body)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+	if r.Body != "" && req.Header.Get("Content-Type") == "" && json.Valid([]byte(r.Body)) {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch profile.Type {
+	case HTTPAuthBearer:
+		req.Header.Set("Authorization", "Bearer "+secret)
+	case HTTPAuthBasic:
+		req.SetBasicAuth(profile.Username, secret)
+	case HTTPAuthHeader:
+		req.Header.Set(profile.Header, secret)
+	}
+
+	client := &http.Client{
+		Transport: transport,
+		CheckRedirect: func(next *http.Request, via []*http.Request) error {
+			// A credential is never carried beyond the URL of its profile
+			if r.Profile != "" && !httpProfileAllows(profile.URL, next.URL.String()) {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the response: %w", err)
+	}
+	elapsed := time.Since(start)
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s %s (%d ms)\n", resp.Proto, resp.Status, elapsed.Milliseconds()))
+	for _, h := range httpResponseHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			sb.WriteString(fmt.Sprintf("%s: %s\n", h, v))
+		}
+	}
+	var rateLimits []string
+	for h, v := range resp.Header {
+		if strings.HasPrefix(strings.ToLower(h), "x-ratelimit") {
+			rateLimits = append(rateLimits, fmt.Sprintf("%s: %s\n", h, strings.Join(v, ", ")))
+		}
+	}
+	sort.Strings(rateLimits)
+	sb.WriteString(strings.Join(rateLimits, ""))
+	sb.WriteString("\n")
+
+	truncated := len(content) > maxBytes
+	if truncated {
+		content = content[:maxBytes]
+		// The cut may split the last character
+		for i := 1; i < utf8.UTFMax && !utf8.Valid(content); i++ {
+			content = content[:len(content)-1]
+		}
+	}
+	switch {
+	case len(content) == 0:
+		sb.WriteString("<empty body>")
+	case !utf8.Valid(content):
+		sb.WriteString(fmt.Sprintf("<binary body, %s>", resp.Header.Get("Content-Type")))
+	default:
+		sb.WriteString(formatHTTPBody(content, resp.Header.Get("Content-Type")))
+	}
+	if truncated {
+		sb.WriteString(fmt.Sprintf("\n[body truncated at %d bytes]", maxBytes))
+	}
+	return sb.String(), nil
+}
+
+// formatHTTPBody pretty-prints a JSON body, other bodies are returned as they are.
+func formatHTTPBody(content []byte, contentType string) string {
+	if strings.Contains(contentType, "json") || json.Valid(content) {
+		var pretty bytes.Buffer
+		if err := json.Indent(&pretty, content, "", "  "); err == nil {
+			return pretty.String()
+		}
+	}
+	return string(content)
+}
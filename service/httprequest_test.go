@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPProfileAllows(t *testing.T) {
+	cases := []struct {
+		base, target string
+		want         bool
+	}{
+		{"https://api.github.com", "https://api.github.com/repos/a/b", true},
+		{"https://api.github.com/", "https://API.github.com/user", true},
+		{"https://api.example.com/v1", "https://api.example.com/v1", true},
+		{"https://api.example.com/v1", "https://api.example.com/v1/items?q=1", true},
+		{"https://api.example.com/v1", "https://api.example.com/v10/items", false},
+		{"https://api.example.com/v1", "https://api.example.com/v2", false},
+		{"https://api.example.com", "http://api.example.com/", false},
+		{"https://api.example.com", "https://api.example.com.evil.io/", false},
+		{"https://api.example.com", "https://api.example.com:8443/", false},
+	}
+	for _, c := range cases {
+		if got := httpProfileAllows(c.base, c.target); got != c.want {
+			t.Errorf("httpProfileAllows(%q, %q) = %v, want %v", c.base, c.target, got, c.want)
+		}
+	}
+}
+
+func TestDoHTTPRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-RateLimit-Remaining", "41")
+			w.WriteHeader(http.StatusCreated)
+			io.WriteString(w, `{"method":"`+r.Method+`","type":"`+r.Header.Get("Content-Type")+`","trace":"`+r.Header.Get("X-Trace")+`","body":`+string(body)+`}`)
+		case "/large":
+			io.WriteString(w, strings.Repeat("x", 2048))
+		case "/binary":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0xff, 0xfe, 0x00, 0x01})
+		}
+	}))
+	defer srv.Close()
+
+	out, err := DoHTTPRequest(context.Background(), nil, HTTPRequest{
+		Method:  "post",
+		URL:     srv.URL + "/echo",
+		Headers: map[string]string{"X-Trace": "abc"},
+		Body:    `{"name":"gllm"}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"201 Created", "X-Ratelimit-Remaining: 41", "\"method\": \"POST\"", "\"type\": \"application/json\"", "\"trace\": \"abc\"", "\"name\": \"gllm\""} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in:\n%s", want, out)
+		}
+	}
+
+	out, err = DoHTTPRequest(context.Background(), nil, HTTPRequest{URL: srv.URL + "/large", MaxBytes: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out, strings.Repeat("x", 100)+"\n[body truncated at 100 bytes]") {
+		t.Errorf("expected the body cut at 100 bytes, got:\n%s", out)
+	}
+
+	out, err = DoHTTPRequest(context.Background(), nil, HTTPRequest{URL: srv.URL + "/binary"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "<binary body, application/octet-stream>") {
+		t.Errorf("expected the binary body left out, got:\n%s", out)
+	}
+
+	if _, err := DoHTTPRequest(context.Background(), nil, HTTPRequest{URL: "ftp://example.com"}); err == nil {
+		t.Error("expected a non-HTTP URL to be refused")
+	}
+}
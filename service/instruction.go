@@ -8,11 +8,6 @@ import (
 	"strings"
 
 	"github.com/activebook/gllm/data"
-	anthropic "github.com/anthropics/anthropic-sdk-go"
-	openai "github.com/openai/openai-go/v3"
-	"github.com/volcengine/volcengine-go-sdk/service/arkruntime/model"
-	"github.com/volcengine/volcengine-go-sdk/volcengine"
-	"google.golang.org/genai"
 )
 
 // InstructionSystemPrompt is the system prompt for GLLM.md generation.
@@ -277,11 +272,6 @@ func readTruncated(sb *strings.Builder, path string, maxLines int) {
 // and calls the active agent's provider synchronously to produce the raw Markdown
 // content of GLLM.md.
 //
-// This function mirrors GenerateSessionName's architectural pattern exactly:
-// a minimal *Agent is constructed from the config (ModelInfo + ContextManager only),
-// the context is packaged as the user message, and the provider-specific sync method
-// is invoked. No agentic tool loop is involved.
-//
 // The caller is responsible for writing the returned string to disk.
 func GenerateInstructionContent(modelConfig *data.AgentConfig) (string, error) {
 	ctx := scanProjectContext()
@@ -290,45 +280,5 @@ func GenerateInstructionContent(modelConfig *data.AgentConfig) (string, error) {
 		ctx,
 	)
 
-	ag := &Agent{Model: constructModelInfo(&modelConfig.Model)}
-	ag.Context = NewContextManager(ag, StrategyNone)
-
-	switch modelConfig.Model.Provider {
-
-	case ModelProviderOpenAI:
-		msgs := []openai.ChatCompletionMessageParamUnion{
-			openai.UserMessage(userPrompt),
-		}
-		return ag.GenerateOpenAISync(msgs, InstructionSystemPrompt)
-
-	case ModelProviderAnthropic:
-		msgs := []anthropic.MessageParam{
-			anthropic.NewUserMessage(anthropic.NewTextBlock(userPrompt)),
-		}
-		return ag.GenerateAnthropicSync(msgs, InstructionSystemPrompt)
-
-	case ModelProviderGemini:
-		msgs := []*genai.Content{
-			{
-				Role:  genai.RoleUser,
-				Parts: []*genai.Part{{Text: userPrompt}},
-			},
-		}
-		return ag.GenerateGeminiSync(msgs, InstructionSystemPrompt)
-
-	case ModelProviderOpenAICompatible:
-		msgs := []*model.ChatCompletionMessage{
-			{
-				Role: model.ChatMessageRoleUser,
-				Content: &model.ChatCompletionMessageContent{
-					StringValue: volcengine.String(userPrompt),
-				},
-				Name: Ptr(""),
-			},
-		}
-		return ag.GenerateOpenChatSync(msgs, InstructionSystemPrompt)
-
-	default:
-		return "", fmt.Errorf("unsupported provider for instruction generation: %s", modelConfig.Model.Provider)
-	}
+	return GenerateText(modelConfig, InstructionSystemPrompt, userPrompt)
 }
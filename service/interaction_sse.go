@@ -61,7 +61,8 @@ func (r *interactionRegistry) delete(id string) {
 }
 
 // ResolveConfirm is called from the /v1/interact endpoint with the user's decision.
-func (r *interactionRegistry) ResolveConfirm(id string, approve string) error {
+// A cancel with feedback declines the call and sends the feedback to the model.
+func (r *interactionRegistry) ResolveConfirm(id string, approve string, feedback string) error {
 	entry, ok := r.load(id)
 	if !ok || entry.kind != InteractionKindConfirm {
 		return fmt.Errorf("interaction %q not found or not a confirm request", id)
@@ -78,8 +79,20 @@ func (r *interactionRegistry) ResolveConfirm(id string, approve string) error {
 		}
 	case "once", "yes":
 		entry.confirm.toolsUse.ConfirmOnce()
+	case "tool":
+		entry.confirm.toolsUse.ConfirmTool()
+	case "prefix":
+		if prefix := data.CommandPrefix(entry.confirm.toolsUse.Command); prefix != "" {
+			entry.confirm.toolsUse.ConfirmCommandPrefix(prefix)
+		} else {
+			entry.confirm.toolsUse.ConfirmOnce()
+		}
 	default:
-		entry.confirm.toolsUse.ConfirmCancel()
+		if feedback = strings.TrimSpace(feedback); feedback != "" {
+			entry.confirm.toolsUse.ConfirmDeny(feedback)
+		} else {
+			entry.confirm.toolsUse.ConfirmCancel()
+		}
 	}
 
 	close(entry.confirm.done)
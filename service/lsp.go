@@ -0,0 +1,439 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/activebook/gllm/util"
+)
+
+const (
+	lspRequestTimeout = 30 * time.Second
+	lspMaxResults     = 100 // Locations listed per lookup
+)
+
+// lspServerSpec describes how to launch the language server of a language.
+type lspServerSpec struct {
+	Name    string
+	Command []string
+}
+
+var (
+	goLSP         = lspServerSpec{Name: "gopls", Command: []string{"gopls"}}
+	pythonLSP     = lspServerSpec{Name: "pyright", Command: []string{"pyright-langserver", "--stdio"}}
+	typescriptLSP = lspServerSpec{Name: "typescript-language-server", Command: []string{"typescript-language-server", "--stdio"}}
+	rustLSP       = lspServerSpec{Name: "rust-analyzer", Command: []string{"rust-analyzer"}}
+
+	// lspLanguages maps file extensions to their server and LSP language id
+	lspLanguages = map[string]struct {
+		server     lspServerSpec
+		languageID string
+	}{
+		".go":  {goLSP, "go"},
+		".py":  {pythonLSP, "python"},
+		".pyi": {pythonLSP, "python"},
+		".ts":  {typescriptLSP, "typescript"},
+		".tsx": {typescriptLSP, "typescriptreact"},
+		".js":  {typescriptLSP, "javascript"},
+		".jsx": {typescriptLSP, "javascriptreact"},
+		".mjs": {typescriptLSP, "javascript"},
+		".cjs": {typescriptLSP, "javascript"},
+		".rs":  {rustLSP, "rust"},
+	}
+
+	// Files marking the root of a project, nearest first wins
+	lspRootMarkers = []string{"go.mod", "go.work", "package.json", "tsconfig.json", "pyproject.toml", "setup.py", "Cargo.toml", ".git"}
+)
+
+// LSP wire types, only the fields the tools need
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+	// LocationLink fields, some servers answer definitions with links
+	TargetURI            string    `json:"targetUri"`
+	TargetSelectionRange *lspRange `json:"targetSelectionRange"`
+}
+
+type lspDocumentSymbol struct {
+	Name           string              `json:"name"`
+	Detail         string              `json:"detail"`
+	Kind           int                 `json:"kind"`
+	Range          lspRange            `json:"range"`
+	SelectionRange lspRange            `json:"selectionRange"`
+	Children       []lspDocumentSymbol `json:"children"`
+	// SymbolInformation fields, for servers without hierarchical symbols
+	Location      *lspLocation `json:"location"`
+	ContainerName string       `json:"containerName"`
+}
+
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// LSPClient talks JSON-RPC to one language server over stdio.
+type LSPClient struct {
+	spec    lspServerSpec
+	root    string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int
+	pending map[int]chan lspMessage
+	opened  map[string]int // Open document uri -> version
+	closed  chan struct{}
+	err     error // Why the connection closed
+}
+
+// StartLSPClient launches the server in root and performs the initialize handshake.
+func StartLSPClient(ctx context.Context, spec lspServerSpec, root string) (*LSPClient, error) {
+	if _, err := exec.LookPath(spec.Command[0]); err != nil {
+		return nil, fmt.Errorf("language server %s is not installed (%s not found in PATH)", spec.Name, spec.Command[0])
+	}
+	cmd := exec.Command(spec.Command[0], spec.Command[1:]...)
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %v", spec.Name, err)
+	}
+
+	c := &LSPClient{
+		spec:    spec,
+		root:    root,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int]chan lspMessage),
+		opened:  make(map[string]int),
+		closed:  make(chan struct{}),
+	}
+	go c.readLoop(bufio.NewReader(stdout))
+
+	rootURI := pathToURI(root)
+	params := map[string]interface{}{
+		"processId": os.Getpid(),
+		"rootUri":   rootURI,
+		"workspaceFolders": []map[string]string{
+			{"uri": rootURI, "name": filepath.Base(root)},
+		},
+		"capabilities": map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"definition":     map[string]interface{}{"linkSupport": true},
+				"references":     map[string]interface{}{},
+				"documentSymbol": map[string]interface{}{"hierarchicalDocumentSymbolSupport": true},
+			},
+			"workspace": map[string]interface{}{"workspaceFolders": true, "configuration": true},
+		},
+	}
+	if err := c.Call(ctx, "initialize", params, nil); err != nil {
+		c.Shutdown()
+		return nil, fmt.Errorf("failed to initialize %s: %v", spec.Name, err)
+	}
+	if err := c.notify("initialized", map[string]interface{}{}); err != nil {
+		c.Shutdown()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Call sends a request and decodes its result into out, which may be nil.
+func (c *LSPClient) Call(ctx context.Context, method string, params interface{}, out interface{}) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan lspMessage, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, lspRequestTimeout)
+	defer cancel()
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return fmt.Errorf("%s: %s", method, msg.Error.Message)
+		}
+		if out != nil && len(msg.Result) > 0 {
+			return json.Unmarshal(msg.Result, out)
+		}
+		return nil
+	case <-c.closed:
+		return c.err
+	case <-ctx.Done():
+		return fmt.Errorf("%s timed out: %v", method, ctx.Err())
+	}
+}
+
+func (c *LSPClient) notify(method string, params interface{}) error {
+	return c.write(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (c *LSPClient) write(msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := fmt.Fprintf(c.stdin, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = c.stdin.Write(body)
+	return err
+}
+
+func (c *LSPClient) readLoop(r *bufio.Reader) {
+	for {
+		msg, err := readLSPMessage(r)
+		if err != nil {
+			c.mu.Lock()
+			c.err = fmt.Errorf("language server %s exited: %v", c.spec.Name, err)
+			c.mu.Unlock()
+			close(c.closed)
+			return
+		}
+		switch {
+		case msg.ID != nil && msg.Method != "":
+			// Server to client request, answer so the server doesn't stall
+			c.answerServerRequest(msg)
+		case msg.ID != nil:
+			id, _ := strconv.Atoi(string(*msg.ID))
+			c.mu.Lock()
+			ch := c.pending[id]
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg
+			}
+		}
+		// Notifications such as diagnostics and progress are ignored
+	}
+}
+
+func (c *LSPClient) answerServerRequest(msg lspMessage) {
+	var result interface{}
+	if msg.Method == "workspace/configuration" {
+		var params struct {
+			Items []interface{} `json:"items"`
+		}
+		_ = json.Unmarshal(msg.Params, &params)
+		result = make([]interface{}, len(params.Items))
+	}
+	_ = c.write(map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID, "result": result})
+}
+
+func readLSPMessage(r *bufio.Reader) (lspMessage, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return lspMessage{}, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, "Content-Length:"); ok {
+			length, _ = strconv.Atoi(strings.TrimSpace(value))
+		}
+	}
+	if length < 0 {
+		return lspMessage{}, fmt.Errorf("missing Content-Length header")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return lspMessage{}, err
+	}
+	var msg lspMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return lspMessage{}, err
+	}
+	return msg, nil
+}
+
+// syncDocument opens path in the server, or sends its current content if already open.
+func (c *LSPClient) syncDocument(path, languageID string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	uri := pathToURI(path)
+
+	c.mu.Lock()
+	version, open := c.opened[uri]
+	c.opened[uri] = version + 1
+	c.mu.Unlock()
+
+	if !open {
+		return uri, c.notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]interface{}{"uri": uri, "languageId": languageID, "version": 1, "text": string(content)},
+		})
+	}
+	return uri, c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument":   map[string]interface{}{"uri": uri, "version": version + 1},
+		"contentChanges": []map[string]string{{"text": string(content)}},
+	})
+}
+
+// Shutdown asks the server to exit and kills it if it doesn't.
+func (c *LSPClient) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := c.Call(ctx, "shutdown", nil, nil); err == nil {
+		_ = c.notify("exit", nil)
+	}
+	c.stdin.Close()
+
+	done := make(chan struct{})
+	go func() {
+		c.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		c.cmd.Process.Kill()
+	}
+}
+
+var (
+	lspPoolMu sync.Mutex
+	lspPool   = make(map[string]*LSPClient) // Keyed by server name and project root
+)
+
+// getLSPClient returns a running server for the file, starting one if needed.
+func getLSPClient(ctx context.Context, path string) (*LSPClient, string, error) {
+	lang, ok := lspLanguages[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, "", fmt.Errorf("no language server is known for %s files, supported: Go, Python, JavaScript/TypeScript, Rust", filepath.Ext(path))
+	}
+	root := findProjectRoot(path)
+	key := lang.server.Name + "|" + root
+
+	lspPoolMu.Lock()
+	defer lspPoolMu.Unlock()
+	if c, ok := lspPool[key]; ok {
+		select {
+		case <-c.closed:
+			delete(lspPool, key) // Crashed, start a new one
+		default:
+			return c, lang.languageID, nil
+		}
+	}
+	c, err := StartLSPClient(ctx, lang.server, root)
+	if err != nil {
+		return nil, "", err
+	}
+	util.LogDebugf("Started language server %s in %s\n", lang.server.Name, root)
+	lspPool[key] = c
+	return c, lang.languageID, nil
+}
+
+// ShutdownLSPClients stops every language server started by the tools.
+func ShutdownLSPClients() {
+	lspPoolMu.Lock()
+	defer lspPoolMu.Unlock()
+	for key, c := range lspPool {
+		c.Shutdown()
+		delete(lspPool, key)
+	}
+}
+
+// findProjectRoot walks up from path to the nearest directory with a project marker.
+func findProjectRoot(path string) string {
+	abs := absPath(path)
+	dir := filepath.Dir(abs)
+	for d := dir; ; d = filepath.Dir(d) {
+		for _, marker := range lspRootMarkers {
+			if _, err := os.Stat(filepath.Join(d, marker)); err == nil {
+				return d
+			}
+		}
+		if parent := filepath.Dir(d); parent == d {
+			break
+		}
+	}
+	return dir
+}
+
+func pathToURI(path string) string {
+	abs := filepath.ToSlash(absPath(path))
+	if runtime.GOOS == "windows" {
+		abs = "/" + abs
+	}
+	return (&url.URL{Scheme: "file", Path: abs}).String()
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	p := u.Path
+	if runtime.GOOS == "windows" {
+		p = strings.TrimPrefix(p, "/")
+	}
+	return filepath.FromSlash(p)
+}
+
+// lspSymbolKinds names the LSP SymbolKind values
+var lspSymbolKinds = []string{"", "file", "module", "namespace", "package", "class", "method", "property",
+	"field", "constructor", "enum", "interface", "function", "variable", "constant", "string", "number",
+	"boolean", "array", "object", "key", "null", "enum member", "struct", "event", "operator", "type parameter"}
+
+func lspSymbolKindName(kind int) string {
+	if kind > 0 && kind < len(lspSymbolKinds) {
+		return lspSymbolKinds[kind]
+	}
+	return "symbol"
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestFakeLSPServer is not a real test, it is started as a language server by the tests below.
+func TestFakeLSPServer(t *testing.T) {
+	if os.Getenv("GLLM_FAKE_LSP") != "1" {
+		t.Skip("helper process")
+	}
+	r := bufio.NewReader(os.Stdin)
+	send := func(v interface{}) {
+		body, _ := json.Marshal(v)
+		fmt.Fprintf(os.Stdout, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	for {
+		msg, err := readLSPMessage(r)
+		if err != nil {
+			os.Exit(0)
+		}
+		var result interface{}
+		switch msg.Method {
+		case "initialize":
+			// Ask the client something first, like real servers do
+			send(map[string]interface{}{"jsonrpc": "2.0", "id": 900, "method": "workspace/configuration",
+				"params": map[string]interface{}{"items": []interface{}{map[string]string{}}}})
+			result = map[string]interface{}{"capabilities": map[string]interface{}{}}
+		case "textDocument/definition":
+			var params struct {
+				TextDocument struct{ URI string } `json:"textDocument"`
+				Position     lspPosition          `json:"position"`
+			}
+			json.Unmarshal(msg.Params, &params)
+			// Point at the first line, echoing the requested column in the end position
+			result = []map[string]interface{}{{
+				"targetUri":            params.TextDocument.URI,
+				"targetSelectionRange": lspRange{Start: lspPosition{Line: 0, Character: 5}, End: params.Position},
+			}}
+		case "textDocument/documentSymbol":
+			result = []lspDocumentSymbol{{Name: "Server", Kind: 23, Range: lspRange{End: lspPosition{Line: 2}},
+				Children: []lspDocumentSymbol{{Name: "Start", Kind: 6, Range: lspRange{Start: lspPosition{Line: 1}, End: lspPosition{Line: 1}}}}}}
+		case "shutdown":
+		default:
+			continue // Notifications and replies
+		}
+		send(map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID, "result": result})
+	}
+}
+
+func TestLSPTools(t *testing.T) {
+	t.Setenv("GLLM_FAKE_LSP", "1")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	lspLanguages[".fake"] = struct {
+		server     lspServerSpec
+		languageID string
+	}{lspServerSpec{Name: "fake", Command: []string{os.Args[0], "-test.run=^TestFakeLSPServer$"}}, "fake"}
+	defer delete(lspLanguages, ".fake")
+	defer ShutdownLSPClients()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.fake")
+	os.WriteFile(path, []byte("type Server struct{}\nfunc (s *Server) Start() { héllo(Server{}) }\n"), 0644)
+	op := &OpenProcessor{ctx: context.Background()}
+
+	args := map[string]interface{}{"path": path, "line": float64(2), "symbol": "Server"}
+	out, err := findDefinitionToolCallImpl(context.Background(), &args, op)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "Definition (1):") || !strings.Contains(out, "main.fake:1:6: type Server struct{}") {
+		t.Errorf("unexpected definition output:\n%s", out)
+	}
+
+	args = map[string]interface{}{"path": path}
+	out, err = documentSymbolsToolCallImpl(context.Background(), &args, op)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "struct Server (lines 1-3)\n  method Start (lines 2-2)") {
+		t.Errorf("unexpected symbols output:\n%s", out)
+	}
+}
+
+func TestLSPPositionFromArgs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.go")
+	os.WriteFile(path, []byte("x := héllo(hello)\n"), 0644)
+
+	tests := []struct {
+		args map[string]interface{}
+		want int
+	}{
+		{map[string]interface{}{"line": float64(1), "symbol": "hello"}, 11}, // Whole word, not inside héllo
+		{map[string]interface{}{"line": float64(1), "column": float64(6)}, 5},
+	}
+	for _, tt := range tests {
+		pos, err := lspPositionFromArgs(path, &tt.args)
+		if err != nil || pos.Line != 0 || pos.Character != tt.want {
+			t.Errorf("lspPositionFromArgs(%v) = %+v, %v, want character %d", tt.args, pos, err, tt.want)
+		}
+	}
+	if _, err := lspPositionFromArgs(path, &map[string]interface{}{"line": float64(1), "symbol": "missing"}); err == nil {
+		t.Errorf("expected an error for a missing symbol")
+	}
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// SinkEmail posts to email addresses, written email:me@example.com,team@example.com.
+const SinkEmail = "email"
+
+// DefaultMailSubject is the subject template when none is configured.
+const DefaultMailSubject = "{{.Title}} - {{.Date}}"
+
+// defaultSMTPPort is the submission port, upgraded with STARTTLS.
+const defaultSMTPPort = 587
+
+// MailMessage is an email with a markdown body, sent as plain text and HTML.
+type MailMessage struct {
+	From    string
+	To      []string
+	Subject string
+	Body    string // Markdown
+}
+
+// MailSubjectData are the fields of the subject template.
+type MailSubjectData struct {
+	Title   string // Name of the workflow or schedule, or the title given to 'gllm mail'
+	Heading string // First heading of the body, or the title
+	Date    string // e.g. 2026-01-02
+	Time    string // e.g. 15:04
+}
+
+// RenderMailSubject fills the subject template, the default one when it is empty.
+func RenderMailSubject(tmpl, title, body string, now time.Time) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultMailSubject
+	}
+	t, err := template.New("subject").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid subject template: %w", err)
+	}
+	fields := MailSubjectData{Title: title, Heading: markdownHeading(body), Date: now.Format("2006-01-02"), Time: now.Format("15:04")}
+	if fields.Heading == "" {
+		fields.Heading = title
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, fields); err != nil {
+		return "", fmt.Errorf("invalid subject template: %w", err)
+	}
+	// Headers are single lines
+	return strings.Join(strings.Fields(sb.String()), " "), nil
+}
+
+// markdownHeading returns the text of the first heading of the markdown.
+func markdownHeading(md string) string {
+	for _, line := range strings.Split(md, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			return strings.TrimSpace(strings.TrimLeft(line, "#"))
+		}
+	}
+	return ""
+}
+
+// MarkdownToHTML renders markdown as a standalone HTML document for email clients.
+func MarkdownToHTML(md string) (string, error) {
+	var body bytes.Buffer
+	renderer := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	if err := renderer.Convert([]byte(md), &body); err != nil {
+		return "", err
+	}
+	// Email clients ignore stylesheets in the head of many messages, keep the styling minimal
+	return `<!DOCTYPE html>
+<html><head><meta charset="utf-8"></head>
+<body style="font-family: -apple-system, Segoe UI, Helvetica, Arial, sans-serif; font-size: 14px; line-height: 1.5; max-width: 800px;">
+` + body.String() + `</body></html>
+`, nil
+}
+
+// BuildMailMessage encodes the message as a MIME multipart/alternative email.
+func BuildMailMessage(msg MailMessage, now time.Time) ([]byte, error) {
+	html, err := MarkdownToHTML(msg.Body)
+	if err != nil {
+		return nil, err
+	}
+	boundary := randomHex(12)
+	domain := "gllm.local"
+	if at := strings.LastIndex(msg.From, "@"); at >= 0 {
+		domain = strings.Trim(msg.From[at+1:], "> ")
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", msg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", randomHex(16), domain)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", boundary)
+
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain", msg.Body},
+		{"text/html", html},
+	} {
+		fmt.Fprintf(&b, "--%s\r\n", boundary)
+		fmt.Fprintf(&b, "Content-Type: %s; charset=utf-8\r\n", part.contentType)
+		b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		encoded := base64.StdEncoding.EncodeToString([]byte(part.content))
+		for len(encoded) > 76 {
+			b.WriteString(encoded[:76] + "\r\n")
+			encoded = encoded[76:]
+		}
+		b.WriteString(encoded + "\r\n")
+	}
+	fmt.Fprintf(&b, "--%s--\r\n", boundary)
+	return b.Bytes(), nil
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// ParseMailAddresses reads a comma separated list of addresses.
+func ParseMailAddresses(list string) ([]string, error) {
+	addrs, err := mail.ParseAddressList(list)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address list '%s': %w", list, err)
+	}
+	out := make([]string, 0, len(addrs))
+	for _, a := range addrs {
+		out = append(out, a.Address)
+	}
+	return out, nil
+}
+
+// SendMail sends the message over SMTP, or through sendmail when no SMTP host is configured.
+func SendMail(ctx context.Context, settings data.MailSettings, msg MailMessage) error {
+	if len(msg.To) == 0 {
+		return fmt.Errorf("no recipients")
+	}
+	if msg.From == "" {
+		msg.From = settings.From
+	}
+	if msg.From == "" {
+		msg.From = settings.Username
+	}
+	if msg.From == "" && settings.Host != "" {
+		return fmt.Errorf("no sender, set it with 'gllm config set mail.from you@example.com'")
+	}
+	raw, err := BuildMailMessage(msg, time.Now())
+	if err != nil {
+		return err
+	}
+	if settings.Host == "" {
+		return sendViaSendmail(ctx, settings.Sendmail, raw)
+	}
+
+	password, err := data.GetSecret(data.SecretSMTPPassword)
+	if err != nil {
+		return err
+	}
+	return sendViaSMTP(ctx, settings, password, msg.From, msg.To, raw)
+}
+
+func sendViaSendmail(ctx context.Context, path string, raw []byte) error {
+	if path == "" {
+		found, err := exec.LookPath("sendmail")
+		if err != nil {
+			return fmt.Errorf("no SMTP host and no sendmail found, set one with 'gllm config set mail.host smtp.example.com'")
+		}
+		path = found
+	}
+	// -t takes the recipients from the headers, -i keeps lines of a single dot
+	cmd := exec.CommandContext(ctx, path, "-t", "-i")
+	cmd.Stdin = bytes.NewReader(raw)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("sendmail failed: %v %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func sendViaSMTP(ctx context.Context, settings data.MailSettings, password, from string, to []string, raw []byte) error {
+	port := settings.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	addr := net.JoinHostPort(settings.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: settings.Host}
+
+	dialer := &net.Dialer{Timeout: sinkRequestTimeout}
+	var conn net.Conn
+	var err error
+	if port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(2 * sinkRequestTimeout))
+	}
+
+	client, err := smtp.NewClient(conn, settings.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok && port != 465 {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	if settings.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", settings.Username, password, settings.Host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// emailSink mails every post as one message, emails need no chunking.
+type emailSink struct {
+	spec SinkSpec
+	to   []string
+}
+
+func (e *emailSink) String() string {
+	return e.spec.String()
+}
+
+func (e *emailSink) Post(ctx context.Context, title, text string) error {
+	settings := data.GetSettingsStore().GetMailSettings()
+	subject, err := RenderMailSubject(settings.Subject, title, text, time.Now())
+	if err != nil {
+		return err
+	}
+	return SendMail(ctx, settings, MailMessage{To: e.to, Subject: subject, Body: text})
+}
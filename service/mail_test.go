@@ -0,0 +1,93 @@
+package service
+
+import (
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMailSubject(t *testing.T) {
+	now := time.Date(2026, 3, 4, 7, 30, 0, 0, time.UTC)
+	got, err := RenderMailSubject("", "/digest", "", now)
+	if err != nil || got != "/digest - 2026-03-04" {
+		t.Errorf("default subject = %q, %v", got, err)
+	}
+	got, err = RenderMailSubject("{{.Heading}} at {{.Time}}", "digest", "intro\n## AI news\n\ntext", now)
+	if err != nil || got != "AI news at 07:30" {
+		t.Errorf("heading subject = %q, %v", got, err)
+	}
+	got, _ = RenderMailSubject("{{.Heading}}", "digest", "no heading", now)
+	if got != "digest" {
+		t.Errorf("heading falls back to the title, got %q", got)
+	}
+	if _, err := RenderMailSubject("{{.Author}}", "digest", "", now); err == nil {
+		t.Error("expected an unknown field error")
+	}
+}
+
+func TestBuildMailMessage(t *testing.T) {
+	raw, err := BuildMailMessage(MailMessage{
+		From:    "bot@example.com",
+		To:      []string{"a@example.com", "b@example.com"},
+		Subject: "Résumé du jour",
+		Body:    "# Digest\n\n- **one**\n- two\n",
+	}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "Résumé du jour" || msg.Header.Get("To") != "a@example.com, b@example.com" {
+		t.Errorf("headers = %v", msg.Header)
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("Message-ID = %q", msg.Header.Get("Message-ID"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v", mediaType, err)
+	}
+	parts := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			break
+		}
+		contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		encoded, _ := io.ReadAll(part)
+		decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(encoded), "\r\n", ""))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[contentType] = string(decoded)
+	}
+	if !strings.HasPrefix(parts["text/plain"], "# Digest") {
+		t.Errorf("plain part = %q", parts["text/plain"])
+	}
+	if !strings.Contains(parts["text/html"], "<h1>Digest</h1>") || !strings.Contains(parts["text/html"], "<strong>one</strong>") {
+		t.Errorf("html part = %q", parts["text/html"])
+	}
+}
+
+func TestParseEmailSinkSpec(t *testing.T) {
+	got, err := ParseSinkSpec("email:me@example.com, Team <team@example.com>")
+	if err != nil || got.Service != SinkEmail {
+		t.Fatalf("ParseSinkSpec(email) = %+v, %v", got, err)
+	}
+	if _, err := ParseSinkSpec("email"); err == nil {
+		t.Error("expected an error without addresses")
+	}
+	if _, err := ParseSinkSpec("email:not an address"); err == nil {
+		t.Error("expected an invalid address error")
+	}
+}
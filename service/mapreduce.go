@@ -0,0 +1,69 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// mapDefaultShards is the number of sub-agent tasks of map_over_files when not given.
+	mapDefaultShards = 4
+	// mapMaxShards caps the sub-agent tasks of map_over_files.
+	mapMaxShards = 16
+	// mapReduceResultMaxRunes caps the reduced result returned inline by map_over_files.
+	mapReduceResultMaxRunes = 8000
+)
+
+// shardFiles splits the files into at most n shards of consecutive files of about the same
+// count, so that files of a directory tend to go together.
+func shardFiles(files []AttachedFile, n int) [][]AttachedFile {
+	if n < 1 {
+		n = 1
+	}
+	if n > len(files) {
+		n = len(files)
+	}
+	var shards [][]AttachedFile
+	start := 0
+	for i := 0; i < n; i++ {
+		// Spread the remainder over the first shards
+		end := start + len(files)/n
+		if i < len(files)%n {
+			end++
+		}
+		shards = append(shards, files[start:end])
+		start = end
+	}
+	return shards
+}
+
+// mapShardInstruction is the prompt of a map task: the files of its shard as a hidden
+// context block, then the per-file instruction.
+func mapShardInstruction(instruction string, set *AttachmentSet, shard []AttachedFile) string {
+	var files strings.Builder
+	for _, f := range shard {
+		content := set.content[f.Path]
+		fmt.Fprintf(&files, "<file path=%q>\n%s", filepath.ToSlash(f.Path), content)
+		if !bytes.HasSuffix(content, []byte("\n")) {
+			files.WriteString("\n")
+		}
+		files.WriteString("</file>\n")
+	}
+
+	var paths []string
+	for _, f := range shard {
+		paths = append(paths, "- "+filepath.ToSlash(f.Path))
+	}
+	return BuildInlineContextBlock([]string{files.String()}) +
+		fmt.Sprintf("Apply this instruction to each of the %d files given above, one file at a time:\n\n%s\n\n"+
+			"Files:\n%s\n\nGive the result of each file under a heading with its path, like '## %s'.",
+			len(shard), instruction, strings.Join(paths, "\n"), filepath.ToSlash(shard[0].Path))
+}
+
+// mapReduceInstruction is the prompt of the reduce task, which gets the results of the map
+// tasks as input keys.
+func mapReduceInstruction(instruction, pattern string, files int) string {
+	return fmt.Sprintf("%s\n\nYour input is the results of the map tasks over the %d files of %s, each under a heading with the path of its file.", instruction, files, pattern)
+}
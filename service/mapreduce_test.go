@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+	"github.com/spf13/viper"
+)
+
+func TestShardFiles(t *testing.T) {
+	var files []AttachedFile
+	for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+		files = append(files, AttachedFile{Path: name})
+	}
+	shards := shardFiles(files, 3)
+	var sizes []int
+	for _, s := range shards {
+		sizes = append(sizes, len(s))
+	}
+	if len(shards) != 3 || sizes[0] != 3 || sizes[1] != 2 || sizes[2] != 2 || shards[2][1].Path != "g" {
+		t.Errorf("unexpected shards %v", shards)
+	}
+	if shards := shardFiles(files[:2], 4); len(shards) != 2 {
+		t.Errorf("no shard may be empty, got %v", shards)
+	}
+}
+
+func TestMapOverFiles(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("HOME", configDir)
+	work := t.TempDir()
+	t.Chdir(work)
+	t.Cleanup(viper.Reset)
+
+	viper.Reset()
+	viper.Set("models.mock-mapper", map[string]interface{}{
+		"provider": ModelProviderMock,
+		"model":    "mock-mapper",
+	})
+	if err := data.EnsureAgentsDir(); err != nil {
+		t.Fatal(err)
+	}
+	agentFile := "---\nname: mapper\nmodel: mock-mapper\nmax_recursions: 3\n---\nYou are a worker.\n"
+	if err := os.WriteFile(filepath.Join(data.GetAgentsDirPath(), "mapper.md"), []byte(agentFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(work, "pkg"), 0755)
+	os.WriteFile(filepath.Join(work, "pkg", "a.go"), []byte("package pkg // a"), 0644)
+	os.WriteFile(filepath.Join(work, "pkg", "b.go"), []byte("package pkg // b"), 0644)
+	os.WriteFile(filepath.Join(work, "pkg", "notes.txt"), []byte("not go"), 0644)
+
+	// The map task and its compression, then the reduce task and its compression
+	RegisterMockScript("mock-mapper", &MockScript{Turns: []MockTurn{
+		{Text: "## pkg/a.go\nfile a\n## pkg/b.go\nfile b"},
+		{Text: "a and b summarized"},
+		{Text: "the package has two files"},
+		{Text: "overview of the package"},
+	}})
+
+	state := data.NewSharedState()
+	executor := NewSubAgentExecutor(state, "map_main", nil, nil, nil)
+	defer executor.Shutdown()
+	op := &OpenProcessor{agentName: "orchestrator", executor: executor, sharedState: state, toolsUse: &data.ToolsUse{AutoApprove: true}}
+
+	args := map[string]interface{}{
+		"pattern":            "pkg/*.go",
+		"instruction":        "Summarize the file.",
+		"agent_name":         "mapper",
+		"task_key":           "sums",
+		"shards":             1,
+		"reduce_instruction": "Write an overview.",
+	}
+	out, err := mapOverFilesToolCallImpl(context.Background(), &args, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Mapped 2 files of pkg/*.go in 1 task(s).", "mapper_sums_map1", "## Reduced result\noverview of the package"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("the result misses %q:\n%s", want, out)
+		}
+	}
+	if val, ok := state.Get("mapper_sums_reduce"); !ok || val != "overview of the package" {
+		t.Errorf("expected the reduced summary in shared state, got %v", val)
+	}
+
+	// The map task got the files and the per-file instruction
+	session, err := ReadSessionContent("map_main::mapper_sums_map1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"package pkg // a", "package pkg // b", "Summarize the file.", "## pkg/a.go"} {
+		if !strings.Contains(string(session), want) {
+			t.Errorf("the map task misses %q", want)
+		}
+	}
+	if strings.Contains(string(session), "not go") {
+		t.Error("the map task got a file the pattern doesn't match")
+	}
+}
+
+func TestMapOverFilesPathRules(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.MkdirAll("pkg", 0755)
+	os.WriteFile(filepath.Join("pkg", "secret.go"), []byte("package pkg // secret"), 0644)
+	rules, err := NewPathRules(data.AgentPaths{Deny: []string{"pkg/secret.go"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := data.NewSharedState()
+	executor := NewSubAgentExecutor(state, "map_rules", nil, nil, nil)
+	defer executor.Shutdown()
+	op := &OpenProcessor{agentName: "orchestrator", executor: executor, sharedState: state, pathRules: rules, toolsUse: &data.ToolsUse{AutoApprove: true}}
+
+	args := map[string]interface{}{"pattern": "pkg/*.go", "instruction": "Summarize the file.", "agent_name": "mapper", "task_key": "sums"}
+	out, err := mapOverFilesToolCallImpl(context.Background(), &args, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "No text files to map over") || !strings.Contains(out, "is denied to this agent") {
+		t.Errorf("expected the denied file to be left out, got:\n%s", out)
+	}
+}
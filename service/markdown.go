@@ -4,11 +4,14 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/activebook/gllm/data"
 	"github.com/activebook/gllm/io"
 	"github.com/activebook/gllm/util"
 	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
 )
 
 // removeCitations removes citation references from text, including:
@@ -41,8 +44,33 @@ func removeCitations(text string) string {
 	return text
 }
 
+const (
+	markdownFrameInterval = 50 * time.Millisecond // The active block is redrawn at most 20 times a second
+	markdownPlainAfter    = 256 * 1024            // Longer responses stream on as plain text
+)
+
+/*
+Markdown collects the response and renders it. By default the response streams as plain text
+and is rendered once it is complete. On a terminal it is rendered while it streams, block by
+block: a paragraph, heading or code block is rendered once it is complete and never touched
+again, only the active block is redrawn, at most once per frame. Text wraps at the width of the
+terminal, checked on every frame so a resize applies to the next block; code is never wrapped
+and tables truncate their cells with … rather than overflow. Anything else written to the
+terminal must call Settle first, so that it lands below the rendered text.
+*/
 type Markdown struct {
 	buffer strings.Builder
+
+	mu        sync.Mutex
+	out       io.Output // Set when rendering while streaming
+	renderer  *glamour.TermRenderer
+	width     int    // Terminal width the renderer wraps at
+	pending   string // Text of the active block
+	live      string // The drawn active block
+	lastFrame time.Time
+	timer     *time.Timer
+	printed   bool // A block has been printed, the next one needs a blank line
+	plain     bool // Past markdownPlainAfter, the rest streams as it comes
 }
 
 // NewMarkdown creates a new instance of Markdown
@@ -52,6 +80,35 @@ func NewMarkdown() *Markdown {
 	return &mr
 }
 
+// NewStreamingMarkdown creates a Markdown that renders to the terminal while the response streams.
+func NewStreamingMarkdown(out io.Output) *Markdown {
+	return &Markdown{out: out}
+}
+
+// Streaming reports whether the response is rendered while it streams, it is not written raw then.
+func (mr *Markdown) Streaming() bool {
+	return mr.out != nil
+}
+
+// newGlamourRenderer creates a renderer that wraps text at the width, cells of tables that do
+// not fit are truncated.
+func newGlamourRenderer(width int) *glamour.TermRenderer {
+	// The configured markdown theme, by default the glamour built-in style whose palette
+	// most closely matches the active theme (dark/light family + hue-distance against
+	// style fingerprints), so we preserve glamour's hand-crafted vibrant colours and rich
+	// Chroma syntax highlighting, rather than a flat programmatic approximation.
+	tr, err := glamour.NewTermRenderer(
+		data.MarkdownStyleOption(),
+		glamour.WithWordWrap(width),
+		glamour.WithTableWrap(false),
+	)
+	if err != nil {
+		// Graceful fallback to auto-style if matching unexpectedly fails.
+		tr, _ = glamour.NewTermRenderer(glamour.WithAutoStyle(), glamour.WithWordWrap(width), glamour.WithTableWrap(false))
+	}
+	return tr
+}
+
 // Start streaming mode (call this before any RenderString)
 // func (mr *MarkdownRenderer) StartStreaming() {
 // 	if mr.keepMarkdownOnly {
@@ -70,11 +127,243 @@ func NewMarkdown() *Markdown {
 func (mr *Markdown) Writef(format string, args ...interface{}) {
 	output := fmt.Sprintf(format, args...)
 	mr.buffer.WriteString(output) // Write to the buffer
+	mr.stream(output)
 }
 
 func (mr *Markdown) Write(args ...interface{}) {
 	output := fmt.Sprint(args...)
 	mr.buffer.WriteString(output) // Write to the buffer
+	mr.stream(output)
+}
+
+// stream renders the blocks the text completes and schedules a frame of the active block.
+func (mr *Markdown) stream(text string) {
+	if mr.out == nil {
+		return
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if mr.plain {
+		mr.out.Write(text)
+		return
+	}
+	mr.pending += text
+	if mr.buffer.Len() > markdownPlainAfter {
+		// Rendering stays cheap on very long responses by stopping it
+		mr.settleLocked()
+		mr.plain = true
+		return
+	}
+
+	blocks, rest := splitMarkdownBlocks(mr.pending)
+	for _, block := range blocks {
+		mr.commitLocked(block)
+	}
+	mr.pending = rest
+	if strings.TrimSpace(rest) == "" {
+		return
+	}
+	if wait := markdownFrameInterval - time.Since(mr.lastFrame); wait > 0 {
+		if mr.timer == nil {
+			mr.timer = time.AfterFunc(wait, func() {
+				mr.mu.Lock()
+				defer mr.mu.Unlock()
+				mr.timer = nil
+				mr.drawLocked()
+			})
+		}
+		return
+	}
+	mr.drawLocked()
+}
+
+// Settle renders the active block as it is, anything written after it starts below.
+func (mr *Markdown) Settle() {
+	if mr.out == nil {
+		return
+	}
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	mr.settleLocked()
+}
+
+func (mr *Markdown) settleLocked() {
+	if mr.timer != nil {
+		mr.timer.Stop()
+		mr.timer = nil
+	}
+	if strings.TrimSpace(mr.pending) != "" {
+		mr.commitLocked(mr.pending)
+	}
+	mr.pending = ""
+}
+
+// commitLocked replaces the drawn active block with the final rendering of a block.
+func (mr *Markdown) commitLocked(block string) {
+	mr.eraseLocked()
+	mr.fitWidthLocked()
+	if out := mr.renderBlock(block); out != "" {
+		mr.out.Write(out)
+		mr.printed = true
+	}
+}
+
+// fitWidthLocked follows the width of the terminal, the renderer is replaced when it changes.
+func (mr *Markdown) fitWidthLocked() int {
+	width := io.GetTerminalWidth()
+	if mr.renderer == nil || width != mr.width {
+		mr.renderer = newGlamourRenderer(width)
+		mr.width = width
+	}
+	return width
+}
+
+// drawLocked redraws the active block. A block taller than the terminal cannot be erased, its
+// complete lines are committed first.
+func (mr *Markdown) drawLocked() {
+	if strings.TrimSpace(mr.pending) == "" {
+		return
+	}
+	width, height := mr.fitWidthLocked(), io.GetTerminalHeight()
+	out := mr.renderBlock(mr.pending)
+	if rows := terminalRows(out, width); rows >= height-1 {
+		if head, rest, ok := splitTallMarkdownBlock(mr.pending); ok {
+			mr.commitLocked(head)
+			mr.pending = rest
+			out = mr.renderBlock(rest)
+		}
+	}
+	mr.eraseLocked()
+	mr.out.Write(out)
+	mr.live = out
+	mr.lastFrame = time.Now()
+}
+
+// eraseLocked removes the drawn active block, counting its rows at the current width as the
+// terminal has rewrapped it after a resize.
+func (mr *Markdown) eraseLocked() {
+	if mr.live != "" {
+		mr.out.Write(fmt.Sprintf("\033[%dA\r\033[J", terminalRows(mr.live, io.GetTerminalWidth())))
+		mr.live = ""
+	}
+}
+
+// renderBlock renders a block with a blank line before it when it follows another one.
+func (mr *Markdown) renderBlock(block string) string {
+	out, err := mr.renderer.Render(block)
+	if err != nil {
+		out = block
+	}
+	out = strings.Trim(out, "\n")
+	if strings.TrimSpace(out) == "" {
+		return ""
+	}
+	if mr.printed {
+		out = "\n" + out
+	}
+	return out + "\n"
+}
+
+// terminalRows counts the rows text takes on a terminal of the width, long lines wrap.
+func terminalRows(text string, width int) int {
+	rows := 0
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		w := lipgloss.Width(line)
+		rows += max(1, (w+width-1)/width)
+	}
+	return rows
+}
+
+// splitMarkdownBlocks cuts the complete blocks off the text: paragraphs ended by a blank line,
+// headings, and fenced code blocks once their fence is closed. The rest is the active block.
+func splitMarkdownBlocks(text string) ([]string, string) {
+	var blocks []string
+	start, pos := 0, 0
+	fence := ""
+	for {
+		nl := strings.IndexByte(text[pos:], '\n')
+		if nl < 0 {
+			break
+		}
+		end := pos + nl + 1
+		line := strings.TrimSpace(text[pos:end])
+		switch {
+		case fence != "":
+			if isClosingFence(line, fence) {
+				fence = ""
+				blocks = append(blocks, text[start:end])
+				start = end
+			}
+		case openingFence(line) != "":
+			// A code block is a block of its own
+			if strings.TrimSpace(text[start:pos]) != "" {
+				blocks = append(blocks, text[start:pos])
+			}
+			start = pos
+			fence = openingFence(line)
+		case line == "":
+			if strings.TrimSpace(text[start:end]) != "" {
+				blocks = append(blocks, text[start:end])
+			}
+			start = end
+		case strings.HasPrefix(line, "#"):
+			if strings.TrimSpace(text[start:pos]) != "" {
+				blocks = append(blocks, text[start:pos])
+			}
+			blocks = append(blocks, text[pos:end])
+			start = end
+		}
+		pos = end
+	}
+	return blocks, text[start:]
+}
+
+// splitTallMarkdownBlock cuts the complete lines off an active block, a code block is closed
+// and opened again after them, a table goes on under its header again.
+func splitTallMarkdownBlock(block string) (string, string, bool) {
+	last := strings.LastIndexByte(block, '\n')
+	first := strings.IndexByte(block, '\n')
+	if last < 0 || last == first {
+		return "", "", false
+	}
+	head, rest := block[:last+1], block[last+1:]
+	if opener := strings.TrimSpace(block[:first]); openingFence(opener) != "" {
+		head += openingFence(opener) + "\n"
+		rest = opener + "\n" + rest
+	} else if header := tableHeader(block); header != "" {
+		if len(head) <= len(header) {
+			return "", "", false
+		}
+		rest = header + rest
+	}
+	return head, rest, true
+}
+
+// tableDelimiter matches the row under the header of a table, e.g. |:---|---:|
+var tableDelimiter = regexp.MustCompile(`^\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?$`)
+
+// tableHeader returns the header and delimiter rows of a block that is a table, or "".
+func tableHeader(block string) string {
+	lines := strings.SplitAfterN(block, "\n", 3)
+	if len(lines) < 3 || !strings.Contains(lines[0], "|") || !tableDelimiter.MatchString(strings.TrimSpace(lines[1])) {
+		return ""
+	}
+	return lines[0] + lines[1]
+}
+
+// openingFence returns the fence a line opens, e.g. ``` of ```go, or "".
+func openingFence(line string) string {
+	for _, c := range []string{"`", "~"} {
+		if strings.HasPrefix(line, c+c+c) {
+			n := len(line) - len(strings.TrimLeft(line, c))
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+func isClosingFence(line, fence string) bool {
+	return len(line) >= len(fence) && strings.Trim(line, fence[:1]) == ""
 }
 
 // RenderMarkdown clears the streaming output and re-renders the entire Markdown
@@ -91,6 +380,19 @@ func (mr *Markdown) Render(r io.Output) {
 		return
 	}
 
+	if mr.out != nil {
+		// The blocks are on the terminal already
+		mr.Settle()
+		data.SaveClipboardText(output)
+		r.Writeln("")
+		r.Writeln(data.TaskCompleteColor + "✓ Task Completed" + data.ResetSeq)
+		mr.mu.Lock()
+		mr.buffer.Reset()
+		mr.printed, mr.plain = false, false
+		mr.mu.Unlock()
+		return
+	}
+
 	// Remove citations
 	// Only gemini has citations
 	//output = removeCitations(output)
@@ -102,19 +404,7 @@ func (mr *Markdown) Render(r io.Output) {
 	r.Writeln("")
 	r.Writeln(data.TaskCompleteColor + "✓ Task Completed" + data.ResetSeq)
 
-	// Pick the glamour built-in style whose palette most closely matches the
-	// active theme (dark/light family + hue-distance against style fingerprints)
-	// so we preserve glamour's hand-crafted vibrant colours and rich Chroma
-	// syntax highlighting, rather than a flat programmatic approximation.
-	glamourStyle := data.MostSimilarGlamourStyle()
-	tr, err := glamour.NewTermRenderer(
-		glamour.WithStandardStyle(glamourStyle),
-	)
-	if err != nil {
-		// Graceful fallback to auto-style if matching unexpectedly fails.
-		tr, _ = glamour.NewTermRenderer(glamour.WithAutoStyle())
-	}
-
+	tr := newGlamourRenderer(io.GetTerminalWidth())
 	out, err2 := tr.Render(output)
 	if err2 != nil {
 		util.LogWarnf("Cannot render Markdown correctly: %v\n", err2)
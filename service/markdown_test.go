@@ -0,0 +1,116 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type recordOutput struct {
+	writes []string
+}
+
+func (r *recordOutput) Writeln(args ...interface{}) {
+	r.writes = append(r.writes, fmt.Sprintln(args...))
+}
+func (r *recordOutput) Writef(format string, args ...interface{}) {
+	r.writes = append(r.writes, fmt.Sprintf(format, args...))
+}
+func (r *recordOutput) Write(args ...interface{}) { r.writes = append(r.writes, fmt.Sprint(args...)) }
+func (r *recordOutput) Close()                    {}
+
+func TestSplitMarkdownBlocks(t *testing.T) {
+	cases := []struct {
+		text   string
+		blocks []string
+		rest   string
+	}{
+		{"one\ntwo\n\nthree", []string{"one\ntwo\n\n"}, "three"},
+		{"intro\n# Title\nbody\n", []string{"intro\n", "# Title\n"}, "body\n"},
+		{"text\n```go\nx := 1\n\ny := 2\n```\nafter", []string{"text\n", "```go\nx := 1\n\ny := 2\n```\n"}, "after"},
+		{"````\n```\nstill code\n````\n", []string{"````\n```\nstill code\n````\n"}, ""},
+		{"~~~\n# not a heading\n", nil, "~~~\n# not a heading\n"},
+		{"\n\nfirst\n\n", []string{"first\n\n"}, ""},
+	}
+	for _, c := range cases {
+		blocks, rest := splitMarkdownBlocks(c.text)
+		if fmt.Sprintf("%q", blocks) != fmt.Sprintf("%q", c.blocks) || rest != c.rest {
+			t.Errorf("splitMarkdownBlocks(%q) = %q, %q; want %q, %q", c.text, blocks, rest, c.blocks, c.rest)
+		}
+	}
+}
+
+func TestSplitTallMarkdownBlock(t *testing.T) {
+	head, rest, ok := splitTallMarkdownBlock("```go\na\nb\nc")
+	if !ok || head != "```go\na\nb\n```\n" || rest != "```go\nc" {
+		t.Errorf("unexpected split: %q, %q", head, rest)
+	}
+	if _, _, ok := splitTallMarkdownBlock("one line\nhalf"); ok {
+		t.Error("expected a block of one complete line not to split")
+	}
+
+	head, rest, ok = splitTallMarkdownBlock("| a | b |\n|:--|--:|\n| 1 | 2 |\n| 3")
+	if !ok || head != "| a | b |\n|:--|--:|\n| 1 | 2 |\n" || rest != "| a | b |\n|:--|--:|\n| 3" {
+		t.Errorf("expected the table to go on under its header, got %q, %q", head, rest)
+	}
+	if _, _, ok := splitTallMarkdownBlock("| a | b |\n|---|---|\n| 1"); ok {
+		t.Error("expected a table without a complete row not to split")
+	}
+}
+
+func TestRenderTableFitsWidth(t *testing.T) {
+	table := "| Name | Description |\n|:-----|------------:|\n| gllm | " + strings.Repeat("a very long description ", 10) + "|\n"
+	out, err := newGlamourRenderer(40).Render(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "…") {
+		t.Errorf("expected the long cell truncated, got:\n%s", out)
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if w := lipgloss.Width(line); w > 40 {
+			t.Errorf("expected lines within 40 columns, got %d: %q", w, line)
+		}
+	}
+}
+
+func TestTerminalRows(t *testing.T) {
+	if rows := terminalRows("short\n"+strings.Repeat("x", 25)+"\n\n", 10); rows != 5 {
+		t.Errorf("expected 5 rows, got %d", rows)
+	}
+}
+
+func TestStreamingMarkdown(t *testing.T) {
+	out := &recordOutput{}
+	mr := NewStreamingMarkdown(out)
+	mr.Write("# Title\n\nSome **bold")
+	mr.Write(" text** here.\n\n")
+	mr.Write("```go\nfmt.Println(1)\n```\n")
+	mr.Write("last words")
+	mr.Settle()
+
+	all := strings.Join(out.writes, "")
+	for _, want := range []string{"Title", "bold text", "fmt.Println(1)", "last words"} {
+		if !strings.Contains(all, want) {
+			t.Errorf("expected %q in the rendering:\n%s", want, all)
+		}
+	}
+	if strings.Contains(all, "```") {
+		t.Errorf("expected the code block rendered, got:\n%s", all)
+	}
+	if strings.Count(all, "Title") != 1 || !strings.Contains(all, "\033[2A\r\033[J") {
+		t.Errorf("expected only the active paragraph redrawn, got:\n%q", all)
+	}
+	if mr.pending != "" || mr.timer != nil {
+		t.Errorf("expected Settle to flush the active block, got %q", mr.pending)
+	}
+
+	out.writes = nil
+	mr.Write(strings.Repeat("word ", markdownPlainAfter/5))
+	mr.Write("**raw**")
+	if !mr.plain || out.writes[len(out.writes)-1] != "**raw**" {
+		t.Errorf("expected plain text past the limit, got %q", out.writes[len(out.writes)-1])
+	}
+}
@@ -79,15 +79,25 @@ type MCPClient struct {
 	servers       []*MCPServer
 	connected     map[string]bool
 	toolToSession map[string]*MCPSession
-	loaded        bool // Whether MCP is loaded already
+	configs       map[string]*data.MCPServer // Configuration of the loaded servers, to connect or reconnect them
+	lazy          map[string]bool            // Servers whose tools are loaded but that are not connected
+	loaded        bool                       // Whether MCP is loaded already
+
+	// Reconnectable connects again to a server whose connection was lost, for processes
+	// that keep the client for long, like the daemon.
+	Reconnectable bool
 }
 type MCPLoadOption struct {
 	LoadAll       bool // load all tools(allowed|blocked)
 	LoadTools     bool // load tools (tools/list)
 	LoadResources bool // load resources (resources/list)
 	LoadPrompts   bool // load prompts (prompts/list)
+	Lazy          bool // load tools from the schema cache, connecting on the first call
 }
 
+// mcpConnectTimeout bounds connecting to a server and listing its tools.
+const mcpConnectTimeout = 30 * time.Second
+
 /*
 A singleton pattern for the MCP client is an excellent approach.
 Since MCP functionality is independent of the LLM model,
@@ -170,14 +180,21 @@ func (mc *MCPClient) Init(servers map[string]*data.MCPServer, option MCPLoadOpti
 		mc.toolToSession = make(map[string]*MCPSession)
 		mc.connected = make(map[string]bool)
 		mc.serverMu = make(map[string]*sync.Mutex)
-		// Create a new client, with no features.
-		mc.client = mcp.NewClient(&mcp.Implementation{Name: "mcp-client", Version: "v1.0.0"}, nil)
+		mc.configs = make(map[string]*data.MCPServer)
+		mc.lazy = make(map[string]bool)
+		// Create a new client, only listening to tool list changes.
+		mc.client = mcp.NewClient(&mcp.Implementation{Name: "mcp-client", Version: "v1.0.0"}, &mcp.ClientOptions{
+			ToolListChangedHandler: mc.onToolListChanged,
+		})
 	}
 
-	initCtx, cancelInit := context.WithTimeout(mc.ctx, 30*time.Second)
+	initCtx, cancelInit := context.WithTimeout(mc.ctx, mcpConnectTimeout)
 	mc.mu.Unlock()
 	defer cancelInit()
 
+	// Cached schemas are enough to offer the tools, but not the resources and prompts
+	lazy := option.Lazy && option.LoadTools && !option.LoadResources && !option.LoadPrompts
+
 	var err error
 	// Connect to each server based on its type
 	for serverName, server := range servers {
@@ -189,12 +206,7 @@ func (mc *MCPClient) Init(servers map[string]*data.MCPServer, option MCPLoadOpti
 		// Retrieve or create a per-server mutex under the global lock.
 		// This prevents concurrent Init calls from spawning duplicate connections
 		// to the same server (e.g. background autoload + user /mcp load racing).
-		mc.mu.Lock()
-		if mc.serverMu[serverName] == nil {
-			mc.serverMu[serverName] = &sync.Mutex{}
-		}
-		srvMu := mc.serverMu[serverName]
-		mc.mu.Unlock()
+		srvMu := mc.serverLock(serverName)
 
 		// Acquire the per-server lock BEFORE checking isConnected.
 		// Any concurrent goroutine attempting the same server will block here
@@ -210,23 +222,22 @@ func (mc *MCPClient) Init(servers map[string]*data.MCPServer, option MCPLoadOpti
 			continue // Already connected, skip
 		}
 
-		// Connect and add session
-		var session *MCPSession
-		if server.Type == "sse" || server.URL != "" || server.BaseURL != "" {
-			// Add SSE server
-			session, err = mc.AddSseServer(initCtx, serverName, server.BaseURL, server.Headers)
-		} else if server.Type == "stdio" || server.Type == "std" || server.Type == "local" || server.Command != "" {
-			// Add stdio server
-			dir := server.WorkDir
-			if dir == "" {
-				dir = server.Cwd
+		// Offer the cached tools and connect on the first call
+		if lazy {
+			if cached := readMCPToolCache(serverName, server); cached != nil {
+				mc.mu.Lock()
+				mc.configs[serverName] = server
+				mc.lazy[serverName] = true
+				mc.registerServer(serverName, server.Allowed, &MCPSession{name: serverName}, cached.Tools, nil, nil)
+				mc.mu.Unlock()
+				srvMu.Unlock()
+				continue
 			}
-			session, err = mc.AddStdServer(initCtx, serverName, server.Command, server.Env, dir, server.Args...)
-		} else if server.Type == "http" || server.HTTPUrl != "" {
-			// Add HTTP server
-			session, err = mc.AddHttpServer(initCtx, serverName, server.HTTPUrl, server.Headers)
 		}
 
+		// Connect and add session
+		var session *MCPSession
+		session, err = mc.connectServer(initCtx, serverName, server)
 		if err != nil {
 			// don't continue with other servers
 			err = fmt.Errorf("error loading mcp server %s: %w", serverName, err)
@@ -242,6 +253,7 @@ func (mc *MCPClient) Init(servers map[string]*data.MCPServer, option MCPLoadOpti
 				srvMu.Unlock()
 				break
 			}
+			writeMCPToolCache(serverName, server, *tools)
 		}
 		var resources *[]MCPResource
 		if option.LoadResources {
@@ -253,35 +265,12 @@ func (mc *MCPClient) Init(servers map[string]*data.MCPServer, option MCPLoadOpti
 		}
 
 		mc.mu.Lock()
-
-		// Populate tool to session map for fast lookup
-		// Bugfix: remember we load servers in parallel (/mcp load and autoload in background),
-		// so we need to check for duplicates when multiple servers have the same tool name
-		// or, the same server is loaded multiple times
-		var filteredTools []MCPTool
+		mc.configs[serverName] = server
+		var toolList []MCPTool
 		if tools != nil {
-			for _, tool := range *tools {
-				// Prevent shadowing built-in system tools
-				if IsAvailableOpenTool(tool.Name) {
-					util.LogWarnf("MCP tool %q from server %q conflicts with built-in tool, ignored\n", tool.Name, serverName)
-					continue
-				}
-
-				// Prevent duplicates across different MCP servers
-				if _, exists := mc.toolToSession[tool.Name]; exists {
-					util.LogWarnf("Duplicate MCP tool ignored: %q (from server %q)\n", tool.Name, serverName)
-					continue
-				}
-
-				mc.toolToSession[tool.Name] = session
-				filteredTools = append(filteredTools, tool)
-			}
+			toolList = *tools
 		}
-
-		mc.servers = append(mc.servers, &MCPServer{
-			Name: serverName, Allowed: server.Allowed,
-			Tools: &filteredTools, Prompts: prompts, Resources: resources})
-		mc.connected[serverName] = true
+		mc.registerServer(serverName, server.Allowed, session, toolList, resources, prompts)
 		mc.mu.Unlock()
 		srvMu.Unlock()
 	}
@@ -291,6 +280,172 @@ func (mc *MCPClient) Init(servers map[string]*data.MCPServer, option MCPLoadOpti
 	return err
 }
 
+// serverLock returns the lock serializing the connection of a server.
+func (mc *MCPClient) serverLock(serverName string) *sync.Mutex {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	if mc.serverMu[serverName] == nil {
+		mc.serverMu[serverName] = &sync.Mutex{}
+	}
+	return mc.serverMu[serverName]
+}
+
+// connectServer connects to a server with the transport of its configuration.
+func (mc *MCPClient) connectServer(ctx context.Context, serverName string, server *data.MCPServer) (*MCPSession, error) {
+	if server.Type == "sse" || server.URL != "" || server.BaseURL != "" {
+		// Add SSE server
+		return mc.AddSseServer(ctx, serverName, server.BaseURL, server.Headers)
+	} else if server.Type == "stdio" || server.Type == "std" || server.Type == "local" || server.Command != "" {
+		// Add stdio server
+		dir := server.WorkDir
+		if dir == "" {
+			dir = server.Cwd
+		}
+		return mc.AddStdServer(ctx, serverName, server.Command, server.Env, dir, server.Args...)
+	} else if server.Type == "http" || server.HTTPUrl != "" {
+		// Add HTTP server
+		return mc.AddHttpServer(ctx, serverName, server.HTTPUrl, server.Headers)
+	}
+	return nil, fmt.Errorf("unknown server type %q", server.Type)
+}
+
+// registerServer records a loaded server and maps its tools to the session. The caller
+// holds mc.mu.
+func (mc *MCPClient) registerServer(serverName string, allowed bool, session *MCPSession, tools []MCPTool, resources *[]MCPResource, prompts *[]MCPPrompt) {
+	filteredTools := mc.mapTools(serverName, session, tools)
+	mc.servers = append(mc.servers, &MCPServer{
+		Name: serverName, Allowed: allowed,
+		Tools: &filteredTools, Prompts: prompts, Resources: resources})
+	mc.connected[serverName] = true
+}
+
+// mapTools maps the tools of a server to its session, leaving out the ones that clash with
+// other tools. The caller holds mc.mu.
+func (mc *MCPClient) mapTools(serverName string, session *MCPSession, tools []MCPTool) []MCPTool {
+	// Populate tool to session map for fast lookup
+	// Bugfix: remember we load servers in parallel (/mcp load and autoload in background),
+	// so we need to check for duplicates when multiple servers have the same tool name
+	// or, the same server is loaded multiple times
+	var filteredTools []MCPTool
+	for _, tool := range tools {
+		// Prevent shadowing built-in system tools
+		if IsAvailableOpenTool(tool.Name) {
+			util.LogWarnf("MCP tool %q from server %q conflicts with built-in tool, ignored\n", tool.Name, serverName)
+			continue
+		}
+
+		// Prevent duplicates across different MCP servers
+		if _, exists := mc.toolToSession[tool.Name]; exists {
+			util.LogWarnf("Duplicate MCP tool ignored: %q (from server %q)\n", tool.Name, serverName)
+			continue
+		}
+
+		mc.toolToSession[tool.Name] = session
+		filteredTools = append(filteredTools, tool)
+	}
+	return filteredTools
+}
+
+// ensureConnected connects the server of a session whose tools were loaded from the cache,
+// or whose connection was lost, before calling one of its tools.
+func (mc *MCPClient) ensureConnected(session *MCPSession) error {
+	mc.mu.Lock()
+	lazy := mc.lazy[session.name]
+	mc.mu.Unlock()
+	if !lazy {
+		return nil
+	}
+
+	srvMu := mc.serverLock(session.name)
+	srvMu.Lock()
+	defer srvMu.Unlock()
+	mc.mu.Lock()
+	lazy = mc.lazy[session.name]
+	server := mc.configs[session.name]
+	ctx := mc.ctx
+	mc.mu.Unlock()
+	if !lazy {
+		return nil // Connected while waiting for the lock
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, mcpConnectTimeout)
+	defer cancel()
+	connected, err := mc.connectServer(connectCtx, session.name, server)
+	if err != nil {
+		return fmt.Errorf("error connecting mcp server %s: %w", session.name, err)
+	}
+	mc.mu.Lock()
+	session.cs = connected.cs
+	delete(mc.lazy, session.name)
+	mc.mu.Unlock()
+
+	// The cached schemas may be stale, check them against the server for the next turns
+	go mc.refreshTools(session)
+	return nil
+}
+
+// onToolListChanged refreshes the tools of a server that reports a change.
+func (mc *MCPClient) onToolListChanged(ctx context.Context, req *mcp.ToolListChangedRequest) {
+	mc.mu.Lock()
+	var session *MCPSession
+	for _, s := range mc.sessions {
+		if s.cs == req.Session {
+			session = s
+		}
+	}
+	// The tools may be mapped to the lazy session the connection was moved to
+	for _, s := range mc.toolToSession {
+		if s.cs == req.Session {
+			session = s
+		}
+	}
+	mc.mu.Unlock()
+	if session != nil {
+		// Don't block the connection that delivers the notification
+		go mc.refreshTools(session)
+	}
+}
+
+// refreshTools lists the tools of a connected server, updating the cache and, when they
+// changed, the tools offered to the model.
+func (mc *MCPClient) refreshTools(session *MCPSession) {
+	mc.mu.Lock()
+	server := mc.configs[session.name]
+	ctx := mc.ctx
+	mc.mu.Unlock()
+	if server == nil || ctx == nil {
+		return
+	}
+	listCtx, cancel := context.WithTimeout(ctx, mcpConnectTimeout)
+	defer cancel()
+	tools, err := mc.GetTools(listCtx, session)
+	if err != nil {
+		util.LogDebugf("Failed to refresh the tools of MCP server %s: %v\n", session.name, err)
+		return
+	}
+	if !writeMCPToolCache(session.name, server, *tools) {
+		return
+	}
+
+	util.LogDebugf("The tools of MCP server %s changed\n", session.name)
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	for name, s := range mc.toolToSession {
+		if s == session {
+			delete(mc.toolToSession, name)
+		}
+	}
+	filteredTools := mc.mapTools(session.name, session, *tools)
+	// Replace the server rather than its tools, callers may be reading the old one
+	for i, s := range mc.servers {
+		if s.Name == session.name {
+			updated := *s
+			updated.Tools = &filteredTools
+			mc.servers[i] = &updated
+		}
+	}
+}
+
 func (mc *MCPClient) Close() {
 	mc.mu.Lock()
 	defer mc.mu.Unlock()
@@ -305,6 +460,8 @@ func (mc *MCPClient) Close() {
 	mc.servers = []*MCPServer{}
 	mc.toolToSession = nil
 	mc.connected = nil
+	mc.configs = nil
+	mc.lazy = nil
 	mc.client = nil
 	mc.ctx = nil
 	mc.loaded = false
@@ -330,7 +487,7 @@ func (mc *MCPClient) AddSseServer(ctx context.Context, name string, url string,
 		return nil, err
 	}
 	// Keep track of the session
-	mcpSession := &MCPSession{name, session}
+	mcpSession := &MCPSession{name: name, cs: session}
 	mc.mu.Lock()
 	mc.sessions = append(mc.sessions, mcpSession)
 	mc.mu.Unlock()
@@ -357,7 +514,7 @@ func (mc *MCPClient) AddHttpServer(ctx context.Context, name string, url string,
 		return nil, err
 	}
 	// Keep track of the session
-	mcpSession := &MCPSession{name, session}
+	mcpSession := &MCPSession{name: name, cs: session}
 	mc.mu.Lock()
 	mc.sessions = append(mc.sessions, mcpSession)
 	mc.mu.Unlock()
@@ -394,7 +551,7 @@ func (mc *MCPClient) AddStdServer(ctx context.Context, name string, cmd string,
 		return nil, err
 	}
 	// Keep track of the session
-	mcpSession := &MCPSession{name, session}
+	mcpSession := &MCPSession{name: name, cs: session}
 	mc.mu.Lock()
 	mc.sessions = append(mc.sessions, mcpSession)
 	mc.mu.Unlock()
@@ -402,6 +559,8 @@ func (mc *MCPClient) AddStdServer(ctx context.Context, name string, cmd string,
 }
 
 func (mc *MCPClient) FindTool(toolName string) *MCPSession {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
 	return mc.toolToSession[toolName]
 }
 
@@ -418,7 +577,8 @@ type MCPToolResponse struct {
 	Contents []string
 }
 
-func (mc *MCPClient) CallTool(toolName string, args map[string]any) (*MCPToolResponse, error) {
+// CallTool calls a tool on its server. The call stops when ctx is cancelled or the client closes.
+func (mc *MCPClient) CallTool(ctx context.Context, toolName string, args map[string]any) (*MCPToolResponse, error) {
 	params := &mcp.CallToolParams{
 		Name:      toolName,
 		Arguments: args,
@@ -429,8 +589,34 @@ func (mc *MCPClient) CallTool(toolName string, args map[string]any) (*MCPToolRes
 	if session == nil {
 		return nil, fmt.Errorf("no session found for tool %s", toolName)
 	}
+	// Servers loaded from the schema cache connect on their first call
+	if err := mc.ensureConnected(session); err != nil {
+		return nil, err
+	}
+	mc.mu.Lock()
+	clientCtx := mc.ctx
+	mc.mu.Unlock()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if clientCtx != nil {
+		defer context.AfterFunc(clientCtx, cancel)()
+	}
+
 	//log.Printf("Calling tool %s on session %s", toolName, session.ID())
-	res, err := session.cs.CallTool(mc.ctx, params)
+	res, err := session.cs.CallTool(ctx, params)
+	if errors.Is(err, mcp.ErrConnectionClosed) && mc.Reconnectable {
+		// A long running process outlives its servers, connect again and retry once
+		util.LogWarnf("MCP server %s disconnected, reconnecting\n", session.name)
+		mc.mu.Lock()
+		if mc.lazy != nil {
+			mc.lazy[session.name] = true
+		}
+		mc.mu.Unlock()
+		if err := mc.ensureConnected(session); err != nil {
+			return nil, err
+		}
+		res, err = session.cs.CallTool(ctx, params)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("call tool failed: %v", err)
 	}
@@ -466,7 +652,9 @@ func (mc *MCPClient) CallTool(toolName string, args map[string]any) (*MCPToolRes
 // Returns a map grouping tools by MCP server session name,
 // with each session containing a slice of its available tools.
 func (mc *MCPClient) GetAllServers() []*MCPServer {
-	return mc.servers
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+	return append([]*MCPServer(nil), mc.servers...)
 }
 
 func (mc *MCPClient) GetTools(ctx context.Context, session *MCPSession) (*[]MCPTool, error) {
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+)
+
+// MCPToolCacheTTL is how long the cached tools of an MCP server are trusted without
+// connecting to the server.
+const MCPToolCacheTTL = 7 * 24 * time.Hour
+
+// mcpToolCacheEntry is the cached tools of an MCP server. ConfigHash invalidates the entry
+// when the server is configured differently, ToolsHash tells whether a fresh tools/list
+// changed anything.
+type mcpToolCacheEntry struct {
+	Server     string    `json:"server"`
+	ConfigHash string    `json:"config_hash"`
+	ToolsHash  string    `json:"tools_hash"`
+	FetchedAt  time.Time `json:"fetched_at"`
+	Tools      []MCPTool `json:"tools"`
+}
+
+func hashJSON(v interface{}) string {
+	content, _ := json.Marshal(v)
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// mcpConfigHash hashes what decides the tools of a server: how it is started or reached.
+func mcpConfigHash(server *data.MCPServer) string {
+	return hashJSON(map[string]interface{}{
+		"type":     server.Type,
+		"command":  server.Command,
+		"args":     server.Args,
+		"url":      server.URL,
+		"http_url": server.HTTPUrl,
+		"base_url": server.BaseURL,
+		"headers":  server.Headers,
+		"env":      server.Env,
+		"work_dir": server.WorkDir,
+		"cwd":      server.Cwd,
+	})
+}
+
+func mcpToolCachePath(name string) string {
+	sum := sha256.Sum256([]byte(name))
+	return filepath.Join(data.GetMCPCacheDirPath(), hex.EncodeToString(sum[:])+".json")
+}
+
+// readMCPToolCache returns the cached tools of a server, nil when they are missing, expired
+// or cached for another configuration of the server.
+func readMCPToolCache(name string, server *data.MCPServer) *mcpToolCacheEntry {
+	content, err := os.ReadFile(mcpToolCachePath(name))
+	if err != nil {
+		return nil
+	}
+	var entry mcpToolCacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil || entry.Server != name {
+		return nil
+	}
+	if entry.ConfigHash != mcpConfigHash(server) || time.Since(entry.FetchedAt) > MCPToolCacheTTL {
+		return nil
+	}
+	return &entry
+}
+
+// writeMCPToolCache caches the tools listed by a server, reporting whether they changed
+// since the last time.
+func writeMCPToolCache(name string, server *data.MCPServer, tools []MCPTool) bool {
+	entry := mcpToolCacheEntry{
+		Server:     name,
+		ConfigHash: mcpConfigHash(server),
+		ToolsHash:  hashJSON(tools),
+		FetchedAt:  time.Now(),
+		Tools:      tools,
+	}
+	changed := true
+	if old := readMCPToolCache(name, server); old != nil {
+		changed = old.ToolsHash != entry.ToolsHash
+	}
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return changed
+	}
+	path := mcpToolCachePath(name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return changed
+	}
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		util.LogDebugf("Failed to cache the tools of MCP server %s: %v\n", name, err)
+	}
+	return changed
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type echoArgs struct {
+	Text string `json:"text" jsonschema:"the text to echo"`
+}
+
+// newFakeMCPServer serves an MCP server with an echo tool over streamable HTTP, counting
+// the sessions clients open.
+func newFakeMCPServer(t *testing.T) (*mcp.Server, *httptest.Server, *atomic.Int32) {
+	server := mcp.NewServer(&mcp.Implementation{Name: "fake", Version: "v1"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "fake_echo", Description: "Echo the text"},
+		func(ctx context.Context, req *mcp.CallToolRequest, args echoArgs) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: args.Text}}}, nil, nil
+		})
+	var sessions atomic.Int32
+	handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
+		sessions.Add(1)
+		return server
+	}, nil)
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return server, ts, &sessions
+}
+
+func TestMCPLazyLoading(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	_, ts, sessions := newFakeMCPServer(t)
+	servers := map[string]*data.MCPServer{
+		"fake": {Name: "fake", Type: "http", HTTPUrl: ts.URL, Allowed: true},
+	}
+	option := MCPLoadOption{LoadTools: true, Lazy: true}
+
+	// Without a cache the server is connected, and its tools cached
+	first := &MCPClient{}
+	defer first.Close()
+	if err := first.Init(servers, option); err != nil {
+		t.Fatal(err)
+	}
+	if sessions.Load() != 1 {
+		t.Fatalf("expected a connection without a cache, got %d", sessions.Load())
+	}
+
+	// With the cache, the tools are offered without connecting
+	second := &MCPClient{}
+	defer second.Close()
+	if err := second.Init(servers, option); err != nil {
+		t.Fatal(err)
+	}
+	if sessions.Load() != 1 {
+		t.Fatalf("expected no connection with the cache, got %d", sessions.Load())
+	}
+	tools := getMCPTools(second)
+	if len(tools) != 1 || tools[0].Function.Name != "fake_echo" {
+		t.Fatalf("expected the cached echo tool, got %+v", tools)
+	}
+	if _, ok := tools[0].Function.Parameters["properties"].(map[string]interface{})["text"]; !ok {
+		t.Errorf("expected the cached schema of the tool, got %+v", tools[0].Function.Parameters)
+	}
+
+	// The first call connects
+	resp, err := second.CallTool(context.Background(), "fake_echo", map[string]any{"text": "hello"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Contents) != 1 || resp.Contents[0] != "hello" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if sessions.Load() != 2 {
+		t.Errorf("expected the call to connect, got %d connections", sessions.Load())
+	}
+	if _, err := second.CallTool(context.Background(), "fake_echo", map[string]any{"text": "again"}); err != nil {
+		t.Fatal(err)
+	}
+	if sessions.Load() != 2 {
+		t.Errorf("expected the connection to be kept, got %d connections", sessions.Load())
+	}
+
+	// Another configuration of the server invalidates the cache
+	servers["fake"].Headers = map[string]string{"Authorization": "Bearer other"}
+	third := &MCPClient{}
+	defer third.Close()
+	if err := third.Init(servers, option); err != nil {
+		t.Fatal(err)
+	}
+	if sessions.Load() != 3 {
+		t.Errorf("expected a connection for the new configuration, got %d", sessions.Load())
+	}
+}
+
+func TestMCPToolListChanged(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	server, ts, _ := newFakeMCPServer(t)
+	servers := map[string]*data.MCPServer{
+		"fake": {Name: "fake", Type: "http", HTTPUrl: ts.URL, Allowed: true},
+	}
+
+	client := &MCPClient{}
+	defer client.Close()
+	if err := client.Init(servers, MCPLoadOption{LoadTools: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The server adds a tool and notifies the client
+	mcp.AddTool(server, &mcp.Tool{Name: "fake_upper", Description: "Uppercase the text"},
+		func(ctx context.Context, req *mcp.CallToolRequest, args echoArgs) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{}, nil, nil
+		})
+	waitFor(t, func() bool { return client.FindTool("fake_upper") != nil })
+	if readMCPToolCache("fake", servers["fake"]) == nil || len(readMCPToolCache("fake", servers["fake"]).Tools) != 2 {
+		t.Error("expected the cache to hold the new tool")
+	}
+}
+
+func TestMCPCallToolStopsOnCancel(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	server, ts, _ := newFakeMCPServer(t)
+	release := make(chan struct{})
+	defer close(release)
+	mcp.AddTool(server, &mcp.Tool{Name: "fake_wait", Description: "Wait for the test"},
+		func(ctx context.Context, req *mcp.CallToolRequest, args echoArgs) (*mcp.CallToolResult, any, error) {
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: args.Text}}}, nil, nil
+		})
+	servers := map[string]*data.MCPServer{
+		"fake": {Name: "fake", Type: "http", HTTPUrl: ts.URL, Allowed: true},
+	}
+	client := &MCPClient{}
+	defer client.Close()
+	if err := client.Init(servers, MCPLoadOption{LoadTools: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := client.CallTool(ctx, "fake_wait", map[string]any{"text": "late"}); err == nil {
+		t.Error("expected the cancelled call to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the call ran on for %v after the cancel", elapsed)
+	}
+}
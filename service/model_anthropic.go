@@ -4,8 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"path/filepath"
 	"strings"
 
+	"github.com/activebook/gllm/data"
 	"github.com/activebook/gllm/util"
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
@@ -62,6 +64,13 @@ func (ag *Agent) GenerateAnthropicSync(messages []anthropic.MessageParam, system
 	if ag.Model.EndPoint != "" {
 		opts = append(opts, option.WithBaseURL(ag.Model.EndPoint))
 	}
+	hc, err := providerHTTPClient(ag.Model)
+	if err != nil {
+		return "", err
+	}
+	if hc != nil {
+		opts = append(opts, option.WithHTTPClient(hc))
+	}
 	client := anthropic.NewClient(opts...)
 
 	maxTokens := DefaultModelLimits.MaxOutputTokens
@@ -70,12 +79,18 @@ func (ag *Agent) GenerateAnthropicSync(messages []anthropic.MessageParam, system
 	} else if ag.Model.MaxOutputTokens > 0 {
 		maxTokens = int(ag.Model.MaxOutputTokens)
 	}
+	if ag.Model.MaxTokens > 0 && int(ag.Model.MaxTokens) < maxTokens {
+		maxTokens = int(ag.Model.MaxTokens)
+	}
 
 	params := anthropic.MessageNewParams{
 		Model:     anthropic.Model(ag.Model.Model),
 		Messages:  messages,
 		MaxTokens: int64(maxTokens),
 	}
+	if len(ag.Model.Stop) > 0 {
+		params.StopSequences = ag.Model.Stop
+	}
 
 	if systemPrompt != "" {
 		params.System = []anthropic.TextBlockParam{{
@@ -128,14 +143,22 @@ func (ag *Agent) GenerateAnthropicStream() error {
 	if ag.Model.EndPoint != "" {
 		opts = append(opts, option.WithBaseURL(ag.Model.EndPoint))
 	}
+	hc, err := providerHTTPClient(ag.Model)
+	if err != nil {
+		return err
+	}
+	if hc != nil {
+		opts = append(opts, option.WithHTTPClient(hc))
+	}
 
 	// When we call client.Messages.NewStreaming, inside it, it would set anthropic-version to 2023-06-01 automatically
 	client := anthropic.NewClient(opts...)
 
 	// Create tools
 	var tools []anthropic.ToolUnionParam
+	var native *anthropicNativeTools
 	if len(ag.EnabledTools) > 0 {
-		tools = ag.getAnthropicTools()
+		tools, native = ag.getAnthropicTools()
 	}
 	if ag.MCPClient != nil {
 		mcpTools := ag.getAnthropicMCPTools()
@@ -146,40 +169,57 @@ func (ag *Agent) GenerateAnthropicStream() error {
 	var executor *SubAgentExecutor
 	if ag.SharedState != nil {
 		executor = NewSubAgentExecutor(ag.SharedState, ag.Session.GetTopSessionName(), ag.StdOutput, ag.FileOutput, ag.SSEOutput)
+		executor.observer = ag.Observer
 		defer executor.Shutdown()
 	}
 
 	op := OpenProcessor{
-		notify:      ag.NotifyChan,
-		data:        ag.DataChan,
-		proceed:     ag.ProceedChan,
-		search:      ag.SearchEngine,
-		toolsUse:    &ag.ToolsUse,
-		interaction: ag.Interaction,
-		quiet:       ag.QuietMode,
-		queries:     make([]string, 0),
-		references:  make([]map[string]interface{}, 0),
-		status:      &ag.Status,
-		mcpClient:   ag.MCPClient,
-		fileHooks:   NewFileHooks(),
+		ctx:            ag.Ctx,
+		notify:         ag.NotifyChan,
+		data:           ag.DataChan,
+		proceed:        ag.ProceedChan,
+		search:         ag.SearchEngine,
+		toolsUse:       &ag.ToolsUse,
+		interaction:    ag.Interaction,
+		quiet:          ag.QuietMode,
+		queries:        make([]string, 0),
+		references:     make([]map[string]interface{}, 0),
+		status:         &ag.Status,
+		mcpClient:      ag.MCPClient,
+		fileHooks:      NewFileHooks(),
+		autoFormat:     ag.AutoFormat,
+		shell:          ag.Shell,
+		network:        ag.Model.Network,
+		redactor:       ag.Redactor,
+		injectionGuard: ag.InjectionGuard,
+		pathRules:      ag.PathRules,
 		// Sub-agent orchestration
 		sharedState: ag.SharedState,
 		executor:    executor,
 		agentName:   ag.AgentName,
+		sessionName: ag.Session.GetName(),
 	}
 
 	chat := &Anthropic{
 		client: client,
 		tools:  tools,
 		op:     &op,
+		native: native,
 	}
 
 	// Prepare Messages
-	err := ag.SortAnthropicMessagesByOrder()
+	err = ag.SortAnthropicMessagesByOrder(chat)
 	if err != nil {
 		return fmt.Errorf("error sorting messages: %v", err)
 	}
 
+	// Code execution, run by Anthropic, also reads the files uploaded to its container
+	history, _ := ag.Session.GetMessages().([]anthropic.MessageParam)
+	if ag.UseCodeTool || hasContainerUploads(history) {
+		chat.tools = append(chat.tools, anthropicCodeExecutionTool())
+		chat.codeExecution = true
+	}
+
 	// Signal started
 	ag.Status.ChangeTo(ag.NotifyChan, StreamNotify{Status: StatusStarted}, ag.ProceedChan)
 
@@ -194,6 +234,14 @@ func (ag *Agent) GenerateAnthropicStream() error {
 		if IsUserCancelError(err) {
 			return err
 		}
+		// Safety block, explained by the caller
+		if IsSafetyBlockError(err) {
+			return err
+		}
+		// Stopped by a cost budget
+		if IsBudgetExceededError(err) {
+			return err
+		}
 		return fmt.Errorf("error processing chat: %v", err)
 	}
 
@@ -201,9 +249,11 @@ func (ag *Agent) GenerateAnthropicStream() error {
 }
 
 type Anthropic struct {
-	client anthropic.Client
-	tools  []anthropic.ToolUnionParam
-	op     *OpenProcessor
+	client        anthropic.Client
+	tools         []anthropic.ToolUnionParam
+	op            *OpenProcessor
+	native        *anthropicNativeTools // Native tools standing for gllm tools, nil without
+	codeExecution bool                  // Whether the code execution tool is offered
 }
 
 func (a *Anthropic) process(ag *Agent) error {
@@ -211,6 +261,10 @@ func (a *Anthropic) process(ag *Agent) error {
 	i := 0
 	for range ag.MaxRecursions {
 		i++
+		// Stop or ask before spending more than the budgets allow
+		if err := ag.checkBudget(); err != nil {
+			return err
+		}
 		a.op.status.ChangeTo(a.op.notify, StreamNotify{Status: StatusProcessing}, a.op.proceed)
 
 		messages, _ := ag.Session.GetMessages().([]anthropic.MessageParam)
@@ -245,6 +299,14 @@ func (a *Anthropic) process(ag *Agent) error {
 			Tools: a.tools, // []ToolUnionParam
 		}
 
+		// Generation controls, Anthropic has no frequency/presence penalties
+		if ag.Model.MaxTokens > 0 && int64(ag.Model.MaxTokens) < params.MaxTokens {
+			params.MaxTokens = int64(ag.Model.MaxTokens)
+		}
+		if len(ag.Model.Stop) > 0 {
+			params.StopSequences = ag.Model.Stop
+		}
+
 		// Enable Thinking if requested, with budget based on level
 		params.Thinking = ag.ThinkingLevel.ToAnthropicParams()
 		if params.Thinking.OfEnabled != nil {
@@ -262,12 +324,28 @@ func (a *Anthropic) process(ag *Agent) error {
 			params.TopP = param.NewOpt(float64(ag.Model.TopP))
 		}
 
-		stream := a.client.Messages.NewStreaming(ag.Ctx, params)
+		var betas []option.RequestOption
+		if a.codeExecution {
+			betas = append(betas,
+				option.WithHeaderAdd("anthropic-beta", anthropicBetaCodeExecution),
+				option.WithHeaderAdd("anthropic-beta", anthropicBetaFilesAPI))
+		}
+		stream := a.client.Messages.NewStreaming(ag.Ctx, params, betas...)
 		a.op.status.ChangeTo(a.op.notify, StreamNotify{Status: StatusStarted}, a.op.proceed)
 
 		// Process stream
 		msg, toolCalls, usage, err := a.processStream(stream)
 		if err != nil {
+			if cancelErr := checkInterrupted(ag.Ctx); cancelErr != nil {
+				// Interrupted by user, keep the partial reply so the conversation can continue from it
+				if len(msg.Content) > 0 {
+					a.saveToSession(ag, msg)
+				}
+				return cancelErr
+			}
+			if IsSafetyBlockError(err) {
+				addUpAnthropicTokenUsage(ag, usage)
+			}
 			return err
 		}
 
@@ -282,7 +360,14 @@ func (a *Anthropic) process(ag *Agent) error {
 
 		if len(toolCalls) > 0 {
 			// Process tool calls
-			for _, tc := range toolCalls {
+			for idx, tc := range toolCalls {
+				if cancelErr := checkInterrupted(ag.Ctx); cancelErr != nil {
+					// Every tool_use requires a tool_result, mark the pending ones as cancelled
+					for _, pending := range toolCalls[idx:] {
+						a.saveToSession(ag, anthropic.NewUserMessage(anthropic.NewToolResultBlock(pending.ID, cancelErr.Error(), true)))
+					}
+					return cancelErr
+				}
 				// Execute tool
 				toolMsg, err := a.processToolCall(tc)
 				if err != nil {
@@ -307,6 +392,9 @@ func (a *Anthropic) process(ag *Agent) error {
 					return err
 				}
 			}
+			if err := a.op.haltError(); err != nil {
+				return err
+			}
 		} else {
 			break
 		}
@@ -348,12 +436,14 @@ func (a *Anthropic) processStream(stream *ssestream.Stream[anthropic.MessageStre
 	var thinkingBuilder strings.Builder
 	var thinkingSignature string
 	var currentToolUse *anthropic.ToolUseBlockParam
+	var currentServerTool string // Name of the tool Anthropic runs, "" for the others
 	var toolCalls []anthropic.ToolUseBlockParam
 	var currentInputBuilder strings.Builder // For accumulating JSON input
 	usage := NewTokenUsage()
 
 	var contentBlocks []anthropic.ContentBlockParamUnion
 	var currentBlockType string
+	var stopReason anthropic.StopReason
 
 	message := anthropic.NewAssistantMessage() // Helper
 
@@ -384,14 +474,9 @@ func (a *Anthropic) processStream(stream *ssestream.Stream[anthropic.MessageStre
 			case "tool_use": // "tool_use"
 				// Start Tool Use
 				// block.ToolUse is not a field. It's flat.
-				// Skip if not our expected function
-				// Because some model made up function name
+				// Unknown function names are answered by processToolCall
 				functionID := block.ID
 				functionName := block.Name
-				if functionName != "" && !IsAvailableOpenTool(functionName) && !IsAvailableMCPTool(functionName, a.op.mcpClient) {
-					util.LogWarnf("Skipping tool call with unknown function name: %s\n", functionName)
-					continue
-				}
 				// ContentBlockStartEventContentBlockUnion fields are embedded.
 				// We can access ID, Name directly.
 				currentToolUse = &anthropic.ToolUseBlockParam{
@@ -401,6 +486,16 @@ func (a *Anthropic) processStream(stream *ssestream.Stream[anthropic.MessageStre
 				}
 				currentInputBuilder.Reset()
 
+			case "server_tool_use":
+				// Code run by Anthropic, its input is shown once complete
+				currentServerTool = block.Name
+				currentInputBuilder.Reset()
+			case "bash_code_execution_tool_result", "code_execution_tool_result", "text_editor_code_execution_tool_result":
+				// Only the rendering is kept, the server blocks can't be replayed without the container
+				if text := anthropicCodeResultText(block.RawJSON()); text != "" {
+					contentBuilder.WriteString(text)
+					a.op.data <- StreamData{Text: text, Type: DataTypeNormal}
+				}
 			case "text":
 			case "thinking":
 				// Start Thinking (signature is typically empty at start)
@@ -429,18 +524,30 @@ func (a *Anthropic) processStream(stream *ssestream.Stream[anthropic.MessageStre
 				// Debugf("Signature delta received: [%s], accumulated: [%s]", signature, thinkingSignature)
 			case "input_json_delta":
 				currentInputBuilder.WriteString(delta.PartialJSON)
+				if currentToolUse != nil {
+					a.op.previewToolCall(currentToolUse.ID, currentToolUse.Name, currentInputBuilder.String())
+				}
 			}
 
 		case "content_block_stop":
 			if currentBlockType == "thinking" {
 				a.op.status.ChangeTo(a.op.notify, StreamNotify{Status: StatusReasoningOver}, a.op.proceed)
 			}
+			if currentServerTool != "" {
+				if text := anthropicServerToolText(currentServerTool, currentInputBuilder.String()); text != "" {
+					contentBuilder.WriteString(text)
+					a.op.data <- StreamData{Text: text, Type: DataTypeNormal}
+				}
+				currentServerTool = ""
+			}
 			if currentToolUse != nil {
 				var input interface{}
 				if err := json.Unmarshal([]byte(currentInputBuilder.String()), &input); err == nil {
 					currentToolUse.Input = input
 				} else {
+					// Left to processToolCall to report, the API only takes an object in the history
 					currentToolUse.Input = interface{}(currentInputBuilder.String())
+					input = map[string]interface{}{}
 				}
 				toolCalls = append(toolCalls, *currentToolUse)
 
@@ -456,6 +563,9 @@ func (a *Anthropic) processStream(stream *ssestream.Stream[anthropic.MessageStre
 
 		case "message_delta":
 			evt := event.AsMessageDelta()
+			if evt.Delta.StopReason != "" {
+				stopReason = evt.Delta.StopReason
+			}
 			// For anthropic model, cache read tokens are included in the message usage
 			// Because cached tokens are not in the prompt tokens, so we need to count them
 			usage.RecordTokenUsage(
@@ -473,8 +583,17 @@ func (a *Anthropic) processStream(stream *ssestream.Stream[anthropic.MessageStre
 	}
 
 	if err := stream.Err(); err != nil {
+		// Return what has been streamed so far, the caller decides whether to keep it
+		// Thinking is dropped because an unsigned thinking block is rejected by the API
+		if text := contentBuilder.String(); text != "" {
+			return anthropic.NewAssistantMessage(anthropic.NewTextBlock(text)), nil, usage, err
+		}
 		return anthropic.MessageParam{}, nil, usage, err
 	}
+	if stopReason == anthropic.StopReasonRefusal {
+		// The classifiers stopped the response, what was streamed is not kept
+		return anthropic.MessageParam{}, nil, usage, SafetyBlockError{Provider: ModelProviderAnthropic, Reason: string(stopReason)}
+	}
 
 	// Finalize message construction
 	textContent := contentBuilder.String()
@@ -505,19 +624,33 @@ func (a *Anthropic) processStream(stream *ssestream.Stream[anthropic.MessageStre
 }
 
 func (a *Anthropic) processToolCall(toolCall anthropic.ToolUseBlockParam) (anthropic.MessageParam, error) {
-	// Parse the query from the arguments
-	var argsMap map[string]interface{}
-	inputVal := toolCall.Input
-	if m, ok := inputVal.(map[string]interface{}); ok {
-		argsMap = m
-	} else {
-		return anthropic.MessageParam{}, fmt.Errorf("invalid tool input arguments: %v", inputVal)
+	// A native tool runs the gllm tool it stands for, under the same ID
+	native := a.native.translate(toolCall.Name, toolCall.Input)
+	if native != nil {
+		if native.result != "" {
+			return anthropic.NewUserMessage(anthropic.NewToolResultBlock(toolCall.ID, native.result, strings.HasPrefix(native.result, "Error:"))), nil
+		}
+		toolCall.Name, toolCall.Input = native.tool, native.args
+	}
+
+	// An invalid call is answered with what is wrong, so the model can fix it
+	argsMap, problem := a.op.checkToolCall(toolCall.Name, toolCall.Input)
+	if problem != "" {
+		return anthropic.NewUserMessage(anthropic.NewToolResultBlock(toolCall.ID, problem, true)), nil
+	}
+
+	// The files of a native call are only read once the rules let it through
+	if native != nil && native.prepare != nil {
+		if result := native.prepare(); result != "" {
+			return anthropic.NewUserMessage(anthropic.NewToolResultBlock(toolCall.ID, result, strings.HasPrefix(result, "Error:"))), nil
+		}
+		toolCall.Name, argsMap = native.tool, native.args
 	}
 
 	var filteredArgs map[string]interface{}
-	if toolCall.Name == ToolEditFile || toolCall.Name == ToolWriteFile || toolCall.Name == ToolAskUser {
+	if toolCall.Name == ToolEditFile || toolCall.Name == ToolWriteFile || toolCall.Name == ToolApplyChanges || toolCall.Name == ToolAskUser || toolCall.Name == ToolPresentOptions || toolCall.Name == ToolRequestApproval {
 		// Don't show content(the modified content could be too long)
-		filteredArgs = FilterOpenToolArguments(argsMap, []string{"content", "edits", "options", "question_type"})
+		filteredArgs = FilterOpenToolArguments(argsMap, []string{"content", "edits", "changes", "options", "question_type"})
 	} else {
 		filteredArgs = FilterOpenToolArguments(argsMap, []string{})
 	}
@@ -533,14 +666,29 @@ func (a *Anthropic) processToolCall(toolCall anthropic.ToolUseBlockParam) (anthr
 	var msg anthropic.MessageParam
 	var err error
 	// Dispatch tool call
+	loopFilter := a.op.toolLoopFilter(toolCall.Name, argsMap)
+	handoffFilter := a.op.handoffFilter(toolCall.Name, argsMap)
+	argsMap = a.op.redactor.restoreToolArgs(toolCall.Name, argsMap)
+	grant := a.op.beginToolGrant(toolCall.Name, argsMap)
+	audit := a.op.beginToolAudit(toolCall.Name, argsMap)
 	msg, err = a.op.dispatchAnthropicToolCall(toolCall, &argsMap)
+	audit.end(anthropicToolResultText(msg), err)
+	if feedback, ok := grant.end(err); ok {
+		msg, err = mapAnthropicToolResult(msg, func(string) string { return feedback }), nil
+	}
+	msg = mapAnthropicToolResult(msg, a.op.toolResultFilter(toolCall.Name))
+	msg = mapAnthropicToolResult(msg, handoffFilter)
+	msg = mapAnthropicToolResult(msg, loopFilter)
 
 	// Function call is done
-	a.op.status.ChangeTo(a.op.notify, StreamNotify{Status: StatusFunctionCallingOver}, a.op.proceed)
+	a.op.status.ChangeTo(a.op.notify, toolCallOver(anthropicToolResultText(msg), err), a.op.proceed)
 	return msg, err
 }
 
-func (ag *Agent) SortAnthropicMessagesByOrder() error {
+// SortAnthropicMessagesByOrder adds the prompt and its attachments to the session. With code
+// execution on, the attachments Claude can't read are uploaded with the Files API of the client
+// to its container, nil leaves them out.
+func (ag *Agent) SortAnthropicMessagesByOrder(chat *Anthropic) error {
 	// Load
 	err := ag.Session.Load()
 	if err != nil {
@@ -558,12 +706,36 @@ func (ag *Agent) SortAnthropicMessagesByOrder() error {
 	}
 
 	// Add Files
+	var uploads *fileUploads
 	for _, file := range ag.Files {
 		if file != nil {
 			part := ag.getAnthropicFilePart(file)
 			if part != nil {
 				userContent = append(userContent, *part) // Dereference pointer
+				continue
+			}
+			if chat == nil || !ag.UseCodeTool {
+				ag.Status.ChangeTo(ag.NotifyChan, StreamNotify{Status: StatusWarn, Data: fmt.Sprintf("Claude can't read %s, turn on code_execution to upload it.", filepath.Base(file.Path()))}, nil)
+				continue
+			}
+			if uploads == nil {
+				uploads = loadFileUploads(data.GetUploadsFilePath())
 			}
+			ag.Status.ChangeTo(ag.NotifyChan, StreamNotify{Status: StatusProcessing}, ag.ProceedChan)
+			block, err := chat.uploadFile(ag, uploads, file)
+			if cancelErr := checkInterrupted(ag.Ctx); cancelErr != nil {
+				return cancelErr
+			}
+			if err != nil {
+				ag.Status.ChangeTo(ag.NotifyChan, StreamNotify{Status: StatusWarn, Data: fmt.Sprintf("%v, leaving it out.", err)}, nil)
+				continue
+			}
+			userContent = append(userContent, block)
+		}
+	}
+	if uploads != nil {
+		if err := uploads.save(); err != nil {
+			util.LogWarnf("Failed to save the record of uploads: %v\n", err)
 		}
 	}
 
@@ -578,13 +750,20 @@ func (ag *Agent) SortAnthropicMessagesByOrder() error {
 	return ag.Session.Save()
 }
 
-func (ag *Agent) getAnthropicTools() []anthropic.ToolUnionParam {
+// getAnthropicTools returns the enabled tools, with Claude's native tools in place of the ones
+// they cover when the model uses them.
+func (ag *Agent) getAnthropicTools() ([]anthropic.ToolUnionParam, *anthropicNativeTools) {
 	var tools []anthropic.ToolUnionParam
-	genericTools := GetOpenToolsFiltered(ag.EnabledTools)
+	var native *anthropicNativeTools
+	enabled := ag.EnabledTools
+	if ag.Model.NativeTools {
+		tools, enabled, native = anthropicNativeToolSet(ag.Model.Model, enabled)
+	}
+	genericTools := GetOpenToolsFiltered(enabled)
 	for _, genericTool := range genericTools {
 		tools = append(tools, genericTool.ToAnthropicTool())
 	}
-	return tools
+	return tools, native
 }
 
 func (ag *Agent) getAnthropicMCPTools() []anthropic.ToolUnionParam {
@@ -5,7 +5,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"iter"
+	"net/http"
+	"strings"
+	"time"
 
+	"github.com/activebook/gllm/data"
 	"github.com/activebook/gllm/util"
 	"google.golang.org/genai"
 )
@@ -49,6 +53,7 @@ type Gemini struct {
 	// With *Agent embedded, Gemini Agent automatically has access to all of Agent's fields and methods.
 	// *Agent // Embedded pointer to Agent
 	client *genai.Client
+	files  *genai.Client // Client of the Files API, built on the first upload
 	op     *OpenProcessor
 }
 
@@ -57,9 +62,25 @@ func (ag *Agent) initGeminiAgent() (*Gemini, error) {
 	// In multi-turn session, even though we create it each time
 	// it can still be cached for advanced gemini models such as 2.5 flash/pro
 	// so it's a server side job
-	client, err := genai.NewClient(ag.Ctx, &genai.ClientConfig{
-		APIKey:  ag.Model.ApiKey,
-		Backend: genai.BackendGeminiAPI,
+	hc, err := providerHTTPClient(ag.Model)
+	if err != nil {
+		return nil, err
+	}
+	client, err := ag.newGeminiClient(hc)
+	if err != nil {
+		return nil, err
+	}
+	return &Gemini{
+		client: client,
+	}, nil
+}
+
+// newGeminiClient returns a Gemini client of the model that sends its requests with hc.
+func (ag *Agent) newGeminiClient(hc *http.Client) (*genai.Client, error) {
+	return genai.NewClient(ag.Ctx, &genai.ClientConfig{
+		APIKey:     ag.Model.ApiKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: hc,
 		HTTPOptions: genai.HTTPOptions{
 			// BaseURL specifies the base URL for the API endpoint. If empty, defaults
 			// to "https://generativelanguage.googleapis.com/"
@@ -67,15 +88,11 @@ func (ag *Agent) initGeminiAgent() (*Gemini, error) {
 			BaseURL: ag.Model.EndPoint,
 		},
 	})
-	if err != nil {
-		return nil, err
-	}
-	return &Gemini{
-		client: client,
-	}, nil
 }
 
-func (ag *Agent) SortGeminiMessagesByOrder() error {
+// SortGeminiMessagesByOrder adds the prompt and its attachments to the session. The large
+// attachments are uploaded with the Files API of the client, nil sends them all inline.
+func (ag *Agent) SortGeminiMessagesByOrder(ga *Gemini) error {
 	// Load previous messages if any
 	err := ag.Session.Load()
 	if err != nil {
@@ -84,21 +101,49 @@ func (ag *Agent) SortGeminiMessagesByOrder() error {
 
 	messages, _ := ag.Session.GetMessages().([]*genai.Content)
 
+	// The uploads expire, the session no longer refers to the expired ones
+	uploads := loadFileUploads(data.GetUploadsFilePath())
+	now := time.Now()
+	changed := uploads.prune(now)
+	messages = forgetExpiredGeminiFiles(messages, uploads, now)
+
 	var parts []*genai.Part
 
 	if ag.UserPrompt != "" {
 		parts = append(parts, &genai.Part{Text: ag.UserPrompt})
 	}
+	inline := 0
 	for _, file := range ag.Files {
 		// Check if the file data is empty
 		if file != nil {
 			// Convert the file data to a blob
 			part := ag.getGeminiFilePart(file)
-			if part.Text != "" || part.InlineData != nil {
+			if part.InlineData != nil && ga != nil && geminiShouldUpload(len(part.InlineData.Data), inline) {
+				ag.Status.ChangeTo(ag.NotifyChan, StreamNotify{Status: StatusProcessing}, ag.ProceedChan)
+				ref, err := ga.uploadFile(ag, uploads, file)
+				if cancelErr := checkInterrupted(ag.Ctx); cancelErr != nil {
+					return cancelErr
+				}
+				if err != nil {
+					ag.Status.ChangeTo(ag.NotifyChan, StreamNotify{Status: StatusWarn, Data: fmt.Sprintf("%v, sending it inline.", err)}, nil)
+				} else {
+					part = *ref
+					changed = true
+				}
+			}
+			if part.InlineData != nil {
+				inline += len(part.InlineData.Data)
+			}
+			if part.Text != "" || part.InlineData != nil || part.FileData != nil {
 				parts = append(parts, &part)
 			}
 		}
 	}
+	if changed {
+		if err := uploads.save(); err != nil {
+			util.LogWarnf("Failed to save the record of uploads: %v\n", err)
+		}
+	}
 
 	if len(parts) > 0 {
 		// Construct Input Content from streamParts
@@ -133,6 +178,7 @@ func (ag *Agent) GenerateGeminiSync(messages []*genai.Content, systemPrompt stri
 		Temperature: &ag.Model.Temperature,
 		TopP:        &ag.Model.TopP,
 	}
+	applyGeminiGenerationParams(config, ag.Model)
 	if ag.Model.Seed != nil {
 		config.Seed = ag.Model.Seed
 	}
@@ -171,26 +217,35 @@ func (ag *Agent) GenerateGeminiStream() error {
 	var executor *SubAgentExecutor
 	if ag.SharedState != nil {
 		executor = NewSubAgentExecutor(ag.SharedState, ag.Session.GetTopSessionName(), ag.StdOutput, ag.FileOutput, ag.SSEOutput)
+		executor.observer = ag.Observer
 		defer executor.Shutdown()
 	}
 
 	op := OpenProcessor{
-		notify:      ag.NotifyChan,
-		data:        ag.DataChan,
-		proceed:     ag.ProceedChan,
-		search:      ag.SearchEngine,
-		toolsUse:    &ag.ToolsUse,
-		interaction: ag.Interaction,
-		quiet:       ag.QuietMode,
-		queries:     make([]string, 0),
-		references:  make([]map[string]interface{}, 0),
-		status:      &ag.Status,
-		mcpClient:   ag.MCPClient,
-		fileHooks:   NewFileHooks(),
+		ctx:            ag.Ctx,
+		notify:         ag.NotifyChan,
+		data:           ag.DataChan,
+		proceed:        ag.ProceedChan,
+		search:         ag.SearchEngine,
+		toolsUse:       &ag.ToolsUse,
+		interaction:    ag.Interaction,
+		quiet:          ag.QuietMode,
+		queries:        make([]string, 0),
+		references:     make([]map[string]interface{}, 0),
+		status:         &ag.Status,
+		mcpClient:      ag.MCPClient,
+		fileHooks:      NewFileHooks(),
+		autoFormat:     ag.AutoFormat,
+		shell:          ag.Shell,
+		network:        ag.Model.Network,
+		redactor:       ag.Redactor,
+		injectionGuard: ag.InjectionGuard,
+		pathRules:      ag.PathRules,
 		// Sub-agent orchestration
 		sharedState: ag.SharedState,
 		executor:    executor,
 		agentName:   ag.AgentName,
+		sessionName: ag.Session.GetName(),
 	}
 	ga.op = &op
 
@@ -202,17 +257,15 @@ func (ag *Agent) GenerateGeminiStream() error {
 		Temperature:    &ag.Model.Temperature,
 		TopP:           &ag.Model.TopP,
 		ThinkingConfig: thinking,
-		Tools:          []*genai.Tool{
-			// Placeholder
-			//{CodeExecution: &genai.ToolCodeExecution{}},
-			//{GoogleSearch: &genai.GoogleSearch{}},
-		},
 	}
 
 	// Add seed if provided
 	if ag.Model.Seed != nil {
 		config.Seed = ag.Model.Seed
 	}
+
+	// Add generation controls if configured
+	applyGeminiGenerationParams(&config, ag.Model)
 	// System Instruction (System Prompt)
 	if ag.SystemPrompt != "" {
 		config.SystemInstruction = &genai.Content{Parts: []*genai.Part{{Text: ag.SystemPrompt}}}
@@ -229,35 +282,34 @@ func (ag *Agent) GenerateGeminiStream() error {
 			tool = appendGeminiTool(tool, mcpTool)
 		}
 	}
-	// Add function tools to config
-	if tool != nil {
-		config.Tools = append(config.Tools, tool)
-	}
+	// Built-in tools, run by Google, work together but not with function tools
+	var builtins []*genai.Tool
+	var builtinNames []string
 	if ag.SearchEngine.UseSearch {
-		// Add web search tool to config
-		tool = ga.getGeminiWebSearchTool()
-		config.Tools = append(config.Tools, tool)
+		builtins = append(builtins, ga.getGeminiWebSearchTool())
+		builtinNames = append(builtinNames, "Google Search")
 	}
-	// // Incompatible tools yet
-	// if ag.UseCodeTool {
-	// 	// Remember: CodeExecution and GoogleSearch cannot be enabled at the same time
-	// 	config.Tools = append(config.Tools, ga.getGeminiCodeExecTool())
-	// }
-
-	// Check whether to show warning message
-	if len(config.Tools) > 1 {
-		// Function tools and Google Search cannot be enabled simultaneously.
-		// Function call is not compatible with Google Search tool
-		// Only keep the first one
-		config.Tools = config.Tools[:1]
-		ag.Status.ChangeTo(ag.NotifyChan, StreamNotify{Status: StatusStarted}, ag.ProceedChan)
-		ag.Status.ChangeTo(ag.NotifyChan, StreamNotify{Status: StatusWarn,
-			Data: fmt.Sprint("Function tools are not compatible with Google Search tool, so Google Search is unavailable now.\n" +
-				"Please disable tools if you want to use Google Search.")}, nil)
+	if ag.UseCodeTool {
+		builtins = append(builtins, ga.getGeminiCodeExecTool())
+		builtinNames = append(builtinNames, "code execution")
+	}
+	if tool != nil {
+		// Add function tools to config
+		config.Tools = append(config.Tools, tool)
+		if len(builtins) > 0 {
+			// Function call is not compatible with the built-in tools, the functions are kept
+			names := strings.Join(builtinNames, " and ")
+			ag.Status.ChangeTo(ag.NotifyChan, StreamNotify{Status: StatusStarted}, ag.ProceedChan)
+			ag.Status.ChangeTo(ag.NotifyChan, StreamNotify{Status: StatusWarn,
+				Data: fmt.Sprintf("Function tools are not compatible with %s, so %s is unavailable now.\n"+
+					"Please disable tools if you want to use %s.", names, names, names)}, nil)
+		}
+	} else {
+		config.Tools = append(config.Tools, builtins...)
 	}
 
 	// Prepare the Messages for Chat Completion
-	err = ag.SortGeminiMessagesByOrder()
+	err = ag.SortGeminiMessagesByOrder(ga)
 	if err != nil {
 		return fmt.Errorf("error sorting messages: %v", err)
 	}
@@ -277,6 +329,14 @@ func (ag *Agent) GenerateGeminiStream() error {
 		if IsUserCancelError(err) {
 			return err
 		}
+		// Safety block, explained by the caller
+		if IsSafetyBlockError(err) {
+			return err
+		}
+		// Stopped by a cost budget
+		if IsBudgetExceededError(err) {
+			return err
+		}
 		return fmt.Errorf("error processing chat: %v", err)
 	}
 	return nil
@@ -290,6 +350,10 @@ func (ga *Gemini) process(ag *Agent, config *genai.GenerateContentConfig) error
 	// Use maxRecursions from LangLogic
 	maxRecursions := ag.MaxRecursions
 	for i := 0; i < maxRecursions; i++ {
+		// Stop or ask before spending more than the budgets allow
+		if err := ag.checkBudget(); err != nil {
+			return err
+		}
 		ga.op.status.ChangeTo(ga.op.notify, StreamNotify{Status: StatusProcessing}, ga.op.proceed)
 
 		// Create a chat session - this is the important part
@@ -323,9 +387,37 @@ func (ga *Gemini) process(ag *Agent, config *genai.GenerateContentConfig) error
 		// Process the stream and collect tool calls
 		modelContent, resp, err := ga.processStream(stream, &references, &queries)
 		if err != nil {
+			if cancelErr := checkInterrupted(ag.Ctx); cancelErr != nil {
+				// Interrupted by user, keep the partial reply so the conversation can continue from it
+				if partial := partialGeminiContent(modelContent); partial != nil {
+					ga.saveToSession(ag, partial)
+				}
+				return cancelErr
+			}
+			if IsSafetyBlockError(err) {
+				ag.addUpGeminiTokenUsage(resp)
+			}
 			return err
 		}
 
+		// A malformed function call has no part to answer, the model is told what went wrong
+		if reason, ok := geminiMalformedCall(resp); ok {
+			ag.addUpGeminiTokenUsage(resp)
+			if len(modelContent.Parts) > 0 {
+				if err := ga.saveToSession(ag, modelContent); err != nil {
+					return err
+				}
+			}
+			problem := ga.op.invalidToolCall("your last function call", reason)
+			if err := ga.saveToSession(ag, genai.NewContentFromText(problem, genai.RoleUser)); err != nil {
+				return err
+			}
+			if err := ga.op.haltError(); err != nil {
+				return err
+			}
+			continue
+		}
+
 		// Update History
 		err = ga.saveToSession(ag, modelContent)
 		if err != nil {
@@ -348,7 +440,21 @@ func (ga *Gemini) process(ag *Agent, config *genai.GenerateContentConfig) error
 			break
 		}
 
-		for _, funcCall := range funcCalls {
+		for idx, funcCall := range funcCalls {
+			if cancelErr := checkInterrupted(ag.Ctx); cancelErr != nil {
+				// Every function call requires a response, mark the pending ones as cancelled
+				for _, pending := range funcCalls[idx:] {
+					ga.saveToSession(ag, &genai.Content{
+						Role: genai.RoleUser,
+						Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{
+							ID:       pending.ID,
+							Name:     pending.Name,
+							Response: map[string]any{"error": cancelErr.Error()},
+						}}},
+					})
+				}
+				return cancelErr
+			}
 			// Handle tool call
 			funcResp, err := ga.processToolCall(funcCall)
 			if err != nil {
@@ -372,6 +478,9 @@ func (ga *Gemini) process(ag *Agent, config *genai.GenerateContentConfig) error
 				return err
 			}
 		}
+		if err := ga.op.haltError(); err != nil {
+			return err
+		}
 	}
 
 	// Add queries to the output if any
@@ -417,10 +526,15 @@ func (ga *Gemini) processStream(stream iter.Seq2[*genai.GenerateContentResponse,
 		Parts: []*genai.Part{},
 	}
 	var finalResp *genai.GenerateContentResponse
+	var block *SafetyBlockError
 
 	for resp, err := range stream {
 		if err != nil {
-			return nil, nil, err
+			// Return what has been streamed so far, the caller decides whether to keep it
+			return modelContent, nil, err
+		}
+		if b := geminiSafetyBlock(resp); b != nil {
+			block = b
 		}
 
 		// Process and send content
@@ -436,16 +550,6 @@ func (ga *Gemini) processStream(stream iter.Seq2[*genai.GenerateContentResponse,
 					part.InlineData != nil || part.FileData != nil ||
 					part.ExecutableCode != nil || part.CodeExecutionResult != nil {
 
-					// Check for unknown tools BEFORE saving to history to prevent "orphan" tool calls (calls with no response)
-					if part.FunctionCall != nil {
-						funcName := part.FunctionCall.Name
-						if funcName != "" && !IsAvailableOpenTool(funcName) && !IsAvailableMCPTool(funcName, ga.op.mcpClient) {
-							// Skip unknown tools so they don't pollute history and cause 400 errors (Missing function response)
-							util.LogWarnf("Skipping tool call with unknown function name: %s\n", funcName)
-							continue
-						}
-					}
-
 					modelContent.Parts = append(modelContent.Parts, part)
 				}
 
@@ -473,6 +577,9 @@ func (ga *Gemini) processStream(stream iter.Seq2[*genai.GenerateContentResponse,
 				} else if part.Text != "" {
 					// Normal text data
 					ga.op.data <- StreamData{Text: (part.Text), Type: DataTypeNormal}
+				} else if text := geminiCodeExecutionText(part); text != "" {
+					// Code run by the code execution tool, and its output
+					ga.op.data <- StreamData{Text: text, Type: DataTypeNormal}
 				}
 			}
 
@@ -487,15 +594,61 @@ func (ga *Gemini) processStream(stream iter.Seq2[*genai.GenerateContentResponse,
 		// It has usage metadata
 		finalResp = resp
 	}
+	if block != nil {
+		// The filter cut the response short, what was streamed is not kept
+		return modelContent, finalResp, *block
+	}
 	return modelContent, finalResp, nil
 }
 
+// applyGeminiGenerationParams sets the optional generation controls configured on the model.
+func applyGeminiGenerationParams(config *genai.GenerateContentConfig, m *ModelInfo) {
+	if m.MaxTokens > 0 {
+		config.MaxOutputTokens = m.MaxTokens
+	}
+	if len(m.Stop) > 0 {
+		config.StopSequences = m.Stop
+	}
+	config.FrequencyPenalty = m.FrequencyPenalty
+	config.PresencePenalty = m.PresencePenalty
+	config.SafetySettings = geminiSafetySettings(m.SafetySettings)
+}
+
+// partialGeminiContent keeps only the text parts of an interrupted model turn.
+// Function calls are dropped because they would never get a matching response.
+func partialGeminiContent(content *genai.Content) *genai.Content {
+	if content == nil {
+		return nil
+	}
+	partial := &genai.Content{Role: content.Role}
+	for _, part := range content.Parts {
+		if part.FunctionCall == nil && part.Text != "" {
+			partial.Parts = append(partial.Parts, part)
+		}
+	}
+	if len(partial.Parts) == 0 {
+		return nil
+	}
+	return partial
+}
+
 func (ga *Gemini) processToolCall(call *genai.FunctionCall) (*genai.Content, error) {
+	// An invalid call is answered with what is wrong, so the model can fix it
+	if _, problem := ga.op.checkToolCall(call.Name, call.Args); problem != "" {
+		return &genai.Content{
+			Role: genai.RoleUser,
+			Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{
+				ID:       call.ID,
+				Name:     call.Name,
+				Response: map[string]any{"output": problem, "error": problem},
+			}}},
+		}, nil
+	}
 
 	var filteredArgs map[string]interface{}
-	if call.Name == ToolEditFile || call.Name == ToolWriteFile || call.Name == ToolAskUser {
+	if call.Name == ToolEditFile || call.Name == ToolWriteFile || call.Name == ToolApplyChanges || call.Name == ToolAskUser || call.Name == ToolPresentOptions || call.Name == ToolRequestApproval {
 		// Don't show content(the modified content could be too long)
-		filteredArgs = FilterOpenToolArguments(call.Args, []string{"content", "edits", "options", "question_type"})
+		filteredArgs = FilterOpenToolArguments(call.Args, []string{"content", "edits", "changes", "options", "question_type"})
 	} else {
 		filteredArgs = FilterOpenToolArguments(call.Args, []string{})
 	}
@@ -512,7 +665,23 @@ func (ga *Gemini) processToolCall(call *genai.FunctionCall) (*genai.Content, err
 	var resp *genai.FunctionResponse
 	var err error
 	// Dispatch tool call - call.Args is map[string]any which is identical to map[string]interface{}
-	resp, err = ga.op.dispatchGeminiToolCall(call, &call.Args)
+	// The call is part of the history, restored values must not end up in it
+	loopFilter := ga.op.toolLoopFilter(call.Name, call.Args)
+	handoffFilter := ga.op.handoffFilter(call.Name, call.Args)
+	args := ga.op.redactor.restoreToolArgs(call.Name, call.Args)
+	grant := ga.op.beginToolGrant(call.Name, args)
+	audit := ga.op.beginToolAudit(call.Name, args)
+	resp, err = ga.op.dispatchGeminiToolCall(call, &args)
+	audit.end(geminiToolResultText(resp), err)
+	if feedback, ok := grant.end(err); ok {
+		resp, err = mapGeminiToolResult(resp, func(string) string { return feedback }), nil
+		if resp != nil {
+			resp.Response["error"] = ""
+		}
+	}
+	resp = mapGeminiToolResult(resp, ga.op.toolResultFilter(call.Name))
+	resp = mapGeminiToolResult(resp, handoffFilter)
+	resp = mapGeminiToolResult(resp, loopFilter)
 
 	// Function response only has one part
 	respPart := genai.Part{FunctionResponse: resp}
@@ -522,7 +691,7 @@ func (ga *Gemini) processToolCall(call *genai.FunctionCall) (*genai.Content, err
 	}
 
 	// Function call is done
-	ga.op.status.ChangeTo(ga.op.notify, StreamNotify{Status: StatusFunctionCallingOver}, ga.op.proceed)
+	ga.op.status.ChangeTo(ga.op.notify, toolCallOver(geminiToolResultText(resp), err), ga.op.proceed)
 	return respContent, err
 }
 
@@ -584,7 +753,7 @@ func (ga *Gemini) getGeminiWebSearchTool() *genai.Tool {
 }
 
 func (ga *Gemini) getGeminiCodeExecTool() *genai.Tool {
-	// return google embedding search tool
+	// return google code execution tool
 	tool := &genai.Tool{CodeExecution: &genai.ToolCodeExecution{}}
 	return tool
 }
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/anthropics/anthropic-sdk-go"
+	anthropicoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+	"google.golang.org/genai"
+)
+
+// ListProviderModels queries the provider's model listing endpoint and returns the sorted model IDs.
+// Only Endpoint, Key and Provider of the model are used, the provider is detected if not set.
+func ListProviderModels(ctx context.Context, model *data.Model) ([]string, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	mi := constructModelInfo(model)
+
+	var ids []string
+	var err error
+	switch mi.Provider {
+	case ModelProviderGemini:
+		ids, err = listGeminiModels(ctx, mi)
+	case ModelProviderAnthropic:
+		ids, err = listAnthropicModels(ctx, mi)
+	case ModelProviderOpenAI, ModelProviderOpenAICompatible:
+		// OpenAI compatible endpoints commonly expose the same GET /models route
+		ids, err = listOpenAIModels(ctx, mi)
+	default:
+		return nil, fmt.Errorf("unsupported model provider: %s", mi.Provider)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list models from %s: %w", mi.Provider, err)
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func listOpenAIModels(ctx context.Context, mi *ModelInfo) ([]string, error) {
+	opts := []option.RequestOption{option.WithAPIKey(mi.ApiKey)}
+	if mi.EndPoint != "" {
+		opts = append(opts, option.WithBaseURL(mi.EndPoint))
+	}
+	hc, err := providerHTTPClient(mi)
+	if err != nil {
+		return nil, err
+	}
+	if hc != nil {
+		opts = append(opts, option.WithHTTPClient(hc))
+	}
+	client := openai.NewClient(opts...)
+
+	var ids []string
+	iter := client.Models.ListAutoPaging(ctx)
+	for iter.Next() {
+		ids = append(ids, iter.Current().ID)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func listAnthropicModels(ctx context.Context, mi *ModelInfo) ([]string, error) {
+	opts := []anthropicoption.RequestOption{
+		anthropicoption.WithAPIKey(mi.ApiKey),
+		anthropicoption.WithAuthToken(mi.ApiKey),
+	}
+	if mi.EndPoint != "" {
+		opts = append(opts, anthropicoption.WithBaseURL(mi.EndPoint))
+	}
+	hc, err := providerHTTPClient(mi)
+	if err != nil {
+		return nil, err
+	}
+	if hc != nil {
+		opts = append(opts, anthropicoption.WithHTTPClient(hc))
+	}
+	client := anthropic.NewClient(opts...)
+
+	var ids []string
+	iter := client.Models.ListAutoPaging(ctx, anthropic.ModelListParams{})
+	for iter.Next() {
+		ids = append(ids, iter.Current().ID)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func listGeminiModels(ctx context.Context, mi *ModelInfo) ([]string, error) {
+	hc, err := providerHTTPClient(mi)
+	if err != nil {
+		return nil, err
+	}
+	client, err := genai.NewClient(ctx, &genai.ClientConfig{
+		APIKey:      mi.ApiKey,
+		Backend:     genai.BackendGeminiAPI,
+		HTTPClient:  hc,
+		HTTPOptions: genai.HTTPOptions{BaseURL: mi.EndPoint},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for m, err := range client.Models.All(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		// Skip embedding and other models that can't chat
+		if len(m.SupportedActions) > 0 && !slices.Contains(m.SupportedActions, "generateContent") {
+			continue
+		}
+		ids = append(ids, strings.TrimPrefix(m.Name, "models/"))
+	}
+	return ids, nil
+}
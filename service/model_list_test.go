@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestListProviderModels_OpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"object":"list","data":[{"id":"gpt-b","object":"model"},{"id":"gpt-a","object":"model"}]}`))
+	}))
+	defer srv.Close()
+
+	ids, err := ListProviderModels(context.Background(), &data.Model{
+		Provider: ModelProviderOpenAI,
+		Endpoint: srv.URL,
+		Key:      "test",
+	})
+	if err != nil {
+		t.Fatalf("ListProviderModels failed: %v", err)
+	}
+	if want := []string{"gpt-a", "gpt-b"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("expected %v, got %v", want, ids)
+	}
+}
+
+func TestListProviderModels_UnknownProvider(t *testing.T) {
+	if _, err := ListProviderModels(context.Background(), &data.Model{Provider: "nope"}); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
@@ -0,0 +1,339 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/activebook/gllm/util"
+	"gopkg.in/yaml.v3"
+)
+
+/*
+The mock provider replays scripted responses so the agent loop can be exercised
+without a real API. It runs an in-process server that speaks the OpenAI chat
+completions protocol and drives the regular OpenAI processor against it, so tool
+dispatch, session handling, subagents and workflows take the same code paths as
+with a real model.
+
+A model with `provider: mock` picks its script by model ID: either a script
+registered with RegisterMockScript, or a YAML/JSON script file path.
+*/
+
+// MockToolCall is a scripted tool call.
+type MockToolCall struct {
+	Name string                 `json:"name" yaml:"name"`
+	Args map[string]interface{} `json:"args" yaml:"args"`
+}
+
+// MockTurn is one scripted model response, consumed by one request.
+type MockTurn struct {
+	Text         string         `json:"text,omitempty" yaml:"text,omitempty"`
+	Reasoning    string         `json:"reasoning,omitempty" yaml:"reasoning,omitempty"`
+	ToolCalls    []MockToolCall `json:"tool_calls,omitempty" yaml:"tool_calls,omitempty"`
+	Error        string         `json:"error,omitempty" yaml:"error,omitempty"`                   // Respond with an API error instead
+	ChunkSize    int            `json:"chunk_size,omitempty" yaml:"chunk_size,omitempty"`         // Runes per streamed chunk (default: whole text)
+	ChunkDelayMs int            `json:"chunk_delay_ms,omitempty" yaml:"chunk_delay_ms,omitempty"` // Delay between chunks, for slow streams
+	InputTokens  int            `json:"input_tokens,omitempty" yaml:"input_tokens,omitempty"`
+	OutputTokens int            `json:"output_tokens,omitempty" yaml:"output_tokens,omitempty"`
+	FinishReason string         `json:"finish_reason,omitempty" yaml:"finish_reason,omitempty"` // Reported instead of stop or tool_calls, e.g. content_filter
+}
+
+// MockScript is an ordered list of turns.
+// Once exhausted, the mock answers with MockScriptExhausted.
+type MockScript struct {
+	Turns []MockTurn `json:"turns" yaml:"turns"`
+
+	mu       sync.Mutex
+	next     int
+	requests []json.RawMessage
+}
+
+const MockScriptExhausted = "[mock script exhausted]"
+
+// Requests returns the raw request bodies received for this script, in order.
+func (s *MockScript) Requests() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]json.RawMessage(nil), s.requests...)
+}
+
+// nextTurn records the request and returns the next turn.
+func (s *MockScript) nextTurn(body []byte) MockTurn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, json.RawMessage(body))
+	if s.next >= len(s.Turns) {
+		return MockTurn{Text: MockScriptExhausted}
+	}
+	turn := s.Turns[s.next]
+	s.next++
+	return turn
+}
+
+var (
+	mockScripts   = map[string]*MockScript{}
+	mockScriptsMu sync.Mutex
+
+	mockServerURL  string
+	mockServerErr  error
+	mockServerOnce sync.Once
+)
+
+// RegisterMockScript makes a script available to mock models with the given model ID.
+// Registering the same ID again replaces the script.
+func RegisterMockScript(modelID string, script *MockScript) {
+	mockScriptsMu.Lock()
+	defer mockScriptsMu.Unlock()
+	mockScripts[modelID] = script
+}
+
+// lookupMockScript returns the registered script for a model ID, loading it from file on first use.
+func lookupMockScript(modelID string) (*MockScript, error) {
+	mockScriptsMu.Lock()
+	defer mockScriptsMu.Unlock()
+	if script, ok := mockScripts[modelID]; ok {
+		return script, nil
+	}
+
+	content, err := os.ReadFile(modelID)
+	if err != nil {
+		return nil, fmt.Errorf("no mock script registered or found for model '%s'", modelID)
+	}
+	script := &MockScript{}
+	// YAML is a superset of JSON, so both formats parse here
+	if err := yaml.Unmarshal(content, script); err != nil {
+		return nil, fmt.Errorf("failed to parse mock script %s: %w", modelID, err)
+	}
+	mockScripts[modelID] = script
+	return script, nil
+}
+
+// MockEndpoint returns the base URL of the in-process mock server, starting it on first use.
+func MockEndpoint() (string, error) {
+	mockServerOnce.Do(func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			mockServerErr = fmt.Errorf("failed to start mock provider: %w", err)
+			return
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/chat/completions", handleMockCompletion)
+		mux.HandleFunc("/models", handleMockModels)
+		go http.Serve(listener, mux)
+		mockServerURL = "http://" + listener.Addr().String()
+	})
+	return mockServerURL, mockServerErr
+}
+
+// useMockProvider points the model at the mock server, which speaks the OpenAI protocol.
+func useMockProvider(mi *ModelInfo) {
+	endpoint, err := MockEndpoint()
+	if err != nil {
+		util.LogErrorf("%v\n", err)
+	}
+	mi.Provider = ModelProviderOpenAI
+	mi.EndPoint = endpoint
+	mi.ApiKey = "mock"
+}
+
+func handleMockModels(w http.ResponseWriter, r *http.Request) {
+	mockScriptsMu.Lock()
+	var models []map[string]string
+	for id := range mockScripts {
+		models = append(models, map[string]string{"id": id, "object": "model"})
+	}
+	mockScriptsMu.Unlock()
+	writeMockJSON(w, http.StatusOK, map[string]interface{}{"object": "list", "data": models})
+}
+
+func handleMockCompletion(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model  string `json:"model"`
+		Stream bool   `json:"stream"`
+	}
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		writeMockError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	script, err := lookupMockScript(req.Model)
+	if err != nil {
+		writeMockError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	turn := script.nextTurn(body)
+
+	if turn.Error != "" {
+		// 400 rather than 5xx, so the client doesn't retry and consume further turns
+		writeMockError(w, http.StatusBadRequest, turn.Error)
+		return
+	}
+	if req.Stream {
+		streamMockTurn(w, r, req.Model, turn)
+	} else {
+		writeMockJSON(w, http.StatusOK, mockCompletion(req.Model, turn))
+	}
+}
+
+func mockToolCalls(turn MockTurn) []map[string]interface{} {
+	var calls []map[string]interface{}
+	for i, tc := range turn.ToolCalls {
+		args, _ := json.Marshal(tc.Args)
+		if tc.Args == nil {
+			args = []byte("{}")
+		}
+		calls = append(calls, map[string]interface{}{
+			"index": i,
+			"id":    fmt.Sprintf("call_mock_%d_%d", time.Now().UnixNano(), i),
+			"type":  "function",
+			"function": map[string]string{
+				"name":      tc.Name,
+				"arguments": string(args),
+			},
+		})
+	}
+	return calls
+}
+
+func mockUsage(turn MockTurn) map[string]int {
+	return map[string]int{
+		"prompt_tokens":     turn.InputTokens,
+		"completion_tokens": turn.OutputTokens,
+		"total_tokens":      turn.InputTokens + turn.OutputTokens,
+	}
+}
+
+func mockFinishReason(turn MockTurn) string {
+	if turn.FinishReason != "" {
+		return turn.FinishReason
+	}
+	if len(turn.ToolCalls) > 0 {
+		return "tool_calls"
+	}
+	return "stop"
+}
+
+func mockCompletion(model string, turn MockTurn) map[string]interface{} {
+	message := map[string]interface{}{"role": "assistant", "content": turn.Text}
+	if calls := mockToolCalls(turn); len(calls) > 0 {
+		message["tool_calls"] = calls
+	}
+	return map[string]interface{}{
+		"id":      "chatcmpl-mock",
+		"object":  "chat.completion",
+		"created": time.Now().Unix(),
+		"model":   model,
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"message":       message,
+			"finish_reason": mockFinishReason(turn),
+		}},
+		"usage": mockUsage(turn),
+	}
+}
+
+// streamMockTurn writes the turn as server-sent chat completion chunks.
+func streamMockTurn(w http.ResponseWriter, r *http.Request, model string, turn MockTurn) {
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(choices []map[string]interface{}, usage map[string]int) bool {
+		chunk := map[string]interface{}{
+			"id":      "chatcmpl-mock",
+			"object":  "chat.completion.chunk",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": choices,
+		}
+		if usage != nil {
+			chunk["usage"] = usage
+		}
+		data, _ := json.Marshal(chunk)
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+	delta := func(d map[string]interface{}) []map[string]interface{} {
+		return []map[string]interface{}{{"index": 0, "delta": d, "finish_reason": nil}}
+	}
+	pause := func() bool {
+		if turn.ChunkDelayMs <= 0 {
+			return true
+		}
+		select {
+		case <-time.After(time.Duration(turn.ChunkDelayMs) * time.Millisecond):
+			return true
+		case <-r.Context().Done():
+			return false
+		}
+	}
+
+	for _, part := range splitMockText(turn.Reasoning, turn.ChunkSize) {
+		if !pause() || !send(delta(map[string]interface{}{"role": "assistant", "reasoning_content": part}), nil) {
+			return
+		}
+	}
+	for _, part := range splitMockText(turn.Text, turn.ChunkSize) {
+		if !pause() || !send(delta(map[string]interface{}{"role": "assistant", "content": part}), nil) {
+			return
+		}
+	}
+	if calls := mockToolCalls(turn); len(calls) > 0 {
+		if !send(delta(map[string]interface{}{"role": "assistant", "tool_calls": calls}), nil) {
+			return
+		}
+	}
+
+	finish := []map[string]interface{}{{"index": 0, "delta": map[string]interface{}{}, "finish_reason": mockFinishReason(turn)}}
+	if !send(finish, nil) || !send([]map[string]interface{}{}, mockUsage(turn)) {
+		return
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// splitMockText splits text into chunks of size runes, or a single chunk if size <= 0.
+func splitMockText(text string, size int) []string {
+	if text == "" {
+		return nil
+	}
+	runes := []rune(text)
+	if size <= 0 || size >= len(runes) {
+		return []string{text}
+	}
+	var parts []string
+	for i := 0; i < len(runes); i += size {
+		end := min(i+size, len(runes))
+		parts = append(parts, string(runes[i:end]))
+	}
+	return parts
+}
+
+func writeMockJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeMockError(w http.ResponseWriter, status int, message string) {
+	writeMockJSON(w, status, map[string]interface{}{
+		"error": map[string]string{"message": message, "type": "mock_error"},
+	})
+}
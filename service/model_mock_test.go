@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/spf13/viper"
+)
+
+// runMockAgent runs the full agent loop against a registered mock script
+// and returns the text written to the output file.
+func runMockAgent(t *testing.T, ctx context.Context, modelID string, script *MockScript, tools []string) (string, error) {
+	t.Helper()
+	// Keep the audit log and settings away from the real config dir
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	RegisterMockScript(modelID, script)
+	outPath := filepath.Join(t.TempDir(), "out.md")
+
+	err := CallAgent(&AgentOptions{
+		Ctx:           ctx,
+		Prompt:        "hello",
+		ModelInfo:     &data.Model{Provider: ModelProviderMock, Model: modelID},
+		EnabledTools:  tools,
+		MaxRecursions: 5,
+		YoloMode:      true,
+		QuietMode:     true,
+		OutputFile:    outPath,
+		TokenUsage:    NewTokenUsage(),
+	})
+	content, _ := os.ReadFile(outPath)
+	return string(content), err
+}
+
+func TestMockProviderText(t *testing.T) {
+	script := &MockScript{Turns: []MockTurn{
+		{Text: "Hello from the mock.", ChunkSize: 4, InputTokens: 10, OutputTokens: 5},
+	}}
+	out, err := runMockAgent(t, context.Background(), "mock-text", script, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "Hello from the mock.") {
+		t.Errorf("output %q does not contain the scripted text", out)
+	}
+	if got := len(script.Requests()); got != 1 {
+		t.Errorf("expected 1 request, got %d", got)
+	}
+}
+
+func TestMockProviderToolLoop(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "note.txt")
+	if err := os.WriteFile(file, []byte("secret-marker"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	script := &MockScript{Turns: []MockTurn{
+		{ToolCalls: []MockToolCall{{Name: ToolReadFile, Args: map[string]interface{}{"path": file}}}},
+		{Text: "The file says hi."},
+	}}
+	out, err := runMockAgent(t, context.Background(), "mock-tools", script, []string{ToolReadFile})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out, "The file says hi.") {
+		t.Errorf("output %q does not contain the final answer", out)
+	}
+
+	requests := script.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	// The second request must carry the tool result back to the model
+	if !strings.Contains(string(requests[1]), "secret-marker") {
+		t.Errorf("tool result was not sent back to the model: %s", requests[1])
+	}
+
+	// And the call must be in the audit log
+	entries, err := data.QueryAuditEntries(data.AuditFilter{Tool: ToolReadFile})
+	if err != nil {
+		t.Fatalf("failed to query audit log: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Args["path"] != file || !strings.Contains(entries[0].Result, "secret-marker") {
+		t.Errorf("unexpected audit entries: %+v", entries)
+	}
+	if entries[0].Approval != data.AuditApprovalAuto {
+		t.Errorf("expected auto approval in yolo mode, got %s", entries[0].Approval)
+	}
+}
+
+func TestMockProviderError(t *testing.T) {
+	script := &MockScript{Turns: []MockTurn{{Error: "quota exceeded"}}}
+	_, err := runMockAgent(t, context.Background(), "mock-error", script, nil)
+	if err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Fatalf("expected scripted error, got %v", err)
+	}
+}
+
+func TestMockProviderSlowStreamCancel(t *testing.T) {
+	script := &MockScript{Turns: []MockTurn{
+		{Text: strings.Repeat("partial ", 50), ChunkSize: 8, ChunkDelayMs: 20},
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 150*time.Millisecond)
+	defer cancel()
+
+	out, err := runMockAgent(t, ctx, "mock-slow", script, nil)
+	if !IsUserCancelError(err) {
+		t.Fatalf("expected user cancel error, got %v", err)
+	}
+	if !strings.Contains(out, "partial") {
+		t.Errorf("partial output was not kept: %q", out)
+	}
+}
+
+func TestMockScriptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "script.yaml")
+	content := "turns:\n  - text: from file\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	script, err := lookupMockScript(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if turn := script.nextTurn(nil); turn.Text != "from file" {
+		t.Errorf("expected 'from file', got %q", turn.Text)
+	}
+	if turn := script.nextTurn(nil); turn.Text != MockScriptExhausted {
+		t.Errorf("expected exhausted marker, got %q", turn.Text)
+	}
+}
+
+func TestSplitMockText(t *testing.T) {
+	tests := []struct {
+		text string
+		size int
+		want int
+	}{
+		{"", 3, 0},
+		{"abcdef", 0, 1},
+		{"abcdef", 4, 2},
+		{"日本語テキスト", 2, 4},
+	}
+	for _, tt := range tests {
+		if got := len(splitMockText(tt.text, tt.size)); got != tt.want {
+			t.Errorf("splitMockText(%q, %d) = %d chunks, want %d", tt.text, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestMockProviderSubAgentExecutor(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("HOME", configDir)
+	t.Chdir(t.TempDir())
+	t.Cleanup(viper.Reset)
+
+	viper.Reset()
+	viper.Set("models.mock-worker", map[string]interface{}{
+		"provider": ModelProviderMock,
+		"model":    "mock-worker",
+	})
+	if err := data.EnsureAgentsDir(); err != nil {
+		t.Fatal(err)
+	}
+	agentFile := "---\nname: worker\nmodel: mock-worker\nmax_recursions: 3\n---\nYou are a worker.\n"
+	if err := os.WriteFile(filepath.Join(data.GetAgentsDirPath(), "worker.md"), []byte(agentFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// One turn for the task itself, one for compressing its session into shared state
+	RegisterMockScript("mock-worker", &MockScript{Turns: []MockTurn{
+		{Text: "task done"},
+		{Text: "summary of the task"},
+	}})
+
+	state := data.NewSharedState()
+	executor := NewSubAgentExecutor(state, "mock_main", nil, nil, nil)
+	defer executor.Shutdown()
+
+	responses, err := executor.Dispatch(context.Background(), []*SubAgentTask{
+		{CallerAgentName: "orchestrator", AgentName: "worker", TaskKey: "job", Instruction: "Do the job"},
+	})
+	if err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+	if len(responses) != 1 || responses[0].Err != nil {
+		t.Fatalf("unexpected responses: %+v", responses)
+	}
+	if val, ok := state.Get("worker_job"); !ok || val != "summary of the task" {
+		t.Errorf("expected compressed summary in shared state, got %v", val)
+	}
+
+	// The full output is kept on disk, with the task in the manifest of the run
+	runs, err := data.ListArtifactRuns()
+	if err != nil || len(runs) != 1 || len(runs[0].Artifacts) != 1 {
+		t.Fatalf("expected one run with one artifact, got %+v, %v", runs, err)
+	}
+	artifact, content, err := data.ReadArtifact(runs[0].ID, "job")
+	if err != nil || content != "task done" || responses[0].Result.Artifact == "" {
+		t.Fatalf("unexpected artifact %q: %v", content, err)
+	}
+	if artifact.Agent != "worker" || artifact.Caller != "orchestrator" || artifact.Instruction != "Do the job" || artifact.Status != StatusCompleted.String() || runs[0].Session != "mock_main" {
+		t.Errorf("unexpected manifest: %+v", runs[0])
+	}
+}
+
+func TestMockProviderTodoTools(t *testing.T) {
+	todos := []interface{}{
+		map[string]interface{}{"id": 1, "content": "Read the code", "status": data.TodoCompleted},
+		map[string]interface{}{"id": "2", "content": "Fix the bug", "status": data.TodoInProgress},
+	}
+	script := &MockScript{Turns: []MockTurn{
+		{ToolCalls: []MockToolCall{{Name: ToolTodoWrite, Args: map[string]interface{}{"todos": todos}}}},
+		{ToolCalls: []MockToolCall{{Name: ToolTodoRead, Args: map[string]interface{}{}}}},
+		{Text: "done"},
+	}}
+	_, err := runMockAgent(t, context.Background(), "mock-todo", script, []string{ToolTodoWrite, ToolTodoRead})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	items, err := data.LoadTodos(todoFilePath(""))
+	if err != nil || len(items) != 2 || items[0].ID != "1" || items[1].Status != data.TodoInProgress {
+		t.Fatalf("todo list not persisted: %v, %v", items, err)
+	}
+
+	// The read call must see what the write call stored
+	requests := script.Requests()
+	if len(requests) != 3 || !strings.Contains(string(requests[2]), "1/2 completed") {
+		t.Errorf("todo_read result missing from the last request")
+	}
+}
@@ -180,6 +180,13 @@ func (ag *Agent) GenerateOpenAISync(messages []openai.ChatCompletionMessageParam
 	if ag.Model.EndPoint != "" {
 		opts = append(opts, option.WithBaseURL(ag.Model.EndPoint))
 	}
+	hc, err := providerHTTPClient(ag.Model)
+	if err != nil {
+		return "", err
+	}
+	if hc != nil {
+		opts = append(opts, option.WithHTTPClient(hc))
+	}
 	client := openai.NewClient(opts...)
 
 	// Add system prompt
@@ -196,6 +203,8 @@ func (ag *Agent) GenerateOpenAISync(messages []openai.ChatCompletionMessageParam
 		req.TopP = openai.Float(float64(ag.Model.TopP))
 	}
 
+	applyOpenAIGenerationParams(&req, ag.Model)
+
 	if ag.Model.Seed != nil {
 		req.Seed = openai.Int(int64(*ag.Model.Seed))
 	}
@@ -222,6 +231,13 @@ func (ag *Agent) GenerateOpenAIStream() error {
 	if ag.Model.EndPoint != "" {
 		clientOpts = append(clientOpts, option.WithBaseURL(ag.Model.EndPoint))
 	}
+	hc, err := providerHTTPClient(ag.Model)
+	if err != nil {
+		return err
+	}
+	if hc != nil {
+		clientOpts = append(clientOpts, option.WithHTTPClient(hc))
+	}
 	client := openai.NewClient(clientOpts...)
 
 	// Create tools
@@ -240,26 +256,35 @@ func (ag *Agent) GenerateOpenAIStream() error {
 	var executor *SubAgentExecutor
 	if ag.SharedState != nil {
 		executor = NewSubAgentExecutor(ag.SharedState, ag.Session.GetTopSessionName(), ag.StdOutput, ag.FileOutput, ag.SSEOutput)
+		executor.observer = ag.Observer
 		defer executor.Shutdown()
 	}
 
 	op := OpenProcessor{
-		notify:      ag.NotifyChan,
-		data:        ag.DataChan,
-		proceed:     ag.ProceedChan,
-		search:      ag.SearchEngine,
-		toolsUse:    &ag.ToolsUse,
-		interaction: ag.Interaction,
-		quiet:       ag.QuietMode,
-		queries:     make([]string, 0),
-		references:  make([]map[string]interface{}, 0), // Updated to match new field type
-		status:      &ag.Status,
-		mcpClient:   ag.MCPClient,
-		fileHooks:   NewFileHooks(),
+		ctx:            ag.Ctx,
+		notify:         ag.NotifyChan,
+		data:           ag.DataChan,
+		proceed:        ag.ProceedChan,
+		search:         ag.SearchEngine,
+		toolsUse:       &ag.ToolsUse,
+		interaction:    ag.Interaction,
+		quiet:          ag.QuietMode,
+		queries:        make([]string, 0),
+		references:     make([]map[string]interface{}, 0), // Updated to match new field type
+		status:         &ag.Status,
+		mcpClient:      ag.MCPClient,
+		fileHooks:      NewFileHooks(),
+		autoFormat:     ag.AutoFormat,
+		shell:          ag.Shell,
+		network:        ag.Model.Network,
+		redactor:       ag.Redactor,
+		injectionGuard: ag.InjectionGuard,
+		pathRules:      ag.PathRules,
 		// Sub-agent orchestration
 		sharedState: ag.SharedState,
 		executor:    executor,
 		agentName:   ag.AgentName,
+		sessionName: ag.Session.GetName(),
 	}
 	chat := &OpenAI{
 		client: &client,
@@ -268,7 +293,7 @@ func (ag *Agent) GenerateOpenAIStream() error {
 	}
 
 	// Prepare the Messages for Chat Completion
-	err := ag.SortOpenAIMessagesByOrder()
+	err = ag.SortOpenAIMessagesByOrder()
 	if err != nil {
 		return fmt.Errorf("error sorting messages: %v", err)
 	}
@@ -288,6 +313,14 @@ func (ag *Agent) GenerateOpenAIStream() error {
 		if IsUserCancelError(err) {
 			return err
 		}
+		// Safety block, explained by the caller
+		if IsSafetyBlockError(err) {
+			return err
+		}
+		// Stopped by a cost budget
+		if IsBudgetExceededError(err) {
+			return err
+		}
 		return fmt.Errorf("error processing chat: %v", err)
 	}
 	return nil
@@ -308,6 +341,10 @@ func (oa *OpenAI) process(ag *Agent) error {
 	for range ag.MaxRecursions {
 		i++
 		//Debugf("Processing session at times: %d\n", i)
+		// Stop or ask before spending more than the budgets allow
+		if err := ag.checkBudget(); err != nil {
+			return err
+		}
 		oa.op.status.ChangeTo(oa.op.notify, StreamNotify{Status: StatusProcessing}, oa.op.proceed)
 
 		// Get all history messages - MUST be inside loop to pick up newly pushed messages.
@@ -353,6 +390,9 @@ func (oa *OpenAI) process(ag *Agent) error {
 			req.Seed = openai.Int(int64(*ag.Model.Seed))
 		}
 
+		// Add generation controls if configured
+		applyOpenAIGenerationParams(&req, ag.Model)
+
 		// Add reasoning effort if thinking is enabled
 		if effort := ag.ThinkingLevel.ToOpenAIReasoningEffort(); effort != "" {
 			req.ReasoningEffort = openai.ReasoningEffort(effort)
@@ -374,6 +414,17 @@ func (oa *OpenAI) process(ag *Agent) error {
 		assistantMessage, toolCalls, resp, err := oa.processStream(stream)
 		stream.Close() // Bugfix: Close immediately after consuming to release the HTTP connection
 		if err != nil {
+			if cancelErr := checkInterrupted(ag.Ctx); cancelErr != nil {
+				// Interrupted by user, keep the partial reply so the conversation can continue from it
+				if assistantMessage.OfAssistant != nil && assistantMessage.OfAssistant.Content.OfString.Value != "" {
+					oa.saveToSession(ag, assistantMessage)
+				}
+				return cancelErr
+			}
+			if block := safetyBlockFromError(ModelProviderOpenAI, err); block != nil {
+				addUpOpenAITokenUsage(ag, resp)
+				return block
+			}
 			return fmt.Errorf("error processing stream: %v", err)
 		}
 
@@ -390,7 +441,14 @@ func (oa *OpenAI) process(ag *Agent) error {
 		// If there are tool calls, process them
 		if len(toolCalls) > 0 {
 			// Process each tool call
-			for _, toolCall := range toolCalls {
+			for idx, toolCall := range toolCalls {
+				if cancelErr := checkInterrupted(ag.Ctx); cancelErr != nil {
+					// Every tool_call requires a response, mark the pending ones as cancelled
+					for _, pending := range toolCalls[idx:] {
+						oa.saveToSession(ag, openai.ToolMessage(cancelErr.Error(), pending.OfFunction.ID))
+					}
+					return cancelErr
+				}
 				toolMessage, err := oa.processToolCall(toolCall)
 				if err != nil {
 					// Switch agent signal, pop up
@@ -415,6 +473,9 @@ func (oa *OpenAI) process(ag *Agent) error {
 					return err
 				}
 			}
+			if err := oa.op.haltError(); err != nil {
+				return err
+			}
 			// Continue the session recursively
 		} else {
 			// No function call and no model content
@@ -470,6 +531,7 @@ func (oa *OpenAI) processStream(stream *ssestream.Stream[openai.ChatCompletionCh
 	contentBuffer := strings.Builder{}
 	reasoningBuffer := strings.Builder{}
 	lastCallId := ""
+	finishReason := ""
 	var finalResp *openai.ChatCompletionChunk
 
 	for stream.Next() {
@@ -480,6 +542,9 @@ func (oa *OpenAI) processStream(stream *ssestream.Stream[openai.ChatCompletionCh
 		// Handle regular content
 		if len(response.Choices) > 0 {
 			delta := response.Choices[0].Delta
+			if response.Choices[0].FinishReason != "" {
+				finishReason = response.Choices[0].FinishReason
+			}
 
 			// (The official SDK param struct has no reasoning_content field.)
 			// Extract vendor reasoning content (DeepSeek R1 → "reasoning_content",
@@ -509,13 +574,6 @@ func (oa *OpenAI) processStream(stream *ssestream.Stream[openai.ChatCompletionCh
 					id := toolCall.ID
 					functionName := toolCall.Function.Name
 
-					// Skip if not our expected function
-					// Because some model made up function name
-					if functionName != "" && !IsAvailableOpenTool(functionName) && !IsAvailableMCPTool(functionName, oa.op.mcpClient) {
-						util.LogWarnf("Skipping tool call with unknown function name: %s\n", functionName)
-						continue
-					}
-
 					// Handle streaming tool call parts
 					if id == "" && lastCallId != "" {
 						// Continue with previous tool call
@@ -538,13 +596,24 @@ func (oa *OpenAI) processStream(stream *ssestream.Stream[openai.ChatCompletionCh
 						}
 					}
 				}
+				if tc, exists := toolCallsMap[lastCallId]; exists {
+					oa.op.previewToolCall(tc.ID, tc.Name, tc.Arguments)
+				}
 			}
 		}
 	}
 
 	if err := stream.Err(); err != nil {
+		// Return what has been streamed so far, the caller decides whether to keep it
+		if partial := contentBuffer.String(); partial != "" {
+			assistantMessage.Content.OfString = param.NewOpt(partial)
+		}
 		return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistantMessage}, nil, nil, fmt.Errorf("error receiving stream data: %v", err)
 	}
+	if isContentFilterFinish(finishReason) {
+		// The filter cut the response short, what was streamed is not kept
+		return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistantMessage}, nil, finalResp, SafetyBlockError{Provider: ModelProviderOpenAI, Reason: finishReason}
+	}
 
 	// Update the assistant reasoning message
 	reasoningContent := reasoningBuffer.String()
@@ -595,28 +664,39 @@ func (oa *OpenAI) processStream(stream *ssestream.Stream[openai.ChatCompletionCh
 	return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistantMessage}, assistantToolCalls, finalResp, nil
 }
 
+// applyOpenAIGenerationParams sets the optional generation controls configured on the model.
+func applyOpenAIGenerationParams(req *openai.ChatCompletionNewParams, m *ModelInfo) {
+	if m.MaxTokens > 0 {
+		req.MaxCompletionTokens = openai.Int(int64(m.MaxTokens))
+	}
+	if len(m.Stop) > 0 {
+		req.Stop = openai.ChatCompletionNewParamsStopUnion{OfStringArray: m.Stop}
+	}
+	if m.FrequencyPenalty != nil {
+		req.FrequencyPenalty = openai.Float(float64(*m.FrequencyPenalty))
+	}
+	if m.PresencePenalty != nil {
+		req.PresencePenalty = openai.Float(float64(*m.PresencePenalty))
+	}
+}
+
 // processToolCall processes a single tool call and returns a tool response message
 func (oa *OpenAI) processToolCall(toolCall openai.ChatCompletionMessageToolCallUnionParam) (openai.ChatCompletionMessageParamUnion, error) {
-	// Parse the query from the arguments
-	var argsMap map[string]interface{}
 	fnCall := toolCall.GetFunction()
 	if fnCall == nil {
 		return openai.ToolMessage("Error: unsupported tool call type", ""), fmt.Errorf("unsupported tool call type")
 	}
 
-	argsStr := fnCall.Arguments
-	if strings.TrimSpace(argsStr) == "" {
-		argsStr = "{}"
-	}
-
-	if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
-		return openai.ChatCompletionMessageParamUnion{}, fmt.Errorf("error parsing arguments: %v", err)
+	// An invalid call is answered with what is wrong, so the model can fix it
+	argsMap, problem := oa.op.checkToolCall(fnCall.Name, fnCall.Arguments)
+	if problem != "" {
+		return openai.ToolMessage(problem, toolCall.OfFunction.ID), nil
 	}
 
 	var filteredArgs map[string]interface{}
-	if fnCall.Name == ToolEditFile || fnCall.Name == ToolWriteFile || fnCall.Name == ToolAskUser {
+	if fnCall.Name == ToolEditFile || fnCall.Name == ToolWriteFile || fnCall.Name == ToolApplyChanges || fnCall.Name == ToolAskUser || fnCall.Name == ToolPresentOptions || fnCall.Name == ToolRequestApproval {
 		// Don't show content(the modified content could be too long)
-		filteredArgs = FilterOpenToolArguments(argsMap, []string{"content", "edits", "options", "question_type"})
+		filteredArgs = FilterOpenToolArguments(argsMap, []string{"content", "edits", "changes", "options", "question_type"})
 	} else {
 		filteredArgs = FilterOpenToolArguments(argsMap, []string{})
 	}
@@ -643,10 +723,22 @@ func (oa *OpenAI) processToolCall(toolCall openai.ChatCompletionMessageToolCallU
 	var msg openai.ChatCompletionMessageParamUnion
 	var err error
 	// Dispatch tool call
+	loopFilter := oa.op.toolLoopFilter(fnCall.Name, argsMap)
+	handoffFilter := oa.op.handoffFilter(fnCall.Name, argsMap)
+	argsMap = oa.op.redactor.restoreToolArgs(fnCall.Name, argsMap)
+	grant := oa.op.beginToolGrant(fnCall.Name, argsMap)
+	audit := oa.op.beginToolAudit(fnCall.Name, argsMap)
 	msg, err = oa.op.dispatchOpenAIToolCall(toolCallUnion, &argsMap)
+	audit.end(openAIToolResultText(msg), err)
+	if feedback, ok := grant.end(err); ok {
+		msg, err = mapOpenAIToolResult(msg, func(string) string { return feedback }), nil
+	}
+	msg = mapOpenAIToolResult(msg, oa.op.toolResultFilter(fnCall.Name))
+	msg = mapOpenAIToolResult(msg, handoffFilter)
+	msg = mapOpenAIToolResult(msg, loopFilter)
 
 	// Function call is done
-	oa.op.status.ChangeTo(oa.op.notify, StreamNotify{Status: StatusFunctionCallingOver}, oa.op.proceed)
+	oa.op.status.ChangeTo(oa.op.notify, toolCallOver(openAIToolResultText(msg), err), oa.op.proceed)
 	return msg, err
 }
 
@@ -672,6 +764,7 @@ func addUpOpenAITokenUsage(ag *Agent, resp *openai.ChatCompletionChunk) {
 			int(thoughtTokens),
 			int(usage.TotalTokens))
 	}
+	ag.addUpOpenRouterUsage()
 }
 
 // getOpenAITools returns the tools for OpenAI
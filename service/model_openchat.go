@@ -136,11 +136,11 @@ func (ag *Agent) GenerateOpenChatSync(messages []*model.ChatCompletionMessage, s
 	if ag.Ctx == nil {
 		ag.Ctx = context.Background()
 	}
-	client := arkruntime.NewClientWithApiKey(
-		ag.Model.ApiKey,
-		arkruntime.WithTimeout(30*time.Minute),
-		arkruntime.WithBaseUrl(ag.Model.EndPoint),
-	)
+	clientOpts, err := openChatClientOptions(ag.Model)
+	if err != nil {
+		return "", err
+	}
+	client := arkruntime.NewClientWithApiKey(ag.Model.ApiKey, clientOpts...)
 
 	// Add system prompt
 	messages = append([]*model.ChatCompletionMessage{{
@@ -157,6 +157,7 @@ func (ag *Agent) GenerateOpenChatSync(messages []*model.ChatCompletionMessage, s
 		TopP:        &ag.Model.TopP,
 		Messages:    messages,
 	}
+	applyOpenChatGenerationParams(&req, ag.Model)
 
 	resp, err := client.CreateChatCompletion(ag.Ctx, req)
 	if err != nil {
@@ -170,16 +171,33 @@ func (ag *Agent) GenerateOpenChatSync(messages []*model.ChatCompletionMessage, s
 	return *resp.Choices[0].Message.Content.StringValue, nil
 }
 
+// openChatClientOptions returns the Volcengine client options for the model.
+func openChatClientOptions(mi *ModelInfo) ([]arkruntime.ConfigOption, error) {
+	var opts []arkruntime.ConfigOption
+	hc, err := providerHTTPClient(mi)
+	if err != nil {
+		return nil, err
+	}
+	if hc != nil {
+		// Must come before the timeout, which is set on the client
+		opts = append(opts, arkruntime.WithHTTPClient(hc))
+	}
+	return append(opts,
+		arkruntime.WithTimeout(30*time.Minute),
+		arkruntime.WithBaseUrl(mi.EndPoint),
+	), nil
+}
+
 // In current openchat api, we can't use cached tokens
 // The context api and response api are not available for current golang lib
 func (ag *Agent) GenerateOpenChatStream() error {
 	// Initialize the Client
 	// Create a client config with custom base URL
-	client := arkruntime.NewClientWithApiKey(
-		ag.Model.ApiKey,
-		arkruntime.WithTimeout(30*time.Minute),
-		arkruntime.WithBaseUrl(ag.Model.EndPoint),
-	)
+	clientOpts, err := openChatClientOptions(ag.Model)
+	if err != nil {
+		return err
+	}
+	client := arkruntime.NewClientWithApiKey(ag.Model.ApiKey, clientOpts...)
 
 	// Create a tool with the function
 	tools := []*model.Tool{}
@@ -197,26 +215,35 @@ func (ag *Agent) GenerateOpenChatStream() error {
 	var executor *SubAgentExecutor
 	if ag.SharedState != nil {
 		executor = NewSubAgentExecutor(ag.SharedState, ag.Session.GetTopSessionName(), ag.StdOutput, ag.FileOutput, ag.SSEOutput)
+		executor.observer = ag.Observer
 		defer executor.Shutdown()
 	}
 
 	op := OpenProcessor{
-		notify:      ag.NotifyChan,
-		data:        ag.DataChan,
-		proceed:     ag.ProceedChan,
-		search:      ag.SearchEngine,
-		toolsUse:    &ag.ToolsUse,
-		interaction: ag.Interaction,
-		quiet:       ag.QuietMode,
-		queries:     make([]string, 0),
-		references:  make([]map[string]interface{}, 0), // Updated to match new field type
-		status:      &ag.Status,
-		mcpClient:   ag.MCPClient,
-		fileHooks:   NewFileHooks(),
+		ctx:            ag.Ctx,
+		notify:         ag.NotifyChan,
+		data:           ag.DataChan,
+		proceed:        ag.ProceedChan,
+		search:         ag.SearchEngine,
+		toolsUse:       &ag.ToolsUse,
+		interaction:    ag.Interaction,
+		quiet:          ag.QuietMode,
+		queries:        make([]string, 0),
+		references:     make([]map[string]interface{}, 0), // Updated to match new field type
+		status:         &ag.Status,
+		mcpClient:      ag.MCPClient,
+		fileHooks:      NewFileHooks(),
+		autoFormat:     ag.AutoFormat,
+		shell:          ag.Shell,
+		network:        ag.Model.Network,
+		redactor:       ag.Redactor,
+		injectionGuard: ag.InjectionGuard,
+		pathRules:      ag.PathRules,
 		// Sub-agent orchestration
 		sharedState: ag.SharedState,
 		executor:    executor,
 		agentName:   ag.AgentName,
+		sessionName: ag.Session.GetName(),
 	}
 	chat := &OpenChat{
 		client: client,
@@ -225,7 +252,7 @@ func (ag *Agent) GenerateOpenChatStream() error {
 	}
 
 	// Prepare the Messages for Chat Completion
-	err := ag.SortOpenChatMessagesByOrder()
+	err = ag.SortOpenChatMessagesByOrder()
 	if err != nil {
 		return fmt.Errorf("error sorting messages: %v", err)
 	}
@@ -245,6 +272,14 @@ func (ag *Agent) GenerateOpenChatStream() error {
 		if IsUserCancelError(err) {
 			return err
 		}
+		// Safety block, explained by the caller
+		if IsSafetyBlockError(err) {
+			return err
+		}
+		// Stopped by a cost budget
+		if IsBudgetExceededError(err) {
+			return err
+		}
 		return fmt.Errorf("error processing chat: %v", err)
 	}
 	return nil
@@ -264,6 +299,10 @@ func (c *OpenChat) process(ag *Agent) error {
 	for range ag.MaxRecursions {
 		i++
 		//Debugf("Processing session at times: %d\n", i)
+		// Stop or ask before spending more than the budgets allow
+		if err := ag.checkBudget(); err != nil {
+			return err
+		}
 		c.op.status.ChangeTo(c.op.notify, StreamNotify{Status: StatusProcessing}, c.op.proceed)
 
 		// Note on Thinking Mode:
@@ -312,6 +351,9 @@ func (c *OpenChat) process(ag *Agent) error {
 			ReasoningEffort: reasoningEffort,
 		}
 
+		// Add generation controls if configured
+		applyOpenChatGenerationParams(&req, ag.Model)
+
 		// Include token usage if tracking is enabled
 		if ag.TokenUsage != nil {
 			req.StreamOptions = &model.StreamOptions{IncludeUsage: true}
@@ -320,6 +362,12 @@ func (c *OpenChat) process(ag *Agent) error {
 		// Make the streaming request
 		stream, err := c.client.CreateChatCompletionStream(ag.Ctx, req)
 		if err != nil {
+			if cancelErr := checkInterrupted(ag.Ctx); cancelErr != nil {
+				return cancelErr
+			}
+			if block := safetyBlockFromError(ModelProviderOpenAICompatible, err); block != nil {
+				return block
+			}
 			// Try to extract detailed API error information
 			var apiErr *model.APIError
 			if errors.As(err, &apiErr) {
@@ -349,6 +397,17 @@ func (c *OpenChat) process(ag *Agent) error {
 		assistantMessage, toolCalls, resp, err := c.processStream(stream)
 		stream.Close() // Bugfix: Close immediately after consuming to release the HTTP connection
 		if err != nil {
+			if cancelErr := checkInterrupted(ag.Ctx); cancelErr != nil {
+				// Interrupted by user, keep the partial reply so the conversation can continue from it
+				if assistantMessage != nil {
+					c.saveToSession(ag, assistantMessage)
+				}
+				return cancelErr
+			}
+			if block := safetyBlockFromError(ModelProviderOpenAICompatible, err); block != nil {
+				ag.addUpOpenChatTokenUsage(resp)
+				return block
+			}
 			return fmt.Errorf("error processing stream: %v", err)
 		}
 
@@ -365,7 +424,25 @@ func (c *OpenChat) process(ag *Agent) error {
 		// If there are tool calls, process them
 		if len(*toolCalls) > 0 {
 			// Process each tool call
+			handled := make(map[string]bool, len(*toolCalls))
 			for _, toolCall := range *toolCalls {
+				if cancelErr := checkInterrupted(ag.Ctx); cancelErr != nil {
+					// Every tool_call requires a response, mark the pending ones as cancelled
+					for id := range *toolCalls {
+						if !handled[id] {
+							c.saveToSession(ag, &model.ChatCompletionMessage{
+								Role:       model.ChatMessageRoleTool,
+								ToolCallID: id,
+								Name:       Ptr(""),
+								Content: &model.ChatCompletionMessageContent{
+									StringValue: volcengine.String(cancelErr.Error()),
+								},
+							})
+						}
+					}
+					return cancelErr
+				}
+				handled[toolCall.ID] = true
 				toolMessage, err := c.processToolCall(toolCall)
 				if err != nil {
 					// Switch agent signal, pop up
@@ -390,6 +467,9 @@ func (c *OpenChat) process(ag *Agent) error {
 					return err
 				}
 			}
+			if err := c.op.haltError(); err != nil {
+				return err
+			}
 			// Continue the session recursively
 		} else {
 			// No function call and no model content
@@ -436,6 +516,19 @@ func (c *OpenChat) saveToSession(ag *Agent, message *model.ChatCompletionMessage
 	return nil
 }
 
+// applyOpenChatGenerationParams sets the optional generation controls configured on the model.
+func applyOpenChatGenerationParams(req *model.CreateChatCompletionRequest, m *ModelInfo) {
+	if m.MaxTokens > 0 {
+		maxTokens := int(m.MaxTokens)
+		req.MaxTokens = &maxTokens
+	}
+	if len(m.Stop) > 0 {
+		req.Stop = m.Stop
+	}
+	req.FrequencyPenalty = m.FrequencyPenalty
+	req.PresencePenalty = m.PresencePenalty
+}
+
 // processStream processes the stream and collects tool calls
 func (c *OpenChat) processStream(stream *utils.ChatCompletionStreamReader) (*model.ChatCompletionMessage, *map[string]model.ToolCall, *model.ChatCompletionStreamResponse, error) {
 	assistantMessage := model.ChatCompletionMessage{
@@ -447,6 +540,7 @@ func (c *OpenChat) processStream(stream *utils.ChatCompletionStreamReader) (*mod
 	contentBuffer := strings.Builder{}
 	reasoningBuffer := strings.Builder{}
 	lastCallId := ""
+	var block *SafetyBlockError
 	var finalResp *model.ChatCompletionStreamResponse
 
 	for {
@@ -455,7 +549,15 @@ func (c *OpenChat) processStream(stream *utils.ChatCompletionStreamReader) (*mod
 			break
 		}
 		if err != nil {
-			return nil, nil, nil, fmt.Errorf("error receiving stream data: %v", err)
+			// Return what has been streamed so far, the caller decides whether to keep it
+			var partial *model.ChatCompletionMessage
+			if content := contentBuffer.String(); content != "" {
+				assistantMessage.Content = &model.ChatCompletionMessageContent{
+					StringValue: volcengine.String(content),
+				}
+				partial = &assistantMessage
+			}
+			return partial, nil, nil, fmt.Errorf("error receiving stream data: %v", err)
 		}
 		// Get the final response
 		finalResp = &response
@@ -463,6 +565,12 @@ func (c *OpenChat) processStream(stream *utils.ChatCompletionStreamReader) (*mod
 		// Handle regular content
 		if len(response.Choices) > 0 {
 			delta := (response.Choices[0].Delta)
+			if reason := string(response.Choices[0].FinishReason); isContentFilterFinish(reason) {
+				block = &SafetyBlockError{Provider: ModelProviderOpenAICompatible, Reason: reason}
+				if hit := response.Choices[0].ModerationHitType; hit != nil {
+					block.Categories = []string{string(*hit)}
+				}
+			}
 
 			if util.HasContent(delta.ReasoningContent) {
 				text := *delta.ReasoningContent
@@ -490,13 +598,6 @@ func (c *OpenChat) processStream(stream *utils.ChatCompletionStreamReader) (*mod
 					id := toolCall.ID
 					functionName := toolCall.Function.Name
 
-					// Skip if not our expected function
-					// Because some model made up function name
-					if functionName != "" && !IsAvailableOpenTool(functionName) && !IsAvailableMCPTool(functionName, c.op.mcpClient) {
-						util.LogWarnf("Skipping tool call with unknown function name: %s\n", functionName)
-						continue
-					}
-
 					// Handle streaming tool call parts
 					if id == "" && lastCallId != "" {
 						// Continue with previous tool call
@@ -524,10 +625,18 @@ func (c *OpenChat) processStream(stream *utils.ChatCompletionStreamReader) (*mod
 						}
 					}
 				}
+				if tc, exists := toolCalls[lastCallId]; exists {
+					c.op.previewToolCall(tc.ID, tc.Function.Name, tc.Function.Arguments)
+				}
 			}
 		}
 	}
 
+	if block != nil {
+		// The filter cut the response short, what was streamed is not kept
+		return nil, nil, finalResp, *block
+	}
+
 	// Update the assistant reasoning message
 	reasoningContent := reasoningBuffer.String()
 	if reasoningContent != "" {
@@ -583,23 +692,22 @@ func (c *OpenChat) processStream(stream *utils.ChatCompletionStreamReader) (*mod
 
 // processToolCall processes a single tool call and returns a tool response message
 func (c *OpenChat) processToolCall(toolCall model.ToolCall) (*model.ChatCompletionMessage, error) {
-	// Parse the query from the arguments
-	var argsMap map[string]interface{}
-	argsStr := toolCall.Function.Arguments
-	if strings.TrimSpace(argsStr) == "" {
-		argsStr = "{}"
-	}
-
-	if err := json.Unmarshal([]byte(argsStr), &argsMap); err != nil {
-		// Log the malformed JSON for debugging
-		util.LogDebugf("Failed to parse tool call arguments. Function: %s, Raw arguments: %s\n", toolCall.Function.Name, toolCall.Function.Arguments)
-		return nil, fmt.Errorf("error parsing arguments: %v (raw: %s)", err, toolCall.Function.Arguments)
+	// An invalid call is answered with what is wrong, so the model can fix it
+	argsMap, problem := c.op.checkToolCall(toolCall.Function.Name, toolCall.Function.Arguments)
+	if problem != "" {
+		util.LogDebugf("Invalid tool call. Function: %s, Raw arguments: %s\n", toolCall.Function.Name, toolCall.Function.Arguments)
+		return &model.ChatCompletionMessage{
+			Role:       model.ChatMessageRoleTool,
+			ToolCallID: toolCall.ID,
+			Name:       Ptr(""),
+			Content:    &model.ChatCompletionMessageContent{StringValue: volcengine.String(problem)},
+		}, nil
 	}
 
 	var filteredArgs map[string]interface{}
-	if toolCall.Function.Name == ToolEditFile || toolCall.Function.Name == ToolWriteFile || toolCall.Function.Name == ToolAskUser {
+	if toolCall.Function.Name == ToolEditFile || toolCall.Function.Name == ToolWriteFile || toolCall.Function.Name == ToolApplyChanges || toolCall.Function.Name == ToolAskUser || toolCall.Function.Name == ToolPresentOptions || toolCall.Function.Name == ToolRequestApproval {
 		// Don't show content(the modified content could be too long)
-		filteredArgs = FilterOpenToolArguments(argsMap, []string{"content", "edits", "options", "question_type"})
+		filteredArgs = FilterOpenToolArguments(argsMap, []string{"content", "edits", "changes", "options", "question_type"})
 	} else {
 		filteredArgs = FilterOpenToolArguments(argsMap, []string{})
 	}
@@ -616,10 +724,22 @@ func (c *OpenChat) processToolCall(toolCall model.ToolCall) (*model.ChatCompleti
 	var msg *model.ChatCompletionMessage
 	var err error
 	// Dispatch tool call
+	loopFilter := c.op.toolLoopFilter(toolCall.Function.Name, argsMap)
+	handoffFilter := c.op.handoffFilter(toolCall.Function.Name, argsMap)
+	argsMap = c.op.redactor.restoreToolArgs(toolCall.Function.Name, argsMap)
+	grant := c.op.beginToolGrant(toolCall.Function.Name, argsMap)
+	audit := c.op.beginToolAudit(toolCall.Function.Name, argsMap)
 	msg, err = c.op.dispatchOpenChatToolCall(&toolCall, &argsMap)
+	audit.end(openChatToolResultText(msg), err)
+	if feedback, ok := grant.end(err); ok {
+		msg, err = mapOpenChatToolResult(msg, func(string) string { return feedback }), nil
+	}
+	msg = mapOpenChatToolResult(msg, c.op.toolResultFilter(toolCall.Function.Name))
+	msg = mapOpenChatToolResult(msg, handoffFilter)
+	msg = mapOpenChatToolResult(msg, loopFilter)
 
 	// Function call is done
-	c.op.status.ChangeTo(c.op.notify, StreamNotify{Status: StatusFunctionCallingOver}, c.op.proceed)
+	c.op.status.ChangeTo(c.op.notify, toolCallOver(openChatToolResultText(msg), err), c.op.proceed)
 	return msg, err
 }
 
@@ -641,6 +761,7 @@ func (ag *Agent) addUpOpenChatTokenUsage(resp *model.ChatCompletionStreamRespons
 			int(resp.Usage.CompletionTokensDetails.ReasoningTokens),
 			int(resp.Usage.TotalTokens))
 	}
+	ag.addUpOpenRouterUsage()
 }
 
 func (ag *Agent) getOpenChatTools() []*model.Tool {
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/internal/event"
+	"github.com/activebook/gllm/util"
+)
+
+// notifyCommandTimeout bounds the desktop tool, a notification must never hold up the session.
+const notifyCommandTimeout = 5 * time.Second
+
+// SendDesktopNotification shows a notification with the tool of the desktop:
+// osascript on macOS, notify-send on Linux and a toast through PowerShell on Windows.
+func SendDesktopNotification(title, message string) error {
+	var name string
+	var args []string
+	switch runtime.GOOS {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptQuote(message), appleScriptQuote(title))
+		name, args = "osascript", []string{"-e", script}
+	case "windows":
+		name, args = "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", windowsToastScript(title, message)}
+	default:
+		name, args = "notify-send", []string{"--app-name=gllm", title, message}
+	}
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("desktop notifications need %s: %w", name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyCommandTimeout)
+	defer cancel()
+	if out, err := exec.CommandContext(ctx, name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %v %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func appleScriptQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func windowsToastScript(title, message string) string {
+	quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", "''") + "'" }
+	return strings.Join([]string{
+		"[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null",
+		"$t = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)",
+		"$x = $t.GetElementsByTagName('text')",
+		"$x.Item(0).AppendChild($t.CreateTextNode(" + quote(title) + ")) > $null",
+		"$x.Item(1).AppendChild($t.CreateTextNode(" + quote(message) + ")) > $null",
+		"[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('gllm').Show([Windows.UI.Notifications.ToastNotification]::new($t))",
+	}, "; ")
+}
+
+// ParseNotifyThreshold parses the notify threshold, a duration such as "30s" or a number of seconds.
+// Empty, "0" and "off" disable notifications.
+func ParseNotifyThreshold(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	switch s {
+	case "", "0", "off", "false", "disable":
+		return 0, nil
+	}
+	if seconds, err := strconv.Atoi(s); err == nil {
+		s = fmt.Sprintf("%ds", seconds)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid notify threshold '%s', expected a duration such as 30s or 2m", s)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("notify threshold must not be negative")
+	}
+	return d, nil
+}
+
+// NotifyTimer times a generation and notifies the desktop when it ran longer than the
+// threshold and finishes, or stops to wait for the user. A nil timer does nothing.
+type NotifyTimer struct {
+	threshold time.Duration
+	start     time.Time
+	send      func(title, message string) error
+}
+
+// NewNotifyTimer starts timing a generation, it returns nil when the threshold disables notifications.
+func NewNotifyTimer(threshold time.Duration) *NotifyTimer {
+	if threshold <= 0 {
+		return nil
+	}
+	return &NotifyTimer{threshold: threshold, start: time.Now(), send: SendDesktopNotification}
+}
+
+// NewNotifyTimerFromSettings starts a timer with the threshold of the settings.
+func NewNotifyTimerFromSettings(settings data.NotifySettings) (*NotifyTimer, error) {
+	threshold, err := ParseNotifyThreshold(settings.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	return NewNotifyTimer(threshold), nil
+}
+
+func (t *NotifyTimer) elapsed() (time.Duration, bool) {
+	if t == nil {
+		return 0, false
+	}
+	d := time.Since(t.start)
+	return d, d >= t.threshold
+}
+
+func (t *NotifyTimer) notify(title, message string) {
+	if err := t.send(title, message); err != nil {
+		util.LogDebugf("Desktop notification failed: %v\n", err)
+	}
+}
+
+// Done notifies that the generation of the agent finished or failed. Cancelling it does not notify,
+// the user is already there.
+func (t *NotifyTimer) Done(agent string, err error) {
+	d, ok := t.elapsed()
+	if !ok || IsUserCancelError(err) {
+		return
+	}
+	d = d.Round(time.Second)
+	if err != nil {
+		t.notify("gllm failed", fmt.Sprintf("%s stopped after %s: %v", agent, d, err))
+		return
+	}
+	t.notify("gllm finished", fmt.Sprintf("%s finished in %s", agent, d))
+}
+
+// Wrap returns the handler notifying when a long generation waits for a confirmation or an answer.
+func (t *NotifyTimer) Wrap(next InteractionHandler) InteractionHandler {
+	if t == nil || next == nil {
+		return next
+	}
+	return notifyingInteraction{InteractionHandler: next, timer: t}
+}
+
+// notifyingInteraction notifies before asking the user.
+type notifyingInteraction struct {
+	InteractionHandler
+	timer *NotifyTimer
+}
+
+func (h notifyingInteraction) RequestConfirm(description string, toolsUse *data.ToolsUse) {
+	if _, ok := h.timer.elapsed(); ok && !toolsUse.AutoApprove {
+		message := "A tool call needs your confirmation"
+		if toolsUse.Tool != "" {
+			message = fmt.Sprintf("%s needs your confirmation", toolsUse.Tool)
+		}
+		h.timer.notify("gllm is waiting", message)
+	}
+	h.InteractionHandler.RequestConfirm(description, toolsUse)
+}
+
+func (h notifyingInteraction) RequestAskUser(req event.AskUserRequest) (event.AskUserResponse, error) {
+	if _, ok := h.timer.elapsed(); ok {
+		h.timer.notify("gllm is waiting", req.Question)
+	}
+	return h.InteractionHandler.RequestAskUser(req)
+}
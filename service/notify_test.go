@@ -0,0 +1,71 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestParseNotifyThreshold(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"off", 0, false},
+		{"30s", 30 * time.Second, false},
+		{"45", 45 * time.Second, false},
+		{"2m", 2 * time.Minute, false},
+		{"-5s", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseNotifyThreshold(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseNotifyThreshold(%q) = %v, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestNotifyTimer(t *testing.T) {
+	if NewNotifyTimer(0) != nil {
+		t.Fatal("a zero threshold must disable the timer")
+	}
+	var nilTimer *NotifyTimer
+	nilTimer.Done("coder", nil) // Must not panic
+	if h := nilTimer.Wrap(HeadlessInteractionHandler{}); h != (HeadlessInteractionHandler{}) {
+		t.Error("a disabled timer must not wrap the handler")
+	}
+
+	var sent []string
+	timer := NewNotifyTimer(time.Minute)
+	timer.send = func(title, message string) error {
+		sent = append(sent, title+": "+message)
+		return nil
+	}
+
+	// Short generations don't notify
+	timer.Done("coder", nil)
+	timer.Wrap(HeadlessInteractionHandler{}).RequestConfirm("write main.go", &data.ToolsUse{Tool: ToolWriteFile})
+	if len(sent) != 0 {
+		t.Fatalf("notified before the threshold: %q", sent)
+	}
+
+	timer.start = time.Now().Add(-2 * time.Minute)
+	timer.Wrap(HeadlessInteractionHandler{}).RequestConfirm("write main.go", &data.ToolsUse{Tool: ToolWriteFile})
+	timer.Wrap(HeadlessInteractionHandler{}).RequestConfirm("read main.go", &data.ToolsUse{Tool: ToolReadFile, AutoApprove: true})
+	timer.Done("coder", UserCancelError{Reason: UserCancelReasonDeny})
+	timer.Done("coder", fmt.Errorf("rate limited"))
+	timer.Done("coder", nil)
+	want := []string{
+		"gllm is waiting: write_file needs your confirmation",
+		"gllm failed: coder stopped after 2m0s: rate limited",
+		"gllm finished: coder finished in 2m0s",
+	}
+	if fmt.Sprint(sent) != fmt.Sprint(want) {
+		t.Errorf("notifications = %q, want %q", sent, want)
+	}
+}
@@ -0,0 +1,567 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	maxAPIToolName        = 64
+	maxAPIToolDescription = 1000
+	maxAPISchemaDepth     = 6 // Nested $refs are inlined this deep, cycles end there
+	maxAPISpecBytes       = 20 * 1024 * 1024
+)
+
+var (
+	apiNamePattern     = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_-]*$`)
+	apiToolNameInvalid = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	apiMethods         = []string{"get", "put", "post", "delete", "patch", "head", "options"}
+	// apiSchemaKeys are the JSON schema keywords kept in tool parameters, the providers reject others
+	apiSchemaKeys = []string{"type", "description", "enum", "format", "default", "minimum", "maximum", "pattern"}
+)
+
+// ValidateAPIName checks that an API name can prefix tool names.
+func ValidateAPIName(name string) error {
+	if !apiNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid API name '%s', use letters, digits, _ and -, starting with a letter", name)
+	}
+	return nil
+}
+
+// ReadOpenAPISpec reads a spec from a file or an http(s) URL.
+func ReadOpenAPISpec(ctx context.Context, source string) ([]byte, error) {
+	if !strings.HasPrefix(source, "https://") && !strings.HasPrefix(source, "http://") {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the spec: %w", err)
+		}
+		return content, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download the spec: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download the spec: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxAPISpecBytes))
+}
+
+// ImportOpenAPI converts the operations of an OpenAPI 3 or Swagger 2 spec, JSON or YAML, into an
+// API definition whose operations are tools. baseURL overrides the server of the spec; a relative
+// server is resolved against source when source is a URL.
+func ImportOpenAPI(content []byte, name, source, baseURL string) (*data.APIDefinition, error) {
+	if err := ValidateAPIName(name); err != nil {
+		return nil, err
+	}
+	var raw interface{}
+	if err := yaml.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse the spec: %w", err)
+	}
+	root, ok := normalizeYAML(raw).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the spec is not an OpenAPI document")
+	}
+	_, isV3 := root["openapi"]
+	_, isV2 := root["swagger"]
+	if !isV3 && !isV2 {
+		return nil, fmt.Errorf("the spec has neither an openapi nor a swagger version")
+	}
+
+	api := &data.APIDefinition{Name: name, Source: source}
+	if info, ok := root["info"].(map[string]interface{}); ok {
+		api.Title, _ = info["title"].(string)
+	}
+	if baseURL == "" {
+		baseURL = specBaseURL(root, isV3, source)
+	}
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("the spec has no absolute server URL, pass one with --base-url")
+	}
+	api.BaseURL = strings.TrimSuffix(baseURL, "/")
+
+	paths, _ := root["paths"].(map[string]interface{})
+	pathKeys := make([]string, 0, len(paths))
+	for p := range paths {
+		pathKeys = append(pathKeys, p)
+	}
+	sort.Strings(pathKeys)
+
+	used := map[string]bool{}
+	for _, p := range pathKeys {
+		item, ok := resolveRef(root, paths[p]).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		shared, _ := item["parameters"].([]interface{})
+		for _, method := range apiMethods {
+			opSpec, ok := item[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			op := data.APIOperation{Method: strings.ToUpper(method), Path: p}
+			op.Tool = apiToolName(name, opSpec, method, p, used)
+
+			summary, _ := opSpec["summary"].(string)
+			desc, _ := opSpec["description"].(string)
+			if summary == "" {
+				summary = desc
+			} else if desc != "" && desc != summary {
+				summary += ". " + desc
+			}
+			op.Description = util.TruncateString(strings.TrimSpace(fmt.Sprintf("%s %s of the %s API. %s", op.Method, p, name, summary)), maxAPIToolDescription)
+
+			// Parameters of the operation override those of the path
+			params := map[string]data.APIParam{}
+			var order []string
+			own, _ := opSpec["parameters"].([]interface{})
+			for _, list := range [][]interface{}{shared, own} {
+				for _, ps := range list {
+					param, ok := resolveRef(root, ps).(map[string]interface{})
+					if !ok {
+						continue
+					}
+					pname, _ := param["name"].(string)
+					in, _ := param["in"].(string)
+					required, _ := param["required"].(bool)
+					switch in {
+					case "path", "query", "header":
+						schema := paramSchema(root, param)
+						if d, ok := param["description"].(string); ok && d != "" {
+							schema["description"] = util.TruncateString(d, maxAPIToolDescription)
+						}
+						key := in + ":" + pname
+						if _, seen := params[key]; !seen {
+							order = append(order, key)
+						}
+						params[key] = data.APIParam{Name: pname, In: in, Required: required || in == "path", Schema: schema}
+					case "body":
+						// Swagger 2 bodies are parameters
+						op.Body = apiSchema(root, param["schema"], 0)
+						op.BodyType = "application/json"
+						op.BodyNeeded = required
+					}
+				}
+			}
+			for _, key := range order {
+				op.Params = append(op.Params, params[key])
+			}
+			if body, ok := resolveRef(root, opSpec["requestBody"]).(map[string]interface{}); ok {
+				op.BodyType, op.Body = requestBodySchema(root, body)
+				op.BodyNeeded, _ = body["required"].(bool)
+			}
+			api.Operations = append(api.Operations, op)
+		}
+	}
+	if len(api.Operations) == 0 {
+		return nil, fmt.Errorf("the spec has no operations")
+	}
+	return api, nil
+}
+
+// CheckAPIProfile checks that the auth profile of an API exists and covers its base URL.
+func CheckAPIProfile(api *data.APIDefinition) error {
+	if api.Profile == "" {
+		return nil
+	}
+	profile, ok := data.GetSettingsStore().GetHTTPProfiles()[api.Profile]
+	if !ok {
+		return fmt.Errorf("auth profile '%s' not found, add it with 'gllm config http add %s'", api.Profile, api.Profile)
+	}
+	if !httpProfileAllows(profile.URL, api.BaseURL) {
+		return fmt.Errorf("auth profile '%s' is only sent to %s, not to %s", api.Profile, profile.URL, api.BaseURL)
+	}
+	return nil
+}
+
+// normalizeYAML turns the map[interface{}]interface{} of YAML, e.g. of response codes, into
+// map[string]interface{}.
+func normalizeYAML(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, item := range t {
+			t[k] = normalizeYAML(item)
+		}
+		return t
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, item := range t {
+			m[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range t {
+			t[i] = normalizeYAML(item)
+		}
+		return t
+	}
+	return v
+}
+
+// specBaseURL finds the server of a spec: the first server of OpenAPI 3 with its variables at
+// their defaults, or the scheme, host and basePath of Swagger 2.
+func specBaseURL(root map[string]interface{}, isV3 bool, source string) string {
+	var base string
+	if isV3 {
+		servers, _ := root["servers"].([]interface{})
+		if len(servers) == 0 {
+			return ""
+		}
+		server, _ := servers[0].(map[string]interface{})
+		base, _ = server["url"].(string)
+		vars, _ := server["variables"].(map[string]interface{})
+		for k, v := range vars {
+			if variable, ok := v.(map[string]interface{}); ok {
+				base = strings.ReplaceAll(base, "{"+k+"}", fmt.Sprint(variable["default"]))
+			}
+		}
+	} else {
+		host, _ := root["host"].(string)
+		basePath, _ := root["basePath"].(string)
+		scheme := "https"
+		if schemes, ok := root["schemes"].([]interface{}); ok && len(schemes) > 0 && !containsValue(schemes, "https") {
+			scheme = fmt.Sprint(schemes[0])
+		}
+		if host == "" {
+			base = basePath
+		} else {
+			base = scheme + "://" + host + basePath
+		}
+	}
+	// A relative server is relative to where the spec was served
+	if src, err := url.Parse(source); err == nil && (src.Scheme == "https" || src.Scheme == "http") {
+		if ref, err := url.Parse(base); err == nil {
+			return src.ResolveReference(ref).String()
+		}
+	}
+	return base
+}
+
+func containsValue(list []interface{}, value string) bool {
+	for _, item := range list {
+		if fmt.Sprint(item) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// apiToolName names the tool of an operation after its operationId, or its method and path,
+// prefixed by the API name.
+func apiToolName(api string, opSpec map[string]interface{}, method, path string, used map[string]bool) string {
+	id, _ := opSpec["operationId"].(string)
+	if id == "" {
+		id = method + strings.NewReplacer("{", "", "}", "").Replace(path)
+	}
+	tool := strings.Trim(apiToolNameInvalid.ReplaceAllString(api+"_"+id, "_"), "_")
+	if len(tool) > maxAPIToolName {
+		tool = tool[:maxAPIToolName]
+	}
+	name := tool
+	for i := 2; used[name]; i++ {
+		suffix := "_" + strconv.Itoa(i)
+		name = tool[:min(len(tool), maxAPIToolName-len(suffix))] + suffix
+	}
+	used[name] = true
+	return name
+}
+
+// resolveRef follows a local $ref, e.g. #/components/schemas/Pet.
+func resolveRef(root map[string]interface{}, v interface{}) interface{} {
+	for range maxAPISchemaDepth {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return v
+		}
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return v
+		}
+		v = lookupRef(root, ref)
+	}
+	return v
+}
+
+func lookupRef(root map[string]interface{}, ref string) interface{} {
+	path, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return nil // References to other files are not followed
+	}
+	var node interface{} = root
+	for _, part := range strings.Split(path, "/") {
+		part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		node = m[part]
+	}
+	return node
+}
+
+// paramSchema is the schema of a parameter: its schema in OpenAPI 3, the parameter itself in Swagger 2.
+func paramSchema(root map[string]interface{}, param map[string]interface{}) map[string]interface{} {
+	if s, ok := param["schema"]; ok {
+		return apiSchema(root, s, 0)
+	}
+	return apiSchema(root, param, 0)
+}
+
+// requestBodySchema picks the JSON content of a request body, or else its first content as a string.
+func requestBodySchema(root map[string]interface{}, body map[string]interface{}) (string, map[string]interface{}) {
+	content, _ := body["content"].(map[string]interface{})
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	for _, t := range types {
+		if strings.Contains(t, "json") {
+			media, _ := content[t].(map[string]interface{})
+			return t, apiSchema(root, media["schema"], 0)
+		}
+	}
+	if len(types) == 0 {
+		return "", nil
+	}
+	return types[0], map[string]interface{}{"type": "string", "description": "Body as " + types[0]}
+}
+
+// apiSchema inlines the $refs of a schema and keeps the keywords the providers accept.
+func apiSchema(root map[string]interface{}, v interface{}, depth int) map[string]interface{} {
+	s, ok := resolveRef(root, v).(map[string]interface{})
+	if !ok || depth > maxAPISchemaDepth {
+		return map[string]interface{}{"type": "object"}
+	}
+	// Merge allOf, take the first of oneOf and anyOf
+	if all, ok := s["allOf"].([]interface{}); ok {
+		merged := map[string]interface{}{"type": "object"}
+		props := map[string]interface{}{}
+		var required []interface{}
+		for _, part := range all {
+			p := apiSchema(root, part, depth+1)
+			if pp, ok := p["properties"].(map[string]interface{}); ok {
+				for k, v := range pp {
+					props[k] = v
+				}
+			}
+			if r, ok := p["required"].([]interface{}); ok {
+				required = append(required, r...)
+			}
+			if d, ok := p["description"]; ok {
+				merged["description"] = d
+			}
+		}
+		if len(props) > 0 {
+			merged["properties"] = props
+		}
+		if len(required) > 0 {
+			merged["required"] = required
+		}
+		return merged
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if alts, ok := s[key].([]interface{}); ok && len(alts) > 0 {
+			alt := apiSchema(root, alts[0], depth+1)
+			if d, ok := s["description"].(string); ok {
+				alt["description"] = d
+			}
+			return alt
+		}
+	}
+
+	out := map[string]interface{}{}
+	for _, key := range apiSchemaKeys {
+		if value, ok := s[key]; ok {
+			out[key] = value
+		}
+	}
+	// OpenAPI 3.1 types may be lists, e.g. [string, null]
+	if types, ok := out["type"].([]interface{}); ok {
+		out["type"] = "string"
+		for _, t := range types {
+			if t != "null" {
+				out["type"] = t
+				break
+			}
+		}
+	}
+	if d, ok := out["description"].(string); ok {
+		out["description"] = util.TruncateString(d, maxAPIToolDescription)
+	}
+	if props, ok := s["properties"].(map[string]interface{}); ok {
+		converted := make(map[string]interface{}, len(props))
+		for k, p := range props {
+			converted[k] = apiSchema(root, p, depth+1)
+		}
+		out["properties"] = converted
+		if _, ok := out["type"]; !ok {
+			out["type"] = "object"
+		}
+	}
+	if required, ok := s["required"].([]interface{}); ok {
+		out["required"] = required
+	}
+	if items, ok := s["items"]; ok {
+		out["items"] = apiSchema(root, items, depth+1)
+		out["type"] = "array"
+	}
+	if _, ok := out["type"]; !ok {
+		out["type"] = "string"
+	}
+	return out
+}
+
+// APIOperationTool is the tool of an operation of an imported API.
+func APIOperationTool(op *data.APIOperation) *OpenTool {
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, p := range op.Params {
+		schema := p.Schema
+		if schema == nil {
+			schema = map[string]interface{}{"type": "string"}
+		}
+		properties[p.Name] = schema
+		if p.Required {
+			required = append(required, p.Name)
+		}
+	}
+	if op.Body != nil {
+		properties["body"] = op.Body
+		if op.BodyNeeded {
+			required = append(required, "body")
+		}
+	}
+	fn := OpenFunctionDefinition{
+		Name:        op.Tool,
+		Description: op.Description,
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &fn}
+}
+
+// getAPITools returns the tools of the imported APIs.
+func getAPITools() []*OpenTool {
+	apis, _ := data.LoadAPIs()
+	var tools []*OpenTool
+	for _, api := range apis {
+		for i := range api.Operations {
+			tools = append(tools, APIOperationTool(&api.Operations[i]))
+		}
+	}
+	return tools
+}
+
+// FindAPIOperation finds the imported API and operation of a tool.
+func FindAPIOperation(toolName string) (*data.APIDefinition, *data.APIOperation) {
+	apis, _ := data.LoadAPIs()
+	for _, api := range apis {
+		if !strings.HasPrefix(toolName, api.Name+"_") {
+			continue
+		}
+		for i := range api.Operations {
+			if api.Operations[i].Tool == toolName {
+				return api, &api.Operations[i]
+			}
+		}
+	}
+	return nil, nil
+}
+
+// AvailableAPITool checks if a tool is an operation of an imported API.
+func AvailableAPITool(toolName string) bool {
+	_, op := FindAPIOperation(toolName)
+	return op != nil
+}
+
+// BuildAPIRequest fills the path, query and headers of an operation from the arguments of its tool.
+func BuildAPIRequest(api *data.APIDefinition, op *data.APIOperation, args map[string]interface{}) (HTTPRequest, error) {
+	req := HTTPRequest{Method: op.Method, Profile: api.Profile, Headers: map[string]string{}}
+	path := op.Path
+	query := url.Values{}
+	for _, p := range op.Params {
+		v, ok := args[p.Name]
+		if !ok || v == nil {
+			if p.Required {
+				return req, fmt.Errorf("missing required parameter '%s'", p.Name)
+			}
+			continue
+		}
+		switch p.In {
+		case "path":
+			path = strings.ReplaceAll(path, "{"+p.Name+"}", url.PathEscape(apiArgString(v)))
+		case "query":
+			if list, ok := v.([]interface{}); ok {
+				for _, item := range list {
+					query.Add(p.Name, apiArgString(item))
+				}
+			} else {
+				query.Set(p.Name, apiArgString(v))
+			}
+		case "header":
+			req.Headers[p.Name] = apiArgString(v)
+		}
+	}
+	req.URL = api.BaseURL + path
+	if len(query) > 0 {
+		req.URL += "?" + query.Encode()
+	}
+
+	if body, ok := args["body"]; ok && op.Body != nil && body != nil {
+		if s, ok := body.(string); ok && !strings.Contains(op.BodyType, "json") {
+			req.Body = s
+		} else {
+			content, err := json.Marshal(body)
+			if err != nil {
+				return req, fmt.Errorf("invalid body: %w", err)
+			}
+			req.Body = string(content)
+		}
+		if op.BodyType != "" {
+			req.Headers["Content-Type"] = op.BodyType
+		}
+	} else if op.BodyNeeded {
+		return req, fmt.Errorf("missing required parameter 'body'")
+	}
+	return req, nil
+}
+
+// apiArgString formats an argument for a path, query or header, whole numbers without a fraction.
+func apiArgString(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		content, _ := json.Marshal(t)
+		return string(content)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+const petstoreSpec = `
+openapi: 3.0.0
+info:
+  title: Swagger Petstore
+servers:
+  - url: https://{region}.petstore.example.com/v1
+    variables:
+      region:
+        default: eu
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      summary: List all pets
+      parameters:
+        - name: limit
+          in: query
+          description: How many items to return
+          schema:
+            type: integer
+            format: int32
+      responses:
+        200:
+          description: A paged array of pets
+    post:
+      operationId: createPet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        201:
+          description: Created
+  /pets/{petId}:
+    parameters:
+      - $ref: '#/components/parameters/PetId'
+    get:
+      summary: Info for a specific pet
+      responses:
+        200:
+          description: Expected response to a valid request
+components:
+  parameters:
+    PetId:
+      name: petId
+      in: path
+      required: true
+      schema:
+        type: string
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+        tag:
+          type: [string, "null"]
+        owner:
+          $ref: '#/components/schemas/Owner'
+    Owner:
+      type: object
+      properties:
+        pets:
+          type: array
+          items:
+            $ref: '#/components/schemas/Pet'
+`
+
+func TestImportOpenAPI(t *testing.T) {
+	api, err := ImportOpenAPI([]byte(petstoreSpec), "petstore", "petstore.yaml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api.BaseURL != "https://eu.petstore.example.com/v1" || api.Title != "Swagger Petstore" {
+		t.Errorf("unexpected server or title: %s, %s", api.BaseURL, api.Title)
+	}
+	var tools []string
+	for _, op := range api.Operations {
+		tools = append(tools, op.Tool)
+	}
+	if got := strings.Join(tools, ","); got != "petstore_listPets,petstore_createPet,petstore_get_pets_petId" {
+		t.Fatalf("unexpected tools: %s", got)
+	}
+
+	list := api.Operations[0]
+	if len(list.Params) != 1 || list.Params[0].In != "query" || list.Params[0].Schema["type"] != "integer" ||
+		list.Params[0].Schema["description"] != "How many items to return" {
+		t.Errorf("unexpected parameters of listPets: %+v", list.Params)
+	}
+	create := api.Operations[1]
+	props, _ := create.Body["properties"].(map[string]interface{})
+	if !create.BodyNeeded || create.BodyType != "application/json" || props["name"] == nil {
+		t.Errorf("unexpected body of createPet: %+v", create.Body)
+	}
+	if tag, _ := props["tag"].(map[string]interface{}); tag["type"] != "string" {
+		t.Errorf("expected the nullable tag to be a string, got %v", props["tag"])
+	}
+	get := api.Operations[2]
+	if len(get.Params) != 1 || get.Params[0].Name != "petId" || !get.Params[0].Required {
+		t.Errorf("expected the shared path parameter, got %+v", get.Params)
+	}
+
+	tool := APIOperationTool(&create)
+	params := tool.Function.Parameters
+	if required, _ := params["required"].([]string); len(required) != 1 || required[0] != "body" {
+		t.Errorf("expected body required, got %v", params["required"])
+	}
+}
+
+func TestImportSwagger2(t *testing.T) {
+	spec := `{"swagger": "2.0", "host": "api.example.com", "basePath": "/v2", "schemes": ["https"],
+		"paths": {"/users/{id}": {"put": {"operationId": "update user!", "parameters": [
+			{"name": "id", "in": "path", "type": "integer"},
+			{"name": "user", "in": "body", "required": true, "schema": {"type": "object", "properties": {"email": {"type": "string"}}}}]}}}}`
+	api, err := ImportOpenAPI([]byte(spec), "example", "spec.json", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := api.Operations[0]
+	if api.BaseURL != "https://api.example.com/v2" || op.Tool != "example_update_user" || op.Params[0].Schema["type"] != "integer" || !op.BodyNeeded {
+		t.Errorf("unexpected import: %s %+v", api.BaseURL, op)
+	}
+
+	if _, err := ImportOpenAPI([]byte(`{"swagger": "2.0", "paths": {}}`), "example", "spec.json", ""); err == nil {
+		t.Error("expected a spec without a server to need --base-url")
+	}
+	if _, err := ImportOpenAPI([]byte(spec), "../example", "spec.json", ""); err == nil {
+		t.Error("expected an invalid name to be refused")
+	}
+}
+
+func TestBuildAPIRequest(t *testing.T) {
+	api := &data.APIDefinition{Name: "shop", BaseURL: "https://shop.example.com/api"}
+	op := &data.APIOperation{Tool: "shop_getOrder", Method: "GET", Path: "/orders/{id}", Params: []data.APIParam{
+		{Name: "id", In: "path", Required: true},
+		{Name: "expand", In: "query"},
+		{Name: "X-Tenant", In: "header"},
+	}}
+	req, err := BuildAPIRequest(api, op, map[string]interface{}{"id": "a/b", "expand": []interface{}{"items", "customer"}, "X-Tenant": float64(42)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.URL != "https://shop.example.com/api/orders/a%2Fb?expand=items&expand=customer" || req.Headers["X-Tenant"] != "42" {
+		t.Errorf("unexpected request: %s %v", req.URL, req.Headers)
+	}
+	if _, err := BuildAPIRequest(api, op, map[string]interface{}{}); err == nil {
+		t.Error("expected the missing path parameter to fail")
+	}
+}
+
+func TestAPIToolCall(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	var gotBody, gotMethod string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method + " " + r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":1}`))
+	}))
+	defer srv.Close()
+
+	api, err := ImportOpenAPI([]byte(petstoreSpec), "petstore", "petstore.yaml", srv.URL+"/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := data.SaveAPI(api); err != nil {
+		t.Fatal(err)
+	}
+	if !AvailableAPITool("petstore_createPet") || AvailableAPITool("petstore_deletePet") {
+		t.Fatal("expected only the imported operations to be tools")
+	}
+	if tools := GetOpenToolsFiltered([]string{ToolHTTPRequest}); len(tools) != 4 {
+		t.Errorf("expected http_request and the 3 operations, got %d tools", len(tools))
+	}
+	if tools := GetOpenToolsFiltered([]string{ToolReadFile}); len(tools) != 1 {
+		t.Errorf("expected no operations without http_request, got %d tools", len(tools))
+	}
+
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}}
+	args := map[string]interface{}{"body": map[string]interface{}{"name": "Rex"}}
+	out, err := apiToolCallImpl(context.Background(), "petstore_createPet", &args, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotMethod != "POST /v1/pets" || gotBody != `{"name":"Rex"}` || !strings.Contains(out, `"id": 1`) {
+		t.Errorf("unexpected call %s %s:\n%s", gotMethod, gotBody, out)
+	}
+}
@@ -0,0 +1,246 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/activebook/gllm/data"
+)
+
+// Sorts of the providers serving an OpenRouter request.
+const (
+	OpenRouterSortPrice      = "price"
+	OpenRouterSortThroughput = "throughput"
+	OpenRouterSortLatency    = "latency"
+)
+
+// openRouterMaxBody caps the non-streamed response read for its metadata.
+const openRouterMaxBody = 4 << 20
+
+// OpenRouterRouting is the provider routing and the fallback models of a model served by OpenRouter.
+type OpenRouterRouting struct {
+	ProviderOrder  []string // Providers tried first, e.g. anthropic, together
+	ProviderSort   string   // price, throughput or latency, empty for OpenRouter's default
+	AllowFallbacks *bool    // Whether providers outside the order may serve the request
+	FallbackModels []string // Models tried in order when the model fails
+}
+
+// openRouterServed is what OpenRouter reports about the requests of a model: who served them
+// and what they cost. It is filled while the responses are read and drained into the usage.
+type openRouterServed struct {
+	mu       sync.Mutex
+	provider string
+	model    string
+	cost     float64
+}
+
+// IsOpenRouterEndpoint reports whether the endpoint is the OpenRouter API.
+func IsOpenRouterEndpoint(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host == "openrouter.ai" || strings.HasSuffix(host, ".openrouter.ai")
+}
+
+// ModelOpenRouterRouting returns the OpenRouter routing of a model, nil when it is not
+// served by OpenRouter.
+func ModelOpenRouterRouting(model *data.Model) *OpenRouterRouting {
+	if !IsOpenRouterEndpoint(model.Endpoint) {
+		return nil
+	}
+	return &OpenRouterRouting{
+		ProviderOrder:  model.ProviderOrder,
+		ProviderSort:   model.ProviderSort,
+		AllowFallbacks: model.AllowFallbacks,
+		FallbackModels: model.FallbackModels,
+	}
+}
+
+// ValidateOpenRouterSort checks a provider sort, empty is allowed.
+func ValidateOpenRouterSort(sort string) error {
+	switch sort {
+	case "", OpenRouterSortPrice, OpenRouterSortThroughput, OpenRouterSortLatency:
+		return nil
+	}
+	return fmt.Errorf("invalid provider sort '%s', use %s, %s or %s", sort, OpenRouterSortPrice, OpenRouterSortThroughput, OpenRouterSortLatency)
+}
+
+// withOpenRouter wraps the client to send the routing of the model to OpenRouter and note
+// the provider, model and cost of its answers.
+func withOpenRouter(client *http.Client, mi *ModelInfo) *http.Client {
+	if mi == nil || mi.OpenRouter == nil {
+		return client
+	}
+	if mi.openRouterServed == nil {
+		mi.openRouterServed = &openRouterServed{}
+	}
+	return &http.Client{Transport: &openRouterTransport{base: client.Transport, routing: mi.OpenRouter, served: mi.openRouterServed}}
+}
+
+// openRouterTransport adds the routing to the chat completion requests and reads the
+// metadata OpenRouter adds to their responses.
+type openRouterTransport struct {
+	base    http.RoundTripper
+	routing *OpenRouterRouting
+	served  *openRouterServed
+}
+
+func (t *openRouterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !isChatCompletionRequest(req) {
+		return base.RoundTrip(req)
+	}
+	req, err := rewriteRequestBody(req, t.routing.apply)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	resp.Body = &openRouterBody{ReadCloser: resp.Body, served: t.served, stream: strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream")}
+	return resp, nil
+}
+
+// apply adds the routing to a chat completion request, the fields set by the caller are kept.
+func (r *OpenRouterRouting) apply(body []byte) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, err
+	}
+	set := func(key string, value interface{}) {
+		if _, ok := fields[key]; ok {
+			return
+		}
+		if raw, err := json.Marshal(value); err == nil {
+			fields[key] = raw
+		}
+	}
+
+	provider := map[string]interface{}{}
+	if len(r.ProviderOrder) > 0 {
+		provider["order"] = r.ProviderOrder
+	}
+	if r.ProviderSort != "" {
+		provider["sort"] = r.ProviderSort
+	}
+	if r.AllowFallbacks != nil {
+		provider["allow_fallbacks"] = *r.AllowFallbacks
+	}
+	if len(provider) > 0 {
+		set("provider", provider)
+	}
+	if len(r.FallbackModels) > 0 {
+		var model string
+		json.Unmarshal(fields["model"], &model)
+		set("models", append([]string{model}, r.FallbackModels...))
+		set("route", "fallback")
+	}
+	// The cost of the request comes with its usage
+	set("usage", map[string]bool{"include": true})
+	return json.Marshal(fields)
+}
+
+// openRouterBody reads the provider, model and cost of the answer while it is passed on.
+type openRouterBody struct {
+	io.ReadCloser
+	served  *openRouterServed
+	stream  bool
+	pending []byte // Incomplete line of a stream, or the whole answer otherwise
+	done    bool
+}
+
+func (b *openRouterBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 && !b.done {
+		b.pending = append(b.pending, p[:n]...)
+		if b.stream {
+			b.scanLines()
+		} else if len(b.pending) > openRouterMaxBody {
+			b.done, b.pending = true, nil
+		}
+	}
+	if err == io.EOF && !b.done {
+		b.done = true
+		if !b.stream {
+			b.served.note(b.pending)
+		}
+		b.pending = nil
+	}
+	return n, err
+}
+
+// scanLines notes the events of the complete lines of the stream.
+func (b *openRouterBody) scanLines() {
+	i := bytes.LastIndexByte(b.pending, '\n')
+	if i < 0 {
+		return
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b.pending[:i]))
+	scanner.Buffer(make([]byte, 0, 64*1024), openRouterMaxBody)
+	for scanner.Scan() {
+		if payload, ok := bytes.CutPrefix(bytes.TrimSpace(scanner.Bytes()), []byte("data:")); ok {
+			b.served.note(bytes.TrimSpace(payload))
+		}
+	}
+	b.pending = append(b.pending[:0], b.pending[i+1:]...)
+}
+
+// note records the metadata of a response or a stream event.
+func (s *openRouterServed) note(payload []byte) {
+	if len(payload) == 0 || payload[0] != '{' {
+		return
+	}
+	var meta struct {
+		Provider string `json:"provider"`
+		Model    string `json:"model"`
+		Usage    *struct {
+			Cost float64 `json:"cost"`
+		} `json:"usage"`
+	}
+	if json.Unmarshal(payload, &meta) != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if meta.Provider != "" {
+		s.provider = meta.Provider
+	}
+	if meta.Model != "" {
+		s.model = meta.Model
+	}
+	if meta.Usage != nil {
+		s.cost += meta.Usage.Cost
+	}
+}
+
+// drain returns what was noted since the last drain and starts over.
+func (s *openRouterServed) drain() (provider, model string, cost float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	provider, model, cost = s.provider, s.model, s.cost
+	s.provider, s.model, s.cost = "", "", 0
+	return provider, model, cost
+}
+
+// addUpOpenRouterUsage adds the cost and the provider reported by OpenRouter to the usage.
+func (ag *Agent) addUpOpenRouterUsage() {
+	if ag.Model == nil || ag.Model.openRouterServed == nil || ag.TokenUsage == nil {
+		return
+	}
+	provider, model, cost := ag.Model.openRouterServed.drain()
+	ag.TokenUsage.RecordServed(provider, model, cost)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestOpenRouterRouting(t *testing.T) {
+	if ModelOpenRouterRouting(&data.Model{Endpoint: "https://api.openai.com/v1", ProviderSort: "price"}) != nil {
+		t.Error("the routing is only for OpenRouter")
+	}
+	routing := ModelOpenRouterRouting(&data.Model{
+		Endpoint:       "https://openrouter.ai/api/v1",
+		ProviderOrder:  []string{"anthropic", "together"},
+		AllowFallbacks: Ptr(false),
+		FallbackModels: []string{"openai/gpt-4o"},
+	})
+	if routing == nil {
+		t.Fatal("no routing for OpenRouter")
+	}
+
+	var sent map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, `data: {"model":"anthropic/claude-sonnet-4","provider":"Anthropic","choices":[{"delta":{"content":"Hi"}}]}`+"\n\n")
+		io.WriteString(w, `data: {"model":"anthropic/claude-sonnet-4","provider":"Anthropic","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":2,"cost":0.0125}}`+"\n\n")
+		io.WriteString(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	mi := &ModelInfo{OpenRouter: routing}
+	client := withOpenRouter(http.DefaultClient, mi)
+	body := `{"model":"anthropic/claude-sonnet-4","stream":true,"messages":[]}`
+	resp, err := client.Post(srv.URL+"/api/v1/chat/completions", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	provider, _ := sent["provider"].(map[string]interface{})
+	if provider["allow_fallbacks"] != false || len(provider["order"].([]interface{})) != 2 {
+		t.Errorf("provider = %v", sent["provider"])
+	}
+	if models, _ := sent["models"].([]interface{}); len(models) != 2 || models[0] != "anthropic/claude-sonnet-4" || sent["route"] != "fallback" {
+		t.Errorf("models = %v, route = %v", sent["models"], sent["route"])
+	}
+	if usage, _ := sent["usage"].(map[string]interface{}); usage["include"] != true {
+		t.Errorf("usage = %v", sent["usage"])
+	}
+
+	tu := NewTokenUsage()
+	tu.RecordTokenUsage(10, 2, 0, 0, 12)
+	ag := &Agent{Model: mi, TokenUsage: tu}
+	ag.addUpOpenRouterUsage()
+	if tu.ReportedCost != 0.0125 || tu.ServedBy != "Anthropic · anthropic/claude-sonnet-4" {
+		t.Errorf("usage = %+v", tu)
+	}
+	if cost := tu.CostAt(100, 100); cost != 0.0125 {
+		t.Errorf("the reported cost should beat the estimate, got %v", cost)
+	}
+	// Drained once
+	ag.addUpOpenRouterUsage()
+	if tu.ReportedCost != 0.0125 {
+		t.Errorf("cost counted twice: %v", tu.ReportedCost)
+	}
+}
+
+func TestIsOpenRouterEndpoint(t *testing.T) {
+	for endpoint, want := range map[string]bool{
+		"https://openrouter.ai/api/v1":     true,
+		"https://eu.openrouter.ai/api/v1":  true,
+		"https://notopenrouter.ai/api/v1":  false,
+		"https://api.openai.com/v1":        false,
+		"http://localhost:4000/openrouter": false,
+	} {
+		if got := IsOpenRouterEndpoint(endpoint); got != want {
+			t.Errorf("IsOpenRouterEndpoint(%s) = %v", endpoint, got)
+		}
+	}
+}
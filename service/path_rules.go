@@ -0,0 +1,198 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+)
+
+// PathRules enforces the paths of an agent on the file arguments of its tools, and on the
+// working directory of the tools that run commands.
+type PathRules struct {
+	read, write, deny []string // Absolute slash separated globs
+	limitReads        bool
+	limitWrites       bool
+}
+
+// pathArg is a file argument of a tool, and whether the tool writes to it.
+type pathArg struct {
+	key   string
+	write bool
+}
+
+// toolPathArgs are the file arguments of the file tools.
+var toolPathArgs = map[string][]pathArg{
+	ToolReadFile:          {{"path", false}},
+	ToolReadSymbol:        {{"path", false}},
+	ToolWatchPath:         {{"path", false}},
+	ToolCheckChanges:      {{"path", false}},
+	ToolListDirectory:     {{"path", false}},
+	ToolSearchTextInFile:  {{"path", false}},
+	ToolSearchFiles:       {{"directory", false}},
+	ToolReadMultipleFiles: {{"paths", false}},
+	ToolDocumentSymbols:   {{"path", false}},
+	ToolFindDefinition:    {{"path", false}},
+	ToolFindReferences:    {{"path", false}},
+	ToolInspectData:       {{"path", false}},
+	ToolQueryData:         {{"files", false}},
+	ToolLint:              {{"paths", false}},
+	ToolMapOverFiles:      {{"pattern", false}},
+	ToolWriteFile:         {{"path", true}},
+	ToolEditFile:          {{"path", true}},
+	ToolDeleteFile:        {{"path", true}},
+	ToolCreateDirectory:   {{"path", true}},
+	ToolDeleteDirectory:   {{"path", true}},
+	ToolRestoreDeleted:    {{"path", true}},
+	ToolFormatCode:        {{"paths", true}},
+	ToolMove:              {{"source", true}, {"destination", true}},
+	ToolCopy:              {{"source", false}, {"destination", true}},
+}
+
+// commandTools run commands in the working directory, which they may write to.
+var commandTools = map[string]bool{
+	ToolShell:          true,
+	ToolRunSkillScript: true,
+	ToolRunTests:       true, // The tests run the code, which may write anywhere
+}
+
+// NewPathRules anchors the globs of an agent's paths in the working directory, nil when the
+// agent may touch any file.
+func NewPathRules(paths data.AgentPaths) (*PathRules, error) {
+	if paths.IsZero() {
+		return nil, nil
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	wd = resolveRulePath(wd)
+	anchor := func(globs []string) []string {
+		var out []string
+		for _, g := range globs {
+			g = strings.TrimSpace(g)
+			if g == "" {
+				continue
+			}
+			if strings.HasPrefix(g, "~/") {
+				if home, err := os.UserHomeDir(); err == nil {
+					g = filepath.Join(home, g[2:])
+				}
+			}
+			if !filepath.IsAbs(g) {
+				g = filepath.Join(wd, g)
+			}
+			out = append(out, filepath.ToSlash(filepath.Clean(g)))
+		}
+		return out
+	}
+	return &PathRules{
+		read:        anchor(paths.Read),
+		write:       anchor(paths.Write),
+		deny:        anchor(paths.Deny),
+		limitReads:  len(paths.Read) > 0,
+		limitWrites: len(paths.Read) > 0 || len(paths.Write) > 0,
+	}, nil
+}
+
+// Check returns why the agent may not read, or write, the path, "" when it may.
+func (r *PathRules) Check(path string, write bool) string {
+	if r == nil {
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Sprintf("invalid path '%s'", path)
+	}
+	abs = filepath.ToSlash(resolveRulePath(abs))
+	if matchPathGlobs(r.deny, abs) {
+		return fmt.Sprintf("'%s' is denied to this agent", path)
+	}
+	if write {
+		if r.limitWrites && !matchPathGlobs(r.write, abs) {
+			return fmt.Sprintf("'%s' is read-only for this agent", path)
+		}
+		return ""
+	}
+	if r.limitReads && !matchPathGlobs(r.read, abs) && !matchPathGlobs(r.write, abs) {
+		return fmt.Sprintf("'%s' is outside the paths this agent may read", path)
+	}
+	return ""
+}
+
+// CheckToolCall returns why the agent may not make the tool call, "" when it may.
+func (r *PathRules) CheckToolCall(name string, args map[string]interface{}) string {
+	if r == nil {
+		return ""
+	}
+	if commandTools[name] {
+		// The commands run in the working directory and may write anywhere in it
+		if problem := r.Check(".", true); problem != "" {
+			return "the working directory " + problem
+		}
+		return ""
+	}
+	if name == ToolApplyChanges {
+		changes, _ := args["changes"].([]interface{})
+		for _, c := range changes {
+			change, _ := c.(map[string]interface{})
+			if p, _ := change["path"].(string); p != "" {
+				if problem := r.Check(p, true); problem != "" {
+					return problem
+				}
+			}
+		}
+		return ""
+	}
+	for _, arg := range toolPathArgs[name] {
+		var values []string
+		switch v := args[arg.key].(type) {
+		case string:
+			values = []string{v}
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					values = append(values, s)
+				}
+			}
+		}
+		for _, p := range values {
+			if p == "" {
+				continue
+			}
+			if problem := r.Check(p, arg.write); problem != "" {
+				return problem
+			}
+		}
+	}
+	return ""
+}
+
+// matchPathGlobs reports whether the path, or a directory it is in, matches one of the globs.
+func matchPathGlobs(globs []string, path string) bool {
+	parts := strings.Split(path, "/")
+	for _, g := range globs {
+		pattern := strings.Split(g, "/")
+		for n := len(parts); n > 0; n-- {
+			if matchGlobParts(pattern, parts[:n]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveRulePath follows the symbolic links of a path, or of the directory of a path that
+// does not exist yet, so that a link can't lead out of the allowed paths.
+func resolveRulePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	dir, base := filepath.Split(path)
+	if dir == "" || filepath.Clean(dir) == path {
+		return path
+	}
+	return filepath.Join(resolveRulePath(filepath.Clean(dir)), base)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestPathRules(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	for _, d := range []string{"src/pkg", "docs", "build", "secrets"} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	outside := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join("docs", "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := NewPathRules(data.AgentPaths{
+		Read:  []string{"src/**", "*.md"},
+		Write: []string{"docs", "build/*.txt"},
+		Deny:  []string{"**/.env", "secrets"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path  string
+		write bool
+		ok    bool
+	}{
+		{"src/pkg/a.go", false, true},
+		{"src/pkg/a.go", true, false},
+		{"README.md", false, true},
+		{"go.mod", false, false},
+		{"docs/guide/new.md", true, true},
+		{"./docs/../docs/x.md", true, true},
+		{"docs/x.md", false, true}, // Writable paths are readable
+		{"build/out.txt", true, true},
+		{"build/out.bin", true, false},
+		{"src/.env", false, false},
+		{"secrets/key", false, false},
+		{"docs/link/escape.md", true, false}, // The link leads out of docs
+		{filepath.Join(dir, "src", "b.go"), false, true},
+		{"/etc/passwd", false, false},
+	}
+	for _, tt := range tests {
+		problem := rules.Check(tt.path, tt.write)
+		if (problem == "") != tt.ok {
+			t.Errorf("Check(%q, write=%v) = %q, want allowed=%v", tt.path, tt.write, problem, tt.ok)
+		}
+	}
+
+	var none *PathRules
+	if problem := none.Check("/etc/passwd", true); problem != "" {
+		t.Errorf("nil rules should allow everything, got %q", problem)
+	}
+	if r, _ := NewPathRules(data.AgentPaths{}); r != nil {
+		t.Errorf("empty paths should not build rules")
+	}
+
+	denyOnly, _ := NewPathRules(data.AgentPaths{Deny: []string{"secrets"}})
+	if problem := denyOnly.Check("anywhere/new.go", true); problem != "" {
+		t.Errorf("deny only should allow other writes, got %q", problem)
+	}
+}
+
+func TestPathRulesToolCall(t *testing.T) {
+	t.Chdir(t.TempDir())
+	rules, err := NewPathRules(data.AgentPaths{Read: []string{"**"}, Write: []string{"out"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		ok   bool
+	}{
+		{ToolReadFile, map[string]interface{}{"path": "main.go"}, true},
+		{ToolWriteFile, map[string]interface{}{"path": "main.go", "content": ""}, false},
+		{ToolWriteFile, map[string]interface{}{"path": "out/a.txt", "content": ""}, true},
+		{ToolCopy, map[string]interface{}{"source": "main.go", "destination": "out/main.go"}, true},
+		{ToolMove, map[string]interface{}{"source": "main.go", "destination": "out/main.go"}, false},
+		{ToolFormatCode, map[string]interface{}{"paths": []interface{}{"out/a.go", "b.go"}}, false},
+		{ToolApplyChanges, map[string]interface{}{"changes": []interface{}{
+			map[string]interface{}{"path": "out/a.go"}, map[string]interface{}{"path": "b.go"},
+		}}, false},
+		{ToolShell, map[string]interface{}{"command": "ls"}, false},
+		{ToolRunTests, map[string]interface{}{"path": "out"}, false},
+		{ToolMapOverFiles, map[string]interface{}{"pattern": "pkg/*.go"}, true},
+		{ToolWebFetch, map[string]interface{}{"url": "https://go.dev"}, true},
+	}
+	for _, tt := range tests {
+		problem := rules.CheckToolCall(tt.name, tt.args)
+		if (problem == "") != tt.ok {
+			t.Errorf("CheckToolCall(%s, %v) = %q, want allowed=%v", tt.name, tt.args, problem, tt.ok)
+		}
+	}
+
+	notify := make(chan StreamNotify, 4)
+	op := &OpenProcessor{notify: notify, status: &StatusStack{}, pathRules: rules}
+	if _, result := op.checkToolCall(ToolDeleteFile, map[string]interface{}{"path": "main.go", "purpose": "clean up"}); !strings.HasPrefix(result, "Error: the call to delete_file was blocked") {
+		t.Errorf("checkToolCall should block the write, got %q", result)
+	}
+}
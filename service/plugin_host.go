@@ -0,0 +1,402 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+)
+
+// Plugins are binaries declared in the plugins section of gllm.yaml. gllm starts each one the
+// first time tools are listed and talks JSON-RPC 2.0 over its stdin and stdout, one message
+// per line:
+//
+//	initialize        {"protocol": 1} -> {"name", "version", "tools": [{"name", "description", "parameters"}], "approval": bool}
+//	tools/call        {"name", "arguments"} -> {"content": "...", "is_error": bool}
+//	approval/request  an approval request -> {"approve": bool, "feedback": "..."}
+//
+// A plugin should exit when its stdin closes. Its stderr goes to the log.
+const (
+	// PluginProtocolVersion is sent in the initialize request.
+	PluginProtocolVersion = 1
+
+	pluginStartTimeout = 10 * time.Second
+	pluginCallTimeout  = 2 * time.Minute
+	pluginMaxToolName  = 64
+)
+
+// PluginTool is a tool a plugin registers.
+type PluginTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"` // JSON schema of the arguments
+}
+
+// PluginInfo is the answer of a plugin to initialize.
+type PluginInfo struct {
+	Name     string       `json:"name"`
+	Version  string       `json:"version"`
+	Tools    []PluginTool `json:"tools"`
+	Approval bool         `json:"approval"` // The plugin answers approval/request
+}
+
+// pluginToolResult is the answer of a plugin to tools/call.
+type pluginToolResult struct {
+	Content string `json:"content"`
+	IsError bool   `json:"is_error"`
+}
+
+type pluginMessage struct {
+	ID     *int64          `json:"id,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// PluginClient talks JSON-RPC to one plugin over stdio.
+type PluginClient struct {
+	plugin  data.ExternalPlugin
+	info    PluginInfo
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan pluginMessage
+	closed  chan struct{}
+	err     error // Why the connection closed
+}
+
+// StartPluginClient launches the plugin and performs the initialize handshake.
+func StartPluginClient(ctx context.Context, plugin data.ExternalPlugin) (*PluginClient, error) {
+	if plugin.Command == "" {
+		return nil, fmt.Errorf("plugin %s has no command", plugin.Name)
+	}
+	if _, err := exec.LookPath(plugin.Command); err != nil {
+		return nil, fmt.Errorf("plugin %s: %s not found", plugin.Name, plugin.Command)
+	}
+	cmd := exec.Command(plugin.Command, plugin.Args...)
+	cmd.Env = append(os.Environ(), plugin.Env...)
+	cmd.Stderr = pluginLogWriter{name: plugin.Name}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %v", plugin.Name, err)
+	}
+
+	c := &PluginClient{
+		plugin:  plugin,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan pluginMessage),
+		closed:  make(chan struct{}),
+	}
+	go c.readLoop(stdout)
+
+	ctx, cancel := context.WithTimeout(ctx, pluginStartTimeout)
+	defer cancel()
+	if err := c.Call(ctx, "initialize", map[string]interface{}{"protocol": PluginProtocolVersion}, &c.info); err != nil {
+		c.Shutdown()
+		return nil, fmt.Errorf("failed to initialize plugin %s: %v", plugin.Name, err)
+	}
+	return c, nil
+}
+
+// Info returns what the plugin registered.
+func (c *PluginClient) Info() PluginInfo {
+	return c.info
+}
+
+// Call sends a request and decodes its result into out, which may be nil.
+func (c *PluginClient) Call(ctx context.Context, method string, params interface{}, out interface{}) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan pluginMessage, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}); err != nil {
+		return err
+	}
+
+	select {
+	case msg := <-ch:
+		if msg.Error != nil {
+			return fmt.Errorf("%s: %s", method, msg.Error.Message)
+		}
+		if out != nil && len(msg.Result) > 0 {
+			return json.Unmarshal(msg.Result, out)
+		}
+		return nil
+	case <-c.closed:
+		return c.err
+	case <-ctx.Done():
+		return fmt.Errorf("%s timed out: %v", method, ctx.Err())
+	}
+}
+
+func (c *PluginClient) write(msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.stdin.Write(append(body, '\n'))
+	return err
+}
+
+func (c *PluginClient) readLoop(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var msg pluginMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || msg.ID == nil {
+			continue // Not a response, e.g. a stray print of the plugin
+		}
+		c.mu.Lock()
+		ch := c.pending[*msg.ID]
+		c.mu.Unlock()
+		if ch != nil {
+			ch <- msg
+		}
+	}
+	err := scanner.Err()
+	if err == nil {
+		err = io.EOF
+	}
+	c.mu.Lock()
+	c.err = fmt.Errorf("plugin %s exited: %v", c.plugin.Name, err)
+	c.mu.Unlock()
+	close(c.closed)
+}
+
+// CallTool runs a tool of the plugin.
+func (c *PluginClient) CallTool(ctx context.Context, tool string, args map[string]interface{}) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, pluginCallTimeout)
+	defer cancel()
+	var result pluginToolResult
+	if err := c.Call(ctx, "tools/call", map[string]interface{}{"name": tool, "arguments": args}, &result); err != nil {
+		return "", err
+	}
+	if result.IsError {
+		return "", fmt.Errorf("%s", result.Content)
+	}
+	return result.Content, nil
+}
+
+// RequestApproval asks the plugin to decide on a tool call.
+func (c *PluginClient) RequestApproval(ctx context.Context, req data.ApprovalRequest) (data.ApprovalDecision, error) {
+	if !c.info.Approval {
+		return data.ApprovalDecision{}, fmt.Errorf("plugin %s does not handle approvals", c.plugin.Name)
+	}
+	var decision data.ApprovalDecision
+	err := c.Call(ctx, "approval/request", req, &decision)
+	return decision, err
+}
+
+// Shutdown closes the stdin of the plugin and kills it if it doesn't exit.
+func (c *PluginClient) Shutdown() {
+	c.stdin.Close()
+	done := make(chan struct{})
+	go func() {
+		c.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		c.cmd.Process.Kill()
+	}
+}
+
+// pluginLogWriter sends the stderr of a plugin to the log.
+type pluginLogWriter struct {
+	name string
+}
+
+func (w pluginLogWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		util.LogDebugf("[plugin %s] %s\n", w.name, line)
+	}
+	return len(p), nil
+}
+
+var (
+	pluginPoolMu sync.Mutex
+	pluginPool   = make(map[string]*PluginClient)
+	pluginFailed = make(map[string]bool) // Plugins that failed to start are not retried in this run
+)
+
+// GetPluginClient returns the running plugin, starting it if needed.
+func GetPluginClient(ctx context.Context, name string) (*PluginClient, error) {
+	plugin := data.NewConfigStore().GetExternalPlugin(name)
+	if plugin == nil {
+		return nil, fmt.Errorf("plugin '%s' not found", name)
+	}
+	if plugin.Disabled {
+		return nil, fmt.Errorf("plugin '%s' is disabled", name)
+	}
+	return getPluginClient(ctx, *plugin)
+}
+
+func getPluginClient(ctx context.Context, plugin data.ExternalPlugin) (*PluginClient, error) {
+	pluginPoolMu.Lock()
+	defer pluginPoolMu.Unlock()
+	if c, ok := pluginPool[plugin.Name]; ok {
+		select {
+		case <-c.closed:
+			delete(pluginPool, plugin.Name) // Crashed, start a new one
+		default:
+			return c, nil
+		}
+	}
+	c, err := StartPluginClient(ctx, plugin)
+	if err != nil {
+		return nil, err
+	}
+	util.LogDebugf("Started plugin %s %s with %d tool(s)\n", c.info.Name, c.info.Version, len(c.info.Tools))
+	pluginPool[plugin.Name] = c
+	return c, nil
+}
+
+// ShutdownPlugins stops every plugin that was started, and forgets the ones that failed.
+func ShutdownPlugins() {
+	pluginPoolMu.Lock()
+	defer pluginPoolMu.Unlock()
+	for name, c := range pluginPool {
+		c.Shutdown()
+		delete(pluginPool, name)
+	}
+	clear(pluginFailed)
+}
+
+var pluginToolNameInvalid = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// PluginToolName namespaces a tool of a plugin, like the operations of imported APIs.
+func PluginToolName(plugin, tool string) string {
+	name := pluginToolNameInvalid.ReplaceAllString(plugin+"_"+tool, "_")
+	if len(name) > pluginMaxToolName {
+		name = name[:pluginMaxToolName]
+	}
+	return name
+}
+
+// getPluginTools starts the enabled plugins and returns their tools. A plugin that fails to
+// start is reported once and left out.
+func getPluginTools() []*OpenTool {
+	var tools []*OpenTool
+	for _, plugin := range data.NewConfigStore().GetExternalPlugins() {
+		if plugin.Disabled {
+			continue
+		}
+		pluginPoolMu.Lock()
+		failed := pluginFailed[plugin.Name]
+		pluginPoolMu.Unlock()
+		if failed {
+			continue
+		}
+		c, err := getPluginClient(context.Background(), plugin)
+		if err != nil {
+			util.LogWarnf("%v\n", err)
+			pluginPoolMu.Lock()
+			pluginFailed[plugin.Name] = true
+			pluginPoolMu.Unlock()
+			continue
+		}
+		for _, t := range c.info.Tools {
+			tools = append(tools, pluginOpenTool(plugin.Name, t))
+		}
+	}
+	return tools
+}
+
+// pluginOpenTool describes a tool of a plugin to the model.
+func pluginOpenTool(plugin string, t PluginTool) *OpenTool {
+	params := t.Parameters
+	if params == nil {
+		params = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	fn := OpenFunctionDefinition{
+		Name:        PluginToolName(plugin, t.Name),
+		Description: fmt.Sprintf("%s (from the %s plugin)", t.Description, plugin),
+		Parameters:  params,
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &fn}
+}
+
+// findPluginTool returns the running plugin of a tool and the tool's own name.
+func findPluginTool(toolName string) (*PluginClient, string) {
+	pluginPoolMu.Lock()
+	defer pluginPoolMu.Unlock()
+	for name, c := range pluginPool {
+		for _, t := range c.info.Tools {
+			if PluginToolName(name, t.Name) == toolName {
+				return c, t.Name
+			}
+		}
+	}
+	return nil, ""
+}
+
+// AvailablePluginTool checks if a tool is registered by a running plugin.
+func AvailablePluginTool(toolName string) bool {
+	c, _ := findPluginTool(toolName)
+	return c != nil
+}
+
+// pluginToolCallImpl runs a tool of a plugin. Plugins can do anything, so every call is
+// confirmed unless tools are auto-approved.
+func pluginToolCallImpl(ctx context.Context, name string, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	c, tool := findPluginTool(name)
+	if c == nil {
+		return "", fmt.Errorf("plugin tool %s not found", name)
+	}
+	if err := CheckToolPermission(name, argsMap); err != nil {
+		return "", err
+	}
+	if !op.toolsUse.AutoApprove {
+		op.toolsUse.Tool = name
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(fmt.Sprintf("Run %s of the %s plugin", tool, c.plugin.Name), op.toolsUse)
+		}
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return fmt.Sprintf("Operation cancelled by user: %s", name), UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+	result, err := c.CallTool(ctx, tool, *argsMap)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return result, nil
+}
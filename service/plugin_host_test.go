@@ -0,0 +1,131 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+	"github.com/spf13/viper"
+)
+
+// TestFakePlugin is not a real test, it is started as a plugin by the tests below.
+func TestFakePlugin(t *testing.T) {
+	if os.Getenv("GLLM_FAKE_PLUGIN") != "1" {
+		t.Skip("helper process")
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	fmt.Println("starting") // Stray output is ignored
+	for scanner.Scan() {
+		var msg struct {
+			ID     int64           `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		json.Unmarshal(scanner.Bytes(), &msg)
+		var result interface{}
+		switch msg.Method {
+		case "initialize":
+			result = PluginInfo{Name: "fake", Version: "1.0", Approval: true, Tools: []PluginTool{{
+				Name:        "echo",
+				Description: "Echo the text",
+				Parameters: map[string]interface{}{"type": "object",
+					"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}}},
+			}}}
+		case "tools/call":
+			var params struct {
+				Name      string
+				Arguments map[string]interface{}
+			}
+			json.Unmarshal(msg.Params, &params)
+			text, _ := params.Arguments["text"].(string)
+			result = pluginToolResult{Content: os.Getenv("FAKE_PREFIX") + text, IsError: text == ""}
+		case "approval/request":
+			var req data.ApprovalRequest
+			json.Unmarshal(msg.Params, &req)
+			result = data.ApprovalDecision{Approve: req.Tool == ToolReadFile, Feedback: "only reads"}
+		default:
+			fmt.Printf(`{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"unknown method"}}`+"\n", msg.ID)
+			continue
+		}
+		body, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": msg.ID, "result": result})
+		fmt.Println(string(body))
+	}
+}
+
+func setupFakePlugin(t *testing.T) {
+	t.Helper()
+	t.Setenv("GLLM_FAKE_PLUGIN", "1")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	t.Cleanup(ShutdownPlugins)
+	viper.Set("plugins", map[string]interface{}{
+		"my.fake": map[string]interface{}{
+			"command": os.Args[0],
+			"args":    []string{"-test.run=^TestFakePlugin$"},
+			"env":     []string{"FAKE_PREFIX=Echo: "},
+		},
+		"broken": map[string]interface{}{"command": "gllm-no-such-plugin"},
+	})
+}
+
+func TestPluginTools(t *testing.T) {
+	setupFakePlugin(t)
+
+	tools := GetOpenToolsFiltered([]string{ToolReadFile})
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Function.Name)
+	}
+	if strings.Join(names, ",") != ToolReadFile+",my_fake_echo" {
+		t.Fatalf("unexpected tools: %v", names)
+	}
+	if !AvailablePluginTool("my_fake_echo") || AvailablePluginTool("broken_echo") {
+		t.Error("only the tools of running plugins are available")
+	}
+
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}}
+	if _, problem := op.validateToolCall("my_fake_echo", `{"text": "hi"}`); problem != "" {
+		t.Errorf("plugin tools should pass the tool call checks: %s", problem)
+	}
+	args := map[string]interface{}{"text": "hi"}
+	out, err := pluginToolCallImpl(context.Background(), "my_fake_echo", &args, op)
+	if err != nil || out != "Echo: hi" {
+		t.Errorf("plugin call = %q, %v", out, err)
+	}
+	args = map[string]interface{}{}
+	out, _ = pluginToolCallImpl(context.Background(), "my_fake_echo", &args, op)
+	if !strings.HasPrefix(out, "Error:") {
+		t.Errorf("a failed plugin call should be reported to the model, got %q", out)
+	}
+
+	// Without auto-approve, a declined call is not sent to the plugin
+	op = &OpenProcessor{toolsUse: &data.ToolsUse{}, interaction: HeadlessInteractionHandler{}}
+	if _, err := pluginToolCallImpl(context.Background(), "my_fake_echo", &args, op); err == nil {
+		t.Error("expected the call to be cancelled")
+	}
+}
+
+func TestApprovalHandlerPlugin(t *testing.T) {
+	setupFakePlugin(t)
+
+	if err := ValidateApprovalSettings(data.ApprovalSettings{Backend: ApprovalBackendPlugin}); err == nil {
+		t.Error("the plugin backend needs a plugin")
+	}
+	h := NewApprovalHandler(data.ApprovalSettings{Backend: ApprovalBackendPlugin, Plugin: "my.fake"}, nil, "s1", "coder")
+	tu := &data.ToolsUse{Tool: ToolReadFile}
+	h.RequestConfirm("read main.go", tu)
+	if tu.Confirm != data.ToolConfirmYes || tu.Approver != ApprovalBackendPlugin {
+		t.Errorf("plugin approval: %+v", tu)
+	}
+	tu = &data.ToolsUse{Tool: ToolWriteFile}
+	h.RequestConfirm("write main.go", tu)
+	if tu.Confirm != data.ToolConfirmCancel || tu.Feedback != "only reads" {
+		t.Errorf("plugin denial: %+v", tu)
+	}
+}
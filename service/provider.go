@@ -12,6 +12,7 @@ const (
 	ModelProviderOpenAI           string = "openai"
 	ModelProviderOpenAICompatible string = "openai-compatible"
 	ModelProviderAnthropic        string = "anthropic" // for anthropic models (official sdk)
+	ModelProviderMock             string = "mock"      // scripted responses for testing
 	ModelProviderUnknown          string = "unknown"
 )
 
@@ -49,6 +50,8 @@ var providerDomains = map[string]string{
 	"minimax.com":      ModelProviderOpenAICompatible,
 	"baidu.com":        ModelProviderOpenAICompatible,
 	"deepseek.com":     ModelProviderOpenAICompatible,
+	"api.x.ai":         ModelProviderOpenAICompatible,
+	"mistral.ai":       ModelProviderOpenAICompatible,
 	"modelscope.cn":    ModelProviderOpenAICompatible,
 }
 
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ProviderPreset is an OpenAI-compatible API whose quirks gllm knows. Its requests are fixed
+// up on the way out, so that its models work without manual tweaking.
+type ProviderPreset struct {
+	Name     string // Preset name, accepted as the provider of a model
+	Title    string // Name shown in the setup
+	Endpoint string
+	Model    string // Suggested model
+	Provider string // Client the preset uses
+	host     string
+	fixup    func(model string, req map[string]interface{})
+}
+
+// ProviderPresets are the APIs with known quirks.
+var ProviderPresets = []ProviderPreset{
+	{
+		Name:     "xai",
+		Title:    "Grok",
+		Endpoint: "https://api.x.ai/v1",
+		Model:    "grok-4-1-fast",
+		Provider: ModelProviderOpenAICompatible,
+		host:     "api.x.ai",
+		fixup:    fixupXAIRequest,
+	},
+	{
+		Name:     "mistral",
+		Title:    "Mistral",
+		Endpoint: "https://api.mistral.ai/v1",
+		Model:    "mistral-large-latest",
+		Provider: ModelProviderOpenAICompatible,
+		host:     "api.mistral.ai",
+		fixup:    fixupMistralRequest,
+	},
+	{
+		Name:     "deepseek",
+		Title:    "DeepSeek",
+		Endpoint: "https://api.deepseek.com",
+		Model:    "deepseek-chat",
+		Provider: ModelProviderOpenAICompatible,
+		host:     "api.deepseek.com",
+		fixup:    fixupDeepSeekRequest,
+	},
+}
+
+// LookupProviderPreset returns the preset of the name, nil when there is none.
+func LookupProviderPreset(name string) *ProviderPreset {
+	for i := range ProviderPresets {
+		if ProviderPresets[i].Name == strings.ToLower(strings.TrimSpace(name)) {
+			return &ProviderPresets[i]
+		}
+	}
+	return nil
+}
+
+// providerPresetForEndpoint returns the preset of the API at the endpoint, nil when there is none.
+func providerPresetForEndpoint(endpoint string) *ProviderPreset {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	for i := range ProviderPresets {
+		if host == ProviderPresets[i].host {
+			return &ProviderPresets[i]
+		}
+	}
+	return nil
+}
+
+// withProviderQuirks wraps the client to fix up the chat completion requests of the model for
+// the API it is served by.
+func withProviderQuirks(client *http.Client, mi *ModelInfo) *http.Client {
+	if mi == nil {
+		return client
+	}
+	preset := providerPresetForEndpoint(mi.EndPoint)
+	if preset == nil {
+		return client
+	}
+	return &http.Client{Transport: &quirksTransport{base: client.Transport, preset: preset}}
+}
+
+type quirksTransport struct {
+	base   http.RoundTripper
+	preset *ProviderPreset
+}
+
+func (t *quirksTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !isChatCompletionRequest(req) {
+		return base.RoundTrip(req)
+	}
+	req, err := rewriteRequestBody(req, func(body []byte) ([]byte, error) {
+		return fixupRequestBody(body, t.preset.fixup)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return base.RoundTrip(req)
+}
+
+// fixupRequestBody applies a fixup to a JSON chat completion request.
+func fixupRequestBody(body []byte, fixup func(model string, req map[string]interface{})) ([]byte, error) {
+	var req map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber() // Seeds and token counts stay exact
+	if err := dec.Decode(&req); err != nil {
+		return nil, err
+	}
+	model, _ := req["model"].(string)
+	fixup(strings.ToLower(model), req)
+	return json.Marshal(req)
+}
+
+// requestMessages returns the messages of a chat completion request.
+func requestMessages(req map[string]interface{}) []map[string]interface{} {
+	list, _ := req["messages"].([]interface{})
+	messages := make([]map[string]interface{}, 0, len(list))
+	for _, m := range list {
+		if msg, ok := m.(map[string]interface{}); ok {
+			messages = append(messages, msg)
+		}
+	}
+	return messages
+}
+
+// fixupXAIRequest drops what the Grok reasoning models reject: the sampling penalties, stop
+// sequences and reasoning effort. grok-3-mini only takes a low or high effort.
+func fixupXAIRequest(model string, req map[string]interface{}) {
+	delete(req, "thinking") // Not an xAI parameter
+	switch {
+	case strings.HasPrefix(model, "grok-3-mini"):
+		switch req["reasoning_effort"] {
+		case "minimal", "low":
+			req["reasoning_effort"] = "low"
+		case nil:
+		default:
+			req["reasoning_effort"] = "high"
+		}
+	case strings.HasPrefix(model, "grok-4") || strings.HasPrefix(model, "grok-code"):
+		for _, key := range []string{"presence_penalty", "frequency_penalty", "stop", "reasoning_effort"} {
+			delete(req, key)
+		}
+	}
+}
+
+// mistralToolCallID matches the tool call IDs Mistral accepts.
+var mistralToolCallID = regexp.MustCompile(`^[a-zA-Z0-9]{9}$`)
+
+// fixupMistralRequest fits a request to Mistral, which rejects the fields it doesn't know,
+// names "required" tool choice "any", and only takes tool call IDs of 9 letters and digits,
+// which the calls of other providers don't have after a switch of model.
+func fixupMistralRequest(model string, req map[string]interface{}) {
+	for _, key := range []string{"thinking", "reasoning_effort", "stream_options", "service_tier", "repetition_penalty"} {
+		delete(req, key)
+	}
+	if req["tool_choice"] == "required" {
+		req["tool_choice"] = "any"
+	}
+	if seed, ok := req["seed"]; ok {
+		req["random_seed"] = seed
+		delete(req, "seed")
+	}
+	if limit, ok := req["max_completion_tokens"]; ok {
+		if _, set := req["max_tokens"]; !set {
+			req["max_tokens"] = limit
+		}
+		delete(req, "max_completion_tokens")
+	}
+	toolCallID := func(id interface{}) interface{} {
+		s, ok := id.(string)
+		if !ok || mistralToolCallID.MatchString(s) {
+			return id
+		}
+		sum := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(sum[:])[:9]
+	}
+	for _, msg := range requestMessages(req) {
+		delete(msg, "reasoning_content")
+		if name, ok := msg["name"].(string); ok && name == "" {
+			delete(msg, "name")
+		}
+		if id, ok := msg["tool_call_id"]; ok {
+			msg["tool_call_id"] = toolCallID(id)
+		}
+		calls, _ := msg["tool_calls"].([]interface{})
+		for _, c := range calls {
+			if call, ok := c.(map[string]interface{}); ok {
+				call["id"] = toolCallID(call["id"])
+			}
+		}
+	}
+}
+
+// fixupDeepSeekRequest drops the reasoning of the earlier turns, which DeepSeek refuses as
+// input. The reasoning of the current turn is kept for its tool calls.
+func fixupDeepSeekRequest(model string, req map[string]interface{}) {
+	messages := requestMessages(req)
+	lastUser := -1
+	for i, msg := range messages {
+		if msg["role"] == "user" {
+			lastUser = i
+		}
+	}
+	for _, msg := range messages[:max(lastUser, 0)] {
+		delete(msg, "reasoning_content")
+	}
+}
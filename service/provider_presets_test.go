@@ -0,0 +1,152 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func fixupForTest(t *testing.T, fixup func(string, map[string]interface{}), body string) map[string]interface{} {
+	t.Helper()
+	out, err := fixupRequestBody([]byte(body), fixup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req map[string]interface{}
+	if err := json.Unmarshal(out, &req); err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func TestLookupProviderPreset(t *testing.T) {
+	if p := LookupProviderPreset(" Mistral "); p == nil || p.Endpoint != "https://api.mistral.ai/v1" {
+		t.Errorf("mistral preset = %+v", p)
+	}
+	if LookupProviderPreset("openai") != nil {
+		t.Error("openai is not a preset")
+	}
+	for endpoint, want := range map[string]string{
+		"https://api.x.ai/v1":            "xai",
+		"https://api.deepseek.com":       "deepseek",
+		"https://API.MISTRAL.AI/v1":      "mistral",
+		"https://openrouter.ai/api/v1":   "",
+		"https://proxy.example/api.x.ai": "",
+	} {
+		got := ""
+		if p := providerPresetForEndpoint(endpoint); p != nil {
+			got = p.Name
+		}
+		if got != want {
+			t.Errorf("providerPresetForEndpoint(%s) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
+
+func TestFixupXAIRequest(t *testing.T) {
+	req := fixupForTest(t, fixupXAIRequest, `{"model":"grok-4-1-fast","presence_penalty":0.5,"stop":["x"],"reasoning_effort":"high","temperature":0.7}`)
+	for _, key := range []string{"presence_penalty", "stop", "reasoning_effort"} {
+		if _, ok := req[key]; ok {
+			t.Errorf("grok-4 request kept %s", key)
+		}
+	}
+	if req["temperature"] != 0.7 {
+		t.Errorf("temperature = %v", req["temperature"])
+	}
+
+	req = fixupForTest(t, fixupXAIRequest, `{"model":"grok-3-mini","reasoning_effort":"medium","presence_penalty":0.5}`)
+	if req["reasoning_effort"] != "high" || req["presence_penalty"] != 0.5 {
+		t.Errorf("grok-3-mini request = %v", req)
+	}
+	req = fixupForTest(t, fixupXAIRequest, `{"model":"grok-3-mini","reasoning_effort":"minimal"}`)
+	if req["reasoning_effort"] != "low" {
+		t.Errorf("reasoning_effort = %v", req["reasoning_effort"])
+	}
+}
+
+func TestFixupMistralRequest(t *testing.T) {
+	req := fixupForTest(t, fixupMistralRequest, `{
+		"model":"mistral-large-latest","tool_choice":"required","seed":12345678901234567,
+		"max_completion_tokens":1000,"stream_options":{"include_usage":true},
+		"messages":[
+			{"role":"user","content":"hi","name":""},
+			{"role":"assistant","reasoning_content":"hmm","tool_calls":[{"id":"call_abc123def456","type":"function"},{"id":"abcDEF123","type":"function"}]},
+			{"role":"tool","tool_call_id":"call_abc123def456","content":"ok"}
+		]}`)
+	if req["tool_choice"] != "any" || req["max_tokens"] != float64(1000) {
+		t.Errorf("request = %v", req)
+	}
+	if _, ok := req["seed"]; ok {
+		t.Error("seed kept")
+	}
+	if _, ok := req["stream_options"]; ok {
+		t.Error("stream_options kept")
+	}
+	messages := requestMessages(req)
+	if _, ok := messages[0]["name"]; ok {
+		t.Error("empty name kept")
+	}
+	if _, ok := messages[1]["reasoning_content"]; ok {
+		t.Error("reasoning_content kept")
+	}
+	calls := messages[1]["tool_calls"].([]interface{})
+	id := calls[0].(map[string]interface{})["id"].(string)
+	if !mistralToolCallID.MatchString(id) {
+		t.Errorf("tool call id %q not accepted by Mistral", id)
+	}
+	if messages[2]["tool_call_id"] != id {
+		t.Errorf("tool result id %v doesn't match its call %s", messages[2]["tool_call_id"], id)
+	}
+	if calls[1].(map[string]interface{})["id"] != "abcDEF123" {
+		t.Error("a valid tool call id was changed")
+	}
+
+	// The seed stays exact
+	out, _ := fixupRequestBody([]byte(`{"seed":12345678901234567}`), fixupMistralRequest)
+	if !strings.Contains(string(out), `"random_seed":12345678901234567`) {
+		t.Errorf("body = %s", out)
+	}
+}
+
+func TestFixupDeepSeekRequest(t *testing.T) {
+	req := fixupForTest(t, fixupDeepSeekRequest, `{"model":"deepseek-reasoner","messages":[
+		{"role":"user","content":"a"},
+		{"role":"assistant","content":"b","reasoning_content":"old"},
+		{"role":"user","content":"c"},
+		{"role":"assistant","reasoning_content":"current","tool_calls":[]},
+		{"role":"tool","content":"d"}
+	]}`)
+	messages := requestMessages(req)
+	if _, ok := messages[1]["reasoning_content"]; ok {
+		t.Error("reasoning of an earlier turn kept")
+	}
+	if messages[3]["reasoning_content"] != "current" {
+		t.Error("reasoning of the current turn dropped")
+	}
+}
+
+func TestProviderQuirksTransport(t *testing.T) {
+	var sent map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	preset := LookupProviderPreset("mistral")
+	client := &http.Client{Transport: &quirksTransport{base: http.DefaultTransport, preset: preset}}
+	resp, err := client.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"model":"mistral-small","tool_choice":"required"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if sent["tool_choice"] != "any" {
+		t.Errorf("tool_choice = %v", sent["tool_choice"])
+	}
+
+	if withProviderQuirks(http.DefaultClient, &ModelInfo{EndPoint: "https://api.openai.com/v1"}) != http.DefaultClient {
+		t.Error("requests to other APIs are fixed up")
+	}
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+)
+
+// RedactionRule finds one kind of sensitive value in text.
+type RedactionRule struct {
+	Name    string         // Rule name, used to enable or disable it
+	Label   string         // Placeholder label, e.g. EMAIL
+	Pattern *regexp.Regexp // Pattern of the value
+	Group   int            // Submatch holding the value, 0 for the whole match
+	Builtin bool
+}
+
+// builtinRedactionRules are checked in order, more specific keys before generic ones.
+var builtinRedactionRules = []RedactionRule{
+	{Name: "private_key", Label: "PRIVATE_KEY", Pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{Name: "anthropic_key", Label: "ANTHROPIC_KEY", Pattern: regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_\-]{20,}`)},
+	{Name: "openai_key", Label: "OPENAI_KEY", Pattern: regexp.MustCompile(`\bsk-(?:proj-|svcacct-)?[A-Za-z0-9_\-]{20,}`)},
+	{Name: "aws_access_key", Label: "AWS_ACCESS_KEY", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "aws_secret_key", Label: "AWS_SECRET_KEY", Pattern: regexp.MustCompile(`(?i)aws_secret_access_key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`), Group: 1},
+	{Name: "github_token", Label: "GITHUB_TOKEN", Pattern: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{Name: "google_api_key", Label: "GOOGLE_API_KEY", Pattern: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}`)},
+	{Name: "slack_token", Label: "SLACK_TOKEN", Pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9\-]{10,}`)},
+	{Name: "bearer_token", Label: "BEARER_TOKEN", Pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]{20,}=*)`), Group: 1},
+	{Name: "email", Label: "EMAIL", Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}\b`)},
+}
+
+func init() {
+	for i := range builtinRedactionRules {
+		builtinRedactionRules[i].Builtin = true
+	}
+}
+
+// BuiltinRedactionRules lists the built-in rules.
+func BuiltinRedactionRules() []RedactionRule {
+	return append([]RedactionRule(nil), builtinRedactionRules...)
+}
+
+// IsBuiltinRedactionRule reports whether name is a built-in rule.
+func IsBuiltinRedactionRule(name string) bool {
+	for _, r := range builtinRedactionRules {
+		if r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// CompileRedactionPattern turns a custom rule into a redaction rule.
+func CompileRedactionPattern(p data.RedactionPattern) (RedactionRule, error) {
+	re, err := regexp.Compile(p.Regex)
+	if err != nil {
+		return RedactionRule{}, fmt.Errorf("invalid regex for '%s': %w", p.Name, err)
+	}
+	label := strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(p.Name))
+	return RedactionRule{Name: p.Name, Label: label, Pattern: re}, nil
+}
+
+// Redactor replaces sensitive values with placeholders before text is sent to a provider.
+// It keeps the mapping, so that the same value always gets the same placeholder
+// and tool calls that touch the disk can have the real values restored.
+type Redactor struct {
+	settings data.RedactionSettings
+	rules    []RedactionRule
+
+	mu            sync.Mutex
+	byValue       map[string]string // Value -> placeholder
+	byPlaceholder map[string]string // Placeholder -> value
+	counts        map[string]int    // Placeholders issued per label
+}
+
+// NewRedactor builds a redactor from the settings. Invalid custom rules are skipped with a warning.
+func NewRedactor(settings data.RedactionSettings) *Redactor {
+	disabled := make(map[string]bool, len(settings.Disabled))
+	for _, name := range settings.Disabled {
+		disabled[name] = true
+	}
+	r := &Redactor{
+		settings:      settings,
+		byValue:       make(map[string]string),
+		byPlaceholder: make(map[string]string),
+		counts:        make(map[string]int),
+	}
+	// Custom rules come first, they are usually more specific than the built-in ones
+	for _, p := range settings.Patterns {
+		rule, err := CompileRedactionPattern(p)
+		if err != nil {
+			util.LogWarnf("Skipping redaction rule: %v\n", err)
+			continue
+		}
+		r.rules = append(r.rules, rule)
+	}
+	for _, rule := range builtinRedactionRules {
+		if !disabled[rule.Name] {
+			r.rules = append(r.rules, rule)
+		}
+	}
+	return r
+}
+
+var (
+	activeRedactor   *Redactor
+	activeRedactorMu sync.Mutex
+)
+
+// GetRedactor returns the redactor for the current settings, nil when redaction is off.
+// The mapping survives settings changes, so placeholders already in a session can still be restored.
+func GetRedactor() *Redactor {
+	settings := data.GetSettingsStore().GetRedactionSettings()
+	if !settings.Enabled {
+		return nil
+	}
+	activeRedactorMu.Lock()
+	defer activeRedactorMu.Unlock()
+	if activeRedactor != nil && reflect.DeepEqual(activeRedactor.settings, settings) {
+		return activeRedactor
+	}
+	r := NewRedactor(settings)
+	if prev := activeRedactor; prev != nil {
+		prev.mu.Lock()
+		r.byValue, r.byPlaceholder, r.counts = prev.byValue, prev.byPlaceholder, prev.counts
+		prev.mu.Unlock()
+	}
+	activeRedactor = r
+	return r
+}
+
+// Redact replaces the sensitive values in text with placeholders such as [REDACTED_EMAIL_1].
+func (r *Redactor) Redact(text string) string {
+	if r == nil || text == "" {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rule := range r.rules {
+		text = r.redactRule(rule, text)
+	}
+	return text
+}
+
+func (r *Redactor) redactRule(rule RedactionRule, text string) string {
+	matches := rule.Pattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		if 2*rule.Group+1 >= len(m) {
+			continue
+		}
+		start, end := m[2*rule.Group], m[2*rule.Group+1]
+		if start < 0 || start == end {
+			continue
+		}
+		value := text[start:end]
+		if _, ok := r.byPlaceholder[value]; ok {
+			continue // Already a placeholder
+		}
+		sb.WriteString(text[last:start])
+		sb.WriteString(r.placeholder(rule.Label, value))
+		last = end
+	}
+	sb.WriteString(text[last:])
+	return sb.String()
+}
+
+// placeholder returns the placeholder of value, issuing a new one on first sight. Needs r.mu.
+func (r *Redactor) placeholder(label, value string) string {
+	if p, ok := r.byValue[value]; ok {
+		return p
+	}
+	r.counts[label]++
+	p := fmt.Sprintf("[REDACTED_%s_%d]", label, r.counts[label])
+	r.byValue[value] = p
+	r.byPlaceholder[p] = value
+	return p
+}
+
+// Restore puts the real values back in place of the placeholders in text.
+func (r *Redactor) Restore(text string) string {
+	if r == nil || !strings.Contains(text, "[REDACTED_") {
+		return text
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// Longest first, so that [REDACTED_X_1] never replaces a part of [REDACTED_X_12]
+	placeholders := make([]string, 0, len(r.byPlaceholder))
+	for p := range r.byPlaceholder {
+		if strings.Contains(text, p) {
+			placeholders = append(placeholders, p)
+		}
+	}
+	sort.Slice(placeholders, func(i, j int) bool { return len(placeholders[i]) > len(placeholders[j]) })
+	for _, p := range placeholders {
+		text = strings.ReplaceAll(text, p, r.byPlaceholder[p])
+	}
+	return text
+}
+
+// Count returns the number of distinct values redacted so far.
+func (r *Redactor) Count() int {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.byValue)
+}
+
+// restoreValue restores the placeholders in the strings of a tool argument value.
+func (r *Redactor) restoreValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return r.Restore(val)
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, item := range val {
+			out[i] = r.restoreValue(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			out[k] = r.restoreValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// redactionRestoredTools are the tools that write to the disk, they get the real values back
+// so that files are not corrupted by placeholders. Commands keep the placeholders: a command
+// could send the secret anywhere.
+var redactionRestoredTools = map[string]bool{
+	ToolWriteFile:       true,
+	ToolEditFile:        true,
+	ToolApplyChanges:    true,
+	ToolCreateDirectory: true,
+	ToolDeleteFile:      true,
+	ToolDeleteDirectory: true,
+	ToolRestoreDeleted:  true,
+	ToolMove:            true,
+	ToolCopy:            true,
+	ToolSaveMemory:      true,
+}
+
+// restoreToolArgs returns the arguments a tool runs with: a copy with the real values
+// for tools that touch the disk, the arguments unchanged for all others.
+// The original map is left alone, it can be part of the session history.
+func (r *Redactor) restoreToolArgs(name string, args map[string]interface{}) map[string]interface{} {
+	if r == nil || !redactionRestoredTools[name] || r.Count() == 0 {
+		return args
+	}
+	return r.restoreValue(args).(map[string]interface{})
+}
+
+// redactFiles returns the attachments with the text files redacted, other files are sent as they are.
+func (r *Redactor) redactFiles(files []*FileData) []*FileData {
+	if r == nil || len(files) == 0 {
+		return files
+	}
+	out := make([]*FileData, len(files))
+	for i, f := range files {
+		out[i] = f
+		if f != nil && IsTextMIMEType(f.Format()) {
+			out[i] = NewFileData(f.Format(), []byte(r.Redact(string(f.Data()))), f.Path())
+		}
+	}
+	return out
+}
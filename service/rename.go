@@ -81,7 +81,7 @@ func GenerateSessionName(modelConfig *data.AgentConfig, sessionData []byte) (str
 
 	switch modelConfig.Model.Provider {
 
-	case ModelProviderOpenAI:
+	case ModelProviderOpenAI, ModelProviderMock:
 		var messages []openai.ChatCompletionMessageParamUnion
 		if err = parseJSONL(sessionData, &messages); err != nil {
 			return "", fmt.Errorf("failed to parse OpenAI session for rename: %w", err)
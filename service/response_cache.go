@@ -0,0 +1,396 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+/*
+The response cache answers a model request that was already sent, with the same endpoint,
+headers and body, from disk. The body holds the model, the messages, the tools and the
+parameters, the headers the API key and the beta features, so only identical requests hit. It sits at the provider transport like cassettes and keeps
+the raw response, streamed ones included, so every provider works the same way. Only
+complete 200 responses of the generation endpoints are kept: batches, file uploads and the
+other calls change state on the provider and must reach it. Request headers, which carry the
+API keys, are never kept, the key only holds a digest of them.
+*/
+
+const (
+	DefaultResponseCacheTTL     = 24 * time.Hour
+	DefaultResponseCacheMaxSize = 200 // Megabytes
+	responseCacheStatsFile      = "stats.json"
+)
+
+// responseCacheDisabled is set by --no-cache for the run.
+var responseCacheDisabled atomic.Bool
+
+// DisableResponseCache turns the response cache off for this process.
+func DisableResponseCache() {
+	responseCacheDisabled.Store(true)
+}
+
+// ResponseCacheConfig reads the TTL and the size limit in bytes from the settings.
+func ResponseCacheConfig(s data.CacheSettings) (time.Duration, int64) {
+	ttl := DefaultResponseCacheTTL
+	if d, err := time.ParseDuration(s.TTL); err == nil && d > 0 {
+		ttl = d
+	}
+	size := s.MaxSize
+	if size <= 0 {
+		size = DefaultResponseCacheMaxSize
+	}
+	return ttl, int64(size) * 1024 * 1024
+}
+
+// activeResponseCache returns the response cache when it is on, nil otherwise.
+func activeResponseCache(base http.RoundTripper) http.RoundTripper {
+	if responseCacheDisabled.Load() {
+		return nil
+	}
+	settings := data.GetSettingsStore().GetCacheSettings()
+	if !settings.Enabled {
+		return nil
+	}
+	ttl, maxBytes := ResponseCacheConfig(settings)
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &ResponseCache{dir: data.GetCacheDirPath(), ttl: ttl, maxBytes: maxBytes, base: base}
+}
+
+// responseCacheEndpoints are the path suffixes of the generation requests the cache keeps.
+var responseCacheEndpoints = []string{"/chat/completions", "/messages", ":generateContent", ":streamGenerateContent"}
+
+// isCacheableRequest reports whether a request generates a response that may be cached.
+func isCacheableRequest(req *http.Request) bool {
+	if req.Method != http.MethodPost || req.Body == nil {
+		return false
+	}
+	for _, suffix := range responseCacheEndpoints {
+		if strings.HasSuffix(req.URL.Path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// responseCacheSkippedHeaders are the response headers that belong to the connection or the
+// session, not to the content.
+var responseCacheSkippedHeaders = []string{"Connection", "Content-Length", "Transfer-Encoding", "Set-Cookie"}
+
+// ResponseCache is a transport that serves identical provider requests from disk.
+type ResponseCache struct {
+	dir      string
+	ttl      time.Duration
+	maxBytes int64
+	base     http.RoundTripper
+}
+
+// cachedResponse is the on-disk format of a cache entry.
+type cachedResponse struct {
+	CreatedAt   time.Time   `json:"created_at"`
+	Method      string      `json:"method"`
+	Endpoint    string      `json:"endpoint"` // Host and path, the query may carry a key
+	Status      int         `json:"status"`
+	ContentType string      `json:"content_type,omitempty"` // Kept for the entries without headers
+	Header      http.Header `json:"header,omitempty"`
+	Body        string      `json:"body"`
+}
+
+// ResponseCacheStats are the counters of 'gllm cache stats'.
+type ResponseCacheStats struct {
+	Since   time.Time `json:"since"`
+	Hits    int64     `json:"hits"`
+	Misses  int64     `json:"misses"`
+	Entries int       `json:"-"`
+	Bytes   int64     `json:"-"`
+	Oldest  time.Time `json:"-"`
+	Newest  time.Time `json:"-"`
+}
+
+// responseCacheIgnoredHeaders don't change the response: the transport's, and the ones the
+// SDKs set anew for each attempt. Headers starting with X-Stainless- are left out too.
+var responseCacheIgnoredHeaders = map[string]bool{
+	"Accept-Encoding": true, "Connection": true, "Content-Length": true, "Transfer-Encoding": true,
+	"User-Agent": true, "Idempotency-Key": true, "Traceparent": true, "Tracestate": true,
+}
+
+// responseCacheAuthHeaders carry the credentials of a request, only a digest of them is keyed.
+var responseCacheAuthHeaders = map[string]bool{
+	"Authorization": true, "X-Api-Key": true, "X-Goog-Api-Key": true, "Api-Key": true,
+}
+
+// responseCacheKey hashes the endpoint, the headers and the body of a request. Requests of
+// another API key, account or beta feature don't share their responses.
+func responseCacheKey(req *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s%s?%s\n", req.Method, req.URL.Host, req.URL.Path, req.URL.RawQuery)
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		canonical := http.CanonicalHeaderKey(name)
+		if responseCacheIgnoredHeaders[canonical] || strings.HasPrefix(canonical, "X-Stainless-") {
+			continue
+		}
+		value := strings.Join(req.Header[name], ",")
+		if responseCacheAuthHeaders[canonical] {
+			sum := sha256.Sum256([]byte(value))
+			value = hex.EncodeToString(sum[:])
+		}
+		fmt.Fprintf(h, "%s: %s\n", strings.ToLower(canonical), value)
+	}
+	h.Write([]byte("\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *ResponseCache) entryPath(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// RoundTrip answers a request from the cache, or forwards it and keeps the response once it
+// has been read to the end.
+func (c *ResponseCache) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Listing models and other reads are not worth keeping, other writes must not be kept
+	if !isCacheableRequest(req) {
+		return c.base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	key := responseCacheKey(req, body)
+
+	if entry, ok := c.lookup(key); ok {
+		recordResponseCacheStats(c.dir, true)
+		header := entry.Header.Clone()
+		if header == nil {
+			header = http.Header{}
+		}
+		if header.Get("Content-Type") == "" && entry.ContentType != "" {
+			header.Set("Content-Type", entry.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", entry.Status, http.StatusText(entry.Status)),
+			StatusCode:    entry.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          io.NopCloser(strings.NewReader(entry.Body)),
+			ContentLength: int64(len(entry.Body)),
+			Request:       req,
+		}, nil
+	}
+	recordResponseCacheStats(c.dir, false)
+
+	resp, err := c.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	header := resp.Header.Clone()
+	for _, h := range responseCacheSkippedHeaders {
+		header.Del(h)
+	}
+	resp.Body = &cacheBody{
+		ReadCloser: resp.Body,
+		onComplete: func(content []byte) {
+			c.store(key, cachedResponse{
+				CreatedAt:   time.Now(),
+				Method:      req.Method,
+				Endpoint:    req.URL.Host + req.URL.Path,
+				Status:      resp.StatusCode,
+				ContentType: resp.Header.Get("Content-Type"),
+				Header:      header,
+				Body:        string(content),
+			})
+		},
+	}
+	return resp, nil
+}
+
+// lookup reads an entry that has not expired.
+func (c *ResponseCache) lookup(key string) (*cachedResponse, bool) {
+	content, err := os.ReadFile(c.entryPath(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry cachedResponse
+	if err := json.Unmarshal(content, &entry); err != nil || time.Since(entry.CreatedAt) > c.ttl {
+		os.Remove(c.entryPath(key))
+		return nil, false
+	}
+	return &entry, true
+}
+
+// store writes an entry and prunes the cache back under its size limit.
+func (c *ResponseCache) store(key string, entry cachedResponse) {
+	if int64(len(entry.Body)) > c.maxBytes {
+		return
+	}
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return
+	}
+	content, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	// Prompts may be private, the entries are only readable by the user
+	if err := os.WriteFile(c.entryPath(key), content, 0600); err != nil {
+		return
+	}
+	pruneResponseCache(c.dir, c.ttl, c.maxBytes)
+}
+
+// cacheBody reports the whole body once it has been read to the end, a stream closed
+// early is not complete and is not reported.
+type cacheBody struct {
+	io.ReadCloser
+	buf        bytes.Buffer
+	once       sync.Once
+	onComplete func([]byte)
+}
+
+func (b *cacheBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.once.Do(func() { b.onComplete(b.buf.Bytes()) })
+	}
+	return n, err
+}
+
+type cacheEntryFile struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func responseCacheEntries(dir string) []cacheEntryFile {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var entries []cacheEntryFile
+	for _, e := range dirEntries {
+		if e.IsDir() || e.Name() == responseCacheStatsFile || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, cacheEntryFile{path: filepath.Join(dir, e.Name()), size: info.Size(), modTime: info.ModTime()})
+	}
+	return entries
+}
+
+// pruneResponseCache removes the expired entries, then the oldest ones until the cache fits.
+func pruneResponseCache(dir string, ttl time.Duration, maxBytes int64) {
+	entries := responseCacheEntries(dir)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].modTime.Before(entries[j].modTime) })
+	var total int64
+	var kept []cacheEntryFile
+	for _, e := range entries {
+		if time.Since(e.modTime) > ttl {
+			os.Remove(e.path)
+			continue
+		}
+		total += e.size
+		kept = append(kept, e)
+	}
+	for _, e := range kept {
+		if total <= maxBytes {
+			break
+		}
+		os.Remove(e.path)
+		total -= e.size
+	}
+}
+
+var responseCacheStatsMu sync.Mutex
+
+func loadResponseCacheStats(dir string) ResponseCacheStats {
+	var stats ResponseCacheStats
+	if content, err := os.ReadFile(filepath.Join(dir, responseCacheStatsFile)); err == nil {
+		json.Unmarshal(content, &stats)
+	}
+	return stats
+}
+
+// recordResponseCacheStats counts a hit or a miss.
+func recordResponseCacheStats(dir string, hit bool) {
+	responseCacheStatsMu.Lock()
+	defer responseCacheStatsMu.Unlock()
+	stats := loadResponseCacheStats(dir)
+	if stats.Since.IsZero() {
+		stats.Since = time.Now()
+	}
+	if hit {
+		stats.Hits++
+	} else {
+		stats.Misses++
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return
+	}
+	if content, err := json.Marshal(stats); err == nil {
+		os.WriteFile(filepath.Join(dir, responseCacheStatsFile), content, 0600)
+	}
+}
+
+// GetResponseCacheStats returns the counters and the size of the response cache.
+func GetResponseCacheStats() ResponseCacheStats {
+	dir := data.GetCacheDirPath()
+	responseCacheStatsMu.Lock()
+	stats := loadResponseCacheStats(dir)
+	responseCacheStatsMu.Unlock()
+	for _, e := range responseCacheEntries(dir) {
+		stats.Entries++
+		stats.Bytes += e.size
+		if stats.Oldest.IsZero() || e.modTime.Before(stats.Oldest) {
+			stats.Oldest = e.modTime
+		}
+		if e.modTime.After(stats.Newest) {
+			stats.Newest = e.modTime
+		}
+	}
+	return stats
+}
+
+// ClearResponseCache removes every cached response and resets the counters, it returns
+// the number of responses removed.
+func ClearResponseCache() (int, error) {
+	dir := data.GetCacheDirPath()
+	entries := responseCacheEntries(dir)
+	for _, e := range entries {
+		if err := os.Remove(e.path); err != nil {
+			return 0, fmt.Errorf("failed to clear the cache: %w", err)
+		}
+	}
+	responseCacheStatsMu.Lock()
+	defer responseCacheStatsMu.Unlock()
+	if err := os.Remove(filepath.Join(dir, responseCacheStatsFile)); err != nil && !os.IsNotExist(err) {
+		return len(entries), fmt.Errorf("failed to reset the counters: %w", err)
+	}
+	return len(entries), nil
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "fail") {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "data: answer to "+string(body)+"\n\n")
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	client := &http.Client{Transport: &ResponseCache{dir: dir, ttl: time.Hour, maxBytes: 1 << 20, base: http.DefaultTransport}}
+	post := func(body string) string {
+		resp, err := client.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		content, _ := io.ReadAll(resp.Body)
+		return string(content)
+	}
+
+	first := post(`{"model":"a","messages":["hi"]}`)
+	if second := post(`{"model":"a","messages":["hi"]}`); second != first || calls != 1 {
+		t.Fatalf("expected the identical request from the cache, got %q after %d calls", second, calls)
+	}
+	post(`{"model":"b","messages":["hi"]}`)
+	if calls != 2 {
+		t.Errorf("expected another model to miss, got %d calls", calls)
+	}
+	post(`{"fail":true}`)
+	post(`{"fail":true}`)
+	if calls != 4 {
+		t.Errorf("expected errors not to be cached, got %d calls", calls)
+	}
+
+	// A stream abandoned halfway is not complete
+	resp, err := client.Post(srv.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"model":"c"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Read(make([]byte, 4))
+	resp.Body.Close()
+	post(`{"model":"c"}`)
+	if calls != 6 {
+		t.Errorf("expected the abandoned stream not to be cached, got %d calls", calls)
+	}
+
+	stats := loadResponseCacheStats(dir)
+	if stats.Hits != 1 || stats.Misses != 6 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestResponseCacheOnlyGeneration(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Goog-Upload-Url", "https://upload.example.com/session")
+		io.WriteString(w, `{"id":"batch_1"}`)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &ResponseCache{dir: t.TempDir(), ttl: time.Hour, maxBytes: 1 << 20, base: http.DefaultTransport}}
+	post := func(path string) *http.Response {
+		resp, err := client.Post(srv.URL+path, "application/json", strings.NewReader(`{"model":"a"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp
+	}
+
+	// Batches and uploads change state on the provider, they always reach it
+	for _, path := range []string{"/v1/messages/batches", "/upload/v1beta/files"} {
+		post(path)
+		post(path)
+	}
+	if calls != 4 {
+		t.Errorf("expected the non-generation requests not to be cached, got %d calls", calls)
+	}
+
+	post("/v1beta/models/gemini:generateContent")
+	resp := post("/v1beta/models/gemini:generateContent")
+	if calls != 5 {
+		t.Errorf("expected the generation request from the cache, got %d calls", calls)
+	}
+	if resp.Header.Get("X-Goog-Upload-Url") == "" || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("expected the cached response to keep its headers, got %v", resp.Header)
+	}
+}
+
+func TestResponseCacheExpiryAndSize(t *testing.T) {
+	dir := t.TempDir()
+	c := &ResponseCache{dir: dir, ttl: time.Hour, maxBytes: 1 << 20}
+	c.store("old", cachedResponse{CreatedAt: time.Now().Add(-2 * time.Hour), Status: 200, Body: "stale"})
+	if _, ok := c.lookup("old"); ok {
+		t.Error("expected the expired entry to miss")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old.json")); !os.IsNotExist(err) {
+		t.Error("expected the expired entry to be removed")
+	}
+
+	// Two entries fit, the third one pushes out the oldest
+	c.maxBytes = 450
+	for i, key := range []string{"a", "b"} {
+		c.store(key, cachedResponse{CreatedAt: time.Now(), Status: 200, Body: strings.Repeat("x", 100)})
+		age := time.Now().Add(time.Duration(i-2) * 10 * time.Second)
+		os.Chtimes(filepath.Join(dir, key+".json"), age, age)
+	}
+	c.store("c", cachedResponse{CreatedAt: time.Now(), Status: 200, Body: strings.Repeat("x", 100)})
+	if _, ok := c.lookup("a"); ok {
+		t.Error("expected the oldest entry to be pruned to fit the size limit")
+	}
+	if _, ok := c.lookup("b"); !ok {
+		t.Error("expected the newer entries to be kept")
+	}
+	if _, ok := c.lookup("c"); !ok {
+		t.Error("expected the newest entry to be kept")
+	}
+}
+
+func TestResponseCacheKeyHeaders(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		io.WriteString(w, `{"content":"answer"}`)
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: &ResponseCache{dir: t.TempDir(), ttl: time.Hour, maxBytes: 1 << 20, base: http.DefaultTransport}}
+	post := func(headers map[string]string) {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/v1/messages", strings.NewReader(`{"model":"claude"}`))
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	post(map[string]string{"X-Api-Key": "key-1", "Anthropic-Beta": "files-api-2025-04-14", "X-Stainless-Retry-Count": "0"})
+	// The retry count changes on each attempt, not the response
+	post(map[string]string{"X-Api-Key": "key-1", "Anthropic-Beta": "files-api-2025-04-14", "X-Stainless-Retry-Count": "1"})
+	if calls != 1 {
+		t.Errorf("expected the retried request from the cache, got %d calls", calls)
+	}
+	post(map[string]string{"X-Api-Key": "key-1", "Anthropic-Beta": "computer-use-2025-01-24"})
+	if calls != 2 {
+		t.Errorf("expected another beta to miss, got %d calls", calls)
+	}
+	post(map[string]string{"X-Api-Key": "key-2", "Anthropic-Beta": "files-api-2025-04-14"})
+	if calls != 3 {
+		t.Errorf("expected another API key to miss, got %d calls", calls)
+	}
+}
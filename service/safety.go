@@ -0,0 +1,241 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+	"google.golang.org/genai"
+)
+
+// geminiBlockFinishReasons are the finish reasons of a Gemini candidate stopped by a filter.
+var geminiBlockFinishReasons = map[genai.FinishReason]bool{
+	genai.FinishReasonSafety:                 true,
+	genai.FinishReasonRecitation:             true,
+	genai.FinishReasonBlocklist:              true,
+	genai.FinishReasonProhibitedContent:      true,
+	genai.FinishReasonSPII:                   true,
+	genai.FinishReasonImageSafety:            true,
+	genai.FinishReasonImageProhibitedContent: true,
+	genai.FinishReasonImageRecitation:        true,
+}
+
+// geminiSafetyBlock returns the block of a Gemini response, nil when it wasn't blocked.
+func geminiSafetyBlock(resp *genai.GenerateContentResponse) *SafetyBlockError {
+	if resp == nil {
+		return nil
+	}
+	if fb := resp.PromptFeedback; fb != nil && fb.BlockReason != "" {
+		return &SafetyBlockError{
+			Provider:   ModelProviderGemini,
+			Reason:     string(fb.BlockReason),
+			Categories: blockedHarmCategories(fb.SafetyRatings),
+			Prompt:     true,
+		}
+	}
+	for _, candidate := range resp.Candidates {
+		if candidate != nil && geminiBlockFinishReasons[candidate.FinishReason] {
+			return &SafetyBlockError{
+				Provider:   ModelProviderGemini,
+				Reason:     string(candidate.FinishReason),
+				Categories: blockedHarmCategories(candidate.SafetyRatings),
+			}
+		}
+	}
+	return nil
+}
+
+// blockedHarmCategories names the categories that were blocked, by their short names.
+func blockedHarmCategories(ratings []*genai.SafetyRating) []string {
+	var categories []string
+	for _, r := range ratings {
+		if r != nil && r.Blocked {
+			categories = append(categories, shortHarmCategory(string(r.Category)))
+		}
+	}
+	return categories
+}
+
+// isContentFilterFinish reports whether an OpenAI style finish reason means the filter
+// stopped the response. Some compatible providers report "sensitive".
+func isContentFilterFinish(reason string) bool {
+	return reason == "content_filter" || reason == "sensitive"
+}
+
+// contentFilterErrorMarkers are found in the API errors of a prompt rejected by a filter.
+var contentFilterErrorMarkers = []string{
+	"content_filter",                // OpenAI and Azure OpenAI
+	"content_policy_violation",      // OpenAI
+	"SensitiveContentDetected",      // Volcengine Ark
+	"data_inspection_failed",        // DashScope
+	"content_management_policy",     // Azure OpenAI
+	"flagged by the content filter", // Compatible gateways
+}
+
+// safetyBlockFromError returns err as a SafetyBlockError when the provider rejected the
+// request because of its content filters, nil otherwise.
+func safetyBlockFromError(provider string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if IsSafetyBlockError(err) {
+		return err
+	}
+	msg := err.Error()
+	for _, marker := range contentFilterErrorMarkers {
+		if strings.Contains(msg, marker) {
+			return SafetyBlockError{Provider: provider, Reason: "content_filter", Prompt: true}
+		}
+	}
+	return nil
+}
+
+// SafetyBlockHint explains a block and what can be done about it.
+func SafetyBlockHint(e SafetyBlockError) string {
+	var hints []string
+	switch strings.ToUpper(e.Reason) {
+	case "RECITATION", "IMAGE_RECITATION":
+		hints = append(hints, "The response reproduced existing content too closely, e.g. copyrighted text. Ask for a summary or for original wording.")
+	case "SPII":
+		hints = append(hints, "The response held sensitive personal information. Leave the personal details out of the request.")
+	case "BLOCKLIST", "PROHIBITED_CONTENT", "IMAGE_PROHIBITED_CONTENT":
+		hints = append(hints, "The content is prohibited by the provider's terms and cannot be unblocked by settings.")
+	default:
+		if e.Prompt {
+			hints = append(hints, "Rephrase the request, or remove the part of the prompt or attachments that tripped the filter.")
+		} else {
+			hints = append(hints, "Rephrase the request, or ask for the part of the answer that was not blocked.")
+		}
+	}
+	if e.Provider == ModelProviderGemini && len(e.Categories) > 0 {
+		hints = append(hints, fmt.Sprintf("The Gemini thresholds can be set per model, e.g. 'gllm model set NAME --safety %s=only_high'.", e.Categories[0]))
+	}
+	if !data.GetSettingsStore().GetSafetySettings().Retry {
+		hints = append(hints, "'gllm config set safety.retry on' retries once with a sanitized prompt.")
+	}
+	return strings.Join(hints, "\n")
+}
+
+// safetyRetryInstruction is added to the system prompt of a retry after a block.
+const safetyRetryInstruction = "\n\nThe previous answer to this request was blocked by the safety filters. " +
+	"Answer within your content policies: if part of the request can't be answered, answer the rest " +
+	"and say briefly what was left out."
+
+// sessionLength returns the number of messages saved in the session.
+func (ag *Agent) sessionLength() int {
+	if err := ag.Session.Load(); err != nil {
+		return -1
+	}
+	v := reflect.ValueOf(ag.Session.GetMessages())
+	if v.Kind() != reflect.Slice {
+		return -1
+	}
+	return v.Len()
+}
+
+// prepareSafetyRetry rolls the session back to its length before the run and sanitizes the
+// prompt: secrets and personal data are redacted and the model is told about the block.
+// A run that went further than the prompt is not retried, its tool calls would run again.
+func (ag *Agent) prepareSafetyRetry(length int) (int, bool) {
+	if length < 0 || ag.sessionLength() != length+1 {
+		return 0, false
+	}
+	v := reflect.ValueOf(ag.Session.GetMessages())
+	ag.Session.SetMessages(v.Slice(0, length).Interface())
+	if err := ag.Session.Save(); err != nil {
+		return 0, false
+	}
+
+	if ag.Redactor == nil {
+		// Placeholders in tool calls are restored by the agent's redactor
+		ag.Redactor = NewRedactor(data.RedactionSettings{})
+	}
+	before := ag.Redactor.Count()
+	ag.UserPrompt = ag.Redactor.Redact(ag.UserPrompt)
+	ag.Files = ag.Redactor.redactFiles(ag.Files)
+	ag.SystemPrompt += safetyRetryInstruction
+	return ag.Redactor.Count() - before, true
+}
+
+// Gemini safety settings are kept by short names, e.g. dangerous_content: only_high.
+var (
+	geminiHarmCategories = map[string]genai.HarmCategory{
+		"harassment":        genai.HarmCategoryHarassment,
+		"hate_speech":       genai.HarmCategoryHateSpeech,
+		"sexually_explicit": genai.HarmCategorySexuallyExplicit,
+		"dangerous_content": genai.HarmCategoryDangerousContent,
+		"civic_integrity":   genai.HarmCategoryCivicIntegrity,
+	}
+	geminiBlockThresholds = map[string]genai.HarmBlockThreshold{
+		"low_and_above":    genai.HarmBlockThresholdBlockLowAndAbove,
+		"medium_and_above": genai.HarmBlockThresholdBlockMediumAndAbove,
+		"only_high":        genai.HarmBlockThresholdBlockOnlyHigh,
+		"none":             genai.HarmBlockThresholdBlockNone,
+		"off":              genai.HarmBlockThresholdOff,
+	}
+)
+
+// shortHarmCategory turns HARM_CATEGORY_HATE_SPEECH into hate_speech.
+func shortHarmCategory(category string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.ToUpper(category), "HARM_CATEGORY_"))
+}
+
+// shortBlockThreshold turns BLOCK_ONLY_HIGH into only_high.
+func shortBlockThreshold(threshold string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.ToUpper(threshold), "BLOCK_"))
+}
+
+// GeminiHarmCategories returns the short names of the Gemini harm categories.
+func GeminiHarmCategories() []string {
+	names := make([]string, 0, len(geminiHarmCategories))
+	for name := range geminiHarmCategories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GeminiBlockThresholds returns the short names of the Gemini block thresholds.
+func GeminiBlockThresholds() []string {
+	return []string{"low_and_above", "medium_and_above", "only_high", "none", "off"}
+}
+
+// ParseGeminiSafetySetting parses CATEGORY=THRESHOLD, by short or API names, and returns
+// the short names.
+func ParseGeminiSafetySetting(s string) (string, string, error) {
+	category, threshold, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("invalid safety setting '%s', expected CATEGORY=THRESHOLD", s)
+	}
+	category = shortHarmCategory(strings.TrimSpace(category))
+	threshold = shortBlockThreshold(strings.TrimSpace(threshold))
+	if _, ok := geminiHarmCategories[category]; !ok {
+		return "", "", fmt.Errorf("unknown harm category '%s', expected one of: %s", category, strings.Join(GeminiHarmCategories(), ", "))
+	}
+	if _, ok := geminiBlockThresholds[threshold]; !ok {
+		return "", "", fmt.Errorf("unknown block threshold '%s', expected one of: %s", threshold, strings.Join(GeminiBlockThresholds(), ", "))
+	}
+	return category, threshold, nil
+}
+
+// geminiSafetySettings converts the safety settings of a model for the Gemini API, unknown
+// names are skipped.
+func geminiSafetySettings(settings map[string]string) []*genai.SafetySetting {
+	var result []*genai.SafetySetting
+	for category, threshold := range settings {
+		category, threshold, err := ParseGeminiSafetySetting(category + "=" + threshold)
+		if err != nil {
+			util.LogWarnf("Skipping Gemini safety setting: %v\n", err)
+			continue
+		}
+		result = append(result, &genai.SafetySetting{
+			Category:  geminiHarmCategories[category],
+			Threshold: geminiBlockThresholds[threshold],
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Category < result[j].Category })
+	return result
+}
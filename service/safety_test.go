@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/openai/openai-go/v3"
+	"google.golang.org/genai"
+)
+
+func TestGeminiSafetyBlock(t *testing.T) {
+	if b := geminiSafetyBlock(&genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonStop}},
+	}); b != nil {
+		t.Errorf("a stopped response is not blocked, got %v", b)
+	}
+
+	b := geminiSafetyBlock(&genai.GenerateContentResponse{
+		Candidates: []*genai.Candidate{{
+			FinishReason: genai.FinishReasonSafety,
+			SafetyRatings: []*genai.SafetyRating{
+				{Category: genai.HarmCategoryHarassment},
+				{Category: genai.HarmCategoryDangerousContent, Blocked: true},
+			},
+		}},
+	})
+	if b == nil || b.Prompt || b.Reason != "SAFETY" || len(b.Categories) != 1 || b.Categories[0] != "dangerous_content" {
+		t.Fatalf("unexpected block: %+v", b)
+	}
+	if !strings.Contains(SafetyBlockHint(*b), "--safety dangerous_content=") {
+		t.Errorf("the hint doesn't tell how to set the threshold: %s", SafetyBlockHint(*b))
+	}
+
+	b = geminiSafetyBlock(&genai.GenerateContentResponse{
+		PromptFeedback: &genai.GenerateContentResponsePromptFeedback{BlockReason: genai.BlockedReasonProhibitedContent},
+	})
+	if b == nil || !b.Prompt || b.Reason != "PROHIBITED_CONTENT" {
+		t.Fatalf("unexpected prompt block: %+v", b)
+	}
+	if !strings.Contains(b.Error(), "prompt was blocked by the gemini safety filters") {
+		t.Errorf("unexpected message: %s", b.Error())
+	}
+}
+
+func TestSafetyBlockFromError(t *testing.T) {
+	if err := safetyBlockFromError(ModelProviderOpenAI, errors.New("429 quota exceeded")); err != nil {
+		t.Errorf("a quota error is not a block, got %v", err)
+	}
+	err := safetyBlockFromError(ModelProviderOpenAI, errors.New(`400 Bad Request {"code":"content_filter"}`))
+	block, ok := AsSafetyBlockError(err)
+	if !ok || !block.Prompt || block.Provider != ModelProviderOpenAI {
+		t.Fatalf("expected a prompt block, got %v", err)
+	}
+	wrapped := fmt.Errorf("error processing chat: %w", SafetyBlockError{Provider: ModelProviderAnthropic, Reason: "refusal"})
+	if got := safetyBlockFromError(ModelProviderOpenAI, wrapped); got != wrapped {
+		t.Errorf("a block must be kept as is, got %v", got)
+	}
+}
+
+func TestParseGeminiSafetySetting(t *testing.T) {
+	for _, tc := range []struct {
+		in, category, threshold string
+		ok                      bool
+	}{
+		{"dangerous_content=only_high", "dangerous_content", "only_high", true},
+		{"HARM_CATEGORY_HATE_SPEECH=BLOCK_NONE", "hate_speech", "none", true},
+		{" harassment = OFF ", "harassment", "off", true},
+		{"violence=none", "", "", false},
+		{"harassment=sometimes", "", "", false},
+		{"harassment", "", "", false},
+	} {
+		category, threshold, err := ParseGeminiSafetySetting(tc.in)
+		if (err == nil) != tc.ok || category != tc.category || threshold != tc.threshold {
+			t.Errorf("ParseGeminiSafetySetting(%q) = %q, %q, %v", tc.in, category, threshold, err)
+		}
+	}
+
+	settings := geminiSafetySettings(map[string]string{"sexually_explicit": "low_and_above", "harassment": "none", "unknown": "none"})
+	if len(settings) != 2 || settings[0].Category != genai.HarmCategoryHarassment || settings[0].Threshold != genai.HarmBlockThresholdBlockNone ||
+		settings[1].Threshold != genai.HarmBlockThresholdBlockLowAndAbove {
+		t.Errorf("unexpected Gemini settings: %+v", settings)
+	}
+}
+
+func TestPrepareSafetyRetry(t *testing.T) {
+	session := &OpenAISession{}
+	session.Messages = []openai.ChatCompletionMessageParamUnion{
+		openai.UserMessage("hi"),
+		openai.AssistantMessage("hello"),
+		openai.UserMessage("write to jane.doe@example.com"),
+	}
+	ag := &Agent{Session: session, SystemPrompt: "You help.", UserPrompt: "write to jane.doe@example.com"}
+
+	// The run went further than the prompt, its tool calls would run again
+	if _, ok := ag.prepareSafetyRetry(1); ok {
+		t.Fatal("a run with more than the prompt must not be retried")
+	}
+
+	redacted, ok := ag.prepareSafetyRetry(2)
+	if !ok || redacted != 1 {
+		t.Fatalf("expected a retry with 1 redaction, got %d, %v", redacted, ok)
+	}
+	if len(session.Messages) != 2 {
+		t.Errorf("the blocked prompt was not removed from the session: %d messages", len(session.Messages))
+	}
+	if strings.Contains(ag.UserPrompt, "jane.doe@example.com") {
+		t.Errorf("the prompt was not sanitized: %s", ag.UserPrompt)
+	}
+	if !strings.HasSuffix(ag.SystemPrompt, safetyRetryInstruction) {
+		t.Errorf("the system prompt doesn't mention the block: %s", ag.SystemPrompt)
+	}
+}
+
+func TestMockProviderContentFilter(t *testing.T) {
+	script := &MockScript{Turns: []MockTurn{{Text: "Here is how", FinishReason: "content_filter"}}}
+	_, err := runMockAgent(t, context.Background(), "mock-filtered", script, nil)
+	if err == nil || !strings.Contains(err.Error(), "response was blocked by the openai safety filters (content_filter)") {
+		t.Fatalf("expected a safety block, got %v", err)
+	}
+}
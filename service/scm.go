@@ -0,0 +1,257 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+// Code hosts of the gh_* tools. GitHub Enterprise and self-hosted GitLab are found by the
+// host of the repository.
+const (
+	SCMProviderGitHub = "github"
+	SCMProviderGitLab = "gitlab"
+)
+
+// scmRequestTimeout bounds a single call to the code host API.
+const scmRequestTimeout = 30 * time.Second
+
+// maxSCMDiffSize bounds the pull request diff returned to the model.
+const maxSCMDiffSize = 100 * 1024
+
+// SCMRepo is a repository on a code host.
+type SCMRepo struct {
+	Provider string // github or gitlab
+	Host     string // e.g. github.com
+	Path     string // owner/name, or group/subgroup/name on GitLab
+}
+
+func (r SCMRepo) String() string {
+	return r.Path
+}
+
+// SCMIssue is an issue of a repository.
+type SCMIssue struct {
+	Number   int
+	Title    string
+	State    string
+	Author   string
+	Labels   []string
+	Comments int
+	URL      string
+}
+
+// SCMIssueQuery filters the listed issues.
+type SCMIssueQuery struct {
+	State  string // open, closed or all
+	Labels []string
+	Limit  int
+}
+
+// SCMPullRequest is a pull request, a merge request on GitLab, to open.
+type SCMPullRequest struct {
+	Title string
+	Body  string
+	Head  string // Branch with the changes
+	Base  string // Branch to merge into, empty for the default branch
+	Draft bool
+}
+
+// SCMProvider is the API of a code host for one repository.
+type SCMProvider interface {
+	ListIssues(ctx context.Context, q SCMIssueQuery) ([]SCMIssue, error)
+	GetPullRequestDiff(ctx context.Context, number int) (string, error)
+	// Comment posts on an issue, or on a pull request when pullRequest is set, and returns its URL.
+	Comment(ctx context.Context, number int, pullRequest bool, body string) (string, error)
+	// CreatePullRequest opens the pull request and returns its URL.
+	CreatePullRequest(ctx context.Context, pr SCMPullRequest) (string, error)
+}
+
+// ParseSCMRepo reads a repository reference: a remote URL such as git@github.com:owner/name.git
+// or https://gitlab.com/group/name, or owner/name on the default host of the provider.
+func ParseSCMRepo(ref, provider string) (SCMRepo, error) {
+	ref = strings.TrimSpace(ref)
+	provider = strings.ToLower(strings.TrimSpace(provider))
+	var host, path string
+	switch {
+	case strings.Contains(ref, "://"):
+		u, err := url.Parse(ref)
+		if err != nil {
+			return SCMRepo{}, fmt.Errorf("invalid repository URL '%s': %w", ref, err)
+		}
+		host, path = u.Hostname(), u.Path
+	case strings.Contains(ref, "@") && strings.Contains(ref, ":"):
+		// scp-like syntax: git@host:owner/name.git
+		_, rest, _ := strings.Cut(ref, "@")
+		host, path, _ = strings.Cut(rest, ":")
+	default:
+		path = ref
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if strings.Count(path, "/") < 1 {
+		return SCMRepo{}, fmt.Errorf("invalid repository '%s', expected owner/name or a remote URL", ref)
+	}
+
+	if provider == "" {
+		switch {
+		case host == "", host == "github.com":
+			provider = SCMProviderGitHub
+		case strings.Contains(host, "gitlab"):
+			provider = SCMProviderGitLab
+		default:
+			provider = SCMProviderGitHub
+		}
+	}
+	switch provider {
+	case SCMProviderGitHub:
+		if host == "" {
+			host = "github.com"
+		}
+	case SCMProviderGitLab:
+		if host == "" {
+			host = "gitlab.com"
+		}
+	default:
+		return SCMRepo{}, fmt.Errorf("unsupported provider '%s', supported: %s, %s", provider, SCMProviderGitHub, SCMProviderGitLab)
+	}
+	return SCMRepo{Provider: provider, Host: host, Path: path}, nil
+}
+
+// ResolveSCMRepo returns the repository of the reference, or of the origin remote of the
+// working directory when it is empty.
+func ResolveSCMRepo(ctx context.Context, ref, provider string) (SCMRepo, error) {
+	if ref == "" {
+		remote, err := runGit(ctx, ".", "remote", "get-url", "origin")
+		if err != nil {
+			return SCMRepo{}, fmt.Errorf("no repository given and no origin remote found: %w", err)
+		}
+		ref = remote
+	}
+	return ParseSCMRepo(ref, provider)
+}
+
+// scmToken returns the token of the provider, from the secret store or else the environment.
+func scmToken(provider string) (string, error) {
+	secret, envs := data.SecretGitHubToken, []string{"GITHUB_TOKEN", "GH_TOKEN"}
+	if provider == SCMProviderGitLab {
+		secret, envs = data.SecretGitLabToken, []string{"GITLAB_TOKEN"}
+	}
+	token, err := data.GetSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	for _, env := range envs {
+		if token != "" {
+			break
+		}
+		token = os.Getenv(env)
+	}
+	if token == "" {
+		return "", fmt.Errorf("no %s token, set it with 'gllm config secret set %s' or $%s", provider, secret, envs[0])
+	}
+	return token, nil
+}
+
+// NewSCMProvider returns the API client of the repository, authenticated with the token of its provider.
+func NewSCMProvider(repo SCMRepo, transport http.RoundTripper) (SCMProvider, error) {
+	token, err := scmToken(repo.Provider)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: transport, Timeout: scmRequestTimeout}
+	switch repo.Provider {
+	case SCMProviderGitLab:
+		return newGitLabProvider(repo, "https://"+repo.Host+"/api/v4", token, client), nil
+	default:
+		apiURL := "https://api.github.com"
+		if repo.Host != "github.com" {
+			apiURL = "https://" + repo.Host + "/api/v3"
+		}
+		return newGitHubProvider(repo, apiURL, token, client), nil
+	}
+}
+
+// scmAPI sends requests to the REST API of a code host or an issue tracker.
+type scmAPI struct {
+	baseURL string
+	client  *http.Client
+	headers map[string]string
+}
+
+// do sends the request and decodes a JSON reply into out. With a raw accept type, the reply
+// is returned as text instead.
+func (a *scmAPI) do(ctx context.Context, method, path string, body, out any, accept string) (string, error) {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return "", err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, a.baseURL+path, reader)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range a.headers {
+		req.Header.Set(k, v)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("%s %s: %s", method, path, scmErrorMessage(resp.Status, content))
+	}
+	if out != nil {
+		if err := json.Unmarshal(content, out); err != nil {
+			return "", fmt.Errorf("failed to parse the reply of %s: %w", path, err)
+		}
+	}
+	return string(content), nil
+}
+
+// scmErrorMessage extracts the message of an API error reply.
+func scmErrorMessage(status string, content []byte) string {
+	var reply struct {
+		Message any    `json:"message"`
+		Error   string `json:"error"`
+	}
+	if json.Unmarshal(content, &reply) == nil {
+		if reply.Message != nil {
+			return fmt.Sprintf("%s: %v", status, reply.Message)
+		}
+		if reply.Error != "" {
+			return fmt.Sprintf("%s: %s", status, reply.Error)
+		}
+	}
+	return status
+}
+
+func truncateSCMDiff(diff string) string {
+	if len(diff) > maxSCMDiffSize {
+		return diff[:maxSCMDiffSize] + "\n... (diff truncated)"
+	}
+	return diff
+}
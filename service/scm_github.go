@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// githubProvider talks to the REST API of GitHub or GitHub Enterprise.
+type githubProvider struct {
+	repo SCMRepo
+	api  *scmAPI
+}
+
+func newGitHubProvider(repo SCMRepo, apiURL, token string, client *http.Client) *githubProvider {
+	return &githubProvider{repo: repo, api: &scmAPI{
+		baseURL: strings.TrimSuffix(apiURL, "/"),
+		client:  client,
+		headers: map[string]string{
+			"Authorization":        "Bearer " + token,
+			"Accept":               "application/vnd.github+json",
+			"X-GitHub-Api-Version": "2022-11-28",
+		},
+	}}
+}
+
+func (g *githubProvider) path(format string, args ...any) string {
+	return "/repos/" + g.repo.Path + fmt.Sprintf(format, args...)
+}
+
+func (g *githubProvider) ListIssues(ctx context.Context, q SCMIssueQuery) ([]SCMIssue, error) {
+	params := url.Values{}
+	params.Set("state", q.State)
+	params.Set("per_page", fmt.Sprint(q.Limit))
+	if len(q.Labels) > 0 {
+		params.Set("labels", strings.Join(q.Labels, ","))
+	}
+	var reply []struct {
+		Number   int    `json:"number"`
+		Title    string `json:"title"`
+		State    string `json:"state"`
+		HTMLURL  string `json:"html_url"`
+		Comments int    `json:"comments"`
+		User     struct {
+			Login string `json:"login"`
+		} `json:"user"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		PullRequest *struct{} `json:"pull_request"`
+	}
+	if _, err := g.api.do(ctx, http.MethodGet, g.path("/issues?%s", params.Encode()), nil, &reply, ""); err != nil {
+		return nil, err
+	}
+	var issues []SCMIssue
+	for _, r := range reply {
+		// The issues API lists pull requests too
+		if r.PullRequest != nil {
+			continue
+		}
+		issue := SCMIssue{Number: r.Number, Title: r.Title, State: r.State, Author: r.User.Login, Comments: r.Comments, URL: r.HTMLURL}
+		for _, l := range r.Labels {
+			issue.Labels = append(issue.Labels, l.Name)
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+func (g *githubProvider) GetPullRequestDiff(ctx context.Context, number int) (string, error) {
+	return g.api.do(ctx, http.MethodGet, g.path("/pulls/%d", number), nil, nil, "application/vnd.github.diff")
+}
+
+func (g *githubProvider) Comment(ctx context.Context, number int, pullRequest bool, body string) (string, error) {
+	// Pull requests take conversation comments through the issues API
+	var reply struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if _, err := g.api.do(ctx, http.MethodPost, g.path("/issues/%d/comments", number), map[string]string{"body": body}, &reply, ""); err != nil {
+		return "", err
+	}
+	return reply.HTMLURL, nil
+}
+
+func (g *githubProvider) CreatePullRequest(ctx context.Context, pr SCMPullRequest) (string, error) {
+	if pr.Base == "" {
+		var repo struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if _, err := g.api.do(ctx, http.MethodGet, g.path(""), nil, &repo, ""); err != nil {
+			return "", err
+		}
+		pr.Base = repo.DefaultBranch
+	}
+	payload := map[string]any{"title": pr.Title, "body": pr.Body, "head": pr.Head, "base": pr.Base, "draft": pr.Draft}
+	var reply struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if _, err := g.api.do(ctx, http.MethodPost, g.path("/pulls"), payload, &reply, ""); err != nil {
+		return "", err
+	}
+	return reply.HTMLURL, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// gitlabProvider talks to the REST API of GitLab, where pull requests are merge requests
+// and numbers are the project scoped IIDs.
+type gitlabProvider struct {
+	repo SCMRepo
+	api  *scmAPI
+}
+
+func newGitLabProvider(repo SCMRepo, apiURL, token string, client *http.Client) *gitlabProvider {
+	return &gitlabProvider{repo: repo, api: &scmAPI{
+		baseURL: strings.TrimSuffix(apiURL, "/"),
+		client:  client,
+		headers: map[string]string{"PRIVATE-TOKEN": token},
+	}}
+}
+
+func (g *gitlabProvider) path(format string, args ...any) string {
+	return "/projects/" + url.PathEscape(g.repo.Path) + fmt.Sprintf(format, args...)
+}
+
+func (g *gitlabProvider) ListIssues(ctx context.Context, q SCMIssueQuery) ([]SCMIssue, error) {
+	params := url.Values{}
+	switch q.State {
+	case "open":
+		params.Set("state", "opened")
+	case "closed":
+		params.Set("state", "closed")
+	}
+	params.Set("per_page", fmt.Sprint(q.Limit))
+	if len(q.Labels) > 0 {
+		params.Set("labels", strings.Join(q.Labels, ","))
+	}
+	var reply []struct {
+		IID            int      `json:"iid"`
+		Title          string   `json:"title"`
+		State          string   `json:"state"`
+		WebURL         string   `json:"web_url"`
+		UserNotesCount int      `json:"user_notes_count"`
+		Labels         []string `json:"labels"`
+		Author         struct {
+			Username string `json:"username"`
+		} `json:"author"`
+	}
+	if _, err := g.api.do(ctx, http.MethodGet, g.path("/issues?%s", params.Encode()), nil, &reply, ""); err != nil {
+		return nil, err
+	}
+	issues := make([]SCMIssue, 0, len(reply))
+	for _, r := range reply {
+		state := r.State
+		if state == "opened" {
+			state = "open"
+		}
+		issues = append(issues, SCMIssue{Number: r.IID, Title: r.Title, State: state, Author: r.Author.Username, Labels: r.Labels, Comments: r.UserNotesCount, URL: r.WebURL})
+	}
+	return issues, nil
+}
+
+func (g *gitlabProvider) GetPullRequestDiff(ctx context.Context, number int) (string, error) {
+	var reply []struct {
+		OldPath     string `json:"old_path"`
+		NewPath     string `json:"new_path"`
+		Diff        string `json:"diff"`
+		NewFile     bool   `json:"new_file"`
+		DeletedFile bool   `json:"deleted_file"`
+	}
+	if _, err := g.api.do(ctx, http.MethodGet, g.path("/merge_requests/%d/diffs?per_page=100", number), nil, &reply, ""); err != nil {
+		return "", err
+	}
+	// GitLab returns the hunks of each file, put the file headers back for a unified diff
+	var sb strings.Builder
+	for _, f := range reply {
+		oldPath, newPath := "a/"+f.OldPath, "b/"+f.NewPath
+		if f.NewFile {
+			oldPath = "/dev/null"
+		}
+		if f.DeletedFile {
+			newPath = "/dev/null"
+		}
+		fmt.Fprintf(&sb, "diff --git a/%s b/%s\n--- %s\n+++ %s\n%s", f.OldPath, f.NewPath, oldPath, newPath, f.Diff)
+		if !strings.HasSuffix(f.Diff, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String(), nil
+}
+
+func (g *gitlabProvider) Comment(ctx context.Context, number int, pullRequest bool, body string) (string, error) {
+	kind := "issues"
+	if pullRequest {
+		kind = "merge_requests"
+	}
+	var reply struct {
+		ID int `json:"id"`
+	}
+	if _, err := g.api.do(ctx, http.MethodPost, g.path("/%s/%d/notes", kind, number), map[string]string{"body": body}, &reply, ""); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("https://%s/%s/-/%s/%d#note_%d", g.repo.Host, g.repo.Path, kind, number, reply.ID), nil
+}
+
+func (g *gitlabProvider) CreatePullRequest(ctx context.Context, pr SCMPullRequest) (string, error) {
+	if pr.Base == "" {
+		var project struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if _, err := g.api.do(ctx, http.MethodGet, g.path(""), nil, &project, ""); err != nil {
+			return "", err
+		}
+		pr.Base = project.DefaultBranch
+	}
+	title := pr.Title
+	if pr.Draft && !strings.HasPrefix(strings.ToLower(title), "draft:") {
+		title = "Draft: " + title
+	}
+	payload := map[string]any{"title": title, "description": pr.Body, "source_branch": pr.Head, "target_branch": pr.Base}
+	var reply struct {
+		WebURL string `json:"web_url"`
+	}
+	if _, err := g.api.do(ctx, http.MethodPost, g.path("/merge_requests"), payload, &reply, ""); err != nil {
+		return "", err
+	}
+	return reply.WebURL, nil
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseSCMRepo(t *testing.T) {
+	tests := []struct {
+		ref, provider string
+		want          SCMRepo
+	}{
+		{"activebook/gllm", "", SCMRepo{SCMProviderGitHub, "github.com", "activebook/gllm"}},
+		{"git@github.com:activebook/gllm.git", "", SCMRepo{SCMProviderGitHub, "github.com", "activebook/gllm"}},
+		{"https://gitlab.com/group/sub/project.git", "", SCMRepo{SCMProviderGitLab, "gitlab.com", "group/sub/project"}},
+		{"group/project", "gitlab", SCMRepo{SCMProviderGitLab, "gitlab.com", "group/project"}},
+		{"https://git.example.com/team/app", "gitlab", SCMRepo{SCMProviderGitLab, "git.example.com", "team/app"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSCMRepo(tt.ref, tt.provider)
+		if err != nil {
+			t.Errorf("ParseSCMRepo(%q, %q) error: %v", tt.ref, tt.provider, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSCMRepo(%q, %q) = %+v, want %+v", tt.ref, tt.provider, got, tt.want)
+		}
+	}
+
+	for _, ref := range []string{"gllm", ""} {
+		if _, err := ParseSCMRepo(ref, ""); err == nil {
+			t.Errorf("ParseSCMRepo(%q) should fail", ref)
+		}
+	}
+	if _, err := ParseSCMRepo("a/b", "bitbucket"); err == nil {
+		t.Error("ParseSCMRepo with an unknown provider should fail")
+	}
+}
+
+func TestGitHubProviderListIssuesSkipsPullRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/o/r/issues" || r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("labels") != "bug,ui" {
+			t.Errorf("labels = %q", r.URL.Query().Get("labels"))
+		}
+		w.Write([]byte(`[
+			{"number":1,"title":"Crash","state":"open","user":{"login":"ann"},"labels":[{"name":"bug"}],"comments":2},
+			{"number":2,"title":"Fix crash","state":"open","user":{"login":"bob"},"pull_request":{}}
+		]`))
+	}))
+	defer server.Close()
+
+	g := newGitHubProvider(SCMRepo{SCMProviderGitHub, "github.com", "o/r"}, server.URL, "tok", server.Client())
+	issues, err := g.ListIssues(context.Background(), SCMIssueQuery{State: "open", Labels: []string{"bug", "ui"}, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issues) != 1 || issues[0].Number != 1 || issues[0].Author != "ann" || issues[0].Labels[0] != "bug" {
+		t.Errorf("issues = %+v", issues)
+	}
+
+	g = newGitHubProvider(SCMRepo{SCMProviderGitHub, "github.com", "o/missing"}, server.URL, "tok", server.Client())
+	if _, err := g.ListIssues(context.Background(), SCMIssueQuery{State: "open", Limit: 10}); err == nil {
+		t.Error("expected the API error")
+	}
+}
+
+func TestGitLabProviderDiffAndComment(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.EscapedPath() {
+		case "/projects/g%2Fp/merge_requests/7/diffs":
+			w.Write([]byte(`[{"old_path":"a.go","new_path":"a.go","diff":"@@ -1 +1 @@\n-x\n+y\n"},{"old_path":"b.go","new_path":"b.go","new_file":true,"diff":"@@ -0,0 +1 @@\n+z"}]`))
+		case "/projects/g%2Fp/merge_requests/7/notes":
+			var body map[string]string
+			json.NewDecoder(r.Body).Decode(&body)
+			if body["body"] != "LGTM" {
+				t.Errorf("note body = %q", body["body"])
+			}
+			w.Write([]byte(`{"id":42}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	g := newGitLabProvider(SCMRepo{SCMProviderGitLab, "gitlab.com", "g/p"}, server.URL, "tok", server.Client())
+	diff, err := g.GetPullRequestDiff(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "diff --git a/a.go b/a.go\n--- a/a.go\n+++ b/a.go\n@@ -1 +1 @@\n-x\n+y\n" +
+		"diff --git a/b.go b/b.go\n--- /dev/null\n+++ b/b.go\n@@ -0,0 +1 @@\n+z\n"
+	if diff != want {
+		t.Errorf("diff =\n%s\nwant\n%s", diff, want)
+	}
+
+	url, err := g.Comment(context.Background(), 7, true, "LGTM")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != "https://gitlab.com/g/p/-/merge_requests/7#note_42" {
+		t.Errorf("url = %s", url)
+	}
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+	"go.starlark.net/lib/json"
+	"go.starlark.net/starlark"
+)
+
+// Hook scripts are Starlark files (*.star) in the hooks directory. A script defines any of
+// these functions, which run in the order of the file names:
+//
+//	on_prompt(prompt, agent)          returns the prompt to send, None keeps it; fail() refuses it
+//	on_tool_call(tool, args, agent)   returns a reason to block the call, None or False lets it run
+//	on_response(text, agent)          returns text shown after the response, None adds nothing
+//	on_session_end(session)           runs when a one-shot run or the REPL ends
+const (
+	HookOnPrompt     = "on_prompt"
+	HookOnToolCall   = "on_tool_call"
+	HookOnResponse   = "on_response"
+	HookOnSessionEnd = "on_session_end"
+
+	// hookMaxSteps stops a hook stuck in a loop
+	hookMaxSteps = 10_000_000
+)
+
+// SupportedScriptHooks lists the functions a hook script may define.
+var SupportedScriptHooks = []string{HookOnPrompt, HookOnToolCall, HookOnResponse, HookOnSessionEnd}
+
+// HookScript is a loaded hook script.
+type HookScript struct {
+	Name    string   // File name
+	Hooks   []string // Hook functions it defines
+	globals starlark.StringDict
+}
+
+// ScriptHooks runs the hook scripts. A nil *ScriptHooks has no scripts.
+type ScriptHooks struct {
+	Scripts   []HookScript
+	Errors    []error // Scripts that failed to load, they are left out
+	signature string  // Names, sizes and times of the files, to reload on changes
+}
+
+// hookBuiltins are predeclared in every script.
+var hookBuiltins = starlark.StringDict{
+	"json": json.Module,
+	"url_host": starlark.NewBuiltin("url_host", func(thread *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var raw string
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &raw); err != nil {
+			return nil, err
+		}
+		u, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || u.Host == "" {
+			// A bare host such as example.com/path
+			u, err = url.Parse("//" + strings.TrimSpace(raw))
+			if err != nil {
+				return starlark.String(""), nil
+			}
+		}
+		return starlark.String(strings.ToLower(u.Hostname())), nil
+	}),
+}
+
+// LoadScriptHooks loads the *.star files of a directory.
+func LoadScriptHooks(dir string) *ScriptHooks {
+	h := &ScriptHooks{signature: hooksSignature(dir)}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.star"))
+	sort.Strings(files)
+	for _, file := range files {
+		name := filepath.Base(file)
+		thread := newHookThread(name)
+		globals, err := starlark.ExecFile(thread, file, nil, hookBuiltins)
+		if err != nil {
+			h.Errors = append(h.Errors, fmt.Errorf("hook %s: %v", name, err))
+			continue
+		}
+		script := HookScript{Name: name, globals: globals}
+		for _, hook := range SupportedScriptHooks {
+			if _, ok := globals[hook].(starlark.Callable); ok {
+				script.Hooks = append(script.Hooks, hook)
+			}
+		}
+		h.Scripts = append(h.Scripts, script)
+	}
+	return h
+}
+
+// hooksSignature describes the scripts of a directory, it changes when one is edited.
+func hooksSignature(dir string) string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.star"))
+	sort.Strings(files)
+	var sb strings.Builder
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			fmt.Fprintf(&sb, "%s:%d:%d;", file, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return sb.String()
+}
+
+var (
+	activeHooks   *ScriptHooks
+	activeHooksMu sync.Mutex
+)
+
+// GetScriptHooks returns the hook scripts of the hooks directory, reloaded when they change.
+// Scripts that fail to load are reported once and left out.
+func GetScriptHooks() *ScriptHooks {
+	dir := data.GetHooksDirPath()
+	signature := hooksSignature(dir)
+	activeHooksMu.Lock()
+	defer activeHooksMu.Unlock()
+	if signature == "" {
+		activeHooks = nil
+		return nil
+	}
+	if activeHooks != nil && activeHooks.signature == signature {
+		return activeHooks
+	}
+	activeHooks = LoadScriptHooks(dir)
+	for _, err := range activeHooks.Errors {
+		util.LogWarnf("%v\n", err)
+	}
+	return activeHooks
+}
+
+func newHookThread(script string) *starlark.Thread {
+	thread := &starlark.Thread{
+		Name: script,
+		Print: func(_ *starlark.Thread, msg string) {
+			util.LogInfof("[%s] %s\n", script, msg)
+		},
+	}
+	thread.SetMaxExecutionSteps(hookMaxSteps)
+	return thread
+}
+
+// call runs a hook function of every script that defines it with the arguments args returns,
+// passing each result to next. It stops at the first error.
+func (h *ScriptHooks) call(hook string, args func() starlark.Tuple, next func(script string, result starlark.Value) error) error {
+	if h == nil {
+		return nil
+	}
+	for _, script := range h.Scripts {
+		fn, ok := script.globals[hook].(starlark.Callable)
+		if !ok {
+			continue
+		}
+		result, err := starlark.Call(newHookThread(script.Name), fn, args(), nil)
+		if err != nil {
+			if evalErr, ok := err.(*starlark.EvalError); ok {
+				err = fmt.Errorf("%s", evalErr.Msg)
+			}
+			return fmt.Errorf("%s in %s: %v", hook, script.Name, err)
+		}
+		if err := next(script.Name, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prompt runs on_prompt, each script getting the prompt the previous one returned.
+func (h *ScriptHooks) Prompt(prompt, agent string) (string, error) {
+	args := func() starlark.Tuple { return starlark.Tuple{starlark.String(prompt), starlark.String(agent)} }
+	err := h.call(HookOnPrompt, args, func(script string, result starlark.Value) error {
+		switch v := result.(type) {
+		case starlark.NoneType:
+		case starlark.String:
+			prompt = string(v)
+		default:
+			return fmt.Errorf("%s in %s returned %s, expected a string or None", HookOnPrompt, script, result.Type())
+		}
+		return nil
+	})
+	return prompt, err
+}
+
+// ToolCall runs on_tool_call and returns why the call is blocked, empty when it may run.
+func (h *ScriptHooks) ToolCall(tool string, args map[string]interface{}, agent string) string {
+	var reason string
+	hookArgs := func() starlark.Tuple {
+		return starlark.Tuple{starlark.String(tool), toStarlarkValue(args), starlark.String(agent)}
+	}
+	err := h.call(HookOnToolCall, hookArgs, func(script string, result starlark.Value) error {
+		if !result.Truth() {
+			return nil // None, False or ""
+		}
+		reason = fmt.Sprintf("blocked by %s", script)
+		if s, ok := result.(starlark.String); ok {
+			reason = string(s)
+		}
+		return errHookBlocked
+	})
+	if err != nil && err != errHookBlocked {
+		// A broken hook blocks, a guard that fails open would be no guard
+		return err.Error()
+	}
+	return reason
+}
+
+var errHookBlocked = errors.New("blocked by a hook")
+
+// Response runs on_response and returns what the scripts add after the response.
+func (h *ScriptHooks) Response(text, agent string) string {
+	var parts []string
+	args := func() starlark.Tuple { return starlark.Tuple{starlark.String(text), starlark.String(agent)} }
+	err := h.call(HookOnResponse, args, func(script string, result starlark.Value) error {
+		if s, ok := result.(starlark.String); ok && s != "" {
+			parts = append(parts, string(s))
+		}
+		return nil
+	})
+	if err != nil {
+		util.LogWarnf("%v\n", err)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// SessionEnd runs on_session_end.
+func (h *ScriptHooks) SessionEnd(session string) {
+	args := func() starlark.Tuple { return starlark.Tuple{starlark.String(session)} }
+	if err := h.call(HookOnSessionEnd, args, func(string, starlark.Value) error { return nil }); err != nil {
+		util.LogWarnf("%v\n", err)
+	}
+}
+
+// RunSessionEndHooks runs the on_session_end hooks of the hook scripts.
+func RunSessionEndHooks(session string) {
+	GetScriptHooks().SessionEnd(session)
+}
+
+// toStarlarkValue converts decoded JSON, such as tool arguments, to Starlark values.
+func toStarlarkValue(v interface{}) starlark.Value {
+	switch v := v.(type) {
+	case nil:
+		return starlark.None
+	case bool:
+		return starlark.Bool(v)
+	case string:
+		return starlark.String(v)
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return starlark.MakeInt64(int64(v))
+		}
+		return starlark.Float(v)
+	case int:
+		return starlark.MakeInt(v)
+	case int64:
+		return starlark.MakeInt64(v)
+	case []interface{}:
+		list := make([]starlark.Value, len(v))
+		for i, item := range v {
+			list[i] = toStarlarkValue(item)
+		}
+		return starlark.NewList(list)
+	case []string:
+		list := make([]starlark.Value, len(v))
+		for i, item := range v {
+			list[i] = starlark.String(item)
+		}
+		return starlark.NewList(list)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		dict := starlark.NewDict(len(v))
+		for _, k := range keys {
+			dict.SetKey(starlark.String(k), toStarlarkValue(v[k]))
+		}
+		return dict
+	default:
+		return starlark.String(fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func writeHookScript(t *testing.T, name, content string) {
+	t.Helper()
+	dir := data.GetHooksDirPath()
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestScriptHooks(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	writeHookScript(t, "10-guard.star", `
+def on_prompt(prompt, agent):
+    if "forbidden" in prompt:
+        fail("forbidden prompt")
+    return prompt + " please"
+
+def on_tool_call(tool, args, agent):
+    if url_host(args.get("url", "")) == "internal.example.com":
+        return "internal hosts are off limits"
+    if args.get("path", "").endswith(".secret"):
+        return True
+
+def on_response(text, agent):
+    return "-- answered by %s in %d words" % (agent, len(text.split()))
+`)
+	writeHookScript(t, "20-more.star", `
+def on_prompt(prompt, agent):
+    return None
+
+def on_response(text, agent):
+    return json.encode({"chars": len(text)})
+`)
+	writeHookScript(t, "30-broken.star", "def on_prompt(:\n")
+
+	hooks := GetScriptHooks()
+	if len(hooks.Scripts) != 2 || len(hooks.Errors) != 1 {
+		t.Fatalf("expected 2 scripts and 1 error, got %+v", hooks)
+	}
+	if got := strings.Join(hooks.Scripts[0].Hooks, ","); got != "on_prompt,on_tool_call,on_response" {
+		t.Errorf("unexpected hooks: %s", got)
+	}
+
+	if prompt, err := hooks.Prompt("fix it", "coder"); err != nil || prompt != "fix it please" {
+		t.Errorf("Prompt = %q, %v", prompt, err)
+	}
+	if _, err := hooks.Prompt("do the forbidden thing", "coder"); err == nil || !strings.Contains(err.Error(), "forbidden prompt") {
+		t.Errorf("expected the prompt to be refused, got %v", err)
+	}
+
+	tests := []struct {
+		args map[string]interface{}
+		want string
+	}{
+		{map[string]interface{}{"url": "https://Internal.example.com/api"}, "internal hosts are off limits"},
+		{map[string]interface{}{"path": "keys.secret"}, "blocked by 10-guard.star"},
+		{map[string]interface{}{"url": "https://example.com", "timeout": float64(10)}, ""},
+	}
+	for _, tt := range tests {
+		if got := hooks.ToolCall(ToolWebFetch, tt.args, "coder"); got != tt.want {
+			t.Errorf("ToolCall(%v) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+
+	if got := hooks.Response("two words", "coder"); got != "-- answered by coder in 2 words\n{\"chars\":9}" {
+		t.Errorf("Response = %q", got)
+	}
+
+	// Edited scripts are reloaded
+	writeHookScript(t, "20-more.star", "def on_prompt(prompt, agent):\n    return prompt.upper()\n")
+	if prompt, _ := GetScriptHooks().Prompt("go", "coder"); prompt != "GO PLEASE" {
+		t.Errorf("edited hook not reloaded, got %q", prompt)
+	}
+}
+
+func TestScriptHooksInAgentLoop(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	writeHookScript(t, "guard.star", `
+def on_prompt(prompt, agent):
+    return prompt + " (be brief)"
+
+def on_tool_call(tool, args, agent):
+    if tool == "read_file":
+        return "reading files is off"
+
+def on_response(text, agent):
+    return "-- footer"
+`)
+	script := &MockScript{Turns: []MockTurn{
+		{ToolCalls: []MockToolCall{{Name: ToolReadFile, Args: map[string]interface{}{"path": "main.go"}}}},
+		{Text: "Done."},
+	}}
+	RegisterMockScript("mock-hooks", script)
+	outPath := filepath.Join(t.TempDir(), "out.md")
+	err := CallAgent(&AgentOptions{
+		Prompt:        "hello",
+		ModelInfo:     &data.Model{Provider: ModelProviderMock, Model: "mock-hooks"},
+		EnabledTools:  []string{ToolReadFile},
+		MaxRecursions: 5,
+		YoloMode:      true,
+		QuietMode:     true,
+		OutputFile:    outPath,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	requests := script.Requests()
+	if len(requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(requests))
+	}
+	if !strings.Contains(string(requests[0]), "hello (be brief)") {
+		t.Errorf("the prompt was not rewritten: %s", requests[0])
+	}
+	if !strings.Contains(string(requests[1]), "was blocked: reading files is off") {
+		t.Errorf("the tool call was not blocked: %s", requests[1])
+	}
+	out, _ := os.ReadFile(outPath)
+	if !strings.Contains(string(out), "Done.\n-- footer") {
+		t.Errorf("the footer was not added: %q", out)
+	}
+}
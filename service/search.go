@@ -76,7 +76,7 @@ func GetNoneSearchEngineName() string {
 	return NoneSearchEngine
 }
 
-func (s *SearchEngine) TavilySearch(query string) (map[string]any, error) {
+func (s *SearchEngine) TavilySearch(ctx context.Context, query string) (map[string]any, error) {
 
 	// Format the JSON payload, inserting the query variable
 	payload := fmt.Sprintf(`{
@@ -96,7 +96,7 @@ func (s *SearchEngine) TavilySearch(query string) (map[string]any, error) {
 }`, query)
 
 	// Create a new POST request with the payload
-	req, err := http.NewRequest("POST", TavilyUrl, strings.NewReader(payload))
+	req, err := http.NewRequestWithContext(ctx, "POST", TavilyUrl, strings.NewReader(payload))
 	if err != nil {
 		util.LogErrorf("[Tavily]Error creating request: %v\n", err)
 		return nil, fmt.Errorf("[Tavily]Error creating request: %v", err)
@@ -135,7 +135,7 @@ func (s *SearchEngine) TavilySearch(query string) (map[string]any, error) {
 		return nil, fmt.Errorf("[Tavily]Error parsing JSON: %v", err)
 	}
 
-	formatted, err := s.tavilyFormatResponse(&tavilyResp)
+	formatted, err := s.tavilyFormatResponse(ctx, &tavilyResp)
 	if err != nil {
 		util.LogErrorf("[Tavily]Error formatting response: %v\n", err)
 		return nil, fmt.Errorf("[Tavily]Error formatting response: %v", err)
@@ -144,7 +144,7 @@ func (s *SearchEngine) TavilySearch(query string) (map[string]any, error) {
 }
 
 // formatResponse converts a TavilyResponse into the desired response format.
-func (s *SearchEngine) tavilyFormatResponse(tavilyResp *TavilyResponse) (map[string]any, error) {
+func (s *SearchEngine) tavilyFormatResponse(ctx context.Context, tavilyResp *TavilyResponse) (map[string]any, error) {
 	results := make([]any, 0, len(tavilyResp.Results))
 	info := map[string]any{
 		"answer": tavilyResp.Answer,
@@ -165,7 +165,7 @@ func (s *SearchEngine) tavilyFormatResponse(tavilyResp *TavilyResponse) (map[str
 	}
 
 	// Fetch contents for all links
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 	contents := FetchProcess(ctx, links)
 
@@ -204,16 +204,15 @@ func (s *SearchEngine) tavilyFormatResponse(tavilyResp *TavilyResponse) (map[str
 }
 
 // Alternative approach with explicit conversions for protocol buffer compatibility
-func (s *SearchEngine) GoogleSearch(query string) (map[string]any, error) {
+func (s *SearchEngine) GoogleSearch(ctx context.Context, query string) (map[string]any, error) {
 	// Create results using only types known to work with proto conversion
-	ctx := context.Background() // Required for NewService
 	svc, err := customsearch.NewService(ctx, option.WithAPIKey(s.ApiKey))
 	if err != nil {
 		util.LogErrorf("[Google]Error creating service: %v\n", err)
 		return nil, fmt.Errorf("[Google]Error creating service: %v", err)
 	}
 
-	resp, err := svc.Cse.List().Safe("off").Num(10).Cx(s.CxKey).Q(query).Do()
+	resp, err := svc.Cse.List().Safe("off").Num(10).Cx(s.CxKey).Q(query).Context(ctx).Do()
 	if err != nil {
 		util.LogErrorf("[Google]Error making API call: %v\n", err)
 		return nil, fmt.Errorf("[Google]Error making API call: %v", err)
@@ -233,7 +232,7 @@ func (s *SearchEngine) GoogleSearch(query string) (map[string]any, error) {
 	}
 
 	// Fetch contents for all links
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 	contents := FetchProcess(ctx, links)
 
@@ -267,12 +266,12 @@ func (s *SearchEngine) GoogleSearch(query string) (map[string]any, error) {
 }
 
 // --- Simulation of Bing Search ---
-func (s *SearchEngine) BingSearch(query string) (map[string]any, error) {
+func (s *SearchEngine) BingSearch(ctx context.Context, query string) (map[string]any, error) {
 	// Call SerpAPI Search API
-	return s.SerpAPISearch(query, "bing")
+	return s.SerpAPISearch(ctx, query, "bing")
 }
 
-func (s *SearchEngine) SerpAPISearch(query string, engine string) (map[string]any, error) {
+func (s *SearchEngine) SerpAPISearch(ctx context.Context, query string, engine string) (map[string]any, error) {
 	// Placeholder
 	return map[string]any{}, nil
 	/*
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SessionTurn is a user turn in a saved session, used to pick a fork point.
+type SessionTurn struct {
+	Index   int    // 1-based turn number
+	Preview string // first line of the user's message
+}
+
+// splitSessionLines returns the non-empty JSONL lines of a session file.
+func splitSessionLines(content []byte) [][]byte {
+	var lines [][]byte
+	for _, line := range bytes.Split(content, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) > 0 {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// userTurnText reports whether a session line starts a user turn and returns its text.
+// Tool results are sent back with the user role by Anthropic and Gemini, those lines are not turns.
+func userTurnText(line []byte) (string, bool) {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return "", false
+	}
+	if role, _ := msg["role"].(string); role != "user" {
+		return "", false
+	}
+
+	var texts []string
+	isTurn := true
+	collect := func(block map[string]interface{}) {
+		if t, ok := block["text"].(string); ok {
+			texts = append(texts, t)
+		}
+		if typ, _ := block["type"].(string); typ == "tool_result" {
+			isTurn = false
+		}
+		if _, ok := block["functionResponse"]; ok {
+			isTurn = false
+		}
+	}
+
+	// OpenAI and Anthropic use content (a string or a list of parts), Gemini uses parts
+	switch content := msg["content"].(type) {
+	case string:
+		texts = append(texts, content)
+	case []interface{}:
+		for _, c := range content {
+			if block, ok := c.(map[string]interface{}); ok {
+				collect(block)
+			}
+		}
+	}
+	if parts, ok := msg["parts"].([]interface{}); ok {
+		for _, p := range parts {
+			if block, ok := p.(map[string]interface{}); ok {
+				collect(block)
+			}
+		}
+	}
+	if !isTurn {
+		return "", false
+	}
+	return StripInlineContext(strings.Join(texts, "\n")), true
+}
+
+// sessionTurnStarts returns the line index where each user turn begins.
+func sessionTurnStarts(lines [][]byte) ([]int, []SessionTurn) {
+	var starts []int
+	var turns []SessionTurn
+	for i, line := range lines {
+		text, ok := userTurnText(line)
+		if !ok {
+			continue
+		}
+		starts = append(starts, i)
+		preview := strings.TrimSpace(text)
+		if idx := strings.IndexByte(preview, '\n'); idx >= 0 {
+			preview = preview[:idx]
+		}
+		if len([]rune(preview)) > 80 {
+			preview = string([]rune(preview)[:80]) + "…"
+		}
+		turns = append(turns, SessionTurn{Index: len(turns) + 1, Preview: preview})
+	}
+	return starts, turns
+}
+
+// ListSessionTurns returns the user turns of a saved session.
+func ListSessionTurns(name string) ([]SessionTurn, error) {
+	content, err := ReadSessionContent(name)
+	if err != nil {
+		return nil, fmt.Errorf("session '%s' not found", name)
+	}
+	_, turns := sessionTurnStarts(splitSessionLines(content))
+	return turns, nil
+}
+
+// truncateSessionAtTurn keeps the history up to and including the reply to the given turn.
+// A turn of 0 keeps the whole history.
+func truncateSessionAtTurn(content []byte, turn int) ([]byte, error) {
+	lines := splitSessionLines(content)
+	starts, _ := sessionTurnStarts(lines)
+	if turn < 0 || turn > len(starts) {
+		return nil, fmt.Errorf("turn %d is out of range, the session has %d turn(s)", turn, len(starts))
+	}
+	if turn > 0 && turn < len(starts) {
+		lines = lines[:starts[turn]]
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// NextForkName returns an unused session name for a fork of src.
+func NextForkName(src string) string {
+	base := src + "-fork"
+	if !SessionExists(base, false) {
+		return base
+	}
+	for i := 2; ; i++ {
+		name := fmt.Sprintf("%s-%d", base, i)
+		if !SessionExists(name, false) {
+			return name
+		}
+	}
+}
+
+// ForkSession creates dst as a new session sharing src's history up to the given turn.
+// A turn of 0 copies the whole history. Sub-agent sessions of src are not copied.
+func ForkSession(src, dst string, turn int) error {
+	if strings.Contains(src, "::") || strings.Contains(dst, "::") {
+		return fmt.Errorf("only top-level sessions can be forked")
+	}
+	if SessionExists(dst, false) {
+		return fmt.Errorf("session '%s' already exists", dst)
+	}
+	content, err := ReadSessionContent(src)
+	if err != nil {
+		return fmt.Errorf("session '%s' not found", src)
+	}
+	forked, err := truncateSessionAtTurn(content, turn)
+	if err != nil {
+		return err
+	}
+	return WriteSessionContent(dst, forked)
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSessionTurnStarts(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []int
+	}{
+		{"openai", []string{
+			`{"role":"system","content":"sys"}`,
+			`{"role":"user","content":"first"}`,
+			`{"role":"assistant","tool_calls":[{"id":"1"}]}`,
+			`{"role":"tool","content":"result","tool_call_id":"1"}`,
+			`{"role":"assistant","content":"done"}`,
+			`{"role":"user","content":[{"type":"text","text":"second"}]}`,
+		}, []int{1, 5}},
+		{"anthropic", []string{
+			`{"role":"user","content":[{"type":"text","text":"first"}]}`,
+			`{"role":"assistant","content":[{"type":"tool_use","id":"1","name":"x","input":{}}]}`,
+			`{"role":"user","content":[{"type":"tool_result","tool_use_id":"1"}]}`,
+			`{"role":"user","content":[{"type":"text","text":"second"}]}`,
+		}, []int{0, 3}},
+		{"gemini", []string{
+			`{"role":"user","parts":[{"text":"first"}]}`,
+			`{"role":"model","parts":[{"functionCall":{"name":"x"}}]}`,
+			`{"role":"user","parts":[{"functionResponse":{"name":"x"}}]}`,
+			`{"role":"user","parts":[{"text":"second"}]}`,
+		}, []int{0, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines [][]byte
+			for _, l := range tt.lines {
+				lines = append(lines, []byte(l))
+			}
+			starts, turns := sessionTurnStarts(lines)
+			if len(starts) != len(tt.want) {
+				t.Fatalf("got starts %v, want %v", starts, tt.want)
+			}
+			for i := range starts {
+				if starts[i] != tt.want[i] {
+					t.Errorf("got starts %v, want %v", starts, tt.want)
+				}
+			}
+			if turns[0].Preview != "first" || turns[1].Preview != "second" {
+				t.Errorf("unexpected previews: %+v", turns)
+			}
+		})
+	}
+}
+
+func TestForkSession(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	history := strings.Join([]string{
+		`{"role":"user","content":"one"}`,
+		`{"role":"assistant","content":"reply one"}`,
+		`{"role":"user","content":"two"}`,
+		`{"role":"assistant","content":"reply two"}`,
+		`{"role":"user","content":"three"}`,
+		`{"role":"assistant","content":"reply three"}`,
+	}, "\n") + "\n"
+	if err := WriteSessionContent("orig", []byte(history)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ForkSession("orig", "branch", 2); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := ReadSessionContent("branch")
+	if !strings.HasSuffix(string(got), `{"role":"assistant","content":"reply two"}`+"\n") || strings.Contains(string(got), "three") {
+		t.Errorf("unexpected fork content:\n%s", got)
+	}
+	if orig, _ := ReadSessionContent("orig"); string(orig) != history {
+		t.Errorf("original session was modified")
+	}
+
+	if err := ForkSession("orig", "branch", 1); err == nil {
+		t.Error("expected an error when the fork target exists")
+	}
+	if err := ForkSession("orig", "other", 4); err == nil || !strings.Contains(err.Error(), "has 3 turn(s)") {
+		t.Errorf("expected an out of range error, got %v", err)
+	}
+	if name := NextForkName("orig"); name != "orig-fork" {
+		t.Errorf("NextForkName = %s", name)
+	}
+	if err := ForkSession("orig", "orig-fork", 0); err != nil {
+		t.Fatal(err)
+	}
+	if name := NextForkName("orig"); name != "orig-fork-2" {
+		t.Errorf("NextForkName = %s", name)
+	}
+	if full, _ := ReadSessionContent("orig-fork"); string(full) != history {
+		t.Errorf("full fork should copy the whole history")
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -191,9 +192,66 @@ func EnsureSessionCompatibility(agent *data.AgentConfig, sessionName string, hoo
 	return nil
 }
 
+// SessionModelSwitch describes the history carried over to another model.
+type SessionModelSwitch struct {
+	From      string // Format of the history before the switch, empty without history
+	To        string
+	Converted bool // The history was rewritten in the format of the new model
+	Messages  int
+	ToolCalls int
+}
+
+// SwitchSessionModel converts the history of a session for the model of the agent right
+// away, tool calls and their results included, so the conversation goes on with the new
+// model where it left off.
+func SwitchSessionModel(agent *data.AgentConfig, sessionName string) (*SessionModelSwitch, error) {
+	sw := &SessionModelSwitch{To: agent.Model.Provider}
+	if sessionName == "" {
+		return sw, nil
+	}
+	sessionData, err := ReadSessionContent(sessionName)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sw, nil
+		}
+		return nil, err
+	}
+	if len(bytes.TrimSpace(sessionData)) == 0 {
+		return sw, nil
+	}
+
+	isCompatible, provider, modelProvider := CheckSessionFormat(agent, sessionData)
+	sw.From, sw.To = provider, modelProvider
+	if !isCompatible {
+		converted, err := ConvertMessages(sessionData, provider, modelProvider)
+		if err != nil {
+			return nil, fmt.Errorf("error converting session: %v", err)
+		}
+		if err := WriteSessionContent(sessionName, converted); err != nil {
+			return nil, err
+		}
+		sessionData, sw.Converted = converted, true
+	}
+
+	// Count what the new model sees
+	msgs, err := ParseUniversalMessages(sessionData, modelProvider)
+	if err != nil {
+		return sw, nil
+	}
+	sw.Messages = len(msgs)
+	for _, m := range msgs {
+		sw.ToolCalls += len(m.ToolCalls)
+	}
+	return sw, nil
+}
+
 // CheckSessionFormat verifies if the session data is compatible with the agent's provider.
 func CheckSessionFormat(agent *data.AgentConfig, sessionData []byte) (isCompatible bool, provider string, modelProvider string) {
 	modelProvider = agent.Model.Provider
+	if modelProvider == ModelProviderMock {
+		// Mock models speak the OpenAI format
+		modelProvider = ModelProviderOpenAI
+	}
 
 	// Detect provider based on message format
 	provider = DetectMessageProviderByContent(sessionData)
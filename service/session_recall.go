@@ -0,0 +1,467 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+)
+
+const (
+	// recallMaxSessions is the number of past sessions prepended to a prompt.
+	recallMaxSessions = 3
+	// recallMinScore is the similarity a past session needs to be prepended.
+	recallMinScore = 0.25
+	// recallCueMinScore is the similarity needed when the prompt refers to an earlier
+	// conversation, e.g. "as we discussed last week".
+	recallCueMinScore = 0.1
+	// recallMinTerms is the number of terms a prompt needs to be compared at all.
+	recallMinTerms = 3
+	// recallSummaryLimit caps the text of a session that is indexed, in runes.
+	recallSummaryLimit = 4000
+	// recallSnippetLimit caps the snippet of a session prepended to a prompt, in runes.
+	recallSnippetLimit = 600
+	// recallEmbeddingMinScore and recallEmbeddingCueMinScore are the same similarities for
+	// the embeddings, which are never far apart.
+	recallEmbeddingMinScore    = 0.5
+	recallEmbeddingCueMinScore = 0.35
+	// recallEmbeddingBatch is how many sessions are embedded in one request.
+	recallEmbeddingBatch = 32
+)
+
+// recallCue matches the ways a prompt refers to an earlier conversation.
+var recallCue = regexp.MustCompile(`(?i)\b(as (we|i|you) (discussed|said|decided|mentioned|talked about)|last (time|week|month|session)|the other day|remember (when|that|how|what)|we (discussed|talked about|decided)|you (said|suggested|told me)|previous(ly)? (session|conversation|chat))\b`)
+
+// recallStopWords are left out of the vectors, they match everything.
+var recallStopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "but": true, "not": true, "you": true,
+	"all": true, "can": true, "was": true, "one": true, "our": true, "out": true, "has": true,
+	"have": true, "how": true, "its": true, "that": true, "this": true, "with": true, "what": true,
+	"from": true, "they": true, "will": true, "would": true, "there": true, "their": true,
+	"about": true, "which": true, "when": true, "make": true, "like": true, "just": true,
+	"into": true, "than": true, "then": true, "them": true, "some": true, "could": true,
+	"should": true, "these": true, "those": true, "been": true, "were": true, "does": true,
+	"did": true, "use": true, "using": true, "also": true, "any": true, "more": true,
+	"need": true, "want": true, "please": true, "let": true, "get": true, "discussed": true,
+	"week": true, "last": true, "time": true, "remember": true, "said": true, "earlier": true,
+}
+
+// recallEntry is a past session in the index: its summary, its term vector and its
+// embedding when an embedding model is set.
+type recallEntry struct {
+	ModTime        int64              `json:"mod_time"`
+	Summary        string             `json:"summary"`
+	Snippet        string             `json:"snippet"`
+	Vector         map[string]float64 `json:"vector"` // Normalized term frequencies
+	Embedding      []float64          `json:"embedding,omitempty"`
+	EmbeddingModel string             `json:"embedding_model,omitempty"` // Model and name of the embedding
+}
+
+// recallIndex is the index of the past sessions, keyed by session name.
+type recallIndex struct {
+	Entries map[string]*recallEntry `json:"entries"`
+}
+
+// RecalledSession is a past session found related to a prompt.
+type RecalledSession struct {
+	Session string
+	ModTime int64
+	Score   float64 // Cosine similarity with the prompt, 0 to 1
+	Snippet string
+}
+
+// SessionRecall is what was retrieved for a prompt, shown by /related.
+type SessionRecall struct {
+	Query    string
+	Cue      bool              // The prompt referred to an earlier conversation
+	Sessions []RecalledSession // Given to the model with the prompt
+}
+
+var (
+	recallMu sync.Mutex
+	// lastRecall is the retrieval of the last prompt
+	lastRecall *SessionRecall
+)
+
+// LastSessionRecall returns what was retrieved for the last prompt, nil when nothing was.
+func LastSessionRecall() *SessionRecall {
+	recallMu.Lock()
+	defer recallMu.Unlock()
+	return lastRecall
+}
+
+// SessionRecallContext returns the most relevant snippets of past sessions for a prompt that
+// looks related to them, "" when none is. They go into the system prompt of the turn, so they
+// are neither saved in the session nor taken for what the user typed.
+func SessionRecallContext(ctx context.Context, prompt, sessionName string) string {
+	prompt = StripInlineContext(prompt)
+	related, cue, err := FindRelatedSessions(ctx, prompt, sessionName, recallMaxSessions)
+	if err != nil {
+		util.LogDebugf("Session recall failed: %v\n", err)
+		return ""
+	}
+
+	recallMu.Lock()
+	lastRecall = &SessionRecall{Query: prompt, Cue: cue, Sessions: related}
+	recallMu.Unlock()
+	if len(related) == 0 {
+		return ""
+	}
+
+	var names []string
+	var sb strings.Builder
+	sb.WriteString("<related_sessions>\nNotes from earlier sessions that look related to the latest request. Use them only if they help, the user doesn't see them.\n")
+	for _, r := range related {
+		names = append(names, r.Session)
+		fmt.Fprintf(&sb, "\n<session name=%q date=%q>\n%s\n</session>\n", r.Session, time.Unix(r.ModTime, 0).Format("2006-01-02"), r.Snippet)
+	}
+	sb.WriteString("</related_sessions>")
+	util.LogInfof("Recalled %d related session(s): %s (see /related)\n", len(related), strings.Join(names, ", "))
+	return sb.String()
+}
+
+// FindRelatedSessions returns the past sessions most similar to a query, the current
+// session excluded, and whether the query refers to an earlier conversation. Sessions are
+// compared by their embeddings with the embedding model of the settings, by their terms
+// without one or when it fails.
+func FindRelatedSessions(ctx context.Context, query, currentSession string, limit int) ([]RecalledSession, bool, error) {
+	cue := recallCue.MatchString(query)
+	terms := recallTerms(query)
+	if len(terms) < recallMinTerms && !cue {
+		return nil, cue, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, embeddingTimeout)
+	defer cancel()
+
+	model := EmbeddingModel()
+	index, err := refreshRecallIndex(ctx, model)
+	if err != nil {
+		return nil, cue, err
+	}
+	current, _, _ := strings.Cut(currentSession, "::")
+
+	score, minScore := recallTermScorer(index, terms), recallMinScore
+	if cue {
+		minScore = recallCueMinScore
+	}
+	if model != nil {
+		if vectors, err := EmbedTexts(ctx, model, []string{query}); err == nil {
+			score, minScore = recallEmbeddingScorer(vectors[0], recallEmbeddingKey(model)), recallEmbeddingMinScore
+			if cue {
+				minScore = recallEmbeddingCueMinScore
+			}
+		} else {
+			util.LogDebugf("Recalling the sessions by their terms: %v\n", err)
+		}
+	}
+
+	var related []RecalledSession
+	for name, e := range index.Entries {
+		if name == current {
+			continue
+		}
+		s := score(e)
+		if s < minScore {
+			continue
+		}
+		related = append(related, RecalledSession{Session: name, ModTime: e.ModTime, Score: s, Snippet: e.Snippet})
+	}
+	sort.Slice(related, func(i, j int) bool {
+		if related[i].Score != related[j].Score {
+			return related[i].Score > related[j].Score
+		}
+		return related[i].ModTime > related[j].ModTime
+	})
+	if len(related) > limit {
+		related = related[:limit]
+	}
+	return related, cue, nil
+}
+
+// recallTermScorer scores the sessions by the terms they share with the query, each term
+// weighed by its rarity across the sessions.
+func recallTermScorer(index *recallIndex, terms []string) func(*recallEntry) float64 {
+	// Terms found in many sessions say little about which one is related
+	df := make(map[string]int)
+	for _, e := range index.Entries {
+		for t := range e.Vector {
+			df[t]++
+		}
+	}
+	idf := func(t string) float64 {
+		return math.Log(1 + float64(len(index.Entries))/float64(1+df[t]))
+	}
+	q := weightRecallVector(recallVector(terms), idf)
+	return func(e *recallEntry) float64 {
+		return cosineSimilarity(q, weightRecallVector(e.Vector, idf))
+	}
+}
+
+// recallEmbeddingScorer scores the sessions by the similarity of their embeddings with the
+// query's. The sessions not embedded with the model yet don't match.
+func recallEmbeddingScorer(query []float64, key string) func(*recallEntry) float64 {
+	return func(e *recallEntry) float64 {
+		if e.EmbeddingModel != key {
+			return 0
+		}
+		return denseCosineSimilarity(query, e.Embedding)
+	}
+}
+
+// recallEmbeddingKey identifies the embeddings of a model, so a change of model embeds the
+// sessions again.
+func recallEmbeddingKey(model *data.Model) string {
+	return model.Name + "/" + model.Model
+}
+
+// refreshRecallIndex brings the index up to date with the saved sessions: new and changed
+// sessions are summarized again, removed ones are dropped. With an embedding model the
+// sessions it has not embedded yet are embedded.
+func refreshRecallIndex(ctx context.Context, model *data.Model) (*recallIndex, error) {
+	index := loadRecallIndex()
+	sessions, err := ListSortedSessions(false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	present := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		present[s.Name] = true
+		if e, ok := index.Entries[s.Name]; ok && e.ModTime == s.ModTime {
+			continue
+		}
+		content, err := ReadSessionContent(s.Name)
+		if err != nil {
+			continue
+		}
+		summary, snippet := summarizeSessionContent(content)
+		index.Entries[s.Name] = &recallEntry{
+			ModTime: s.ModTime,
+			Summary: summary,
+			Snippet: snippet,
+			Vector:  recallVector(recallTerms(s.Name + "\n" + summary)),
+		}
+		changed = true
+	}
+	for name := range index.Entries {
+		if !present[name] {
+			delete(index.Entries, name)
+			changed = true
+		}
+	}
+	if model != nil && embedRecallEntries(ctx, model, index) {
+		changed = true
+	}
+	if changed {
+		if err := saveRecallIndex(index); err != nil {
+			util.LogDebugf("Failed to save the session recall index: %v\n", err)
+		}
+	}
+	return index, nil
+}
+
+// embedRecallEntries embeds the sessions of the index the model has not embedded yet and
+// reports whether any was. The ones that fail are embedded on the next refresh.
+func embedRecallEntries(ctx context.Context, model *data.Model, index *recallIndex) bool {
+	key := recallEmbeddingKey(model)
+	var names []string
+	for name, e := range index.Entries {
+		if e.EmbeddingModel != key || len(e.Embedding) == 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	embedded := false
+	for start := 0; start < len(names); start += recallEmbeddingBatch {
+		batch := names[start:min(start+recallEmbeddingBatch, len(names))]
+		texts := make([]string, len(batch))
+		for i, name := range batch {
+			texts[i] = name + "\n" + index.Entries[name].Summary
+		}
+		vectors, err := EmbedTexts(ctx, model, texts)
+		if err != nil {
+			util.LogDebugf("Failed to embed the past sessions: %v\n", err)
+			break
+		}
+		for i, name := range batch {
+			index.Entries[name].Embedding = vectors[i]
+			index.Entries[name].EmbeddingModel = key
+		}
+		embedded = true
+	}
+	return embedded
+}
+
+func loadRecallIndex() *recallIndex {
+	index := &recallIndex{Entries: map[string]*recallEntry{}}
+	content, err := os.ReadFile(data.GetRecallIndexFilePath())
+	if err != nil {
+		return index
+	}
+	if err := json.Unmarshal(content, index); err != nil || index.Entries == nil {
+		return &recallIndex{Entries: map[string]*recallEntry{}}
+	}
+	return index
+}
+
+func saveRecallIndex(index *recallIndex) error {
+	content, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	path := data.GetRecallIndexFilePath()
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return err
+	}
+	// The summaries of past sessions are as private as the sessions, an index written before
+	// with wider permissions is narrowed too
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		return err
+	}
+	return os.Chmod(path, 0600)
+}
+
+// summarizeSessionContent returns the text of a session that is indexed, its prompts and
+// answers, and the snippet prepended to a related prompt: the first prompt and the last answer.
+func summarizeSessionContent(content []byte) (summary, snippet string) {
+	var sb strings.Builder
+	var firstPrompt, lastAnswer string
+	for _, line := range splitSessionLines(content) {
+		if text, ok := userTurnText(line); ok {
+			text = strings.TrimSpace(text)
+			if firstPrompt == "" {
+				firstPrompt = text
+			}
+			sb.WriteString(text + "\n")
+		} else if text := assistantTurnText(line); text != "" {
+			lastAnswer = text
+			sb.WriteString(text + "\n")
+		}
+	}
+
+	summary = truncateRunes(sb.String(), recallSummaryLimit)
+	snippet = "User: " + truncateRunes(firstPrompt, recallSnippetLimit/3)
+	if lastAnswer != "" {
+		snippet += "\nAssistant: " + truncateRunes(lastAnswer, recallSnippetLimit)
+	}
+	return summary, snippet
+}
+
+// assistantTurnText returns the text of an answer of the model in a session line, empty
+// for other lines. Thinking and tool calls are left out.
+func assistantTurnText(line []byte) string {
+	var msg map[string]interface{}
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return ""
+	}
+	if role, _ := msg["role"].(string); role != "assistant" && role != "model" {
+		return ""
+	}
+
+	var texts []string
+	collect := func(block map[string]interface{}) {
+		if thought, _ := block["thought"].(bool); thought {
+			return
+		}
+		if t, ok := block["text"].(string); ok {
+			texts = append(texts, t)
+		}
+	}
+	switch content := msg["content"].(type) {
+	case string:
+		texts = append(texts, content)
+	case []interface{}:
+		for _, c := range content {
+			if block, ok := c.(map[string]interface{}); ok {
+				collect(block)
+			}
+		}
+	}
+	if parts, ok := msg["parts"].([]interface{}); ok {
+		for _, p := range parts {
+			if block, ok := p.(map[string]interface{}); ok {
+				collect(block)
+			}
+		}
+	}
+	return strings.TrimSpace(strings.Join(texts, "\n"))
+}
+
+// truncateRunes keeps the first n runes of a text.
+func truncateRunes(text string, n int) string {
+	text = strings.TrimSpace(text)
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "…"
+}
+
+// recallTerms splits a text into lowercase words, short and common words left out.
+func recallTerms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var terms []string
+	for _, w := range words {
+		if len([]rune(w)) < 3 || recallStopWords[w] {
+			continue
+		}
+		terms = append(terms, w)
+	}
+	return terms
+}
+
+// recallVector returns the term frequencies of a text, dampened and normalized.
+func recallVector(terms []string) map[string]float64 {
+	counts := make(map[string]float64)
+	for _, t := range terms {
+		counts[t]++
+	}
+	vector := make(map[string]float64, len(counts))
+	for t, n := range counts {
+		vector[t] = 1 + math.Log(n)
+	}
+	return vector
+}
+
+// weightRecallVector weighs each term of a vector by its rarity across the sessions.
+func weightRecallVector(vector map[string]float64, idf func(string) float64) map[string]float64 {
+	weighted := make(map[string]float64, len(vector))
+	for t, w := range vector {
+		weighted[t] = w * idf(t)
+	}
+	return weighted
+}
+
+// cosineSimilarity returns the cosine of the angle between two sparse vectors.
+func cosineSimilarity(a, b map[string]float64) float64 {
+	var dot, na, nb float64
+	for t, w := range a {
+		dot += w * b[t]
+		na += w * w
+	}
+	for _, w := range b {
+		nb += w * w
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func writeRecallSession(t *testing.T, name, prompt, answer string) {
+	t.Helper()
+	content := `{"role":"user","content":"` + prompt + `"}` + "\n" +
+		`{"role":"assistant","content":[{"type":"text","text":"` + answer + `"}]}` + "\n"
+	if err := WriteSessionContent(name, []byte(content)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindRelatedSessions(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	writeRecallSession(t, "postgres-index-tuning", "Why is the orders query slow on postgres?", "Add a composite index on orders (customer_id, created_at) and vacuum the table.")
+	writeRecallSession(t, "css-grid-layout", "How do I center a div with css grid?", "Use place-items: center on the grid container.")
+	writeRecallSession(t, "current", "Hello", "Hi")
+
+	related, _, err := FindRelatedSessions(context.Background(), "The orders query on postgres is slow again, which index did we add?", "current", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(related) != 1 || related[0].Session != "postgres-index-tuning" {
+		t.Fatalf("unexpected related sessions: %+v", related)
+	}
+	if !strings.Contains(related[0].Snippet, "composite index") {
+		t.Errorf("the snippet misses the answer: %q", related[0].Snippet)
+	}
+
+	if related, _, _ := FindRelatedSessions(context.Background(), "Write a haiku about autumn leaves falling", "current", 3); len(related) != 0 {
+		t.Errorf("an unrelated prompt recalled %+v", related)
+	}
+}
+
+func TestSessionRecallContext(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	writeRecallSession(t, "kafka-consumer-lag", "Our kafka consumer lag keeps growing", "Increase the partitions and the consumer instances, and check max.poll.records.")
+
+	prompt := "As we discussed last week, the kafka consumer lag is back"
+	recall := SessionRecallContext(context.Background(), prompt, "recall-test")
+	if !strings.HasPrefix(recall, "<related_sessions>") || !strings.Contains(recall, `<session name="kafka-consumer-lag"`) || !strings.Contains(recall, "max.poll.records") {
+		t.Fatalf("the related session was not recalled: %q", recall)
+	}
+	if last := LastSessionRecall(); last == nil || len(last.Sessions) != 1 || !last.Cue || last.Query != prompt {
+		t.Errorf("unexpected last recall: %+v", last)
+	}
+
+	// The index holds summaries of private sessions
+	if info, err := os.Stat(data.GetRecallIndexFilePath()); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("the recall index must only be readable by the user: %v, %v", info.Mode().Perm(), err)
+	}
+
+	if recall := SessionRecallContext(context.Background(), "Write a haiku about autumn leaves falling", "recall-test"); recall != "" {
+		t.Errorf("an unrelated prompt recalled %q", recall)
+	}
+}
+
+func TestRecallIndexEmbeddings(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	model, calls := fakeEmbeddingServer(t)
+
+	writeRecallSession(t, "postgres-index-tuning", "Why is the orders query slow on postgres?", "Add a composite index on orders (customer_id, created_at).")
+	writeRecallSession(t, "css-grid-layout", "How do I center a div with css grid?", "Use place-items: center on the grid container.")
+
+	index, err := refreshRecallIndex(context.Background(), model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, e := range index.Entries {
+		if len(e.Embedding) == 0 || e.EmbeddingModel != recallEmbeddingKey(model) {
+			t.Errorf("%s was not embedded: %+v", name, e)
+		}
+	}
+
+	// The embeddings are kept in the index, a refresh doesn't ask for them again
+	before := *calls
+	if index, err = refreshRecallIndex(context.Background(), model); err != nil {
+		t.Fatal(err)
+	}
+	if *calls != before {
+		t.Errorf("the sessions were embedded again")
+	}
+
+	// No term in common, the meaning is
+	query, err := EmbedTexts(context.Background(), model, []string{"The database is sluggish once more"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	score := recallEmbeddingScorer(query[0], recallEmbeddingKey(model))
+	if s := score(index.Entries["postgres-index-tuning"]); s < recallEmbeddingMinScore {
+		t.Errorf("the postgres session must be related, scored %.2f", s)
+	}
+	if s := score(index.Entries["css-grid-layout"]); s >= recallEmbeddingMinScore {
+		t.Errorf("the css session must not be related, scored %.2f", s)
+	}
+	if s := recallEmbeddingScorer(query[0], "other/model")(index.Entries["postgres-index-tuning"]); s != 0 {
+		t.Errorf("the embeddings of another model must not be compared, scored %.2f", s)
+	}
+}
@@ -89,7 +89,7 @@ func RenderSessionForViewport(agent *data.AgentConfig, name string) (provider, c
 func renderUserBlock(text string) string {
 	tcol := io.GetTerminalWidth()
 	// Strip out any inline context meant only for the model
-	cleanText := stripInlineContext(text)
+	cleanText := StripInlineContext(text)
 
 	return lipgloss.NewStyle().
 		Background(lipgloss.Color(data.CurrentTheme.Background)).
@@ -121,7 +121,8 @@ func BuildInlineContextBlock(blobs []string) string {
 	return tb.String()
 }
 
-func stripInlineContext(text string) string {
+// StripInlineContext removes the hidden context blocks of a prompt, leaving what was typed.
+func StripInlineContext(text string) string {
 	for {
 		start := strings.Index(text, InlineContextStart)
 		if start == -1 {
@@ -185,7 +186,7 @@ func renderMarkdown(text string) string {
 		return ""
 	}
 
-	tr, err := glamour.NewTermRenderer(glamour.WithStandardStyle(data.MostSimilarGlamourStyle()))
+	tr, err := glamour.NewTermRenderer(data.MarkdownStyleOption())
 	if err != nil {
 		tr, _ = glamour.NewTermRenderer(glamour.WithAutoStyle())
 	}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Shells the shell tool can run commands with.
+const (
+	ShellAuto       = "auto"
+	ShellSh         = "sh"
+	ShellBash       = "bash"
+	ShellPowerShell = "powershell" // Windows PowerShell 5.1
+	ShellPwsh       = "pwsh"       // PowerShell 7+
+	ShellCmd        = "cmd"
+)
+
+// SupportedShells lists the values accepted for an agent's shell.
+var SupportedShells = []string{ShellAuto, ShellSh, ShellBash, ShellPowerShell, ShellPwsh, ShellCmd}
+
+// Shell is a resolved shell used to run commands.
+type Shell struct {
+	Name string // One of the Shell* constants, never ShellAuto
+	Path string // Executable
+}
+
+// powerShellPreamble switches PowerShell's output to UTF-8 so that non-ASCII output survives the pipe.
+const powerShellPreamble = "[Console]::OutputEncoding=[System.Text.Encoding]::UTF8;$OutputEncoding=[System.Text.Encoding]::UTF8;"
+
+// ResolveShell finds the shell to use for name, an empty name or "auto" detects one.
+func ResolveShell(name string) (*Shell, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || name == ShellAuto {
+		return detectShell(), nil
+	}
+
+	var candidates []string
+	switch name {
+	case ShellSh:
+		candidates = []string{"sh"}
+	case ShellBash:
+		candidates = bashCandidates()
+	case ShellPowerShell:
+		candidates = []string{"powershell"}
+	case ShellPwsh:
+		candidates = []string{"pwsh"}
+	case ShellCmd:
+		if runtime.GOOS != "windows" {
+			return nil, fmt.Errorf("the cmd shell is only available on Windows")
+		}
+		candidates = []string{"cmd"}
+	default:
+		return nil, fmt.Errorf("unknown shell '%s', expected one of: %s", name, strings.Join(SupportedShells, ", "))
+	}
+	for _, c := range candidates {
+		if path, err := exec.LookPath(c); err == nil {
+			return &Shell{Name: name, Path: path}, nil
+		}
+	}
+	return nil, fmt.Errorf("shell '%s' was not found", name)
+}
+
+// detectShell picks the default shell: sh on Unix; on Windows bash inside Git Bash/MSYS,
+// otherwise PowerShell 7, Windows PowerShell, and cmd as the last resort.
+func detectShell() *Shell {
+	if runtime.GOOS != "windows" {
+		return &Shell{Name: ShellSh, Path: "sh"}
+	}
+	if os.Getenv("MSYSTEM") != "" {
+		if sh, err := ResolveShell(ShellBash); err == nil {
+			return sh
+		}
+	}
+	for _, name := range []string{ShellPwsh, ShellPowerShell} {
+		if path, err := exec.LookPath(name); err == nil {
+			return &Shell{Name: name, Path: path}
+		}
+	}
+	return &Shell{Name: ShellCmd, Path: "cmd"}
+}
+
+// bashCandidates lists where bash is looked for. On Windows Git Bash comes before
+// the bash on PATH, which is usually the WSL launcher.
+func bashCandidates() []string {
+	if runtime.GOOS != "windows" {
+		return []string{"bash"}
+	}
+	var candidates []string
+	for _, env := range []string{"ProgramFiles", "ProgramW6432", "LocalAppData"} {
+		if dir := os.Getenv(env); dir != "" {
+			sub := "Git"
+			if env == "LocalAppData" {
+				sub = filepath.Join("Programs", "Git")
+			}
+			candidates = append(candidates, filepath.Join(dir, sub, "bin", "bash.exe"))
+		}
+	}
+	return append(candidates, "bash")
+}
+
+// Command builds the command that runs a command line in this shell.
+func (s *Shell) Command(ctx context.Context, command string) *exec.Cmd {
+	var cmd *exec.Cmd
+	switch s.Name {
+	case ShellPowerShell, ShellPwsh:
+		args := []string{"-NoProfile", "-NonInteractive"}
+		if runtime.GOOS == "windows" {
+			args = append(args, "-ExecutionPolicy", "Bypass")
+		}
+		args = append(args, "-Command", powerShellPreamble+command)
+		cmd = exec.CommandContext(ctx, s.Path, args...)
+	case ShellCmd:
+		// chcp 65001 makes cmd and most console programs write UTF-8.
+		// The command line is passed verbatim, cmd does not follow the usual argument quoting rules.
+		cmd = exec.CommandContext(ctx, s.Path)
+		setRawCommandLine(cmd, fmt.Sprintf(`"%s" /D /S /C "chcp 65001>nul & %s"`, s.Path, command))
+	default:
+		cmd = exec.CommandContext(ctx, s.Path, "-c", command)
+	}
+	return cmd
+}
+
+// DecodeShellOutput turns command output into valid UTF-8.
+// Windows programs may still write UTF-16, which is detected by its BOM or its zero bytes.
+func DecodeShellOutput(out []byte) string {
+	if isUTF16LE(out) {
+		if len(out) >= 2 && out[0] == 0xFF && out[1] == 0xFE {
+			out = out[2:]
+		}
+		units := make([]uint16, len(out)/2)
+		for i := range units {
+			units[i] = binary.LittleEndian.Uint16(out[2*i:])
+		}
+		return string(utf16.Decode(units))
+	}
+	s := strings.TrimPrefix(string(out), "\ufeff")
+	if !utf8.ValidString(s) {
+		s = strings.ToValidUTF8(s, "\uFFFD")
+	}
+	return s
+}
+
+// isUTF16LE reports whether out looks like UTF-16LE text.
+func isUTF16LE(out []byte) bool {
+	if len(out) >= 2 && out[0] == 0xFF && out[1] == 0xFE {
+		return true
+	}
+	if runtime.GOOS != "windows" || len(out) < 4 || len(out)%2 != 0 {
+		return false
+	}
+	// Mostly-ASCII UTF-16LE has a zero in nearly every odd byte
+	zeros := 0
+	for i := 1; i < len(out); i += 2 {
+		if out[i] == 0 {
+			zeros++
+		}
+	}
+	return zeros*4 >= len(out)/2*3
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package service
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setRawCommandLine is only needed for cmd.exe, which exists on Windows alone.
+func setRawCommandLine(cmd *exec.Cmd, cmdLine string) {}
+
+// killProcessGroup makes a cancel kill the whole process group of the command, so that
+// the pipelines and background jobs it started stop with it.
+func killProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
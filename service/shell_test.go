@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestResolveShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix shells only")
+	}
+	sh, err := ResolveShell("")
+	if err != nil || sh.Name != ShellSh {
+		t.Fatalf("auto should use sh on %s, got %+v, %v", runtime.GOOS, sh, err)
+	}
+	out, err := sh.Command(context.Background(), `printf '%s' "a b" | tr a-z A-Z`).CombinedOutput()
+	if err != nil || string(out) != "A B" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+
+	if _, err := ResolveShell("cmd"); err == nil {
+		t.Error("cmd should not resolve outside Windows")
+	}
+	if _, err := ResolveShell("fish"); err == nil || !strings.Contains(err.Error(), "unknown shell") {
+		t.Errorf("expected an unknown shell error, got %v", err)
+	}
+}
+
+func TestShellToolUsesAgentShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix shells only")
+	}
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}, quiet: true, shell: ShellSh}
+	args := map[string]interface{}{"command": "echo hello"}
+	resp, err := shellToolCallImpl(context.Background(), &args, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp, "shell executed (sh): echo hello") || !strings.Contains(resp, "hello\n") {
+		t.Errorf("unexpected response:\n%s", resp)
+	}
+
+	op.shell = "fish"
+	resp, _ = shellToolCallImpl(context.Background(), &args, op)
+	if !strings.Contains(resp, "unknown shell") {
+		t.Errorf("expected an error for an unknown shell, got:\n%s", resp)
+	}
+}
+
+func TestDecodeShellOutput(t *testing.T) {
+	utf16 := []byte{0xFF, 0xFE, 'o', 0, 'k', 0, 0xE9, 0}
+	if got := DecodeShellOutput(utf16); got != "oké" {
+		t.Errorf("UTF-16 output decoded as %q", got)
+	}
+	if got := DecodeShellOutput([]byte("\xef\xbb\xbfplain")); got != "plain" {
+		t.Errorf("UTF-8 BOM not stripped: %q", got)
+	}
+	if got := DecodeShellOutput([]byte("bad \xff byte")); got != "bad \uFFFD byte" {
+		t.Errorf("invalid UTF-8 not replaced: %q", got)
+	}
+}
+
+func TestShellToolStopsOnCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Unix shells only")
+	}
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}, quiet: true, shell: ShellSh}
+	// The pipeline's children hold the output open after the shell is killed
+	args := map[string]interface{}{"command": "echo started; sleep 30 | cat", "timeout": float64(60)}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(300*time.Millisecond, cancel)
+
+	start := time.Now()
+	resp, err := shellToolCallImpl(ctx, &args, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("the command ran on for %v after the cancel", elapsed)
+	}
+	if !strings.Contains(resp, "interrupted by user") || !strings.Contains(resp, "started") {
+		t.Errorf("expected the interruption and the output so far, got:\n%s", resp)
+	}
+}
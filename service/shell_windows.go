@@ -0,0 +1,17 @@
+package service
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setRawCommandLine passes the command line to the process unchanged.
+func setRawCommandLine(cmd *exec.Cmd, cmdLine string) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CmdLine = cmdLine
+}
+
+// killProcessGroup leaves the cancel to the default kill of the shell on Windows.
+func killProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,316 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/activebook/gllm/data"
+)
+
+// Chat services the outputs of workflows and scheduled runs are posted to, see also SinkEmail.
+const (
+	SinkSlack   = "slack"
+	SinkDiscord = "discord"
+)
+
+// Message limits of the services, longer outputs are posted in chunks.
+const (
+	slackChunkSize   = 3900 // Slack truncates text beyond 4000 characters
+	discordChunkSize = 1900 // Discord rejects content beyond 2000 characters
+)
+
+// slackPostMessageURL is the Web API method used with a bot token.
+var slackPostMessageURL = "https://slack.com/api/chat.postMessage"
+
+// sinkRequestTimeout bounds a single post.
+const sinkRequestTimeout = 30 * time.Second
+
+// Sink posts messages to a chat channel.
+type Sink interface {
+	// Post sends the text under the title, in several messages when it is too long for one.
+	Post(ctx context.Context, title, text string) error
+	String() string
+}
+
+// SinkSpec is a parsed sink, written as service or service:target, e.g. slack:#releases.
+type SinkSpec struct {
+	Service string
+	Target  string // Slack channel or name of a Discord webhook, may be empty
+}
+
+func (s SinkSpec) String() string {
+	if s.Target == "" {
+		return s.Service
+	}
+	return s.Service + ":" + s.Target
+}
+
+// ParseSinkSpec reads a sink written as slack, slack:#channel, discord, discord:name
+// or email:addresses.
+func ParseSinkSpec(spec string) (SinkSpec, error) {
+	service, target, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	s := SinkSpec{Service: strings.ToLower(strings.TrimSpace(service)), Target: strings.TrimSpace(target)}
+	switch s.Service {
+	case SinkSlack, SinkDiscord:
+	case SinkEmail:
+		if _, err := ParseMailAddresses(s.Target); err != nil {
+			return SinkSpec{}, err
+		}
+	default:
+		return SinkSpec{}, fmt.Errorf("unsupported sink '%s', expected slack[:#channel], discord[:name] or email:address", spec)
+	}
+	return s, nil
+}
+
+// sinkSecret returns the first secret that is set among the names.
+func sinkSecret(names ...string) (string, error) {
+	for _, name := range names {
+		value, err := data.GetSecret(name)
+		if err != nil {
+			return "", err
+		}
+		if value != "" {
+			return value, nil
+		}
+	}
+	return "", nil
+}
+
+// NewSink returns the sink of the spec with its credentials from the secret store: a webhook
+// of the target (slack_webhook:#channel), the webhook of the service, or for Slack a bot token
+// that can post to any channel.
+func NewSink(spec string) (Sink, error) {
+	s, err := ParseSinkSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: sinkRequestTimeout}
+	switch s.Service {
+	case SinkEmail:
+		to, _ := ParseMailAddresses(s.Target)
+		return &emailSink{spec: s, to: to}, nil
+	case SinkSlack:
+		var names []string
+		if s.Target != "" {
+			names = append(names, data.SecretSlackWebhook+":"+s.Target)
+		}
+		webhook, err := sinkSecret(append(names, data.SecretSlackWebhook)...)
+		if err != nil {
+			return nil, err
+		}
+		token, err := sinkSecret(data.SecretSlackToken)
+		if err != nil {
+			return nil, err
+		}
+		// A bot token reaches the channel, a webhook only posts where it was created
+		if token != "" && s.Target != "" {
+			webhook = ""
+		}
+		if webhook == "" && token == "" {
+			return nil, fmt.Errorf("no Slack webhook or token, set one with 'gllm config secret set %s' or '%s'", data.SecretSlackWebhook, data.SecretSlackToken)
+		}
+		if webhook == "" && s.Target == "" {
+			return nil, fmt.Errorf("posting with a Slack token needs a channel, e.g. slack:#general")
+		}
+		return &slackSink{spec: s, webhook: webhook, token: token, client: client}, nil
+	default:
+		var names []string
+		if s.Target != "" {
+			names = append(names, data.SecretDiscordWebhook+":"+s.Target)
+		}
+		webhook, err := sinkSecret(append(names, data.SecretDiscordWebhook)...)
+		if err != nil {
+			return nil, err
+		}
+		if webhook == "" {
+			return nil, fmt.Errorf("no Discord webhook, set it with 'gllm config secret set %s'", data.SecretDiscordWebhook)
+		}
+		return &discordSink{spec: s, webhook: webhook, client: client}, nil
+	}
+}
+
+// PostToSink posts the text to the sink of the spec.
+func PostToSink(ctx context.Context, spec, title, text string) error {
+	sink, err := NewSink(spec)
+	if err != nil {
+		return err
+	}
+	return sink.Post(ctx, title, text)
+}
+
+// slackSink posts through an incoming webhook, or chat.postMessage with a bot token.
+type slackSink struct {
+	spec    SinkSpec
+	webhook string
+	token   string
+	client  *http.Client
+}
+
+func (s *slackSink) String() string {
+	return s.spec.String()
+}
+
+func (s *slackSink) Post(ctx context.Context, title, text string) error {
+	if title != "" {
+		text = "*" + title + "*\n\n" + text
+	}
+	for _, chunk := range ChunkMessage(text, slackChunkSize) {
+		payload := map[string]any{"text": chunk}
+		if s.spec.Target != "" {
+			payload["channel"] = s.spec.Target
+		}
+		if s.webhook != "" {
+			if _, err := postSinkJSON(ctx, s.client, s.webhook, "", payload); err != nil {
+				return err
+			}
+			continue
+		}
+		reply, err := postSinkJSON(ctx, s.client, slackPostMessageURL, "Bearer "+s.token, payload)
+		if err != nil {
+			return err
+		}
+		// The Web API answers 200 with ok false on errors
+		var result struct {
+			OK    bool   `json:"ok"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(reply, &result); err != nil {
+			return fmt.Errorf("invalid Slack reply: %w", err)
+		}
+		if !result.OK {
+			return fmt.Errorf("slack: %s", result.Error)
+		}
+	}
+	return nil
+}
+
+// discordSink posts through a channel webhook.
+type discordSink struct {
+	spec    SinkSpec
+	webhook string
+	client  *http.Client
+}
+
+func (d *discordSink) String() string {
+	return d.spec.String()
+}
+
+func (d *discordSink) Post(ctx context.Context, title, text string) error {
+	if title != "" {
+		text = "**" + title + "**\n\n" + text
+	}
+	for _, chunk := range ChunkMessage(text, discordChunkSize) {
+		if _, err := postSinkJSON(ctx, d.client, d.webhook, "", map[string]any{"content": chunk}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postSinkJSON posts the payload and returns the reply. A rate limited post is retried once
+// after the delay the service asks for.
+func postSinkJSON(ctx context.Context, client *http.Client, url, auth string, payload any) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		reply, err := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt == 0 {
+			delay, _ := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(time.Duration(min(max(delay, 1), 30) * float64(time.Second))):
+			}
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("post failed: %s %s", resp.Status, strings.TrimSpace(string(reply)))
+		}
+		return reply, nil
+	}
+}
+
+// ChunkMessage splits the text into messages of at most limit bytes, at line breaks where
+// it can. A code block cut in two is closed at the end of a chunk and reopened in the next.
+func ChunkMessage(text string, limit int) []string {
+	text = strings.TrimSpace(text)
+	if len(text) <= limit {
+		return []string{text}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	fence := "" // Opening line of the code block the chunk ends in
+	flush := func() {
+		chunk := strings.TrimRight(current.String(), "\n")
+		if fence != "" {
+			chunk += "\n```"
+		}
+		if strings.TrimSpace(chunk) != "" {
+			chunks = append(chunks, chunk)
+		}
+		current.Reset()
+		if fence != "" {
+			current.WriteString(fence + "\n")
+		}
+	}
+	// Room for the fence closed at the end of a chunk
+	room := limit - len("\n```")
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		// A line longer than a message is cut wherever it must
+		for len(line) > room-len(fence)-1 {
+			if current.Len() > 0 {
+				flush()
+			}
+			n := room - current.Len()
+			for n > 0 && !utf8.RuneStart(line[n]) {
+				n--
+			}
+			current.WriteString(line[:n])
+			line = line[n:]
+			flush()
+		}
+		if current.Len()+len(line) > room {
+			flush()
+		}
+		current.WriteString(line)
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, "```") {
+			if fence == "" {
+				fence = trimmed
+			} else {
+				fence = ""
+			}
+		}
+	}
+	// A block the text leaves open stays open
+	fence = ""
+	flush()
+	return chunks
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseSinkSpec(t *testing.T) {
+	tests := []struct {
+		spec string
+		want SinkSpec
+	}{
+		{"slack", SinkSpec{SinkSlack, ""}},
+		{"slack:#releases", SinkSpec{SinkSlack, "#releases"}},
+		{" Discord : alerts ", SinkSpec{SinkDiscord, "alerts"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSinkSpec(tt.spec)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSinkSpec(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+	if _, err := ParseSinkSpec("teams:#general"); err == nil {
+		t.Error("expected an unsupported sink error")
+	}
+}
+
+func TestChunkMessage(t *testing.T) {
+	if got := ChunkMessage("short", 100); len(got) != 1 || got[0] != "short" {
+		t.Errorf("ChunkMessage(short) = %q", got)
+	}
+
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, strings.Repeat("x", 30))
+	}
+	chunks := ChunkMessage(strings.Join(lines, "\n"), 200)
+	if len(chunks) < 8 {
+		t.Fatalf("expected the text split in chunks, got %d", len(chunks))
+	}
+	for _, c := range chunks {
+		if len(c) > 200 {
+			t.Errorf("chunk of %d bytes exceeds the limit", len(c))
+		}
+		if strings.HasPrefix(c, "\n") || strings.HasSuffix(c, "\n") {
+			t.Errorf("chunk not cut at a line break: %q", c)
+		}
+	}
+
+	// A code block across chunks is closed and reopened
+	code := "intro\n```go\n" + strings.Repeat("fmt.Println(1)\n", 20) + "```\nend"
+	chunks = ChunkMessage(code, 120)
+	for i, c := range chunks {
+		if strings.Count(c, "```")%2 != 0 {
+			t.Errorf("chunk %d has an unbalanced fence: %q", i, c)
+		}
+	}
+	if !strings.HasPrefix(chunks[1], "```go\n") {
+		t.Errorf("second chunk does not reopen the block: %q", chunks[1])
+	}
+
+	// A line longer than a message is cut, on rune boundaries
+	long := strings.Repeat("é", 150)
+	for _, c := range ChunkMessage(long, 100) {
+		if len(c) > 100 || !strings.HasPrefix(c, "é") {
+			t.Errorf("bad cut of a long line: %d bytes %q", len(c), c)
+		}
+	}
+}
+
+func TestSlackSinkPostsChunksToWebhook(t *testing.T) {
+	var posts []map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		posts = append(posts, body)
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	sink := &slackSink{spec: SinkSpec{SinkSlack, "#reports"}, webhook: server.URL, client: server.Client()}
+	text := strings.Repeat("line of the report\n", 400)
+	if err := sink.Post(context.Background(), "Report", text); err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) < 2 {
+		t.Fatalf("expected several posts, got %d", len(posts))
+	}
+	for _, p := range posts {
+		if p["channel"] != "#reports" || len(p["text"]) > slackChunkSize {
+			t.Errorf("bad post: channel %q, %d bytes", p["channel"], len(p["text"]))
+		}
+	}
+}
+
+func TestSlackSinkTokenError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-1" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"ok":false,"error":"channel_not_found"}`))
+	}))
+	defer server.Close()
+
+	old := slackPostMessageURL
+	slackPostMessageURL = server.URL
+	defer func() { slackPostMessageURL = old }()
+
+	sink := &slackSink{spec: SinkSpec{SinkSlack, "#nope"}, token: "xoxb-1", client: server.Client()}
+	err := sink.Post(context.Background(), "", "hello")
+	if err == nil || !strings.Contains(err.Error(), "channel_not_found") {
+		t.Errorf("expected the Slack error, got %v", err)
+	}
+}
+
+func TestDiscordSinkRetriesRateLimit(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink := &discordSink{spec: SinkSpec{SinkDiscord, ""}, webhook: server.URL, client: server.Client()}
+	if err := sink.Post(context.Background(), "", "done"); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("expected a retry, got %d calls", calls)
+	}
+}
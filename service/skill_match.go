@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+)
+
+const (
+	// skillMatchMinScore is the similarity from which a skill is relevant to a prompt.
+	skillMatchMinScore = 0.2
+	// skillEmbeddingMinScore is the same for the embeddings, which are never far apart.
+	skillEmbeddingMinScore = 0.45
+	// skillHintLimit is how many relevant skills the system prompt hints at most.
+	skillHintLimit = 3
+)
+
+// SkillMatch is a skill relevant to a prompt.
+type SkillMatch struct {
+	Skill data.SkillMetadata
+	Score float64
+}
+
+var (
+	skillSuggestMu sync.Mutex
+	// autoActivatedSkills holds the skills activated without a tool call, by session,
+	// so they are injected once.
+	autoActivatedSkills = map[string]map[string]bool{}
+)
+
+// skillTerms returns the words a skill is matched by: its name and description.
+func skillTerms(skill data.SkillMetadata) []string {
+	return recallTerms(skill.Name + " " + skill.Description)
+}
+
+// MatchSkills scores the skills against a prompt, the best first. Skills are compared by
+// their name and description: by their embeddings with the embedding model of the settings,
+// by their words without one or when it fails.
+func MatchSkills(ctx context.Context, prompt string, skills []data.SkillMetadata) []SkillMatch {
+	if len(recallTerms(prompt)) == 0 || len(skills) == 0 {
+		return nil
+	}
+	if model := EmbeddingModel(); model != nil {
+		matches, err := matchSkillsByEmbedding(ctx, model, prompt, skills)
+		if err == nil {
+			return matches
+		}
+		util.LogDebugf("Matching the skills by their words: %v\n", err)
+	}
+	return matchSkillsByTerms(prompt, skills)
+}
+
+// matchSkillsByEmbedding scores the skills by the similarity of their embeddings with the
+// prompt's. The embeddings of the skills are computed once.
+func matchSkillsByEmbedding(ctx context.Context, model *data.Model, prompt string, skills []data.SkillMetadata) ([]SkillMatch, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, embeddingTimeout)
+	defer cancel()
+
+	texts := make([]string, 0, len(skills)+1)
+	for _, skill := range skills {
+		texts = append(texts, skill.Name+": "+skill.Description)
+	}
+	vectors, err := embedCached(ctx, model, texts)
+	if err != nil {
+		return nil, err
+	}
+	query, err := EmbedTexts(ctx, model, []string{prompt})
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []SkillMatch
+	for i, skill := range skills {
+		if score := denseCosineSimilarity(query[0], vectors[i]); score >= skillEmbeddingMinScore {
+			matches = append(matches, SkillMatch{Skill: skill, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches, nil
+}
+
+// matchSkillsByTerms scores the skills by the words they share with the prompt, each word
+// weighed by its rarity across the skills.
+func matchSkillsByTerms(prompt string, skills []data.SkillMetadata) []SkillMatch {
+	query := recallVector(recallTerms(prompt))
+
+	vectors := make([]map[string]float64, len(skills))
+	df := make(map[string]int)
+	for i, skill := range skills {
+		vectors[i] = recallVector(skillTerms(skill))
+		for t := range vectors[i] {
+			df[t]++
+		}
+	}
+	idf := func(t string) float64 {
+		return 1 + math.Log(float64(len(skills)+1)/float64(df[t]+1))
+	}
+
+	weighted := weightRecallVector(query, idf)
+	var matches []SkillMatch
+	for i, skill := range skills {
+		score := cosineSimilarity(weighted, weightRecallVector(vectors[i], idf))
+		if score >= skillMatchMinScore {
+			matches = append(matches, SkillMatch{Skill: skill, Score: score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	return matches
+}
+
+// SuggestSkills matches a prompt against the enabled skills. When the best match is marked
+// auto_activate, its instructions are prepended to the prompt, once per session. The other
+// matches are returned as a hint for the system prompt, "" when none.
+func SuggestSkills(ctx context.Context, prompt, sessionName string) (string, string) {
+	sm := GetSkillManager()
+	matches := MatchSkills(ctx, StripInlineContext(prompt), sm.GetAvailableSkillsMetadata())
+	if len(matches) == 0 {
+		return prompt, ""
+	}
+
+	skillSuggestMu.Lock()
+	activated := autoActivatedSkills[sessionName]
+	if activated == nil {
+		activated = map[string]bool{}
+		autoActivatedSkills[sessionName] = activated
+	}
+	top := matches[0].Skill
+	activate := top.AutoActivate && !activated[strings.ToLower(top.Name)]
+	skillSuggestMu.Unlock()
+
+	if activate {
+		details, _, _, err := sm.ActivateSkill(top.Name)
+		if err != nil {
+			util.LogWarnf("Failed to auto-activate skill %s: %v\n", top.Name, err)
+		} else {
+			skillSuggestMu.Lock()
+			activated[strings.ToLower(top.Name)] = true
+			skillSuggestMu.Unlock()
+			util.LogInfof("Activated skill %s, it matches the prompt\n", top.Name)
+			note := fmt.Sprintf("The skill '%s' was activated because it matches the request below. Follow its instructions.\n\n%s", top.Name, details)
+			prompt = BuildInlineContextBlock([]string{note}) + prompt
+		}
+	}
+
+	skillSuggestMu.Lock()
+	defer skillSuggestMu.Unlock()
+	var hints []string
+	for _, m := range matches {
+		if activated[strings.ToLower(m.Skill.Name)] {
+			continue
+		}
+		hints = append(hints, fmt.Sprintf("- %s: %s", m.Skill.Name, m.Skill.Description))
+		if len(hints) == skillHintLimit {
+			break
+		}
+	}
+	if len(hints) == 0 {
+		return prompt, ""
+	}
+	hint := fmt.Sprintf("<relevant_skills>\nThese skills look relevant to the latest request. Activate them with %s if they help:\n%s\n</relevant_skills>", ToolActivateSkill, strings.Join(hints, "\n"))
+	return prompt, hint
+}
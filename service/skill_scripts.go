@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+)
+
+// skillScriptInterpreters run the scripts that are not executables themselves, by extension.
+var skillScriptInterpreters = map[string][]string{
+	".py":  {"python3"},
+	".sh":  {"bash"},
+	".js":  {"node"},
+	".mjs": {"node"},
+	".ts":  {"npx", "tsx"},
+	".rb":  {"ruby"},
+	".pl":  {"perl"},
+	".ps1": {"pwsh", "-NoProfile", "-File"},
+}
+
+// findAvailableSkill returns the enabled skill of a name, case-insensitive.
+func (sm *SkillManager) findAvailableSkill(name string) *data.SkillMetadata {
+	for _, skill := range sm.GetAvailableSkillsMetadata() {
+		if strings.EqualFold(skill.Name, name) {
+			return &skill
+		}
+	}
+	return nil
+}
+
+// findSkillScript returns the script of a skill declared under a name.
+func findSkillScript(skill *data.SkillMetadata, name string) *data.SkillScript {
+	for _, script := range skill.Scripts {
+		if strings.EqualFold(script.Name, name) {
+			return &script
+		}
+	}
+	return nil
+}
+
+// resolveSkillScriptPath returns the absolute path of a script, which must be a file
+// within the skill's directory, links resolved.
+func resolveSkillScriptPath(skill *data.SkillMetadata, script *data.SkillScript) (string, error) {
+	dir, err := filepath.EvalSymlinks(filepath.Dir(skill.Location))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve the directory of skill '%s': %w", skill.Name, err)
+	}
+	if filepath.IsAbs(script.Path) {
+		return "", fmt.Errorf("script '%s' of skill '%s' must have a path relative to the skill directory", script.Name, skill.Name)
+	}
+	path, err := filepath.EvalSymlinks(filepath.Join(dir, script.Path))
+	if err != nil {
+		return "", fmt.Errorf("script '%s' of skill '%s' not found: %w", script.Name, skill.Name, err)
+	}
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("script '%s' of skill '%s' is outside the skill directory", script.Name, skill.Name)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("script '%s' of skill '%s' is a directory", script.Name, skill.Name)
+	}
+	return path, nil
+}
+
+// skillScriptArgs orders the arguments of a call as the script declares them. Unknown
+// arguments and missing required ones are errors; optional ones left out pass an empty string
+// when a later argument is given, so the positions hold.
+func skillScriptArgs(script *data.SkillScript, values map[string]interface{}) ([]string, error) {
+	for name := range values {
+		known := false
+		for _, arg := range script.Args {
+			if arg.Name == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("script '%s' has no argument '%s'", script.Name, name)
+		}
+	}
+
+	var args []string
+	pending := 0
+	for _, arg := range script.Args {
+		v, ok := values[arg.Name]
+		if !ok || v == nil {
+			if arg.Required {
+				return nil, fmt.Errorf("argument '%s' of script '%s' is required", arg.Name, script.Name)
+			}
+			pending++
+			continue
+		}
+		for ; pending > 0; pending-- {
+			args = append(args, "")
+		}
+		args = append(args, fmt.Sprint(v))
+	}
+	return args, nil
+}
+
+// skillScriptCommand builds the command that runs a script in its skill's directory.
+func skillScriptCommand(ctx context.Context, skillDir, path string, args []string) *exec.Cmd {
+	var cmd *exec.Cmd
+	ext := strings.ToLower(filepath.Ext(path))
+	if info, err := os.Stat(path); err == nil && runtime.GOOS != "windows" && info.Mode()&0111 != 0 {
+		// Executables run themselves, by their shebang
+		cmd = exec.CommandContext(ctx, path, args...)
+	} else if interpreter, ok := skillScriptInterpreters[ext]; ok {
+		argv := append(append([]string{}, interpreter[1:]...), path)
+		cmd = exec.CommandContext(ctx, interpreter[0], append(argv, args...)...)
+	} else {
+		cmd = exec.CommandContext(ctx, path, args...)
+	}
+	cmd.Dir = skillDir
+	return cmd
+}
+
+// describeSkillScripts lists the scripts of a skill for its activation.
+func describeSkillScripts(skill *data.SkillMetadata) string {
+	if len(skill.Scripts) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("  <scripts>\n")
+	for _, script := range skill.Scripts {
+		fmt.Fprintf(&sb, "    <script name=%q path=%q>\n", script.Name, script.Path)
+		if script.Description != "" {
+			fmt.Fprintf(&sb, "      %s\n", script.Description)
+		}
+		for _, arg := range script.Args {
+			required := ""
+			if arg.Required {
+				required = " (required)"
+			}
+			fmt.Fprintf(&sb, "      - %s%s: %s\n", arg.Name, required, arg.Description)
+		}
+		sb.WriteString("    </script>\n")
+	}
+	sb.WriteString("  </scripts>\n")
+	return sb.String()
+}
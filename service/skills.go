@@ -196,6 +196,10 @@ func (sm *SkillManager) ActivateSkill(name string) (string, string, string, erro
 	sb.WriteString(fmt.Sprintf("  <available_resources root=\"%s\">\n", skillDir))
 	sb.WriteString(tree)
 	sb.WriteString("  </available_resources>\n")
+	if scripts := describeSkillScripts(selectedSkill); scripts != "" {
+		sb.WriteString(fmt.Sprintf("  <!-- Run these scripts with %s, giving the arguments by name -->\n", ToolRunSkillScript))
+		sb.WriteString(scripts)
+	}
 	sb.WriteString("</activated_skill>")
 
 	return sb.String(), selectedSkill.Description, tree, nil
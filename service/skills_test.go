@@ -1,7 +1,9 @@
 package service
 
 import (
+	"context"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -104,3 +106,132 @@ Do testing stuff.`
 
 	t.Logf("ActivateSkill output:\n%s", instructions)
 }
+
+func TestMatchSkills(t *testing.T) {
+	skills := []data.SkillMetadata{
+		{Name: "arxiv-search", Description: "Search arxiv for research papers and summarize their abstracts."},
+		{Name: "youtube-download", Description: "Download youtube videos and extract their audio."},
+		{Name: "pdf-tools", Description: "Merge, split and extract text from pdf documents."},
+	}
+
+	matches := MatchSkills(context.Background(), "Find recent research papers about diffusion models on arxiv", skills)
+	if len(matches) == 0 || matches[0].Skill.Name != "arxiv-search" {
+		t.Fatalf("expected arxiv-search first, got %+v", matches)
+	}
+	for _, m := range matches {
+		if m.Skill.Name == "youtube-download" {
+			t.Errorf("youtube-download is not relevant: %+v", matches)
+		}
+	}
+
+	if matches := MatchSkills(context.Background(), "What's the weather like today?", skills); len(matches) != 0 {
+		t.Errorf("no skill is relevant, got %+v", matches)
+	}
+}
+
+func TestMatchSkillsByEmbedding(t *testing.T) {
+	model, _ := fakeEmbeddingServer(t)
+	skills := []data.SkillMetadata{
+		{Name: "arxiv-search", Description: "Search arxiv for research papers and summarize their abstracts."},
+		{Name: "youtube-download", Description: "Download youtube videos and extract their audio."},
+	}
+
+	// No word in common with the skill, the meaning is
+	matches, err := matchSkillsByEmbedding(context.Background(), model, "Any new publication on diffusion models?", skills)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Skill.Name != "arxiv-search" {
+		t.Errorf("expected only arxiv-search, got %+v", matches)
+	}
+}
+
+func TestSuggestSkills(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for name, manifest := range map[string]string{
+		"changelog":    "name: changelog\ndescription: Write the changelog entry of a release from the git log.\nauto_activate: true",
+		"release-tags": "name: release-tags\ndescription: Tag a release in git and push the tag.",
+	} {
+		dir := filepath.Join(data.GetSkillsDirPath(), name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "SKILL.md"), []byte("---\n"+manifest+"\n---\nUse keep-a-changelog sections."), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prompt, hint := SuggestSkills(context.Background(), "Write the changelog of the release from the git log", "suggest-test")
+	if !strings.Contains(prompt, "Use keep-a-changelog sections.") || StripInlineContext(prompt) != "Write the changelog of the release from the git log" {
+		t.Errorf("changelog must be activated:\n%s", prompt)
+	}
+	if !strings.Contains(hint, "- release-tags:") || strings.Contains(hint, "- changelog:") {
+		t.Errorf("only release-tags must be hinted at:\n%s", hint)
+	}
+
+	// An auto-activated skill is injected once per session
+	prompt, hint = SuggestSkills(context.Background(), "Now the changelog of the next release", "suggest-test")
+	if prompt != "Now the changelog of the next release" || strings.Contains(hint, "changelog:") {
+		t.Errorf("changelog is already active: %q, %q", prompt, hint)
+	}
+}
+
+func TestRunSkillScript(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := filepath.Join(data.GetSkillsDirPath(), "greeter")
+	if err := os.MkdirAll(filepath.Join(dir, "scripts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifest := `---
+name: greeter
+description: Greets people.
+scripts:
+  - name: greet
+    path: scripts/greet.sh
+    description: Prints a greeting.
+    args:
+      - name: who
+        required: true
+      - name: punctuation
+  - name: escape
+    path: ../escape.sh
+---
+Greet politely.`
+	files := map[string]string{
+		filepath.Join(dir, "SKILL.md"):         manifest,
+		filepath.Join(dir, "scripts/greet.sh"): "#!/bin/sh\necho \"hello $1$2 from $(basename \"$PWD\")\"\n",
+		filepath.Join(dir, "../escape.sh"):     "#!/bin/sh\necho escaped\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}}
+	run := func(args map[string]interface{}) string {
+		out, err := runSkillScriptToolCallImpl(context.Background(), &args, op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	if out := run(map[string]interface{}{"skill": "Greeter", "script": "greet", "args": map[string]interface{}{"who": "world", "punctuation": "!"}}); !strings.Contains(out, "hello world! from greeter") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	for want, args := range map[string]map[string]interface{}{
+		"argument 'who' of script 'greet' is required":   {"skill": "greeter", "script": "greet"},
+		"script 'greet' has no argument 'loud'":          {"skill": "greeter", "script": "greet", "args": map[string]interface{}{"who": "x", "loud": "1"}},
+		"is outside the skill directory":                 {"skill": "greeter", "script": "escape"},
+		"has no script 'missing', it has: greet, escape": {"skill": "greeter", "script": "missing"},
+		"skill 'nope' not found":                         {"skill": "nope", "script": "greet"},
+	} {
+		if out := run(args); !strings.Contains(out, want) || strings.Contains(out, "escaped") {
+			t.Errorf("expected %q, got:\n%s", want, out)
+		}
+	}
+}
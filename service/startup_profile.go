@@ -0,0 +1,84 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The startup profile records when a run reaches each step before its first request to the
+// model, to find what delays it. gllm --profile-startup prints it on exit.
+const (
+	StartupConfigLoaded   = "config loaded"
+	StartupAgentResolved  = "agent resolved"
+	StartupSessionChecked = "session checked"
+	StartupPromptBuilt    = "prompt built"
+	StartupFastPath       = "fast path"
+	StartupMCPReady       = "mcp ready"
+	StartupSystemPrompt   = "system prompt"
+	StartupToolsBuilt     = "tools built"
+	StartupRequestSent    = "request sent"
+	StartupFirstToken     = "first token"
+)
+
+type startupMark struct {
+	step string
+	at   time.Duration
+}
+
+var startupProfile = struct {
+	mu      sync.Mutex
+	enabled bool
+	start   time.Time
+	marks   []startupMark
+}{start: time.Now()}
+
+// EnableStartupProfile starts recording the steps of the run. Times are relative to the start
+// of the process.
+func EnableStartupProfile() {
+	startupProfile.mu.Lock()
+	defer startupProfile.mu.Unlock()
+	startupProfile.enabled = true
+}
+
+// MarkStartup records that the run reached a step. Only the first time counts, so that later
+// turns of a session don't move the marks.
+func MarkStartup(step string) {
+	startupProfile.mu.Lock()
+	defer startupProfile.mu.Unlock()
+	if !startupProfile.enabled {
+		return
+	}
+	for _, m := range startupProfile.marks {
+		if m.step == step {
+			return
+		}
+	}
+	startupProfile.marks = append(startupProfile.marks, startupMark{step: step, at: time.Since(startupProfile.start)})
+}
+
+// StartupProfileReport renders the recorded steps with the time each one took, "" when the
+// profile is off.
+func StartupProfileReport() string {
+	startupProfile.mu.Lock()
+	defer startupProfile.mu.Unlock()
+	if !startupProfile.enabled {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("Startup profile:\n")
+	var last time.Duration
+	for _, m := range startupProfile.marks {
+		fmt.Fprintf(&sb, "  %-16s %9s  +%s\n", m.step, formatProfileDuration(m.at), formatProfileDuration(m.at-last))
+		last = m.at
+	}
+	if len(startupProfile.marks) == 0 {
+		sb.WriteString("  no steps recorded\n")
+	}
+	return sb.String()
+}
+
+func formatProfileDuration(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d.Microseconds())/1000)
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestFastPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	if err := data.NewMemoryStore().Add("The user is called fast-path-marker"); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(model string, tools []string) string {
+		t.Helper()
+		script := &MockScript{Turns: []MockTurn{{Text: "ok"}}}
+		RegisterMockScript(model, script)
+		err := CallAgent(&AgentOptions{
+			Ctx:           context.Background(),
+			Prompt:        "hello",
+			ModelInfo:     &data.Model{Provider: ModelProviderMock, Model: model},
+			EnabledTools:  tools,
+			Capabilities:  []string{CapabilityAgentMemory, CapabilityMCPServers},
+			MaxRecursions: 5,
+			YoloMode:      true,
+			QuietMode:     true,
+			OutputFile:    filepath.Join(t.TempDir(), "out.md"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(script.Requests()[0])
+	}
+
+	// Without tools, memory is not loaded and no tool is offered
+	request := run("mock-fast", nil)
+	if strings.Contains(request, "fast-path-marker") {
+		t.Error("expected the fast path to skip the memory")
+	}
+	if strings.Contains(request, `"tools"`) {
+		t.Errorf("expected no tools on the fast path: %s", request)
+	}
+
+	// An agent with tools gets its memory and tools
+	request = run("mock-full", []string{ToolReadFile})
+	if !strings.Contains(request, "fast-path-marker") {
+		t.Error("expected the memory in the system prompt")
+	}
+	if !strings.Contains(request, ToolReadFile) {
+		t.Error("expected the tools of the agent")
+	}
+
+	if isFastPath(&AgentOptions{Capabilities: []string{CapabilityWebSearch}}) {
+		t.Error("web search brings tools, it is not a fast path")
+	}
+	if isFastPath(&AgentOptions{Files: []*FileData{NewFileData("text/plain", []byte("x"), "x.txt")}}) {
+		t.Error("attachments are not a fast path")
+	}
+}
+
+func TestStartupProfile(t *testing.T) {
+	if StartupProfileReport() != "" {
+		t.Fatal("expected no report while the profile is off")
+	}
+	EnableStartupProfile()
+	defer func() {
+		startupProfile.mu.Lock()
+		startupProfile.enabled = false
+		startupProfile.marks = nil
+		startupProfile.mu.Unlock()
+	}()
+	MarkStartup(StartupConfigLoaded)
+	MarkStartup(StartupRequestSent)
+	MarkStartup(StartupConfigLoaded)
+
+	report := StartupProfileReport()
+	if strings.Count(report, StartupConfigLoaded) != 1 || !strings.Contains(report, StartupRequestSent) {
+		t.Errorf("unexpected report:\n%s", report)
+	}
+	if strings.Index(report, StartupConfigLoaded) > strings.Index(report, StartupRequestSent) {
+		t.Errorf("expected the steps in order:\n%s", report)
+	}
+}
@@ -17,8 +17,10 @@ const (
 	StatusFunctionCallingOver
 	StatusShowDiff
 	StatusShowDiffOver
+	StatusShowTodos
 	StatusSwitchAgent
 	StatusUserCancel
+	StatusToolPreview // Arguments of a tool call still streaming, Data sums them up
 )
 
 type StreamNotify struct {
@@ -27,6 +29,23 @@ type StreamNotify struct {
 	Extra  interface{} // For additional metadata (e.g., switch instruction)
 }
 
+// toolCallOver is the notification that ends a tool call, it carries the result for the display.
+func toolCallOver(result string, err error) StreamNotify {
+	if result == "" && err != nil {
+		result = fmt.Sprintf("Error: %v", err)
+	}
+	return StreamNotify{Status: StatusFunctionCallingOver, Data: result}
+}
+
+// StreamObserver receives the events of an agent run as they are processed,
+// for front ends that render them on their own, such as the TUI dashboard.
+// Calls come from the agent's goroutines and must not block.
+type StreamObserver interface {
+	OnData(data StreamData)
+	OnNotify(notify StreamNotify)
+	OnSubAgent(result SubAgentResult) // Sub-agent task started, completed or failed
+}
+
 // StateStack is a stack data structure for managing states.
 type StatusStack struct {
 	statuses []StreamStatus
@@ -121,7 +140,7 @@ func (s *StatusStack) ChangeTo(
 		for s.IsTop(StatusShowDiff) || s.IsTop(StatusShowDiffOver) {
 			s.Pop() // Remove the diff confirm status
 		}
-	case StatusWarn:
+	case StatusWarn, StatusShowTodos, StatusToolPreview:
 		// Do nothing
 	default:
 		// For other statuses, we just push the new status
@@ -1,7 +1,10 @@
 package service
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -9,6 +12,7 @@ import (
 	"github.com/activebook/gllm/data"
 	"github.com/activebook/gllm/io"
 	"github.com/activebook/gllm/util"
+	"github.com/google/jsonschema-go/jsonschema"
 )
 
 // SubAgentStatus represents the execution status of a sub-agent task
@@ -46,6 +50,10 @@ type SubAgentTask struct {
 	Instruction     string   // Task instruction/prompt
 	TaskKey         string   // Key to store result in SharedState (becomes agentName_taskKey)
 	InputKeys       []string // Keys to read as input context (virtual files), injected into instruction
+	Priority        int      // Tasks of higher priority start first when the concurrency limits are reached
+	OutputSchema    string   // JSON schema the output must match, kept whole in SharedState; empty for free text
+	RunID           string   // Artifacts run and queued batch of the dispatch, set by Dispatch
+	QueueIndex      int      // Position of the task in its queued batch
 }
 
 // SubAgentResult represents the outcome of a sub-agent execution
@@ -59,10 +67,13 @@ type SubAgentResult struct {
 	Duration  time.Duration  // Execution duration
 	StartTime time.Time      // When execution started
 	EndTime   time.Time      // When execution ended
+	Artifact  string         // Path of the saved output, empty when not saved
+	Recovered bool           // Completed by a previous run, its result restored from the queue
 }
 
 // AgentMessage is a task delivery envelope sent on an agent's TaskChan.
 type AgentMessage struct {
+	Ctx      context.Context // Cancellation of the dispatch, stops the task
 	Task     *SubAgentTask
 	RespChan chan<- AgentResponse // caller-owned, per-request
 }
@@ -97,6 +108,12 @@ type SubAgentExecutor struct {
 	stdOutput    io.Output
 	fileOutput   io.Output
 	sseOutput    *io.SSEOutput
+	observer     StreamObserver // Receiver of task status changes, nil when none
+
+	queueMu sync.Mutex
+	queue   map[string]*data.TaskBatch // Batches being run, saved to disk on every change
+
+	scheduler *taskScheduler // Concurrency limits of the tasks
 }
 
 // NewSubAgentExecutor creates a new SubAgentExecutor
@@ -105,6 +122,8 @@ func NewSubAgentExecutor(state *data.SharedState, mainSessionName string, stdOut
 		state:           state,
 		mainSessionName: mainSessionName,
 		activeAgents:    make(map[string]*ActiveAgent),
+		queue:           make(map[string]*data.TaskBatch),
+		scheduler:       newTaskScheduler(data.GetSettingsStore().GetSubagentLimits()),
 		mcpStore:        data.NewMCPStore(),
 		runner:          CallAgent, // Default runner
 		stdOutput:       stdOutput,
@@ -167,10 +186,25 @@ func (e *SubAgentExecutor) agentLoop(agent *ActiveAgent) {
 	}
 }
 
+// SetLimits replaces the concurrency limits of the tasks dispatched from now on.
+func (e *SubAgentExecutor) SetLimits(limits data.SubagentSettings) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scheduler = newTaskScheduler(limits)
+}
+
 // handleMsg performs the work requested by an AgentMessage.
 func (e *SubAgentExecutor) handleMsg(agent *ActiveAgent, msg AgentMessage) {
-	// Execute the task
-	result := e.executeTask(agent, msg.Task)
+	// Wait for a slot under the concurrency limits
+	e.mu.RLock()
+	scheduler := e.scheduler
+	e.mu.RUnlock()
+	if err := scheduler.acquire(msg.Ctx, agent.Name, msg.Task.Priority); err == nil {
+		defer scheduler.release(agent.Name)
+	}
+
+	// Execute the task, one cancelled while waiting is reported without starting
+	result := e.executeTask(msg.Ctx, agent, msg.Task)
 
 	// Send the response back to the caller
 	msg.RespChan <- AgentResponse{
@@ -181,11 +215,21 @@ func (e *SubAgentExecutor) handleMsg(agent *ActiveAgent, msg AgentMessage) {
 }
 
 // Dispatch fans out tasks asynchronously to subagents and waits for all responses.
-func (e *SubAgentExecutor) Dispatch(tasks []*SubAgentTask) ([]AgentResponse, error) {
+// The tasks are queued on disk until they complete, for gllm resume-tasks to run them
+// again after a crash.
+func (e *SubAgentExecutor) Dispatch(ctx context.Context, tasks []*SubAgentTask) ([]AgentResponse, error) {
 	if len(tasks) == 0 {
 		return nil, nil
 	}
 
+	// The outputs of the dispatch are saved together
+	runID := data.NewArtifactRunID()
+	e.queueBatch(runID, tasks)
+	return e.dispatch(ctx, tasks)
+}
+
+// dispatch runs queued tasks concurrently and collects their responses.
+func (e *SubAgentExecutor) dispatch(ctx context.Context, tasks []*SubAgentTask) ([]AgentResponse, error) {
 	// Buffered channel avoids goroutine leak if the caller panics or gives up early
 	respChan := make(chan AgentResponse, len(tasks))
 
@@ -193,6 +237,10 @@ func (e *SubAgentExecutor) Dispatch(tasks []*SubAgentTask) ([]AgentResponse, err
 	for _, task := range tasks {
 		agent, err := e.startSubAgent(task.AgentName)
 		if err != nil {
+			e.updateQueuedTask(task, func(q *data.QueuedTask) {
+				q.Status = data.QueuedTaskFailed
+				q.Error = err.Error()
+			})
 			// Fast-fail if agent config is missing before trying to send
 			respChan <- AgentResponse{
 				TaskKey: task.TaskKey,
@@ -208,6 +256,7 @@ func (e *SubAgentExecutor) Dispatch(tasks []*SubAgentTask) ([]AgentResponse, err
 		// If TaskChan buffer is full, we must use a goroutine to wait
 		go func(a *ActiveAgent, t *SubAgentTask) {
 			a.TaskChan <- AgentMessage{
+				Ctx:      ctx,
 				Task:     t,
 				RespChan: respChan,
 			}
@@ -225,7 +274,7 @@ func (e *SubAgentExecutor) Dispatch(tasks []*SubAgentTask) ([]AgentResponse, err
 }
 
 // executeTask runs the LLM call for a sub-agent task.
-func (e *SubAgentExecutor) executeTask(agent *ActiveAgent, task *SubAgentTask) *SubAgentResult {
+func (e *SubAgentExecutor) executeTask(ctx context.Context, agent *ActiveAgent, task *SubAgentTask) *SubAgentResult {
 	result := &SubAgentResult{
 		AgentName: agent.Name,
 		TaskKey:   task.TaskKey,
@@ -248,8 +297,17 @@ func (e *SubAgentExecutor) executeTask(agent *ActiveAgent, task *SubAgentTask) *
 	// The key used to store the output in SharedState
 	result.StateKey = agentTaskKey
 
+	// A task still waiting for a slot when the dispatch is cancelled doesn't start
+	if ctx.Err() != nil {
+		e.setTaskCancelled(result, task.TaskKey)
+		e.finishQueuedTask(task, result)
+		return result
+	}
+
 	// Set task start status and print the start message
 	e.setTaskStart(result, task, sessionName)
+	e.updateQueuedTask(task, func(q *data.QueuedTask) { q.Status = data.QueuedTaskRunning })
+	defer e.finishQueuedTask(task, result)
 
 	// Load MCP config
 	mcpConfig, _ := e.mcpStore.Load()
@@ -263,6 +321,10 @@ func (e *SubAgentExecutor) executeTask(agent *ActiveAgent, task *SubAgentTask) *
 		for _, key := range task.InputKeys {
 			if val, ok := e.state.Get(key); ok {
 				contentStr := fmt.Sprintf("%v", val)
+				if isStructuredOutput(contentStr) {
+					ctxBlob.WriteString(fmt.Sprintf("\n## Output from '%s' (JSON):\n```json\n%s\n```\n", util.GetSanitizeTitle(key), contentStr))
+					continue
+				}
 				ctxBlob.WriteString(fmt.Sprintf("\n## Output from '%s':\n%s\n", util.GetSanitizeTitle(key), contentStr))
 			} else {
 				util.LogWarnf("Sub-agent input key '%s' not found in SharedState, skipping.\n", key)
@@ -271,28 +333,57 @@ func (e *SubAgentExecutor) executeTask(agent *ActiveAgent, task *SubAgentTask) *
 		finalInstruction = BuildInlineContextBlock([]string{ctxBlob.String()}) + task.Instruction
 	}
 
+	// A structured output is validated against its schema once the agent answers
+	var schema *jsonschema.Resolved
+	if task.OutputSchema != "" {
+		resolved, err := resolveOutputSchema(task.OutputSchema)
+		if err != nil {
+			e.setTaskError(result, task.TaskKey, err)
+			return result
+		}
+		schema = resolved
+		finalInstruction = outputSchemaInstruction(finalInstruction, task.OutputSchema)
+	}
+
 	// Prepare agent options
 	op := AgentOptions{
-		Prompt:        finalInstruction,
-		SysPrompt:     agent.Config.SystemPrompt,
-		Files:         nil,
-		ModelInfo:     &agent.Config.Model,
-		MaxRecursions: agent.Config.MaxRecursions,
-		ThinkingLevel: agent.Config.Think,
-		EnabledTools:  agent.Config.Tools,
-		Capabilities:  agent.Config.Capabilities,
-		Interaction:   nil,  // Sub-agents don't need interaction
-		YoloMode:      true, // Sub-agents always auto-approve
-		QuietMode:     true, // Sub-agents run quietly
-		SessionName:   sessionName,
-		MCPConfig:     mcpConfig,
-		SharedState:   e.state,
-		AgentName:     agent.Name,
-		ModelName:     agent.Config.Model.Name,
+		Ctx:            ctx,
+		Prompt:         finalInstruction,
+		SysPrompt:      agent.Config.SystemPrompt,
+		Files:          nil,
+		ModelInfo:      &agent.Config.Model,
+		MaxRecursions:  agent.Config.MaxRecursions,
+		ThinkingLevel:  agent.Config.Think,
+		EnabledTools:   agent.Config.Tools,
+		AutoFormat:     agent.Config.AutoFormat,
+		Shell:          agent.Config.Shell,
+		InjectionGuard: agent.Config.InjectionGuard,
+		Paths:          agent.Config.Paths,
+		Capabilities:   agent.Config.Capabilities,
+		Interaction:    nil,  // Sub-agents don't need interaction
+		YoloMode:       true, // Sub-agents always auto-approve
+		QuietMode:      true, // Sub-agents run quietly
+		SessionName:    sessionName,
+		MCPConfig:      mcpConfig,
+		SharedState:    e.state,
+		AgentName:      agent.Name,
+		ModelName:      agent.Config.Model.Name,
+		TokenUsage:     NewTokenUsage(),
 	}
 
 	// Execute the agent (synchronous blocking call within this goroutine)
 	err := e.runner(&op)
+	if ctx.Err() != nil {
+		// Interrupted, its session keeps what the agent did so far
+		e.setTaskCancelled(result, task.TaskKey)
+		return result
+	}
+	var structured string
+	var schemaErr error
+	if err == nil && schema != nil {
+		structured, schemaErr = e.conformOutput(&op, schema)
+		err = schemaErr
+	}
 	if err != nil {
 		e.setTaskError(result, task.TaskKey, err)
 	}
@@ -301,6 +392,17 @@ func (e *SubAgentExecutor) executeTask(agent *ActiveAgent, task *SubAgentTask) *
 	if agentTaskKey != "" && e.state != nil {
 		sessionData, readErr := ReadSessionContent(sessionName)
 		if readErr == nil {
+			defer e.saveArtifact(result, task, op.TokenUsage, lastAssistantText(sessionData))
+			if structured != "" {
+				// A structured output is kept whole for the consumers to parse
+				e.state.Set(agentTaskKey, structured, agent.Name)
+				e.setTaskCompleted(result, task.TaskKey)
+				return result
+			}
+			if schemaErr != nil {
+				e.state.Set(agentTaskKey, fmt.Sprintf("[%v]", schemaErr), agent.Name)
+				return result
+			}
 			summary, compressErr := CompressSession(agent.Config, sessionData)
 			if compressErr != nil {
 				e.state.Set(agentTaskKey, fmt.Sprintf("[compression failed: %v]", compressErr), agent.Name)
@@ -319,6 +421,179 @@ func (e *SubAgentExecutor) executeTask(agent *ActiveAgent, task *SubAgentTask) *
 	return result
 }
 
+// saveArtifact writes the full output of a task to the artifacts of its run, the SharedState
+// only holding its summary.
+func (e *SubAgentExecutor) saveArtifact(result *SubAgentResult, task *SubAgentTask, tu *TokenUsage, output string) {
+	if task.RunID == "" {
+		return
+	}
+	artifact := data.Artifact{
+		TaskKey:     task.TaskKey,
+		StateKey:    result.StateKey,
+		Agent:       result.AgentName,
+		Caller:      task.CallerAgentName,
+		Instruction: task.Instruction,
+		Status:      result.Status.String(),
+		StartTime:   result.StartTime,
+		EndTime:     result.EndTime,
+		DurationMs:  result.Duration.Milliseconds(),
+	}
+	if result.Error != nil {
+		artifact.Error = result.Error.Error()
+	}
+	if tu != nil {
+		artifact.InputTokens = tu.InputTokens
+		artifact.OutputTokens = tu.OutputTokens
+		artifact.TotalTokens = tu.TotalTokens
+	}
+	path, err := data.SaveArtifact(task.RunID, e.mainSessionName, artifact, output)
+	if err != nil {
+		util.LogWarnf("Failed to save the output of task %s: %v\n", task.TaskKey, err)
+		return
+	}
+	result.Artifact = path
+}
+
+// queueBatch saves the tasks of a dispatch as a pending batch under the run ID.
+func (e *SubAgentExecutor) queueBatch(runID string, tasks []*SubAgentTask) {
+	wd, _ := os.Getwd()
+	batch := &data.TaskBatch{
+		ID:      runID,
+		Session: e.mainSessionName,
+		WorkDir: wd,
+		Created: time.Now(),
+	}
+	for i, task := range tasks {
+		task.RunID = runID
+		task.QueueIndex = i
+		batch.Tasks = append(batch.Tasks, data.QueuedTask{
+			CallerAgentName: task.CallerAgentName,
+			AgentName:       task.AgentName,
+			Instruction:     task.Instruction,
+			TaskKey:         task.TaskKey,
+			InputKeys:       task.InputKeys,
+			Priority:        task.Priority,
+			OutputSchema:    task.OutputSchema,
+			Status:          data.QueuedTaskPending,
+			UpdatedAt:       time.Now(),
+		})
+	}
+	e.queueMu.Lock()
+	defer e.queueMu.Unlock()
+	e.queue[runID] = batch
+	if err := data.SaveTaskBatch(batch); err != nil {
+		util.LogWarnf("Failed to queue the sub-agent tasks: %v\n", err)
+	}
+}
+
+// updateQueuedTask changes the queued entry of a task and saves its batch, or removes the
+// batch once all its tasks completed.
+func (e *SubAgentExecutor) updateQueuedTask(task *SubAgentTask, fn func(q *data.QueuedTask)) {
+	e.queueMu.Lock()
+	defer e.queueMu.Unlock()
+	batch, ok := e.queue[task.RunID]
+	if !ok || task.QueueIndex < 0 || task.QueueIndex >= len(batch.Tasks) {
+		return
+	}
+	fn(&batch.Tasks[task.QueueIndex])
+	batch.Tasks[task.QueueIndex].UpdatedAt = time.Now()
+
+	var err error
+	if batch.Done() {
+		delete(e.queue, task.RunID)
+		err = data.RemoveTaskBatch(batch.ID)
+	} else {
+		err = data.SaveTaskBatch(batch)
+	}
+	if err != nil {
+		util.LogWarnf("Failed to update the queued task %s: %v\n", task.TaskKey, err)
+	}
+}
+
+// finishQueuedTask records the outcome of a task in its batch, with the SharedState value
+// of a completed task so a resume can restore it without running the task again.
+func (e *SubAgentExecutor) finishQueuedTask(task *SubAgentTask, result *SubAgentResult) {
+	e.updateQueuedTask(task, func(q *data.QueuedTask) {
+		q.StateKey = result.StateKey
+		if result.Status != StatusCompleted {
+			q.Status = data.QueuedTaskFailed
+			if result.Error != nil {
+				q.Error = result.Error.Error()
+			}
+			return
+		}
+		q.Status = data.QueuedTaskCompleted
+		q.Error = ""
+		if e.state != nil {
+			q.Result = e.state.GetString(result.StateKey)
+		}
+	})
+}
+
+// ResumeBatch finishes a batch left by a previous run: the results of its completed tasks are
+// restored into the SharedState and the other tasks are run again, under the same run so
+// their outputs join the artifacts of the batch.
+func (e *SubAgentExecutor) ResumeBatch(ctx context.Context, batch *data.TaskBatch) ([]AgentResponse, error) {
+	var recovered []AgentResponse
+	var tasks []*SubAgentTask
+	for i, q := range batch.Tasks {
+		if q.Status == data.QueuedTaskCompleted {
+			if e.state != nil && q.StateKey != "" {
+				e.state.Set(q.StateKey, q.Result, q.AgentName)
+			}
+			recovered = append(recovered, AgentResponse{
+				TaskKey: q.TaskKey,
+				Result: &SubAgentResult{
+					AgentName: q.AgentName,
+					TaskKey:   q.TaskKey,
+					Status:    StatusCompleted,
+					StateKey:  q.StateKey,
+					Recovered: true,
+				},
+			})
+			continue
+		}
+		batch.Tasks[i].Status = data.QueuedTaskPending
+		batch.Tasks[i].Error = ""
+		tasks = append(tasks, &SubAgentTask{
+			CallerAgentName: q.CallerAgentName,
+			AgentName:       q.AgentName,
+			Instruction:     q.Instruction,
+			TaskKey:         q.TaskKey,
+			InputKeys:       q.InputKeys,
+			Priority:        q.Priority,
+			OutputSchema:    q.OutputSchema,
+			RunID:           batch.ID,
+			QueueIndex:      i,
+		})
+	}
+	if len(tasks) == 0 {
+		return recovered, data.RemoveTaskBatch(batch.ID)
+	}
+
+	e.queueMu.Lock()
+	e.queue[batch.ID] = batch
+	err := data.SaveTaskBatch(batch)
+	e.queueMu.Unlock()
+	if err != nil {
+		util.LogWarnf("Failed to queue the sub-agent tasks: %v\n", err)
+	}
+
+	responses, err := e.dispatch(ctx, tasks)
+	return append(recovered, responses...), err
+}
+
+// lastAssistantText returns the last answer of the model in a session.
+func lastAssistantText(sessionData []byte) string {
+	lines := bytes.Split(sessionData, []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		if text := assistantTurnText(lines[i]); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
 // setTaskStart sets the task to running status and prints the start message.
 func (e *SubAgentExecutor) setTaskStart(result *SubAgentResult, task *SubAgentTask, sessionName string) {
 	result.Status = StatusRunning
@@ -340,6 +615,9 @@ func (e *SubAgentExecutor) setTaskStart(result *SubAgentResult, task *SubAgentTa
 	if e.sseOutput != nil {
 		e.sseOutput.Writef("==> %s task: %s [%s -> %s] ...\n", mode, task.TaskKey, task.CallerAgentName, task.AgentName)
 	}
+	if e.observer != nil {
+		e.observer.OnSubAgent(*result)
+	}
 }
 
 // setTaskCompleted sets the task to completed status and prints the success message.
@@ -359,6 +637,9 @@ func (e *SubAgentExecutor) setTaskCompleted(result *SubAgentResult, taskKey stri
 	if e.sseOutput != nil {
 		e.sseOutput.Writef("✓ > Task completed: %s\n", taskKey)
 	}
+	if e.observer != nil {
+		e.observer.OnSubAgent(*result)
+	}
 }
 
 // setTaskError sets the task to failed status and prints the error message.
@@ -378,6 +659,34 @@ func (e *SubAgentExecutor) setTaskError(result *SubAgentResult, taskKey string,
 	if e.sseOutput != nil {
 		e.sseOutput.Writef("✗ > Task failed: %s - %v\n", taskKey, err)
 	}
+	if e.observer != nil {
+		e.observer.OnSubAgent(*result)
+	}
+}
+
+// setTaskCancelled sets the task to cancelled status and prints the cancel message.
+func (e *SubAgentExecutor) setTaskCancelled(result *SubAgentResult, taskKey string) {
+	result.Status = StatusCancelled
+	if result.StartTime.IsZero() {
+		result.StartTime = time.Now()
+	}
+	result.EndTime = time.Now()
+	result.Duration = result.EndTime.Sub(result.StartTime)
+	result.Error = fmt.Errorf("task cancelled")
+	result.Progress = fmt.Sprintf("Cancelled after %s", result.Duration.Round(time.Millisecond))
+
+	if e.stdOutput != nil {
+		e.stdOutput.Writef("%s✗ > Task cancelled: %s%s\n", data.StatusWarnColor, taskKey, data.ResetSeq)
+	}
+	if e.fileOutput != nil {
+		e.fileOutput.Writef("✗ > Task cancelled: %s\n", taskKey)
+	}
+	if e.sseOutput != nil {
+		e.sseOutput.Writef("✗ > Task cancelled: %s\n", taskKey)
+	}
+	if e.observer != nil {
+		e.observer.OnSubAgent(*result)
+	}
 }
 
 // FormatSummary returns a brief summary of task execution
@@ -389,6 +698,8 @@ func (e *SubAgentExecutor) FormatSummary(responses []AgentResponse) string {
 	completed := 0
 	failed := 0
 	var outputs []string
+	var artifacts []string
+	var recovered []string
 
 	for _, r := range responses {
 		if r.Err != nil || (r.Result != nil && r.Result.Status == StatusFailed) {
@@ -398,6 +709,12 @@ func (e *SubAgentExecutor) FormatSummary(responses []AgentResponse) string {
 			if r.Result != nil && r.Result.StateKey != "" {
 				outputs = append(outputs, r.Result.StateKey)
 			}
+			if r.Result != nil && r.Result.Artifact != "" {
+				artifacts = append(artifacts, r.Result.Artifact)
+			}
+			if r.Result != nil && r.Result.Recovered {
+				recovered = append(recovered, r.Result.StateKey)
+			}
 		}
 	}
 
@@ -408,6 +725,12 @@ func (e *SubAgentExecutor) FormatSummary(responses []AgentResponse) string {
 		summary += fmt.Sprintf("\nResults stored in SharedState keys: %v", outputs)
 		summary += "\nUse get_state tool to retrieve detailed results."
 	}
+	if len(artifacts) > 0 {
+		summary += fmt.Sprintf("\nFull outputs saved to: %v", artifacts)
+	}
+	if len(recovered) > 0 {
+		summary += fmt.Sprintf("\nRecovered from a previous run, not run again: %v", recovered)
+	}
 
 	return summary
 }
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/activebook/gllm/data"
+)
+
+// taskScheduler admits sub-agent tasks under the concurrency limits of the executor, so a
+// large spawn does not open a stream per task against one provider. Waiting tasks start by
+// priority, then the agent with the fewest running tasks first so a burst of one agent does
+// not starve the others, then in submission order.
+type taskScheduler struct {
+	mu       sync.Mutex
+	limits   data.SubagentSettings
+	running  int
+	perAgent map[string]int
+	waiting  []*schedulerTicket
+	seq      uint64
+}
+
+// schedulerTicket is a task waiting for a slot, its channel is closed when it may start.
+type schedulerTicket struct {
+	agent    string
+	priority int
+	seq      uint64
+	ready    chan struct{}
+}
+
+func newTaskScheduler(limits data.SubagentSettings) *taskScheduler {
+	return &taskScheduler{
+		limits:   limits,
+		perAgent: make(map[string]int),
+	}
+}
+
+// acquire blocks until a task of the agent may run, or the context is cancelled. The slot
+// is only held, and must be released, when it returns nil.
+func (s *taskScheduler) acquire(ctx context.Context, agent string, priority int) error {
+	s.mu.Lock()
+	s.seq++
+	ticket := &schedulerTicket{agent: agent, priority: priority, seq: s.seq, ready: make(chan struct{})}
+	s.waiting = append(s.waiting, ticket)
+	s.admit()
+	s.mu.Unlock()
+
+	select {
+	case <-ticket.ready:
+		return nil
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, t := range s.waiting {
+		if t == ticket {
+			s.waiting = append(s.waiting[:i], s.waiting[i+1:]...)
+			return ctx.Err()
+		}
+	}
+	// Admitted while giving up, the slot goes to the next task
+	s.running--
+	s.perAgent[agent]--
+	s.admit()
+	return ctx.Err()
+}
+
+// release frees the slot of a finished task of the agent.
+func (s *taskScheduler) release(agent string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running--
+	s.perAgent[agent]--
+	s.admit()
+}
+
+// agentLimit returns how many tasks of the agent may run at once, 0 for no limit.
+func (s *taskScheduler) agentLimit(agent string) int {
+	if n, ok := s.limits.AgentLimits[agent]; ok && n > 0 {
+		return n
+	}
+	return s.limits.MaxPerAgent
+}
+
+// admit starts the best waiting tasks while there are free slots. Callers hold the lock.
+func (s *taskScheduler) admit() {
+	for s.limits.MaxConcurrent <= 0 || s.running < s.limits.MaxConcurrent {
+		best := -1
+		for i, t := range s.waiting {
+			if limit := s.agentLimit(t.agent); limit > 0 && s.perAgent[t.agent] >= limit {
+				continue
+			}
+			if best < 0 || s.before(t, s.waiting[best]) {
+				best = i
+			}
+		}
+		if best < 0 {
+			return
+		}
+		ticket := s.waiting[best]
+		s.waiting = append(s.waiting[:best], s.waiting[best+1:]...)
+		s.running++
+		s.perAgent[ticket.agent]++
+		close(ticket.ready)
+	}
+}
+
+// before reports whether ticket a starts before ticket b.
+func (s *taskScheduler) before(a, b *schedulerTicket) bool {
+	if a.priority != b.priority {
+		return a.priority > b.priority
+	}
+	if ra, rb := s.perAgent[a.agent], s.perAgent[b.agent]; ra != rb {
+		return ra < rb
+	}
+	return a.seq < b.seq
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+// waitForWaiting blocks until n tasks wait for a slot.
+func waitForWaiting(t *testing.T, s *taskScheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		waiting := len(s.waiting)
+		s.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d waiting tasks", n)
+}
+
+// startOrder queues the tasks behind a running one of agent 'busy' and returns the order they start in.
+func startOrder(t *testing.T, s *taskScheduler, tasks []schedulerTicket) []string {
+	t.Helper()
+	s.acquire(context.Background(), "busy", 0)
+
+	var mu sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(agent string, priority int) {
+			defer wg.Done()
+			s.acquire(context.Background(), agent, priority)
+			mu.Lock()
+			order = append(order, agent)
+			mu.Unlock()
+			s.release(agent)
+		}(task.agent, task.priority)
+		// Submit one at a time so the submission order is known
+		waitForWaiting(t, s, i+1)
+	}
+	s.release("busy")
+	wg.Wait()
+	return order
+}
+
+func TestTaskSchedulerPriority(t *testing.T) {
+	s := newTaskScheduler(data.SubagentSettings{MaxConcurrent: 1})
+	order := startOrder(t, s, []schedulerTicket{
+		{agent: "low", priority: 0},
+		{agent: "high", priority: 5},
+		{agent: "mid", priority: 1},
+	})
+	if len(order) != 3 || order[0] != "high" || order[1] != "mid" || order[2] != "low" {
+		t.Errorf("expected tasks by priority, got %v", order)
+	}
+}
+
+func TestTaskSchedulerFairness(t *testing.T) {
+	s := newTaskScheduler(data.SubagentSettings{MaxConcurrent: 2})
+	s.acquire(context.Background(), "busy", 0)
+	s.acquire(context.Background(), "filler", 0)
+
+	// At the same priority, the agent with no running task goes before the earlier submission
+	order := make(chan string, 2)
+	go func() { s.acquire(context.Background(), "busy", 0); order <- "busy" }()
+	waitForWaiting(t, s, 1)
+	go func() { s.acquire(context.Background(), "idle", 0); order <- "idle" }()
+	waitForWaiting(t, s, 2)
+	s.release("filler")
+	if first := <-order; first != "idle" {
+		t.Errorf("expected the idle agent to start first, got %s", first)
+	}
+}
+
+func TestTaskSchedulerAgentLimit(t *testing.T) {
+	s := newTaskScheduler(data.SubagentSettings{MaxConcurrent: 4, AgentLimits: map[string]int{"research": 1}})
+	s.acquire(context.Background(), "research", 0)
+
+	started := make(chan string, 2)
+	go func() { s.acquire(context.Background(), "research", 0); started <- "research" }()
+	waitForWaiting(t, s, 1)
+	// Another agent is not held back by the limit of 'research'
+	go func() { s.acquire(context.Background(), "writer", 0); started <- "writer" }()
+	if got := <-started; got != "writer" {
+		t.Fatalf("expected the writer to start, got %s", got)
+	}
+	waitForWaiting(t, s, 1)
+
+	s.release("research")
+	if got := <-started; got != "research" {
+		t.Errorf("expected the second research task to start once the first finished, got %s", got)
+	}
+}
+
+func TestTaskSchedulerCancel(t *testing.T) {
+	s := newTaskScheduler(data.SubagentSettings{MaxConcurrent: 1})
+	s.acquire(context.Background(), "busy", 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.acquire(ctx, "waiting", 0) }()
+	waitForWaiting(t, s, 1)
+	cancel()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected the cancelled task not to get a slot")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("a cancelled task kept waiting for a slot")
+	}
+	waitForWaiting(t, s, 0)
+
+	// The slot freed by the running task goes to the next one, not the cancelled one
+	s.release("busy")
+	if err := s.acquire(context.Background(), "next", 0); err != nil {
+		t.Fatal(err)
+	}
+}
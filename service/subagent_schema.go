@@ -0,0 +1,89 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+)
+
+// subagentSchemaRetries is how many more turns a sub-agent gets to fix an output that does
+// not match the output schema of its task.
+const subagentSchemaRetries = 2
+
+// resolveOutputSchema parses the output schema of a task.
+func resolveOutputSchema(schemaJSON string) (*jsonschema.Resolved, error) {
+	var schema jsonschema.Schema
+	if err := json.Unmarshal([]byte(schemaJSON), &schema); err != nil {
+		return nil, fmt.Errorf("invalid output schema: %w", err)
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output schema: %w", err)
+	}
+	return resolved, nil
+}
+
+// outputSchemaInstruction asks for an output that is only a JSON value of the schema.
+func outputSchemaInstruction(instruction, schemaJSON string) string {
+	return fmt.Sprintf("%s\n\nYour final answer must be ONLY a JSON value matching this JSON schema, with no prose around it:\n```json\n%s\n```", instruction, schemaJSON)
+}
+
+// validateSchemaOutput extracts the JSON value of an output and validates it against the
+// schema, returning it indented.
+func validateSchemaOutput(resolved *jsonschema.Resolved, output string) (string, error) {
+	text := strings.TrimSpace(output)
+	// Models often fence the JSON even when told not to
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+	if text == "" {
+		return "", fmt.Errorf("the answer is empty")
+	}
+
+	var value any
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return "", fmt.Errorf("the answer is not valid JSON: %v", err)
+	}
+	if err := resolved.Validate(value); err != nil {
+		return "", err
+	}
+	indented, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(indented), nil
+}
+
+// isStructuredOutput reports whether a SharedState value is a JSON object or array, as
+// stored for a task with an output schema.
+func isStructuredOutput(value string) bool {
+	value = strings.TrimSpace(value)
+	return (strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[")) && json.Valid([]byte(value))
+}
+
+// conformOutput validates the last answer of a sub-agent against the output schema of its
+// task, giving it the validation errors to fix its answer until it matches or the retries
+// run out.
+func (e *SubAgentExecutor) conformOutput(op *AgentOptions, resolved *jsonschema.Resolved) (string, error) {
+	for attempt := 0; ; attempt++ {
+		sessionData, err := ReadSessionContent(op.SessionName)
+		if err != nil {
+			return "", err
+		}
+		output, verr := validateSchemaOutput(resolved, lastAssistantText(sessionData))
+		if verr == nil {
+			return output, nil
+		}
+		if attempt == subagentSchemaRetries {
+			return "", fmt.Errorf("output does not match the schema after %d retries: %v", subagentSchemaRetries, verr)
+		}
+		op.Prompt = fmt.Sprintf("Your answer does not match the required JSON schema: %v\n\nAnswer again with ONLY the corrected JSON value.", verr)
+		if err := e.runner(op); err != nil {
+			return "", err
+		}
+	}
+}
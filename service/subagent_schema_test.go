@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+	"github.com/spf13/viper"
+)
+
+const findingsSchema = `{"type":"object","properties":{"findings":{"type":"array","items":{"type":"string"}}},"required":["findings"]}`
+
+func TestValidateSchemaOutput(t *testing.T) {
+	resolved, err := resolveOutputSchema(findingsSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := validateSchemaOutput(resolved, "```json\n{\"findings\": [\"a\"]}\n```")
+	if err != nil {
+		t.Fatalf("a fenced answer should validate: %v", err)
+	}
+	if !isStructuredOutput(out) || !strings.Contains(out, `"findings"`) {
+		t.Errorf("unexpected output %q", out)
+	}
+	if _, err := validateSchemaOutput(resolved, "Here are the findings: a"); err == nil {
+		t.Error("prose should not validate")
+	}
+	if _, err := validateSchemaOutput(resolved, `{"issues": []}`); err == nil {
+		t.Error("an answer without the required property should not validate")
+	}
+	if _, err := resolveOutputSchema(`{"type": 3}`); err == nil {
+		t.Error("expected an invalid schema to be rejected")
+	}
+	if isStructuredOutput("[compression failed]") {
+		t.Error("text is not a structured output")
+	}
+}
+
+func TestSpawnWithOutputSchema(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("HOME", configDir)
+	t.Chdir(t.TempDir())
+	t.Cleanup(viper.Reset)
+
+	viper.Reset()
+	viper.Set("models.mock-analyst", map[string]interface{}{
+		"provider": ModelProviderMock,
+		"model":    "mock-analyst",
+	})
+	if err := data.EnsureAgentsDir(); err != nil {
+		t.Fatal(err)
+	}
+	agentFile := "---\nname: analyst\nmodel: mock-analyst\nmax_recursions: 3\n---\nYou are an analyst.\n"
+	if err := os.WriteFile(filepath.Join(data.GetAgentsDirPath(), "analyst.md"), []byte(agentFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first answer is prose, the retry fixes it
+	RegisterMockScript("mock-analyst", &MockScript{Turns: []MockTurn{
+		{Text: "I found one issue: the token is logged."},
+		{Text: `{"findings": ["the token is logged"]}`},
+	}})
+
+	state := data.NewSharedState()
+	executor := NewSubAgentExecutor(state, "schema_main", nil, nil, nil)
+	defer executor.Shutdown()
+	op := &OpenProcessor{agentName: "orchestrator", executor: executor, sharedState: state, toolsUse: &data.ToolsUse{AutoApprove: true}}
+
+	args := map[string]interface{}{
+		"tasks": []interface{}{map[string]interface{}{
+			"agent_name":    "analyst",
+			"instruction":   "Review the auth code.",
+			"task_key":      "auth",
+			"output_schema": map[string]interface{}{"type": "object", "required": []interface{}{"findings"}},
+		}},
+	}
+	out, err := spawnSubAgentsToolCallImpl(context.Background(), &args, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "1 completed") {
+		t.Errorf("expected the task to complete:\n%s", out)
+	}
+	val := state.GetString("analyst_auth")
+	if !isStructuredOutput(val) || !strings.Contains(val, "the token is logged") {
+		t.Errorf("expected the JSON output in shared state, got %q", val)
+	}
+
+	session, err := ReadSessionContent("schema_main::analyst_auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"matching this JSON schema", "does not match the required JSON schema"} {
+		if !strings.Contains(string(session), want) {
+			t.Errorf("the session misses %q", want)
+		}
+	}
+
+	// An invalid schema is rejected before anything runs
+	args["tasks"] = []interface{}{map[string]interface{}{
+		"agent_name": "analyst", "instruction": "x", "task_key": "bad",
+		"output_schema": map[string]interface{}{"type": 3},
+	}}
+	if _, err := spawnSubAgentsToolCallImpl(context.Background(), &args, op); err == nil {
+		t.Error("expected an invalid output_schema to be rejected")
+	}
+}
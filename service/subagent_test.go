@@ -1,8 +1,13 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -41,6 +46,7 @@ func defaultState() *data.SharedState {
 
 // Test Dispatch with multiple parallel tasks
 func TestDispatchMultipleTasks(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 	setupTestConfig()
 	state := defaultState()
 	executor := NewSubAgentExecutor(state, "test_session", nil, nil, nil)
@@ -62,7 +68,7 @@ func TestDispatchMultipleTasks(t *testing.T) {
 	}
 
 	start := time.Now()
-	responses, err := executor.Dispatch(tasks)
+	responses, err := executor.Dispatch(context.Background(), tasks)
 	elapsed := time.Since(start)
 
 	if err != nil {
@@ -82,6 +88,7 @@ func TestDispatchMultipleTasks(t *testing.T) {
 
 // Test that cross-agent deadlock is resolved by goroutines
 func TestCrossAgentCallDeadlock(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 	setupTestConfig()
 	state := defaultState()
 	executor := NewSubAgentExecutor(state, "test_session", nil, nil, nil)
@@ -102,7 +109,7 @@ func TestCrossAgentCallDeadlock(t *testing.T) {
 	go func() {
 		defer wg.Done()
 		tasks := []*SubAgentTask{{CallerAgentName: "A", AgentName: "test_agent", TaskKey: "A1", Instruction: "call B"}}
-		resp, err := executor.Dispatch(tasks)
+		resp, err := executor.Dispatch(context.Background(), tasks)
 		if err != nil || len(resp) == 0 {
 			t.Errorf("Agent A dispatch failed: %v", err)
 		}
@@ -111,7 +118,7 @@ func TestCrossAgentCallDeadlock(t *testing.T) {
 	go func() {
 		defer wg.Done()
 		tasks := []*SubAgentTask{{CallerAgentName: "B", AgentName: "test_agent", TaskKey: "B1", Instruction: "call A"}}
-		resp, err := executor.Dispatch(tasks)
+		resp, err := executor.Dispatch(context.Background(), tasks)
 		if err != nil || len(resp) == 0 {
 			t.Errorf("Agent B dispatch failed: %v", err)
 		}
@@ -163,3 +170,160 @@ func TestFormatSummary(t *testing.T) {
 		t.Fatal("Summary should contain task keys")
 	}
 }
+
+type recordingObserver struct {
+	results []SubAgentResult
+}
+
+func (o *recordingObserver) OnData(StreamData)     {}
+func (o *recordingObserver) OnNotify(StreamNotify) {}
+func (o *recordingObserver) OnSubAgent(result SubAgentResult) {
+	o.results = append(o.results, result)
+}
+
+func TestSubAgentStatusNotifiesObserver(t *testing.T) {
+	executor := NewSubAgentExecutor(defaultState(), "test_session", nil, nil, nil)
+	observer := &recordingObserver{}
+	executor.observer = observer
+
+	result := &SubAgentResult{AgentName: "test_agent", TaskKey: "task1"}
+	executor.setTaskStart(result, &SubAgentTask{CallerAgentName: "orchestrator", AgentName: "test_agent", TaskKey: "task1"}, "")
+	executor.setTaskError(result, "task1", fmt.Errorf("simulated error"))
+
+	if len(observer.results) != 2 {
+		t.Fatalf("expected the start and the end of the task, got %+v", observer.results)
+	}
+	if first := observer.results[0]; first.Status != StatusRunning || first.TaskKey != "task1" {
+		t.Errorf("first update = %+v", first)
+	}
+	if last := observer.results[1]; last.Status != StatusFailed || last.Error == nil || last.EndTime.IsZero() {
+		t.Errorf("last update = %+v", last)
+	}
+}
+
+func TestResumeBatch(t *testing.T) {
+	configDir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", configDir)
+	t.Setenv("HOME", configDir)
+	t.Chdir(t.TempDir())
+	t.Cleanup(viper.Reset)
+
+	viper.Reset()
+	viper.Set("models.mock-worker", map[string]interface{}{
+		"provider": ModelProviderMock,
+		"model":    "mock-worker",
+	})
+	if err := data.EnsureAgentsDir(); err != nil {
+		t.Fatal(err)
+	}
+	agentFile := "---\nname: test_agent\nmodel: mock-worker\n---\nYou are a worker.\n"
+	if err := os.WriteFile(filepath.Join(data.GetAgentsDirPath(), "test_agent.md"), []byte(agentFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state := defaultState()
+	executor := NewSubAgentExecutor(state, "test_session", nil, nil, nil)
+
+	var mu sync.Mutex
+	var ran []string
+	executor.runner = func(op *AgentOptions) error {
+		mu.Lock()
+		ran = append(ran, op.Prompt)
+		mu.Unlock()
+		return nil
+	}
+
+	batch := &data.TaskBatch{
+		ID:      "20260101-120000-abcd",
+		Session: "test_session",
+		Created: time.Now(),
+		Tasks: []data.QueuedTask{
+			{CallerAgentName: "orchestrator", AgentName: "test_agent", TaskKey: "done", Instruction: "Do 1",
+				Status: data.QueuedTaskCompleted, StateKey: "test_agent_done", Result: "summary of 1"},
+			{CallerAgentName: "orchestrator", AgentName: "test_agent", TaskKey: "lost", Instruction: "Do 2",
+				Status: data.QueuedTaskRunning},
+		},
+	}
+	if err := data.SaveTaskBatch(batch); err != nil {
+		t.Fatal(err)
+	}
+
+	responses, err := executor.ResumeBatch(context.Background(), batch)
+	if err != nil {
+		t.Fatalf("ResumeBatch failed: %v", err)
+	}
+	if len(responses) != 2 {
+		t.Fatalf("Expected 2 responses, got %d", len(responses))
+	}
+	if len(ran) != 1 || ran[0] != "Do 2" {
+		t.Errorf("Expected only the incomplete task to run, ran %v", ran)
+	}
+	if got := state.GetString("test_agent_done"); got != "summary of 1" {
+		t.Errorf("Expected the completed result to be restored, got %q", got)
+	}
+	if summary := executor.FormatSummary(responses); !strings.Contains(summary, "Recovered from a previous run, not run again: [test_agent_done]") {
+		t.Errorf("Summary does not report the recovered result: %s", summary)
+	}
+
+	// The task failed again without a session to read, so the batch stays queued
+	saved, err := data.LoadTaskBatch(batch.ID)
+	if err != nil {
+		t.Fatalf("Expected the batch to stay queued: %v", err)
+	}
+	if saved.Tasks[0].Status != data.QueuedTaskCompleted || saved.Tasks[1].Status != data.QueuedTaskFailed {
+		t.Errorf("Unexpected statuses %s, %s", saved.Tasks[0].Status, saved.Tasks[1].Status)
+	}
+
+	// A batch with nothing left to run is removed
+	saved.Tasks = saved.Tasks[:1]
+	if _, err := executor.ResumeBatch(context.Background(), saved); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := data.LoadTaskBatch(batch.ID); err == nil {
+		t.Error("Expected the completed batch to be removed")
+	}
+}
+
+func TestDispatchCancelled(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Cleanup(viper.Reset)
+	viper.Reset()
+	viper.Set("models.mock-cancel", map[string]interface{}{"provider": ModelProviderMock, "model": "mock-cancel"})
+	if err := data.EnsureAgentsDir(); err != nil {
+		t.Fatal(err)
+	}
+	agentFile := "---\nname: test_agent\nmodel: mock-cancel\n---\nYou are a worker.\n"
+	if err := os.WriteFile(filepath.Join(data.GetAgentsDirPath(), "test_agent.md"), []byte(agentFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	executor := NewSubAgentExecutor(defaultState(), "test_session", nil, nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var runs atomic.Int32
+	executor.runner = func(op *AgentOptions) error {
+		runs.Add(1)
+		cancel()
+		<-op.Ctx.Done()
+		return op.Ctx.Err()
+	}
+
+	responses, err := executor.Dispatch(ctx, []*SubAgentTask{
+		{CallerAgentName: "orchestrator", AgentName: "test_agent", TaskKey: "task1", Instruction: "Do 1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runs.Load() != 1 {
+		t.Fatalf("expected the task to run once, got %d: %+v", runs.Load(), responses)
+	}
+	if r := responses[0].Result; r.Status != StatusCancelled || r.Error == nil {
+		t.Errorf("expected the task to be cancelled, got %+v", r)
+	}
+
+	// A dispatch cancelled before it starts runs nothing
+	responses, _ = executor.Dispatch(ctx, []*SubAgentTask{
+		{CallerAgentName: "orchestrator", AgentName: "test_agent", TaskKey: "task2", Instruction: "Do 2"},
+	})
+	if runs.Load() != 1 || responses[0].Result.Status != StatusCancelled {
+		t.Errorf("expected the task not to run, got %d runs and %+v", runs.Load(), responses[0].Result)
+	}
+}
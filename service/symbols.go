@@ -0,0 +1,421 @@
+package service
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// CodeSymbol is a named declaration found in a source file.
+type CodeSymbol struct {
+	Name      string // Qualified with its type or class for methods, e.g. Server.Start
+	Kind      string
+	StartLine int // 1-based, including doc comments and decorators
+	EndLine   int // 1-based, inclusive
+}
+
+// Matches reports whether the symbol is what name refers to,
+// either the qualified name or the bare name of a method.
+func (s CodeSymbol) Matches(name string) bool {
+	if s.Name == name {
+		return true
+	}
+	_, bare, ok := strings.Cut(s.Name, ".")
+	return ok && !strings.Contains(name, ".") && bare == name
+}
+
+// ExtractSymbols finds the declarations of a Go, Python, JavaScript/TypeScript or Rust file.
+// Go is parsed exactly with go/parser. The other languages use a lexical scanner that
+// understands strings, comments, braces and indentation, which is enough to cut out a
+// declaration without shipping cgo parsers.
+func ExtractSymbols(path string, content []byte) ([]CodeSymbol, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".go":
+		return extractGoSymbols(path, content)
+	case ".py", ".pyi":
+		return extractPythonSymbols(string(content)), nil
+	case ".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts":
+		return extractBraceSymbols(string(content), jsDeclPatterns, jsMethodPattern, false), nil
+	case ".rs":
+		return extractBraceSymbols(string(content), rustDeclPatterns, rustMethodPattern, true), nil
+	}
+	return nil, fmt.Errorf("symbols can't be extracted from %s files, supported: Go, Python, JavaScript/TypeScript, Rust", filepath.Ext(path))
+}
+
+func extractGoSymbols(path string, content []byte) ([]CodeSymbol, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments|parser.SkipObjectResolution)
+	if file == nil {
+		return nil, err
+	}
+	// A file with syntax errors still yields the declarations parsed so far
+
+	line := func(pos token.Pos) int { return fset.Position(pos).Line }
+	start := func(doc *ast.CommentGroup, pos token.Pos) int {
+		if doc != nil {
+			return line(doc.Pos())
+		}
+		return line(pos)
+	}
+
+	var symbols []CodeSymbol
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			sym := CodeSymbol{Name: d.Name.Name, Kind: "function", StartLine: start(d.Doc, d.Pos()), EndLine: line(d.End())}
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				sym.Name = goReceiverName(d.Recv.List[0].Type) + "." + d.Name.Name
+				sym.Kind = "method"
+			}
+			symbols = append(symbols, sym)
+		case *ast.GenDecl:
+			grouped := d.Lparen.IsValid()
+			for _, spec := range d.Specs {
+				// Ungrouped declarations include the keyword and the doc comment of the decl
+				from, to, doc := spec.Pos(), spec.End(), (*ast.CommentGroup)(nil)
+				if !grouped {
+					from, to, doc = d.Pos(), d.End(), d.Doc
+				}
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if grouped {
+						doc = s.Doc
+					}
+					kind := "type"
+					switch s.Type.(type) {
+					case *ast.StructType:
+						kind = "struct"
+					case *ast.InterfaceType:
+						kind = "interface"
+					}
+					symbols = append(symbols, CodeSymbol{Name: s.Name.Name, Kind: kind, StartLine: start(doc, from), EndLine: line(to)})
+				case *ast.ValueSpec:
+					if grouped {
+						doc = s.Doc
+					}
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, name := range s.Names {
+						symbols = append(symbols, CodeSymbol{Name: name.Name, Kind: kind, StartLine: start(doc, from), EndLine: line(to)})
+					}
+				}
+			}
+		}
+	}
+	return symbols, nil
+}
+
+func goReceiverName(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return goReceiverName(t.X)
+	case *ast.IndexExpr:
+		return goReceiverName(t.X)
+	case *ast.IndexListExpr:
+		return goReceiverName(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return ""
+}
+
+var pythonDeclPattern = regexp.MustCompile(`^(\s*)(?:async\s+)?(def|class)\s+([A-Za-z_]\w*)`)
+
+func extractPythonSymbols(src string) []CodeSymbol {
+	lines := strings.Split(src, "\n")
+	type scope struct {
+		name   string
+		indent int
+	}
+	var classes []scope
+	var symbols []CodeSymbol
+
+	inString := pythonStringLines(lines)
+	for i, text := range lines {
+		if inString[i] {
+			continue
+		}
+		m := pythonDeclPattern.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		indent := indentWidth(m[1])
+		for len(classes) > 0 && classes[len(classes)-1].indent >= indent {
+			classes = classes[:len(classes)-1]
+		}
+
+		name, kind := m[3], "function"
+		if m[2] == "class" {
+			kind = "class"
+		} else if len(classes) > 0 {
+			kind = "method"
+		}
+		if len(classes) > 0 {
+			name = classes[len(classes)-1].name + "." + name
+		}
+
+		// The body ends before the next code line indented at or left of the declaration
+		end := i
+		for j := i + 1; j < len(lines); j++ {
+			trimmed := strings.TrimSpace(lines[j])
+			if trimmed == "" || (!inString[j] && strings.HasPrefix(trimmed, "#")) {
+				continue
+			}
+			if !inString[j] && indentWidth(lines[j]) <= indent {
+				break
+			}
+			end = j
+		}
+		// Decorators belong to the declaration
+		startLine := i
+		for startLine > 0 && strings.HasPrefix(strings.TrimSpace(lines[startLine-1]), "@") {
+			startLine--
+		}
+
+		symbols = append(symbols, CodeSymbol{Name: name, Kind: kind, StartLine: startLine + 1, EndLine: end + 1})
+		if kind == "class" {
+			classes = append(classes, scope{name: name, indent: indent})
+		}
+	}
+	return symbols
+}
+
+// pythonStringLines marks lines that start inside a triple-quoted string.
+func pythonStringLines(lines []string) []bool {
+	marks := make([]bool, len(lines))
+	quote := ""
+	for i, text := range lines {
+		marks[i] = quote != ""
+		for rest := text; ; {
+			if quote == "" {
+				idx := strings.IndexAny(rest, `"'#`)
+				if idx < 0 || rest[idx] == '#' {
+					break
+				}
+				if strings.HasPrefix(rest[idx:], `"""`) || strings.HasPrefix(rest[idx:], `'''`) {
+					quote = rest[idx : idx+3]
+					rest = rest[idx+3:]
+					continue
+				}
+				// Skip a single-line string
+				q := rest[idx]
+				end := strings.IndexByte(rest[idx+1:], q)
+				if end < 0 {
+					break
+				}
+				rest = rest[idx+1+end+1:]
+				continue
+			}
+			idx := strings.Index(rest, quote)
+			if idx < 0 {
+				break
+			}
+			rest = rest[idx+3:]
+			quote = ""
+		}
+	}
+	return marks
+}
+
+func indentWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += 8 - width%8
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// braceDeclPattern recognizes one kind of declaration, the name is the last capture group.
+type braceDeclPattern struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+var (
+	jsDeclPatterns = []braceDeclPattern{
+		{"class", regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+([A-Za-z_$][\w$]*)`)},
+		{"function", regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\s*\*?\s*([A-Za-z_$][\w$]*)`)},
+		{"function", regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+([A-Za-z_$][\w$]*)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=]+)?=>|[A-Za-z_$][\w$]*\s*=>)`)},
+		{"interface", regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?interface\s+([A-Za-z_$][\w$]*)`)},
+		{"enum", regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?(?:const\s+)?enum\s+([A-Za-z_$][\w$]*)`)},
+		{"type", regexp.MustCompile(`^\s*(?:export\s+)?(?:declare\s+)?type\s+([A-Za-z_$][\w$]*)`)},
+	}
+	jsMethodPattern = regexp.MustCompile(`^\s*(?:(?:public|private|protected|static|async|readonly|override|get|set)\s+)*\*?\s*([A-Za-z_$#][\w$]*)\s*(?:<[^>]*>)?\s*\(`)
+
+	rustDeclPatterns = []braceDeclPattern{
+		{"function", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?(?:extern\s+"[^"]*"\s+)?fn\s+([A-Za-z_]\w*)`)},
+		{"struct", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?struct\s+([A-Za-z_]\w*)`)},
+		{"enum", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?enum\s+([A-Za-z_]\w*)`)},
+		{"trait", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:unsafe\s+)?trait\s+([A-Za-z_]\w*)`)},
+		{"impl", regexp.MustCompile(`^\s*(?:unsafe\s+)?impl(?:\s*<[^{]*?>)?\s+(?:[\w:<>, ]+\s+for\s+)?([A-Za-z_]\w*)`)},
+		{"module", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?mod\s+([A-Za-z_]\w*)`)},
+		{"macro", regexp.MustCompile(`^\s*macro_rules!\s*([A-Za-z_]\w*)`)},
+		{"type", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?type\s+([A-Za-z_]\w*)`)},
+		{"const", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const|static)\s+(?:mut\s+)?([A-Za-z_]\w*)`)},
+	}
+	rustMethodPattern = regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:const\s+)?(?:async\s+)?(?:unsafe\s+)?fn\s+([A-Za-z_]\w*)`)
+
+	jsKeywords = map[string]bool{"if": true, "for": true, "while": true, "switch": true, "catch": true, "return": true, "function": true, "with": true}
+)
+
+// extractBraceSymbols finds top-level declarations of C-like languages and the methods of
+// their classes or impl blocks, using brace matching to find where each one ends.
+func extractBraceSymbols(src string, decls []braceDeclPattern, method *regexp.Regexp, rust bool) []CodeSymbol {
+	lines := strings.Split(src, "\n")
+	depths := braceDepths(src, rust)
+
+	var symbols []CodeSymbol
+	for i := 0; i < len(lines); i++ {
+		if depths[i].depth != 0 || depths[i].inComment {
+			continue
+		}
+		for _, decl := range decls {
+			m := decl.pattern.FindStringSubmatch(lines[i])
+			if m == nil {
+				continue
+			}
+			end := findBlockEnd(depths, i, len(lines))
+			start := leadingCommentStart(lines, i)
+			symbols = append(symbols, CodeSymbol{Name: m[len(m)-1], Kind: decl.kind, StartLine: start + 1, EndLine: end + 1})
+
+			// Methods are the declarations one level inside classes and impl blocks
+			if decl.kind == "class" || decl.kind == "impl" || decl.kind == "trait" {
+				owner := m[len(m)-1]
+				for j := i + 1; j < end; j++ {
+					if depths[j].depth != 1 || depths[j].inComment {
+						continue
+					}
+					mm := method.FindStringSubmatch(lines[j])
+					if mm == nil || jsKeywords[mm[1]] {
+						continue
+					}
+					mEnd := findBlockEnd(depths, j, end+1)
+					symbols = append(symbols, CodeSymbol{Name: owner + "." + mm[1], Kind: "method",
+						StartLine: leadingCommentStart(lines, j) + 1, EndLine: mEnd + 1})
+					j = mEnd
+				}
+			}
+			i = end
+			break
+		}
+	}
+	return symbols
+}
+
+// lineDepth is the brace nesting at the start of a line, and how the line's code ends.
+type lineDepth struct {
+	depth     int
+	inComment bool // The line starts inside a block comment or template string
+	opens     bool // A brace opens on this line
+	endsDecl  bool // A ';' at the starting depth ends a declaration without a body
+}
+
+// braceDepths scans the source once, skipping strings and comments.
+func braceDepths(src string, rust bool) []lineDepth {
+	var result []lineDepth
+	depth, line := 0, lineDepth{}
+	inBlock, inTemplate := false, false
+	inString := byte(0)
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		next := byte(0)
+		if i+1 < len(src) {
+			next = src[i+1]
+		}
+		switch {
+		case c == '\n':
+			result = append(result, line)
+			line = lineDepth{depth: depth, inComment: inBlock || inTemplate}
+			continue
+		case inBlock:
+			if c == '*' && next == '/' {
+				inBlock = false
+				i++
+			}
+		case inString != 0:
+			if c == '\\' {
+				i++
+			} else if c == inString {
+				inString = 0
+			}
+		case inTemplate:
+			if c == '\\' {
+				i++
+			} else if c == '`' {
+				inTemplate = false
+			}
+		case c == '/' && next == '/':
+			for i+1 < len(src) && src[i+1] != '\n' {
+				i++
+			}
+		case c == '/' && next == '*':
+			inBlock = true
+			i++
+		case c == '"':
+			inString = c
+		case c == '\'':
+			// In Rust a quote is also a lifetime, only 'x' and '\x' are characters
+			if !rust || (i+2 < len(src) && src[i+2] == '\'') || next == '\\' {
+				inString = c
+			}
+		case c == '`' && !rust:
+			inTemplate = true
+		case c == '{':
+			depth++
+			line.opens = true
+		case c == '}':
+			depth--
+		case c == ';' && depth == line.depth:
+			line.endsDecl = true
+		}
+	}
+	return append(result, line)
+}
+
+// findBlockEnd returns the last line of the declaration starting at line start.
+func findBlockEnd(depths []lineDepth, start, limit int) int {
+	base := depths[start].depth
+	opened := false
+	for j := start; j < limit; j++ {
+		if j > start && depths[j].depth <= base && opened {
+			return j - 1
+		}
+		if depths[j].opens {
+			opened = true
+		}
+		if !opened && depths[j].endsDecl {
+			return j // Declaration without a body, e.g. a type alias
+		}
+	}
+	if !opened {
+		return start
+	}
+	return limit - 1
+}
+
+// leadingCommentStart includes the comments, doc comments and attributes right above a declaration.
+func leadingCommentStart(lines []string, i int) int {
+	for i > 0 {
+		prev := strings.TrimSpace(lines[i-1])
+		if !(strings.HasPrefix(prev, "//") || strings.HasPrefix(prev, "*") || strings.HasPrefix(prev, "/*") ||
+			strings.HasPrefix(prev, "#[") || strings.HasPrefix(prev, "@")) {
+			break
+		}
+		i--
+	}
+	return i
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func symbolRange(t *testing.T, symbols []CodeSymbol, name string) (int, int) {
+	t.Helper()
+	for _, s := range symbols {
+		if s.Name == name {
+			return s.StartLine, s.EndLine
+		}
+	}
+	t.Fatalf("symbol %s not found in %+v", name, symbols)
+	return 0, 0
+}
+
+func TestExtractSymbols(t *testing.T) {
+	tests := []struct {
+		file string
+		src  string
+		want map[string][2]int
+	}{
+		{"a.go", `package a
+
+// Server serves.
+type Server struct {
+	addr string
+}
+
+// Start starts it.
+func (s *Server) Start() error {
+	return nil
+}
+
+const (
+	A = 1
+	B = 2
+)
+`, map[string][2]int{"Server": {3, 6}, "Server.Start": {8, 11}, "B": {15, 15}}},
+		{"a.py", `import os
+
+@dataclass
+class Config:
+    """Docs
+
+def not_a_function():
+    """
+    name: str
+
+    def load(self):
+        s = "}"
+
+        return s
+
+def main():
+    pass
+`, map[string][2]int{"Config": {3, 14}, "Config.load": {11, 14}, "main": {16, 17}}},
+		{"a.ts", `import x from "y";
+
+/** Greeter greets. */
+export class Greeter {
+  private name = "{";
+
+  greet(who: string): string {
+    return ` + "`hi ${who} }`" + `;
+  }
+}
+
+export const add = (a: number, b: number) => {
+  return a + b; // }
+};
+
+type ID = string;
+`, map[string][2]int{"Greeter": {3, 10}, "Greeter.greet": {7, 9}, "add": {12, 14}, "ID": {16, 16}}},
+		{"a.rs", `use std::fmt;
+
+/// A point.
+#[derive(Debug)]
+pub struct Point<'a> {
+    name: &'a str,
+}
+
+impl<'a> Point<'a> {
+    pub fn new(name: &'a str) -> Self {
+        let c = '{';
+        Point { name }
+    }
+}
+
+struct Unit;
+`, map[string][2]int{"Point": {3, 7}, "Point.new": {10, 13}, "Unit": {16, 16}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			symbols, err := ExtractSymbols(tt.file, []byte(tt.src))
+			if err != nil {
+				t.Fatalf("ExtractSymbols failed: %v", err)
+			}
+			for name, want := range tt.want {
+				start, end := symbolRange(t, symbols, name)
+				if start != want[0] || end != want[1] {
+					t.Errorf("%s: got lines %d-%d, want %d-%d", name, start, end, want[0], want[1])
+				}
+			}
+		})
+	}
+}
+
+func TestReadSymbolToolCallImpl(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	os.WriteFile(path, []byte("package main\n\nfunc helper() int {\n\treturn 1\n}\n\nfunc main() {}\n"), 0644)
+
+	args := map[string]interface{}{"path": path, "symbol": "helper"}
+	out, err := readSymbolToolCallImpl(context.Background(), &args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "function helper in") || !strings.Contains(out, "   4 | \treturn 1") || strings.Contains(out, "func main") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	args = map[string]interface{}{"path": path, "symbol": "missing"}
+	if out, _ = readSymbolToolCallImpl(context.Background(), &args); !strings.Contains(out, "Available symbols: helper, main") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
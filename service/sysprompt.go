@@ -0,0 +1,152 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+)
+
+/*
+The system prompt is composed from layers, in the order configured in settings.json (prompt.layers).
+Layers left out of the configured order are not injected at all.
+*/
+
+const (
+	PromptLayerBase         = "base"         // The agent's own system prompt
+	PromptLayerInstructions = "instructions" // Global and project GLLM.md / AGENTS.md
+	PromptLayerSkills       = "skills"       // Available skills preamble
+	PromptLayerMemory       = "memory"       // Saved memory digest
+	PromptLayerPlanMode     = "plan"         // Plan mode instructions, only while plan mode is on
+	PromptLayerTime         = "time"         // Date, time, timezone and locale
+	PromptLayerEnvironment  = "environment"  // OS, shell and working directory
+)
+
+// DefaultPromptLayers is the layer order used when none is configured.
+// Layers that change often come last so the stable prefix can be cached by providers.
+var DefaultPromptLayers = []string{
+	PromptLayerBase,
+	PromptLayerInstructions,
+	PromptLayerSkills,
+	PromptLayerMemory,
+	PromptLayerPlanMode,
+	PromptLayerTime,
+	PromptLayerEnvironment,
+}
+
+// PromptLayer is one assembled part of the system prompt.
+type PromptLayer struct {
+	Name    string
+	Content string
+	Tokens  int
+}
+
+// ValidatePromptLayers checks a layer order for unknown or repeated names.
+func ValidatePromptLayers(layers []string) error {
+	seen := make(map[string]bool)
+	for _, name := range layers {
+		known := false
+		for _, l := range DefaultPromptLayers {
+			if l == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown prompt layer '%s', expected one of: %s", name, strings.Join(DefaultPromptLayers, ", "))
+		}
+		if seen[name] {
+			return fmt.Errorf("prompt layer '%s' is listed twice", name)
+		}
+		seen[name] = true
+	}
+	if !seen[PromptLayerBase] {
+		return fmt.Errorf("the '%s' layer holds the agent's own prompt and cannot be left out", PromptLayerBase)
+	}
+	return nil
+}
+
+// GetPromptLayerOrder returns the configured layer order, falling back to the default on invalid settings.
+func GetPromptLayerOrder() []string {
+	layers := data.GetSettingsStore().GetPromptLayers()
+	if layers == nil {
+		return DefaultPromptLayers
+	}
+	if err := ValidatePromptLayers(layers); err != nil {
+		return DefaultPromptLayers
+	}
+	return layers
+}
+
+// promptLayerContent builds the content of a single layer, "" when it contributes nothing.
+func promptLayerContent(name, prompt string, capabilities []string) string {
+	switch name {
+	case PromptLayerBase:
+		return prompt
+	case PromptLayerInstructions:
+		return data.GetInstructionContent()
+	case PromptLayerSkills:
+		if IsAgentSkillsEnabled(capabilities) {
+			return GetSkillManager().GetAvailableSkills()
+		}
+	case PromptLayerMemory:
+		if IsAgentMemoryEnabled(capabilities) {
+			return data.NewMemoryStore().GetAll()
+		}
+	case PromptLayerPlanMode:
+		if IsPlanModeEnabled(capabilities) && data.GetPlanModeInSession() {
+			return fmt.Sprintf(data.PlanModeSystemPrompt, data.GetPlansDirPath())
+		}
+	case PromptLayerTime:
+		return timeBlock()
+	case PromptLayerEnvironment:
+		return environmentBlock()
+	}
+	return ""
+}
+
+// environmentBlock describes where the agent runs.
+func environmentBlock() string {
+	var sb strings.Builder
+	sb.WriteString("<environment>\n")
+	fmt.Fprintf(&sb, "OS: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	if shell := os.Getenv("SHELL"); shell != "" {
+		fmt.Fprintf(&sb, "Shell: %s\n", shell)
+	} else if runtime.GOOS == "windows" {
+		fmt.Fprintf(&sb, "Shell: %s\n", detectShell().Name)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		fmt.Fprintf(&sb, "Working directory: %s\n", wd)
+	}
+	sb.WriteString("</environment>")
+	return sb.String()
+}
+
+// ComposeSystemPrompt assembles the system prompt layers in the configured order.
+// Layers with no content are left out.
+func ComposeSystemPrompt(prompt string, capabilities []string) []PromptLayer {
+	return composePromptLayers(GetPromptLayerOrder(), prompt, capabilities)
+}
+
+func composePromptLayers(order []string, prompt string, capabilities []string) []PromptLayer {
+	var layers []PromptLayer
+	for _, name := range order {
+		content := strings.TrimSpace(promptLayerContent(name, prompt, capabilities))
+		if content == "" {
+			continue
+		}
+		layers = append(layers, PromptLayer{Name: name, Content: content, Tokens: EstimateTokens(content)})
+	}
+	return layers
+}
+
+// JoinPromptLayers renders the final system prompt from its layers.
+func JoinPromptLayers(layers []PromptLayer) string {
+	parts := make([]string, len(layers))
+	for i, l := range layers {
+		parts[i] = l.Content
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePromptLayers(t *testing.T) {
+	if err := ValidatePromptLayers(DefaultPromptLayers); err != nil {
+		t.Errorf("default order should be valid: %v", err)
+	}
+	for _, layers := range [][]string{
+		{"base", "unknown"},
+		{"base", "memory", "memory"},
+		{"instructions", "memory"},
+	} {
+		if err := ValidatePromptLayers(layers); err == nil {
+			t.Errorf("expected %v to be rejected", layers)
+		}
+	}
+}
+
+func TestComposePromptLayers(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	layers := composePromptLayers(DefaultPromptLayers, "You are a tester.", nil)
+	if len(layers) == 0 || layers[0].Name != PromptLayerBase || layers[0].Tokens == 0 {
+		t.Fatalf("unexpected layers: %+v", layers)
+	}
+	prompt := JoinPromptLayers(layers)
+	if !strings.HasPrefix(prompt, "You are a tester.\n\n") || !strings.Contains(prompt, "<environment>") {
+		t.Errorf("unexpected prompt:\n%s", prompt)
+	}
+
+	prompt = JoinPromptLayers(composePromptLayers([]string{PromptLayerEnvironment, PromptLayerBase}, "You are a tester.", nil))
+	if !strings.HasPrefix(prompt, "<environment>") || !strings.HasSuffix(prompt, "</environment>\n\nYou are a tester.") {
+		t.Errorf("layers were not reordered:\n%s", prompt)
+	}
+
+	if prompt = JoinPromptLayers(composePromptLayers([]string{PromptLayerBase}, "You are a tester.", nil)); prompt != "You are a tester." {
+		t.Errorf("left out layers should not be injected:\n%s", prompt)
+	}
+}
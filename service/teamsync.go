@@ -0,0 +1,133 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+// TeamSyncTimeout bounds a single clone or refresh of the team config repository.
+const TeamSyncTimeout = 2 * time.Minute
+
+// TeamSyncResult describes the team config after a sync.
+type TeamSyncResult struct {
+	Commit    string // Short hash of the synced commit
+	Cloned    bool   // The repository was cloned rather than refreshed
+	Conflicts []data.TeamConflict
+}
+
+// runGit runs git with the given arguments and returns its trimmed output.
+func runGit(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	// Never prompt for credentials, a sync must not hang waiting for input
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(out.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], msg)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// syncTeamRepo clones repo into dir, or brings an existing checkout to the latest commit of ref.
+// The checkout is a read-only mirror, local changes in it are discarded.
+func syncTeamRepo(ctx context.Context, repo, ref, dir string) (commit string, cloned bool, err error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", false, fmt.Errorf("git is required to sync the team config")
+	}
+
+	current, _ := runGit(ctx, dir, "config", "--get", "remote.origin.url")
+	if _, statErr := os.Stat(filepath.Join(dir, ".git")); statErr != nil || current != repo {
+		// Not a checkout of this repository, start over
+		if err := os.RemoveAll(dir); err != nil {
+			return "", false, fmt.Errorf("failed to clear %s: %w", dir, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(dir), 0750); err != nil {
+			return "", false, err
+		}
+		args := []string{"clone", "--depth", "1", "--quiet"}
+		if ref != "" {
+			args = append(args, "--branch", ref)
+		}
+		args = append(args, repo, dir)
+		if _, err := runGit(ctx, filepath.Dir(dir), args...); err != nil {
+			return "", false, err
+		}
+		cloned = true
+	} else {
+		fetchRef := ref
+		if fetchRef == "" {
+			fetchRef = "HEAD"
+		}
+		if _, err := runGit(ctx, dir, "fetch", "--depth", "1", "--quiet", "origin", fetchRef); err != nil {
+			return "", false, err
+		}
+		if _, err := runGit(ctx, dir, "reset", "--hard", "--quiet", "FETCH_HEAD"); err != nil {
+			return "", false, err
+		}
+		if _, err := runGit(ctx, dir, "clean", "-fdq"); err != nil {
+			return "", false, err
+		}
+	}
+
+	commit, err = runGit(ctx, dir, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", cloned, err
+	}
+	return commit, cloned, nil
+}
+
+// SyncTeamConfig pulls the configured team config repository and records the sync time.
+func SyncTeamConfig() (*TeamSyncResult, error) {
+	settings := data.GetSettingsStore()
+	team := settings.GetTeamSettings()
+	if team.Repo == "" {
+		return nil, fmt.Errorf("no team config repository is configured")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), TeamSyncTimeout)
+	defer cancel()
+	commit, cloned, err := syncTeamRepo(ctx, team.Repo, team.Ref, data.GetTeamConfigDirPath())
+	if err != nil {
+		return nil, err
+	}
+
+	team.SyncedAt = time.Now()
+	if err := settings.SetTeamSettings(team); err != nil {
+		return nil, fmt.Errorf("failed to record the sync: %w", err)
+	}
+	return &TeamSyncResult{Commit: commit, Cloned: cloned, Conflicts: data.FindTeamConflicts()}, nil
+}
+
+// TeamSyncDue reports whether the periodic refresh of the team config should run.
+func TeamSyncDue(team data.TeamSettings, now time.Time) bool {
+	if team.Repo == "" || team.Refresh == "" {
+		return false
+	}
+	every, err := time.ParseDuration(team.Refresh)
+	if err != nil || every <= 0 {
+		return false
+	}
+	return now.Sub(team.SyncedAt) >= every
+}
+
+// RemoveTeamConfig stops syncing and removes the local checkout of the team config.
+func RemoveTeamConfig() error {
+	if err := os.RemoveAll(data.GetTeamConfigDirPath()); err != nil {
+		return fmt.Errorf("failed to remove the team config: %w", err)
+	}
+	return data.GetSettingsStore().SetTeamSettings(data.TeamSettings{})
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestSyncTeamRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	ctx := context.Background()
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		if _, err := runGit(ctx, repo, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	commitFile := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(repo, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "-A")
+		git("-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "update "+name)
+	}
+	git("init", "-q", "-b", "main")
+	commitFile("agents/base.md", "---\nname: base\n---\n\nv1\n")
+
+	dir := filepath.Join(t.TempDir(), "team")
+	first, cloned, err := syncTeamRepo(ctx, repo, "main", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cloned {
+		t.Error("the first sync should clone")
+	}
+
+	commitFile("agents/base.md", "---\nname: base\n---\n\nv2\n")
+	// Local edits in the checkout are discarded
+	if err := os.WriteFile(filepath.Join(dir, "stray.md"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	second, cloned, err := syncTeamRepo(ctx, repo, "main", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cloned || second == first {
+		t.Errorf("expected a refresh to a new commit, got cloned=%v %s→%s", cloned, first, second)
+	}
+	content, _ := os.ReadFile(filepath.Join(dir, "agents", "base.md"))
+	if string(content) != "---\nname: base\n---\n\nv2\n" {
+		t.Errorf("checkout not updated: %q", content)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "stray.md")); !os.IsNotExist(err) {
+		t.Error("untracked files should be removed from the checkout")
+	}
+}
+
+func TestTeamSyncDue(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		team data.TeamSettings
+		want bool
+	}{
+		{data.TeamSettings{}, false},
+		{data.TeamSettings{Repo: "r"}, false},
+		{data.TeamSettings{Repo: "r", Refresh: "24h", SyncedAt: now.Add(-time.Hour)}, false},
+		{data.TeamSettings{Repo: "r", Refresh: "24h", SyncedAt: now.Add(-25 * time.Hour)}, true},
+		{data.TeamSettings{Repo: "r", Refresh: "bogus"}, false},
+	}
+	for _, c := range cases {
+		if got := TeamSyncDue(c.team, now); got != c.want {
+			t.Errorf("TeamSyncDue(%+v) = %v, want %v", c.team, got, c.want)
+		}
+	}
+}
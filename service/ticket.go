@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+)
+
+// Issue trackers of the ticket_* tools.
+const (
+	TicketProviderJira   = "jira"
+	TicketProviderLinear = "linear"
+)
+
+// defaultLinearURL is the GraphQL endpoint of Linear.
+const defaultLinearURL = "https://api.linear.app/graphql"
+
+// Ticket is an issue of a tracker.
+type Ticket struct {
+	Key         string // e.g. PROJ-123
+	Title       string
+	Status      string
+	Type        string
+	Priority    string
+	Assignee    string
+	Reporter    string
+	Labels      []string
+	Description string
+	URL         string
+	Comments    []TicketComment
+	Transitions []string // Statuses the ticket can move to
+}
+
+// TicketComment is a comment of a ticket.
+type TicketComment struct {
+	Author string
+	Body   string
+}
+
+// TicketQuery filters the searched tickets.
+type TicketQuery struct {
+	Text    string // Matched against the title and description, empty matches all
+	Project string // Jira project or Linear team key, empty searches all
+	All     bool   // Include done and cancelled tickets
+	Limit   int
+}
+
+// NewTicket is a ticket to create.
+type NewTicket struct {
+	Project     string // Jira project or Linear team key
+	Title       string
+	Description string
+	Type        string // Jira issue type, Task by default; Linear has none
+	Labels      []string
+}
+
+// TicketTracker is the API of an issue tracker.
+type TicketTracker interface {
+	Search(ctx context.Context, q TicketQuery) ([]Ticket, error)
+	Get(ctx context.Context, key string) (Ticket, error)
+	Create(ctx context.Context, t NewTicket) (Ticket, error)
+	// Transition moves the ticket to the status of the name and returns the new status.
+	Transition(ctx context.Context, key, status string) (string, error)
+}
+
+// TicketProviderOf returns the tracker to use: the requested one, the configured default,
+// or the only one with an endpoint.
+func TicketProviderOf(settings data.TicketSettings, requested string) (string, error) {
+	provider := strings.ToLower(strings.TrimSpace(requested))
+	if provider == "" {
+		provider = strings.ToLower(settings.Provider)
+	}
+	if provider == "" {
+		if settings.JiraURL != "" {
+			provider = TicketProviderJira
+		} else {
+			// Linear has a well-known endpoint, it needs no configuration besides the key
+			provider = TicketProviderLinear
+		}
+	}
+	if provider != TicketProviderJira && provider != TicketProviderLinear {
+		return "", fmt.Errorf("unsupported tracker '%s', supported: %s, %s", provider, TicketProviderJira, TicketProviderLinear)
+	}
+	return provider, nil
+}
+
+// ticketToken returns the token of the tracker, from the secret store or else the environment.
+func ticketToken(provider string) (string, error) {
+	secret, env := data.SecretJiraToken, "JIRA_API_TOKEN"
+	if provider == TicketProviderLinear {
+		secret, env = data.SecretLinearToken, "LINEAR_API_KEY"
+	}
+	token, err := data.GetSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	if token == "" {
+		token = os.Getenv(env)
+	}
+	if token == "" {
+		return "", fmt.Errorf("no %s token, set it with 'gllm config secret set %s' or $%s", provider, secret, env)
+	}
+	return token, nil
+}
+
+// NewTicketTracker returns the API client of the tracker, authenticated with its token.
+func NewTicketTracker(settings data.TicketSettings, provider string, transport http.RoundTripper) (TicketTracker, error) {
+	token, err := ticketToken(provider)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: transport, Timeout: scmRequestTimeout}
+	switch provider {
+	case TicketProviderJira:
+		if settings.JiraURL == "" {
+			return nil, fmt.Errorf("no Jira site, set it with 'gllm config set tickets.jira.url https://<site>.atlassian.net'")
+		}
+		return newJiraTracker(settings.JiraURL, settings.JiraEmail, token, client), nil
+	default:
+		endpoint := settings.LinearURL
+		if endpoint == "" {
+			endpoint = defaultLinearURL
+		}
+		return newLinearTracker(endpoint, token, client), nil
+	}
+}
+
+// FormatTicket renders a ticket with its description and comments for the model.
+func FormatTicket(t Ticket) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s: %s\n", t.Key, t.Title))
+	sb.WriteString(fmt.Sprintf("Status: %s\n", t.Status))
+	if t.Type != "" {
+		sb.WriteString(fmt.Sprintf("Type: %s\n", t.Type))
+	}
+	if t.Priority != "" {
+		sb.WriteString(fmt.Sprintf("Priority: %s\n", t.Priority))
+	}
+	if t.Assignee != "" {
+		sb.WriteString(fmt.Sprintf("Assignee: %s\n", t.Assignee))
+	}
+	if t.Reporter != "" {
+		sb.WriteString(fmt.Sprintf("Reporter: %s\n", t.Reporter))
+	}
+	if len(t.Labels) > 0 {
+		sb.WriteString(fmt.Sprintf("Labels: %s\n", strings.Join(t.Labels, ", ")))
+	}
+	if len(t.Transitions) > 0 {
+		sb.WriteString(fmt.Sprintf("Can move to: %s\n", strings.Join(t.Transitions, ", ")))
+	}
+	sb.WriteString(fmt.Sprintf("URL: %s\n", t.URL))
+	if desc := strings.TrimSpace(t.Description); desc != "" {
+		sb.WriteString("\n" + desc + "\n")
+	}
+	if len(t.Comments) > 0 {
+		sb.WriteString(fmt.Sprintf("\nComments (%d):\n", len(t.Comments)))
+		for _, c := range t.Comments {
+			sb.WriteString(fmt.Sprintf("- %s: %s\n", c.Author, strings.TrimSpace(c.Body)))
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// jiraTracker talks to the REST API v2 of Jira Cloud or Jira Server, which takes plain text
+// descriptions where v3 wants documents.
+type jiraTracker struct {
+	siteURL string
+	cloud   bool // Jira Cloud, authenticated with an account and an API token
+	api     *scmAPI
+}
+
+func newJiraTracker(siteURL, email, token string, client *http.Client) *jiraTracker {
+	siteURL = strings.TrimSuffix(siteURL, "/")
+	auth := "Bearer " + token
+	if email != "" {
+		auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(email+":"+token))
+	}
+	return &jiraTracker{siteURL: siteURL, cloud: email != "", api: &scmAPI{
+		baseURL: siteURL + "/rest/api/2",
+		client:  client,
+		headers: map[string]string{"Authorization": auth, "Accept": "application/json"},
+	}}
+}
+
+// jiraIssue is the part of a Jira issue the tools show.
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string   `json:"summary"`
+		Description string   `json:"description"`
+		Labels      []string `json:"labels"`
+		Status      struct {
+			Name string `json:"name"`
+		} `json:"status"`
+		IssueType struct {
+			Name string `json:"name"`
+		} `json:"issuetype"`
+		Priority *struct {
+			Name string `json:"name"`
+		} `json:"priority"`
+		Assignee *struct {
+			DisplayName string `json:"displayName"`
+		} `json:"assignee"`
+		Reporter *struct {
+			DisplayName string `json:"displayName"`
+		} `json:"reporter"`
+		Comment struct {
+			Comments []struct {
+				Body   string `json:"body"`
+				Author struct {
+					DisplayName string `json:"displayName"`
+				} `json:"author"`
+			} `json:"comments"`
+		} `json:"comment"`
+	} `json:"fields"`
+}
+
+func (j *jiraTracker) ticket(issue jiraIssue) Ticket {
+	f := issue.Fields
+	t := Ticket{
+		Key:         issue.Key,
+		Title:       f.Summary,
+		Status:      f.Status.Name,
+		Type:        f.IssueType.Name,
+		Labels:      f.Labels,
+		Description: f.Description,
+		URL:         j.siteURL + "/browse/" + issue.Key,
+	}
+	if f.Priority != nil {
+		t.Priority = f.Priority.Name
+	}
+	if f.Assignee != nil {
+		t.Assignee = f.Assignee.DisplayName
+	}
+	if f.Reporter != nil {
+		t.Reporter = f.Reporter.DisplayName
+	}
+	for _, c := range f.Comment.Comments {
+		t.Comments = append(t.Comments, TicketComment{Author: c.Author.DisplayName, Body: c.Body})
+	}
+	return t
+}
+
+// jqlQuote quotes a value for JQL.
+func jqlQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// ticketJQL builds the JQL of a search.
+func ticketJQL(q TicketQuery) string {
+	var clauses []string
+	if q.Project != "" {
+		clauses = append(clauses, "project = "+jqlQuote(q.Project))
+	}
+	if q.Text != "" {
+		clauses = append(clauses, "text ~ "+jqlQuote(q.Text))
+	}
+	if !q.All {
+		clauses = append(clauses, "statusCategory != Done")
+	}
+	return strings.Join(clauses, " AND ") + " ORDER BY updated DESC"
+}
+
+func (j *jiraTracker) Search(ctx context.Context, q TicketQuery) ([]Ticket, error) {
+	params := url.Values{}
+	params.Set("jql", ticketJQL(q))
+	params.Set("maxResults", fmt.Sprint(q.Limit))
+	params.Set("fields", "summary,status,issuetype,priority,assignee,labels")
+	// Jira Cloud retired the plain search endpoint
+	path := "/search"
+	if j.cloud {
+		path = "/search/jql"
+	}
+	var reply struct {
+		Issues []jiraIssue `json:"issues"`
+	}
+	if _, err := j.api.do(ctx, http.MethodGet, path+"?"+params.Encode(), nil, &reply, ""); err != nil {
+		return nil, err
+	}
+	tickets := make([]Ticket, 0, len(reply.Issues))
+	for _, issue := range reply.Issues {
+		tickets = append(tickets, j.ticket(issue))
+	}
+	return tickets, nil
+}
+
+func (j *jiraTracker) Get(ctx context.Context, key string) (Ticket, error) {
+	var issue jiraIssue
+	path := "/issue/" + url.PathEscape(key) + "?fields=summary,description,status,issuetype,priority,assignee,reporter,labels,comment"
+	if _, err := j.api.do(ctx, http.MethodGet, path, nil, &issue, ""); err != nil {
+		return Ticket{}, err
+	}
+	t := j.ticket(issue)
+	if transitions, err := j.transitions(ctx, key); err == nil {
+		for _, tr := range transitions {
+			t.Transitions = append(t.Transitions, tr.To.Name)
+		}
+	}
+	return t, nil
+}
+
+func (j *jiraTracker) Create(ctx context.Context, nt NewTicket) (Ticket, error) {
+	if nt.Project == "" {
+		return Ticket{}, fmt.Errorf("no project given and no default, set it with 'gllm config set tickets.jira.project KEY'")
+	}
+	if nt.Type == "" {
+		nt.Type = "Task"
+	}
+	fields := map[string]any{
+		"project":     map[string]string{"key": nt.Project},
+		"summary":     nt.Title,
+		"description": nt.Description,
+		"issuetype":   map[string]string{"name": nt.Type},
+	}
+	if len(nt.Labels) > 0 {
+		fields["labels"] = nt.Labels
+	}
+	var reply struct {
+		Key string `json:"key"`
+	}
+	if _, err := j.api.do(ctx, http.MethodPost, "/issue", map[string]any{"fields": fields}, &reply, ""); err != nil {
+		return Ticket{}, err
+	}
+	return Ticket{Key: reply.Key, Title: nt.Title, Type: nt.Type, URL: j.siteURL + "/browse/" + reply.Key}, nil
+}
+
+type jiraTransition struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	To   struct {
+		Name string `json:"name"`
+	} `json:"to"`
+}
+
+func (j *jiraTracker) transitions(ctx context.Context, key string) ([]jiraTransition, error) {
+	var reply struct {
+		Transitions []jiraTransition `json:"transitions"`
+	}
+	if _, err := j.api.do(ctx, http.MethodGet, "/issue/"+url.PathEscape(key)+"/transitions", nil, &reply, ""); err != nil {
+		return nil, err
+	}
+	return reply.Transitions, nil
+}
+
+func (j *jiraTracker) Transition(ctx context.Context, key, status string) (string, error) {
+	transitions, err := j.transitions(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	// Workflows name the transition ("Start progress") and its target status ("In Progress"), take either
+	var names []string
+	for _, tr := range transitions {
+		if strings.EqualFold(tr.To.Name, status) || strings.EqualFold(tr.Name, status) {
+			body := map[string]any{"transition": map[string]string{"id": tr.ID}}
+			if _, err := j.api.do(ctx, http.MethodPost, "/issue/"+url.PathEscape(key)+"/transitions", body, nil, ""); err != nil {
+				return "", err
+			}
+			return tr.To.Name, nil
+		}
+		names = append(names, tr.To.Name)
+	}
+	return "", fmt.Errorf("%s cannot move to '%s', available: %s", key, status, strings.Join(names, ", "))
+}
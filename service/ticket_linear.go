@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// linearTracker talks to the GraphQL API of Linear, where projects are teams.
+type linearTracker struct {
+	api *scmAPI
+}
+
+func newLinearTracker(endpoint, token string, client *http.Client) *linearTracker {
+	// Personal API keys go as they are, OAuth tokens carry their Bearer prefix
+	return &linearTracker{api: &scmAPI{
+		baseURL: endpoint,
+		client:  client,
+		headers: map[string]string{"Authorization": token},
+	}}
+}
+
+// query runs a GraphQL operation and decodes its data into out.
+func (l *linearTracker) query(ctx context.Context, query string, variables map[string]any, out any) error {
+	var reply struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if _, err := l.api.do(ctx, http.MethodPost, "", map[string]any{"query": query, "variables": variables}, &reply, ""); err != nil {
+		return err
+	}
+	if len(reply.Errors) > 0 {
+		return fmt.Errorf("linear: %s", reply.Errors[0].Message)
+	}
+	return json.Unmarshal(reply.Data, out)
+}
+
+const linearIssueFields = `identifier title description url priorityLabel
+state { name }
+assignee { name }
+creator { name }
+labels { nodes { name } }`
+
+// linearIssue is the part of a Linear issue the tools show.
+type linearIssue struct {
+	ID            string `json:"id"`
+	Identifier    string `json:"identifier"`
+	Title         string `json:"title"`
+	Description   string `json:"description"`
+	URL           string `json:"url"`
+	PriorityLabel string `json:"priorityLabel"`
+	State         struct {
+		Name string `json:"name"`
+	} `json:"state"`
+	Assignee *struct {
+		Name string `json:"name"`
+	} `json:"assignee"`
+	Creator *struct {
+		Name string `json:"name"`
+	} `json:"creator"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Comments struct {
+		Nodes []struct {
+			Body string `json:"body"`
+			User *struct {
+				Name string `json:"name"`
+			} `json:"user"`
+		} `json:"nodes"`
+	} `json:"comments"`
+	Team struct {
+		States linearStates `json:"states"`
+	} `json:"team"`
+}
+
+type linearStates struct {
+	Nodes []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"nodes"`
+}
+
+func (i linearIssue) ticket() Ticket {
+	t := Ticket{
+		Key:         i.Identifier,
+		Title:       i.Title,
+		Status:      i.State.Name,
+		Priority:    i.PriorityLabel,
+		Description: i.Description,
+		URL:         i.URL,
+	}
+	if i.Assignee != nil {
+		t.Assignee = i.Assignee.Name
+	}
+	if i.Creator != nil {
+		t.Reporter = i.Creator.Name
+	}
+	for _, label := range i.Labels.Nodes {
+		t.Labels = append(t.Labels, label.Name)
+	}
+	for _, c := range i.Comments.Nodes {
+		author := "unknown"
+		if c.User != nil {
+			author = c.User.Name
+		}
+		t.Comments = append(t.Comments, TicketComment{Author: author, Body: c.Body})
+	}
+	for _, s := range i.Team.States.Nodes {
+		if s.Name != t.Status {
+			t.Transitions = append(t.Transitions, s.Name)
+		}
+	}
+	return t
+}
+
+func (l *linearTracker) Search(ctx context.Context, q TicketQuery) ([]Ticket, error) {
+	filter := map[string]any{}
+	if q.Text != "" {
+		filter["or"] = []map[string]any{
+			{"title": map[string]any{"containsIgnoreCase": q.Text}},
+			{"description": map[string]any{"containsIgnoreCase": q.Text}},
+		}
+	}
+	if q.Project != "" {
+		filter["team"] = map[string]any{"key": map[string]any{"eq": q.Project}}
+	}
+	if !q.All {
+		filter["state"] = map[string]any{"type": map[string]any{"nin": []string{"completed", "canceled"}}}
+	}
+	var out struct {
+		Issues struct {
+			Nodes []linearIssue `json:"nodes"`
+		} `json:"issues"`
+	}
+	query := `query($filter: IssueFilter, $first: Int) {
+  issues(filter: $filter, first: $first, orderBy: updatedAt) { nodes { ` + linearIssueFields + ` } }
+}`
+	if err := l.query(ctx, query, map[string]any{"filter": filter, "first": q.Limit}, &out); err != nil {
+		return nil, err
+	}
+	tickets := make([]Ticket, 0, len(out.Issues.Nodes))
+	for _, issue := range out.Issues.Nodes {
+		tickets = append(tickets, issue.ticket())
+	}
+	return tickets, nil
+}
+
+func (l *linearTracker) issue(ctx context.Context, key string) (linearIssue, error) {
+	var out struct {
+		Issue *linearIssue `json:"issue"`
+	}
+	query := `query($id: String!) {
+  issue(id: $id) { id ` + linearIssueFields + `
+    comments { nodes { body user { name } } }
+    team { states { nodes { id name } } }
+  }
+}`
+	if err := l.query(ctx, query, map[string]any{"id": key}, &out); err != nil {
+		return linearIssue{}, err
+	}
+	if out.Issue == nil {
+		return linearIssue{}, fmt.Errorf("issue %s not found", key)
+	}
+	return *out.Issue, nil
+}
+
+func (l *linearTracker) Get(ctx context.Context, key string) (Ticket, error) {
+	issue, err := l.issue(ctx, key)
+	if err != nil {
+		return Ticket{}, err
+	}
+	return issue.ticket(), nil
+}
+
+func (l *linearTracker) Create(ctx context.Context, nt NewTicket) (Ticket, error) {
+	if nt.Project == "" {
+		return Ticket{}, fmt.Errorf("no team given and no default, set it with 'gllm config set tickets.linear.team KEY'")
+	}
+	var teams struct {
+		Teams struct {
+			Nodes []struct {
+				ID string `json:"id"`
+			} `json:"nodes"`
+		} `json:"teams"`
+	}
+	err := l.query(ctx, `query($key: String!) { teams(filter: { key: { eq: $key } }) { nodes { id } } }`, map[string]any{"key": nt.Project}, &teams)
+	if err != nil {
+		return Ticket{}, err
+	}
+	if len(teams.Teams.Nodes) == 0 {
+		return Ticket{}, fmt.Errorf("team %s not found", nt.Project)
+	}
+
+	input := map[string]any{"teamId": teams.Teams.Nodes[0].ID, "title": nt.Title, "description": nt.Description}
+	var out struct {
+		IssueCreate struct {
+			Success bool        `json:"success"`
+			Issue   linearIssue `json:"issue"`
+		} `json:"issueCreate"`
+	}
+	mutation := `mutation($input: IssueCreateInput!) {
+  issueCreate(input: $input) { success issue { ` + linearIssueFields + ` } }
+}`
+	if err := l.query(ctx, mutation, map[string]any{"input": input}, &out); err != nil {
+		return Ticket{}, err
+	}
+	if !out.IssueCreate.Success {
+		return Ticket{}, fmt.Errorf("linear did not create the issue")
+	}
+	return out.IssueCreate.Issue.ticket(), nil
+}
+
+func (l *linearTracker) Transition(ctx context.Context, key, status string) (string, error) {
+	issue, err := l.issue(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	var names []string
+	for _, s := range issue.Team.States.Nodes {
+		if !strings.EqualFold(s.Name, status) {
+			names = append(names, s.Name)
+			continue
+		}
+		var out struct {
+			IssueUpdate struct {
+				Success bool `json:"success"`
+			} `json:"issueUpdate"`
+		}
+		mutation := `mutation($id: String!, $stateId: String!) { issueUpdate(id: $id, input: { stateId: $stateId }) { success } }`
+		if err := l.query(ctx, mutation, map[string]any{"id": issue.ID, "stateId": s.ID}, &out); err != nil {
+			return "", err
+		}
+		if !out.IssueUpdate.Success {
+			return "", fmt.Errorf("linear did not update %s", key)
+		}
+		return s.Name, nil
+	}
+	return "", fmt.Errorf("%s cannot move to '%s', available: %s", key, status, strings.Join(names, ", "))
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestTicketProviderOf(t *testing.T) {
+	tests := []struct {
+		settings  data.TicketSettings
+		requested string
+		want      string
+	}{
+		{data.TicketSettings{}, "", TicketProviderLinear},
+		{data.TicketSettings{JiraURL: "https://acme.atlassian.net"}, "", TicketProviderJira},
+		{data.TicketSettings{JiraURL: "https://acme.atlassian.net", Provider: "linear"}, "", TicketProviderLinear},
+		{data.TicketSettings{Provider: "linear"}, "Jira", TicketProviderJira},
+	}
+	for _, tt := range tests {
+		got, err := TicketProviderOf(tt.settings, tt.requested)
+		if err != nil || got != tt.want {
+			t.Errorf("TicketProviderOf(%+v, %q) = %q, %v, want %q", tt.settings, tt.requested, got, err, tt.want)
+		}
+	}
+	if _, err := TicketProviderOf(data.TicketSettings{}, "trello"); err == nil {
+		t.Error("expected an unsupported tracker error")
+	}
+}
+
+func TestTicketJQL(t *testing.T) {
+	got := ticketJQL(TicketQuery{Project: "ENG", Text: `say "hi"`})
+	want := `project = "ENG" AND text ~ "say \"hi\"" AND statusCategory != Done ORDER BY updated DESC`
+	if got != want {
+		t.Errorf("ticketJQL = %s, want %s", got, want)
+	}
+	if got := ticketJQL(TicketQuery{All: true}); got != " ORDER BY updated DESC" {
+		t.Errorf("ticketJQL of all = %q", got)
+	}
+}
+
+func TestJiraTrackerTransition(t *testing.T) {
+	var moved string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Basic ") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/rest/api/2/issue/ENG-1/transitions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodPost {
+			var body struct {
+				Transition struct {
+					ID string `json:"id"`
+				} `json:"transition"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			moved = body.Transition.ID
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Write([]byte(`{"transitions":[{"id":"11","name":"Start progress","to":{"name":"In Progress"}},{"id":"31","name":"Close","to":{"name":"Done"}}]}`))
+	}))
+	defer server.Close()
+
+	j := newJiraTracker(server.URL+"/", "me@acme.com", "tok", server.Client())
+	status, err := j.Transition(context.Background(), "ENG-1", "in progress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "In Progress" || moved != "11" {
+		t.Errorf("status = %q, transition = %q", status, moved)
+	}
+
+	_, err = j.Transition(context.Background(), "ENG-1", "Blocked")
+	if err == nil || !strings.Contains(err.Error(), "In Progress, Done") {
+		t.Errorf("expected the available statuses, got %v", err)
+	}
+}
+
+func TestLinearTrackerSearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "lin_key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Variables struct {
+				Filter map[string]any `json:"filter"`
+				First  int            `json:"first"`
+			} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Variables.First != 5 || req.Variables.Filter["team"] == nil || req.Variables.Filter["state"] == nil {
+			t.Errorf("variables = %+v", req.Variables)
+		}
+		w.Write([]byte(`{"data":{"issues":{"nodes":[{"identifier":"ENG-7","title":"Flaky login","url":"https://linear.app/acme/issue/ENG-7","state":{"name":"Todo"},"assignee":{"name":"Ann"},"labels":{"nodes":[{"name":"bug"}]}}]}}}`))
+	}))
+	defer server.Close()
+
+	l := newLinearTracker(server.URL, "lin_key", server.Client())
+	tickets, err := l.Search(context.Background(), TicketQuery{Text: "login", Project: "ENG", Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickets) != 1 || tickets[0].Key != "ENG-7" || tickets[0].Assignee != "Ann" || tickets[0].Labels[0] != "bug" {
+		t.Errorf("tickets = %+v", tickets)
+	}
+}
+
+func TestLinearTrackerGraphQLError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errors":[{"message":"Entity not found"}]}`))
+	}))
+	defer server.Close()
+
+	l := newLinearTracker(server.URL, "lin_key", server.Client())
+	if _, err := l.Get(context.Background(), "ENG-404"); err == nil || !strings.Contains(err.Error(), "Entity not found") {
+		t.Errorf("expected the GraphQL error, got %v", err)
+	}
+}
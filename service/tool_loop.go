@@ -0,0 +1,123 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+const (
+	// toolLoopRepeats is the number of identical calls with identical results that make a loop.
+	toolLoopRepeats = 3
+	// toolLoopWindow is the number of recent calls the loop detection looks at.
+	toolLoopWindow = 12
+)
+
+// toolLoopNote is added to the result of the call that closes a loop, the model is warned once.
+const toolLoopNote = "\n\n[System note: %s. Repeating it won't give a different result. " +
+	"Change your approach, or stop and tell the user what is blocking you. If the loop goes on, the run is stopped.]"
+
+// toolCallRecord is one tool call seen by the loop detection.
+type toolCallRecord struct {
+	call        string // Tool and arguments, e.g. read_file {"path":"a.go"}
+	fingerprint string // Call and a hash of its result
+}
+
+// toolLoopDetector spots a model going round in circles, which MaxRecursions only stops
+// after the whole budget is spent: the same call with the same result again and again,
+// or two calls undoing each other, like an edit and its reverse.
+type toolLoopDetector struct {
+	recent []toolCallRecord
+	total  int
+	warned bool
+	loop   string // The loop that went on after the warning, the run is stopped
+}
+
+// toolCallText renders a call for the loop detection, equal arguments give equal text
+// because the keys are sorted.
+func toolCallText(name string, args map[string]interface{}) string {
+	argsJSON, _ := json.Marshal(args)
+	return fmt.Sprintf("%s %s", name, argsJSON)
+}
+
+// record adds a call and its result, and describes the loop it closes, if any.
+func (d *toolLoopDetector) record(call, result string) string {
+	h := fnv.New64a()
+	h.Write([]byte(result))
+	rec := toolCallRecord{call: call, fingerprint: fmt.Sprintf("%s\x00%x", call, h.Sum64())}
+
+	d.total++
+	d.recent = append(d.recent, rec)
+	if len(d.recent) > toolLoopWindow {
+		d.recent = d.recent[len(d.recent)-toolLoopWindow:]
+	}
+
+	repeats := 0
+	for _, r := range d.recent {
+		if r.fingerprint == rec.fingerprint {
+			repeats++
+		}
+	}
+	if repeats >= toolLoopRepeats {
+		return fmt.Sprintf("you called %s %d times and got the same result each time", shortToolCall(rec.call), repeats)
+	}
+
+	// A B A B: the last two calls undo each other
+	if n := len(d.recent); n >= 4 {
+		a, b := d.recent[n-2], d.recent[n-1]
+		if a.fingerprint != b.fingerprint && d.recent[n-4].fingerprint == a.fingerprint && d.recent[n-3].fingerprint == b.fingerprint {
+			return fmt.Sprintf("you alternated between %s and %s twice, each one undoing the other", shortToolCall(a.call), shortToolCall(b.call))
+		}
+	}
+	return ""
+}
+
+// shortToolCall keeps a call readable in a note.
+func shortToolCall(call string) string {
+	const limit = 120
+	if len(call) <= limit {
+		return call
+	}
+	return call[:limit] + "...}"
+}
+
+// toolLoopFilter returns the result filter that records the call for the loop detection.
+// args are the arguments sent by the model, before placeholders are restored. The first
+// loop gets a note in the result asking the model to change its approach, a loop that goes
+// on after it stops the run once the calls of the turn have their results.
+func (op *OpenProcessor) toolLoopFilter(name string, args map[string]interface{}) func(string) string {
+	call := toolCallText(name, args)
+	return func(text string) string {
+		loop := op.loops.record(call, text)
+		if loop == "" {
+			return text
+		}
+		if op.loops.warned {
+			op.loops.loop = loop
+			return text
+		}
+		op.loops.warned = true
+		op.status.ChangeTo(op.notify, StreamNotify{Status: StatusWarn,
+			Data: fmt.Sprintf("Tool call loop detected, asked the model to change its approach: %s", strings.TrimPrefix(loop, "you "))}, nil)
+		return text + fmt.Sprintf(toolLoopNote, loop)
+	}
+}
+
+// toolLoopError returns an error once a loop went on after the model was warned.
+func (op *OpenProcessor) toolLoopError() error {
+	if op.loops.loop == "" {
+		return nil
+	}
+	return fmt.Errorf("stopped a tool call loop after %d tool calls: the model %s, even after it was asked to change its approach",
+		op.loops.total, strings.TrimPrefix(op.loops.loop, "you "))
+}
+
+// haltError returns why the run must stop after the calls of a turn: too many invalid
+// calls in a row or a tool call loop.
+func (op *OpenProcessor) haltError() error {
+	if err := op.invalidToolCallsError(); err != nil {
+		return err
+	}
+	return op.toolLoopError()
+}
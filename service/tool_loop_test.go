@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestToolLoopDetector(t *testing.T) {
+	var d toolLoopDetector
+	read := toolCallText(ToolReadFile, map[string]interface{}{"path": "a.go", "max_lines": 10})
+	if read != toolCallText(ToolReadFile, map[string]interface{}{"max_lines": 10, "path": "a.go"}) {
+		t.Fatal("the order of the arguments must not matter")
+	}
+	if d.record(read, "v1") != "" || d.record(read, "v2") != "" || d.record(read, "v2") != "" {
+		t.Fatal("a call with a changing result is not a loop")
+	}
+	if loop := d.record(read, "v2"); !strings.Contains(loop, "3 times and got the same result") {
+		t.Errorf("expected a repeat loop, got %q", loop)
+	}
+
+	d = toolLoopDetector{}
+	edit := toolCallText(ToolEditFile, map[string]interface{}{"path": "a.go", "old": "x", "new": "y"})
+	undo := toolCallText(ToolEditFile, map[string]interface{}{"path": "a.go", "old": "y", "new": "x"})
+	for i, call := range []string{edit, undo, edit} {
+		if loop := d.record(call, "ok"); loop != "" {
+			t.Fatalf("call %d is not a loop yet, got %q", i, loop)
+		}
+	}
+	if loop := d.record(undo, "ok"); !strings.Contains(loop, "alternated between") {
+		t.Errorf("expected an edit/undo loop, got %q", loop)
+	}
+}
+
+func TestMockProviderRepeatedToolCalls(t *testing.T) {
+	read := MockTurn{ToolCalls: []MockToolCall{{Name: ToolTodoRead, Args: map[string]interface{}{}}}}
+	script := &MockScript{Turns: []MockTurn{read, read, read, read, {Text: "never"}}}
+	_, err := runMockAgent(t, context.Background(), "mock-loop", script, []string{ToolTodoRead})
+	if err == nil || !strings.Contains(err.Error(), "stopped a tool call loop after 4 tool calls") {
+		t.Fatalf("expected the loop to be stopped, got %v", err)
+	}
+	requests := script.Requests()
+	if len(requests) != 4 || !strings.Contains(string(requests[3]), "System note: you called todo_read {} 3 times") {
+		t.Errorf("the model was not warned before the run was stopped")
+	}
+}
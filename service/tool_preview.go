@@ -0,0 +1,250 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// toolPreviewStep is how much the arguments of a streamed tool call grow before they are
+// parsed again for the preview. Long arguments are parsed less often, see previewToolCall.
+const toolPreviewStep = 256
+
+// toolCallPreview follows the arguments of the tool call the model is streaming.
+type toolCallPreview struct {
+	id     string // Call being streamed
+	parsed int    // Length of its arguments when last parsed
+	shown  string // Preview on the status line
+}
+
+// previewToolCall shows on the status line what a tool call will do while the model is still
+// streaming its arguments, so that a long write_file doesn't leave the terminal idle until
+// the confirmation. Providers call it with all the arguments received so far.
+func (op *OpenProcessor) previewToolCall(id, name, args string) {
+	if op.quiet || op.notify == nil || name == "" {
+		return
+	}
+	p := &op.preview
+	if p.id == id && len(args)-p.parsed < max(toolPreviewStep, p.parsed/8) {
+		return
+	}
+	if p.id != id {
+		p.shown = ""
+	}
+	p.id, p.parsed = id, len(args)
+	parsed, _ := parsePartialJSON(args)
+	text := describeToolPreview(name, parsed)
+	if text == p.shown {
+		return
+	}
+	p.shown = text
+	op.status.ChangeTo(op.notify, StreamNotify{Status: StatusToolPreview, Data: text}, op.proceed)
+}
+
+// describeToolPreview sums up the arguments streamed so far of a tool call, e.g.
+// "write_file main.go · 120 lines" or "shell: go test ./...".
+func describeToolPreview(name string, args map[string]interface{}) string {
+	str := func(key string) string {
+		s, _ := args[key].(string)
+		return s
+	}
+	switch name {
+	case ToolShell:
+		if command := firstLine(strings.TrimSpace(str("command"))); command != "" {
+			return "shell: " + command
+		}
+	case ToolWriteFile:
+		if path := str("path"); path != "" {
+			return fmt.Sprintf("%s %s · %s", name, filepath.Base(path), previewLines(str("content")))
+		}
+	case ToolEditFile:
+		if path := str("path"); path != "" {
+			edits, _ := args["edits"].([]interface{})
+			return fmt.Sprintf("%s %s · %s", name, filepath.Base(path), describeEdits(edits))
+		}
+	case ToolApplyChanges:
+		changes, _ := args["changes"].([]interface{})
+		if len(changes) > 0 {
+			last, _ := changes[len(changes)-1].(map[string]interface{})
+			path, _ := last["path"].(string)
+			files := "1 file"
+			if len(changes) > 1 {
+				files = fmt.Sprintf("%d files", len(changes))
+			}
+			if path != "" {
+				return fmt.Sprintf("%s %s · %s", name, files, filepath.Base(path))
+			}
+			return name + " " + files
+		}
+	}
+	for _, key := range []string{"path", "url", "query", "command"} {
+		if s := firstLine(strings.TrimSpace(str(key))); s != "" {
+			return name + " " + s
+		}
+	}
+	return name
+}
+
+// describeEdits counts the lines removed and added by search-replace edits.
+func describeEdits(edits []interface{}) string {
+	var removed, added int
+	for _, e := range edits {
+		edit, _ := e.(map[string]interface{})
+		search, _ := edit["search"].(string)
+		replace, _ := edit["replace"].(string)
+		removed += strings.Count(search, "\n") + 1
+		if replace != "" {
+			added += strings.Count(replace, "\n") + 1
+		}
+	}
+	noun := "edits"
+	if len(edits) == 1 {
+		noun = "edit"
+	}
+	return fmt.Sprintf("%d %s, +%d -%d", len(edits), noun, added, removed)
+}
+
+func previewLines(content string) string {
+	n := strings.Count(content, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		n++
+	}
+	if n == 1 {
+		return "1 line"
+	}
+	return fmt.Sprintf("%d lines", n)
+}
+
+// parsePartialJSON parses the beginning of a JSON object as the model streams it, closing
+// the open strings, arrays and objects. A number or literal cut in the middle becomes null.
+// ok is false when the text doesn't start an object.
+func parsePartialJSON(s string) (map[string]interface{}, bool) {
+	const (
+		expectKey = iota
+		expectColon
+		expectValue
+		expectComma
+	)
+	type frame struct {
+		object bool
+		state  int
+	}
+	var stack []frame
+	done := func() {
+		if len(stack) > 0 {
+			stack[len(stack)-1].state = expectComma
+		}
+	}
+	inString, escaped, isKey := false, false, false
+	scalarStart, unicodeStart := -1, -1
+	endScalar := func() {
+		if scalarStart >= 0 {
+			scalarStart = -1
+			done()
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inString {
+			if unicodeStart >= 0 && i >= unicodeStart+6 {
+				unicodeStart = -1
+			}
+			switch {
+			case escaped:
+				escaped = false
+				if c == 'u' {
+					unicodeStart = i - 1
+				}
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+				if isKey {
+					stack[len(stack)-1].state = expectColon
+				} else {
+					done()
+				}
+			}
+			continue
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			endScalar()
+		case '{':
+			stack = append(stack, frame{object: true, state: expectKey})
+		case '[':
+			stack = append(stack, frame{state: expectValue})
+		case '}', ']':
+			endScalar()
+			if len(stack) == 0 {
+				return nil, false
+			}
+			stack = stack[:len(stack)-1]
+			done()
+		case ':':
+			if len(stack) > 0 {
+				stack[len(stack)-1].state = expectValue
+			}
+		case ',':
+			endScalar()
+			if len(stack) > 0 {
+				top := &stack[len(stack)-1]
+				top.state = expectValue
+				if top.object {
+					top.state = expectKey
+				}
+			}
+		case '"':
+			inString = true
+			isKey = len(stack) > 0 && stack[len(stack)-1].object && stack[len(stack)-1].state == expectKey
+		default:
+			if scalarStart < 0 {
+				scalarStart = i
+			}
+		}
+	}
+
+	out := s
+	switch {
+	case inString:
+		if unicodeStart >= 0 && len(s) < unicodeStart+6 {
+			out = s[:unicodeStart]
+		} else if escaped {
+			out = s[:len(s)-1]
+		}
+		out += `"`
+		if isKey {
+			out += ":null"
+		}
+	case scalarStart >= 0:
+		if !json.Valid([]byte(s[scalarStart:])) {
+			out = s[:scalarStart] + "null"
+		}
+	case len(stack) > 0:
+		top := stack[len(stack)-1]
+		switch {
+		case top.object && top.state == expectColon:
+			out += ":null"
+		case top.object && top.state == expectValue:
+			out += "null"
+		case top.state == expectKey || top.state == expectValue:
+			out = strings.TrimSuffix(strings.TrimRight(out, " \t\r\n"), ",")
+		}
+	}
+	var closing strings.Builder
+	for i := len(stack) - 1; i >= 0; i-- {
+		if stack[i].object {
+			closing.WriteByte('}')
+		} else {
+			closing.WriteByte(']')
+		}
+	}
+
+	var v map[string]interface{}
+	if err := json.Unmarshal([]byte(out+closing.String()), &v); err != nil {
+		return nil, false
+	}
+	return v, true
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePartialJSON(t *testing.T) {
+	tests := []struct {
+		in   string
+		want map[string]interface{}
+	}{
+		{`{`, map[string]interface{}{}},
+		{`{"pa`, map[string]interface{}{"pa": nil}},
+		{`{"path"`, map[string]interface{}{"path": nil}},
+		{`{"path": `, map[string]interface{}{"path": nil}},
+		{`{"path": "src/ma`, map[string]interface{}{"path": "src/ma"}},
+		{`{"path": "a.go", `, map[string]interface{}{"path": "a.go"}},
+		{`{"content": "line\`, map[string]interface{}{"content": "line"}},
+		{`{"content": "caf\u00`, map[string]interface{}{"content": "caf"}},
+		{`{"content": "café`, map[string]interface{}{"content": "café"}},
+		{`{"n": 12`, map[string]interface{}{"n": float64(12)}},
+		{`{"n": tr`, map[string]interface{}{"n": nil}},
+		{`{"ok": true, "edits": [{"search": "a", "replace": "b"}, {"sea`,
+			map[string]interface{}{"ok": true, "edits": []interface{}{
+				map[string]interface{}{"search": "a", "replace": "b"},
+				map[string]interface{}{"sea": nil},
+			}}},
+		{`{"edits": [{"search": "a"},`, map[string]interface{}{"edits": []interface{}{map[string]interface{}{"search": "a"}}}},
+		{`{"quote": "say \"hi\" {not [json"`, map[string]interface{}{"quote": `say "hi" {not [json`}},
+	}
+	for _, tt := range tests {
+		got, ok := parsePartialJSON(tt.in)
+		if !ok {
+			t.Errorf("parsePartialJSON(%q) failed", tt.in)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePartialJSON(%q) = %#v, want %#v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", `[1, 2`, `"text`, `}`} {
+		if _, ok := parsePartialJSON(in); ok {
+			t.Errorf("parsePartialJSON(%q) should fail", in)
+		}
+	}
+}
+
+func TestDescribeToolPreview(t *testing.T) {
+	parse := func(s string) map[string]interface{} {
+		v, _ := parsePartialJSON(s)
+		return v
+	}
+	tests := []struct {
+		name, args, want string
+	}{
+		{ToolShell, `{"command": "go test ./...\ngo vet`, "shell: go test ./... …"},
+		{ToolWriteFile, `{"path": "src/main.go", "content": "package main\n\nfunc`, "write_file main.go · 3 lines"},
+		{ToolEditFile, `{"path": "a.go", "edits": [{"search": "x\ny", "replace": "z"}, {"search": "w`, "edit_file a.go · 2 edits, +1 -3"},
+		{ToolApplyChanges, `{"changes": [{"path": "a.go"}, {"path": "docs/b.md", "con`, "apply_changes 2 files · b.md"},
+		{ToolWebFetch, `{"url": "https://go.dev`, "web_fetch https://go.dev"},
+		{ToolWriteFile, `{"pur`, "write_file"},
+	}
+	for _, tt := range tests {
+		if got := describeToolPreview(tt.name, parse(tt.args)); got != tt.want {
+			t.Errorf("describeToolPreview(%s, %q) = %q, want %q", tt.name, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestPreviewToolCall(t *testing.T) {
+	notify := make(chan StreamNotify, 64)
+	proceed := make(chan bool, 64)
+	for i := 0; i < cap(proceed); i++ {
+		proceed <- true
+	}
+	op := &OpenProcessor{notify: notify, proceed: proceed, status: &StatusStack{}}
+
+	args := `{"path": "main.go", "content": "`
+	op.previewToolCall("call_1", ToolWriteFile, args)
+	// A few more bytes are not parsed again
+	op.previewToolCall("call_1", ToolWriteFile, args+`package main\n`)
+	content := strings.Repeat(`x := 1\n`, 100)
+	op.previewToolCall("call_1", ToolWriteFile, args+content)
+	// A new call shows at once
+	op.previewToolCall("call_2", ToolShell, `{"command": "ls`)
+	close(notify)
+
+	var got []string
+	for n := range notify {
+		if n.Status != StatusToolPreview {
+			t.Errorf("unexpected status %v", n.Status)
+		}
+		got = append(got, n.Data)
+	}
+	want := []string{"write_file main.go · 0 lines", "write_file main.go · 100 lines", "shell: ls"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("previews = %q, want %q", got, want)
+	}
+	if !op.status.IsEmpty() {
+		t.Errorf("previews should not change the status stack, got %v", op.status.statuses)
+	}
+
+	quiet := &OpenProcessor{quiet: true, notify: make(chan StreamNotify), status: &StatusStack{}}
+	quiet.previewToolCall("call_1", ToolShell, `{"command": "ls"}`)
+}
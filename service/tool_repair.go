@@ -0,0 +1,411 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// maxInvalidToolCalls is the number of invalid tool calls in a row after which the run stops,
+// a model that can't fix its calls would otherwise loop until the recursion limit.
+const maxInvalidToolCalls = 3
+
+// checkToolCall parses and validates a tool call of the model against the tool's schema.
+// args is the raw JSON of the arguments or the decoded object. Values that are plainly
+// meant as the declared type are repaired, e.g. "10" for an integer. For an invalid call
+// it returns what is wrong, worded for the model to fix the call, and a warning is shown.
+func (op *OpenProcessor) checkToolCall(name string, args interface{}) (map[string]interface{}, string) {
+	argsMap, problem := op.validateToolCall(name, args)
+	if problem == "" {
+		op.invalidCalls = 0
+		// The paths of the agent are not the model's mistake either
+		if reason := op.pathRules.CheckToolCall(name, argsMap); reason != "" {
+			op.status.ChangeTo(op.notify, StreamNotify{Status: StatusWarn,
+				Data: fmt.Sprintf("Blocked the call to %s: %s", name, reason)}, nil)
+			return nil, fmt.Sprintf("Error: the call to %s was blocked: %s", name, reason)
+		}
+		// The hook scripts may block a valid call, that is not the model's mistake
+		if reason := GetScriptHooks().ToolCall(name, argsMap, op.agentName); reason != "" {
+			op.status.ChangeTo(op.notify, StreamNotify{Status: StatusWarn,
+				Data: fmt.Sprintf("Blocked the call to %s: %s", name, reason)}, nil)
+			return nil, fmt.Sprintf("Error: the call to %s was blocked: %s", name, reason)
+		}
+		return argsMap, ""
+	}
+	return nil, op.invalidToolCall(fmt.Sprintf("the call to %s", name), problem)
+}
+
+// invalidToolCall counts an invalid tool call, shows a warning and returns the message
+// that tells the model what to fix.
+func (op *OpenProcessor) invalidToolCall(call, problem string) string {
+	op.invalidCalls++
+	op.lastInvalid = problem
+	op.status.ChangeTo(op.notify, StreamNotify{Status: StatusWarn,
+		Data: fmt.Sprintf("Invalid tool call, asked the model to fix it: %s was not run, %s", call, problem)}, nil)
+	return fmt.Sprintf("Error: %s was not run, %s. Fix the call and try again.", call, problem)
+}
+
+// invalidToolCallsError returns an error once the model made maxInvalidToolCalls invalid
+// tool calls in a row. It is checked after every call of a turn got its result, so the
+// session stays valid.
+func (op *OpenProcessor) invalidToolCallsError() error {
+	if op.invalidCalls < maxInvalidToolCalls {
+		return nil
+	}
+	return fmt.Errorf("stopped after %d invalid tool calls in a row, the last one: %s", op.invalidCalls, op.lastInvalid)
+}
+
+func (op *OpenProcessor) validateToolCall(name string, args interface{}) (map[string]interface{}, string) {
+	var argsMap map[string]interface{}
+	switch v := args.(type) {
+	case map[string]interface{}:
+		argsMap = v
+	case nil:
+		argsMap = map[string]interface{}{}
+	case string:
+		parsed, err := parseToolArgs(v)
+		if err != nil {
+			return nil, err.Error()
+		}
+		argsMap = parsed
+	default:
+		return nil, fmt.Sprintf("the arguments must be a JSON object, got %T", args)
+	}
+
+	if name == "" {
+		return nil, "the tool name is empty"
+	}
+	if IsAvailableMCPTool(name, op.mcpClient) {
+		// The schemas of MCP tools are checked by their servers
+		return argsMap, ""
+	}
+	tool := findOpenTool(name)
+	if tool == nil {
+		problem := fmt.Sprintf("there is no tool named '%s'", name)
+		if suggestion := closestToolName(name, op.knownToolNames()); suggestion != "" {
+			problem += fmt.Sprintf(", did you mean '%s'?", suggestion)
+		}
+		return nil, problem
+	}
+	if problems := validateToolValue("", tool.Function.Parameters, argsMap); len(problems) > 0 {
+		return nil, strings.Join(problems, "; ")
+	}
+	return argsMap, ""
+}
+
+// parseToolArgs decodes the JSON arguments of a tool call. Empty arguments are an empty
+// object, a markdown fence around the object and data after it are dropped.
+func parseToolArgs(raw string) (map[string]interface{}, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "```") {
+		raw = strings.TrimPrefix(strings.TrimPrefix(raw, "```json"), "```")
+		raw = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(raw), "```"))
+	}
+	if raw == "" {
+		return map[string]interface{}{}, nil
+	}
+	var argsMap map[string]interface{}
+	dec := json.NewDecoder(strings.NewReader(raw))
+	if err := dec.Decode(&argsMap); err != nil {
+		offset := dec.InputOffset()
+		if syntaxErr, ok := err.(*json.SyntaxError); ok {
+			offset = syntaxErr.Offset
+		}
+		return nil, fmt.Errorf("the arguments are not a valid JSON object (%v near %q)", err, jsonExcerpt(raw, int(offset)))
+	}
+	if argsMap == nil {
+		return nil, fmt.Errorf("the arguments must be a JSON object, got null")
+	}
+	return argsMap, nil
+}
+
+// jsonExcerpt returns the text around offset, to show the model where its JSON broke.
+func jsonExcerpt(raw string, offset int) string {
+	start := max(0, min(offset, len(raw))-20)
+	end := min(len(raw), offset+20)
+	return raw[start:end]
+}
+
+// validateToolValue checks value against a JSON schema of the tool definitions and repairs
+// the values that are plainly meant as the declared type. Only the keywords the
+// definitions use are checked: type, enum, required, properties and items.
+func validateToolValue(path string, schema map[string]interface{}, value interface{}) []string {
+	if schema == nil {
+		return nil
+	}
+	label := "the arguments"
+	if path != "" {
+		label = fmt.Sprintf("argument '%s'", path)
+	}
+
+	typ, _ := schema["type"].(string)
+	if typ != "" && !matchesSchemaType(typ, value) {
+		return []string{fmt.Sprintf("%s must be %s, got %s", label, withArticle(typ), jsonTypeName(value))}
+	}
+	if enum := schemaStrings(schema["enum"]); len(enum) > 0 {
+		if s, ok := value.(string); ok && !containsString(enum, s) {
+			return []string{fmt.Sprintf("%s must be one of %s, got '%s'", label, strings.Join(enum, ", "), s)}
+		}
+	}
+
+	var problems []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+		for _, name := range schemaStrings(schema["required"]) {
+			if arg, ok := v[name]; !ok || arg == nil {
+				problem := fmt.Sprintf("missing required argument '%s'", joinToolPath(path, name))
+				if prop, ok := props[name].(map[string]interface{}); ok {
+					if t, ok := prop["type"].(string); ok {
+						problem += fmt.Sprintf(" (%s)", t)
+					}
+				}
+				problems = append(problems, problem)
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := props[name].(map[string]interface{})
+			if !ok || v[name] == nil {
+				continue
+			}
+			v[name] = repairToolValue(prop, v[name])
+			problems = append(problems, validateToolValue(joinToolPath(path, name), prop, v[name])...)
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i := range v {
+				v[i] = repairToolValue(items, v[i])
+				problems = append(problems, validateToolValue(fmt.Sprintf("%s[%d]", path, i), items, v[i])...)
+			}
+		}
+	}
+	return problems
+}
+
+// repairToolValue converts a value to the declared type when it plainly means it: numbers
+// and booleans sent as strings or the other way around, a single value where an array is
+// expected, and objects or arrays sent as JSON text.
+func repairToolValue(schema map[string]interface{}, value interface{}) interface{} {
+	typ, _ := schema["type"].(string)
+	if typ == "" || matchesSchemaType(typ, value) {
+		return value
+	}
+	s, isString := value.(string)
+	switch typ {
+	case "string":
+		switch v := value.(type) {
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		case bool:
+			return strconv.FormatBool(v)
+		}
+	case "integer", "number":
+		if isString {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return f
+			}
+		}
+	case "boolean":
+		if isString {
+			if b, err := strconv.ParseBool(strings.TrimSpace(s)); err == nil {
+				return b
+			}
+		}
+	case "array":
+		if isString && strings.HasPrefix(strings.TrimSpace(s), "[") {
+			var arr []interface{}
+			if json.Unmarshal([]byte(s), &arr) == nil {
+				return arr
+			}
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			if t, ok := items["type"].(string); ok && matchesSchemaType(t, value) {
+				return []interface{}{value}
+			}
+		}
+	case "object":
+		if isString && strings.HasPrefix(strings.TrimSpace(s), "{") {
+			var obj map[string]interface{}
+			if json.Unmarshal([]byte(s), &obj) == nil {
+				return obj
+			}
+		}
+	}
+	return value
+}
+
+func matchesSchemaType(typ string, value interface{}) bool {
+	switch typ {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return true
+}
+
+func jsonTypeName(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("the string %q", v)
+	case float64:
+		return fmt.Sprintf("the number %v", v)
+	case bool:
+		return fmt.Sprintf("%v", v)
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func withArticle(typ string) string {
+	if typ == "integer" || typ == "array" || typ == "object" {
+		return "an " + typ
+	}
+	return "a " + typ
+}
+
+func joinToolPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// schemaStrings reads a list of strings of a schema, which the definitions write as
+// []string or []interface{}.
+func schemaStrings(v interface{}) []string {
+	switch list := v.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		result := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// findOpenTool returns the definition of an embedded or imported API tool, nil if there is none.
+func findOpenTool(name string) *OpenTool {
+	if !IsAvailableOpenTool(name) {
+		return nil
+	}
+	for _, tool := range getOpenTools() {
+		if tool.Function.Name == name {
+			return tool
+		}
+	}
+	if _, operation := FindAPIOperation(name); operation != nil {
+		return APIOperationTool(operation)
+	}
+	if c, tool := findPluginTool(name); c != nil {
+		for _, t := range c.info.Tools {
+			if t.Name == tool {
+				return pluginOpenTool(c.plugin.Name, t)
+			}
+		}
+	}
+	return nil
+}
+
+// knownToolNames returns the names of the embedded tools and of the MCP tools.
+func (op *OpenProcessor) knownToolNames() []string {
+	names := GetAllOpenTools()
+	if op.mcpClient != nil {
+		for _, tool := range getMCPTools(op.mcpClient) {
+			names = append(names, tool.Function.Name)
+		}
+	}
+	return names
+}
+
+// closestToolName returns the known name closest to a misspelled one, empty when none is close.
+func closestToolName(name string, known []string) string {
+	best, bestDist := "", len(name)/2+1
+	for _, candidate := range known {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(candidate)); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance of a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// geminiMalformedCall returns why Gemini stopped on a function call it could not form.
+func geminiMalformedCall(resp *genai.GenerateContentResponse) (string, bool) {
+	if resp == nil {
+		return "", false
+	}
+	for _, candidate := range resp.Candidates {
+		if candidate != nil && candidate.FinishReason == genai.FinishReasonMalformedFunctionCall {
+			reason := strings.TrimSpace(candidate.FinishMessage)
+			if reason == "" {
+				reason = "it was malformed"
+			}
+			return reason, true
+		}
+	}
+	return "", false
+}
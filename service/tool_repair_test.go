@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseToolArgs(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		path string
+		ok   bool
+	}{
+		{"", "", true},
+		{`{"path": "a.go"}`, "a.go", true},
+		{"```json\n{\"path\": \"a.go\"}\n```", "a.go", true},
+		{`{"path": "a.go"}{}`, "a.go", true},
+		{`{"path": "a.go"`, "", false},
+		{`null`, "", false},
+		{`["a.go"]`, "", false},
+	} {
+		args, err := parseToolArgs(tc.in)
+		if (err == nil) != tc.ok {
+			t.Errorf("parseToolArgs(%q) error = %v", tc.in, err)
+			continue
+		}
+		if tc.ok && tc.path != "" && args["path"] != tc.path {
+			t.Errorf("parseToolArgs(%q) = %v", tc.in, args)
+		}
+	}
+}
+
+func TestValidateToolCall(t *testing.T) {
+	op := &OpenProcessor{}
+
+	args, problem := op.validateToolCall(ToolReadFile, `{"path": "a.go", "start_line": "10", "line_numbers": "true"}`)
+	if problem != "" {
+		t.Fatalf("a repairable call was rejected: %s", problem)
+	}
+	if args["start_line"] != float64(10) || args["line_numbers"] != true {
+		t.Errorf("the values were not repaired: %v", args)
+	}
+
+	for _, tc := range []struct {
+		name, args, problem string
+	}{
+		{ToolReadFile, `{"start_line": 1}`, "missing required argument 'path' (string)"},
+		{ToolReadFile, `{"path": "a.go", "start_line": 1.5}`, "argument 'start_line' must be an integer, got the number 1.5"},
+		{ToolReadFile, `{"path": ["a.go", "b.go"]}`, "argument 'path' must be a string, got an array"},
+		{ToolReadFile, `{"path": "a.go",}`, "the arguments are not a valid JSON object"},
+		{ToolTodoWrite, `{"todos": [{"id": "1", "content": "x", "status": "done"}]}`, "argument 'todos[0].status' must be one of pending, in_progress, completed, got 'done'"},
+		{"read_fil", `{}`, "there is no tool named 'read_fil', did you mean 'read_file'?"},
+		{"teleport", `{}`, "there is no tool named 'teleport'"},
+	} {
+		_, problem := op.validateToolCall(tc.name, tc.args)
+		if !strings.Contains(problem, tc.problem) {
+			t.Errorf("validateToolCall(%s, %s) = %q, expected %q", tc.name, tc.args, problem, tc.problem)
+		}
+	}
+}
+
+func TestMockProviderInvalidToolCalls(t *testing.T) {
+	script := &MockScript{Turns: []MockTurn{
+		{ToolCalls: []MockToolCall{{Name: "todo_reed", Args: map[string]interface{}{}}}},
+		{ToolCalls: []MockToolCall{{Name: ToolTodoRead, Args: map[string]interface{}{}}}},
+		{Text: "done"},
+	}}
+	if _, err := runMockAgent(t, context.Background(), "mock-repair", script, []string{ToolTodoRead}); err != nil {
+		t.Fatalf("a fixed call must let the run go on, got %v", err)
+	}
+	requests := script.Requests()
+	if len(requests) != 3 || !strings.Contains(string(requests[1]), "did you mean 'todo_read'?") {
+		t.Errorf("the model was not told how to fix the call")
+	}
+
+	bad := MockTurn{ToolCalls: []MockToolCall{{Name: ToolTodoWrite, Args: map[string]interface{}{"items": []interface{}{}}}}}
+	script = &MockScript{Turns: []MockTurn{bad, bad, bad, {Text: "never"}}}
+	_, err := runMockAgent(t, context.Background(), "mock-repair-abort", script, []string{ToolTodoWrite})
+	if err == nil || !strings.Contains(err.Error(), "stopped after 3 invalid tool calls in a row") {
+		t.Fatalf("expected the run to stop, got %v", err)
+	}
+}
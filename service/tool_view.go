@@ -0,0 +1,136 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/io"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// Display modes of tool calls, set by --show-tools, /tools-view and the show_tools key of
+// agents. Empty keeps the boxes of the verbose setting.
+const (
+	ShowToolsFull    = "full"    // Name, pretty-printed arguments, duration and a truncated result
+	ShowToolsCompact = "compact" // One summary line per call
+	ShowToolsOff     = "off"     // Nothing
+)
+
+// ShowToolsModes lists the display modes of tool calls.
+var ShowToolsModes = []string{ShowToolsFull, ShowToolsCompact, ShowToolsOff}
+
+const (
+	toolResultPreviewLines = 8 // Lines of the result shown in full mode
+)
+
+// ValidateShowTools checks a display mode of tool calls, empty is the default.
+func ValidateShowTools(mode string) error {
+	if mode == "" || slices.Contains(ShowToolsModes, mode) {
+		return nil
+	}
+	return fmt.Errorf("invalid tool display '%s', expected %s", mode, strings.Join(ShowToolsModes, ", "))
+}
+
+// toolCallView is a tool call being displayed, it is summed up once the result is back.
+type toolCallView struct {
+	name    string
+	args    interface{}
+	started time.Time
+}
+
+// beginToolView shows the start of a tool call in the display mode of the agent.
+func (ag *Agent) beginToolView(text string) {
+	var call struct {
+		Function string      `json:"function"`
+		Args     interface{} `json:"args"`
+	}
+	if err := json.Unmarshal([]byte(text), &call); err != nil || call.Function == "" {
+		call.Function = text
+	}
+	ag.toolView = &toolCallView{name: call.Function, args: call.Args, started: time.Now()}
+
+	if ag.ShowTools != ShowToolsFull {
+		return
+	}
+	header := data.ToolCallColor + data.BoldSeq + "⏺ " + call.Function + data.ResetSeq
+	if call.Args != nil {
+		args, _ := json.MarshalIndent(call.Args, "  ", "  ")
+		if string(args) != "{}" && string(args) != "null" {
+			header += "\n  " + data.DetailColor + string(args) + data.ResetSeq
+		}
+	}
+	ag.StdOutput.Writeln(header)
+}
+
+// endToolView sums up the tool call with its duration and result.
+func (ag *Agent) endToolView(result string) {
+	view := ag.toolView
+	ag.toolView = nil
+	if view == nil || ag.ShowTools == ShowToolsOff {
+		return
+	}
+
+	mark, color := "✓", data.StatusSuccessColor
+	if strings.HasPrefix(result, "Error:") {
+		mark, color = "✗", data.StatusErrorColor
+	}
+	elapsed := time.Since(view.started)
+	summary := fmt.Sprintf("%s · %s", formatToolDuration(elapsed), formatResultSize(result))
+
+	if ag.ShowTools == ShowToolsCompact {
+		line := color + mark + data.ResetSeq + " " + data.ToolCallColor + view.name + data.ResetSeq
+		if detail := extractFirstArg(view.args); detail != "" {
+			line += " " + data.LabelColor + firstLine(detail) + data.ResetSeq
+		}
+		line += " " + data.DetailColor + "(" + summary + ")" + data.ResetSeq
+		ag.StdOutput.Writeln(ansi.Truncate(line, io.GetTerminalWidth()-1, "…"))
+		return
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "  %s%s %s%s\n", color, mark, summary, data.ResetSeq)
+	width := max(20, io.GetTerminalWidth()-6)
+	lines := strings.Split(strings.TrimRight(result, "\n"), "\n")
+	for i, line := range lines {
+		if i == toolResultPreviewLines {
+			fmt.Fprintf(&sb, "  %s│ … %d more lines%s\n", data.DetailColor, len(lines)-i, data.ResetSeq)
+			break
+		}
+		fmt.Fprintf(&sb, "  %s│ %s%s\n", data.DetailColor, ansi.Truncate(line, width, "…"), data.ResetSeq)
+	}
+	ag.StdOutput.Write(sb.String())
+}
+
+// formatToolDuration formats the time a tool took, e.g. 0.4s or 1m12s.
+func formatToolDuration(d time.Duration) string {
+	if d < time.Minute {
+		return fmt.Sprintf("%.1fs", d.Seconds())
+	}
+	return d.Round(time.Second).String()
+}
+
+// formatResultSize describes a tool result by its lines, or its bytes when it is one line.
+func formatResultSize(result string) string {
+	switch lines := strings.Count(strings.TrimRight(result, "\n"), "\n") + 1; {
+	case result == "":
+		return "no output"
+	case lines > 1:
+		return fmt.Sprintf("%d lines", lines)
+	case len(result) >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(len(result))/1024)
+	default:
+		return fmt.Sprintf("%d bytes", len(result))
+	}
+}
+
+// firstLine returns the first line of a text, marked when there is more.
+func firstLine(text string) string {
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		return text[:i] + " …"
+	}
+	return text
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatResultSize(t *testing.T) {
+	cases := map[string]string{
+		"":                        "no output",
+		"ok":                      "2 bytes",
+		"a\nb\nc\n":               "3 lines",
+		strings.Repeat("x", 2048): "2.0 KB",
+	}
+	for result, want := range cases {
+		if got := formatResultSize(result); got != want {
+			t.Errorf("formatResultSize(%.10q) = %q, want %q", result, got, want)
+		}
+	}
+}
+
+func TestToolView(t *testing.T) {
+	out := &recordOutput{}
+	ag := &Agent{StdOutput: out, ShowTools: ShowToolsCompact}
+	ag.beginToolView(`{"function":"read_file","args":{"path":"main.go"}}`)
+	if len(out.writes) != 0 {
+		t.Fatalf("expected nothing before the result in compact mode, got %q", out.writes)
+	}
+	ag.endToolView("package main\n\nfunc main() {}\n")
+	if len(out.writes) != 1 || !strings.Contains(out.writes[0], "read_file") || !strings.Contains(out.writes[0], "3 lines") {
+		t.Errorf("expected one summary line, got %q", out.writes)
+	}
+
+	out.writes = nil
+	ag.ShowTools = ShowToolsFull
+	ag.beginToolView(`{"function":"list_files","args":{"dir":"."}}`)
+	ag.endToolView(strings.Repeat("file\n", toolResultPreviewLines+4))
+	all := strings.Join(out.writes, "")
+	if !strings.Contains(all, `"dir": "."`) || !strings.Contains(all, "… 4 more lines") {
+		t.Errorf("expected the arguments and a truncated result, got:\n%s", all)
+	}
+
+	out.writes = nil
+	ag.ShowTools = ShowToolsOff
+	ag.beginToolView(`{"function":"list_files"}`)
+	ag.endToolView("x")
+	if len(out.writes) != 0 {
+		t.Errorf("expected nothing when off, got %q", out.writes)
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/activebook/gllm/data"
+)
+
+// toolGrant applies the session grants given at confirmation prompts to one tool call.
+type toolGrant struct {
+	op      *OpenProcessor
+	granted bool // The call was approved by a grant, approval is reverted when it ends
+}
+
+// beginToolGrant tells the confirmation prompt which tool and command it is about,
+// and approves the call without asking when a session grant covers it. Finish it with end.
+func (op *OpenProcessor) beginToolGrant(name string, args map[string]interface{}) *toolGrant {
+	g := &toolGrant{op: op}
+	if op.toolsUse == nil {
+		return g
+	}
+	command := ""
+	if name == ToolShell {
+		command, _ = args["command"].(string)
+	}
+	op.toolsUse.Tool = name
+	op.toolsUse.Command = command
+	op.toolsUse.Feedback = ""
+	op.toolsUse.Ctx = op.toolContext()
+	if !op.toolsUse.AutoApprove && data.GetSessionToolGrants().Allows(name, command) {
+		op.toolsUse.AutoApprove = true
+		g.granted = true
+	}
+	return g
+}
+
+// end reverts the approval of a granted call. When the user declined the call with feedback,
+// it returns the tool result that tells the model why, and the turn goes on instead of stopping.
+func (g *toolGrant) end(err error) (string, bool) {
+	tu := g.op.toolsUse
+	if tu == nil {
+		return "", false
+	}
+	if g.granted {
+		tu.AutoApprove = false
+	}
+	feedback := tu.Feedback
+	tu.Feedback = ""
+	if cancelErr, ok := AsUserCancelError(err); !ok || cancelErr.Reason != UserCancelReasonDeny || feedback == "" {
+		return "", false
+	}
+	return fmt.Sprintf("The user declined this tool call and said: %s", feedback), true
+}
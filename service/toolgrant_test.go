@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestToolGrantApprovesGrantedCalls(t *testing.T) {
+	grants := data.GetSessionToolGrants()
+	grants.Reset()
+	t.Cleanup(grants.Reset)
+	grants.AllowCommandPrefix("go test")
+
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{}}
+	g := op.beginToolGrant(ToolShell, map[string]interface{}{"command": "go test ./..."})
+	if !op.toolsUse.AutoApprove || op.toolsUse.Command != "go test ./..." {
+		t.Fatalf("granted command not approved: %+v", op.toolsUse)
+	}
+	if _, ok := g.end(nil); ok || op.toolsUse.AutoApprove {
+		t.Error("the approval must end with the call")
+	}
+
+	g = op.beginToolGrant(ToolShell, map[string]interface{}{"command": "go test ./... ; rm -rf ~"})
+	if op.toolsUse.AutoApprove {
+		t.Error("chained commands must not be approved by a prefix")
+	}
+	g.end(nil)
+
+	// Session yolo stays on after a call
+	op.toolsUse.AutoApprove = true
+	op.beginToolGrant(ToolWriteFile, nil).end(nil)
+	if !op.toolsUse.AutoApprove {
+		t.Error("an approval that was not granted must be kept")
+	}
+}
+
+func TestToolGrantDenyFeedback(t *testing.T) {
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{}}
+	g := op.beginToolGrant(ToolWriteFile, nil)
+	op.toolsUse.ConfirmDeny("use the existing helper instead")
+	result, ok := g.end(UserCancelError{Reason: UserCancelReasonDeny})
+	if !ok || result != "The user declined this tool call and said: use the existing helper instead" {
+		t.Errorf("end() = %q, %v", result, ok)
+	}
+	if op.toolsUse.Feedback != "" {
+		t.Error("feedback must not carry over to the next call")
+	}
+
+	// A plain cancel still stops the turn
+	g = op.beginToolGrant(ToolWriteFile, nil)
+	op.toolsUse.ConfirmCancel()
+	if _, ok := g.end(UserCancelError{Reason: UserCancelReasonDeny}); ok {
+		t.Error("a cancel without feedback must keep the error")
+	}
+}
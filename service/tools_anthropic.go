@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -11,7 +12,7 @@ import (
  * Antrhopic tool call implements
  */
 
-func (op *OpenProcessor) anthropicMCPToolCall(toolCall anthropic.ToolUseBlockParam, argsMap *map[string]interface{}) (anthropic.MessageParam, error) {
+func (op *OpenProcessor) anthropicMCPToolCall(ctx context.Context, toolCall anthropic.ToolUseBlockParam, argsMap *map[string]interface{}) (anthropic.MessageParam, error) {
 
 	if op.mcpClient == nil {
 		err := fmt.Errorf("MCP client not initialized")
@@ -28,7 +29,7 @@ func (op *OpenProcessor) anthropicMCPToolCall(toolCall anthropic.ToolUseBlockPar
 	}
 
 	// Call the MCP tool
-	result, err := op.mcpClient.CallTool(toolCall.Name, *argsMap)
+	result, err := op.mcpClient.CallTool(ctx, toolCall.Name, *argsMap)
 	if err != nil {
 		errorStr := fmt.Sprintf("Error: MCP tool call failed: %v", err)
 		toolResult := anthropic.NewToolResultBlock(toolCall.ID, errorStr, true)
@@ -57,8 +58,8 @@ func (op *OpenProcessor) anthropicMCPToolCall(toolCall anthropic.ToolUseBlockPar
 	return anthropic.NewUserMessage(toolResult), nil
 }
 
-func (op *OpenProcessor) anthropicSwitchAgentToolCall(toolCall anthropic.ToolUseBlockParam, argsMap *map[string]interface{}) (anthropic.MessageParam, error) {
-	response, err := switchAgentToolCallImpl(argsMap, op)
+func (op *OpenProcessor) anthropicSwitchAgentToolCall(ctx context.Context, toolCall anthropic.ToolUseBlockParam, argsMap *map[string]interface{}) (anthropic.MessageParam, error) {
+	response, err := agentSwitchToolCallImpl(ctx, toolCall.Name, argsMap, op)
 
 	isError := err != nil && !IsSwitchAgentError(err)
 
@@ -81,68 +82,162 @@ func runAnthropicTool(toolID string, fn ToolFunc) (anthropic.MessageParam, error
 	return anthropic.NewUserMessage(toolResult), err
 }
 
+// mapAnthropicToolResult rewrites the text of an Anthropic tool result message with f.
+func mapAnthropicToolResult(msg anthropic.MessageParam, f func(string) string) anthropic.MessageParam {
+	for _, block := range msg.Content {
+		if block.OfToolResult == nil {
+			continue
+		}
+		for _, c := range block.OfToolResult.Content {
+			if c.OfText != nil {
+				c.OfText.Text = f(c.OfText.Text)
+			}
+		}
+	}
+	return msg
+}
+
 // dispatchAnthropicToolCall handles the routing of Anthropic tool calls to the correct implementation.
 func (op *OpenProcessor) dispatchAnthropicToolCall(toolCall anthropic.ToolUseBlockParam, a *map[string]interface{}) (anthropic.MessageParam, error) {
+	ctx := op.toolContext()
 	switch toolCall.Name {
 	case ToolShell:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return shellToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return shellToolCallImpl(ctx, a, op) })
+	case ToolRunTests:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return runTestsToolCallImpl(ctx, a, op) })
 	case ToolWebFetch:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return webFetchToolCallImpl(a) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return webFetchToolCallImpl(ctx, a, op) })
+	case ToolGetTranscript:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return getTranscriptToolCallImpl(ctx, a, op) })
 	case ToolWebSearch:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return webSearchToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return webSearchToolCallImpl(ctx, a, op) })
 	case ToolReadFile:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return readFileToolCallImpl(a) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return readFileToolCallImpl(ctx, a) })
 	case ToolWriteFile:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return writeFileToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return writeFileToolCallImpl(ctx, a, op) })
 	case ToolEditFile:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return editFileToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return editFileToolCallImpl(ctx, a, op) })
+	case ToolApplyChanges:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return applyChangesToolCallImpl(ctx, a, op) })
 	case ToolCreateDirectory:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return createDirectoryToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return createDirectoryToolCallImpl(ctx, a, op) })
 	case ToolListDirectory:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return listDirectoryToolCallImpl(a) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return listDirectoryToolCallImpl(ctx, a) })
 	case ToolDeleteFile:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return deleteFileToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return deleteFileToolCallImpl(ctx, a, op) })
 	case ToolDeleteDirectory:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return deleteDirectoryToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return deleteDirectoryToolCallImpl(ctx, a, op) })
+	case ToolRestoreDeleted:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return restoreDeletedToolCallImpl(ctx, a, op) })
+	case ToolWatchPath:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return watchPathToolCallImpl(ctx, a, op) })
+	case ToolCheckChanges:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return checkChangesToolCallImpl(ctx, a, op) })
+	case ToolGetEnvironment:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return getEnvironmentToolCallImpl(ctx, a, op) })
+	case ToolGetTime:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return getTimeToolCallImpl(ctx, a) })
 	case ToolMove:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return moveToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return moveToolCallImpl(ctx, a, op) })
 	case ToolCopy:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return copyToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return copyToolCallImpl(ctx, a, op) })
 	case ToolSearchFiles:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return searchFilesToolCallImpl(a) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return searchFilesToolCallImpl(ctx, a) })
 	case ToolSearchTextInFile:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return searchTextInFileToolCallImpl(a) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return searchTextInFileToolCallImpl(ctx, a) })
 	case ToolReadMultipleFiles:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return readMultipleFilesToolCallImpl(a) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return readMultipleFilesToolCallImpl(ctx, a) })
+	case ToolReadSymbol:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return readSymbolToolCallImpl(ctx, a) })
+	case ToolLint:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return lintToolCallImpl(ctx, a, op) })
+	case ToolFormatCode:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return formatCodeToolCallImpl(ctx, a, op) })
 	case ToolListMemory:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return listMemoryToolCallImpl() })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return listMemoryToolCallImpl(ctx) })
 	case ToolSaveMemory:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return saveMemoryToolCallImpl(a) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return saveMemoryToolCallImpl(ctx, a) })
 	case ToolBuildAgent:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return buildAgentToolCallImpl(a, op) })
-	case ToolSwitchAgent:
-		return op.anthropicSwitchAgentToolCall(toolCall, a)
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return buildAgentToolCallImpl(ctx, a, op) })
+	case ToolSwitchAgent, ToolHandoffBack:
+		return op.anthropicSwitchAgentToolCall(ctx, toolCall, a)
 	case ToolListAgent:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return listAgentToolCallImpl() })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return listAgentToolCallImpl(ctx) })
 	case ToolSpawnSubAgents:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return spawnSubAgentsToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return spawnSubAgentsToolCallImpl(ctx, a, op) })
+	case ToolMapOverFiles:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return mapOverFilesToolCallImpl(ctx, a, op) })
 	case ToolGetState:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return getStateToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return getStateToolCallImpl(ctx, a, op) })
 	case ToolSetState:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return setStateToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return setStateToolCallImpl(ctx, a, op) })
 	case ToolListState:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return listStateToolCallImpl(op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return listStateToolCallImpl(ctx, op) })
 	case ToolActivateSkill:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return activateSkillToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return activateSkillToolCallImpl(ctx, a, op) })
+	case ToolRunSkillScript:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return runSkillScriptToolCallImpl(ctx, a, op) })
+	case ToolTodoWrite:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return todoWriteToolCallImpl(ctx, a, op) })
+	case ToolTodoRead:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return todoReadToolCallImpl(ctx, a, op) })
+	case ToolFindDefinition:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return findDefinitionToolCallImpl(ctx, a, op) })
+	case ToolFindReferences:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return findReferencesToolCallImpl(ctx, a, op) })
+	case ToolDocumentSymbols:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return documentSymbolsToolCallImpl(ctx, a, op) })
+	case ToolGHListIssues:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return ghListIssuesToolCallImpl(ctx, a, op) })
+	case ToolGHGetPRDiff:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return ghGetPRDiffToolCallImpl(ctx, a, op) })
+	case ToolGHComment:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return ghCommentToolCallImpl(ctx, a, op) })
+	case ToolGHCreatePR:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return ghCreatePRToolCallImpl(ctx, a, op) })
+	case ToolTicketSearch:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return ticketSearchToolCallImpl(ctx, a, op) })
+	case ToolTicketGet:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return ticketGetToolCallImpl(ctx, a, op) })
+	case ToolTicketCreate:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return ticketCreateToolCallImpl(ctx, a, op) })
+	case ToolTicketTransition:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return ticketTransitionToolCallImpl(ctx, a, op) })
+	case ToolQueryDatabase:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return queryDatabaseToolCallImpl(ctx, a, op) })
+	case ToolInspectData:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return inspectDataToolCallImpl(ctx, a) })
+	case ToolQueryData:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return queryDataToolCallImpl(ctx, a, op) })
+	case ToolDockerPS:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return dockerPSToolCallImpl(ctx, a, op) })
+	case ToolDockerLogs:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return dockerLogsToolCallImpl(ctx, a, op) })
+	case ToolDockerRun:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return dockerRunToolCallImpl(ctx, a, op) })
+	case ToolDockerExec:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return dockerExecToolCallImpl(ctx, a, op) })
+	case ToolHTTPRequest:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return httpRequestToolCallImpl(ctx, a, op) })
 	case ToolAskUser:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return askUserToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return askUserToolCallImpl(ctx, a, op) })
+	case ToolPresentOptions:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return presentOptionsToolCallImpl(ctx, a, op) })
+	case ToolRequestApproval:
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return requestApprovalToolCallImpl(ctx, a, op) })
 	case ToolExitPlanMode:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return exitPlanModeToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return exitPlanModeToolCallImpl(ctx, a, op) })
 	case ToolEnterPlanMode:
-		return runAnthropicTool(toolCall.ID, func() (string, error) { return enterPlanModeToolCallImpl(a, op) })
+		return runAnthropicTool(toolCall.ID, func() (string, error) { return enterPlanModeToolCallImpl(ctx, a, op) })
 	default:
+		if AvailableAPITool(toolCall.Name) {
+			return runAnthropicTool(toolCall.ID, func() (string, error) { return apiToolCallImpl(ctx, toolCall.Name, a, op) })
+		}
+		if AvailablePluginTool(toolCall.Name) {
+			return runAnthropicTool(toolCall.ID, func() (string, error) { return pluginToolCallImpl(ctx, toolCall.Name, a, op) })
+		}
 		if op.mcpClient != nil && op.mcpClient.FindTool(toolCall.Name) != nil {
-			return op.anthropicMCPToolCall(toolCall, a)
+			return op.anthropicMCPToolCall(ctx, toolCall, a)
 		}
 		errorMsg := fmt.Sprintf("Error: Unknown function '%s'. This function is not available. Please use one of the available functions from the tool list.", toolCall.Name)
 		toolResult := anthropic.NewToolResultBlock(toolCall.ID, errorMsg, true)
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"slices"
 
 	"github.com/activebook/gllm/data"
@@ -22,33 +23,70 @@ const (
 
 	// Tool Names
 	ToolShell             = "shell"
+	ToolRunTests          = "run_tests"
+	ToolLint              = "lint"
+	ToolFormatCode        = "format_code"
 	ToolReadFile          = "read_file"
 	ToolWriteFile         = "write_file"
 	ToolEditFile          = "edit_file"
+	ToolApplyChanges      = "apply_changes"
 	ToolDeleteFile        = "delete_file"
 	ToolCreateDirectory   = "create_directory"
 	ToolListDirectory     = "list_directory"
 	ToolDeleteDirectory   = "delete_directory"
+	ToolRestoreDeleted    = "restore_deleted"
+	ToolWatchPath         = "watch_path"
+	ToolCheckChanges      = "check_changes"
+	ToolGetEnvironment    = "get_environment"
+	ToolGetTime           = "get_time"
 	ToolMove              = "move"
 	ToolCopy              = "copy"
 	ToolSearchFiles       = "search_files"
 	ToolSearchTextInFile  = "search_text_in_file"
 	ToolReadMultipleFiles = "read_multiple_files"
+	ToolReadSymbol        = "read_symbol"
 	ToolWebFetch          = "web_fetch"
+	ToolGetTranscript     = "get_transcript"
 	ToolSwitchAgent       = "switch_agent"
+	ToolHandoffBack       = "handoff_back"
 	ToolBuildAgent        = "build_agent"
 	ToolAskUser           = "ask_user"
+	ToolRequestApproval   = "request_approval"
+	ToolPresentOptions    = "present_options"
 	ToolWebSearch         = "web_search"
 	ToolActivateSkill     = "activate_skill"
+	ToolRunSkillScript    = "run_skill_script"
 	ToolListMemory        = "list_memory"
 	ToolSaveMemory        = "save_memory"
 	ToolListAgent         = "list_agent"
 	ToolSpawnSubAgents    = "spawn_subagents"
+	ToolMapOverFiles      = "map_over_files"
 	ToolGetState          = "get_state"
 	ToolSetState          = "set_state"
 	ToolListState         = "list_state"
 	ToolExitPlanMode      = "exit_plan_mode"
 	ToolEnterPlanMode     = "enter_plan_mode"
+	ToolTodoWrite         = "todo_write"
+	ToolTodoRead          = "todo_read"
+	ToolFindDefinition    = "find_definition"
+	ToolFindReferences    = "find_references"
+	ToolDocumentSymbols   = "document_symbols"
+	ToolGHListIssues      = "gh_list_issues"
+	ToolGHGetPRDiff       = "gh_get_pr_diff"
+	ToolGHComment         = "gh_comment"
+	ToolGHCreatePR        = "gh_create_pr"
+	ToolTicketSearch      = "ticket_search"
+	ToolTicketGet         = "ticket_get"
+	ToolTicketCreate      = "ticket_create"
+	ToolTicketTransition  = "ticket_transition"
+	ToolQueryDatabase     = "query_database"
+	ToolInspectData       = "inspect_data"
+	ToolQueryData         = "query_data"
+	ToolDockerPS          = "docker_ps"
+	ToolDockerLogs        = "docker_logs"
+	ToolDockerRun         = "docker_run"
+	ToolDockerExec        = "docker_exec"
+	ToolHTTPRequest       = "http_request"
 )
 
 // OpenTool is a generic tool definition that is not tied to any specific model.
@@ -71,29 +109,76 @@ const (
 	ToolRespDiscardEditFile = "Based on your request, the OPERATION is CANCELLED: " +
 		"Cancel edit file: %s\n" +
 		"The user has explicitly declined to apply these file edits. The file will remain unchanged. Do not proceed with any file modifications or ask for further confirmation without explicit new user instruction."
+
+	// ToolRespFileChangedOnDisk is the response when a write would clobber changes made outside the tools.
+	ToolRespFileChangedOnDisk = "WRITE ABORTED — %s was changed on disk since you last read it, possibly by the user in their editor. " +
+		"No changes were written. Read the file again and redo your change on its current content."
 )
 
 var (
 	embeddingTools = []string{
 		// shell tool
 		ToolShell,
+		ToolRunTests,
+		ToolGetEnvironment,
+		ToolGetTime,
 		// file tools
 		ToolReadFile,
 		ToolWriteFile,
 		ToolEditFile,
+		ToolApplyChanges,
 		ToolDeleteFile,
 		ToolCreateDirectory,
 		ToolListDirectory,
 		ToolDeleteDirectory,
+		ToolRestoreDeleted,
 		ToolMove,
 		ToolCopy,
 		ToolSearchFiles,
 		ToolSearchTextInFile,
 		ToolReadMultipleFiles,
+		ToolReadSymbol,
+		ToolWatchPath,
+		ToolCheckChanges,
+		// code quality tools
+		ToolLint,
+		ToolFormatCode,
 		// web tools
 		ToolWebFetch,
+		ToolGetTranscript,
 		// Interactive tools
 		ToolAskUser,
+		ToolPresentOptions,
+		ToolRequestApproval,
+		// Task tracking tools
+		ToolTodoWrite,
+		ToolTodoRead,
+		// Code intelligence tools
+		ToolFindDefinition,
+		ToolFindReferences,
+		ToolDocumentSymbols,
+		// Code host tools
+		ToolGHListIssues,
+		ToolGHGetPRDiff,
+		ToolGHComment,
+		ToolGHCreatePR,
+		// Issue tracker tools
+		ToolTicketSearch,
+		ToolTicketGet,
+		ToolTicketCreate,
+		ToolTicketTransition,
+		// Database tools
+		ToolQueryDatabase,
+		// Data file tools
+		ToolInspectData,
+		ToolQueryData,
+		// Container tools
+		ToolDockerPS,
+		ToolDockerLogs,
+		ToolDockerRun,
+		ToolDockerExec,
+		// HTTP tools
+		ToolHTTPRequest,
 	}
 	searchTools = []string{
 		// web tools
@@ -102,6 +187,7 @@ var (
 	skillTools = []string{
 		// skill tools
 		ToolActivateSkill,
+		ToolRunSkillScript,
 	}
 	memoryTools = []string{
 		// memory tools
@@ -114,7 +200,9 @@ var (
 		ToolBuildAgent,
 		// Interaction
 		ToolSwitchAgent,
+		ToolHandoffBack, // Only offered after a switch with return_to_caller
 		ToolSpawnSubAgents,
+		ToolMapOverFiles,
 		// Shared State
 		ToolGetState,
 		ToolSetState,
@@ -130,20 +218,65 @@ var (
 	readOnlyTools = map[string]bool{
 		ToolReadFile:          true,
 		ToolReadMultipleFiles: true,
+		ToolReadSymbol:        true,
+		ToolLint:              true, // Reports diagnostics, never applies fixes
 		ToolSearchFiles:       true,
 		ToolSearchTextInFile:  true,
 		ToolListDirectory:     true,
+		ToolWatchPath:         true,
+		ToolCheckChanges:      true,
+		ToolGetEnvironment:    true,
+		ToolGetTime:           true,
 		ToolWebFetch:          true,
+		ToolGetTranscript:     true,
 		ToolWebSearch:         true,
 		ToolAskUser:           true,
+		ToolPresentOptions:    true,
+		ToolRequestApproval:   true,
 		ToolExitPlanMode:      true,
 		ToolEnterPlanMode:     true,
+		ToolHandoffBack:       true, // Returns to the caller, which is in plan mode too
 		ToolActivateSkill:     true,
 		ToolListMemory:        true,
 		ToolListAgent:         true,
 		ToolSpawnSubAgents:    true,
+		ToolMapOverFiles:      true,
 		ToolGetState:          true,
 		ToolListState:         true,
+		ToolTodoWrite:         true, // Only touches the session's task list
+		ToolTodoRead:          true,
+		ToolFindDefinition:    true,
+		ToolFindReferences:    true,
+		ToolDocumentSymbols:   true,
+		ToolGHListIssues:      true,
+		ToolGHGetPRDiff:       true,
+		ToolTicketSearch:      true,
+		ToolTicketGet:         true,
+		ToolInspectData:       true,
+		ToolQueryData:         true, // Loads the files in memory, never changes them
+		ToolDockerPS:          true,
+		ToolDockerLogs:        true,
+	}
+
+	// optionalTools are not selected by default for new agents, they need extra programs installed
+	// or an access token
+	optionalTools = map[string]bool{
+		ToolFindDefinition:   true,
+		ToolFindReferences:   true,
+		ToolDocumentSymbols:  true,
+		ToolGHListIssues:     true,
+		ToolGHGetPRDiff:      true,
+		ToolGHComment:        true,
+		ToolGHCreatePR:       true,
+		ToolTicketSearch:     true,
+		ToolTicketGet:        true,
+		ToolTicketCreate:     true,
+		ToolTicketTransition: true,
+		ToolQueryDatabase:    true,
+		ToolDockerPS:         true,
+		ToolDockerLogs:       true,
+		ToolDockerRun:        true,
+		ToolDockerExec:       true,
 	}
 )
 
@@ -196,7 +329,14 @@ func IsAvailableOpenTool(toolName string) bool {
 		AvailableSkillTool(toolName) ||
 		AvailableMemoryTool(toolName) ||
 		AvailableSubagentTool(toolName) ||
-		AvailablePlanTool(toolName)
+		AvailablePlanTool(toolName) ||
+		AvailableAPITool(toolName) ||
+		AvailablePluginTool(toolName)
+}
+
+// IsOptionalTool reports whether a tool should be left off when creating an agent.
+func IsOptionalTool(toolName string) bool {
+	return optionalTools[toolName]
 }
 
 // AvailableEmbeddingTool checks if a tool is available in the embedding tools.
@@ -383,6 +523,12 @@ func GetOpenToolsFiltered(allowedTools []string) []*OpenTool {
 			filtered = append(filtered, tool)
 		}
 	}
+	// The operations of imported APIs are sent through http_request
+	if allowedSet[ToolHTTPRequest] {
+		filtered = append(filtered, getAPITools()...)
+	}
+	// Plugins are declared by the user, their tools come with any tool set
+	filtered = append(filtered, getPluginTools()...)
 
 	return filtered
 }
@@ -590,10 +736,20 @@ func getOpenTools() []*OpenTool {
 	shellTool := getOpenShellTool()
 	tools = append(tools, shellTool)
 
+	// Run tests tool
+	runTestsTool := getRunTestsTool()
+	tools = append(tools, runTestsTool)
+
+	// Environment tools
+	tools = append(tools, getEnvironmentTool(), getTimeTool())
+
 	// Web fetch tool
 	webFetchTool := getWebFetchTool()
 	tools = append(tools, webFetchTool)
 
+	// Video transcript tool
+	tools = append(tools, getTranscriptTool())
+
 	// Web Search tool
 	webSearchTool := getWebSearchTool()
 	tools = append(tools, webSearchTool)
@@ -622,6 +778,12 @@ func getOpenTools() []*OpenTool {
 	deleteDirectoryTool := getDeleteDirectoryTool()
 	tools = append(tools, deleteDirectoryTool)
 
+	// Restore deleted tool
+	tools = append(tools, getRestoreDeletedTool())
+
+	// File watching tools
+	tools = append(tools, getWatchPathTool(), getCheckChangesTool())
+
 	// Search files tool
 	searchFilesTool := getSearchFilesTool()
 	tools = append(tools, searchFilesTool)
@@ -634,10 +796,26 @@ func getOpenTools() []*OpenTool {
 	readMultipleFilesTool := getReadMultipleFilesTool()
 	tools = append(tools, readMultipleFilesTool)
 
+	// Read symbol tool
+	readSymbolTool := getReadSymbolTool()
+	tools = append(tools, readSymbolTool)
+
+	// Lint tool
+	lintTool := getLintTool()
+	tools = append(tools, lintTool)
+
+	// Format code tool
+	formatCodeTool := getFormatCodeTool()
+	tools = append(tools, formatCodeTool)
+
 	// Edit file tool
 	editFileTool := getEditFileTool()
 	tools = append(tools, editFileTool)
 
+	// Multi-file change tool
+	applyChangesTool := getApplyChangesTool()
+	tools = append(tools, applyChangesTool)
+
 	// Move file/directory tool
 	moveTool := getMoveTool()
 	tools = append(tools, moveTool)
@@ -658,6 +836,10 @@ func getOpenTools() []*OpenTool {
 	switchAgentTool := getSwitchAgentTool()
 	tools = append(tools, switchAgentTool)
 
+	// handoff_back tool - Return to the agent that switched with return_to_caller
+	handoffBackTool := getHandoffBackTool()
+	tools = append(tools, handoffBackTool)
+
 	// build_agent tool
 	buildAgentTool := getBuildAgentTool()
 	tools = append(tools, buildAgentTool)
@@ -670,10 +852,20 @@ func getOpenTools() []*OpenTool {
 	askUserTool := getAskUserTool()
 	tools = append(tools, askUserTool)
 
+	// present_options tool
+	tools = append(tools, getPresentOptionsTool())
+
+	// request_approval tool
+	tools = append(tools, getRequestApprovalTool())
+
 	// spawn_subagents tool - The core orchestration tool
 	spawnSubAgentsTool := getSpawnSubAgentsTool()
 	tools = append(tools, spawnSubAgentsTool)
 
+	// map_over_files tool - Shard a glob of files across sub-agents, then reduce
+	mapOverFilesTool := getMapOverFilesTool()
+	tools = append(tools, mapOverFilesTool)
+
 	// get_state tool - Read from SharedState
 	getStateTool := getGetStateTool()
 	tools = append(tools, getStateTool)
@@ -690,6 +882,10 @@ func getOpenTools() []*OpenTool {
 	activateSkillTool := getActivateSkillTool()
 	tools = append(tools, activateSkillTool)
 
+	// run_skill_script tool
+	runSkillScriptTool := getRunSkillScriptTool()
+	tools = append(tools, runSkillScriptTool)
+
 	// enter_plan_mode tool
 	enterPlanModeTool := getEnterPlanModeTool()
 	tools = append(tools, enterPlanModeTool)
@@ -698,13 +894,51 @@ func getOpenTools() []*OpenTool {
 	exitPlanModeTool := getExitPlanModeTool()
 	tools = append(tools, exitPlanModeTool)
 
+	// todo_write tool
+	todoWriteTool := getTodoWriteTool()
+	tools = append(tools, todoWriteTool)
+
+	// todo_read tool
+	todoReadTool := getTodoReadTool()
+	tools = append(tools, todoReadTool)
+
+	// find_definition tool
+	findDefinitionTool := getFindDefinitionTool()
+	tools = append(tools, findDefinitionTool)
+
+	// find_references tool
+	findReferencesTool := getFindReferencesTool()
+	tools = append(tools, findReferencesTool)
+
+	// document_symbols tool
+	documentSymbolsTool := getDocumentSymbolsTool()
+	tools = append(tools, documentSymbolsTool)
+
+	// Code host tools
+	tools = append(tools, getGHListIssuesTool(), getGHGetPRDiffTool(), getGHCommentTool(), getGHCreatePRTool())
+
+	// Issue tracker tools
+	tools = append(tools, getTicketSearchTool(), getTicketGetTool(), getTicketCreateTool(), getTicketTransitionTool())
+
+	// Database tools
+	tools = append(tools, getQueryDatabaseTool())
+
+	// Data file tools
+	tools = append(tools, getInspectDataTool(), getQueryDataTool())
+
+	// Container tools
+	tools = append(tools, getDockerPSTool(), getDockerLogsTool(), getDockerRunTool(), getDockerExecTool())
+
+	// HTTP tools
+	tools = append(tools, getHTTPRequestTool())
+
 	return tools
 }
 
 func getReadFileTool() *OpenTool {
 	readFileFunc := OpenFunctionDefinition{
 		Name:        ToolReadFile,
-		Description: "Read the contents of a file from the filesystem. Supports range reading via offset and limit (or lines).",
+		Description: "Read the contents of a file from the filesystem. Large files should be paged with start_line/end_line or max_lines; results report the total line count and are truncated at a per-read token limit, with a notice telling where to continue.",
 		Parameters: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -717,19 +951,29 @@ func getReadFileTool() *OpenTool {
 					"description": "Whether to include line numbers in the output.",
 					"default":     false,
 				},
-				"offset": map[string]interface{}{
+				"start_line": map[string]interface{}{
 					"type":        "integer",
-					"description": "The starting line number (1-indexed). If omitted, starts from line 1.",
+					"description": "The first line to read (1-indexed). If omitted, starts from line 1.",
 					"minimum":     1,
 				},
-				"limit": map[string]interface{}{
+				"end_line": map[string]interface{}{
 					"type":        "integer",
-					"description": "The maximum number of lines to read. If omitted, reads the entire file.",
+					"description": "The last line to read (1-indexed, inclusive). If omitted, reads to the end of the file.",
 					"minimum":     1,
 				},
-				"lines": map[string]interface{}{
+				"max_lines": map[string]interface{}{
 					"type":        "integer",
-					"description": "The maximum number of lines to read (alias for limit).",
+					"description": "The maximum number of lines to read.",
+					"minimum":     1,
+				},
+				"offset": map[string]interface{}{
+					"type":        "integer",
+					"description": "Alias for start_line.",
+					"minimum":     1,
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Alias for max_lines.",
 					"minimum":     1,
 				},
 			},
@@ -743,10 +987,38 @@ func getReadFileTool() *OpenTool {
 	return &readFileTool
 }
 
+func getReadSymbolTool() *OpenTool {
+	readSymbolFunc := OpenFunctionDefinition{
+		Name: ToolReadSymbol,
+		Description: "Read just one function, method, class, struct or other declaration from a source file, " +
+			"with line numbers and its doc comment. Uses far less context than read_file on large files. " +
+			"Supports Go, Python, JavaScript/TypeScript and Rust. Omit symbol to list the declarations of the file.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "The source file to read from.",
+				},
+				"symbol": map[string]interface{}{
+					"type":        "string",
+					"description": "The declaration name, e.g. 'parseConfig'. Qualify methods with their type or class, e.g. 'Server.Start', to disambiguate.",
+				},
+			},
+			"required": []string{"path"},
+		},
+	}
+	readSymbolTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &readSymbolFunc,
+	}
+	return &readSymbolTool
+}
+
 func getWriteFileTool() *OpenTool {
 	writeFileFunc := OpenFunctionDefinition{
 		Name:        ToolWriteFile,
-		Description: "Write content to a file in the filesystem. Creates the file if it doesn't exist, or overwrites it if it does.",
+		Description: "Write content to a file in the filesystem. Creates the file if it doesn't exist, or overwrites it if it does. The write is refused if the file changed on disk since you last read it; read it again and retry.",
 		Parameters: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -824,7 +1096,7 @@ func getListDirectoryTool() *OpenTool {
 func getDeleteFileTool() *OpenTool {
 	deleteFileFunc := OpenFunctionDefinition{
 		Name:        ToolDeleteFile,
-		Description: "Delete a file in the filesystem.",
+		Description: "Delete a file in the filesystem. The file is moved to the trash of the session, restore_deleted brings it back.",
 		Parameters: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -850,7 +1122,7 @@ func getDeleteFileTool() *OpenTool {
 func getDeleteDirectoryTool() *OpenTool {
 	deleteDirectoryFunc := OpenFunctionDefinition{
 		Name:        ToolDeleteDirectory,
-		Description: "Delete a directory in the filesystem.",
+		Description: "Delete a directory in the filesystem. The directory is moved to the trash of the session, restore_deleted brings it back.",
 		Parameters: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -873,6 +1145,134 @@ func getDeleteDirectoryTool() *OpenTool {
 	return &deleteDirectoryTool
 }
 
+func getRestoreDeletedTool() *OpenTool {
+	restoreDeletedFunc := OpenFunctionDefinition{
+		Name:        ToolRestoreDeleted,
+		Description: "Restore a file or directory deleted earlier in this session by delete_file or delete_directory, from the trash to where it was.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "The path the file or directory was deleted from.",
+				},
+				"purpose": map[string]interface{}{
+					"type":        "string",
+					"description": "A terse explanation of why this path is being restored.",
+				},
+			},
+			"required": []string{"path", "purpose"},
+		},
+	}
+	restoreDeletedTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &restoreDeletedFunc,
+	}
+	return &restoreDeletedTool
+}
+
+func getEnvironmentTool() *OpenTool {
+	environmentFunc := OpenFunctionDefinition{
+		Name: ToolGetEnvironment,
+		Description: "Get the basics of the environment at once: OS and architecture, shell, CPUs and memory, working directory, " +
+			"git branch and working tree summary, and the versions of the installed runtimes (go, node, python, java, ...). " +
+			"Use it instead of running shell commands to learn them.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"include_env": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Also list the environment variables, the ones that may hold secrets are left out. Defaults to false.",
+				},
+			},
+		},
+	}
+	environmentTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &environmentFunc,
+	}
+	return &environmentTool
+}
+
+func getTimeTool() *OpenTool {
+	timeFunc := OpenFunctionDefinition{
+		Name: ToolGetTime,
+		Description: "Get the current date and time, or convert a time between timezones. " +
+			"Use it whenever the exact time matters, e.g. for schedules, deadlines or \"today\" in search queries.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"timezone": map[string]interface{}{
+					"type":        "string",
+					"description": "IANA timezone to show the time in as well, e.g. America/New_York, Asia/Tokyo or UTC.",
+				},
+				"time": map[string]interface{}{
+					"type":        "string",
+					"description": "Time to convert instead of now, e.g. 2025-03-14 15:04, 2025-03-14T15:04:05Z or 15:04 for today.",
+				},
+				"from_timezone": map[string]interface{}{
+					"type":        "string",
+					"description": "IANA timezone of the time to convert, defaults to the local timezone.",
+				},
+			},
+		},
+	}
+	timeTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &timeFunc,
+	}
+	return &timeTool
+}
+
+func getWatchPathTool() *OpenTool {
+	watchPathFunc := OpenFunctionDefinition{
+		Name: ToolWatchPath,
+		Description: "Start watching a file or directory for changes made outside your tools, e.g. by the user rebuilding or editing. " +
+			"Call check_changes later to get the files created, modified or deleted since then.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "The file or directory to watch, directories are watched with their subdirectories.",
+				},
+				"globs": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Only watch the files of a directory matching one of these globs, e.g. [\"*.go\", \"web/**/*.ts\"]. Defaults to all files.",
+				},
+			},
+			"required": []string{"path"},
+		},
+	}
+	watchPathTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &watchPathFunc,
+	}
+	return &watchPathTool
+}
+
+func getCheckChangesTool() *OpenTool {
+	checkChangesFunc := OpenFunctionDefinition{
+		Name:        ToolCheckChanges,
+		Description: "Get the files created, modified or deleted in the paths watched with watch_path since the last check, with diffs for small text files.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Only check this watched path. Defaults to every watched path.",
+				},
+			},
+		},
+	}
+	checkChangesTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &checkChangesFunc,
+	}
+	return &checkChangesTool
+}
+
 func getSearchFilesTool() *OpenTool {
 	searchFilesFunc := OpenFunctionDefinition{
 		Name:        ToolSearchFiles,
@@ -1032,6 +1432,72 @@ func getEditFileTool() *OpenTool {
 	return &editFileTool
 }
 
+func getApplyChangesTool() *OpenTool {
+	applyChangesFunc := OpenFunctionDefinition{
+		Name: ToolApplyChanges,
+		Description: "Apply changes across several files as one transaction, instead of a chain of edit_file calls.\n" +
+			"\n" +
+			"Each change either edits an existing file with search-replace 'edits' (same contract as\n" +
+			"edit_file: every search block must appear EXACTLY ONCE) or writes the full 'content' of a\n" +
+			"new or existing file.\n" +
+			"\n" +
+			"ATOMICITY: every change is validated before anything is written, the user confirms one\n" +
+			"combined diff, and if any write fails all files are rolled back. A checkpoint of the\n" +
+			"original files is recorded so the whole change can be undone later.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"changes": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"path": map[string]interface{}{
+								"type":        "string",
+								"description": "The path to the file to change.",
+							},
+							"edits": map[string]interface{}{
+								"type": "array",
+								"items": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"search": map[string]interface{}{
+											"type":        "string",
+											"description": "The exact text to find, must appear EXACTLY ONCE in the file.",
+										},
+										"replace": map[string]interface{}{
+											"type":        "string",
+											"description": "The replacement text. Use empty string to delete the search text.",
+										},
+									},
+									"required": []string{"search", "replace"},
+								},
+								"description": "Search-replace operations on the existing file. Omit when 'content' is given.",
+							},
+							"content": map[string]interface{}{
+								"type":        "string",
+								"description": "The full new content of the file. Omit when 'edits' are given.",
+							},
+						},
+						"required": []string{"path"},
+					},
+					"description": "The file changes to apply together, at most one per path.",
+				},
+				"purpose": map[string]interface{}{
+					"type":        "string",
+					"description": "A terse explanation of the overall change.",
+				},
+			},
+			"required": []string{"changes", "purpose"},
+		},
+	}
+	applyChangesTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &applyChangesFunc,
+	}
+	return &applyChangesTool
+}
+
 func getMoveTool() *OpenTool {
 	moveFunc := OpenFunctionDefinition{
 		Name:        ToolMove,
@@ -1156,6 +1622,7 @@ Use this tool when:
 IMPORTANT: This function is highly powerful.
 Pass 'list' as the name to see all available agents and their capabilities before switching.
 When a switch occurs, if an instruction is provided, it replaces the original prompt for the next agent's execution. This essentially "briefs" the new agent on what to do next, preserving context.
+With return_to_caller, the switch is a round trip: the new agent sees this session, works on the instruction and hands its results back to you. Use it for a step that needs another agent's tools or expertise, when you want to finish the task yourself.
 `,
 		Parameters: map[string]interface{}{
 			"type": "object",
@@ -1168,6 +1635,14 @@ When a switch occurs, if an instruction is provided, it replaces the original pr
 					"type":        "string",
 					"description": "Optional context or instruction to pass to the new agent. This helps the new agent understand the task and current state.",
 				},
+				"share_results": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Pass the results of your latest reads and searches, and the keys of the shared state, to the new agent so it doesn't redo them. Defaults to false.",
+				},
+				"return_to_caller": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Get control back when the new agent is done: it works on the instruction, then calls handoff_back with its results, and you continue with them. Requires an instruction. Defaults to false, a permanent handoff.",
+				},
 			},
 			"required": []string{"name"},
 		},
@@ -1179,6 +1654,29 @@ When a switch occurs, if an instruction is provided, it replaces the original pr
 	return &switchAgentTool
 }
 
+func getHandoffBackTool() *OpenTool {
+	handoffBackFunc := OpenFunctionDefinition{
+		Name: ToolHandoffBack,
+		Description: `Hand control back to the agent that switched to you with return_to_caller, once you have done the instruction it gave you.
+The result is all the caller gets from your work besides the session, so make it complete: what you did, what you found, the files you changed and anything left undone.`,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"result": map[string]interface{}{
+					"type":        "string",
+					"description": "The summary of your results for the calling agent.",
+				},
+			},
+			"required": []string{"result"},
+		},
+	}
+	handoffBackTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &handoffBackFunc,
+	}
+	return &handoffBackTool
+}
+
 // getBuildAgentTool returns the JSON Schema definition for the build_agent tool.
 // Enum arrays constrain both tools and capabilities to the exact valid values
 // present in the gllm registry, eliminating taxonomic drift at the schema level.
@@ -1223,13 +1721,15 @@ Available Embedding Tools (select from these for the 'tools' field):
 - search_files: Find files by regex pattern.
 - search_text_in_file: Grep codebase for strings.
 - web_fetch: Retrieve text from web URLs.
+- get_transcript: Read the transcript of a YouTube video.
 - ask_user: Prompt user for clarification or input.
+- request_approval: Wait for a person to approve an action that is hard to undo, like a deploy or a publication.
 
 Capability details (CRITICAL: Do NOT place these tools in the 'tools' field. Enable the valid capability instead):
 - mcp_servers: enables communication with locally running MCP servers.
-- agent_skills: lightweight, open format for extending AI agent workflows (injects 'activate_skill' tool).
+- agent_skills: lightweight, open format for extending AI agent workflows (injects 'activate_skill', 'run_skill_script' tools).
 - agent_memory: allows agents to remember important facts across sessions (injects 'list_memory', 'save_memory' tools).
-- sub_agents: allow you to create, manage, and orchestrate specialized sub-agents (injects 'list_agent', 'build_agent', 'switch_agent', 'spawn_subagents', 'get_state', 'set_state', 'list_state' tools).
+- sub_agents: allow you to create, manage, and orchestrate specialized sub-agents (injects 'list_agent', 'build_agent', 'switch_agent', 'spawn_subagents', 'map_over_files', 'get_state', 'set_state', 'list_state' tools).
 - web_search: enables the agent to search the web for real-time information (injects 'web_search' tool).
 - token_usage: allows agents to track their token usage.
 - markdown_output: allows agents to generate final response in Markdown format.
@@ -1320,13 +1820,20 @@ The system automatically namespaces it as 'agentName_taskKey' (e.g. 'reviewer_au
 This full namespaced key is what gets written to SharedState and returned in the result summary.
 Always use the FULL 'agentName_taskKey' key when calling get_state or specifying input_keys.
 
+CONCURRENCY:
+Only a limited number of tasks run at once (configured by the user); the others wait for a slot.
+Give a higher 'priority' to tasks whose results matter most.
+
+STRUCTURED OUTPUT:
+Give a task an 'output_schema' when a later task or you will parse its result, e.g. a list of findings.
+
 SESSION PERSISTENCE:
 - Same agent + same task_key → resumes prior subagent session (context restored)
 - Same agent + new  task_key → fresh subagent session, no prior context
 
 Differs from switch_agent:
 - spawn_subagents: Sub-agents return results to you; you maintain control
-- switch_agent: Permanently hands off control; you won't see results`,
+- switch_agent: Hands off control; you won't see results, unless you pass return_to_caller and the agent hands back a summary`,
 		Parameters: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
@@ -1354,6 +1861,14 @@ Differs from switch_agent:
 								},
 								"description": "Optional. Full SharedState keys in 'agentName_taskKey' format (e.g. 'reviewer_auth_review') whose stored content is injected into this sub-agent's prompt as context. Use keys printed in a PREVIOUS spawn_subagents result, or from list_state. All tasks in one call are concurrent — never reference a result from the same batch.",
 							},
+							"priority": map[string]interface{}{
+								"type":        "integer",
+								"description": "Optional. Tasks run under a concurrency limit; when some must wait, tasks of higher priority start first. Default 0.",
+							},
+							"output_schema": map[string]interface{}{
+								"type":        "object",
+								"description": "Optional. A JSON schema the sub-agent's answer must match. The answer is validated and the sub-agent retries with the validation errors; the JSON is stored whole in SharedState instead of a summary, so a later task can take it as typed input through input_keys.",
+							},
 						},
 						"required": []string{"agent_name", "instruction", "task_key"},
 					},
@@ -1370,6 +1885,60 @@ Differs from switch_agent:
 	return &spawnSubAgentsTool
 }
 
+func getMapOverFilesTool() *OpenTool {
+	mapOverFilesFunc := OpenFunctionDefinition{
+		Name: ToolMapOverFiles,
+		Description: `Apply an instruction to every file of a glob with sub-agents, then combine the results (map-reduce).
+Use it for work over many files, like "summarize every file in this directory" or "list the TODOs of each package",
+instead of reading the files one by one or writing the spawn_subagents tasks yourself.
+
+The text files matching the pattern (git-ignored, binary and very large files are left out) are split into shards,
+each given to a sub-agent task with the files' content and the per-file instruction.
+The result of each shard is stored in SharedState under 'agentName_taskKey_mapN'.
+With a reduce_instruction, one more task gets all the shard results and combines them,
+its result is stored under 'reduceAgent_taskKey_reduce' and returned.`,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"pattern": map[string]interface{}{
+					"type":        "string",
+					"description": "Glob of the files, where ** matches any number of directories (e.g. 'src/**/*.go'), or a directory for all its files.",
+				},
+				"instruction": map[string]interface{}{
+					"type":        "string",
+					"description": "What to do with each file, e.g. 'Summarize what this file does in 3 sentences.'",
+				},
+				"agent_name": map[string]interface{}{
+					"type":        "string",
+					"description": "Name of the agent running the map tasks. Use list_agent to see available agents.",
+				},
+				"task_key": map[string]interface{}{
+					"type":        "string",
+					"description": "A short, semantic identifier of the job (e.g. 'pkg_summaries'), the base of the SharedState keys.",
+				},
+				"shards": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of map tasks run concurrently, defaults to 4, at most 16.",
+				},
+				"reduce_instruction": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional. How to combine the results of all files, e.g. 'Write an overview of the package from the file summaries.'",
+				},
+				"reduce_agent": map[string]interface{}{
+					"type":        "string",
+					"description": "Optional. Name of the agent running the reduce task, defaults to agent_name.",
+				},
+			},
+			"required": []string{"pattern", "instruction", "agent_name", "task_key"},
+		},
+	}
+	mapOverFilesTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &mapOverFilesFunc,
+	}
+	return &mapOverFilesTool
+}
+
 func getGetStateTool() *OpenTool {
 	getStateFunc := OpenFunctionDefinition{
 		Name: ToolGetState,
@@ -1525,6 +2094,29 @@ LLM should call:
 	return &webFetchTool
 }
 
+func getTranscriptTool() *OpenTool {
+	transcriptFunc := OpenFunctionDefinition{
+		Name: ToolGetTranscript,
+		Description: `Get the transcript of a YouTube video from its captions, in chunks of about 30 seconds each starting with its [m:ss] timestamp.
+Use it to summarize, quote or answer questions about a video; cite the timestamps so the user can jump to the part.`,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"url": map[string]interface{}{
+					"type":        "string",
+					"description": "The URL of the video, e.g. https://www.youtube.com/watch?v=dQw4w9WgXcQ or https://youtu.be/dQw4w9WgXcQ.",
+				},
+				"language": map[string]interface{}{
+					"type":        "string",
+					"description": "Language code of the captions, e.g. 'en' or 'fr'. Defaults to the language of the video.",
+				},
+			},
+			"required": []string{"url"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &transcriptFunc}
+}
+
 func getActivateSkillTool() *OpenTool {
 	activateSkillFunc := OpenFunctionDefinition{
 		Name: ToolActivateSkill,
@@ -1550,10 +2142,53 @@ ONLY use names exactly as they appear in the <available_skills> section.`,
 	return &activateSkillTool
 }
 
+func getRunSkillScriptTool() *OpenTool {
+	runSkillScriptFunc := OpenFunctionDefinition{
+		Name: ToolRunSkillScript,
+		Description: `Runs a script bundled with a skill, in the skill's directory.
+Only the scripts listed in the <scripts> section of an activated skill can be run, with the arguments they declare.
+The user may be asked to confirm the run.`,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"skill": map[string]interface{}{
+					"type":        "string",
+					"description": "The exact name of the skill (case-insensitive).",
+				},
+				"script": map[string]interface{}{
+					"type":        "string",
+					"description": "The name of the script, as listed by the activated skill.",
+				},
+				"args": map[string]interface{}{
+					"type":                 "object",
+					"description":          "The arguments of the script by name, e.g. {\"query\": \"diffusion models\"}.",
+					"additionalProperties": map[string]interface{}{"type": "string"},
+				},
+				"purpose": map[string]interface{}{
+					"type":        "string",
+					"description": "A brief explanation of why the script is run, shown to the user for confirmation.",
+				},
+				"timeout": map[string]interface{}{
+					"type":        "integer",
+					"description": "Timeout in seconds, defaults to 30.",
+				},
+			},
+			"required": []string{"skill", "script"},
+		},
+	}
+	runSkillScriptTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &runSkillScriptFunc,
+	}
+	return &runSkillScriptTool
+}
+
 func getOpenShellTool() *OpenTool {
 	shellFunc := OpenFunctionDefinition{
 		Name: ToolShell,
 		Description: `Executes a shell command on the user's local machine.
+Commands run in sh on macOS and Linux, and in PowerShell on Windows unless the agent
+configures another shell. The result names the shell, write commands in its syntax.
 
 IMPORTANT: This function is highly powerful and potentially dangerous.
 Always prioritize user safety. Do not execute commands that could delete files (rm),
@@ -1604,27 +2239,138 @@ LLM should call with:
 	return &shellTool
 }
 
-func getAskUserTool() *OpenTool {
-	askUserFunc := OpenFunctionDefinition{
-		Name:        ToolAskUser,
-		Description: "Pause execution and ask the user an interactive question. Use to resolve ambiguity, gather parameters, confirm actions, or present choices. The answer is returned as: a plain string for 'text'; binary choice for 'confirm'; the selected option string for 'select'; an array of selected option strings for 'multiselect'.",
+func getRunTestsTool() *OpenTool {
+	runTestsFunc := OpenFunctionDefinition{
+		Name: ToolRunTests,
+		Description: `Runs the project's test suite and returns a structured summary: the failed tests with their file:line and failure message.
+Passing output is left out, so prefer this over the shell tool when running tests in a fix-the-tests loop.
+
+Supported runners: go (go test), pytest, npm (npm test, jest/vitest output is parsed) and cargo (cargo test).
+The runner is detected from the nearest go.mod, Cargo.toml, package.json or Python project file when not given.
+The command runs code from the project, so the user is asked for confirmation.`,
 		Parameters: map[string]interface{}{
 			"type": "object",
 			"properties": map[string]interface{}{
-				"question": map[string]interface{}{
+				"path": map[string]interface{}{
 					"type":        "string",
-					"description": "The question or prompt text to display to the user.",
+					"description": "Directory to run the tests from. Defaults to the current directory.",
 				},
-				"question_type": map[string]interface{}{
+				"runner": map[string]interface{}{
 					"type":        "string",
-					"enum":        []string{"select", "multiselect", "text", "confirm"},
-					"description": "The interaction mode. 'select'=single choice from options, 'multiselect'=multiple choices from options, 'text'=free-form input, 'confirm'=binary boolean choice (yes/no, true/false, proceed/cancel, enable/disable, etc.).",
+					"enum":        []string{TestRunnerGo, TestRunnerPytest, TestRunnerNpm, TestRunnerCargo},
+					"description": "Test runner to use. Detected from the project files when omitted.",
 				},
-				"options": map[string]interface{}{
-					"type": "array",
-					"items": map[string]interface{}{
-						"type": "string",
-					},
+				"target": map[string]interface{}{
+					"type": "string",
+					"description": "What to test: a Go package pattern (default ./...), a pytest file or directory, a cargo package name, " +
+						"or a test file path passed to npm test.",
+				},
+				"filter": map[string]interface{}{
+					"type":        "string",
+					"description": "Only run tests matching this name pattern (go test -run, pytest -k, cargo test filter, jest/vitest -t).",
+				},
+				"purpose": map[string]interface{}{
+					"type":        "string",
+					"description": "A short explanation of why the tests are being run, shown to the user for confirmation.",
+				},
+				"timeout": map[string]interface{}{
+					"type":        "integer",
+					"description": "Optional timeout in seconds. Default is 300 seconds.",
+					"default":     300,
+				},
+			},
+		},
+	}
+
+	runTestsTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &runTestsFunc,
+	}
+
+	return &runTestsTool
+}
+
+func getLintTool() *OpenTool {
+	lintFunc := OpenFunctionDefinition{
+		Name: ToolLint,
+		Description: `Runs the project's linter on the given files and returns the diagnostics as file:line:col: message lines.
+Linters: go vet for Go (the whole package of each file), ruff for Python, eslint for JavaScript/TypeScript.
+A linter installed in the project's node_modules is preferred over one on PATH. Fixes are never applied.
+Use it after editing files to catch mistakes before running the tests.`,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"paths": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "The files to lint.",
+				},
+			},
+			"required": []string{"paths"},
+		},
+	}
+
+	lintTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &lintFunc,
+	}
+
+	return &lintTool
+}
+
+func getFormatCodeTool() *OpenTool {
+	formatCodeFunc := OpenFunctionDefinition{
+		Name: ToolFormatCode,
+		Description: `Formats the given files in place with the project's formatter and reports which files changed.
+Formatters: goimports (or gofmt) for Go, ruff format for Python, prettier for JavaScript/TypeScript, JSON, CSS, Markdown and YAML.
+A formatter installed in the project's node_modules is preferred over one on PATH.
+The user reviews the formatting diff before files are written. Read a file again before editing it after formatting.`,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"paths": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "The files to format.",
+				},
+				"purpose": map[string]interface{}{
+					"type":        "string",
+					"description": "A short explanation of why the files are being formatted, shown to the user for confirmation.",
+				},
+			},
+			"required": []string{"paths"},
+		},
+	}
+
+	formatCodeTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &formatCodeFunc,
+	}
+
+	return &formatCodeTool
+}
+
+func getAskUserTool() *OpenTool {
+	askUserFunc := OpenFunctionDefinition{
+		Name:        ToolAskUser,
+		Description: "Pause execution and ask the user an interactive question. Use to resolve ambiguity, gather parameters, confirm actions, or present choices, rather than guessing and retrying. The answer is returned as: a plain string for 'text'; binary choice for 'confirm'; the selected option string for 'select'; an array of selected option strings for 'multiselect'. In headless runs no one is asked: go on with your best judgment.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"question": map[string]interface{}{
+					"type":        "string",
+					"description": "The question or prompt text to display to the user.",
+				},
+				"question_type": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"select", "multiselect", "text", "confirm"},
+					"description": "The interaction mode. 'select'=single choice from options, 'multiselect'=multiple choices from options, 'text'=free-form input, 'confirm'=binary boolean choice (yes/no, true/false, proceed/cancel, enable/disable, etc.).",
+				},
+				"options": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "string",
+					},
 					"description": "List of choices. REQUIRED and must be non-empty when question_type is 'select' or 'multiselect' or 'confrim'. Omit for 'text'.",
 				},
 				"placeholder": map[string]interface{}{
@@ -1642,6 +2388,92 @@ func getAskUserTool() *OpenTool {
 	return &askUserTool
 }
 
+func getPresentOptionsTool() *OpenTool {
+	presentOptionsFunc := OpenFunctionDefinition{
+		Name: ToolPresentOptions,
+		Description: `Present alternative approaches, such as refactoring strategies or designs, as a list the user picks one from, each with its trade-offs.
+Use it when there are real alternatives and the choice is the user's, instead of describing them in text and waiting for a reply.
+Returns JSON: {"choice": "<title>", "index": n} for a listed option, {"comment": "..."} when the user describes another approach, or {"cancelled": true}. In headless runs no one is asked: go on with the recommended option.`,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"question": map[string]interface{}{
+					"type":        "string",
+					"description": "The decision to make, e.g. 'Which refactor strategy do you prefer?'.",
+				},
+				"options": map[string]interface{}{
+					"type":        "array",
+					"description": "The alternatives, at least two.",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"title": map[string]interface{}{
+								"type":        "string",
+								"description": "Short distinct name of the approach.",
+							},
+							"summary": map[string]interface{}{
+								"type":        "string",
+								"description": "One or two sentences on what the approach does.",
+							},
+							"pros": map[string]interface{}{
+								"type":  "array",
+								"items": map[string]interface{}{"type": "string"},
+							},
+							"cons": map[string]interface{}{
+								"type":  "array",
+								"items": map[string]interface{}{"type": "string"},
+							},
+						},
+						"required": []string{"title"},
+					},
+				},
+				"recommended": map[string]interface{}{
+					"type":        "string",
+					"description": "Title of the option you recommend, if any.",
+				},
+				"allow_other": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Offer a 'Something else' choice to describe another approach. Defaults to true.",
+				},
+			},
+			"required": []string{"question", "options"},
+		},
+	}
+	presentOptionsTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &presentOptionsFunc,
+	}
+	return &presentOptionsTool
+}
+
+func getRequestApprovalTool() *OpenTool {
+	requestApprovalFunc := OpenFunctionDefinition{
+		Name: ToolRequestApproval,
+		Description: `Pause and wait for a person to approve before an action that is hard to undo, such as a deploy, a release or publishing content.
+Present what will be done and the output it is based on. The person approves, approves with edits to make first, or rejects with a reason; without a terminal the configured approval backend (e.g. a webhook) decides.
+Returns JSON: {"approved": bool, "edits": "...", "feedback": "..."}. Never carry out the action when it is not approved.`,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"action": map[string]interface{}{
+					"type":        "string",
+					"description": "The action waiting for approval, e.g. 'Deploy v1.4.2 to production'.",
+				},
+				"content": map[string]interface{}{
+					"type":        "string",
+					"description": "The output to review, e.g. the draft to publish or the changes to deploy.",
+				},
+			},
+			"required": []string{"action"},
+		},
+	}
+	requestApprovalTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &requestApprovalFunc,
+	}
+	return &requestApprovalTool
+}
+
 func getEnterPlanModeTool() *OpenTool {
 	enterPlanModeFunc := OpenFunctionDefinition{
 		Name: ToolEnterPlanMode,
@@ -1700,28 +2532,203 @@ Use this tool when your planning phase is complete, and you require the reinstat
 	return &exitPlanModeTool
 }
 
+func getTodoWriteTool() *OpenTool {
+	todoWriteFunc := OpenFunctionDefinition{
+		Name: ToolTodoWrite,
+		Description: `Create or update the task list of the current session. The list you pass REPLACES the previous one, so always send every item.
+Use it for multi-step tasks (3 steps or more) to plan the work and show progress to the user:
+- Break the task into concrete steps before starting.
+- Mark exactly one item 'in_progress' while you work on it, and mark it 'completed' as soon as it is done.
+- Add items when you discover new work, and remove items that are no longer relevant.
+Skip it for trivial single-step requests.`,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"todos": map[string]interface{}{
+					"type":        "array",
+					"description": "The complete, ordered task list.",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"id": map[string]interface{}{
+								"type":        "string",
+								"description": "Short stable identifier of the item, e.g. '1' or 'tests'.",
+							},
+							"content": map[string]interface{}{
+								"type":        "string",
+								"description": "What needs to be done, in imperative form.",
+							},
+							"status": map[string]interface{}{
+								"type":        "string",
+								"enum":        []string{data.TodoPending, data.TodoInProgress, data.TodoCompleted},
+								"description": "Current status of the item.",
+							},
+						},
+						"required": []string{"id", "content", "status"},
+					},
+				},
+			},
+			"required": []string{"todos"},
+		},
+	}
+	todoWriteTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &todoWriteFunc,
+	}
+	return &todoWriteTool
+}
+
+func getTodoReadTool() *OpenTool {
+	todoReadFunc := OpenFunctionDefinition{
+		Name:        ToolTodoRead,
+		Description: "Read the task list of the current session with the status of each item. Use it to get back on track after a long detour or when resuming a session.",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+			"required":   []string{},
+		},
+	}
+	todoReadTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &todoReadFunc,
+	}
+	return &todoReadTool
+}
+
+// lspPositionProperties are the parameters locating a symbol for the code intelligence tools
+func lspPositionProperties() map[string]interface{} {
+	return map[string]interface{}{
+		"path": map[string]interface{}{
+			"type":        "string",
+			"description": "The source file containing the symbol.",
+		},
+		"line": map[string]interface{}{
+			"type":        "integer",
+			"description": "The 1-based line where the symbol appears.",
+			"minimum":     1,
+		},
+		"symbol": map[string]interface{}{
+			"type":        "string",
+			"description": "The identifier on that line, e.g. a function or type name. Preferred over column.",
+		},
+		"column": map[string]interface{}{
+			"type":        "integer",
+			"description": "The 1-based column of the symbol, if symbol is not given.",
+			"minimum":     1,
+		},
+	}
+}
+
+func getFindDefinitionTool() *OpenTool {
+	findDefinitionFunc := OpenFunctionDefinition{
+		Name: ToolFindDefinition,
+		Description: "Find where a symbol is defined using the project's language server (gopls, pyright, " +
+			"typescript-language-server, rust-analyzer). More precise than searching text: it follows imports, " +
+			"methods and shadowing. Returns file:line:column with the source line.",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": lspPositionProperties(),
+			"required":   []string{"path", "line"},
+		},
+	}
+	findDefinitionTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &findDefinitionFunc,
+	}
+	return &findDefinitionTool
+}
+
+func getFindReferencesTool() *OpenTool {
+	properties := lspPositionProperties()
+	properties["include_declaration"] = map[string]interface{}{
+		"type":        "boolean",
+		"description": "Whether to list the declaration itself among the references.",
+		"default":     false,
+	}
+	findReferencesFunc := OpenFunctionDefinition{
+		Name: ToolFindReferences,
+		Description: "Find every usage of a symbol across the project using the language server. " +
+			"Use before renaming or changing a signature. Returns file:line:column with the source line.",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   []string{"path", "line"},
+		},
+	}
+	findReferencesTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &findReferencesFunc,
+	}
+	return &findReferencesTool
+}
+
+func getDocumentSymbolsTool() *OpenTool {
+	documentSymbolsFunc := OpenFunctionDefinition{
+		Name: ToolDocumentSymbols,
+		Description: "List the functions, types, methods and other symbols of a source file with their line ranges, " +
+			"using the language server. Use it to get an outline before reading parts of a large file.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "The source file to outline.",
+				},
+			},
+			"required": []string{"path"},
+		},
+	}
+	documentSymbolsTool := OpenTool{
+		Type:     ToolTypeFunction,
+		Function: &documentSymbolsFunc,
+	}
+	return &documentSymbolsTool
+}
+
 // OpenProcessor is the main processor for OpenAI-like models
 // For tools implementation
 // - It manages the context, notifications, data streaming, and tool usage
 // - It handles queries and references, and maintains the status stack
 type OpenProcessor struct {
-	notify      chan<- StreamNotify      // Sub Channel to send notifications
-	data        chan<- StreamData        // Sub Channel to send data
-	proceed     <-chan bool              // Main Channel to receive proceed signal
-	search      *SearchEngine            // Search engine
-	toolsUse    *data.ToolsUse           // Use tools
-	interaction InteractionHandler       // Handle interactive dialogs
-	quiet       bool                     // Whether to suppress console output
-	queries     []string                 // List of queries to be sent to the AI assistant
-	references  []map[string]interface{} // keep track of the references
-	status      *StatusStack             // Stack to manage streaming status
-	mcpClient   *MCPClient               // MCP client for MCP tool calls
-	fileHooks   FileHooks                // lifecycle hooks for file write/edit events
+	ctx            context.Context          // Cancellation context of the current generation
+	notify         chan<- StreamNotify      // Sub Channel to send notifications
+	data           chan<- StreamData        // Sub Channel to send data
+	proceed        <-chan bool              // Main Channel to receive proceed signal
+	search         *SearchEngine            // Search engine
+	toolsUse       *data.ToolsUse           // Use tools
+	interaction    InteractionHandler       // Handle interactive dialogs
+	quiet          bool                     // Whether to suppress console output
+	queries        []string                 // List of queries to be sent to the AI assistant
+	references     []map[string]interface{} // keep track of the references
+	status         *StatusStack             // Stack to manage streaming status
+	mcpClient      *MCPClient               // MCP client for MCP tool calls
+	fileHooks      FileHooks                // lifecycle hooks for file write/edit events
+	autoFormat     bool                     // format files after successful edits
+	shell          string                   // shell of the shell tool, empty detects one
+	network        NetworkSettings          // proxy and TLS settings of the model, also used by web_fetch
+	redactor       *Redactor                // redacts tool results and restores tool arguments, nil when off
+	injectionGuard string                   // handling of prompt injection in web and MCP results
+	pathRules      *PathRules               // paths the file tools may read and write, nil for any
+	invalidCalls   int                      // invalid tool calls in a row, see checkToolCall
+	lastInvalid    string                   // what was wrong with the last invalid tool call
+	loops          toolLoopDetector         // spots repeated and oscillating tool calls
+	handoff        handoffLog               // latest reading tool results, passed on by switch_agent
+	preview        toolCallPreview          // tool call whose arguments are streaming, see previewToolCall
 
 	// Sub-agent orchestration
 	sharedState *data.SharedState // Shared state for inter-agent communication
 	executor    *SubAgentExecutor // Sub-agent executor for spawn_subagents tool
 	agentName   string            // Current agent name (for set_state metadata)
+	sessionName string            // Current session name (for the audit log)
+}
+
+// toolContext returns the cancellation context of the generation, which every tool call
+// runs under so that a user cancel stops it.
+func (op *OpenProcessor) toolContext() context.Context {
+	if op.ctx != nil {
+		return op.ctx
+	}
+	return context.Background()
 }
 
 // Diff confirm func
@@ -1739,3 +2746,496 @@ func (op *OpenProcessor) closeDiff() {
 	// because in terminal, the diff cannot be closed
 	op.status.ChangeTo(op.notify, StreamNotify{Status: StatusShowDiffOver}, op.proceed)
 }
+
+// scmRepoProperties are the parameters locating the repository for the code host tools
+func scmRepoProperties() map[string]interface{} {
+	return map[string]interface{}{
+		"repo": map[string]interface{}{
+			"type":        "string",
+			"description": "The repository as owner/name or a remote URL. Defaults to the origin remote of the working directory.",
+		},
+		"provider": map[string]interface{}{
+			"type":        "string",
+			"enum":        []string{SCMProviderGitHub, SCMProviderGitLab},
+			"description": "The code host. Detected from the repository host when omitted, owner/name defaults to GitHub.",
+		},
+	}
+}
+
+func getGHListIssuesTool() *OpenTool {
+	properties := scmRepoProperties()
+	properties["state"] = map[string]interface{}{
+		"type":        "string",
+		"enum":        []string{"open", "closed", "all"},
+		"description": "Which issues to list. Defaults to open.",
+	}
+	properties["labels"] = map[string]interface{}{
+		"type":        "array",
+		"items":       map[string]interface{}{"type": "string"},
+		"description": "Only list issues with all these labels.",
+	}
+	properties["limit"] = map[string]interface{}{
+		"type":        "integer",
+		"description": "Maximum number of issues, 20 by default and at most 100.",
+	}
+	listIssuesFunc := OpenFunctionDefinition{
+		Name:        ToolGHListIssues,
+		Description: "List the issues of a GitHub or GitLab repository with their number, state, labels, author and comment count. Pull requests are not included.",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   []string{},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &listIssuesFunc}
+}
+
+func getGHGetPRDiffTool() *OpenTool {
+	properties := scmRepoProperties()
+	properties["number"] = map[string]interface{}{
+		"type":        "integer",
+		"description": "The number of the pull request, the IID of the merge request on GitLab.",
+	}
+	prDiffFunc := OpenFunctionDefinition{
+		Name:        ToolGHGetPRDiff,
+		Description: "Get the unified diff of a GitHub pull request or GitLab merge request, to review it. Large diffs are truncated.",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   []string{"number"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &prDiffFunc}
+}
+
+func getGHCommentTool() *OpenTool {
+	properties := scmRepoProperties()
+	properties["number"] = map[string]interface{}{
+		"type":        "integer",
+		"description": "The number of the issue or pull request.",
+	}
+	properties["target"] = map[string]interface{}{
+		"type":        "string",
+		"enum":        []string{"pull_request", "issue"},
+		"description": "Whether the number is a pull request or an issue. Defaults to pull_request.",
+	}
+	properties["body"] = map[string]interface{}{
+		"type":        "string",
+		"description": "The comment in Markdown.",
+	}
+	commentFunc := OpenFunctionDefinition{
+		Name:        ToolGHComment,
+		Description: "Post a comment on a GitHub or GitLab issue or pull request. The user confirms the comment before it is posted.",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   []string{"number", "body"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &commentFunc}
+}
+
+func getGHCreatePRTool() *OpenTool {
+	properties := scmRepoProperties()
+	properties["title"] = map[string]interface{}{
+		"type":        "string",
+		"description": "The title of the pull request.",
+	}
+	properties["body"] = map[string]interface{}{
+		"type":        "string",
+		"description": "The description in Markdown.",
+	}
+	properties["head"] = map[string]interface{}{
+		"type":        "string",
+		"description": "The branch with the changes, already pushed. Defaults to the current branch.",
+	}
+	properties["base"] = map[string]interface{}{
+		"type":        "string",
+		"description": "The branch to merge into. Defaults to the default branch of the repository.",
+	}
+	properties["draft"] = map[string]interface{}{
+		"type":        "boolean",
+		"description": "Open the pull request as a draft.",
+	}
+	createPRFunc := OpenFunctionDefinition{
+		Name:        ToolGHCreatePR,
+		Description: "Open a GitHub pull request or GitLab merge request from a pushed branch. The user confirms it before it is opened.",
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   []string{"title"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &createPRFunc}
+}
+
+// ticketProviderProperty is the parameter choosing the issue tracker of the ticket tools
+func ticketProviderProperty() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "string",
+		"enum":        []string{TicketProviderJira, TicketProviderLinear},
+		"description": "The issue tracker. Defaults to the configured one.",
+	}
+}
+
+func getTicketSearchTool() *OpenTool {
+	searchFunc := OpenFunctionDefinition{
+		Name:        ToolTicketSearch,
+		Description: "Search the tickets of the Jira or Linear issue tracker by text, most recently updated first, with their key, status, assignee and labels.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Text to find in the title or description. Omit to list the latest tickets.",
+				},
+				"project": map[string]interface{}{
+					"type":        "string",
+					"description": "The Jira project or Linear team key, e.g. ENG. Defaults to the configured project.",
+				},
+				"include_done": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Include done and cancelled tickets.",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of tickets, 20 by default and at most 100.",
+				},
+				"tracker": ticketProviderProperty(),
+			},
+			"required": []string{},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &searchFunc}
+}
+
+func getTicketGetTool() *OpenTool {
+	getFunc := OpenFunctionDefinition{
+		Name:        ToolTicketGet,
+		Description: "Read a Jira or Linear ticket with its description, comments and the statuses it can move to.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"key": map[string]interface{}{
+					"type":        "string",
+					"description": "The ticket key, e.g. ENG-123.",
+				},
+				"tracker": ticketProviderProperty(),
+			},
+			"required": []string{"key"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &getFunc}
+}
+
+func getTicketCreateTool() *OpenTool {
+	createFunc := OpenFunctionDefinition{
+		Name:        ToolTicketCreate,
+		Description: "Create a Jira or Linear ticket, to turn a conversation into tracked work. The user confirms it before it is created.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"title": map[string]interface{}{
+					"type":        "string",
+					"description": "The title of the ticket.",
+				},
+				"description": map[string]interface{}{
+					"type":        "string",
+					"description": "The description, with the context and acceptance criteria.",
+				},
+				"project": map[string]interface{}{
+					"type":        "string",
+					"description": "The Jira project or Linear team key. Defaults to the configured project.",
+				},
+				"type": map[string]interface{}{
+					"type":        "string",
+					"description": "The Jira issue type, e.g. Bug or Story. Defaults to Task, ignored on Linear.",
+				},
+				"labels": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Jira labels of the ticket.",
+				},
+				"tracker": ticketProviderProperty(),
+			},
+			"required": []string{"title"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &createFunc}
+}
+
+func getTicketTransitionTool() *OpenTool {
+	transitionFunc := OpenFunctionDefinition{
+		Name:        ToolTicketTransition,
+		Description: "Move a Jira or Linear ticket to another status, e.g. In Progress or Done. ticket_get lists the statuses it can move to. The user confirms the change.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"key": map[string]interface{}{
+					"type":        "string",
+					"description": "The ticket key, e.g. ENG-123.",
+				},
+				"status": map[string]interface{}{
+					"type":        "string",
+					"description": "The status to move to, or the name of the Jira transition.",
+				},
+				"tracker": ticketProviderProperty(),
+			},
+			"required": []string{"key", "status"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &transitionFunc}
+}
+
+func getQueryDatabaseTool() *OpenTool {
+	queryFunc := OpenFunctionDefinition{
+		Name: ToolQueryDatabase,
+		Description: `Run a SQL query on a SQLite, Postgres or MySQL database configured for this project and get the rows as a markdown table with the row count.
+Databases are read only: only single SELECT, WITH, SHOW, EXPLAIN or DESCRIBE statements run, unless the user allowed writes for the database, and each write is confirmed by the user.
+Select the columns you need and aggregate in SQL rather than reading many rows.`,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "The SQL statement, in the dialect of the database.",
+				},
+				"database": map[string]interface{}{
+					"type":        "string",
+					"description": "Name of the database, needed when several are configured.",
+				},
+				"max_rows": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of rows to show, 50 by default and at most 500. The total is still counted.",
+				},
+			},
+			"required": []string{"query"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &queryFunc}
+}
+
+func getInspectDataTool() *OpenTool {
+	inspectFunc := OpenFunctionDefinition{
+		Name:        ToolInspectData,
+		Description: "Describe a CSV, TSV, JSON, JSON lines or Parquet data file without reading it whole: its row count, the type, non-null count, distinct values, min, max and mean of each column, and its first rows. Use it before query_data, and instead of read_file for data files.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":        "string",
+					"description": "Path of the data file.",
+				},
+				"rows": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of first rows to show, 5 by default and at most 50.",
+				},
+			},
+			"required": []string{"path"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &inspectFunc}
+}
+
+func getQueryDataTool() *OpenTool {
+	queryFunc := OpenFunctionDefinition{
+		Name: ToolQueryData,
+		Description: `Run a SQLite SELECT query over local CSV, TSV, JSON, JSON lines or Parquet files and get the result as a markdown table with the row count.
+Each file is loaded as a table named after the file without its extension, other characters than letters, digits and underscores replaced by _, e.g. data/sales-2024.csv is the table sales_2024. Quote column names with spaces in double quotes.
+Filter, join and aggregate in SQL so that only the answer is returned, not the data.`,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"files": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Paths of the data files the query reads.",
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "The SQLite SELECT or WITH statement.",
+				},
+				"max_rows": map[string]interface{}{
+					"type":        "integer",
+					"description": "Maximum number of rows to show, 50 by default and at most 500. The total is still counted.",
+				},
+			},
+			"required": []string{"files", "query"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &queryFunc}
+}
+
+// dockerTimeoutProperty is the timeout parameter of the Docker tools that run commands
+func dockerTimeoutProperty() map[string]interface{} {
+	return map[string]interface{}{
+		"type":        "integer",
+		"description": "Seconds to wait for the command, 120 by default and at most 1800.",
+	}
+}
+
+func getDockerPSTool() *OpenTool {
+	psFunc := OpenFunctionDefinition{
+		Name:        ToolDockerPS,
+		Description: "List the Docker containers with their ID, name, image, state, status and published ports.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"all": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Include stopped containers.",
+				},
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Only list the containers whose name contains this text.",
+				},
+			},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &psFunc}
+}
+
+func getDockerLogsTool() *OpenTool {
+	logsFunc := OpenFunctionDefinition{
+		Name:        ToolDockerLogs,
+		Description: "Read the last lines of the logs of a Docker container, stdout and stderr, with timestamps.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"container": map[string]interface{}{
+					"type":        "string",
+					"description": "Name or ID of the container.",
+				},
+				"lines": map[string]interface{}{
+					"type":        "integer",
+					"description": "Number of last lines, 200 by default and at most 2000.",
+				},
+				"since": map[string]interface{}{
+					"type":        "string",
+					"description": "Only logs since a duration ago, e.g. 10m or 2h, or an RFC 3339 timestamp.",
+				},
+			},
+			"required": []string{"container"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &logsFunc}
+}
+
+func getDockerRunTool() *OpenTool {
+	runFunc := OpenFunctionDefinition{
+		Name:        ToolDockerRun,
+		Description: "Run a Docker container from an image, pulled when missing. By default waits for it to exit, returns its exit code and output and removes it; with detach it is left running. The user confirms it before it runs.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"image": map[string]interface{}{
+					"type":        "string",
+					"description": "The image, e.g. python:3.12-slim.",
+				},
+				"command": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "The command and its arguments, e.g. [\"python\", \"-c\", \"print(1)\"]. Omit to run the command of the image.",
+				},
+				"env": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "Environment variables as KEY=value.",
+				},
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Name of the container.",
+				},
+				"workdir": map[string]interface{}{
+					"type":        "string",
+					"description": "Working directory in the container.",
+				},
+				"detach": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Leave the container running in the background, e.g. a service.",
+				},
+				"timeout": dockerTimeoutProperty(),
+			},
+			"required": []string{"image"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &runFunc}
+}
+
+func getDockerExecTool() *OpenTool {
+	execFunc := OpenFunctionDefinition{
+		Name:        ToolDockerExec,
+		Description: "Run a command in a running Docker container and get its exit code and output. The user confirms it before it runs.",
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"container": map[string]interface{}{
+					"type":        "string",
+					"description": "Name or ID of the container.",
+				},
+				"command": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "The command and its arguments, e.g. [\"sh\", \"-c\", \"ls /app\"].",
+				},
+				"workdir": map[string]interface{}{
+					"type":        "string",
+					"description": "Working directory in the container.",
+				},
+				"user": map[string]interface{}{
+					"type":        "string",
+					"description": "User to run the command as.",
+				},
+				"timeout": dockerTimeoutProperty(),
+			},
+			"required": []string{"container", "command"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &execFunc}
+}
+
+func getHTTPRequestTool() *OpenTool {
+	requestFunc := OpenFunctionDefinition{
+		Name: ToolHTTPRequest,
+		Description: `Send an HTTP request to an API and get the status, main headers and body, JSON pretty-printed. Prefer it to curl in the shell.
+Credentials come from the auth profiles the user configured, pass the name of one as profile; a profile only works for URLs under its base URL. Never put tokens in headers yourself.
+Requests other than GET, HEAD and OPTIONS are confirmed by the user. Use web_fetch to read web pages.`,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"method": map[string]interface{}{
+					"type":        "string",
+					"description": "The HTTP method, GET by default.",
+					"enum":        []string{"GET", "HEAD", "OPTIONS", "POST", "PUT", "PATCH", "DELETE"},
+				},
+				"url": map[string]interface{}{
+					"type":        "string",
+					"description": "The http:// or https:// URL, with its query string.",
+				},
+				"headers": map[string]interface{}{
+					"type":                 "object",
+					"additionalProperties": map[string]interface{}{"type": "string"},
+					"description":          "Request headers, e.g. {\"Accept\": \"application/json\"}.",
+				},
+				"body": map[string]interface{}{
+					"type":        "string",
+					"description": "The request body. A JSON body is sent as application/json unless Content-Type is set.",
+				},
+				"profile": map[string]interface{}{
+					"type":        "string",
+					"description": "Name of the auth profile whose credential is sent.",
+				},
+				"timeout": map[string]interface{}{
+					"type":        "integer",
+					"description": "Seconds to wait for the response, 30 by default and at most 300.",
+				},
+				"max_bytes": map[string]interface{}{
+					"type":        "integer",
+					"description": "Bytes of the body to return, 100000 by default and at most 1048576.",
+				},
+			},
+			"required": []string{"url"},
+		},
+	}
+	return &OpenTool{Type: ToolTypeFunction, Function: &requestFunc}
+}
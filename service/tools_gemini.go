@@ -1,12 +1,13 @@
 package service
 
 import (
+	"context"
 	"fmt"
 
 	"google.golang.org/genai"
 )
 
-func (op *OpenProcessor) geminiMCPToolCall(call *genai.FunctionCall, a *map[string]interface{}) (*genai.FunctionResponse, error) {
+func (op *OpenProcessor) geminiMCPToolCall(ctx context.Context, call *genai.FunctionCall, a *map[string]interface{}) (*genai.FunctionResponse, error) {
 	resp := genai.FunctionResponse{
 		ID:   call.ID,
 		Name: call.Name,
@@ -32,7 +33,7 @@ func (op *OpenProcessor) geminiMCPToolCall(call *genai.FunctionCall, a *map[stri
 	}
 
 	// Call the MCP tool
-	result, err := op.mcpClient.CallTool(call.Name, *a)
+	result, err := op.mcpClient.CallTool(ctx, call.Name, *a)
 	if err != nil {
 		error := fmt.Sprintf("Error: MCP tool call failed: %v", err)
 		resp.Response = map[string]any{
@@ -64,14 +65,14 @@ func (op *OpenProcessor) geminiMCPToolCall(call *genai.FunctionCall, a *map[stri
 	return &resp, nil
 }
 
-func (op *OpenProcessor) geminiSwitchAgentToolCall(call *genai.FunctionCall, a *map[string]interface{}) (*genai.FunctionResponse, error) {
+func (op *OpenProcessor) geminiSwitchAgentToolCall(ctx context.Context, call *genai.FunctionCall, a *map[string]interface{}) (*genai.FunctionResponse, error) {
 	resp := genai.FunctionResponse{
 		ID:   call.ID,
 		Name: call.Name,
 	}
 
 	// Call shared implementation
-	response, err := switchAgentToolCallImpl(a, op)
+	response, err := agentSwitchToolCallImpl(ctx, call.Name, a, op)
 	error := ""
 	if err != nil {
 		if IsSwitchAgentError(err) {
@@ -91,6 +92,17 @@ func (op *OpenProcessor) geminiSwitchAgentToolCall(call *genai.FunctionCall, a *
 	return &resp, err
 }
 
+// mapGeminiToolResult rewrites the output of a Gemini function response with f.
+func mapGeminiToolResult(resp *genai.FunctionResponse, f func(string) string) *genai.FunctionResponse {
+	if resp == nil {
+		return resp
+	}
+	if output, ok := resp.Response["output"].(string); ok {
+		resp.Response["output"] = f(output)
+	}
+	return resp
+}
+
 // runGeminiTool wraps a (string, error) result into a genai.FunctionResponse.
 // IMPORTANT: When only the error field is set with an empty output, the Gemini API
 // model often hangs or returns empty responses. We always ensure output is set.
@@ -115,64 +127,143 @@ func runGeminiTool(call *genai.FunctionCall, fn ToolFunc) (*genai.FunctionRespon
 
 // dispatchGeminiToolCall handles the routing of Gemini tool calls to the correct implementation.
 func (op *OpenProcessor) dispatchGeminiToolCall(call *genai.FunctionCall, a *map[string]interface{}) (*genai.FunctionResponse, error) {
+	ctx := op.toolContext()
 	switch call.Name {
 	case ToolShell:
-		return runGeminiTool(call, func() (string, error) { return shellToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return shellToolCallImpl(ctx, a, op) })
+	case ToolRunTests:
+		return runGeminiTool(call, func() (string, error) { return runTestsToolCallImpl(ctx, a, op) })
 	case ToolReadFile:
-		return runGeminiTool(call, func() (string, error) { return readFileToolCallImpl(a) })
+		return runGeminiTool(call, func() (string, error) { return readFileToolCallImpl(ctx, a) })
 	case ToolWriteFile:
-		return runGeminiTool(call, func() (string, error) { return writeFileToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return writeFileToolCallImpl(ctx, a, op) })
 	case ToolCreateDirectory:
-		return runGeminiTool(call, func() (string, error) { return createDirectoryToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return createDirectoryToolCallImpl(ctx, a, op) })
 	case ToolListDirectory:
-		return runGeminiTool(call, func() (string, error) { return listDirectoryToolCallImpl(a) })
+		return runGeminiTool(call, func() (string, error) { return listDirectoryToolCallImpl(ctx, a) })
 	case ToolDeleteFile:
-		return runGeminiTool(call, func() (string, error) { return deleteFileToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return deleteFileToolCallImpl(ctx, a, op) })
 	case ToolDeleteDirectory:
-		return runGeminiTool(call, func() (string, error) { return deleteDirectoryToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return deleteDirectoryToolCallImpl(ctx, a, op) })
+	case ToolRestoreDeleted:
+		return runGeminiTool(call, func() (string, error) { return restoreDeletedToolCallImpl(ctx, a, op) })
+	case ToolWatchPath:
+		return runGeminiTool(call, func() (string, error) { return watchPathToolCallImpl(ctx, a, op) })
+	case ToolCheckChanges:
+		return runGeminiTool(call, func() (string, error) { return checkChangesToolCallImpl(ctx, a, op) })
+	case ToolGetEnvironment:
+		return runGeminiTool(call, func() (string, error) { return getEnvironmentToolCallImpl(ctx, a, op) })
+	case ToolGetTime:
+		return runGeminiTool(call, func() (string, error) { return getTimeToolCallImpl(ctx, a) })
 	case ToolMove:
-		return runGeminiTool(call, func() (string, error) { return moveToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return moveToolCallImpl(ctx, a, op) })
 	case ToolCopy:
-		return runGeminiTool(call, func() (string, error) { return copyToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return copyToolCallImpl(ctx, a, op) })
 	case ToolSearchFiles:
-		return runGeminiTool(call, func() (string, error) { return searchFilesToolCallImpl(a) })
+		return runGeminiTool(call, func() (string, error) { return searchFilesToolCallImpl(ctx, a) })
 	case ToolSearchTextInFile:
-		return runGeminiTool(call, func() (string, error) { return searchTextInFileToolCallImpl(a) })
+		return runGeminiTool(call, func() (string, error) { return searchTextInFileToolCallImpl(ctx, a) })
 	case ToolReadMultipleFiles:
-		return runGeminiTool(call, func() (string, error) { return readMultipleFilesToolCallImpl(a) })
+		return runGeminiTool(call, func() (string, error) { return readMultipleFilesToolCallImpl(ctx, a) })
+	case ToolReadSymbol:
+		return runGeminiTool(call, func() (string, error) { return readSymbolToolCallImpl(ctx, a) })
+	case ToolLint:
+		return runGeminiTool(call, func() (string, error) { return lintToolCallImpl(ctx, a, op) })
+	case ToolFormatCode:
+		return runGeminiTool(call, func() (string, error) { return formatCodeToolCallImpl(ctx, a, op) })
 	case ToolWebFetch:
-		return runGeminiTool(call, func() (string, error) { return webFetchToolCallImpl(a) })
+		return runGeminiTool(call, func() (string, error) { return webFetchToolCallImpl(ctx, a, op) })
+	case ToolGetTranscript:
+		return runGeminiTool(call, func() (string, error) { return getTranscriptToolCallImpl(ctx, a, op) })
 	case ToolEditFile:
-		return runGeminiTool(call, func() (string, error) { return editFileToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return editFileToolCallImpl(ctx, a, op) })
+	case ToolApplyChanges:
+		return runGeminiTool(call, func() (string, error) { return applyChangesToolCallImpl(ctx, a, op) })
 	case ToolListMemory:
-		return runGeminiTool(call, func() (string, error) { return listMemoryToolCallImpl() })
+		return runGeminiTool(call, func() (string, error) { return listMemoryToolCallImpl(ctx) })
 	case ToolSaveMemory:
-		return runGeminiTool(call, func() (string, error) { return saveMemoryToolCallImpl(a) })
+		return runGeminiTool(call, func() (string, error) { return saveMemoryToolCallImpl(ctx, a) })
 	case ToolListAgent:
-		return runGeminiTool(call, func() (string, error) { return listAgentToolCallImpl() })
+		return runGeminiTool(call, func() (string, error) { return listAgentToolCallImpl(ctx) })
 	case ToolSpawnSubAgents:
-		return runGeminiTool(call, func() (string, error) { return spawnSubAgentsToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return spawnSubAgentsToolCallImpl(ctx, a, op) })
+	case ToolMapOverFiles:
+		return runGeminiTool(call, func() (string, error) { return mapOverFilesToolCallImpl(ctx, a, op) })
 	case ToolGetState:
-		return runGeminiTool(call, func() (string, error) { return getStateToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return getStateToolCallImpl(ctx, a, op) })
 	case ToolSetState:
-		return runGeminiTool(call, func() (string, error) { return setStateToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return setStateToolCallImpl(ctx, a, op) })
 	case ToolListState:
-		return runGeminiTool(call, func() (string, error) { return listStateToolCallImpl(op) })
+		return runGeminiTool(call, func() (string, error) { return listStateToolCallImpl(ctx, op) })
 	case ToolActivateSkill:
-		return runGeminiTool(call, func() (string, error) { return activateSkillToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return activateSkillToolCallImpl(ctx, a, op) })
+	case ToolRunSkillScript:
+		return runGeminiTool(call, func() (string, error) { return runSkillScriptToolCallImpl(ctx, a, op) })
+	case ToolTodoWrite:
+		return runGeminiTool(call, func() (string, error) { return todoWriteToolCallImpl(ctx, a, op) })
+	case ToolTodoRead:
+		return runGeminiTool(call, func() (string, error) { return todoReadToolCallImpl(ctx, a, op) })
+	case ToolFindDefinition:
+		return runGeminiTool(call, func() (string, error) { return findDefinitionToolCallImpl(ctx, a, op) })
+	case ToolFindReferences:
+		return runGeminiTool(call, func() (string, error) { return findReferencesToolCallImpl(ctx, a, op) })
+	case ToolDocumentSymbols:
+		return runGeminiTool(call, func() (string, error) { return documentSymbolsToolCallImpl(ctx, a, op) })
+	case ToolGHListIssues:
+		return runGeminiTool(call, func() (string, error) { return ghListIssuesToolCallImpl(ctx, a, op) })
+	case ToolGHGetPRDiff:
+		return runGeminiTool(call, func() (string, error) { return ghGetPRDiffToolCallImpl(ctx, a, op) })
+	case ToolGHComment:
+		return runGeminiTool(call, func() (string, error) { return ghCommentToolCallImpl(ctx, a, op) })
+	case ToolGHCreatePR:
+		return runGeminiTool(call, func() (string, error) { return ghCreatePRToolCallImpl(ctx, a, op) })
+	case ToolTicketSearch:
+		return runGeminiTool(call, func() (string, error) { return ticketSearchToolCallImpl(ctx, a, op) })
+	case ToolTicketGet:
+		return runGeminiTool(call, func() (string, error) { return ticketGetToolCallImpl(ctx, a, op) })
+	case ToolTicketCreate:
+		return runGeminiTool(call, func() (string, error) { return ticketCreateToolCallImpl(ctx, a, op) })
+	case ToolTicketTransition:
+		return runGeminiTool(call, func() (string, error) { return ticketTransitionToolCallImpl(ctx, a, op) })
+	case ToolQueryDatabase:
+		return runGeminiTool(call, func() (string, error) { return queryDatabaseToolCallImpl(ctx, a, op) })
+	case ToolInspectData:
+		return runGeminiTool(call, func() (string, error) { return inspectDataToolCallImpl(ctx, a) })
+	case ToolQueryData:
+		return runGeminiTool(call, func() (string, error) { return queryDataToolCallImpl(ctx, a, op) })
+	case ToolDockerPS:
+		return runGeminiTool(call, func() (string, error) { return dockerPSToolCallImpl(ctx, a, op) })
+	case ToolDockerLogs:
+		return runGeminiTool(call, func() (string, error) { return dockerLogsToolCallImpl(ctx, a, op) })
+	case ToolDockerRun:
+		return runGeminiTool(call, func() (string, error) { return dockerRunToolCallImpl(ctx, a, op) })
+	case ToolDockerExec:
+		return runGeminiTool(call, func() (string, error) { return dockerExecToolCallImpl(ctx, a, op) })
+	case ToolHTTPRequest:
+		return runGeminiTool(call, func() (string, error) { return httpRequestToolCallImpl(ctx, a, op) })
 	case ToolAskUser:
-		return runGeminiTool(call, func() (string, error) { return askUserToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return askUserToolCallImpl(ctx, a, op) })
+	case ToolPresentOptions:
+		return runGeminiTool(call, func() (string, error) { return presentOptionsToolCallImpl(ctx, a, op) })
+	case ToolRequestApproval:
+		return runGeminiTool(call, func() (string, error) { return requestApprovalToolCallImpl(ctx, a, op) })
 	case ToolExitPlanMode:
-		return runGeminiTool(call, func() (string, error) { return exitPlanModeToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return exitPlanModeToolCallImpl(ctx, a, op) })
 	case ToolEnterPlanMode:
-		return runGeminiTool(call, func() (string, error) { return enterPlanModeToolCallImpl(a, op) })
+		return runGeminiTool(call, func() (string, error) { return enterPlanModeToolCallImpl(ctx, a, op) })
 	case ToolBuildAgent:
-		return runGeminiTool(call, func() (string, error) { return buildAgentToolCallImpl(a, op) })
-	case ToolSwitchAgent:
-		return op.geminiSwitchAgentToolCall(call, a)
+		return runGeminiTool(call, func() (string, error) { return buildAgentToolCallImpl(ctx, a, op) })
+	case ToolSwitchAgent, ToolHandoffBack:
+		return op.geminiSwitchAgentToolCall(ctx, call, a)
 	default:
+		if AvailableAPITool(call.Name) {
+			return runGeminiTool(call, func() (string, error) { return apiToolCallImpl(ctx, call.Name, a, op) })
+		}
+		if AvailablePluginTool(call.Name) {
+			return runGeminiTool(call, func() (string, error) { return pluginToolCallImpl(ctx, call.Name, a, op) })
+		}
 		if op.mcpClient != nil && op.mcpClient.FindTool(call.Name) != nil {
-			return op.geminiMCPToolCall(call, a)
+			return op.geminiMCPToolCall(ctx, call, a)
 		}
 		// Unknown function
 		resp := &genai.FunctionResponse{
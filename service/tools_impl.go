@@ -2,6 +2,7 @@ package service
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -23,7 +24,7 @@ const (
 // Shared implementation functions that work with map[string]interface{} arguments
 // These functions contain the actual logic that can be shared between OpenAI and OpenChat
 
-func processFileContentRange(path string, content []byte, includeLineNumbers bool, offset int, limit int) string {
+func processFileContentRange(path string, content []byte, includeLineNumbers bool, offset int, limit int, maxTokens int) string {
 	lines := strings.Split(string(content), "\n")
 	totalLines := len(lines)
 
@@ -45,11 +46,13 @@ func processFileContentRange(path string, content []byte, includeLineNumbers boo
 		end = offset + limit
 	}
 
-	selectedLines := lines[offset:end]
+	// Stop early once the token cap is reached
+	selectedLines, capped, cut := capLinesToTokens(lines[offset:end], maxTokens)
+	end = offset + len(selectedLines)
 
 	var response string
 	// Build response header
-	if offset == 0 && (limit <= 0 || limit >= totalLines) {
+	if offset == 0 && end == totalLines && !cut {
 		// Full file reading
 		if includeLineNumbers {
 			response = fmt.Sprintf("Content of %s (%d lines, with line numbers):\n", path, totalLines)
@@ -57,11 +60,7 @@ func processFileContentRange(path string, content []byte, includeLineNumbers boo
 			response = fmt.Sprintf("Content of %s (%d lines):\n", path, totalLines)
 		}
 	} else {
-		if limit > 0 {
-			response = fmt.Sprintf("Content of %s (lines %d-%d of %d):\n", path, offset+1, end, totalLines)
-		} else {
-			response = fmt.Sprintf("Content of %s (from line %d of %d):\n", path, offset+1, totalLines)
-		}
+		response = fmt.Sprintf("Content of %s (lines %d-%d of %d):\n", path, offset+1, end, totalLines)
 	}
 
 	if includeLineNumbers {
@@ -74,10 +73,50 @@ func processFileContentRange(path string, content []byte, includeLineNumbers boo
 		response += strings.Join(selectedLines, "\n")
 	}
 
+	// Tell the model how to page through the rest
+	if cut {
+		response += fmt.Sprintf("\n[Truncated: line %d is longer than the %d token limit per read and was cut, use shell commands to inspect it]",
+			end, maxTokens)
+		if end < totalLines {
+			response += fmt.Sprintf("\n[%d more lines, continue with start_line=%d]", totalLines-end, end+1)
+		}
+	} else if capped {
+		response += fmt.Sprintf("\n[Truncated: output reached the %d token limit per read. %d more lines, continue with start_line=%d]",
+			maxTokens, totalLines-end, end+1)
+	} else if end < totalLines {
+		response += fmt.Sprintf("\n[%d more lines, continue with start_line=%d]", totalLines-end, end+1)
+	}
+
 	return response
 }
 
-func readFileToolCallImpl(argsMap *map[string]interface{}) (string, error) {
+// capLinesToTokens keeps leading lines while they fit in maxTokens, and reports whether any were dropped.
+// A first line that is too long on its own is cut to fit.
+func capLinesToTokens(lines []string, maxTokens int) (kept []string, capped bool, cut bool) {
+	if maxTokens <= 0 || len(lines) == 0 {
+		return lines, false, false
+	}
+
+	// Estimate once from a sample, per-line estimation is too slow for large files
+	sample := strings.Join(lines[:min(len(lines), 200)], "\n")
+	budget := int(float64(maxTokens) * detectCharsPerToken(sample))
+
+	used := 0
+	for i, line := range lines {
+		used += len(line) + 1
+		if used <= budget {
+			continue
+		}
+		if i == 0 {
+			runes := []rune(line)
+			return []string{string(runes[:min(len(runes), budget)]) + "…"}, true, true
+		}
+		return lines[:i], true, false
+	}
+	return lines, false, false
+}
+
+func readFileToolCallImpl(ctx context.Context, argsMap *map[string]interface{}) (string, error) {
 	if err := CheckToolPermission(ToolReadFile, argsMap); err != nil {
 		return "", err
 	}
@@ -110,31 +149,55 @@ func readFileToolCallImpl(argsMap *map[string]interface{}) (string, error) {
 	if err != nil {
 		return fmt.Sprintf("Error reading file %s: %v", path, err), nil
 	}
+	rememberFileContent(path, content)
 
-	// Parse optional offset and limit parameters
+	// Parse optional range parameters, start_line/offset are 1-indexed
 	offset := 0
 	limit := -1 // -1 means read all lines
 
-	if offsetVal, exists := (*argsMap)["offset"]; exists {
-		offset = int(toInt64(offsetVal))
-		if offset > 0 {
-			offset-- // Convert from 1-indexed to 0-indexed
+	for _, paramName := range []string{"start_line", "offset"} {
+		if offsetVal, exists := (*argsMap)[paramName]; exists {
+			offset = int(toInt64(offsetVal))
+			if offset > 0 {
+				offset-- // Convert from 1-indexed to 0-indexed
+			}
+			break
 		}
 	}
 
-	// Support both 'limit' and 'lines' parameter names (learned from model behavior)
-	for _, paramName := range []string{"limit", "lines"} {
+	// end_line is inclusive
+	if endVal, exists := (*argsMap)["end_line"]; exists {
+		endLine := int(toInt64(endVal))
+		if endLine <= offset {
+			return fmt.Sprintf("Error: end_line %d is before start_line %d", endLine, offset+1), nil
+		}
+		limit = endLine - offset
+	}
+
+	// Support 'max_lines', 'limit' and 'lines' parameter names (learned from model behavior)
+	for _, paramName := range []string{"max_lines", "limit", "lines"} {
 		if limitVal, exists := (*argsMap)[paramName]; exists {
-			limit = int(toInt64(limitVal))
+			if n := int(toInt64(limitVal)); n > 0 && (limit <= 0 || n < limit) {
+				limit = n
+			}
 			break // Use first found parameter
 		}
 	}
 
-	response := processFileContentRange(path, content, includeLineNumbers, offset, limit)
+	// Binary files get a description instead of mangled text, other encodings are transcoded
+	text, encoding, ok := DecodeText(content)
+	if !ok {
+		return DescribeBinaryFile(path, content), nil
+	}
+
+	response := processFileContentRange(path, []byte(text), includeLineNumbers, offset, limit, data.GetSettingsStore().GetReadMaxTokens())
+	if encoding != EncodingUTF8 {
+		response = fmt.Sprintf("[Decoded from %s, files written back by the tools are saved as UTF-8]\n", encoding) + response
+	}
 	return response, nil
 }
 
-func writeFileToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func writeFileToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolWriteFile, argsMap); err != nil {
 		return "", err
 	}
@@ -150,6 +213,11 @@ func writeFileToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (
 		return "", fmt.Errorf("content not found in arguments")
 	}
 
+	// Don't overwrite changes made since the model read the file
+	if fileChangedOnDisk(path) {
+		return fmt.Sprintf(ToolRespFileChangedOnDisk, path), nil
+	}
+
 	if !op.toolsUse.AutoApprove {
 		// Check if file exists and read current content
 		var currentContent string
@@ -158,6 +226,8 @@ func writeFileToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (
 			currentData, err := os.ReadFile(path)
 			if err == nil {
 				currentContent = string(currentData)
+				// The user approves the diff against this content
+				rememberFileContent(path, currentData)
 			}
 		}
 
@@ -189,23 +259,26 @@ func writeFileToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (
 		return fmt.Sprintf("Error creating directory for %s: %v", path, err), nil
 	}
 
-	// Determine file permissions
-	mode := os.FileMode(0644)
-	if info, err := os.Stat(path); err == nil {
-		mode = info.Mode()
+	// The file may have been edited while the user was reviewing the diff
+	if fileChangedOnDisk(path) {
+		op.fileHooks.RejectDiff(path)
+		return fmt.Sprintf(ToolRespFileChangedOnDisk, path), nil
 	}
 
 	// Write the file
-	err := os.WriteFile(path, []byte(content), mode)
-	if err != nil {
+	if err := writeFileAtomic(path, []byte(content)); err != nil {
 		op.fileHooks.RejectDiff(path)
 		return fmt.Sprintf("Error writing file %s: %v", path, err), nil
 	}
+	rememberFileContent(path, []byte(content))
 	op.fileHooks.AcceptDiff(path)
+	if note := op.autoFormatFile(ctx, path); note != "" {
+		return fmt.Sprintf("Successfully wrote to file %s\n%s", path, note), nil
+	}
 	return fmt.Sprintf("Successfully wrote to file %s", path), nil
 }
 
-func createDirectoryToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func createDirectoryToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolCreateDirectory, argsMap); err != nil {
 		return "", err
 	}
@@ -240,7 +313,7 @@ func createDirectoryToolCallImpl(argsMap *map[string]interface{}, op *OpenProces
 	return fmt.Sprintf("Successfully created directory %s", path), nil
 }
 
-func listDirectoryToolCallImpl(argsMap *map[string]interface{}) (string, error) {
+func listDirectoryToolCallImpl(ctx context.Context, argsMap *map[string]interface{}) (string, error) {
 	if err := CheckToolPermission(ToolListDirectory, argsMap); err != nil {
 		return "", err
 	}
@@ -294,7 +367,7 @@ func listDirectoryToolCallImpl(argsMap *map[string]interface{}) (string, error)
 	return result.String(), nil
 }
 
-func deleteFileToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func deleteFileToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolDeleteFile, argsMap); err != nil {
 		return "", err
 	}
@@ -321,16 +394,22 @@ func deleteFileToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor)
 		}
 	}
 
-	// Delete the file
-	err := os.Remove(path)
-	if err != nil {
-		return fmt.Sprintf("Error deleting file %s: %v", path, err), nil
+	if data.GetSettingsStore().GetHardDelete() {
+		err := os.Remove(path)
+		if err != nil {
+			return fmt.Sprintf("Error deleting file %s: %v", path, err), nil
+		}
+		return fmt.Sprintf("Successfully deleted file %s", path), nil
 	}
 
-	return fmt.Sprintf("Successfully deleted file %s", path), nil
+	// Keep the file in the trash of the session
+	if _, err := MoveToTrash(path, op.sessionName, op.agentName); err != nil {
+		return fmt.Sprintf("Error deleting file %s: %v", path, err), nil
+	}
+	return fmt.Sprintf("Successfully deleted file %s, it was moved to the trash and %s can bring it back", path, ToolRestoreDeleted), nil
 }
 
-func deleteDirectoryToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func deleteDirectoryToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolDeleteDirectory, argsMap); err != nil {
 		return "", err
 	}
@@ -356,16 +435,67 @@ func deleteDirectoryToolCallImpl(argsMap *map[string]interface{}, op *OpenProces
 		}
 	}
 
-	// Delete the directory
-	err := os.RemoveAll(path)
-	if err != nil {
+	if data.GetSettingsStore().GetHardDelete() {
+		err := os.RemoveAll(path)
+		if err != nil {
+			return fmt.Sprintf("Error deleting directory %s: %v", path, err), nil
+		}
+		return fmt.Sprintf("Successfully deleted directory %s", path), nil
+	}
+
+	// Keep the directory in the trash of the session
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		// Like os.RemoveAll, a directory that is already gone is deleted
+		return fmt.Sprintf("Successfully deleted directory %s", path), nil
+	}
+	if _, err := MoveToTrash(path, op.sessionName, op.agentName); err != nil {
 		return fmt.Sprintf("Error deleting directory %s: %v", path, err), nil
 	}
+	return fmt.Sprintf("Successfully deleted directory %s, it was moved to the trash and %s can bring it back", path, ToolRestoreDeleted), nil
+}
 
-	return fmt.Sprintf("Successfully deleted directory %s", path), nil
+func restoreDeletedToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolRestoreDeleted, argsMap); err != nil {
+		return "", err
+	}
+
+	path, ok := (*argsMap)["path"].(string)
+	if !ok {
+		return "", fmt.Errorf("path not found in arguments")
+	}
+
+	entry, err := FindTrashedPath(op.sessionName, path)
+	if err != nil {
+		return fmt.Sprintf("Error reading the trash: %v", err), nil
+	}
+	if entry == nil {
+		return fmt.Sprintf("Error restoring %s: nothing deleted from this path is in the trash of the session", path), nil
+	}
+	op.toolsUse.FilePath = path
+
+	if !op.toolsUse.AutoApprove {
+		// Get purpose if provided
+		purpose, _ := (*argsMap)["purpose"].(string)
+		if purpose == "" {
+			purpose = fmt.Sprintf("restore the deleted path: %s", path)
+		}
+
+		// Prompt user for confirmation
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(purpose, op.toolsUse)
+		}
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return fmt.Sprintf("Operation cancelled by user: restore %s", path), UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+
+	if err := RestoreTrashEntry(entry); err != nil {
+		return fmt.Sprintf("Error restoring %s: %v", path, err), nil
+	}
+	return fmt.Sprintf("Successfully restored %s", path), nil
 }
 
-func moveToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func moveToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolMove, argsMap); err != nil {
 		return "", err
 	}
@@ -405,7 +535,7 @@ func moveToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (strin
 	return fmt.Sprintf("Successfully moved %s to %s", source, destination), nil
 }
 
-func searchFilesToolCallImpl(argsMap *map[string]interface{}) (string, error) {
+func searchFilesToolCallImpl(ctx context.Context, argsMap *map[string]interface{}) (string, error) {
 	if err := CheckToolPermission(ToolSearchFiles, argsMap); err != nil {
 		return "", err
 	}
@@ -434,6 +564,9 @@ func searchFilesToolCallImpl(argsMap *map[string]interface{}) (string, error) {
 	if recursive {
 		// Recursive search using filepath.WalkDir
 		err = filepath.WalkDir(directory, func(path string, d os.DirEntry, walkErr error) error {
+			if err := ctx.Err(); err != nil {
+				return err // Interrupted, keep the matches found so far
+			}
 			if walkErr != nil {
 				return nil // Skip inaccessible paths
 			}
@@ -450,7 +583,7 @@ func searchFilesToolCallImpl(argsMap *map[string]interface{}) (string, error) {
 			}
 			return nil
 		})
-		if err != nil {
+		if err != nil && ctx.Err() == nil {
 			return fmt.Sprintf("Error walking directory %s: %v", directory, err), nil
 		}
 	} else {
@@ -475,11 +608,14 @@ func searchFilesToolCallImpl(argsMap *map[string]interface{}) (string, error) {
 			result.WriteString(fmt.Sprintf("- %s\n", match))
 		}
 	}
+	if ctx.Err() != nil {
+		result.WriteString("The search was interrupted by user, the results are partial.\n")
+	}
 
 	return result.String(), nil
 }
 
-func searchTextInFileToolCallImpl(argsMap *map[string]interface{}) (string, error) {
+func searchTextInFileToolCallImpl(ctx context.Context, argsMap *map[string]interface{}) (string, error) {
 	if err := CheckToolPermission(ToolSearchTextInFile, argsMap); err != nil {
 		return "", err
 	}
@@ -588,7 +724,7 @@ func searchTextInFileToolCallImpl(argsMap *map[string]interface{}) (string, erro
 	return result.String(), nil
 }
 
-func readMultipleFilesToolCallImpl(argsMap *map[string]interface{}) (string, error) {
+func readMultipleFilesToolCallImpl(ctx context.Context, argsMap *map[string]interface{}) (string, error) {
 	if err := CheckToolPermission(ToolReadMultipleFiles, argsMap); err != nil {
 		return "", err
 	}
@@ -645,6 +781,7 @@ func readMultipleFilesToolCallImpl(argsMap *map[string]interface{}) (string, err
 			result.WriteString(fmt.Sprintf("Error reading file %s: %v\n\n", path, err))
 			continue
 		}
+		rememberFileContent(path, content)
 
 		if includeLineNumbers {
 			lines := strings.Split(string(content), "\n")
@@ -726,6 +863,68 @@ func applyWSNormalizedReplace(content, search, replace string) (string, bool) {
 	return "", false
 }
 
+// simulateEdits applies search/replace edits to content in memory, nothing touches disk.
+// Failures describe every edit that could not be applied.
+func simulateEdits(content string, editsInterface []interface{}) (simulatedContent string, outcomes []editOutcome, failures []string) {
+	// We accumulate into simulatedContent so each edit operates on the result of
+	// the previous one — mirroring real application order — while the original
+	// content is preserved for the diff.
+	simulatedContent = content
+
+	for i, editInterface := range editsInterface {
+		editMap, ok := editInterface.(map[string]interface{})
+		if !ok {
+			failures = append(failures, fmt.Sprintf("edit[%d]: invalid format (expected object)", i))
+			continue
+		}
+
+		searchText, ok := editMap["search"].(string)
+		if !ok || searchText == "" {
+			failures = append(failures, fmt.Sprintf("edit[%d]: missing or empty 'search' field", i))
+			continue
+		}
+
+		replaceText, _ := editMap["replace"].(string) // empty string is valid (deletion)
+
+		display := searchText
+		if len(display) > 60 {
+			display = display[:60] + "..."
+		}
+
+		// Strategy 1: exact match (requires uniqueness)
+		result, count := replaceFirstOccurrence(simulatedContent, searchText, replaceText)
+		if count == 1 {
+			simulatedContent = result
+			outcomes = append(outcomes, editOutcome{displaySearch: display})
+			continue
+		}
+		if count > 1 {
+			failures = append(failures, fmt.Sprintf(
+				"edit[%d]: ambiguous — search text appears %d times (must be exactly 1).\n"+
+					"         Expand the search block with more surrounding context to make it unique.\n"+
+					"         search: %q",
+				i, count, display))
+			continue
+		}
+
+		// Strategy 2: whitespace-normalised fallback (count == 0 from exact)
+		if wsResult, found := applyWSNormalizedReplace(simulatedContent, searchText, replaceText); found {
+			simulatedContent = wsResult
+			outcomes = append(outcomes, editOutcome{displaySearch: display, normalized: true})
+			continue
+		}
+
+		// All strategies exhausted
+		failures = append(failures, fmt.Sprintf(
+			"edit[%d]: not found — search text does not appear in the file\n"+
+				"         (checked exact match and whitespace-normalised match).\n"+
+				"         Use read_file with line_numbers=true to obtain exact content before retrying.\n"+
+				"         search: %q",
+			i, display))
+	}
+	return simulatedContent, outcomes, failures
+}
+
 // editOutcome records the result of a single validated edit for the success report.
 type editOutcome struct {
 	displaySearch string
@@ -791,7 +990,7 @@ func validateEditSchema(editsInterface []interface{}) string {
 	return ""
 }
 
-func editFileToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func editFileToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolEditFile, argsMap); err != nil {
 		return "", err
 	}
@@ -821,66 +1020,12 @@ func editFileToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (s
 		return fmt.Sprintf("Error reading file %s: %v", path, err), nil
 	}
 	content := string(originalContent)
+	// Edits are matched against the current content, so an earlier read can't go stale here,
+	// only changes made before the write below can be lost
+	rememberFileContent(path, originalContent)
 
 	// ── Phase 1: Validate & simulate ALL edits before touching disk ────────────
-	// We accumulate into simulatedContent so each edit operates on the result of
-	// the previous one — mirroring real application order — while the original
-	// content is preserved for the diff in Phase 3.
-	simulatedContent := content
-	var outcomes []editOutcome
-	var failures []string
-
-	for i, editInterface := range editsInterface {
-		editMap, ok := editInterface.(map[string]interface{})
-		if !ok {
-			failures = append(failures, fmt.Sprintf("edit[%d]: invalid format (expected object)", i))
-			continue
-		}
-
-		searchText, ok := editMap["search"].(string)
-		if !ok || searchText == "" {
-			failures = append(failures, fmt.Sprintf("edit[%d]: missing or empty 'search' field", i))
-			continue
-		}
-
-		replaceText, _ := editMap["replace"].(string) // empty string is valid (deletion)
-
-		display := searchText
-		if len(display) > 60 {
-			display = display[:60] + "..."
-		}
-
-		// Strategy 1: exact match (requires uniqueness)
-		result, count := replaceFirstOccurrence(simulatedContent, searchText, replaceText)
-		if count == 1 {
-			simulatedContent = result
-			outcomes = append(outcomes, editOutcome{displaySearch: display})
-			continue
-		}
-		if count > 1 {
-			failures = append(failures, fmt.Sprintf(
-				"edit[%d]: ambiguous — search text appears %d times (must be exactly 1).\n"+
-					"         Expand the search block with more surrounding context to make it unique.\n"+
-					"         search: %q",
-				i, count, display))
-			continue
-		}
-
-		// Strategy 2: whitespace-normalised fallback (count == 0 from exact)
-		if wsResult, found := applyWSNormalizedReplace(simulatedContent, searchText, replaceText); found {
-			simulatedContent = wsResult
-			outcomes = append(outcomes, editOutcome{displaySearch: display, normalized: true})
-			continue
-		}
-
-		// All strategies exhausted
-		failures = append(failures, fmt.Sprintf(
-			"edit[%d]: not found — search text does not appear in the file\n"+
-				"         (checked exact match and whitespace-normalised match).\n"+
-				"         Use read_file with line_numbers=true to obtain exact content before retrying.\n"+
-				"         search: %q",
-			i, display))
-	}
+	simulatedContent, outcomes, failures := simulateEdits(content, editsInterface)
 
 	// ── Phase 2: Abort ALL if any edit failed (no partial writes) ──────────────
 	if len(failures) > 0 {
@@ -917,16 +1062,16 @@ func editFileToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (s
 	}
 
 	// ── Phase 4: Write (only reached when all edits validated and user approved) ─
-	// Determine file permissions
-	mode := os.FileMode(0644)
-	if info, err := os.Stat(path); err == nil {
-		mode = info.Mode()
+	if fileChangedOnDisk(path) {
+		op.fileHooks.RejectDiff(path)
+		return fmt.Sprintf(ToolRespFileChangedOnDisk, path), nil
 	}
 
-	if err := os.WriteFile(path, []byte(simulatedContent), mode); err != nil {
+	if err := writeFileAtomic(path, []byte(simulatedContent)); err != nil {
 		op.fileHooks.RejectDiff(path)
 		return fmt.Sprintf("Error writing file %s: %v", path, err), nil
 	}
+	rememberFileContent(path, []byte(simulatedContent))
 	op.fileHooks.AcceptDiff(path)
 
 	// Build success report
@@ -939,10 +1084,13 @@ func editFileToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (s
 		}
 		result.WriteString(fmt.Sprintf("  [%d] %s%s\n", i+1, o.displaySearch, note))
 	}
+	if note := op.autoFormatFile(ctx, path); note != "" {
+		result.WriteString(note + "\n")
+	}
 	return result.String(), nil
 }
 
-func copyToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func copyToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolCopy, argsMap); err != nil {
 		return "", err
 	}
@@ -974,50 +1122,79 @@ func copyToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (strin
 	}
 
 	// Copy the file or directory
-	err := copyFileOrDir(source, destination)
+	copied, err := copyFileOrDir(ctx, source, destination)
 	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Sprintf("Copy of %s to %s interrupted by user after %d file(s), the copy is incomplete", source, destination, copied), nil
+		}
 		return fmt.Sprintf("Error copying %s to %s: %v", source, destination, err), nil
 	}
 
 	return fmt.Sprintf("Successfully copied %s to %s", source, destination), nil
 }
 
-// Helper function to copy files or directories
-func copyFileOrDir(src, dst string) error {
+// Helper function to copy files or directories, it returns the number of files copied and
+// stops when the context is cancelled
+func copyFileOrDir(ctx context.Context, src, dst string) (int, error) {
 	srcInfo, err := os.Stat(src)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	if srcInfo.IsDir() {
-		return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
+	if !srcInfo.IsDir() {
+		// Copy single file
+		if err := copyFile(ctx, src, dst); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
 
-			// Calculate the destination path
-			relPath, err := filepath.Rel(src, path)
-			if err != nil {
-				return err
-			}
-			dstPath := filepath.Join(dst, relPath)
+	copied := 0
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
-			if info.IsDir() {
-				// Create directory
-				return os.MkdirAll(dstPath, info.Mode())
-			} else {
-				// Copy file
-				return copyFile(path, dstPath)
-			}
-		})
-	} else {
-		// Copy single file
-		return copyFile(src, dst)
+		// Calculate the destination path
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		dstPath := filepath.Join(dst, relPath)
+
+		if info.IsDir() {
+			// Create directory
+			return os.MkdirAll(dstPath, info.Mode())
+		}
+		// Copy file
+		if err := copyFile(ctx, path, dstPath); err != nil {
+			return err
+		}
+		copied++
+		return nil
+	})
+	return copied, err
+}
+
+// contextReader stops reading once its context is cancelled, so that copying a huge file
+// can be interrupted.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
 	}
+	return r.r.Read(p)
 }
 
 // Helper function to copy a single file (preserves permissions)
-func copyFile(src, dst string) error {
+func copyFile(ctx context.Context, src, dst string) error {
 	sourceFile, err := os.Open(src)
 	if err != nil {
 		return err
@@ -1043,7 +1220,7 @@ func copyFile(src, dst string) error {
 	defer destinationFile.Close()
 
 	// Copy file contents
-	_, err = destinationFile.ReadFrom(sourceFile)
+	_, err = io.Copy(destinationFile, contextReader{ctx: ctx, r: sourceFile})
 	if err != nil {
 		return err
 	}
@@ -1,8 +1,11 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 
@@ -11,7 +14,7 @@ import (
 )
 
 // listMemoryToolCallImpl handles the list_memory tool call
-func listMemoryToolCallImpl() (string, error) {
+func listMemoryToolCallImpl(ctx context.Context) (string, error) {
 	if err := CheckToolPermission(ToolListMemory, nil); err != nil {
 		return "", err
 	}
@@ -36,7 +39,7 @@ func listMemoryToolCallImpl() (string, error) {
 
 // saveMemoryToolCallImpl handles the save_memory tool call
 // Simplified design: takes complete memory content and replaces all memories
-func saveMemoryToolCallImpl(argsMap *map[string]interface{}) (string, error) {
+func saveMemoryToolCallImpl(ctx context.Context, argsMap *map[string]interface{}) (string, error) {
 	if err := CheckToolPermission(ToolSaveMemory, argsMap); err != nil {
 		return "", err
 	}
@@ -85,7 +88,7 @@ func saveMemoryToolCallImpl(argsMap *map[string]interface{}) (string, error) {
 }
 
 // switchAgentToolCallImpl handles the switch_agent tool call
-func switchAgentToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func switchAgentToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolSwitchAgent, argsMap); err != nil {
 		return "", err
 	}
@@ -152,27 +155,79 @@ func switchAgentToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor)
 		}
 	}
 
+	// Set instruction for new agent
+	var instruction string
+	if v, ok := (*argsMap)["instruction"].(string); ok {
+		instruction = v
+	}
+	returnToCaller, _ := (*argsMap)["return_to_caller"].(bool)
+	if returnToCaller && strings.TrimSpace(instruction) == "" {
+		return "An instruction is required with return_to_caller: describe the task the agent must do before handing back.", nil
+	}
+
 	// Set active agent
+	caller := store.GetActiveAgentName()
 	err := store.SetActiveAgent(name)
 	if err != nil {
 		return fmt.Sprintf("Failed to set active agent: %v", err), nil
 	}
+	if returnToCaller {
+		pushHandoffReturn(caller, name)
+		instruction += "\n\n" + handoffReturnNote(caller)
+	}
 
-	// Set instruction for new agent
-	var instruction string
-	if v, ok := (*argsMap)["instruction"].(string); ok {
-		instruction = v
+	// Pass on what this agent already read
+	var handoff string
+	if share, _ := (*argsMap)["share_results"].(bool); share {
+		handoff = op.handoffDigest(name)
 	}
 
 	// Signal to switch
-	return fmt.Sprintf("Switching to agent '%s'...", name), SwitchAgentError{TargetAgent: name, Instruction: instruction}
+	return fmt.Sprintf("Switching to agent '%s'...", name), SwitchAgentError{TargetAgent: name, Instruction: instruction, Handoff: handoff}
+}
+
+// handoffBackToolCallImpl handles the handoff_back tool call: it switches back to the agent
+// that called switch_agent with return_to_caller, and briefs it with the results.
+func handoffBackToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolHandoffBack, argsMap); err != nil {
+		return "", err
+	}
+
+	result, _ := (*argsMap)["result"].(string)
+	if strings.TrimSpace(result) == "" {
+		return "A result is required: summarize what you did and found for the agent you hand back to.", nil
+	}
+
+	store := data.NewConfigStore()
+	agent := store.GetActiveAgentName()
+	caller, ok := popHandoffReturn(agent)
+	if !ok {
+		return "There is no agent to hand back to: you weren't switched to with return_to_caller. Carry on with the task.", nil
+	}
+	if store.GetAgent(caller) == nil {
+		return fmt.Sprintf("Agent '%s' to hand back to no longer exists. Carry on with the task.", caller), nil
+	}
+	if err := store.SetActiveAgent(caller); err != nil {
+		return fmt.Sprintf("Failed to set active agent: %v", err), nil
+	}
+
+	instruction := fmt.Sprintf("Agent '%s' finished the task you handed to it and returned:\n\n%s\n\nContinue the original task with these results.", agent, result)
+	return fmt.Sprintf("Handing back to agent '%s'...", caller), SwitchAgentError{TargetAgent: caller, Instruction: instruction}
+}
+
+// agentSwitchToolCallImpl runs the tools that end the turn with a switch of agent.
+func agentSwitchToolCallImpl(ctx context.Context, name string, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if name == ToolHandoffBack {
+		return handoffBackToolCallImpl(ctx, argsMap, op)
+	}
+	return switchAgentToolCallImpl(ctx, argsMap, op)
 }
 
 // buildAgentToolCallImpl handles the build_agent tool call.
 // It performs deterministic validation of all enum-constrained fields
 // before writing the agent .md file, returning a structured corrective
 // error message to the LLM on any validation failure (reflection loop).
-func buildAgentToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func buildAgentToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolBuildAgent, argsMap); err != nil {
 		return "", err
 	}
@@ -324,7 +379,7 @@ func buildAgentToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor)
 
 // listAgentToolCallImpl handles the list_agent tool call
 // Returns a formatted list of all available agents with their capabilities
-func listAgentToolCallImpl() (string, error) {
+func listAgentToolCallImpl(ctx context.Context) (string, error) {
 	if err := CheckToolPermission(ToolListAgent, nil); err != nil {
 		return "", err
 	}
@@ -395,7 +450,7 @@ func formatAgentInfo(name string, ag *data.AgentConfig) string {
 
 // spawnSubAgentsToolCallImpl handles the spawn_subagents tool call
 // Invokes one or more sub-agents and returns progress summary
-func spawnSubAgentsToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func spawnSubAgentsToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolSpawnSubAgents, argsMap); err != nil {
 		return "", err
 	}
@@ -467,17 +522,37 @@ func spawnSubAgentsToolCallImpl(argsMap *map[string]interface{}, op *OpenProcess
 			}
 		}
 
+		var priority int
+		if v, ok := taskMap["priority"]; ok {
+			priority = int(toInt64(v))
+		}
+
+		// Parse optional output_schema, rejected here so the caller can fix it
+		var outputSchema string
+		if v, ok := taskMap["output_schema"].(map[string]interface{}); ok && len(v) > 0 {
+			schemaJSON, err := json.Marshal(v)
+			if err != nil {
+				return "", fmt.Errorf("task at index %d has an invalid 'output_schema': %v", i, err)
+			}
+			if _, err := resolveOutputSchema(string(schemaJSON)); err != nil {
+				return "", fmt.Errorf("task at index %d: %v", i, err)
+			}
+			outputSchema = string(schemaJSON)
+		}
+
 		tasks = append(tasks, &SubAgentTask{
 			CallerAgentName: op.agentName,
 			AgentName:       agentName,
 			Instruction:     instruction,
 			TaskKey:         taskKey,
 			InputKeys:       inputKeys,
+			Priority:        priority,
+			OutputSchema:    outputSchema,
 		})
 	}
 
 	// Dispatch tasks concurrently via the actor model
-	responses, err := op.executor.Dispatch(tasks)
+	responses, err := op.executor.Dispatch(ctx, tasks)
 	if err != nil {
 		return "", fmt.Errorf("failed to dispatch sub-agents: %v", err)
 	}
@@ -486,9 +561,132 @@ func spawnSubAgentsToolCallImpl(argsMap *map[string]interface{}, op *OpenProcess
 	return op.executor.FormatSummary(responses), nil
 }
 
+// mapOverFilesToolCallImpl handles the map_over_files tool call: the files of a glob are
+// sharded across sub-agent tasks that apply the instruction to each file, then an optional
+// reduce task combines their results.
+func mapOverFilesToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolMapOverFiles, argsMap); err != nil {
+		return "", err
+	}
+	if op.executor == nil {
+		return "", fmt.Errorf("sub-agent executor not initialized")
+	}
+
+	pattern, _ := (*argsMap)["pattern"].(string)
+	instruction, _ := (*argsMap)["instruction"].(string)
+	agentName, _ := (*argsMap)["agent_name"].(string)
+	taskKey, _ := (*argsMap)["task_key"].(string)
+	if pattern == "" || instruction == "" || agentName == "" || taskKey == "" {
+		return "", fmt.Errorf("pattern, instruction, agent_name and task_key are required")
+	}
+	reduceInstruction, _ := (*argsMap)["reduce_instruction"].(string)
+	reduceAgent, _ := (*argsMap)["reduce_agent"].(string)
+	if reduceAgent == "" {
+		reduceAgent = agentName
+	}
+	shards := mapDefaultShards
+	if v := toInt64((*argsMap)["shards"]); v > 0 {
+		shards = int(min(v, mapMaxShards))
+	}
+
+	set, err := ExpandAttachment(pattern)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	// A glob the agent may read can still match files it may not
+	allowed := set.Text[:0]
+	for _, f := range set.Text {
+		if problem := op.pathRules.Check(f.Path, false); problem != "" {
+			set.Skipped = append(set.Skipped, SkippedFile{Path: f.Path, Reason: problem})
+			delete(set.content, f.Path)
+			continue
+		}
+		allowed = append(allowed, f)
+	}
+	set.Text = allowed
+	if len(set.Text) == 0 {
+		return fmt.Sprintf("No text files to map over in %s.\n%s", pattern, set.Manifest()), nil
+	}
+	groups := shardFiles(set.Text, shards)
+
+	if !op.toolsUse.AutoApprove {
+		desc := fmt.Sprintf("Map over %d files of %s in %d task(s) [Agent: %s]", len(set.Text), pattern, len(groups), agentName)
+		if reduceInstruction != "" {
+			desc += fmt.Sprintf("\nThen reduce the results [Agent: %s]", reduceAgent)
+		}
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(desc, op.toolsUse)
+		}
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return "Operation cancelled by user: map over files", UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+
+	var tasks []*SubAgentTask
+	for i, group := range groups {
+		tasks = append(tasks, &SubAgentTask{
+			CallerAgentName: op.agentName,
+			AgentName:       agentName,
+			Instruction:     mapShardInstruction(instruction, set, group),
+			TaskKey:         fmt.Sprintf("%s_map%d", taskKey, i+1),
+		})
+	}
+	responses, err := op.executor.Dispatch(ctx, tasks)
+	if err != nil {
+		return "", fmt.Errorf("failed to dispatch sub-agents: %v", err)
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Mapped %d files of %s in %d task(s).\n", len(set.Text), pattern, len(groups))
+	var leftOut []string
+	for _, m := range set.Media {
+		leftOut = append(leftOut, fmt.Sprintf("- %s: not a text file", m.Path()))
+	}
+	for _, f := range set.Skipped {
+		leftOut = append(leftOut, fmt.Sprintf("- %s: %s", f.Path, f.Reason))
+	}
+	if len(leftOut) > 0 {
+		fmt.Fprintf(&sb, "Left out %d files:\n", len(leftOut))
+		if len(leftOut) > 20 {
+			leftOut = append(leftOut[:20], fmt.Sprintf("- and %d more", len(leftOut)-20))
+		}
+		sb.WriteString(strings.Join(leftOut, "\n") + "\n")
+	}
+	sb.WriteString(op.executor.FormatSummary(responses))
+
+	var inputKeys []string
+	for _, r := range responses {
+		if r.Err == nil && r.Result != nil && r.Result.Status == StatusCompleted && r.Result.StateKey != "" {
+			inputKeys = append(inputKeys, r.Result.StateKey)
+		}
+	}
+	if reduceInstruction == "" || len(inputKeys) == 0 {
+		return sb.String(), nil
+	}
+
+	reduced, err := op.executor.Dispatch(ctx, []*SubAgentTask{{
+		CallerAgentName: op.agentName,
+		AgentName:       reduceAgent,
+		Instruction:     mapReduceInstruction(reduceInstruction, pattern, len(set.Text)),
+		TaskKey:         taskKey + "_reduce",
+		InputKeys:       inputKeys,
+	}})
+	if err != nil {
+		return "", fmt.Errorf("failed to dispatch the reduce task: %v", err)
+	}
+	sb.WriteString("\n\nReduce: ")
+	sb.WriteString(op.executor.FormatSummary(reduced))
+	if len(reduced) == 1 && reduced[0].Result != nil && reduced[0].Result.StateKey != "" {
+		if val, ok := op.sharedState.Get(reduced[0].Result.StateKey); ok {
+			fmt.Fprintf(&sb, "\n\n## Reduced result\n%s", truncateRunes(fmt.Sprintf("%v", val), mapReduceResultMaxRunes))
+		}
+	}
+	return sb.String(), nil
+}
+
 // getStateToolCallImpl handles the get_state tool call
 // Retrieves a value from SharedState
-func getStateToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func getStateToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolGetState, argsMap); err != nil {
 		return "", err
 	}
@@ -523,6 +721,7 @@ func getStateToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (s
 // setStateToolCallImpl handles the set_state tool call
 // Stores a value in SharedState
 func setStateToolCallImpl(
+	ctx context.Context,
 	argsMap *map[string]interface{},
 	op *OpenProcessor,
 ) (string, error) {
@@ -559,7 +758,7 @@ func setStateToolCallImpl(
 
 // listStateToolCallImpl handles the list_state tool call
 // Lists all keys and metadata in SharedState
-func listStateToolCallImpl(op *OpenProcessor) (string, error) {
+func listStateToolCallImpl(ctx context.Context, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolListState, nil); err != nil {
 		return "", err
 	}
@@ -572,7 +771,7 @@ func listStateToolCallImpl(op *OpenProcessor) (string, error) {
 }
 
 // activateSkillToolCallImpl handles the activate_skill tool call.
-func activateSkillToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func activateSkillToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolActivateSkill, argsMap); err != nil {
 		return "", err
 	}
@@ -604,8 +803,11 @@ func activateSkillToolCallImpl(argsMap *map[string]interface{}, op *OpenProcesso
 	return skillDetails, nil
 }
 
+// askUserHeadlessNote answers ask_user when the run is headless.
+const askUserHeadlessNote = "The question was not asked: no user is available in this run. Don't ask again, go on with your best judgment and state the assumptions you made in your final answer."
+
 // askUserToolCallImpl handles the ask_user tool call.
-func askUserToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func askUserToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolAskUser, argsMap); err != nil {
 		return "", err
 	}
@@ -630,7 +832,18 @@ func askUserToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (st
 		Placeholder:  placeholder,
 	}
 
+	if (qType == "select" || qType == "multiselect") && len(options) == 0 {
+		return fmt.Sprintf("Error: options are required for a %s question", qType), nil
+	}
+	if op.interaction == nil {
+		return askUserHeadlessNote, nil
+	}
+
 	resp, err := op.interaction.RequestAskUser(req)
+	if errors.Is(err, errNoUser) {
+		// Denied rather than failed, so that the run goes on
+		return askUserHeadlessNote, nil
+	}
 	if err != nil {
 		return "", err
 	}
@@ -640,8 +853,243 @@ func askUserToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (st
 	return string(out), nil
 }
 
+// Choices of an approval asked in the terminal.
+const (
+	approvalChoiceApprove = "Approve"
+	approvalChoiceEdit    = "Approve with edits"
+	approvalChoiceReject  = "Reject"
+)
+
+// approvalResult is the answer of request_approval to the model.
+type approvalResult struct {
+	Approved bool   `json:"approved"`
+	Edits    string `json:"edits,omitempty"`    // Changes to make before going on
+	Feedback string `json:"feedback,omitempty"` // Why it was rejected
+	Approver string `json:"approver,omitempty"` // Approval backend that decided instead of a person
+}
+
+// requestApprovalToolCallImpl handles the request_approval tool call: the run pauses until
+// someone approves the content, approves it with edits or rejects it. Without a terminal the
+// approval backend decides. It is never auto-approved, not even in YOLO mode.
+func requestApprovalToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolRequestApproval, argsMap); err != nil {
+		return "", err
+	}
+	action, _ := (*argsMap)["action"].(string)
+	content, _ := (*argsMap)["content"].(string)
+	if strings.TrimSpace(action) == "" {
+		return "", fmt.Errorf("action is required")
+	}
+	description := action
+	if content != "" {
+		description = fmt.Sprintf("%s\n\n%s", action, content)
+	}
+
+	var result approvalResult
+	switch h := op.interaction.(type) {
+	case nil:
+		return "", fmt.Errorf("no one is available to approve: %s", action)
+	case *ApprovalHandler:
+		toolsUse := &data.ToolsUse{Tool: ToolRequestApproval}
+		h.RequestConfirm(description, toolsUse)
+		result = approvalResult{Approved: toolsUse.Confirm == data.ToolConfirmYes, Feedback: toolsUse.Feedback, Approver: toolsUse.Approver}
+	default:
+		resp, err := h.RequestAskUser(event.AskUserRequest{
+			Question:     "Approve before going on: " + description,
+			QuestionType: "select",
+			Options:      []string{approvalChoiceApprove, approvalChoiceEdit, approvalChoiceReject},
+		})
+		if err != nil {
+			return "", err
+		}
+		if resp.Cancelled {
+			return "Operation cancelled by user: approval was not given", UserCancelError{Reason: UserCancelReasonDeny}
+		}
+		switch resp.Answer {
+		case approvalChoiceApprove:
+			result.Approved = true
+		case approvalChoiceEdit:
+			edits, err := h.RequestAskUser(event.AskUserRequest{Question: "Which edits should be made first?", QuestionType: "text"})
+			if err != nil {
+				return "", err
+			}
+			result = approvalResult{Approved: true, Edits: edits.Answer}
+		default:
+			reason, err := h.RequestAskUser(event.AskUserRequest{Question: "Why is it rejected? (optional)", QuestionType: "text"})
+			if err == nil && !reason.Cancelled {
+				result.Feedback = reason.Answer
+			}
+		}
+	}
+
+	out, _ := json.Marshal(result)
+	if !result.Approved {
+		return string(out) + "\nNot approved: do not carry out the action.", nil
+	}
+	if result.Edits != "" {
+		return string(out) + "\nApproved on condition: make the edits before carrying out the action.", nil
+	}
+	return string(out), nil
+}
+
+// presentedOption is one of the approaches offered by present_options.
+type presentedOption struct {
+	Title   string
+	Summary string
+	Pros    []string
+	Cons    []string
+}
+
+// optionOther is the choice of present_options to describe another approach.
+const optionOther = "Something else"
+
+// optionChoice is the answer of present_options to the model.
+type optionChoice struct {
+	Choice    string `json:"choice,omitempty"`
+	Index     int    `json:"index,omitempty"`   // 1-based index of the option chosen
+	Comment   string `json:"comment,omitempty"` // The approach described instead of the options
+	Cancelled bool   `json:"cancelled,omitempty"`
+}
+
+// presentOptionsToolCallImpl handles the present_options tool call: the alternatives are listed
+// with their trade-offs and the user picks one, or describes another approach.
+func presentOptionsToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolPresentOptions, argsMap); err != nil {
+		return "", err
+	}
+	question, _ := (*argsMap)["question"].(string)
+	recommended, _ := (*argsMap)["recommended"].(string)
+	allowOther := true
+	if v, ok := (*argsMap)["allow_other"].(bool); ok {
+		allowOther = v
+	}
+
+	options, err := parsePresentedOptions((*argsMap)["options"])
+	if err != nil {
+		return "Error: " + err.Error(), nil
+	}
+	if strings.TrimSpace(question) == "" {
+		question = "Which approach do you prefer?"
+	}
+	recommendedIndex := slices.IndexFunc(options, func(o presentedOption) bool { return o.Title == recommended })
+
+	if op.interaction == nil {
+		return presentOptionsHeadlessNote(options, recommendedIndex), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(question + "\n")
+	labels := make([]string, 0, len(options)+1)
+	for i, o := range options {
+		label := o.Title
+		if i == recommendedIndex {
+			label += " (recommended)"
+		}
+		labels = append(labels, label)
+		fmt.Fprintf(&sb, "\n%d. %s\n", i+1, label)
+		if o.Summary != "" {
+			fmt.Fprintf(&sb, "   %s\n", o.Summary)
+		}
+		for _, p := range o.Pros {
+			fmt.Fprintf(&sb, "   + %s\n", p)
+		}
+		for _, c := range o.Cons {
+			fmt.Fprintf(&sb, "   - %s\n", c)
+		}
+	}
+	if allowOther {
+		labels = append(labels, optionOther)
+	}
+
+	resp, err := op.interaction.RequestAskUser(event.AskUserRequest{
+		Question:     strings.TrimRight(sb.String(), "\n"),
+		QuestionType: "select",
+		Options:      labels,
+	})
+	if errors.Is(err, errNoUser) {
+		return presentOptionsHeadlessNote(options, recommendedIndex), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var result optionChoice
+	switch i := slices.Index(labels, resp.Answer); {
+	case resp.Cancelled:
+		result.Cancelled = true
+	case i >= 0 && i < len(options):
+		result = optionChoice{Choice: options[i].Title, Index: i + 1}
+	default:
+		// Something else, or an answer typed instead of picked
+		result.Comment = resp.Answer
+		if resp.Answer == optionOther {
+			other, err := op.interaction.RequestAskUser(event.AskUserRequest{Question: "Which approach do you want instead?", QuestionType: "text"})
+			if err != nil {
+				return "", err
+			}
+			result = optionChoice{Comment: other.Answer, Cancelled: other.Cancelled}
+		}
+	}
+
+	out, _ := json.Marshal(result)
+	if result.Cancelled {
+		return string(out) + "\nNo option was chosen: ask before going on with any of them.", nil
+	}
+	return string(out), nil
+}
+
+// parsePresentedOptions reads the options of present_options: at least two, each with a
+// distinct title.
+func parsePresentedOptions(raw interface{}) ([]presentedOption, error) {
+	items, _ := raw.([]interface{})
+	if len(items) < 2 {
+		return nil, fmt.Errorf("at least two options are required")
+	}
+	strs := func(v interface{}) []string {
+		var out []string
+		list, _ := v.([]interface{})
+		for _, s := range list {
+			if s, ok := s.(string); ok && strings.TrimSpace(s) != "" {
+				out = append(out, strings.TrimSpace(s))
+			}
+		}
+		return out
+	}
+	var options []presentedOption
+	for i, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("option %d must be an object with a title", i+1)
+		}
+		title, _ := m["title"].(string)
+		title = strings.TrimSpace(title)
+		if title == "" {
+			return nil, fmt.Errorf("option %d has no title", i+1)
+		}
+		if title == optionOther || slices.ContainsFunc(options, func(o presentedOption) bool { return o.Title == title }) {
+			return nil, fmt.Errorf("option titles must be distinct, '%s' is repeated or reserved", title)
+		}
+		summary, _ := m["summary"].(string)
+		options = append(options, presentedOption{
+			Title:   title,
+			Summary: strings.TrimSpace(summary),
+			Pros:    strs(m["pros"]),
+			Cons:    strs(m["cons"]),
+		})
+	}
+	return options, nil
+}
+
+// presentOptionsHeadlessNote answers present_options when no one can choose.
+func presentOptionsHeadlessNote(options []presentedOption, recommended int) string {
+	if recommended >= 0 {
+		return fmt.Sprintf("No user is available to choose in this run. Go on with the recommended option '%s' and say so in your final answer.", options[recommended].Title)
+	}
+	return "No user is available to choose in this run. Go on with the option you judge best and explain the choice in your final answer."
+}
+
 // enterPlanModeToolCallImpl handles the enter_plan_mode tool call.
-func enterPlanModeToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func enterPlanModeToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolEnterPlanMode, argsMap); err != nil {
 		return "", err
 	}
@@ -675,7 +1123,7 @@ func enterPlanModeToolCallImpl(argsMap *map[string]interface{}, op *OpenProcesso
 }
 
 // exitPlanModeToolCallImpl handles the exit_plan_mode tool call.
-func exitPlanModeToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func exitPlanModeToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolExitPlanMode, argsMap); err != nil {
 		return "", err
 	}
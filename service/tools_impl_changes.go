@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+)
+
+// fileChange is one validated change of an apply_changes transaction.
+type fileChange struct {
+	path     string
+	existed  bool
+	original []byte
+	mode     os.FileMode
+	updated  string
+	outcomes []editOutcome
+}
+
+func applyChangesToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolApplyChanges, argsMap); err != nil {
+		return "", err
+	}
+
+	changesInterface, ok := (*argsMap)["changes"].([]interface{})
+	if !ok || len(changesInterface) == 0 {
+		return "", fmt.Errorf("changes not found in arguments or empty")
+	}
+	purpose, _ := (*argsMap)["purpose"].(string)
+
+	// ── Phase 1: Validate & simulate every change before touching disk ────────
+	var changes []*fileChange
+	var failures []string
+	seen := make(map[string]bool)
+	for i, changeInterface := range changesInterface {
+		changeMap, ok := changeInterface.(map[string]interface{})
+		if !ok {
+			failures = append(failures, fmt.Sprintf("change[%d]: invalid format (expected object)", i))
+			continue
+		}
+		path, _ := changeMap["path"].(string)
+		if path == "" {
+			failures = append(failures, fmt.Sprintf("change[%d]: missing 'path'", i))
+			continue
+		}
+		if seen[absPath(path)] {
+			failures = append(failures, fmt.Sprintf("change[%d]: %s is changed more than once, merge its changes into one entry", i, path))
+			continue
+		}
+		seen[absPath(path)] = true
+
+		change, problems := prepareFileChange(path, changeMap)
+		for _, p := range problems {
+			failures = append(failures, fmt.Sprintf("change[%d] %s: %s", i, path, p))
+		}
+		if change != nil {
+			changes = append(changes, change)
+		}
+	}
+
+	// ── Phase 2: Abort ALL if any change failed ───────────────────────────────
+	if len(failures) > 0 {
+		var msg strings.Builder
+		msg.WriteString(fmt.Sprintf("CHANGES ABORTED — no files were written.\n%d problem(s) found:\n\n", len(failures)))
+		for _, f := range failures {
+			msg.WriteString(fmt.Sprintf("  • %s\n\n", f))
+		}
+		msg.WriteString("Fix all problems and retry the entire transaction.")
+		return msg.String(), nil
+	}
+
+	// ── Phase 3: Show one combined diff and request a single confirmation ─────
+	if !op.toolsUse.AutoApprove {
+		var diff strings.Builder
+		for _, c := range changes {
+			diff.WriteString(fmt.Sprintf("── %s\n", c.path))
+			diff.WriteString(op.interaction.RequestDiff(string(c.original), c.updated, 3))
+			diff.WriteString("\n")
+		}
+		op.toolsUse.FilePath = changes[0].path
+		op.showDiff(strings.TrimRight(diff.String(), "\n"))
+
+		if purpose == "" {
+			purpose = fmt.Sprintf("apply changes to %d files", len(changes))
+		}
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(purpose, op.toolsUse)
+		}
+		op.closeDiff()
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return fmt.Sprintf("Operation cancelled by user: apply changes to %d files. No files were changed.", len(changes)),
+				UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+
+	// ── Phase 4: Checkpoint, then write all-or-nothing ────────────────────────
+	for _, c := range changes {
+		if fileChangedOnDisk(c.path) {
+			return fmt.Sprintf(ToolRespFileChangedOnDisk, c.path), nil
+		}
+	}
+
+	checkpoint := &data.Checkpoint{Session: op.sessionName, Agent: op.agentName, Purpose: purpose}
+	for _, c := range changes {
+		checkpoint.Files = append(checkpoint.Files, data.CheckpointFile{
+			Path: absPath(c.path), Existed: c.existed, Content: c.original, Mode: uint32(c.mode.Perm()),
+		})
+	}
+	if err := data.SaveCheckpoint(checkpoint); err != nil {
+		return fmt.Sprintf("Error recording checkpoint, no files were written: %v", err), nil
+	}
+
+	for i, c := range changes {
+		err := os.MkdirAll(filepath.Dir(c.path), 0755)
+		if err == nil {
+			err = writeFileAtomic(c.path, []byte(c.updated))
+		}
+		if err != nil {
+			rollbackErr := restoreCheckpointFiles(checkpoint.Files[:i])
+			msg := fmt.Sprintf("Error writing file %s: %v\nAll changes were rolled back.", c.path, err)
+			if rollbackErr != nil {
+				msg = fmt.Sprintf("Error writing file %s: %v\nRolling back failed: %v\nRestore manually with 'gllm checkpoint restore %s'.",
+					c.path, err, rollbackErr, checkpoint.ID)
+			}
+			return msg, nil
+		}
+	}
+
+	var result strings.Builder
+	result.WriteString(fmt.Sprintf("Successfully applied changes to %d file(s), checkpoint %s:\n", len(changes), checkpoint.ID))
+	for _, c := range changes {
+		rememberFileContent(c.path, []byte(c.updated))
+		switch {
+		case !c.existed:
+			result.WriteString(fmt.Sprintf("  • %s (created)\n", c.path))
+		case c.outcomes != nil:
+			result.WriteString(fmt.Sprintf("  • %s (%d edit(s))\n", c.path, len(c.outcomes)))
+		default:
+			result.WriteString(fmt.Sprintf("  • %s (rewritten)\n", c.path))
+		}
+	}
+	return result.String(), nil
+}
+
+// prepareFileChange reads the file and computes its new content, returning any problems found.
+func prepareFileChange(path string, changeMap map[string]interface{}) (*fileChange, []string) {
+	editsInterface, hasEdits := changeMap["edits"].([]interface{})
+	content, hasContent := changeMap["content"].(string)
+	if hasEdits == hasContent {
+		return nil, []string{"give exactly one of 'edits' or 'content'"}
+	}
+
+	change := &fileChange{path: path, mode: 0644}
+	if info, err := os.Stat(path); err == nil {
+		if info.IsDir() {
+			return nil, []string{"is a directory"}
+		}
+		original, err := os.ReadFile(path)
+		if err != nil {
+			return nil, []string{fmt.Sprintf("cannot read file: %v", err)}
+		}
+		change.existed = true
+		change.original = original
+		change.mode = info.Mode()
+	} else if hasEdits {
+		return nil, []string{"file does not exist, use 'content' to create it"}
+	}
+
+	if hasContent {
+		// A full rewrite must be based on what the model last read
+		if fileChangedOnDisk(path) {
+			return nil, []string{"changed on disk since you last read it, read it again"}
+		}
+		change.updated = content
+	} else {
+		if schemaErr := validateEditSchema(editsInterface); schemaErr != "" {
+			return nil, []string{schemaErr}
+		}
+		updated, outcomes, failures := simulateEdits(string(change.original), editsInterface)
+		if len(failures) > 0 {
+			return nil, failures
+		}
+		change.updated = updated
+		change.outcomes = outcomes
+	}
+	if change.existed {
+		// Later writes must not clobber changes made after this read
+		rememberFileContent(path, change.original)
+	}
+	return change, nil
+}
+
+// RestoreCheckpoint puts the files of a checkpoint back to their recorded state.
+func RestoreCheckpoint(cp *data.Checkpoint) error {
+	return restoreCheckpointFiles(cp.Files)
+}
+
+func restoreCheckpointFiles(files []data.CheckpointFile) error {
+	var errs []string
+	for _, f := range files {
+		var err error
+		if !f.Existed {
+			if err = os.Remove(f.Path); os.IsNotExist(err) {
+				err = nil
+			}
+		} else if err = os.MkdirAll(filepath.Dir(f.Path), 0755); err == nil {
+			if err = writeFileAtomic(f.Path, f.Content); err == nil && f.Mode != 0 {
+				err = os.Chmod(f.Path, os.FileMode(f.Mode))
+			}
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", f.Path, err))
+			continue
+		}
+		rememberFileContent(f.Path, f.Content)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to restore %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
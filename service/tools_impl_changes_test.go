@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func runApplyChanges(t *testing.T, changes ...map[string]interface{}) string {
+	t.Helper()
+	list := make([]interface{}, len(changes))
+	for i, c := range changes {
+		list[i] = c
+	}
+	args := map[string]interface{}{"changes": list, "purpose": "rename greeting"}
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}}
+	out, err := applyChangesToolCallImpl(context.Background(), &args, op)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return out
+}
+
+func changeEdit(search, replace string) []interface{} {
+	return []interface{}{map[string]interface{}{"search": search, "replace": replace}}
+}
+
+func TestApplyChangesTransaction(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	created := filepath.Join(dir, "sub", "c.go")
+	os.WriteFile(a, []byte("func hello() {}\n"), 0644)
+	os.WriteFile(b, []byte("hello()\n"), 0600)
+
+	// One failing edit aborts the whole transaction
+	out := runApplyChanges(t,
+		map[string]interface{}{"path": a, "edits": changeEdit("hello", "greet")},
+		map[string]interface{}{"path": b, "edits": changeEdit("missing", "x")},
+	)
+	if !strings.HasPrefix(out, "CHANGES ABORTED") {
+		t.Fatalf("expected abort, got %q", out)
+	}
+	if got, _ := os.ReadFile(a); string(got) != "func hello() {}\n" {
+		t.Fatalf("a.go was modified by an aborted transaction: %q", got)
+	}
+
+	out = runApplyChanges(t,
+		map[string]interface{}{"path": a, "edits": changeEdit("hello", "greet")},
+		map[string]interface{}{"path": b, "edits": changeEdit("hello", "greet")},
+		map[string]interface{}{"path": created, "content": "package sub\n"},
+	)
+	if !strings.HasPrefix(out, "Successfully applied changes to 3 file(s)") {
+		t.Fatalf("unexpected result %q", out)
+	}
+	if got, _ := os.ReadFile(b); string(got) != "greet()\n" {
+		t.Errorf("b.go = %q", got)
+	}
+
+	checkpoints, err := data.ListCheckpoints()
+	if err != nil || len(checkpoints) != 1 || len(checkpoints[0].Files) != 3 {
+		t.Fatalf("expected one checkpoint of 3 files, got %v, %v", checkpoints, err)
+	}
+	if err := RestoreCheckpoint(checkpoints[0]); err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if got, _ := os.ReadFile(a); string(got) != "func hello() {}\n" {
+		t.Errorf("a.go not restored: %q", got)
+	}
+	if info, _ := os.Stat(b); info.Mode().Perm() != 0600 {
+		t.Errorf("b.go mode not restored: %v", info.Mode().Perm())
+	}
+	if _, err := os.Stat(created); !os.IsNotExist(err) {
+		t.Errorf("created file was not removed on restore")
+	}
+}
+
+func TestApplyChangesRollback(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.txt")
+	os.WriteFile(a, []byte("one\n"), 0644)
+	blocker := filepath.Join(dir, "blocker")
+	os.WriteFile(blocker, []byte("not a dir"), 0644)
+
+	out := runApplyChanges(t,
+		map[string]interface{}{"path": a, "edits": changeEdit("one", "two")},
+		map[string]interface{}{"path": filepath.Join(blocker, "new.txt"), "content": "x"},
+	)
+	if !strings.Contains(out, "All changes were rolled back") {
+		t.Fatalf("expected a rollback, got %q", out)
+	}
+	if got, _ := os.ReadFile(a); string(got) != "one\n" {
+		t.Errorf("a.txt not rolled back: %q", got)
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+func inspectDataToolCallImpl(ctx context.Context, argsMap *map[string]interface{}) (string, error) {
+	if err := CheckToolPermission(ToolInspectData, argsMap); err != nil {
+		return "", err
+	}
+
+	path, ok := (*argsMap)["path"].(string)
+	if !ok || strings.TrimSpace(path) == "" {
+		return "", fmt.Errorf("path not found in arguments")
+	}
+	rows := defaultInspectRows
+	if n, ok := (*argsMap)["rows"]; ok {
+		rows = int(min(max(toInt64(n), 0), 50))
+	}
+
+	result, err := InspectDataFile(path, rows)
+	if err != nil {
+		return fmt.Sprintf("Error inspecting %s: %v", path, err), nil
+	}
+	return result, nil
+}
+
+func queryDataToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolQueryData, argsMap); err != nil {
+		return "", err
+	}
+
+	query, ok := (*argsMap)["query"].(string)
+	if !ok || strings.TrimSpace(query) == "" {
+		return "", fmt.Errorf("query not found in arguments")
+	}
+	var files []string
+	if list, ok := (*argsMap)["files"].([]interface{}); ok {
+		for _, f := range list {
+			if s, ok := f.(string); ok && s != "" {
+				files = append(files, s)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("files not found in arguments")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, databaseQueryTimeout)
+	defer cancel()
+	result, err := QueryDataFiles(ctx, files, query, int(toInt64((*argsMap)["max_rows"])))
+	if err != nil {
+		return fmt.Sprintf("%sError: %v", result, err), nil
+	}
+	return result, nil
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+)
+
+func queryDatabaseToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolQueryDatabase, argsMap); err != nil {
+		return "", err
+	}
+
+	query, ok := (*argsMap)["query"].(string)
+	if !ok || strings.TrimSpace(query) == "" {
+		return "", fmt.Errorf("query not found in arguments")
+	}
+
+	project := DatabaseProjectDir()
+	dbs := data.GetSettingsStore().GetDatabases(project)
+	name, _ := (*argsMap)["database"].(string)
+	var db *data.DatabaseSettings
+	for i := range dbs {
+		if (name == "" && len(dbs) == 1) || dbs[i].Name == name {
+			db = &dbs[i]
+		}
+	}
+	if db == nil {
+		if len(dbs) == 0 {
+			return fmt.Sprintf("Error: no database is configured for %s, the user can add one with 'gllm config db add NAME DSN'", project), nil
+		}
+		var names []string
+		for _, d := range dbs {
+			names = append(names, d.Name)
+		}
+		if name == "" {
+			return fmt.Sprintf("Error: several databases are configured, pass one of: %s", strings.Join(names, ", ")), nil
+		}
+		return fmt.Sprintf("Error: database '%s' not found, configured: %s", name, strings.Join(names, ", ")), nil
+	}
+
+	dsn, err := data.GetSecret(data.DatabaseSecretName(project, db.Name))
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	if dsn == "" {
+		return fmt.Sprintf("Error: the DSN of database '%s' is missing, add it again with 'gllm config db add %s DSN'", db.Name, db.Name), nil
+	}
+
+	write := !IsReadOnlySQL(query)
+	// A SQLite database is a file, the paths of the agent apply to it
+	if engine, source, err := DatabaseEngine(dsn); err == nil && engine == DatabaseSQLite {
+		if path := sqliteFilePath(source); path != "" {
+			if problem := op.pathRules.Check(path, write); problem != "" {
+				return fmt.Sprintf("Error: database '%s' is blocked: %s", db.Name, problem), nil
+			}
+		}
+	}
+	if write {
+		if !db.Write {
+			return fmt.Sprintf("Error: database '%s' is read only and the statement may change data. Only single SELECT, WITH, SHOW, EXPLAIN or DESCRIBE statements can run; the user can allow writes with 'gllm config db add %s DSN --write'", db.Name, db.Name), nil
+		}
+		if !op.toolsUse.AutoApprove {
+			purpose := fmt.Sprintf("Run a statement that changes database '%s':\n%s", db.Name, strings.TrimSpace(query))
+			if op.interaction != nil {
+				op.interaction.RequestConfirm(purpose, op.toolsUse)
+			}
+			if op.toolsUse.Confirm == data.ToolConfirmCancel {
+				return fmt.Sprintf("Operation cancelled by user: write to database '%s'", db.Name), UserCancelError{Reason: UserCancelReasonDeny}
+			}
+		}
+	}
+
+	conn, engine, err := OpenDatabase(dsn, write)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, databaseQueryTimeout)
+	defer cancel()
+	result, err := RunDatabaseQuery(ctx, conn, engine, query, write, int(toInt64((*argsMap)["max_rows"])))
+	if err != nil {
+		return fmt.Sprintf("Error querying %s: %v", db.Name, err), nil
+	}
+	return result, nil
+}
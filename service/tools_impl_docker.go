@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+// stringListArg reads an array of strings argument.
+func stringListArg(argsMap *map[string]interface{}, name string) []string {
+	var list []string
+	if items, ok := (*argsMap)[name].([]interface{}); ok {
+		for _, item := range items {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+	}
+	return list
+}
+
+// dockerTimeout reads the timeout argument in seconds.
+func dockerTimeout(argsMap *map[string]interface{}) time.Duration {
+	timeout := defaultDockerTimeout
+	if v := toInt64((*argsMap)["timeout"]); v > 0 {
+		timeout = min(time.Duration(v)*time.Second, maxDockerTimeout)
+	}
+	return timeout
+}
+
+func dockerPSToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolDockerPS, argsMap); err != nil {
+		return "", err
+	}
+
+	all, _ := (*argsMap)["all"].(bool)
+	name, _ := (*argsMap)["name"].(string)
+
+	cli, err := NewDockerClient()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	defer cli.Close()
+	result, err := DockerPS(ctx, cli, all, name)
+	if err != nil {
+		return fmt.Sprintf("Error listing containers: %v", err), nil
+	}
+	return result, nil
+}
+
+func dockerLogsToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolDockerLogs, argsMap); err != nil {
+		return "", err
+	}
+
+	containerID, ok := (*argsMap)["container"].(string)
+	if !ok || strings.TrimSpace(containerID) == "" {
+		return "", fmt.Errorf("container not found in arguments")
+	}
+	since, _ := (*argsMap)["since"].(string)
+
+	cli, err := NewDockerClient()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	defer cli.Close()
+	logs, err := DockerLogs(ctx, cli, containerID, int(toInt64((*argsMap)["lines"])), since)
+	if err != nil {
+		return fmt.Sprintf("Error reading the logs of %s: %v", containerID, err), nil
+	}
+	return logs, nil
+}
+
+func dockerRunToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolDockerRun, argsMap); err != nil {
+		return "", err
+	}
+
+	image, ok := (*argsMap)["image"].(string)
+	if !ok || strings.TrimSpace(image) == "" {
+		return "", fmt.Errorf("image not found in arguments")
+	}
+	opts := DockerRunOptions{
+		Image: strings.TrimSpace(image),
+		Cmd:   stringListArg(argsMap, "command"),
+		Env:   stringListArg(argsMap, "env"),
+	}
+	opts.Name, _ = (*argsMap)["name"].(string)
+	opts.Workdir, _ = (*argsMap)["workdir"].(string)
+	opts.Detach, _ = (*argsMap)["detach"].(bool)
+
+	if !op.toolsUse.AutoApprove {
+		purpose := fmt.Sprintf("Run a container from %s", opts.Image)
+		if len(opts.Cmd) > 0 {
+			purpose += ": " + strings.Join(opts.Cmd, " ")
+		}
+		if opts.Detach {
+			purpose += " (left running)"
+		}
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(purpose, op.toolsUse)
+		}
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return fmt.Sprintf("Operation cancelled by user: run container from '%s'", opts.Image), UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+
+	cli, err := NewDockerClient()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(ctx, dockerTimeout(argsMap))
+	defer cancel()
+	result, err := DockerRun(ctx, cli, opts)
+	if err != nil {
+		if result != "" {
+			return fmt.Sprintf("Error: %v\n%s", err, result), nil
+		}
+		return fmt.Sprintf("Error running %s: %v", opts.Image, err), nil
+	}
+	return result, nil
+}
+
+func dockerExecToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolDockerExec, argsMap); err != nil {
+		return "", err
+	}
+
+	containerID, ok := (*argsMap)["container"].(string)
+	if !ok || strings.TrimSpace(containerID) == "" {
+		return "", fmt.Errorf("container not found in arguments")
+	}
+	cmd := stringListArg(argsMap, "command")
+	if len(cmd) == 0 {
+		return "", fmt.Errorf("command not found in arguments")
+	}
+	workdir, _ := (*argsMap)["workdir"].(string)
+	user, _ := (*argsMap)["user"].(string)
+
+	if !op.toolsUse.AutoApprove {
+		purpose := fmt.Sprintf("Run in container %s: %s", containerID, strings.Join(cmd, " "))
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(purpose, op.toolsUse)
+		}
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return fmt.Sprintf("Operation cancelled by user: exec in container '%s'", containerID), UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+
+	cli, err := NewDockerClient()
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	defer cli.Close()
+	ctx, cancel := context.WithTimeout(ctx, dockerTimeout(argsMap))
+	defer cancel()
+	result, err := DockerExec(ctx, cli, containerID, cmd, workdir, user)
+	if err != nil {
+		if result != "" {
+			return fmt.Sprintf("Error: %v\n%s", err, result), nil
+		}
+		return fmt.Sprintf("Error running in %s: %v", containerID, err), nil
+	}
+	return result, nil
+}
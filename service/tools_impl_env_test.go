@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilteredEnviron(t *testing.T) {
+	environ := []string{
+		"PATH=/usr/bin",
+		"OPENAI_API_KEY=sk-123",
+		"GITHUB_TOKEN=ghp_123",
+		"DB_PASSWORD=hunter2",
+		"DATABASE_URL=postgres://app:hunter2@db/app",
+		"HOME=/home/dev",
+		"AWS_SECRET_ACCESS_KEY=abc",
+		"GOFLAGS=-mod=mod",
+		"EMPTY=",
+	}
+	want := []string{"EMPTY=", "GOFLAGS=-mod=mod", "HOME=/home/dev", "PATH=/usr/bin"}
+	if got := filteredEnviron(environ); !reflect.DeepEqual(got, want) {
+		t.Errorf("filteredEnviron = %v, want %v", got, want)
+	}
+}
+
+func TestGitSummary(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	t.Chdir(t.TempDir())
+	if got := gitSummary(context.Background()); got != "" {
+		t.Errorf("gitSummary outside a repository = %q", got)
+	}
+	if _, err := runGit(context.Background(), ".", "init", "-q", "-b", "main"); err != nil {
+		t.Skip(err)
+	}
+	got := gitSummary(context.Background())
+	for _, want := range []string{"Branch: ", "main", "Working tree: clean"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("gitSummary should contain %q, got %q", want, got)
+		}
+	}
+}
+
+func TestGetEnvironmentTool(t *testing.T) {
+	t.Setenv("GLLM_TEST_TOKEN", "secret-value")
+	op := &OpenProcessor{ctx: context.Background()}
+	result, err := getEnvironmentToolCallImpl(context.Background(), &map[string]interface{}{"include_env": true}, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"OS: ", "CPUs: ", "Working directory: ", "Runtimes:", "Environment variables"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("get_environment should contain %q, got:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "secret-value") {
+		t.Errorf("get_environment leaked a secret:\n%s", result)
+	}
+}
@@ -0,0 +1,393 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+const (
+	// DefaultFormatTimeout bounds a single formatter or linter run
+	DefaultFormatTimeout = 60 * time.Second
+
+	// maxLintDiagnostics caps the diagnostics reported by one lint call
+	maxLintDiagnostics = 100
+)
+
+// prettierExtensions are the file types handed to prettier and eslint.
+var prettierExtensions = map[string]bool{
+	".js": true, ".jsx": true, ".mjs": true, ".cjs": true,
+	".ts": true, ".tsx": true, ".mts": true, ".cts": true,
+	".json": true, ".css": true, ".scss": true, ".less": true,
+	".html": true, ".vue": true, ".md": true, ".yaml": true, ".yml": true,
+}
+
+// eslintExtensions are the file types eslint understands by default.
+var eslintExtensions = map[string]bool{
+	".js": true, ".jsx": true, ".mjs": true, ".cjs": true,
+	".ts": true, ".tsx": true, ".mts": true, ".cts": true, ".vue": true,
+}
+
+// lintDiagnosticPattern matches the file:line[:col]: message lines printed by go vet and ruff.
+var lintDiagnosticPattern = regexp.MustCompile(`^(\S+?):(\d+)(?::(\d+))?:? (.+)$`)
+
+// findProjectTool looks for a tool installed in the project's node_modules first, then on PATH.
+func findProjectTool(name, path string) string {
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err == nil {
+		for {
+			candidate := filepath.Join(dir, "node_modules", ".bin", name)
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate
+			}
+			parent := filepath.Dir(dir)
+			if parent == dir {
+				break
+			}
+			dir = parent
+		}
+	}
+	if p, err := exec.LookPath(name); err == nil {
+		return p
+	}
+	return ""
+}
+
+// formatterCommand returns the formatter for path; it reads the source on stdin and writes the result to stdout.
+func formatterCommand(path string) (string, []string) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case ext == ".go":
+		if bin := findProjectTool("goimports", path); bin != "" {
+			return "goimports", []string{bin, "-srcdir", filepath.Dir(path)}
+		}
+		if bin := findProjectTool("gofmt", path); bin != "" {
+			return "gofmt", []string{bin}
+		}
+	case ext == ".py" || ext == ".pyi":
+		if bin := findProjectTool("ruff", path); bin != "" {
+			return "ruff", []string{bin, "format", "--stdin-filename", path, "-"}
+		}
+	case prettierExtensions[ext]:
+		if bin := findProjectTool("prettier", path); bin != "" {
+			return "prettier", []string{bin, "--stdin-filepath", path}
+		}
+	}
+	return "", nil
+}
+
+// linterCommand returns the linter for path and the directory to run it in.
+func linterCommand(path string) (string, []string, string) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch {
+	case ext == ".go":
+		if bin := findProjectTool("go", path); bin != "" {
+			// go vet works on packages, so vet the file's directory
+			return "go vet", []string{bin, "vet", "."}, filepath.Dir(path)
+		}
+	case ext == ".py" || ext == ".pyi":
+		if bin := findProjectTool("ruff", path); bin != "" {
+			return "ruff", []string{bin, "check", "--output-format=concise", "--no-fix", path}, ""
+		}
+	case eslintExtensions[ext]:
+		if bin := findProjectTool("eslint", path); bin != "" {
+			return "eslint", []string{bin, "--format", "json", path}, ""
+		}
+	}
+	return "", nil, ""
+}
+
+// runCodeTool runs argv in dir with optional stdin, returning stdout, stderr and the exit code.
+func runCodeTool(ctx context.Context, argv []string, dir string, stdin []byte) (string, string, int, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, DefaultFormatTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Dir = dir
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	cmd.Env = append(os.Environ(), "NO_COLOR=1")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", "", -1, fmt.Errorf("%s timed out after %v", filepath.Base(argv[0]), DefaultFormatTimeout)
+	}
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return "", "", -1, err
+		}
+		return stdout.String(), stderr.String(), exitErr.ExitCode(), nil
+	}
+	return stdout.String(), stderr.String(), 0, nil
+}
+
+// pathsFromArgs accepts either a paths array or a single path string.
+func pathsFromArgs(argsMap *map[string]interface{}) []string {
+	var paths []string
+	if list, ok := (*argsMap)["paths"].([]interface{}); ok {
+		for _, p := range list {
+			if s, ok := p.(string); ok && s != "" {
+				paths = append(paths, s)
+			}
+		}
+	}
+	if p, ok := (*argsMap)["path"].(string); ok && p != "" {
+		paths = append(paths, p)
+	}
+	return paths
+}
+
+// formatSource runs the formatter for path over content.
+func formatSource(ctx context.Context, path string, content []byte) (formatted []byte, tool string, err error) {
+	tool, argv := formatterCommand(path)
+	if tool == "" {
+		return nil, "", nil
+	}
+	stdout, stderr, code, err := runCodeTool(ctx, argv, "", content)
+	if err != nil {
+		return nil, tool, err
+	}
+	if code != 0 {
+		msg := strings.TrimSpace(stderr)
+		if msg == "" {
+			msg = strings.TrimSpace(stdout)
+		}
+		return nil, tool, fmt.Errorf("%s failed: %s", tool, limitLines(msg, maxFailureMessageLines))
+	}
+	return []byte(stdout), tool, nil
+}
+
+type formatChange struct {
+	path      string
+	tool      string
+	original  []byte
+	formatted []byte
+}
+
+func formatCodeToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolFormatCode, argsMap); err != nil {
+		return "", err
+	}
+
+	paths := pathsFromArgs(argsMap)
+	if len(paths) == 0 {
+		return "", fmt.Errorf("paths not found in arguments")
+	}
+
+	var changes []formatChange
+	var notes []string
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s: error reading file: %v", path, err))
+			continue
+		}
+		formatted, tool, err := formatSource(ctx, path, content)
+		switch {
+		case tool == "":
+			notes = append(notes, fmt.Sprintf("%s: no formatter available (install gofmt/goimports, ruff or prettier)", path))
+		case err != nil:
+			notes = append(notes, fmt.Sprintf("%s: %v", path, err))
+		case bytes.Equal(formatted, content):
+			notes = append(notes, fmt.Sprintf("%s: already formatted (%s)", path, tool))
+		default:
+			changes = append(changes, formatChange{path: path, tool: tool, original: content, formatted: formatted})
+		}
+	}
+
+	if len(changes) > 0 && !op.toolsUse.AutoApprove {
+		var diff strings.Builder
+		for _, c := range changes {
+			diff.WriteString(fmt.Sprintf("── %s (%s)\n", c.path, c.tool))
+			diff.WriteString(op.interaction.RequestDiff(string(c.original), string(c.formatted), 3))
+			diff.WriteString("\n")
+		}
+		op.toolsUse.FilePath = changes[0].path
+		op.showDiff(strings.TrimRight(diff.String(), "\n"))
+
+		purpose, _ := (*argsMap)["purpose"].(string)
+		if purpose == "" {
+			purpose = fmt.Sprintf("format %d file(s)", len(changes))
+		}
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(purpose, op.toolsUse)
+		}
+		op.closeDiff()
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return fmt.Sprintf("Operation cancelled by user: format %d file(s). No files were changed.", len(changes)),
+				UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+
+	for _, c := range changes {
+		if fileChangedOnDisk(c.path) {
+			notes = append(notes, fmt.Sprintf(ToolRespFileChangedOnDisk, c.path))
+			continue
+		}
+		if err := writeFileAtomic(c.path, c.formatted); err != nil {
+			notes = append(notes, fmt.Sprintf("%s: error writing file: %v", c.path, err))
+			continue
+		}
+		rememberFileContent(c.path, c.formatted)
+		notes = append(notes, fmt.Sprintf("%s: formatted with %s", c.path, c.tool))
+	}
+
+	return strings.Join(notes, "\n"), nil
+}
+
+// autoFormatFile formats a file the agent just wrote when the agent enables auto_format.
+// It returns a note for the tool response, or "" when nothing changed.
+func (op *OpenProcessor) autoFormatFile(ctx context.Context, path string) string {
+	if !op.autoFormat {
+		return ""
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	formatted, tool, err := formatSource(ctx, path, content)
+	if tool == "" {
+		return ""
+	}
+	if err != nil {
+		return fmt.Sprintf("Auto-format skipped: %v", err)
+	}
+	if bytes.Equal(formatted, content) {
+		return ""
+	}
+	if err := writeFileAtomic(path, formatted); err != nil {
+		return fmt.Sprintf("Auto-format failed: %v", err)
+	}
+	rememberFileContent(path, formatted)
+	return fmt.Sprintf("The file was auto-formatted with %s, read it again before making further edits.", tool)
+}
+
+// eslintResult is the subset of eslint's JSON formatter output that is reported.
+type eslintResult struct {
+	FilePath string `json:"filePath"`
+	Messages []struct {
+		RuleID   string `json:"ruleId"`
+		Severity int    `json:"severity"`
+		Message  string `json:"message"`
+		Line     int    `json:"line"`
+		Column   int    `json:"column"`
+	} `json:"messages"`
+}
+
+// parseLintOutput turns linter output into file:line:col: message diagnostics.
+// Relative paths are reported relative to dir, the directory the linter ran in.
+func parseLintOutput(tool, dir, output string) []string {
+	var diags []string
+	if tool == "eslint" {
+		var results []eslintResult
+		if err := json.Unmarshal([]byte(output), &results); err != nil {
+			return nil
+		}
+		for _, r := range results {
+			for _, m := range r.Messages {
+				level := "warning"
+				if m.Severity == 2 {
+					level = "error"
+				}
+				msg := fmt.Sprintf("%s:%d:%d: %s: %s", r.FilePath, m.Line, m.Column, level, m.Message)
+				if m.RuleID != "" {
+					msg += fmt.Sprintf(" (%s)", m.RuleID)
+				}
+				diags = append(diags, msg)
+			}
+		}
+		return diags
+	}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimPrefix(strings.TrimSpace(line), "vet: ")
+		m := lintDiagnosticPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if dir != "" && !filepath.IsAbs(m[1]) {
+			line = filepath.Join(dir, m[1]) + line[len(m[1]):]
+		}
+		diags = append(diags, line)
+	}
+	return diags
+}
+
+func lintToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolLint, argsMap); err != nil {
+		return "", err
+	}
+
+	paths := pathsFromArgs(argsMap)
+	if len(paths) == 0 {
+		return "", fmt.Errorf("paths not found in arguments")
+	}
+
+	var diags, notes []string
+	seen := make(map[string]bool) // go vet runs once per package
+	for _, path := range paths {
+		if _, err := os.Stat(path); err != nil {
+			notes = append(notes, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		tool, argv, dir := linterCommand(path)
+		if tool == "" {
+			notes = append(notes, fmt.Sprintf("%s: no linter available (install go, ruff or eslint)", path))
+			continue
+		}
+		key := dir + "\x00" + strings.Join(argv, "\x00")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		stdout, stderr, code, err := runCodeTool(ctx, argv, dir, nil)
+		if err != nil {
+			notes = append(notes, fmt.Sprintf("%s: %v", path, err))
+			continue
+		}
+		found := parseLintOutput(tool, dir, stdout+"\n"+stderr)
+		if len(found) == 0 && code != 0 {
+			// The linter failed without reporting diagnostics, e.g. a config error
+			msg := strings.TrimSpace(stderr + "\n" + stdout)
+			notes = append(notes, fmt.Sprintf("%s: %s exited with code %d: %s", path, tool, code, limitLines(msg, maxFailureMessageLines)))
+			continue
+		}
+		for _, d := range found {
+			diags = append(diags, fmt.Sprintf("%s [%s]", d, tool))
+		}
+	}
+
+	var sb strings.Builder
+	if len(diags) == 0 {
+		sb.WriteString("No lint issues found.")
+	} else {
+		fmt.Fprintf(&sb, "%d lint issue(s):\n", len(diags))
+		for i, d := range diags {
+			if i == maxLintDiagnostics {
+				fmt.Fprintf(&sb, "... %d more issues not shown\n", len(diags)-maxLintDiagnostics)
+				break
+			}
+			sb.WriteString(d + "\n")
+		}
+	}
+	for _, n := range notes {
+		sb.WriteString("\n" + n)
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
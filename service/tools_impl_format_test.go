@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+const unformattedGo = "package demo\n\nfunc  Add(a,b int) int {\nreturn a+b\n}\n"
+
+func TestFormatCodeToolCallImpl(t *testing.T) {
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt not installed")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "demo.go")
+	os.WriteFile(path, []byte(unformattedGo), 0644)
+
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}}
+	args := map[string]interface{}{"paths": []interface{}{path, filepath.Join(dir, "notes.txt")}}
+	out, err := formatCodeToolCallImpl(context.Background(), &args, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	if !strings.Contains(string(got), "func Add(a, b int) int {\n\treturn a + b\n}") {
+		t.Errorf("file was not formatted:\n%s", got)
+	}
+	if !strings.Contains(out, path+": formatted with") || !strings.Contains(out, "notes.txt: error reading file") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	if out, _ = formatCodeToolCallImpl(context.Background(), &args, op); !strings.Contains(out, path+": already formatted") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestAutoFormatAfterWrite(t *testing.T) {
+	if _, err := exec.LookPath("gofmt"); err != nil {
+		t.Skip("gofmt not installed")
+	}
+	path := filepath.Join(t.TempDir(), "demo.go")
+
+	for _, autoFormat := range []bool{false, true} {
+		op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}, autoFormat: autoFormat}
+		args := map[string]interface{}{"path": path, "content": unformattedGo}
+		out, err := writeFileToolCallImpl(context.Background(), &args, op)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _ := os.ReadFile(path)
+		formatted := string(got) != unformattedGo
+		if formatted != autoFormat || strings.Contains(out, "auto-formatted") != autoFormat {
+			t.Errorf("autoFormat=%v: formatted=%v, output %q", autoFormat, formatted, out)
+		}
+	}
+}
+
+func TestLintToolCallImpl(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not installed")
+	}
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/demo\n\ngo 1.21\n"), 0644)
+	path := filepath.Join(dir, "demo.go")
+	os.WriteFile(path, []byte("package demo\n\nimport \"fmt\"\n\nfunc Hello() {\n\tfmt.Printf(\"%d\\n\", \"x\")\n}\n"), 0644)
+
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}}
+	args := map[string]interface{}{"paths": []interface{}{path}}
+	out, err := lintToolCallImpl(context.Background(), &args, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "1 lint issue(s):") || !strings.Contains(out, path+":6:") || !strings.Contains(out, "[go vet]") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestParseLintOutputESLint(t *testing.T) {
+	out := `[{"filePath":"/p/a.ts","messages":[{"ruleId":"no-unused-vars","severity":2,"message":"'x' is unused","line":3,"column":7}]}]`
+	got := parseLintOutput("eslint", "", out)
+	if len(got) != 1 || got[0] != "/p/a.ts:3:7: error: 'x' is unused (no-unused-vars)" {
+		t.Errorf("unexpected diagnostics: %v", got)
+	}
+}
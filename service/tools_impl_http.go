@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+func httpRequestToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolHTTPRequest, argsMap); err != nil {
+		return "", err
+	}
+
+	url, ok := (*argsMap)["url"].(string)
+	if !ok || strings.TrimSpace(url) == "" {
+		return "", fmt.Errorf("url not found in arguments")
+	}
+	req := HTTPRequest{URL: strings.TrimSpace(url)}
+	req.Method, _ = (*argsMap)["method"].(string)
+	req.Body, _ = (*argsMap)["body"].(string)
+	req.Profile, _ = (*argsMap)["profile"].(string)
+	if headers, ok := (*argsMap)["headers"].(map[string]interface{}); ok {
+		req.Headers = make(map[string]string, len(headers))
+		for k, v := range headers {
+			req.Headers[k] = fmt.Sprint(v)
+		}
+	}
+	if v := toInt64((*argsMap)["timeout"]); v > 0 {
+		req.Timeout = time.Duration(v) * time.Second
+	}
+	req.MaxBytes = int(toInt64((*argsMap)["max_bytes"]))
+
+	if msg, err := confirmHTTPRequest(op, req); err != nil {
+		return msg, err
+	}
+
+	transport, err := networkTransport(op.network)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	result, err := DoHTTPRequest(ctx, transport, req)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return result, nil
+}
+
+// confirmHTTPRequest asks before a request that may change the remote side, reading needs no
+// confirmation.
+func confirmHTTPRequest(op *OpenProcessor, req HTTPRequest) (string, error) {
+	method := strings.ToUpper(req.Method)
+	if readOnlyHTTPMethod(method) || op.toolsUse.AutoApprove {
+		return "", nil
+	}
+	purpose := fmt.Sprintf("Send %s %s", method, req.URL)
+	if req.Profile != "" {
+		purpose += fmt.Sprintf(" with the credential of '%s'", req.Profile)
+	}
+	if op.interaction != nil {
+		op.interaction.RequestConfirm(purpose, op.toolsUse)
+	}
+	if op.toolsUse.Confirm == data.ToolConfirmCancel {
+		return fmt.Sprintf("Operation cancelled by user: %s %s", method, req.URL), UserCancelError{Reason: UserCancelReasonDeny}
+	}
+	return "", nil
+}
+
+func readOnlyHTTPMethod(method string) bool {
+	return method == "" || method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// apiToolCallImpl calls an operation of an API imported with gllm api import.
+func apiToolCallImpl(ctx context.Context, name string, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	api, operation := FindAPIOperation(name)
+	if operation == nil {
+		return "", fmt.Errorf("API operation %s not found", name)
+	}
+	// Reading operations are allowed in plan mode like web_fetch
+	if !readOnlyHTTPMethod(operation.Method) {
+		if err := CheckToolPermission(name, argsMap); err != nil {
+			return "", err
+		}
+	}
+
+	req, err := BuildAPIRequest(api, operation, *argsMap)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	if msg, err := confirmHTTPRequest(op, req); err != nil {
+		return msg, err
+	}
+
+	transport, err := networkTransport(op.network)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	result, err := DoHTTPRequest(ctx, transport, req)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	return result, nil
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+func findDefinitionToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolFindDefinition, argsMap); err != nil {
+		return "", err
+	}
+	return lspLocationQuery(ctx, argsMap, op, "textDocument/definition", nil, "Definition")
+}
+
+func findReferencesToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolFindReferences, argsMap); err != nil {
+		return "", err
+	}
+	includeDeclaration, _ := (*argsMap)["include_declaration"].(bool)
+	extra := map[string]interface{}{"context": map[string]bool{"includeDeclaration": includeDeclaration}}
+	return lspLocationQuery(ctx, argsMap, op, "textDocument/references", extra, "References")
+}
+
+func documentSymbolsToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolDocumentSymbols, argsMap); err != nil {
+		return "", err
+	}
+	path, ok := (*argsMap)["path"].(string)
+	if !ok {
+		return "", fmt.Errorf("path not found in arguments")
+	}
+
+	client, languageID, err := getLSPClient(ctx, path)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	uri, err := client.syncDocument(path, languageID)
+	if err != nil {
+		return fmt.Sprintf("Error reading file %s: %v", path, err), nil
+	}
+	var symbols []lspDocumentSymbol
+	params := map[string]interface{}{"textDocument": map[string]string{"uri": uri}}
+	if err := client.Call(ctx, "textDocument/documentSymbol", params, &symbols); err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	if len(symbols) == 0 {
+		return fmt.Sprintf("No symbols found in %s", path), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Symbols of %s:\n", path))
+	writeLSPSymbols(&sb, symbols, 0)
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// lspLocationQuery runs a position based request and lists the locations it returns.
+func lspLocationQuery(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor, method string, extra map[string]interface{}, title string) (string, error) {
+	path, ok := (*argsMap)["path"].(string)
+	if !ok {
+		return "", fmt.Errorf("path not found in arguments")
+	}
+	pos, err := lspPositionFromArgs(path, argsMap)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	client, languageID, err := getLSPClient(ctx, path)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	uri, err := client.syncDocument(path, languageID)
+	if err != nil {
+		return fmt.Sprintf("Error reading file %s: %v", path, err), nil
+	}
+	params := map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     pos,
+	}
+	for k, v := range extra {
+		params[k] = v
+	}
+	var raw json.RawMessage
+	if err := client.Call(ctx, method, params, &raw); err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	locations := decodeLSPLocations(raw)
+	if len(locations) == 0 {
+		return fmt.Sprintf("%s: none found for %s:%d:%d", title, path, pos.Line+1, pos.Character+1), nil
+	}
+	return formatLSPLocations(title, locations), nil
+}
+
+// lspPositionFromArgs converts the 1-based line and the symbol or column arguments to an LSP position.
+func lspPositionFromArgs(path string, argsMap *map[string]interface{}) (lspPosition, error) {
+	line := int(toInt64((*argsMap)["line"]))
+	if line < 1 {
+		return lspPosition{}, fmt.Errorf("line must be a 1-based line number")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return lspPosition{}, err
+	}
+	lines := strings.Split(string(content), "\n")
+	if line > len(lines) {
+		return lspPosition{}, fmt.Errorf("line %d exceeds total lines (%d) in %s", line, len(lines), path)
+	}
+	text := strings.TrimRight(lines[line-1], "\r")
+
+	var prefix string
+	if symbol, _ := (*argsMap)["symbol"].(string); symbol != "" {
+		idx := findIdentifier(text, symbol)
+		if idx < 0 {
+			return lspPosition{}, fmt.Errorf("symbol %q not found on line %d: %s", symbol, line, strings.TrimSpace(text))
+		}
+		prefix = text[:idx]
+	} else if column := int(toInt64((*argsMap)["column"])); column >= 1 {
+		runes := []rune(text)
+		prefix = string(runes[:min(column-1, len(runes))])
+	} else {
+		return lspPosition{}, fmt.Errorf("give the symbol name or the 1-based column on line %d", line)
+	}
+	// LSP columns count UTF-16 code units
+	return lspPosition{Line: line - 1, Character: len(utf16.Encode([]rune(prefix)))}, nil
+}
+
+// findIdentifier returns the byte offset of name as a whole word in text, or -1.
+func findIdentifier(text, name string) int {
+	isIdent := func(r rune) bool { return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) }
+	for from := 0; from < len(text); {
+		idx := strings.Index(text[from:], name)
+		if idx < 0 {
+			break
+		}
+		start, end := from+idx, from+idx+len(name)
+		before, after := ' ', ' '
+		if start > 0 {
+			before = []rune(text[:start])[len([]rune(text[:start]))-1]
+		}
+		if end < len(text) {
+			after = []rune(text[end:])[0]
+		}
+		if !isIdent(before) && !isIdent(after) {
+			return start
+		}
+		from = end
+	}
+	return strings.Index(text, name)
+}
+
+// decodeLSPLocations accepts a Location, a list of Locations or a list of LocationLinks.
+func decodeLSPLocations(raw json.RawMessage) []lspLocation {
+	var list []lspLocation
+	if err := json.Unmarshal(raw, &list); err != nil {
+		var single lspLocation
+		if err := json.Unmarshal(raw, &single); err != nil {
+			return nil
+		}
+		list = []lspLocation{single}
+	}
+	locations := make([]lspLocation, 0, len(list))
+	for _, loc := range list {
+		if loc.TargetURI != "" {
+			loc.URI = loc.TargetURI
+			if loc.TargetSelectionRange != nil {
+				loc.Range = *loc.TargetSelectionRange
+			}
+		}
+		if loc.URI != "" {
+			locations = append(locations, loc)
+		}
+	}
+	return locations
+}
+
+func formatLSPLocations(title string, locations []lspLocation) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%s (%d):\n", title, len(locations)))
+
+	cwd, _ := os.Getwd()
+	fileLines := make(map[string][]string)
+	for i, loc := range locations {
+		if i == lspMaxResults {
+			sb.WriteString(fmt.Sprintf("... %d more not shown\n", len(locations)-lspMaxResults))
+			break
+		}
+		path := uriToPath(loc.URI)
+		lines, ok := fileLines[path]
+		if !ok {
+			if content, err := os.ReadFile(path); err == nil {
+				lines = strings.Split(string(content), "\n")
+			}
+			fileLines[path] = lines
+		}
+		display := path
+		if rel, err := filepath.Rel(cwd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			display = rel
+		}
+		line := loc.Range.Start.Line
+		source := ""
+		if line < len(lines) {
+			source = strings.TrimSpace(lines[line])
+		}
+		sb.WriteString(fmt.Sprintf("%s:%d:%d: %s\n", display, line+1, loc.Range.Start.Character+1, source))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func writeLSPSymbols(sb *strings.Builder, symbols []lspDocumentSymbol, depth int) {
+	for _, s := range symbols {
+		r := s.Range
+		if s.Location != nil {
+			r = s.Location.Range
+		}
+		name := s.Name
+		if s.Detail != "" {
+			name += " " + s.Detail
+		}
+		if s.ContainerName != "" {
+			name = s.ContainerName + "." + name
+		}
+		sb.WriteString(fmt.Sprintf("%s%s %s (lines %d-%d)\n", strings.Repeat("  ", depth),
+			lspSymbolKindName(s.Kind), name, r.Start.Line+1, r.End.Line+1))
+		writeLSPSymbols(sb, s.Children, depth+1)
+	}
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+)
+
+// scmProviderFromArgs resolves the repository of the tool call and its API client.
+func scmProviderFromArgs(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (SCMRepo, SCMProvider, error) {
+	ref, _ := (*argsMap)["repo"].(string)
+	provider, _ := (*argsMap)["provider"].(string)
+	repo, err := ResolveSCMRepo(ctx, ref, provider)
+	if err != nil {
+		return SCMRepo{}, nil, err
+	}
+	// Reach the code host through the model's proxy and TLS settings, like web_fetch
+	transport, err := networkTransport(op.network)
+	if err != nil {
+		return SCMRepo{}, nil, err
+	}
+	client, err := NewSCMProvider(repo, transport)
+	if err != nil {
+		return SCMRepo{}, nil, err
+	}
+	return repo, client, nil
+}
+
+func ghListIssuesToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolGHListIssues, argsMap); err != nil {
+		return "", err
+	}
+
+	repo, client, err := scmProviderFromArgs(ctx, argsMap, op)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	q := SCMIssueQuery{State: "open", Limit: 20}
+	if state, _ := (*argsMap)["state"].(string); state != "" {
+		q.State = state
+	}
+	if limit := toInt64((*argsMap)["limit"]); limit > 0 {
+		q.Limit = int(min(limit, 100))
+	}
+	if labels, ok := (*argsMap)["labels"].([]interface{}); ok {
+		for _, l := range labels {
+			if s, ok := l.(string); ok && s != "" {
+				q.Labels = append(q.Labels, s)
+			}
+		}
+	}
+
+	issues, err := client.ListIssues(ctx, q)
+	if err != nil {
+		return fmt.Sprintf("Error listing issues of %s: %v", repo, err), nil
+	}
+	if len(issues) == 0 {
+		return fmt.Sprintf("No %s issues found in %s.", q.State, repo), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d %s issues of %s:\n", len(issues), q.State, repo))
+	for _, issue := range issues {
+		sb.WriteString(fmt.Sprintf("#%d [%s] %s (by %s, %d comments)", issue.Number, issue.State, issue.Title, issue.Author, issue.Comments))
+		if len(issue.Labels) > 0 {
+			sb.WriteString(fmt.Sprintf(" labels: %s", strings.Join(issue.Labels, ", ")))
+		}
+		sb.WriteString(fmt.Sprintf("\n  %s\n", issue.URL))
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+func ghGetPRDiffToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolGHGetPRDiff, argsMap); err != nil {
+		return "", err
+	}
+
+	number := int(toInt64((*argsMap)["number"]))
+	if number <= 0 {
+		return "", fmt.Errorf("number not found in arguments")
+	}
+
+	repo, client, err := scmProviderFromArgs(ctx, argsMap, op)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	diff, err := client.GetPullRequestDiff(ctx, number)
+	if err != nil {
+		return fmt.Sprintf("Error getting the diff of %s#%d: %v", repo, number, err), nil
+	}
+	if strings.TrimSpace(diff) == "" {
+		return fmt.Sprintf("The pull request %s#%d has no changes.", repo, number), nil
+	}
+	return fmt.Sprintf("Diff of %s#%d:\n%s", repo, number, truncateSCMDiff(diff)), nil
+}
+
+func ghCommentToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolGHComment, argsMap); err != nil {
+		return "", err
+	}
+
+	number := int(toInt64((*argsMap)["number"]))
+	if number <= 0 {
+		return "", fmt.Errorf("number not found in arguments")
+	}
+	body, ok := (*argsMap)["body"].(string)
+	if !ok || strings.TrimSpace(body) == "" {
+		return "", fmt.Errorf("body not found in arguments")
+	}
+	target, _ := (*argsMap)["target"].(string)
+	pullRequest := target != "issue"
+	kind := "pull request"
+	if !pullRequest {
+		kind = "issue"
+	}
+
+	repo, client, err := scmProviderFromArgs(ctx, argsMap, op)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	// Comments are public, the user sees the text before it is posted
+	if !op.toolsUse.AutoApprove {
+		purpose := fmt.Sprintf("Comment on %s %s#%d:\n%s", kind, repo, number, body)
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(purpose, op.toolsUse)
+		}
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return fmt.Sprintf("Operation cancelled by user: comment on %s#%d", repo, number), UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+
+	url, err := client.Comment(ctx, number, pullRequest, body)
+	if err != nil {
+		return fmt.Sprintf("Error commenting on %s#%d: %v", repo, number, err), nil
+	}
+	return fmt.Sprintf("Successfully commented on %s %s#%d: %s", kind, repo, number, url), nil
+}
+
+func ghCreatePRToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolGHCreatePR, argsMap); err != nil {
+		return "", err
+	}
+
+	title, ok := (*argsMap)["title"].(string)
+	if !ok || strings.TrimSpace(title) == "" {
+		return "", fmt.Errorf("title not found in arguments")
+	}
+	pr := SCMPullRequest{Title: title}
+	pr.Body, _ = (*argsMap)["body"].(string)
+	pr.Head, _ = (*argsMap)["head"].(string)
+	pr.Base, _ = (*argsMap)["base"].(string)
+	pr.Draft, _ = (*argsMap)["draft"].(bool)
+
+	repo, client, err := scmProviderFromArgs(ctx, argsMap, op)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	if pr.Head == "" {
+		branch, err := runGit(ctx, ".", "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil || branch == "HEAD" {
+			return "Error: no head branch given and the working directory is not on a branch", nil
+		}
+		pr.Head = branch
+	}
+
+	if !op.toolsUse.AutoApprove {
+		base := pr.Base
+		if base == "" {
+			base = "the default branch"
+		}
+		purpose := fmt.Sprintf("Open a pull request on %s from %s into %s: %s", repo, pr.Head, base, pr.Title)
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(purpose, op.toolsUse)
+		}
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return fmt.Sprintf("Operation cancelled by user: open pull request '%s'", pr.Title), UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+
+	url, err := client.CreatePullRequest(ctx, pr)
+	if err != nil {
+		return fmt.Sprintf("Error opening the pull request on %s: %v", repo, err), nil
+	}
+	return fmt.Sprintf("Successfully opened the pull request: %s", url), nil
+}
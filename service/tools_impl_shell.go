@@ -5,23 +5,43 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
-	"runtime"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/internal/event"
 )
 
 const (
 	DefaultShellTimeout = 30 * time.Second
 
 	// ToolRespShellOutput is the template for the response to the user after executing a command.
-	ToolRespShellOutput = `shell executed: %s
+	ToolRespShellOutput = `shell executed (%s): %s
+Status:
+%s
+%s`
+
+	// ToolRespSkillScriptOutput is the template for the response after running a skill script.
+	ToolRespSkillScriptOutput = `script '%s' of skill '%s' executed: %s
 Status:
 %s
 %s`
 )
 
-func shellToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+// commandWaitDelay is how long a cancelled command may keep its output open, after which
+// the output read so far is returned.
+const commandWaitDelay = 2 * time.Second
+
+// stopOnCancel makes a cancel of the tool call kill the command with the processes it
+// started, and not wait for a child that still holds the output pipe.
+func stopOnCancel(cmd *exec.Cmd) *exec.Cmd {
+	killProcessGroup(cmd)
+	cmd.WaitDelay = commandWaitDelay
+	return cmd
+}
+
+func shellToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolShell, argsMap); err != nil {
 		return "", err
 	}
@@ -31,6 +51,11 @@ func shellToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (stri
 		return "", fmt.Errorf("command not found in arguments")
 	}
 
+	shell, err := ResolveShell(op.shell)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
 	// Get timeout from arguments, default to DefaultShellTimeout
 	timeout := DefaultShellTimeout
 	if timeoutValue, exists := (*argsMap)["timeout"]; exists {
@@ -58,24 +83,20 @@ func shellToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (stri
 
 	var errStr string
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	// Create context with timeout, derived from the generation context so an interrupt kills the command
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	// Do the real command with timeout
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.CommandContext(ctx, "cmd", "/C", cmdStr)
-	} else {
-		cmd = exec.CommandContext(ctx, "sh", "-c", cmdStr)
-	}
-
+	cmd := stopOnCancel(shell.Command(ctx, cmdStr))
 	out, err := cmd.CombinedOutput()
 
 	// Handle command exec failed
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			errStr = fmt.Sprintf("Command timed out after %v", timeout)
+		} else if ctx.Err() == context.Canceled {
+			errStr = "Command interrupted by user"
 		} else {
 			var exitCode int
 			if exitError, ok := err.(*exec.ExitError); ok {
@@ -86,7 +107,7 @@ func shellToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (stri
 	}
 
 	// Output the result
-	outStr := string(out)
+	outStr := DecodeShellOutput(out)
 	if outStr != "" {
 		outStr = outStr + "\n"
 	}
@@ -104,10 +125,11 @@ func shellToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (stri
 		outputInfo = "Output: <no output>"
 	}
 	// Create a response that prompts the LLM to provide insightful analysis of the command output
-	finalResponse := fmt.Sprintf(ToolRespShellOutput, cmdStr, errorInfo, outputInfo)
+	finalResponse := fmt.Sprintf(ToolRespShellOutput, shell.Name, cmdStr, errorInfo, outputInfo)
 
 	// Respect QuietMode – only output to Console if NOT in quiet mode and Verbose is enabled
 	if !op.quiet && data.GetSettingsStore().GetVerboseEnabled() {
+		defer event.PauseIndicator()()
 		fmt.Fprintf(os.Stderr, "%s$ %s%s\n", data.ToolCallColor, cmdStr, data.ResetSeq)
 		if outStr != "" {
 			fmt.Fprintf(os.Stderr, "%s%s%s", data.ShellOutputColor, outStr, data.ResetSeq)
@@ -119,3 +141,88 @@ func shellToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (stri
 
 	return finalResponse, nil
 }
+
+// runSkillScriptToolCallImpl handles the run_skill_script tool call. Only a script declared
+// by an enabled skill runs, from within the skill's directory and with its declared arguments.
+func runSkillScriptToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolRunSkillScript, argsMap); err != nil {
+		return "", err
+	}
+
+	skillName, _ := (*argsMap)["skill"].(string)
+	scriptName, _ := (*argsMap)["script"].(string)
+	if skillName == "" || scriptName == "" {
+		return "", fmt.Errorf("skill and script are required")
+	}
+
+	skill := GetSkillManager().findAvailableSkill(skillName)
+	if skill == nil {
+		return fmt.Sprintf("Error: skill '%s' not found or disabled", skillName), nil
+	}
+	script := findSkillScript(skill, scriptName)
+	if script == nil {
+		var names []string
+		for _, s := range skill.Scripts {
+			names = append(names, s.Name)
+		}
+		if len(names) == 0 {
+			return fmt.Sprintf("Error: skill '%s' has no scripts", skill.Name), nil
+		}
+		return fmt.Sprintf("Error: skill '%s' has no script '%s', it has: %s", skill.Name, scriptName, strings.Join(names, ", ")), nil
+	}
+	path, err := resolveSkillScriptPath(skill, script)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	values, _ := (*argsMap)["args"].(map[string]interface{})
+	args, err := skillScriptArgs(script, values)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	timeout := DefaultShellTimeout
+	if v := toInt64((*argsMap)["timeout"]); v > 0 {
+		timeout = time.Duration(v) * time.Second
+	}
+
+	cmdLine := strings.TrimSpace(script.Path + " " + strings.Join(args, " "))
+	if !op.toolsUse.AutoApprove {
+		purpose, _ := (*argsMap)["purpose"].(string)
+		description := fmt.Sprintf("Run script '%s' of skill '%s':\n%s", script.Name, skill.Name, cmdLine)
+		if purpose != "" {
+			description = purpose + "\n\n" + description
+		}
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(description, op.toolsUse)
+		}
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return fmt.Sprintf("Operation cancelled by user: run script '%s' of skill '%s'", script.Name, skill.Name), UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	out, err := stopOnCancel(skillScriptCommand(ctx, filepath.Dir(skill.Location), path, args)).CombinedOutput()
+	errorInfo := ""
+	if err != nil {
+		switch {
+		case ctx.Err() == context.DeadlineExceeded:
+			errorInfo = fmt.Sprintf("Error: Script timed out after %v", timeout)
+		case ctx.Err() == context.Canceled:
+			errorInfo = "Error: Script interrupted by user"
+		default:
+			var exitCode int
+			if exitError, ok := err.(*exec.ExitError); ok {
+				exitCode = exitError.ExitCode()
+			}
+			errorInfo = fmt.Sprintf("Error: Script failed with exit code %d: %v", exitCode, err)
+		}
+	}
+
+	outputInfo := "Output: <no output>"
+	if outStr := DecodeShellOutput(out); outStr != "" {
+		outputInfo = fmt.Sprintf("Output:\n%s\n", outStr)
+	}
+	return fmt.Sprintf(ToolRespSkillScriptOutput, script.Name, skill.Name, cmdLine, errorInfo, outputInfo), nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const readSymbolMaxMatches = 5
+
+func readSymbolToolCallImpl(ctx context.Context, argsMap *map[string]interface{}) (string, error) {
+	if err := CheckToolPermission(ToolReadSymbol, argsMap); err != nil {
+		return "", err
+	}
+
+	path, ok := (*argsMap)["path"].(string)
+	if !ok {
+		return "", fmt.Errorf("path not found in arguments")
+	}
+	name, _ := (*argsMap)["symbol"].(string)
+	name = strings.TrimSpace(name)
+
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return fmt.Sprintf("Error accessing file %s: %v", path, err), nil
+	}
+	if fileInfo.Size() > MaxFileSize {
+		return fmt.Sprintf("Error: File %s is too large (%d bytes, max allowed: %d bytes)", path, fileInfo.Size(), MaxFileSize), nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Sprintf("Error reading file %s: %v", path, err), nil
+	}
+	rememberFileContent(path, content)
+
+	symbols, err := ExtractSymbols(path, content)
+	if err != nil && len(symbols) == 0 {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	lines := strings.Split(string(content), "\n")
+
+	// Without a name, list what can be read
+	if name == "" {
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("Symbols of %s (%d lines):\n", path, len(lines)))
+		for _, s := range symbols {
+			sb.WriteString(fmt.Sprintf("%s %s (lines %d-%d)\n", s.Kind, s.Name, s.StartLine, s.EndLine))
+		}
+		return strings.TrimRight(sb.String(), "\n"), nil
+	}
+
+	var matches []CodeSymbol
+	for _, s := range symbols {
+		if s.Matches(name) {
+			matches = append(matches, s)
+		}
+	}
+	if len(matches) == 0 {
+		names := make([]string, 0, len(symbols))
+		for _, s := range symbols {
+			names = append(names, s.Name)
+		}
+		if len(names) > 50 {
+			names = append(names[:50], "...")
+		}
+		return fmt.Sprintf("Symbol %q not found in %s. Available symbols: %s", name, path, strings.Join(names, ", ")), nil
+	}
+
+	var sb strings.Builder
+	for i, s := range matches {
+		if i == readSymbolMaxMatches {
+			sb.WriteString(fmt.Sprintf("... %d more matches, qualify the name (e.g. Type.Method) to narrow it down\n", len(matches)-i))
+			break
+		}
+		end := min(s.EndLine, len(lines))
+		sb.WriteString(fmt.Sprintf("%s %s in %s (lines %d-%d of %d):\n", s.Kind, s.Name, path, s.StartLine, end, len(lines)))
+		for n := s.StartLine; n <= end; n++ {
+			sb.WriteString(fmt.Sprintf("%4d | %s\n", n, lines[n-1]))
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
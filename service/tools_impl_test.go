@@ -1,10 +1,13 @@
 package service
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/activebook/gllm/data"
 )
 
 func TestProcessFileContentRange(t *testing.T) {
@@ -74,7 +77,7 @@ func TestProcessFileContentRange(t *testing.T) {
 			} else {
 				testContent = content
 			}
-			got := processFileContentRange(path, testContent, tt.includeLineNumbers, tt.offset, tt.limit)
+			got := processFileContentRange(path, testContent, tt.includeLineNumbers, tt.offset, tt.limit, 0)
 			for _, want := range tt.wantContains {
 				if !strings.Contains(got, want) {
 					t.Errorf("processFileContentRange() got:\n%s\nwant to contain: %s", got, want)
@@ -89,6 +92,66 @@ func TestProcessFileContentRange(t *testing.T) {
 	}
 }
 
+func TestProcessFileContentRangeTokenCap(t *testing.T) {
+	var sb strings.Builder
+	for i := 1; i <= 100; i++ {
+		sb.WriteString(strings.Repeat("x", 39) + "\n")
+	}
+	got := processFileContentRange("big.txt", []byte(sb.String()), false, 0, -1, 100)
+	if !strings.Contains(got, "[Truncated: output reached the 100 token limit per read.") {
+		t.Fatalf("expected a truncation notice, got:\n%s", got)
+	}
+	if !strings.Contains(got, "of 101):") || !strings.Contains(got, "continue with start_line=") {
+		t.Errorf("expected range metadata, got:\n%s", got)
+	}
+
+	// A single line longer than the cap is cut rather than dropped
+	got = processFileContentRange("long.txt", []byte(strings.Repeat("y", 10000)), false, 0, -1, 10)
+	if !strings.Contains(got, "lines 1-1 of 1") || !strings.Contains(got, "line 1 is longer than the 10 token limit") {
+		t.Errorf("expected the long line to be cut, got:\n%s", got)
+	}
+}
+
+func TestReadFileToolCallImplRange(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "lines.txt")
+	if err := os.WriteFile(path, []byte("a\nb\nc\nd\ne\nf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want []string
+	}{
+		{"start and end", map[string]interface{}{"start_line": float64(2), "end_line": float64(3)},
+			[]string{"(lines 2-3 of 6):\nb\nc", "[3 more lines, continue with start_line=4]"}},
+		{"max lines wins when smaller", map[string]interface{}{"start_line": float64(2), "end_line": float64(6), "max_lines": float64(1)},
+			[]string{"(lines 2-2 of 6):\nb\n"}},
+		{"legacy offset and limit", map[string]interface{}{"offset": float64(5), "limit": float64(10)},
+			[]string{"(lines 5-6 of 6):\ne\nf"}},
+		{"end before start", map[string]interface{}{"start_line": float64(4), "end_line": float64(2)},
+			[]string{"Error: end_line 2 is before start_line 4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := map[string]interface{}{"path": path}
+			for k, v := range tt.args {
+				args[k] = v
+			}
+			got, err := readFileToolCallImpl(context.Background(), &args)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("got:\n%s\nwant to contain: %q", got, want)
+				}
+			}
+		})
+	}
+}
+
 func TestReplaceFirstOccurrence(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -325,7 +388,7 @@ func TestSearchTextInFileToolCallImpl(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := searchTextInFileToolCallImpl(&tt.args)
+			got, err := searchTextInFileToolCallImpl(context.Background(), &tt.args)
 			if err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
@@ -342,3 +405,44 @@ func TestSearchTextInFileToolCallImpl(t *testing.T) {
 		})
 	}
 }
+
+func TestCopyStopsOnCancel(t *testing.T) {
+	src := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt", filepath.Join("sub", "c.txt")} {
+		os.MkdirAll(filepath.Dir(filepath.Join(src, name)), 0755)
+		os.WriteFile(filepath.Join(src, name), []byte(name), 0644)
+	}
+
+	copied, err := copyFileOrDir(context.Background(), src, filepath.Join(t.TempDir(), "all"))
+	if err != nil || copied != 3 {
+		t.Fatalf("expected 3 files copied, got %d, %v", copied, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}}
+	args := map[string]interface{}{"source": src, "destination": filepath.Join(t.TempDir(), "none")}
+	resp, err := copyToolCallImpl(ctx, &args, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp, "interrupted by user after 0 file(s)") {
+		t.Errorf("expected the copy to stop, got %q", resp)
+	}
+}
+
+func TestSearchFilesStopsOnCancel(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	args := map[string]interface{}{"directory": dir, "pattern": "*.go", "recursive": true}
+	resp, err := searchFilesToolCallImpl(ctx, &args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(resp, "interrupted by user, the results are partial") {
+		t.Errorf("expected a partial result, got %q", resp)
+	}
+}
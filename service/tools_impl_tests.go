@@ -0,0 +1,581 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+const (
+	DefaultTestTimeout = 5 * time.Minute
+
+	// Test runners understood by run_tests
+	TestRunnerGo     = "go"
+	TestRunnerPytest = "pytest"
+	TestRunnerNpm    = "npm"
+	TestRunnerCargo  = "cargo"
+
+	// maxTestFailures caps how many failures are reported in detail
+	maxTestFailures = 20
+	// maxFailureMessageLines caps the message lines kept for a single failure
+	maxFailureMessageLines = 15
+	// testOutputTailLines is how much raw output is kept when no failure could be parsed
+	testOutputTailLines = 60
+)
+
+// TestFailure is a single failed test extracted from runner output.
+type TestFailure struct {
+	Name     string
+	Location string // file:line, when the runner reports one
+	Message  string
+}
+
+// TestReport is the structured result of a test run.
+type TestReport struct {
+	Runner   string
+	Command  string
+	Passed   bool
+	ExitCode int
+	Summary  string
+	Failures []TestFailure
+	Output   string // raw output, only reported when nothing could be parsed
+}
+
+// testRunnerMarkers maps project files to the runner they imply, checked in order.
+var testRunnerMarkers = []struct {
+	file   string
+	runner string
+}{
+	{"go.mod", TestRunnerGo},
+	{"Cargo.toml", TestRunnerCargo},
+	{"package.json", TestRunnerNpm},
+	{"pyproject.toml", TestRunnerPytest},
+	{"pytest.ini", TestRunnerPytest},
+	{"setup.cfg", TestRunnerPytest},
+	{"setup.py", TestRunnerPytest},
+	{"tox.ini", TestRunnerPytest},
+	{"conftest.py", TestRunnerPytest},
+}
+
+// detectTestRunner finds the runner for dir by walking up to the nearest project marker.
+func detectTestRunner(dir string) (string, string) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", ""
+	}
+	for {
+		for _, m := range testRunnerMarkers {
+			if _, err := os.Stat(filepath.Join(dir, m.file)); err == nil {
+				return m.runner, dir
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", ""
+		}
+		dir = parent
+	}
+}
+
+// buildTestCommand returns the argv used to run the tests for runner.
+func buildTestCommand(runner, target, filter string) ([]string, error) {
+	switch runner {
+	case TestRunnerGo:
+		args := []string{"go", "test"}
+		if filter != "" {
+			args = append(args, "-run", filter)
+		}
+		if target == "" {
+			target = "./..."
+		}
+		return append(args, target), nil
+	case TestRunnerPytest:
+		args := []string{"pytest"}
+		if _, err := exec.LookPath("pytest"); err != nil {
+			args = []string{"python3", "-m", "pytest"}
+		}
+		args = append(args, "-q", "--tb=short", "-rfE", "--color=no")
+		if filter != "" {
+			args = append(args, "-k", filter)
+		}
+		if target != "" {
+			args = append(args, target)
+		}
+		return args, nil
+	case TestRunnerCargo:
+		args := []string{"cargo", "test", "--color", "never"}
+		if target != "" {
+			args = append(args, "-p", target)
+		}
+		if filter != "" {
+			args = append(args, filter)
+		}
+		return args, nil
+	case TestRunnerNpm:
+		args := []string{"npm", "test", "--silent"}
+		var extra []string
+		if target != "" {
+			extra = append(extra, target)
+		}
+		if filter != "" {
+			extra = append(extra, "-t", filter)
+		}
+		if len(extra) > 0 {
+			args = append(append(args, "--"), extra...)
+		}
+		return args, nil
+	}
+	return nil, fmt.Errorf("unsupported test runner '%s', expected one of: go, pytest, npm, cargo", runner)
+}
+
+func runTestsToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolRunTests, argsMap); err != nil {
+		return "", err
+	}
+
+	dir, _ := (*argsMap)["path"].(string)
+	if dir == "" {
+		dir = "."
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return fmt.Sprintf("Error: %s is not a directory", dir), nil
+	}
+	target, _ := (*argsMap)["target"].(string)
+	filter, _ := (*argsMap)["filter"].(string)
+
+	runner, _ := (*argsMap)["runner"].(string)
+	runner = strings.ToLower(strings.TrimSpace(runner))
+	if runner == "" {
+		detected, root := detectTestRunner(dir)
+		if detected == "" {
+			return fmt.Sprintf("Error: could not detect a test runner for %s (no go.mod, Cargo.toml, package.json or Python project files found). Pass runner explicitly.", dir), nil
+		}
+		runner = detected
+		// Cargo and npm need to run from the project root; go and pytest take the target relative to dir
+		if runner == TestRunnerCargo || runner == TestRunnerNpm {
+			dir = root
+		}
+	}
+
+	argv, err := buildTestCommand(runner, target, filter)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	cmdStr := strings.Join(argv, " ")
+
+	timeout := DefaultTestTimeout
+	if v := toInt64((*argsMap)["timeout"]); v > 0 {
+		timeout = time.Duration(v) * time.Second
+	}
+
+	if !op.toolsUse.AutoApprove {
+		purpose, _ := (*argsMap)["purpose"].(string)
+		if purpose == "" {
+			purpose = fmt.Sprintf("Run tests: %s (in %s)", cmdStr, dir)
+		}
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(purpose, op.toolsUse)
+		}
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return fmt.Sprintf("Operation cancelled by user: run tests '%s'", cmdStr), UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := stopOnCancel(exec.CommandContext(ctx, argv[0], argv[1:]...))
+	cmd.Dir = dir
+	// Keep runner output plain so it can be parsed
+	cmd.Env = append(os.Environ(), "NO_COLOR=1", "FORCE_COLOR=0", "CI=true")
+	out, err := cmd.CombinedOutput()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Sprintf("Tests timed out after %v: %s\n%s", timeout, cmdStr, tailLines(string(out), testOutputTailLines)), nil
+	}
+	if ctx.Err() == context.Canceled {
+		return fmt.Sprintf("Tests interrupted by user: %s\n%s", cmdStr, tailLines(string(out), testOutputTailLines)), nil
+	}
+
+	exitCode := 0
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return fmt.Sprintf("Error: failed to run %s: %v", cmdStr, err), nil
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	report := parseTestOutput(runner, string(out))
+	report.Command = cmdStr
+	report.ExitCode = exitCode
+	report.Passed = exitCode == 0
+	if !report.Passed && len(report.Failures) == 0 {
+		report.Output = tailLines(string(out), testOutputTailLines)
+	}
+
+	if !op.quiet && data.GetSettingsStore().GetVerboseEnabled() {
+		fmt.Fprintf(os.Stderr, "%s$ %s%s\n", data.ToolCallColor, cmdStr, data.ResetSeq)
+		color := data.StatusSuccessColor
+		if !report.Passed {
+			color = data.StatusErrorColor
+		}
+		fmt.Fprintf(os.Stderr, "%s%s%s\n", color, report.Summary, data.ResetSeq)
+	}
+
+	return formatTestReport(report), nil
+}
+
+// parseTestOutput extracts failures and the summary line for runner.
+func parseTestOutput(runner, output string) TestReport {
+	output = stripANSI(strings.ReplaceAll(output, "\r\n", "\n"))
+	report := TestReport{Runner: runner}
+	switch runner {
+	case TestRunnerGo:
+		report.Failures, report.Summary = parseGoTestOutput(output)
+	case TestRunnerPytest:
+		report.Failures, report.Summary = parsePytestOutput(output)
+	case TestRunnerCargo:
+		report.Failures, report.Summary = parseCargoTestOutput(output)
+	case TestRunnerNpm:
+		report.Failures, report.Summary = parseJestOutput(output)
+	}
+	return report
+}
+
+var (
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+	goFailPattern       = regexp.MustCompile(`^\s*--- FAIL: (\S+)`)
+	goLocationPattern   = regexp.MustCompile(`^\s+(\S+\.go:\d+): ?(.*)$`)
+	goPanicPattern      = regexp.MustCompile(`^\s+(/\S+\.go:\d+)`)
+	goPackagePattern    = regexp.MustCompile(`^(ok|FAIL|---)\s+(\S+)\s`)
+	goBuildErrorPattern = regexp.MustCompile(`^(\S+\.go:\d+(?::\d+)?): (.+)$`)
+
+	pytestFailedPattern   = regexp.MustCompile(`^(FAILED|ERROR) (\S+?)(?: - (.*))?$`)
+	pytestLocationPattern = regexp.MustCompile(`^(\S+\.py:\d+): `)
+	pytestSectionPattern  = regexp.MustCompile(`^_{3,} (.+?) _{3,}$`)
+	pytestSummaryPattern  = regexp.MustCompile(`^=+ (.*\d+ (?:passed|failed|error|errors|skipped|deselected).*) =+$`)
+
+	cargoFailPattern     = regexp.MustCompile(`^---- (\S+) stdout ----$`)
+	cargoPanicPattern    = regexp.MustCompile(`panicked at (?:'.*', )?(\S+:\d+:\d+)`)
+	cargoSummaryPattern  = regexp.MustCompile(`^test result: .*$`)
+	cargoCompilePattern  = regexp.MustCompile(`^error(\[E\d+\])?: (.+)$`)
+	cargoCompileLocation = regexp.MustCompile(`^\s+--> (\S+:\d+:\d+)`)
+
+	jestFailPattern      = regexp.MustCompile(`^\s*● (.+)$`)
+	jestLocationPattern  = regexp.MustCompile(`\(?((?:[A-Za-z]:)?[^\s():]+\.[cm]?[jt]sx?):(\d+):\d+\)?`)
+	jestSummaryPattern   = regexp.MustCompile(`^\s*Tests?:\s+(.+)$`)
+	jestCodeFramePattern = regexp.MustCompile(`^\d+ \|`)
+)
+
+func stripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// parseGoTestOutput handles the plain text format of go test.
+func parseGoTestOutput(output string) ([]TestFailure, string) {
+	var failures []TestFailure
+	var current *TestFailure
+	var msg []string
+	okPkgs, failPkgs := 0, 0
+
+	flush := func() {
+		if current != nil {
+			current.Message = strings.Join(msg, "\n")
+			failures = append(failures, *current)
+		}
+		current, msg = nil, nil
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if m := goFailPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			current = &TestFailure{Name: m[1]}
+			continue
+		}
+		if m := goPackagePattern.FindStringSubmatch(line); m != nil && m[1] != "---" {
+			flush()
+			if m[1] == "ok" {
+				okPkgs++
+			} else {
+				failPkgs++
+			}
+			continue
+		}
+		if current != nil {
+			if m := goLocationPattern.FindStringSubmatch(line); m != nil {
+				if current.Location == "" {
+					current.Location = m[1]
+				}
+				if m[2] != "" {
+					msg = append(msg, m[2])
+				}
+				continue
+			}
+			if strings.HasPrefix(line, "=== ") || strings.HasPrefix(line, "--- PASS") || line == "FAIL" || line == "PASS" {
+				continue
+			}
+			if strings.TrimSpace(line) != "" {
+				msg = append(msg, strings.TrimSpace(line))
+			}
+			continue
+		}
+		// Compiler errors are reported before any test runs
+		if m := goBuildErrorPattern.FindStringSubmatch(line); m != nil {
+			failures = append(failures, TestFailure{Name: "build", Location: strings.TrimPrefix(m[1], "./"), Message: m[2]})
+			continue
+		}
+		// Panics outside a --- FAIL block carry the location on an indented frame line
+		if strings.HasPrefix(line, "panic: ") {
+			flush()
+			current = &TestFailure{Name: "panic"}
+			msg = append(msg, line)
+			continue
+		}
+		if m := goPanicPattern.FindStringSubmatch(line); m != nil && len(failures) > 0 && failures[len(failures)-1].Name == "panic" && failures[len(failures)-1].Location == "" {
+			failures[len(failures)-1].Location = m[1]
+		}
+	}
+	flush()
+
+	// A panic report lists the failing test in its message; keep the frames short
+	for i := range failures {
+		failures[i].Message = limitLines(failures[i].Message, maxFailureMessageLines)
+	}
+
+	summary := fmt.Sprintf("%d package(s) ok, %d package(s) failed, %d test failure(s)", okPkgs, failPkgs, countTestFailures(failures))
+	return failures, summary
+}
+
+// parsePytestOutput uses the short test summary (-rfE) for names and the tracebacks for locations.
+func parsePytestOutput(output string) ([]TestFailure, string) {
+	var failures []TestFailure
+	locations := make(map[string]string) // section title -> file:line
+	var summary, section string
+
+	for _, line := range strings.Split(output, "\n") {
+		if m := pytestSectionPattern.FindStringSubmatch(line); m != nil {
+			section = m[1]
+			continue
+		}
+		if m := pytestLocationPattern.FindStringSubmatch(line); m != nil && section != "" {
+			// The last location in a traceback is the one that raised
+			locations[section] = m[1]
+			continue
+		}
+		if m := pytestFailedPattern.FindStringSubmatch(line); m != nil {
+			failures = append(failures, TestFailure{Name: m[2], Message: m[3]})
+			continue
+		}
+		if m := pytestSummaryPattern.FindStringSubmatch(line); m != nil {
+			summary = m[1]
+		}
+	}
+
+	// Section titles are the test function (optionally Class.test), match them against node ids
+	for i := range failures {
+		name := failures[i].Name
+		short := name[strings.LastIndex(name, "::")+2:]
+		if idx := strings.LastIndex(name, "::"); idx > 0 {
+			if prev := strings.LastIndex(name[:idx], "::"); prev >= 0 {
+				short = name[prev+2:idx] + "." + short
+			}
+		}
+		for _, key := range []string{short, name} {
+			if loc, ok := locations[key]; ok {
+				failures[i].Location = loc
+				break
+			}
+		}
+	}
+	return failures, summary
+}
+
+// parseCargoTestOutput reads the per-test stdout sections and compiler errors.
+func parseCargoTestOutput(output string) ([]TestFailure, string) {
+	var failures []TestFailure
+	var current *TestFailure
+	var msg []string
+	var summaries []string
+
+	flush := func() {
+		if current != nil {
+			current.Message = limitLines(strings.TrimSpace(strings.Join(msg, "\n")), maxFailureMessageLines)
+			failures = append(failures, *current)
+		}
+		current, msg = nil, nil
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if m := cargoFailPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			current = &TestFailure{Name: m[1]}
+			continue
+		}
+		if cargoSummaryPattern.MatchString(line) {
+			flush()
+			summaries = append(summaries, line)
+			continue
+		}
+		if m := cargoCompilePattern.FindStringSubmatch(line); m != nil && !strings.HasPrefix(m[2], "test failed") && !strings.HasPrefix(m[2], "could not compile") {
+			flush()
+			current = &TestFailure{Name: "build", Message: m[2]}
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		if current.Name == "build" {
+			if m := cargoCompileLocation.FindStringSubmatch(line); m != nil && current.Location == "" {
+				current.Location = m[1]
+			} else if strings.TrimSpace(line) == "" {
+				flush()
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "failures:") {
+			flush()
+			continue
+		}
+		if m := cargoPanicPattern.FindStringSubmatch(line); m != nil && current.Location == "" {
+			current.Location = m[1]
+			continue
+		}
+		if strings.HasPrefix(line, "note: run with `RUST_BACKTRACE") {
+			continue
+		}
+		msg = append(msg, line)
+	}
+	flush()
+
+	// A build failure is reported once per target; keep unique ones
+	seen := make(map[string]bool)
+	unique := failures[:0]
+	for _, f := range failures {
+		key := f.Name + "\x00" + f.Location + "\x00" + f.Message
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, f)
+		}
+	}
+	return unique, strings.Join(summaries, "; ")
+}
+
+// parseJestOutput covers jest and vitest, the common runners behind npm test.
+func parseJestOutput(output string) ([]TestFailure, string) {
+	var failures []TestFailure
+	var current *TestFailure
+	var msg []string
+	var summary string
+
+	flush := func() {
+		if current != nil {
+			current.Message = limitLines(strings.TrimSpace(strings.Join(msg, "\n")), maxFailureMessageLines)
+			failures = append(failures, *current)
+		}
+		current, msg = nil, nil
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		if m := jestFailPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			current = &TestFailure{Name: strings.TrimSpace(m[1])}
+			continue
+		}
+		if m := jestSummaryPattern.FindStringSubmatch(line); m != nil {
+			flush()
+			summary = "Tests: " + strings.TrimSpace(m[1])
+			continue
+		}
+		if current == nil {
+			continue
+		}
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "at ") {
+			// First stack frame outside node_modules is the test location
+			if m := jestLocationPattern.FindStringSubmatch(trimmed); m != nil && current.Location == "" && !strings.Contains(m[1], "node_modules") {
+				current.Location = m[1] + ":" + m[2]
+			}
+			continue
+		}
+		// Skip the code frame jest prints around the failing line
+		if strings.HasPrefix(trimmed, ">") || jestCodeFramePattern.MatchString(trimmed) || strings.HasPrefix(trimmed, "|") {
+			continue
+		}
+		msg = append(msg, trimmed)
+	}
+	flush()
+	return failures, summary
+}
+
+func countTestFailures(failures []TestFailure) int {
+	n := 0
+	for _, f := range failures {
+		if f.Name != "build" {
+			n++
+		}
+	}
+	return n
+}
+
+// limitLines keeps the first n lines of s.
+func limitLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) <= n {
+		return s
+	}
+	return strings.Join(lines[:n], "\n") + fmt.Sprintf("\n... (%d more lines)", len(lines)-n)
+}
+
+// tailLines keeps the last n non-empty-trailing lines of s.
+func tailLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) <= n {
+		return strings.Join(lines, "\n")
+	}
+	return fmt.Sprintf("... (%d earlier lines omitted)\n", len(lines)-n) + strings.Join(lines[len(lines)-n:], "\n")
+}
+
+// formatTestReport renders the report for the model, leaving out passing output.
+func formatTestReport(r TestReport) string {
+	var sb strings.Builder
+	status := "PASSED"
+	if !r.Passed {
+		status = fmt.Sprintf("FAILED (exit code %d)", r.ExitCode)
+	}
+	fmt.Fprintf(&sb, "Tests %s: %s\n", status, r.Command)
+	if r.Summary != "" {
+		fmt.Fprintf(&sb, "Summary: %s\n", r.Summary)
+	}
+	if len(r.Failures) > 0 {
+		fmt.Fprintf(&sb, "\nFailures (%d):\n", len(r.Failures))
+		for i, f := range r.Failures {
+			if i == maxTestFailures {
+				fmt.Fprintf(&sb, "... %d more failures not shown\n", len(r.Failures)-maxTestFailures)
+				break
+			}
+			fmt.Fprintf(&sb, "%d. %s", i+1, f.Name)
+			if f.Location != "" {
+				fmt.Fprintf(&sb, " (%s)", f.Location)
+			}
+			sb.WriteString("\n")
+			if f.Message != "" {
+				for _, line := range strings.Split(f.Message, "\n") {
+					fmt.Fprintf(&sb, "   %s\n", line)
+				}
+			}
+		}
+	}
+	if r.Output != "" {
+		fmt.Fprintf(&sb, "\nNo failures could be parsed, output tail:\n%s\n", r.Output)
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestParseGoTestOutput(t *testing.T) {
+	out := `--- FAIL: TestAdd (0.00s)
+    math_test.go:12: Add(1, 2) = 4, want 3
+    math_test.go:13: second
+=== RUN   TestSub
+--- PASS: TestSub (0.00s)
+FAIL
+FAIL	example.com/math	0.004s
+ok  	example.com/other	0.002s
+# example.com/broken
+broken/x.go:10:2: undefined: foo
+FAIL	example.com/broken [build failed]
+`
+	r := parseTestOutput(TestRunnerGo, out)
+	if len(r.Failures) != 2 {
+		t.Fatalf("expected 2 failures, got %+v", r.Failures)
+	}
+	f := r.Failures[0]
+	if f.Name != "TestAdd" || f.Location != "math_test.go:12" || f.Message != "Add(1, 2) = 4, want 3\nsecond" {
+		t.Errorf("unexpected failure: %+v", f)
+	}
+	if b := r.Failures[1]; b.Name != "build" || b.Location != "broken/x.go:10:2" || b.Message != "undefined: foo" {
+		t.Errorf("unexpected build failure: %+v", b)
+	}
+	if r.Summary != "1 package(s) ok, 2 package(s) failed, 1 test failure(s)" {
+		t.Errorf("unexpected summary: %s", r.Summary)
+	}
+}
+
+func TestParsePytestOutput(t *testing.T) {
+	out := `F.                                                                       [100%]
+=================================== FAILURES ===================================
+___________________________ TestMath.test_add ___________________________
+
+    def test_add(self):
+>       assert add(1, 2) == 4
+E       assert 3 == 4
+
+tests/test_math.py:8: AssertionError
+=========================== short test summary info ============================
+FAILED tests/test_math.py::TestMath::test_add - assert 3 == 4
+========================= 1 failed, 1 passed in 0.02s ==========================
+`
+	r := parseTestOutput(TestRunnerPytest, out)
+	if len(r.Failures) != 1 {
+		t.Fatalf("expected 1 failure, got %+v", r.Failures)
+	}
+	f := r.Failures[0]
+	if f.Name != "tests/test_math.py::TestMath::test_add" || f.Location != "tests/test_math.py:8" || f.Message != "assert 3 == 4" {
+		t.Errorf("unexpected failure: %+v", f)
+	}
+	if r.Summary != "1 failed, 1 passed in 0.02s" {
+		t.Errorf("unexpected summary: %s", r.Summary)
+	}
+}
+
+func TestParseCargoTestOutput(t *testing.T) {
+	out := `running 2 tests
+test tests::ok ... ok
+test tests::bad ... FAILED
+
+failures:
+
+---- tests::bad stdout ----
+thread 'tests::bad' panicked at src/lib.rs:10:9:
+assertion ` + "`left == right`" + ` failed
+  left: 1
+ right: 2
+note: run with ` + "`RUST_BACKTRACE=1`" + ` environment variable to display a backtrace
+
+
+failures:
+    tests::bad
+
+test result: FAILED. 1 passed; 1 failed; 0 ignored; 0 measured; 0 filtered out; finished in 0.00s
+`
+	r := parseTestOutput(TestRunnerCargo, out)
+	if len(r.Failures) != 1 {
+		t.Fatalf("expected 1 failure, got %+v", r.Failures)
+	}
+	f := r.Failures[0]
+	if f.Name != "tests::bad" || f.Location != "src/lib.rs:10:9" || !strings.HasPrefix(f.Message, "assertion `left == right` failed") {
+		t.Errorf("unexpected failure: %+v", f)
+	}
+	if !strings.HasPrefix(r.Summary, "test result: FAILED. 1 passed; 1 failed") {
+		t.Errorf("unexpected summary: %s", r.Summary)
+	}
+
+	build := parseTestOutput(TestRunnerCargo, "error[E0425]: cannot find value `x` in this scope\n --> src/main.rs:3:5\n  |\n\nerror: could not compile `demo`\n")
+	if len(build.Failures) != 1 || build.Failures[0].Location != "src/main.rs:3:5" {
+		t.Errorf("unexpected build failures: %+v", build.Failures)
+	}
+}
+
+func TestParseJestOutput(t *testing.T) {
+	out := `FAIL src/math.test.js
+  ● math › adds numbers
+
+    expect(received).toBe(expected) // Object.is equality
+
+    Expected: 4
+    Received: 3
+
+      3 | test('adds numbers', () => {
+    > 4 |   expect(add(1, 2)).toBe(4);
+        |                     ^
+      5 | });
+
+      at Object.toBe (node_modules/expect/build/index.js:1:1)
+      at Object.<anonymous> (src/math.test.js:4:21)
+
+Test Suites: 1 failed, 1 total
+Tests:       1 failed, 2 passed, 3 total
+`
+	r := parseTestOutput(TestRunnerNpm, out)
+	if len(r.Failures) != 1 {
+		t.Fatalf("expected 1 failure, got %+v", r.Failures)
+	}
+	f := r.Failures[0]
+	if f.Name != "math › adds numbers" || f.Location != "src/math.test.js:4" {
+		t.Errorf("unexpected failure: %+v", f)
+	}
+	if strings.Contains(f.Message, "|") || !strings.Contains(f.Message, "Received: 3") {
+		t.Errorf("unexpected message: %q", f.Message)
+	}
+	if r.Summary != "Tests: 1 failed, 2 passed, 3 total" {
+		t.Errorf("unexpected summary: %s", r.Summary)
+	}
+}
+
+func TestDetectTestRunner(t *testing.T) {
+	root := t.TempDir()
+	os.WriteFile(filepath.Join(root, "Cargo.toml"), []byte("[package]\n"), 0644)
+	sub := filepath.Join(root, "src", "nested")
+	os.MkdirAll(sub, 0755)
+
+	runner, dir := detectTestRunner(sub)
+	if runner != TestRunnerCargo || dir != root {
+		t.Errorf("got %s in %s, want cargo in %s", runner, dir, root)
+	}
+}
+
+func TestRunTestsToolCallImplGo(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/demo\n\ngo 1.21\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "demo_test.go"), []byte(`package demo
+
+import "testing"
+
+func TestPass(t *testing.T) { t.Log("noisy passing output") }
+
+func TestFail(t *testing.T) { t.Errorf("got %d, want %d", 1, 2) }
+`), 0644)
+
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}}
+	args := map[string]interface{}{"path": dir}
+	out, err := runTestsToolCallImpl(context.Background(), &args, op)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Tests FAILED (exit code 1): go test ./...") ||
+		!strings.Contains(out, "1. TestFail (demo_test.go:7)") ||
+		!strings.Contains(out, "got 1, want 2") {
+		t.Errorf("unexpected report:\n%s", out)
+	}
+	if strings.Contains(out, "noisy passing output") {
+		t.Errorf("passing output should be left out:\n%s", out)
+	}
+
+	args = map[string]interface{}{"path": dir, "filter": "TestPass"}
+	if out, _ = runTestsToolCallImpl(context.Background(), &args, op); !strings.HasPrefix(out, "Tests PASSED: go test -run TestPass ./...") {
+		t.Errorf("unexpected report:\n%s", out)
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+)
+
+// ticketTrackerFromArgs returns the issue tracker of the tool call, its name and the settings
+// holding the default project.
+func ticketTrackerFromArgs(argsMap *map[string]interface{}, op *OpenProcessor) (TicketTracker, string, data.TicketSettings, error) {
+	settings := data.GetSettingsStore().GetTicketSettings()
+	requested, _ := (*argsMap)["tracker"].(string)
+	provider, err := TicketProviderOf(settings, requested)
+	if err != nil {
+		return nil, "", settings, err
+	}
+	transport, err := networkTransport(op.network)
+	if err != nil {
+		return nil, "", settings, err
+	}
+	tracker, err := NewTicketTracker(settings, provider, transport)
+	if err != nil {
+		return nil, "", settings, err
+	}
+	return tracker, provider, settings, nil
+}
+
+// defaultTicketProject is the configured project of the tracker.
+func defaultTicketProject(settings data.TicketSettings, provider string) string {
+	if provider == TicketProviderLinear {
+		return settings.LinearTeam
+	}
+	return settings.JiraProject
+}
+
+func ticketSearchToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolTicketSearch, argsMap); err != nil {
+		return "", err
+	}
+
+	tracker, provider, settings, err := ticketTrackerFromArgs(argsMap, op)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	q := TicketQuery{Limit: 20}
+	q.Text, _ = (*argsMap)["query"].(string)
+	q.Project, _ = (*argsMap)["project"].(string)
+	if q.Project == "" {
+		q.Project = defaultTicketProject(settings, provider)
+	}
+	q.All, _ = (*argsMap)["include_done"].(bool)
+	if limit := toInt64((*argsMap)["limit"]); limit > 0 {
+		q.Limit = int(min(limit, 100))
+	}
+
+	tickets, err := tracker.Search(ctx, q)
+	if err != nil {
+		return fmt.Sprintf("Error searching %s: %v", provider, err), nil
+	}
+	if len(tickets) == 0 {
+		return fmt.Sprintf("No tickets found in %s.", provider), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d tickets found in %s:\n", len(tickets), provider))
+	for _, t := range tickets {
+		sb.WriteString(fmt.Sprintf("%s [%s] %s", t.Key, t.Status, t.Title))
+		if t.Assignee != "" {
+			sb.WriteString(fmt.Sprintf(" (assigned to %s)", t.Assignee))
+		}
+		if len(t.Labels) > 0 {
+			sb.WriteString(fmt.Sprintf(" labels: %s", strings.Join(t.Labels, ", ")))
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+func ticketGetToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolTicketGet, argsMap); err != nil {
+		return "", err
+	}
+
+	key, ok := (*argsMap)["key"].(string)
+	if !ok || strings.TrimSpace(key) == "" {
+		return "", fmt.Errorf("key not found in arguments")
+	}
+
+	tracker, provider, _, err := ticketTrackerFromArgs(argsMap, op)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+	ticket, err := tracker.Get(ctx, strings.TrimSpace(key))
+	if err != nil {
+		return fmt.Sprintf("Error reading %s from %s: %v", key, provider, err), nil
+	}
+	return FormatTicket(ticket), nil
+}
+
+func ticketCreateToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolTicketCreate, argsMap); err != nil {
+		return "", err
+	}
+
+	title, ok := (*argsMap)["title"].(string)
+	if !ok || strings.TrimSpace(title) == "" {
+		return "", fmt.Errorf("title not found in arguments")
+	}
+
+	tracker, provider, settings, err := ticketTrackerFromArgs(argsMap, op)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	nt := NewTicket{Title: strings.TrimSpace(title)}
+	nt.Description, _ = (*argsMap)["description"].(string)
+	nt.Type, _ = (*argsMap)["type"].(string)
+	nt.Project, _ = (*argsMap)["project"].(string)
+	if nt.Project == "" {
+		nt.Project = defaultTicketProject(settings, provider)
+	}
+	if labels, ok := (*argsMap)["labels"].([]interface{}); ok {
+		for _, l := range labels {
+			if s, ok := l.(string); ok && s != "" {
+				nt.Labels = append(nt.Labels, s)
+			}
+		}
+	}
+
+	if !op.toolsUse.AutoApprove {
+		purpose := fmt.Sprintf("Create a %s ticket in %s: %s", provider, nt.Project, nt.Title)
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(purpose, op.toolsUse)
+		}
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return fmt.Sprintf("Operation cancelled by user: create ticket '%s'", nt.Title), UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+
+	ticket, err := tracker.Create(ctx, nt)
+	if err != nil {
+		return fmt.Sprintf("Error creating the ticket in %s: %v", provider, err), nil
+	}
+	return fmt.Sprintf("Successfully created %s: %s", ticket.Key, ticket.URL), nil
+}
+
+func ticketTransitionToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolTicketTransition, argsMap); err != nil {
+		return "", err
+	}
+
+	key, ok := (*argsMap)["key"].(string)
+	if !ok || strings.TrimSpace(key) == "" {
+		return "", fmt.Errorf("key not found in arguments")
+	}
+	status, ok := (*argsMap)["status"].(string)
+	if !ok || strings.TrimSpace(status) == "" {
+		return "", fmt.Errorf("status not found in arguments")
+	}
+	key, status = strings.TrimSpace(key), strings.TrimSpace(status)
+
+	tracker, provider, _, err := ticketTrackerFromArgs(argsMap, op)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err), nil
+	}
+
+	if !op.toolsUse.AutoApprove {
+		purpose := fmt.Sprintf("Move the %s ticket %s to %s", provider, key, status)
+		if op.interaction != nil {
+			op.interaction.RequestConfirm(purpose, op.toolsUse)
+		}
+		if op.toolsUse.Confirm == data.ToolConfirmCancel {
+			return fmt.Sprintf("Operation cancelled by user: move %s to %s", key, status), UserCancelError{Reason: UserCancelReasonDeny}
+		}
+	}
+
+	newStatus, err := tracker.Transition(ctx, key, status)
+	if err != nil {
+		return fmt.Sprintf("Error moving %s: %v", key, err), nil
+	}
+	return fmt.Sprintf("Successfully moved %s to %s", key, newStatus), nil
+}
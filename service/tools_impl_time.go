@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // Timezones for get_time where the system has no zoneinfo, as on Windows
+)
+
+// timeLayouts are the layouts get_time accepts for the time to convert.
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"15:04:05",
+	"15:04",
+}
+
+// promptTime is the time written in the time layer of the system prompt. It only moves when
+// the date changes, so that the prompt stays the same, and cached by the providers, for the
+// turns of a day.
+var promptTime = struct {
+	mu sync.Mutex
+	at time.Time
+}{}
+
+func getPromptTime(now time.Time) time.Time {
+	promptTime.mu.Lock()
+	defer promptTime.mu.Unlock()
+	if promptTime.at.IsZero() || promptTime.at.Format("2006-01-02") != now.Format("2006-01-02") {
+		promptTime.at = now.Truncate(time.Minute)
+	}
+	return promptTime.at
+}
+
+// timeBlock tells the model the date, time, timezone and locale of the user, which it can't
+// know from its training.
+func timeBlock() string {
+	at := getPromptTime(time.Now())
+	var sb strings.Builder
+	sb.WriteString("<time>\n")
+	fmt.Fprintf(&sb, "Date: %s (%s)\n", at.Format("2006-01-02"), at.Weekday())
+	fmt.Fprintf(&sb, "Time at the start of the session: %s\n", at.Format("15:04"))
+	fmt.Fprintf(&sb, "Timezone: %s\n", describeZone(at))
+	if locale := localeName(); locale != "" {
+		fmt.Fprintf(&sb, "Locale: %s\n", locale)
+	}
+	fmt.Fprintf(&sb, "Use this date for \"today\", e.g. in search queries, and the %s tool for the exact time or other timezones.\n", ToolGetTime)
+	sb.WriteString("</time>")
+	return sb.String()
+}
+
+func getTimeToolCallImpl(ctx context.Context, argsMap *map[string]interface{}) (string, error) {
+	if err := CheckToolPermission(ToolGetTime, argsMap); err != nil {
+		return "", err
+	}
+	timezone, _ := (*argsMap)["timezone"].(string)
+	value, _ := (*argsMap)["time"].(string)
+	fromZone, _ := (*argsMap)["from_timezone"].(string)
+
+	from := time.Local
+	if strings.TrimSpace(fromZone) != "" {
+		loc, err := loadZone(fromZone)
+		if err != nil {
+			return "Error: " + err.Error(), nil
+		}
+		from = loc
+	}
+	t := time.Now().In(from)
+	if strings.TrimSpace(value) != "" {
+		parsed, err := parseToolTime(value, from)
+		if err != nil {
+			return "Error: " + err.Error(), nil
+		}
+		t = parsed
+	}
+
+	var sb strings.Builder
+	if value == "" {
+		fmt.Fprintf(&sb, "Now: %s\n", formatToolTime(t.In(time.Local)))
+	} else {
+		fmt.Fprintf(&sb, "Given: %s\n", formatToolTime(t))
+		if from != time.Local {
+			fmt.Fprintf(&sb, "Local: %s\n", formatToolTime(t.In(time.Local)))
+		}
+	}
+	if strings.TrimSpace(timezone) != "" {
+		loc, err := loadZone(timezone)
+		if err != nil {
+			return "Error: " + err.Error(), nil
+		}
+		fmt.Fprintf(&sb, "In %s: %s\n", loc, formatToolTime(t.In(loc)))
+	}
+	fmt.Fprintf(&sb, "UTC: %s\n", t.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&sb, "Unix: %d\n", t.Unix())
+	return sb.String(), nil
+}
+
+// parseToolTime parses a time given to get_time in the zone it is in. A time of day alone is
+// on the current date of that zone.
+func parseToolTime(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		t, err := time.ParseInLocation(layout, value, loc)
+		if err != nil {
+			continue
+		}
+		if !strings.HasPrefix(layout, "2006") {
+			now := time.Now().In(loc)
+			t = time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s', use e.g. 2025-03-14T15:04:05Z, 2025-03-14 15:04 or 15:04", value)
+}
+
+// loadZone loads an IANA timezone, also accepting "local" and "UTC".
+func loadZone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if strings.EqualFold(name, "local") {
+		return time.Local, nil
+	}
+	if strings.EqualFold(name, "utc") || strings.EqualFold(name, "gmt") {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone '%s', use an IANA name such as America/New_York", name)
+	}
+	return loc, nil
+}
+
+func formatToolTime(t time.Time) string {
+	return fmt.Sprintf("%s (%s, %s)", t.Format("2006-01-02 15:04:05"), t.Weekday(), describeZone(t))
+}
+
+// describeZone names the zone of a time with its offset, e.g. "Europe/Paris, CET, UTC+01:00".
+func describeZone(t time.Time) string {
+	abbr, _ := t.Zone()
+	offset := "UTC" + t.Format("-07:00")
+	name := t.Location().String()
+	if t.Location() == time.Local {
+		name = localZoneName()
+	}
+	var parts []string
+	for _, p := range []string{name, abbr} {
+		if p != "" && p != "Local" && p != "UTC" && !strings.HasPrefix(p, "+") && !strings.HasPrefix(p, "-") && !slices.Contains(parts, p) {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(append(parts, offset), ", ")
+}
+
+// localZoneName returns the IANA name of the local timezone, "" when it is unknown.
+func localZoneName() string {
+	if tz := strings.TrimPrefix(os.Getenv("TZ"), ":"); tz != "" && !filepath.IsAbs(tz) {
+		return tz
+	}
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	link, err := filepath.EvalSymlinks("/etc/localtime")
+	if err != nil {
+		return ""
+	}
+	if _, name, ok := strings.Cut(filepath.ToSlash(link), "zoneinfo/"); ok {
+		return name
+	}
+	return ""
+}
+
+// localeName returns the locale of the user from the environment, "" when none is set.
+func localeName() string {
+	for _, key := range []string{"LC_ALL", "LC_TIME", "LANG"} {
+		if v := os.Getenv(key); v != "" && v != "C" && v != "POSIX" {
+			return v
+		}
+	}
+	return ""
+}
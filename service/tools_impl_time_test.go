@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetTimeConversion(t *testing.T) {
+	args := map[string]interface{}{
+		"time":          "2025-03-14 09:30",
+		"from_timezone": "America/New_York",
+		"timezone":      "Asia/Tokyo",
+	}
+	result, err := getTimeToolCallImpl(context.Background(), &args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Given: 2025-03-14 09:30:00 (Friday, America/New_York, EDT, UTC-04:00)",
+		"In Asia/Tokyo: 2025-03-14 22:30:00 (Friday, Asia/Tokyo, JST, UTC+09:00)",
+		"UTC: 2025-03-14T13:30:00Z",
+		"Unix: 1741959000",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("get_time should contain %q, got:\n%s", want, result)
+		}
+	}
+
+	result, _ = getTimeToolCallImpl(context.Background(), &map[string]interface{}{"timezone": "UTC"})
+	if !strings.HasPrefix(result, "Now: ") || !strings.Contains(result, "In UTC: ") {
+		t.Errorf("get_time now = %q", result)
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"timezone": "Mars/Olympus"},
+		{"time": "next tuesday"},
+	} {
+		if result, err := getTimeToolCallImpl(context.Background(), &bad); err != nil || !strings.HasPrefix(result, "Error: ") {
+			t.Errorf("get_time(%v) = %q, %v", bad, result, err)
+		}
+	}
+}
+
+func TestParseToolTimeOfDay(t *testing.T) {
+	loc, _ := time.LoadLocation("Europe/Paris")
+	got, err := parseToolTime("15:04", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().In(loc)
+	if got.Hour() != 15 || got.Minute() != 4 || got.YearDay() != now.YearDay() || got.Location() != loc {
+		t.Errorf("parseToolTime(15:04) = %v", got)
+	}
+}
+
+func TestTimeBlock(t *testing.T) {
+	t.Setenv("LC_ALL", "fr_FR.UTF-8")
+	block := timeBlock()
+	today := time.Now().Format("2006-01-02")
+	for _, want := range []string{"<time>\n", "Date: " + today, "Timezone: ", "Locale: fr_FR.UTF-8", ToolGetTime, "</time>"} {
+		if !strings.Contains(block, want) {
+			t.Errorf("time block should contain %q, got:\n%s", want, block)
+		}
+	}
+	// The block stays the same for the day, so that the prompt can be cached
+	if again := timeBlock(); again != block {
+		t.Errorf("time block changed:\n%s\n%s", block, again)
+	}
+	first := getPromptTime(time.Date(2025, 1, 1, 9, 0, 0, 0, time.Local))
+	if got := getPromptTime(time.Date(2025, 1, 1, 18, 0, 0, 0, time.Local)); !got.Equal(first) {
+		t.Errorf("prompt time moved within a day: %v", got)
+	}
+	if got := getPromptTime(time.Date(2025, 1, 2, 8, 0, 0, 0, time.Local)); got.Day() != 2 {
+		t.Errorf("prompt time should move to the next day, got %v", got)
+	}
+}
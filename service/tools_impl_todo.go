@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+)
+
+// todoFilePath returns where the todo list of a session is stored, next to its history.
+// Sessions that are not saved keep their list in memory.
+func todoFilePath(sessionName string) string {
+	if sessionName == "" {
+		return ""
+	}
+	return strings.TrimSuffix(GetSessionFilePath(sessionName), SessionFileExtension) + ".todos.json"
+}
+
+func todoWriteToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolTodoWrite, argsMap); err != nil {
+		return "", err
+	}
+
+	rawTodos, ok := (*argsMap)["todos"].([]interface{})
+	if !ok {
+		return "", fmt.Errorf("todos not found in arguments")
+	}
+	items := make([]data.TodoItem, 0, len(rawTodos))
+	for i, raw := range rawTodos {
+		m, ok := raw.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("todo #%d is not an object", i+1)
+		}
+		item := data.TodoItem{}
+		// Models sometimes send numeric ids
+		if id, ok := m["id"]; ok && id != nil {
+			item.ID = strings.TrimSpace(fmt.Sprintf("%v", id))
+		}
+		item.Content, _ = m["content"].(string)
+		item.Status, _ = m["status"].(string)
+		items = append(items, item)
+	}
+	if err := data.ValidateTodos(items); err != nil {
+		return "", err
+	}
+
+	if err := data.SaveTodos(todoFilePath(op.sessionName), items); err != nil {
+		return "", fmt.Errorf("failed to save todo list: %v", err)
+	}
+	op.showTodos(items)
+
+	return fmt.Sprintf("Todo list updated, %s\n%s", todoProgress(items), formatTodos(items)), nil
+}
+
+func todoReadToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolTodoRead, argsMap); err != nil {
+		return "", err
+	}
+
+	items, err := data.LoadTodos(todoFilePath(op.sessionName))
+	if err != nil {
+		return "", fmt.Errorf("failed to load todo list: %v", err)
+	}
+	if len(items) == 0 {
+		return "The todo list is empty.", nil
+	}
+	return fmt.Sprintf("Todo list, %s\n%s", todoProgress(items), formatTodos(items)), nil
+}
+
+// showTodos renders the checklist in the terminal.
+func (op *OpenProcessor) showTodos(items []data.TodoItem) {
+	// Function call is over
+	op.status.ChangeTo(op.notify, StreamNotify{Status: StatusFunctionCallingOver}, op.proceed)
+
+	payload, _ := json.Marshal(items)
+	op.status.ChangeTo(op.notify, StreamNotify{Status: StatusShowTodos, Data: string(payload)}, op.proceed)
+}
+
+func todoProgress(items []data.TodoItem) string {
+	done := 0
+	for _, item := range items {
+		if item.Status == data.TodoCompleted {
+			done++
+		}
+	}
+	return fmt.Sprintf("%d/%d completed:", done, len(items))
+}
+
+// formatTodos renders the list as plain text for the model.
+func formatTodos(items []data.TodoItem) string {
+	var sb strings.Builder
+	for _, item := range items {
+		mark := " "
+		switch item.Status {
+		case data.TodoCompleted:
+			mark = "x"
+		case data.TodoInProgress:
+			mark = ">"
+		}
+		sb.WriteString(fmt.Sprintf("[%s] %s: %s\n", mark, item.ID, item.Content))
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
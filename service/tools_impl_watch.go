@@ -0,0 +1,321 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+const (
+	maxPathWatches       = 8         // Watches kept per session, the oldest is dropped
+	maxWatchedFiles      = 5000      // Files of a watch, the rest of a large tree is left out
+	maxWatchFileDiffSize = 32 * 1024 // Text files up to this size get a diff
+	maxWatchContentSize  = 8 << 20   // Content kept per watch to diff against
+)
+
+// watchedFile is the state of a watched file at the last check. The content of small text
+// files is kept to diff against.
+type watchedFile struct {
+	size    int64
+	modTime time.Time
+	content []byte // nil when the file is too large or binary
+}
+
+// pathWatch is a path an agent registered with watch_path. check_changes compares the files
+// under it with their state at the last check, so it reports what changed since then, even
+// while no turn of the agent is running.
+type pathWatch struct {
+	mu        sync.Mutex
+	path      string // As the agent gave it
+	root      string // Absolute
+	isFile    bool
+	globs     []string
+	files     map[string]watchedFile // Keyed by slash path relative to root
+	truncated bool                   // The tree has more than maxWatchedFiles files
+}
+
+// watchChange is a file created, modified or deleted since the last check.
+type watchChange struct {
+	kind string
+	path string
+	diff string
+}
+
+var (
+	pathWatchesMu sync.Mutex
+	pathWatches   = make(map[string][]*pathWatch) // Keyed by session
+)
+
+func watchPathToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolWatchPath, argsMap); err != nil {
+		return "", err
+	}
+
+	path, ok := (*argsMap)["path"].(string)
+	if !ok {
+		return "", fmt.Errorf("path not found in arguments")
+	}
+	var globs []string
+	if raw, ok := (*argsMap)["globs"].([]interface{}); ok {
+		for _, g := range raw {
+			if s, ok := g.(string); ok && strings.TrimSpace(s) != "" {
+				globs = append(globs, strings.TrimSpace(s))
+			}
+		}
+	}
+
+	w, err := newPathWatch(path, globs)
+	if err != nil {
+		return fmt.Sprintf("Error watching %s: %v", path, err), nil
+	}
+	addPathWatch(op.sessionName, w)
+
+	what := fmt.Sprintf("%d files", len(w.files))
+	if w.isFile {
+		what = "the file"
+	} else if w.truncated {
+		what = fmt.Sprintf("the first %d files", maxWatchedFiles)
+	}
+	return fmt.Sprintf("Watching %s (%s). Call %s to get what was created, modified or deleted since now.", path, what, ToolCheckChanges), nil
+}
+
+func checkChangesToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolCheckChanges, argsMap); err != nil {
+		return "", err
+	}
+
+	path, _ := (*argsMap)["path"].(string)
+	watches := sessionPathWatches(op.sessionName, path)
+	if len(watches) == 0 {
+		if path != "" {
+			return fmt.Sprintf("Error: %s is not watched, call %s first.", path, ToolWatchPath), nil
+		}
+		return fmt.Sprintf("Error: no path is watched, call %s first.", ToolWatchPath), nil
+	}
+
+	var sb strings.Builder
+	size := 0
+	for _, w := range watches {
+		changes := w.check()
+		if len(changes) == 0 {
+			fmt.Fprintf(&sb, "No changes in %s since the last check.\n", w.path)
+			continue
+		}
+		fmt.Fprintf(&sb, "Changes in %s since the last check:\n", w.path)
+		for _, c := range changes {
+			fmt.Fprintf(&sb, "- %s %s\n", c.kind, c.path)
+			if c.diff == "" {
+				continue
+			}
+			if size+len(c.diff) > maxWatchDiffSize {
+				sb.WriteString("  (diff left out, the changes are too large, read the file)\n")
+				continue
+			}
+			size += len(c.diff)
+			sb.WriteString("```diff\n" + c.diff)
+			if !strings.HasSuffix(c.diff, "\n") {
+				sb.WriteString("\n")
+			}
+			sb.WriteString("```\n")
+		}
+	}
+	return sb.String(), nil
+}
+
+// newPathWatch records the state of the files under path, or of the file at path.
+func newPathWatch(path string, globs []string) (*pathWatch, error) {
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range globs {
+		if !strings.Contains(g, "**") {
+			if _, err := filepath.Match(g, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob '%s': %w", g, err)
+			}
+		}
+	}
+	w := &pathWatch{path: path, root: root, isFile: !info.IsDir(), globs: globs}
+	w.files, w.truncated = w.scan(nil)
+	return w, nil
+}
+
+// scan reads the state of the watched files. The content of the files unchanged since the
+// previous state is taken from it rather than read again.
+func (w *pathWatch) scan(previous map[string]watchedFile) (map[string]watchedFile, bool) {
+	files := make(map[string]watchedFile)
+	budget := maxWatchContentSize
+	add := func(rel, abs string, info fs.FileInfo) {
+		f := watchedFile{size: info.Size(), modTime: info.ModTime()}
+		if old, ok := previous[rel]; ok && old.size == f.size && old.modTime.Equal(f.modTime) {
+			f.content = old.content
+		} else if f.size <= maxWatchFileDiffSize && int(f.size) <= budget {
+			if content, err := os.ReadFile(abs); err == nil && !looksBinary(content) {
+				f.content = content
+			}
+		}
+		budget -= len(f.content)
+		files[rel] = f
+	}
+
+	if w.isFile {
+		if info, err := os.Stat(w.root); err == nil && !info.IsDir() {
+			add(filepath.Base(w.root), w.root, info)
+		}
+		return files, false
+	}
+	truncated := false
+	filepath.WalkDir(w.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() {
+			if p != w.root && (watchSkipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(w.root, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if !w.match(rel) {
+			return nil
+		}
+		if len(files) >= maxWatchedFiles {
+			truncated = true
+			return filepath.SkipAll
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			add(rel, p, info)
+		}
+		return nil
+	})
+	return files, truncated
+}
+
+func (w *pathWatch) match(rel string) bool {
+	if len(w.globs) == 0 {
+		return true
+	}
+	for _, g := range w.globs {
+		if MatchGlob(g, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns the files created, modified or deleted since the last check, sorted by path,
+// and makes their current state the one the next check compares with.
+func (w *pathWatch) check() []watchChange {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	current, truncated := w.scan(w.files)
+	var changes []watchChange
+	for rel, f := range current {
+		old, existed := w.files[rel]
+		switch {
+		case !existed:
+			changes = append(changes, watchChange{kind: "created", path: w.display(rel), diff: watchDiff(rel, nil, f.content)})
+		case old.size != f.size || !old.modTime.Equal(f.modTime):
+			if old.content != nil && f.content != nil && bytes.Equal(old.content, f.content) {
+				continue // Rewritten with the same content, as a rebuild does
+			}
+			change := watchChange{kind: "modified", path: w.display(rel)}
+			if old.content != nil {
+				change.diff = watchDiff(rel, old.content, f.content)
+			}
+			changes = append(changes, change)
+		}
+	}
+	for rel := range w.files {
+		if _, ok := current[rel]; !ok {
+			if _, err := os.Lstat(filepath.Join(w.root, filepath.FromSlash(rel))); err == nil && !w.isFile {
+				continue // Left out of a tree larger than maxWatchedFiles
+			}
+			changes = append(changes, watchChange{kind: "deleted", path: w.display(rel)})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].path < changes[j].path })
+	w.files, w.truncated = current, truncated
+	return changes
+}
+
+// display returns the path of a watched file as the agent knows it.
+func (w *pathWatch) display(rel string) string {
+	if w.isFile {
+		return w.path
+	}
+	return filepath.ToSlash(filepath.Join(w.path, filepath.FromSlash(rel)))
+}
+
+// watchDiff returns the unified diff of a small text file, "" when it has no content to diff.
+func watchDiff(rel string, before, after []byte) string {
+	if after == nil || (before == nil && len(after) == 0) {
+		return ""
+	}
+	diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(before)),
+		B:        difflib.SplitLines(string(after)),
+		FromFile: "a/" + rel,
+		ToFile:   "b/" + rel,
+		Context:  3,
+	})
+	if err != nil {
+		return ""
+	}
+	return diff
+}
+
+// addPathWatch registers a watch of the session, replacing the watch of the same path.
+func addPathWatch(session string, w *pathWatch) {
+	pathWatchesMu.Lock()
+	defer pathWatchesMu.Unlock()
+	watches := pathWatches[session]
+	for i, old := range watches {
+		if old.root == w.root {
+			watches = append(watches[:i], watches[i+1:]...)
+			break
+		}
+	}
+	watches = append(watches, w)
+	if len(watches) > maxPathWatches {
+		watches = watches[len(watches)-maxPathWatches:]
+	}
+	pathWatches[session] = watches
+}
+
+// sessionPathWatches returns the watches of the session, only the one of path when it is set.
+func sessionPathWatches(session, path string) []*pathWatch {
+	pathWatchesMu.Lock()
+	defer pathWatchesMu.Unlock()
+	watches := pathWatches[session]
+	if path == "" {
+		return append([]*pathWatch(nil), watches...)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	for _, w := range watches {
+		if w.root == abs {
+			return []*pathWatch{w}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchPathAndCheckChanges(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.MkdirAll(filepath.Join("src", "node_modules"), 0755)
+	os.WriteFile(filepath.Join("src", "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	os.WriteFile(filepath.Join("src", "old.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join("src", "same.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join("src", "notes.txt"), []byte("ignored\n"), 0644)
+
+	op := &OpenProcessor{sessionName: "watch-test"}
+	if result, _ := checkChangesToolCallImpl(context.Background(), &map[string]interface{}{}, op); !strings.HasPrefix(result, "Error: no path is watched") {
+		t.Errorf("check_changes without a watch = %q", result)
+	}
+	result, err := watchPathToolCallImpl(context.Background(), &map[string]interface{}{"path": "src", "globs": []interface{}{"*.go"}}, op)
+	if err != nil || !strings.Contains(result, "3 files") {
+		t.Fatalf("watch_path = %q, %v", result, err)
+	}
+
+	later := time.Now().Add(time.Second)
+	os.WriteFile(filepath.Join("src", "main.go"), []byte("package main\n\nfunc main() { run() }\n"), 0644)
+	os.Chtimes(filepath.Join("src", "main.go"), later, later)
+	os.WriteFile(filepath.Join("src", "same.go"), []byte("package main\n"), 0644)
+	os.Chtimes(filepath.Join("src", "same.go"), later, later)
+	os.Remove(filepath.Join("src", "old.go"))
+	os.WriteFile(filepath.Join("src", "new.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join("src", "node_modules", "dep.go"), []byte("package dep\n"), 0644)
+	os.WriteFile(filepath.Join("src", "notes.txt"), []byte("changed\n"), 0644)
+
+	result, _ = checkChangesToolCallImpl(context.Background(), &map[string]interface{}{}, op)
+	for _, want := range []string{
+		"- modified src/main.go\n```diff\n",
+		"-func main() {}\n+func main() { run() }\n",
+		"- created src/new.go\n",
+		"+package main\n",
+		"- deleted src/old.go\n",
+	} {
+		if !strings.Contains(result, want) {
+			t.Errorf("check_changes should report %q, got:\n%s", want, result)
+		}
+	}
+	for _, unwanted := range []string{"same.go", "notes.txt", "dep.go"} {
+		if strings.Contains(result, unwanted) {
+			t.Errorf("check_changes should not report %s, got:\n%s", unwanted, result)
+		}
+	}
+
+	// The changes are reported once
+	if result, _ := checkChangesToolCallImpl(context.Background(), &map[string]interface{}{"path": "src"}, op); result != "No changes in src since the last check.\n" {
+		t.Errorf("second check_changes = %q", result)
+	}
+	if result, _ := checkChangesToolCallImpl(context.Background(), &map[string]interface{}{"path": "docs"}, op); !strings.HasPrefix(result, "Error: docs is not watched") {
+		t.Errorf("check_changes of an unwatched path = %q", result)
+	}
+	// Other sessions have their own watches
+	if result, _ := checkChangesToolCallImpl(context.Background(), &map[string]interface{}{}, &OpenProcessor{sessionName: "other"}); !strings.HasPrefix(result, "Error") {
+		t.Errorf("check_changes of another session = %q", result)
+	}
+}
+
+func TestWatchSingleFile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	os.WriteFile("app.log", []byte("start\n"), 0644)
+	op := &OpenProcessor{sessionName: "watch-file-test"}
+	if result, _ := watchPathToolCallImpl(context.Background(), &map[string]interface{}{"path": "app.log"}, op); !strings.Contains(result, "the file") {
+		t.Fatalf("watch_path = %q", result)
+	}
+	os.Remove("app.log")
+	if result, _ := checkChangesToolCallImpl(context.Background(), &map[string]interface{}{}, op); !strings.Contains(result, "- deleted app.log\n") {
+		t.Errorf("check_changes = %q", result)
+	}
+	os.WriteFile("app.log", []byte("again\n"), 0644)
+	if result, _ := checkChangesToolCallImpl(context.Background(), &map[string]interface{}{}, op); !strings.Contains(result, "- created app.log\n") {
+		t.Errorf("check_changes = %q", result)
+	}
+}
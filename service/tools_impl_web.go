@@ -7,7 +7,7 @@ import (
 	"time"
 )
 
-func webFetchToolCallImpl(argsMap *map[string]interface{}) (string, error) {
+func webFetchToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolWebFetch, argsMap); err != nil {
 		return "", err
 	}
@@ -17,10 +17,16 @@ func webFetchToolCallImpl(argsMap *map[string]interface{}) (string, error) {
 		return "", fmt.Errorf("url not found in arguments")
 	}
 
+	// Fetch through the model's proxy and TLS settings, corporate networks often allow no direct traffic
+	transport, err := networkTransport(op.network)
+	if err != nil {
+		return fmt.Sprintf("Error fetching content from %s: %v", url, err), nil
+	}
+
 	// Call the fetch function
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
-	results := FetchProcess(ctx, []string{url})
+	results := FetchProcessWithTransport(ctx, []string{url}, transport)
 
 	// Check if FetchProcess returned any results
 	if len(results) == 0 {
@@ -40,7 +46,30 @@ func webFetchToolCallImpl(argsMap *map[string]interface{}) (string, error) {
 	return fmt.Sprintf("Fetched content from %s:\n%s", url, res.Content), nil
 }
 
-func webSearchToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+func getTranscriptToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
+	if err := CheckToolPermission(ToolGetTranscript, argsMap); err != nil {
+		return "", err
+	}
+
+	url, ok := (*argsMap)["url"].(string)
+	if !ok {
+		return "", fmt.Errorf("url not found in arguments")
+	}
+	language, _ := (*argsMap)["language"].(string)
+	if !IsYouTubeURL(url) {
+		return fmt.Sprintf("%s is not a YouTube video, use web_fetch to read web pages.", url), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	transcript, err := FetchYouTubeTranscript(ctx, url, language, op.network)
+	if err != nil {
+		return fmt.Sprintf("Error getting the transcript of %s: %v", url, err), nil
+	}
+	return transcript.Render(), nil
+}
+
+func webSearchToolCallImpl(ctx context.Context, argsMap *map[string]interface{}, op *OpenProcessor) (string, error) {
 	if err := CheckToolPermission(ToolWebSearch, argsMap); err != nil {
 		return "", err
 	}
@@ -57,13 +86,13 @@ func webSearchToolCallImpl(argsMap *map[string]interface{}, op *OpenProcessor) (
 	switch engine {
 	case GoogleSearchEngine:
 		// Use Google Search Engine
-		data, err = op.search.GoogleSearch(query)
+		data, err = op.search.GoogleSearch(ctx, query)
 	case BingSearchEngine:
 		// Use Bing Search Engine
-		data, err = op.search.BingSearch(query)
+		data, err = op.search.BingSearch(ctx, query)
 	case TavilySearchEngine:
 		// Use Tavily Search Engine
-		data, err = op.search.TavilySearch(query)
+		data, err = op.search.TavilySearch(ctx, query)
 	case NoneSearchEngine:
 		// Use None Search Engine
 		data, err = op.search.NoneSearch(query)
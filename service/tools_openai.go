@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 
 	openai "github.com/openai/openai-go/v3"
@@ -11,7 +12,7 @@ import (
  */
 
 // MCPToolCall has it's own logic
-func (op *OpenProcessor) openAIMCPToolCall(toolCall openai.ChatCompletionMessageToolCallUnion, argsMap *map[string]interface{}) (openai.ChatCompletionMessageParamUnion, error) {
+func (op *OpenProcessor) openAIMCPToolCall(ctx context.Context, toolCall openai.ChatCompletionMessageToolCallUnion, argsMap *map[string]interface{}) (openai.ChatCompletionMessageParamUnion, error) {
 	if op.mcpClient == nil {
 		err := fmt.Errorf("MCP client not initialized")
 		return openai.ToolMessage(fmt.Sprintf("Error: MCP tool call failed: %v", err), toolCall.ID), err
@@ -23,7 +24,7 @@ func (op *OpenProcessor) openAIMCPToolCall(toolCall openai.ChatCompletionMessage
 	}
 
 	// Call the MCP tool
-	result, err := op.mcpClient.CallTool(toolCall.Function.Name, *argsMap)
+	result, err := op.mcpClient.CallTool(ctx, toolCall.Function.Name, *argsMap)
 	if err != nil {
 		return openai.ToolMessage(fmt.Sprintf("Error: MCP tool call failed: %v", err), toolCall.ID), err
 	}
@@ -52,8 +53,8 @@ func (op *OpenProcessor) openAIMCPToolCall(toolCall openai.ChatCompletionMessage
 }
 
 // Switch agent tool call is special, it need to deal with IsSwitchAgentError
-func (op *OpenProcessor) openAISwitchAgentToolCall(toolCall openai.ChatCompletionMessageToolCallUnion, argsMap *map[string]interface{}) (openai.ChatCompletionMessageParamUnion, error) {
-	response, err := switchAgentToolCallImpl(argsMap, op)
+func (op *OpenProcessor) openAISwitchAgentToolCall(ctx context.Context, toolCall openai.ChatCompletionMessageToolCallUnion, argsMap *map[string]interface{}) (openai.ChatCompletionMessageParamUnion, error) {
+	response, err := agentSwitchToolCallImpl(ctx, toolCall.Function.Name, argsMap, op)
 
 	if err != nil {
 		if IsSwitchAgentError(err) {
@@ -75,70 +76,161 @@ func runOpenAITool(tc openai.ChatCompletionMessageToolCallUnion, fn ToolFunc) (o
 	return openai.ToolMessage(response, tc.ID), err
 }
 
+// mapOpenAIToolResult rewrites the text of an OpenAI tool message with f.
+func mapOpenAIToolResult(msg openai.ChatCompletionMessageParamUnion, f func(string) string) openai.ChatCompletionMessageParamUnion {
+	if msg.OfTool == nil {
+		return msg
+	}
+	text := msg.OfTool.Content.OfString.Value
+	if mapped := f(text); mapped != text {
+		return openai.ToolMessage(mapped, msg.OfTool.ToolCallID)
+	}
+	return msg
+}
+
 // dispatchOpenAIToolCall handles the routing of OpenAI tool calls to the correct implementation.
 func (op *OpenProcessor) dispatchOpenAIToolCall(toolCall openai.ChatCompletionMessageToolCallUnion, a *map[string]interface{}) (openai.ChatCompletionMessageParamUnion, error) {
+	ctx := op.toolContext()
 	switch toolCall.Function.Name {
 	case ToolShell:
-		return runOpenAITool(toolCall, func() (string, error) { return shellToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return shellToolCallImpl(ctx, a, op) })
+	case ToolRunTests:
+		return runOpenAITool(toolCall, func() (string, error) { return runTestsToolCallImpl(ctx, a, op) })
 	case ToolWebFetch:
-		return runOpenAITool(toolCall, func() (string, error) { return webFetchToolCallImpl(a) })
+		return runOpenAITool(toolCall, func() (string, error) { return webFetchToolCallImpl(ctx, a, op) })
+	case ToolGetTranscript:
+		return runOpenAITool(toolCall, func() (string, error) { return getTranscriptToolCallImpl(ctx, a, op) })
 	case ToolWebSearch:
 		return runOpenAITool(toolCall, func() (string, error) {
-			return webSearchToolCallImpl(a, op)
+			return webSearchToolCallImpl(ctx, a, op)
 		})
 	case ToolReadFile:
-		return runOpenAITool(toolCall, func() (string, error) { return readFileToolCallImpl(a) })
+		return runOpenAITool(toolCall, func() (string, error) { return readFileToolCallImpl(ctx, a) })
 	case ToolWriteFile:
-		return runOpenAITool(toolCall, func() (string, error) { return writeFileToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return writeFileToolCallImpl(ctx, a, op) })
 	case ToolEditFile:
-		return runOpenAITool(toolCall, func() (string, error) { return editFileToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return editFileToolCallImpl(ctx, a, op) })
+	case ToolApplyChanges:
+		return runOpenAITool(toolCall, func() (string, error) { return applyChangesToolCallImpl(ctx, a, op) })
 	case ToolCreateDirectory:
-		return runOpenAITool(toolCall, func() (string, error) { return createDirectoryToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return createDirectoryToolCallImpl(ctx, a, op) })
 	case ToolListDirectory:
-		return runOpenAITool(toolCall, func() (string, error) { return listDirectoryToolCallImpl(a) })
+		return runOpenAITool(toolCall, func() (string, error) { return listDirectoryToolCallImpl(ctx, a) })
 	case ToolDeleteFile:
-		return runOpenAITool(toolCall, func() (string, error) { return deleteFileToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return deleteFileToolCallImpl(ctx, a, op) })
 	case ToolDeleteDirectory:
-		return runOpenAITool(toolCall, func() (string, error) { return deleteDirectoryToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return deleteDirectoryToolCallImpl(ctx, a, op) })
+	case ToolRestoreDeleted:
+		return runOpenAITool(toolCall, func() (string, error) { return restoreDeletedToolCallImpl(ctx, a, op) })
+	case ToolWatchPath:
+		return runOpenAITool(toolCall, func() (string, error) { return watchPathToolCallImpl(ctx, a, op) })
+	case ToolCheckChanges:
+		return runOpenAITool(toolCall, func() (string, error) { return checkChangesToolCallImpl(ctx, a, op) })
+	case ToolGetEnvironment:
+		return runOpenAITool(toolCall, func() (string, error) { return getEnvironmentToolCallImpl(ctx, a, op) })
+	case ToolGetTime:
+		return runOpenAITool(toolCall, func() (string, error) { return getTimeToolCallImpl(ctx, a) })
 	case ToolMove:
-		return runOpenAITool(toolCall, func() (string, error) { return moveToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return moveToolCallImpl(ctx, a, op) })
 	case ToolCopy:
-		return runOpenAITool(toolCall, func() (string, error) { return copyToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return copyToolCallImpl(ctx, a, op) })
 	case ToolSearchFiles:
-		return runOpenAITool(toolCall, func() (string, error) { return searchFilesToolCallImpl(a) })
+		return runOpenAITool(toolCall, func() (string, error) { return searchFilesToolCallImpl(ctx, a) })
 	case ToolSearchTextInFile:
-		return runOpenAITool(toolCall, func() (string, error) { return searchTextInFileToolCallImpl(a) })
+		return runOpenAITool(toolCall, func() (string, error) { return searchTextInFileToolCallImpl(ctx, a) })
 	case ToolReadMultipleFiles:
-		return runOpenAITool(toolCall, func() (string, error) { return readMultipleFilesToolCallImpl(a) })
+		return runOpenAITool(toolCall, func() (string, error) { return readMultipleFilesToolCallImpl(ctx, a) })
+	case ToolReadSymbol:
+		return runOpenAITool(toolCall, func() (string, error) { return readSymbolToolCallImpl(ctx, a) })
+	case ToolLint:
+		return runOpenAITool(toolCall, func() (string, error) { return lintToolCallImpl(ctx, a, op) })
+	case ToolFormatCode:
+		return runOpenAITool(toolCall, func() (string, error) { return formatCodeToolCallImpl(ctx, a, op) })
 	case ToolListMemory:
-		return runOpenAITool(toolCall, func() (string, error) { return listMemoryToolCallImpl() })
+		return runOpenAITool(toolCall, func() (string, error) { return listMemoryToolCallImpl(ctx) })
 	case ToolSaveMemory:
-		return runOpenAITool(toolCall, func() (string, error) { return saveMemoryToolCallImpl(a) })
+		return runOpenAITool(toolCall, func() (string, error) { return saveMemoryToolCallImpl(ctx, a) })
 	case ToolListAgent:
-		return runOpenAITool(toolCall, func() (string, error) { return listAgentToolCallImpl() })
+		return runOpenAITool(toolCall, func() (string, error) { return listAgentToolCallImpl(ctx) })
 	case ToolSpawnSubAgents:
-		return runOpenAITool(toolCall, func() (string, error) { return spawnSubAgentsToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return spawnSubAgentsToolCallImpl(ctx, a, op) })
+	case ToolMapOverFiles:
+		return runOpenAITool(toolCall, func() (string, error) { return mapOverFilesToolCallImpl(ctx, a, op) })
 	case ToolGetState:
-		return runOpenAITool(toolCall, func() (string, error) { return getStateToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return getStateToolCallImpl(ctx, a, op) })
 	case ToolSetState:
-		return runOpenAITool(toolCall, func() (string, error) { return setStateToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return setStateToolCallImpl(ctx, a, op) })
 	case ToolListState:
-		return runOpenAITool(toolCall, func() (string, error) { return listStateToolCallImpl(op) })
+		return runOpenAITool(toolCall, func() (string, error) { return listStateToolCallImpl(ctx, op) })
 	case ToolActivateSkill:
-		return runOpenAITool(toolCall, func() (string, error) { return activateSkillToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return activateSkillToolCallImpl(ctx, a, op) })
+	case ToolRunSkillScript:
+		return runOpenAITool(toolCall, func() (string, error) { return runSkillScriptToolCallImpl(ctx, a, op) })
+	case ToolTodoWrite:
+		return runOpenAITool(toolCall, func() (string, error) { return todoWriteToolCallImpl(ctx, a, op) })
+	case ToolTodoRead:
+		return runOpenAITool(toolCall, func() (string, error) { return todoReadToolCallImpl(ctx, a, op) })
+	case ToolFindDefinition:
+		return runOpenAITool(toolCall, func() (string, error) { return findDefinitionToolCallImpl(ctx, a, op) })
+	case ToolFindReferences:
+		return runOpenAITool(toolCall, func() (string, error) { return findReferencesToolCallImpl(ctx, a, op) })
+	case ToolDocumentSymbols:
+		return runOpenAITool(toolCall, func() (string, error) { return documentSymbolsToolCallImpl(ctx, a, op) })
+	case ToolGHListIssues:
+		return runOpenAITool(toolCall, func() (string, error) { return ghListIssuesToolCallImpl(ctx, a, op) })
+	case ToolGHGetPRDiff:
+		return runOpenAITool(toolCall, func() (string, error) { return ghGetPRDiffToolCallImpl(ctx, a, op) })
+	case ToolGHComment:
+		return runOpenAITool(toolCall, func() (string, error) { return ghCommentToolCallImpl(ctx, a, op) })
+	case ToolGHCreatePR:
+		return runOpenAITool(toolCall, func() (string, error) { return ghCreatePRToolCallImpl(ctx, a, op) })
+	case ToolTicketSearch:
+		return runOpenAITool(toolCall, func() (string, error) { return ticketSearchToolCallImpl(ctx, a, op) })
+	case ToolTicketGet:
+		return runOpenAITool(toolCall, func() (string, error) { return ticketGetToolCallImpl(ctx, a, op) })
+	case ToolTicketCreate:
+		return runOpenAITool(toolCall, func() (string, error) { return ticketCreateToolCallImpl(ctx, a, op) })
+	case ToolTicketTransition:
+		return runOpenAITool(toolCall, func() (string, error) { return ticketTransitionToolCallImpl(ctx, a, op) })
+	case ToolQueryDatabase:
+		return runOpenAITool(toolCall, func() (string, error) { return queryDatabaseToolCallImpl(ctx, a, op) })
+	case ToolInspectData:
+		return runOpenAITool(toolCall, func() (string, error) { return inspectDataToolCallImpl(ctx, a) })
+	case ToolQueryData:
+		return runOpenAITool(toolCall, func() (string, error) { return queryDataToolCallImpl(ctx, a, op) })
+	case ToolDockerPS:
+		return runOpenAITool(toolCall, func() (string, error) { return dockerPSToolCallImpl(ctx, a, op) })
+	case ToolDockerLogs:
+		return runOpenAITool(toolCall, func() (string, error) { return dockerLogsToolCallImpl(ctx, a, op) })
+	case ToolDockerRun:
+		return runOpenAITool(toolCall, func() (string, error) { return dockerRunToolCallImpl(ctx, a, op) })
+	case ToolDockerExec:
+		return runOpenAITool(toolCall, func() (string, error) { return dockerExecToolCallImpl(ctx, a, op) })
+	case ToolHTTPRequest:
+		return runOpenAITool(toolCall, func() (string, error) { return httpRequestToolCallImpl(ctx, a, op) })
 	case ToolAskUser:
-		return runOpenAITool(toolCall, func() (string, error) { return askUserToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return askUserToolCallImpl(ctx, a, op) })
+	case ToolPresentOptions:
+		return runOpenAITool(toolCall, func() (string, error) { return presentOptionsToolCallImpl(ctx, a, op) })
+	case ToolRequestApproval:
+		return runOpenAITool(toolCall, func() (string, error) { return requestApprovalToolCallImpl(ctx, a, op) })
 	case ToolExitPlanMode:
-		return runOpenAITool(toolCall, func() (string, error) { return exitPlanModeToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return exitPlanModeToolCallImpl(ctx, a, op) })
 	case ToolEnterPlanMode:
-		return runOpenAITool(toolCall, func() (string, error) { return enterPlanModeToolCallImpl(a, op) })
+		return runOpenAITool(toolCall, func() (string, error) { return enterPlanModeToolCallImpl(ctx, a, op) })
 	case ToolBuildAgent:
-		return runOpenAITool(toolCall, func() (string, error) { return buildAgentToolCallImpl(a, op) })
-	case ToolSwitchAgent:
-		return op.openAISwitchAgentToolCall(toolCall, a)
+		return runOpenAITool(toolCall, func() (string, error) { return buildAgentToolCallImpl(ctx, a, op) })
+	case ToolSwitchAgent, ToolHandoffBack:
+		return op.openAISwitchAgentToolCall(ctx, toolCall, a)
 	default:
+		if AvailableAPITool(toolCall.Function.Name) {
+			return runOpenAITool(toolCall, func() (string, error) { return apiToolCallImpl(ctx, toolCall.Function.Name, a, op) })
+		}
+		if AvailablePluginTool(toolCall.Function.Name) {
+			return runOpenAITool(toolCall, func() (string, error) { return pluginToolCallImpl(ctx, toolCall.Function.Name, a, op) })
+		}
 		if op.mcpClient != nil && op.mcpClient.FindTool(toolCall.Function.Name) != nil {
-			return op.openAIMCPToolCall(toolCall, a)
+			return op.openAIMCPToolCall(ctx, toolCall, a)
 		}
 		// Unknown function fallback
 		errorMsg := fmt.Sprintf("Error: Unknown function '%s'. This function is not available. Please use one of the available functions from the tool list.", toolCall.Function.Name)
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
@@ -16,8 +17,8 @@ import (
 
 func Ptr[T any](t T) *T { return &t }
 
-func (op *OpenProcessor) openChatSwitchAgentToolCall(toolCall *model.ToolCall, argsMap *map[string]interface{}) (*model.ChatCompletionMessage, error) {
-	response, err := switchAgentToolCallImpl(argsMap, op)
+func (op *OpenProcessor) openChatSwitchAgentToolCall(ctx context.Context, toolCall *model.ToolCall, argsMap *map[string]interface{}) (*model.ChatCompletionMessage, error) {
+	response, err := agentSwitchToolCallImpl(ctx, toolCall.Function.Name, argsMap, op)
 
 	toolMessage := model.ChatCompletionMessage{
 		Role:       model.ChatMessageRoleTool,
@@ -38,7 +39,7 @@ func (op *OpenProcessor) openChatSwitchAgentToolCall(toolCall *model.ToolCall, a
 	return &toolMessage, err
 }
 
-func (op *OpenProcessor) openChatMCPToolCall(toolCall *model.ToolCall, argsMap *map[string]interface{}) (*model.ChatCompletionMessage, error) {
+func (op *OpenProcessor) openChatMCPToolCall(ctx context.Context, toolCall *model.ToolCall, argsMap *map[string]interface{}) (*model.ChatCompletionMessage, error) {
 	if op.mcpClient == nil {
 		err := fmt.Errorf("MCP client not initialized")
 		toolMessage := model.ChatCompletionMessage{
@@ -66,7 +67,7 @@ func (op *OpenProcessor) openChatMCPToolCall(toolCall *model.ToolCall, argsMap *
 	}
 
 	// Call the MCP tool
-	result, err := op.mcpClient.CallTool(toolCall.Function.Name, *argsMap)
+	result, err := op.mcpClient.CallTool(ctx, toolCall.Function.Name, *argsMap)
 	if err != nil {
 		toolMessage := model.ChatCompletionMessage{
 			Role:       model.ChatMessageRoleTool,
@@ -114,6 +115,15 @@ func (op *OpenProcessor) openChatMCPToolCall(toolCall *model.ToolCall, argsMap *
 }
 
 // runOpenChatTool runs fn and wraps the result into an OpenChat tool message.
+// mapOpenChatToolResult rewrites the text of an OpenChat tool message with f.
+func mapOpenChatToolResult(msg *model.ChatCompletionMessage, f func(string) string) *model.ChatCompletionMessage {
+	if msg == nil || msg.Content == nil || msg.Content.StringValue == nil {
+		return msg
+	}
+	msg.Content.StringValue = volcengine.String(f(*msg.Content.StringValue))
+	return msg
+}
+
 func runOpenChatTool(tc *model.ToolCall, fn ToolFunc) (*model.ChatCompletionMessage, error) {
 	response, err := fn()
 	if err != nil {
@@ -131,66 +141,145 @@ func runOpenChatTool(tc *model.ToolCall, fn ToolFunc) (*model.ChatCompletionMess
 
 // dispatchOpenChatToolCall handles the routing of OpenChat tool calls to the correct implementation.
 func (op *OpenProcessor) dispatchOpenChatToolCall(toolCall *model.ToolCall, a *map[string]interface{}) (*model.ChatCompletionMessage, error) {
+	ctx := op.toolContext()
 	switch toolCall.Function.Name {
 	case ToolShell:
-		return runOpenChatTool(toolCall, func() (string, error) { return shellToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return shellToolCallImpl(ctx, a, op) })
+	case ToolRunTests:
+		return runOpenChatTool(toolCall, func() (string, error) { return runTestsToolCallImpl(ctx, a, op) })
 	case ToolWebFetch:
-		return runOpenChatTool(toolCall, func() (string, error) { return webFetchToolCallImpl(a) })
+		return runOpenChatTool(toolCall, func() (string, error) { return webFetchToolCallImpl(ctx, a, op) })
+	case ToolGetTranscript:
+		return runOpenChatTool(toolCall, func() (string, error) { return getTranscriptToolCallImpl(ctx, a, op) })
 	case ToolWebSearch:
-		return runOpenChatTool(toolCall, func() (string, error) { return webSearchToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return webSearchToolCallImpl(ctx, a, op) })
 	case ToolReadFile:
-		return runOpenChatTool(toolCall, func() (string, error) { return readFileToolCallImpl(a) })
+		return runOpenChatTool(toolCall, func() (string, error) { return readFileToolCallImpl(ctx, a) })
 	case ToolWriteFile:
-		return runOpenChatTool(toolCall, func() (string, error) { return writeFileToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return writeFileToolCallImpl(ctx, a, op) })
 	case ToolEditFile:
-		return runOpenChatTool(toolCall, func() (string, error) { return editFileToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return editFileToolCallImpl(ctx, a, op) })
+	case ToolApplyChanges:
+		return runOpenChatTool(toolCall, func() (string, error) { return applyChangesToolCallImpl(ctx, a, op) })
 	case ToolCreateDirectory:
-		return runOpenChatTool(toolCall, func() (string, error) { return createDirectoryToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return createDirectoryToolCallImpl(ctx, a, op) })
 	case ToolListDirectory:
-		return runOpenChatTool(toolCall, func() (string, error) { return listDirectoryToolCallImpl(a) })
+		return runOpenChatTool(toolCall, func() (string, error) { return listDirectoryToolCallImpl(ctx, a) })
 	case ToolDeleteFile:
-		return runOpenChatTool(toolCall, func() (string, error) { return deleteFileToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return deleteFileToolCallImpl(ctx, a, op) })
 	case ToolDeleteDirectory:
-		return runOpenChatTool(toolCall, func() (string, error) { return deleteDirectoryToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return deleteDirectoryToolCallImpl(ctx, a, op) })
+	case ToolRestoreDeleted:
+		return runOpenChatTool(toolCall, func() (string, error) { return restoreDeletedToolCallImpl(ctx, a, op) })
+	case ToolWatchPath:
+		return runOpenChatTool(toolCall, func() (string, error) { return watchPathToolCallImpl(ctx, a, op) })
+	case ToolCheckChanges:
+		return runOpenChatTool(toolCall, func() (string, error) { return checkChangesToolCallImpl(ctx, a, op) })
+	case ToolGetEnvironment:
+		return runOpenChatTool(toolCall, func() (string, error) { return getEnvironmentToolCallImpl(ctx, a, op) })
+	case ToolGetTime:
+		return runOpenChatTool(toolCall, func() (string, error) { return getTimeToolCallImpl(ctx, a) })
 	case ToolMove:
-		return runOpenChatTool(toolCall, func() (string, error) { return moveToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return moveToolCallImpl(ctx, a, op) })
 	case ToolCopy:
-		return runOpenChatTool(toolCall, func() (string, error) { return copyToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return copyToolCallImpl(ctx, a, op) })
 	case ToolSearchFiles:
-		return runOpenChatTool(toolCall, func() (string, error) { return searchFilesToolCallImpl(a) })
+		return runOpenChatTool(toolCall, func() (string, error) { return searchFilesToolCallImpl(ctx, a) })
 	case ToolSearchTextInFile:
-		return runOpenChatTool(toolCall, func() (string, error) { return searchTextInFileToolCallImpl(a) })
+		return runOpenChatTool(toolCall, func() (string, error) { return searchTextInFileToolCallImpl(ctx, a) })
 	case ToolReadMultipleFiles:
-		return runOpenChatTool(toolCall, func() (string, error) { return readMultipleFilesToolCallImpl(a) })
+		return runOpenChatTool(toolCall, func() (string, error) { return readMultipleFilesToolCallImpl(ctx, a) })
+	case ToolReadSymbol:
+		return runOpenChatTool(toolCall, func() (string, error) { return readSymbolToolCallImpl(ctx, a) })
+	case ToolLint:
+		return runOpenChatTool(toolCall, func() (string, error) { return lintToolCallImpl(ctx, a, op) })
+	case ToolFormatCode:
+		return runOpenChatTool(toolCall, func() (string, error) { return formatCodeToolCallImpl(ctx, a, op) })
 	case ToolListMemory:
-		return runOpenChatTool(toolCall, func() (string, error) { return listMemoryToolCallImpl() })
+		return runOpenChatTool(toolCall, func() (string, error) { return listMemoryToolCallImpl(ctx) })
 	case ToolSaveMemory:
-		return runOpenChatTool(toolCall, func() (string, error) { return saveMemoryToolCallImpl(a) })
+		return runOpenChatTool(toolCall, func() (string, error) { return saveMemoryToolCallImpl(ctx, a) })
 	case ToolListAgent:
-		return runOpenChatTool(toolCall, func() (string, error) { return listAgentToolCallImpl() })
+		return runOpenChatTool(toolCall, func() (string, error) { return listAgentToolCallImpl(ctx) })
 	case ToolSpawnSubAgents:
-		return runOpenChatTool(toolCall, func() (string, error) { return spawnSubAgentsToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return spawnSubAgentsToolCallImpl(ctx, a, op) })
+	case ToolMapOverFiles:
+		return runOpenChatTool(toolCall, func() (string, error) { return mapOverFilesToolCallImpl(ctx, a, op) })
 	case ToolGetState:
-		return runOpenChatTool(toolCall, func() (string, error) { return getStateToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return getStateToolCallImpl(ctx, a, op) })
 	case ToolSetState:
-		return runOpenChatTool(toolCall, func() (string, error) { return setStateToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return setStateToolCallImpl(ctx, a, op) })
 	case ToolListState:
-		return runOpenChatTool(toolCall, func() (string, error) { return listStateToolCallImpl(op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return listStateToolCallImpl(ctx, op) })
 	case ToolActivateSkill:
-		return runOpenChatTool(toolCall, func() (string, error) { return activateSkillToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return activateSkillToolCallImpl(ctx, a, op) })
+	case ToolRunSkillScript:
+		return runOpenChatTool(toolCall, func() (string, error) { return runSkillScriptToolCallImpl(ctx, a, op) })
+	case ToolTodoWrite:
+		return runOpenChatTool(toolCall, func() (string, error) { return todoWriteToolCallImpl(ctx, a, op) })
+	case ToolTodoRead:
+		return runOpenChatTool(toolCall, func() (string, error) { return todoReadToolCallImpl(ctx, a, op) })
+	case ToolFindDefinition:
+		return runOpenChatTool(toolCall, func() (string, error) { return findDefinitionToolCallImpl(ctx, a, op) })
+	case ToolFindReferences:
+		return runOpenChatTool(toolCall, func() (string, error) { return findReferencesToolCallImpl(ctx, a, op) })
+	case ToolDocumentSymbols:
+		return runOpenChatTool(toolCall, func() (string, error) { return documentSymbolsToolCallImpl(ctx, a, op) })
+	case ToolGHListIssues:
+		return runOpenChatTool(toolCall, func() (string, error) { return ghListIssuesToolCallImpl(ctx, a, op) })
+	case ToolGHGetPRDiff:
+		return runOpenChatTool(toolCall, func() (string, error) { return ghGetPRDiffToolCallImpl(ctx, a, op) })
+	case ToolGHComment:
+		return runOpenChatTool(toolCall, func() (string, error) { return ghCommentToolCallImpl(ctx, a, op) })
+	case ToolGHCreatePR:
+		return runOpenChatTool(toolCall, func() (string, error) { return ghCreatePRToolCallImpl(ctx, a, op) })
+	case ToolTicketSearch:
+		return runOpenChatTool(toolCall, func() (string, error) { return ticketSearchToolCallImpl(ctx, a, op) })
+	case ToolTicketGet:
+		return runOpenChatTool(toolCall, func() (string, error) { return ticketGetToolCallImpl(ctx, a, op) })
+	case ToolTicketCreate:
+		return runOpenChatTool(toolCall, func() (string, error) { return ticketCreateToolCallImpl(ctx, a, op) })
+	case ToolTicketTransition:
+		return runOpenChatTool(toolCall, func() (string, error) { return ticketTransitionToolCallImpl(ctx, a, op) })
+	case ToolQueryDatabase:
+		return runOpenChatTool(toolCall, func() (string, error) { return queryDatabaseToolCallImpl(ctx, a, op) })
+	case ToolInspectData:
+		return runOpenChatTool(toolCall, func() (string, error) { return inspectDataToolCallImpl(ctx, a) })
+	case ToolQueryData:
+		return runOpenChatTool(toolCall, func() (string, error) { return queryDataToolCallImpl(ctx, a, op) })
+	case ToolDockerPS:
+		return runOpenChatTool(toolCall, func() (string, error) { return dockerPSToolCallImpl(ctx, a, op) })
+	case ToolDockerLogs:
+		return runOpenChatTool(toolCall, func() (string, error) { return dockerLogsToolCallImpl(ctx, a, op) })
+	case ToolDockerRun:
+		return runOpenChatTool(toolCall, func() (string, error) { return dockerRunToolCallImpl(ctx, a, op) })
+	case ToolDockerExec:
+		return runOpenChatTool(toolCall, func() (string, error) { return dockerExecToolCallImpl(ctx, a, op) })
+	case ToolHTTPRequest:
+		return runOpenChatTool(toolCall, func() (string, error) { return httpRequestToolCallImpl(ctx, a, op) })
 	case ToolAskUser:
-		return runOpenChatTool(toolCall, func() (string, error) { return askUserToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return askUserToolCallImpl(ctx, a, op) })
+	case ToolPresentOptions:
+		return runOpenChatTool(toolCall, func() (string, error) { return presentOptionsToolCallImpl(ctx, a, op) })
+	case ToolRequestApproval:
+		return runOpenChatTool(toolCall, func() (string, error) { return requestApprovalToolCallImpl(ctx, a, op) })
 	case ToolExitPlanMode:
-		return runOpenChatTool(toolCall, func() (string, error) { return exitPlanModeToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return exitPlanModeToolCallImpl(ctx, a, op) })
 	case ToolEnterPlanMode:
-		return runOpenChatTool(toolCall, func() (string, error) { return enterPlanModeToolCallImpl(a, op) })
+		return runOpenChatTool(toolCall, func() (string, error) { return enterPlanModeToolCallImpl(ctx, a, op) })
 	case ToolBuildAgent:
-		return runOpenChatTool(toolCall, func() (string, error) { return buildAgentToolCallImpl(a, op) })
-	case ToolSwitchAgent:
-		return op.openChatSwitchAgentToolCall(toolCall, a)
+		return runOpenChatTool(toolCall, func() (string, error) { return buildAgentToolCallImpl(ctx, a, op) })
+	case ToolSwitchAgent, ToolHandoffBack:
+		return op.openChatSwitchAgentToolCall(ctx, toolCall, a)
 	default:
+		if AvailableAPITool(toolCall.Function.Name) {
+			return runOpenChatTool(toolCall, func() (string, error) { return apiToolCallImpl(ctx, toolCall.Function.Name, a, op) })
+		}
+		if AvailablePluginTool(toolCall.Function.Name) {
+			return runOpenChatTool(toolCall, func() (string, error) { return pluginToolCallImpl(ctx, toolCall.Function.Name, a, op) })
+		}
 		if op.mcpClient != nil && op.mcpClient.FindTool(toolCall.Function.Name) != nil {
-			return op.openChatMCPToolCall(toolCall, a)
+			return op.openChatMCPToolCall(ctx, toolCall, a)
 		}
 		errorMsg := fmt.Sprintf("Error: Unknown function '%s'. This function is not available. Please use one of the available functions from the tool list.", toolCall.Function.Name)
 		msg := &model.ChatCompletionMessage{
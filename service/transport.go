@@ -0,0 +1,316 @@
+package service
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+// Defaults of the provider HTTP client. A session sends its turns and the turns of its
+// subagents to the same few hosts, so more idle connections are kept than Go's default of 2.
+const (
+	DefaultHTTPConnectTimeout  = 30 * time.Second
+	DefaultHTTPIdleTimeout     = 90 * time.Second
+	DefaultHTTPMaxConnsPerHost = 16
+)
+
+var (
+	providerTransport   http.RoundTripper
+	providerTransportMu sync.RWMutex
+
+	// networkTransports caches the transports built for model network settings,
+	// so that clients created per request share their connections.
+	networkTransports   = make(map[NetworkSettings]*http.Transport)
+	networkTransportsMu sync.Mutex
+
+	// providerClients caches the provider clients by their settings, so that every turn,
+	// subagent and model on the same settings reuses the same connection pool.
+	providerClients   = make(map[providerClientKey]*http.Client)
+	providerClientsMu sync.Mutex
+)
+
+type providerClientKey struct {
+	network NetworkSettings
+	http    data.HTTPSettings
+}
+
+// NetworkSettings is the proxy and TLS setup of a model's HTTP traffic.
+type NetworkSettings struct {
+	Proxy              string // http://, https:// or socks5:// proxy URL, empty uses the environment (HTTPS_PROXY ...)
+	CACert             string // PEM file with extra CA certificates to trust, e.g. of a TLS-inspecting proxy
+	InsecureSkipVerify bool   // Skip TLS certificate verification
+}
+
+// IsZero reports whether the settings leave the default transport in place.
+func (n NetworkSettings) IsZero() bool {
+	return n == NetworkSettings{}
+}
+
+// setProviderTransport overrides the transport of all provider clients, nil restores the default.
+func setProviderTransport(rt http.RoundTripper) {
+	providerTransportMu.Lock()
+	defer providerTransportMu.Unlock()
+	providerTransport = rt
+}
+
+// providerHTTPClient returns the HTTP client provider SDKs should use for the model. Clients
+// are shared, so connections stay open across turns and subagents.
+func providerHTTPClient(mi *ModelInfo) (*http.Client, error) {
+	return newProviderHTTPClient(mi, true)
+}
+
+// uncachedProviderHTTPClient returns the HTTP client of the model without the response cache,
+// for the clients whose requests change state on the provider, like batches and file uploads.
+func uncachedProviderHTTPClient(mi *ModelInfo) (*http.Client, error) {
+	return newProviderHTTPClient(mi, false)
+}
+
+func newProviderHTTPClient(mi *ModelInfo, cached bool) (*http.Client, error) {
+	// Providers build their client right before sending the request
+	MarkStartup(StartupRequestSent)
+	providerTransportMu.RLock()
+	override := providerTransport
+	providerTransportMu.RUnlock()
+	if override != nil {
+		return withProviderTransports(&http.Client{Transport: override}, mi), nil
+	}
+	var network NetworkSettings
+	if mi != nil {
+		network = mi.Network
+	}
+	client, err := sharedProviderClient(network, data.GetSettingsStore().GetHTTPSettings())
+	if err != nil {
+		return nil, err
+	}
+	// Recording and replaying see the real traffic, the cache only wraps live requests
+	if cached {
+		if cache := activeResponseCache(client.Transport); cache != nil {
+			client = &http.Client{Transport: cache}
+		}
+	}
+	return withProviderTransports(client, mi), nil
+}
+
+// withProviderTransports wraps the client with what the model's API needs on top of HTTP:
+// the fixups of its quirks, the OpenRouter routing and the extra headers and query parameters.
+func withProviderTransports(client *http.Client, mi *ModelInfo) *http.Client {
+	return withRequestExtras(withOpenRouter(withProviderQuirks(client, mi), mi), mi)
+}
+
+// requestExtrasTransport adds the extra headers and query parameters of a model to its requests.
+type requestExtrasTransport struct {
+	base        http.RoundTripper
+	headers     map[string]string
+	queryParams map[string]string
+}
+
+func (t *requestExtrasTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// A RoundTripper must not modify the request it is given
+	req = req.Clone(req.Context())
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	if len(t.queryParams) > 0 {
+		q := req.URL.Query()
+		for k, v := range t.queryParams {
+			q.Set(k, v)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(req)
+}
+
+// withRequestExtras wraps the client to send the extra headers and query parameters of the
+// model. The shared client is returned as is for the models without any.
+func withRequestExtras(client *http.Client, mi *ModelInfo) *http.Client {
+	if mi == nil || (len(mi.Headers) == 0 && len(mi.QueryParams) == 0) {
+		return client
+	}
+	return &http.Client{Transport: &requestExtrasTransport{base: client.Transport, headers: mi.Headers, queryParams: mi.QueryParams}}
+}
+
+// isChatCompletionRequest reports whether the request asks for a chat completion.
+func isChatCompletionRequest(req *http.Request) bool {
+	return req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/chat/completions") && req.Body != nil
+}
+
+// rewriteRequestBody returns a copy of the request with its body rewritten, the body is sent
+// as is when it can't be rewritten.
+func rewriteRequestBody(req *http.Request, rewrite func([]byte) ([]byte, error)) (*http.Request, error) {
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if rewritten, err := rewrite(body); err == nil {
+		body = rewritten
+	}
+	// A RoundTripper must not modify the request it is given
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.ContentLength = int64(len(body))
+	req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	return req, nil
+}
+
+// sharedProviderClient returns the client for the network settings of a model and the HTTP
+// settings, building its transport on first use. The global proxy applies to the models
+// without their own.
+func sharedProviderClient(n NetworkSettings, h data.HTTPSettings) (*http.Client, error) {
+	if n.Proxy == "" {
+		n.Proxy = h.Proxy
+	}
+	key := providerClientKey{network: n, http: h}
+	providerClientsMu.Lock()
+	defer providerClientsMu.Unlock()
+	if c, ok := providerClients[key]; ok {
+		return c, nil
+	}
+	t, err := newNetworkTransport(n)
+	if err != nil {
+		return nil, err
+	}
+	tuneTransport(t, h)
+	// No overall timeout: streamed answers run as long as the model writes
+	c := &http.Client{Transport: t}
+	providerClients[key] = c
+	return c, nil
+}
+
+// tuneTransport applies the HTTP settings to a transport.
+func tuneTransport(t *http.Transport, h data.HTTPSettings) {
+	connect := httpSettingDuration(h.ConnectTimeout, DefaultHTTPConnectTimeout)
+	t.DialContext = (&net.Dialer{Timeout: connect, KeepAlive: 30 * time.Second}).DialContext
+	t.TLSHandshakeTimeout = connect
+	t.IdleConnTimeout = httpSettingDuration(h.IdleTimeout, DefaultHTTPIdleTimeout)
+	t.ResponseHeaderTimeout = httpSettingDuration(h.ResponseTimeout, 0)
+	t.MaxIdleConnsPerHost = DefaultHTTPMaxConnsPerHost
+	if h.MaxConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = h.MaxConnsPerHost
+	}
+	if h.DisableHTTP2 {
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		t.Protocols = &protocols
+		t.ForceAttemptHTTP2 = false
+	} else {
+		t.ForceAttemptHTTP2 = true
+	}
+}
+
+// httpSettingDuration parses a duration of the HTTP settings, def when it is empty or invalid.
+func httpSettingDuration(value string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+
+// networkTransport returns a transport that applies the network settings, nil for the zero settings.
+func networkTransport(n NetworkSettings) (http.RoundTripper, error) {
+	if n.IsZero() {
+		return nil, nil
+	}
+	networkTransportsMu.Lock()
+	defer networkTransportsMu.Unlock()
+	if t, ok := networkTransports[n]; ok {
+		return t, nil
+	}
+	t, err := newNetworkTransport(n)
+	if err != nil {
+		return nil, err
+	}
+	networkTransports[n] = t
+	return t, nil
+}
+
+// newNetworkTransport builds a transport that applies the network settings.
+func newNetworkTransport(n NetworkSettings) (*http.Transport, error) {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if n.Proxy != "" {
+		proxyURL, err := parseProxyURL(n.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		t.Proxy = http.ProxyURL(proxyURL)
+	}
+	if n.CACert != "" || n.InsecureSkipVerify {
+		tlsConfig := &tls.Config{InsecureSkipVerify: n.InsecureSkipVerify}
+		if n.CACert != "" {
+			pem, err := os.ReadFile(n.CACert)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil || pool == nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no PEM certificates found in %s", n.CACert)
+			}
+			tlsConfig.RootCAs = pool
+		}
+		t.TLSClientConfig = tlsConfig
+	}
+	return t, nil
+}
+
+// ValidateProxy checks a proxy URL as accepted in a model's settings, empty is allowed.
+func ValidateProxy(proxy string) error {
+	if proxy == "" {
+		return nil
+	}
+	_, err := parseProxyURL(proxy)
+	return err
+}
+
+// parseProxyURL parses a proxy URL and checks that Go's transport supports its scheme.
+func parseProxyURL(proxy string) (*url.URL, error) {
+	proxyURL, err := url.Parse(proxy)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy '%s'", proxy)
+	}
+	switch strings.ToLower(proxyURL.Scheme) {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme '%s', use http, https or socks5", proxyURL.Scheme)
+	}
+	return proxyURL, nil
+}
+
+// ParseRequestExtra parses a KEY=VALUE header or query parameter of a model, an empty value
+// removes the key.
+func ParseRequestExtra(kind, s string) (string, string, error) {
+	key, value, ok := strings.Cut(s, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("invalid %s '%s', use KEY=VALUE", kind, s)
+	}
+	if strings.ContainsAny(key, " \t\r\n:") || strings.ContainsAny(value, "\r\n") {
+		return "", "", fmt.Errorf("invalid %s '%s': no spaces or colons in the name, no line breaks in the value", kind, s)
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+// RedactHeaderValue hides the value of a header whose name suggests a secret.
+func RedactHeaderValue(name, value string) string {
+	if isSensitiveEnvName(name) {
+		return "********"
+	}
+	return value
+}
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestNetworkTransportProxy(t *testing.T) {
+	if rt, err := networkTransport(NetworkSettings{}); err != nil || rt != nil {
+		t.Fatalf("zero settings should keep the default transport, got %v, %v", rt, err)
+	}
+	for _, proxy := range []string{"ftp://proxy:21", "not a url", "socks4://proxy:1080"} {
+		if _, err := networkTransport(NetworkSettings{Proxy: proxy}); err == nil {
+			t.Errorf("proxy %q should be rejected", proxy)
+		}
+	}
+
+	rt, err := networkTransport(NetworkSettings{Proxy: "socks5://127.0.0.1:1080"})
+	if err != nil {
+		t.Fatalf("networkTransport: %v", err)
+	}
+	req, _ := http.NewRequest("GET", "https://api.example.com/v1", nil)
+	proxyURL, err := rt.(*http.Transport).Proxy(req)
+	if err != nil || proxyURL == nil || proxyURL.String() != "socks5://127.0.0.1:1080" {
+		t.Fatalf("proxy = %v, %v", proxyURL, err)
+	}
+
+	again, _ := networkTransport(NetworkSettings{Proxy: "socks5://127.0.0.1:1080"})
+	if again != rt {
+		t.Error("transports for the same settings should be shared")
+	}
+}
+
+func TestNetworkTransportCACert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok")
+	}))
+	defer server.Close()
+
+	// Without the server's certificate the request fails verification
+	if _, err := http.Get(server.URL); err == nil {
+		t.Fatal("expected a certificate error without the CA certificate")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	client, err := providerHTTPClient(&ModelInfo{Network: NetworkSettings{CACert: caFile}})
+	if err != nil {
+		t.Fatalf("providerHTTPClient: %v", err)
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with the CA certificate failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "ok" {
+		t.Errorf("body = %q", body)
+	}
+
+	if _, err := networkTransport(NetworkSettings{CACert: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("a missing CA file should fail")
+	}
+}
+
+func TestProviderHTTPClientReuse(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	client, err := providerHTTPClient(&ModelInfo{})
+	if err != nil || client == nil {
+		t.Fatalf("providerHTTPClient: %v, %v", client, err)
+	}
+	again, _ := providerHTTPClient(nil)
+	if again != client {
+		t.Error("requests on the same settings should share the client")
+	}
+	tr := client.Transport.(*http.Transport)
+	if tr.MaxIdleConnsPerHost != DefaultHTTPMaxConnsPerHost || !tr.ForceAttemptHTTP2 || tr.ResponseHeaderTimeout != 0 {
+		t.Errorf("default tuning not applied: %d idle, http2 %v, header timeout %s",
+			tr.MaxIdleConnsPerHost, tr.ForceAttemptHTTP2, tr.ResponseHeaderTimeout)
+	}
+
+	settings := data.HTTPSettings{
+		Proxy:           "http://127.0.0.1:3128",
+		ResponseTimeout: "45s",
+		MaxConnsPerHost: 4,
+		DisableHTTP2:    true,
+	}
+	tuned, err := sharedProviderClient(NetworkSettings{}, settings)
+	if err != nil {
+		t.Fatalf("sharedProviderClient: %v", err)
+	}
+	tr = tuned.Transport.(*http.Transport)
+	if tr.MaxIdleConnsPerHost != 4 || tr.ResponseHeaderTimeout != 45*time.Second {
+		t.Errorf("settings not applied: %d idle, header timeout %s", tr.MaxIdleConnsPerHost, tr.ResponseHeaderTimeout)
+	}
+	if tr.ForceAttemptHTTP2 || tr.Protocols == nil || tr.Protocols.HTTP2() {
+		t.Error("HTTP/2 should be disabled")
+	}
+	req, _ := http.NewRequest("GET", "https://api.example.com/v1", nil)
+	if proxyURL, _ := tr.Proxy(req); proxyURL == nil || proxyURL.String() != settings.Proxy {
+		t.Errorf("global proxy = %v", proxyURL)
+	}
+
+	// A model's own proxy wins over the global one
+	own, _ := sharedProviderClient(NetworkSettings{Proxy: "socks5://127.0.0.1:1080"}, settings)
+	if proxyURL, _ := own.Transport.(*http.Transport).Proxy(req); proxyURL == nil || proxyURL.String() != "socks5://127.0.0.1:1080" {
+		t.Errorf("model proxy = %v", proxyURL)
+	}
+}
+
+func TestProviderHTTPClientRequestExtras(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+	defer srv.Close()
+
+	mi := &ModelInfo{
+		Headers:     map[string]string{"http-referer": "https://example.com", "x-title": "gllm"},
+		QueryParams: map[string]string{"api-version": "2025-01-01"},
+	}
+	client, err := providerHTTPClient(mi)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, _ := providerHTTPClient(&ModelInfo{})
+	if client == shared {
+		t.Fatal("a model with extras should not change the shared client")
+	}
+	req, _ := http.NewRequest("POST", srv.URL+"/v1/chat/completions?stream=true", nil)
+	req.Header.Set("X-Title", "sdk")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got.Header.Get("HTTP-Referer") != "https://example.com" || got.Header.Get("X-Title") != "gllm" {
+		t.Errorf("headers = %v", got.Header)
+	}
+	if q := got.URL.Query(); q.Get("api-version") != "2025-01-01" || q.Get("stream") != "true" {
+		t.Errorf("query = %v", q)
+	}
+	if req.Header.Get("X-Title") != "sdk" || req.URL.RawQuery != "stream=true" {
+		t.Error("the request given to the client should not be modified")
+	}
+
+	for _, bad := range []string{"X-Title", "=gllm", "X Title=gllm", "X-Title=a\r\nb"} {
+		if _, _, err := ParseRequestExtra("header", bad); err == nil {
+			t.Errorf("ParseRequestExtra(%q) should fail", bad)
+		}
+	}
+}
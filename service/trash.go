@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/activebook/gllm/data"
+)
+
+// trashSession returns the session whose trash keeps the files deleted in a session, the
+// tasks of sub-agents share the trash of their main session.
+func trashSession(session string) string {
+	top, _, _ := strings.Cut(session, "::")
+	return top
+}
+
+// MoveToTrash moves a file or directory to the trash of the session instead of deleting it,
+// so that restore_deleted or 'gllm trash restore' can bring it back.
+func MoveToTrash(path, session, agent string) (*data.TrashEntry, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Lstat(abs)
+	if err != nil {
+		return nil, err
+	}
+	entry := &data.TrashEntry{
+		Session: trashSession(session),
+		Agent:   agent,
+		Path:    abs,
+		IsDir:   info.IsDir(),
+		Size:    trashSize(abs, info),
+	}
+	if err := data.CreateTrashEntry(entry); err != nil {
+		return nil, fmt.Errorf("failed to create the trash entry: %w", err)
+	}
+	if err := movePath(abs, entry.ItemPath()); err != nil {
+		data.RemoveTrashEntry(entry)
+		return nil, err
+	}
+	return entry, nil
+}
+
+// RestoreTrashEntry moves a deleted file or directory back where it was deleted from. It
+// fails rather than overwrite something created there since.
+func RestoreTrashEntry(e *data.TrashEntry) error {
+	if _, err := os.Lstat(e.Path); err == nil {
+		return fmt.Errorf("%s already exists, move it away first", e.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(e.Path), 0755); err != nil {
+		return err
+	}
+	if err := movePath(e.ItemPath(), e.Path); err != nil {
+		return err
+	}
+	return data.RemoveTrashEntry(e)
+}
+
+// FindTrashedPath returns the latest entry of the session deleted from the path, nil when
+// there is none.
+func FindTrashedPath(session, path string) (*data.TrashEntry, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := data.ListTrash()
+	if err != nil {
+		return nil, err
+	}
+	session = trashSession(session)
+	for _, e := range entries {
+		if e.Session == session && e.Path == abs {
+			return e, nil
+		}
+	}
+	return nil, nil
+}
+
+// EmptyTrash deletes for good the entries of a session, or of every session when all is
+// set, and returns how many there were.
+func EmptyTrash(session string, all bool) (int, error) {
+	entries, err := data.ListTrash()
+	if err != nil {
+		return 0, err
+	}
+	session = trashSession(session)
+	n := 0
+	for _, e := range entries {
+		if !all && e.Session != session {
+			continue
+		}
+		if err := data.RemoveTrashEntry(e); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// movePath renames a file or directory, copying it when the rename crosses file systems.
+func movePath(src, dst string) error {
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if _, statErr := os.Lstat(src); statErr != nil {
+		return err
+	}
+	// A move is all or nothing, it isn't interrupted halfway
+	if _, err := copyFileOrDir(context.Background(), src, dst); err != nil {
+		os.RemoveAll(dst)
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+// trashSize returns the bytes of a file, or of the files of a directory.
+func trashSize(path string, info os.FileInfo) int64 {
+	if !info.IsDir() {
+		return info.Size()
+	}
+	var size int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if fi, err := d.Info(); err == nil {
+				size += fi.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestTrashDeleteAndRestore(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(filepath.Join("pkg", "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile("main.go", []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join("pkg", "sub", "a.go"), []byte("package sub\n"), 0644)
+
+	op := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}, sessionName: "work::task_1", agentName: "coder"}
+	result, err := deleteFileToolCallImpl(context.Background(), &map[string]interface{}{"path": "main.go"}, op)
+	if err != nil || !strings.Contains(result, "moved to the trash") {
+		t.Fatalf("delete_file = %q, %v", result, err)
+	}
+	if _, err := os.Stat("main.go"); !os.IsNotExist(err) {
+		t.Fatalf("main.go should be gone, got %v", err)
+	}
+	if result, _ := deleteDirectoryToolCallImpl(context.Background(), &map[string]interface{}{"path": "pkg"}, op); !strings.Contains(result, "moved to the trash") {
+		t.Fatalf("delete_directory = %q", result)
+	}
+
+	entries, err := data.ListTrash()
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 trash entries, got %v, %v", entries, err)
+	}
+	for _, e := range entries {
+		if e.Session != "work" || e.Agent != "coder" {
+			t.Errorf("entry %s should belong to session work and agent coder, got %q, %q", e.ID, e.Session, e.Agent)
+		}
+	}
+
+	// Another session can't restore them
+	other := &OpenProcessor{toolsUse: &data.ToolsUse{AutoApprove: true}, sessionName: "other"}
+	if result, _ := restoreDeletedToolCallImpl(context.Background(), &map[string]interface{}{"path": "main.go"}, other); !strings.HasPrefix(result, "Error") {
+		t.Errorf("restore from another session = %q", result)
+	}
+
+	if result, _ := restoreDeletedToolCallImpl(context.Background(), &map[string]interface{}{"path": "main.go"}, op); !strings.HasPrefix(result, "Successfully") {
+		t.Fatalf("restore_deleted = %q", result)
+	}
+	if content, err := os.ReadFile("main.go"); err != nil || string(content) != "package main\n" {
+		t.Errorf("main.go was not restored: %q, %v", content, err)
+	}
+
+	// A restore never overwrites
+	os.MkdirAll("pkg", 0755)
+	if result, _ := restoreDeletedToolCallImpl(context.Background(), &map[string]interface{}{"path": "pkg"}, op); !strings.Contains(result, "already exists") {
+		t.Errorf("restore over an existing path = %q", result)
+	}
+	os.Remove("pkg")
+	if result, _ := restoreDeletedToolCallImpl(context.Background(), &map[string]interface{}{"path": "pkg"}, op); !strings.HasPrefix(result, "Successfully") {
+		t.Fatalf("restore_deleted = %q", result)
+	}
+	if _, err := os.Stat(filepath.Join("pkg", "sub", "a.go")); err != nil {
+		t.Errorf("pkg was not restored: %v", err)
+	}
+
+	if entries, _ := data.ListTrash(); len(entries) != 0 {
+		t.Errorf("restored entries should leave the trash, got %d", len(entries))
+	}
+}
+
+func TestEmptyTrash(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	for i, session := range []string{"a", "a", "b"} {
+		name := filepath.Join(t.TempDir(), "f"+string(rune('0'+i)))
+		os.WriteFile(name, []byte("x"), 0644)
+		if _, err := MoveToTrash(name, session, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n, err := EmptyTrash("a", false); err != nil || n != 2 {
+		t.Errorf("EmptyTrash(a) = %d, %v", n, err)
+	}
+	if n, err := EmptyTrash("", true); err != nil || n != 1 {
+		t.Errorf("EmptyTrash(all) = %d, %v", n, err)
+	}
+	if entries, _ := data.ListTrash(); len(entries) != 0 {
+		t.Errorf("the trash should be empty, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/activebook/gllm/util"
+)
+
+const (
+	// uploadMargin keeps an upload from expiring in the middle of a session.
+	uploadMargin = time.Hour
+	// uploadRetention is how long the record of an expired upload is kept, to name it in the
+	// sessions that referenced it.
+	uploadRetention = 30 * 24 * time.Hour
+)
+
+// fileUpload is an attachment uploaded to the files API of a provider.
+type fileUpload struct {
+	Name      string    `json:"name"` // ID of the file for the API
+	URI       string    `json:"uri,omitempty"`
+	MIMEType  string    `json:"mime_type"`
+	File      string    `json:"file,omitempty"`      // Base name of the attachment
+	ExpiresAt time.Time `json:"expires_at,omitzero"` // Zero for the files kept until deleted
+}
+
+// usable reports whether the upload can still be referenced.
+func (up fileUpload) usable(now time.Time) bool {
+	return up.ExpiresAt.IsZero() || now.Before(up.ExpiresAt.Add(-uploadMargin))
+}
+
+// fileUploads records the uploads by API key and content, so that an attachment is uploaded
+// once while the provider keeps it.
+type fileUploads struct {
+	path    string
+	Uploads map[string]fileUpload `json:"uploads"`
+}
+
+// loadFileUploads reads the record of the uploads, an unreadable record starts empty.
+func loadFileUploads(path string) *fileUploads {
+	u := &fileUploads{path: path}
+	if content, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(content, u); err != nil {
+			util.LogWarnf("Ignoring the record of uploads %s: %v\n", path, err)
+		}
+	}
+	if u.Uploads == nil {
+		u.Uploads = make(map[string]fileUpload)
+	}
+	return u
+}
+
+func (u *fileUploads) save() error {
+	content, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(u.path), 0750); err != nil {
+		return err
+	}
+	return os.WriteFile(u.path, content, 0600)
+}
+
+// prune forgets the uploads that expired long ago and reports whether any was.
+func (u *fileUploads) prune(now time.Time) bool {
+	pruned := false
+	for key, up := range u.Uploads {
+		if !up.ExpiresAt.IsZero() && now.After(up.ExpiresAt.Add(uploadRetention)) {
+			delete(u.Uploads, key)
+			pruned = true
+		}
+	}
+	return pruned
+}
+
+// active returns the upload of the key while it can still be used.
+func (u *fileUploads) active(key string, now time.Time) (fileUpload, bool) {
+	up, ok := u.Uploads[key]
+	if !ok || !up.usable(now) {
+		return fileUpload{}, false
+	}
+	return up, true
+}
+
+// byURI returns the upload of a URI, expired or not.
+func (u *fileUploads) byURI(uri string) (fileUpload, bool) {
+	for _, up := range u.Uploads {
+		if up.URI == uri {
+			return up, true
+		}
+	}
+	return fileUpload{}, false
+}
+
+// uploadKey identifies an attachment uploaded for a model: uploads belong to the account of
+// the API key.
+func uploadKey(mi *ModelInfo, content []byte) string {
+	account := sha256.Sum256([]byte(mi.EndPoint + "\n" + mi.ApiKey))
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(account[:8]) + "-" + hex.EncodeToString(sum[:])
+}
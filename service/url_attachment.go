@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/data"
+	"github.com/activebook/gllm/util"
+)
+
+const (
+	// URLAttachmentCacheTTL is how long a fetched URL attachment is reused.
+	URLAttachmentCacheTTL = time.Hour
+	// URLAttachmentMaxSize is the largest document or image a URL attachment downloads.
+	URLAttachmentMaxSize = 20 * 1024 * 1024
+)
+
+// urlAttachmentCacheEntry is a fetched URL attachment on disk.
+type urlAttachmentCacheEntry struct {
+	URL       string    `json:"url"`
+	Format    string    `json:"format"`
+	Data      []byte    `json:"data"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// IsURLAttachment reports whether an attachment is a web URL to fetch.
+func IsURLAttachment(attachment string) bool {
+	return strings.HasPrefix(attachment, "http://") || strings.HasPrefix(attachment, "https://")
+}
+
+// FetchURLAttachment fetches a URL for the prompt. Documents and images are attached as
+// they are, like a local file, web pages become their extracted text and YouTube videos
+// their transcript. Fetched URLs are cached for URLAttachmentCacheTTL.
+func FetchURLAttachment(ctx context.Context, rawURL string, network NetworkSettings) (*FileData, error) {
+	if file := readURLAttachmentCache(rawURL); file != nil {
+		util.LogDebugf("Attachment %s read from the cache\n", rawURL)
+		return file, nil
+	}
+
+	if IsYouTubeURL(rawURL) {
+		file, err := fetchYouTubeAttachment(ctx, rawURL, network)
+		if err != nil {
+			return nil, err
+		}
+		writeURLAttachmentCache(rawURL, file)
+		return file, nil
+	}
+
+	transport, err := networkTransport(network)
+	if err != nil {
+		return nil, err
+	}
+	config := defaultConfig
+	config.Transport = transport
+
+	resp, err := fetchURLResponse(ctx, rawURL, &config)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, URLAttachmentMaxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+	if len(body) > URLAttachmentMaxSize {
+		return nil, fmt.Errorf("%s is larger than %d MB", rawURL, URLAttachmentMaxSize/(1024*1024))
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	var file *FileData
+	if format, ok := urlDocumentFormat(rawURL, contentType, body); ok {
+		file = NewFileData(format, body, rawURL)
+	} else {
+		lines, err := extractTextByContentType(contentType, bytes.NewReader(body), &config)
+		if err != nil {
+			return nil, err
+		}
+		text := strings.Join(lines, "\n")
+		if strings.TrimSpace(text) == "" {
+			return nil, fmt.Errorf("no text found at %s", rawURL)
+		}
+		file = NewFileData("text/plain", []byte(fmt.Sprintf("Content of %s:\n\n%s\n", rawURL, text)), rawURL)
+	}
+	writeURLAttachmentCache(rawURL, file)
+	return file, nil
+}
+
+// fetchYouTubeAttachment attaches the transcript of a video, or its audio for the model
+// to transcribe when it has no captions.
+func fetchYouTubeAttachment(ctx context.Context, rawURL string, network NetworkSettings) (*FileData, error) {
+	transcript, err := FetchYouTubeTranscript(ctx, rawURL, "", network)
+	if err == nil {
+		return NewFileData("text/plain", []byte(transcript.Render()), rawURL), nil
+	}
+	util.LogDebugf("No transcript for %s: %v\n", rawURL, err)
+	audio, audioErr := DownloadYouTubeAudio(ctx, rawURL, network)
+	if audioErr != nil {
+		return nil, fmt.Errorf("%w, and the audio fallback failed: %v", err, audioErr)
+	}
+	return audio, nil
+}
+
+// urlDocumentFormat returns the MIME type of a fetched PDF or image, which the model
+// reads itself, false for a page to extract the text of.
+func urlDocumentFormat(rawURL, contentType string, body []byte) (string, bool) {
+	if isImage, format, err := CheckIfImageFromBytes(body); err == nil && isImage {
+		return format, true
+	}
+	contentType = strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if IsPDFMIMEType(contentType) || bytes.HasPrefix(body, []byte("%PDF-")) {
+		return "application/pdf", true
+	}
+	// Servers often send documents as a generic download
+	if contentType == "" || contentType == "application/octet-stream" {
+		if u, err := url.Parse(rawURL); err == nil && IsPDFMIMEType(GetMIMEType(u.Path)) {
+			return "application/pdf", true
+		}
+	}
+	return "", false
+}
+
+func urlAttachmentCachePath(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return filepath.Join(data.GetURLCacheDirPath(), hex.EncodeToString(sum[:])+".json")
+}
+
+// readURLAttachmentCache returns the cached attachment of a URL, nil when it is missing
+// or expired.
+func readURLAttachmentCache(rawURL string) *FileData {
+	content, err := os.ReadFile(urlAttachmentCachePath(rawURL))
+	if err != nil {
+		return nil
+	}
+	var entry urlAttachmentCacheEntry
+	if err := json.Unmarshal(content, &entry); err != nil || entry.URL != rawURL {
+		return nil
+	}
+	if time.Since(entry.FetchedAt) > URLAttachmentCacheTTL {
+		return nil
+	}
+	return NewFileData(entry.Format, entry.Data, rawURL)
+}
+
+func writeURLAttachmentCache(rawURL string, file *FileData) {
+	content, err := json.Marshal(urlAttachmentCacheEntry{URL: rawURL, Format: file.Format(), Data: file.Data(), FetchedAt: time.Now()})
+	if err != nil {
+		return
+	}
+	path := urlAttachmentCachePath(rawURL)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		util.LogDebugf("Failed to cache the attachment %s: %v\n", rawURL, err)
+	}
+}
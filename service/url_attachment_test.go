@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchURLAttachment(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	pdf := []byte("%PDF-1.4\n%fake document\n")
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte(`<html><body><nav>Home | About</nav><article><p>The release notes of version two describe the new parser.</p></article></body></html>`))
+		case "/files/spec.pdf":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(pdf)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	page, err := FetchURLAttachment(ctx, server.URL+"/page", NetworkSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(page.Data())
+	if page.Format() != "text/plain" || !strings.HasPrefix(text, "Content of "+server.URL+"/page:") || !strings.Contains(text, "release notes of version two") {
+		t.Errorf("unexpected page attachment %s: %q", page.Format(), text)
+	}
+	if strings.Contains(text, "Home | About") {
+		t.Errorf("the navigation was not removed: %q", text)
+	}
+
+	doc, err := FetchURLAttachment(ctx, server.URL+"/files/spec.pdf", NetworkSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Format() != "application/pdf" || string(doc.Data()) != string(pdf) {
+		t.Errorf("the PDF was not attached as a document: %s %q", doc.Format(), doc.Data())
+	}
+
+	// Fetched URLs are read from the cache
+	if _, err := FetchURLAttachment(ctx, server.URL+"/page", NetworkSettings{}); err != nil || requests != 2 {
+		t.Errorf("the page was fetched again: %d requests, %v", requests, err)
+	}
+
+	if _, err := FetchURLAttachment(ctx, server.URL+"/missing", NetworkSettings{}); err == nil {
+		t.Errorf("expected an error for a missing page")
+	}
+}
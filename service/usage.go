@@ -18,6 +18,10 @@ type TokenUsage struct {
 	// For providers like Anthropic, cached tokens are not included in the prompt tokens
 	// OpenAI, OpenChat and Gemini all include cached tokens in the prompt tokens
 	CachedTokensInPrompt bool
+
+	// Reported by gateways such as OpenRouter, zero when unknown
+	ReportedCost float64 // USD charged for the requests
+	ServedBy     string  // Provider and model that served the latest request
 }
 
 const (
@@ -156,7 +160,7 @@ func (tu *TokenUsage) renderLipgloss() string {
 		valueStyle.Bold(true).Foreground(totalColor).Render(fmt.Sprintf("%d", tu.TotalTokens)),
 	)
 
-	block := lipgloss.JoinVertical(lipgloss.Left,
+	rows := []string{
 		titleStyle.Render("Token Usage"),
 		underline,
 		headers,
@@ -169,7 +173,17 @@ func (tu *TokenUsage) renderLipgloss() string {
 		rowThought,
 		underline,
 		rowTotal,
-	)
+	}
+	if tu.ReportedCost > 0 {
+		rows = append(rows, lipgloss.JoinHorizontal(lipgloss.Left,
+			labelStyle.Render("Cost"),
+			valueStyle.Render(fmt.Sprintf("$%.4f", tu.ReportedCost)),
+		))
+	}
+	if tu.ServedBy != "" {
+		rows = append(rows, lipgloss.NewStyle().Foreground(labelColor).Width(colWidth*2).Render("Served by "+tu.ServedBy))
+	}
+	block := lipgloss.JoinVertical(lipgloss.Left, rows...)
 
 	return boxStyle.Render(block)
 }
@@ -181,3 +195,38 @@ func (tu *TokenUsage) RecordTokenUsage(input, output, cached, thought, total int
 	tu.ThoughtTokens += thought
 	tu.TotalTokens += total
 }
+
+// RecordServed records the provider and the model that served a request and what it cost.
+func (tu *TokenUsage) RecordServed(provider, model string, cost float64) {
+	tu.ReportedCost += cost
+	switch {
+	case provider != "" && model != "":
+		tu.ServedBy = provider + " · " + model
+	case provider != "" || model != "":
+		tu.ServedBy = provider + model
+	}
+}
+
+// Cost estimates the cost in USD of the usage with the prices of the model, 0 when it has none.
+func (tu *TokenUsage) Cost(model *data.Model) float64 {
+	if model == nil {
+		return 0
+	}
+	return tu.CostAt(model.InputPrice, model.OutputPrice)
+}
+
+// CostAt estimates the cost in USD of the usage with prices in USD per million tokens.
+func (tu *TokenUsage) CostAt(inputPrice, outputPrice float32) float64 {
+	if tu == nil {
+		return 0
+	}
+	// The cost charged beats the estimate
+	if tu.ReportedCost > 0 {
+		return tu.ReportedCost
+	}
+	input := tu.InputTokens
+	if !tu.CachedTokensInPrompt {
+		input += tu.CachedTokens
+	}
+	return (float64(input)*float64(inputPrice) + float64(tu.OutputTokens)*float64(outputPrice)) / 1e6
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"github.com/activebook/gllm/data"
+)
+
+func TestTokenUsageCost(t *testing.T) {
+	model := &data.Model{InputPrice: 2.5, OutputPrice: 10}
+
+	tu := NewTokenUsage()
+	tu.RecordTokenUsage(200_000, 50_000, 100_000, 0, 250_000)
+	if got := tu.Cost(model); math.Abs(got-1.0) > 1e-9 {
+		t.Errorf("Cost() = %v, want 1.0", got)
+	}
+
+	// Cached tokens outside the prompt are input too
+	tu.CachedTokensInPrompt = CachedTokensNotInPrompt
+	if got := tu.Cost(model); math.Abs(got-1.25) > 1e-9 {
+		t.Errorf("Cost() with cached tokens outside the prompt = %v, want 1.25", got)
+	}
+
+	if got := tu.Cost(&data.Model{}); got != 0 {
+		t.Errorf("Cost() without prices = %v, want 0", got)
+	}
+}
@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/activebook/gllm/util"
+	"github.com/fsnotify/fsnotify"
+)
+
+// DefaultWatchDebounce is how long a watch waits for changes to settle before a rerun.
+const DefaultWatchDebounce = time.Second
+
+// maxWatchDiffSize bounds the diff added to a watch prompt.
+const maxWatchDiffSize = 64 * 1024
+
+// watchSkipDirs are never watched, they change a lot and are not reviewed.
+var watchSkipDirs = map[string]bool{".git": true, "node_modules": true, "vendor": true, "__pycache__": true}
+
+// MatchGlob reports whether the slash separated path matches the pattern, where ** matches
+// any number of directories. A pattern without a slash matches the base name, as in .gitignore.
+func MatchGlob(pattern, name string) bool {
+	pattern = strings.TrimPrefix(filepath.ToSlash(pattern), "./")
+	name = filepath.ToSlash(name)
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchGlobParts(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchGlobParts(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every number of directories ** can stand for
+			for i := 0; i <= len(name); i++ {
+				if matchGlobParts(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// FileWatcher watches a directory tree and reports the changed files matching its globs,
+// in batches once the changes settle.
+type FileWatcher struct {
+	root     string
+	globs    []string
+	debounce time.Duration
+	watcher  *fsnotify.Watcher
+}
+
+// NewFileWatcher starts watching the tree under root. Hidden directories and the ones of
+// watchSkipDirs are left out. Close it when done.
+func NewFileWatcher(root string, globs []string, debounce time.Duration) (*FileWatcher, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	if len(globs) == 0 {
+		return nil, fmt.Errorf("at least one glob is required")
+	}
+	for _, g := range globs {
+		if _, err := path.Match(strings.ReplaceAll(g, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("invalid glob '%s': %w", g, err)
+		}
+	}
+	if debounce <= 0 {
+		debounce = DefaultWatchDebounce
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start watching: %w", err)
+	}
+	w := &FileWatcher{root: root, globs: globs, debounce: debounce, watcher: watcher}
+	if _, err := w.addTree(root); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// Root returns the absolute directory being watched.
+func (w *FileWatcher) Root() string {
+	return w.root
+}
+
+// Close stops watching.
+func (w *FileWatcher) Close() error {
+	return w.watcher.Close()
+}
+
+// addTree watches dir and the directories below it, returning the watched files already in them.
+func (w *FileWatcher) addTree(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// A directory removed or unreadable meanwhile is not fatal
+			if p == dir {
+				return err
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			if rel, ok := w.match(p); ok {
+				files = append(files, rel)
+			}
+			return nil
+		}
+		if p != w.root && (watchSkipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+			return filepath.SkipDir
+		}
+		if err := w.watcher.Add(p); err != nil {
+			return fmt.Errorf("failed to watch %s: %w", p, err)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// addDir watches a directory created under the root. Files can be written to it before it is
+// watched, so those already in it are returned as changed.
+func (w *FileWatcher) addDir(event fsnotify.Event) ([]string, bool) {
+	if !event.Has(fsnotify.Create) {
+		return nil, false
+	}
+	if info, err := os.Stat(event.Name); err != nil || !info.IsDir() {
+		return nil, false
+	}
+	files, err := w.addTree(event.Name)
+	if err != nil {
+		util.LogWarnf("%v\n", err)
+	}
+	return files, true
+}
+
+// match returns the path relative to the root when it is a watched file.
+func (w *FileWatcher) match(name string) (string, bool) {
+	rel, err := filepath.Rel(w.root, name)
+	if err != nil {
+		return "", false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, g := range w.globs {
+		if MatchGlob(g, rel) {
+			return rel, true
+		}
+	}
+	return "", false
+}
+
+// isChange reports whether the event changes the content of a file, rather than its permissions.
+func isChange(event fsnotify.Event) bool {
+	return event.Has(fsnotify.Write) || event.Has(fsnotify.Create) || event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+}
+
+// Next waits for changes to watched files and returns them, sorted and relative to the root,
+// once no change came for the debounce period.
+func (w *FileWatcher) Next(ctx context.Context) ([]string, error) {
+	changed := make(map[string]bool)
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return nil, errors.New("file watcher closed")
+			}
+			// New directories are watched as they appear
+			files, isDir := w.addDir(event)
+			if !isDir && isChange(event) {
+				if rel, ok := w.match(event.Name); ok {
+					files = append(files, rel)
+				}
+			}
+			for _, f := range files {
+				changed[f] = true
+			}
+			if len(files) > 0 {
+				settled = time.After(w.debounce)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return nil, errors.New("file watcher closed")
+			}
+			util.LogWarnf("File watcher: %v\n", err)
+		case <-settled:
+			files := make([]string, 0, len(changed))
+			for f := range changed {
+				files = append(files, f)
+			}
+			sort.Strings(files)
+			return files, nil
+		}
+	}
+}
+
+// Discard drops the changes queued so far, such as the edits of the run that just finished.
+// It returns once the events have been quiet for a moment.
+func (w *FileWatcher) Discard() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.addDir(event)
+		case <-time.After(100 * time.Millisecond):
+			return
+		}
+	}
+}
+
+// WatchDiff returns the git diff of the files against HEAD, empty when root is not in a
+// git repository. Large diffs are cut short.
+func WatchDiff(ctx context.Context, root string, files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	args := append([]string{"diff", "--no-color", "HEAD", "--"}, files...)
+	diff, err := runGit(ctx, root, args...)
+	if err != nil {
+		util.LogDebugf("No diff for the watched files: %v\n", err)
+		return ""
+	}
+	if len(diff) > maxWatchDiffSize {
+		diff = diff[:maxWatchDiffSize] + "\n... (diff truncated)"
+	}
+	return diff
+}
+
+// BuildWatchPrompt adds the changed files, and their diff when there is one, to the prompt
+// of a watch run. Files no longer under root are marked deleted.
+func BuildWatchPrompt(prompt, root string, files []string, diff string) string {
+	var sb strings.Builder
+	sb.WriteString(prompt)
+	sb.WriteString("\n\nChanged files:\n")
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(root, filepath.FromSlash(f))); os.IsNotExist(err) {
+			fmt.Fprintf(&sb, "- %s (deleted)\n", f)
+		} else {
+			fmt.Fprintf(&sb, "- %s\n", f)
+		}
+	}
+	if diff != "" {
+		sb.WriteString("\nDiff:\n```diff\n")
+		sb.WriteString(diff)
+		sb.WriteString("\n```\n")
+	}
+	return sb.String()
+}
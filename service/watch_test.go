@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/root.go", true},
+		{"*.go", "README.md", false},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/c.go", true},
+		{"src/**/*.go", "lib/main.go", false},
+		{"./src/*.go", "src/main.go", true},
+		{"src/*.go", "src/a/main.go", false},
+		{"**/*", "a/b/c.txt", true},
+		{"docs/**", "docs/guide/intro.md", true},
+		{"**/test/*.py", "test/a.py", true},
+	}
+	for _, tt := range tests {
+		if got := MatchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("MatchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBuildWatchPrompt(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.go"), []byte("package a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got := BuildWatchPrompt("Review", root, []string{"a.go", "b.go"}, "")
+	for _, want := range []string{"Review\n", "- a.go\n", "- b.go (deleted)\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prompt %q misses %q", got, want)
+		}
+	}
+	if strings.Contains(got, "```diff") {
+		t.Errorf("prompt without a diff has a diff block: %q", got)
+	}
+	if got := BuildWatchPrompt("Review", root, []string{"a.go"}, "+package a"); !strings.Contains(got, "```diff\n+package a\n```") {
+		t.Errorf("prompt misses the diff: %q", got)
+	}
+}
+
+func TestFileWatcherNext(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewFileWatcher(root, []string{"src/**/*.go"}, 50*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	write := func(name string) {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	write("src/main.go")
+	write("src/notes.txt")
+	write("other.go")
+	files, err := w.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"src/main.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("Next = %v, want %v", files, want)
+	}
+
+	// Directories created after the start are watched, with the files written before
+	write("src/pkg/util.go")
+	files, err = w.Next(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"src/pkg/util.go"}; !reflect.DeepEqual(files, want) {
+		t.Errorf("Next = %v, want %v", files, want)
+	}
+
+	// Discarded changes are not reported
+	write("src/main.go")
+	w.Discard()
+	short, cancelShort := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancelShort()
+	if files, err := w.Next(short); err == nil {
+		t.Errorf("Next after Discard = %v, want no changes", files)
+	}
+}
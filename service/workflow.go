@@ -15,6 +15,7 @@ import (
 type WorkflowManager struct {
 	workflows        []data.WorkflowMetadata
 	workflowsDir     string
+	teamDir          string            // read-only workflows of the team config, shadowed by local ones
 	reservedCommands map[string]string // reserved commands that cannot be used as workflow names
 	mu               sync.RWMutex
 }
@@ -30,6 +31,7 @@ func GetWorkflowManager() *WorkflowManager {
 		data.EnsureWorkflowsDir()
 		workflowManagerInstance = &WorkflowManager{
 			workflowsDir: data.GetWorkflowsDirPath(),
+			teamDir:      data.GetTeamWorkflowsDirPath(),
 		}
 	})
 	return workflowManagerInstance
@@ -44,6 +46,21 @@ func (wm *WorkflowManager) LoadMetadata(reservedCommands map[string]string) erro
 	if err != nil {
 		return err
 	}
+	if wm.teamDir != "" {
+		team, _ := data.ScanWorkflowsInDir(wm.teamDir)
+		for _, w := range team {
+			shadowed := false
+			for _, own := range workflows {
+				if strings.EqualFold(own.Name, w.Name) {
+					shadowed = true
+					break
+				}
+			}
+			if !shadowed {
+				workflows = append(workflows, w)
+			}
+		}
+	}
 	wm.workflows = workflows
 	wm.reservedCommands = reservedCommands
 	return nil
@@ -75,6 +92,68 @@ func (wm *WorkflowManager) GetWorkflowByName(name string) (string, string, error
 	return content, selected.Description, nil
 }
 
+// GetWorkflowNotify returns the sink a workflow posts its final output to, empty for none.
+func (wm *WorkflowManager) GetWorkflowNotify(name string) string {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	for _, w := range wm.workflows {
+		if strings.EqualFold(w.Name, name) {
+			return w.Notify
+		}
+	}
+	return ""
+}
+
+// GetWorkflowApprove returns the action of a workflow that waits for approval, empty for none.
+func (wm *WorkflowManager) GetWorkflowApprove(name string) string {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	for _, w := range wm.workflows {
+		if strings.EqualFold(w.Name, name) {
+			return w.Approve
+		}
+	}
+	return ""
+}
+
+// GetWorkflowBatch reports whether a workflow submits its jobs file as a batch rather than
+// running in the session.
+func (wm *WorkflowManager) GetWorkflowBatch(name string) bool {
+	wm.mu.RLock()
+	defer wm.mu.RUnlock()
+
+	for _, w := range wm.workflows {
+		if strings.EqualFold(w.Name, name) {
+			return w.Batch
+		}
+	}
+	return false
+}
+
+// WorkflowApprovalGuideline tells the agent running a workflow to stop for approval before
+// its action.
+func WorkflowApprovalGuideline(action string) string {
+	return fmt.Sprintf("This workflow needs approval before you %s. When you get there, call '%s' with that action and the output it is based on, "+
+		"make the edits it asks for, and do not %s unless it is approved.", action, ToolRequestApproval, action)
+}
+
+// workflowFileContent renders a workflow file with its frontmatter.
+func workflowFileContent(name, description, notify, approve string, batch bool, content string) string {
+	frontmatter := fmt.Sprintf("name: %s\ndescription: %s\n", name, description)
+	if notify != "" {
+		frontmatter += fmt.Sprintf("notify: %s\n", notify)
+	}
+	if approve != "" {
+		frontmatter += fmt.Sprintf("approve: %s\n", approve)
+	}
+	if batch {
+		frontmatter += "batch: true\n"
+	}
+	return fmt.Sprintf("---\n%s---\n\n%s", frontmatter, content)
+}
+
 // GetWorkflowNames returns a sorted list of all available workflow names
 func (wm *WorkflowManager) GetWorkflowNames() []string {
 	wm.mu.RLock()
@@ -101,6 +180,11 @@ func (wm *WorkflowManager) GetCommands() map[string]string {
 	return commands
 }
 
+// isTeamWorkflow reports whether a workflow file belongs to the read-only team config.
+func (wm *WorkflowManager) isTeamWorkflow(path string) bool {
+	return wm.teamDir != "" && strings.HasPrefix(path, wm.teamDir+string(filepath.Separator))
+}
+
 // IsReservedCommand checks if a command is reserved
 func (wm *WorkflowManager) IsReservedCommand(name string) bool {
 	if !strings.HasPrefix(name, "/") {
@@ -112,6 +196,10 @@ func (wm *WorkflowManager) IsReservedCommand(name string) bool {
 
 // CreateWorkflow creates a new workflow file
 func (wm *WorkflowManager) CreateWorkflow(name, description, content string) error {
+	return wm.createWorkflow(name, description, "", "", false, content)
+}
+
+func (wm *WorkflowManager) createWorkflow(name, description, notify, approve string, batch bool, content string) error {
 	if wm.IsReservedCommand(name) {
 		return fmt.Errorf("cannot create workflow '%s': conflicts with reserved command", name)
 	}
@@ -125,7 +213,7 @@ func (wm *WorkflowManager) CreateWorkflow(name, description, content string) err
 	}
 
 	// Prepare content with frontmatter
-	fullContent := fmt.Sprintf("---\nname: %s\ndescription: %s\n---\n\n%s", name, description, content)
+	fullContent := workflowFileContent(name, description, notify, approve, batch, content)
 
 	if err := os.MkdirAll(wm.workflowsDir, 0750); err != nil {
 		return err
@@ -142,11 +230,12 @@ func (wm *WorkflowManager) CreateWorkflow(name, description, content string) err
 // UpdateWorkflow updates an existing workflow file
 func (wm *WorkflowManager) UpdateWorkflow(name, description, content string) error {
 	wm.mu.RLock()
-	var path string
+	var path, notify, approve string
+	var batch bool
 	lowerName := strings.ToLower(name)
 	for _, w := range wm.workflows {
 		if strings.ToLower(w.Name) == lowerName {
-			path = w.Location
+			path, notify, approve, batch = w.Location, w.Notify, w.Approve, w.Batch
 			break
 		}
 	}
@@ -155,9 +244,12 @@ func (wm *WorkflowManager) UpdateWorkflow(name, description, content string) err
 	if path == "" {
 		return fmt.Errorf("workflow '%s' not found", name)
 	}
+	if wm.isTeamWorkflow(path) {
+		return fmt.Errorf("workflow '%s' comes from the team config and is read-only", name)
+	}
 
-	// Prepare content with frontmatter
-	fullContent := fmt.Sprintf("---\nname: %s\ndescription: %s\n---\n\n%s", name, description, content)
+	// Prepare content with frontmatter, keeping the sink, the approval and the batch option
+	fullContent := workflowFileContent(name, description, notify, approve, batch, content)
 
 	if err := os.WriteFile(path, []byte(fullContent), 0644); err != nil {
 		return fmt.Errorf("failed to update workflow file: %w", err)
@@ -183,6 +275,9 @@ func (wm *WorkflowManager) RemoveWorkflow(name string) error {
 	if path == "" {
 		return fmt.Errorf("workflow '%s' not found", name)
 	}
+	if wm.isTeamWorkflow(path) {
+		return fmt.Errorf("workflow '%s' comes from the team config and is read-only", name)
+	}
 
 	if err := os.Remove(path); err != nil {
 		return fmt.Errorf("failed to remove workflow file: %w", err)
@@ -201,6 +296,15 @@ func (wm *WorkflowManager) RenameWorkflow(oldName, newName string) error {
 		return fmt.Errorf("cannot rename to '%s': conflicts with reserved command", newName)
 	}
 
+	wm.mu.RLock()
+	for _, w := range wm.workflows {
+		if strings.EqualFold(w.Name, oldName) && wm.isTeamWorkflow(w.Location) {
+			wm.mu.RUnlock()
+			return fmt.Errorf("workflow '%s' comes from the team config and is read-only", oldName)
+		}
+	}
+	wm.mu.RUnlock()
+
 	// Get existing data
 	content, desc, err := wm.GetWorkflowByName(oldName)
 	if err != nil {
@@ -209,7 +313,7 @@ func (wm *WorkflowManager) RenameWorkflow(oldName, newName string) error {
 
 	// Create new workflow first to ensure it's valid
 	// This will check if new name is reserved or already exists
-	if err := wm.CreateWorkflow(newName, desc, content); err != nil {
+	if err := wm.createWorkflow(newName, desc, wm.GetWorkflowNotify(oldName), wm.GetWorkflowApprove(oldName), wm.GetWorkflowBatch(oldName), content); err != nil {
 		return err
 	}
 
@@ -0,0 +1,394 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// TranscriptChunkLength is the span of the transcript under one timestamp.
+	TranscriptChunkLength = 30 * time.Second
+	// youtubeAudioMaxSize caps the audio attached when a video has no captions.
+	youtubeAudioMaxSize = 20 * 1024 * 1024
+)
+
+// youtubeWatchURL is the page holding the caption tracks of a video, a variable for the tests.
+var youtubeWatchURL = "https://www.youtube.com/watch?v="
+
+var youtubeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// TranscriptSegment is a caption of a video.
+type TranscriptSegment struct {
+	Start    time.Duration
+	Duration time.Duration
+	Text     string
+}
+
+// Transcript is the captions of a video.
+type Transcript struct {
+	VideoID   string
+	Title     string
+	Language  string
+	Generated bool // Captions recognized automatically, not written by a person
+	Length    time.Duration
+	Segments  []TranscriptSegment
+}
+
+// TranscriptChunk is a span of a transcript under one timestamp.
+type TranscriptChunk struct {
+	Start time.Duration
+	Text  string
+}
+
+// YouTubeVideoID returns the ID of the video of a YouTube URL, false for other URLs.
+func YouTubeVideoID(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	var id string
+	switch host {
+	case "youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case "youtube.com", "m.youtube.com", "music.youtube.com", "youtube-nocookie.com":
+		if u.Path == "/watch" {
+			id = u.Query().Get("v")
+		} else if parts := strings.Split(strings.Trim(u.Path, "/"), "/"); len(parts) == 2 {
+			switch parts[0] {
+			case "shorts", "embed", "live", "v":
+				id = parts[1]
+			}
+		}
+	}
+	if !youtubeIDPattern.MatchString(id) {
+		return "", false
+	}
+	return id, true
+}
+
+// IsYouTubeURL reports whether a URL is a YouTube video.
+func IsYouTubeURL(rawURL string) bool {
+	_, ok := YouTubeVideoID(rawURL)
+	return ok
+}
+
+// youtubePlayerResponse is the part of the player data of a video page we read.
+type youtubePlayerResponse struct {
+	VideoDetails struct {
+		Title         string `json:"title"`
+		LengthSeconds string `json:"lengthSeconds"`
+	} `json:"videoDetails"`
+	Captions struct {
+		Renderer struct {
+			Tracks []youtubeCaptionTrack `json:"captionTracks"`
+		} `json:"playerCaptionsTracklistRenderer"`
+	} `json:"captions"`
+}
+
+type youtubeCaptionTrack struct {
+	BaseURL      string `json:"baseUrl"`
+	LanguageCode string `json:"languageCode"`
+	Kind         string `json:"kind"` // "asr" for automatic captions
+}
+
+// youtubeCaptions is a caption track in the json3 format.
+type youtubeCaptions struct {
+	Events []struct {
+		StartMs    int64 `json:"tStartMs"`
+		DurationMs int64 `json:"dDurationMs"`
+		Segs       []struct {
+			Text string `json:"utf8"`
+		} `json:"segs"`
+	} `json:"events"`
+}
+
+// FetchYouTubeTranscript fetches the captions of a YouTube video in a language, e.g. "en",
+// empty for the language of the video. Captions written by a person are preferred to
+// automatic ones. When the page lists none, yt-dlp is tried if it is installed.
+func FetchYouTubeTranscript(ctx context.Context, rawURL, language string, network NetworkSettings) (*Transcript, error) {
+	id, ok := YouTubeVideoID(rawURL)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a YouTube video", rawURL)
+	}
+	transport, err := networkTransport(network)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Timeout: 30 * time.Second, Transport: transport}
+
+	transcript, err := fetchYouTubeCaptions(ctx, client, id, language)
+	if err == nil {
+		return transcript, nil
+	}
+	if _, lookErr := exec.LookPath("yt-dlp"); lookErr != nil {
+		return nil, err
+	}
+	if fallback, dlpErr := ytdlpTranscript(ctx, id, language, network); dlpErr == nil {
+		return fallback, nil
+	}
+	return nil, err
+}
+
+// fetchYouTubeCaptions reads the caption tracks from the page of the video.
+func fetchYouTubeCaptions(ctx context.Context, client *http.Client, id, language string) (*Transcript, error) {
+	page, err := youtubeGet(ctx, client, youtubeWatchURL+id)
+	if err != nil {
+		return nil, err
+	}
+	player, err := parseYouTubePlayerResponse(page)
+	if err != nil {
+		return nil, err
+	}
+	track, ok := pickCaptionTrack(player.Captions.Renderer.Tracks, language)
+	if !ok {
+		return nil, fmt.Errorf("video %s has no captions", id)
+	}
+
+	trackURL := track.BaseURL
+	if strings.HasPrefix(trackURL, "/") {
+		base, _ := url.Parse(youtubeWatchURL)
+		trackURL = base.Scheme + "://" + base.Host + trackURL
+	}
+	content, err := youtubeGet(ctx, client, trackURL+"&fmt=json3")
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the captions of %s: %w", id, err)
+	}
+	segments, err := parseJSON3Captions(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the captions of %s: %w", id, err)
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("the captions of %s are empty", id)
+	}
+
+	seconds, _ := strconv.Atoi(player.VideoDetails.LengthSeconds)
+	return &Transcript{
+		VideoID:   id,
+		Title:     player.VideoDetails.Title,
+		Language:  track.LanguageCode,
+		Generated: track.Kind == "asr",
+		Length:    time.Duration(seconds) * time.Second,
+		Segments:  segments,
+	}, nil
+}
+
+func youtubeGet(ctx context.Context, client *http.Client, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", defaultConfig.UserAgent)
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+	// Skips the cookie consent page served in Europe
+	req.AddCookie(&http.Cookie{Name: "CONSENT", Value: "YES+1"})
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// parseYouTubePlayerResponse reads the player data embedded in the page of a video.
+func parseYouTubePlayerResponse(page []byte) (*youtubePlayerResponse, error) {
+	marker := []byte("ytInitialPlayerResponse = ")
+	i := bytes.Index(page, marker)
+	if i < 0 {
+		return nil, fmt.Errorf("no video data found in the page")
+	}
+	var player youtubePlayerResponse
+	// The decoder stops at the end of the object, the script goes on after it
+	if err := json.NewDecoder(bytes.NewReader(page[i+len(marker):])).Decode(&player); err != nil {
+		return nil, fmt.Errorf("failed to read the video data: %w", err)
+	}
+	return &player, nil
+}
+
+// pickCaptionTrack picks the track in the language, or in any language when it is empty
+// or missing, written by a person if there is one.
+func pickCaptionTrack(tracks []youtubeCaptionTrack, language string) (youtubeCaptionTrack, bool) {
+	rank := func(t youtubeCaptionTrack) int {
+		r := 0
+		if language != "" && (strings.EqualFold(t.LanguageCode, language) || strings.HasPrefix(strings.ToLower(t.LanguageCode), strings.ToLower(language)+"-")) {
+			r += 2
+		}
+		if t.Kind != "asr" {
+			r++
+		}
+		return r
+	}
+	best, found := youtubeCaptionTrack{}, false
+	for _, t := range tracks {
+		if t.BaseURL != "" && (!found || rank(t) > rank(best)) {
+			best, found = t, true
+		}
+	}
+	return best, found
+}
+
+func parseJSON3Captions(content []byte) ([]TranscriptSegment, error) {
+	var captions youtubeCaptions
+	if err := json.Unmarshal(content, &captions); err != nil {
+		return nil, err
+	}
+	var segments []TranscriptSegment
+	for _, e := range captions.Events {
+		var sb strings.Builder
+		for _, s := range e.Segs {
+			sb.WriteString(s.Text)
+		}
+		text := strings.Join(strings.Fields(sb.String()), " ")
+		if text == "" {
+			continue
+		}
+		segments = append(segments, TranscriptSegment{
+			Start:    time.Duration(e.StartMs) * time.Millisecond,
+			Duration: time.Duration(e.DurationMs) * time.Millisecond,
+			Text:     text,
+		})
+	}
+	return segments, nil
+}
+
+// ytdlpTranscript downloads the captions of a video with yt-dlp, automatic ones included.
+func ytdlpTranscript(ctx context.Context, id, language string, network NetworkSettings) (*Transcript, error) {
+	dir, err := os.MkdirTemp("", "gllm-transcript-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	langs := "en.*,.*-orig"
+	if language != "" {
+		langs = language + ".*"
+	}
+	args := []string{"--skip-download", "--write-subs", "--write-auto-subs", "--sub-format", "json3",
+		"--sub-langs", langs, "--print", "%(title)s\n%(duration)s", "--no-simulate", "-o", filepath.Join(dir, "%(id)s.%(ext)s")}
+	if network.Proxy != "" {
+		args = append(args, "--proxy", network.Proxy)
+	}
+	out, err := exec.CommandContext(ctx, "yt-dlp", append(args, "--", id)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp failed: %w", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json3"))
+	if len(files) == 0 {
+		return nil, fmt.Errorf("video %s has no captions", id)
+	}
+	content, err := os.ReadFile(files[0])
+	if err != nil {
+		return nil, err
+	}
+	segments, err := parseJSON3Captions(content)
+	if err != nil || len(segments) == 0 {
+		return nil, fmt.Errorf("failed to read the captions of %s", id)
+	}
+
+	// Files are named <id>.<language>.json3
+	transcript := &Transcript{VideoID: id, Segments: segments}
+	if parts := strings.Split(filepath.Base(files[0]), "."); len(parts) >= 3 {
+		transcript.Language = parts[len(parts)-2]
+	}
+	lines := strings.SplitN(strings.TrimSpace(string(out)), "\n", 2)
+	transcript.Title = lines[0]
+	if len(lines) == 2 {
+		if seconds, err := strconv.ParseFloat(strings.TrimSpace(lines[1]), 64); err == nil {
+			transcript.Length = time.Duration(seconds) * time.Second
+		}
+	}
+	return transcript, nil
+}
+
+// DownloadYouTubeAudio downloads the audio of a video with yt-dlp, for a model to
+// transcribe when the video has no captions.
+func DownloadYouTubeAudio(ctx context.Context, rawURL string, network NetworkSettings) (*FileData, error) {
+	id, ok := YouTubeVideoID(rawURL)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a YouTube video", rawURL)
+	}
+	if _, err := exec.LookPath("yt-dlp"); err != nil {
+		return nil, fmt.Errorf("yt-dlp is needed to download the audio of a video without captions")
+	}
+	dir, err := os.MkdirTemp("", "gllm-audio-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	args := []string{"-x", "--audio-format", "mp3", "--audio-quality", "9", "--max-filesize", strconv.Itoa(youtubeAudioMaxSize),
+		"-o", filepath.Join(dir, "%(id)s.%(ext)s")}
+	if network.Proxy != "" {
+		args = append(args, "--proxy", network.Proxy)
+	}
+	if out, err := exec.CommandContext(ctx, "yt-dlp", append(args, "--", id)...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("yt-dlp failed: %s", strings.TrimSpace(string(out)))
+	}
+	content, err := os.ReadFile(filepath.Join(dir, id+".mp3"))
+	if err != nil {
+		return nil, fmt.Errorf("the audio of %s is larger than %d MB", id, youtubeAudioMaxSize/(1024*1024))
+	}
+	return NewFileData("audio/mp3", content, rawURL), nil
+}
+
+// Chunks groups the captions into spans of about length, each under the timestamp of its
+// first caption.
+func (t *Transcript) Chunks(length time.Duration) []TranscriptChunk {
+	var chunks []TranscriptChunk
+	for _, s := range t.Segments {
+		if len(chunks) == 0 || s.Start-chunks[len(chunks)-1].Start >= length {
+			chunks = append(chunks, TranscriptChunk{Start: s.Start, Text: s.Text})
+			continue
+		}
+		chunks[len(chunks)-1].Text += " " + s.Text
+	}
+	return chunks
+}
+
+// Render returns the transcript for the model: a header, then the chunks with timestamps.
+func (t *Transcript) Render() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Transcript of %q (https://youtu.be/%s)", t.Title, t.VideoID)
+	var details []string
+	if t.Language != "" {
+		details = append(details, t.Language)
+	}
+	if t.Generated {
+		details = append(details, "automatic captions")
+	}
+	if t.Length > 0 {
+		details = append(details, formatTimestamp(t.Length)+" long")
+	}
+	if len(details) > 0 {
+		fmt.Fprintf(&sb, ", %s", strings.Join(details, ", "))
+	}
+	sb.WriteString(":\n\n")
+	for _, c := range t.Chunks(TranscriptChunkLength) {
+		fmt.Fprintf(&sb, "[%s] %s\n", formatTimestamp(c.Start), c.Text)
+	}
+	return sb.String()
+}
+
+// formatTimestamp writes a position in a video as m:ss, or h:mm:ss past an hour.
+func formatTimestamp(d time.Duration) string {
+	s := int(d / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
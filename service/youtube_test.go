@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestYouTubeVideoID(t *testing.T) {
+	for rawURL, want := range map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42s": "dQw4w9WgXcQ",
+		"https://youtu.be/dQw4w9WgXcQ":                      "dQw4w9WgXcQ",
+		"https://m.youtube.com/shorts/dQw4w9WgXcQ":          "dQw4w9WgXcQ",
+		"https://www.youtube.com/embed/dQw4w9WgXcQ":         "dQw4w9WgXcQ",
+		"https://www.youtube.com/channel/UC123":             "",
+		"https://example.com/watch?v=dQw4w9WgXcQ":           "",
+	} {
+		id, ok := YouTubeVideoID(rawURL)
+		if id != want || ok != (want != "") {
+			t.Errorf("YouTubeVideoID(%q) = %q, %v", rawURL, id, ok)
+		}
+	}
+}
+
+func TestFetchYouTubeTranscript(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/watch":
+			w.Write([]byte(`<script>var ytInitialPlayerResponse = {"videoDetails":{"title":"Go in 100 seconds","lengthSeconds":"100"},` +
+				`"captions":{"playerCaptionsTracklistRenderer":{"captionTracks":[` +
+				`{"baseUrl":"` + server.URL + `/api/timedtext?lang=en&kind=asr","languageCode":"en","kind":"asr"},` +
+				`{"baseUrl":"` + server.URL + `/api/timedtext?lang=en","languageCode":"en"},` +
+				`{"baseUrl":"` + server.URL + `/api/timedtext?lang=fr","languageCode":"fr"}]}}};var meta = {};</script>`))
+		case "/api/timedtext":
+			if r.URL.Query().Get("fmt") != "json3" || r.URL.Query().Get("kind") == "asr" {
+				http.Error(w, "wrong track", http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"events":[` +
+				`{"tStartMs":0,"dDurationMs":4000,"segs":[{"utf8":"Go is a "},{"utf8":"language"}]},` +
+				`{"tStartMs":5000,"dDurationMs":1000,"segs":[{"utf8":"\n"}]},` +
+				`{"tStartMs":12000,"dDurationMs":4000,"segs":[{"utf8":"made at Google."}]},` +
+				`{"tStartMs":41000,"dDurationMs":4000,"segs":[{"utf8":"It compiles fast."}]}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	old := youtubeWatchURL
+	youtubeWatchURL = server.URL + "/watch?v="
+	defer func() { youtubeWatchURL = old }()
+
+	transcript, err := FetchYouTubeTranscript(context.Background(), "https://youtu.be/dQw4w9WgXcQ", "en", NetworkSettings{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transcript.Title != "Go in 100 seconds" || transcript.Generated || transcript.Length != 100*time.Second || len(transcript.Segments) != 3 {
+		t.Fatalf("unexpected transcript: %+v", transcript)
+	}
+
+	want := "Transcript of \"Go in 100 seconds\" (https://youtu.be/dQw4w9WgXcQ), en, 1:40 long:\n\n" +
+		"[0:00] Go is a language made at Google.\n" +
+		"[0:41] It compiles fast.\n"
+	if got := transcript.Render(); got != want {
+		t.Errorf("unexpected rendering:\n%s", got)
+	}
+}
+
+func TestPickCaptionTrack(t *testing.T) {
+	tracks := []youtubeCaptionTrack{
+		{BaseURL: "a", LanguageCode: "en", Kind: "asr"},
+		{BaseURL: "b", LanguageCode: "pt-BR"},
+		{BaseURL: "c", LanguageCode: "pt-BR", Kind: "asr"},
+	}
+	for language, want := range map[string]string{"": "b", "en": "a", "pt": "b", "de": "b"} {
+		if got, _ := pickCaptionTrack(tracks, language); got.BaseURL != want {
+			t.Errorf("pickCaptionTrack(%q) = %s, want %s", language, got.BaseURL, want)
+		}
+	}
+	if _, ok := pickCaptionTrack(nil, "en"); ok {
+		t.Errorf("a video without captions has a track")
+	}
+}
@@ -1,7 +1,10 @@
 package util
 
 import (
+	"io"
 	"os"
+	"strings"
+	"sync"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -48,6 +51,47 @@ func InitLogger() {
 			DisableColors:    false,
 			DisableTimestamp: true, // Remove timestamp numbers like [0000]
 		})
+		logger.AddHook(recentLogs)
+	}
+}
+
+// recentLogLines is the number of log lines kept for crash reports.
+const recentLogLines = 100
+
+// logTail keeps the latest log lines, so a crash report can tell what led to the crash.
+type logTail struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+var recentLogs = &logTail{}
+
+func (t *logTail) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (t *logTail) Fire(entry *log.Entry) error {
+	line := entry.Time.Format("15:04:05") + " " + entry.Level.String() + ": " + strings.TrimRight(entry.Message, "\n")
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lines = append(t.lines, line)
+	if len(t.lines) > recentLogLines {
+		t.lines = t.lines[len(t.lines)-recentLogLines:]
+	}
+	return nil
+}
+
+// RecentLogs returns the latest log lines, oldest first.
+func RecentLogs() []string {
+	recentLogs.mu.Lock()
+	defer recentLogs.mu.Unlock()
+	return append([]string(nil), recentLogs.lines...)
+}
+
+// SetLoggerOutput redirects the log, e.g. into a full-screen UI that owns the terminal.
+func SetLoggerOutput(w io.Writer) {
+	if logger != nil {
+		logger.SetOutput(w)
 	}
 }
 